@@ -62,7 +62,7 @@ type Builder struct {
 
 	eg errgroup.Group
 
-	signer            *signing.EdSigner
+	signer            signing.Signer
 	accountLock       sync.RWMutex
 	nodeID            types.NodeID
 	coinbaseAccount   types.Address
@@ -129,7 +129,7 @@ func WithPoetConfig(c PoetConfig) BuilderOption {
 func NewBuilder(
 	conf Config,
 	nodeID types.NodeID,
-	signer *signing.EdSigner,
+	signer signing.Signer,
 	cdb *datastore.CachedDB,
 	hdlr atxHandler,
 	publisher pubsub.Publisher,
@@ -657,8 +657,12 @@ func (b *Builder) GetPositioningAtx() (types.ATXID, error) {
 }
 
 // SignAndFinalizeAtx signs the atx with specified signer and calculates the ID of the ATX.
-func SignAndFinalizeAtx(signer *signing.EdSigner, atx *types.ActivationTx) error {
-	atx.Signature = signer.Sign(signing.ATX, atx.SignedBytes())
+func SignAndFinalizeAtx(signer signing.Signer, atx *types.ActivationTx) error {
+	sig, err := signer.SignContext(context.Background(), signing.ATX, atx.SignedBytes())
+	if err != nil {
+		return err
+	}
+	atx.Signature = sig
 	atx.SmesherID = signer.NodeID()
 	return atx.Initialize()
 }
@@ -105,12 +105,17 @@ type testAtxBuilder struct {
 }
 
 func newTestBuilder(tb testing.TB, opts ...BuilderOption) *testAtxBuilder {
+	return newTestBuilderWithDB(tb, datastore.NewCachedDB(sql.InMemory(), logtest.New(tb)), opts...)
+}
+
+// newTestBuilderWithDB creates a builder for a new identity that uses the database.
+func newTestBuilderWithDB(tb testing.TB, cdb *datastore.CachedDB, opts ...BuilderOption) *testAtxBuilder {
 	lg := logtest.New(tb)
 	edSigner, err := signing.NewEdSigner()
 	require.NoError(tb, err)
 	ctrl := gomock.NewController(tb)
 	tab := &testAtxBuilder{
-		cdb:         datastore.NewCachedDB(sql.InMemory(), lg),
+		cdb:         cdb,
 		sig:         edSigner,
 		nodeID:      edSigner.NodeID(),
 		coinbase:    types.GenerateAddress([]byte("33333")),
@@ -412,6 +417,40 @@ func TestBuilder_PublishActivationTx_HappyFlow(t *testing.T) {
 	require.Equal(t, atx1.TargetEpoch()+1, atx2.TargetEpoch())
 }
 
+func TestBuilder_PublishActivationTx_MultipleIdentities(t *testing.T) {
+	// identities of the node share the database, but every identity builds its own chain of atxs
+	cdb := datastore.NewCachedDB(sql.InMemory(), logtest.New(t))
+	posEpoch := postGenesisEpoch
+	builders := make([]*testAtxBuilder, 3)
+	prevs := make([]*types.VerifiedActivationTx, len(builders))
+	for i := range builders {
+		builders[i] = newTestBuilderWithDB(t, cdb, WithPoetConfig(PoetConfig{PhaseShift: layerDuration}))
+		challenge := newChallenge(uint64(i+1), types.ATXID{1, 2, 3}, types.ATXID{1, 2, 3}, posEpoch, nil)
+		nipost := newNIPostWithChallenge(t, types.HexToHash32("55555"), []byte("66666"))
+		prevs[i] = addAtx(t, cdb, builders[i].sig, newAtx(t, builders[i].sig, challenge, nipost, 2, types.Address{}))
+	}
+
+	for epoch := posEpoch; epoch < posEpoch+2; epoch++ {
+		published := map[types.ATXID]struct{}{}
+		for i, tab := range builders {
+			currLayer := epoch.FirstLayer()
+			tab.mclock.EXPECT().CurrentLayer().Return(currLayer).Times(5)
+			atx, err := publishAtx(t, tab, prevs[i].ID(), epoch, &currLayer, layersPerEpoch)
+			require.NoError(t, err)
+			require.NotNil(t, atx)
+			require.Equal(t, tab.nodeID, atx.SmesherID)
+			require.Equal(t, prevs[i].ID(), atx.PrevATXID)
+			require.Equal(t, prevs[i].Sequence+1, atx.Sequence)
+			require.Equal(t, epoch+1, atx.PublishEpoch)
+			published[atx.ID()] = struct{}{}
+
+			prevs[i], err = cdb.GetFullAtx(atx.ID())
+			require.NoError(t, err)
+		}
+		require.Len(t, published, len(builders))
+	}
+}
+
 // TestBuilder_Loop_WaitsOnStaleChallenge checks if loop waits between attempts
 // failing with ErrATXChallengeExpired.
 func TestBuilder_Loop_WaitsOnStaleChallenge(t *testing.T) {
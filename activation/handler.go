@@ -492,7 +492,7 @@ func (h *Handler) HandleGossipAtx(ctx context.Context, peer p2p.Peer, msg []byte
 func (h *Handler) handleGossipAtx(ctx context.Context, peer p2p.Peer, msg []byte) error {
 	receivedTime := time.Now()
 	var atx types.ActivationTx
-	if err := codec.Decode(msg, &atx); err != nil {
+	if err := codec.DecodeStrict(msg, &atx); err != nil {
 		return fmt.Errorf("%w: %v", errMalformedData, err)
 	}
 
@@ -506,8 +506,8 @@ func (h *Handler) handleGossipAtx(ctx context.Context, peer p2p.Peer, msg []byte
 		return fmt.Errorf("failed to derive ID from atx: %w", err)
 	}
 
-	if !h.edVerifier.Verify(signing.ATX, atx.SmesherID, atx.SignedBytes(), atx.Signature) {
-		return fmt.Errorf("failed to verify atx signature: %w", errMalformedData)
+	if err := h.verifyAtxSignature(ctx, &atx); err != nil {
+		return err
 	}
 
 	logger := h.log.WithContext(ctx).WithFields(atx.ID())
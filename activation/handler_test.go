@@ -986,6 +986,7 @@ func TestHandler_HandleAtxData(t *testing.T) {
 		require.NoError(t, err)
 
 		atxHdlr.mclock.EXPECT().LayerToTime(gomock.Any()).Return(time.Now())
+		atxHdlr.mockFetch.EXPECT().GetKeyRotations(gomock.Any(), []types.NodeID{sig.NodeID()}).Return(nil)
 		require.ErrorContains(t, atxHdlr.HandleAtxData(context.Background(), p2p.NoPeer, buf), "failed to verify atx signature")
 	})
 }
@@ -0,0 +1,140 @@
+package activation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/spacemeshos/go-spacemesh/codec"
+	"github.com/spacemeshos/go-spacemesh/common/types"
+	"github.com/spacemeshos/go-spacemesh/log"
+	"github.com/spacemeshos/go-spacemesh/p2p"
+	"github.com/spacemeshos/go-spacemesh/p2p/pubsub"
+	"github.com/spacemeshos/go-spacemesh/signing"
+	"github.com/spacemeshos/go-spacemesh/sql"
+	"github.com/spacemeshos/go-spacemesh/sql/keyrotations"
+)
+
+var (
+	errKnownKeyRotation   = errors.New("known key rotation")
+	errPendingKeyRotation = fmt.Errorf("%w: identity has a pending key rotation", pubsub.ErrValidationReject)
+	errInvalidKeyRotation = fmt.Errorf("%w: invalid key rotation", pubsub.ErrValidationReject)
+)
+
+// HandleGossipKeyRotation handles key rotation records on the atx gossip path.
+func (h *Handler) HandleGossipKeyRotation(ctx context.Context, peer p2p.Peer, msg []byte) error {
+	var rotation types.KeyRotation
+	if err := codec.DecodeStrict(msg, &rotation); err != nil {
+		return fmt.Errorf("%w: %v", errMalformedData, err)
+	}
+	err := h.handleKeyRotation(ctx, &rotation)
+	if err != nil && !errors.Is(err, errKnownKeyRotation) {
+		h.log.WithContext(ctx).With().Warning("failed to process key rotation",
+			log.Stringer("sender", peer),
+			log.Err(err),
+		)
+	}
+	return err
+}
+
+// HandleSyncedKeyRotations handles key rotations of the identity received by sync.
+// Rotations are expected in the order of transition epochs.
+func (h *Handler) HandleSyncedKeyRotations(ctx context.Context, _ p2p.Peer, data []byte) error {
+	rotations, err := codec.DecodeSlice[types.KeyRotation](data)
+	if err != nil {
+		return fmt.Errorf("%w: %v", errMalformedData, err)
+	}
+	for i := range rotations {
+		if err := h.handleKeyRotation(ctx, &rotations[i]); err != nil && !errors.Is(err, errKnownKeyRotation) {
+			return err
+		}
+	}
+	return nil
+}
+
+// verifyAtxSignature checks that the atx is signed by one of the keys accepted for the identity
+// in the publish epoch. If none of the known keys match, key rotations of the identity are fetched
+// and the signature is checked again.
+func (h *Handler) verifyAtxSignature(ctx context.Context, atx *types.ActivationTx) error {
+	verify := func(key types.NodeID) bool {
+		return h.edVerifier.Verify(signing.ATX, key, atx.SignedBytes(), atx.Signature)
+	}
+	valid, err := keyrotations.Verify(h.cdb, atx.SmesherID, atx.PublishEpoch, verify)
+	if err != nil {
+		return err
+	}
+	if valid {
+		return nil
+	}
+	if err := h.fetcher.GetKeyRotations(ctx, []types.NodeID{atx.SmesherID}); err != nil {
+		h.log.WithContext(ctx).With().Debug("failed to fetch key rotations",
+			log.Stringer("smesher", atx.SmesherID),
+			log.Err(err),
+		)
+		return fmt.Errorf("failed to verify atx signature: %w", errMalformedData)
+	}
+	if valid, err = keyrotations.Verify(h.cdb, atx.SmesherID, atx.PublishEpoch, verify); err != nil {
+		return err
+	} else if !valid {
+		return fmt.Errorf("failed to verify atx signature: %w", errMalformedData)
+	}
+	return nil
+}
+
+// handleKeyRotation validates and stores the key rotation. Rotation is valid if:
+// - it references an atx of the identity;
+// - transition epoch starts after the target epoch of that atx;
+// - identity has no rotation whose transition epoch starts after the target epoch of that atx;
+// - it is signed by the newest key of the identity that is accepted in the target epoch of that atx.
+//
+// The rules don't depend on the time when the rotation is received, so that
+// rotations fetched during sync are validated in the same way as gossiped ones.
+func (h *Handler) handleKeyRotation(ctx context.Context, rotation *types.KeyRotation) error {
+	if rotation.NewKey == types.EmptyNodeID || rotation.NewKey == rotation.NodeID {
+		return fmt.Errorf("%w: new key %s", errInvalidKeyRotation, rotation.NewKey.ShortString())
+	}
+	if rotation.AtxID == types.EmptyATXID || rotation.AtxID == h.goldenATXID {
+		return fmt.Errorf("%w: atx %s", errInvalidKeyRotation, rotation.AtxID.ShortString())
+	}
+	if err := h.fetcher.GetAtxs(ctx, []types.ATXID{rotation.AtxID}); err != nil {
+		return fmt.Errorf("fetch atx %s for key rotation: %w", rotation.AtxID.ShortString(), err)
+	}
+	atx, err := h.cdb.GetAtxHeader(rotation.AtxID)
+	if err != nil {
+		return fmt.Errorf("get atx %s for key rotation: %w", rotation.AtxID.ShortString(), err)
+	}
+	if atx.NodeID != rotation.NodeID {
+		return fmt.Errorf("%w: atx %s belongs to %s", errInvalidKeyRotation, rotation.AtxID.ShortString(), atx.NodeID.ShortString())
+	}
+	target := atx.TargetEpoch()
+	if rotation.Epoch <= target {
+		return fmt.Errorf("%w: transition epoch %v is not after the atx target epoch %v",
+			errInvalidKeyRotation, rotation.Epoch, target)
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	latest, err := keyrotations.Latest(h.cdb, rotation.NodeID)
+	switch {
+	case errors.Is(err, sql.ErrNotFound):
+	case err != nil:
+		return err
+	case latest.InnerKeyRotation == rotation.InnerKeyRotation:
+		return fmt.Errorf("%w: %s", errKnownKeyRotation, rotation.NodeID.ShortString())
+	case latest.Epoch > target:
+		return fmt.Errorf("%w: %s in epoch %v", errPendingKeyRotation, rotation.NodeID.ShortString(), latest.Epoch)
+	}
+	keys, err := keyrotations.Keys(h.cdb, rotation.NodeID, target)
+	if err != nil {
+		return err
+	}
+	if !h.edVerifier.Verify(signing.KEY_ROTATION, keys[0], rotation.SignedBytes(), rotation.Signature) {
+		return fmt.Errorf("%w: signature", errInvalidKeyRotation)
+	}
+	if err := keyrotations.Add(h.cdb, rotation); err != nil {
+		return err
+	}
+	h.log.WithContext(ctx).With().Info("new key rotation", log.Inline(rotation))
+	return nil
+}
@@ -0,0 +1,157 @@
+package activation
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/spacemeshos/go-spacemesh/codec"
+	"github.com/spacemeshos/go-spacemesh/common/types"
+	"github.com/spacemeshos/go-spacemesh/p2p"
+	"github.com/spacemeshos/go-spacemesh/p2p/pubsub"
+	"github.com/spacemeshos/go-spacemesh/signing"
+	"github.com/spacemeshos/go-spacemesh/sql/atxs"
+	"github.com/spacemeshos/go-spacemesh/sql/keyrotations"
+)
+
+func newKeyRotation(signer *signing.EdSigner, nodeID, newKey types.NodeID, atxID types.ATXID, epoch types.EpochID) *types.KeyRotation {
+	rotation := &types.KeyRotation{
+		InnerKeyRotation: types.InnerKeyRotation{NodeID: nodeID, NewKey: newKey, AtxID: atxID, Epoch: epoch},
+	}
+	rotation.Signature = signer.Sign(signing.KEY_ROTATION, rotation.SignedBytes())
+	return rotation
+}
+
+func TestHandler_HandleGossipKeyRotation(t *testing.T) {
+	goldenATXID := types.ATXID{2, 3, 4}
+	sig, err := signing.NewEdSigner()
+	require.NoError(t, err)
+	first, err := signing.NewEdSigner()
+	require.NoError(t, err)
+	second, err := signing.NewEdSigner()
+	require.NoError(t, err)
+	other, err := signing.NewEdSigner()
+	require.NoError(t, err)
+	nodeID := sig.NodeID()
+
+	// clock is not mocked, rotations are validated without the wall clock
+	atxHdlr := newTestHandler(t, goldenATXID)
+	atxHdlr.mockFetch.EXPECT().GetAtxs(gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+	handle := func(rotation *types.KeyRotation) error {
+		return atxHdlr.HandleGossipKeyRotation(context.Background(), p2p.NoPeer, codec.MustEncode(rotation))
+	}
+	require.ErrorIs(t, atxHdlr.HandleGossipKeyRotation(context.Background(), p2p.NoPeer, []byte{1, 2, 3}), errMalformedData)
+
+	npst := newNIPostWithChallenge(t, types.HexToHash32("0x3333"), []byte{0xba, 0xbe})
+	coinbase := types.GenerateAddress([]byte("aaaa"))
+	atx2 := newActivationTx(t, sig, 0, types.EmptyATXID, types.EmptyATXID, &goldenATXID, 2, 0, 100, coinbase, 100, npst)
+	atx3 := newActivationTx(t, sig, 1, atx2.ID(), atx2.ID(), nil, 3, 0, 100, coinbase, 100, npst)
+	otherAtx := newActivationTx(t, other, 0, types.EmptyATXID, types.EmptyATXID, &goldenATXID, 2, 0, 100, coinbase, 100, npst)
+	for _, atx := range []*types.VerifiedActivationTx{atx2, atx3, otherAtx} {
+		require.NoError(t, atxs.Add(atxHdlr.cdb, atx))
+	}
+
+	t.Run("invalid", func(t *testing.T) {
+		for _, tc := range []struct {
+			desc     string
+			rotation *types.KeyRotation
+		}{
+			{"same key", newKeyRotation(sig, nodeID, nodeID, atx2.ID(), 4)},
+			{"golden atx", newKeyRotation(sig, nodeID, first.NodeID(), goldenATXID, 4)},
+			{"atx of other identity", newKeyRotation(sig, nodeID, first.NodeID(), otherAtx.ID(), 4)},
+			{"atx target epoch", newKeyRotation(sig, nodeID, first.NodeID(), atx2.ID(), 3)},
+			{"not signed by identity", newKeyRotation(first, nodeID, first.NodeID(), atx2.ID(), 4)},
+		} {
+			err := handle(tc.rotation)
+			require.ErrorIs(t, err, pubsub.ErrValidationReject, tc.desc)
+		}
+	})
+
+	rotation := newKeyRotation(sig, nodeID, first.NodeID(), atx2.ID(), 4)
+	require.NoError(t, handle(rotation))
+	require.ErrorIs(t, handle(rotation), errKnownKeyRotation)
+	// previous rotation is pending in the target epoch of the referenced atx
+	require.ErrorIs(t, handle(newKeyRotation(sig, nodeID, second.NodeID(), atx2.ID(), 5)), errPendingKeyRotation)
+
+	// transition epoch, both keys are accepted
+	keys, err := keyrotations.Keys(atxHdlr.cdb, nodeID, 4)
+	require.NoError(t, err)
+	require.Equal(t, []types.NodeID{first.NodeID(), nodeID}, keys)
+
+	// next rotation must be signed by the newest key in the target epoch of the referenced atx
+	require.ErrorIs(t, handle(newKeyRotation(sig, nodeID, second.NodeID(), atx3.ID(), 5)), errInvalidKeyRotation)
+	require.NoError(t, handle(newKeyRotation(first, nodeID, second.NodeID(), atx3.ID(), 5)))
+
+	keys, err = keyrotations.Keys(atxHdlr.cdb, nodeID, 6)
+	require.NoError(t, err)
+	require.Equal(t, []types.NodeID{second.NodeID()}, keys)
+
+	t.Run("missing atx", func(t *testing.T) {
+		atxHdlr := newTestHandler(t, goldenATXID)
+		atxHdlr.mockFetch.EXPECT().GetAtxs(gomock.Any(), []types.ATXID{atx2.ID()}).Return(errors.New("not found"))
+		err := atxHdlr.HandleGossipKeyRotation(context.Background(), p2p.NoPeer, codec.MustEncode(rotation))
+		require.ErrorContains(t, err, "not found")
+	})
+
+	t.Run("synced", func(t *testing.T) {
+		blob, err := keyrotations.GetBlob(atxHdlr.cdb, nodeID.Bytes())
+		require.NoError(t, err)
+
+		synced := newTestHandler(t, goldenATXID)
+		synced.mockFetch.EXPECT().GetAtxs(gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+		for _, atx := range []*types.VerifiedActivationTx{atx2, atx3} {
+			require.NoError(t, atxs.Add(synced.cdb, atx))
+		}
+		// first rotation is already known
+		require.NoError(t, synced.handleKeyRotation(context.Background(), rotation))
+		require.NoError(t, synced.HandleSyncedKeyRotations(context.Background(), p2p.NoPeer, blob))
+		all, err := keyrotations.All(synced.cdb, nodeID)
+		require.NoError(t, err)
+		expected, err := keyrotations.All(atxHdlr.cdb, nodeID)
+		require.NoError(t, err)
+		require.Equal(t, expected, all)
+
+		require.ErrorIs(t, synced.HandleSyncedKeyRotations(context.Background(), p2p.NoPeer, []byte{1, 2}), errMalformedData)
+	})
+}
+
+func TestHandler_VerifyAtxSignatureRotatedKey(t *testing.T) {
+	goldenATXID := types.ATXID{2, 3, 4}
+	sig, err := signing.NewEdSigner()
+	require.NoError(t, err)
+	rotated, err := signing.NewEdSigner()
+	require.NoError(t, err)
+
+	atxHdlr := newTestHandler(t, goldenATXID)
+	npst := newNIPostWithChallenge(t, types.HexToHash32("0x3333"), []byte{0xba, 0xbe})
+	coinbase := types.GenerateAddress([]byte("aaaa"))
+	prev := newActivationTx(t, sig, 0, types.EmptyATXID, types.EmptyATXID, &goldenATXID, 2, 0, 100, coinbase, 100, npst)
+	require.NoError(t, atxs.Add(atxHdlr.cdb, prev))
+
+	// atx of the identity signed by the rotated key
+	challenge := newChallenge(1, prev.ID(), prev.ID(), 4, nil)
+	atx := newAtx(t, sig, challenge, npst, 100, coinbase)
+	atx.Signature = rotated.Sign(signing.ATX, atx.SignedBytes())
+	atx.SmesherID = sig.NodeID()
+	require.NoError(t, atx.Initialize())
+
+	// rotation is fetched when the signature doesn't match known keys
+	atxHdlr.mockFetch.EXPECT().GetKeyRotations(gomock.Any(), []types.NodeID{sig.NodeID()}).Return(errors.New("not found"))
+	require.ErrorIs(t, atxHdlr.verifyAtxSignature(context.Background(), atx), errMalformedData)
+
+	atxHdlr.mockFetch.EXPECT().GetKeyRotations(gomock.Any(), []types.NodeID{sig.NodeID()}).DoAndReturn(
+		func(context.Context, []types.NodeID) error {
+			return keyrotations.Add(atxHdlr.cdb, newKeyRotation(sig, sig.NodeID(), rotated.NodeID(), prev.ID(), 4))
+		})
+	require.NoError(t, atxHdlr.verifyAtxSignature(context.Background(), atx))
+	require.NoError(t, atxHdlr.verifyAtxSignature(context.Background(), atx))
+
+	// rotated key is not accepted before the transition epoch
+	atx.PublishEpoch = 3
+	atx.Signature = rotated.Sign(signing.ATX, atx.SignedBytes())
+	atxHdlr.mockFetch.EXPECT().GetKeyRotations(gomock.Any(), []types.NodeID{sig.NodeID()}).Return(nil)
+	require.ErrorIs(t, atxHdlr.verifyAtxSignature(context.Background(), atx), errMalformedData)
+}
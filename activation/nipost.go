@@ -66,7 +66,7 @@ type NIPostBuilder struct {
 	poetDB            poetDbAPI
 	state             *types.NIPostBuilderState
 	log               log.Log
-	signer            *signing.EdSigner
+	signer            signing.Signer
 	layerClock        layerClock
 	poetCfg           PoetConfig
 }
@@ -84,7 +84,7 @@ func NewNIPostBuilder(
 	poetDB poetDbAPI,
 	dataDir string,
 	lg log.Log,
-	signer *signing.EdSigner,
+	signer signing.Signer,
 	poetCfg PoetConfig,
 	layerClock layerClock,
 ) *NIPostBuilder {
@@ -167,7 +167,10 @@ func (nb *NIPostBuilder) BuildNIPost(ctx context.Context, challenge *types.NIPos
 			return nil, 0, fmt.Errorf("%w: poet round has already started at %s (now: %s)", ErrATXChallengeExpired, poetRoundStart, now)
 		}
 
-		signature := nb.signer.Sign(signing.POET, challengeHash.Bytes())
+		signature, err := nb.signer.SignContext(ctx, signing.POET, challengeHash.Bytes())
+		if err != nil {
+			return nil, 0, fmt.Errorf("sign poet challenge: %w", err)
+		}
 		prefix := bytes.Join([][]byte{nb.signer.Prefix(), {byte(signing.POET)}}, nil)
 		submitCtx, cancel := context.WithDeadline(ctx, poetRoundStart)
 		defer cancel()
@@ -0,0 +1,2224 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.31.0
+// 	protoc        v3.21.5
+// source: api/debug/v1/debug.proto
+
+package v1
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	emptypb "google.golang.org/protobuf/types/known/emptypb"
+	reflect "reflect"
+	sync "sync"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type Validity int32
+
+const (
+	Validity_VALIDITY_UNDECIDED Validity = 0
+	Validity_VALIDITY_VALID     Validity = 1
+	Validity_VALIDITY_INVALID   Validity = 2
+)
+
+// Enum value maps for Validity.
+var (
+	Validity_name = map[int32]string{
+		0: "VALIDITY_UNDECIDED",
+		1: "VALIDITY_VALID",
+		2: "VALIDITY_INVALID",
+	}
+	Validity_value = map[string]int32{
+		"VALIDITY_UNDECIDED": 0,
+		"VALIDITY_VALID":     1,
+		"VALIDITY_INVALID":   2,
+	}
+)
+
+func (x Validity) Enum() *Validity {
+	p := new(Validity)
+	*p = x
+	return p
+}
+
+func (x Validity) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (Validity) Descriptor() protoreflect.EnumDescriptor {
+	return file_api_debug_v1_debug_proto_enumTypes[0].Descriptor()
+}
+
+func (Validity) Type() protoreflect.EnumType {
+	return &file_api_debug_v1_debug_proto_enumTypes[0]
+}
+
+func (x Validity) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use Validity.Descriptor instead.
+func (Validity) EnumDescriptor() ([]byte, []int) {
+	return file_api_debug_v1_debug_proto_rawDescGZIP(), []int{0}
+}
+
+type ValiditySource int32
+
+const (
+	ValiditySource_VALIDITY_SOURCE_HARE        ValiditySource = 0
+	ValiditySource_VALIDITY_SOURCE_CERTIFICATE ValiditySource = 1
+	ValiditySource_VALIDITY_SOURCE_VERIFYING   ValiditySource = 2
+	ValiditySource_VALIDITY_SOURCE_HEALING     ValiditySource = 3
+)
+
+// Enum value maps for ValiditySource.
+var (
+	ValiditySource_name = map[int32]string{
+		0: "VALIDITY_SOURCE_HARE",
+		1: "VALIDITY_SOURCE_CERTIFICATE",
+		2: "VALIDITY_SOURCE_VERIFYING",
+		3: "VALIDITY_SOURCE_HEALING",
+	}
+	ValiditySource_value = map[string]int32{
+		"VALIDITY_SOURCE_HARE":        0,
+		"VALIDITY_SOURCE_CERTIFICATE": 1,
+		"VALIDITY_SOURCE_VERIFYING":   2,
+		"VALIDITY_SOURCE_HEALING":     3,
+	}
+)
+
+func (x ValiditySource) Enum() *ValiditySource {
+	p := new(ValiditySource)
+	*p = x
+	return p
+}
+
+func (x ValiditySource) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (ValiditySource) Descriptor() protoreflect.EnumDescriptor {
+	return file_api_debug_v1_debug_proto_enumTypes[1].Descriptor()
+}
+
+func (ValiditySource) Type() protoreflect.EnumType {
+	return &file_api_debug_v1_debug_proto_enumTypes[1]
+}
+
+func (x ValiditySource) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use ValiditySource.Descriptor instead.
+func (ValiditySource) EnumDescriptor() ([]byte, []int) {
+	return file_api_debug_v1_debug_proto_rawDescGZIP(), []int{1}
+}
+
+type OpinionReportRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	From uint32 `protobuf:"varint,1,opt,name=from,proto3" json:"from,omitempty"`
+	To   uint32 `protobuf:"varint,2,opt,name=to,proto3" json:"to,omitempty"`
+	Sign bool   `protobuf:"varint,3,opt,name=sign,proto3" json:"sign,omitempty"` // sign the report with the node identity
+}
+
+func (x *OpinionReportRequest) Reset() {
+	*x = OpinionReportRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_api_debug_v1_debug_proto_msgTypes[0]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *OpinionReportRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*OpinionReportRequest) ProtoMessage() {}
+
+func (x *OpinionReportRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_debug_v1_debug_proto_msgTypes[0]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use OpinionReportRequest.ProtoReflect.Descriptor instead.
+func (*OpinionReportRequest) Descriptor() ([]byte, []int) {
+	return file_api_debug_v1_debug_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *OpinionReportRequest) GetFrom() uint32 {
+	if x != nil {
+		return x.From
+	}
+	return 0
+}
+
+func (x *OpinionReportRequest) GetTo() uint32 {
+	if x != nil {
+		return x.To
+	}
+	return 0
+}
+
+func (x *OpinionReportRequest) GetSign() bool {
+	if x != nil {
+		return x.Sign
+	}
+	return false
+}
+
+// OpinionReportResponse is the local opinion for a range of layers.
+// The signature covers the json encoding of the report, see tortoise.OpinionReport.SignedBytes.
+type OpinionReportResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	From      uint32          `protobuf:"varint,1,opt,name=from,proto3" json:"from,omitempty"`
+	To        uint32          `protobuf:"varint,2,opt,name=to,proto3" json:"to,omitempty"`
+	Layers    []*LayerOpinion `protobuf:"bytes,3,rep,name=layers,proto3" json:"layers,omitempty"`
+	NodeId    []byte          `protobuf:"bytes,4,opt,name=node_id,json=nodeId,proto3" json:"node_id,omitempty"` // empty if the report is not signed
+	Signature []byte          `protobuf:"bytes,5,opt,name=signature,proto3" json:"signature,omitempty"`         // empty if the report is not signed
+}
+
+func (x *OpinionReportResponse) Reset() {
+	*x = OpinionReportResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_api_debug_v1_debug_proto_msgTypes[1]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *OpinionReportResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*OpinionReportResponse) ProtoMessage() {}
+
+func (x *OpinionReportResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_debug_v1_debug_proto_msgTypes[1]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use OpinionReportResponse.ProtoReflect.Descriptor instead.
+func (*OpinionReportResponse) Descriptor() ([]byte, []int) {
+	return file_api_debug_v1_debug_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *OpinionReportResponse) GetFrom() uint32 {
+	if x != nil {
+		return x.From
+	}
+	return 0
+}
+
+func (x *OpinionReportResponse) GetTo() uint32 {
+	if x != nil {
+		return x.To
+	}
+	return 0
+}
+
+func (x *OpinionReportResponse) GetLayers() []*LayerOpinion {
+	if x != nil {
+		return x.Layers
+	}
+	return nil
+}
+
+func (x *OpinionReportResponse) GetNodeId() []byte {
+	if x != nil {
+		return x.NodeId
+	}
+	return nil
+}
+
+func (x *OpinionReportResponse) GetSignature() []byte {
+	if x != nil {
+		return x.Signature
+	}
+	return nil
+}
+
+type LayerOpinion struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Layer     uint32          `protobuf:"varint,1,opt,name=layer,proto3" json:"layer,omitempty"`
+	Verified  bool            `protobuf:"varint,2,opt,name=verified,proto3" json:"verified,omitempty"`
+	Opinion   []byte          `protobuf:"bytes,3,opt,name=opinion,proto3" json:"opinion,omitempty"`
+	Threshold float64         `protobuf:"fixed64,4,opt,name=threshold,proto3" json:"threshold,omitempty"`
+	Blocks    []*BlockOpinion `protobuf:"bytes,5,rep,name=blocks,proto3" json:"blocks,omitempty"`
+}
+
+func (x *LayerOpinion) Reset() {
+	*x = LayerOpinion{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_api_debug_v1_debug_proto_msgTypes[2]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *LayerOpinion) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LayerOpinion) ProtoMessage() {}
+
+func (x *LayerOpinion) ProtoReflect() protoreflect.Message {
+	mi := &file_api_debug_v1_debug_proto_msgTypes[2]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use LayerOpinion.ProtoReflect.Descriptor instead.
+func (*LayerOpinion) Descriptor() ([]byte, []int) {
+	return file_api_debug_v1_debug_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *LayerOpinion) GetLayer() uint32 {
+	if x != nil {
+		return x.Layer
+	}
+	return 0
+}
+
+func (x *LayerOpinion) GetVerified() bool {
+	if x != nil {
+		return x.Verified
+	}
+	return false
+}
+
+func (x *LayerOpinion) GetOpinion() []byte {
+	if x != nil {
+		return x.Opinion
+	}
+	return nil
+}
+
+func (x *LayerOpinion) GetThreshold() float64 {
+	if x != nil {
+		return x.Threshold
+	}
+	return 0
+}
+
+func (x *LayerOpinion) GetBlocks() []*BlockOpinion {
+	if x != nil {
+		return x.Blocks
+	}
+	return nil
+}
+
+type BlockOpinion struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Id       []byte `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Height   uint64 `protobuf:"varint,2,opt,name=height,proto3" json:"height,omitempty"`
+	Validity string `protobuf:"bytes,3,opt,name=validity,proto3" json:"validity,omitempty"` // valid, invalid or undecided
+	Source   string `protobuf:"bytes,4,opt,name=source,proto3" json:"source,omitempty"`     // hare, verifying, healing, coin or recovered. empty if undecided
+	Hare     bool   `protobuf:"varint,5,opt,name=hare,proto3" json:"hare,omitempty"`
+	// margin is the weight of votes for the block minus the weight of votes against it.
+	Margin float64 `protobuf:"fixed64,6,opt,name=margin,proto3" json:"margin,omitempty"`
+	// relative is the margin divided by the global threshold of the layer.
+	Relative float64 `protobuf:"fixed64,7,opt,name=relative,proto3" json:"relative,omitempty"`
+}
+
+func (x *BlockOpinion) Reset() {
+	*x = BlockOpinion{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_api_debug_v1_debug_proto_msgTypes[3]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *BlockOpinion) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BlockOpinion) ProtoMessage() {}
+
+func (x *BlockOpinion) ProtoReflect() protoreflect.Message {
+	mi := &file_api_debug_v1_debug_proto_msgTypes[3]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BlockOpinion.ProtoReflect.Descriptor instead.
+func (*BlockOpinion) Descriptor() ([]byte, []int) {
+	return file_api_debug_v1_debug_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *BlockOpinion) GetId() []byte {
+	if x != nil {
+		return x.Id
+	}
+	return nil
+}
+
+func (x *BlockOpinion) GetHeight() uint64 {
+	if x != nil {
+		return x.Height
+	}
+	return 0
+}
+
+func (x *BlockOpinion) GetValidity() string {
+	if x != nil {
+		return x.Validity
+	}
+	return ""
+}
+
+func (x *BlockOpinion) GetSource() string {
+	if x != nil {
+		return x.Source
+	}
+	return ""
+}
+
+func (x *BlockOpinion) GetHare() bool {
+	if x != nil {
+		return x.Hare
+	}
+	return false
+}
+
+func (x *BlockOpinion) GetMargin() float64 {
+	if x != nil {
+		return x.Margin
+	}
+	return 0
+}
+
+func (x *BlockOpinion) GetRelative() float64 {
+	if x != nil {
+		return x.Relative
+	}
+	return 0
+}
+
+type DumpStateResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	State []byte `protobuf:"bytes,1,opt,name=state,proto3" json:"state,omitempty"`
+}
+
+func (x *DumpStateResponse) Reset() {
+	*x = DumpStateResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_api_debug_v1_debug_proto_msgTypes[4]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *DumpStateResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DumpStateResponse) ProtoMessage() {}
+
+func (x *DumpStateResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_debug_v1_debug_proto_msgTypes[4]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DumpStateResponse.ProtoReflect.Descriptor instead.
+func (*DumpStateResponse) Descriptor() ([]byte, []int) {
+	return file_api_debug_v1_debug_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *DumpStateResponse) GetState() []byte {
+	if x != nil {
+		return x.State
+	}
+	return nil
+}
+
+type EncodeVotesRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Layer uint32 `protobuf:"varint,1,opt,name=layer,proto3" json:"layer,omitempty"`
+}
+
+func (x *EncodeVotesRequest) Reset() {
+	*x = EncodeVotesRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_api_debug_v1_debug_proto_msgTypes[5]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *EncodeVotesRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*EncodeVotesRequest) ProtoMessage() {}
+
+func (x *EncodeVotesRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_debug_v1_debug_proto_msgTypes[5]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use EncodeVotesRequest.ProtoReflect.Descriptor instead.
+func (*EncodeVotesRequest) Descriptor() ([]byte, []int) {
+	return file_api_debug_v1_debug_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *EncodeVotesRequest) GetLayer() uint32 {
+	if x != nil {
+		return x.Layer
+	}
+	return 0
+}
+
+type EncodeVotesResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Opinion *Opinion    `protobuf:"bytes,1,opt,name=opinion,proto3" json:"opinion,omitempty"` // not set if votes can't be encoded
+	Trace   *VotesTrace `protobuf:"bytes,2,opt,name=trace,proto3" json:"trace,omitempty"`
+	Error   string      `protobuf:"bytes,3,opt,name=error,proto3" json:"error,omitempty"`
+}
+
+func (x *EncodeVotesResponse) Reset() {
+	*x = EncodeVotesResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_api_debug_v1_debug_proto_msgTypes[6]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *EncodeVotesResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*EncodeVotesResponse) ProtoMessage() {}
+
+func (x *EncodeVotesResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_debug_v1_debug_proto_msgTypes[6]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use EncodeVotesResponse.ProtoReflect.Descriptor instead.
+func (*EncodeVotesResponse) Descriptor() ([]byte, []int) {
+	return file_api_debug_v1_debug_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *EncodeVotesResponse) GetOpinion() *Opinion {
+	if x != nil {
+		return x.Opinion
+	}
+	return nil
+}
+
+func (x *EncodeVotesResponse) GetTrace() *VotesTrace {
+	if x != nil {
+		return x.Trace
+	}
+	return nil
+}
+
+func (x *EncodeVotesResponse) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+type Opinion struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Hash    []byte   `protobuf:"bytes,1,opt,name=hash,proto3" json:"hash,omitempty"`
+	Base    []byte   `protobuf:"bytes,2,opt,name=base,proto3" json:"base,omitempty"`
+	Support []*Vote  `protobuf:"bytes,3,rep,name=support,proto3" json:"support,omitempty"`
+	Against []*Vote  `protobuf:"bytes,4,rep,name=against,proto3" json:"against,omitempty"`
+	Abstain []uint32 `protobuf:"varint,5,rep,packed,name=abstain,proto3" json:"abstain,omitempty"`
+}
+
+func (x *Opinion) Reset() {
+	*x = Opinion{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_api_debug_v1_debug_proto_msgTypes[7]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Opinion) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Opinion) ProtoMessage() {}
+
+func (x *Opinion) ProtoReflect() protoreflect.Message {
+	mi := &file_api_debug_v1_debug_proto_msgTypes[7]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Opinion.ProtoReflect.Descriptor instead.
+func (*Opinion) Descriptor() ([]byte, []int) {
+	return file_api_debug_v1_debug_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *Opinion) GetHash() []byte {
+	if x != nil {
+		return x.Hash
+	}
+	return nil
+}
+
+func (x *Opinion) GetBase() []byte {
+	if x != nil {
+		return x.Base
+	}
+	return nil
+}
+
+func (x *Opinion) GetSupport() []*Vote {
+	if x != nil {
+		return x.Support
+	}
+	return nil
+}
+
+func (x *Opinion) GetAgainst() []*Vote {
+	if x != nil {
+		return x.Against
+	}
+	return nil
+}
+
+func (x *Opinion) GetAbstain() []uint32 {
+	if x != nil {
+		return x.Abstain
+	}
+	return nil
+}
+
+type Vote struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Id     []byte `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Layer  uint32 `protobuf:"varint,2,opt,name=layer,proto3" json:"layer,omitempty"`
+	Height uint64 `protobuf:"varint,3,opt,name=height,proto3" json:"height,omitempty"`
+}
+
+func (x *Vote) Reset() {
+	*x = Vote{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_api_debug_v1_debug_proto_msgTypes[8]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Vote) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Vote) ProtoMessage() {}
+
+func (x *Vote) ProtoReflect() protoreflect.Message {
+	mi := &file_api_debug_v1_debug_proto_msgTypes[8]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Vote.ProtoReflect.Descriptor instead.
+func (*Vote) Descriptor() ([]byte, []int) {
+	return file_api_debug_v1_debug_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *Vote) GetId() []byte {
+	if x != nil {
+		return x.Id
+	}
+	return nil
+}
+
+func (x *Vote) GetLayer() uint32 {
+	if x != nil {
+		return x.Layer
+	}
+	return 0
+}
+
+func (x *Vote) GetHeight() uint64 {
+	if x != nil {
+		return x.Height
+	}
+	return 0
+}
+
+// VotesTrace explains how votes were encoded.
+type VotesTrace struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Layer uint32 `protobuf:"varint,1,opt,name=layer,proto3" json:"layer,omitempty"`
+	// candidates for the base ballot in the order they were considered.
+	Candidates []*CandidateTrace `protobuf:"bytes,2,rep,name=candidates,proto3" json:"candidates,omitempty"`
+	// blocks with local opinion that were compared with the opinion of the last candidate.
+	Blocks []*BlockOpinionTrace `protobuf:"bytes,3,rep,name=blocks,proto3" json:"blocks,omitempty"`
+	// exceptions that were added on top of the opinion of the last candidate.
+	Exceptions []*ExceptionTrace `protobuf:"bytes,4,rep,name=exceptions,proto3" json:"exceptions,omitempty"`
+}
+
+func (x *VotesTrace) Reset() {
+	*x = VotesTrace{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_api_debug_v1_debug_proto_msgTypes[9]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *VotesTrace) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*VotesTrace) ProtoMessage() {}
+
+func (x *VotesTrace) ProtoReflect() protoreflect.Message {
+	mi := &file_api_debug_v1_debug_proto_msgTypes[9]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use VotesTrace.ProtoReflect.Descriptor instead.
+func (*VotesTrace) Descriptor() ([]byte, []int) {
+	return file_api_debug_v1_debug_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *VotesTrace) GetLayer() uint32 {
+	if x != nil {
+		return x.Layer
+	}
+	return 0
+}
+
+func (x *VotesTrace) GetCandidates() []*CandidateTrace {
+	if x != nil {
+		return x.Candidates
+	}
+	return nil
+}
+
+func (x *VotesTrace) GetBlocks() []*BlockOpinionTrace {
+	if x != nil {
+		return x.Blocks
+	}
+	return nil
+}
+
+func (x *VotesTrace) GetExceptions() []*ExceptionTrace {
+	if x != nil {
+		return x.Exceptions
+	}
+	return nil
+}
+
+type CandidateTrace struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Ballot    []byte `protobuf:"bytes,1,opt,name=ballot,proto3" json:"ballot,omitempty"`
+	Layer     uint32 `protobuf:"varint,2,opt,name=layer,proto3" json:"layer,omitempty"`
+	Malicious bool   `protobuf:"varint,3,opt,name=malicious,proto3" json:"malicious,omitempty"`
+	BadBeacon bool   `protobuf:"varint,4,opt,name=bad_beacon,json=badBeacon,proto3" json:"bad_beacon,omitempty"`
+	Selected  bool   `protobuf:"varint,5,opt,name=selected,proto3" json:"selected,omitempty"`
+	Verdict   string `protobuf:"bytes,6,opt,name=verdict,proto3" json:"verdict,omitempty"` // good, abandoned or bad
+	Reason    string `protobuf:"bytes,7,opt,name=reason,proto3" json:"reason,omitempty"`
+	Error     string `protobuf:"bytes,8,opt,name=error,proto3" json:"error,omitempty"`
+}
+
+func (x *CandidateTrace) Reset() {
+	*x = CandidateTrace{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_api_debug_v1_debug_proto_msgTypes[10]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *CandidateTrace) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CandidateTrace) ProtoMessage() {}
+
+func (x *CandidateTrace) ProtoReflect() protoreflect.Message {
+	mi := &file_api_debug_v1_debug_proto_msgTypes[10]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CandidateTrace.ProtoReflect.Descriptor instead.
+func (*CandidateTrace) Descriptor() ([]byte, []int) {
+	return file_api_debug_v1_debug_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *CandidateTrace) GetBallot() []byte {
+	if x != nil {
+		return x.Ballot
+	}
+	return nil
+}
+
+func (x *CandidateTrace) GetLayer() uint32 {
+	if x != nil {
+		return x.Layer
+	}
+	return 0
+}
+
+func (x *CandidateTrace) GetMalicious() bool {
+	if x != nil {
+		return x.Malicious
+	}
+	return false
+}
+
+func (x *CandidateTrace) GetBadBeacon() bool {
+	if x != nil {
+		return x.BadBeacon
+	}
+	return false
+}
+
+func (x *CandidateTrace) GetSelected() bool {
+	if x != nil {
+		return x.Selected
+	}
+	return false
+}
+
+func (x *CandidateTrace) GetVerdict() string {
+	if x != nil {
+		return x.Verdict
+	}
+	return ""
+}
+
+func (x *CandidateTrace) GetReason() string {
+	if x != nil {
+		return x.Reason
+	}
+	return ""
+}
+
+func (x *CandidateTrace) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+type BlockOpinionTrace struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Block    []byte `protobuf:"bytes,1,opt,name=block,proto3" json:"block,omitempty"`
+	Layer    uint32 `protobuf:"varint,2,opt,name=layer,proto3" json:"layer,omitempty"`
+	Height   uint64 `protobuf:"varint,3,opt,name=height,proto3" json:"height,omitempty"`
+	Data     bool   `protobuf:"varint,4,opt,name=data,proto3" json:"data,omitempty"`
+	Hare     string `protobuf:"bytes,5,opt,name=hare,proto3" json:"hare,omitempty"`
+	Validity string `protobuf:"bytes,6,opt,name=validity,proto3" json:"validity,omitempty"`
+	Margin   string `protobuf:"bytes,7,opt,name=margin,proto3" json:"margin,omitempty"`
+	Vote     string `protobuf:"bytes,8,opt,name=vote,proto3" json:"vote,omitempty"`
+	Reason   string `protobuf:"bytes,9,opt,name=reason,proto3" json:"reason,omitempty"`
+}
+
+func (x *BlockOpinionTrace) Reset() {
+	*x = BlockOpinionTrace{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_api_debug_v1_debug_proto_msgTypes[11]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *BlockOpinionTrace) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BlockOpinionTrace) ProtoMessage() {}
+
+func (x *BlockOpinionTrace) ProtoReflect() protoreflect.Message {
+	mi := &file_api_debug_v1_debug_proto_msgTypes[11]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BlockOpinionTrace.ProtoReflect.Descriptor instead.
+func (*BlockOpinionTrace) Descriptor() ([]byte, []int) {
+	return file_api_debug_v1_debug_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *BlockOpinionTrace) GetBlock() []byte {
+	if x != nil {
+		return x.Block
+	}
+	return nil
+}
+
+func (x *BlockOpinionTrace) GetLayer() uint32 {
+	if x != nil {
+		return x.Layer
+	}
+	return 0
+}
+
+func (x *BlockOpinionTrace) GetHeight() uint64 {
+	if x != nil {
+		return x.Height
+	}
+	return 0
+}
+
+func (x *BlockOpinionTrace) GetData() bool {
+	if x != nil {
+		return x.Data
+	}
+	return false
+}
+
+func (x *BlockOpinionTrace) GetHare() string {
+	if x != nil {
+		return x.Hare
+	}
+	return ""
+}
+
+func (x *BlockOpinionTrace) GetValidity() string {
+	if x != nil {
+		return x.Validity
+	}
+	return ""
+}
+
+func (x *BlockOpinionTrace) GetMargin() string {
+	if x != nil {
+		return x.Margin
+	}
+	return ""
+}
+
+func (x *BlockOpinionTrace) GetVote() string {
+	if x != nil {
+		return x.Vote
+	}
+	return ""
+}
+
+func (x *BlockOpinionTrace) GetReason() string {
+	if x != nil {
+		return x.Reason
+	}
+	return ""
+}
+
+type ExceptionTrace struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Layer    uint32 `protobuf:"varint,1,opt,name=layer,proto3" json:"layer,omitempty"`
+	Block    []byte `protobuf:"bytes,2,opt,name=block,proto3" json:"block,omitempty"` // empty for abstain votes
+	Vote     string `protobuf:"bytes,3,opt,name=vote,proto3" json:"vote,omitempty"`
+	BaseVote string `protobuf:"bytes,4,opt,name=base_vote,json=baseVote,proto3" json:"base_vote,omitempty"`
+	Reason   string `protobuf:"bytes,5,opt,name=reason,proto3" json:"reason,omitempty"`
+}
+
+func (x *ExceptionTrace) Reset() {
+	*x = ExceptionTrace{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_api_debug_v1_debug_proto_msgTypes[12]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ExceptionTrace) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ExceptionTrace) ProtoMessage() {}
+
+func (x *ExceptionTrace) ProtoReflect() protoreflect.Message {
+	mi := &file_api_debug_v1_debug_proto_msgTypes[12]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ExceptionTrace.ProtoReflect.Descriptor instead.
+func (*ExceptionTrace) Descriptor() ([]byte, []int) {
+	return file_api_debug_v1_debug_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *ExceptionTrace) GetLayer() uint32 {
+	if x != nil {
+		return x.Layer
+	}
+	return 0
+}
+
+func (x *ExceptionTrace) GetBlock() []byte {
+	if x != nil {
+		return x.Block
+	}
+	return nil
+}
+
+func (x *ExceptionTrace) GetVote() string {
+	if x != nil {
+		return x.Vote
+	}
+	return ""
+}
+
+func (x *ExceptionTrace) GetBaseVote() string {
+	if x != nil {
+		return x.BaseVote
+	}
+	return ""
+}
+
+func (x *ExceptionTrace) GetReason() string {
+	if x != nil {
+		return x.Reason
+	}
+	return ""
+}
+
+type HareResultRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Layer uint32 `protobuf:"varint,1,opt,name=layer,proto3" json:"layer,omitempty"`
+}
+
+func (x *HareResultRequest) Reset() {
+	*x = HareResultRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_api_debug_v1_debug_proto_msgTypes[13]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *HareResultRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*HareResultRequest) ProtoMessage() {}
+
+func (x *HareResultRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_debug_v1_debug_proto_msgTypes[13]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use HareResultRequest.ProtoReflect.Descriptor instead.
+func (*HareResultRequest) Descriptor() ([]byte, []int) {
+	return file_api_debug_v1_debug_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *HareResultRequest) GetLayer() uint32 {
+	if x != nil {
+		return x.Layer
+	}
+	return 0
+}
+
+type HareResultResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Layer     uint32   `protobuf:"varint,1,opt,name=layer,proto3" json:"layer,omitempty"`
+	Proposals [][]byte `protobuf:"bytes,2,rep,name=proposals,proto3" json:"proposals,omitempty"`
+}
+
+func (x *HareResultResponse) Reset() {
+	*x = HareResultResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_api_debug_v1_debug_proto_msgTypes[14]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *HareResultResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*HareResultResponse) ProtoMessage() {}
+
+func (x *HareResultResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_debug_v1_debug_proto_msgTypes[14]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use HareResultResponse.ProtoReflect.Descriptor instead.
+func (*HareResultResponse) Descriptor() ([]byte, []int) {
+	return file_api_debug_v1_debug_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *HareResultResponse) GetLayer() uint32 {
+	if x != nil {
+		return x.Layer
+	}
+	return 0
+}
+
+func (x *HareResultResponse) GetProposals() [][]byte {
+	if x != nil {
+		return x.Proposals
+	}
+	return nil
+}
+
+type ValidityHistoryRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Block []byte `protobuf:"bytes,1,opt,name=block,proto3" json:"block,omitempty"` // 20 bytes, or 32 bytes padded with zeroes
+}
+
+func (x *ValidityHistoryRequest) Reset() {
+	*x = ValidityHistoryRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_api_debug_v1_debug_proto_msgTypes[15]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ValidityHistoryRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ValidityHistoryRequest) ProtoMessage() {}
+
+func (x *ValidityHistoryRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_debug_v1_debug_proto_msgTypes[15]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ValidityHistoryRequest.ProtoReflect.Descriptor instead.
+func (*ValidityHistoryRequest) Descriptor() ([]byte, []int) {
+	return file_api_debug_v1_debug_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *ValidityHistoryRequest) GetBlock() []byte {
+	if x != nil {
+		return x.Block
+	}
+	return nil
+}
+
+type ValidityHistoryResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Block   []byte            `protobuf:"bytes,1,opt,name=block,proto3" json:"block,omitempty"`
+	Changes []*ValidityChange `protobuf:"bytes,2,rep,name=changes,proto3" json:"changes,omitempty"`
+}
+
+func (x *ValidityHistoryResponse) Reset() {
+	*x = ValidityHistoryResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_api_debug_v1_debug_proto_msgTypes[16]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ValidityHistoryResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ValidityHistoryResponse) ProtoMessage() {}
+
+func (x *ValidityHistoryResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_debug_v1_debug_proto_msgTypes[16]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ValidityHistoryResponse.ProtoReflect.Descriptor instead.
+func (*ValidityHistoryResponse) Descriptor() ([]byte, []int) {
+	return file_api_debug_v1_debug_proto_rawDescGZIP(), []int{16}
+}
+
+func (x *ValidityHistoryResponse) GetBlock() []byte {
+	if x != nil {
+		return x.Block
+	}
+	return nil
+}
+
+func (x *ValidityHistoryResponse) GetChanges() []*ValidityChange {
+	if x != nil {
+		return x.Changes
+	}
+	return nil
+}
+
+type ValidityChange struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Layer  uint32         `protobuf:"varint,1,opt,name=layer,proto3" json:"layer,omitempty"` // layer processed by the node when the change was decided
+	Old    Validity       `protobuf:"varint,2,opt,name=old,proto3,enum=spacemesh.debug.v1.Validity" json:"old,omitempty"`
+	New    Validity       `protobuf:"varint,3,opt,name=new,proto3,enum=spacemesh.debug.v1.Validity" json:"new,omitempty"`
+	Source ValiditySource `protobuf:"varint,4,opt,name=source,proto3,enum=spacemesh.debug.v1.ValiditySource" json:"source,omitempty"`
+}
+
+func (x *ValidityChange) Reset() {
+	*x = ValidityChange{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_api_debug_v1_debug_proto_msgTypes[17]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ValidityChange) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ValidityChange) ProtoMessage() {}
+
+func (x *ValidityChange) ProtoReflect() protoreflect.Message {
+	mi := &file_api_debug_v1_debug_proto_msgTypes[17]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ValidityChange.ProtoReflect.Descriptor instead.
+func (*ValidityChange) Descriptor() ([]byte, []int) {
+	return file_api_debug_v1_debug_proto_rawDescGZIP(), []int{17}
+}
+
+func (x *ValidityChange) GetLayer() uint32 {
+	if x != nil {
+		return x.Layer
+	}
+	return 0
+}
+
+func (x *ValidityChange) GetOld() Validity {
+	if x != nil {
+		return x.Old
+	}
+	return Validity_VALIDITY_UNDECIDED
+}
+
+func (x *ValidityChange) GetNew() Validity {
+	if x != nil {
+		return x.New
+	}
+	return Validity_VALIDITY_UNDECIDED
+}
+
+func (x *ValidityChange) GetSource() ValiditySource {
+	if x != nil {
+		return x.Source
+	}
+	return ValiditySource_VALIDITY_SOURCE_HARE
+}
+
+var File_api_debug_v1_debug_proto protoreflect.FileDescriptor
+
+var file_api_debug_v1_debug_proto_rawDesc = []byte{
+	0x0a, 0x18, 0x61, 0x70, 0x69, 0x2f, 0x64, 0x65, 0x62, 0x75, 0x67, 0x2f, 0x76, 0x31, 0x2f, 0x64,
+	0x65, 0x62, 0x75, 0x67, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x12, 0x12, 0x73, 0x70, 0x61, 0x63,
+	0x65, 0x6d, 0x65, 0x73, 0x68, 0x2e, 0x64, 0x65, 0x62, 0x75, 0x67, 0x2e, 0x76, 0x31, 0x1a, 0x1b,
+	0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2f,
+	0x65, 0x6d, 0x70, 0x74, 0x79, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x22, 0x4e, 0x0a, 0x14, 0x4f,
+	0x70, 0x69, 0x6e, 0x69, 0x6f, 0x6e, 0x52, 0x65, 0x70, 0x6f, 0x72, 0x74, 0x52, 0x65, 0x71, 0x75,
+	0x65, 0x73, 0x74, 0x12, 0x12, 0x0a, 0x04, 0x66, 0x72, 0x6f, 0x6d, 0x18, 0x01, 0x20, 0x01, 0x28,
+	0x0d, 0x52, 0x04, 0x66, 0x72, 0x6f, 0x6d, 0x12, 0x0e, 0x0a, 0x02, 0x74, 0x6f, 0x18, 0x02, 0x20,
+	0x01, 0x28, 0x0d, 0x52, 0x02, 0x74, 0x6f, 0x12, 0x12, 0x0a, 0x04, 0x73, 0x69, 0x67, 0x6e, 0x18,
+	0x03, 0x20, 0x01, 0x28, 0x08, 0x52, 0x04, 0x73, 0x69, 0x67, 0x6e, 0x22, 0xac, 0x01, 0x0a, 0x15,
+	0x4f, 0x70, 0x69, 0x6e, 0x69, 0x6f, 0x6e, 0x52, 0x65, 0x70, 0x6f, 0x72, 0x74, 0x52, 0x65, 0x73,
+	0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x12, 0x0a, 0x04, 0x66, 0x72, 0x6f, 0x6d, 0x18, 0x01, 0x20,
+	0x01, 0x28, 0x0d, 0x52, 0x04, 0x66, 0x72, 0x6f, 0x6d, 0x12, 0x0e, 0x0a, 0x02, 0x74, 0x6f, 0x18,
+	0x02, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x02, 0x74, 0x6f, 0x12, 0x38, 0x0a, 0x06, 0x6c, 0x61, 0x79,
+	0x65, 0x72, 0x73, 0x18, 0x03, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x20, 0x2e, 0x73, 0x70, 0x61, 0x63,
+	0x65, 0x6d, 0x65, 0x73, 0x68, 0x2e, 0x64, 0x65, 0x62, 0x75, 0x67, 0x2e, 0x76, 0x31, 0x2e, 0x4c,
+	0x61, 0x79, 0x65, 0x72, 0x4f, 0x70, 0x69, 0x6e, 0x69, 0x6f, 0x6e, 0x52, 0x06, 0x6c, 0x61, 0x79,
+	0x65, 0x72, 0x73, 0x12, 0x17, 0x0a, 0x07, 0x6e, 0x6f, 0x64, 0x65, 0x5f, 0x69, 0x64, 0x18, 0x04,
+	0x20, 0x01, 0x28, 0x0c, 0x52, 0x06, 0x6e, 0x6f, 0x64, 0x65, 0x49, 0x64, 0x12, 0x1c, 0x0a, 0x09,
+	0x73, 0x69, 0x67, 0x6e, 0x61, 0x74, 0x75, 0x72, 0x65, 0x18, 0x05, 0x20, 0x01, 0x28, 0x0c, 0x52,
+	0x09, 0x73, 0x69, 0x67, 0x6e, 0x61, 0x74, 0x75, 0x72, 0x65, 0x22, 0xb2, 0x01, 0x0a, 0x0c, 0x4c,
+	0x61, 0x79, 0x65, 0x72, 0x4f, 0x70, 0x69, 0x6e, 0x69, 0x6f, 0x6e, 0x12, 0x14, 0x0a, 0x05, 0x6c,
+	0x61, 0x79, 0x65, 0x72, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x05, 0x6c, 0x61, 0x79, 0x65,
+	0x72, 0x12, 0x1a, 0x0a, 0x08, 0x76, 0x65, 0x72, 0x69, 0x66, 0x69, 0x65, 0x64, 0x18, 0x02, 0x20,
+	0x01, 0x28, 0x08, 0x52, 0x08, 0x76, 0x65, 0x72, 0x69, 0x66, 0x69, 0x65, 0x64, 0x12, 0x18, 0x0a,
+	0x07, 0x6f, 0x70, 0x69, 0x6e, 0x69, 0x6f, 0x6e, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x07,
+	0x6f, 0x70, 0x69, 0x6e, 0x69, 0x6f, 0x6e, 0x12, 0x1c, 0x0a, 0x09, 0x74, 0x68, 0x72, 0x65, 0x73,
+	0x68, 0x6f, 0x6c, 0x64, 0x18, 0x04, 0x20, 0x01, 0x28, 0x01, 0x52, 0x09, 0x74, 0x68, 0x72, 0x65,
+	0x73, 0x68, 0x6f, 0x6c, 0x64, 0x12, 0x38, 0x0a, 0x06, 0x62, 0x6c, 0x6f, 0x63, 0x6b, 0x73, 0x18,
+	0x05, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x20, 0x2e, 0x73, 0x70, 0x61, 0x63, 0x65, 0x6d, 0x65, 0x73,
+	0x68, 0x2e, 0x64, 0x65, 0x62, 0x75, 0x67, 0x2e, 0x76, 0x31, 0x2e, 0x42, 0x6c, 0x6f, 0x63, 0x6b,
+	0x4f, 0x70, 0x69, 0x6e, 0x69, 0x6f, 0x6e, 0x52, 0x06, 0x62, 0x6c, 0x6f, 0x63, 0x6b, 0x73, 0x22,
+	0xb2, 0x01, 0x0a, 0x0c, 0x42, 0x6c, 0x6f, 0x63, 0x6b, 0x4f, 0x70, 0x69, 0x6e, 0x69, 0x6f, 0x6e,
+	0x12, 0x0e, 0x0a, 0x02, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x02, 0x69, 0x64,
+	0x12, 0x16, 0x0a, 0x06, 0x68, 0x65, 0x69, 0x67, 0x68, 0x74, 0x18, 0x02, 0x20, 0x01, 0x28, 0x04,
+	0x52, 0x06, 0x68, 0x65, 0x69, 0x67, 0x68, 0x74, 0x12, 0x1a, 0x0a, 0x08, 0x76, 0x61, 0x6c, 0x69,
+	0x64, 0x69, 0x74, 0x79, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x08, 0x76, 0x61, 0x6c, 0x69,
+	0x64, 0x69, 0x74, 0x79, 0x12, 0x16, 0x0a, 0x06, 0x73, 0x6f, 0x75, 0x72, 0x63, 0x65, 0x18, 0x04,
+	0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x73, 0x6f, 0x75, 0x72, 0x63, 0x65, 0x12, 0x12, 0x0a, 0x04,
+	0x68, 0x61, 0x72, 0x65, 0x18, 0x05, 0x20, 0x01, 0x28, 0x08, 0x52, 0x04, 0x68, 0x61, 0x72, 0x65,
+	0x12, 0x16, 0x0a, 0x06, 0x6d, 0x61, 0x72, 0x67, 0x69, 0x6e, 0x18, 0x06, 0x20, 0x01, 0x28, 0x01,
+	0x52, 0x06, 0x6d, 0x61, 0x72, 0x67, 0x69, 0x6e, 0x12, 0x1a, 0x0a, 0x08, 0x72, 0x65, 0x6c, 0x61,
+	0x74, 0x69, 0x76, 0x65, 0x18, 0x07, 0x20, 0x01, 0x28, 0x01, 0x52, 0x08, 0x72, 0x65, 0x6c, 0x61,
+	0x74, 0x69, 0x76, 0x65, 0x22, 0x29, 0x0a, 0x11, 0x44, 0x75, 0x6d, 0x70, 0x53, 0x74, 0x61, 0x74,
+	0x65, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x14, 0x0a, 0x05, 0x73, 0x74, 0x61,
+	0x74, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x05, 0x73, 0x74, 0x61, 0x74, 0x65, 0x22,
+	0x2a, 0x0a, 0x12, 0x45, 0x6e, 0x63, 0x6f, 0x64, 0x65, 0x56, 0x6f, 0x74, 0x65, 0x73, 0x52, 0x65,
+	0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x14, 0x0a, 0x05, 0x6c, 0x61, 0x79, 0x65, 0x72, 0x18, 0x01,
+	0x20, 0x01, 0x28, 0x0d, 0x52, 0x05, 0x6c, 0x61, 0x79, 0x65, 0x72, 0x22, 0x98, 0x01, 0x0a, 0x13,
+	0x45, 0x6e, 0x63, 0x6f, 0x64, 0x65, 0x56, 0x6f, 0x74, 0x65, 0x73, 0x52, 0x65, 0x73, 0x70, 0x6f,
+	0x6e, 0x73, 0x65, 0x12, 0x35, 0x0a, 0x07, 0x6f, 0x70, 0x69, 0x6e, 0x69, 0x6f, 0x6e, 0x18, 0x01,
+	0x20, 0x01, 0x28, 0x0b, 0x32, 0x1b, 0x2e, 0x73, 0x70, 0x61, 0x63, 0x65, 0x6d, 0x65, 0x73, 0x68,
+	0x2e, 0x64, 0x65, 0x62, 0x75, 0x67, 0x2e, 0x76, 0x31, 0x2e, 0x4f, 0x70, 0x69, 0x6e, 0x69, 0x6f,
+	0x6e, 0x52, 0x07, 0x6f, 0x70, 0x69, 0x6e, 0x69, 0x6f, 0x6e, 0x12, 0x34, 0x0a, 0x05, 0x74, 0x72,
+	0x61, 0x63, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x1e, 0x2e, 0x73, 0x70, 0x61, 0x63,
+	0x65, 0x6d, 0x65, 0x73, 0x68, 0x2e, 0x64, 0x65, 0x62, 0x75, 0x67, 0x2e, 0x76, 0x31, 0x2e, 0x56,
+	0x6f, 0x74, 0x65, 0x73, 0x54, 0x72, 0x61, 0x63, 0x65, 0x52, 0x05, 0x74, 0x72, 0x61, 0x63, 0x65,
+	0x12, 0x14, 0x0a, 0x05, 0x65, 0x72, 0x72, 0x6f, 0x72, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52,
+	0x05, 0x65, 0x72, 0x72, 0x6f, 0x72, 0x22, 0xb3, 0x01, 0x0a, 0x07, 0x4f, 0x70, 0x69, 0x6e, 0x69,
+	0x6f, 0x6e, 0x12, 0x12, 0x0a, 0x04, 0x68, 0x61, 0x73, 0x68, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0c,
+	0x52, 0x04, 0x68, 0x61, 0x73, 0x68, 0x12, 0x12, 0x0a, 0x04, 0x62, 0x61, 0x73, 0x65, 0x18, 0x02,
+	0x20, 0x01, 0x28, 0x0c, 0x52, 0x04, 0x62, 0x61, 0x73, 0x65, 0x12, 0x32, 0x0a, 0x07, 0x73, 0x75,
+	0x70, 0x70, 0x6f, 0x72, 0x74, 0x18, 0x03, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x18, 0x2e, 0x73, 0x70,
+	0x61, 0x63, 0x65, 0x6d, 0x65, 0x73, 0x68, 0x2e, 0x64, 0x65, 0x62, 0x75, 0x67, 0x2e, 0x76, 0x31,
+	0x2e, 0x56, 0x6f, 0x74, 0x65, 0x52, 0x07, 0x73, 0x75, 0x70, 0x70, 0x6f, 0x72, 0x74, 0x12, 0x32,
+	0x0a, 0x07, 0x61, 0x67, 0x61, 0x69, 0x6e, 0x73, 0x74, 0x18, 0x04, 0x20, 0x03, 0x28, 0x0b, 0x32,
+	0x18, 0x2e, 0x73, 0x70, 0x61, 0x63, 0x65, 0x6d, 0x65, 0x73, 0x68, 0x2e, 0x64, 0x65, 0x62, 0x75,
+	0x67, 0x2e, 0x76, 0x31, 0x2e, 0x56, 0x6f, 0x74, 0x65, 0x52, 0x07, 0x61, 0x67, 0x61, 0x69, 0x6e,
+	0x73, 0x74, 0x12, 0x18, 0x0a, 0x07, 0x61, 0x62, 0x73, 0x74, 0x61, 0x69, 0x6e, 0x18, 0x05, 0x20,
+	0x03, 0x28, 0x0d, 0x52, 0x07, 0x61, 0x62, 0x73, 0x74, 0x61, 0x69, 0x6e, 0x22, 0x44, 0x0a, 0x04,
+	0x56, 0x6f, 0x74, 0x65, 0x12, 0x0e, 0x0a, 0x02, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0c,
+	0x52, 0x02, 0x69, 0x64, 0x12, 0x14, 0x0a, 0x05, 0x6c, 0x61, 0x79, 0x65, 0x72, 0x18, 0x02, 0x20,
+	0x01, 0x28, 0x0d, 0x52, 0x05, 0x6c, 0x61, 0x79, 0x65, 0x72, 0x12, 0x16, 0x0a, 0x06, 0x68, 0x65,
+	0x69, 0x67, 0x68, 0x74, 0x18, 0x03, 0x20, 0x01, 0x28, 0x04, 0x52, 0x06, 0x68, 0x65, 0x69, 0x67,
+	0x68, 0x74, 0x22, 0xe9, 0x01, 0x0a, 0x0a, 0x56, 0x6f, 0x74, 0x65, 0x73, 0x54, 0x72, 0x61, 0x63,
+	0x65, 0x12, 0x14, 0x0a, 0x05, 0x6c, 0x61, 0x79, 0x65, 0x72, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0d,
+	0x52, 0x05, 0x6c, 0x61, 0x79, 0x65, 0x72, 0x12, 0x42, 0x0a, 0x0a, 0x63, 0x61, 0x6e, 0x64, 0x69,
+	0x64, 0x61, 0x74, 0x65, 0x73, 0x18, 0x02, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x22, 0x2e, 0x73, 0x70,
+	0x61, 0x63, 0x65, 0x6d, 0x65, 0x73, 0x68, 0x2e, 0x64, 0x65, 0x62, 0x75, 0x67, 0x2e, 0x76, 0x31,
+	0x2e, 0x43, 0x61, 0x6e, 0x64, 0x69, 0x64, 0x61, 0x74, 0x65, 0x54, 0x72, 0x61, 0x63, 0x65, 0x52,
+	0x0a, 0x63, 0x61, 0x6e, 0x64, 0x69, 0x64, 0x61, 0x74, 0x65, 0x73, 0x12, 0x3d, 0x0a, 0x06, 0x62,
+	0x6c, 0x6f, 0x63, 0x6b, 0x73, 0x18, 0x03, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x25, 0x2e, 0x73, 0x70,
+	0x61, 0x63, 0x65, 0x6d, 0x65, 0x73, 0x68, 0x2e, 0x64, 0x65, 0x62, 0x75, 0x67, 0x2e, 0x76, 0x31,
+	0x2e, 0x42, 0x6c, 0x6f, 0x63, 0x6b, 0x4f, 0x70, 0x69, 0x6e, 0x69, 0x6f, 0x6e, 0x54, 0x72, 0x61,
+	0x63, 0x65, 0x52, 0x06, 0x62, 0x6c, 0x6f, 0x63, 0x6b, 0x73, 0x12, 0x42, 0x0a, 0x0a, 0x65, 0x78,
+	0x63, 0x65, 0x70, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x18, 0x04, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x22,
+	0x2e, 0x73, 0x70, 0x61, 0x63, 0x65, 0x6d, 0x65, 0x73, 0x68, 0x2e, 0x64, 0x65, 0x62, 0x75, 0x67,
+	0x2e, 0x76, 0x31, 0x2e, 0x45, 0x78, 0x63, 0x65, 0x70, 0x74, 0x69, 0x6f, 0x6e, 0x54, 0x72, 0x61,
+	0x63, 0x65, 0x52, 0x0a, 0x65, 0x78, 0x63, 0x65, 0x70, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x22, 0xdf,
+	0x01, 0x0a, 0x0e, 0x43, 0x61, 0x6e, 0x64, 0x69, 0x64, 0x61, 0x74, 0x65, 0x54, 0x72, 0x61, 0x63,
+	0x65, 0x12, 0x16, 0x0a, 0x06, 0x62, 0x61, 0x6c, 0x6c, 0x6f, 0x74, 0x18, 0x01, 0x20, 0x01, 0x28,
+	0x0c, 0x52, 0x06, 0x62, 0x61, 0x6c, 0x6c, 0x6f, 0x74, 0x12, 0x14, 0x0a, 0x05, 0x6c, 0x61, 0x79,
+	0x65, 0x72, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x05, 0x6c, 0x61, 0x79, 0x65, 0x72, 0x12,
+	0x1c, 0x0a, 0x09, 0x6d, 0x61, 0x6c, 0x69, 0x63, 0x69, 0x6f, 0x75, 0x73, 0x18, 0x03, 0x20, 0x01,
+	0x28, 0x08, 0x52, 0x09, 0x6d, 0x61, 0x6c, 0x69, 0x63, 0x69, 0x6f, 0x75, 0x73, 0x12, 0x1d, 0x0a,
+	0x0a, 0x62, 0x61, 0x64, 0x5f, 0x62, 0x65, 0x61, 0x63, 0x6f, 0x6e, 0x18, 0x04, 0x20, 0x01, 0x28,
+	0x08, 0x52, 0x09, 0x62, 0x61, 0x64, 0x42, 0x65, 0x61, 0x63, 0x6f, 0x6e, 0x12, 0x1a, 0x0a, 0x08,
+	0x73, 0x65, 0x6c, 0x65, 0x63, 0x74, 0x65, 0x64, 0x18, 0x05, 0x20, 0x01, 0x28, 0x08, 0x52, 0x08,
+	0x73, 0x65, 0x6c, 0x65, 0x63, 0x74, 0x65, 0x64, 0x12, 0x18, 0x0a, 0x07, 0x76, 0x65, 0x72, 0x64,
+	0x69, 0x63, 0x74, 0x18, 0x06, 0x20, 0x01, 0x28, 0x09, 0x52, 0x07, 0x76, 0x65, 0x72, 0x64, 0x69,
+	0x63, 0x74, 0x12, 0x16, 0x0a, 0x06, 0x72, 0x65, 0x61, 0x73, 0x6f, 0x6e, 0x18, 0x07, 0x20, 0x01,
+	0x28, 0x09, 0x52, 0x06, 0x72, 0x65, 0x61, 0x73, 0x6f, 0x6e, 0x12, 0x14, 0x0a, 0x05, 0x65, 0x72,
+	0x72, 0x6f, 0x72, 0x18, 0x08, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x65, 0x72, 0x72, 0x6f, 0x72,
+	0x22, 0xdf, 0x01, 0x0a, 0x11, 0x42, 0x6c, 0x6f, 0x63, 0x6b, 0x4f, 0x70, 0x69, 0x6e, 0x69, 0x6f,
+	0x6e, 0x54, 0x72, 0x61, 0x63, 0x65, 0x12, 0x14, 0x0a, 0x05, 0x62, 0x6c, 0x6f, 0x63, 0x6b, 0x18,
+	0x01, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x05, 0x62, 0x6c, 0x6f, 0x63, 0x6b, 0x12, 0x14, 0x0a, 0x05,
+	0x6c, 0x61, 0x79, 0x65, 0x72, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x05, 0x6c, 0x61, 0x79,
+	0x65, 0x72, 0x12, 0x16, 0x0a, 0x06, 0x68, 0x65, 0x69, 0x67, 0x68, 0x74, 0x18, 0x03, 0x20, 0x01,
+	0x28, 0x04, 0x52, 0x06, 0x68, 0x65, 0x69, 0x67, 0x68, 0x74, 0x12, 0x12, 0x0a, 0x04, 0x64, 0x61,
+	0x74, 0x61, 0x18, 0x04, 0x20, 0x01, 0x28, 0x08, 0x52, 0x04, 0x64, 0x61, 0x74, 0x61, 0x12, 0x12,
+	0x0a, 0x04, 0x68, 0x61, 0x72, 0x65, 0x18, 0x05, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x68, 0x61,
+	0x72, 0x65, 0x12, 0x1a, 0x0a, 0x08, 0x76, 0x61, 0x6c, 0x69, 0x64, 0x69, 0x74, 0x79, 0x18, 0x06,
+	0x20, 0x01, 0x28, 0x09, 0x52, 0x08, 0x76, 0x61, 0x6c, 0x69, 0x64, 0x69, 0x74, 0x79, 0x12, 0x16,
+	0x0a, 0x06, 0x6d, 0x61, 0x72, 0x67, 0x69, 0x6e, 0x18, 0x07, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06,
+	0x6d, 0x61, 0x72, 0x67, 0x69, 0x6e, 0x12, 0x12, 0x0a, 0x04, 0x76, 0x6f, 0x74, 0x65, 0x18, 0x08,
+	0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x76, 0x6f, 0x74, 0x65, 0x12, 0x16, 0x0a, 0x06, 0x72, 0x65,
+	0x61, 0x73, 0x6f, 0x6e, 0x18, 0x09, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x72, 0x65, 0x61, 0x73,
+	0x6f, 0x6e, 0x22, 0x85, 0x01, 0x0a, 0x0e, 0x45, 0x78, 0x63, 0x65, 0x70, 0x74, 0x69, 0x6f, 0x6e,
+	0x54, 0x72, 0x61, 0x63, 0x65, 0x12, 0x14, 0x0a, 0x05, 0x6c, 0x61, 0x79, 0x65, 0x72, 0x18, 0x01,
+	0x20, 0x01, 0x28, 0x0d, 0x52, 0x05, 0x6c, 0x61, 0x79, 0x65, 0x72, 0x12, 0x14, 0x0a, 0x05, 0x62,
+	0x6c, 0x6f, 0x63, 0x6b, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x05, 0x62, 0x6c, 0x6f, 0x63,
+	0x6b, 0x12, 0x12, 0x0a, 0x04, 0x76, 0x6f, 0x74, 0x65, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52,
+	0x04, 0x76, 0x6f, 0x74, 0x65, 0x12, 0x1b, 0x0a, 0x09, 0x62, 0x61, 0x73, 0x65, 0x5f, 0x76, 0x6f,
+	0x74, 0x65, 0x18, 0x04, 0x20, 0x01, 0x28, 0x09, 0x52, 0x08, 0x62, 0x61, 0x73, 0x65, 0x56, 0x6f,
+	0x74, 0x65, 0x12, 0x16, 0x0a, 0x06, 0x72, 0x65, 0x61, 0x73, 0x6f, 0x6e, 0x18, 0x05, 0x20, 0x01,
+	0x28, 0x09, 0x52, 0x06, 0x72, 0x65, 0x61, 0x73, 0x6f, 0x6e, 0x22, 0x29, 0x0a, 0x11, 0x48, 0x61,
+	0x72, 0x65, 0x52, 0x65, 0x73, 0x75, 0x6c, 0x74, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12,
+	0x14, 0x0a, 0x05, 0x6c, 0x61, 0x79, 0x65, 0x72, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x05,
+	0x6c, 0x61, 0x79, 0x65, 0x72, 0x22, 0x48, 0x0a, 0x12, 0x48, 0x61, 0x72, 0x65, 0x52, 0x65, 0x73,
+	0x75, 0x6c, 0x74, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x14, 0x0a, 0x05, 0x6c,
+	0x61, 0x79, 0x65, 0x72, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x05, 0x6c, 0x61, 0x79, 0x65,
+	0x72, 0x12, 0x1c, 0x0a, 0x09, 0x70, 0x72, 0x6f, 0x70, 0x6f, 0x73, 0x61, 0x6c, 0x73, 0x18, 0x02,
+	0x20, 0x03, 0x28, 0x0c, 0x52, 0x09, 0x70, 0x72, 0x6f, 0x70, 0x6f, 0x73, 0x61, 0x6c, 0x73, 0x22,
+	0x2e, 0x0a, 0x16, 0x56, 0x61, 0x6c, 0x69, 0x64, 0x69, 0x74, 0x79, 0x48, 0x69, 0x73, 0x74, 0x6f,
+	0x72, 0x79, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x14, 0x0a, 0x05, 0x62, 0x6c, 0x6f,
+	0x63, 0x6b, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x05, 0x62, 0x6c, 0x6f, 0x63, 0x6b, 0x22,
+	0x6d, 0x0a, 0x17, 0x56, 0x61, 0x6c, 0x69, 0x64, 0x69, 0x74, 0x79, 0x48, 0x69, 0x73, 0x74, 0x6f,
+	0x72, 0x79, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x14, 0x0a, 0x05, 0x62, 0x6c,
+	0x6f, 0x63, 0x6b, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x05, 0x62, 0x6c, 0x6f, 0x63, 0x6b,
+	0x12, 0x3c, 0x0a, 0x07, 0x63, 0x68, 0x61, 0x6e, 0x67, 0x65, 0x73, 0x18, 0x02, 0x20, 0x03, 0x28,
+	0x0b, 0x32, 0x22, 0x2e, 0x73, 0x70, 0x61, 0x63, 0x65, 0x6d, 0x65, 0x73, 0x68, 0x2e, 0x64, 0x65,
+	0x62, 0x75, 0x67, 0x2e, 0x76, 0x31, 0x2e, 0x56, 0x61, 0x6c, 0x69, 0x64, 0x69, 0x74, 0x79, 0x43,
+	0x68, 0x61, 0x6e, 0x67, 0x65, 0x52, 0x07, 0x63, 0x68, 0x61, 0x6e, 0x67, 0x65, 0x73, 0x22, 0xc2,
+	0x01, 0x0a, 0x0e, 0x56, 0x61, 0x6c, 0x69, 0x64, 0x69, 0x74, 0x79, 0x43, 0x68, 0x61, 0x6e, 0x67,
+	0x65, 0x12, 0x14, 0x0a, 0x05, 0x6c, 0x61, 0x79, 0x65, 0x72, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0d,
+	0x52, 0x05, 0x6c, 0x61, 0x79, 0x65, 0x72, 0x12, 0x2e, 0x0a, 0x03, 0x6f, 0x6c, 0x64, 0x18, 0x02,
+	0x20, 0x01, 0x28, 0x0e, 0x32, 0x1c, 0x2e, 0x73, 0x70, 0x61, 0x63, 0x65, 0x6d, 0x65, 0x73, 0x68,
+	0x2e, 0x64, 0x65, 0x62, 0x75, 0x67, 0x2e, 0x76, 0x31, 0x2e, 0x56, 0x61, 0x6c, 0x69, 0x64, 0x69,
+	0x74, 0x79, 0x52, 0x03, 0x6f, 0x6c, 0x64, 0x12, 0x2e, 0x0a, 0x03, 0x6e, 0x65, 0x77, 0x18, 0x03,
+	0x20, 0x01, 0x28, 0x0e, 0x32, 0x1c, 0x2e, 0x73, 0x70, 0x61, 0x63, 0x65, 0x6d, 0x65, 0x73, 0x68,
+	0x2e, 0x64, 0x65, 0x62, 0x75, 0x67, 0x2e, 0x76, 0x31, 0x2e, 0x56, 0x61, 0x6c, 0x69, 0x64, 0x69,
+	0x74, 0x79, 0x52, 0x03, 0x6e, 0x65, 0x77, 0x12, 0x3a, 0x0a, 0x06, 0x73, 0x6f, 0x75, 0x72, 0x63,
+	0x65, 0x18, 0x04, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x22, 0x2e, 0x73, 0x70, 0x61, 0x63, 0x65, 0x6d,
+	0x65, 0x73, 0x68, 0x2e, 0x64, 0x65, 0x62, 0x75, 0x67, 0x2e, 0x76, 0x31, 0x2e, 0x56, 0x61, 0x6c,
+	0x69, 0x64, 0x69, 0x74, 0x79, 0x53, 0x6f, 0x75, 0x72, 0x63, 0x65, 0x52, 0x06, 0x73, 0x6f, 0x75,
+	0x72, 0x63, 0x65, 0x2a, 0x4c, 0x0a, 0x08, 0x56, 0x61, 0x6c, 0x69, 0x64, 0x69, 0x74, 0x79, 0x12,
+	0x16, 0x0a, 0x12, 0x56, 0x41, 0x4c, 0x49, 0x44, 0x49, 0x54, 0x59, 0x5f, 0x55, 0x4e, 0x44, 0x45,
+	0x43, 0x49, 0x44, 0x45, 0x44, 0x10, 0x00, 0x12, 0x12, 0x0a, 0x0e, 0x56, 0x41, 0x4c, 0x49, 0x44,
+	0x49, 0x54, 0x59, 0x5f, 0x56, 0x41, 0x4c, 0x49, 0x44, 0x10, 0x01, 0x12, 0x14, 0x0a, 0x10, 0x56,
+	0x41, 0x4c, 0x49, 0x44, 0x49, 0x54, 0x59, 0x5f, 0x49, 0x4e, 0x56, 0x41, 0x4c, 0x49, 0x44, 0x10,
+	0x02, 0x2a, 0x87, 0x01, 0x0a, 0x0e, 0x56, 0x61, 0x6c, 0x69, 0x64, 0x69, 0x74, 0x79, 0x53, 0x6f,
+	0x75, 0x72, 0x63, 0x65, 0x12, 0x18, 0x0a, 0x14, 0x56, 0x41, 0x4c, 0x49, 0x44, 0x49, 0x54, 0x59,
+	0x5f, 0x53, 0x4f, 0x55, 0x52, 0x43, 0x45, 0x5f, 0x48, 0x41, 0x52, 0x45, 0x10, 0x00, 0x12, 0x1f,
+	0x0a, 0x1b, 0x56, 0x41, 0x4c, 0x49, 0x44, 0x49, 0x54, 0x59, 0x5f, 0x53, 0x4f, 0x55, 0x52, 0x43,
+	0x45, 0x5f, 0x43, 0x45, 0x52, 0x54, 0x49, 0x46, 0x49, 0x43, 0x41, 0x54, 0x45, 0x10, 0x01, 0x12,
+	0x1d, 0x0a, 0x19, 0x56, 0x41, 0x4c, 0x49, 0x44, 0x49, 0x54, 0x59, 0x5f, 0x53, 0x4f, 0x55, 0x52,
+	0x43, 0x45, 0x5f, 0x56, 0x45, 0x52, 0x49, 0x46, 0x59, 0x49, 0x4e, 0x47, 0x10, 0x02, 0x12, 0x1b,
+	0x0a, 0x17, 0x56, 0x41, 0x4c, 0x49, 0x44, 0x49, 0x54, 0x59, 0x5f, 0x53, 0x4f, 0x55, 0x52, 0x43,
+	0x45, 0x5f, 0x48, 0x45, 0x41, 0x4c, 0x49, 0x4e, 0x47, 0x10, 0x03, 0x32, 0x9c, 0x02, 0x0a, 0x08,
+	0x54, 0x6f, 0x72, 0x74, 0x6f, 0x69, 0x73, 0x65, 0x12, 0x64, 0x0a, 0x0d, 0x4f, 0x70, 0x69, 0x6e,
+	0x69, 0x6f, 0x6e, 0x52, 0x65, 0x70, 0x6f, 0x72, 0x74, 0x12, 0x28, 0x2e, 0x73, 0x70, 0x61, 0x63,
+	0x65, 0x6d, 0x65, 0x73, 0x68, 0x2e, 0x64, 0x65, 0x62, 0x75, 0x67, 0x2e, 0x76, 0x31, 0x2e, 0x4f,
+	0x70, 0x69, 0x6e, 0x69, 0x6f, 0x6e, 0x52, 0x65, 0x70, 0x6f, 0x72, 0x74, 0x52, 0x65, 0x71, 0x75,
+	0x65, 0x73, 0x74, 0x1a, 0x29, 0x2e, 0x73, 0x70, 0x61, 0x63, 0x65, 0x6d, 0x65, 0x73, 0x68, 0x2e,
+	0x64, 0x65, 0x62, 0x75, 0x67, 0x2e, 0x76, 0x31, 0x2e, 0x4f, 0x70, 0x69, 0x6e, 0x69, 0x6f, 0x6e,
+	0x52, 0x65, 0x70, 0x6f, 0x72, 0x74, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x4a,
+	0x0a, 0x09, 0x44, 0x75, 0x6d, 0x70, 0x53, 0x74, 0x61, 0x74, 0x65, 0x12, 0x16, 0x2e, 0x67, 0x6f,
+	0x6f, 0x67, 0x6c, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e, 0x45, 0x6d,
+	0x70, 0x74, 0x79, 0x1a, 0x25, 0x2e, 0x73, 0x70, 0x61, 0x63, 0x65, 0x6d, 0x65, 0x73, 0x68, 0x2e,
+	0x64, 0x65, 0x62, 0x75, 0x67, 0x2e, 0x76, 0x31, 0x2e, 0x44, 0x75, 0x6d, 0x70, 0x53, 0x74, 0x61,
+	0x74, 0x65, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x5e, 0x0a, 0x0b, 0x45, 0x6e,
+	0x63, 0x6f, 0x64, 0x65, 0x56, 0x6f, 0x74, 0x65, 0x73, 0x12, 0x26, 0x2e, 0x73, 0x70, 0x61, 0x63,
+	0x65, 0x6d, 0x65, 0x73, 0x68, 0x2e, 0x64, 0x65, 0x62, 0x75, 0x67, 0x2e, 0x76, 0x31, 0x2e, 0x45,
+	0x6e, 0x63, 0x6f, 0x64, 0x65, 0x56, 0x6f, 0x74, 0x65, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73,
+	0x74, 0x1a, 0x27, 0x2e, 0x73, 0x70, 0x61, 0x63, 0x65, 0x6d, 0x65, 0x73, 0x68, 0x2e, 0x64, 0x65,
+	0x62, 0x75, 0x67, 0x2e, 0x76, 0x31, 0x2e, 0x45, 0x6e, 0x63, 0x6f, 0x64, 0x65, 0x56, 0x6f, 0x74,
+	0x65, 0x73, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x32, 0x5f, 0x0a, 0x04, 0x48, 0x61,
+	0x72, 0x65, 0x12, 0x57, 0x0a, 0x06, 0x52, 0x65, 0x73, 0x75, 0x6c, 0x74, 0x12, 0x25, 0x2e, 0x73,
+	0x70, 0x61, 0x63, 0x65, 0x6d, 0x65, 0x73, 0x68, 0x2e, 0x64, 0x65, 0x62, 0x75, 0x67, 0x2e, 0x76,
+	0x31, 0x2e, 0x48, 0x61, 0x72, 0x65, 0x52, 0x65, 0x73, 0x75, 0x6c, 0x74, 0x52, 0x65, 0x71, 0x75,
+	0x65, 0x73, 0x74, 0x1a, 0x26, 0x2e, 0x73, 0x70, 0x61, 0x63, 0x65, 0x6d, 0x65, 0x73, 0x68, 0x2e,
+	0x64, 0x65, 0x62, 0x75, 0x67, 0x2e, 0x76, 0x31, 0x2e, 0x48, 0x61, 0x72, 0x65, 0x52, 0x65, 0x73,
+	0x75, 0x6c, 0x74, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x32, 0x72, 0x0a, 0x04, 0x4d,
+	0x65, 0x73, 0x68, 0x12, 0x6a, 0x0a, 0x0f, 0x56, 0x61, 0x6c, 0x69, 0x64, 0x69, 0x74, 0x79, 0x48,
+	0x69, 0x73, 0x74, 0x6f, 0x72, 0x79, 0x12, 0x2a, 0x2e, 0x73, 0x70, 0x61, 0x63, 0x65, 0x6d, 0x65,
+	0x73, 0x68, 0x2e, 0x64, 0x65, 0x62, 0x75, 0x67, 0x2e, 0x76, 0x31, 0x2e, 0x56, 0x61, 0x6c, 0x69,
+	0x64, 0x69, 0x74, 0x79, 0x48, 0x69, 0x73, 0x74, 0x6f, 0x72, 0x79, 0x52, 0x65, 0x71, 0x75, 0x65,
+	0x73, 0x74, 0x1a, 0x2b, 0x2e, 0x73, 0x70, 0x61, 0x63, 0x65, 0x6d, 0x65, 0x73, 0x68, 0x2e, 0x64,
+	0x65, 0x62, 0x75, 0x67, 0x2e, 0x76, 0x31, 0x2e, 0x56, 0x61, 0x6c, 0x69, 0x64, 0x69, 0x74, 0x79,
+	0x48, 0x69, 0x73, 0x74, 0x6f, 0x72, 0x79, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x42,
+	0x32, 0x5a, 0x30, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x73, 0x70,
+	0x61, 0x63, 0x65, 0x6d, 0x65, 0x73, 0x68, 0x6f, 0x73, 0x2f, 0x67, 0x6f, 0x2d, 0x73, 0x70, 0x61,
+	0x63, 0x65, 0x6d, 0x65, 0x73, 0x68, 0x2f, 0x61, 0x70, 0x69, 0x2f, 0x64, 0x65, 0x62, 0x75, 0x67,
+	0x2f, 0x76, 0x31, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+}
+
+var (
+	file_api_debug_v1_debug_proto_rawDescOnce sync.Once
+	file_api_debug_v1_debug_proto_rawDescData = file_api_debug_v1_debug_proto_rawDesc
+)
+
+func file_api_debug_v1_debug_proto_rawDescGZIP() []byte {
+	file_api_debug_v1_debug_proto_rawDescOnce.Do(func() {
+		file_api_debug_v1_debug_proto_rawDescData = protoimpl.X.CompressGZIP(file_api_debug_v1_debug_proto_rawDescData)
+	})
+	return file_api_debug_v1_debug_proto_rawDescData
+}
+
+var file_api_debug_v1_debug_proto_enumTypes = make([]protoimpl.EnumInfo, 2)
+var file_api_debug_v1_debug_proto_msgTypes = make([]protoimpl.MessageInfo, 18)
+var file_api_debug_v1_debug_proto_goTypes = []interface{}{
+	(Validity)(0),                   // 0: spacemesh.debug.v1.Validity
+	(ValiditySource)(0),             // 1: spacemesh.debug.v1.ValiditySource
+	(*OpinionReportRequest)(nil),    // 2: spacemesh.debug.v1.OpinionReportRequest
+	(*OpinionReportResponse)(nil),   // 3: spacemesh.debug.v1.OpinionReportResponse
+	(*LayerOpinion)(nil),            // 4: spacemesh.debug.v1.LayerOpinion
+	(*BlockOpinion)(nil),            // 5: spacemesh.debug.v1.BlockOpinion
+	(*DumpStateResponse)(nil),       // 6: spacemesh.debug.v1.DumpStateResponse
+	(*EncodeVotesRequest)(nil),      // 7: spacemesh.debug.v1.EncodeVotesRequest
+	(*EncodeVotesResponse)(nil),     // 8: spacemesh.debug.v1.EncodeVotesResponse
+	(*Opinion)(nil),                 // 9: spacemesh.debug.v1.Opinion
+	(*Vote)(nil),                    // 10: spacemesh.debug.v1.Vote
+	(*VotesTrace)(nil),              // 11: spacemesh.debug.v1.VotesTrace
+	(*CandidateTrace)(nil),          // 12: spacemesh.debug.v1.CandidateTrace
+	(*BlockOpinionTrace)(nil),       // 13: spacemesh.debug.v1.BlockOpinionTrace
+	(*ExceptionTrace)(nil),          // 14: spacemesh.debug.v1.ExceptionTrace
+	(*HareResultRequest)(nil),       // 15: spacemesh.debug.v1.HareResultRequest
+	(*HareResultResponse)(nil),      // 16: spacemesh.debug.v1.HareResultResponse
+	(*ValidityHistoryRequest)(nil),  // 17: spacemesh.debug.v1.ValidityHistoryRequest
+	(*ValidityHistoryResponse)(nil), // 18: spacemesh.debug.v1.ValidityHistoryResponse
+	(*ValidityChange)(nil),          // 19: spacemesh.debug.v1.ValidityChange
+	(*emptypb.Empty)(nil),           // 20: google.protobuf.Empty
+}
+var file_api_debug_v1_debug_proto_depIdxs = []int32{
+	4,  // 0: spacemesh.debug.v1.OpinionReportResponse.layers:type_name -> spacemesh.debug.v1.LayerOpinion
+	5,  // 1: spacemesh.debug.v1.LayerOpinion.blocks:type_name -> spacemesh.debug.v1.BlockOpinion
+	9,  // 2: spacemesh.debug.v1.EncodeVotesResponse.opinion:type_name -> spacemesh.debug.v1.Opinion
+	11, // 3: spacemesh.debug.v1.EncodeVotesResponse.trace:type_name -> spacemesh.debug.v1.VotesTrace
+	10, // 4: spacemesh.debug.v1.Opinion.support:type_name -> spacemesh.debug.v1.Vote
+	10, // 5: spacemesh.debug.v1.Opinion.against:type_name -> spacemesh.debug.v1.Vote
+	12, // 6: spacemesh.debug.v1.VotesTrace.candidates:type_name -> spacemesh.debug.v1.CandidateTrace
+	13, // 7: spacemesh.debug.v1.VotesTrace.blocks:type_name -> spacemesh.debug.v1.BlockOpinionTrace
+	14, // 8: spacemesh.debug.v1.VotesTrace.exceptions:type_name -> spacemesh.debug.v1.ExceptionTrace
+	19, // 9: spacemesh.debug.v1.ValidityHistoryResponse.changes:type_name -> spacemesh.debug.v1.ValidityChange
+	0,  // 10: spacemesh.debug.v1.ValidityChange.old:type_name -> spacemesh.debug.v1.Validity
+	0,  // 11: spacemesh.debug.v1.ValidityChange.new:type_name -> spacemesh.debug.v1.Validity
+	1,  // 12: spacemesh.debug.v1.ValidityChange.source:type_name -> spacemesh.debug.v1.ValiditySource
+	2,  // 13: spacemesh.debug.v1.Tortoise.OpinionReport:input_type -> spacemesh.debug.v1.OpinionReportRequest
+	20, // 14: spacemesh.debug.v1.Tortoise.DumpState:input_type -> google.protobuf.Empty
+	7,  // 15: spacemesh.debug.v1.Tortoise.EncodeVotes:input_type -> spacemesh.debug.v1.EncodeVotesRequest
+	15, // 16: spacemesh.debug.v1.Hare.Result:input_type -> spacemesh.debug.v1.HareResultRequest
+	17, // 17: spacemesh.debug.v1.Mesh.ValidityHistory:input_type -> spacemesh.debug.v1.ValidityHistoryRequest
+	3,  // 18: spacemesh.debug.v1.Tortoise.OpinionReport:output_type -> spacemesh.debug.v1.OpinionReportResponse
+	6,  // 19: spacemesh.debug.v1.Tortoise.DumpState:output_type -> spacemesh.debug.v1.DumpStateResponse
+	8,  // 20: spacemesh.debug.v1.Tortoise.EncodeVotes:output_type -> spacemesh.debug.v1.EncodeVotesResponse
+	16, // 21: spacemesh.debug.v1.Hare.Result:output_type -> spacemesh.debug.v1.HareResultResponse
+	18, // 22: spacemesh.debug.v1.Mesh.ValidityHistory:output_type -> spacemesh.debug.v1.ValidityHistoryResponse
+	18, // [18:23] is the sub-list for method output_type
+	13, // [13:18] is the sub-list for method input_type
+	13, // [13:13] is the sub-list for extension type_name
+	13, // [13:13] is the sub-list for extension extendee
+	0,  // [0:13] is the sub-list for field type_name
+}
+
+func init() { file_api_debug_v1_debug_proto_init() }
+func file_api_debug_v1_debug_proto_init() {
+	if File_api_debug_v1_debug_proto != nil {
+		return
+	}
+	if !protoimpl.UnsafeEnabled {
+		file_api_debug_v1_debug_proto_msgTypes[0].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*OpinionReportRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_api_debug_v1_debug_proto_msgTypes[1].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*OpinionReportResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_api_debug_v1_debug_proto_msgTypes[2].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*LayerOpinion); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_api_debug_v1_debug_proto_msgTypes[3].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*BlockOpinion); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_api_debug_v1_debug_proto_msgTypes[4].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*DumpStateResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_api_debug_v1_debug_proto_msgTypes[5].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*EncodeVotesRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_api_debug_v1_debug_proto_msgTypes[6].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*EncodeVotesResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_api_debug_v1_debug_proto_msgTypes[7].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*Opinion); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_api_debug_v1_debug_proto_msgTypes[8].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*Vote); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_api_debug_v1_debug_proto_msgTypes[9].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*VotesTrace); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_api_debug_v1_debug_proto_msgTypes[10].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*CandidateTrace); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_api_debug_v1_debug_proto_msgTypes[11].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*BlockOpinionTrace); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_api_debug_v1_debug_proto_msgTypes[12].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ExceptionTrace); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_api_debug_v1_debug_proto_msgTypes[13].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*HareResultRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_api_debug_v1_debug_proto_msgTypes[14].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*HareResultResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_api_debug_v1_debug_proto_msgTypes[15].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ValidityHistoryRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_api_debug_v1_debug_proto_msgTypes[16].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ValidityHistoryResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_api_debug_v1_debug_proto_msgTypes[17].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ValidityChange); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: file_api_debug_v1_debug_proto_rawDesc,
+			NumEnums:      2,
+			NumMessages:   18,
+			NumExtensions: 0,
+			NumServices:   3,
+		},
+		GoTypes:           file_api_debug_v1_debug_proto_goTypes,
+		DependencyIndexes: file_api_debug_v1_debug_proto_depIdxs,
+		EnumInfos:         file_api_debug_v1_debug_proto_enumTypes,
+		MessageInfos:      file_api_debug_v1_debug_proto_msgTypes,
+	}.Build()
+	File_api_debug_v1_debug_proto = out.File
+	file_api_debug_v1_debug_proto_rawDesc = nil
+	file_api_debug_v1_debug_proto_goTypes = nil
+	file_api_debug_v1_debug_proto_depIdxs = nil
+}
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ context.Context
+var _ grpc.ClientConnInterface
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+const _ = grpc.SupportPackageIsVersion6
+
+// TortoiseClient is the client API for Tortoise service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://godoc.org/google.golang.org/grpc#ClientConn.NewStream.
+type TortoiseClient interface {
+	// OpinionReport returns validity of blocks in the range of layers as it is seen by the local tortoise.
+	// Range that is not in the tortoise window is rejected with INVALID_ARGUMENT.
+	OpinionReport(ctx context.Context, in *OpinionReportRequest, opts ...grpc.CallOption) (*OpinionReportResponse, error)
+	// DumpState returns in-memory state of the tortoise, it can be loaded with tortoise.LoadState
+	// to replay decisions for the subsequent layers.
+	DumpState(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*DumpStateResponse, error)
+	// EncodeVotes encodes votes for the layer and returns them together with the trace of the vote selection.
+	// Votes are not used for the ballot.
+	EncodeVotes(ctx context.Context, in *EncodeVotesRequest, opts ...grpc.CallOption) (*EncodeVotesResponse, error)
+}
+
+type tortoiseClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewTortoiseClient(cc grpc.ClientConnInterface) TortoiseClient {
+	return &tortoiseClient{cc}
+}
+
+func (c *tortoiseClient) OpinionReport(ctx context.Context, in *OpinionReportRequest, opts ...grpc.CallOption) (*OpinionReportResponse, error) {
+	out := new(OpinionReportResponse)
+	err := c.cc.Invoke(ctx, "/spacemesh.debug.v1.Tortoise/OpinionReport", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *tortoiseClient) DumpState(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*DumpStateResponse, error) {
+	out := new(DumpStateResponse)
+	err := c.cc.Invoke(ctx, "/spacemesh.debug.v1.Tortoise/DumpState", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *tortoiseClient) EncodeVotes(ctx context.Context, in *EncodeVotesRequest, opts ...grpc.CallOption) (*EncodeVotesResponse, error) {
+	out := new(EncodeVotesResponse)
+	err := c.cc.Invoke(ctx, "/spacemesh.debug.v1.Tortoise/EncodeVotes", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// TortoiseServer is the server API for Tortoise service.
+type TortoiseServer interface {
+	// OpinionReport returns validity of blocks in the range of layers as it is seen by the local tortoise.
+	// Range that is not in the tortoise window is rejected with INVALID_ARGUMENT.
+	OpinionReport(context.Context, *OpinionReportRequest) (*OpinionReportResponse, error)
+	// DumpState returns in-memory state of the tortoise, it can be loaded with tortoise.LoadState
+	// to replay decisions for the subsequent layers.
+	DumpState(context.Context, *emptypb.Empty) (*DumpStateResponse, error)
+	// EncodeVotes encodes votes for the layer and returns them together with the trace of the vote selection.
+	// Votes are not used for the ballot.
+	EncodeVotes(context.Context, *EncodeVotesRequest) (*EncodeVotesResponse, error)
+}
+
+// UnimplementedTortoiseServer can be embedded to have forward compatible implementations.
+type UnimplementedTortoiseServer struct {
+}
+
+func (*UnimplementedTortoiseServer) OpinionReport(context.Context, *OpinionReportRequest) (*OpinionReportResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method OpinionReport not implemented")
+}
+func (*UnimplementedTortoiseServer) DumpState(context.Context, *emptypb.Empty) (*DumpStateResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DumpState not implemented")
+}
+func (*UnimplementedTortoiseServer) EncodeVotes(context.Context, *EncodeVotesRequest) (*EncodeVotesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method EncodeVotes not implemented")
+}
+
+func RegisterTortoiseServer(s *grpc.Server, srv TortoiseServer) {
+	s.RegisterService(&_Tortoise_serviceDesc, srv)
+}
+
+func _Tortoise_OpinionReport_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(OpinionReportRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TortoiseServer).OpinionReport(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/spacemesh.debug.v1.Tortoise/OpinionReport",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TortoiseServer).OpinionReport(ctx, req.(*OpinionReportRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Tortoise_DumpState_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(emptypb.Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TortoiseServer).DumpState(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/spacemesh.debug.v1.Tortoise/DumpState",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TortoiseServer).DumpState(ctx, req.(*emptypb.Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Tortoise_EncodeVotes_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(EncodeVotesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TortoiseServer).EncodeVotes(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/spacemesh.debug.v1.Tortoise/EncodeVotes",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TortoiseServer).EncodeVotes(ctx, req.(*EncodeVotesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+var _Tortoise_serviceDesc = grpc.ServiceDesc{
+	ServiceName: "spacemesh.debug.v1.Tortoise",
+	HandlerType: (*TortoiseServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "OpinionReport",
+			Handler:    _Tortoise_OpinionReport_Handler,
+		},
+		{
+			MethodName: "DumpState",
+			Handler:    _Tortoise_DumpState_Handler,
+		},
+		{
+			MethodName: "EncodeVotes",
+			Handler:    _Tortoise_EncodeVotes_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "api/debug/v1/debug.proto",
+}
+
+// HareClient is the client API for Hare service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://godoc.org/google.golang.org/grpc#ClientConn.NewStream.
+type HareClient interface {
+	// Result returns the proposals agreed upon by hare for the layer. The call blocks until hare
+	// terminates for the layer or the deadline of the request.
+	Result(ctx context.Context, in *HareResultRequest, opts ...grpc.CallOption) (*HareResultResponse, error)
+}
+
+type hareClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewHareClient(cc grpc.ClientConnInterface) HareClient {
+	return &hareClient{cc}
+}
+
+func (c *hareClient) Result(ctx context.Context, in *HareResultRequest, opts ...grpc.CallOption) (*HareResultResponse, error) {
+	out := new(HareResultResponse)
+	err := c.cc.Invoke(ctx, "/spacemesh.debug.v1.Hare/Result", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// HareServer is the server API for Hare service.
+type HareServer interface {
+	// Result returns the proposals agreed upon by hare for the layer. The call blocks until hare
+	// terminates for the layer or the deadline of the request.
+	Result(context.Context, *HareResultRequest) (*HareResultResponse, error)
+}
+
+// UnimplementedHareServer can be embedded to have forward compatible implementations.
+type UnimplementedHareServer struct {
+}
+
+func (*UnimplementedHareServer) Result(context.Context, *HareResultRequest) (*HareResultResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Result not implemented")
+}
+
+func RegisterHareServer(s *grpc.Server, srv HareServer) {
+	s.RegisterService(&_Hare_serviceDesc, srv)
+}
+
+func _Hare_Result_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(HareResultRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(HareServer).Result(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/spacemesh.debug.v1.Hare/Result",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(HareServer).Result(ctx, req.(*HareResultRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+var _Hare_serviceDesc = grpc.ServiceDesc{
+	ServiceName: "spacemesh.debug.v1.Hare",
+	HandlerType: (*HareServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Result",
+			Handler:    _Hare_Result_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "api/debug/v1/debug.proto",
+}
+
+// MeshClient is the client API for Mesh service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://godoc.org/google.golang.org/grpc#ClientConn.NewStream.
+type MeshClient interface {
+	// ValidityHistory returns the changes of the contextual validity of the block, from the oldest to the latest.
+	ValidityHistory(ctx context.Context, in *ValidityHistoryRequest, opts ...grpc.CallOption) (*ValidityHistoryResponse, error)
+}
+
+type meshClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewMeshClient(cc grpc.ClientConnInterface) MeshClient {
+	return &meshClient{cc}
+}
+
+func (c *meshClient) ValidityHistory(ctx context.Context, in *ValidityHistoryRequest, opts ...grpc.CallOption) (*ValidityHistoryResponse, error) {
+	out := new(ValidityHistoryResponse)
+	err := c.cc.Invoke(ctx, "/spacemesh.debug.v1.Mesh/ValidityHistory", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// MeshServer is the server API for Mesh service.
+type MeshServer interface {
+	// ValidityHistory returns the changes of the contextual validity of the block, from the oldest to the latest.
+	ValidityHistory(context.Context, *ValidityHistoryRequest) (*ValidityHistoryResponse, error)
+}
+
+// UnimplementedMeshServer can be embedded to have forward compatible implementations.
+type UnimplementedMeshServer struct {
+}
+
+func (*UnimplementedMeshServer) ValidityHistory(context.Context, *ValidityHistoryRequest) (*ValidityHistoryResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ValidityHistory not implemented")
+}
+
+func RegisterMeshServer(s *grpc.Server, srv MeshServer) {
+	s.RegisterService(&_Mesh_serviceDesc, srv)
+}
+
+func _Mesh_ValidityHistory_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ValidityHistoryRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MeshServer).ValidityHistory(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/spacemesh.debug.v1.Mesh/ValidityHistory",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MeshServer).ValidityHistory(ctx, req.(*ValidityHistoryRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+var _Mesh_serviceDesc = grpc.ServiceDesc{
+	ServiceName: "spacemesh.debug.v1.Mesh",
+	HandlerType: (*MeshServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "ValidityHistory",
+			Handler:    _Mesh_ValidityHistory_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "api/debug/v1/debug.proto",
+}
@@ -13,12 +13,18 @@ import (
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
 
+	adminv1 "github.com/spacemeshos/go-spacemesh/api/admin/v1"
 	"github.com/spacemeshos/go-spacemesh/checkpoint"
 	"github.com/spacemeshos/go-spacemesh/common/types"
 	"github.com/spacemeshos/go-spacemesh/events"
 	"github.com/spacemeshos/go-spacemesh/log"
+	"github.com/spacemeshos/go-spacemesh/p2p/peerscore"
 	"github.com/spacemeshos/go-spacemesh/sql"
+	"github.com/spacemeshos/go-spacemesh/tortoise"
 )
 
 const (
@@ -26,25 +32,41 @@ const (
 	defaultNumAtxs = 4
 )
 
+// peerScorer is implemented by peerscore.Scorer.
+type peerScorer interface {
+	Snapshot() peerscore.Snapshot
+}
+
+// rerunner is implemented by tortoise.
+type rerunner interface {
+	Rerun(context.Context, types.LayerID) (<-chan tortoise.RerunProgress, error)
+}
+
 // AdminService exposes endpoints for node administration.
 type AdminService struct {
-	logger  log.Log
-	db      *sql.Database
-	dataDir string
+	logger   log.Log
+	db       *sql.Database
+	dataDir  string
+	tortoise rerunner
+	scorer   peerScorer
 }
 
 // NewAdminService creates a new admin grpc service.
-func NewAdminService(db *sql.Database, dataDir string, lg log.Log) *AdminService {
+func NewAdminService(db *sql.Database, dataDir string, trtl rerunner, scorer peerScorer, lg log.Log) *AdminService {
 	return &AdminService{
-		logger:  lg,
-		db:      db,
-		dataDir: dataDir,
+		logger:   lg,
+		db:       db,
+		dataDir:  dataDir,
+		tortoise: trtl,
+		scorer:   scorer,
 	}
 }
 
 // RegisterService registers this service with a grpc server instance.
 func (a AdminService) RegisterService(server *Server) {
 	pb.RegisterAdminServiceServer(server.GrpcServer, a)
+	adminv1.RegisterTortoiseServer(server.GrpcServer, a)
+	adminv1.RegisterPeersServer(server.GrpcServer, a)
 }
 
 func (a AdminService) CheckpointStream(req *pb.CheckpointStreamRequest, stream pb.AdminService_CheckpointStreamServer) error {
@@ -92,6 +114,68 @@ func (a AdminService) CheckpointStream(req *pb.CheckpointStreamRequest, stream p
 	}
 }
 
+// Rerun reruns tortoise starting from the requested layer and streams the progress.
+// Rerun is cancelled, and its results are discarded, if the stream is closed before rerun completes.
+func (a AdminService) Rerun(req *adminv1.RerunRequest, stream adminv1.Tortoise_RerunServer) error {
+	if a.tortoise == nil {
+		return status.Errorf(codes.Unimplemented, "tortoise is not available")
+	}
+	progress, err := a.tortoise.Rerun(stream.Context(), types.LayerID(req.From))
+	if errors.Is(err, tortoise.ErrRerunInProgress) {
+		return status.Errorf(codes.Unavailable, err.Error())
+	} else if err != nil {
+		return status.Errorf(codes.InvalidArgument, err.Error())
+	}
+	if err := stream.SendHeader(metadata.MD{}); err != nil {
+		return status.Errorf(codes.Unavailable, "can't send header")
+	}
+	for update := range progress {
+		resp := &adminv1.RerunProgress{
+			From:      update.From.Uint32(),
+			To:        update.To.Uint32(),
+			Processed: update.Processed,
+			Changed:   update.Changed,
+			Remaining: durationpb.New(update.Remaining),
+			Done:      update.Done,
+		}
+		if update.Err != nil {
+			resp.Error = update.Err.Error()
+		}
+		if err := stream.Send(resp); err != nil {
+			return fmt.Errorf("send to stream: %w", err)
+		}
+	}
+	return nil
+}
+
+// Scores returns the scores of the peers and the current bans.
+func (a AdminService) Scores(_ context.Context, _ *emptypb.Empty) (*adminv1.ScoresResponse, error) {
+	if a.scorer == nil {
+		return nil, status.Errorf(codes.Unimplemented, "peer scoring is not available")
+	}
+	snapshot := a.scorer.Snapshot()
+	resp := &adminv1.ScoresResponse{
+		Peers: make([]*adminv1.PeerScore, 0, len(snapshot.Peers)),
+		Ips:   make([]*adminv1.IPBan, 0, len(snapshot.IPs)),
+	}
+	for _, peer := range snapshot.Peers {
+		score := &adminv1.PeerScore{
+			Id:      peer.ID.String(),
+			Score:   peer.Score,
+			Bans:    uint32(peer.Bans),
+			Trusted: peer.Trusted,
+		}
+		if !peer.BannedUntil.IsZero() {
+			score.BannedUntil = timestamppb.New(peer.BannedUntil)
+		}
+		resp.Peers = append(resp.Peers, score)
+	}
+	for _, ban := range snapshot.IPs {
+		resp.Ips = append(resp.Ips, &adminv1.IPBan{Ip: ban.IP, BannedUntil: timestamppb.New(ban.BannedUntil)})
+	}
+	return resp, nil
+}
+
 func (a AdminService) Recover(_ context.Context, _ *pb.RecoverRequest) (*empty.Empty, error) {
 	a.logger.Panic("going to recover from checkpoint")
 	return &empty.Empty{}, nil
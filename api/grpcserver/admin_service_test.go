@@ -7,14 +7,24 @@ import (
 	"testing"
 	"time"
 
+	"github.com/libp2p/go-libp2p/core/test"
 	pb "github.com/spacemeshos/api/release/go/spacemesh/v1"
 	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
 
+	adminv1 "github.com/spacemeshos/go-spacemesh/api/admin/v1"
 	"github.com/spacemeshos/go-spacemesh/common/types"
 	"github.com/spacemeshos/go-spacemesh/log/logtest"
+	"github.com/spacemeshos/go-spacemesh/p2p/peerscore"
 	"github.com/spacemeshos/go-spacemesh/sql"
 	"github.com/spacemeshos/go-spacemesh/sql/accounts"
 	"github.com/spacemeshos/go-spacemesh/sql/atxs"
+	"github.com/spacemeshos/go-spacemesh/tortoise"
 )
 
 const snapshot uint32 = 15
@@ -57,7 +67,7 @@ func TestAdminService_Checkpoint(t *testing.T) {
 	logtest.SetupGlobal(t)
 	db := sql.InMemory()
 	createMesh(t, db)
-	svc := NewAdminService(db, t.TempDir(), logtest.New(t))
+	svc := NewAdminService(db, t.TempDir(), nil, nil, logtest.New(t))
 	t.Cleanup(launchServer(t, cfg, svc))
 
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
@@ -93,7 +103,7 @@ func TestAdminService_Checkpoint(t *testing.T) {
 func TestAdminService_CheckpointError(t *testing.T) {
 	logtest.SetupGlobal(t)
 	db := sql.InMemory()
-	svc := NewAdminService(db, t.TempDir(), logtest.New(t))
+	svc := NewAdminService(db, t.TempDir(), nil, nil, logtest.New(t))
 	t.Cleanup(launchServer(t, cfg, svc))
 
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
@@ -106,3 +116,99 @@ func TestAdminService_CheckpointError(t *testing.T) {
 	_, err = stream.Recv()
 	require.ErrorContains(t, err, sql.ErrNotFound.Error())
 }
+
+type rerunnerFunc func(context.Context, types.LayerID) (<-chan tortoise.RerunProgress, error)
+
+func (f rerunnerFunc) Rerun(ctx context.Context, from types.LayerID) (<-chan tortoise.RerunProgress, error) {
+	return f(ctx, from)
+}
+
+func TestAdminService_TortoiseRerun(t *testing.T) {
+	logtest.SetupGlobal(t)
+	db := sql.InMemory()
+	trtl := rerunnerFunc(func(_ context.Context, from types.LayerID) (<-chan tortoise.RerunProgress, error) {
+		if from == 0 {
+			return nil, tortoise.ErrRerunInProgress
+		}
+		progress := make(chan tortoise.RerunProgress, 2)
+		progress <- tortoise.RerunProgress{From: from, To: from + 1, Processed: 1, Remaining: time.Second}
+		progress <- tortoise.RerunProgress{From: from, To: from + 1, Processed: 2, Changed: 1, Done: true}
+		close(progress)
+		return progress, nil
+	})
+	svc := NewAdminService(db, t.TempDir(), trtl, nil, logtest.New(t))
+	t.Cleanup(launchServer(t, cfg, svc))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	conn := dialGrpc(ctx, t, cfg.PublicListener)
+
+	c := adminv1.NewTortoiseClient(conn)
+
+	t.Run("in progress", func(t *testing.T) {
+		stream, err := c.Rerun(ctx, &adminv1.RerunRequest{From: 0})
+		require.NoError(t, err)
+		_, err = stream.Recv()
+		require.Equal(t, codes.Unavailable, status.Code(err))
+	})
+	t.Run("progress", func(t *testing.T) {
+		stream, err := c.Rerun(ctx, &adminv1.RerunRequest{From: 10})
+		require.NoError(t, err)
+		var updates []*adminv1.RerunProgress
+		for {
+			update, err := stream.Recv()
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			require.NoError(t, err)
+			updates = append(updates, update)
+		}
+		require.Len(t, updates, 2)
+		require.True(t, proto.Equal(&adminv1.RerunProgress{
+			From: 10, To: 11, Processed: 1, Remaining: durationpb.New(time.Second),
+		}, updates[0]))
+		require.True(t, proto.Equal(&adminv1.RerunProgress{
+			From: 10, To: 11, Processed: 2, Changed: 1, Remaining: durationpb.New(0), Done: true,
+		}, updates[1]))
+	})
+}
+
+type peerScorerFunc func() peerscore.Snapshot
+
+func (f peerScorerFunc) Snapshot() peerscore.Snapshot {
+	return f()
+}
+
+func TestAdminService_PeerScores(t *testing.T) {
+	logtest.SetupGlobal(t)
+	db := sql.InMemory()
+	expected := peerscore.Snapshot{
+		Peers: []peerscore.PeerState{
+			{ID: test.RandPeerIDFatal(t), Score: 20, Bans: 1, BannedUntil: time.Unix(100, 0).UTC()},
+			{ID: test.RandPeerIDFatal(t), Score: 1},
+		},
+		IPs: []peerscore.IPBan{{IP: "10.0.0.1", BannedUntil: time.Unix(100, 0).UTC()}},
+	}
+	svc := NewAdminService(db, t.TempDir(), nil, peerScorerFunc(func() peerscore.Snapshot {
+		return expected
+	}), logtest.New(t))
+	t.Cleanup(launchServer(t, cfg, svc))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	conn := dialGrpc(ctx, t, cfg.PublicListener)
+	resp, err := adminv1.NewPeersClient(conn).Scores(ctx, &emptypb.Empty{})
+	require.NoError(t, err)
+	require.True(t, proto.Equal(&adminv1.ScoresResponse{
+		Peers: []*adminv1.PeerScore{
+			{
+				Id:          expected.Peers[0].ID.String(),
+				Score:       20,
+				Bans:        1,
+				BannedUntil: timestamppb.New(time.Unix(100, 0)),
+			},
+			{Id: expected.Peers[1].ID.String(), Score: 1},
+		},
+		Ips: []*adminv1.IPBan{{Ip: "10.0.0.1", BannedUntil: timestamppb.New(time.Unix(100, 0))}},
+	}, resp), resp)
+}
@@ -1,8 +1,11 @@
 package grpcserver
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
+	"io"
 
 	"github.com/golang/protobuf/ptypes/empty"
 	pb "github.com/spacemeshos/api/release/go/spacemesh/v1"
@@ -11,33 +14,68 @@ import (
 	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/types/known/emptypb"
 
+	debugv1 "github.com/spacemeshos/go-spacemesh/api/debug/v1"
 	"github.com/spacemeshos/go-spacemesh/common/types"
 	"github.com/spacemeshos/go-spacemesh/events"
+	"github.com/spacemeshos/go-spacemesh/hare"
 	"github.com/spacemeshos/go-spacemesh/log"
+	"github.com/spacemeshos/go-spacemesh/signing"
 	"github.com/spacemeshos/go-spacemesh/sql"
 	"github.com/spacemeshos/go-spacemesh/sql/accounts"
+	"github.com/spacemeshos/go-spacemesh/sql/blocks"
+	"github.com/spacemeshos/go-spacemesh/tortoise"
 )
 
+// tortoiseDebugger is implemented by tortoise.
+type tortoiseDebugger interface {
+	OpinionReport(from, to types.LayerID) (*tortoise.OpinionReport, error)
+	DumpState(w io.Writer) error
+	EncodeVotesWithTrace(context.Context, types.LayerID) (*types.Opinion, *tortoise.VotesTrace, error)
+}
+
+// hareResults is implemented by hare.
+type hareResults interface {
+	GetResult(context.Context, types.LayerID) ([]types.ProposalID, error)
+}
+
 // DebugService exposes global state data, output from the STF.
 type DebugService struct {
 	db       *sql.Database
 	conState conservativeState
 	identity networkIdentity
 	oracle   oracle
+	tortoise tortoiseDebugger
+	hare     hareResults
+	signer   signing.Signer
 }
 
 // RegisterService registers this service with a grpc server instance.
 func (d DebugService) RegisterService(server *Server) {
 	pb.RegisterDebugServiceServer(server.GrpcServer, d)
+	debugv1.RegisterTortoiseServer(server.GrpcServer, d)
+	debugv1.RegisterHareServer(server.GrpcServer, d)
+	debugv1.RegisterMeshServer(server.GrpcServer, d)
 }
 
 // NewDebugService creates a new grpc service using config data.
-func NewDebugService(db *sql.Database, conState conservativeState, host networkIdentity, oracle oracle) *DebugService {
+// Signer is optional, reports can't be signed without it.
+func NewDebugService(
+	db *sql.Database,
+	conState conservativeState,
+	host networkIdentity,
+	oracle oracle,
+	trtl tortoiseDebugger,
+	hare hareResults,
+	signer signing.Signer,
+) *DebugService {
 	return &DebugService{
 		db:       db,
 		conState: conState,
 		identity: host,
 		oracle:   oracle,
+		tortoise: trtl,
+		hare:     hare,
+		signer:   signer,
 	}
 }
 
@@ -115,6 +153,10 @@ func (d DebugService) ProposalsStream(_ *emptypb.Empty, stream pb.DebugService_P
 		case <-fullch:
 			return status.Errorf(codes.Canceled, "buffer is full")
 		case ev := <-eventch:
+			if ev.Status == events.ProposalExpired {
+				// expired proposals were never published and have no status in the api
+				continue
+			}
 			if err := stream.Send(castEventProposal(&ev)); err != nil {
 				return fmt.Errorf("send to stream: %w", err)
 			}
@@ -147,3 +189,215 @@ func castEventProposal(ev *events.EventProposal) *pb.Proposal {
 	}
 	return proposal
 }
+
+// OpinionReport returns validity of blocks in the requested range of layers
+// as it is seen by the local tortoise.
+func (d DebugService) OpinionReport(ctx context.Context, in *debugv1.OpinionReportRequest) (*debugv1.OpinionReportResponse, error) {
+	if d.tortoise == nil {
+		return nil, status.Errorf(codes.Unimplemented, "tortoise is not available")
+	}
+	if in.Sign && d.signer == nil {
+		return nil, status.Errorf(codes.FailedPrecondition, "signer is not available")
+	}
+	report, err := d.tortoise.OpinionReport(types.LayerID(in.From), types.LayerID(in.To))
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, err.Error())
+	}
+	if in.Sign {
+		if err := report.Sign(ctx, d.signer); err != nil {
+			return nil, status.Errorf(codes.Internal, err.Error())
+		}
+	}
+	return castOpinionReport(report), nil
+}
+
+// DumpState returns in-memory state of the tortoise, it can be loaded with tortoise.LoadState
+// to replay decisions for the subsequent layers.
+func (d DebugService) DumpState(_ context.Context, _ *emptypb.Empty) (*debugv1.DumpStateResponse, error) {
+	if d.tortoise == nil {
+		return nil, status.Errorf(codes.Unimplemented, "tortoise is not available")
+	}
+	var buf bytes.Buffer
+	if err := d.tortoise.DumpState(&buf); err != nil {
+		return nil, status.Errorf(codes.Internal, "dump state: %s", err.Error())
+	}
+	return &debugv1.DumpStateResponse{State: buf.Bytes()}, nil
+}
+
+// EncodeVotes encodes votes for the layer and returns them together with the trace of the vote selection.
+// Votes are not used for the ballot, the call is meant for debugging of the divergent opinions.
+func (d DebugService) EncodeVotes(ctx context.Context, in *debugv1.EncodeVotesRequest) (*debugv1.EncodeVotesResponse, error) {
+	if d.tortoise == nil {
+		return nil, status.Errorf(codes.Unimplemented, "tortoise is not available")
+	}
+	opinion, trace, err := d.tortoise.EncodeVotesWithTrace(ctx, types.LayerID(in.Layer))
+	resp := &debugv1.EncodeVotesResponse{Opinion: castOpinion(opinion), Trace: castVotesTrace(trace)}
+	if err != nil {
+		resp.Error = err.Error()
+	}
+	return resp, nil
+}
+
+// Result returns the proposals agreed upon by hare for the layer. It waits for hare to terminate
+// for the layer until the deadline of the request.
+func (d DebugService) Result(ctx context.Context, in *debugv1.HareResultRequest) (*debugv1.HareResultResponse, error) {
+	if d.hare == nil {
+		return nil, status.Errorf(codes.Unimplemented, "hare is not available")
+	}
+	lid := types.LayerID(in.Layer)
+	pids, err := d.hare.GetResult(ctx, lid)
+	switch {
+	case errors.Is(err, hare.ErrHareNotRun):
+		return nil, status.Errorf(codes.NotFound, "hare not run for layer %d", lid)
+	case errors.Is(err, hare.ErrHareFailed):
+		return nil, status.Errorf(codes.Aborted, "hare failed for layer %d", lid)
+	case errors.Is(err, context.DeadlineExceeded):
+		return nil, status.Errorf(codes.DeadlineExceeded, "hare didn't terminate for layer %d", lid)
+	case errors.Is(err, context.Canceled):
+		return nil, status.Errorf(codes.Canceled, err.Error())
+	case err != nil:
+		return nil, status.Errorf(codes.Internal, err.Error())
+	}
+	resp := &debugv1.HareResultResponse{Layer: lid.Uint32(), Proposals: make([][]byte, 0, len(pids))}
+	for _, pid := range pids {
+		resp.Proposals = append(resp.Proposals, pid.Bytes())
+	}
+	return resp, nil
+}
+
+// ValidityHistory returns the changes of the contextual validity of the block, from the oldest to the latest.
+func (d DebugService) ValidityHistory(_ context.Context, in *debugv1.ValidityHistoryRequest) (*debugv1.ValidityHistoryResponse, error) {
+	var id types.BlockID
+	// the id is accepted as is and in the padded form returned by types.BlockID.Bytes
+	if len(in.Block) != len(id) && len(in.Block) != types.BlockIDSize {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid block id length %d", len(in.Block))
+	}
+	copy(id[:], in.Block)
+	changes, err := blocks.ValidityHistory(d.db, id)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, err.Error())
+	}
+	resp := &debugv1.ValidityHistoryResponse{Block: id.Bytes(), Changes: make([]*debugv1.ValidityChange, 0, len(changes))}
+	for _, change := range changes {
+		resp.Changes = append(resp.Changes, &debugv1.ValidityChange{
+			Layer:  change.Layer.Uint32(),
+			Old:    castValidity(change.Old),
+			New:    castValidity(change.New),
+			Source: debugv1.ValiditySource(change.Source),
+		})
+	}
+	return resp, nil
+}
+
+func castValidity(validity blocks.Validity) debugv1.Validity {
+	switch validity {
+	case blocks.Valid:
+		return debugv1.Validity_VALIDITY_VALID
+	case blocks.Invalid:
+		return debugv1.Validity_VALIDITY_INVALID
+	}
+	return debugv1.Validity_VALIDITY_UNDECIDED
+}
+
+func castOpinionReport(report *tortoise.OpinionReport) *debugv1.OpinionReportResponse {
+	resp := &debugv1.OpinionReportResponse{
+		From:   report.From.Uint32(),
+		To:     report.To.Uint32(),
+		Layers: make([]*debugv1.LayerOpinion, 0, len(report.Layers)),
+	}
+	for _, layer := range report.Layers {
+		opinion := &debugv1.LayerOpinion{
+			Layer:     layer.Layer.Uint32(),
+			Verified:  layer.Verified,
+			Opinion:   layer.Opinion.Bytes(),
+			Threshold: layer.Threshold,
+			Blocks:    make([]*debugv1.BlockOpinion, 0, len(layer.Blocks)),
+		}
+		for _, block := range layer.Blocks {
+			opinion.Blocks = append(opinion.Blocks, &debugv1.BlockOpinion{
+				Id:       block.ID.Bytes(),
+				Height:   block.Height,
+				Validity: block.Validity,
+				Source:   string(block.Source),
+				Hare:     block.Hare,
+				Margin:   block.Margin,
+				Relative: block.Relative,
+			})
+		}
+		resp.Layers = append(resp.Layers, opinion)
+	}
+	if report.NodeID != nil && report.Signature != nil {
+		resp.NodeId = report.NodeID.Bytes()
+		resp.Signature = report.Signature.Bytes()
+	}
+	return resp
+}
+
+func castOpinion(opinion *types.Opinion) *debugv1.Opinion {
+	if opinion == nil {
+		return nil
+	}
+	rst := &debugv1.Opinion{
+		Hash:    opinion.Hash.Bytes(),
+		Base:    opinion.Base.Bytes(),
+		Support: castVotes(opinion.Support),
+		Against: castVotes(opinion.Against),
+	}
+	for _, lid := range opinion.Abstain {
+		rst.Abstain = append(rst.Abstain, lid.Uint32())
+	}
+	return rst
+}
+
+func castVotes(votes []types.Vote) []*debugv1.Vote {
+	var rst []*debugv1.Vote
+	for _, vote := range votes {
+		rst = append(rst, &debugv1.Vote{Id: vote.ID.Bytes(), Layer: vote.LayerID.Uint32(), Height: vote.Height})
+	}
+	return rst
+}
+
+func castVotesTrace(trace *tortoise.VotesTrace) *debugv1.VotesTrace {
+	if trace == nil {
+		return nil
+	}
+	rst := &debugv1.VotesTrace{Layer: trace.Layer.Uint32()}
+	for _, candidate := range trace.Candidates {
+		rst.Candidates = append(rst.Candidates, &debugv1.CandidateTrace{
+			Ballot:    candidate.Ballot.Bytes(),
+			Layer:     candidate.Layer.Uint32(),
+			Malicious: candidate.Malicious,
+			BadBeacon: candidate.BadBeacon,
+			Selected:  candidate.Selected,
+			Verdict:   string(candidate.Verdict),
+			Reason:    candidate.Reason,
+			Error:     candidate.Error,
+		})
+	}
+	for _, block := range trace.Blocks {
+		rst.Blocks = append(rst.Blocks, &debugv1.BlockOpinionTrace{
+			Block:    block.Block.Bytes(),
+			Layer:    block.Layer.Uint32(),
+			Height:   block.Height,
+			Data:     block.Data,
+			Hare:     block.Hare,
+			Validity: block.Validity,
+			Margin:   block.Margin,
+			Vote:     block.Vote,
+			Reason:   block.Reason,
+		})
+	}
+	for _, exception := range trace.Exceptions {
+		ex := &debugv1.ExceptionTrace{
+			Layer:    exception.Layer.Uint32(),
+			Vote:     exception.Vote,
+			BaseVote: exception.BaseVote,
+			Reason:   exception.Reason,
+		}
+		if exception.Block != nil {
+			ex.Block = exception.Block.Bytes()
+		}
+		rst.Exceptions = append(rst.Exceptions, ex)
+	}
+	return rst
+}
@@ -13,6 +13,7 @@ const subscriptionChanBufSize = 1 << 16
 // Errors for cases with a full event buffer.
 var (
 	errTxBufferFull          = "tx buffer is full"
+	errTxStateBufferFull     = "tx state buffer is full"
 	errLayerBufferFull       = "layer buffer is full"
 	errAccountBufferFull     = "account buffer is full"
 	errRewardsBufferFull     = "rewards buffer is full"
@@ -4,6 +4,7 @@ package grpcserver
 import (
 	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
@@ -34,22 +35,29 @@ import (
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/credentials/insecure"
 	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
 
 	"github.com/spacemeshos/go-spacemesh/activation"
+	debugv1 "github.com/spacemeshos/go-spacemesh/api/debug/v1"
 	"github.com/spacemeshos/go-spacemesh/codec"
 	"github.com/spacemeshos/go-spacemesh/common/types"
 	"github.com/spacemeshos/go-spacemesh/events"
 	vm "github.com/spacemeshos/go-spacemesh/genvm"
 	"github.com/spacemeshos/go-spacemesh/genvm/sdk"
 	"github.com/spacemeshos/go-spacemesh/genvm/sdk/wallet"
+	"github.com/spacemeshos/go-spacemesh/hare"
 	"github.com/spacemeshos/go-spacemesh/log/logtest"
+	"github.com/spacemeshos/go-spacemesh/miner"
 	"github.com/spacemeshos/go-spacemesh/p2p"
 	pubsubmocks "github.com/spacemeshos/go-spacemesh/p2p/pubsub/mocks"
 	"github.com/spacemeshos/go-spacemesh/rand"
 	"github.com/spacemeshos/go-spacemesh/signing"
 	"github.com/spacemeshos/go-spacemesh/sql"
 	"github.com/spacemeshos/go-spacemesh/sql/accounts"
+	"github.com/spacemeshos/go-spacemesh/sql/blocks"
 	"github.com/spacemeshos/go-spacemesh/system"
+	"github.com/spacemeshos/go-spacemesh/tortoise"
 	"github.com/spacemeshos/go-spacemesh/txs"
 )
 
@@ -305,6 +313,32 @@ func (m *MeshAPIMock) GetLayer(tid types.LayerID) (*types.Layer, error) {
 	return types.NewExistingLayer(tid, ballots, blocks), nil
 }
 
+func (m *MeshAPIMock) IterateLayerBallots(tid types.LayerID, fn func(*types.Ballot) bool) error {
+	layer, err := m.GetLayer(tid)
+	if err != nil {
+		return err
+	}
+	for _, ballot := range layer.Ballots() {
+		if !fn(ballot) {
+			break
+		}
+	}
+	return nil
+}
+
+func (m *MeshAPIMock) IterateLayerBlocks(tid types.LayerID, fn func(*types.Block) bool) error {
+	layer, err := m.GetLayer(tid)
+	if err != nil {
+		return err
+	}
+	for _, block := range layer.Blocks() {
+		if !fn(block) {
+			break
+		}
+	}
+	return nil
+}
+
 func (m *MeshAPIMock) EpochAtxs(types.EpochID) ([]types.ATXID, error) {
 	return types.RandomActiveSet(activesetSize), nil
 }
@@ -331,6 +365,7 @@ type ConStateAPIMock struct {
 	// but we just mock a very simple use case here and only store some of these data
 	poolByAddress map[types.Address]types.TransactionID
 	poolByTxId    map[types.TransactionID]*types.Transaction
+	pending       map[types.Address][]txs.PendingTX
 }
 
 func (t *ConStateAPIMock) put(id types.TransactionID, tx *types.Transaction) {
@@ -345,6 +380,18 @@ func (t *ConStateAPIMock) GetProjection(types.Address) (uint64, uint64) {
 	return accountCounter + 1, accountBalance + 1
 }
 
+func (t *ConStateAPIMock) GetPending(addr types.Address) []txs.PendingTX {
+	return t.pending[addr]
+}
+
+func (t *ConStateAPIMock) AccountPendingState(addr types.Address) (uint64, uint64, []types.TransactionID) {
+	var pending []types.TransactionID
+	for _, tx := range t.pending[addr] {
+		pending = append(pending, tx.ID)
+	}
+	return accountCounter + 1, accountBalance + 1, pending
+}
+
 func (t *ConStateAPIMock) GetAllAccounts() (res []*types.Account, err error) {
 	for address, balance := range t.balances {
 		res = append(res, &types.Account{
@@ -947,7 +994,7 @@ func TestSmesherService(t *testing.T) {
 	postProvider.EXPECT().Status().Return(&activation.PostSetupStatus{}).AnyTimes()
 	postProvider.EXPECT().Providers().Return(nil, nil).AnyTimes()
 	smeshingAPI := &SmeshingAPIMock{}
-	svc := NewSmesherService(postProvider, smeshingAPI, 10*time.Millisecond, activation.DefaultPostSetupOpts())
+	svc := NewSmesherService(postProvider, smeshingAPI, nil, 10*time.Millisecond, activation.DefaultPostSetupOpts())
 	t.Cleanup(launchServer(t, cfg, svc))
 
 	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
@@ -1082,6 +1129,177 @@ func TestSmesherService(t *testing.T) {
 	})
 }
 
+func TestSmesherService_ProposalSchedule(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	builder := NewMockproposalBuilder(ctrl)
+	svc := NewSmesherService(NewMockpostSetupProvider(ctrl), &SmeshingAPIMock{}, builder, time.Second, activation.DefaultPostSetupOpts())
+	t.Cleanup(launchServer(t, cfg, svc))
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	conn := dialGrpc(ctx, t, cfg.PublicListener)
+
+	now := time.Now().UTC().Truncate(time.Second)
+	schedule := []miner.ScheduledProposal{
+		{NodeID: types.NodeID{1}, Layer: 10, Time: now, Count: 1},
+		{NodeID: types.NodeID{1}, Layer: 12, Time: now.Add(time.Minute), Count: 2},
+	}
+	builder.EXPECT().Schedule().Return(schedule, nil)
+	resp := &wrapperspb.StringValue{}
+	require.NoError(t, conn.Invoke(ctx, proposalScheduleMethod, &emptypb.Empty{}, resp))
+	var rst []ScheduledProposal
+	require.NoError(t, json.Unmarshal([]byte(resp.Value), &rst))
+	require.Len(t, rst, len(schedule))
+	for i, sp := range schedule {
+		require.Equal(t, sp.NodeID.String(), rst[i].NodeID)
+		require.Equal(t, sp.Layer, rst[i].Layer)
+		require.True(t, sp.Time.Equal(rst[i].Time))
+		require.Equal(t, sp.Count, rst[i].Count)
+	}
+
+	builder.EXPECT().Schedule().Return(nil, errors.New("test"))
+	err := conn.Invoke(ctx, proposalScheduleMethod, &emptypb.Empty{}, resp)
+	require.Equal(t, codes.Internal, status.Code(err))
+}
+
+func TestSmesherService_ProposalDryRun(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	builder := NewMockproposalBuilder(ctrl)
+	svc := NewSmesherService(NewMockpostSetupProvider(ctrl), &SmeshingAPIMock{}, builder, time.Second, activation.DefaultPostSetupOpts())
+	t.Cleanup(launchServer(t, cfg, svc))
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	conn := dialGrpc(ctx, t, cfg.PublicListener)
+
+	primary, err := signing.NewEdSigner()
+	require.NoError(t, err)
+	secondary, err := signing.NewEdSigner()
+	require.NoError(t, err)
+	builder.EXPECT().Identities().Return([]signing.Signer{primary, secondary}).AnyTimes()
+
+	proposal := func(signer signing.Signer, lid types.LayerID) *types.Proposal {
+		p := &types.Proposal{
+			InnerProposal: types.InnerProposal{
+				Ballot: types.Ballot{
+					InnerBallot: types.InnerBallot{
+						Layer:     lid,
+						AtxID:     types.RandomATXID(),
+						EpochData: &types.EpochData{ActiveSetHash: types.RandomHash(), EligibilityCount: 2},
+					},
+					Votes: types.Votes{
+						Base:    types.RandomBallotID(),
+						Support: []types.Vote{{ID: types.RandomBlockID()}, {ID: types.RandomBlockID()}},
+						Abstain: []types.LayerID{lid.Sub(1)},
+					},
+					EligibilityProofs: []types.VotingEligibility{{J: 1}, {J: 2}},
+					ActiveSet:         []types.ATXID{types.RandomATXID(), types.RandomATXID(), types.RandomATXID()},
+				},
+				TxIDs:    []types.TransactionID{types.RandomTransactionID()},
+				MeshHash: types.RandomHash(),
+			},
+		}
+		p.SmesherID = signer.NodeID()
+		require.NoError(t, p.Initialize())
+		return p
+	}
+	dryRun := func(req DryRunRequest) (*DryRunProposal, error) {
+		data, err := json.Marshal(req)
+		require.NoError(t, err)
+		resp := &wrapperspb.StringValue{}
+		if err := conn.Invoke(ctx, proposalDryRunMethod, wrapperspb.String(string(data)), resp); err != nil {
+			return nil, err
+		}
+		var rst DryRunProposal
+		require.NoError(t, json.Unmarshal([]byte(resp.Value), &rst))
+		return &rst, nil
+	}
+
+	t.Run("primary", func(t *testing.T) {
+		p := proposal(primary, 10)
+		builder.EXPECT().BuildDryRun(gomock.Any(), p.Layer, primary).Return(p, nil)
+		rst, err := dryRun(DryRunRequest{Layer: p.Layer})
+		require.NoError(t, err)
+		require.Equal(t, primary.NodeID().String(), rst.NodeID)
+		require.Equal(t, p.Layer, rst.Layer)
+		require.Equal(t, p.ID().AsHash32().String(), rst.Proposal)
+		require.Equal(t, p.Ballot.ID().AsHash32().String(), rst.Ballot)
+		require.Empty(t, rst.RefBallot)
+		require.Equal(t, 3, rst.ActiveSetSize)
+		require.Equal(t, 2, rst.Eligibilities)
+		require.Equal(t, p.Votes.Base.AsHash32().String(), rst.Base)
+		require.Equal(t, 2, rst.Support)
+		require.Equal(t, 0, rst.Against)
+		require.Equal(t, 1, rst.Abstain)
+		require.Equal(t, p.MeshHash.String(), rst.MeshHash)
+		require.Equal(t, []string{p.TxIDs[0].Hash32().String()}, rst.Transactions)
+		encoded, err := codec.Encode(p)
+		require.NoError(t, err)
+		require.Equal(t, len(encoded), rst.Size)
+	})
+	t.Run("secondary", func(t *testing.T) {
+		p := proposal(secondary, 11)
+		builder.EXPECT().BuildDryRun(gomock.Any(), p.Layer, secondary).Return(p, nil)
+		rst, err := dryRun(DryRunRequest{Layer: p.Layer, NodeID: secondary.NodeID().String()})
+		require.NoError(t, err)
+		require.Equal(t, secondary.NodeID().String(), rst.NodeID)
+	})
+	t.Run("unknown identity", func(t *testing.T) {
+		_, err := dryRun(DryRunRequest{Layer: 10, NodeID: types.RandomNodeID().String()})
+		require.Equal(t, codes.NotFound, status.Code(err))
+	})
+	t.Run("invalid identity", func(t *testing.T) {
+		_, err := dryRun(DryRunRequest{Layer: 10, NodeID: "abc"})
+		require.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+	t.Run("build error", func(t *testing.T) {
+		builder.EXPECT().BuildDryRun(gomock.Any(), types.LayerID(12), primary).Return(nil, errors.New("test"))
+		_, err := dryRun(DryRunRequest{Layer: 12})
+		require.Equal(t, codes.FailedPrecondition, status.Code(err))
+	})
+}
+
+func TestSmesherService_ProposalBuildEvents(t *testing.T) {
+	events.InitializeReporter()
+	t.Cleanup(events.CloseEventReporter)
+	ctrl := gomock.NewController(t)
+	svc := NewSmesherService(NewMockpostSetupProvider(ctrl), &SmeshingAPIMock{}, nil, time.Second, activation.DefaultPostSetupOpts())
+	t.Cleanup(launchServer(t, cfg, svc))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	conn := dialGrpc(ctx, t, cfg.PublicListener)
+	stream, err := conn.NewStream(ctx, &grpc.StreamDesc{ServerStreams: true}, proposalBuildEventsMethod)
+	require.NoError(t, err)
+	require.NoError(t, stream.SendMsg(&emptypb.Empty{}))
+	require.NoError(t, stream.CloseSend())
+	// header is sent after the subscription is created
+	_, err = stream.Header()
+	require.NoError(t, err)
+
+	nodeID := types.RandomNodeID()
+	atx := types.RandomATXID()
+	proposal := types.ProposalID{1, 2, 3}
+	events.ReportEligibilityComputed(events.EligibilityComputed{Epoch: 2, NodeID: nodeID, Atx: atx, Count: 3})
+	events.ReportProposalBuildStarted(events.ProposalBuildStarted{Layer: 10, NodeID: nodeID, Count: 2})
+	events.ReportProposalPublished(events.ProposalPublished{Layer: 10, NodeID: nodeID, Proposal: proposal})
+	events.ReportProposalFailed(events.ProposalFailed{Layer: 11, NodeID: nodeID, Reason: events.FailureNoBeacon, Error: "test"})
+
+	expected := []BuilderEventResponse{
+		{Type: "eligibility_computed", NodeID: nodeID.String(), Epoch: 2, Atx: atx.Hash32().String(), Count: 3},
+		{Type: "build_started", NodeID: nodeID.String(), Layer: 10, Count: 2},
+		{Type: "published", NodeID: nodeID.String(), Layer: 10, Proposal: proposal.AsHash32().String()},
+		{Type: "failed", NodeID: nodeID.String(), Layer: 11, Reason: "no_beacon", Error: "test"},
+	}
+	for _, ev := range expected {
+		msg := &wrapperspb.StringValue{}
+		require.NoError(t, stream.RecvMsg(msg))
+		var received BuilderEventResponse
+		require.NoError(t, json.Unmarshal([]byte(msg.Value), &received))
+		require.Equal(t, ev, received)
+	}
+}
+
 func TestMeshService(t *testing.T) {
 	logtest.SetupGlobal(t)
 	ctrl := gomock.NewController(t)
@@ -1654,6 +1872,228 @@ func TestTransactionServiceSubmitUnsync(t *testing.T) {
 	req.NoError(err)
 }
 
+func TestTransactionService_MempoolPending(t *testing.T) {
+	pending := func(nonce uint64, layer types.LayerID, parked bool) txs.PendingTX {
+		tx := NewTx(nonce, addr2, signer1)
+		return txs.PendingTX{
+			NanoTX: txs.NewNanoTX(&types.MeshTransaction{Transaction: *tx, LayerID: layer}),
+			Parked: parked,
+		}
+	}
+	principal := addr1
+	conStateAPI.pending = map[types.Address][]txs.PendingTX{
+		principal: {pending(1, 10, false), pending(2, 0, false), pending(4, 0, true)},
+	}
+	t.Cleanup(func() { conStateAPI.pending = nil })
+
+	grpcService := NewTransactionService(sql.InMemory(), nil, meshAPIMock, conStateAPI, nil, nil)
+	t.Cleanup(launchServer(t, cfg, grpcService))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	conn := dialGrpc(ctx, t, cfg.PublicListener)
+	query := func(addr string) (*PendingTransactions, error) {
+		resp := &wrapperspb.StringValue{}
+		if err := conn.Invoke(ctx, mempoolPendingMethod, wrapperspb.String(addr), resp); err != nil {
+			return nil, err
+		}
+		var rst PendingTransactions
+		require.NoError(t, json.Unmarshal([]byte(resp.Value), &rst))
+		return &rst, nil
+	}
+
+	t.Run("labeled", func(t *testing.T) {
+		rst, err := query(principal.String())
+		require.NoError(t, err)
+		require.Equal(t, principal.String(), rst.Address)
+		require.EqualValues(t, accountCounter+1, rst.NextNonce)
+		require.Len(t, rst.Transactions, 3)
+		for i, expected := range conStateAPI.pending[principal] {
+			got := rst.Transactions[i]
+			require.Equal(t, expected.ID.String(), got.ID)
+			require.Equal(t, expected.Nonce, got.Nonce)
+			require.Equal(t, expected.Layer, got.Layer)
+			require.Equal(t, expected.MaxSpending(), got.MaxSpending)
+		}
+		require.Equal(t, []string{pendingReady, pendingReady, pendingParked}, []string{
+			rst.Transactions[0].State, rst.Transactions[1].State, rst.Transactions[2].State,
+		})
+	})
+	t.Run("no transactions", func(t *testing.T) {
+		rst, err := query(addr2.String())
+		require.NoError(t, err)
+		require.Empty(t, rst.Transactions)
+	})
+	t.Run("invalid address", func(t *testing.T) {
+		_, err := query("sm1invalid")
+		require.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+}
+
+func TestTransactionService_MempoolAccountState(t *testing.T) {
+	principal := addr1
+	tx1, tx2 := NewTx(1, addr2, signer1), NewTx(2, addr2, signer1)
+	conStateAPI.pending = map[types.Address][]txs.PendingTX{
+		principal: {
+			{NanoTX: txs.NewNanoTX(&types.MeshTransaction{Transaction: *tx1})},
+			{NanoTX: txs.NewNanoTX(&types.MeshTransaction{Transaction: *tx2})},
+		},
+	}
+	t.Cleanup(func() { conStateAPI.pending = nil })
+
+	grpcService := NewTransactionService(sql.InMemory(), nil, meshAPIMock, conStateAPI, nil, nil)
+	t.Cleanup(launchServer(t, cfg, grpcService))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	conn := dialGrpc(ctx, t, cfg.PublicListener)
+	query := func(addr string) (*AccountPendingState, error) {
+		resp := &wrapperspb.StringValue{}
+		if err := conn.Invoke(ctx, mempoolAccountStateMethod, wrapperspb.String(addr), resp); err != nil {
+			return nil, err
+		}
+		var rst AccountPendingState
+		require.NoError(t, json.Unmarshal([]byte(resp.Value), &rst))
+		return &rst, nil
+	}
+
+	t.Run("pending", func(t *testing.T) {
+		rst, err := query(principal.String())
+		require.NoError(t, err)
+		require.Equal(t, AccountPendingState{
+			Address:   principal.String(),
+			NextNonce: accountCounter + 1,
+			Balance:   accountBalance + 1,
+			Pending:   []string{tx1.ID.String(), tx2.ID.String()},
+		}, *rst)
+	})
+	t.Run("no transactions", func(t *testing.T) {
+		rst, err := query(addr2.String())
+		require.NoError(t, err)
+		require.Empty(t, rst.Pending)
+	})
+	t.Run("invalid address", func(t *testing.T) {
+		_, err := query("sm1invalid")
+		require.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+}
+
+func TestTransactionService_MempoolTransactionState(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	conState := NewMockconservativeState(ctrl)
+	grpcService := NewTransactionService(sql.InMemory(), nil, meshAPIMock, conState, nil, nil)
+	t.Cleanup(launchServer(t, cfg, grpcService))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	conn := dialGrpc(ctx, t, cfg.PublicListener)
+	query := func(id string) (*TransactionLifecycle, error) {
+		resp := &wrapperspb.StringValue{}
+		if err := conn.Invoke(ctx, mempoolTxStateMethod, wrapperspb.String(id), resp); err != nil {
+			return nil, err
+		}
+		var rst TransactionLifecycle
+		require.NoError(t, json.Unmarshal([]byte(resp.Value), &rst))
+		return &rst, nil
+	}
+
+	t.Run("block", func(t *testing.T) {
+		tx := &types.MeshTransaction{
+			Transaction: *globalTx,
+			State:       types.BLOCK,
+			LayerID:     txReturnLayer,
+			BlockID:     types.BlockID{1},
+		}
+		conState.EXPECT().GetMeshTransaction(globalTx.ID).Return(tx, nil)
+		rst, err := query(globalTx.ID.String())
+		require.NoError(t, err)
+		require.Equal(t, TransactionLifecycle{
+			ID:    globalTx.ID.String(),
+			State: "block",
+			Layer: txReturnLayer,
+			Block: types.BlockID{1}.String(),
+		}, *rst)
+	})
+	t.Run("mempool", func(t *testing.T) {
+		tx := &types.MeshTransaction{Transaction: *globalTx, State: types.MEMPOOL}
+		conState.EXPECT().GetMeshTransaction(globalTx.ID).Return(tx, nil)
+		rst, err := query(globalTx.ID.String())
+		require.NoError(t, err)
+		require.Equal(t, TransactionLifecycle{ID: globalTx.ID.String(), State: "mempool"}, *rst)
+	})
+	t.Run("not found", func(t *testing.T) {
+		conState.EXPECT().GetMeshTransaction(globalTx.ID).Return(nil, sql.ErrNotFound)
+		_, err := query(globalTx.ID.String())
+		require.Equal(t, codes.NotFound, status.Code(err))
+	})
+	t.Run("invalid id", func(t *testing.T) {
+		_, err := query("0x01")
+		require.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+}
+
+func TestTransactionService_MempoolEvents(t *testing.T) {
+	events.InitializeReporter()
+	t.Cleanup(events.CloseEventReporter)
+	grpcService := NewTransactionService(sql.InMemory(), nil, meshAPIMock, nil, nil, nil)
+	t.Cleanup(launchServer(t, cfg, grpcService))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	conn := dialGrpc(ctx, t, cfg.PublicListener)
+	stream, err := conn.NewStream(ctx, &grpc.StreamDesc{ServerStreams: true}, mempoolEventsMethod)
+	require.NoError(t, err)
+	require.NoError(t, stream.SendMsg(&emptypb.Empty{}))
+	require.NoError(t, stream.CloseSend())
+	// header is sent after the subscription is created
+	_, err = stream.Header()
+	require.NoError(t, err)
+
+	tid := globalTx.ID
+	for _, tc := range []struct {
+		report   func()
+		expected MempoolEvent
+	}{
+		{
+			report: func() {
+				events.ReportTxAdmitted(events.TxAdmitted{ID: tid, Principal: globalTx.Principal, Nonce: 1, GasPrice: 2})
+			},
+			expected: MempoolEvent{Type: "admitted", ID: tid.String(), Principal: globalTx.Principal.String(), Nonce: 1, GasPrice: 2},
+		},
+		{
+			report: func() {
+				events.ReportTxIncludedInProposal(events.TxIncludedInProposal{ID: tid, Layer: 10, Proposal: types.ProposalID{1}})
+			},
+			expected: MempoolEvent{Type: "proposal", ID: tid.String(), Layer: 10, Proposal: types.ProposalID{1}.String()},
+		},
+		{
+			report: func() {
+				events.ReportTxIncludedInBlock(events.TxIncludedInBlock{ID: tid, Layer: 10, Block: types.BlockID{2}})
+			},
+			expected: MempoolEvent{Type: "block", ID: tid.String(), Layer: 10, Block: types.BlockID{2}.String()},
+		},
+		{
+			report: func() {
+				events.ReportTxEvicted(events.TxEvicted{ID: tid, Principal: globalTx.Principal, Nonce: 1, GasPrice: 2})
+			},
+			expected: MempoolEvent{Type: "evicted", ID: tid.String(), Principal: globalTx.Principal.String(), Nonce: 1, GasPrice: 2},
+		},
+		{
+			report: func() {
+				events.ReportTxExpired(events.TxExpired{ID: tid, Principal: globalTx.Principal, Nonce: 1, Expiry: 12})
+			},
+			expected: MempoolEvent{Type: "expired", ID: tid.String(), Principal: globalTx.Principal.String(), Nonce: 1, Layer: 12},
+		},
+	} {
+		tc.report()
+		msg := &wrapperspb.StringValue{}
+		require.NoError(t, stream.RecvMsg(msg))
+		var received MempoolEvent
+		require.NoError(t, json.Unmarshal([]byte(msg.Value), &received))
+		require.Equal(t, tc.expected, received)
+	}
+}
+
 func TestTransactionServiceSubmitInvalidTx(t *testing.T) {
 	logtest.SetupGlobal(t)
 	req := require.New(t)
@@ -1686,6 +2126,42 @@ func TestTransactionServiceSubmitInvalidTx(t *testing.T) {
 	req.Nil(res)
 }
 
+func TestTransactionServiceSubmitNotAdmitted(t *testing.T) {
+	logtest.SetupGlobal(t)
+	for _, tc := range []struct {
+		desc string
+		err  error
+		code codes.Code
+	}{
+		{desc: "balance", err: txs.ErrBalanceTooLow, code: codes.FailedPrecondition},
+		{desc: "nonce", err: txs.ErrNonceTooFar, code: codes.OutOfRange},
+		{desc: "pending", err: txs.ErrTooManyPending, code: codes.ResourceExhausted},
+	} {
+		tc := tc
+		t.Run(tc.desc, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			syncer := NewMocksyncer(ctrl)
+			syncer.EXPECT().IsSynced(gomock.Any()).Return(true)
+			publisher := pubsubmocks.NewMockPublisher(ctrl) // publish is not called
+			txHandler := NewMocktxValidator(ctrl)
+			txHandler.EXPECT().VerifyAndCacheTx(gomock.Any(), gomock.Any()).Return(fmt.Errorf("tx: %w", tc.err))
+
+			grpcService := NewTransactionService(sql.InMemory(), publisher, meshAPIMock, conStateAPI, syncer, txHandler)
+			t.Cleanup(launchServer(t, cfg, grpcService))
+
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+			conn := dialGrpc(ctx, t, cfg.PublicListener)
+			c := pb.NewTransactionServiceClient(conn)
+
+			serializedTx, err := codec.Encode(globalTx)
+			require.NoError(t, err)
+			_, err = c.SubmitTransaction(ctx, &pb.SubmitTransactionRequest{Transaction: serializedTx})
+			require.Equal(t, tc.code, status.Code(err))
+		})
+	}
+}
+
 func TestTransactionService_SubmitNoConcurrency(t *testing.T) {
 	logtest.SetupGlobal(t)
 
@@ -1872,6 +2348,41 @@ func TestTransactionService(t *testing.T) {
 			require.Equal(t, pb.TransactionState_TRANSACTION_STATE_PROCESSED, res.TransactionState.State)
 			checkTransaction(t, res.Transaction)
 		}},
+		{"TransactionsStateStream_Lifecycle", func(t *testing.T) {
+			logtest.SetupGlobal(t)
+			events.CloseEventReporter()
+			events.InitializeReporter()
+			t.Cleanup(events.CloseEventReporter)
+
+			req := &pb.TransactionsStateStreamRequest{}
+			req.TransactionId = append(req.TransactionId, &pb.TransactionId{
+				Id: globalTx.ID.Bytes(),
+			})
+
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+			stream, err := c.TransactionsStateStream(ctx, req)
+			require.NoError(t, err)
+			// Give the server-side time to subscribe to events
+			time.Sleep(time.Millisecond * 50)
+
+			for _, tc := range []struct {
+				state  types.TXState
+				expect pb.TransactionState_TransactionState
+			}{
+				{types.PROPOSAL, pb.TransactionState_TRANSACTION_STATE_MESH},
+				{types.BLOCK, pb.TransactionState_TRANSACTION_STATE_MESH},
+				{types.APPLIED, pb.TransactionState_TRANSACTION_STATE_PROCESSED},
+				{types.MEMPOOL, pb.TransactionState_TRANSACTION_STATE_MEMPOOL},
+			} {
+				events.ReportTxState(events.TxState{ID: types.RandomTransactionID(), State: tc.state})
+				events.ReportTxState(events.TxState{ID: globalTx.ID, State: tc.state, Layer: txReturnLayer})
+				res, err := stream.Recv()
+				require.NoError(t, err)
+				require.Equal(t, globalTx.ID.Bytes(), res.TransactionState.Id.Id)
+				require.Equal(t, tc.expect, res.TransactionState.State, tc.state)
+			}
+		}},
 		// Submit a tx, then receive it over the stream
 		{"TransactionsState_SubmitThenStream", func(t *testing.T) {
 			logtest.SetupGlobal(t)
@@ -2477,13 +2988,85 @@ func TestJsonApi(t *testing.T) {
 	require.Equal(t, uint64(genesis.Unix()), msg2.Unixtime.Value)
 }
 
+type opinionReporterFunc func(from, to types.LayerID) (*tortoise.OpinionReport, error)
+
+func (f opinionReporterFunc) OpinionReport(from, to types.LayerID) (*tortoise.OpinionReport, error) {
+	return f(from, to)
+}
+
+type votesEncoderFunc func(context.Context, types.LayerID) (*types.Opinion, *tortoise.VotesTrace, error)
+
+func (f votesEncoderFunc) EncodeVotesWithTrace(ctx context.Context, lid types.LayerID) (*types.Opinion, *tortoise.VotesTrace, error) {
+	return f(ctx, lid)
+}
+
+type debugTortoise struct {
+	opinionReporterFunc
+	votesEncoderFunc
+	state []byte
+}
+
+func (d debugTortoise) DumpState(w io.Writer) error {
+	_, err := w.Write(d.state)
+	return err
+}
+
+type hareResultsFunc func(context.Context, types.LayerID) ([]types.ProposalID, error)
+
+func (f hareResultsFunc) GetResult(ctx context.Context, lid types.LayerID) ([]types.ProposalID, error) {
+	return f(ctx, lid)
+}
+
 func TestDebugService(t *testing.T) {
 	logtest.SetupGlobal(t)
 	ctrl := gomock.NewController(t)
 	identity := NewMocknetworkIdentity(ctrl)
 	mOracle := NewMockoracle(ctrl)
 	db := sql.InMemory()
-	svc := NewDebugService(db, conStateAPI, identity, mOracle)
+	reporter := opinionReporterFunc(func(from, to types.LayerID) (*tortoise.OpinionReport, error) {
+		if to < from {
+			return nil, errors.New("invalid range")
+		}
+		report := &tortoise.OpinionReport{From: from, To: to}
+		for lid := from; lid <= to; lid++ {
+			report.Layers = append(report.Layers, tortoise.LayerOpinion{
+				Layer:    lid,
+				Verified: true,
+				Blocks: []tortoise.BlockOpinion{{
+					ID:       types.BlockID{byte(lid)},
+					Validity: "valid",
+					Source:   tortoise.DecidedByHare,
+				}},
+			})
+		}
+		return report, nil
+	})
+	opinion := &types.Opinion{Hash: types.Hash32{1}, Votes: types.Votes{Abstain: []types.LayerID{6}}}
+	votes := votesEncoderFunc(func(_ context.Context, lid types.LayerID) (*types.Opinion, *tortoise.VotesTrace, error) {
+		if lid == 0 {
+			return nil, &tortoise.VotesTrace{}, errors.New("no ballots within a sliding window")
+		}
+		return opinion, &tortoise.VotesTrace{
+			Layer:      lid,
+			Candidates: []tortoise.CandidateTrace{{Ballot: types.BallotID{1}, Layer: lid - 2, Selected: true}},
+		}, nil
+	})
+	trtl := debugTortoise{opinionReporterFunc: reporter, votesEncoderFunc: votes, state: []byte("tortoise state")}
+	signer, err := signing.NewEdSigner()
+	require.NoError(t, err)
+	results := hareResultsFunc(func(ctx context.Context, lid types.LayerID) ([]types.ProposalID, error) {
+		switch lid {
+		case 10:
+			return []types.ProposalID{{1}, {2}}, nil
+		case 11:
+			return nil, hare.ErrHareFailed
+		case 12:
+			return nil, hare.ErrHareNotRun
+		}
+		<-ctx.Done()
+		return nil, ctx.Err()
+	})
+	svc := NewDebugService(db, conStateAPI, identity, mOracle, trtl, results, signer)
 	t.Cleanup(launchServer(t, cfg, svc))
 
 	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
@@ -2556,6 +3139,100 @@ func TestDebugService(t *testing.T) {
 		}
 		require.ElementsMatch(t, activeSet, ids)
 	})
+	t.Run("OpinionReport", func(t *testing.T) {
+		c := debugv1.NewTortoiseClient(conn)
+		resp, err := c.OpinionReport(ctx, &debugv1.OpinionReportRequest{From: 10, To: 12})
+		require.NoError(t, err)
+		require.Len(t, resp.Layers, 3)
+		require.Empty(t, resp.Signature)
+
+		verifier, err := signing.NewEdVerifier()
+		require.NoError(t, err)
+		resp, err = c.OpinionReport(ctx, &debugv1.OpinionReportRequest{From: 10, To: 12, Sign: true})
+		require.NoError(t, err)
+		require.Equal(t, signer.NodeID().Bytes(), resp.NodeId)
+		report := decodeOpinionReport(resp)
+		require.True(t, report.Verify(verifier))
+		report.Layers[0].Blocks[0].Validity = "invalid"
+		require.False(t, report.Verify(verifier))
+
+		_, err = c.OpinionReport(ctx, &debugv1.OpinionReportRequest{From: 12, To: 10})
+		require.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+	t.Run("DumpState", func(t *testing.T) {
+		resp, err := debugv1.NewTortoiseClient(conn).DumpState(ctx, &emptypb.Empty{})
+		require.NoError(t, err)
+		require.Equal(t, trtl.state, resp.State)
+	})
+	t.Run("EncodeVotes", func(t *testing.T) {
+		c := debugv1.NewTortoiseClient(conn)
+		resp, err := c.EncodeVotes(ctx, &debugv1.EncodeVotesRequest{Layer: 7})
+		require.NoError(t, err)
+		require.True(t, proto.Equal(castOpinion(opinion), resp.Opinion))
+		require.Equal(t, opinion.Hash.Bytes(), resp.Opinion.Hash)
+		require.EqualValues(t, 7, resp.Trace.Layer)
+		require.Len(t, resp.Trace.Candidates, 1)
+		require.True(t, resp.Trace.Candidates[0].Selected)
+		require.Empty(t, resp.Error)
+
+		resp, err = c.EncodeVotes(ctx, &debugv1.EncodeVotesRequest{Layer: 0})
+		require.NoError(t, err)
+		require.Nil(t, resp.Opinion)
+		require.Equal(t, "no ballots within a sliding window", resp.Error)
+	})
+	t.Run("HareResult", func(t *testing.T) {
+		c := debugv1.NewHareClient(conn)
+		resp, err := c.Result(ctx, &debugv1.HareResultRequest{Layer: 10})
+		require.NoError(t, err)
+		require.EqualValues(t, 10, resp.Layer)
+		require.Equal(t, [][]byte{types.ProposalID{1}.Bytes(), types.ProposalID{2}.Bytes()}, resp.Proposals)
+
+		_, err = c.Result(ctx, &debugv1.HareResultRequest{Layer: 11})
+		require.Equal(t, codes.Aborted, status.Code(err))
+		_, err = c.Result(ctx, &debugv1.HareResultRequest{Layer: 12})
+		require.Equal(t, codes.NotFound, status.Code(err))
+
+		waitCtx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+		defer cancel()
+		_, err = c.Result(waitCtx, &debugv1.HareResultRequest{Layer: 13})
+		require.Equal(t, codes.DeadlineExceeded, status.Code(err))
+	})
+	t.Run("ValidityHistory", func(t *testing.T) {
+		id := types.RandomBlockID()
+		changes := []blocks.ValidityChange{
+			{Layer: 11, Old: blocks.Undecided, New: blocks.Valid, Source: blocks.SourceHare},
+			{Layer: 12, Old: blocks.Valid, New: blocks.Invalid, Source: blocks.SourceVerifying},
+		}
+		for _, change := range changes {
+			require.NoError(t, blocks.AddValidityChange(db, id, 10, change))
+		}
+		expected := &debugv1.ValidityHistoryResponse{
+			Block: id.Bytes(),
+			Changes: []*debugv1.ValidityChange{
+				{
+					Layer:  11,
+					Old:    debugv1.Validity_VALIDITY_UNDECIDED,
+					New:    debugv1.Validity_VALIDITY_VALID,
+					Source: debugv1.ValiditySource_VALIDITY_SOURCE_HARE,
+				},
+				{
+					Layer:  12,
+					Old:    debugv1.Validity_VALIDITY_VALID,
+					New:    debugv1.Validity_VALIDITY_INVALID,
+					Source: debugv1.ValiditySource_VALIDITY_SOURCE_VERIFYING,
+				},
+			},
+		}
+		c := debugv1.NewMeshClient(conn)
+		for _, req := range [][]byte{id[:], id.Bytes()} {
+			resp, err := c.ValidityHistory(ctx, &debugv1.ValidityHistoryRequest{Block: req})
+			require.NoError(t, err)
+			require.True(t, proto.Equal(expected, resp), resp)
+		}
+
+		_, err := c.ValidityHistory(ctx, &debugv1.ValidityHistoryRequest{Block: []byte{1}})
+		require.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
 	t.Run("ProposalsStream", func(t *testing.T) {
 		events.InitializeReporter()
 		t.Cleanup(events.CloseEventReporter)
@@ -2830,3 +3507,38 @@ func TestMeshService_EpochStream(t *testing.T) {
 	}
 	require.Equal(t, activesetSize, total)
 }
+
+// decodeOpinionReport restores the report from the response to verify the signature.
+func decodeOpinionReport(resp *debugv1.OpinionReportResponse) *tortoise.OpinionReport {
+	report := &tortoise.OpinionReport{From: types.LayerID(resp.From), To: types.LayerID(resp.To)}
+	for _, layer := range resp.Layers {
+		opinion := tortoise.LayerOpinion{
+			Layer:     types.LayerID(layer.Layer),
+			Verified:  layer.Verified,
+			Opinion:   types.BytesToHash(layer.Opinion),
+			Threshold: layer.Threshold,
+		}
+		for _, block := range layer.Blocks {
+			var id types.BlockID
+			copy(id[:], block.Id)
+			opinion.Blocks = append(opinion.Blocks, tortoise.BlockOpinion{
+				ID:       id,
+				Height:   block.Height,
+				Validity: block.Validity,
+				Source:   tortoise.DecisionSource(block.Source),
+				Hare:     block.Hare,
+				Margin:   block.Margin,
+				Relative: block.Relative,
+			})
+		}
+		report.Layers = append(report.Layers, opinion)
+	}
+	if len(resp.NodeId) > 0 {
+		id := types.BytesToNodeID(resp.NodeId)
+		var sig types.EdSignature
+		copy(sig[:], resp.Signature)
+		report.NodeID = &id
+		report.Signature = &sig
+	}
+	return report
+}
@@ -6,8 +6,11 @@ import (
 
 	"github.com/spacemeshos/go-spacemesh/activation"
 	"github.com/spacemeshos/go-spacemesh/common/types"
+	"github.com/spacemeshos/go-spacemesh/miner"
 	"github.com/spacemeshos/go-spacemesh/p2p"
+	"github.com/spacemeshos/go-spacemesh/signing"
 	"github.com/spacemeshos/go-spacemesh/system"
+	"github.com/spacemeshos/go-spacemesh/txs"
 )
 
 //go:generate mockgen -package=grpcserver -destination=./mocks.go -source=./interface.go
@@ -25,6 +28,8 @@ type conservativeState interface {
 	GetBalance(types.Address) (uint64, error)
 	GetNonce(types.Address) (types.Nonce, error)
 	GetProjection(types.Address) (uint64, uint64)
+	GetPending(types.Address) []txs.PendingTX
+	AccountPendingState(types.Address) (uint64, uint64, []types.TransactionID)
 	GetMeshTransaction(types.TransactionID) (*types.MeshTransaction, error)
 	GetMeshTransactions([]types.TransactionID) ([]*types.MeshTransaction, map[types.TransactionID]struct{})
 	GetTransactionsByAddress(types.LayerID, types.LayerID, types.Address) ([]*types.MeshTransaction, error)
@@ -59,6 +64,14 @@ type peerCounter interface {
 	PeerCount() uint64
 }
 
+// proposalBuilder provides the upcoming proposal eligibilities of the node, and builds proposals
+// without publishing them.
+type proposalBuilder interface {
+	Schedule() ([]miner.ScheduledProposal, error)
+	Identities() []signing.Signer
+	BuildDryRun(context.Context, types.LayerID, signing.Signer) (*types.Proposal, error)
+}
+
 // genesisTimeAPI is an API to get genesis time and current layer of the system.
 type genesisTimeAPI interface {
 	GenesisTime() time.Time
@@ -69,7 +82,8 @@ type genesisTimeAPI interface {
 type meshAPI interface {
 	EpochAtxs(types.EpochID) ([]types.ATXID, error)
 	GetATXs(context.Context, []types.ATXID) (map[types.ATXID]*types.VerifiedActivationTx, []types.ATXID)
-	GetLayer(types.LayerID) (*types.Layer, error)
+	IterateLayerBallots(types.LayerID, func(*types.Ballot) bool) error
+	IterateLayerBlocks(types.LayerID, func(*types.Block) bool) error
 	GetRewards(types.Address) ([]*types.Reward, error)
 	LatestLayer() types.LayerID
 	LatestLayerInState() types.LayerID
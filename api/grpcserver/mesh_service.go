@@ -121,14 +121,13 @@ func (s MeshService) getFilteredActivations(ctx context.Context, startLayer type
 	// See https://github.com/spacemeshos/go-spacemesh/issues/2064.
 	var atxids []types.ATXID
 	for l := startLayer; !l.After(s.mesh.LatestLayer()); l = l.Add(1) {
-		layer, err := s.mesh.GetLayer(l)
-		if layer == nil || err != nil {
-			return nil, status.Errorf(codes.Internal, "error retrieving layer data")
-		}
-		for _, b := range layer.Ballots() {
+		if err := s.mesh.IterateLayerBallots(l, func(b *types.Ballot) bool {
 			if b.EpochData != nil && b.ActiveSet != nil {
 				atxids = append(atxids, b.ActiveSet...)
 			}
+			return true
+		}); err != nil {
+			return nil, status.Errorf(codes.Internal, "error retrieving layer data")
 		}
 	}
 
@@ -292,30 +291,17 @@ func (s MeshService) readLayer(ctx context.Context, layerID types.LayerID, layer
 	// Save activations too
 	var activations []types.ATXID
 
-	// read layer blocks
-	layer, err := s.mesh.GetLayer(layerID)
-	// TODO: Be careful with how we handle missing layers here.
-	// A layer that's newer than the currentLayer (defined above)
-	// is clearly an input error. A missing layer that's older than
-	// lastValidLayer is clearly an internal error. A missing layer
-	// between these two is a gray area: do we define this as an
-	// internal or an input error? For now, all missing layers produce
-	// internal errors.
-	if err != nil {
-		log.With().Error("could not read layer from database", layerID, log.Err(err))
-		return nil, status.Errorf(codes.Internal, "error reading layer data")
-	}
-
-	// TODO add proposal data as needed.
-
-	for _, b := range layer.Blocks() {
+	// read layer blocks one at a time
+	var berr error
+	err := s.mesh.IterateLayerBlocks(layerID, func(b *types.Block) bool {
 		mtxs, missing := s.conState.GetMeshTransactions(b.TxIDs)
 		// TODO: Do we ever expect txs to be missing here?
 		// E.g., if this node has not synced/received them yet.
 		if len(missing) != 0 {
 			log.With().Error("could not find transactions from layer",
-				log.String("missing", fmt.Sprint(missing)), layer.Index())
-			return nil, status.Errorf(codes.Internal, "error retrieving tx data")
+				log.String("missing", fmt.Sprint(missing)), layerID)
+			berr = status.Errorf(codes.Internal, "error retrieving tx data")
+			return false
 		}
 
 		pbTxs := make([]*pb.Transaction, 0, len(mtxs))
@@ -326,12 +312,30 @@ func (s MeshService) readLayer(ctx context.Context, layerID types.LayerID, layer
 			Id:           types.Hash20(b.ID()).Bytes(),
 			Transactions: pbTxs,
 		})
+		return true
+	})
+	// TODO add proposal data as needed.
+	if err == nil {
+		err = s.mesh.IterateLayerBallots(layerID, func(b *types.Ballot) bool {
+			if b.EpochData != nil && b.ActiveSet != nil {
+				activations = append(activations, b.ActiveSet...)
+			}
+			return true
+		})
 	}
-
-	for _, b := range layer.Ballots() {
-		if b.EpochData != nil && b.ActiveSet != nil {
-			activations = append(activations, b.ActiveSet...)
-		}
+	// TODO: Be careful with how we handle missing layers here.
+	// A layer that's newer than the currentLayer (defined above)
+	// is clearly an input error. A missing layer that's older than
+	// lastValidLayer is clearly an internal error. A missing layer
+	// between these two is a gray area: do we define this as an
+	// internal or an input error? For now, all missing layers produce
+	// internal errors.
+	if err != nil {
+		log.With().Error("could not read layer from database", layerID, log.Err(err))
+		return nil, status.Errorf(codes.Internal, "error retrieving layer data")
+	}
+	if berr != nil {
+		return nil, berr
 	}
 
 	// Extract ATX data from block data
@@ -341,29 +345,29 @@ func (s MeshService) readLayer(ctx context.Context, layerID types.LayerID, layer
 	atxs, matxs := s.mesh.GetATXs(ctx, activations)
 	if len(matxs) != 0 {
 		log.With().Error("could not find activations from layer",
-			log.String("missing", fmt.Sprint(matxs)), layer.Index())
+			log.String("missing", fmt.Sprint(matxs)), layerID)
 		return nil, status.Errorf(codes.Internal, "error retrieving activations data")
 	}
 	for _, atx := range atxs {
 		pbActivations = append(pbActivations, convertActivation(atx))
 	}
 
-	stateRoot, err := s.conState.GetLayerStateRoot(layer.Index())
+	stateRoot, err := s.conState.GetLayerStateRoot(layerID)
 	if err != nil {
 		// This is expected. We can only retrieve state root for a layer that was applied to state,
 		// which only happens after it's approved/confirmed.
 		log.With().Debug("no state root for layer",
-			layer, log.String("status", layerStatus.String()), log.Err(err))
+			layerID, log.String("status", layerStatus.String()), log.Err(err))
 	}
 	hash, err := s.mesh.MeshHash(layerID)
 	if err != nil {
 		// This is expected. We can only retrieve state root for a layer that was applied to state,
 		// which only happens after it's approved/confirmed.
 		log.With().Debug("no mesh hash at layer",
-			layer, log.String("status", layerStatus.String()), log.Err(err))
+			layerID, log.String("status", layerStatus.String()), log.Err(err))
 	}
 	return &pb.Layer{
-		Number:        &pb.LayerNumber{Number: layer.Index().Uint32()},
+		Number:        &pb.LayerNumber{Number: layerID.Uint32()},
 		Status:        layerStatus,
 		Blocks:        blocks,
 		Activations:   pbActivations,
@@ -401,19 +405,6 @@ func (s MeshService) LayersQuery(ctx context.Context, in *pb.LayersQueryRequest)
 			layerStatus = pb.Layer_LAYER_STATUS_CONFIRMED
 		}
 
-		layer, err := s.mesh.GetLayer(l)
-		// TODO: Be careful with how we handle missing layers here.
-		// A layer that's newer than the currentLayer (defined above)
-		// is clearly an input error. A missing layer that's older than
-		// lastValidLayer is clearly an internal error. A missing layer
-		// between these two is a gray area: do we define this as an
-		// internal or an input error? For now, all missing layers produce
-		// internal errors.
-		if layer == nil || err != nil {
-			log.With().Error("error retrieving layer data", log.Err(err))
-			return nil, status.Errorf(codes.Internal, "error retrieving layer data")
-		}
-
 		pbLayer, err := s.readLayer(ctx, l, layerStatus)
 		if err != nil {
 			return nil, err
@@ -541,6 +532,10 @@ func (s MeshService) LayerStream(_ *pb.LayerStreamRequest, stream pb.MeshService
 			if err != nil {
 				return fmt.Errorf("read layer: %w", err)
 			}
+			// the root recorded when the layer was applied, the layer may be reverted by the time it is read
+			if layer.StateRoot != (types.Hash32{}) {
+				pbLayer.RootStateHash = layer.StateRoot.Bytes()
+			}
 
 			if err := stream.Send(&pb.LayerStreamResponse{Layer: pbLayer}); err != nil {
 				return fmt.Errorf("send to stream: %w", err)
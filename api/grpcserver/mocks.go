@@ -12,8 +12,11 @@ import (
 	gomock "github.com/golang/mock/gomock"
 	activation "github.com/spacemeshos/go-spacemesh/activation"
 	types "github.com/spacemeshos/go-spacemesh/common/types"
+	miner "github.com/spacemeshos/go-spacemesh/miner"
 	p2p "github.com/spacemeshos/go-spacemesh/p2p"
+	signing "github.com/spacemeshos/go-spacemesh/signing"
 	system "github.com/spacemeshos/go-spacemesh/system"
+	txs "github.com/spacemeshos/go-spacemesh/txs"
 )
 
 // MocknetworkIdentity is a mock of networkIdentity interface.
@@ -76,6 +79,22 @@ func (m *MockconservativeState) EXPECT() *MockconservativeStateMockRecorder {
 	return m.recorder
 }
 
+// AccountPendingState mocks base method.
+func (m *MockconservativeState) AccountPendingState(arg0 types.Address) (uint64, uint64, []types.TransactionID) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AccountPendingState", arg0)
+	ret0, _ := ret[0].(uint64)
+	ret1, _ := ret[1].(uint64)
+	ret2, _ := ret[2].([]types.TransactionID)
+	return ret0, ret1, ret2
+}
+
+// AccountPendingState indicates an expected call of AccountPendingState.
+func (mr *MockconservativeStateMockRecorder) AccountPendingState(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AccountPendingState", reflect.TypeOf((*MockconservativeState)(nil).AccountPendingState), arg0)
+}
+
 // GetAllAccounts mocks base method.
 func (m *MockconservativeState) GetAllAccounts() ([]*types.Account, error) {
 	m.ctrl.T.Helper()
@@ -166,6 +185,20 @@ func (mr *MockconservativeStateMockRecorder) GetNonce(arg0 interface{}) *gomock.
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetNonce", reflect.TypeOf((*MockconservativeState)(nil).GetNonce), arg0)
 }
 
+// GetPending mocks base method.
+func (m *MockconservativeState) GetPending(arg0 types.Address) []txs.PendingTX {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetPending", arg0)
+	ret0, _ := ret[0].([]txs.PendingTX)
+	return ret0
+}
+
+// GetPending indicates an expected call of GetPending.
+func (mr *MockconservativeStateMockRecorder) GetPending(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPending", reflect.TypeOf((*MockconservativeState)(nil).GetPending), arg0)
+}
+
 // GetProjection mocks base method.
 func (m *MockconservativeState) GetProjection(arg0 types.Address) (uint64, uint64) {
 	m.ctrl.T.Helper()
@@ -470,6 +503,73 @@ func (mr *MockpeerCounterMockRecorder) PeerCount() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PeerCount", reflect.TypeOf((*MockpeerCounter)(nil).PeerCount))
 }
 
+// MockproposalBuilder is a mock of proposalBuilder interface.
+type MockproposalBuilder struct {
+	ctrl     *gomock.Controller
+	recorder *MockproposalBuilderMockRecorder
+}
+
+// MockproposalBuilderMockRecorder is the mock recorder for MockproposalBuilder.
+type MockproposalBuilderMockRecorder struct {
+	mock *MockproposalBuilder
+}
+
+// NewMockproposalBuilder creates a new mock instance.
+func NewMockproposalBuilder(ctrl *gomock.Controller) *MockproposalBuilder {
+	mock := &MockproposalBuilder{ctrl: ctrl}
+	mock.recorder = &MockproposalBuilderMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockproposalBuilder) EXPECT() *MockproposalBuilderMockRecorder {
+	return m.recorder
+}
+
+// BuildDryRun mocks base method.
+func (m *MockproposalBuilder) BuildDryRun(arg0 context.Context, arg1 types.LayerID, arg2 signing.Signer) (*types.Proposal, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "BuildDryRun", arg0, arg1, arg2)
+	ret0, _ := ret[0].(*types.Proposal)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// BuildDryRun indicates an expected call of BuildDryRun.
+func (mr *MockproposalBuilderMockRecorder) BuildDryRun(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "BuildDryRun", reflect.TypeOf((*MockproposalBuilder)(nil).BuildDryRun), arg0, arg1, arg2)
+}
+
+// Identities mocks base method.
+func (m *MockproposalBuilder) Identities() []signing.Signer {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Identities")
+	ret0, _ := ret[0].([]signing.Signer)
+	return ret0
+}
+
+// Identities indicates an expected call of Identities.
+func (mr *MockproposalBuilderMockRecorder) Identities() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Identities", reflect.TypeOf((*MockproposalBuilder)(nil).Identities))
+}
+
+// Schedule mocks base method.
+func (m *MockproposalBuilder) Schedule() ([]miner.ScheduledProposal, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Schedule")
+	ret0, _ := ret[0].([]miner.ScheduledProposal)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Schedule indicates an expected call of Schedule.
+func (mr *MockproposalBuilderMockRecorder) Schedule() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Schedule", reflect.TypeOf((*MockproposalBuilder)(nil).Schedule))
+}
+
 // MockgenesisTimeAPI is a mock of genesisTimeAPI interface.
 type MockgenesisTimeAPI struct {
 	ctrl     *gomock.Controller
@@ -574,21 +674,6 @@ func (mr *MockmeshAPIMockRecorder) GetATXs(arg0, arg1 interface{}) *gomock.Call
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetATXs", reflect.TypeOf((*MockmeshAPI)(nil).GetATXs), arg0, arg1)
 }
 
-// GetLayer mocks base method.
-func (m *MockmeshAPI) GetLayer(arg0 types.LayerID) (*types.Layer, error) {
-	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetLayer", arg0)
-	ret0, _ := ret[0].(*types.Layer)
-	ret1, _ := ret[1].(error)
-	return ret0, ret1
-}
-
-// GetLayer indicates an expected call of GetLayer.
-func (mr *MockmeshAPIMockRecorder) GetLayer(arg0 interface{}) *gomock.Call {
-	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetLayer", reflect.TypeOf((*MockmeshAPI)(nil).GetLayer), arg0)
-}
-
 // GetRewards mocks base method.
 func (m *MockmeshAPI) GetRewards(arg0 types.Address) ([]*types.Reward, error) {
 	m.ctrl.T.Helper()
@@ -604,6 +689,34 @@ func (mr *MockmeshAPIMockRecorder) GetRewards(arg0 interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetRewards", reflect.TypeOf((*MockmeshAPI)(nil).GetRewards), arg0)
 }
 
+// IterateLayerBallots mocks base method.
+func (m *MockmeshAPI) IterateLayerBallots(arg0 types.LayerID, arg1 func(*types.Ballot) bool) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "IterateLayerBallots", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// IterateLayerBallots indicates an expected call of IterateLayerBallots.
+func (mr *MockmeshAPIMockRecorder) IterateLayerBallots(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IterateLayerBallots", reflect.TypeOf((*MockmeshAPI)(nil).IterateLayerBallots), arg0, arg1)
+}
+
+// IterateLayerBlocks mocks base method.
+func (m *MockmeshAPI) IterateLayerBlocks(arg0 types.LayerID, arg1 func(*types.Block) bool) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "IterateLayerBlocks", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// IterateLayerBlocks indicates an expected call of IterateLayerBlocks.
+func (mr *MockmeshAPIMockRecorder) IterateLayerBlocks(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IterateLayerBlocks", reflect.TypeOf((*MockmeshAPI)(nil).IterateLayerBlocks), arg0, arg1)
+}
+
 // LatestLayer mocks base method.
 func (m *MockmeshAPI) LatestLayer() types.LayerID {
 	m.ctrl.T.Helper()
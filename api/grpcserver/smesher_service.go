@@ -2,6 +2,8 @@ package grpcserver
 
 import (
 	"context"
+	"encoding/hex"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"time"
@@ -11,31 +13,312 @@ import (
 	"github.com/spacemeshos/post/config"
 	"google.golang.org/genproto/googleapis/rpc/code"
 	rpcstatus "google.golang.org/genproto/googleapis/rpc/status"
+	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
 
 	"github.com/spacemeshos/go-spacemesh/activation"
+	"github.com/spacemeshos/go-spacemesh/codec"
 	"github.com/spacemeshos/go-spacemesh/common/types"
+	"github.com/spacemeshos/go-spacemesh/events"
 	"github.com/spacemeshos/go-spacemesh/log"
+	"github.com/spacemeshos/go-spacemesh/signing"
 )
 
+// proposalScheduleMethod is served outside of the SmesherService, as it is not defined in the api.
+// Request is google.protobuf.Empty, response is the json encoded list of ScheduledProposal
+// as google.protobuf.StringValue.
+const proposalScheduleMethod = "/spacemesh.smesher.v1.Proposals/Schedule"
+
+// proposalBuildEventsMethod streams the lifecycle events of the proposal builder.
+// Request is google.protobuf.Empty, responses are the json encoded BuilderEventResponse
+// as google.protobuf.StringValue.
+const proposalBuildEventsMethod = "/spacemesh.smesher.v1.Proposals/BuildEvents"
+
+// proposalDryRunMethod builds the proposal without publishing it.
+// Request is the json encoded DryRunRequest as google.protobuf.StringValue, response is
+// the json encoded DryRunProposal as google.protobuf.StringValue.
+const proposalDryRunMethod = "/spacemesh.smesher.v1.Proposals/DryRun"
+
 // SmesherService exposes endpoints to manage smeshing.
 type SmesherService struct {
 	postSetupProvider postSetupProvider
 	smeshingProvider  activation.SmeshingProvider
+	builder           proposalBuilder
 
 	streamInterval time.Duration
 	postOpts       activation.PostSetupOpts
 }
 
+// ScheduledProposal is a layer where an identity of the node is eligible to build proposals.
+type ScheduledProposal struct {
+	NodeID string        `json:"node_id"`
+	Layer  types.LayerID `json:"layer"`
+	Time   time.Time     `json:"time"`
+	Count  int           `json:"count"`
+}
+
+// DryRunRequest requests the proposal the identity would build in the layer.
+type DryRunRequest struct {
+	Layer types.LayerID `json:"layer"`
+	// NodeID is the hex encoded id of the identity, the primary identity of the node is used if empty.
+	NodeID string `json:"node_id,omitempty"`
+}
+
+// DryRunProposal is the proposal the identity would build in the layer with the current state of the node.
+type DryRunProposal struct {
+	NodeID    string        `json:"node_id"`
+	Layer     types.LayerID `json:"layer"`
+	Proposal  string        `json:"proposal"`
+	Ballot    string        `json:"ballot"`
+	RefBallot string        `json:"ref_ballot,omitempty"`
+	// ActiveSetSize is set only for the reference ballot of the identity in the epoch.
+	ActiveSetSize int      `json:"active_set_size"`
+	Eligibilities int      `json:"eligibilities"`
+	Base          string   `json:"base"`
+	Support       int      `json:"support"`
+	Against       int      `json:"against"`
+	Abstain       int      `json:"abstain"`
+	MeshHash      string   `json:"mesh_hash"`
+	Transactions  []string `json:"transactions"`
+	// Size of the encoded proposal in bytes. Signatures have fixed size, therefore it is the same
+	// for the signed proposal.
+	Size int `json:"size"`
+}
+
+// BuilderEventResponse is a lifecycle event of the proposal builder.
+// Type is one of eligibility_computed, build_started, published and failed.
+type BuilderEventResponse struct {
+	Type     string        `json:"type"`
+	NodeID   string        `json:"node_id"`
+	Epoch    types.EpochID `json:"epoch,omitempty"`
+	Layer    types.LayerID `json:"layer,omitempty"`
+	Atx      string        `json:"atx,omitempty"`
+	Proposal string        `json:"proposal,omitempty"`
+	Count    int           `json:"count,omitempty"`
+	Reason   string        `json:"reason,omitempty"`
+	Error    string        `json:"error,omitempty"`
+}
+
+func castBuilderEvent(ev *events.BuilderEvent) BuilderEventResponse {
+	switch {
+	case ev.EligibilityComputed != nil:
+		e := ev.EligibilityComputed
+		return BuilderEventResponse{
+			Type:   "eligibility_computed",
+			NodeID: e.NodeID.String(),
+			Epoch:  e.Epoch,
+			Atx:    e.Atx.Hash32().String(),
+			Count:  e.Count,
+		}
+	case ev.ProposalBuildStarted != nil:
+		e := ev.ProposalBuildStarted
+		return BuilderEventResponse{
+			Type:   "build_started",
+			NodeID: e.NodeID.String(),
+			Layer:  e.Layer,
+			Count:  e.Count,
+		}
+	case ev.ProposalPublished != nil:
+		e := ev.ProposalPublished
+		return BuilderEventResponse{
+			Type:     "published",
+			NodeID:   e.NodeID.String(),
+			Layer:    e.Layer,
+			Proposal: e.Proposal.AsHash32().String(),
+		}
+	default:
+		e := ev.ProposalFailed
+		return BuilderEventResponse{
+			Type:   "failed",
+			NodeID: e.NodeID.String(),
+			Layer:  e.Layer,
+			Reason: e.Reason.String(),
+			Error:  e.Error,
+		}
+	}
+}
+
 // RegisterService registers this service with a grpc server instance.
 func (s SmesherService) RegisterService(server *Server) {
 	pb.RegisterSmesherServiceServer(server.GrpcServer, s)
+	server.GrpcServer.RegisterService(&grpc.ServiceDesc{
+		ServiceName: "spacemesh.smesher.v1.Proposals",
+		HandlerType: (*any)(nil),
+		Methods: []grpc.MethodDesc{{
+			MethodName: "Schedule",
+			Handler: func(_ any, ctx context.Context, dec func(any) error, _ grpc.UnaryServerInterceptor) (any, error) {
+				req := &emptypb.Empty{}
+				if err := dec(req); err != nil {
+					return nil, err
+				}
+				return s.ProposalSchedule(ctx, req)
+			},
+		}, {
+			MethodName: "DryRun",
+			Handler: func(_ any, ctx context.Context, dec func(any) error, _ grpc.UnaryServerInterceptor) (any, error) {
+				req := &wrapperspb.StringValue{}
+				if err := dec(req); err != nil {
+					return nil, err
+				}
+				return s.ProposalDryRun(ctx, req)
+			},
+		}},
+		Streams: []grpc.StreamDesc{{
+			StreamName:    "BuildEvents",
+			ServerStreams: true,
+			Handler: func(_ any, stream grpc.ServerStream) error {
+				req := &emptypb.Empty{}
+				if err := stream.RecvMsg(req); err != nil {
+					return err
+				}
+				return s.ProposalBuildEvents(req, stream)
+			},
+		}},
+	}, s)
 }
 
 // NewSmesherService creates a new grpc service using config data.
-func NewSmesherService(post postSetupProvider, smeshing activation.SmeshingProvider, streamInterval time.Duration, postOpts activation.PostSetupOpts) *SmesherService {
-	return &SmesherService{post, smeshing, streamInterval, postOpts}
+// Builder is optional, the proposal schedule and dry run are not served without it.
+func NewSmesherService(
+	post postSetupProvider,
+	smeshing activation.SmeshingProvider,
+	builder proposalBuilder,
+	streamInterval time.Duration,
+	postOpts activation.PostSetupOpts,
+) *SmesherService {
+	return &SmesherService{post, smeshing, builder, streamInterval, postOpts}
+}
+
+// ProposalBuildEvents streams the lifecycle events of the proposal builder, starting from the moment of subscription.
+func (s SmesherService) ProposalBuildEvents(_ *emptypb.Empty, stream grpc.ServerStream) error {
+	sub, err := events.Subscribe[events.BuilderEvent](events.WithBuffer(1000))
+	if err != nil {
+		return status.Errorf(codes.FailedPrecondition, err.Error())
+	}
+	defer sub.Close()
+	// send empty header after subscribing to the channel.
+	// this is optional but allows subscriber to wait until stream is fully initialized.
+	if err := stream.SendHeader(metadata.MD{}); err != nil {
+		return status.Errorf(codes.Unavailable, "can't send header")
+	}
+	for {
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case <-sub.Full():
+			return status.Errorf(codes.Canceled, "buffer is full")
+		case ev := <-sub.Out():
+			buf, err := json.Marshal(castBuilderEvent(&ev))
+			if err != nil {
+				return status.Errorf(codes.Internal, "encode event: %s", err.Error())
+			}
+			if err := stream.SendMsg(wrapperspb.String(string(buf))); err != nil {
+				return fmt.Errorf("send to stream: %w", err)
+			}
+		}
+	}
+}
+
+// ProposalSchedule returns the upcoming layers of the current epoch where the identities
+// of the node are eligible to build proposals.
+func (s SmesherService) ProposalSchedule(context.Context, *emptypb.Empty) (*wrapperspb.StringValue, error) {
+	if s.builder == nil {
+		return nil, status.Errorf(codes.FailedPrecondition, "proposal builder is not running")
+	}
+	schedule, err := s.builder.Schedule()
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, err.Error())
+	}
+	rst := make([]ScheduledProposal, 0, len(schedule))
+	for _, sp := range schedule {
+		rst = append(rst, ScheduledProposal{
+			NodeID: sp.NodeID.String(),
+			Layer:  sp.Layer,
+			Time:   sp.Time,
+			Count:  sp.Count,
+		})
+	}
+	data, err := json.Marshal(rst)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, err.Error())
+	}
+	return wrapperspb.String(string(data)), nil
+}
+
+// ProposalDryRun returns the proposal the identity would build in the layer with the current state of the node.
+// The proposal is neither signed nor published.
+func (s SmesherService) ProposalDryRun(ctx context.Context, in *wrapperspb.StringValue) (*wrapperspb.StringValue, error) {
+	if s.builder == nil {
+		return nil, status.Errorf(codes.FailedPrecondition, "proposal builder is not running")
+	}
+	var req DryRunRequest
+	if err := json.Unmarshal([]byte(in.Value), &req); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "decode request: %s", err.Error())
+	}
+	signer, err := s.identity(req.NodeID)
+	if err != nil {
+		return nil, err
+	}
+	p, err := s.builder.BuildDryRun(ctx, req.Layer, signer)
+	if err != nil {
+		return nil, status.Errorf(codes.FailedPrecondition, err.Error())
+	}
+	rst := DryRunProposal{
+		NodeID:        p.SmesherID.String(),
+		Layer:         p.Layer,
+		Proposal:      p.ID().AsHash32().String(),
+		Ballot:        p.Ballot.ID().AsHash32().String(),
+		ActiveSetSize: len(p.ActiveSet),
+		Eligibilities: len(p.EligibilityProofs),
+		Base:          p.Votes.Base.AsHash32().String(),
+		Support:       len(p.Votes.Support),
+		Against:       len(p.Votes.Against),
+		Abstain:       len(p.Votes.Abstain),
+		MeshHash:      p.MeshHash.String(),
+		Transactions:  make([]string, 0, len(p.TxIDs)),
+	}
+	if p.RefBallot != types.EmptyBallotID {
+		rst.RefBallot = p.RefBallot.AsHash32().String()
+	}
+	for _, tid := range p.TxIDs {
+		rst.Transactions = append(rst.Transactions, tid.Hash32().String())
+	}
+	encoded, err := codec.Encode(p)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "encode proposal: %s", err.Error())
+	}
+	rst.Size = len(encoded)
+	data, err := json.Marshal(rst)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, err.Error())
+	}
+	return wrapperspb.String(string(data)), nil
+}
+
+// identity returns the signer of the identity with the hex encoded id, or the primary identity if id is empty.
+func (s SmesherService) identity(id string) (signing.Signer, error) {
+	identities := s.builder.Identities()
+	if id == "" {
+		if len(identities) == 0 {
+			return nil, status.Errorf(codes.FailedPrecondition, "node has no identities")
+		}
+		return identities[0], nil
+	}
+	buf, err := hex.DecodeString(id)
+	if err != nil || len(buf) != types.NodeIDSize {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid node id %q", id)
+	}
+	nodeID := types.BytesToNodeID(buf)
+	for _, signer := range identities {
+		if signer.NodeID() == nodeID {
+			return signer, nil
+		}
+	}
+	return nil, status.Errorf(codes.NotFound, "identity %s is not operated by the node", id)
 }
 
 // IsSmeshing reports whether the node is smeshing.
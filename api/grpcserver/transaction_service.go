@@ -3,6 +3,7 @@ package grpcserver
 import (
 	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
@@ -10,9 +11,12 @@ import (
 	pb "github.com/spacemeshos/api/release/go/spacemesh/v1"
 	"google.golang.org/genproto/googleapis/rpc/code"
 	rpcstatus "google.golang.org/genproto/googleapis/rpc/status"
+	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
 
 	"github.com/spacemeshos/go-spacemesh/common/types"
 	"github.com/spacemeshos/go-spacemesh/events"
@@ -21,8 +25,97 @@ import (
 	"github.com/spacemeshos/go-spacemesh/p2p/pubsub"
 	"github.com/spacemeshos/go-spacemesh/sql"
 	"github.com/spacemeshos/go-spacemesh/sql/transactions"
+	"github.com/spacemeshos/go-spacemesh/txs"
 )
 
+// mempoolPendingMethod is served outside of the TransactionService, as it is not defined in the api.
+// Request is the bech32 encoded address as google.protobuf.StringValue, response is the json encoded
+// PendingTransactions as google.protobuf.StringValue.
+const mempoolPendingMethod = "/spacemesh.transaction.v1.Mempool/Pending"
+
+// mempoolAccountStateMethod is served the same way as mempoolPendingMethod, response is
+// the json encoded AccountPendingState.
+const mempoolAccountStateMethod = "/spacemesh.transaction.v1.Mempool/AccountState"
+
+// mempoolTxStateMethod is served the same way as mempoolPendingMethod, request is the hex encoded
+// transaction id, response is the json encoded TransactionLifecycle.
+const mempoolTxStateMethod = "/spacemesh.transaction.v1.Mempool/TransactionState"
+
+// mempoolEventsMethod streams the json encoded MempoolEvent as google.protobuf.StringValue,
+// starting from the moment of subscription. Request is google.protobuf.Empty.
+const mempoolEventsMethod = "/spacemesh.transaction.v1.Mempool/Events"
+
+// Types of the mempool events.
+const (
+	mempoolAdmitted   = "admitted"
+	mempoolEvicted    = "evicted"
+	mempoolExpired    = "expired"
+	mempoolInProposal = "proposal"
+	mempoolInBlock    = "block"
+)
+
+// Labels of the pending transaction state.
+const (
+	pendingReady  = "ready"
+	pendingParked = "parked"
+)
+
+// PendingTransaction is the transaction of the account in the mempool.
+type PendingTransaction struct {
+	ID          string        `json:"id"`
+	Nonce       uint64        `json:"nonce"`
+	Fee         uint64        `json:"fee"`
+	MaxSpending uint64        `json:"max_spending"`
+	Layer       types.LayerID `json:"layer,omitempty"`
+	// State is "ready" if the transaction can be selected for proposals, or "parked"
+	// if it waits for the missing nonce.
+	State string `json:"state"`
+}
+
+// PendingTransactions are the transactions of the account in the mempool, ordered by nonce.
+type PendingTransactions struct {
+	Address string `json:"address"`
+	// NextNonce is the projected nonce of the account, including pending transactions.
+	NextNonce    uint64               `json:"next_nonce"`
+	Transactions []PendingTransaction `json:"transactions"`
+}
+
+// AccountPendingState is the state of the account projected over its ready transactions in the mempool.
+type AccountPendingState struct {
+	Address string `json:"address"`
+	// NextNonce is the next nonce usable by the account.
+	NextNonce uint64 `json:"next_nonce"`
+	// Balance is the balance spendable by the next transaction of the account.
+	Balance uint64 `json:"balance"`
+	// Pending are the ids of the pending transactions of the account, ordered by nonce.
+	Pending []string `json:"pending"`
+}
+
+// TransactionLifecycle is the state of the transaction known to the node.
+type TransactionLifecycle struct {
+	ID string `json:"id"`
+	// State is one of "mempool", "proposal", "block" or "applied".
+	State string `json:"state"`
+	// Layer is the layer of the earliest proposal or block that includes the transaction,
+	// or the layer where it was applied.
+	Layer types.LayerID `json:"layer,omitempty"`
+	Block string        `json:"block,omitempty"`
+}
+
+// MempoolEvent is the change of the mempool: the transaction was admitted, evicted, expired,
+// or included in a proposal or a block. Layer of the expired transaction is its expiry layer.
+type MempoolEvent struct {
+	// Type is one of "admitted", "evicted", "expired", "proposal" or "block".
+	Type      string        `json:"type"`
+	ID        string        `json:"id"`
+	Principal string        `json:"principal,omitempty"`
+	Nonce     uint64        `json:"nonce,omitempty"`
+	GasPrice  uint64        `json:"gas_price,omitempty"`
+	Layer     types.LayerID `json:"layer,omitempty"`
+	Proposal  string        `json:"proposal,omitempty"`
+	Block     string        `json:"block,omitempty"`
+}
+
 // TransactionService exposes transaction data, and a submit tx endpoint.
 type TransactionService struct {
 	db        *sql.Database
@@ -36,6 +129,49 @@ type TransactionService struct {
 // RegisterService registers this service with a grpc server instance.
 func (s TransactionService) RegisterService(server *Server) {
 	pb.RegisterTransactionServiceServer(server.GrpcServer, s)
+	server.GrpcServer.RegisterService(&grpc.ServiceDesc{
+		ServiceName: "spacemesh.transaction.v1.Mempool",
+		HandlerType: (*any)(nil),
+		Methods: []grpc.MethodDesc{{
+			MethodName: "Pending",
+			Handler: func(_ any, ctx context.Context, dec func(any) error, _ grpc.UnaryServerInterceptor) (any, error) {
+				req := &wrapperspb.StringValue{}
+				if err := dec(req); err != nil {
+					return nil, err
+				}
+				return s.MempoolPending(ctx, req)
+			},
+		}, {
+			MethodName: "AccountState",
+			Handler: func(_ any, ctx context.Context, dec func(any) error, _ grpc.UnaryServerInterceptor) (any, error) {
+				req := &wrapperspb.StringValue{}
+				if err := dec(req); err != nil {
+					return nil, err
+				}
+				return s.MempoolAccountState(ctx, req)
+			},
+		}, {
+			MethodName: "TransactionState",
+			Handler: func(_ any, ctx context.Context, dec func(any) error, _ grpc.UnaryServerInterceptor) (any, error) {
+				req := &wrapperspb.StringValue{}
+				if err := dec(req); err != nil {
+					return nil, err
+				}
+				return s.MempoolTransactionState(ctx, req)
+			},
+		}},
+		Streams: []grpc.StreamDesc{{
+			StreamName:    "Events",
+			ServerStreams: true,
+			Handler: func(_ any, stream grpc.ServerStream) error {
+				req := &emptypb.Empty{}
+				if err := stream.RecvMsg(req); err != nil {
+					return err
+				}
+				return s.MempoolEvents(req, stream)
+			},
+		}},
+	}, s)
 }
 
 // NewTransactionService creates a new grpc service using config data.
@@ -78,6 +214,20 @@ func (s TransactionService) ParseTransaction(ctx context.Context, in *pb.ParseTr
 	return &pb.ParseTransactionResponse{Tx: castTransaction(&tx)}, nil
 }
 
+// admissionCode distinguishes transactions that may be accepted later from the invalid ones.
+func admissionCode(err error) codes.Code {
+	switch {
+	case errors.Is(err, txs.ErrBalanceTooLow):
+		return codes.FailedPrecondition
+	case errors.Is(err, txs.ErrNonceTooFar):
+		return codes.OutOfRange
+	case errors.Is(err, txs.ErrTooManyPending):
+		return codes.ResourceExhausted
+	default:
+		return codes.InvalidArgument
+	}
+}
+
 // SubmitTransaction allows a new tx to be submitted.
 func (s TransactionService) SubmitTransaction(ctx context.Context, in *pb.SubmitTransactionRequest) (*pb.SubmitTransactionResponse, error) {
 	if len(in.Transaction) == 0 {
@@ -89,7 +239,7 @@ func (s TransactionService) SubmitTransaction(ctx context.Context, in *pb.Submit
 	}
 
 	if err := s.txHandler.VerifyAndCacheTx(ctx, in.Transaction); err != nil {
-		return nil, status.Error(codes.InvalidArgument, fmt.Sprintf("Failed to verify transaction: %s", err.Error()))
+		return nil, status.Error(admissionCode(err), fmt.Sprintf("Failed to verify transaction: %s", err.Error()))
 	}
 
 	if err := s.publisher.Publish(ctx, pubsub.TxProtocol, in.Transaction); err != nil {
@@ -106,23 +256,211 @@ func (s TransactionService) SubmitTransaction(ctx context.Context, in *pb.Submit
 	}, nil
 }
 
-// Get transaction and status for a given txid. It's not an error if we cannot find the tx,
-// we just return all nils.
-func (s TransactionService) getTransactionAndStatus(txID types.TransactionID) (*types.Transaction, pb.TransactionState_TransactionState) {
-	var state pb.TransactionState_TransactionState
-	tx, err := s.conState.GetMeshTransaction(txID)
+// MempoolPending returns the transactions of the account in the mempool, labeled as ready or parked.
+func (s TransactionService) MempoolPending(_ context.Context, in *wrapperspb.StringValue) (*wrapperspb.StringValue, error) {
+	addr, err := types.StringToAddress(in.Value)
 	if err != nil {
-		return nil, state
+		return nil, status.Errorf(codes.InvalidArgument, "invalid address: %s", err.Error())
+	}
+	nonce, _ := s.conState.GetProjection(addr)
+	rst := PendingTransactions{
+		Address:      addr.String(),
+		NextNonce:    nonce,
+		Transactions: []PendingTransaction{},
+	}
+	for _, tx := range s.conState.GetPending(addr) {
+		state := pendingReady
+		if tx.Parked {
+			state = pendingParked
+		}
+		rst.Transactions = append(rst.Transactions, PendingTransaction{
+			ID:          tx.ID.String(),
+			Nonce:       tx.Nonce,
+			Fee:         tx.Fee(),
+			MaxSpending: tx.MaxSpending(),
+			Layer:       tx.Layer,
+			State:       state,
+		})
+	}
+	data, err := json.Marshal(rst)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, err.Error())
+	}
+	return wrapperspb.String(string(data)), nil
+}
+
+// MempoolAccountState returns the next nonce and the balance of the account projected over
+// its ready transactions in the mempool.
+func (s TransactionService) MempoolAccountState(_ context.Context, in *wrapperspb.StringValue) (*wrapperspb.StringValue, error) {
+	addr, err := types.StringToAddress(in.Value)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid address: %s", err.Error())
+	}
+	nonce, balance, pending := s.conState.AccountPendingState(addr)
+	rst := AccountPendingState{
+		Address:   addr.String(),
+		NextNonce: nonce,
+		Balance:   balance,
+		Pending:   make([]string, 0, len(pending)),
+	}
+	for _, tid := range pending {
+		rst.Pending = append(rst.Pending, tid.String())
+	}
+	data, err := json.Marshal(rst)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, err.Error())
+	}
+	return wrapperspb.String(string(data)), nil
+}
+
+// MempoolTransactionState returns the lifecycle state of the transaction.
+func (s TransactionService) MempoolTransactionState(_ context.Context, in *wrapperspb.StringValue) (*wrapperspb.StringValue, error) {
+	var hash types.Hash32
+	if err := hash.UnmarshalText([]byte(in.Value)); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid transaction id: %s", err.Error())
+	}
+	tid := types.TransactionID(hash)
+	tx, err := s.conState.GetMeshTransaction(tid)
+	if errors.Is(err, sql.ErrNotFound) {
+		return nil, status.Errorf(codes.NotFound, "transaction %s not found", tid)
+	} else if err != nil {
+		return nil, status.Errorf(codes.Internal, err.Error())
 	}
-	switch tx.State {
+	rst := TransactionLifecycle{
+		ID:    tid.String(),
+		State: tx.State.String(),
+		Layer: tx.LayerID,
+	}
+	if tx.BlockID != types.EmptyBlockID {
+		rst.Block = tx.BlockID.String()
+	}
+	data, err := json.Marshal(rst)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, err.Error())
+	}
+	return wrapperspb.String(string(data)), nil
+}
+
+// MempoolEvents streams the admissions, evictions, expirations and inclusions of the mempool transactions,
+// starting from the moment of subscription.
+func (s TransactionService) MempoolEvents(_ *emptypb.Empty, stream grpc.ServerStream) error {
+	admitted, err := events.Subscribe[events.TxAdmitted](events.WithBuffer(1000))
+	if err != nil {
+		return status.Errorf(codes.FailedPrecondition, err.Error())
+	}
+	defer admitted.Close()
+	evicted, err := events.Subscribe[events.TxEvicted](events.WithBuffer(1000))
+	if err != nil {
+		return status.Errorf(codes.FailedPrecondition, err.Error())
+	}
+	defer evicted.Close()
+	expired, err := events.Subscribe[events.TxExpired](events.WithBuffer(1000))
+	if err != nil {
+		return status.Errorf(codes.FailedPrecondition, err.Error())
+	}
+	defer expired.Close()
+	proposed, err := events.Subscribe[events.TxIncludedInProposal](events.WithBuffer(1000))
+	if err != nil {
+		return status.Errorf(codes.FailedPrecondition, err.Error())
+	}
+	defer proposed.Close()
+	blocked, err := events.Subscribe[events.TxIncludedInBlock](events.WithBuffer(1000))
+	if err != nil {
+		return status.Errorf(codes.FailedPrecondition, err.Error())
+	}
+	defer blocked.Close()
+	// send empty header after subscribing to the channels.
+	// this is optional but allows subscriber to wait until stream is fully initialized.
+	if err := stream.SendHeader(metadata.MD{}); err != nil {
+		return status.Errorf(codes.Unavailable, "can't send header")
+	}
+	for {
+		var ev MempoolEvent
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case <-admitted.Full():
+			return status.Errorf(codes.Canceled, "buffer is full")
+		case <-evicted.Full():
+			return status.Errorf(codes.Canceled, "buffer is full")
+		case <-expired.Full():
+			return status.Errorf(codes.Canceled, "buffer is full")
+		case <-proposed.Full():
+			return status.Errorf(codes.Canceled, "buffer is full")
+		case <-blocked.Full():
+			return status.Errorf(codes.Canceled, "buffer is full")
+		case tx := <-admitted.Out():
+			ev = MempoolEvent{
+				Type:      mempoolAdmitted,
+				ID:        tx.ID.String(),
+				Principal: tx.Principal.String(),
+				Nonce:     tx.Nonce,
+				GasPrice:  tx.GasPrice,
+			}
+		case tx := <-evicted.Out():
+			ev = MempoolEvent{
+				Type:      mempoolEvicted,
+				ID:        tx.ID.String(),
+				Principal: tx.Principal.String(),
+				Nonce:     tx.Nonce,
+				GasPrice:  tx.GasPrice,
+			}
+		case tx := <-expired.Out():
+			ev = MempoolEvent{
+				Type:      mempoolExpired,
+				ID:        tx.ID.String(),
+				Principal: tx.Principal.String(),
+				Nonce:     tx.Nonce,
+				Layer:     tx.Expiry,
+			}
+		case tx := <-proposed.Out():
+			ev = MempoolEvent{
+				Type:     mempoolInProposal,
+				ID:       tx.ID.String(),
+				Layer:    tx.Layer,
+				Proposal: tx.Proposal.String(),
+			}
+		case tx := <-blocked.Out():
+			ev = MempoolEvent{
+				Type:  mempoolInBlock,
+				ID:    tx.ID.String(),
+				Layer: tx.Layer,
+				Block: tx.Block.String(),
+			}
+		}
+		buf, err := json.Marshal(ev)
+		if err != nil {
+			return status.Errorf(codes.Internal, "encode event: %s", err.Error())
+		}
+		if err := stream.SendMsg(wrapperspb.String(string(buf))); err != nil {
+			return fmt.Errorf("send to stream: %w", err)
+		}
+	}
+}
+
+// castTxState maps the lifecycle state of the transaction to the api state.
+// Transactions included in proposals and blocks are reported as submitted to the mesh.
+func castTxState(state types.TXState) pb.TransactionState_TransactionState {
+	switch state {
 	case types.MEMPOOL:
-		state = pb.TransactionState_TRANSACTION_STATE_MEMPOOL
+		return pb.TransactionState_TRANSACTION_STATE_MEMPOOL
+	case types.PROPOSAL, types.BLOCK:
+		return pb.TransactionState_TRANSACTION_STATE_MESH
 	case types.APPLIED:
-		state = pb.TransactionState_TRANSACTION_STATE_PROCESSED
+		return pb.TransactionState_TRANSACTION_STATE_PROCESSED
 	default:
-		state = pb.TransactionState_TRANSACTION_STATE_UNSPECIFIED
+		return pb.TransactionState_TRANSACTION_STATE_UNSPECIFIED
 	}
-	return &tx.Transaction, state
+}
+
+// Get transaction and status for a given txid. It's not an error if we cannot find the tx,
+// we just return all nils.
+func (s TransactionService) getTransactionAndStatus(txID types.TransactionID) (*types.Transaction, pb.TransactionState_TransactionState) {
+	tx, err := s.conState.GetMeshTransaction(txID)
+	if err != nil {
+		return nil, pb.TransactionState_TRANSACTION_STATE_UNSPECIFIED
+	}
+	return &tx.Transaction, castTxState(tx.State)
 }
 
 // TransactionsState returns current tx data for one or more txs.
@@ -167,10 +505,13 @@ func (s TransactionService) TransactionsStateStream(in *pb.TransactionsStateStre
 
 	// The tx channel tells us about newly received and newly created transactions
 	// The layer channel tells us about status updates
+	// The state channel tells us about transactions moving between proposals, blocks and the state
 	var (
 		txCh                    <-chan events.Transaction
 		layerCh                 <-chan events.LayerUpdate
+		stateCh                 <-chan events.TxState
 		txBufFull, layerBufFull <-chan struct{}
+		stateBufFull            <-chan struct{}
 	)
 
 	if txsSubscription := events.SubscribeTxs(); txsSubscription != nil {
@@ -181,12 +522,43 @@ func (s TransactionService) TransactionsStateStream(in *pb.TransactionsStateStre
 		layerCh, layerBufFull = consumeEvents[events.LayerUpdate](stream.Context(), layersSubscription)
 	}
 
+	if statesSubscription := events.SubscribeTxStates(); statesSubscription != nil {
+		stateCh, stateBufFull = consumeEvents[events.TxState](stream.Context(), statesSubscription)
+	}
+
 	if err := stream.SendHeader(metadata.MD{}); err != nil {
 		return status.Errorf(codes.Unavailable, "can't send header")
 	}
 
 	for {
 		select {
+		case <-stateBufFull:
+			log.Info("tx state buffer is full, shutting down")
+			return status.Error(codes.Canceled, errTxStateBufferFull)
+		case ev := <-stateCh:
+			for _, txid := range in.TransactionId {
+				if !bytes.Equal(ev.ID.Bytes(), txid.Id) {
+					continue
+				}
+				res := &pb.TransactionsStateStreamResponse{
+					TransactionState: &pb.TransactionState{
+						Id:    txid,
+						State: castTxState(ev.State),
+					},
+				}
+				if in.IncludeTransactions {
+					tx, err := s.conState.GetMeshTransaction(ev.ID)
+					if err != nil {
+						log.With().Error("could not find transaction", ev.ID, log.Err(err))
+						return status.Error(codes.Internal, "error retrieving tx data")
+					}
+					res.Transaction = castTransaction(&tx.Transaction)
+				}
+				if err := stream.Send(res); err != nil {
+					return fmt.Errorf("send stream: %w", err)
+				}
+				break
+			}
 		case <-txBufFull:
 			log.Info("tx buffer is full, shutting down")
 			return status.Error(codes.Canceled, errTxBufferFull)
@@ -230,15 +602,9 @@ func (s TransactionService) TransactionsStateStream(in *pb.TransactionsStateStre
 			// TODO: this is inefficient, come up with a more optimal way of doing this
 			// TODO: tx status should depend upon block status, not layer status
 
-			// In order to read transactions, we first need to read layer blocks
-			layerObj, err := s.mesh.GetLayer(layer.LayerID)
-			if err != nil {
-				log.With().Error("error reading layer data for updated layer", layer.LayerID, log.Err(err))
-				return status.Error(codes.Internal, "error reading layer data")
-			}
-
-			// Filter for any matching transactions in the reported layer
-			for _, b := range layerObj.Blocks() {
+			// Filter for any matching transactions in the reported layer, reading layer blocks one at a time
+			var serr error
+			if err := s.mesh.IterateLayerBlocks(layer.LayerID, func(b *types.Block) bool {
 				blockTXIDSet := make(map[types.TransactionID]struct{})
 
 				// create a set for the block transaction IDs
@@ -277,17 +643,26 @@ func (s TransactionService) TransactionsStateStream(in *pb.TransactionsStateStre
 							tx, err := s.conState.GetMeshTransaction(txid)
 							if err != nil {
 								log.Error("could not find transaction %v from layer %v: %v", txid, layer, err)
-								return status.Error(codes.Internal, "error retrieving tx data")
+								serr = status.Error(codes.Internal, "error retrieving tx data")
+								return false
 							}
 
 							res.Transaction = castTransaction(&tx.Transaction)
 						}
 
 						if err := stream.Send(res); err != nil {
-							return fmt.Errorf("send stream: %w", err)
+							serr = fmt.Errorf("send stream: %w", err)
+							return false
 						}
 					}
 				}
+				return true
+			}); err != nil {
+				log.With().Error("error reading layer data for updated layer", layer.LayerID, log.Err(err))
+				return status.Error(codes.Internal, "error reading layer data")
+			}
+			if serr != nil {
+				return serr
 			}
 		case <-stream.Context().Done():
 			return nil
@@ -90,6 +90,14 @@ func WithConfig(cfg Config) Opt {
 	}
 }
 
+// WithSigner adds a local identity that proposes, votes and makes weak coin proposals in the protocol
+// along with the primary identity. The vrf signer must belong to the same identity.
+func WithSigner(signer signing.Signer, vrf vrfSigner) Opt {
+	return func(pd *ProtocolDriver) {
+		pd.participants = append(pd.participants, participant{signer: signer, vrf: vrf})
+	}
+}
+
 func withWeakCoin(wc coin) Opt {
 	return func(pd *ProtocolDriver) {
 		pd.weakCoin = wc
@@ -106,7 +114,7 @@ func withNonceFetcher(nf nonceFetcher) Opt {
 func New(
 	nodeID types.NodeID,
 	publisher pubsub.Publisher,
-	edSigner *signing.EdSigner,
+	edSigner signing.Signer,
 	edVerifier *signing.EdVerifier,
 	vrfSigner vrfSigner,
 	vrfVerifier vrfVerifier,
@@ -120,9 +128,7 @@ func New(
 		config:         DefaultConfig(),
 		nodeID:         nodeID,
 		publisher:      publisher,
-		edSigner:       edSigner,
 		edVerifier:     edVerifier,
-		vrfSigner:      vrfSigner,
 		vrfVerifier:    vrfVerifier,
 		cdb:            cdb,
 		clock:          clock,
@@ -134,6 +140,7 @@ func New(
 	for _, opt := range opts {
 		opt(pd)
 	}
+	pd.participants = append([]participant{{signer: edSigner, vrf: vrfSigner}}, pd.participants...)
 	pd.msgTimes = &messageTimes{
 		clock: clock,
 		conf:  pd.config,
@@ -146,17 +153,29 @@ func New(
 	}
 
 	if pd.weakCoin == nil {
-		pd.weakCoin = weakcoin.New(pd.publisher, vrfSigner, vrfVerifier, pd.nonceFetcher, pd,
-			pd.msgTimes,
+		coinOpts := []weakcoin.OptionFunc{
 			weakcoin.WithLog(pd.logger.WithName("weakCoin")),
 			weakcoin.WithMaxRound(pd.config.RoundsNumber),
-		)
+			// the proposals are delayed in proportion to their value, so that the larger ones aren't published
+			// once the smallest one is received
+			weakcoin.WithPublishDelay(pd.config.WeakCoinRoundDuration / 2),
+		}
+		for _, p := range pd.participants[1:] {
+			coinOpts = append(coinOpts, weakcoin.WithSigner(p.vrf))
+		}
+		pd.weakCoin = weakcoin.New(pd.publisher, vrfSigner, vrfVerifier, pd.nonceFetcher, pd, pd.msgTimes, coinOpts...)
 	}
 
 	pd.metricsCollector = metrics.NewBeaconMetricsCollector(pd.gatherMetricsData, pd.logger.WithName("metrics"))
 	return pd
 }
 
+// participant is a local identity that takes part in the protocol.
+type participant struct {
+	signer signing.Signer
+	vrf    vrfSigner
+}
+
 // ProtocolDriver is the driver for the beacon protocol.
 type ProtocolDriver struct {
 	inProtocol uint64
@@ -170,9 +189,8 @@ type ProtocolDriver struct {
 	nodeID       types.NodeID
 	sync         system.SyncStateProvider
 	publisher    pubsub.Publisher
-	edSigner     *signing.EdSigner
+	participants []participant // the local identities, starting with the primary one
 	edVerifier   *signing.EdVerifier
-	vrfSigner    vrfSigner
 	vrfVerifier  vrfVerifier
 	nonceFetcher nonceFetcher
 	weakCoin     coin
@@ -538,8 +556,7 @@ func (pd *ProtocolDriver) initEpochStateIfNotPresent(logger log.Log, epoch types
 	var (
 		epochWeight uint64
 		miners      = make(map[types.NodeID]types.ATXID)
-		active      bool
-		nonce       *types.VRFPostIndex
+		nonces      = make(map[types.NodeID]types.VRFPostIndex)
 		// w1 is the weight units at δ before the end of the previous epoch, used to calculate `thresholdStrict`
 		// w2 is the weight units at the end of the previous epoch, used to calculate `threshold`
 		w1, w2 int
@@ -560,9 +577,6 @@ func (pd *ProtocolDriver) initEpochStateIfNotPresent(logger log.Log, epoch types
 				header.ID,
 				log.Stringer("smesher", header.NodeID))
 		}
-		if header.NodeID == pd.nodeID {
-			active = true
-		}
 		return true
 	}); err != nil {
 		return nil, err
@@ -573,16 +587,20 @@ func (pd *ProtocolDriver) initEpochStateIfNotPresent(logger log.Log, epoch types
 		return nil, errZeroEpochWeight
 	}
 
-	if active {
-		nnc, err := pd.nonceFetcher.VRFNonce(pd.nodeID, epoch)
+	for _, p := range pd.participants {
+		nodeID := p.signer.NodeID()
+		if _, ok := miners[nodeID]; !ok {
+			continue
+		}
+		nnc, err := pd.nonceFetcher.VRFNonce(nodeID, epoch)
 		if err != nil {
-			logger.With().Error("failed to get own VRF nonce", log.Err(err))
-			return nil, fmt.Errorf("get own VRF nonce: %w", err)
+			logger.With().Error("failed to get own VRF nonce", log.Stringer("smesher", nodeID), log.Err(err))
+			return nil, fmt.Errorf("get own VRF nonce %v: %w", nodeID.ShortString(), err)
 		}
-		nonce = &nnc
+		nonces[nodeID] = nnc
 	}
 	checker := createProposalChecker(logger, pd.config, w1, w1+w2)
-	pd.states[epoch] = newState(logger, pd.config, nonce, epochWeight, miners, checker)
+	pd.states[epoch] = newState(logger, pd.config, nonces, epochWeight, miners, checker)
 	return pd.states[epoch], nil
 }
 
@@ -726,7 +744,7 @@ func (pd *ProtocolDriver) runProtocol(ctx context.Context, epoch types.EpochID,
 		logger.With().Warning("proposal phase failed", log.Err(err))
 		return
 	}
-	lastRoundOwnVotes, err := pd.runConsensusPhase(ctx, epoch, st.nonce)
+	lastRoundOwnVotes, err := pd.runConsensusPhase(ctx, epoch, st.nonces)
 	if err != nil {
 		logger.With().Warning("consensus phase failed", log.Err(err))
 		return
@@ -773,9 +791,14 @@ func (pd *ProtocolDriver) runProposalPhase(ctx context.Context, epoch types.Epoc
 	ctx, cancel = context.WithTimeout(ctx, pd.config.ProposalDuration)
 	defer cancel()
 
-	if st.nonce != nil {
+	for _, p := range pd.participants {
+		nonce, ok := st.nonces[p.signer.NodeID()]
+		if !ok {
+			continue
+		}
+		p := p
 		pd.eg.Go(func() error {
-			pd.sendProposal(ctx, epoch, *st.nonce, st.proposalChecker)
+			pd.sendProposal(ctx, epoch, p, nonce, st.proposalChecker)
 			return nil
 		})
 	}
@@ -794,22 +817,27 @@ func (pd *ProtocolDriver) runProposalPhase(ctx context.Context, epoch types.Epoc
 	return nil
 }
 
-func (pd *ProtocolDriver) sendProposal(ctx context.Context, epoch types.EpochID, nonce types.VRFPostIndex, checker eligibilityChecker) {
+func (pd *ProtocolDriver) sendProposal(ctx context.Context, epoch types.EpochID, p participant, nonce types.VRFPostIndex, checker eligibilityChecker) {
 	if pd.isClosed() {
 		return
 	}
 
-	atx, err := pd.minerAtxHdr(epoch, pd.edSigner.NodeID())
+	nodeID := p.signer.NodeID()
+	atx, err := pd.minerAtxHdr(epoch, nodeID)
 	if err != nil {
 		return
 	}
 
-	logger := pd.logger.WithContext(ctx).WithFields(epoch)
-	vrfSig := buildSignedProposal(ctx, pd.logger, pd.vrfSigner, epoch, nonce)
+	logger := pd.logger.WithContext(ctx).WithFields(epoch, log.Stringer("smesher", nodeID))
+	vrfSig, err := buildSignedProposal(ctx, pd.logger, p.vrf, epoch, nonce)
+	if err != nil {
+		logger.With().Warning("failed to sign beacon proposal", log.Err(err))
+		return
+	}
 	proposal := ProposalFromVrf(vrfSig)
 	m := ProposalMessage{
 		EpochID:      epoch,
-		NodeID:       pd.nodeID,
+		NodeID:       nodeID,
 		VRFSignature: vrfSig,
 	}
 
@@ -834,7 +862,8 @@ func (pd *ProtocolDriver) sendProposal(ctx context.Context, epoch types.EpochID,
 }
 
 // runConsensusPhase runs K voting rounds and returns result from last weak coin round.
-func (pd *ProtocolDriver) runConsensusPhase(ctx context.Context, epoch types.EpochID, nonce *types.VRFPostIndex) (allVotes, error) {
+// every local identity with a nonce in the epoch casts its own votes.
+func (pd *ProtocolDriver) runConsensusPhase(ctx context.Context, epoch types.EpochID, nonces map[types.NodeID]types.VRFPostIndex) (allVotes, error) {
 	logger := pd.logger.WithContext(ctx).WithFields(epoch)
 	logger.Info("starting consensus phase")
 
@@ -855,15 +884,19 @@ func (pd *ProtocolDriver) runConsensusPhase(ctx context.Context, epoch types.Epo
 		pd.setRoundInProgress(round)
 		rLogger := logger.WithFields(round)
 		votes := ownVotes
-		if nonce != nil {
+		for _, p := range pd.participants {
+			if _, ok := nonces[p.signer.NodeID()]; !ok {
+				continue
+			}
+			signer := p.signer
 			pd.eg.Go(func() error {
 				if round == types.FirstRound {
-					if err := pd.sendFirstRoundVote(ctx, epoch); err != nil {
-						rLogger.With().Error("failed to send proposal vote", log.Err(err))
+					if err := pd.sendFirstRoundVote(ctx, epoch, signer); err != nil {
+						rLogger.With().Error("failed to send proposal vote", log.Stringer("smesher", signer.NodeID()), log.Err(err))
 					}
 				} else {
-					if err := pd.sendFollowingVote(ctx, epoch, round, votes); err != nil {
-						rLogger.With().Error("failed to send following vote", log.Err(err))
+					if err := pd.sendFollowingVote(ctx, epoch, round, signer, votes); err != nil {
+						rLogger.With().Error("failed to send following vote", log.Stringer("smesher", signer.NodeID()), log.Err(err))
 					}
 				}
 				return nil
@@ -887,7 +920,7 @@ func (pd *ProtocolDriver) runConsensusPhase(ctx context.Context, epoch types.Epo
 			timer.Reset(pd.config.WeakCoinRoundDuration)
 
 			pd.eg.Go(func() error {
-				pd.weakCoin.StartRound(ctx, round, nonce)
+				pd.weakCoin.StartRound(ctx, round, nonces)
 				return nil
 			})
 			select {
@@ -946,7 +979,7 @@ func (pd *ProtocolDriver) genFirstRoundMsgBody(epoch types.EpochID) (FirstVoting
 	}, nil
 }
 
-func (pd *ProtocolDriver) sendFirstRoundVote(ctx context.Context, epoch types.EpochID) error {
+func (pd *ProtocolDriver) sendFirstRoundVote(ctx context.Context, epoch types.EpochID, signer signing.Signer) error {
 	mb, err := pd.genFirstRoundMsgBody(epoch)
 	if err != nil {
 		return err
@@ -956,15 +989,18 @@ func (pd *ProtocolDriver) sendFirstRoundVote(ctx context.Context, epoch types.Ep
 	if err != nil {
 		pd.logger.With().Fatal("failed to serialize message for signing", log.Err(err))
 	}
-	sig := pd.edSigner.Sign(signing.BEACON_FIRST_MSG, encoded)
+	sig, err := signer.SignContext(ctx, signing.BEACON_FIRST_MSG, encoded)
+	if err != nil {
+		return fmt.Errorf("sign first round vote: %w", err)
+	}
 
 	m := FirstVotingMessage{
 		FirstVotingMessageBody: mb,
-		SmesherID:              pd.edSigner.NodeID(),
+		SmesherID:              signer.NodeID(),
 		Signature:              sig,
 	}
 
-	pd.logger.WithContext(ctx).With().Debug("sending first round vote", epoch, types.FirstRound)
+	pd.logger.WithContext(ctx).With().Debug("sending first round vote", epoch, types.FirstRound, log.Stringer("smesher", signer.NodeID()))
 	serialized, err := codec.Encode(&m)
 	if err != nil {
 		pd.logger.With().Fatal("failed to serialize message for gossip", log.Err(err))
@@ -986,10 +1022,10 @@ func (pd *ProtocolDriver) getFirstRoundVote(epoch types.EpochID, nodeID types.No
 	return st.getMinerFirstRoundVote(nodeID)
 }
 
-func (pd *ProtocolDriver) sendFollowingVote(ctx context.Context, epoch types.EpochID, round types.RoundID, ownCurrentRoundVotes allVotes) error {
-	firstRoundVotes, err := pd.getFirstRoundVote(epoch, pd.edSigner.NodeID())
+func (pd *ProtocolDriver) sendFollowingVote(ctx context.Context, epoch types.EpochID, round types.RoundID, signer signing.Signer, ownCurrentRoundVotes allVotes) error {
+	firstRoundVotes, err := pd.getFirstRoundVote(epoch, signer.NodeID())
 	if err != nil {
-		return fmt.Errorf("get own first round votes %v: %w", pd.edSigner.NodeID().String(), err)
+		return fmt.Errorf("get own first round votes %v: %w", signer.NodeID().String(), err)
 	}
 
 	bitVector := encodeVotes(ownCurrentRoundVotes, firstRoundVotes)
@@ -1003,15 +1039,18 @@ func (pd *ProtocolDriver) sendFollowingVote(ctx context.Context, epoch types.Epo
 	if err != nil {
 		pd.logger.With().Fatal("failed to serialize message for signing", log.Err(err))
 	}
-	sig := pd.edSigner.Sign(signing.BEACON_FOLLOWUP_MSG, encoded)
+	sig, err := signer.SignContext(ctx, signing.BEACON_FOLLOWUP_MSG, encoded)
+	if err != nil {
+		return fmt.Errorf("sign following vote: %w", err)
+	}
 
 	m := FollowingVotingMessage{
 		FollowingVotingMessageBody: mb,
-		SmesherID:                  pd.edSigner.NodeID(),
+		SmesherID:                  signer.NodeID(),
 		Signature:                  sig,
 	}
 
-	pd.logger.WithContext(ctx).With().Debug("sending following round vote", epoch, round)
+	pd.logger.WithContext(ctx).With().Debug("sending following round vote", epoch, round, log.Stringer("smesher", signer.NodeID()))
 
 	serialized, err := codec.Encode(&m)
 	if err != nil {
@@ -1106,16 +1145,19 @@ func atxThreshold(kappa int, q *big.Rat, numATXs int) *big.Int {
 	return threshold
 }
 
-func buildSignedProposal(ctx context.Context, logger log.Log, signer vrfSigner, epoch types.EpochID, nonce types.VRFPostIndex) types.VrfSignature {
+func buildSignedProposal(ctx context.Context, logger log.Log, signer vrfSigner, epoch types.EpochID, nonce types.VRFPostIndex) (types.VrfSignature, error) {
 	p := buildProposal(logger, epoch, nonce)
-	vrfSig := signer.Sign(p)
+	vrfSig, err := signer.Sign(p)
+	if err != nil {
+		return types.EmptyVrfSignature, err
+	}
 	proposal := ProposalFromVrf(vrfSig)
 	logger.WithContext(ctx).With().Debug("calculated beacon proposal",
 		epoch,
 		nonce,
 		log.String("proposal", hex.EncodeToString(proposal[:])),
 	)
-	return vrfSig
+	return vrfSig, nil
 }
 
 func buildProposal(logger log.Log, epoch types.EpochID, nonce types.VRFPostIndex) []byte {
@@ -17,6 +17,7 @@ import (
 	"github.com/stretchr/testify/require"
 
 	"github.com/spacemeshos/go-spacemesh/activation"
+	"github.com/spacemeshos/go-spacemesh/codec"
 	"github.com/spacemeshos/go-spacemesh/common/types"
 	"github.com/spacemeshos/go-spacemesh/common/types/result"
 	"github.com/spacemeshos/go-spacemesh/datastore"
@@ -42,10 +43,9 @@ func coinValueMock(tb testing.TB, value bool) coin {
 		gomock.AssignableToTypeOf(types.EpochID(0)),
 	).AnyTimes()
 	coinMock.EXPECT().FinishEpoch(gomock.Any(), gomock.AssignableToTypeOf(types.EpochID(0))).AnyTimes()
-	nonce := types.VRFPostIndex(0)
 	coinMock.EXPECT().StartRound(gomock.Any(),
 		gomock.AssignableToTypeOf(types.RoundID(0)),
-		gomock.AssignableToTypeOf(&nonce),
+		gomock.AssignableToTypeOf(map[types.NodeID]types.VRFPostIndex{}),
 	).AnyTimes()
 	coinMock.EXPECT().FinishRound(gomock.Any()).AnyTimes()
 	coinMock.EXPECT().Get(
@@ -77,13 +77,14 @@ type testProtocolDriver struct {
 	mSigner       *MockvrfSigner
 	mVerifier     *MockvrfVerifier
 	mNonceFetcher *MocknonceFetcher
+	edSigner      *signing.EdSigner
 }
 
 func setUpProtocolDriver(tb testing.TB) *testProtocolDriver {
 	return newTestDriver(tb, UnitTestConfig(), newPublisher(tb))
 }
 
-func newTestDriver(tb testing.TB, cfg Config, p pubsub.Publisher) *testProtocolDriver {
+func newTestDriver(tb testing.TB, cfg Config, p pubsub.Publisher, opts ...Opt) *testProtocolDriver {
 	ctrl := gomock.NewController(tb)
 	tpd := &testProtocolDriver{
 		ctrl:          ctrl,
@@ -97,26 +98,29 @@ func newTestDriver(tb testing.TB, cfg Config, p pubsub.Publisher) *testProtocolD
 	require.NoError(tb, err)
 	edVerify, err := signing.NewEdVerifier()
 	require.NoError(tb, err)
+	tpd.edSigner = edSgn
 	minerID := edSgn.NodeID()
 	lg := logtest.New(tb).WithName(minerID.ShortString())
 
-	tpd.mSigner.EXPECT().Sign(gomock.Any()).AnyTimes().Return(types.EmptyVrfSignature)
+	tpd.mSigner.EXPECT().Sign(gomock.Any()).AnyTimes().Return(types.EmptyVrfSignature, nil)
 	tpd.mVerifier.EXPECT().Verify(gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes().Return(true)
 	tpd.mNonceFetcher.EXPECT().VRFNonce(gomock.Any(), gomock.Any()).AnyTimes().Return(types.VRFPostIndex(1), nil)
 
 	tpd.cdb = datastore.NewCachedDB(sql.InMemory(), lg)
 	tpd.ProtocolDriver = New(minerID, p, edSgn, edVerify, tpd.mSigner, tpd.mVerifier, tpd.cdb, tpd.mClock,
-		WithConfig(cfg),
-		WithLogger(lg),
-		withWeakCoin(coinValueMock(tb, true)),
-		withNonceFetcher(tpd.mNonceFetcher),
+		append([]Opt{
+			WithConfig(cfg),
+			WithLogger(lg),
+			withWeakCoin(coinValueMock(tb, true)),
+			withNonceFetcher(tpd.mNonceFetcher),
+		}, opts...)...,
 	)
 	tpd.ProtocolDriver.SetSyncState(tpd.mSync)
 	tpd.ProtocolDriver.setMetricsRegistry(prometheus.NewPedanticRegistry())
 	return tpd
 }
 
-func createATX(tb testing.TB, db *datastore.CachedDB, lid types.LayerID, sig *signing.EdSigner, numUnits uint32, received time.Time) types.ATXID {
+func createATX(tb testing.TB, db *datastore.CachedDB, lid types.LayerID, sig signing.Signer, numUnits uint32, received time.Time) types.ATXID {
 	nonce := types.VRFPostIndex(1)
 	atx := types.NewActivationTx(
 		types.NIPostChallenge{PublishEpoch: lid.GetEpoch()},
@@ -221,6 +225,80 @@ func TestBeacon_MultipleNodes(t *testing.T) {
 	require.Len(t, beacons, 1)
 }
 
+func TestBeacon_MultipleSigners(t *testing.T) {
+	var (
+		mu        sync.Mutex
+		node      *testProtocolDriver
+		proposals = map[types.NodeID]int{}
+		first     = map[types.NodeID]int{}
+		following = map[types.NodeID]int{}
+	)
+	publisher := pubsubmocks.NewMockPublisher(gomock.NewController(t))
+	publisher.EXPECT().Publish(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+		func(ctx context.Context, protocol string, data []byte) error {
+			mu.Lock()
+			defer mu.Unlock()
+			peer := p2p.Peer(node.nodeID.ShortString())
+			switch protocol {
+			case pubsub.BeaconProposalProtocol:
+				var m ProposalMessage
+				require.NoError(t, codec.Decode(data, &m))
+				proposals[m.NodeID]++
+				require.NoError(t, node.HandleProposal(ctx, peer, data))
+			case pubsub.BeaconFirstVotesProtocol:
+				var m FirstVotingMessage
+				require.NoError(t, codec.Decode(data, &m))
+				first[m.SmesherID]++
+				require.NoError(t, node.HandleFirstVotes(ctx, peer, data))
+			case pubsub.BeaconFollowingVotesProtocol:
+				var m FollowingVotingMessage
+				require.NoError(t, codec.Decode(data, &m))
+				following[m.SmesherID]++
+				require.NoError(t, node.HandleFollowingVotes(ctx, peer, data))
+			}
+			return nil
+		}).AnyTimes()
+
+	cfg := NodeSimUnitTestConfig()
+	signers := make([]*signing.EdSigner, 2)
+	var opts []Opt
+	for i := range signers {
+		signer, err := signing.NewEdSigner()
+		require.NoError(t, err)
+		vrf, err := signer.VRFSigner()
+		require.NoError(t, err)
+		signers[i] = signer
+		opts = append(opts, WithSigner(signer, vrf))
+	}
+	node = newTestDriver(t, cfg, publisher, opts...)
+
+	atxPublishLid := types.LayerID(types.GetLayersPerEpoch()*2 - 1)
+	current := atxPublishLid.Add(1)
+	node.mSync.EXPECT().IsSynced(gomock.Any()).Return(true).AnyTimes()
+	node.mClock.EXPECT().CurrentLayer().Return(current).AnyTimes()
+	node.mClock.EXPECT().LayerToTime(current).Return(time.Now()).AnyTimes()
+	// every identity has its own atx in the epoch
+	for _, signer := range append([]*signing.EdSigner{node.edSigner}, signers...) {
+		createATX(t, node.cdb, atxPublishLid, signer, 1, time.Now().Add(-1*time.Second))
+	}
+
+	require.NoError(t, node.onNewEpoch(context.Background(), types.EpochID(2)))
+	require.NoError(t, node.eg.Wait())
+	got, err := node.GetBeacon(types.EpochID(3))
+	require.NoError(t, err)
+	require.NotEqual(t, types.EmptyBeacon, got)
+
+	// every identity proposes and votes in every round on its own
+	require.Len(t, proposals, len(signers)+1)
+	require.Len(t, first, len(signers)+1)
+	require.Len(t, following, len(signers)+1)
+	for _, signer := range append([]*signing.EdSigner{node.edSigner}, signers...) {
+		require.Equal(t, 1, proposals[signer.NodeID()])
+		require.Equal(t, 1, first[signer.NodeID()])
+		require.Equal(t, int(cfg.RoundsNumber)-1, following[signer.NodeID()])
+	}
+}
+
 func TestBeacon_NoProposals(t *testing.T) {
 	numNodes := 5
 	testNodes := make([]*testProtocolDriver, 0, numNodes)
@@ -903,7 +981,8 @@ func TestBeacon_proposalPassesEligibilityThreshold(t *testing.T) {
 				require.NoError(t, err)
 				vrfSigner, err := signer.VRFSigner()
 				require.NoError(t, err)
-				proposal := buildSignedProposal(context.Background(), logtest.New(t), vrfSigner, 3, types.VRFPostIndex(1))
+				proposal, err := buildSignedProposal(context.Background(), logtest.New(t), vrfSigner, 3, types.VRFPostIndex(1))
+				require.NoError(t, err)
 				if checker.PassThreshold(proposal) {
 					numEligible++
 				}
@@ -953,19 +1032,19 @@ func TestBeacon_getSignedProposal(t *testing.T) {
 	require.NoError(t, err)
 
 	tt := []struct {
-		name   string
-		epoch  types.EpochID
-		result types.VrfSignature
+		name    string
+		epoch   types.EpochID
+		message []byte
 	}{
 		{
-			name:   "Case 1",
-			epoch:  1,
-			result: vrfSigner.Sign([]byte{0x04, 0x04, 0x04}),
+			name:    "Case 1",
+			epoch:   1,
+			message: []byte{0x04, 0x04, 0x04},
 		},
 		{
-			name:   "Case 2",
-			epoch:  2,
-			result: vrfSigner.Sign([]byte{0x04, 0x04, 0x08}),
+			name:    "Case 2",
+			epoch:   2,
+			message: []byte{0x04, 0x04, 0x08},
 		},
 	}
 
@@ -974,8 +1053,11 @@ func TestBeacon_getSignedProposal(t *testing.T) {
 		t.Run(tc.name, func(t *testing.T) {
 			t.Parallel()
 
-			result := buildSignedProposal(context.Background(), logtest.New(t), vrfSigner, tc.epoch, types.VRFPostIndex(1))
-			require.Equal(t, tc.result, result)
+			expected, err := vrfSigner.Sign(tc.message)
+			require.NoError(t, err)
+			result, err := buildSignedProposal(context.Background(), logtest.New(t), vrfSigner, tc.epoch, types.VRFPostIndex(1))
+			require.NoError(t, err)
+			require.Equal(t, expected, result)
 		})
 	}
 }
@@ -64,15 +64,17 @@ func setMinerFirstRoundVotes(t *testing.T, pd *ProtocolDriver, epoch types.Epoch
 	pd.states[epoch].setMinerFirstRoundVote(minerID, minerFirstRound)
 }
 
-func createProposal(t *testing.T, vrfSigner *signing.VRFSigner, epoch types.EpochID, corruptSignature bool) *ProposalMessage {
-	sig := buildSignedProposal(context.Background(), logtest.New(t), vrfSigner, epoch, types.VRFPostIndex(rand.Uint64()))
+func createProposal(t *testing.T, vrfSigner signing.VRFSigner, epoch types.EpochID, corruptSignature bool) *ProposalMessage {
+	sig, err := buildSignedProposal(context.Background(), logtest.New(t), vrfSigner, epoch, types.VRFPostIndex(rand.Uint64()))
+	require.NoError(t, err)
 	msg := &ProposalMessage{
 		NodeID:       vrfSigner.NodeID(),
 		EpochID:      epoch,
 		VRFSignature: sig,
 	}
 	if corruptSignature {
-		msg.VRFSignature = vrfSigner.Sign(types.RandomBytes(32))
+		msg.VRFSignature, err = vrfSigner.Sign(types.RandomBytes(32))
+		require.NoError(t, err)
 	}
 	return msg
 }
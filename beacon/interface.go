@@ -12,7 +12,7 @@ import (
 
 type coin interface {
 	StartEpoch(context.Context, types.EpochID)
-	StartRound(context.Context, types.RoundID, *types.VRFPostIndex)
+	StartRound(context.Context, types.RoundID, map[types.NodeID]types.VRFPostIndex)
 	FinishRound(context.Context)
 	Get(context.Context, types.EpochID, types.RoundID) (bool, error)
 	FinishEpoch(context.Context, types.EpochID)
@@ -31,7 +31,7 @@ type layerClock interface {
 }
 
 type vrfSigner interface {
-	Sign(msg []byte) types.VrfSignature
+	Sign(msg []byte) (types.VrfSignature, error)
 	NodeID() types.NodeID
 	LittleEndian() bool
 }
@@ -103,7 +103,7 @@ func (mr *MockcoinMockRecorder) StartEpoch(arg0, arg1 interface{}) *gomock.Call
 }
 
 // StartRound mocks base method.
-func (m *Mockcoin) StartRound(arg0 context.Context, arg1 types.RoundID, arg2 *types.VRFPostIndex) {
+func (m *Mockcoin) StartRound(arg0 context.Context, arg1 types.RoundID, arg2 map[types.NodeID]types.VRFPostIndex) {
 	m.ctrl.T.Helper()
 	m.ctrl.Call(m, "StartRound", arg0, arg1, arg2)
 }
@@ -282,11 +282,12 @@ func (mr *MockvrfSignerMockRecorder) NodeID() *gomock.Call {
 }
 
 // Sign mocks base method.
-func (m *MockvrfSigner) Sign(msg []byte) types.VrfSignature {
+func (m *MockvrfSigner) Sign(msg []byte) (types.VrfSignature, error) {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "Sign", msg)
 	ret0, _ := ret[0].(types.VrfSignature)
-	return ret0
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
 }
 
 // Sign indicates an expected call of Sign.
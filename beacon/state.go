@@ -13,9 +13,10 @@ import (
 // state does the data management for epoch specific data for the protocol.
 // not thread-safe. it relies on ProtocolDriver's thread-safety mechanism.
 type state struct {
-	cfg         Config
-	logger      log.Log
-	nonce       *types.VRFPostIndex
+	cfg    Config
+	logger log.Log
+	// nonces of the local identities that have an atx in the epoch.
+	nonces      map[types.NodeID]types.VRFPostIndex
 	epochWeight uint64
 	// the original proposals as received, bucketed by validity.
 	incomingProposals proposals
@@ -34,7 +35,7 @@ type state struct {
 func newState(
 	logger log.Log,
 	cfg Config,
-	nonce *types.VRFPostIndex,
+	nonces map[types.NodeID]types.VRFPostIndex,
 	epochWeight uint64,
 	miners map[types.NodeID]types.ATXID,
 	checker eligibilityChecker,
@@ -43,7 +44,7 @@ func newState(
 		cfg:                     cfg,
 		logger:                  logger,
 		epochWeight:             epochWeight,
-		nonce:                   nonce,
+		nonces:                  nonces,
 		minerAtxs:               miners,
 		firstRoundIncomingVotes: make(map[types.NodeID]proposalList),
 		votesMargin:             map[Proposal]*big.Int{},
@@ -51,6 +51,9 @@ func (wc *WeakCoin) receiveMessage(ctx context.Context, message Message) error {
 	defer wc.mu.Unlock()
 	if wc.epoch != message.Epoch || wc.round != message.Round || !wc.epochStarted || !wc.roundStarted {
 		if wc.isNextRound(message.Epoch, message.Round) && len(wc.nextRoundBuffer) < cap(wc.nextRoundBuffer) {
+			if err := wc.updateNextRoundBest(message); err != nil {
+				return err
+			}
 			wc.nextRoundBuffer = append(wc.nextRoundBuffer, message)
 			return nil
 		}
@@ -76,3 +79,20 @@ func (wc *WeakCoin) isNextRound(epoch types.EpochID, round types.RoundID) bool {
 	}
 	return false
 }
+
+// updateNextRoundBest rejects the proposal for the next round of the current epoch if it is not smaller
+// than the best valid proposal buffered for that round, so that it is not relayed. The proposals for the
+// next epoch can't be validated before the epoch starts and are buffered as is.
+func (wc *WeakCoin) updateNextRoundBest(message Message) error {
+	if message.Epoch != wc.epoch {
+		return nil
+	}
+	if best, ok := wc.nextRoundBest[message.Round]; ok && message.VRFSignature.Cmp(&best) != -1 {
+		return errNotSmallest
+	}
+	if err := wc.verifyProposal(message); err != nil {
+		return err
+	}
+	wc.nextRoundBest[message.Round] = message.VRFSignature
+	return nil
+}
@@ -7,7 +7,7 @@ import (
 //go:generate mockgen -package=weakcoin -destination=./mocks.go -source=./interface.go
 
 type vrfSigner interface {
-	Sign(msg []byte) types.VrfSignature
+	Sign(msg []byte) (types.VrfSignature, error)
 	NodeID() types.NodeID
 	LittleEndian() bool
 }
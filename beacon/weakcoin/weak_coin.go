@@ -2,8 +2,10 @@ package weakcoin
 
 import (
 	"context"
+	"encoding/binary"
 	"errors"
 	"fmt"
+	"math"
 	"sync"
 	"time"
 
@@ -42,6 +44,9 @@ type config struct {
 	Threshold           types.VrfSignature
 	NextRoundBufferSize int
 	MaxRound            types.RoundID
+	// PublishDelay is the max delay of the own proposal. The proposal is delayed in proportion to its value,
+	// so that the smallest proposals are published first and the larger ones are not published at all.
+	PublishDelay time.Duration
 }
 
 //go:generate scalegen -types Message,VrfMessage
@@ -95,6 +100,13 @@ func WithNextRoundBufferSize(size int) OptionFunc {
 	}
 }
 
+// WithPublishDelay changes the max delay of the own proposal.
+func WithPublishDelay(delay time.Duration) OptionFunc {
+	return func(wc *WeakCoin) {
+		wc.config.PublishDelay = delay
+	}
+}
+
 // messageTime interface exists so that we can pass an object from the beacon
 // package to the weakCoinPackage (as does allowance), this is indicative of a
 // circular dependency, probably the weak coin should be merged with the beacon
@@ -104,6 +116,13 @@ type messageTime interface {
 	WeakCoinProposalSendTime(epoch types.EpochID, round types.RoundID) time.Time
 }
 
+// WithSigner adds a local identity that makes weak coin proposals along with the primary identity.
+func WithSigner(signer vrfSigner) OptionFunc {
+	return func(wc *WeakCoin) {
+		wc.signers = append(wc.signers, signer)
+	}
+}
+
 // New creates an instance of weak coin protocol.
 func New(
 	publisher pubsub.Publisher,
@@ -117,7 +136,6 @@ func New(
 	wc := &WeakCoin{
 		logger:       log.NewNop(),
 		config:       defaultConfig(),
-		signer:       signer,
 		nonceFetcher: nonceFetcher,
 		allowance:    allowance,
 		publisher:    publisher,
@@ -128,8 +146,10 @@ func New(
 	for _, opt := range opts {
 		opt(wc)
 	}
+	wc.signers = append([]vrfSigner{signer}, wc.signers...)
 
 	wc.nextRoundBuffer = make([]Message, 0, wc.config.NextRoundBufferSize)
+	wc.nextRoundBest = make(map[types.RoundID]types.VrfSignature)
 	return wc
 }
 
@@ -138,7 +158,7 @@ type WeakCoin struct {
 	logger       log.Log
 	config       config
 	verifier     vrfVerifier
-	signer       vrfSigner
+	signers      []vrfSigner
 	nonceFetcher nonceFetcher
 	publisher    pubsub.Publisher
 
@@ -152,6 +172,8 @@ type WeakCoin struct {
 	nextRoundBuffer []Message
 	coins           map[types.RoundID]bool
 	msgTime         messageTime
+	// nextRoundBest is the smallest valid proposal buffered for the rounds of the current epoch.
+	nextRoundBest map[types.RoundID]types.VrfSignature
 }
 
 // Get the result of the coin flip in this round. It is only valid in between StartEpoch/EndEpoch
@@ -198,12 +220,14 @@ func (wc *WeakCoin) FinishEpoch(ctx context.Context, epoch types.EpochID) {
 	}
 	wc.epochStarted = false
 	wc.coins = map[types.RoundID]bool{}
+	wc.nextRoundBest = map[types.RoundID]types.VrfSignature{}
 	wc.round = 0
 	logger.Info("weak coin finished epoch")
 }
 
 // StartRound process any buffered messages for this round and broadcast our proposal.
-func (wc *WeakCoin) StartRound(ctx context.Context, round types.RoundID, nonce *types.VRFPostIndex) {
+// Every local identity with a nonce makes a proposal, only the smallest one is broadcast.
+func (wc *WeakCoin) StartRound(ctx context.Context, round types.RoundID, nonces map[types.NodeID]types.VRFPostIndex) {
 	wc.mu.Lock()
 	logger := wc.logger.WithContext(ctx).WithFields(wc.epoch, round)
 	logger.Info("started beacon weak coin round")
@@ -219,14 +243,23 @@ func (wc *WeakCoin) StartRound(ctx context.Context, round types.RoundID, nonce *
 		wc.nextRoundBuffer[i] = Message{}
 	}
 	wc.nextRoundBuffer = wc.nextRoundBuffer[:0]
+	wc.nextRoundBest = map[types.RoundID]types.VrfSignature{}
 	wc.mu.Unlock()
 
-	if nonce != nil {
-		wc.publishProposal(ctx, wc.epoch, *nonce, wc.round)
+	if len(nonces) > 0 {
+		wc.publishProposal(ctx, wc.epoch, nonces, wc.round)
 	}
 }
 
 func (wc *WeakCoin) updateProposal(ctx context.Context, message Message) error {
+	if err := wc.verifyProposal(message); err != nil {
+		return err
+	}
+	return wc.updateSmallest(ctx, message.VRFSignature)
+}
+
+// verifyProposal checks the signature of the proposal and that the miner is allowed to submit it.
+func (wc *WeakCoin) verifyProposal(message Message) error {
 	nonce, err := wc.nonceFetcher.VRFNonce(message.NodeID, message.Epoch)
 	if err != nil {
 		wc.logger.With().Error("failed to get vrf nonce", log.Err(err))
@@ -241,38 +274,45 @@ func (wc *WeakCoin) updateProposal(ctx context.Context, message Message) error {
 	if allowance < message.Unit {
 		return fmt.Errorf("miner %x is not allowed to submit proposal for unit %d (allowed %d)", message.NodeID, message.Unit, allowance)
 	}
-
-	return wc.updateSmallest(ctx, message.VRFSignature)
+	return nil
 }
 
-func (wc *WeakCoin) prepareProposal(epoch types.EpochID, nonce types.VRFPostIndex, round types.RoundID) ([]byte, types.VrfSignature) {
-	minerAllowance := wc.allowance.MinerAllowance(wc.epoch, wc.signer.NodeID())
-	if minerAllowance == 0 {
-		return nil, types.EmptyVrfSignature
-	}
+func (wc *WeakCoin) prepareProposal(epoch types.EpochID, nonces map[types.NodeID]types.VRFPostIndex, round types.RoundID) ([]byte, types.VrfSignature) {
 	var broadcast []byte
 	var smallest *types.VrfSignature
-	for unit := uint32(0); unit < minerAllowance; unit++ {
-		proposal := wc.encodeProposal(epoch, nonce, round, unit)
-		signature := wc.signer.Sign(proposal)
-		if wc.aboveThreshold(signature) {
+	for _, signer := range wc.signers {
+		nonce, ok := nonces[signer.NodeID()]
+		if !ok {
 			continue
 		}
-		if signature.Cmp(smallest) == -1 {
-			message := Message{
-				Epoch:        epoch,
-				Round:        round,
-				Unit:         unit,
-				NodeID:       wc.signer.NodeID(),
-				VRFSignature: signature,
-			}
-			msg, err := codec.Encode(&message)
+		minerAllowance := wc.allowance.MinerAllowance(wc.epoch, signer.NodeID())
+		for unit := uint32(0); unit < minerAllowance; unit++ {
+			proposal := wc.encodeProposal(epoch, nonce, round, unit)
+			signature, err := signer.Sign(proposal)
 			if err != nil {
-				wc.logger.With().Fatal("failed to serialize weak coin message", log.Err(err))
+				wc.logger.With().Warning("failed to sign weak coin proposal",
+					epoch, round, log.Stringer("smesher", signer.NodeID()), log.Err(err))
+				break
+			}
+			if wc.aboveThreshold(signature) {
+				continue
+			}
+			if signature.Cmp(smallest) == -1 {
+				message := Message{
+					Epoch:        epoch,
+					Round:        round,
+					Unit:         unit,
+					NodeID:       signer.NodeID(),
+					VRFSignature: signature,
+				}
+				msg, err := codec.Encode(&message)
+				if err != nil {
+					wc.logger.With().Fatal("failed to serialize weak coin message", log.Err(err))
+				}
+
+				broadcast = msg
+				smallest = &signature
 			}
-
-			broadcast = msg
-			smallest = &signature
 		}
 	}
 
@@ -284,11 +324,26 @@ func (wc *WeakCoin) prepareProposal(epoch types.EpochID, nonce types.VRFPostInde
 	return broadcast, *smallest
 }
 
-func (wc *WeakCoin) publishProposal(ctx context.Context, epoch types.EpochID, nonce types.VRFPostIndex, round types.RoundID) {
-	msg, proposal := wc.prepareProposal(epoch, nonce, round)
+func (wc *WeakCoin) publishProposal(ctx context.Context, epoch types.EpochID, nonces map[types.NodeID]types.VRFPostIndex, round types.RoundID) {
+	msg, proposal := wc.prepareProposal(epoch, nonces, round)
 	if msg == nil {
 		return
 	}
+	if delay := wc.publishDelay(proposal); delay > 0 {
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return
+		}
+		if !wc.isSmallest(epoch, round, proposal) {
+			wc.logger.WithContext(ctx).With().Debug("smaller proposal received, not publishing own",
+				epoch,
+				round,
+				log.Stringer("proposal", proposal),
+			)
+			return
+		}
+	}
 
 	if err := wc.publisher.Publish(ctx, pubsub.BeaconWeakCoinProtocol, msg); err != nil {
 		wc.logger.With().Warning("failed to publish own weak coin proposal",
@@ -307,6 +362,20 @@ func (wc *WeakCoin) publishProposal(ctx context.Context, epoch types.EpochID, no
 	)
 }
 
+// publishDelay returns the delay of the own proposal, proportional to its most significant bytes.
+func (wc *WeakCoin) publishDelay(proposal types.VrfSignature) time.Duration {
+	// VRF signatures are little endian
+	scale := binary.LittleEndian.Uint64(proposal[len(proposal)-8:])
+	return time.Duration(float64(wc.config.PublishDelay) * float64(scale) / math.MaxUint64)
+}
+
+// isSmallest returns true if the proposal is smaller than all proposals received in the ongoing round.
+func (wc *WeakCoin) isSmallest(epoch types.EpochID, round types.RoundID, proposal types.VrfSignature) bool {
+	wc.mu.RLock()
+	defer wc.mu.RUnlock()
+	return wc.epoch == epoch && wc.round == round && wc.roundStarted && proposal.Cmp(wc.smallest) == -1
+}
+
 // FinishRound computes coinflip based on proposals received in this round.
 // After it is called new proposals for this round won't be accepted.
 func (wc *WeakCoin) FinishRound(ctx context.Context) {
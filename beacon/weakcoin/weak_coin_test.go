@@ -6,6 +6,8 @@ import (
 	"fmt"
 	"math/rand"
 	"os"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -46,7 +48,7 @@ func encoded(tb testing.TB, msg weakcoin.Message) []byte {
 func staticSigner(tb testing.TB, ctrl *gomock.Controller, nodeId types.NodeID, sig types.VrfSignature) *weakcoin.MockvrfSigner {
 	tb.Helper()
 	signer := weakcoin.NewMockvrfSigner(ctrl)
-	signer.EXPECT().Sign(gomock.Any()).Return(sig).AnyTimes()
+	signer.EXPECT().Sign(gomock.Any()).Return(sig, nil).AnyTimes()
 	signer.EXPECT().NodeID().Return(nodeId).AnyTimes()
 	signer.EXPECT().LittleEndian().Return(true).AnyTimes()
 	return signer
@@ -165,9 +167,10 @@ func TestWeakCoin(t *testing.T) {
 			).AnyTimes()
 			var threshold types.VrfSignature
 			threshold[79] = 0xfe
+			nodeID := types.RandomNodeID()
 			wc = weakcoin.New(
 				mockPublisher,
-				staticSigner(t, ctrl, types.RandomNodeID(), tc.nodeSig),
+				staticSigner(t, ctrl, nodeID, tc.nodeSig),
 				sigVerifier(t, ctrl),
 				nonceFetcher(t, ctrl),
 				mockAllowance,
@@ -177,9 +180,8 @@ func TestWeakCoin(t *testing.T) {
 			)
 
 			wc.StartEpoch(context.Background(), epoch)
-			nonce := types.VRFPostIndex(1)
 			if tc.mining {
-				wc.StartRound(context.Background(), round, &nonce)
+				wc.StartRound(context.Background(), round, map[types.NodeID]types.VRFPostIndex{nodeID: 1})
 			} else {
 				wc.StartRound(context.Background(), round, nil)
 			}
@@ -374,13 +376,16 @@ func TestWeakCoinNextRoundBufferOverflow(t *testing.T) {
 	wc.StartEpoch(context.Background(), epoch)
 	wc.StartRound(context.Background(), round, nil)
 	for i := 0; i < bufSize; i++ {
-		wc.HandleProposal(context.Background(), "", encoded(t, weakcoin.Message{
+		// every buffered proposal is smaller than the previous one, otherwise it is dropped
+		sig := oneLSBSig
+		sig[79] = byte(bufSize - i - 1)
+		require.NoError(t, wc.HandleProposal(context.Background(), "", encoded(t, weakcoin.Message{
 			Epoch:        epoch,
 			Round:        nextRound,
 			Unit:         1,
 			NodeID:       oneLSBMiner,
-			VRFSignature: oneLSBSig,
-		}))
+			VRFSignature: sig,
+		})))
 	}
 	wc.HandleProposal(context.Background(), "", encoded(t, weakcoin.Message{
 		Epoch:        epoch,
@@ -396,6 +401,213 @@ func TestWeakCoinNextRoundBufferOverflow(t *testing.T) {
 	require.True(t, flip)
 }
 
+func TestWeakCoinNextRoundBestProposal(t *testing.T) {
+	var (
+		ctrl                    = gomock.NewController(t)
+		epoch     types.EpochID = 10
+		round     types.RoundID = 2
+		nextRound               = round + 1
+		small                   = types.VrfSignature{0b0001, 1}
+		large                   = types.VrfSignature{0b0000, 2}
+	)
+	mockAllowance := weakcoin.NewMockallowance(ctrl)
+	mockAllowance.EXPECT().MinerAllowance(epoch, gomock.Any()).Return(uint32(1)).AnyTimes()
+	wc := weakcoin.New(
+		noopBroadcaster(t, ctrl),
+		staticSigner(t, ctrl, types.RandomNodeID(), types.VrfSignature{}),
+		sigVerifier(t, ctrl),
+		nonceFetcher(t, ctrl),
+		mockAllowance,
+		&stubClock{},
+	)
+	wc.StartEpoch(context.Background(), epoch)
+	wc.StartRound(context.Background(), round, nil)
+
+	msg := func(sig types.VrfSignature) []byte {
+		return encoded(t, weakcoin.Message{Epoch: epoch, Round: nextRound, Unit: 1, NodeID: types.RandomNodeID(), VRFSignature: sig})
+	}
+	require.NoError(t, wc.HandleProposal(context.Background(), "", msg(large)))
+	require.NoError(t, wc.HandleProposal(context.Background(), "", msg(small)))
+	// the proposals that are not smaller than the best buffered one are not relayed
+	require.Error(t, wc.HandleProposal(context.Background(), "", msg(small)))
+	require.Error(t, wc.HandleProposal(context.Background(), "", msg(large)))
+
+	wc.FinishRound(context.Background())
+	wc.StartRound(context.Background(), nextRound, nil)
+	wc.FinishRound(context.Background())
+	flip, err := wc.Get(context.Background(), epoch, nextRound)
+	require.NoError(t, err)
+	require.True(t, flip)
+}
+
+func TestWeakCoinWithheldProposal(t *testing.T) {
+	var (
+		ctrl                  = gomock.NewController(t)
+		epoch   types.EpochID = 10
+		round   types.RoundID = 2
+		own                   = types.VrfSignature{0b0000, 2}
+		smaller               = types.VrfSignature{0b0001, 1}
+	)
+	mockAllowance := weakcoin.NewMockallowance(ctrl)
+	mockAllowance.EXPECT().MinerAllowance(epoch, gomock.Any()).Return(uint32(1)).AnyTimes()
+	var wc *weakcoin.WeakCoin
+	mockPublisher := mocks.NewMockPublisher(ctrl)
+	mockPublisher.EXPECT().Publish(gomock.Any(), pubsub.BeaconWeakCoinProtocol, gomock.Any()).DoAndReturn(
+		func(ctx context.Context, _ string, msg []byte) error {
+			return wc.HandleProposal(ctx, "", msg)
+		})
+	nodeID := types.RandomNodeID()
+	wc = weakcoin.New(
+		mockPublisher,
+		staticSigner(t, ctrl, nodeID, own),
+		sigVerifier(t, ctrl),
+		nonceFetcher(t, ctrl),
+		mockAllowance,
+		&stubClock{},
+		weakcoin.WithPublishDelay(time.Millisecond),
+	)
+	wc.StartEpoch(context.Background(), epoch)
+	wc.StartRound(context.Background(), round, map[types.NodeID]types.VRFPostIndex{nodeID: 1})
+
+	// the smaller proposal released late in the round is still accepted
+	withheld := encoded(t, weakcoin.Message{Epoch: epoch, Round: round, Unit: 1, NodeID: types.RandomNodeID(), VRFSignature: smaller})
+	require.NoError(t, wc.HandleProposal(context.Background(), "", withheld))
+	wc.FinishRound(context.Background())
+	flip, err := wc.Get(context.Background(), epoch, round)
+	require.NoError(t, err)
+	require.True(t, flip)
+
+	// but not after the round is closed
+	smallest := encoded(t, weakcoin.Message{Epoch: epoch, Round: round, Unit: 1, NodeID: types.RandomNodeID(), VRFSignature: types.VrfSignature{}})
+	wc.HandleProposal(context.Background(), "", smallest)
+	flip, err = wc.Get(context.Background(), epoch, round)
+	require.NoError(t, err)
+	require.True(t, flip)
+}
+
+func TestWeakCoinMultipleSigners(t *testing.T) {
+	var (
+		ctrl                  = gomock.NewController(t)
+		epoch   types.EpochID = 10
+		round   types.RoundID = 2
+		primary               = types.RandomNodeID()
+		other                 = types.RandomNodeID()
+	)
+	for _, tc := range []struct {
+		desc     string
+		nonces   map[types.NodeID]types.VRFPostIndex
+		expected types.NodeID
+	}{
+		{
+			desc:     "smallest proposal of all identities",
+			nonces:   map[types.NodeID]types.VRFPostIndex{primary: 1, other: 2},
+			expected: other,
+		},
+		{
+			desc:     "only primary has nonce",
+			nonces:   map[types.NodeID]types.VRFPostIndex{primary: 1},
+			expected: primary,
+		},
+	} {
+		tc := tc
+		t.Run(tc.desc, func(t *testing.T) {
+			mockAllowance := weakcoin.NewMockallowance(ctrl)
+			mockAllowance.EXPECT().MinerAllowance(epoch, gomock.Any()).Return(uint32(1)).AnyTimes()
+			var published []weakcoin.Message
+			mockPublisher := mocks.NewMockPublisher(ctrl)
+			mockPublisher.EXPECT().Publish(gomock.Any(), pubsub.BeaconWeakCoinProtocol, gomock.Any()).DoAndReturn(
+				func(_ context.Context, _ string, data []byte) error {
+					var msg weakcoin.Message
+					require.NoError(t, codec.Decode(data, &msg))
+					published = append(published, msg)
+					return nil
+				}).AnyTimes()
+			wc := weakcoin.New(
+				mockPublisher,
+				staticSigner(t, ctrl, primary, types.VrfSignature{0b0001, 2}),
+				sigVerifier(t, ctrl),
+				nonceFetcher(t, ctrl),
+				mockAllowance,
+				&stubClock{},
+				weakcoin.WithSigner(staticSigner(t, ctrl, other, types.VrfSignature{0b0000, 1})),
+				weakcoin.WithLog(logtest.New(t)),
+			)
+			wc.StartEpoch(context.Background(), epoch)
+			wc.StartRound(context.Background(), round, tc.nonces)
+			require.Len(t, published, 1)
+			require.Equal(t, tc.expected, published[0].NodeID)
+		})
+	}
+}
+
+func TestWeakCoinRelayedProposals(t *testing.T) {
+	const size = 50
+	var (
+		ctrl                    = gomock.NewController(t)
+		instances               = make([]*weakcoin.WeakCoin, size)
+		epoch     types.EpochID = 2
+		round     types.RoundID = 1
+		relayed   atomic.Int64
+		rng       = rand.New(rand.NewSource(1001))
+		nonces    = map[types.NodeID]types.VRFPostIndex{}
+	)
+	mockAllowance := weakcoin.NewMockallowance(ctrl)
+	mockAllowance.EXPECT().MinerAllowance(gomock.Any(), gomock.Any()).Return(uint32(1)).AnyTimes()
+	for i := range instances {
+		broadcaster := mocks.NewMockPublisher(ctrl)
+		broadcaster.EXPECT().Publish(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+			func(_ context.Context, _ string, data []byte) error {
+				for _, instance := range instances {
+					// the message is relayed by every node that accepts it
+					if instance.HandleProposal(context.Background(), "", data) == nil {
+						relayed.Add(1)
+					}
+				}
+				return nil
+			}).AnyTimes()
+		signer, err := signing.NewEdSigner(signing.WithKeyFromRand(rng))
+		require.NoError(t, err)
+		vrfSigner, err := signer.VRFSigner()
+		require.NoError(t, err)
+		nonces[signer.NodeID()] = 1
+		instances[i] = weakcoin.New(
+			broadcaster,
+			vrfSigner,
+			signing.NewVRFVerifier(),
+			nonceFetcher(t, ctrl),
+			mockAllowance,
+			&stubClock{},
+			weakcoin.WithPublishDelay(2*time.Second),
+			weakcoin.WithLog(logtest.New(t).Named(fmt.Sprintf("coin=%d", i))),
+		)
+		instances[i].StartEpoch(context.Background(), epoch)
+	}
+
+	var wg sync.WaitGroup
+	for _, instance := range instances {
+		instance := instance
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			instance.StartRound(context.Background(), round, nonces)
+		}()
+	}
+	wg.Wait()
+	for _, instance := range instances {
+		instance.FinishRound(context.Background())
+	}
+
+	// every proposal would be relayed by every node without deduplication
+	require.Less(t, relayed.Load(), int64(size*size/10))
+	expected, err := instances[0].Get(context.Background(), epoch, round)
+	require.NoError(t, err)
+	for _, instance := range instances[1:] {
+		flip, err := instance.Get(context.Background(), epoch, round)
+		require.NoError(t, err)
+		require.Equal(t, expected, flip)
+	}
+}
+
 func TestWeakCoinEncodingRegression(t *testing.T) {
 	ctrl := gomock.NewController(t)
 
@@ -438,8 +650,7 @@ func TestWeakCoinEncodingRegression(t *testing.T) {
 		weakcoin.WithLog(logtest.New(t)),
 	)
 	instance.StartEpoch(context.Background(), epoch)
-	nonce := types.VRFPostIndex(1)
-	instance.StartRound(context.Background(), round, &nonce)
+	instance.StartRound(context.Background(), round, map[types.NodeID]types.VRFPostIndex{signer.NodeID(): 1})
 
 	require.Equal(t,
 		"78f523319fd2cdf3812a3bc3905561acb2f7f1b7e47de71f92811d7bb82460e5999a048051cefa2d1b6f3f16656de83c2756b7539b33fa563a3e8fea5130235e66e8dce914d69bd40f13174f3914ad07",
@@ -453,7 +664,7 @@ func TestWeakCoinExchangeProposals(t *testing.T) {
 	var (
 		instances                          = make([]*weakcoin.WeakCoin, 10)
 		broadcasters                       = make([]*mocks.MockPublisher, 10)
-		vrfSigners                         = make([]*signing.VRFSigner, 10)
+		vrfSigners                         = make([]signing.VRFSigner, 10)
 		epochStart, epochEnd types.EpochID = 2, 6
 		start, end           types.RoundID = 0, 9
 		rng                                = rand.New(rand.NewSource(999))
@@ -494,7 +705,10 @@ func TestWeakCoinExchangeProposals(t *testing.T) {
 		)
 	}
 
-	nonce := types.VRFPostIndex(1)
+	nonces := map[types.NodeID]types.VRFPostIndex{}
+	for _, signer := range vrfSigners {
+		nonces[signer.NodeID()] = 1
+	}
 	for epoch := epochStart; epoch <= epochEnd; epoch++ {
 		for _, instance := range instances {
 			instance.StartEpoch(context.Background(), epoch)
@@ -504,7 +718,7 @@ func TestWeakCoinExchangeProposals(t *testing.T) {
 				if i == 0 {
 					instance.StartRound(context.Background(), current, nil)
 				} else {
-					instance.StartRound(context.Background(), current, &nonce)
+					instance.StartRound(context.Background(), current, nonces)
 				}
 			}
 			for _, instance := range instances {
@@ -11,6 +11,7 @@ import (
 	"github.com/spacemeshos/go-spacemesh/codec"
 	"github.com/spacemeshos/go-spacemesh/common/types"
 	"github.com/spacemeshos/go-spacemesh/datastore"
+	"github.com/spacemeshos/go-spacemesh/events"
 	"github.com/spacemeshos/go-spacemesh/hare"
 	"github.com/spacemeshos/go-spacemesh/hare/eligibility"
 	"github.com/spacemeshos/go-spacemesh/log"
@@ -36,6 +37,8 @@ type CertConfig struct {
 	CertifyThreshold int
 	LayerBuffer      uint32
 	NumLayersToKeep  uint32
+	// SignatureDomainLayer is the first layer where certify messages are signed in the CERTIFY domain.
+	SignatureDomainLayer types.LayerID
 }
 
 func defaultCertConfig() CertConfig {
@@ -89,7 +92,7 @@ type Certifier struct {
 	db         *datastore.CachedDB
 	oracle     hare.Rolacle
 	nodeID     types.NodeID
-	signer     *signing.EdSigner
+	signer     signing.Signer
 	edVerifier *signing.EdVerifier
 	publisher  pubsub.Publisher
 	layerClock layerClock
@@ -108,7 +111,7 @@ func NewCertifier(
 	db *datastore.CachedDB,
 	o hare.Rolacle,
 	n types.NodeID,
-	s *signing.EdSigner,
+	s signing.Signer,
 	v *signing.EdVerifier,
 	p pubsub.Publisher,
 	lc layerClock,
@@ -249,7 +252,11 @@ func (c *Certifier) CertifyIfEligible(ctx context.Context, logger log.Log, lid t
 		},
 		SmesherID: c.nodeID,
 	}
-	msg.Signature = c.signer.Sign(signing.HARE, msg.Bytes())
+	msg.Signature, err = c.signer.SignContext(ctx, signing.CertifyDomain(c.cfg.SignatureDomainLayer, lid), msg.Bytes())
+	if err != nil {
+		logger.With().Error("failed to sign certify message", log.Err(err))
+		return err
+	}
 	data, err := codec.Encode(&msg)
 	if err != nil {
 		logger.With().Panic("failed to serialize certify message", log.Err(err))
@@ -374,7 +381,7 @@ func (c *Certifier) handleCertifyMessage(ctx context.Context, _ p2p.Peer, data [
 }
 
 func (c *Certifier) validate(ctx context.Context, logger log.Log, msg types.CertifyMessage) error {
-	if !c.edVerifier.Verify(signing.HARE, msg.SmesherID, msg.Bytes(), msg.Signature) {
+	if !c.edVerifier.Verify(signing.CertifyDomain(c.cfg.SignatureDomainLayer, msg.LayerID), msg.SmesherID, msg.Bytes(), msg.Signature) {
 		return fmt.Errorf("%w: failed to verify signature", errMalformedData)
 	}
 	valid, err := c.oracle.Validate(ctx, msg.LayerID, eligibility.CertifyRound, c.cfg.CommitteeSize, msg.SmesherID, msg.Proof, msg.EligibilityCnt)
@@ -476,6 +483,7 @@ func (c *Certifier) checkAndSave(ctx context.Context, logger log.Log, lid types.
 	}
 	if len(valid) > 0 {
 		logger.Warning("multiple valid certificates found")
+		events.ReportConflictingCertificates(lid, append(valid, cert.BlockID))
 		// stop processing certify message for this block
 		if _, ok := c.certifyMsgs[lid]; ok {
 			if _, ok = c.certifyMsgs[lid][cert.BlockID]; ok {
@@ -5,6 +5,7 @@ import (
 	"errors"
 	"sync"
 	"testing"
+	"time"
 
 	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/require"
@@ -13,6 +14,7 @@ import (
 	"github.com/spacemeshos/go-spacemesh/codec"
 	"github.com/spacemeshos/go-spacemesh/common/types"
 	"github.com/spacemeshos/go-spacemesh/datastore"
+	"github.com/spacemeshos/go-spacemesh/events"
 	"github.com/spacemeshos/go-spacemesh/hare/eligibility"
 	hmocks "github.com/spacemeshos/go-spacemesh/hare/mocks"
 	"github.com/spacemeshos/go-spacemesh/log/logtest"
@@ -217,6 +219,11 @@ func Test_HandleSyncedCertificate_MultipleCertificates(t *testing.T) {
 	for _, tc := range tt {
 		tc := tc
 		t.Run(tc.name, func(t *testing.T) {
+			events.InitializeReporter()
+			t.Cleanup(events.CloseEventReporter)
+			sub, err := events.Subscribe[events.ConflictingCertificates]()
+			require.NoError(t, err)
+
 			tcc := newTestCertifier(t)
 			numMsgs := tcc.cfg.CertifyThreshold / int(defaultCnt)
 			b := generateBlock(t, tcc.db)
@@ -267,6 +274,12 @@ func Test_HandleSyncedCertificate_MultipleCertificates(t *testing.T) {
 				expected[b.ID()] = true
 				expected[bid] = true
 				require.Empty(t, tcc.CertCount())
+				select {
+				case ev := <-sub.Out():
+					require.Equal(t, events.ConflictingCertificates{Layer: b.LayerIndex, Blocks: []types.BlockID{bid, b.ID()}}, ev)
+				case <-time.After(time.Second):
+					require.Fail(t, "timeout")
+				}
 			}
 			verifyCerts(t, tcc.db, b.LayerIndex, expected)
 		})
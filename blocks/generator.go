@@ -37,8 +37,10 @@ type Generator struct {
 	fetcher  system.ProposalFetcher
 	cert     certifier
 	patrol   layerPatrol
+	clock    layerClock
+	results  hareResults
 
-	hareCh           chan hare.LayerOutput
+	hareCh           chan layerOutput
 	optimisticOutput map[types.LayerID]*proposalMetadata
 }
 
@@ -85,13 +87,21 @@ func WithGeneratorLogger(logger log.Log) GeneratorOpt {
 	}
 }
 
-// WithHareOutputChan sets the chan to listen to hare output.
-func WithHareOutputChan(ch chan hare.LayerOutput) GeneratorOpt {
+// WithHareResults sets the source of hare output, which is queried for every layer of the clock.
+func WithHareResults(clock layerClock, results hareResults) GeneratorOpt {
 	return func(g *Generator) {
-		g.hareCh = ch
+		g.clock = clock
+		g.results = results
 	}
 }
 
+// layerOutput is the hare output for a layer.
+type layerOutput struct {
+	Ctx       context.Context
+	Layer     types.LayerID
+	Proposals []types.ProposalID
+}
+
 // NewGenerator creates new block generator.
 func NewGenerator(
 	cdb *datastore.CachedDB,
@@ -112,6 +122,7 @@ func NewGenerator(
 		fetcher:          f,
 		cert:             c,
 		patrol:           p,
+		hareCh:           make(chan layerOutput, 100),
 		optimisticOutput: map[types.LayerID]*proposalMetadata{},
 	}
 	for _, opt := range opts {
@@ -127,6 +138,11 @@ func (g *Generator) Start() {
 		g.eg.Go(func() error {
 			return g.run()
 		})
+		if g.results != nil {
+			g.eg.Go(func() error {
+				return g.awaitResults()
+			})
+		}
 	})
 }
 
@@ -171,6 +187,40 @@ func (g *Generator) run() error {
 	}
 }
 
+// awaitResults waits for the hare output of every layer starting from the current one.
+func (g *Generator) awaitResults() error {
+	for lid := g.clock.CurrentLayer(); ; lid = lid.Add(1) {
+		select {
+		case <-g.ctx.Done():
+			return fmt.Errorf("context done: %w", g.ctx.Err())
+		case <-g.clock.AwaitLayer(lid):
+		}
+		lid := lid
+		g.eg.Go(func() error {
+			g.awaitResult(lid)
+			return nil
+		})
+	}
+}
+
+func (g *Generator) awaitResult(lid types.LayerID) {
+	pids, err := g.results.GetResult(g.ctx, lid)
+	switch {
+	case errors.Is(err, hare.ErrHareFailed), errors.Is(err, hare.ErrHareNotRun):
+		g.logger.With().Debug("no hare output for layer", lid, log.Err(err))
+		return
+	case err != nil:
+		if g.ctx.Err() == nil {
+			g.logger.With().Error("failed to get hare output", lid, log.Err(err))
+		}
+		return
+	}
+	select {
+	case g.hareCh <- layerOutput{Ctx: g.ctx, Layer: lid, Proposals: pids}:
+	case <-g.ctx.Done():
+	}
+}
+
 func (g *Generator) getProposals(pids []types.ProposalID) ([]*types.Proposal, error) {
 	var (
 		result = make([]*types.Proposal, 0, len(pids))
@@ -186,7 +236,11 @@ func (g *Generator) getProposals(pids []types.ProposalID) ([]*types.Proposal, er
 	return result, nil
 }
 
-func (g *Generator) processHareOutput(out hare.LayerOutput) (*types.Block, error) {
+// processHareOutput builds the block from the proposals agreed upon by hare. The transactions of the proposals
+// are merged and, if the proposals agree on the state, executed optimistically once the previous layer is applied.
+// An empty agreed set yields no block: the empty block is registered for certification and reported to the mesh
+// as the hare output for the layer.
+func (g *Generator) processHareOutput(out layerOutput) (*types.Block, error) {
 	var md *proposalMetadata
 	if len(out.Proposals) > 0 {
 		getMetadata := func() error {
@@ -286,7 +340,7 @@ func (g *Generator) processOptimisticLayers(max types.LayerID) {
 func (g *Generator) saveAndCertify(ctx context.Context, lid types.LayerID, block *types.Block) error {
 	hareOutput := types.EmptyBlockID
 	if block != nil {
-		if err := g.msh.AddBlockWithTXs(ctx, block); err != nil {
+		if _, err := g.msh.AddBlockWithTXs(ctx, block); err != nil {
 			failErrCnt.Inc()
 			return fmt.Errorf("post process add block: %w", err)
 		}
@@ -16,6 +16,7 @@ import (
 
 	"github.com/spacemeshos/go-spacemesh/activation"
 	"github.com/spacemeshos/go-spacemesh/blocks/mocks"
+	"github.com/spacemeshos/go-spacemesh/codec"
 	"github.com/spacemeshos/go-spacemesh/common/types"
 	"github.com/spacemeshos/go-spacemesh/datastore"
 	"github.com/spacemeshos/go-spacemesh/genvm/sdk/wallet"
@@ -75,7 +76,6 @@ func createTestGenerator(t *testing.T) *testGenerator {
 	cdb := datastore.NewCachedDB(sql.InMemory(), lg)
 	tg.Generator = NewGenerator(cdb, tg.mockExec, tg.mockMesh, tg.mockFetch, tg.mockCert, tg.mockPatrol,
 		WithGeneratorLogger(lg),
-		WithHareOutputChan(make(chan hare.LayerOutput, 100)),
 		WithConfig(testConfig()))
 	return tg
 }
@@ -234,7 +234,7 @@ func Test_StartStop(t *testing.T) {
 	tg.Stop()
 }
 
-func genData(t *testing.T, cdb *datastore.CachedDB, lid types.LayerID, optimistic bool) hare.LayerOutput {
+func genData(t *testing.T, cdb *datastore.CachedDB, lid types.LayerID, optimistic bool) layerOutput {
 	numTXs := 1000
 	numProposals := 10
 	txIDs := createAndSaveTxs(t, numTXs, cdb)
@@ -244,9 +244,9 @@ func genData(t *testing.T, cdb *datastore.CachedDB, lid types.LayerID, optimisti
 	if optimistic {
 		meshHash = types.RandomHash()
 	}
-	require.NoError(t, layers.SetMeshHash(cdb, lid.Sub(1), meshHash))
+	require.NoError(t, layers.SetAggregatedHash(cdb, lid.Sub(1), meshHash))
 	plist := createProposals(t, cdb, lid, meshHash, signers, activeSet, txIDs)
-	return hare.LayerOutput{
+	return layerOutput{
 		Ctx:       context.Background(),
 		Layer:     lid,
 		Proposals: types.ToProposalIDs(plist),
@@ -337,7 +337,7 @@ func Test_run(t *testing.T) {
 			if tc.optimistic {
 				meshHash = types.RandomHash()
 			}
-			require.NoError(t, layers.SetMeshHash(tg.cdb, layerID.Sub(1), meshHash))
+			require.NoError(t, layers.SetAggregatedHash(tg.cdb, layerID.Sub(1), meshHash))
 			// create multiple proposals with overlapping TXs
 			numProposals := 10
 			txIDs := createAndSaveTxs(t, numTXs, tg.cdb)
@@ -365,7 +365,7 @@ func Test_run(t *testing.T) {
 					})
 			}
 			tg.mockMesh.EXPECT().AddBlockWithTXs(gomock.Any(), gomock.Any()).DoAndReturn(
-				func(_ context.Context, got *types.Block) error {
+				func(_ context.Context, got *types.Block) (bool, error) {
 					if tc.optimistic {
 						require.Equal(t, block, got)
 					} else {
@@ -379,7 +379,7 @@ func Test_run(t *testing.T) {
 					// the expected weight for each eligibility is `numUnit` * 1/3
 					expWeight := new(big.Rat).SetInt64(numUnit * 1 / 3)
 					checkRewards(t, atxes, expWeight, block.Rewards)
-					return nil
+					return true, nil
 				})
 			tg.mockCert.EXPECT().RegisterForCert(gomock.Any(), layerID, gomock.Any()).DoAndReturn(
 				func(_ context.Context, _ types.LayerID, got types.BlockID) error {
@@ -398,7 +398,7 @@ func Test_run(t *testing.T) {
 				})
 			tg.mockPatrol.EXPECT().CompleteHare(layerID)
 			tg.Start()
-			tg.hareCh <- hare.LayerOutput{Ctx: context.Background(), Layer: layerID, Proposals: pids}
+			tg.hareCh <- layerOutput{Ctx: context.Background(), Layer: layerID, Proposals: pids}
 			require.Eventually(t, func() bool { return len(tg.hareCh) == 0 }, time.Second, 100*time.Millisecond)
 			tg.Stop()
 		})
@@ -414,11 +414,60 @@ func Test_processHareOutput_EmptyOutput(t *testing.T) {
 	tg.mockCert.EXPECT().CertifyIfEligible(gomock.Any(), gomock.Any(), layerID, types.EmptyBlockID)
 	tg.mockMesh.EXPECT().ProcessLayerPerHareOutput(gomock.Any(), layerID, types.EmptyBlockID, false)
 	tg.mockPatrol.EXPECT().CompleteHare(layerID)
-	tg.hareCh <- hare.LayerOutput{Ctx: context.Background(), Layer: layerID}
+	tg.hareCh <- layerOutput{Ctx: context.Background(), Layer: layerID}
 	require.Eventually(t, func() bool { return len(tg.hareCh) == 0 }, time.Second, 100*time.Millisecond)
 	tg.Stop()
 }
 
+func Test_run_HareResults(t *testing.T) {
+	tg := createTestGenerator(t)
+	ctrl := gomock.NewController(t)
+	clock := mocks.NewMocklayerClock(ctrl)
+	results := mocks.NewMockhareResults(ctrl)
+	tg.clock = clock
+	tg.results = results
+	layerID := types.GetEffectiveGenesis().Add(100)
+	require.NoError(t, layers.SetApplied(tg.cdb, layerID-2, types.EmptyBlockID))
+
+	ready := make(chan struct{})
+	close(ready)
+	clock.EXPECT().CurrentLayer().Return(layerID - 1)
+	clock.EXPECT().AwaitLayer(layerID - 1).Return(ready)
+	clock.EXPECT().AwaitLayer(layerID).Return(ready)
+	clock.EXPECT().AwaitLayer(layerID + 1).Return(make(chan struct{}))
+	results.EXPECT().GetResult(gomock.Any(), layerID-1).Return(nil, hare.ErrHareNotRun)
+	results.EXPECT().GetResult(gomock.Any(), layerID).Return([]types.ProposalID{}, nil)
+
+	done := make(chan struct{})
+	tg.mockCert.EXPECT().RegisterForCert(gomock.Any(), layerID, types.EmptyBlockID)
+	tg.mockCert.EXPECT().CertifyIfEligible(gomock.Any(), gomock.Any(), layerID, types.EmptyBlockID)
+	tg.mockMesh.EXPECT().ProcessLayerPerHareOutput(gomock.Any(), layerID, types.EmptyBlockID, false)
+	tg.mockPatrol.EXPECT().CompleteHare(layerID).Do(func(types.LayerID) { close(done) })
+	tg.Start()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		require.FailNow(t, "hare output is not processed")
+	}
+	tg.Stop()
+}
+
+func Test_processHareOutput_EmptySet(t *testing.T) {
+	tg := createTestGenerator(t)
+	layerID := types.GetEffectiveGenesis().Add(100)
+	// the layer is not executed optimistically even if it is next to the applied one
+	require.NoError(t, layers.SetApplied(tg.cdb, layerID-1, types.EmptyBlockID))
+	ho := layerOutput{Ctx: context.Background(), Layer: layerID, Proposals: []types.ProposalID{}}
+	tg.mockCert.EXPECT().RegisterForCert(ho.Ctx, layerID, types.EmptyBlockID)
+	tg.mockCert.EXPECT().CertifyIfEligible(ho.Ctx, gomock.Any(), layerID, types.EmptyBlockID)
+	tg.mockMesh.EXPECT().ProcessLayerPerHareOutput(ho.Ctx, layerID, types.EmptyBlockID, false)
+	tg.mockPatrol.EXPECT().CompleteHare(layerID)
+	got, err := tg.processHareOutput(ho)
+	require.NoError(t, err)
+	require.Nil(t, got)
+	require.Empty(t, tg.optimisticOutput)
+}
+
 func Test_run_FetchFailed(t *testing.T) {
 	tg := createTestGenerator(t)
 	layerID := types.GetEffectiveGenesis().Add(100)
@@ -430,7 +479,7 @@ func Test_run_FetchFailed(t *testing.T) {
 			return errors.New("unknown")
 		})
 	tg.mockPatrol.EXPECT().CompleteHare(layerID)
-	tg.hareCh <- hare.LayerOutput{Ctx: context.Background(), Layer: layerID, Proposals: pids}
+	tg.hareCh <- layerOutput{Ctx: context.Background(), Layer: layerID, Proposals: pids}
 	require.Eventually(t, func() bool { return len(tg.hareCh) == 0 }, time.Second, 100*time.Millisecond)
 	tg.Stop()
 }
@@ -448,11 +497,11 @@ func Test_run_DiffHasFromConsensus(t *testing.T) {
 	meshHash := types.RandomHash()
 	plist := createProposals(t, tg.cdb, layerID, meshHash, signers, activeSet, txIDs)
 	pids := types.ToProposalIDs(plist)
-	require.NoError(t, layers.SetMeshHash(tg.cdb, layerID.Sub(1), types.RandomHash()))
+	require.NoError(t, layers.SetAggregatedHash(tg.cdb, layerID.Sub(1), types.RandomHash()))
 
 	tg.mockFetch.EXPECT().GetProposals(gomock.Any(), pids)
 	tg.mockPatrol.EXPECT().CompleteHare(layerID)
-	tg.hareCh <- hare.LayerOutput{Ctx: context.Background(), Layer: layerID, Proposals: pids}
+	tg.hareCh <- layerOutput{Ctx: context.Background(), Layer: layerID, Proposals: pids}
 	require.Eventually(t, func() bool { return len(tg.hareCh) == 0 }, time.Second, 100*time.Millisecond)
 	tg.Stop()
 }
@@ -468,7 +517,7 @@ func Test_run_ExecuteFailed(t *testing.T) {
 	meshHash := types.RandomHash()
 	plist := createProposals(t, tg.cdb, layerID, meshHash, signers, activeSet, txIDs)
 	pids := types.ToProposalIDs(plist)
-	require.NoError(t, layers.SetMeshHash(tg.cdb, layerID.Sub(1), meshHash))
+	require.NoError(t, layers.SetAggregatedHash(tg.cdb, layerID.Sub(1), meshHash))
 
 	tg.mockFetch.EXPECT().GetProposals(gomock.Any(), pids)
 	tg.mockExec.EXPECT().ExecuteOptimistic(gomock.Any(), layerID, uint64(baseTickHeight), gomock.Any(), gomock.Any()).DoAndReturn(
@@ -477,7 +526,7 @@ func Test_run_ExecuteFailed(t *testing.T) {
 			return nil, errors.New("unknown")
 		})
 	tg.mockPatrol.EXPECT().CompleteHare(layerID)
-	tg.hareCh <- hare.LayerOutput{Ctx: context.Background(), Layer: layerID, Proposals: pids}
+	tg.hareCh <- layerOutput{Ctx: context.Background(), Layer: layerID, Proposals: pids}
 	require.Eventually(t, func() bool { return len(tg.hareCh) == 0 }, time.Second, 100*time.Millisecond)
 	tg.Stop()
 }
@@ -493,14 +542,14 @@ func Test_run_AddBlockFailed(t *testing.T) {
 	meshHash := types.RandomHash()
 	plist := createProposals(t, tg.cdb, layerID, meshHash, signers, activeSet, txIDs)
 	pids := types.ToProposalIDs(plist)
-	require.NoError(t, layers.SetMeshHash(tg.cdb, layerID.Sub(1), meshHash))
+	require.NoError(t, layers.SetAggregatedHash(tg.cdb, layerID.Sub(1), meshHash))
 
 	tg.mockFetch.EXPECT().GetProposals(gomock.Any(), pids)
 	block := types.NewExistingBlock(types.BlockID{1, 2, 3}, types.InnerBlock{LayerIndex: layerID})
 	tg.mockExec.EXPECT().ExecuteOptimistic(gomock.Any(), layerID, uint64(baseTickHeight), gomock.Any(), gomock.Any()).Return(block, nil)
-	tg.mockMesh.EXPECT().AddBlockWithTXs(gomock.Any(), gomock.Any()).Return(errors.New("unknown"))
+	tg.mockMesh.EXPECT().AddBlockWithTXs(gomock.Any(), gomock.Any()).Return(false, errors.New("unknown"))
 	tg.mockPatrol.EXPECT().CompleteHare(layerID)
-	tg.hareCh <- hare.LayerOutput{Ctx: context.Background(), Layer: layerID, Proposals: pids}
+	tg.hareCh <- layerOutput{Ctx: context.Background(), Layer: layerID, Proposals: pids}
 	require.Eventually(t, func() bool { return len(tg.hareCh) == 0 }, time.Second, 100*time.Millisecond)
 	tg.Stop()
 }
@@ -516,7 +565,7 @@ func Test_run_RegisterCertFailureIgnored(t *testing.T) {
 	meshHash := types.RandomHash()
 	plist := createProposals(t, tg.cdb, layerID, meshHash, signers, activeSet, txIDs)
 	pids := types.ToProposalIDs(plist)
-	require.NoError(t, layers.SetMeshHash(tg.cdb, layerID.Sub(1), meshHash))
+	require.NoError(t, layers.SetAggregatedHash(tg.cdb, layerID.Sub(1), meshHash))
 
 	tg.mockFetch.EXPECT().GetProposals(gomock.Any(), pids)
 	block := types.NewExistingBlock(types.BlockID{1, 2, 3}, types.InnerBlock{LayerIndex: layerID})
@@ -526,7 +575,7 @@ func Test_run_RegisterCertFailureIgnored(t *testing.T) {
 	tg.mockCert.EXPECT().CertifyIfEligible(gomock.Any(), gomock.Any(), layerID, gomock.Any())
 	tg.mockMesh.EXPECT().ProcessLayerPerHareOutput(gomock.Any(), layerID, block.ID(), true)
 	tg.mockPatrol.EXPECT().CompleteHare(layerID)
-	tg.hareCh <- hare.LayerOutput{Ctx: context.Background(), Layer: layerID, Proposals: pids}
+	tg.hareCh <- layerOutput{Ctx: context.Background(), Layer: layerID, Proposals: pids}
 	require.Eventually(t, func() bool { return len(tg.hareCh) == 0 }, time.Second, 100*time.Millisecond)
 	tg.Stop()
 }
@@ -542,7 +591,7 @@ func Test_run_CertifyFailureIgnored(t *testing.T) {
 	meshHash := types.RandomHash()
 	plist := createProposals(t, tg.cdb, layerID, meshHash, signers, activeSet, txIDs)
 	pids := types.ToProposalIDs(plist)
-	require.NoError(t, layers.SetMeshHash(tg.cdb, layerID.Sub(1), meshHash))
+	require.NoError(t, layers.SetAggregatedHash(tg.cdb, layerID.Sub(1), meshHash))
 
 	tg.mockFetch.EXPECT().GetProposals(gomock.Any(), pids)
 	block := types.NewExistingBlock(types.BlockID{1, 2, 3}, types.InnerBlock{LayerIndex: layerID})
@@ -552,7 +601,7 @@ func Test_run_CertifyFailureIgnored(t *testing.T) {
 	tg.mockCert.EXPECT().CertifyIfEligible(gomock.Any(), gomock.Any(), layerID, gomock.Any()).Return(errors.New("unknown"))
 	tg.mockMesh.EXPECT().ProcessLayerPerHareOutput(gomock.Any(), layerID, block.ID(), true)
 	tg.mockPatrol.EXPECT().CompleteHare(layerID)
-	tg.hareCh <- hare.LayerOutput{Ctx: context.Background(), Layer: layerID, Proposals: pids}
+	tg.hareCh <- layerOutput{Ctx: context.Background(), Layer: layerID, Proposals: pids}
 	require.Eventually(t, func() bool { return len(tg.hareCh) == 0 }, time.Second, 100*time.Millisecond)
 	tg.Stop()
 }
@@ -568,7 +617,7 @@ func Test_run_ProcessLayerFailed(t *testing.T) {
 	meshHash := types.RandomHash()
 	plist := createProposals(t, tg.cdb, layerID, meshHash, signers, activeSet, txIDs)
 	pids := types.ToProposalIDs(plist)
-	require.NoError(t, layers.SetMeshHash(tg.cdb, layerID.Sub(1), meshHash))
+	require.NoError(t, layers.SetAggregatedHash(tg.cdb, layerID.Sub(1), meshHash))
 
 	tg.mockFetch.EXPECT().GetProposals(gomock.Any(), pids)
 	block := types.NewExistingBlock(types.BlockID{1, 2, 3}, types.InnerBlock{LayerIndex: layerID})
@@ -578,7 +627,7 @@ func Test_run_ProcessLayerFailed(t *testing.T) {
 	tg.mockCert.EXPECT().CertifyIfEligible(gomock.Any(), gomock.Any(), layerID, gomock.Any())
 	tg.mockMesh.EXPECT().ProcessLayerPerHareOutput(gomock.Any(), layerID, block.ID(), true).Return(errors.New("unknown"))
 	tg.mockPatrol.EXPECT().CompleteHare(layerID)
-	tg.hareCh <- hare.LayerOutput{Ctx: context.Background(), Layer: layerID, Proposals: pids}
+	tg.hareCh <- layerOutput{Ctx: context.Background(), Layer: layerID, Proposals: pids}
 	require.Eventually(t, func() bool { return len(tg.hareCh) == 0 }, time.Second, 100*time.Millisecond)
 	tg.Stop()
 }
@@ -598,7 +647,7 @@ func Test_processHareOutput_UnequalHeight(t *testing.T) {
 	})
 	activeSet := types.ToATXIDs(atxes)
 	pList := createProposals(t, tg.cdb, layerID, types.Hash32{}, signers, activeSet, nil)
-	ho := hare.LayerOutput{
+	ho := layerOutput{
 		Ctx:       context.Background(),
 		Layer:     layerID,
 		Proposals: types.ToProposalIDs(pList),
@@ -606,9 +655,9 @@ func Test_processHareOutput_UnequalHeight(t *testing.T) {
 	tg.mockFetch.EXPECT().GetProposals(ho.Ctx, ho.Proposals)
 	var block *types.Block
 	tg.mockMesh.EXPECT().AddBlockWithTXs(ho.Ctx, gomock.Any()).DoAndReturn(
-		func(_ context.Context, b *types.Block) error {
+		func(_ context.Context, b *types.Block) (bool, error) {
 			block = b
-			return nil
+			return true, nil
 		})
 	tg.mockCert.EXPECT().RegisterForCert(ho.Ctx, layerID, gomock.Any()).DoAndReturn(
 		func(_ context.Context, _ types.LayerID, bid types.BlockID) error {
@@ -645,7 +694,7 @@ func Test_processHareOutput_bad_state(t *testing.T) {
 
 	t.Run("tx missing", func(t *testing.T) {
 		p := createProposal(t, tg.cdb, activeSet, layerID, types.Hash32{}, activeSet[0], signers[0], []types.TransactionID{types.RandomTransactionID()}, 1)
-		ho := hare.LayerOutput{
+		ho := layerOutput{
 			Ctx:       context.Background(),
 			Layer:     layerID,
 			Proposals: types.ToProposalIDs([]*types.Proposal{p}),
@@ -663,7 +712,7 @@ func Test_processHareOutput_bad_state(t *testing.T) {
 		}
 		require.NoError(t, transactions.Add(tg.cdb, &tx, time.Now()))
 		p := createProposal(t, tg.cdb, activeSet, layerID, types.Hash32{}, activeSet[0], signers[0], []types.TransactionID{tx.ID}, 1)
-		ho := hare.LayerOutput{
+		ho := layerOutput{
 			Ctx:       context.Background(),
 			Layer:     layerID,
 			Proposals: types.ToProposalIDs([]*types.Proposal{p}),
@@ -687,7 +736,7 @@ func Test_processHareOutput_EmptyProposals(t *testing.T) {
 		p := createProposal(t, tg.cdb, activeSet, lid, types.Hash32{}, activeSet[i], signers[i], nil, 1)
 		plist = append(plist, p)
 	}
-	ho := hare.LayerOutput{
+	ho := layerOutput{
 		Ctx:       context.Background(),
 		Layer:     lid,
 		Proposals: types.ToProposalIDs(plist),
@@ -695,9 +744,9 @@ func Test_processHareOutput_EmptyProposals(t *testing.T) {
 	tg.mockFetch.EXPECT().GetProposals(ho.Ctx, ho.Proposals)
 	var block *types.Block
 	tg.mockMesh.EXPECT().AddBlockWithTXs(ho.Ctx, gomock.Any()).DoAndReturn(
-		func(_ context.Context, b *types.Block) error {
+		func(_ context.Context, b *types.Block) (bool, error) {
 			block = b
-			return nil
+			return true, nil
 		})
 	tg.mockCert.EXPECT().RegisterForCert(ho.Ctx, lid, gomock.Any()).DoAndReturn(
 		func(_ context.Context, _ types.LayerID, bid types.BlockID) error {
@@ -736,7 +785,7 @@ func Test_processHareOutput_StableBlockID(t *testing.T) {
 	signers, atxes := createATXs(t, tg.cdb, (layerID.GetEpoch() - 1).FirstLayer(), numProposals)
 	activeSet := types.ToATXIDs(atxes)
 	plist := createProposals(t, tg.cdb, layerID, types.Hash32{}, signers, activeSet, txIDs)
-	ho1 := hare.LayerOutput{
+	ho1 := layerOutput{
 		Ctx:       context.Background(),
 		Layer:     layerID,
 		Proposals: types.ToProposalIDs(plist),
@@ -757,7 +806,7 @@ func Test_processHareOutput_StableBlockID(t *testing.T) {
 	ordered := plist[numProposals/2 : numProposals]
 	ordered = append(ordered, plist[0:numProposals/2]...)
 	require.NotEqual(t, plist, ordered)
-	ho2 := hare.LayerOutput{
+	ho2 := layerOutput{
 		Ctx:       context.Background(),
 		Layer:     layerID,
 		Proposals: types.ToProposalIDs(ordered),
@@ -786,7 +835,7 @@ func Test_processHareOutput_SameATX(t *testing.T) {
 	proposal1 := createProposal(t, tg.cdb, activeSet, layerID, types.Hash32{}, atxID, signers[0], txIDs[0:500], 1)
 	proposal2 := createProposal(t, tg.cdb, activeSet, layerID, types.Hash32{}, atxID, signers[0], txIDs[400:], 1)
 	plist := []*types.Proposal{proposal1, proposal2}
-	ho := hare.LayerOutput{
+	ho := layerOutput{
 		Ctx:       context.Background(),
 		Layer:     layerID,
 		Proposals: types.ToProposalIDs(plist),
@@ -809,7 +858,7 @@ func Test_processHareOutput_EmptyATXID(t *testing.T) {
 	plist := createProposals(t, tg.cdb, layerID, types.Hash32{}, signers[1:], activeSet[1:], txIDs[1:])
 	p := createProposal(t, tg.cdb, activeSet, layerID, types.Hash32{}, types.EmptyATXID, signers[0], txIDs, 1)
 	plist = append(plist, p)
-	ho := hare.LayerOutput{
+	ho := layerOutput{
 		Ctx:       context.Background(),
 		Layer:     layerID,
 		Proposals: types.ToProposalIDs(plist),
@@ -832,7 +881,7 @@ func Test_processHareOutput_MultipleEligibilities(t *testing.T) {
 		createProposal(t, tg.cdb, activeSet, layerID, types.Hash32{}, atxes[1].ID(), signers[1], ids, 1),
 		createProposal(t, tg.cdb, activeSet, layerID, types.Hash32{}, atxes[2].ID(), signers[2], ids, 5),
 	}
-	ho := hare.LayerOutput{
+	ho := layerOutput{
 		Ctx:       context.Background(),
 		Layer:     layerID,
 		Proposals: types.ToProposalIDs(plist),
@@ -861,3 +910,84 @@ func Test_processHareOutput_MultipleEligibilities(t *testing.T) {
 	}
 	require.Equal(t, new(big.Rat).SetInt64(numUnit*8*1/3), totalWeight)
 }
+
+func Test_processHareOutput_DuplicateTXs(t *testing.T) {
+	tg1 := createTestGenerator(t)
+	tg2 := createTestGenerator(t)
+	layerID := types.GetEffectiveGenesis().Add(100)
+	numProposals := 3
+
+	sender, err := signing.NewEdSigner()
+	require.NoError(t, err)
+	var (
+		shared   = genTx(t, sender, types.Address{1}, 100, 1, 10)
+		conflict = []types.Transaction{
+			genTx(t, sender, types.Address{1}, 200, 2, 10),
+			genTx(t, sender, types.Address{1}, 300, 2, 20),
+			genTx(t, sender, types.Address{1}, 400, 2, 30),
+		}
+		unique []types.TransactionID
+		all    = append([]types.Transaction{shared}, conflict...)
+	)
+	for i := 0; i < numProposals; i++ {
+		signer, err := signing.NewEdSigner()
+		require.NoError(t, err)
+		tx := genTx(t, signer, types.Address{1}, 100, 1, 10)
+		unique = append(unique, tx.ID)
+		all = append(all, tx)
+	}
+	for _, db := range []sql.Executor{tg1.cdb, tg2.cdb} {
+		for _, tx := range all {
+			require.NoError(t, transactions.Add(db, &tx, time.Now()))
+		}
+	}
+
+	var vatxs []*types.VerifiedActivationTx
+	signers, atxes := createModifiedATXs(t, tg1.cdb, (layerID.GetEpoch() - 1).FirstLayer(), numProposals,
+		func(atx *types.ActivationTx) (*types.VerifiedActivationTx, error) {
+			vatx, err := atx.Verify(baseTickHeight, 1)
+			vatxs = append(vatxs, vatx)
+			return vatx, err
+		})
+	activeSet := types.ToATXIDs(atxes)
+	plist := make([]*types.Proposal, 0, numProposals)
+	for i := 0; i < numProposals; i++ {
+		tids := []types.TransactionID{shared.ID, conflict[i].ID, unique[i]}
+		plist = append(plist, createProposal(t, tg1.cdb, activeSet, layerID, types.Hash32{}, activeSet[i], signers[i], tids, 1))
+	}
+	for i, p := range plist {
+		require.NoError(t, atxs.Add(tg2.cdb, vatxs[i]))
+		require.NoError(t, ballots.Add(tg2.cdb, &p.Ballot))
+		require.NoError(t, proposals.Add(tg2.cdb, p))
+	}
+
+	process := func(tg *testGenerator, ordered []*types.Proposal) *types.Block {
+		ho := layerOutput{
+			Ctx:       context.Background(),
+			Layer:     layerID,
+			Proposals: types.ToProposalIDs(ordered),
+		}
+		tg.mockFetch.EXPECT().GetProposals(ho.Ctx, ho.Proposals)
+		tg.mockMesh.EXPECT().AddBlockWithTXs(ho.Ctx, gomock.Any())
+		tg.mockCert.EXPECT().RegisterForCert(ho.Ctx, layerID, gomock.Any())
+		tg.mockCert.EXPECT().CertifyIfEligible(ho.Ctx, gomock.Any(), layerID, gomock.Any()).Return(eligibility.ErrNotActive)
+		tg.mockMesh.EXPECT().ProcessLayerPerHareOutput(gomock.Any(), layerID, gomock.Any(), false)
+		tg.mockPatrol.EXPECT().CompleteHare(layerID)
+		block, err := tg.processHareOutput(ho)
+		require.NoError(t, err)
+		require.NotNil(t, block)
+		return block
+	}
+	block1 := process(tg1, plist)
+	block2 := process(tg2, []*types.Proposal{plist[2], plist[0], plist[1]})
+	require.Equal(t, codec.MustEncode(block1), codec.MustEncode(block2))
+
+	// the conflicting tx of the proposal with the lowest id wins
+	first := 0
+	for i, p := range plist {
+		if p.ID().Compare(plist[first].ID()) {
+			first = i
+		}
+	}
+	require.ElementsMatch(t, append([]types.TransactionID{shared.ID, conflict[first].ID}, unique...), block1.TxIDs)
+}
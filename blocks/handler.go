@@ -89,7 +89,7 @@ func (h *Handler) HandleSyncedBlock(ctx context.Context, peer p2p.Peer, data []b
 		return err
 	}
 
-	if err := h.mesh.AddBlockWithTXs(ctx, &b); err != nil {
+	if _, err := h.mesh.AddBlockWithTXs(ctx, &b); err != nil {
 		logger.With().Error("failed to save block", log.Err(err))
 		return fmt.Errorf("save block: %w", err)
 	}
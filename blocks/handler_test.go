@@ -96,7 +96,7 @@ func Test_HandleBlockData_FailedToAddBlock(t *testing.T) {
 	block, data := createBlockData(t, layerID, txIDs)
 	th.mockFetcher.EXPECT().GetBlockTxs(gomock.Any(), txIDs).Return(nil).Times(1)
 	errUnknown := errors.New("unknown")
-	th.mockMesh.EXPECT().AddBlockWithTXs(gomock.Any(), block).Return(errUnknown).Times(1)
+	th.mockMesh.EXPECT().AddBlockWithTXs(gomock.Any(), block).Return(false, errUnknown).Times(1)
 	peer := p2p.Peer("buddy")
 	th.mockFetcher.EXPECT().RegisterPeerHashes(peer, types.TransactionIDsToHashes(block.TxIDs))
 	assert.ErrorIs(t, th.HandleSyncedBlock(context.TODO(), peer, data), errUnknown)
@@ -109,7 +109,7 @@ func Test_HandleBlockData(t *testing.T) {
 
 	block, data := createBlockData(t, layerID, txIDs)
 	th.mockFetcher.EXPECT().GetBlockTxs(gomock.Any(), txIDs).Return(nil).Times(1)
-	th.mockMesh.EXPECT().AddBlockWithTXs(gomock.Any(), block).Return(nil).Times(1)
+	th.mockMesh.EXPECT().AddBlockWithTXs(gomock.Any(), block).Return(true, nil).Times(1)
 	peer := p2p.Peer("buddy")
 	th.mockFetcher.EXPECT().RegisterPeerHashes(peer, types.TransactionIDsToHashes(block.TxIDs))
 	assert.NoError(t, th.HandleSyncedBlock(context.TODO(), peer, data))
@@ -14,7 +14,7 @@ type layerPatrol interface {
 }
 
 type meshProvider interface {
-	AddBlockWithTXs(context.Context, *types.Block) error
+	AddBlockWithTXs(context.Context, *types.Block) (bool, error)
 	ProcessLayerPerHareOutput(context.Context, types.LayerID, types.BlockID, bool) error
 }
 
@@ -27,6 +27,10 @@ type layerClock interface {
 	CurrentLayer() types.LayerID
 }
 
+type hareResults interface {
+	GetResult(context.Context, types.LayerID) ([]types.ProposalID, error)
+}
+
 type certifier interface {
 	RegisterForCert(context.Context, types.LayerID, types.BlockID) error
 	CertifyIfEligible(context.Context, log.Log, types.LayerID, types.BlockID) error
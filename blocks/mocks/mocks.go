@@ -72,11 +72,12 @@ func (m *MockmeshProvider) EXPECT() *MockmeshProviderMockRecorder {
 }
 
 // AddBlockWithTXs mocks base method.
-func (m *MockmeshProvider) AddBlockWithTXs(arg0 context.Context, arg1 *types.Block) error {
+func (m *MockmeshProvider) AddBlockWithTXs(arg0 context.Context, arg1 *types.Block) (bool, error) {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "AddBlockWithTXs", arg0, arg1)
-	ret0, _ := ret[0].(error)
-	return ret0
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
 }
 
 // AddBlockWithTXs indicates an expected call of AddBlockWithTXs.
@@ -188,6 +189,44 @@ func (mr *MocklayerClockMockRecorder) CurrentLayer() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CurrentLayer", reflect.TypeOf((*MocklayerClock)(nil).CurrentLayer))
 }
 
+// MockhareResults is a mock of hareResults interface.
+type MockhareResults struct {
+	ctrl     *gomock.Controller
+	recorder *MockhareResultsMockRecorder
+}
+
+// MockhareResultsMockRecorder is the mock recorder for MockhareResults.
+type MockhareResultsMockRecorder struct {
+	mock *MockhareResults
+}
+
+// NewMockhareResults creates a new mock instance.
+func NewMockhareResults(ctrl *gomock.Controller) *MockhareResults {
+	mock := &MockhareResults{ctrl: ctrl}
+	mock.recorder = &MockhareResultsMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockhareResults) EXPECT() *MockhareResultsMockRecorder {
+	return m.recorder
+}
+
+// GetResult mocks base method.
+func (m *MockhareResults) GetResult(arg0 context.Context, arg1 types.LayerID) ([]types.ProposalID, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetResult", arg0, arg1)
+	ret0, _ := ret[0].([]types.ProposalID)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetResult indicates an expected call of GetResult.
+func (mr *MockhareResultsMockRecorder) GetResult(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetResult", reflect.TypeOf((*MockhareResults)(nil).GetResult), arg0, arg1)
+}
+
 // Mockcertifier is a mock of certifier interface.
 type Mockcertifier struct {
 	ctrl     *gomock.Controller
@@ -20,7 +20,6 @@ import (
 	"github.com/spacemeshos/go-spacemesh/log"
 	"github.com/spacemeshos/go-spacemesh/proposals"
 	"github.com/spacemeshos/go-spacemesh/sql/layers"
-	"github.com/spacemeshos/go-spacemesh/sql/transactions"
 	"github.com/spacemeshos/go-spacemesh/txs"
 )
 
@@ -60,8 +59,6 @@ func getProposalMetadata(
 			lid:       lid,
 			proposals: proposals,
 		}
-		mtxs       []*types.MeshTransaction
-		seen       = make(map[types.TransactionID]struct{})
 		meshHashes = make(map[types.Hash32]*meshState)
 		err        error
 	)
@@ -79,22 +76,21 @@ func getProposalMetadata(
 		} else {
 			meshHashes[key].count++
 		}
-
-		for _, tid := range p.TxIDs {
-			if _, ok := seen[tid]; ok {
-				continue
-			}
-			mtx, err := transactions.Get(cdb, tid)
-			if err != nil {
-				return nil, fmt.Errorf("%w: get proposal tx: %v", errProposalTxMissing, err.Error())
-			}
-			if mtx.TxHeader == nil {
-				return nil, fmt.Errorf("%w: inconsistent state: tx %s is missing header", errProposalTxHdrMissing, mtx.ID)
-			}
-			seen[tid] = struct{}{}
-			mtxs = append(mtxs, mtx)
+	}
+	tids := mergeProposalTXs(proposals)
+	mtxs, missing, err := txs.GetBatch(cdb, tids)
+	if err != nil {
+		return nil, fmt.Errorf("get proposal txs: %w", err)
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("%w: %d txs, first %s", errProposalTxMissing, len(missing), missing[0])
+	}
+	for _, mtx := range mtxs {
+		if mtx.TxHeader == nil {
+			return nil, fmt.Errorf("%w: inconsistent state: tx %s is missing header", errProposalTxHdrMissing, mtx.ID)
 		}
 	}
+	mtxs = dropSatisfied(logger, tids, mtxs)
 	majority := cfg.OptFilterThreshold * len(proposals)
 	var majorityState *meshState
 	for _, ms := range meshHashes {
@@ -136,11 +132,70 @@ func getProposalMetadata(
 	return md, nil
 }
 
+// mergeProposalTXs merges the transactions of the proposals in the order of the proposal ids,
+// so that every node merges the same set of proposals the same way.
+// Only the first occurrence of a transaction is kept.
+func mergeProposalTXs(proposals []*types.Proposal) []types.TransactionID {
+	ordered := make([]*types.Proposal, len(proposals))
+	copy(ordered, proposals)
+	sort.Slice(ordered, func(i, j int) bool { return ordered[i].ID().Compare(ordered[j].ID()) })
+	var (
+		tids []types.TransactionID
+		seen = make(map[types.TransactionID]struct{})
+	)
+	for _, p := range ordered {
+		for _, tid := range p.TxIDs {
+			if _, ok := seen[tid]; ok {
+				continue
+			}
+			seen[tid] = struct{}{}
+			tids = append(tids, tid)
+		}
+	}
+	return tids
+}
+
+type principalNonce struct {
+	principal types.Address
+	nonce     uint64
+}
+
+// dropSatisfied keeps the transactions in the merged order and drops the ones whose principal and nonce
+// are already used by a transaction that comes earlier in that order.
+func dropSatisfied(logger log.Log, tids []types.TransactionID, mtxs []*types.MeshTransaction) []*types.MeshTransaction {
+	byTid := make(map[types.TransactionID]*types.MeshTransaction, len(mtxs))
+	for _, mtx := range mtxs {
+		byTid[mtx.ID] = mtx
+	}
+	var (
+		rst  = make([]*types.MeshTransaction, 0, len(mtxs))
+		used = make(map[principalNonce]types.TransactionID, len(mtxs))
+	)
+	for _, tid := range tids {
+		mtx, ok := byTid[tid]
+		if !ok {
+			continue
+		}
+		key := principalNonce{principal: mtx.Principal, nonce: mtx.Nonce}
+		if prev, ok := used[key]; ok {
+			logger.With().Debug("dropping tx with satisfied nonce",
+				tid,
+				log.Stringer("prev_tx", prev),
+				mtx.Principal,
+				log.Uint64("nonce", mtx.Nonce))
+			continue
+		}
+		used[key] = tid
+		rst = append(rst, mtx)
+	}
+	return rst
+}
+
 func getBlockTXs(logger log.Log, mtxs []*types.MeshTransaction, blockSeed []byte, gasLimit uint64) ([]types.TransactionID, error) {
 	stateF := func(_ types.Address) (uint64, uint64) {
 		return 0, math.MaxUint64
 	}
-	txCache := txs.NewCache(stateF, logger)
+	txCache := txs.NewCache(stateF, logger, txs.WithoutParking())
 	if err := txCache.BuildFromTXs(mtxs, blockSeed); err != nil {
 		return nil, fmt.Errorf("build txs for block: %w", err)
 	}
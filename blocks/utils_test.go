@@ -86,3 +86,37 @@ func Test_getBlockTXs(t *testing.T) {
 	require.NoError(t, err)
 	require.Empty(t, got)
 }
+
+func Test_mergeProposalTXs(t *testing.T) {
+	tids := []types.TransactionID{{1}, {2}, {3}, {4}}
+	plist := []*types.Proposal{
+		{InnerProposal: types.InnerProposal{TxIDs: []types.TransactionID{tids[2], tids[1], tids[3]}}},
+		{InnerProposal: types.InnerProposal{TxIDs: []types.TransactionID{tids[0], tids[1]}}},
+	}
+	plist[0].SetID(types.ProposalID{2})
+	plist[1].SetID(types.ProposalID{1})
+	expected := []types.TransactionID{tids[0], tids[1], tids[2], tids[3]}
+	require.Equal(t, expected, mergeProposalTXs(plist))
+	require.Equal(t, expected, mergeProposalTXs([]*types.Proposal{plist[1], plist[0]}))
+}
+
+func Test_dropSatisfied(t *testing.T) {
+	signer, err := signing.NewEdSigner()
+	require.NoError(t, err)
+	first := genTx(t, signer, types.Address{1}, 100, 1, 10)
+	replaced := genTx(t, signer, types.Address{1}, 200, 1, 20)
+	next := genTx(t, signer, types.Address{1}, 100, 2, 10)
+	other, err := signing.NewEdSigner()
+	require.NoError(t, err)
+	same := genTx(t, other, types.Address{1}, 100, 1, 10)
+
+	mtxs := []*types.MeshTransaction{
+		{Transaction: replaced},
+		{Transaction: same},
+		{Transaction: next},
+		{Transaction: first},
+	}
+	tids := []types.TransactionID{first.ID, same.ID, replaced.ID, next.ID}
+	got := dropSatisfied(logtest.New(t), tids, mtxs)
+	require.Equal(t, []*types.MeshTransaction{mtxs[3], mtxs[1], mtxs[2]}, got)
+}
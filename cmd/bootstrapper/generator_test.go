@@ -105,7 +105,12 @@ func (m *MeshAPIMock) LatestLayer() types.LayerID                        { panic
 func (m *MeshAPIMock) LatestLayerInState() types.LayerID                 { panic("not implemented") }
 func (m *MeshAPIMock) ProcessedLayer() types.LayerID                     { panic("not implemented") }
 func (m *MeshAPIMock) GetRewards(types.Address) ([]*types.Reward, error) { panic("not implemented") }
-func (m *MeshAPIMock) GetLayer(types.LayerID) (*types.Layer, error)      { panic("not implemented") }
+func (m *MeshAPIMock) IterateLayerBallots(types.LayerID, func(*types.Ballot) bool) error {
+	panic("not implemented")
+}
+func (m *MeshAPIMock) IterateLayerBlocks(types.LayerID, func(*types.Block) bool) error {
+	panic("not implemented")
+}
 func (m *MeshAPIMock) GetATXs(context.Context, []types.ATXID) (map[types.ATXID]*types.VerifiedActivationTx, []types.ATXID) {
 	panic("not implemented")
 }
@@ -0,0 +1,23 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/spacemeshos/go-spacemesh/testvectors"
+)
+
+var (
+	out  = flag.String("out", "testvectors/data", "directory for generated vectors")
+	seed = flag.Int64("seed", testvectors.DefaultSeed, "seed for keys and generated objects")
+)
+
+func main() {
+	flag.Parse()
+
+	if err := testvectors.Write(*out, *seed); err != nil {
+		fmt.Printf("failed to generate vectors: %s\n", err)
+		os.Exit(1)
+	}
+}
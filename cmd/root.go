@@ -68,6 +68,34 @@ func AddCommands(cmd *cobra.Command) {
 		cfg.TxsPerProposal, "the number of transactions to select per proposal")
 	cmd.PersistentFlags().Uint64Var(&cfg.BlockGasLimit, "block-gas-limit",
 		cfg.BlockGasLimit, "max gas allowed per block")
+	cmd.PersistentFlags().StringVar(&cfg.TxsSelection, "txs-selection",
+		cfg.TxsSelection, "the policy to select transactions for proposals: fifo or fee")
+	cmd.PersistentFlags().Uint64Var(&cfg.TxsSizePerProposal, "txs-size-per-proposal",
+		cfg.TxsSizePerProposal, "the max total size in bytes of the transactions selected per proposal, 0 is unlimited")
+	cmd.PersistentFlags().Uint64Var(&cfg.TxsReplaceFeeBump, "txs-replace-fee-bump",
+		cfg.TxsReplaceFeeBump, "the min fee increase in percent for a transaction to replace the pending transaction with the same nonce")
+	cmd.PersistentFlags().Uint32Var(&cfg.TxsMempoolMaxAge, "txs-mempool-max-age",
+		cfg.TxsMempoolMaxAge, "the max age in layers of the pending transactions reloaded after restart, 0 reloads all")
+	cmd.PersistentFlags().IntVar(&cfg.TxsMempoolMaxTXs, "txs-mempool-max-txs",
+		cfg.TxsMempoolMaxTXs, "the max number of transactions in the mempool, 0 is unlimited")
+	cmd.PersistentFlags().Uint64Var(&cfg.TxsMempoolMaxBytes, "txs-mempool-max-bytes",
+		cfg.TxsMempoolMaxBytes, "the max total size in bytes of transactions in the mempool, 0 is unlimited")
+	cmd.PersistentFlags().Uint64Var(&cfg.TxsMaxNonceGap, "txs-max-nonce-gap",
+		cfg.TxsMaxNonceGap, "the max distance between the nonce of a submitted transaction and the next nonce of its principal")
+	cmd.PersistentFlags().Uint64Var(&cfg.TxsLocalLane, "txs-local-lane",
+		cfg.TxsLocalLane, "the percent of the proposal budget reserved for transactions submitted via the api of this node, 0 disables")
+	cmd.PersistentFlags().DurationVar(&cfg.ActiveSetGracePeriod, "active-set-grace-period",
+		cfg.ActiveSetGracePeriod, "how long before the start of the epoch the atxs must be received to be included into the active set")
+	cmd.PersistentFlags().IntVar(&cfg.MaxProposalBytes, "max-proposal-bytes",
+		cfg.MaxProposalBytes, "the max size in bytes of the encoded proposal, 0 is unlimited")
+	cmd.PersistentFlags().IntVar(&cfg.MaxActiveSetSize, "max-active-set-size",
+		cfg.MaxActiveSetSize, "the max number of atxs in the active set of the reference ballot, 0 is unlimited")
+	cmd.PersistentFlags().IntVar(&cfg.MaxPendingBallots, "max-pending-ballots",
+		cfg.MaxPendingBallots, "the max number of ballots waiting for a missing base or reference ballot, 0 drops them right away")
+	cmd.PersistentFlags().Float64Var(&cfg.ProposalBuildDeadline, "proposal-build-deadline",
+		cfg.ProposalBuildDeadline, "fraction of the layer duration after which proposals are not built for the layer, 0 disables the deadline")
+	cmd.PersistentFlags().DurationVar(&cfg.ProposalBuildLead, "proposal-build-lead",
+		cfg.ProposalBuildLead, "how long before the start of the layer proposals are built")
 	cmd.PersistentFlags().IntVar(&cfg.OptFilterThreshold, "optimistic-filtering-threshold",
 		cfg.OptFilterThreshold, "threshold for optimistic filtering in percentage")
 
@@ -78,6 +106,10 @@ func AddCommands(cmd *cobra.Command) {
 		cfg.DatabaseConnections, "configure number of active connections to enable parallel read requests")
 	cmd.PersistentFlags().BoolVar(&cfg.DatabaseLatencyMetering, "db-latency-metering",
 		cfg.DatabaseLatencyMetering, "if enabled collect latency histogram for every database query")
+	cmd.PersistentFlags().IntVar(&cfg.DatabaseBallotCacheSize, "db-ballot-cache-size",
+		cfg.DatabaseBallotCacheSize, "number of decoded ballots kept in memory, 0 disables the cache")
+	cmd.PersistentFlags().IntVar(&cfg.DatabaseBlockCacheSize, "db-block-cache-size",
+		cfg.DatabaseBlockCacheSize, "number of decoded blocks kept in memory, 0 disables the cache")
 
 	/** ======================== P2P Flags ========================== **/
 
@@ -113,6 +145,14 @@ func AddCommands(cmd *cobra.Command) {
 		cfg.P2P.Bootnodes, "entrypoints into the network")
 	cmd.PersistentFlags().StringVar(&cfg.P2P.AdvertiseAddress, "advertise-address",
 		cfg.P2P.AdvertiseAddress, "libp2p address with identity (example: /dns4/bootnode.spacemesh.io/tcp/5003)")
+	cmd.PersistentFlags().StringSliceVar(&cfg.P2P.Scoring.Trusted, "trusted-peers",
+		cfg.P2P.Scoring.Trusted, "ids of the peers that are never banned for misbehavior")
+	cmd.PersistentFlags().Float64Var(&cfg.P2P.Scoring.Threshold, "peer-ban-threshold",
+		cfg.P2P.Scoring.Threshold, "score of the misbehaving peer that bans it")
+	cmd.PersistentFlags().DurationVar(&cfg.P2P.Scoring.BanDuration, "peer-ban-duration",
+		cfg.P2P.Scoring.BanDuration, "duration of the first ban of the peer, every next ban is twice as long")
+	cmd.PersistentFlags().BoolVar(&cfg.P2P.Scoring.BanIP, "peer-ban-ip",
+		cfg.P2P.Scoring.BanIP, "ban the ip addresses of the banned peers")
 
 	/** ======================== TIME Flags ========================== **/
 
@@ -204,8 +244,20 @@ func AddCommands(cmd *cobra.Command) {
 		cfg.Tortoise.MaxExceptions, "number of exceptions tolerated for a base ballot")
 	cmd.PersistentFlags().Uint32Var(&cfg.Tortoise.BadBeaconVoteDelayLayers, "tortoise-delay-layers",
 		cfg.Tortoise.BadBeaconVoteDelayLayers, "number of layers to ignore a ballot with a different beacon")
+	cmd.PersistentFlags().Uint32Var(&cfg.Tortoise.CoinflipTheta, "tortoise-coinflip-theta",
+		cfg.Tortoise.CoinflipTheta, "margin band, in percents of the global threshold, where healing decides validity by the weak coin")
+	cmd.PersistentFlags().Uint32Var(&cfg.Tortoise.HealingDistance, "tortoise-healing-distance",
+		cfg.Tortoise.HealingDistance, "number of layers after which the weak coin decides validity of undecided blocks")
+	cmd.PersistentFlags().IntVar(&cfg.Tortoise.QueueSize, "tortoise-queue-size",
+		cfg.Tortoise.QueueSize, "number of ballots, blocks and hare outputs that are queued before they are applied")
 	cmd.PersistentFlags().BoolVar(&cfg.Tortoise.EnableTracer, "tortoise-enable-tracer",
 		cfg.Tortoise.EnableTracer, "recovrd every tortoise input/output into the loggin output")
+	cmd.PersistentFlags().StringVar(&cfg.Tortoise.OpinionReportFile, "tortoise-opinion-report",
+		cfg.Tortoise.OpinionReportFile, "write signed report with validity of blocks in the tortoise window to the file on shutdown")
+	cmd.PersistentFlags().StringVar(&cfg.Tortoise.DumpStateFile, "tortoise-dump-state",
+		cfg.Tortoise.DumpStateFile, "write in-memory state of the tortoise to the file on shutdown, to be attached to bug reports")
+	cmd.PersistentFlags().BoolVar(&cfg.Tortoise.LeanVerifying, "tortoise-lean-verifying",
+		cfg.Tortoise.LeanVerifying, "keep only opinions of the ballots in verifying mode, votes are loaded from the database when needed")
 
 	// TODO(moshababo): add usage desc
 	cmd.PersistentFlags().Uint64Var(&cfg.POST.LabelsPerUnit, "post-labels-per-unit",
@@ -240,6 +292,8 @@ func AddCommands(cmd *cobra.Command) {
 		cfg.SMESHING.Opts.ProviderID, "")
 	cmd.PersistentFlags().BoolVar(&cfg.SMESHING.Opts.Throttle, "smeshing-opts-throttle",
 		cfg.SMESHING.Opts.Throttle, "")
+	cmd.PersistentFlags().StringVar(&cfg.SMESHING.RemoteSignerURL, "smeshing-remote-signer-url",
+		cfg.SMESHING.RemoteSignerURL, "address of the signing service that holds the key of the primary identity")
 
 	/**======================== Consensus Flags ========================== **/
 
@@ -1,12 +1,15 @@
 package main
 
 import (
+	"encoding/json"
 	"flag"
+	"os"
 	"runtime"
 
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 
+	"github.com/spacemeshos/go-spacemesh/common/types"
 	"github.com/spacemeshos/go-spacemesh/log"
 	"github.com/spacemeshos/go-spacemesh/tortoise"
 )
@@ -14,12 +17,21 @@ import (
 var (
 	level  = zap.LevelFlag("level", zapcore.ErrorLevel, "set verbosity level for execution")
 	bpoint = flag.Bool("breakpoint", false, "enable breakpoint after every step")
+	state  = flag.Bool("state", false, "load state written by the tortoise dump instead of running the trace")
+	from   = flag.Uint("from", 0, "first layer in the opinion report for the loaded state")
+	to     = flag.Uint("to", 0, "last layer in the opinion report for the loaded state")
 )
 
 func main() {
 	flag.Parse()
 	atom := zap.NewAtomicLevelAt(*level)
 	logger := log.NewWithLevel("trace", atom)
+	if *state {
+		if err := reportState(flag.Arg(0), logger); err != nil {
+			logger.With().Fatal("report state failed", log.Err(err))
+		}
+		return
+	}
 	logger.With().Debug("using trace", log.String("path", flag.Arg(0)))
 	var breakpoint func()
 	if *bpoint {
@@ -29,3 +41,23 @@ func main() {
 		logger.With().Fatal("run trace failed", log.Err(err))
 	}
 }
+
+// reportState loads the state and writes opinion report for the requested layers to stdout.
+func reportState(path string, logger log.Log) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	trtl, err := tortoise.LoadState(f, tortoise.WithLogger(logger))
+	if err != nil {
+		return err
+	}
+	report, err := trtl.OpinionReport(types.LayerID(*from), types.LayerID(*to))
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(report)
+}
@@ -5,12 +5,16 @@ import (
 	"errors"
 	"fmt"
 	"io"
-	"sync"
 
 	"github.com/spacemeshos/go-scale"
 )
 
-var ErrShortRead = errors.New("decode from buffer: not all bytes were consumed")
+var (
+	ErrShortRead = errors.New("decode from buffer: not all bytes were consumed")
+	// ErrNonCanonical is returned by DecodeStrict if the value decoded from a buffer
+	// doesn't encode back to the same bytes.
+	ErrNonCanonical = errors.New("decode from buffer: encoding is not canonical")
+)
 
 // Encodable is an interface that must be implemented by a struct to be encoded.
 type Encodable = scale.Encodable
@@ -18,6 +22,12 @@ type Encodable = scale.Encodable
 // Decodable is an interface that must be implemented bya struct to be decoded.
 type Decodable = scale.Decodable
 
+// Codec is an interface implemented by a struct that can be both encoded and decoded.
+type Codec interface {
+	Encodable
+	Decodable
+}
+
 // EncodeTo encodes value to a writer stream.
 func EncodeTo(w io.Writer, value Encodable) (int, error) {
 	return value.EncodeScale(scale.NewEncoder(w, scale.WithEncodeMaxNested(6)))
@@ -28,25 +38,63 @@ func DecodeFrom(r io.Reader, value Decodable) (int, error) {
 	return value.DecodeScale(scale.NewDecoder(r, scale.WithDecodeMaxNested(6)))
 }
 
-// TODO(dshulyak) this is a temporary solution to improve encoder allocations.
-// if this will stay it must be changed to one of the:
-// - use buffer with allocations that can be adjusted using stats
-// - use multiple buffers that increase in size (e.g. 16, 32, 64, 128 bytes).
-var encoderPool = sync.Pool{
-	New: func() any {
-		b := new(bytes.Buffer)
-		b.Grow(64)
-		return b
-	},
+// SizedEncodable is implemented by values that can compute the size of their encoding
+// without encoding them.
+type SizedEncodable interface {
+	Encodable
+	EncodedSize() int
+}
+
+// CompactSize returns the number of bytes used by the compact encoding of the value.
+func CompactSize(value uint32) int {
+	switch {
+	case value <= 1<<6-1:
+		return 1
+	case value <= 1<<14-1:
+		return 2
+	case value <= 1<<30-1:
+		return 4
+	default:
+		return 5
+	}
 }
 
-func getEncoderBuffer() *bytes.Buffer {
-	return encoderPool.Get().(*bytes.Buffer)
+// EncodeWithLength encodes value to a writer stream, prefixed with the compact encoded length of the encoding.
+// The output is identical to encoding the result of Encode as a byte slice, but the value is never
+// materialized in memory.
+func EncodeWithLength(w io.Writer, value SizedEncodable) (int, error) {
+	size := value.EncodedSize()
+	total, err := scale.EncodeCompact32(scale.NewEncoder(w), uint32(size))
+	if err != nil {
+		return total, err
+	}
+	n, err := EncodeTo(w, value)
+	total += n
+	if err != nil {
+		return total, err
+	}
+	if n != size {
+		return total, fmt.Errorf("encode with length: declared %d, written %d", size, n)
+	}
+	return total, nil
 }
 
-func putEncoderBuffer(b *bytes.Buffer) {
-	b.Reset()
-	encoderPool.Put(b)
+// DecodeWithLength decodes a value that was encoded with EncodeWithLength.
+// The decoder never reads past the declared length and fails if the value doesn't consume it fully.
+func DecodeWithLength(r io.Reader, value Decodable) (int, error) {
+	size, total, err := scale.DecodeCompact32(scale.NewDecoder(r))
+	if err != nil {
+		return total, err
+	}
+	n, err := DecodeFrom(io.LimitReader(r, int64(size)), value)
+	total += n
+	if err != nil {
+		return total, err
+	}
+	if n != int(size) {
+		return total, ErrShortRead
+	}
+	return total, nil
 }
 
 func MustEncode(value Encodable) []byte {
@@ -59,8 +107,8 @@ func MustEncode(value Encodable) []byte {
 
 // Encode value to a byte buffer.
 func Encode(value Encodable) ([]byte, error) {
-	b := getEncoderBuffer()
-	defer putEncoderBuffer(b)
+	b := getBuffer(sizeHint(value))
+	defer putBuffer(b)
 	n, err := EncodeTo(b, value)
 	if err != nil {
 		return nil, err
@@ -89,6 +137,29 @@ func Decode(buf []byte, value Decodable) error {
 	return nil
 }
 
+// DecodeStrict decodes value from a byte buffer and fails if the buffer is not the canonical
+// encoding of the decoded value. For example trailing bytes, non-minimal compact integers or
+// boolean values other than 0 and 1 are rejected.
+//
+// The check encodes the decoded value again, therefore it doubles the cost of decoding.
+// It must be used for consensus objects received from gossip, as gossip relays the data as received and
+// the identity of the object is a hash over its canonical encoding. Data received from sync is stored
+// as it is encoded by the node, and can be decoded with Decode.
+func DecodeStrict(buf []byte, value Codec) error {
+	if err := Decode(buf, value); err != nil {
+		return err
+	}
+	b := getBuffer(len(buf))
+	defer putBuffer(b)
+	if _, err := EncodeTo(b, value); err != nil {
+		return fmt.Errorf("encode decoded value: %w", err)
+	}
+	if !bytes.Equal(b.Bytes(), buf) {
+		return ErrNonCanonical
+	}
+	return nil
+}
+
 // EncodeSlice encodes slice to a buffer.
 func EncodeSlice[V any, H scale.EncodablePtr[V]](value []V) ([]byte, error) {
 	var b bytes.Buffer
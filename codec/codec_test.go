@@ -0,0 +1,247 @@
+package codec
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/spacemeshos/go-scale"
+	"github.com/stretchr/testify/require"
+)
+
+type testID [32]byte
+
+// testList mimics a list of ATXIDs, like the one used in the active set.
+type testList struct {
+	IDs []testID
+}
+
+func (t *testList) EncodeScale(enc *scale.Encoder) (int, error) {
+	total, err := scale.EncodeCompact32(enc, uint32(len(t.IDs)))
+	if err != nil {
+		return total, err
+	}
+	for i := range t.IDs {
+		n, err := scale.EncodeByteArray(enc, t.IDs[i][:])
+		total += n
+		if err != nil {
+			return total, err
+		}
+	}
+	return total, nil
+}
+
+func (t *testList) DecodeScale(dec *scale.Decoder) (int, error) {
+	length, total, err := scale.DecodeLen(dec, 1<<20)
+	if err != nil {
+		return total, err
+	}
+	t.IDs = make([]testID, length)
+	for i := range t.IDs {
+		n, err := scale.DecodeByteArray(dec, t.IDs[i][:])
+		total += n
+		if err != nil {
+			return total, err
+		}
+	}
+	return total, nil
+}
+
+func (t *testList) EncodedSize() int {
+	return CompactSize(uint32(len(t.IDs))) + len(t.IDs)*len(testID{})
+}
+
+func genList(size int) *testList {
+	list := &testList{IDs: make([]testID, size)}
+	for i := range list.IDs {
+		list.IDs[i][0] = byte(i)
+		list.IDs[i][1] = byte(i >> 8)
+		list.IDs[i][2] = byte(i >> 16)
+	}
+	return list
+}
+
+func TestEncodeWithLength(t *testing.T) {
+	list := genList(100)
+
+	var buf bytes.Buffer
+	n, err := EncodeWithLength(&buf, list)
+	require.NoError(t, err)
+	require.Equal(t, buf.Len(), n)
+
+	// same bytes as the encoding of the materialized value as a byte slice.
+	encoded, err := Encode(list)
+	require.NoError(t, err)
+	var expected bytes.Buffer
+	_, err = EncodeByteSlice(&expected, encoded)
+	require.NoError(t, err)
+	require.Equal(t, expected.Bytes(), buf.Bytes())
+
+	buf.Write([]byte{1, 2, 3})
+	var decoded testList
+	n, err = DecodeWithLength(&buf, &decoded)
+	require.NoError(t, err)
+	require.Equal(t, expected.Len(), n)
+	require.Equal(t, list, &decoded)
+	require.Equal(t, []byte{1, 2, 3}, buf.Bytes())
+}
+
+func TestCompactSize(t *testing.T) {
+	for _, value := range []uint32{0, 1, 1<<6 - 1, 1 << 6, 1<<14 - 1, 1 << 14, 1<<30 - 1, 1 << 30, 1<<32 - 1} {
+		var buf bytes.Buffer
+		n, err := scale.EncodeCompact32(scale.NewEncoder(&buf), value)
+		require.NoError(t, err)
+		require.Equal(t, n, CompactSize(value), "value %d", value)
+	}
+}
+
+func TestDecodeWithLengthShort(t *testing.T) {
+	encoded, err := Encode(genList(3))
+	require.NoError(t, err)
+
+	t.Run("not consumed", func(t *testing.T) {
+		var buf bytes.Buffer
+		_, err := EncodeByteSlice(&buf, append(encoded, 0))
+		require.NoError(t, err)
+		var decoded testList
+		_, err = DecodeWithLength(&buf, &decoded)
+		require.ErrorIs(t, err, ErrShortRead)
+	})
+	t.Run("truncated", func(t *testing.T) {
+		var buf bytes.Buffer
+		_, err := EncodeByteSlice(&buf, encoded[:len(encoded)-1])
+		require.NoError(t, err)
+		buf.Write([]byte{1, 2, 3})
+		var decoded testList
+		_, err = DecodeWithLength(&buf, &decoded)
+		require.Error(t, err)
+		// bytes past declared length are left untouched.
+		require.Equal(t, []byte{1, 2, 3}, buf.Bytes())
+	})
+}
+
+func FuzzDecodeWithLength(f *testing.F) {
+	encoded, err := Encode(genList(2))
+	require.NoError(f, err)
+	f.Add(encoded, []byte{1})
+	f.Add([]byte{}, []byte{})
+	f.Add([]byte{0xff, 0xff}, []byte{0})
+	f.Fuzz(func(t *testing.T, payload, trailer []byte) {
+		var buf bytes.Buffer
+		_, err := EncodeByteSlice(&buf, payload)
+		require.NoError(t, err)
+		prefix := buf.Len() - len(payload)
+		buf.Write(trailer)
+
+		rd := bytes.NewReader(buf.Bytes())
+		var decoded testList
+		n, err := DecodeWithLength(rd, &decoded)
+		require.GreaterOrEqual(t, rd.Len(), len(trailer))
+		if err == nil {
+			require.Equal(t, prefix+len(payload), n)
+			require.Equal(t, len(trailer), rd.Len())
+		}
+	})
+}
+
+func BenchmarkEncodeLargeList(b *testing.B) {
+	list := genList(50_000)
+	b.Run("materialized", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			buf, err := Encode(list)
+			if err != nil {
+				b.Fatal(err)
+			}
+			if _, err := scale.EncodeByteSliceWithLimit(scale.NewEncoder(io.Discard), buf, 10<<20); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("streamed", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if _, err := EncodeWithLength(io.Discard, list); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+type testFlag struct {
+	Flag bool
+	Size uint64
+}
+
+func (t *testFlag) EncodeScale(enc *scale.Encoder) (int, error) {
+	total, err := scale.EncodeBool(enc, t.Flag)
+	if err != nil {
+		return total, err
+	}
+	n, err := scale.EncodeCompact64(enc, t.Size)
+	return total + n, err
+}
+
+func (t *testFlag) DecodeScale(dec *scale.Decoder) (int, error) {
+	var (
+		total int
+		n     int
+		err   error
+	)
+	t.Flag, n, err = scale.DecodeBool(dec)
+	total += n
+	if err != nil {
+		return total, err
+	}
+	t.Size, n, err = scale.DecodeCompact64(dec)
+	return total + n, err
+}
+
+func TestDecodeStrict(t *testing.T) {
+	valid := MustEncode(&testFlag{Flag: true, Size: 1 << 40})
+	for _, tc := range []struct {
+		desc   string
+		buf    []byte
+		err    error
+		strict error
+	}{
+		{
+			desc: "canonical",
+			buf:  valid,
+		},
+		{
+			desc:   "trailing bytes",
+			buf:    append(append([]byte{}, valid...), 0),
+			err:    ErrShortRead,
+			strict: ErrShortRead,
+		},
+		{
+			desc:   "non canonical bool",
+			buf:    append([]byte{2}, MustEncode(&testFlag{Size: 1})[1:]...),
+			strict: ErrNonCanonical,
+		},
+		{
+			desc: "non minimal compact",
+			// 1<<40 encoded with 8 bytes instead of 6.
+			buf:    []byte{1, 0b00010011, 0, 0, 0, 0, 0, 1, 0, 0},
+			strict: ErrNonCanonical,
+		},
+	} {
+		tc := tc
+		t.Run(tc.desc, func(t *testing.T) {
+			var decoded testFlag
+			err := Decode(tc.buf, &decoded)
+			if tc.err != nil {
+				require.ErrorIs(t, err, tc.err)
+			} else {
+				require.NoError(t, err)
+			}
+			err = DecodeStrict(tc.buf, &decoded)
+			if tc.strict != nil {
+				require.ErrorIs(t, err, tc.strict)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,5 @@
+//go:build race || debug
+
+package codec
+
+const poisonBuffers = true
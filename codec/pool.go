@@ -0,0 +1,96 @@
+package codec
+
+import (
+	"bytes"
+	"sync"
+)
+
+// sizeClasses of the pooled buffers. Buffers larger than the last class are not pooled.
+var sizeClasses = [...]int{64, 256, 1 << 10, 4 << 10, 16 << 10, 64 << 10, 256 << 10, 1 << 20}
+
+var pools [len(sizeClasses)]sync.Pool
+
+const poisonByte = 0xde
+
+// classFor returns the smallest class that fits size, or -1 if size is larger than every class.
+func classFor(size int) int {
+	for i, class := range sizeClasses {
+		if size <= class {
+			return i
+		}
+	}
+	return -1
+}
+
+func getBuffer(size int) *bytes.Buffer {
+	class := classFor(size)
+	if class < 0 {
+		b := new(bytes.Buffer)
+		b.Grow(size)
+		return b
+	}
+	if b, ok := pools[class].Get().(*bytes.Buffer); ok {
+		return b
+	}
+	b := new(bytes.Buffer)
+	b.Grow(sizeClasses[class])
+	return b
+}
+
+func putBuffer(b *bytes.Buffer) {
+	if poisonBuffers {
+		// fill buffer with garbage and don't reuse it, so that reads after release
+		// are visible in tests and writes after release are reported by the race detector.
+		buf := b.Bytes()
+		buf = buf[:cap(buf)]
+		for i := range buf {
+			buf[i] = poisonByte
+		}
+		return
+	}
+	// buffer is returned to the largest class that it can serve without growing.
+	class := -1
+	for i, size := range sizeClasses {
+		if b.Cap() < size {
+			break
+		}
+		class = i
+	}
+	if class < 0 || b.Cap() > 2*sizeClasses[len(sizeClasses)-1] {
+		return
+	}
+	b.Reset()
+	pools[class].Put(b)
+}
+
+// sizeHint returns the size of the encoding if the value knows it, otherwise the smallest class.
+func sizeHint(value Encodable) int {
+	if sized, ok := value.(SizedEncodable); ok {
+		return sized.EncodedSize()
+	}
+	return sizeClasses[0]
+}
+
+// EncodeToPool encodes value into the buffer from the pool. Buffer must not be used
+// after release is called, including slices returned by Bytes method.
+//
+// It should be used only when the lifetime of the encoded bytes is clearly bounded,
+// e.g. the bytes are copied or written to the stream before release.
+// Buffer is taken from the class that fits the value if it implements SizedEncodable,
+// otherwise buffer grows during encoding.
+// Builds with race or debug tags poison released buffers.
+func EncodeToPool(value Encodable) (*bytes.Buffer, func(), error) {
+	b := getBuffer(sizeHint(value))
+	if _, err := EncodeTo(b, value); err != nil {
+		putBuffer(b)
+		return nil, nil, err
+	}
+	released := false
+	return b, func() {
+		if released {
+			panic("codec: pooled buffer released twice")
+		}
+		released = true
+		putBuffer(b)
+	}, nil
+}
@@ -0,0 +1,50 @@
+package codec
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestEncodeToPool(t *testing.T) {
+	for _, size := range []int{0, 1, 100, 10_000, 40_000} {
+		list := genList(size)
+		expected, err := Encode(list)
+		require.NoError(t, err)
+
+		buf, release, err := EncodeToPool(list)
+		require.NoError(t, err)
+		require.Equal(t, expected, buf.Bytes())
+		if class := classFor(len(expected)); class >= 0 {
+			require.GreaterOrEqual(t, buf.Cap(), sizeClasses[class])
+		}
+
+		encoded := buf.Bytes()
+		release()
+		if poisonBuffers && len(encoded) > 0 {
+			require.Equal(t, byte(poisonByte), encoded[0])
+		}
+		require.Panics(t, release)
+	}
+}
+
+func TestEncodeToPoolReuse(t *testing.T) {
+	if poisonBuffers {
+		t.Skip("buffers are not reused when poisoning is enabled")
+	}
+	list := genList(100)
+	buf, release, err := EncodeToPool(list)
+	require.NoError(t, err)
+	release()
+	require.Equal(t, 0, buf.Len())
+
+	reused := testing.AllocsPerRun(100, func() {
+		_, release, err := EncodeToPool(list)
+		if err != nil {
+			panic(err)
+		}
+		release()
+	})
+	// encoders and release closure, but never the buffer itself.
+	require.Less(t, reused, 10.0)
+}
@@ -58,6 +58,12 @@ func Data() RBlockOpt {
 	}
 }
 
+func Changed() RBlockOpt {
+	return func(b *result.Block) {
+		b.Changed = true
+	}
+}
+
 func Good() RBlockOpt {
 	return func(b *result.Block) {
 		b.Valid = true
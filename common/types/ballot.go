@@ -114,6 +114,25 @@ type InnerBallot struct {
 	EpochData *EpochData
 }
 
+// DecodeBallotHeader decodes Layer and AtxID from the scale encoded InnerBallot without
+// decoding the rest of the data. Encoded Ballot and Proposal start with InnerBallot,
+// therefore it can be used to inspect them before full decoding.
+// Must be kept in sync with the order of fields in InnerBallot.
+func DecodeBallotHeader(data []byte) (LayerID, ATXID, error) {
+	var (
+		dec   = scale.NewDecoder(bytes.NewReader(data))
+		atxID ATXID
+	)
+	layer, _, err := scale.DecodeCompact32(dec)
+	if err != nil {
+		return 0, atxID, fmt.Errorf("decode layer: %w", err)
+	}
+	if _, err := scale.DecodeByteArray(dec, atxID[:]); err != nil {
+		return 0, atxID, fmt.Errorf("decode atx id: %w", err)
+	}
+	return LayerID(layer), atxID, nil
+}
+
 // Votes is for encoding local votes to send over the wire.
 //
 // a smesher creates votes in the following steps:
@@ -266,6 +285,21 @@ func (b *Ballot) HashInnerBytes() []byte {
 	return h.Sum(nil)
 }
 
+// Copy returns a deep copy of the ballot, that can be modified without affecting the original.
+func (b *Ballot) Copy() *Ballot {
+	cp := *b
+	if b.EpochData != nil {
+		data := *b.EpochData
+		cp.EpochData = &data
+	}
+	cp.Votes.Support = cloneSlice(b.Votes.Support)
+	cp.Votes.Against = cloneSlice(b.Votes.Against)
+	cp.Votes.Abstain = cloneSlice(b.Votes.Abstain)
+	cp.EligibilityProofs = cloneSlice(b.EligibilityProofs)
+	cp.ActiveSet = cloneSlice(b.ActiveSet)
+	return &cp
+}
+
 // SetID from stored data.
 func (b *Ballot) SetID(id BallotID) {
 	b.ballotID = id
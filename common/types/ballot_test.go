@@ -1,11 +1,13 @@
 package types_test
 
 import (
+	"bytes"
 	"testing"
 
 	"github.com/spacemeshos/go-scale/tester"
 	"github.com/stretchr/testify/require"
 
+	"github.com/spacemeshos/go-spacemesh/codec"
 	"github.com/spacemeshos/go-spacemesh/common/types"
 	"github.com/spacemeshos/go-spacemesh/signing"
 )
@@ -103,3 +105,70 @@ func FuzzVotingEligibilityProofSafety(f *testing.F) {
 func TestBallotEncoding(t *testing.T) {
 	types.CheckLayerFirstEncoding(t, func(object types.Ballot) types.LayerID { return object.Layer })
 }
+
+func TestDecodeBallotHeader(t *testing.T) {
+	ballot := types.RandomBallot()
+	ballot.Layer = types.LayerID(1 << 20)
+	p := types.Proposal{InnerProposal: types.InnerProposal{Ballot: *ballot}}
+	for _, data := range [][]byte{
+		codec.MustEncode(&ballot.InnerBallot),
+		codec.MustEncode(ballot),
+		codec.MustEncode(&p),
+	} {
+		layer, atxID, err := types.DecodeBallotHeader(data)
+		require.NoError(t, err)
+		require.Equal(t, ballot.Layer, layer)
+		require.Equal(t, ballot.AtxID, atxID)
+	}
+
+	data := codec.MustEncode(ballot)
+	_, _, err := types.DecodeBallotHeader(data[:len(data)-1])
+	require.NoError(t, err)
+	_, _, err = types.DecodeBallotHeader(data[:10])
+	require.Error(t, err)
+	_, _, err = types.DecodeBallotHeader(nil)
+	require.Error(t, err)
+}
+
+func FuzzDecodeBallotHeader(f *testing.F) {
+	f.Add(codec.MustEncode(types.RandomBallot()))
+	f.Add(codec.MustEncode(&types.Proposal{InnerProposal: types.InnerProposal{Ballot: *types.RandomBallot()}}))
+	f.Add([]byte{})
+	f.Fuzz(func(t *testing.T, data []byte) {
+		layer, atxID, err := types.DecodeBallotHeader(data)
+		// full decoder for every object that starts with InnerBallot must agree with the header.
+		var inner types.InnerBallot
+		if _, decodeErr := codec.DecodeFrom(bytes.NewReader(data), &inner); decodeErr == nil {
+			require.NoError(t, err)
+			require.Equal(t, inner.Layer, layer)
+			require.Equal(t, inner.AtxID, atxID)
+		}
+	})
+}
+
+func TestBallot_Copy(t *testing.T) {
+	ballot := types.RandomBallot()
+	ballot.EpochData = &types.EpochData{ActiveSetHash: types.RandomHash(), EligibilityCount: 2}
+	ballot.ActiveSet = types.RandomActiveSet(3)
+	ballot.EligibilityProofs = []types.VotingEligibility{{J: 1}}
+	ballot.SetID(types.RandomBallotID())
+	ballot.SetMalicious()
+
+	cp := ballot.Copy()
+	require.Equal(t, ballot, cp)
+	require.Equal(t, ballot.ID(), cp.ID())
+	require.True(t, cp.IsMalicious())
+
+	original := *ballot.EpochData
+	active := ballot.ActiveSet[0]
+	support := ballot.Votes.Support[0]
+	cp.EpochData.EligibilityCount++
+	cp.ActiveSet[0] = types.RandomATXID()
+	cp.Votes.Support[0].ID = types.RandomBlockID()
+	cp.EligibilityProofs[0].J++
+	require.Equal(t, original, *ballot.EpochData)
+	require.Equal(t, active, ballot.ActiveSet[0])
+	require.Equal(t, support, ballot.Votes.Support[0])
+	require.EqualValues(t, 1, ballot.EligibilityProofs[0].J)
+	require.Nil(t, cp.Votes.Against)
+}
@@ -117,6 +117,14 @@ func (b *Block) Initialize() {
 	b.blockID = BlockID(CalcHash32(b.Bytes()).ToHash20())
 }
 
+// Copy returns a deep copy of the block, that can be modified without affecting the original.
+func (b *Block) Copy() *Block {
+	cp := *b
+	cp.Rewards = cloneSlice(b.Rewards)
+	cp.TxIDs = cloneSlice(b.TxIDs)
+	return &cp
+}
+
 // Bytes returns the serialization of the InnerBlock.
 func (b *Block) Bytes() []byte {
 	data, err := codec.Encode(&b.InnerBlock)
@@ -248,3 +256,11 @@ func (cm *CertifyMessage) Bytes() []byte {
 	}
 	return data
 }
+
+// cloneSlice returns a copy of the slice, nil if the slice is nil.
+func cloneSlice[T any](s []T) []T {
+	if s == nil {
+		return nil
+	}
+	return append(make([]T, 0, len(s)), s...)
+}
@@ -196,3 +196,20 @@ func FuzzInnerBlockSafety(f *testing.F) {
 func TestBlockEncoding(t *testing.T) {
 	types.CheckLayerFirstEncoding(t, func(object types.Block) types.LayerID { return object.LayerIndex })
 }
+
+func TestBlock_Copy(t *testing.T) {
+	block := types.NewExistingBlock(types.RandomBlockID(), types.InnerBlock{
+		LayerIndex: types.LayerID(1),
+		Rewards:    []types.AnyReward{{AtxID: types.RandomATXID(), Weight: types.RatNum{Num: 1, Denom: 1}}},
+		TxIDs:      types.RandomTXSet(2),
+	})
+	cp := block.Copy()
+	require.Equal(t, block, cp)
+	require.Equal(t, block.ID(), cp.ID())
+
+	tid := block.TxIDs[0]
+	cp.TxIDs[0] = types.RandomTransactionID()
+	cp.Rewards[0].Weight.Num++
+	require.Equal(t, tid, block.TxIDs[0])
+	require.EqualValues(t, 1, block.Rewards[0].Weight.Num)
+}
@@ -0,0 +1,43 @@
+package types
+
+import (
+	"github.com/spacemeshos/go-spacemesh/codec"
+	"github.com/spacemeshos/go-spacemesh/log"
+)
+
+//go:generate scalegen -types KeyRotation,InnerKeyRotation
+
+// InnerKeyRotation is the signed part of the KeyRotation.
+type InnerKeyRotation struct {
+	// NodeID of the identity that is bound to the PoST and ATXs.
+	NodeID NodeID
+	// NewKey is the ed25519 key that signs messages of the identity starting from the Epoch.
+	NewKey NodeID
+	// AtxID is an atx of the identity. Rotation is signed by the newest key accepted
+	// in the target epoch of the atx, and it takes effect only after that epoch.
+	AtxID ATXID
+	// Epoch is the transition epoch. During the transition epoch both the previous and the new key
+	// are accepted, after it only the new key.
+	Epoch EpochID
+}
+
+// KeyRotation binds a new signing key to the existing identity.
+// It is signed by the key that is active for the identity when the rotation is published.
+type KeyRotation struct {
+	InnerKeyRotation
+	Signature EdSignature
+}
+
+// SignedBytes returns the bytes signed by the previous key.
+func (r *KeyRotation) SignedBytes() []byte {
+	return codec.MustEncode(&r.InnerKeyRotation)
+}
+
+// MarshalLogObject implements logging interface.
+func (r *KeyRotation) MarshalLogObject(encoder log.ObjectEncoder) error {
+	encoder.AddString("node_id", r.NodeID.String())
+	encoder.AddString("new_key", r.NewKey.String())
+	encoder.AddString("atx_id", r.AtxID.String())
+	encoder.AddUint32("epoch", r.Epoch.Uint32())
+	return nil
+}
@@ -6,6 +6,7 @@ import (
 	"github.com/spacemeshos/go-scale/tester"
 	"github.com/stretchr/testify/require"
 
+	"github.com/spacemeshos/go-spacemesh/codec"
 	"github.com/spacemeshos/go-spacemesh/common/types"
 	"github.com/spacemeshos/go-spacemesh/signing"
 )
@@ -33,6 +34,32 @@ func TestProposal_Initialize(t *testing.T) {
 	require.EqualError(t, err, "proposal already initialized")
 }
 
+func TestProposal_SignedBytesScale(t *testing.T) {
+	p := types.Proposal{
+		InnerProposal: types.InnerProposal{
+			Ballot: *types.RandomBallot(),
+		},
+	}
+	empty := p.SignedBytes()
+	expected, err := codec.Encode(&p.InnerProposal)
+	require.NoError(t, err)
+	require.Equal(t, expected, empty)
+
+	for i := 0; i < 200; i++ {
+		p.TxIDs = append(p.TxIDs, types.RandomTransactionID())
+	}
+	full := p.SignedBytes()
+	expected, err = codec.Encode(&p.InnerProposal)
+	require.NoError(t, err)
+	require.Equal(t, expected, full)
+	// every tx id is encoded as a fixed size array, compact length prefix grows from 1 to 2 bytes.
+	require.Equal(t, len(empty)+200*types.TransactionIDSize+1, len(full))
+
+	var decoded types.InnerProposal
+	require.NoError(t, codec.Decode(full, &decoded))
+	require.Equal(t, p.TxIDs, decoded.TxIDs)
+}
+
 func FuzzProposalIDConsistency(f *testing.F) {
 	tester.FuzzConsistency[types.ProposalID](f)
 }
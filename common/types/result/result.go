@@ -54,6 +54,9 @@ type Block struct {
 	Invalid bool       `json:"i"`
 	Hare    bool       `json:"h"`
 	Data    bool       `json:"d"`
+	// Changed is set by tortoise Updates if validity of the block
+	// is different from the validity returned by the previous call.
+	Changed bool `json:"c,omitempty"`
 }
 
 func (b *Block) MarshalLogObject(encoder log.ObjectEncoder) error {
@@ -63,5 +66,6 @@ func (b *Block) MarshalLogObject(encoder log.ObjectEncoder) error {
 	encoder.AddBool("hare", b.Hare)
 	encoder.AddBool("data", b.Data)
 	encoder.AddBool("local", b.Local)
+	encoder.AddBool("changed", b.Changed)
 	return nil
 }
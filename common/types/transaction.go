@@ -129,10 +129,31 @@ const (
 	PENDING TXState = iota
 	// MEMPOOL represents the state when a transaction is in mempool.
 	MEMPOOL
+	// PROPOSAL represents the state when a transaction is included in a proposal.
+	PROPOSAL
+	// BLOCK represents the state when a transaction is included in a block that is not applied yet.
+	BLOCK
 	// APPLIED represents the state when a transaction is applied to the state.
 	APPLIED
 )
 
+func (s TXState) String() string {
+	switch s {
+	case PENDING:
+		return "pending"
+	case MEMPOOL:
+		return "mempool"
+	case PROPOSAL:
+		return "proposal"
+	case BLOCK:
+		return "block"
+	case APPLIED:
+		return "applied"
+	default:
+		return "unknown"
+	}
+}
+
 // MeshTransaction is stored in the mesh and included in the block.
 type MeshTransaction struct {
 	Transaction
@@ -27,6 +27,16 @@ func (h *TxHeader) Spending() uint64 {
 	return h.Fee() + h.MaxSpend
 }
 
+// Expiry is the last layer the transaction can be applied in. Zero if the transaction doesn't expire.
+func (h *TxHeader) Expiry() LayerID {
+	return LayerID(h.LayerLimits.Max)
+}
+
+// Expired returns true if the transaction can't be applied in the layer anymore.
+func (h *TxHeader) Expired(lid LayerID) bool {
+	return h.LayerLimits.Max != 0 && lid.After(h.Expiry())
+}
+
 // MarshalLogObject implements encoding for the tx header.
 func (h *TxHeader) MarshalLogObject(encoder log.ObjectEncoder) error {
 	encoder.AddString("principal", h.Principal.String())
@@ -46,3 +56,11 @@ type LayerLimits struct {
 
 // Nonce alias to uint64.
 type Nonce = uint64
+
+// ParsedTx is the transaction decoded from its wire format.
+type ParsedTx struct {
+	ID     TransactionID
+	Header *TxHeader
+	// Expiry is the last layer the transaction can be applied in. Zero if the transaction doesn't expire.
+	Expiry LayerID
+}
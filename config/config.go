@@ -15,10 +15,12 @@ import (
 	"github.com/spacemeshos/go-spacemesh/beacon"
 	"github.com/spacemeshos/go-spacemesh/bootstrap"
 	"github.com/spacemeshos/go-spacemesh/checkpoint"
+	"github.com/spacemeshos/go-spacemesh/datastore"
 	"github.com/spacemeshos/go-spacemesh/fetch"
 	vm "github.com/spacemeshos/go-spacemesh/genvm"
 	hareConfig "github.com/spacemeshos/go-spacemesh/hare/config"
 	eligConfig "github.com/spacemeshos/go-spacemesh/hare/eligibility/config"
+	"github.com/spacemeshos/go-spacemesh/mesh"
 	"github.com/spacemeshos/go-spacemesh/p2p"
 	"github.com/spacemeshos/go-spacemesh/syncer"
 	timeConfig "github.com/spacemeshos/go-spacemesh/timesync/config"
@@ -60,6 +62,7 @@ type Config struct {
 	Bootstrap       bootstrap.Config      `mapstructure:"bootstrap"`
 	Sync            syncer.Config         `mapstructure:"syncer"`
 	Recovery        checkpoint.Config     `mapstructure:"recovery"`
+	Mesh            mesh.Config           `mapstructure:"mesh"`
 }
 
 // DataDir returns the absolute path to use for the node's data. This is the tilde-expanded path given in the config
@@ -103,6 +106,42 @@ type BaseConfig struct {
 
 	TxsPerProposal int    `mapstructure:"txs-per-proposal"`
 	BlockGasLimit  uint64 `mapstructure:"block-gas-limit"`
+	// TxsSelection is the policy to select transactions for proposals, either "fifo" or "fee".
+	TxsSelection string `mapstructure:"txs-selection"`
+	// TxsSizePerProposal is the max total size in bytes of the transactions selected per proposal, zero is unlimited.
+	TxsSizePerProposal uint64 `mapstructure:"txs-size-per-proposal"`
+	// TxsReplaceFeeBump is the min fee increase in percent for a transaction to replace the transaction
+	// with the same principal and nonce in the mempool.
+	TxsReplaceFeeBump uint64 `mapstructure:"txs-replace-fee-bump"`
+	// TxsMempoolMaxAge is the max number of layers since a transaction was admitted to the mempool
+	// for it to be reloaded after restart.
+	TxsMempoolMaxAge uint32 `mapstructure:"txs-mempool-max-age"`
+	// TxsMempoolMaxTXs and TxsMempoolMaxBytes limit the number and the total size of transactions in the mempool.
+	TxsMempoolMaxTXs   int    `mapstructure:"txs-mempool-max-txs"`
+	TxsMempoolMaxBytes uint64 `mapstructure:"txs-mempool-max-bytes"`
+	// TxsMaxNonceGap is the max distance between the nonce of a submitted transaction
+	// and the next nonce of its principal.
+	TxsMaxNonceGap uint64 `mapstructure:"txs-max-nonce-gap"`
+	// TxsLocalLane is the percent of the proposal budget reserved for the transactions submitted
+	// via the api of this node.
+	TxsLocalLane uint64 `mapstructure:"txs-local-lane"`
+	// ActiveSetGracePeriod is how long before the start of the epoch the ATXs must be received
+	// to be included into the active set of the reference ballots built by the node.
+	ActiveSetGracePeriod time.Duration `mapstructure:"active-set-grace-period"`
+	// MaxProposalBytes is the max size in bytes of the encoded proposal, zero is unlimited.
+	// Proposals built by the node fit within it and larger proposals from the network are rejected.
+	MaxProposalBytes int `mapstructure:"max-proposal-bytes"`
+	// MaxActiveSetSize is the max number of ATXs in the active set of the reference ballot, zero is unlimited.
+	// Proposals from the network with larger active sets are rejected.
+	MaxActiveSetSize int `mapstructure:"max-active-set-size"`
+	// MaxPendingBallots is the max number of ballots from the network waiting for a missing base
+	// or reference ballot, zero drops such ballots right away.
+	MaxPendingBallots int `mapstructure:"max-pending-ballots"`
+	// ProposalBuildDeadline is the fraction of the layer duration after which the node doesn't build
+	// proposals for the layer, zero disables the deadline.
+	ProposalBuildDeadline float64 `mapstructure:"proposal-build-deadline"`
+	// ProposalBuildLead is how long before the start of the layer the node builds proposals.
+	ProposalBuildLead time.Duration `mapstructure:"proposal-build-lead"`
 	// if the number of proposals with the same mesh state crosses this threshold (in percentage),
 	// then we optimistically filter out infeasible transactions before constructing the block.
 	OptFilterThreshold int    `mapstructure:"optimistic-filtering-threshold"`
@@ -110,8 +149,30 @@ type BaseConfig struct {
 
 	DatabaseConnections     int  `mapstructure:"db-connections"`
 	DatabaseLatencyMetering bool `mapstructure:"db-latency-metering"`
+	// DatabaseBallotCacheSize and DatabaseBlockCacheSize are the numbers of decoded ballots and blocks
+	// kept in memory. Zero disables the cache.
+	DatabaseBallotCacheSize int `mapstructure:"db-ballot-cache-size"`
+	DatabaseBlockCacheSize  int `mapstructure:"db-block-cache-size"`
 
 	NetworkHRP string `mapstructure:"network-hrp"`
+
+	// SignatureDomainLayer is the first layer where proposals and certify messages are signed
+	// in their own domains instead of the ones shared with ballots and hare. Zero disables the upgrade.
+	SignatureDomainLayer uint32 `mapstructure:"signature-domain-layer"`
+
+	// GossipVersionLayer is the first layer where payloads on proposal, atx and beacon topics
+	// are prefixed with the encoding version. Unversioned payloads are accepted until then, and
+	// both formats are accepted within pubsub.GraceLayers around it. Zero disables the upgrade.
+	GossipVersionLayer uint32 `mapstructure:"gossip-version-layer"`
+
+	// HareSetHashLayer is the first layer where hare messages refer to the proposal sets by hash.
+	// Hare messages and certificates are versioned, so it can't precede GossipVersionLayer.
+	// Zero disables the upgrade.
+	HareSetHashLayer uint32 `mapstructure:"hare-set-hash-layer"`
+
+	// StateRootMeshHashLayer is the first layer where the state root of the layer is included
+	// in the mesh hash. Zero disables the upgrade.
+	StateRootMeshHashLayer uint32 `mapstructure:"state-root-mesh-hash-layer"`
 }
 
 // SmeshingConfig defines configuration for the node's smeshing (mining).
@@ -121,6 +182,51 @@ type SmeshingConfig struct {
 	Opts            activation.PostSetupOpts          `mapstructure:"smeshing-opts"`
 	ProvingOpts     activation.PostProvingOpts        `mapstructure:"smeshing-proving-opts"`
 	VerifyingOpts   activation.PostProofVerifyingOpts `mapstructure:"smeshing-verifying-opts"`
+	Identity        IdentityConfig                    `mapstructure:"smeshing-identity"`
+	// RemoteSignerURL is the address of the signing service that holds the key of the primary identity.
+	// The identity file and the mnemonic are not used if it is set, the connection is configured
+	// with smeshing-identity.remote-signer.
+	RemoteSignerURL string `mapstructure:"remote-signer-url"`
+}
+
+// IdentityConfig configures how smesher identity is loaded. Identity can be derived from the BIP-39
+// mnemonic instead of the key file, and the key file can be encrypted with a password.
+type IdentityConfig struct {
+	// MnemonicFile is the keystore with the BIP-39 mnemonic encrypted with the identity password.
+	// If the file doesn't exist the node generates new mnemonic and writes it to the file.
+	MnemonicFile string `mapstructure:"mnemonic-file"`
+	Passphrase   string `mapstructure:"passphrase"`
+	// Index of the identity derived with path m/44'/540'/0'/0'/index'.
+	Index uint32 `mapstructure:"index"`
+
+	// PasswordFile with the password for encrypted identity file.
+	// SPACEMESH_KEY_PASSWORD environment variable takes precedence.
+	PasswordFile string `mapstructure:"password-file"`
+	// EncryptKey replaces unencrypted identity file with the encrypted one, if password is configured.
+	EncryptKey bool `mapstructure:"encrypt-key"`
+
+	// Dir with key files of additional identities that build proposals with this node.
+	// Only files with .key extension are loaded, encrypted files use the same password.
+	Dir string `mapstructure:"dir"`
+
+	// RemoteSigner configures the connection to the signing service at smeshing.remote-signer-url.
+	RemoteSigner RemoteSignerConfig `mapstructure:"remote-signer"`
+}
+
+// RemoteSignerConfig configures the connection to the remote signing service.
+type RemoteSignerConfig struct {
+	// URL is a deprecated alias for smeshing.remote-signer-url.
+	URL string `mapstructure:"url"`
+	// TokenFile with the token that authorizes calls to the signing service.
+	// SPACEMESH_SIGNER_TOKEN environment variable takes precedence.
+	TokenFile string `mapstructure:"token-file"`
+	// CACert verifies the certificate of the signing service. TLS is enabled if CACert or Cert is set.
+	CACert string `mapstructure:"ca-cert"`
+	// Cert and Key are presented to the signing service for mutual TLS.
+	Cert string `mapstructure:"cert"`
+	Key  string `mapstructure:"key"`
+	// Timeout of every signing call.
+	Timeout time.Duration `mapstructure:"timeout"`
 }
 
 // DefaultConfig returns the default configuration for a spacemesh node.
@@ -144,6 +250,7 @@ func DefaultConfig() Config {
 		Bootstrap:       bootstrap.DefaultConfig(),
 		Sync:            syncer.DefaultConfig(),
 		Recovery:        checkpoint.DefaultConfig(),
+		Mesh:            mesh.DefaultConfig(),
 	}
 }
 
@@ -171,10 +278,21 @@ func defaultBaseConfig() BaseConfig {
 		PoETServers:         []string{"127.0.0.1"},
 		TxsPerProposal:      100,
 		BlockGasLimit:       math.MaxUint64,
+		TxsSelection:        "fifo",
+		TxsReplaceFeeBump:   10,
+		TxsMempoolMaxAge:    1000,
+		TxsMempoolMaxTXs:    100_000,
+		TxsMempoolMaxBytes:  64 << 20,
+		TxsMaxNonceGap:      100,
+		TxsLocalLane:        20,
+		MaxPendingBallots:   10_000,
 		OptFilterThreshold:  90,
 		TickSize:            100,
 		DatabaseConnections: 16,
 		NetworkHRP:          "sm",
+
+		DatabaseBallotCacheSize: datastore.DefaultBallotCacheSize,
+		DatabaseBlockCacheSize:  datastore.DefaultBlockCacheSize,
 	}
 }
 
@@ -186,6 +304,9 @@ func DefaultSmeshingConfig() SmeshingConfig {
 		Opts:            activation.DefaultPostSetupOpts(),
 		ProvingOpts:     activation.DefaultPostProvingOpts(),
 		VerifyingOpts:   activation.DefaultPostVerifyingOpts(),
+		Identity: IdentityConfig{
+			RemoteSigner: RemoteSignerConfig{Timeout: 5 * time.Second},
+		},
 	}
 }
 
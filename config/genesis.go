@@ -13,6 +13,7 @@ import (
 	"github.com/spacemeshos/go-spacemesh/common/util"
 	"github.com/spacemeshos/go-spacemesh/hash"
 	"github.com/spacemeshos/go-spacemesh/log"
+	"github.com/spacemeshos/go-spacemesh/mesh"
 	"github.com/spacemeshos/go-spacemesh/signing"
 )
 
@@ -39,6 +40,20 @@ func (g *GenesisConfig) GoldenATX() types.Hash32 {
 	return types.BytesToHash(hh.Sum(nil))
 }
 
+// MeshGenesis returns the parameters the genesis of the mesh is derived from.
+func (g *GenesisConfig) MeshGenesis() mesh.GenesisConfig {
+	parsed, err := time.Parse(time.RFC3339, g.GenesisTime)
+	if err != nil {
+		panic("code should have run Validate before this method")
+	}
+	return mesh.GenesisConfig{
+		Time:      parsed,
+		ExtraData: g.ExtraData,
+		Accounts:  g.ToAccounts(),
+		Layer:     types.GetEffectiveGenesis(),
+	}
+}
+
 // Validate GenesisConfig.
 func (g *GenesisConfig) Validate() error {
 	if len(g.ExtraData) == 0 {
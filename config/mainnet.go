@@ -14,6 +14,7 @@ import (
 	"github.com/spacemeshos/go-spacemesh/fetch"
 	hareConfig "github.com/spacemeshos/go-spacemesh/hare/config"
 	eligConfig "github.com/spacemeshos/go-spacemesh/hare/eligibility/config"
+	"github.com/spacemeshos/go-spacemesh/mesh"
 	"github.com/spacemeshos/go-spacemesh/p2p"
 	"github.com/spacemeshos/go-spacemesh/syncer"
 	timeConfig "github.com/spacemeshos/go-spacemesh/timesync/config"
@@ -40,8 +41,10 @@ func MainnetConfig() Config {
 
 			TxsPerProposal: 700,       // https://github.com/spacemeshos/go-spacemesh/issues/4559
 			BlockGasLimit:  100107000, // 3000 of spends
+			TxsSelection:   "fifo",
 
 			OptFilterThreshold: 90,
+			MaxPendingBallots:  10_000,
 
 			TickSize: 9331200,
 		},
@@ -54,7 +57,9 @@ func MainnetConfig() Config {
 			Zdist:                    2,
 			WindowSize:               10000,
 			MaxExceptions:            1000,
+			QueueSize:                1000,
 			BadBeaconVoteDelayLayers: 4032,
+			HealingDistance:          200,
 			// TODO update it with safe but reasonble minimum weight in network before first ballot
 			MinimalActiveSetWeight: 1000 * 9331200,
 		},
@@ -111,5 +116,6 @@ func MainnetConfig() Config {
 		LOGGING:  defaultLoggingConfig(),
 		Sync:     syncer.DefaultConfig(),
 		Recovery: checkpoint.DefaultConfig(),
+		Mesh:     mesh.DefaultConfig(),
 	}
 }
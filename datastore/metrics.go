@@ -0,0 +1,27 @@
+package datastore
+
+import (
+	"github.com/spacemeshos/go-spacemesh/metrics"
+)
+
+const subsystem = "datastore"
+
+var (
+	cacheHits = metrics.NewCounter(
+		"cache_hits",
+		subsystem,
+		"Number of lookups served from the cache",
+		[]string{"store"},
+	)
+	cacheMisses = metrics.NewCounter(
+		"cache_misses",
+		subsystem,
+		"Number of lookups served from the database",
+		[]string{"store"},
+	)
+
+	ballotHits   = cacheHits.WithLabelValues("ballots")
+	ballotMisses = cacheMisses.WithLabelValues("ballots")
+	blockHits    = cacheHits.WithLabelValues("blocks")
+	blockMisses  = cacheMisses.WithLabelValues("blocks")
+)
@@ -0,0 +1,121 @@
+package datastore
+
+import (
+	lru "github.com/hashicorp/golang-lru/v2"
+
+	"github.com/spacemeshos/go-spacemesh/common/types"
+	"github.com/spacemeshos/go-spacemesh/sql/ballots"
+	"github.com/spacemeshos/go-spacemesh/sql/blocks"
+)
+
+const (
+	// DefaultBallotCacheSize is the default number of decoded ballots kept in memory.
+	DefaultBallotCacheSize = 5000
+	// DefaultBlockCacheSize is the default number of decoded blocks kept in memory.
+	DefaultBlockCacheSize = 500
+)
+
+type cacheOpts struct {
+	ballots, blocks int
+}
+
+// Opt for configuring CachedDB.
+type Opt func(*cacheOpts)
+
+// WithBallotCacheSize sets the number of decoded ballots kept in memory. Zero disables the cache.
+func WithBallotCacheSize(size int) Opt {
+	return func(opts *cacheOpts) {
+		opts.ballots = size
+	}
+}
+
+// WithBlockCacheSize sets the number of decoded blocks kept in memory. Zero disables the cache.
+func WithBlockCacheSize(size int) Opt {
+	return func(opts *cacheOpts) {
+		opts.blocks = size
+	}
+}
+
+func newCache[K comparable, V any](size int) (*lru.Cache[K, V], error) {
+	if size <= 0 {
+		return nil, nil
+	}
+	return lru.New[K, V](size)
+}
+
+// GetBallot returns the ballot with the id.
+// The returned ballot is a copy that the caller is free to modify.
+func (db *CachedDB) GetBallot(id types.BallotID) (*types.Ballot, error) {
+	if db.ballotCache != nil {
+		if ballot, ok := db.ballotCache.Get(id); ok {
+			ballotHits.Inc()
+			cp := ballot.Copy()
+			if !cp.IsMalicious() && cp.SmesherID != types.EmptyNodeID {
+				// the smesher may be found malicious after the ballot was cached
+				malicious, err := db.IsMalicious(cp.SmesherID)
+				if err != nil {
+					return nil, err
+				}
+				if malicious {
+					cp.SetMalicious()
+				}
+			}
+			return cp, nil
+		}
+		ballotMisses.Inc()
+	}
+	ballot, err := ballots.Get(db, id)
+	if err != nil {
+		return nil, err
+	}
+	db.CacheBallot(ballot)
+	return ballot, nil
+}
+
+// CacheBallot adds the ballot that was written to the database to the cache.
+// It should be called after the transaction that stored the ballot is committed.
+func (db *CachedDB) CacheBallot(ballot *types.Ballot) {
+	if db.ballotCache != nil {
+		db.ballotCache.Add(ballot.ID(), ballot.Copy())
+	}
+}
+
+// InvalidateBallot removes the ballot from the cache. It must be called when the ballot is deleted from the database.
+func (db *CachedDB) InvalidateBallot(id types.BallotID) {
+	if db.ballotCache != nil {
+		db.ballotCache.Remove(id)
+	}
+}
+
+// GetBlock returns the block with the id.
+// The returned block is a copy that the caller is free to modify.
+func (db *CachedDB) GetBlock(id types.BlockID) (*types.Block, error) {
+	if db.blockCache != nil {
+		if block, ok := db.blockCache.Get(id); ok {
+			blockHits.Inc()
+			return block.Copy(), nil
+		}
+		blockMisses.Inc()
+	}
+	block, err := blocks.Get(db, id)
+	if err != nil {
+		return nil, err
+	}
+	db.CacheBlock(block)
+	return block, nil
+}
+
+// CacheBlock adds the block that was written to the database to the cache.
+// It should be called after the transaction that stored the block is committed.
+func (db *CachedDB) CacheBlock(block *types.Block) {
+	if db.blockCache != nil {
+		db.blockCache.Add(block.ID(), block.Copy())
+	}
+}
+
+// InvalidateBlock removes the block from the cache. It must be called when the block is deleted from the database.
+func (db *CachedDB) InvalidateBlock(id types.BlockID) {
+	if db.blockCache != nil {
+		db.blockCache.Remove(id)
+	}
+}
@@ -0,0 +1,175 @@
+package datastore
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+
+	"github.com/spacemeshos/go-spacemesh/common/types"
+	"github.com/spacemeshos/go-spacemesh/log/logtest"
+	"github.com/spacemeshos/go-spacemesh/signing"
+	"github.com/spacemeshos/go-spacemesh/sql"
+	"github.com/spacemeshos/go-spacemesh/sql/ballots"
+	"github.com/spacemeshos/go-spacemesh/sql/blocks"
+	"github.com/spacemeshos/go-spacemesh/sql/identities"
+)
+
+func newBallot(tb testing.TB, lid types.LayerID) *types.Ballot {
+	tb.Helper()
+	sig, err := signing.NewEdSigner()
+	require.NoError(tb, err)
+	ballot := types.RandomBallot()
+	ballot.Layer = lid
+	ballot.Signature = sig.Sign(signing.BALLOT, ballot.SignedBytes())
+	ballot.SmesherID = sig.NodeID()
+	require.NoError(tb, ballot.Initialize())
+	return ballot
+}
+
+func TestCachedDB_Ballots(t *testing.T) {
+	db := NewCachedDB(sql.InMemory(), logtest.New(t))
+	ballot := newBallot(t, types.LayerID(10))
+	_, err := db.GetBallot(ballot.ID())
+	require.ErrorIs(t, err, sql.ErrNotFound)
+	require.NoError(t, ballots.Add(db, ballot))
+
+	hits, misses := testutil.ToFloat64(ballotHits), testutil.ToFloat64(ballotMisses)
+	got, err := db.GetBallot(ballot.ID())
+	require.NoError(t, err)
+	require.Equal(t, ballot, got)
+	require.Equal(t, misses+1, testutil.ToFloat64(ballotMisses))
+
+	// the caller can't corrupt the cached ballot
+	got.Votes.Support[0].ID = types.RandomBlockID()
+	got, err = db.GetBallot(ballot.ID())
+	require.NoError(t, err)
+	require.Equal(t, ballot, got)
+	require.Equal(t, hits+1, testutil.ToFloat64(ballotHits))
+
+	// the smesher is found malicious after the ballot was cached
+	require.NoError(t, identities.SetMalicious(db, ballot.SmesherID, []byte("proof")))
+	db.CacheMalfeasanceProof(ballot.SmesherID, &types.MalfeasanceProof{})
+	got, err = db.GetBallot(ballot.ID())
+	require.NoError(t, err)
+	require.True(t, got.IsMalicious())
+
+	// the ballot stored in the database is cached without reading it back
+	stored := newBallot(t, types.LayerID(11))
+	require.NoError(t, ballots.Add(db, stored))
+	db.CacheBallot(stored)
+	hits = testutil.ToFloat64(ballotHits)
+	got, err = db.GetBallot(stored.ID())
+	require.NoError(t, err)
+	require.Equal(t, stored, got)
+	require.Equal(t, hits+1, testutil.ToFloat64(ballotHits))
+
+	db.InvalidateBallot(stored.ID())
+	misses = testutil.ToFloat64(ballotMisses)
+	_, err = db.GetBallot(stored.ID())
+	require.NoError(t, err)
+	require.Equal(t, misses+1, testutil.ToFloat64(ballotMisses))
+}
+
+func TestCachedDB_Blocks(t *testing.T) {
+	db := NewCachedDB(sql.InMemory(), logtest.New(t))
+	block := types.NewExistingBlock(types.BlockID{}, types.InnerBlock{
+		LayerIndex: types.LayerID(10),
+		TxIDs:      types.RandomTXSet(3),
+	})
+	block.Initialize()
+	_, err := db.GetBlock(block.ID())
+	require.ErrorIs(t, err, sql.ErrNotFound)
+	require.NoError(t, blocks.Add(db, block))
+
+	hits, misses := testutil.ToFloat64(blockHits), testutil.ToFloat64(blockMisses)
+	got, err := db.GetBlock(block.ID())
+	require.NoError(t, err)
+	require.Equal(t, block, got)
+	require.Equal(t, misses+1, testutil.ToFloat64(blockMisses))
+
+	got.TxIDs[0] = types.RandomTransactionID()
+	got, err = db.GetBlock(block.ID())
+	require.NoError(t, err)
+	require.Equal(t, block, got)
+	require.Equal(t, hits+1, testutil.ToFloat64(blockHits))
+
+	db.InvalidateBlock(block.ID())
+	misses = testutil.ToFloat64(blockMisses)
+	_, err = db.GetBlock(block.ID())
+	require.NoError(t, err)
+	require.Equal(t, misses+1, testutil.ToFloat64(blockMisses))
+}
+
+func TestCachedDB_CacheDisabled(t *testing.T) {
+	db := NewCachedDB(sql.InMemory(), logtest.New(t), WithBallotCacheSize(0), WithBlockCacheSize(0))
+	ballot := newBallot(t, types.LayerID(10))
+	require.NoError(t, ballots.Add(db, ballot))
+	db.CacheBallot(ballot)
+	misses := testutil.ToFloat64(ballotMisses)
+	for i := 0; i < 2; i++ {
+		got, err := db.GetBallot(ballot.ID())
+		require.NoError(t, err)
+		require.Equal(t, ballot, got)
+	}
+	require.Equal(t, misses, testutil.ToFloat64(ballotMisses))
+}
+
+// BenchmarkWindowWalk counts the database queries made by a walk over the ballots in the tortoise window,
+// that loads every ballot together with its base and reference ballots.
+func BenchmarkWindowWalk(b *testing.B) {
+	const (
+		window   = 100
+		perLayer = 50
+	)
+	types.SetLayersPerEpoch(10)
+	var (
+		db      = sql.InMemory()
+		walk    [][3]types.BallotID
+		prev    []*types.Ballot
+		refs    = make([]*types.Ballot, perLayer)
+		genesis = types.GetEffectiveGenesis()
+	)
+	for lid := genesis.Add(1); !lid.After(genesis.Add(window)); lid = lid.Add(1) {
+		layer := make([]*types.Ballot, 0, perLayer)
+		for i := 0; i < perLayer; i++ {
+			ballot := newBallot(b, lid)
+			require.NoError(b, ballots.Add(db, ballot))
+			if lid.FirstInEpoch() || refs[i] == nil {
+				refs[i] = ballot
+			}
+			base := ballot
+			if prev != nil {
+				base = prev[i%len(prev)]
+			}
+			walk = append(walk, [3]types.BallotID{ballot.ID(), base.ID(), refs[i].ID()})
+			layer = append(layer, ballot)
+		}
+		prev = layer
+	}
+	run := func(b *testing.B, opts ...Opt) {
+		var queries float64
+		for i := 0; i < b.N; i++ {
+			cdb := NewCachedDB(db, logtest.New(b), opts...)
+			before := testutil.ToFloat64(ballotMisses)
+			for _, ids := range walk {
+				for _, id := range ids {
+					_, err := cdb.GetBallot(id)
+					require.NoError(b, err)
+				}
+			}
+			if cdb.ballotCache == nil {
+				queries += float64(len(walk) * 3)
+			} else {
+				queries += testutil.ToFloat64(ballotMisses) - before
+			}
+		}
+		b.ReportMetric(queries/float64(b.N), "queries/op")
+	}
+	b.Run("NoCache", func(b *testing.B) {
+		run(b, WithBallotCacheSize(0))
+	})
+	b.Run("Cache", func(b *testing.B) {
+		run(b)
+	})
+}
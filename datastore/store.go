@@ -14,7 +14,9 @@ import (
 	"github.com/spacemeshos/go-spacemesh/sql/atxs"
 	"github.com/spacemeshos/go-spacemesh/sql/ballots"
 	"github.com/spacemeshos/go-spacemesh/sql/blocks"
+	"github.com/spacemeshos/go-spacemesh/sql/haresets"
 	"github.com/spacemeshos/go-spacemesh/sql/identities"
+	"github.com/spacemeshos/go-spacemesh/sql/keyrotations"
 	"github.com/spacemeshos/go-spacemesh/sql/poets"
 	"github.com/spacemeshos/go-spacemesh/sql/proposals"
 	"github.com/spacemeshos/go-spacemesh/sql/transactions"
@@ -41,10 +43,18 @@ type CachedDB struct {
 	// used to coordinate db update and cache
 	mu               sync.Mutex
 	malfeasanceCache *lru.Cache[types.NodeID, *types.MalfeasanceProof]
+
+	// ballotCache and blockCache hold decoded objects, they are nil if disabled.
+	ballotCache *lru.Cache[types.BallotID, *types.Ballot]
+	blockCache  *lru.Cache[types.BlockID, *types.Block]
 }
 
 // NewCachedDB create an instance of a CachedDB.
-func NewCachedDB(db *sql.Database, lg log.Log) *CachedDB {
+func NewCachedDB(db *sql.Database, lg log.Log, opts ...Opt) *CachedDB {
+	options := cacheOpts{ballots: DefaultBallotCacheSize, blocks: DefaultBlockCacheSize}
+	for _, opt := range opts {
+		opt(&options)
+	}
 	atxHdrCache, err := lru.New[types.ATXID, *types.ActivationTxHeader](atxHdrCacheSize)
 	if err != nil {
 		lg.Fatal("failed to create atx cache", err)
@@ -60,12 +70,24 @@ func NewCachedDB(db *sql.Database, lg log.Log) *CachedDB {
 		lg.Fatal("failed to create vrf nonce cache", err)
 	}
 
+	ballotCache, err := newCache[types.BallotID, *types.Ballot](options.ballots)
+	if err != nil {
+		lg.Fatal("failed to create ballot cache", err)
+	}
+
+	blockCache, err := newCache[types.BlockID, *types.Block](options.blocks)
+	if err != nil {
+		lg.Fatal("failed to create block cache", err)
+	}
+
 	return &CachedDB{
 		Database:         db,
 		logger:           lg,
 		atxHdrCache:      atxHdrCache,
 		malfeasanceCache: malfeasanceCache,
 		vrfNonceCache:    vrfNonceCache,
+		ballotCache:      ballotCache,
+		blockCache:       blockCache,
 	}
 }
 
@@ -278,6 +300,8 @@ const (
 	TXDB        Hint = "TXDB"
 	POETDB      Hint = "POETDB"
 	Malfeasance Hint = "malfeasance"
+	KeyRotation Hint = "keyRotation"
+	HareSet     Hint = "hareSet"
 )
 
 // NewBlobStore returns a BlobStore.
@@ -327,6 +351,10 @@ func (bs *BlobStore) Get(hint Hint, key []byte) ([]byte, error) {
 		return poets.Get(bs.DB, ref)
 	case Malfeasance:
 		return identities.GetMalfeasanceBlob(bs.DB, key)
+	case KeyRotation:
+		return keyrotations.GetBlob(bs.DB, key)
+	case HareSet:
+		return haresets.GetBlob(bs.DB, key)
 	}
 	return nil, fmt.Errorf("blob store not found %s", hint)
 }
@@ -225,6 +225,16 @@ func (fo *FixedRolacle) eligible(ctx context.Context, layer types.LayerID, round
 	return exist, nil
 }
 
+// CommitteeSize returns the configured committee size, limited by the number of clients.
+func (fo *FixedRolacle) CommitteeSize(_ context.Context, _ types.LayerID, configured int) (int, error) {
+	fo.mapRW.RLock()
+	defer fo.mapRW.RUnlock()
+	if total := len(fo.honest) + len(fo.faulty); total < configured {
+		return total, nil
+	}
+	return configured, nil
+}
+
 // Proof generates a proof for the round. used to satisfy interface.
 func (fo *FixedRolacle) Proof(ctx context.Context, layer types.LayerID, round uint32) (types.VrfSignature, error) {
 	kInBytes := make([]byte, 4)
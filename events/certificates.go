@@ -0,0 +1,25 @@
+package events
+
+import (
+	"github.com/spacemeshos/go-spacemesh/common/types"
+	"github.com/spacemeshos/go-spacemesh/log"
+)
+
+// ConflictingCertificates is reported when valid certificates for more than one block in the layer are found.
+// It is possible only if a large fraction of the hare committee equivocated. All certificates are kept,
+// and the tortoise decides the layer as if hare didn't output a block.
+type ConflictingCertificates struct {
+	Layer  types.LayerID
+	Blocks []types.BlockID
+}
+
+// ReportConflictingCertificates reports the blocks with valid certificates in the layer.
+func ReportConflictingCertificates(layer types.LayerID, blocks []types.BlockID) {
+	mu.RLock()
+	defer mu.RUnlock()
+	if reporter != nil {
+		if err := reporter.certificatesEmitter.Emit(ConflictingCertificates{Layer: layer, Blocks: blocks}); err != nil {
+			log.With().Error("failed to emit conflicting certificates", layer, log.Err(err))
+		}
+	}
+}
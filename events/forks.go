@@ -0,0 +1,29 @@
+package events
+
+import (
+	"github.com/spacemeshos/go-spacemesh/common/types"
+	"github.com/spacemeshos/go-spacemesh/log"
+)
+
+// ForkDetected is reported when the quorum of peers advertises a mesh hash different from the local
+// mesh hash of the verified layer. Divergent is the earliest layer where the mesh of the probed peer
+// differs from the local mesh, within the configured probing depth.
+type ForkDetected struct {
+	Layer     types.LayerID
+	Divergent types.LayerID
+	Hash      types.Hash32
+	PeerHash  types.Hash32
+	Peers     int
+	Disagreed int
+}
+
+// ReportForkDetected reports the divergence of the local mesh from the mesh of the peers.
+func ReportForkDetected(fork ForkDetected) {
+	mu.RLock()
+	defer mu.RUnlock()
+	if reporter != nil {
+		if err := reporter.forksEmitter.Emit(fork); err != nil {
+			log.With().Error("failed to emit fork detected", fork.Layer, log.Err(err))
+		}
+	}
+}
@@ -0,0 +1,24 @@
+package events
+
+import (
+	"github.com/spacemeshos/go-spacemesh/common/types"
+	"github.com/spacemeshos/go-spacemesh/log"
+)
+
+// HareLayerFailed is reported when hare didn't reach agreement on the layer within the iterations limit.
+// Hare doesn't produce an output for such layer, and ballots abstain on it.
+type HareLayerFailed struct {
+	Layer      types.LayerID
+	Iterations int
+}
+
+// ReportHareLayerFailed reports that hare terminated without agreement on the layer.
+func ReportHareLayerFailed(layer types.LayerID, iterations int) {
+	mu.RLock()
+	defer mu.RUnlock()
+	if reporter != nil {
+		if err := reporter.hareEmitter.Emit(HareLayerFailed{Layer: layer, Iterations: iterations}); err != nil {
+			log.With().Error("failed to emit hare layer failure", layer, log.Err(err))
+		}
+	}
+}
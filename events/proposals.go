@@ -1,6 +1,10 @@
 package events
 
 import (
+	"fmt"
+
+	pb "github.com/spacemeshos/api/release/go/spacemesh/v1"
+
 	"github.com/spacemeshos/go-spacemesh/common/types"
 	"github.com/spacemeshos/go-spacemesh/log"
 )
@@ -14,6 +18,8 @@ func (s ProposalStatus) String() string {
 		return "created"
 	case ProposalIncluded:
 		return "included"
+	case ProposalExpired:
+		return "expired"
 	default:
 		panic("unknown status")
 	}
@@ -24,12 +30,17 @@ const (
 	ProposalCreated ProposalStatus = iota
 	// ProposalIncluded is a status of the proposal when it is included into the block.
 	ProposalIncluded
+	// ProposalExpired is a status of the proposal that the node failed to publish before the layer ended.
+	ProposalExpired
 )
 
 // EventProposal includes proposal and proposal status.
 type EventProposal struct {
 	Status   ProposalStatus
 	Proposal *types.Proposal
+
+	// Reason is set for the expired proposals.
+	Reason string
 }
 
 // ReportProposal reports a proposal.
@@ -44,6 +55,160 @@ func ReportProposal(status ProposalStatus, proposal *types.Proposal) {
 	}
 }
 
+// ReportProposalExpired reports a proposal that wasn't published before the layer ended.
+func ReportProposalExpired(proposal *types.Proposal, reason string) {
+	mu.RLock()
+	defer mu.RUnlock()
+	if reporter != nil {
+		if err := reporter.proposalsEmitter.Emit(EventProposal{Status: ProposalExpired, Proposal: proposal, Reason: reason}); err != nil {
+			log.With().Error("failed to emit proposal", log.Err(err))
+		}
+	}
+}
+
+// BuilderEvent is a lifecycle event of the proposal builder. Exactly one of the fields is set.
+type BuilderEvent struct {
+	EligibilityComputed  *EligibilityComputed
+	ProposalBuildStarted *ProposalBuildStarted
+	ProposalPublished    *ProposalPublished
+	ProposalFailed       *ProposalFailed
+}
+
+// EligibilityComputed is reported when the identity computed its proposal eligibilities for the epoch.
+type EligibilityComputed struct {
+	Epoch  types.EpochID
+	NodeID types.NodeID
+	Atx    types.ATXID
+	// Count is the number of proposals the identity is eligible for in the epoch.
+	Count int
+}
+
+// ProposalBuildStarted is reported when the identity is eligible in the layer and starts building proposal.
+type ProposalBuildStarted struct {
+	Layer  types.LayerID
+	NodeID types.NodeID
+	// Count is the number of eligibilities included into the proposal.
+	Count int
+}
+
+// ProposalPublished is reported when the proposal was published to the network.
+type ProposalPublished struct {
+	Layer    types.LayerID
+	NodeID   types.NodeID
+	Proposal types.ProposalID
+}
+
+// ProposalFailed is reported when the identity didn't build or publish proposal in the layer.
+type ProposalFailed struct {
+	Layer  types.LayerID
+	NodeID types.NodeID
+	Reason ProposalFailureReason
+	// Error describes the failure, it is empty if the reason is self-explanatory.
+	Error string
+}
+
+// ProposalFailureReason is a reason why the identity didn't build or publish proposal in the layer.
+type ProposalFailureReason int
+
+const (
+	// FailureNotEligible is reported when the identity is not eligible in the layer.
+	FailureNotEligible ProposalFailureReason = iota + 1
+	// FailureNotSynced is reported when the node is not synced.
+	FailureNotSynced
+	// FailureNoBeacon is reported when the beacon of the epoch is not known.
+	FailureNoBeacon
+	// FailureNoNonce is reported when the identity has no vrf nonce for the epoch.
+	FailureNoNonce
+	// FailureNoATX is reported when the identity has no atx targeting the epoch.
+	FailureNoATX
+	// FailureEligibility is reported when eligibilities of the identity can't be computed.
+	FailureEligibility
+	// FailureDuplicateLayer is reported when the identity already has a ballot in the layer.
+	FailureDuplicateLayer
+	// FailureNoOpinion is reported when tortoise can't encode votes.
+	FailureNoOpinion
+	// FailureBuild is reported when the proposal can't be created.
+	FailureBuild
+	// FailurePublish is reported when the proposal wasn't published before the layer ended.
+	FailurePublish
+	// FailureLate is reported when the builder woke up past the build deadline of the layer.
+	FailureLate
+)
+
+func (r ProposalFailureReason) String() string {
+	switch r {
+	case FailureNotEligible:
+		return "not_eligible"
+	case FailureNotSynced:
+		return "not_synced"
+	case FailureNoBeacon:
+		return "no_beacon"
+	case FailureNoNonce:
+		return "no_vrf_nonce"
+	case FailureNoATX:
+		return "no_atx"
+	case FailureEligibility:
+		return "eligibility"
+	case FailureDuplicateLayer:
+		return "duplicate_layer"
+	case FailureNoOpinion:
+		return "no_opinion"
+	case FailureBuild:
+		return "build"
+	case FailurePublish:
+		return "publish"
+	case FailureLate:
+		return "late"
+	default:
+		return fmt.Sprintf("unknown(%d)", int(r))
+	}
+}
+
+func reportBuilder(ev BuilderEvent) {
+	mu.RLock()
+	defer mu.RUnlock()
+	if reporter != nil {
+		if err := reporter.builderEmitter.Emit(ev); err != nil {
+			log.With().Error("failed to emit builder event", log.Err(err))
+		}
+	}
+}
+
+// ReportEligibilityComputed reports eligibilities computed by the identity for the epoch.
+func ReportEligibilityComputed(ev EligibilityComputed) {
+	reportBuilder(BuilderEvent{EligibilityComputed: &ev})
+}
+
+// ReportProposalBuildStarted reports that the identity started building proposal in the layer.
+func ReportProposalBuildStarted(ev ProposalBuildStarted) {
+	reportBuilder(BuilderEvent{ProposalBuildStarted: &ev})
+}
+
+// ReportProposalPublished reports that the proposal was published.
+func ReportProposalPublished(ev ProposalPublished) {
+	reportBuilder(BuilderEvent{ProposalPublished: &ev})
+}
+
+// ReportProposalFailed reports that the identity didn't build or publish proposal in the layer.
+// Failures other than not being eligible are also emitted as user events.
+func ReportProposalFailed(ev ProposalFailed) {
+	reportBuilder(BuilderEvent{ProposalFailed: &ev})
+	if ev.Reason == FailureNotEligible {
+		return
+	}
+	help := fmt.Sprintf("Node failed to build proposal for %s: %s.", ev.NodeID.ShortString(), ev.Reason)
+	if ev.Error != "" {
+		help = fmt.Sprintf("Node failed to build proposal for %s: %s: %s.", ev.NodeID.ShortString(), ev.Reason, ev.Error)
+	}
+	emitUserEvent(
+		help,
+		true,
+		&pb.Event_Proposal{
+			Proposal: &pb.EventProposal{Layer: ev.Layer.Uint32()},
+		},
+	)
+}
+
 // SubcribeProposals subscribes to the proposals.
 func SubcribeProposals() Subscription {
 	mu.RLock()
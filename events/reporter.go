@@ -57,13 +57,28 @@ func ReportNewTx(layerID types.LayerID, tx *types.Transaction) {
 
 // ReportTxWithValidity reports a tx along with whether it was just invalidated.
 func ReportTxWithValidity(layerID types.LayerID, tx *types.Transaction, valid bool) {
-	mu.RLock()
-	defer mu.RUnlock()
-	txWithValidity := Transaction{
+	reportTx(Transaction{
 		Transaction: tx,
 		LayerID:     layerID,
 		Valid:       valid,
-	}
+	})
+}
+
+// ReportReorgedTx reports a tx executed again after the state was reverted and the layer was reapplied.
+func ReportReorgedTx(layerID types.LayerID, tx *types.Transaction) {
+	reportTx(Transaction{
+		Transaction: tx,
+		LayerID:     layerID,
+		Valid:       true,
+		Reorg:       true,
+	})
+}
+
+func reportTx(txWithValidity Transaction) {
+	mu.RLock()
+	defer mu.RUnlock()
+	tx := txWithValidity.Transaction
+	layerID := txWithValidity.LayerID
 	if reporter != nil {
 		if err := reporter.transactionEmitter.Emit(txWithValidity); err != nil {
 			// TODO(nkryuchkov): consider returning an error and log outside the function
@@ -355,6 +370,8 @@ const (
 type LayerUpdate struct {
 	LayerID types.LayerID
 	Status  int
+	// StateRoot is the state root after the layer was applied, set for the applied layers.
+	StateRoot types.Hash32
 }
 
 // Field returns a log field. Implements the LoggableField interface.
@@ -386,6 +403,8 @@ type Reward struct {
 	Total       uint64
 	LayerReward uint64
 	Coinbase    types.Address
+	// Reorg is set if the reward is reported again after the state was reverted and the layer was reapplied.
+	Reorg bool
 }
 
 // Transaction wraps a tx with its layer ID and validity info.
@@ -393,6 +412,8 @@ type Transaction struct {
 	Transaction *types.Transaction
 	LayerID     types.LayerID
 	Valid       bool
+	// Reorg is set if the tx is reported again after the state was reverted and the layer was reapplied.
+	Reorg bool
 }
 
 // ActivationTx wraps *types.VerifiedActivationTx.
@@ -420,12 +441,24 @@ type EventReporter struct {
 	rewardEmitter      event.Emitter
 	resultsEmitter     event.Emitter
 	proposalsEmitter   event.Emitter
+	hareEmitter        event.Emitter
 	events             struct {
 		sync.Mutex
 		buf     *Ring[UserEvent]
 		emitter event.Emitter
 	}
 	stopChan chan struct{}
+
+	certificatesEmitter event.Emitter
+	forksEmitter        event.Emitter
+	builderEmitter      event.Emitter
+	txReplacedEmitter   event.Emitter
+	txEvictedEmitter    event.Emitter
+	txExpiredEmitter    event.Emitter
+	txStateEmitter      event.Emitter
+	txAdmittedEmitter   event.Emitter
+	txInProposalEmitter event.Emitter
+	txInBlockEmitter    event.Emitter
 }
 
 func (r *EventReporter) emitUserEvent(ev UserEvent) error {
@@ -484,6 +517,50 @@ func newEventReporter() *EventReporter {
 	if err != nil {
 		log.With().Panic("failed to to create proposal emitter", log.Err(err))
 	}
+	hareEmitter, err := bus.Emitter(new(HareLayerFailed))
+	if err != nil {
+		log.With().Panic("failed to create hare emitter", log.Err(err))
+	}
+	certificatesEmitter, err := bus.Emitter(new(ConflictingCertificates))
+	if err != nil {
+		log.With().Panic("failed to create certificates emitter", log.Err(err))
+	}
+	forksEmitter, err := bus.Emitter(new(ForkDetected))
+	if err != nil {
+		log.With().Panic("failed to create forks emitter", log.Err(err))
+	}
+	builderEmitter, err := bus.Emitter(new(BuilderEvent))
+	if err != nil {
+		log.With().Panic("failed to create builder emitter", log.Err(err))
+	}
+	txReplacedEmitter, err := bus.Emitter(new(TxReplaced))
+	if err != nil {
+		log.With().Panic("failed to create tx replaced emitter", log.Err(err))
+	}
+	txEvictedEmitter, err := bus.Emitter(new(TxEvicted))
+	if err != nil {
+		log.With().Panic("failed to create tx evicted emitter", log.Err(err))
+	}
+	txStateEmitter, err := bus.Emitter(new(TxState))
+	if err != nil {
+		log.With().Panic("failed to create tx state emitter", log.Err(err))
+	}
+	txAdmittedEmitter, err := bus.Emitter(new(TxAdmitted))
+	if err != nil {
+		log.With().Panic("failed to create tx admitted emitter", log.Err(err))
+	}
+	txExpiredEmitter, err := bus.Emitter(new(TxExpired))
+	if err != nil {
+		log.With().Panic("failed to create tx expired emitter", log.Err(err))
+	}
+	txInProposalEmitter, err := bus.Emitter(new(TxIncludedInProposal))
+	if err != nil {
+		log.With().Panic("failed to create tx included in proposal emitter", log.Err(err))
+	}
+	txInBlockEmitter, err := bus.Emitter(new(TxIncludedInBlock))
+	if err != nil {
+		log.With().Panic("failed to create tx included in block emitter", log.Err(err))
+	}
 	eventsEmitter, err := bus.Emitter(new(UserEvent))
 	if err != nil {
 		log.With().Panic("failed to to create proposal emitter", log.Err(err))
@@ -500,7 +577,19 @@ func newEventReporter() *EventReporter {
 		resultsEmitter:     resultsEmitter,
 		errorEmitter:       errorEmitter,
 		proposalsEmitter:   proposalsEmitter,
+		hareEmitter:        hareEmitter,
 		stopChan:           make(chan struct{}),
+
+		certificatesEmitter: certificatesEmitter,
+		forksEmitter:        forksEmitter,
+		builderEmitter:      builderEmitter,
+		txReplacedEmitter:   txReplacedEmitter,
+		txEvictedEmitter:    txEvictedEmitter,
+		txExpiredEmitter:    txExpiredEmitter,
+		txStateEmitter:      txStateEmitter,
+		txAdmittedEmitter:   txAdmittedEmitter,
+		txInProposalEmitter: txInProposalEmitter,
+		txInBlockEmitter:    txInBlockEmitter,
 	}
 	reporter.events.buf = newRing[UserEvent](100)
 	reporter.events.emitter = eventsEmitter
@@ -539,6 +628,39 @@ func CloseEventReporter() {
 		if err := reporter.proposalsEmitter.Close(); err != nil {
 			log.With().Panic("failed to close propoposalsEmitter", log.Err(err))
 		}
+		if err := reporter.hareEmitter.Close(); err != nil {
+			log.With().Panic("failed to close hareEmitter", log.Err(err))
+		}
+		if err := reporter.certificatesEmitter.Close(); err != nil {
+			log.With().Panic("failed to close certificatesEmitter", log.Err(err))
+		}
+		if err := reporter.forksEmitter.Close(); err != nil {
+			log.With().Panic("failed to close forksEmitter", log.Err(err))
+		}
+		if err := reporter.builderEmitter.Close(); err != nil {
+			log.With().Panic("failed to close builderEmitter", log.Err(err))
+		}
+		if err := reporter.txReplacedEmitter.Close(); err != nil {
+			log.With().Panic("failed to close txReplacedEmitter", log.Err(err))
+		}
+		if err := reporter.txEvictedEmitter.Close(); err != nil {
+			log.With().Panic("failed to close txEvictedEmitter", log.Err(err))
+		}
+		if err := reporter.txStateEmitter.Close(); err != nil {
+			log.With().Panic("failed to close txStateEmitter", log.Err(err))
+		}
+		if err := reporter.txExpiredEmitter.Close(); err != nil {
+			log.With().Panic("failed to close txExpiredEmitter", log.Err(err))
+		}
+		if err := reporter.txAdmittedEmitter.Close(); err != nil {
+			log.With().Panic("failed to close txAdmittedEmitter", log.Err(err))
+		}
+		if err := reporter.txInProposalEmitter.Close(); err != nil {
+			log.With().Panic("failed to close txInProposalEmitter", log.Err(err))
+		}
+		if err := reporter.txInBlockEmitter.Close(); err != nil {
+			log.With().Panic("failed to close txInBlockEmitter", log.Err(err))
+		}
 
 		close(reporter.stopChan)
 		reporter = nil
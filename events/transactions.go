@@ -0,0 +1,157 @@
+package events
+
+import (
+	"github.com/spacemeshos/go-spacemesh/common/types"
+	"github.com/spacemeshos/go-spacemesh/log"
+)
+
+// TxReplaced is reported when the transaction in the mempool is superseded by the transaction
+// with the same principal and nonce, but a higher fee.
+type TxReplaced struct {
+	Principal types.Address
+	Nonce     uint64
+	Replaced  types.TransactionID
+	New       types.TransactionID
+}
+
+// ReportTxReplaced reports that the transaction in the mempool was replaced by fee.
+func ReportTxReplaced(ev TxReplaced) {
+	mu.RLock()
+	defer mu.RUnlock()
+	if reporter != nil {
+		if err := reporter.txReplacedEmitter.Emit(ev); err != nil {
+			log.With().Error("failed to emit tx replaced", ev.Replaced, log.Err(err))
+		}
+	}
+}
+
+// TxAdmitted is reported when the transaction is admitted to the mempool.
+type TxAdmitted struct {
+	ID        types.TransactionID
+	Principal types.Address
+	Nonce     uint64
+	GasPrice  uint64
+}
+
+// ReportTxAdmitted reports that the transaction was admitted to the mempool.
+func ReportTxAdmitted(ev TxAdmitted) {
+	mu.RLock()
+	defer mu.RUnlock()
+	if reporter != nil {
+		if err := reporter.txAdmittedEmitter.Emit(ev); err != nil {
+			log.With().Error("failed to emit tx admitted", ev.ID, log.Err(err))
+		}
+	}
+}
+
+// TxEvicted is reported when the transaction is evicted from the full mempool in favor of
+// the transactions with higher fees.
+type TxEvicted struct {
+	ID        types.TransactionID
+	Principal types.Address
+	Nonce     uint64
+	GasPrice  uint64
+}
+
+// ReportTxEvicted reports that the transaction was evicted from the mempool.
+func ReportTxEvicted(ev TxEvicted) {
+	mu.RLock()
+	defer mu.RUnlock()
+	if reporter != nil {
+		if err := reporter.txEvictedEmitter.Emit(ev); err != nil {
+			log.With().Error("failed to emit tx evicted", ev.ID, log.Err(err))
+		}
+	}
+}
+
+// TxExpired is reported when the transaction is dropped from the mempool because it can't be
+// applied after its expiry layer.
+type TxExpired struct {
+	ID        types.TransactionID
+	Principal types.Address
+	Nonce     uint64
+	Expiry    types.LayerID
+}
+
+// ReportTxExpired reports that the transaction expired and was dropped from the mempool.
+func ReportTxExpired(ev TxExpired) {
+	mu.RLock()
+	defer mu.RUnlock()
+	if reporter != nil {
+		if err := reporter.txExpiredEmitter.Emit(ev); err != nil {
+			log.With().Error("failed to emit tx expired", ev.ID, log.Err(err))
+		}
+	}
+}
+
+// TxIncludedInProposal is reported when the transaction is included in the proposal.
+type TxIncludedInProposal struct {
+	ID       types.TransactionID
+	Layer    types.LayerID
+	Proposal types.ProposalID
+}
+
+// ReportTxIncludedInProposal reports that the transaction was included in the proposal.
+func ReportTxIncludedInProposal(ev TxIncludedInProposal) {
+	mu.RLock()
+	defer mu.RUnlock()
+	if reporter != nil {
+		if err := reporter.txInProposalEmitter.Emit(ev); err != nil {
+			log.With().Error("failed to emit tx included in proposal", ev.ID, log.Err(err))
+		}
+	}
+}
+
+// TxIncludedInBlock is reported when the transaction is included in the block.
+type TxIncludedInBlock struct {
+	ID    types.TransactionID
+	Layer types.LayerID
+	Block types.BlockID
+}
+
+// ReportTxIncludedInBlock reports that the transaction was included in the block.
+func ReportTxIncludedInBlock(ev TxIncludedInBlock) {
+	mu.RLock()
+	defer mu.RUnlock()
+	if reporter != nil {
+		if err := reporter.txInBlockEmitter.Emit(ev); err != nil {
+			log.With().Error("failed to emit tx included in block", ev.ID, log.Err(err))
+		}
+	}
+}
+
+// TxState is reported when the transaction moves to another state of its lifecycle:
+// from the mempool to a proposal, a block and finally applied to the state, or back
+// if the block is not applied or the layer is reverted.
+type TxState struct {
+	ID    types.TransactionID
+	State types.TXState
+	Layer types.LayerID
+	Block types.BlockID
+}
+
+// ReportTxState reports that the transaction moved to another state.
+func ReportTxState(ev TxState) {
+	mu.RLock()
+	defer mu.RUnlock()
+	if reporter != nil {
+		if err := reporter.txStateEmitter.Emit(ev); err != nil {
+			log.With().Error("failed to emit tx state", ev.ID, log.Err(err))
+		}
+	}
+}
+
+// SubscribeTxStates subscribes to the changes of the transactions states.
+func SubscribeTxStates() Subscription {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	if reporter != nil {
+		sub, err := reporter.bus.Subscribe(new(TxState))
+		if err != nil {
+			log.With().Panic("failed to subscribe to tx states", log.Err(err))
+		}
+		return sub
+	}
+	return nil
+}
@@ -16,6 +16,7 @@ import (
 	"github.com/spacemeshos/go-spacemesh/datastore"
 	"github.com/spacemeshos/go-spacemesh/log"
 	"github.com/spacemeshos/go-spacemesh/p2p"
+	"github.com/spacemeshos/go-spacemesh/p2p/peerscore"
 	"github.com/spacemeshos/go-spacemesh/p2p/server"
 	"github.com/spacemeshos/go-spacemesh/system"
 )
@@ -143,9 +144,11 @@ type Fetch struct {
 	logger log.Log
 	bs     *datastore.BlobStore
 	host   host
+	scorer peerscore.Reporter
 
 	servers    map[string]requester
 	validators *dataValidators
+	batches    map[datastore.Hint]BatchValidator
 
 	// unprocessed contains requests that are not processed
 	unprocessed map[types.Hash32]*request
@@ -186,12 +189,16 @@ func NewFetch(cdb *datastore.CachedDB, msh meshProvider, b system.BeaconGetter,
 		server.WithTimeout(f.cfg.RequestTimeout),
 		server.WithLog(f.logger),
 	}
+	if host != nil {
+		f.scorer = host.Scorer()
+		srvOpts = append(srvOpts, server.WithScorer(f.scorer))
+	}
 	if len(f.servers) == 0 {
 		h := newHandler(cdb, f.cfg, bs, msh, b, f.logger)
-		f.servers[atxProtocol] = server.New(host, atxProtocol, h.handleEpochInfoReq, srvOpts...)
+		f.servers[atxProtocol] = server.NewStreaming(host, atxProtocol, h.handleEpochInfoReq, srvOpts...)
 		f.servers[lyrDataProtocol] = server.New(host, lyrDataProtocol, h.handleLayerDataReq, srvOpts...)
 		f.servers[lyrOpnsProtocol] = server.New(host, lyrOpnsProtocol, h.handleLayerOpinionsReq, srvOpts...)
-		f.servers[hashProtocol] = server.New(host, hashProtocol, h.handleHashReq, srvOpts...)
+		f.servers[hashProtocol] = server.NewStreaming(host, hashProtocol, h.handleHashReq, srvOpts...)
 		f.servers[meshHashProtocol] = server.New(host, meshHashProtocol, h.handleMeshHashReq, srvOpts...)
 		f.servers[malProtocol] = server.New(host, malProtocol, h.handleMaliciousIDsReq, srvOpts...)
 	}
@@ -207,6 +214,8 @@ type dataValidators struct {
 	txBlock     SyncValidator
 	txProposal  SyncValidator
 	malfeasance SyncValidator
+	keyRotation SyncValidator
+	hareSet     SyncValidator
 }
 
 // SetValidators sets the handlers to validate various mesh data fetched from peers.
@@ -219,6 +228,8 @@ func (f *Fetch) SetValidators(
 	txBlock SyncValidator,
 	txProposal SyncValidator,
 	mal SyncValidator,
+	keyRotation SyncValidator,
+	hareSet SyncValidator,
 ) {
 	f.validators = &dataValidators{
 		atx:         atx,
@@ -229,9 +240,20 @@ func (f *Fetch) SetValidators(
 		txBlock:     txBlock,
 		txProposal:  txProposal,
 		malfeasance: mal,
+		keyRotation: keyRotation,
+		hareSet:     hareSet,
 	}
 }
 
+// SetBatchValidator sets the handler to validate the data with the hint received in a single batch
+// before it is validated by the handler set with SetValidators. Must be called before Start.
+func (f *Fetch) SetBatchValidator(hint datastore.Hint, v BatchValidator) {
+	if f.batches == nil {
+		f.batches = map[datastore.Hint]BatchValidator{}
+	}
+	f.batches[hint] = v
+}
+
 // Start starts handling fetch requests.
 func (f *Fetch) Start() error {
 	if f.validators == nil {
@@ -295,14 +317,17 @@ func (f *Fetch) loop() {
 }
 
 // receive Data from message server and call response handlers accordingly.
-func (f *Fetch) receiveResponse(data []byte) {
+func (f *Fetch) receiveResponse(peer p2p.Peer, data []byte) {
 	if f.stopped() {
 		return
 	}
 
 	var response ResponseBatch
 	if err := codec.Decode(data, &response); err != nil {
-		f.logger.With().Warning("failed to decode batch response", log.Err(err))
+		f.logger.With().Warning("failed to decode batch response", log.Stringer("peer", peer), log.Err(err))
+		if f.scorer != nil {
+			f.scorer.Report(peer, peerscore.FailedResponse)
+		}
 		return
 	}
 
@@ -322,6 +347,7 @@ func (f *Fetch) receiveResponse(data []byte) {
 	}
 
 	batchMap := batch.toMap()
+	grouped := map[datastore.Hint][]ResponseMessage{}
 	// iterate all hash Responses
 	for _, resp := range response.Responses {
 		f.logger.With().Debug("received response for hash", log.Stringer("hash", resp.Hash))
@@ -334,6 +360,11 @@ func (f *Fetch) receiveResponse(data []byte) {
 				log.Stringer("hash", resp.Hash))
 			continue
 		}
+		delete(batchMap, resp.Hash)
+		if _, ok := f.batches[req.hint]; ok {
+			grouped[req.hint] = append(grouped[req.hint], resp)
+			continue
+		}
 
 		rsp := resp
 		f.eg.Go(func() error {
@@ -341,7 +372,13 @@ func (f *Fetch) receiveResponse(data []byte) {
 			f.hashValidationDone(rsp.Hash, req.validator(req.ctx, batch.peer, rsp.Data))
 			return nil
 		})
-		delete(batchMap, resp.Hash)
+	}
+	for hint, resps := range grouped {
+		hint, resps := hint, resps
+		f.eg.Go(func() error {
+			f.validateBatch(batch.peer, f.batches[hint], resps)
+			return nil
+		})
 	}
 
 	// iterate all requests that didn't return value from peer and notify
@@ -356,6 +393,33 @@ func (f *Fetch) receiveResponse(data []byte) {
 	}
 }
 
+// validateBatch validates responses with the batch validator, and then every response that passed it
+// with the validator of its request.
+func (f *Fetch) validateBatch(peer p2p.Peer, bv BatchValidator, resps []ResponseMessage) {
+	data := make([][]byte, 0, len(resps))
+	for _, resp := range resps {
+		data = append(data, resp.Data)
+	}
+	errs := bv.HandleMessages(f.shutdownCtx, peer, data)
+	for i, resp := range resps {
+		if errs[i] != nil {
+			f.hashValidationDone(resp.Hash, errs[i])
+			continue
+		}
+		f.mu.Lock()
+		req, ok := f.ongoing[resp.Hash]
+		f.mu.Unlock()
+		if !ok {
+			continue
+		}
+		rsp := resp
+		f.eg.Go(func() error {
+			f.hashValidationDone(rsp.Hash, req.validator(req.ctx, peer, rsp.Data))
+			return nil
+		})
+	}
+}
+
 func (f *Fetch) hashValidationDone(hash types.Hash32, err error) {
 	f.mu.Lock()
 	defer f.mu.Unlock()
@@ -505,23 +569,31 @@ func (f *Fetch) sendBatch(p p2p.Peer, batch *batchInfo) error {
 	f.logger.With().Debug("sending batch request",
 		log.Stringer("batch_hash", batch.ID),
 		log.Stringer("peer", batch.peer))
+	buf, release, err := codec.EncodeToPool(&batch.RequestBatch)
+	if err != nil {
+		f.handleHashError(batch.ID, err)
+		return err
+	}
+	// request is written to the stream before one of the callbacks is called,
+	// therefore buffer can be released in both of them.
+	okFunc := func(data []byte) {
+		release()
+		f.receiveResponse(p, data)
+	}
 	// timeout function will be called if no response was received for the hashes sent
 	errorFunc := func(err error) {
+		release()
 		f.logger.With().Warning("failed to send batch",
 			log.Stringer("batch_hash", batch.ID),
 			log.Err(err))
 		f.handleHashError(batch.ID, err)
 	}
 
-	bytes, err := codec.Encode(&batch.RequestBatch)
-	if err != nil {
-		f.handleHashError(batch.ID, err)
-	}
-
 	// try sending batch to provided peer
 	retries := 0
 	for {
 		if f.stopped() {
+			release()
 			return nil
 		}
 
@@ -530,13 +602,14 @@ func (f *Fetch) sendBatch(p p2p.Peer, batch *batchInfo) error {
 			log.Int("num_requests", len(batch.Requests)),
 			log.Stringer("peer", p))
 
-		err = f.servers[hashProtocol].Request(f.shutdownCtx, p, bytes, f.receiveResponse, errorFunc)
+		err = f.servers[hashProtocol].Request(f.shutdownCtx, p, buf.Bytes(), okFunc, errorFunc)
 		if err == nil {
 			break
 		}
 
 		retries++
 		if retries > f.cfg.MaxRetriesForPeer {
+			release()
 			f.handleHashError(batch.ID, fmt.Errorf("batched request failed w retries: %w", err))
 			break
 		}
@@ -1,8 +1,10 @@
 package fetch
 
 import (
+	"bytes"
 	"context"
 	"errors"
+	"sync"
 	"testing"
 	"time"
 
@@ -41,6 +43,8 @@ type testFetch struct {
 	mTxBlocksH   *mocks.MockSyncValidator
 	mTxProposalH *mocks.MockSyncValidator
 	mPoetH       *mocks.MockSyncValidator
+	mKeyRotH     *mocks.MockSyncValidator
+	mHareSetH    *mocks.MockSyncValidator
 }
 
 func createFetch(tb testing.TB) *testFetch {
@@ -61,6 +65,8 @@ func createFetch(tb testing.TB) *testFetch {
 		mTxBlocksH:   mocks.NewMockSyncValidator(ctrl),
 		mTxProposalH: mocks.NewMockSyncValidator(ctrl),
 		mPoetH:       mocks.NewMockSyncValidator(ctrl),
+		mKeyRotH:     mocks.NewMockSyncValidator(ctrl),
+		mHareSetH:    mocks.NewMockSyncValidator(ctrl),
 	}
 	cfg := Config{
 		time.Millisecond * time.Duration(2000), // make sure we never hit the batch timeout
@@ -84,7 +90,7 @@ func createFetch(tb testing.TB) *testFetch {
 			meshHashProtocol: tf.mMHashS,
 		}),
 		withHost(tf.mh))
-	tf.Fetch.SetValidators(tf.mAtxH, tf.mPoetH, tf.mBallotH, tf.mBlocksH, tf.mProposalH, tf.mTxBlocksH, tf.mTxProposalH, tf.mMalH)
+	tf.Fetch.SetValidators(tf.mAtxH, tf.mPoetH, tf.mBallotH, tf.mBlocksH, tf.mProposalH, tf.mTxBlocksH, tf.mTxProposalH, tf.mMalH, tf.mKeyRotH, tf.mHareSetH)
 	return tf
 }
 
@@ -219,6 +225,66 @@ func TestFetch_RequestHashBatchFromPeers(t *testing.T) {
 	}
 }
 
+func TestFetch_BatchValidator(t *testing.T) {
+	f := createFetch(t)
+	f.cfg.MaxRetriesForRequest = 0
+	f.cfg.MaxRetriesForPeer = 0
+	peer := p2p.Peer("buddy")
+	f.mh.EXPECT().GetPeers().Return([]p2p.Peer{peer})
+
+	var (
+		good  = ResponseMessage{Hash: types.RandomHash(), Data: []byte("good")}
+		bad   = ResponseMessage{Hash: types.RandomHash(), Data: []byte("bad")}
+		other = ResponseMessage{Hash: types.RandomHash(), Data: []byte("other")}
+	)
+	f.mHashS.EXPECT().Request(gomock.Any(), peer, gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+		func(_ context.Context, _ p2p.Peer, req []byte, okFunc func([]byte), _ func(error)) error {
+			var rb RequestBatch
+			require.NoError(t, codec.Decode(req, &rb))
+			bts, err := codec.Encode(&ResponseBatch{ID: rb.ID, Responses: []ResponseMessage{good, bad, other}})
+			require.NoError(t, err)
+			okFunc(bts)
+			return nil
+		})
+	errBatch := errors.New("invalid in batch")
+	f.SetBatchValidator(datastore.BallotDB, BatchValidatorFunc(func(_ context.Context, _ p2p.Peer, msgs [][]byte) []error {
+		require.ElementsMatch(t, [][]byte{good.Data, bad.Data}, msgs)
+		errs := make([]error, len(msgs))
+		for i, msg := range msgs {
+			if bytes.Equal(msg, bad.Data) {
+				errs[i] = errBatch
+			}
+		}
+		return errs
+	}))
+
+	var validated sync.Map
+	receiver := func(_ context.Context, _ p2p.Peer, data []byte) error {
+		validated.Store(string(data), struct{}{})
+		return nil
+	}
+	pgood, err := f.getHash(context.TODO(), good.Hash, datastore.BallotDB, receiver)
+	require.NoError(t, err)
+	pbad, err := f.getHash(context.TODO(), bad.Hash, datastore.BallotDB, receiver)
+	require.NoError(t, err)
+	pother, err := f.getHash(context.TODO(), other.Hash, datastore.BlockDB, receiver)
+	require.NoError(t, err)
+
+	f.requestHashBatchFromPeers()
+	for _, p := range []*promise{pgood, pbad, pother} {
+		<-p.completed
+	}
+	require.NoError(t, pgood.err)
+	require.NoError(t, pother.err)
+	require.ErrorIs(t, pbad.err, errBatch)
+	_, ok := validated.Load(string(bad.Data))
+	require.False(t, ok, "rejected by the batch validator")
+	for _, data := range [][]byte{good.Data, other.Data} {
+		_, ok := validated.Load(string(data))
+		require.True(t, ok)
+	}
+}
+
 func TestFetch_GetHash_StartStopSanity(t *testing.T) {
 	f := createFetch(t)
 	f.mh.EXPECT().Close()
@@ -370,7 +436,7 @@ func TestFetch_PeerDroppedWhenMessageResultsInValidationReject(t *testing.T) {
 
 	// We set a validatior just for atxs, this validator does not drop connections
 	vf := ValidatorFunc(func(ctx context.Context, id peer.ID, data []byte) error { return pubsub.ErrValidationReject })
-	fetcher.SetValidators(vf, nil, nil, nil, nil, nil, nil, nil)
+	fetcher.SetValidators(vf, nil, nil, nil, nil, nil, nil, nil, nil, nil)
 
 	// Request an atx by hash
 	_, err = fetcher.getHash(ctx, types.Hash32{}, datastore.ATXDB, fetcher.validators.atx.HandleMessage)
@@ -385,7 +451,7 @@ func TestFetch_PeerDroppedWhenMessageResultsInValidationReject(t *testing.T) {
 	}
 
 	// Now wrap the atx validator with  DropPeerOnValidationReject and set it again
-	fetcher.SetValidators(ValidatorFunc(pubsub.DropPeerOnValidationReject(vf, h, lg)), nil, nil, nil, nil, nil, nil, nil)
+	fetcher.SetValidators(ValidatorFunc(pubsub.DropPeerOnValidationReject(vf, h, lg)), nil, nil, nil, nil, nil, nil, nil, nil, nil)
 
 	// Request an atx by hash
 	_, err = fetcher.getHash(ctx, types.Hash32{}, datastore.ATXDB, fetcher.validators.atx.HandleMessage)
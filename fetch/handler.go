@@ -56,7 +56,8 @@ func (h *handler) handleMaliciousIDsReq(ctx context.Context, _ []byte) ([]byte,
 }
 
 // handleEpochInfoReq returns the ATXs published in the specified epoch.
-func (h *handler) handleEpochInfoReq(ctx context.Context, msg []byte) ([]byte, error) {
+// The response is encoded directly into the stream, as the list of ATXs may be large.
+func (h *handler) handleEpochInfoReq(ctx context.Context, msg []byte) (codec.SizedEncodable, error) {
 	var epoch types.EpochID
 	if err := codec.Decode(msg, &epoch); err != nil {
 		return nil, err
@@ -66,17 +67,13 @@ func (h *handler) handleEpochInfoReq(ctx context.Context, msg []byte) ([]byte, e
 		h.logger.WithContext(ctx).With().Warning("failed to get epoch atx IDs", epoch, log.Err(err))
 		return nil, err
 	}
-	ed := EpochData{
+	ed := &EpochData{
 		AtxIDs: atxids,
 	}
 	h.logger.WithContext(ctx).With().Debug("responded to epoch info request",
 		epoch,
 		log.Int("atx_count", len(ed.AtxIDs)))
-	bts, err := codec.Encode(&ed)
-	if err != nil {
-		h.logger.WithContext(ctx).With().Fatal("failed to serialize epoch atx", epoch, log.Err(err))
-	}
-	return bts, nil
+	return ed, nil
 }
 
 // handleLayerDataReq returns all data in a layer, described in LayerData.
@@ -146,7 +143,9 @@ func (h *handler) handleLayerOpinionsReq(ctx context.Context, req []byte) ([]byt
 	return out, nil
 }
 
-func (h *handler) handleHashReq(ctx context.Context, data []byte) ([]byte, error) {
+// handleHashReq returns the data for the requested hashes.
+// The response is encoded directly into the stream, as reference ballots with active sets may be large.
+func (h *handler) handleHashReq(ctx context.Context, data []byte) (codec.SizedEncodable, error) {
 	var requestBatch RequestBatch
 	if err := codec.Decode(data, &requestBatch); err != nil {
 		h.logger.WithContext(ctx).With().Warning("failed to parse request", log.Err(err))
@@ -187,17 +186,11 @@ func (h *handler) handleHashReq(ctx context.Context, data []byte) ([]byte, error
 		resBatch.Responses = append(resBatch.Responses, m)
 	}
 
-	bts, err := codec.Encode(&resBatch)
-	if err != nil {
-		h.logger.WithContext(ctx).With().Fatal("failed to serialize batch id",
-			log.Err(err),
-			log.String("batch_hash", resBatch.ID.ShortString()))
-	}
 	h.logger.WithContext(ctx).With().Debug("returning response for batch",
 		log.String("batch_hash", resBatch.ID.ShortString()),
 		log.Int("count_responses", len(resBatch.Responses)),
-		log.Int("data_size", len(bts)))
-	return bts, nil
+		log.Int("data_size", resBatch.EncodedSize()))
+	return &resBatch, nil
 }
 
 func (h *handler) handleMeshHashReq(ctx context.Context, reqData []byte) ([]byte, error) {
@@ -78,7 +78,7 @@ func createOpinions(t *testing.T, db *datastore.CachedDB, lid types.LayerID, gen
 		require.NoError(t, certificates.Add(db, lid, &types.Certificate{BlockID: certified}))
 	}
 	aggHash := types.RandomHash()
-	require.NoError(t, layers.SetMeshHash(db, lid.Sub(1), aggHash))
+	require.NoError(t, layers.SetAggregatedHash(db, lid.Sub(1), aggHash))
 	return certified, aggHash
 }
 
@@ -217,7 +217,7 @@ func TestHandleMeshHashReq(t *testing.T) {
 			}
 			if !tc.hashMissing {
 				for lid := req.From; !lid.After(req.To); lid = lid.Add(1) {
-					require.NoError(t, layers.SetMeshHash(th.cdb, lid, types.RandomHash()))
+					require.NoError(t, layers.SetAggregatedHash(th.cdb, lid, types.RandomHash()))
 				}
 			}
 			reqData, err := codec.Encode(req)
@@ -291,8 +291,11 @@ func TestHandleEpochInfoReq(t *testing.T) {
 			epochBytes, err := codec.Encode(epoch)
 			require.NoError(t, err)
 
-			out, err := th.handleEpochInfoReq(context.Background(), epochBytes)
+			resp, err := th.handleEpochInfoReq(context.Background(), epochBytes)
 			require.NoError(t, err)
+			out, err := codec.Encode(resp)
+			require.NoError(t, err)
+			require.Equal(t, len(out), resp.EncodedSize())
 			var got EpochData
 			require.NoError(t, codec.Decode(out, &got))
 			require.ElementsMatch(t, expected.AtxIDs, got.AtxIDs)
@@ -300,6 +303,50 @@ func TestHandleEpochInfoReq(t *testing.T) {
 	}
 }
 
+func TestHandleHashReq(t *testing.T) {
+	th := createTestHandler(t)
+	lid := types.LayerID(11)
+	blts, _ := createLayer(t, th.cdb, lid)
+
+	signer, err := signing.NewEdSigner()
+	require.NoError(t, err)
+	ref := types.RandomBallot()
+	ref.Layer = lid
+	ref.ActiveSet = types.RandomActiveSet(1000)
+	ref.Signature = signer.Sign(signing.BALLOT, ref.SignedBytes())
+	ref.SmesherID = signer.NodeID()
+	require.NoError(t, ref.Initialize())
+	require.NoError(t, ballots.Add(th.cdb, ref))
+	blts = append(blts, ref.ID())
+
+	req := RequestBatch{ID: types.RandomHash()}
+	for _, id := range blts {
+		req.Requests = append(req.Requests, RequestMessage{Hint: datastore.BallotDB, Hash: id.AsHash32()})
+	}
+	// missing hashes are not included in the response
+	req.Requests = append(req.Requests, RequestMessage{Hint: datastore.BallotDB, Hash: types.RandomHash()})
+	data, err := codec.Encode(&req)
+	require.NoError(t, err)
+
+	resp, err := th.handleHashReq(context.Background(), data)
+	require.NoError(t, err)
+	out, err := codec.Encode(resp)
+	require.NoError(t, err)
+	require.Equal(t, len(out), resp.EncodedSize())
+
+	var got ResponseBatch
+	require.NoError(t, codec.Decode(out, &got))
+	require.Equal(t, req.ID, got.ID)
+	require.Len(t, got.Responses, len(blts))
+	for i, id := range blts {
+		require.Equal(t, id.AsHash32(), got.Responses[i].Hash)
+		var ballot types.Ballot
+		require.NoError(t, codec.Decode(got.Responses[i].Data, &ballot))
+		require.NoError(t, ballot.Initialize())
+		require.Equal(t, id, ballot.ID())
+	}
+}
+
 func TestHandleMaliciousIDsReq(t *testing.T) {
 	tt := []struct {
 		name   string
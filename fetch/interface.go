@@ -30,6 +30,19 @@ type SyncValidator interface {
 	HandleMessage(context.Context, p2p.Peer, []byte) error
 }
 
+// BatchValidator checks the messages received together in a batch response before they are
+// passed to the SyncValidator one by one. The messages with an error are not passed further.
+type BatchValidator interface {
+	HandleMessages(context.Context, p2p.Peer, [][]byte) []error
+}
+
+// The BatchValidatorFunc type is an adapter to allow the use of functions as BatchValidators.
+type BatchValidatorFunc func(context.Context, p2p.Peer, [][]byte) []error
+
+func (f BatchValidatorFunc) HandleMessages(ctx context.Context, peer p2p.Peer, msgs [][]byte) []error {
+	return f(ctx, peer, msgs)
+}
+
 type PoetValidator interface {
 	ValidateAndStoreMsg(context.Context, p2p.Peer, []byte) error
 }
@@ -68,6 +68,25 @@ func (f *Fetch) GetMalfeasanceProofs(ctx context.Context, ids []types.NodeID) er
 	return f.getHashes(ctx, hashes, datastore.Malfeasance, f.validators.malfeasance.HandleMessage)
 }
 
+// GetKeyRotations gets key rotations of the specified NodeIDs and validates them.
+func (f *Fetch) GetKeyRotations(ctx context.Context, ids []types.NodeID) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	f.logger.WithContext(ctx).With().Debug("requesting key rotations from peer", log.Int("num_identities", len(ids)))
+	hashes := types.NodeIDsToHashes(ids)
+	return f.getHashes(ctx, hashes, datastore.KeyRotation, f.validators.keyRotation.HandleMessage)
+}
+
+// GetHareSets gets the proposal sets referred to by hare messages and validates them.
+func (f *Fetch) GetHareSets(ctx context.Context, hashes []types.Hash32) error {
+	if len(hashes) == 0 {
+		return nil
+	}
+	f.logger.WithContext(ctx).With().Debug("requesting hare sets from peer", log.Int("num_sets", len(hashes)))
+	return f.getHashes(ctx, hashes, datastore.HareSet, f.validators.hareSet.HandleMessage)
+}
+
 // GetBallots gets data for the specified BallotIDs and validates them.
 func (f *Fetch) GetBallots(ctx context.Context, ids []types.BallotID) error {
 	if len(ids) == 0 {
@@ -208,6 +208,34 @@ func TestFetch_GetMalfeasanceProofs(t *testing.T) {
 	require.NoError(t, eg.Wait())
 }
 
+func TestFetch_GetKeyRotations(t *testing.T) {
+	nodeIDs := []types.NodeID{{1}, {2}, {3}}
+	f := createFetch(t)
+	f.mKeyRotH.EXPECT().HandleMessage(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).Times(len(nodeIDs))
+
+	stop := make(chan struct{}, 1)
+	var eg errgroup.Group
+	startTestLoop(t, f.Fetch, &eg, stop)
+
+	require.NoError(t, f.GetKeyRotations(context.Background(), nodeIDs))
+	close(stop)
+	require.NoError(t, eg.Wait())
+}
+
+func TestFetch_GetHareSets(t *testing.T) {
+	hashes := []types.Hash32{types.RandomHash(), types.RandomHash()}
+	f := createFetch(t)
+	f.mHareSetH.EXPECT().HandleMessage(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).Times(len(hashes))
+
+	stop := make(chan struct{}, 1)
+	var eg errgroup.Group
+	startTestLoop(t, f.Fetch, &eg, stop)
+
+	require.NoError(t, f.GetHareSets(context.Background(), hashes))
+	close(stop)
+	require.NoError(t, eg.Wait())
+}
+
 func TestFetch_GetBlocks(t *testing.T) {
 	blks := []*types.Block{
 		genLayerBlock(types.LayerID(10), types.RandomTXSet(10)),
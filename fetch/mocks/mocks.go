@@ -87,6 +87,43 @@ func (mr *MockSyncValidatorMockRecorder) HandleMessage(arg0, arg1, arg2 interfac
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "HandleMessage", reflect.TypeOf((*MockSyncValidator)(nil).HandleMessage), arg0, arg1, arg2)
 }
 
+// MockBatchValidator is a mock of BatchValidator interface.
+type MockBatchValidator struct {
+	ctrl     *gomock.Controller
+	recorder *MockBatchValidatorMockRecorder
+}
+
+// MockBatchValidatorMockRecorder is the mock recorder for MockBatchValidator.
+type MockBatchValidatorMockRecorder struct {
+	mock *MockBatchValidator
+}
+
+// NewMockBatchValidator creates a new mock instance.
+func NewMockBatchValidator(ctrl *gomock.Controller) *MockBatchValidator {
+	mock := &MockBatchValidator{ctrl: ctrl}
+	mock.recorder = &MockBatchValidatorMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockBatchValidator) EXPECT() *MockBatchValidatorMockRecorder {
+	return m.recorder
+}
+
+// HandleMessages mocks base method.
+func (m *MockBatchValidator) HandleMessages(arg0 context.Context, arg1 p2p.Peer, arg2 [][]byte) []error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "HandleMessages", arg0, arg1, arg2)
+	ret0, _ := ret[0].([]error)
+	return ret0
+}
+
+// HandleMessages indicates an expected call of HandleMessages.
+func (mr *MockBatchValidatorMockRecorder) HandleMessages(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "HandleMessages", reflect.TypeOf((*MockBatchValidator)(nil).HandleMessages), arg0, arg1, arg2)
+}
+
 // MockPoetValidator is a mock of PoetValidator interface.
 type MockPoetValidator struct {
 	ctrl     *gomock.Controller
@@ -3,6 +3,7 @@ package fetch
 import (
 	"fmt"
 
+	"github.com/spacemeshos/go-spacemesh/codec"
 	"github.com/spacemeshos/go-spacemesh/common/types"
 	"github.com/spacemeshos/go-spacemesh/datastore"
 	"github.com/spacemeshos/go-spacemesh/log"
@@ -38,6 +39,16 @@ type ResponseBatch struct {
 	Responses []ResponseMessage `scale:"max=1000"` // depends on fetch config `BatchSize` which defaults to 20, more than 1000 seems unlikely
 }
 
+// EncodedSize returns the size of the scale encoding of the batch.
+func (b *ResponseBatch) EncodedSize() int {
+	size := len(b.ID) + codec.CompactSize(uint32(len(b.Responses)))
+	for i := range b.Responses {
+		size += len(b.Responses[i].Hash) +
+			codec.CompactSize(uint32(len(b.Responses[i].Data))) + len(b.Responses[i].Data)
+	}
+	return size
+}
+
 // MeshHashRequest is used by ForkFinder to request the hashes of layers from
 // a peer to find the layer at which a divergence occurred in the local mesh of
 // the node.
@@ -105,6 +116,11 @@ type EpochData struct {
 	AtxIDs []types.ATXID `scale:"max=100000"` // max. expected number of ATXs per epoch is 100_000
 }
 
+// EncodedSize returns the size of the scale encoding of the epoch data.
+func (d *EpochData) EncodedSize() int {
+	return codec.CompactSize(uint32(len(d.AtxIDs))) + len(d.AtxIDs)*types.ATXIDSize
+}
+
 // LayerData is the data response for a given layer ID.
 type LayerData struct {
 	Ballots []types.BallotID `scale:"max=500"` // expected are 50 proposals per layer + safety margin
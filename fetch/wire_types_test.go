@@ -5,9 +5,43 @@ import (
 
 	"github.com/stretchr/testify/require"
 
+	"github.com/spacemeshos/go-spacemesh/codec"
 	"github.com/spacemeshos/go-spacemesh/common/types"
 )
 
+func TestEncodedSize(t *testing.T) {
+	atxs := make([]types.ATXID, 100)
+	for i := range atxs {
+		atxs[i] = types.RandomATXID()
+	}
+	for _, tc := range []struct {
+		desc string
+		v    codec.SizedEncodable
+	}{
+		{desc: "empty batch", v: &ResponseBatch{}},
+		{
+			desc: "batch",
+			v: &ResponseBatch{
+				ID: types.RandomHash(),
+				Responses: []ResponseMessage{
+					{Hash: types.RandomHash()},
+					{Hash: types.RandomHash(), Data: types.RandomBytes(10)},
+					{Hash: types.RandomHash(), Data: types.RandomBytes(100)},
+					{Hash: types.RandomHash(), Data: types.RandomBytes(20_000)},
+				},
+			},
+		},
+		{desc: "empty epoch", v: &EpochData{}},
+		{desc: "epoch", v: &EpochData{AtxIDs: atxs}},
+	} {
+		tc := tc
+		t.Run(tc.desc, func(t *testing.T) {
+			t.Parallel()
+			require.Equal(t, len(codec.MustEncode(tc.v)), tc.v.EncodedSize())
+		})
+	}
+}
+
 func Fuzz_NewMeshHashRequest(f *testing.F) {
 	// examples from https://github.com/spacemeshos/go-spacemesh/issues/4654
 	f.Add(uint32(6269), uint32(10265))
@@ -263,6 +263,7 @@ func (v *VM) Apply(lctx ApplyContext, txs []types.Transaction, blockRewards []ty
 			Total:       reward.TotalReward,
 			LayerReward: reward.LayerReward,
 			Coinbase:    reward.Coinbase,
+			Reorg:       lctx.Reorg,
 		})
 	}
 
@@ -592,4 +593,6 @@ func verify(ctx *core.Context, raw []byte, dec *scale.Decoder) bool {
 // ApplyContext has information on layer and block id.
 type ApplyContext struct {
 	Layer types.LayerID
+	// Reorg is set if the layer is applied again after the state was reverted.
+	Reorg bool
 }
@@ -42,10 +42,15 @@ type report struct {
 	id        types.LayerID // layer id
 	set       *Set          // agreed-upon set
 	completed bool          // whether the CP completed
+	cert      *Certificate  // commit messages for the agreed-upon set, only if the CP completed
 }
 
 func (proc *consensusProcess) report(completed bool) {
-	proc.comm.report <- report{id: proc.layer, set: proc.value, completed: completed}
+	rst := report{id: proc.layer, set: proc.value, completed: completed}
+	if completed {
+		rst.cert = proc.certificate
+	}
+	proc.comm.report <- rst
 }
 
 type wcReport struct {
@@ -153,9 +158,25 @@ type communication struct {
 	// if the consensus process terminates, output the result to report
 	report chan report
 	wc     chan wcReport
+	// consensus process passes its state to persist at the beginning of every round
+	persist func(*Snapshot)
+	// the sets of the preround and status messages are stored and referred to by their hash.
+	// if nil, the messages carry the sets.
+	sets *setStore
+	// the messages are encoded and signed as before SetHashVersion.
+	legacy bool
 }
 
-// consensusProcess is an entity (a single participant) in the Hare protocol.
+// participant is a local identity that takes part in the protocol. Every identity checks its own
+// eligibility with its oracle and signs its own messages.
+type participant struct {
+	signer           signing.Signer
+	oracle           Rolacle // the roles oracle provider of the identity
+	eligibilityCount uint16
+}
+
+// consensusProcess is an entity in the Hare protocol that participates on behalf of the local identities.
+// Incoming messages are tracked and validated once, while the local identities are independent committee members.
 // Once started, the CP iterates through the rounds until consensus is reached or the instance is canceled.
 // The output is then written to the provided TerminationReport channel.
 // If the consensus process is canceled one should not expect the output to be written to the output channel.
@@ -163,29 +184,29 @@ type consensusProcess struct {
 	log.Log
 	State
 
-	ctx              context.Context
-	cancel           context.CancelFunc
-	eg               errgroup.Group
-	mu               sync.RWMutex
-	layer            types.LayerID
-	oracle           Rolacle // the roles oracle provider
-	signer           *signing.EdSigner
-	nid              types.NodeID
-	publisher        pubsub.Publisher
-	comm             communication
-	validator        messageValidator
-	preRoundTracker  *preRoundTracker
-	statusesTracker  *statusTracker
-	proposalTracker  proposalTrackerProvider
-	commitTracker    commitTrackerProvider
-	notifyTracker    *notifyTracker
-	cfg              config.Config
-	pending          map[types.NodeID]*Message // buffer for early messages that are pending process
-	mTracker         *msgsTracker              // tracks valid messages
-	eTracker         *EligibilityTracker       // tracks eligible identities by rounds
-	eligibilityCount uint16
-	clock            RoundClock
-	once             sync.Once
+	ctx             context.Context
+	cancel          context.CancelFunc
+	eg              errgroup.Group
+	mu              sync.RWMutex
+	layer           types.LayerID
+	participants    []*participant
+	publisher       pubsub.Publisher
+	comm            communication
+	validator       messageValidator
+	preRoundTracker *preRoundTracker
+	statusesTracker *statusTracker
+	proposalTracker proposalTrackerProvider
+	commitTracker   commitTrackerProvider
+	notifyTracker   *notifyTracker
+	cfg             config.Config
+	pending         map[types.NodeID]*Message // buffer for early messages that are pending process
+	mTracker        *msgsTracker              // tracks valid messages
+	eTracker        *EligibilityTracker       // tracks eligible identities by rounds
+	clock           RoundClock
+	once            sync.Once
+	resumed         bool // the process is restored from the snapshot and skips the preround
+	commitThreshold bool // the commits of the current iteration satisfied the threshold
+	sentSet         *Set // the set of the latest preround or status message sent by the node
 }
 
 // newConsensusProcess creates a new consensus process instance.
@@ -194,12 +215,10 @@ func newConsensusProcess(
 	cfg config.Config,
 	layer types.LayerID,
 	s *Set,
-	oracle Rolacle,
+	participants []participant,
 	stateQuerier stateQuerier,
-	signing *signing.EdSigner,
 	edVerifier *signing.EdVerifier,
 	et *EligibilityTracker,
-	nid types.NodeID,
 	p2p pubsub.Publisher,
 	comm communication,
 	ev roleValidator,
@@ -213,9 +232,6 @@ func newConsensusProcess(
 			value:          s.Clone(),
 		},
 		layer:     layer,
-		oracle:    oracle,
-		signer:    signing,
-		nid:       nid,
 		publisher: p2p,
 		cfg:       cfg,
 		comm:      comm,
@@ -225,9 +241,13 @@ func newConsensusProcess(
 		eTracker:  et,
 		clock:     clock,
 	}
+	for _, p := range participants {
+		p := p
+		proc.participants = append(proc.participants, &p)
+	}
 	proc.ctx, proc.cancel = context.WithCancel(ctx)
 	proc.preRoundTracker = newPreRoundTracker(logger.WithContext(proc.ctx).WithFields(proc.layer), comm.mchOut, proc.eTracker, cfg.N/2+1, cfg.N)
-	proc.validator = newSyntaxContextValidator(signing, edVerifier, cfg.N/2+1, proc.statusValidator(), stateQuerier, ev, proc.mTracker, proc.eTracker, logger)
+	proc.validator = newSyntaxContextValidator(participants[0].signer, edVerifier, cfg.N/2+1, proc.statusValidator(), stateQuerier, ev, proc.mTracker, proc.eTracker, logger)
 
 	return proc
 }
@@ -281,23 +301,55 @@ func (proc *consensusProcess) eventLoop() {
 		log.Int("set_size", proc.value.Size()),
 	)
 
-	// check participation and send message
-	proc.eg.Go(func() error {
-		// check participation
-		if proc.shouldParticipate(ctx) {
-			// set pre-round InnerMsg and send
-			builder, err := proc.initDefaultBuilder(proc.value)
-			if err != nil {
-				logger.With().Error("failed to init msg builder", log.Err(err))
-				return nil
+	if !proc.resumed && !proc.runPreRound(ctx) {
+		return
+	}
+
+	// start first iteration, or the round the process was restored in
+	proc.onRoundBegin(ctx)
+	proc.persist()
+	if !proc.skipEndedRounds(ctx) {
+		return
+	}
+	endOfRound := proc.clock.AwaitEndOfRound(proc.getRound())
+
+	for {
+		select {
+		case msg := <-proc.comm.inbox: // msg event
+			if proc.terminating() {
+				return
 			}
-			m := builder.SetType(pre).Sign(proc.signer).Build()
-			proc.sendMessage(ctx, m)
-		} else {
-			logger.With().Debug("should not participate",
-				log.Uint32("current_round", proc.getRound()),
-			)
+			if hmsg, ok := msg.(*Message); ok {
+				proc.handleMessage(ctx, hmsg)
+			} else if emsg, ok := msg.(*types.HareEligibilityGossip); ok {
+				proc.onMalfeasance(emsg)
+			} else {
+				proc.Log.Fatal("unexpected message type")
+			}
+		case <-endOfRound: // next round event
+			if !proc.nextRound(ctx) || !proc.skipEndedRounds(ctx) {
+				return
+			}
+			endOfRound = proc.clock.AwaitEndOfRound(proc.getRound())
+
+		case <-proc.ctx.Done(): // close event
+			logger.With().Debug("terminating: received signal",
+				log.Uint32("current_round", proc.getRound()))
+			return
 		}
+	}
+}
+
+// runs the preround and advances to the first iteration.
+// returns false if the process terminated.
+func (proc *consensusProcess) runPreRound(ctx context.Context) bool {
+	logger := proc.WithContext(ctx).WithFields(proc.layer)
+
+	// check participation and send messages
+	proc.eg.Go(func() error {
+		proc.participate(ctx, proc.value, func(b *messageBuilder) *messageBuilder {
+			return b.SetType(pre)
+		})
 		return nil
 	})
 
@@ -320,7 +372,7 @@ PreRound:
 		case <-proc.ctx.Done():
 			logger.With().Info("terminating: received signal during preround",
 				log.Uint32("current_round", proc.getRound()))
-			return
+			return false
 		}
 	}
 	logger.With().Debug("preround ended, filtering preliminary set",
@@ -334,50 +386,53 @@ PreRound:
 	}
 	proc.reportWeakCoin()
 	proc.advanceToNextRound(ctx) // K was initialized to -1, K should be 0
+	return true
+}
 
-	// start first iteration
+// ends the current round and begins the next one.
+// returns false if the process terminated.
+func (proc *consensusProcess) nextRound(ctx context.Context) bool {
+	proc.onRoundEnd(ctx)
+	if proc.terminating() {
+		return false
+	}
+	proc.advanceToNextRound(ctx)
+
+	// exit if we reached the limit on number of iterations
+	round := proc.getRound()
+	if round >= uint32(proc.cfg.LimitIterations)*RoundsPerIteration {
+		proc.WithContext(ctx).With().Warning("terminating: reached iterations limit",
+			proc.layer,
+			log.Int("limit", proc.cfg.LimitIterations),
+			log.Uint32("current_round", round))
+		proc.report(notCompleted)
+		proc.terminate()
+		return false
+	}
 	proc.onRoundBegin(ctx)
-	endOfRound = proc.clock.AwaitEndOfRound(proc.getRound())
+	proc.persist()
+	return true
+}
 
-	for {
-		select {
-		case msg := <-proc.comm.inbox: // msg event
-			if proc.terminating() {
-				return
-			}
-			if hmsg, ok := msg.(*Message); ok {
-				proc.handleMessage(ctx, hmsg)
-			} else if emsg, ok := msg.(*types.HareEligibilityGossip); ok {
-				proc.onMalfeasance(emsg)
-			} else {
-				proc.Log.Fatal("unexpected message type")
-			}
-		case <-endOfRound: // next round event
-			proc.onRoundEnd(ctx)
-			if proc.terminating() {
-				return
-			}
-			proc.advanceToNextRound(ctx)
-
-			// exit if we reached the limit on number of iterations
-			round := proc.getRound()
-			if round >= uint32(proc.cfg.LimitIterations)*RoundsPerIteration {
-				logger.With().Warning("terminating: reached iterations limit",
-					log.Int("limit", proc.cfg.LimitIterations),
-					log.Uint32("current_round", round))
-				proc.report(notCompleted)
-				proc.terminate()
-				return
-			}
-			proc.onRoundBegin(ctx)
-			endOfRound = proc.clock.AwaitEndOfRound(round)
+// returns true if the current round already ended according to the round clock.
+func (proc *consensusProcess) roundEnded() bool {
+	return !time.Now().Before(proc.clock.RoundEnd(proc.getRound()))
+}
 
-		case <-proc.ctx.Done(): // close event
-			logger.With().Debug("terminating: received signal",
-				log.Uint32("current_round", proc.getRound()))
-			return
+// rounds are aligned to the layer clock. if the process started late or was blocked, it skips
+// the rounds that already ended without waiting for them, so that it runs in phase with the rest
+// of the network. messages are not sent in the skipped rounds as they would be discarded as late.
+// returns false if the process terminated.
+func (proc *consensusProcess) skipEndedRounds(ctx context.Context) bool {
+	for proc.roundEnded() {
+		proc.WithContext(ctx).With().Debug("skipping ended round",
+			proc.layer,
+			log.Uint32("current_round", proc.getRound()))
+		if !proc.nextRound(ctx) {
+			return false
 		}
 	}
+	return true
 }
 
 // handles eligibility proof from hare gossip handler and malfeasance proof gossip handler.
@@ -516,6 +571,14 @@ func (proc *consensusProcess) sendMessage(ctx context.Context, msg *Message) boo
 	)
 	logger := proc.WithContext(ctx)
 
+	if proc.comm.sets != nil && (msg.Type == pre || msg.Type == status) {
+		compact, err := proc.compactValues(msg)
+		if err != nil {
+			logger.With().Error("failed to compact message values", log.Err(err))
+		} else {
+			msg = compact
+		}
+	}
 	if err := proc.publisher.Publish(ctx, pubsub.HareProtocol, msg.Bytes()); err != nil {
 		logger.With().Error("failed to broadcast round message", log.Err(err))
 		return false
@@ -525,6 +588,19 @@ func (proc *consensusProcess) sendMessage(ctx context.Context, msg *Message) boo
 	return true
 }
 
+// compactValues replaces the values of the message with the reference to the stored set.
+// the set is sent as a change of the previously sent set, as the peers are expected to know it.
+func (proc *consensusProcess) compactValues(msg *Message) (*Message, error) {
+	proc.mu.Lock()
+	defer proc.mu.Unlock()
+	compact, err := compactValues(proc.comm.sets, msg, proc.sentSet)
+	if err != nil {
+		return nil, err
+	}
+	proc.sentSet = NewSet(msg.Values)
+	return compact, nil
+}
+
 // logic of the end of a round by the round type.
 func (proc *consensusProcess) onRoundEnd(ctx context.Context) {
 	logger := proc.WithContext(ctx).WithFields(
@@ -571,18 +647,9 @@ func (proc *consensusProcess) beginStatusRound(ctx context.Context) {
 		proc.cfg.N/2+1,
 		proc.cfg.N)
 
-	// check participation
-	if !proc.shouldParticipate(ctx) {
-		return
-	}
-
-	b, err := proc.initDefaultBuilder(proc.value)
-	if err != nil {
-		proc.WithContext(ctx).With().Error("failed to init msg builder", proc.layer, log.Err(err))
-		return
-	}
-	statusMsg := b.SetType(status).Sign(proc.signer).Build()
-	proc.sendMessage(ctx, statusMsg)
+	proc.participate(ctx, proc.value, func(b *messageBuilder) *messageBuilder {
+		return b.SetType(status)
+	})
 }
 
 func (proc *consensusProcess) beginProposalRound(ctx context.Context) {
@@ -594,26 +661,25 @@ func (proc *consensusProcess) beginProposalRound(ctx context.Context) {
 	// done with building proposal, reset statuses tracking
 	defer func() { proc.statusesTracker = nil }()
 
-	if proc.statusesTracker.IsSVPReady() && proc.shouldParticipate(ctx) {
-		builder, err := proc.initDefaultBuilder(proc.statusesTracker.ProposalSet(defaultSetSize))
-		if err != nil {
-			proc.WithContext(ctx).With().Error("failed to init msg builder", proc.layer, log.Err(err))
-			return
-		}
-		svp := proc.statusesTracker.BuildSVP()
-		if svp != nil {
-			proposalMsg := builder.SetType(proposal).SetSVP(svp).Sign(proc.signer).Build()
-			proc.sendMessage(ctx, proposalMsg)
-		} else {
-			proc.WithContext(ctx).With().Error("failed to build SVP", proc.layer)
-		}
+	// statuses are not known if the process was restored in the proposal round
+	if proc.statusesTracker == nil || !proc.statusesTracker.IsSVPReady() {
+		return
+	}
+	svp := proc.statusesTracker.BuildSVP()
+	if svp == nil {
+		proc.WithContext(ctx).With().Error("failed to build SVP", proc.layer)
+		return
 	}
+	proc.participate(ctx, proc.statusesTracker.ProposalSet(defaultSetSize), func(b *messageBuilder) *messageBuilder {
+		return b.SetType(proposal).SetSVP(svp)
+	})
 }
 
 func (proc *consensusProcess) beginCommitRound(ctx context.Context) {
 	proposedSet := proc.proposalTracker.ProposedSet()
 
 	// proposedSet may be nil, in such case the tracker will ignore Messages
+	proc.commitThreshold = false
 	proc.commitTracker = newCommitTracker(
 		proc.Log.WithContext(proc.ctx).WithFields(proc.layer),
 		proc.getRound(),
@@ -627,19 +693,9 @@ func (proc *consensusProcess) beginCommitRound(ctx context.Context) {
 		return
 	}
 
-	// check participation
-	if !proc.shouldParticipate(ctx) {
-		return
-	}
-
-	builder, err := proc.initDefaultBuilder(proposedSet)
-	if err != nil {
-		proc.WithContext(ctx).With().Error("failed to init msg builder", proc.layer, log.Err(err))
-		return
-	}
-	builder = builder.SetType(commit).Sign(proc.signer)
-	commitMsg := builder.Build()
-	proc.sendMessage(ctx, commitMsg)
+	proc.participate(ctx, proposedSet, func(b *messageBuilder) *messageBuilder {
+		return b.SetType(commit)
+	})
 }
 
 func (proc *consensusProcess) beginNotifyRound(ctx context.Context) {
@@ -658,6 +714,11 @@ func (proc *consensusProcess) beginNotifyRound(ctx context.Context) {
 		proc.proposalTracker = nil
 	}()
 
+	if proc.commitTracker == nil {
+		logger.Debug("begin notify round: commits are not known after restore")
+		return
+	}
+
 	if proc.proposalTracker.IsConflicting() {
 		logger.Warning("begin notify round: proposal is conflicting")
 		return
@@ -687,22 +748,36 @@ func (proc *consensusProcess) beginNotifyRound(ctx context.Context) {
 	proc.value = s
 	proc.certificate = cert
 
-	// check participation
-	if !proc.shouldParticipate(ctx) {
-		return
+	// build & send notify messages
+	proc.participate(ctx, proc.value, func(b *messageBuilder) *messageBuilder {
+		return b.SetType(notify).SetCertificate(proc.certificate)
+	})
+}
+
+// participate sends a message on behalf of every local identity that is eligible in the current round.
+// the messages of the identities differ only by the eligibility and the signature.
+func (proc *consensusProcess) participate(ctx context.Context, s *Set, build func(*messageBuilder) *messageBuilder) {
+	for _, p := range proc.shouldParticipate(ctx) {
+		builder, err := proc.initDefaultBuilder(p, s)
+		if err != nil {
+			proc.WithContext(ctx).With().Error("failed to init msg builder",
+				proc.layer,
+				log.Stringer("smesher", p.signer.NodeID()),
+				log.Err(err),
+			)
+			continue
+		}
+		proc.signAndSend(ctx, p, build(builder))
 	}
+}
 
-	// build & send notify message
-	builder, err := proc.initDefaultBuilder(proc.value)
-	if err != nil {
-		logger.With().Error("failed to init msg builder", proc.layer, log.Err(err))
+// signAndSend signs the message built by the builder with the key of the participant and sends it to the network.
+func (proc *consensusProcess) signAndSend(ctx context.Context, p *participant, builder *messageBuilder) {
+	if _, err := builder.Sign(ctx, p.signer); err != nil {
+		proc.WithContext(ctx).With().Error("failed to sign message", proc.layer, log.Err(err))
 		return
 	}
-
-	builder = builder.SetType(notify).SetCertificate(proc.certificate).Sign(proc.signer)
-	notifyMsg := builder.Build()
-	logger.With().Debug("sending notify message", notifyMsg)
-	proc.sendMessage(ctx, notifyMsg)
+	proc.sendMessage(ctx, builder.Build())
 }
 
 // passes all pending messages to the inbox of the process so they will be handled.
@@ -746,16 +821,17 @@ func (proc *consensusProcess) onRoundBegin(ctx context.Context) {
 	})
 }
 
-// init a new message builder with the current state (s, k, ki) for this instance.
-func (proc *consensusProcess) initDefaultBuilder(s *Set) (*messageBuilder, error) {
+// init a new message builder with the current state (s, k, ki) for this instance and the eligibility of the participant.
+func (proc *consensusProcess) initDefaultBuilder(p *participant, s *Set) (*messageBuilder, error) {
 	builder := newMessageBuilder().SetLayer(proc.layer)
 	builder = builder.SetRoundCounter(proc.getRound()).SetCommittedRound(proc.committedRound).SetValues(s)
-	proof, err := proc.oracle.Proof(context.TODO(), proc.layer, proc.getRound())
+	builder = builder.SetLegacy(proc.comm.legacy)
+	proof, err := p.oracle.Proof(context.TODO(), proc.layer, proc.getRound())
 	if err != nil {
 		return nil, fmt.Errorf("init default builder: %w", err)
 	}
 	builder.SetRoleProof(proof)
-	builder.SetEligibilityCount(proc.getEligibilityCount())
+	builder.SetEligibilityCount(proc.getEligibilityCount(p))
 	return builder, nil
 }
 
@@ -785,6 +861,16 @@ func (proc *consensusProcess) processProposalMsg(ctx context.Context, msg *Messa
 func (proc *consensusProcess) processCommitMsg(ctx context.Context, msg *Message) {
 	proc.mTracker.Track(msg) // a commit msg passed for processing is assumed to be valid
 	proc.commitTracker.OnCommit(ctx, msg)
+	if !proc.commitThreshold && proc.commitTracker.HasEnoughCommits() {
+		proc.commitThreshold = true
+		proc.reportThreshold(msg.Round)
+	}
+}
+
+// reportThreshold reports the time since the beginning of the round until its threshold was satisfied.
+func (proc *consensusProcess) reportThreshold(round uint32) {
+	roundThreshold.WithLabelValues(layerLabel(proc.layer), roundLabel(round)).
+		Observe(time.Since(proc.clock.RoundEnd(round - 1)).Seconds())
 }
 
 func (proc *consensusProcess) processNotifyMsg(ctx context.Context, msg *Message) {
@@ -827,7 +913,9 @@ func (proc *consensusProcess) processNotifyMsg(ctx context.Context, msg *Message
 	}
 
 	// enough notifications, should terminate
+	proc.reportThreshold(msg.Round)
 	proc.value = s // update to the agreed set
+	proc.certificate = msg.Cert
 	proc.WithContext(ctx).Event().Info("consensus process terminated",
 		log.String("current_set", proc.value.String()),
 		log.Uint32("current_round", proc.getRound()),
@@ -883,45 +971,54 @@ func (proc *consensusProcess) endOfStatusRound() {
 		log.String("analyze_duration", time.Since(before).String()))
 }
 
-// checks if we should participate in the current round
-// returns true if we should participate, false otherwise.
-func (proc *consensusProcess) shouldParticipate(ctx context.Context) bool {
+// checks which local identities should participate in the current round.
+// returns the participants that are eligible, an empty list if none of them is.
+func (proc *consensusProcess) shouldParticipate(ctx context.Context) []*participant {
 	logger := proc.WithContext(ctx).WithFields(
 		log.Uint32("current_round", proc.getRound()),
 		proc.layer)
 
-	// query if identity is active
-	res, err := proc.oracle.IsIdentityActiveOnConsensusView(ctx, proc.signer.NodeID(), proc.layer)
-	if err != nil {
-		logger.With().Error("failed to check own identity for activeness", log.Err(err))
-		return false
+	if proc.roundEnded() {
+		logger.Debug("should not participate: round ended")
+		return nil
 	}
 
-	if !res {
-		logger.Debug("should not participate: identity is not active")
-		return false
-	}
+	var eligible []*participant
+	for _, p := range proc.participants {
+		logger := logger.WithFields(log.Stringer("smesher", p.signer.NodeID()))
 
-	currentRole := proc.currentRole(ctx)
-	if currentRole == passive {
-		logger.Debug("should not participate: passive")
-		return false
-	}
+		// query if identity is active
+		res, err := p.oracle.IsIdentityActiveOnConsensusView(ctx, p.signer.NodeID(), proc.layer)
+		if err != nil {
+			logger.With().Error("failed to check own identity for activeness", log.Err(err))
+			continue
+		}
 
-	eligibilityCount := proc.getEligibilityCount()
+		if !res {
+			logger.Debug("should not participate: identity is not active")
+			continue
+		}
 
-	// should participate
-	logger.With().Debug("should participate",
-		log.Bool("leader", currentRole == leader),
-		log.Uint32("eligibility_count", uint32(eligibilityCount)),
-	)
-	return true
+		currentRole := proc.currentRole(ctx, p)
+		if currentRole == passive {
+			logger.Debug("should not participate: passive")
+			continue
+		}
+
+		// should participate
+		logger.With().Debug("should participate",
+			log.Bool("leader", currentRole == leader),
+			log.Uint32("eligibility_count", uint32(proc.getEligibilityCount(p))),
+		)
+		eligible = append(eligible, p)
+	}
+	return eligible
 }
 
-// Returns the role matching the current round if eligible for this round, false otherwise.
-func (proc *consensusProcess) currentRole(ctx context.Context) role {
-	logger := proc.WithContext(ctx).WithFields(proc.layer)
-	proof, err := proc.oracle.Proof(ctx, proc.layer, proc.getRound())
+// Returns the role of the participant matching the current round if eligible for this round, passive otherwise.
+func (proc *consensusProcess) currentRole(ctx context.Context, p *participant) role {
+	logger := proc.WithContext(ctx).WithFields(proc.layer, log.Stringer("smesher", p.signer.NodeID()))
+	proof, err := p.oracle.Proof(ctx, proc.layer, proc.getRound())
 	if err != nil {
 		logger.With().Error("failed to get eligibility proof from oracle", log.Err(err))
 		return passive
@@ -930,13 +1027,13 @@ func (proc *consensusProcess) currentRole(ctx context.Context) role {
 	k := proc.getRound()
 
 	size := expectedCommitteeSize(k, proc.cfg.N, proc.cfg.ExpectedLeaders)
-	eligibilityCount, err := proc.oracle.CalcEligibility(ctx, proc.layer, k, size, proc.nid, proof)
+	eligibilityCount, err := p.oracle.CalcEligibility(ctx, proc.layer, k, size, p.signer.NodeID(), proof)
 	if err != nil {
 		logger.With().Error("failed to check eligibility", log.Err(err))
 		return passive
 	}
 
-	proc.setEligibilityCount(eligibilityCount)
+	proc.setEligibilityCount(p, eligibilityCount)
 
 	if eligibilityCount > 0 { // eligible
 		if proc.currentRound() == proposalRound {
@@ -948,16 +1045,16 @@ func (proc *consensusProcess) currentRole(ctx context.Context) role {
 	return passive
 }
 
-func (proc *consensusProcess) getEligibilityCount() uint16 {
+func (proc *consensusProcess) getEligibilityCount(p *participant) uint16 {
 	proc.mu.RLock()
 	defer proc.mu.RUnlock()
-	return proc.eligibilityCount
+	return p.eligibilityCount
 }
 
-func (proc *consensusProcess) setEligibilityCount(count uint16) {
+func (proc *consensusProcess) setEligibilityCount(p *participant, count uint16) {
 	proc.mu.Lock()
 	defer proc.mu.Unlock()
-	proc.eligibilityCount = count
+	p.eligibilityCount = count
 }
 
 func (proc *consensusProcess) getRound() uint32 {
@@ -20,6 +20,7 @@ import (
 	"github.com/spacemeshos/go-spacemesh/log/logtest"
 	pubsubmocks "github.com/spacemeshos/go-spacemesh/p2p/pubsub/mocks"
 	"github.com/spacemeshos/go-spacemesh/signing"
+	"github.com/spacemeshos/go-spacemesh/sql"
 	smocks "github.com/spacemeshos/go-spacemesh/system/mocks"
 )
 
@@ -109,6 +110,10 @@ func (mpt *mockProposalTracker) ProposedSet() *Set {
 	return mpt.proposedSet
 }
 
+func (mpt *mockProposalTracker) Proposal() *Message {
+	return nil
+}
+
 type mockCommitTracker struct {
 	countOnCommit         int
 	countHasEnoughCommits int
@@ -141,6 +146,7 @@ type testBroker struct {
 	mockStateQ    *mocks.MockstateQuerier
 	mockSyncS     *smocks.MockSyncStateProvider
 	mockPublisher *pubsubmocks.MockPublisher
+	mch           chan *types.MalfeasanceGossip
 }
 
 func buildBroker(tb testing.TB, testName string) *testBroker {
@@ -155,13 +161,20 @@ func buildBrokerWithLimit(tb testing.TB, testName string, limit int) *testBroker
 	edVerifier, err := signing.NewEdVerifier()
 	require.NoError(tb, err)
 	mpub := pubsubmocks.NewMockPublisher(ctrl)
+	cfg := config.DefaultConfig()
+	mch := make(chan *types.MalfeasanceGossip, cfg.N)
+	db := sql.InMemory()
+	fetcher := newTestSetFetcher(tb, db)
+	broker := newBroker(cfg, mockMesh, newSetStore(db), func(types.LayerID) byte { return SetHashVersion }, fetcher, edVerifier, &mockEligibilityValidator{valid: 1},
+		mockStateQ, mockSyncS, mpub, mch, limit, logtest.New(tb).WithName(testName))
+	fetcher.handler = broker.handleSyncedSet
 	return &testBroker{
-		Broker: newBroker(config.DefaultConfig(), mockMesh, edVerifier, &mockEligibilityValidator{valid: 1}, mockStateQ, mockSyncS,
-			mpub, limit, logtest.New(tb).WithName(testName)),
+		Broker:        broker,
 		mockMesh:      mockMesh,
 		mockSyncS:     mockSyncS,
 		mockStateQ:    mockStateQ,
 		mockPublisher: mpub,
+		mch:           mch,
 	}
 }
 
@@ -243,8 +256,8 @@ func TestConsensusProcess_eventLoop(t *testing.T) {
 	mo := mocks.NewMockRolacle(gomock.NewController(t))
 	mo.EXPECT().IsIdentityActiveOnConsensusView(gomock.Any(), gomock.Any(), proc.layer).Return(true, nil).Times(1)
 	mo.EXPECT().Proof(gomock.Any(), proc.layer, proc.getRound()).Return(types.EmptyVrfSignature, nil).Times(2)
-	mo.EXPECT().CalcEligibility(gomock.Any(), proc.layer, proc.getRound(), gomock.Any(), proc.nid, gomock.Any()).Return(uint16(1), nil).Times(1)
-	proc.oracle = mo
+	mo.EXPECT().CalcEligibility(gomock.Any(), proc.layer, proc.getRound(), gomock.Any(), proc.participants[0].signer.NodeID(), gomock.Any()).Return(uint16(1), nil).Times(1)
+	proc.participants[0].oracle = mo
 	proc.value = NewSetFromValues(types.ProposalID{1}, types.ProposalID{2})
 
 	var wg sync.WaitGroup
@@ -268,8 +281,8 @@ func TestConsensusProcess_StartAndStop(t *testing.T) {
 	mo := mocks.NewMockRolacle(gomock.NewController(t))
 	mo.EXPECT().IsIdentityActiveOnConsensusView(gomock.Any(), gomock.Any(), proc.layer).Return(true, nil).AnyTimes()
 	mo.EXPECT().Proof(gomock.Any(), gomock.Any(), gomock.Any()).Return(types.EmptyVrfSignature, nil).AnyTimes()
-	mo.EXPECT().CalcEligibility(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), proc.nid, gomock.Any()).Return(uint16(1), nil).AnyTimes()
-	proc.oracle = mo
+	mo.EXPECT().CalcEligibility(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), proc.participants[0].signer.NodeID(), gomock.Any()).Return(uint16(1), nil).AnyTimes()
+	proc.participants[0].oracle = mo
 
 	proc.value = NewSetFromValues(types.ProposalID{1}, types.ProposalID{2})
 	proc.Start()
@@ -291,7 +304,7 @@ func TestConsensusProcess_handleMessage(t *testing.T) {
 	proc := generateConsensusProcess(t)
 	proc.publisher = net
 	mo := mocks.NewMockRolacle(ctrl)
-	proc.oracle = mo
+	proc.participants[0].oracle = mo
 	mValidator := &mockMessageValidator{}
 	proc.validator = mValidator
 	signer, err := signing.NewEdSigner()
@@ -369,12 +382,10 @@ func generateConsensusProcessWithConfig(tb testing.TB, cfg config.Config, inbox
 		cfg,
 		instanceID1,
 		NewSetFromValues(types.ProposalID{1}),
-		oracle,
+		[]participant{{signer: edSigner, oracle: oracle}},
 		sq,
-		edSigner,
 		edVerifier,
 		NewEligibilityTracker(cfg.N),
-		types.BytesToNodeID(edPubkey.Bytes()),
 		noopPubSub(tb),
 		comm,
 		truer{},
@@ -400,7 +411,7 @@ func TestConsensusProcess_InitDefaultBuilder(t *testing.T) {
 	proc := generateConsensusProcess(t)
 	s := NewEmptySet(defaultSetSize)
 	s.Add(types.ProposalID{1})
-	builder, err := proc.initDefaultBuilder(s)
+	builder, err := proc.initDefaultBuilder(proc.participants[0], s)
 	require.Nil(t, err)
 	require.True(t, NewSet(builder.inner.Values).Equals(s))
 	require.Equal(t, builder.msg.Round, proc.getRound())
@@ -413,12 +424,12 @@ func TestConsensusProcess_isEligible_NotEligible(t *testing.T) {
 
 	proc := generateConsensusProcess(t)
 	mo := mocks.NewMockRolacle(ctrl)
-	proc.oracle = mo
+	proc.participants[0].oracle = mo
 
 	mo.EXPECT().Proof(gomock.Any(), proc.layer, proc.getRound()).Return(types.EmptyVrfSignature, nil).Times(1)
 	mo.EXPECT().IsIdentityActiveOnConsensusView(gomock.Any(), gomock.Any(), proc.layer).Return(true, nil).Times(1)
-	mo.EXPECT().CalcEligibility(gomock.Any(), proc.layer, proc.getRound(), gomock.Any(), proc.nid, gomock.Any()).Return(uint16(0), nil).Times(1)
-	require.False(t, proc.shouldParticipate(context.Background()))
+	mo.EXPECT().CalcEligibility(gomock.Any(), proc.layer, proc.getRound(), gomock.Any(), proc.participants[0].signer.NodeID(), gomock.Any()).Return(uint16(0), nil).Times(1)
+	require.Empty(t, proc.shouldParticipate(context.Background()))
 }
 
 func TestConsensusProcess_isEligible_Eligible(t *testing.T) {
@@ -426,12 +437,12 @@ func TestConsensusProcess_isEligible_Eligible(t *testing.T) {
 
 	proc := generateConsensusProcess(t)
 	mo := mocks.NewMockRolacle(ctrl)
-	proc.oracle = mo
+	proc.participants[0].oracle = mo
 
 	mo.EXPECT().Proof(gomock.Any(), proc.layer, proc.getRound()).Return(types.EmptyVrfSignature, nil).Times(1)
 	mo.EXPECT().IsIdentityActiveOnConsensusView(gomock.Any(), gomock.Any(), proc.layer).Return(true, nil).Times(1)
-	mo.EXPECT().CalcEligibility(gomock.Any(), proc.layer, proc.getRound(), gomock.Any(), proc.nid, gomock.Any()).Return(uint16(1), nil).Times(1)
-	require.True(t, proc.shouldParticipate(context.Background()))
+	mo.EXPECT().CalcEligibility(gomock.Any(), proc.layer, proc.getRound(), gomock.Any(), proc.participants[0].signer.NodeID(), gomock.Any()).Return(uint16(1), nil).Times(1)
+	require.Len(t, proc.shouldParticipate(context.Background()), 1)
 }
 
 func TestConsensusProcess_isEligible_ActiveSetFailed(t *testing.T) {
@@ -439,10 +450,10 @@ func TestConsensusProcess_isEligible_ActiveSetFailed(t *testing.T) {
 
 	proc := generateConsensusProcess(t)
 	mo := mocks.NewMockRolacle(ctrl)
-	proc.oracle = mo
+	proc.participants[0].oracle = mo
 
 	mo.EXPECT().IsIdentityActiveOnConsensusView(gomock.Any(), gomock.Any(), proc.layer).Return(false, errors.New("some err")).Times(1)
-	require.False(t, proc.shouldParticipate(context.Background()))
+	require.Empty(t, proc.shouldParticipate(context.Background()))
 }
 
 func TestConsensusProcess_isEligible_NotActive(t *testing.T) {
@@ -450,10 +461,10 @@ func TestConsensusProcess_isEligible_NotActive(t *testing.T) {
 
 	proc := generateConsensusProcess(t)
 	mo := mocks.NewMockRolacle(ctrl)
-	proc.oracle = mo
+	proc.participants[0].oracle = mo
 
 	mo.EXPECT().IsIdentityActiveOnConsensusView(gomock.Any(), gomock.Any(), proc.layer).Return(false, nil).Times(1)
-	require.False(t, proc.shouldParticipate(context.Background()))
+	require.Empty(t, proc.shouldParticipate(context.Background()))
 }
 
 func TestConsensusProcess_sendMessage(t *testing.T) {
@@ -636,8 +647,8 @@ func TestConsensusProcess_beginStatusRound(t *testing.T) {
 	mo := mocks.NewMockRolacle(ctrl)
 	mo.EXPECT().IsIdentityActiveOnConsensusView(gomock.Any(), gomock.Any(), proc.layer).Return(true, nil).Times(1)
 	mo.EXPECT().Proof(gomock.Any(), proc.layer, proc.getRound()).Return(types.EmptyVrfSignature, nil).Times(2)
-	mo.EXPECT().CalcEligibility(gomock.Any(), proc.layer, proc.getRound(), gomock.Any(), proc.nid, gomock.Any()).Return(uint16(1), nil).Times(1)
-	proc.oracle = mo
+	mo.EXPECT().CalcEligibility(gomock.Any(), proc.layer, proc.getRound(), gomock.Any(), proc.participants[0].signer.NodeID(), gomock.Any()).Return(uint16(1), nil).Times(1)
+	proc.participants[0].oracle = mo
 
 	s := NewDefaultEmptySet()
 	signer, err := signing.NewEdSigner()
@@ -663,8 +674,8 @@ func TestConsensusProcess_beginProposalRound(t *testing.T) {
 	mo := mocks.NewMockRolacle(ctrl)
 	mo.EXPECT().IsIdentityActiveOnConsensusView(gomock.Any(), gomock.Any(), proc.layer).Return(true, nil).Times(1)
 	mo.EXPECT().Proof(gomock.Any(), proc.layer, proc.getRound()).Return(types.EmptyVrfSignature, nil).Times(2)
-	mo.EXPECT().CalcEligibility(gomock.Any(), proc.layer, proc.getRound(), gomock.Any(), proc.nid, gomock.Any()).Return(uint16(1), nil).Times(1)
-	proc.oracle = mo
+	mo.EXPECT().CalcEligibility(gomock.Any(), proc.layer, proc.getRound(), gomock.Any(), proc.participants[0].signer.NodeID(), gomock.Any()).Return(uint16(1), nil).Times(1)
+	proc.participants[0].oracle = mo
 
 	statusTracker := newStatusTracker(logtest.New(t), statusRound, make(chan *types.MalfeasanceGossip), proc.eTracker, 1, 1)
 	s := NewSetFromValues(types.ProposalID{1})
@@ -690,7 +701,7 @@ func TestConsensusProcess_beginCommitRound(t *testing.T) {
 	proc.publisher = network
 
 	mo := mocks.NewMockRolacle(ctrl)
-	proc.oracle = mo
+	proc.participants[0].oracle = mo
 
 	mpt := &mockProposalTracker{}
 	proc.proposalTracker = mpt
@@ -699,7 +710,7 @@ func TestConsensusProcess_beginCommitRound(t *testing.T) {
 	preCommitTracker := proc.commitTracker
 	mo.EXPECT().IsIdentityActiveOnConsensusView(gomock.Any(), gomock.Any(), proc.layer).Return(true, nil).Times(1)
 	mo.EXPECT().Proof(gomock.Any(), proc.layer, proc.getRound()).Return(types.EmptyVrfSignature, nil).Times(1)
-	mo.EXPECT().CalcEligibility(gomock.Any(), proc.layer, proc.getRound(), gomock.Any(), proc.nid, gomock.Any()).Return(uint16(0), nil).Times(1)
+	mo.EXPECT().CalcEligibility(gomock.Any(), proc.layer, proc.getRound(), gomock.Any(), proc.participants[0].signer.NodeID(), gomock.Any()).Return(uint16(0), nil).Times(1)
 	proc.beginCommitRound(context.Background())
 	require.NotEqual(t, preCommitTracker, proc.commitTracker)
 
@@ -707,7 +718,7 @@ func TestConsensusProcess_beginCommitRound(t *testing.T) {
 	mpt.proposedSet = NewSetFromValues(types.ProposalID{1})
 	mo.EXPECT().IsIdentityActiveOnConsensusView(gomock.Any(), gomock.Any(), proc.layer).Return(true, nil).Times(1)
 	mo.EXPECT().Proof(gomock.Any(), proc.layer, proc.getRound()).Return(types.EmptyVrfSignature, nil).Times(2)
-	mo.EXPECT().CalcEligibility(gomock.Any(), proc.layer, proc.getRound(), gomock.Any(), proc.nid, gomock.Any()).Return(uint16(1), nil).Times(1)
+	mo.EXPECT().CalcEligibility(gomock.Any(), proc.layer, proc.getRound(), gomock.Any(), proc.participants[0].signer.NodeID(), gomock.Any()).Return(uint16(1), nil).Times(1)
 	proc.beginCommitRound(context.Background())
 	require.Equal(t, 1, network.getCount())
 }
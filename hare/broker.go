@@ -6,6 +6,8 @@ import (
 	"fmt"
 	"sync"
 
+	lru "github.com/hashicorp/golang-lru/v2"
+
 	"github.com/spacemeshos/go-spacemesh/codec"
 	"github.com/spacemeshos/go-spacemesh/common/types"
 	"github.com/spacemeshos/go-spacemesh/hare/config"
@@ -17,13 +19,35 @@ import (
 	"github.com/spacemeshos/go-spacemesh/system"
 )
 
-const inboxCapacity = 1024 // inbox size per instance
+const (
+	inboxCapacity = 1024 // inbox size per instance
+
+	// the number of recently received messages kept to drop exact duplicates before verification.
+	seenCacheSize = 10_000
+
+	// the max number of messages an identity sends in a round according to the protocol.
+	// the next different message is still verified, as it is evidence of equivocation.
+	maxMsgsPerRound = 1
+)
 
 type validator interface {
 	Validate(context.Context, *Message) bool
 	ValidateEligibilityGossip(context.Context, *types.HareEligibilityGossip) bool
 }
 
+// msgKey identifies a message that an identity is allowed to send only once in a layer.
+type msgKey struct {
+	id    types.NodeID
+	round uint32
+	typ   MessageType
+}
+
+// roundKey identifies messages of an identity in a round.
+type roundKey struct {
+	id    types.NodeID
+	round uint32
+}
+
 // Broker is the dispatcher of incoming Hare messages.
 // The broker validates that the sender is eligible and active and forwards the message to the corresponding outbox.
 type Broker struct {
@@ -32,6 +56,9 @@ type Broker struct {
 
 	cfg           config.Config
 	msh           mesh
+	sets          *setStore                // proposal sets referred to by the messages
+	version       func(types.LayerID) byte // the version of the messages in the layer
+	fetcher       setFetcher               // fetches the sets unknown to the node
 	edVerifier    *signing.EdVerifier
 	roleValidator validator                // provides eligibility validation
 	stateQuerier  stateQuerier             // provides activeness check
@@ -39,11 +66,22 @@ type Broker struct {
 	publisher     pubsub.Publisher
 	outbox        map[types.LayerID]chan any
 	trackers      map[types.LayerID]*EligibilityTracker
-	pending       map[types.LayerID][]any // the buffer of pending early messages for the next layer
-	latestLayer   types.LayerID           // the latest layer to attempt register (successfully or unsuccessfully)
+	pending       map[types.LayerID][]*Message // the buffer of pending early messages for the next layer
+	latestLayer   types.LayerID                // the latest layer to attempt register (successfully or unsuccessfully)
 	minDeleted    types.LayerID
 	limit         int // max number of simultaneous consensus processes
 
+	// the first message of every identity in every round, used to detect equivocation
+	firsts map[types.LayerID]map[msgKey]*Message
+	// hashes of distinct signed messages of every identity in every round, used to limit floods
+	signed map[types.LayerID]map[roundKey][]types.Hash32
+	// hashes of recently received valid messages
+	seen *lru.Cache[types.Hash12, struct{}]
+	// identities that equivocated in the layer. messages from them are ignored
+	// until the malfeasance proof is stored and can be found in the mesh.
+	equivocators map[types.NodeID]types.LayerID
+	mchOut       chan<- *types.MalfeasanceGossip
+
 	ctx    context.Context
 	cancel context.CancelFunc
 	once   sync.Once
@@ -52,18 +90,29 @@ type Broker struct {
 func newBroker(
 	cfg config.Config,
 	msh mesh,
+	sets *setStore,
+	version func(types.LayerID) byte,
+	fetcher setFetcher,
 	edVerifier *signing.EdVerifier,
 	roleValidator validator,
 	stateQuerier stateQuerier,
 	syncState system.SyncStateProvider,
 	publisher pubsub.Publisher,
+	mch chan<- *types.MalfeasanceGossip,
 	limit int,
 	log log.Log,
 ) *Broker {
+	seen, err := lru.New[types.Hash12, struct{}](seenCacheSize)
+	if err != nil {
+		log.Fatal("failed to create lru cache for hare messages", err)
+	}
 	b := &Broker{
 		Log:           log,
 		cfg:           cfg,
 		msh:           msh,
+		sets:          sets,
+		version:       version,
+		fetcher:       fetcher,
 		edVerifier:    edVerifier,
 		roleValidator: roleValidator,
 		stateQuerier:  stateQuerier,
@@ -71,10 +120,15 @@ func newBroker(
 		publisher:     publisher,
 		trackers:      map[types.LayerID]*EligibilityTracker{},
 		outbox:        make(map[types.LayerID]chan any),
-		pending:       make(map[types.LayerID][]any),
+		pending:       make(map[types.LayerID][]*Message),
 		latestLayer:   types.GetEffectiveGenesis(),
 		limit:         limit,
 		minDeleted:    types.GetEffectiveGenesis(),
+		firsts:        make(map[types.LayerID]map[msgKey]*Message),
+		signed:        make(map[types.LayerID]map[roundKey][]types.Hash32),
+		seen:          seen,
+		equivocators:  make(map[types.NodeID]types.LayerID),
+		mchOut:        mch,
 	}
 	b.ctx, b.cancel = context.WithCancel(context.Background())
 	return b
@@ -99,6 +153,15 @@ var (
 	errRegistration      = errors.New("failed during registration")
 	errInstanceNotSynced = errors.New("instance not synchronized")
 	errClosed            = errors.New("closed")
+	errEquivocation      = errors.New("equivocation")
+	errDuplicateMsg      = errors.New("duplicate message")
+	errRateLimited       = errors.New("too many messages in round")
+	errMalicious         = errors.New("known malicious")
+
+	// errors that are rejected by pubsub, so that the peer that relayed the message is penalized.
+	errMalformedMsg     = fmt.Errorf("%w: malformed message", pubsub.ErrValidationReject)
+	errInvalidSignature = fmt.Errorf("%w: invalid signature", pubsub.ErrValidationReject)
+	errNotEligible      = fmt.Errorf("%w: not eligible", pubsub.ErrValidationReject)
 )
 
 func (b *Broker) validateTiming(ctx context.Context, m *Message) error {
@@ -129,7 +192,19 @@ func (b *Broker) validateTiming(ctx context.Context, m *Message) error {
 }
 
 // HandleMessage separate listener routine that receives gossip messages and adds them to the priority queue.
-func (b *Broker) HandleMessage(ctx context.Context, _ p2p.Peer, msg []byte) error {
+func (b *Broker) HandleMessage(ctx context.Context, peer p2p.Peer, msg []byte) error {
+	return b.handleMessage(ctx, peer, msg, SetHashVersion)
+}
+
+// HandleLegacyMessage is the handler for the messages encoded before SetHashVersion.
+func (b *Broker) HandleLegacyMessage(ctx context.Context, peer p2p.Peer, msg []byte) error {
+	return b.handleMessage(ctx, peer, msg, LegacyVersion)
+}
+
+func (b *Broker) handleMessage(ctx context.Context, peer p2p.Peer, msg []byte, version byte) (err error) {
+	var hareMsg *Message
+	defer func() { reportMessage(hareMsg, err) }()
+
 	select {
 	case <-ctx.Done():
 		return errClosed
@@ -140,16 +215,23 @@ func (b *Broker) HandleMessage(ctx context.Context, _ p2p.Peer, msg []byte) erro
 
 	h := types.CalcMessageHash12(msg, pubsub.HareProtocol)
 	logger := b.WithContext(ctx).WithFields(log.Stringer("latest_layer", b.getLatestLayer()), h)
-	hareMsg, err := MessageFromBuffer(msg)
+	if version == LegacyVersion {
+		hareMsg, err = LegacyMessageFromBuffer(msg)
+	} else {
+		hareMsg, err = MessageFromBuffer(msg)
+	}
 	if err != nil {
 		logger.With().Error("failed to build message", h, log.Err(err))
-		return err
+		return fmt.Errorf("%w: %s", errMalformedMsg, err)
 	}
 	logger = logger.WithFields(log.Inline(hareMsg))
 
 	if hareMsg.InnerMessage == nil {
 		logger.With().Warning("hare msg missing inner msg", log.Err(errNilInner))
-		return errNilInner
+		return fmt.Errorf("%w: %s", errMalformedMsg, errNilInner)
+	}
+	if expected := b.version(hareMsg.Layer); expected != version {
+		return fmt.Errorf("%w: version %d in layer with version %d", errMalformedMsg, version, expected)
 	}
 
 	logger.Debug("broker received hare message")
@@ -172,13 +254,28 @@ func (b *Broker) HandleMessage(ctx context.Context, _ p2p.Peer, msg []byte) erro
 		isEarly = true
 	}
 
+	// cheap checks before verifying signature and eligibility
+	if b.seen.Contains(h) {
+		droppedDuplicate.Inc()
+		logger.Debug("duplicate message")
+		return errDuplicateMsg
+	}
+	if b.limitReached(hareMsg) {
+		droppedByLimit.Inc()
+		logger.With().Debug("too many messages from identity in round",
+			log.Stringer("smesher", hareMsg.SmesherID))
+		return fmt.Errorf("%w: %s in round %d", errRateLimited, hareMsg.SmesherID, hareMsg.Round)
+	}
+
 	if !b.edVerifier.Verify(signing.HARE, hareMsg.SmesherID, hareMsg.SignedBytes(), hareMsg.Signature) {
 		logger.With().Error("failed to verify signature",
 			log.Int("sig_len", len(hareMsg.Signature)),
 		)
-		return fmt.Errorf("verify ed25519 signature")
+		return errInvalidSignature
 	}
 	hareMsg.signedHash = types.BytesToHash(hareMsg.InnerMessage.HashBytes())
+	// only messages signed by the identity are counted, so that others can't exhaust its limit
+	b.countMsg(hareMsg)
 
 	if err := checkIdentity(ctx, b.Log, hareMsg, b.stateQuerier); err != nil {
 		logger.With().Warning("message validation failed: could not construct msg", log.Err(err))
@@ -188,7 +285,7 @@ func (b *Broker) HandleMessage(ctx context.Context, _ p2p.Peer, msg []byte) erro
 	// validate msg
 	if !b.roleValidator.Validate(ctx, hareMsg) {
 		logger.Warning("message validation failed: eligibility validator returned false")
-		return errors.New("not eligible")
+		return errNotEligible
 	}
 
 	// validation passed, report
@@ -206,11 +303,25 @@ func (b *Broker) HandleMessage(ctx context.Context, _ p2p.Peer, msg []byte) erro
 		// - relay the eligibility proof to the consensus process
 		// - return error so the node don't relay messages from malicious parties
 		b.handleMaliciousHareMessage(ctx, hareMsg.SmesherID, proof, hareMsg)
-		return fmt.Errorf("known malicious %v", hareMsg.SmesherID.String())
+		return fmt.Errorf("%w %v", errMalicious, hareMsg.SmesherID.String())
+	}
+
+	if err := b.detectEquivocation(ctx, hareMsg); err != nil {
+		logger.With().Debug("message from equivocating identity", log.Err(err))
+		return err
+	}
+
+	if err := b.resolveValues(ctx, peer, hareMsg); err != nil {
+		logger.With().Debug("failed to resolve message values", log.Err(err))
+		return err
 	}
 
+	// only valid messages are remembered, invalid ones may become valid later (e.g. after registration)
+	b.seen.Add(h, struct{}{})
+
 	if isEarly {
-		return b.handleEarlyMessage(logger, msgLayer, hareMsg.SmesherID, hareMsg)
+		b.handleEarlyMessage(logger, hareMsg)
+		return nil
 	}
 
 	// has instance, just send
@@ -231,6 +342,49 @@ func (b *Broker) HandleMessage(ctx context.Context, _ p2p.Peer, msg []byte) erro
 	return nil
 }
 
+// reportMessage updates the metrics of the messages handled by the broker.
+func reportMessage(msg *Message, err error) {
+	if errors.Is(err, errClosed) {
+		return
+	}
+	layer, round := unknown, unknown
+	if msg != nil && msg.InnerMessage != nil {
+		layer, round = layerLabel(msg.Layer), roundLabel(msg.Round)
+	}
+	receivedMsgs.WithLabelValues(layer, round).Inc()
+	if err == nil {
+		acceptedMsgs.WithLabelValues(layer, round).Inc()
+		return
+	}
+	var reason string
+	switch {
+	case errors.Is(err, errMalformedMsg):
+		reason = malformed
+	case errors.Is(err, errInvalidSignature):
+		reason = signature
+	case errors.Is(err, errNotEligible):
+		reason = notEligible
+	case errors.Is(err, errDuplicateMsg):
+		reason = duplicate
+	case errors.Is(err, errRateLimited):
+		reason = limit
+	case errors.Is(err, errNotSynced):
+		reason = notSynced
+	case errors.Is(err, errUnregistered), errors.Is(err, errRegistration),
+		errors.Is(err, errFutureMsg), errors.Is(err, errTooOld):
+		reason = timing
+	case errors.Is(err, errMalicious):
+		reason = malicious
+	case errors.Is(err, errEquivocation):
+		reason = equivocation
+	case errors.Is(err, errUnknownSet):
+		reason = unknownSet
+	default:
+		reason = other
+	}
+	rejectedMsgs.WithLabelValues(layer, round, reason).Inc()
+}
+
 func (b *Broker) handleMaliciousHareMessage(
 	ctx context.Context,
 	nodeID types.NodeID,
@@ -277,6 +431,95 @@ func (b *Broker) handleMaliciousHareMessage(
 	}
 }
 
+// limitReached returns true if the identity already sent more messages in the round than the protocol allows.
+func (b *Broker) limitReached(msg *Message) bool {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return len(b.signed[msg.Layer][roundKey{id: msg.SmesherID, round: msg.Round}]) > maxMsgsPerRound
+}
+
+// countMsg counts the message towards the limit of the identity in the round.
+// retransmissions of the same message are counted once.
+func (b *Broker) countMsg(msg *Message) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	signed, ok := b.signed[msg.Layer]
+	if !ok {
+		signed = make(map[roundKey][]types.Hash32)
+		b.signed[msg.Layer] = signed
+	}
+	key := roundKey{id: msg.SmesherID, round: msg.Round}
+	for _, hash := range signed[key] {
+		if hash == msg.signedHash {
+			return
+		}
+	}
+	signed[key] = append(signed[key], msg.signedHash)
+}
+
+// detectEquivocation keeps the first message of the identity for every round and message type.
+// If the identity sends a different message, the equivocation is reported and all subsequent messages
+// from the identity are ignored. Eligibility of the identity is counted as dishonest.
+func (b *Broker) detectEquivocation(ctx context.Context, msg *Message) error {
+	key := msgKey{id: msg.SmesherID, round: msg.Round, typ: msg.Type}
+	b.mu.Lock()
+	tracker, ok := b.trackers[msg.Layer]
+	if !ok {
+		tracker = NewEligibilityTracker(b.cfg.N)
+		b.trackers[msg.Layer] = tracker
+	}
+	if _, known := b.equivocators[msg.SmesherID]; known {
+		b.mu.Unlock()
+		tracker.Track(msg.SmesherID, msg.Round, msg.Eligibility.Count, false)
+		return fmt.Errorf("%w: known equivocator %s", errEquivocation, msg.SmesherID)
+	}
+	firsts, ok := b.firsts[msg.Layer]
+	if !ok {
+		firsts = make(map[msgKey]*Message)
+		b.firsts[msg.Layer] = firsts
+	}
+	prev, exist := firsts[key]
+	if !exist {
+		firsts[key] = msg
+	}
+	if !exist || prev.signedHash == msg.signedHash {
+		b.mu.Unlock()
+		return nil
+	}
+	b.equivocators[msg.SmesherID] = msg.Layer
+	b.mu.Unlock()
+
+	b.WithContext(ctx).With().Warning("equivocation detected",
+		log.Stringer("smesher", msg.SmesherID),
+		msg.Layer,
+		log.Uint32("round", msg.Round),
+		log.String("msg_type", msg.Type.String()),
+	)
+	tracker.Track(msg.SmesherID, msg.Round, msg.Eligibility.Count, false)
+	old := &types.HareProofMsg{
+		InnerMsg: types.HareMetadata{
+			Layer:   prev.Layer,
+			Round:   prev.Round,
+			MsgHash: prev.signedHash,
+		},
+		SmesherID: prev.SmesherID,
+		Signature: prev.Signature,
+	}
+	this := &types.HareProofMsg{
+		InnerMsg: types.HareMetadata{
+			Layer:   msg.Layer,
+			Round:   msg.Round,
+			MsgHash: msg.signedHash,
+		},
+		SmesherID: msg.SmesherID,
+		Signature: msg.Signature,
+	}
+	if err := reportEquivocation(ctx, msg.SmesherID, old, this, &msg.Eligibility, b.mchOut); err != nil {
+		return fmt.Errorf("%w: report %s: %s", errEquivocation, msg.SmesherID, err)
+	}
+	return fmt.Errorf("%w: %s in round %d", errEquivocation, msg.SmesherID, msg.Round)
+}
+
 func (b *Broker) HandleEligibility(ctx context.Context, em *types.HareEligibilityGossip) bool {
 	if em == nil {
 		b.Log.WithContext(ctx).Fatal("invalid hare eligibility")
@@ -335,23 +578,42 @@ func (b *Broker) getInbox(id types.LayerID) chan any {
 	return out
 }
 
-func (b *Broker) handleEarlyMessage(logger log.Log, layer types.LayerID, nodeID types.NodeID, msg any) error {
+// handleEarlyMessage buffers a validated message for the instance that is not registered yet.
+// when the buffer is full the messages of the earlier rounds are preferred, as the instance
+// needs them first: the last buffered message of the latest round is evicted to make space.
+func (b *Broker) handleEarlyMessage(logger log.Log, msg *Message) {
 	b.mu.Lock()
 	defer b.mu.Unlock()
+	layer := msg.Layer
 	if _, exist := b.pending[layer]; !exist { // create buffer if first msg
-		b.pending[layer] = make([]any, 0, inboxCapacity)
+		b.pending[layer] = make([]*Message, 0, inboxCapacity)
 	}
 
 	// we want to write all buffered messages to a chan with InboxCapacity len
 	// hence, we limit the buffer for pending messages
-	if len(b.pending[layer]) == inboxCapacity {
-		logger.With().Warning("too many pending messages, ignoring message",
+	buf := b.pending[layer]
+	if len(buf) == inboxCapacity {
+		// preround is math.MaxUint32, adding 1 orders it before the rounds of the first iteration
+		evict := len(buf) - 1
+		for i := len(buf) - 1; i >= 0; i-- {
+			if buf[i].Round+1 > buf[evict].Round+1 {
+				evict = i
+			}
+		}
+		droppedEarly.Inc()
+		if buf[evict].Round+1 <= msg.Round+1 {
+			logger.With().Debug("too many pending messages, ignoring message",
+				log.Int("inbox_capacity", inboxCapacity),
+				log.Stringer("smesher", msg.SmesherID))
+			return
+		}
+		logger.With().Debug("too many pending messages, evicting message of a later round",
 			log.Int("inbox_capacity", inboxCapacity),
-			log.Stringer("smesher", nodeID))
-		return nil
+			log.Uint32("evicted_round", buf[evict].Round),
+			log.Stringer("evicted_smesher", buf[evict].SmesherID))
+		buf = append(buf[:evict], buf[evict+1:]...)
 	}
-	b.pending[layer] = append(b.pending[layer], msg)
-	return nil
+	b.pending[layer] = append(buf, msg)
 }
 
 func (b *Broker) CleanOldLayers(current types.LayerID) {
@@ -371,6 +633,25 @@ func (b *Broker) CleanOldLayers(current types.LayerID) {
 			delete(b.pending, lid)
 		}
 	}
+	for lid := range b.firsts {
+		if lid <= b.minDeleted {
+			delete(b.firsts, lid)
+		}
+	}
+	for lid := range b.signed {
+		if lid <= b.minDeleted {
+			delete(b.signed, lid)
+		}
+	}
+	// by now the malfeasance proofs are stored, and messages are ignored as from known malicious identities
+	for id, lid := range b.equivocators {
+		if lid <= b.minDeleted {
+			delete(b.equivocators, id)
+		}
+	}
+	if err := b.sets.prune(b.minDeleted + 1); err != nil {
+		b.With().Error("failed to prune hare sets", b.minDeleted, log.Err(err))
+	}
 }
 
 // Register a layer to receive messages
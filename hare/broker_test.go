@@ -47,7 +47,7 @@ func createMessage(tb testing.TB, instanceID types.LayerID) []byte {
 	sr, err := signing.NewEdSigner()
 	require.NoError(tb, err)
 	b := newMessageBuilder()
-	msg := b.SetLayer(instanceID).Sign(sr).Build()
+	msg := signMessage(b.SetLayer(instanceID).SetValues(NewDefaultEmptySet()), sr).Build()
 	return mustEncode(tb, msg)
 }
 
@@ -296,6 +296,8 @@ func TestBroker_HandleMaliciousHareMessage(t *testing.T) {
 			},
 		},
 	}
+	// the identity became known malicious after it sent the first message
+	data = mustEncode(t, BuildPreRoundMsg(signer, NewSetFromValues(types.RandomProposalID()), types.EmptyVrfSignature))
 	broker.mockMesh.EXPECT().GetMalfeasanceProof(signer.NodeID()).Return(&proof, nil)
 	gossip := &types.MalfeasanceGossip{
 		MalfeasanceProof: proof,
@@ -317,8 +319,7 @@ func TestBroker_HandleMaliciousHareMessage(t *testing.T) {
 	require.True(t, et.Dishonest(signer.NodeID(), gossip.Eligibility.Round))
 
 	// receive the same gossip should not cause the eligibility to get gossiped again
-	broker.mockMesh.EXPECT().GetMalfeasanceProof(signer.NodeID()).Return(&proof, nil)
-	require.ErrorContains(t, broker.HandleMessage(ctx, "", data), "known malicious")
+	require.ErrorIs(t, broker.HandleMessage(ctx, "", data), errRateLimited)
 	require.True(t, et.Dishonest(signer.NodeID(), gossip.Eligibility.Round))
 }
 
@@ -426,7 +427,7 @@ func TestBroker_Register(t *testing.T) {
 	msg := BuildPreRoundMsg(signer, NewSetFromValues(types.RandomProposalID()), types.EmptyVrfSignature)
 
 	broker.mu.Lock()
-	broker.pending[instanceID1] = []any{msg, msg}
+	broker.pending[instanceID1] = []*Message{msg, msg}
 	broker.mu.Unlock()
 
 	broker.Register(context.Background(), instanceID1)
@@ -489,6 +490,119 @@ func TestBroker_Register3(t *testing.T) {
 	}
 }
 
+func TestBroker_EarlyMessage(t *testing.T) {
+	broker := buildBroker(t, t.Name())
+	broker.mockSyncS.EXPECT().IsSynced(gomock.Any()).Return(true).AnyTimes()
+	broker.mockSyncS.EXPECT().IsBeaconSynced(gomock.Any()).Return(true).AnyTimes()
+	broker.mockStateQ.EXPECT().IsIdentityActiveOnConsensusView(gomock.Any(), gomock.Any(), gomock.Any()).Return(true, nil).AnyTimes()
+	broker.mockMesh.EXPECT().GetMalfeasanceProof(gomock.Any()).AnyTimes()
+	broker.Start(context.Background())
+	t.Cleanup(broker.Close)
+	_, _, err := broker.Register(context.Background(), instanceID1)
+	require.NoError(t, err)
+
+	build := func(lid types.LayerID) *Message {
+		signer, err := signing.NewEdSigner()
+		require.NoError(t, err)
+		return signMessage(newMessageBuilder().SetLayer(lid).SetValues(NewSetFromValues(types.RandomProposalID())), signer).Build()
+	}
+	pending := func() int {
+		broker.mu.RLock()
+		defer broker.mu.RUnlock()
+		return len(broker.pending[instanceID2])
+	}
+
+	valid := build(instanceID2)
+	require.NoError(t, broker.HandleMessage(context.Background(), "", mustEncode(t, valid)))
+	require.Equal(t, 1, pending())
+
+	t.Run("malformed", func(t *testing.T) {
+		err := broker.HandleMessage(context.Background(), "", []byte{1, 2, 3})
+		require.ErrorIs(t, err, pubsub.ErrValidationReject)
+		require.Equal(t, 1, pending())
+	})
+	t.Run("invalid signature", func(t *testing.T) {
+		msg := build(instanceID2)
+		msg.Signature[0] ^= 1
+		err := broker.HandleMessage(context.Background(), "", mustEncode(t, msg))
+		require.ErrorIs(t, err, pubsub.ErrValidationReject)
+		require.Equal(t, 1, pending())
+	})
+	t.Run("not eligible", func(t *testing.T) {
+		ev := broker.roleValidator.(*mockEligibilityValidator)
+		atomic.StoreInt32(&ev.valid, 0)
+		t.Cleanup(func() { atomic.StoreInt32(&ev.valid, 1) })
+		err := broker.HandleMessage(context.Background(), "", mustEncode(t, build(instanceID2)))
+		require.ErrorIs(t, err, pubsub.ErrValidationReject)
+		require.Equal(t, 1, pending())
+	})
+	t.Run("outside of window", func(t *testing.T) {
+		err := broker.HandleMessage(context.Background(), "", mustEncode(t, build(instanceID3)))
+		require.ErrorIs(t, err, errFutureMsg)
+		require.NotErrorIs(t, err, pubsub.ErrValidationReject)
+	})
+
+	inbox, _, err := broker.Register(context.Background(), instanceID2)
+	require.NoError(t, err)
+	require.Len(t, inbox, 1)
+	msg := <-inbox
+	require.Equal(t, valid.SmesherID, msg.(*Message).SmesherID)
+	require.Zero(t, pending())
+}
+
+func TestBroker_EarlyMessageEviction(t *testing.T) {
+	broker := buildBroker(t, t.Name())
+	broker.mockSyncS.EXPECT().IsSynced(gomock.Any()).Return(true).AnyTimes()
+	broker.mockSyncS.EXPECT().IsBeaconSynced(gomock.Any()).Return(true).AnyTimes()
+	broker.mockStateQ.EXPECT().IsIdentityActiveOnConsensusView(gomock.Any(), gomock.Any(), gomock.Any()).Return(true, nil).AnyTimes()
+	broker.mockMesh.EXPECT().GetMalfeasanceProof(gomock.Any()).AnyTimes()
+	broker.Start(context.Background())
+	t.Cleanup(broker.Close)
+	_, _, err := broker.Register(context.Background(), instanceID1)
+	require.NoError(t, err)
+
+	build := func(round uint32) *Message {
+		signer, err := signing.NewEdSigner()
+		require.NoError(t, err)
+		return signMessage(newMessageBuilder().SetLayer(instanceID2).SetRoundCounter(round).SetValues(NewDefaultEmptySet()), signer).Build()
+	}
+	// the buffer is filled with the messages of the preround and the commit round
+	buf := make([]*Message, 0, inboxCapacity)
+	for i := 0; i < inboxCapacity; i++ {
+		round := preRound
+		if i%2 == 1 {
+			round = commitRound
+		}
+		buf = append(buf, build(round))
+	}
+	broker.mu.Lock()
+	broker.pending[instanceID2] = append([]*Message{}, buf...)
+	broker.mu.Unlock()
+
+	// messages of the same or the later round than the latest buffered are dropped
+	for _, round := range []uint32{commitRound, notifyRound} {
+		require.NoError(t, broker.HandleMessage(context.Background(), "", mustEncode(t, build(round))))
+		broker.mu.RLock()
+		require.Equal(t, buf, broker.pending[instanceID2])
+		broker.mu.RUnlock()
+	}
+
+	// messages of the earlier rounds evict the last message of the latest round
+	proposal := build(proposalRound)
+	require.NoError(t, broker.HandleMessage(context.Background(), "", mustEncode(t, proposal)))
+	status := build(statusRound)
+	require.NoError(t, broker.HandleMessage(context.Background(), "", mustEncode(t, status)))
+
+	expected := append([]*Message{}, buf[:inboxCapacity-3]...)
+	expected = append(expected, buf[inboxCapacity-2], proposal, status)
+	broker.mu.RLock()
+	require.Len(t, broker.pending[instanceID2], inboxCapacity)
+	for i, msg := range broker.pending[instanceID2] {
+		require.Equal(t, expected[i].SmesherID, msg.SmesherID, i)
+	}
+	broker.mu.RUnlock()
+}
+
 func TestBroker_PubkeyExtraction(t *testing.T) {
 	broker := buildBroker(t, t.Name())
 	broker.mockSyncS.EXPECT().IsSynced(gomock.Any()).Return(true).AnyTimes()
@@ -747,7 +861,7 @@ func TestBroker_Flow(t *testing.T) {
 		SetCommittedRound(preRound).
 		SetValues(NewDefaultEmptySet()).
 		SetEligibilityCount(1)
-	m1 := builder.Sign(signer1).Build()
+	m1 := signMessage(builder, signer1).Build()
 	b.HandleMessage(context.Background(), "", mustEncode(t, m1))
 
 	ch1, _, e := b.Register(context.Background(), instanceID1)
@@ -764,11 +878,11 @@ func TestBroker_Flow(t *testing.T) {
 		SetCommittedRound(preRound).
 		SetValues(NewDefaultEmptySet()).
 		SetEligibilityCount(1)
-	m2 := builder.Sign(signer2).Build()
+	m2 := signMessage(builder, signer2).Build()
 	ch2, _, e := b.Register(context.Background(), instanceID2)
 	r.NoError(e)
 
-	b.HandleMessage(context.Background(), "", mustEncode(t, m1))
+	b.HandleMessage(context.Background(), "", createMessage(t, instanceID1))
 	b.HandleMessage(context.Background(), "", mustEncode(t, m2))
 
 	<-ch2
@@ -781,10 +895,270 @@ func TestBroker_Flow(t *testing.T) {
 	r.Equal(instanceID0, minDeleted(b))
 
 	// check still receiving msgs on ch1
-	b.HandleMessage(context.Background(), "", mustEncode(t, m1))
+	b.HandleMessage(context.Background(), "", createMessage(t, instanceID1))
 	<-ch1
 
 	b.Unregister(context.Background(), instanceID1)
 	b.CleanOldLayers(instanceID4)
 	r.Equal(instanceID2, minDeleted(b))
 }
+
+func buildRoundMsg(tb testing.TB, sig *signing.EdSigner, typ MessageType, round uint32, s *Set) *Message {
+	tb.Helper()
+	builder := newMessageBuilder().
+		SetType(typ).
+		SetLayer(instanceID1).
+		SetRoundCounter(round).
+		SetCommittedRound(preRound).
+		SetValues(s).
+		SetEligibilityCount(1)
+	return signMessage(builder, sig).Build()
+}
+
+func TestBroker_Equivocation(t *testing.T) {
+	for _, tc := range []struct {
+		desc  string
+		typ   MessageType
+		round uint32
+	}{
+		{desc: "status", typ: status, round: statusRound},
+		{desc: "proposal", typ: proposal, round: proposalRound},
+		{desc: "commit", typ: commit, round: commitRound},
+		{desc: "notify", typ: notify, round: notifyRound},
+		{desc: "status next iteration", typ: status, round: statusRound + RoundsPerIteration},
+	} {
+		tc := tc
+		t.Run(tc.desc, func(t *testing.T) {
+			ctx := context.Background()
+			broker := buildBroker(t, t.Name())
+			broker.mockSyncS.EXPECT().IsSynced(gomock.Any()).Return(true).AnyTimes()
+			broker.mockSyncS.EXPECT().IsBeaconSynced(gomock.Any()).Return(true).AnyTimes()
+			broker.mockStateQ.EXPECT().IsIdentityActiveOnConsensusView(gomock.Any(), gomock.Any(), gomock.Any()).Return(true, nil).AnyTimes()
+			broker.mockMesh.EXPECT().GetMalfeasanceProof(gomock.Any()).AnyTimes()
+			broker.Start(ctx)
+			t.Cleanup(broker.Close)
+			inbox, et, err := broker.Register(ctx, instanceID1)
+			require.NoError(t, err)
+
+			signer, err := signing.NewEdSigner()
+			require.NoError(t, err)
+			first := buildRoundMsg(t, signer, tc.typ, tc.round, NewSetFromValues(types.ProposalID{1}))
+			require.NoError(t, broker.HandleMessage(ctx, "", mustEncode(t, first)))
+			// the same message is not an equivocation
+			require.ErrorIs(t, broker.HandleMessage(ctx, "", mustEncode(t, first)), errDuplicateMsg)
+			require.Len(t, inbox, 1)
+			require.False(t, et.Dishonest(signer.NodeID(), tc.round))
+
+			second := buildRoundMsg(t, signer, tc.typ, tc.round, NewSetFromValues(types.ProposalID{2}))
+			require.ErrorIs(t, broker.HandleMessage(ctx, "", mustEncode(t, second)), errEquivocation)
+			require.Len(t, inbox, 1)
+			require.True(t, et.Dishonest(signer.NodeID(), tc.round))
+			require.Len(t, broker.mch, 1)
+			gossip := <-broker.mch
+			verifyMalfeasanceProof(t, signer, gossip)
+			require.Equal(t, signer.NodeID(), gossip.Eligibility.NodeID)
+			require.Equal(t, tc.round, gossip.Eligibility.Round)
+
+			// subsequent messages from the equivocator are ignored in the active
+			// and in the future instances, until the proof is stored in the mesh
+			next := buildRoundMsg(t, signer, tc.typ, tc.round+1, NewSetFromValues(types.ProposalID{1}))
+			require.ErrorIs(t, broker.HandleMessage(ctx, "", mustEncode(t, next)), errEquivocation)
+			require.True(t, et.Dishonest(signer.NodeID(), tc.round+1))
+			early := buildRoundMsg(t, signer, tc.typ, tc.round, NewSetFromValues(types.ProposalID{1}))
+			early.Layer = instanceID2
+			early.Signature = signer.Sign(signing.HARE, early.SignedBytes())
+			require.ErrorIs(t, broker.HandleMessage(ctx, "", mustEncode(t, early)), errEquivocation)
+			require.Len(t, inbox, 1)
+			require.Empty(t, broker.mch)
+		})
+	}
+	t.Run("across iterations", func(t *testing.T) {
+		ctx := context.Background()
+		broker := buildBroker(t, t.Name())
+		broker.mockSyncS.EXPECT().IsSynced(gomock.Any()).Return(true).AnyTimes()
+		broker.mockSyncS.EXPECT().IsBeaconSynced(gomock.Any()).Return(true).AnyTimes()
+		broker.mockStateQ.EXPECT().IsIdentityActiveOnConsensusView(gomock.Any(), gomock.Any(), gomock.Any()).Return(true, nil).AnyTimes()
+		broker.mockMesh.EXPECT().GetMalfeasanceProof(gomock.Any()).AnyTimes()
+		broker.Start(ctx)
+		t.Cleanup(broker.Close)
+		inbox, et, err := broker.Register(ctx, instanceID1)
+		require.NoError(t, err)
+
+		signer, err := signing.NewEdSigner()
+		require.NoError(t, err)
+		for i := uint32(0); i < 3; i++ {
+			msg := buildRoundMsg(t, signer, status, statusRound+i*RoundsPerIteration, NewSetFromValues(types.ProposalID{byte(i)}))
+			require.NoError(t, broker.HandleMessage(ctx, "", mustEncode(t, msg)))
+			require.False(t, et.Dishonest(signer.NodeID(), msg.Round))
+		}
+		require.Len(t, inbox, 3)
+		require.Empty(t, broker.mch)
+	})
+}
+
+func TestBroker_CleanOldLayersEquivocation(t *testing.T) {
+	ctx := context.Background()
+	broker := buildBroker(t, t.Name())
+	broker.mockSyncS.EXPECT().IsSynced(gomock.Any()).Return(true).AnyTimes()
+	broker.mockSyncS.EXPECT().IsBeaconSynced(gomock.Any()).Return(true).AnyTimes()
+	broker.mockStateQ.EXPECT().IsIdentityActiveOnConsensusView(gomock.Any(), gomock.Any(), gomock.Any()).Return(true, nil).AnyTimes()
+	broker.mockMesh.EXPECT().GetMalfeasanceProof(gomock.Any()).AnyTimes()
+	broker.Start(ctx)
+	t.Cleanup(broker.Close)
+	_, _, err := broker.Register(ctx, instanceID1)
+	require.NoError(t, err)
+
+	signer, err := signing.NewEdSigner()
+	require.NoError(t, err)
+	require.NoError(t, broker.HandleMessage(ctx, "", mustEncode(t, buildRoundMsg(t, signer, status, statusRound, NewSetFromValues(types.ProposalID{1})))))
+	require.ErrorIs(t, broker.HandleMessage(ctx, "", mustEncode(t, buildRoundMsg(t, signer, status, statusRound, NewSetFromValues(types.ProposalID{2})))), errEquivocation)
+	require.Len(t, broker.firsts, 1)
+	require.Len(t, broker.equivocators, 1)
+
+	broker.Unregister(ctx, instanceID1)
+	broker.CleanOldLayers(instanceID1)
+	require.Empty(t, broker.firsts)
+	require.Empty(t, broker.equivocators)
+}
+
+func TestBroker_RateLimit(t *testing.T) {
+	ctx := context.Background()
+	broker := buildBroker(t, t.Name())
+	broker.mockSyncS.EXPECT().IsSynced(gomock.Any()).Return(true).AnyTimes()
+	broker.mockSyncS.EXPECT().IsBeaconSynced(gomock.Any()).Return(true).AnyTimes()
+	broker.mockStateQ.EXPECT().IsIdentityActiveOnConsensusView(gomock.Any(), gomock.Any(), gomock.Any()).Return(true, nil).AnyTimes()
+	broker.mockMesh.EXPECT().GetMalfeasanceProof(gomock.Any()).AnyTimes()
+	broker.Start(ctx)
+	t.Cleanup(broker.Close)
+	inbox, _, err := broker.Register(ctx, instanceID1)
+	require.NoError(t, err)
+
+	signer, err := signing.NewEdSigner()
+	require.NoError(t, err)
+	other, err := signing.NewEdSigner()
+	require.NoError(t, err)
+
+	// a forged message doesn't count towards the limit of the identity
+	forged := buildRoundMsg(t, other, status, statusRound, NewSetFromValues(types.ProposalID{1}))
+	forged.SmesherID = signer.NodeID()
+	for i := 0; i < 3; i++ {
+		err := broker.HandleMessage(ctx, "", mustEncode(t, forged))
+		require.Error(t, err)
+		require.NotErrorIs(t, err, errRateLimited)
+		require.NotErrorIs(t, err, errDuplicateMsg)
+	}
+
+	first := buildRoundMsg(t, signer, status, statusRound, NewSetFromValues(types.ProposalID{1}))
+	require.NoError(t, broker.HandleMessage(ctx, "", mustEncode(t, first)))
+	require.ErrorIs(t, broker.HandleMessage(ctx, "", mustEncode(t, first)), errDuplicateMsg)
+
+	// the message over the limit is still verified to prove equivocation
+	second := buildRoundMsg(t, signer, status, statusRound, NewSetFromValues(types.ProposalID{2}))
+	require.ErrorIs(t, broker.HandleMessage(ctx, "", mustEncode(t, second)), errEquivocation)
+	require.Len(t, broker.mch, 1)
+
+	for i := 3; i < 10; i++ {
+		msg := buildRoundMsg(t, signer, status, statusRound, NewSetFromValues(types.ProposalID{byte(i)}))
+		require.ErrorIs(t, broker.HandleMessage(ctx, "", mustEncode(t, msg)), errRateLimited)
+	}
+
+	// other rounds and identities are not affected
+	msg := buildRoundMsg(t, other, status, statusRound, NewSetFromValues(types.ProposalID{1}))
+	require.NoError(t, broker.HandleMessage(ctx, "", mustEncode(t, msg)))
+	require.Len(t, inbox, 2)
+	require.Len(t, broker.signed, 1)
+
+	broker.Unregister(ctx, instanceID1)
+	broker.CleanOldLayers(instanceID1)
+	require.Empty(t, broker.signed)
+}
+
+func TestBroker_Version(t *testing.T) {
+	ctx := context.Background()
+	broker := buildBroker(t, t.Name())
+	broker.mockSyncS.EXPECT().IsSynced(gomock.Any()).Return(true).AnyTimes()
+	broker.mockSyncS.EXPECT().IsBeaconSynced(gomock.Any()).Return(true).AnyTimes()
+	broker.mockStateQ.EXPECT().IsIdentityActiveOnConsensusView(gomock.Any(), gomock.Any(), gomock.Any()).Return(true, nil).AnyTimes()
+	broker.mockMesh.EXPECT().GetMalfeasanceProof(gomock.Any()).AnyTimes()
+	version := LegacyVersion
+	broker.version = func(types.LayerID) byte { return version }
+	broker.Start(ctx)
+	t.Cleanup(broker.Close)
+	inbox, _, err := broker.Register(ctx, instanceID1)
+	require.NoError(t, err)
+
+	values := NewSetFromValues(types.ProposalID{1})
+	signer, err := signing.NewEdSigner()
+	require.NoError(t, err)
+	msg := buildRoundMsg(t, signer, status, statusRound, values)
+	require.ErrorIs(t, broker.HandleMessage(ctx, "", mustEncode(t, msg)), errMalformedMsg)
+
+	msg.legacy = true
+	signMessage(&messageBuilder{msg: msg, inner: msg.InnerMessage}, signer)
+	require.NoError(t, broker.HandleLegacyMessage(ctx, "", msg.Bytes()))
+	require.Len(t, inbox, 1)
+	received := (<-inbox).(*Message)
+	require.True(t, received.legacy)
+	require.Equal(t, values.ID(), received.ValuesHash)
+	require.Equal(t, types.BytesToHash(msg.InnerMessage.HashBytes()), received.signedHash)
+
+	version = SetHashVersion
+	other, err := signing.NewEdSigner()
+	require.NoError(t, err)
+	msg = buildRoundMsg(t, other, status, statusRound, values)
+	msg.legacy = true
+	signMessage(&messageBuilder{msg: msg, inner: msg.InnerMessage}, other)
+	require.ErrorIs(t, broker.HandleLegacyMessage(ctx, "", msg.Bytes()), errMalformedMsg)
+	require.Empty(t, inbox)
+}
+
+func BenchmarkBroker_Flood(b *testing.B) {
+	for _, bc := range []struct {
+		desc     string
+		identity bool // all messages are from one identity
+	}{
+		{desc: "distinct identities"},
+		{desc: "single identity", identity: true},
+	} {
+		bc := bc
+		b.Run(bc.desc, func(b *testing.B) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			broker := buildBroker(b, b.Name())
+			broker.mockSyncS.EXPECT().IsSynced(gomock.Any()).Return(true).AnyTimes()
+			broker.mockSyncS.EXPECT().IsBeaconSynced(gomock.Any()).Return(true).AnyTimes()
+			broker.mockStateQ.EXPECT().IsIdentityActiveOnConsensusView(gomock.Any(), gomock.Any(), gomock.Any()).Return(true, nil).AnyTimes()
+			broker.mockMesh.EXPECT().GetMalfeasanceProof(gomock.Any()).AnyTimes()
+			broker.Start(ctx)
+			b.Cleanup(broker.Close)
+			inbox, _, err := broker.Register(ctx, instanceID1)
+			require.NoError(b, err)
+			go func() {
+				for {
+					select {
+					case <-ctx.Done():
+						return
+					case <-inbox:
+					case <-broker.mch:
+					}
+				}
+			}()
+
+			signer, err := signing.NewEdSigner()
+			require.NoError(b, err)
+			msgs := make([][]byte, b.N)
+			for i := range msgs {
+				if !bc.identity {
+					signer, err = signing.NewEdSigner()
+					require.NoError(b, err)
+				}
+				values := NewSetFromValues(types.ProposalID{byte(i), byte(i >> 8), byte(i >> 16), byte(i >> 24)})
+				msgs[i] = mustEncode(b, buildRoundMsg(b, signer, status, statusRound, values))
+			}
+			b.ResetTimer()
+			for _, msg := range msgs {
+				broker.HandleMessage(ctx, "", msg)
+			}
+		})
+	}
+}
@@ -1,6 +1,7 @@
 package hare
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/spacemeshos/go-spacemesh/codec"
@@ -10,7 +11,7 @@ import (
 	"github.com/spacemeshos/go-spacemesh/signing"
 )
 
-//go:generate scalegen -types Message,Certificate,AggregatedMessages,InnerMessage
+//go:generate scalegen -types Message,Certificate,AggregatedMessages,InnerMessage,SetDelta
 
 type Message struct {
 	*InnerMessage
@@ -33,7 +34,7 @@ type Message struct {
 // It returns an error if unmarshal of the provided byte slice failed.
 func MessageFromBuffer(buf []byte) (*Message, error) {
 	msg := &Message{}
-	if err := codec.Decode(buf, msg); err != nil {
+	if err := codec.DecodeStrict(buf, msg); err != nil {
 		return msg, fmt.Errorf("serialize: %w", err)
 	}
 	return msg, nil
@@ -56,6 +57,14 @@ func (m *Message) Field() log.Field {
 // Bytes returns the message as bytes.
 // It panics if the message errored on unmarshal.
 func (m *Message) Bytes() []byte {
+	if m.legacy {
+		legacy := toLegacyMessage(m)
+		buf, err := codec.Encode(&legacy)
+		if err != nil {
+			log.With().Fatal("failed to encode legacy Message", log.Err(err))
+		}
+		return buf
+	}
 	buf, err := codec.Encode(m)
 	if err != nil {
 		log.With().Fatal("failed to encode Message", log.Err(err))
@@ -95,20 +104,52 @@ type InnerMessage struct {
 	Type           MessageType
 	CommittedRound uint32              // the round Values (S) is committed (Ki)
 	Values         []types.ProposalID  `scale:"max=500"` // the set S. optional for commit InnerMsg in a certificate - expected are 50 proposals per layer + safety margin
+	ValuesHash     types.Hash32        // the hash of the sorted set S. Values are omitted if the receiver is expected to know the set
+	Delta          *SetDelta           // optional. the set S as a change of the set previously sent by the smesher
 	Svp            *AggregatedMessages // optional. only for proposal Messages
 	Cert           *Certificate        // optional
+
+	// the message is encoded and signed as before SetHashVersion.
+	legacy bool
+}
+
+// SetDelta describes a set as a change of a base set.
+type SetDelta struct {
+	Base   types.Hash32
+	Add    []types.ProposalID `scale:"max=500"`
+	Remove []types.ProposalID `scale:"max=500"`
 }
 
 // HashBytes returns the message as bytes.
+// Values and Delta are not hashed, as the set is committed to by ValuesHash.
+// Legacy messages are hashed with the values, as they were signed before SetHashVersion.
 func (im *InnerMessage) HashBytes() []byte {
+	var signed codec.Encodable
+	if im.legacy {
+		signed = toLegacyInner(im)
+	} else {
+		inner := *im
+		inner.Values = nil
+		inner.Delta = nil
+		signed = &inner
+	}
 	h := hash.New()
-	_, err := codec.EncodeTo(h, im)
+	_, err := codec.EncodeTo(h, signed)
 	if err != nil {
 		log.With().Fatal("failed to encode InnerMsg for hashing", log.Err(err))
 	}
 	return h.Sum(nil)
 }
 
+// fillValues sets the values omitted from the message.
+// The hash of the legacy message is not encoded, and is derived from the values.
+func (im *InnerMessage) fillValues(values []types.ProposalID) {
+	im.Values = values
+	if im.legacy {
+		im.ValuesHash = NewSet(values).ID()
+	}
+}
+
 func (im *InnerMessage) MarshalLogObject(encoder log.ObjectEncoder) error {
 	encoder.AddString("msg_type", im.Type.String())
 	encoder.AddUint32("committed_round", im.CommittedRound)
@@ -142,10 +183,14 @@ func (mb *messageBuilder) SetCertificate(certificate *Certificate) *messageBuild
 }
 
 // Sign calls the provided signer to calculate the signature and then set it accordingly.
-func (mb *messageBuilder) Sign(signer *signing.EdSigner) *messageBuilder {
-	mb.msg.Signature = signer.Sign(signing.HARE, mb.msg.SignedBytes())
+func (mb *messageBuilder) Sign(ctx context.Context, signer signing.Signer) (*messageBuilder, error) {
+	sig, err := signer.SignContext(ctx, signing.HARE, mb.msg.SignedBytes())
+	if err != nil {
+		return nil, fmt.Errorf("sign hare message: %w", err)
+	}
+	mb.msg.Signature = sig
 	mb.msg.SmesherID = signer.NodeID()
-	return mb
+	return mb, nil
 }
 
 // SetType sets message type.
@@ -175,6 +220,13 @@ func (mb *messageBuilder) SetCommittedRound(round uint32) *messageBuilder {
 // SetValues sets values.
 func (mb *messageBuilder) SetValues(set *Set) *messageBuilder {
 	mb.inner.Values = set.ToSlice()
+	mb.inner.ValuesHash = set.ID()
+	return mb
+}
+
+// SetLegacy sets whether the message is encoded and signed as before SetHashVersion.
+func (mb *messageBuilder) SetLegacy(legacy bool) *messageBuilder {
+	mb.inner.legacy = legacy
 	return mb
 }
 
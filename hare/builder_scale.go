@@ -170,6 +170,20 @@ func (t *InnerMessage) EncodeScale(enc *scale.Encoder) (total int, err error) {
 		}
 		total += n
 	}
+	{
+		n, err := scale.EncodeByteArray(enc, t.ValuesHash[:])
+		if err != nil {
+			return total, err
+		}
+		total += n
+	}
+	{
+		n, err := scale.EncodeOption(enc, t.Delta)
+		if err != nil {
+			return total, err
+		}
+		total += n
+	}
 	{
 		n, err := scale.EncodeOption(enc, t.Svp)
 		if err != nil {
@@ -228,6 +242,21 @@ func (t *InnerMessage) DecodeScale(dec *scale.Decoder) (total int, err error) {
 		total += n
 		t.Values = field
 	}
+	{
+		n, err := scale.DecodeByteArray(dec, t.ValuesHash[:])
+		if err != nil {
+			return total, err
+		}
+		total += n
+	}
+	{
+		field, n, err := scale.DecodeOption[SetDelta](dec)
+		if err != nil {
+			return total, err
+		}
+		total += n
+		t.Delta = field
+	}
 	{
 		field, n, err := scale.DecodeOption[AggregatedMessages](dec)
 		if err != nil {
@@ -246,3 +275,55 @@ func (t *InnerMessage) DecodeScale(dec *scale.Decoder) (total int, err error) {
 	}
 	return total, nil
 }
+
+func (t *SetDelta) EncodeScale(enc *scale.Encoder) (total int, err error) {
+	{
+		n, err := scale.EncodeByteArray(enc, t.Base[:])
+		if err != nil {
+			return total, err
+		}
+		total += n
+	}
+	{
+		n, err := scale.EncodeStructSliceWithLimit(enc, t.Add, 500)
+		if err != nil {
+			return total, err
+		}
+		total += n
+	}
+	{
+		n, err := scale.EncodeStructSliceWithLimit(enc, t.Remove, 500)
+		if err != nil {
+			return total, err
+		}
+		total += n
+	}
+	return total, nil
+}
+
+func (t *SetDelta) DecodeScale(dec *scale.Decoder) (total int, err error) {
+	{
+		n, err := scale.DecodeByteArray(dec, t.Base[:])
+		if err != nil {
+			return total, err
+		}
+		total += n
+	}
+	{
+		field, n, err := scale.DecodeStructSliceWithLimit[types.ProposalID](dec, 500)
+		if err != nil {
+			return total, err
+		}
+		total += n
+		t.Add = field
+	}
+	{
+		field, n, err := scale.DecodeStructSliceWithLimit[types.ProposalID](dec, 500)
+		if err != nil {
+			return total, err
+		}
+		total += n
+		t.Remove = field
+	}
+	return total, nil
+}
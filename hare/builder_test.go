@@ -22,11 +22,20 @@ func marshallUnmarshall(t *testing.T, msg *Message) *Message {
 	return m
 }
 
+// signMessage signs the message with the local key in tests, signing with the local key never fails.
+func signMessage(mb *messageBuilder, signer signing.Signer) *messageBuilder {
+	signed, err := mb.Sign(context.Background(), signer)
+	if err != nil {
+		panic(err)
+	}
+	return signed
+}
+
 func TestBuilder_TestBuild(t *testing.T) {
 	b := newMessageBuilder()
 	signer, err := signing.NewEdSigner()
 	require.NoError(t, err)
-	msg := b.SetLayer(instanceID1).Sign(signer).Build()
+	msg := signMessage(b.SetLayer(instanceID1), signer).Build()
 
 	m := marshallUnmarshall(t, msg)
 	assert.Equal(t, m, msg)
@@ -63,7 +72,7 @@ func TestMessageFromBuffer(t *testing.T) {
 	b := newMessageBuilder()
 	signer, err := signing.NewEdSigner()
 	require.NoError(t, err)
-	msg := b.SetLayer(instanceID1).Sign(signer).Build()
+	msg := signMessage(b.SetLayer(instanceID1), signer).Build()
 
 	buf, err := codec.Encode(msg)
 	require.NoError(t, err)
@@ -72,3 +81,43 @@ func TestMessageFromBuffer(t *testing.T) {
 	require.NoError(t, err)
 	require.Equal(t, msg, got)
 }
+
+func BenchmarkEncodeMessages(b *testing.B) {
+	const count = 10_000
+	signer, err := signing.NewEdSigner()
+	require.NoError(b, err)
+	values := make([]types.ProposalID, 50)
+	for i := range values {
+		values[i] = types.RandomProposalID()
+	}
+	msgs := make([]*Message, count)
+	for i := range msgs {
+		builder := newMessageBuilder().
+			SetLayer(instanceID1).
+			SetRoundCounter(uint32(i)).
+			SetValues(NewSetFromValues(values...))
+		msgs[i] = signMessage(builder, signer).Build()
+	}
+	b.Run("encode", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			for _, msg := range msgs {
+				if _, err := codec.Encode(msg); err != nil {
+					b.Fatal(err)
+				}
+			}
+		}
+	})
+	b.Run("pool", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			for _, msg := range msgs {
+				_, release, err := codec.EncodeToPool(msg)
+				if err != nil {
+					b.Fatal(err)
+				}
+				release()
+			}
+		}
+	})
+}
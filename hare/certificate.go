@@ -0,0 +1,189 @@
+package hare
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/spacemeshos/go-spacemesh/codec"
+	"github.com/spacemeshos/go-spacemesh/common/types"
+	"github.com/spacemeshos/go-spacemesh/log"
+	"github.com/spacemeshos/go-spacemesh/p2p"
+	"github.com/spacemeshos/go-spacemesh/p2p/pubsub"
+	"github.com/spacemeshos/go-spacemesh/signing"
+	"github.com/spacemeshos/go-spacemesh/sql/certificates"
+)
+
+//go:generate scalegen -types CertificateMessage
+
+// CertificateMessage is the certificate of the hare output for the layer.
+// It is gossiped once the hare terminates, so that nodes that didn't participate
+// in the protocol can verify the output.
+type CertificateMessage struct {
+	Layer       types.LayerID
+	Certificate Certificate
+}
+
+var (
+	errCertKnown      = errors.New("certificate for the layer is already known")
+	errCertFuture     = errors.New("certificate for the future layer")
+	errCertEmpty      = errors.New("certificate without commit messages")
+	errCertWrongMsg   = errors.New("certificate contains message that is not a commit for the layer")
+	errCertValues     = errors.New("certificate values don't match the commit messages")
+	errCertSignature  = errors.New("certificate contains message with invalid signature")
+	errCertNotEnough  = errors.New("certificate doesn't meet the threshold")
+	errCertCommittee  = errors.New("committee is unknown")
+	errCertIneligible = errors.New("certificate contains message from ineligible identity")
+)
+
+// compactCertificate returns a copy of the certificate where values are removed from the commit messages,
+// as all of them commit to the values of the certificate.
+func compactCertificate(cert *Certificate) Certificate {
+	rst := Certificate{Values: cert.Values, AggMsgs: &AggregatedMessages{}}
+	for _, msg := range cert.AggMsgs.Messages {
+		inner := *msg.InnerMessage
+		inner.Values = nil
+		msg.InnerMessage = &inner
+		rst.AggMsgs.Messages = append(rst.AggMsgs.Messages, msg)
+	}
+	return rst
+}
+
+// persistCertificate stores the certificate of the hare output and gossips it, if the certificate
+// for the layer wasn't stored before.
+func (h *Hare) persistCertificate(ctx context.Context, lid types.LayerID, cert *Certificate) error {
+	if cert == nil || cert.AggMsgs == nil {
+		return nil
+	}
+	has, err := certificates.HasHare(h.msh.Cache(), lid)
+	if err != nil {
+		return err
+	}
+	if has {
+		return nil
+	}
+	compact := compactCertificate(cert)
+	var encoded []byte
+	if h.version(lid) == LegacyVersion {
+		encoded, err = codec.Encode(&LegacyCertificateMessage{Layer: lid, Certificate: *toLegacyCertificate(&compact)})
+	} else {
+		encoded, err = codec.Encode(&CertificateMessage{Layer: lid, Certificate: compact})
+	}
+	if err != nil {
+		h.With().Fatal("failed to encode hare certificate", log.Err(err))
+	}
+	if err := certificates.AddHare(h.msh.Cache(), lid, encoded); err != nil {
+		return err
+	}
+	certificatesCnt.Inc()
+	if err := h.versions.PublishLayer(ctx, pubsub.HareCertificateProtocol, lid, encoded); err != nil {
+		h.With().Error("failed to broadcast hare certificate", log.Context(ctx), lid, log.Err(err))
+	}
+	return nil
+}
+
+// validateCertificate checks that the certificate contains commit messages for the certified values
+// from distinct eligible identities, and that their eligibility meets the threshold of the committee.
+func (h *Hare) validateCertificate(ctx context.Context, msg *CertificateMessage) error {
+	cert := &msg.Certificate
+	if cert.AggMsgs == nil || len(cert.AggMsgs.Messages) == 0 {
+		return errCertEmpty
+	}
+	c, err := h.ev.committee(ctx, msg.Layer)
+	if err != nil {
+		return fmt.Errorf("%w: %s", errCertCommittee, err)
+	}
+	var (
+		round   = cert.AggMsgs.Messages[0].Round
+		senders = make(map[types.NodeID]struct{}, len(cert.AggMsgs.Messages))
+		count   int
+	)
+	for i := range cert.AggMsgs.Messages {
+		m := &cert.AggMsgs.Messages[i]
+		if m.InnerMessage == nil || m.Layer != msg.Layer || m.Type != commit ||
+			m.Round != round || m.Round%RoundsPerIteration != commitRound {
+			return errCertWrongMsg
+		}
+		// the values were removed to reduce data volume
+		m.fillValues(cert.Values)
+		if NewSet(m.Values).ID() != m.ValuesHash {
+			return fmt.Errorf("%w: %s", errCertValues, m.SmesherID)
+		}
+		if !h.sigVerifier.Verify(signing.HARE, m.SmesherID, m.SignedBytes(), m.Signature) {
+			return fmt.Errorf("%w: %s", errCertSignature, m.SmesherID)
+		}
+		if _, exist := senders[m.SmesherID]; exist {
+			return fmt.Errorf("%w: %s", errDupSender, m.SmesherID)
+		}
+		senders[m.SmesherID] = struct{}{}
+		if !h.ev.Validate(ctx, m) {
+			return fmt.Errorf("%w: %s", errCertIneligible, m.SmesherID)
+		}
+		count += int(m.Eligibility.Count)
+	}
+	if threshold := c.size/2 + 1; count < threshold {
+		return fmt.Errorf("%w: %d < %d", errCertNotEnough, count, threshold)
+	}
+	return nil
+}
+
+// HandleCertificate is the gossip handler for certificates of the hare output.
+// Valid certificate is stored, and the certified values are used as the hare output
+// if the node didn't participate in the protocol for the layer.
+func (h *Hare) HandleCertificate(ctx context.Context, _ p2p.Peer, data []byte) error {
+	var msg CertificateMessage
+	if err := codec.Decode(data, &msg); err != nil {
+		return fmt.Errorf("%w: malformed hare certificate: %s", pubsub.ErrValidationReject, err)
+	}
+	return h.handleCertificate(ctx, &msg, data, SetHashVersion)
+}
+
+// HandleLegacyCertificate is the gossip handler for certificates encoded before SetHashVersion.
+func (h *Hare) HandleLegacyCertificate(ctx context.Context, _ p2p.Peer, data []byte) error {
+	var legacy LegacyCertificateMessage
+	if err := codec.Decode(data, &legacy); err != nil {
+		return fmt.Errorf("%w: malformed hare certificate: %s", pubsub.ErrValidationReject, err)
+	}
+	msg := CertificateMessage{Layer: legacy.Layer, Certificate: legacy.Certificate.certificate()}
+	return h.handleCertificate(ctx, &msg, data, LegacyVersion)
+}
+
+func (h *Hare) handleCertificate(ctx context.Context, msg *CertificateMessage, data []byte, version byte) error {
+	logger := h.WithContext(ctx).WithFields(msg.Layer)
+	if expected := h.version(msg.Layer); expected != version {
+		return fmt.Errorf("%w: certificate version %d in layer with version %d",
+			pubsub.ErrValidationReject, version, expected)
+	}
+	if msg.Layer.After(h.layerClock.CurrentLayer()) {
+		return errCertFuture
+	}
+	has, err := certificates.HasHare(h.msh.Cache(), msg.Layer)
+	if err != nil {
+		return err
+	}
+	if has {
+		return errCertKnown
+	}
+	if err := h.validateCertificate(ctx, msg); err != nil {
+		logger.With().Debug("invalid hare certificate", log.Err(err))
+		if errors.Is(err, errCertCommittee) {
+			return err
+		}
+		return fmt.Errorf("%w: %s", pubsub.ErrValidationReject, err)
+	}
+	if err := certificates.AddHare(h.msh.Cache(), msg.Layer, data); err != nil {
+		return err
+	}
+	logger.With().Debug("received hare certificate", log.Int("num_proposals", len(msg.Certificate.Values)))
+	if h.getCP(msg.Layer) != nil || h.outOfBufferRange(msg.Layer) {
+		return nil
+	}
+	if _, err := h.getResult(msg.Layer); err == nil {
+		return nil
+	}
+	return h.collectOutput(ctx, report{
+		id:        msg.Layer,
+		set:       NewSet(msg.Certificate.Values),
+		completed: true,
+	})
+}
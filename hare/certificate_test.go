@@ -0,0 +1,308 @@
+package hare
+
+import (
+	"context"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/spacemeshos/go-spacemesh/codec"
+	"github.com/spacemeshos/go-spacemesh/common/types"
+	"github.com/spacemeshos/go-spacemesh/datastore"
+	"github.com/spacemeshos/go-spacemesh/hare/config"
+	"github.com/spacemeshos/go-spacemesh/log/logtest"
+	"github.com/spacemeshos/go-spacemesh/p2p"
+	"github.com/spacemeshos/go-spacemesh/p2p/pubsub"
+	pubsubmocks "github.com/spacemeshos/go-spacemesh/p2p/pubsub/mocks"
+	"github.com/spacemeshos/go-spacemesh/signing"
+	"github.com/spacemeshos/go-spacemesh/sql"
+	"github.com/spacemeshos/go-spacemesh/sql/certificates"
+	"github.com/spacemeshos/go-spacemesh/sql/hareresults"
+)
+
+func buildCertificate(tb testing.TB, s *Set, signers int) *Certificate {
+	tb.Helper()
+	cert := &Certificate{Values: s.ToSlice(), AggMsgs: &AggregatedMessages{}}
+	for i := 0; i < signers; i++ {
+		signer, err := signing.NewEdSigner()
+		require.NoError(tb, err)
+		cert.AggMsgs.Messages = append(cert.AggMsgs.Messages, *BuildCommitMsg(signer, s))
+	}
+	return cert
+}
+
+func encodeCertificate(tb testing.TB, lid types.LayerID, cert *Certificate) []byte {
+	tb.Helper()
+	data, err := codec.Encode(&CertificateMessage{Layer: lid, Certificate: compactCertificate(cert)})
+	require.NoError(tb, err)
+	return data
+}
+
+func createCertTestHare(tb testing.TB, publisher pubsub.PublishSubsciber) (*hareWithMocks, *datastore.CachedDB) {
+	tb.Helper()
+	cfg := config.DefaultConfig()
+	cfg.N = 4
+	cdb := datastore.NewCachedDB(sql.InMemory(), logtest.New(tb))
+	mockMesh := newMockMesh(tb)
+	mockMesh.EXPECT().Cache().Return(cdb).AnyTimes()
+	return createTestHare(tb, mockMesh, cfg, newMockClock(), publisher, tb.Name()), cdb
+}
+
+func TestHandleCertificate(t *testing.T) {
+	lid := instanceID1
+	values := NewSetFromValues(types.RandomProposalID(), types.RandomProposalID())
+
+	t.Run("valid", func(t *testing.T) {
+		h, cdb := createCertTestHare(t, noopPubSub(t))
+		h.mockRoracle.EXPECT().Validate(gomock.Any(), lid, commitRound, 4, gomock.Any(), gomock.Any(), uint16(1)).
+			Return(true, nil).Times(3)
+		data := encodeCertificate(t, lid, buildCertificate(t, values, 3))
+		require.NoError(t, h.HandleCertificate(context.Background(), p2p.NoPeer, data))
+
+		stored, err := certificates.GetHare(cdb, lid)
+		require.NoError(t, err)
+		require.Equal(t, data, stored)
+		out, err := h.GetResult(context.Background(), lid)
+		require.NoError(t, err)
+		require.ElementsMatch(t, values.ToSlice(), out)
+		res, err := h.getResult(lid)
+		require.NoError(t, err)
+		require.ElementsMatch(t, values.ToSlice(), res)
+
+		require.ErrorIs(t, h.HandleCertificate(context.Background(), p2p.NoPeer, data), errCertKnown)
+	})
+	t.Run("running instance", func(t *testing.T) {
+		h, cdb := createCertTestHare(t, noopPubSub(t))
+		h.mockRoracle.EXPECT().Validate(gomock.Any(), lid, commitRound, 4, gomock.Any(), gomock.Any(), uint16(1)).
+			Return(true, nil).Times(3)
+		h.addCP(context.Background(), &mockConsensusProcess{id: lid})
+		data := encodeCertificate(t, lid, buildCertificate(t, values, 3))
+		require.NoError(t, h.HandleCertificate(context.Background(), p2p.NoPeer, data))
+
+		has, err := certificates.HasHare(cdb, lid)
+		require.NoError(t, err)
+		require.True(t, has)
+		_, _, err = hareresults.Get(cdb, lid)
+		require.ErrorIs(t, err, sql.ErrNotFound)
+	})
+	for _, tc := range []struct {
+		desc     string
+		cert     func() *Certificate
+		eligible bool
+		err      error
+	}{
+		{
+			desc:     "not enough",
+			cert:     func() *Certificate { return buildCertificate(t, values, 2) },
+			eligible: true,
+			err:      errCertNotEnough,
+		},
+		{
+			desc: "duplicate sender",
+			cert: func() *Certificate {
+				cert := buildCertificate(t, values, 2)
+				cert.AggMsgs.Messages = append(cert.AggMsgs.Messages, cert.AggMsgs.Messages[0])
+				return cert
+			},
+			eligible: true,
+			err:      errDupSender,
+		},
+		{
+			desc:     "ineligible",
+			cert:     func() *Certificate { return buildCertificate(t, values, 3) },
+			eligible: false,
+			err:      errCertIneligible,
+		},
+		{
+			desc: "values don't match commits",
+			cert: func() *Certificate {
+				cert := buildCertificate(t, values, 3)
+				cert.Values = append(cert.Values, types.RandomProposalID())
+				return cert
+			},
+			err: errCertValues,
+		},
+		{
+			desc: "values not signed",
+			cert: func() *Certificate {
+				cert := buildCertificate(t, values, 3)
+				cert.Values = append(cert.Values, types.RandomProposalID())
+				for i := range cert.AggMsgs.Messages {
+					inner := *cert.AggMsgs.Messages[i].InnerMessage
+					inner.ValuesHash = NewSet(cert.Values).ID()
+					cert.AggMsgs.Messages[i].InnerMessage = &inner
+				}
+				return cert
+			},
+			err: errCertSignature,
+		},
+		{
+			desc: "not a commit",
+			cert: func() *Certificate {
+				cert := buildCertificate(t, values, 3)
+				inner := *cert.AggMsgs.Messages[1].InnerMessage
+				inner.Type = notify
+				cert.AggMsgs.Messages[1].InnerMessage = &inner
+				return cert
+			},
+			eligible: true,
+			err:      errCertWrongMsg,
+		},
+		{
+			desc:     "empty",
+			cert:     func() *Certificate { return &Certificate{Values: values.ToSlice(), AggMsgs: &AggregatedMessages{}} },
+			eligible: true,
+			err:      errCertEmpty,
+		},
+	} {
+		tc := tc
+		t.Run(tc.desc, func(t *testing.T) {
+			h, cdb := createCertTestHare(t, noopPubSub(t))
+			h.mockRoracle.EXPECT().Validate(gomock.Any(), lid, commitRound, 4, gomock.Any(), gomock.Any(), uint16(1)).
+				Return(tc.eligible, nil).AnyTimes()
+			data := encodeCertificate(t, lid, tc.cert())
+			var msg CertificateMessage
+			require.NoError(t, codec.Decode(data, &msg))
+			require.ErrorIs(t, h.validateCertificate(context.Background(), &msg), tc.err)
+			require.ErrorIs(t, h.HandleCertificate(context.Background(), p2p.NoPeer, data), pubsub.ErrValidationReject)
+
+			has, err := certificates.HasHare(cdb, lid)
+			require.NoError(t, err)
+			require.False(t, has)
+			_, _, err = hareresults.Get(cdb, lid)
+			require.ErrorIs(t, err, sql.ErrNotFound)
+		})
+	}
+	t.Run("future layer", func(t *testing.T) {
+		h, _ := createCertTestHare(t, noopPubSub(t))
+		data := encodeCertificate(t, lid.Add(1), buildCertificate(t, values, 3))
+		require.ErrorIs(t, h.HandleCertificate(context.Background(), p2p.NoPeer, data), errCertFuture)
+	})
+	t.Run("malformed", func(t *testing.T) {
+		h, _ := createCertTestHare(t, noopPubSub(t))
+		require.ErrorIs(t, h.HandleCertificate(context.Background(), p2p.NoPeer, []byte{1, 2, 3}), pubsub.ErrValidationReject)
+	})
+}
+
+func TestHare_collectOutputPersistsCertificate(t *testing.T) {
+	lid := instanceID1
+	values := NewSetFromValues(types.RandomProposalID(), types.RandomProposalID())
+	cert := buildCertificate(t, values, 3)
+	expected := encodeCertificate(t, lid, cert)
+
+	publisher := pubsubmocks.NewMockPublishSubsciber(gomock.NewController(t))
+	publisher.EXPECT().Register(gomock.Any(), gomock.Any()).AnyTimes()
+	publisher.EXPECT().Publish(gomock.Any(), pubsub.HareCertificateProtocol, expected).Times(1)
+	h, cdb := createCertTestHare(t, publisher)
+
+	rst := report{id: lid, set: values, completed: true, cert: cert}
+	require.NoError(t, h.collectOutput(context.Background(), rst))
+	stored, err := certificates.GetHare(cdb, lid)
+	require.NoError(t, err)
+	require.Equal(t, expected, stored)
+	// the certificate is published only once
+	require.NoError(t, h.collectOutput(context.Background(), rst))
+
+	var msg CertificateMessage
+	require.NoError(t, codec.Decode(stored, &msg))
+	require.Equal(t, lid, msg.Layer)
+	require.Equal(t, cert.Values, msg.Certificate.Values)
+	for _, commit := range msg.Certificate.AggMsgs.Messages {
+		require.Empty(t, commit.Values)
+	}
+
+	// failed instance doesn't produce a certificate
+	h.mockPatrol.EXPECT().CompleteHare(lid.Add(1))
+	require.NoError(t, h.collectOutput(context.Background(), report{id: lid.Add(1), set: values}))
+	has, err := certificates.HasHare(cdb, lid.Add(1))
+	require.NoError(t, err)
+	require.False(t, has)
+}
+
+// testVersions uses LegacyVersion in the layers before the set hash layer.
+type testVersions struct {
+	pubsub.Publisher
+	setHash types.LayerID
+}
+
+func (v testVersions) Version(_ string, lid types.LayerID) (byte, bool) {
+	if lid.Before(v.setHash) {
+		return LegacyVersion, true
+	}
+	return SetHashVersion, true
+}
+
+func (v testVersions) PublishLayer(ctx context.Context, topic string, _ types.LayerID, msg []byte) error {
+	return v.Publish(ctx, topic, msg)
+}
+
+func buildLegacyCertificate(tb testing.TB, s *Set, signers int) *Certificate {
+	tb.Helper()
+	cert := &Certificate{Values: s.ToSlice(), AggMsgs: &AggregatedMessages{}}
+	for i := 0; i < signers; i++ {
+		signer, err := signing.NewEdSigner()
+		require.NoError(tb, err)
+		cert.AggMsgs.Messages = append(cert.AggMsgs.Messages, *buildLegacyCommitMsg(tb, signer, s))
+	}
+	return cert
+}
+
+func encodeLegacyCertificate(tb testing.TB, lid types.LayerID, cert *Certificate) []byte {
+	tb.Helper()
+	compact := compactCertificate(cert)
+	data, err := codec.Encode(&LegacyCertificateMessage{Layer: lid, Certificate: *toLegacyCertificate(&compact)})
+	require.NoError(tb, err)
+	return data
+}
+
+func TestHandleLegacyCertificate(t *testing.T) {
+	lid := instanceID1
+	values := NewSetFromValues(types.RandomProposalID(), types.RandomProposalID())
+
+	t.Run("valid", func(t *testing.T) {
+		h, cdb := createCertTestHare(t, noopPubSub(t))
+		h.versions = testVersions{setHash: lid.Add(1)}
+		h.mockRoracle.EXPECT().Validate(gomock.Any(), lid, commitRound, 4, gomock.Any(), gomock.Any(), uint16(1)).
+			Return(true, nil).Times(3)
+		cert := buildLegacyCertificate(t, values, 3)
+		require.ErrorIs(t, h.HandleCertificate(context.Background(), p2p.NoPeer, encodeCertificate(t, lid, cert)),
+			pubsub.ErrValidationReject)
+
+		data := encodeLegacyCertificate(t, lid, cert)
+		require.NoError(t, h.HandleLegacyCertificate(context.Background(), p2p.NoPeer, data))
+		stored, err := certificates.GetHare(cdb, lid)
+		require.NoError(t, err)
+		require.Equal(t, data, stored)
+		res, err := h.getResult(lid)
+		require.NoError(t, err)
+		require.ElementsMatch(t, values.ToSlice(), res)
+	})
+	t.Run("set hash layer", func(t *testing.T) {
+		h, cdb := createCertTestHare(t, noopPubSub(t))
+		h.versions = testVersions{setHash: lid}
+		data := encodeLegacyCertificate(t, lid, buildLegacyCertificate(t, values, 3))
+		require.ErrorIs(t, h.HandleLegacyCertificate(context.Background(), p2p.NoPeer, data), pubsub.ErrValidationReject)
+		has, err := certificates.HasHare(cdb, lid)
+		require.NoError(t, err)
+		require.False(t, has)
+	})
+}
+
+func TestHare_collectOutputPersistsLegacyCertificate(t *testing.T) {
+	lid := instanceID1
+	values := NewSetFromValues(types.RandomProposalID(), types.RandomProposalID())
+	cert := buildLegacyCertificate(t, values, 3)
+	expected := encodeLegacyCertificate(t, lid, cert)
+
+	publisher := pubsubmocks.NewMockPublishSubsciber(gomock.NewController(t))
+	publisher.EXPECT().Register(gomock.Any(), gomock.Any()).AnyTimes()
+	publisher.EXPECT().Publish(gomock.Any(), pubsub.HareCertificateProtocol, expected).Times(1)
+	h, cdb := createCertTestHare(t, publisher)
+	h.versions = testVersions{Publisher: publisher, setHash: lid.Add(1)}
+
+	require.NoError(t, h.collectOutput(context.Background(), report{id: lid, set: values, completed: true, cert: cert}))
+	stored, err := certificates.GetHare(cdb, lid)
+	require.NoError(t, err)
+	require.Equal(t, expected, stored)
+}
@@ -0,0 +1,37 @@
+package hare
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/spacemeshos/go-spacemesh/common/types"
+	"github.com/spacemeshos/go-spacemesh/hare/config"
+)
+
+// committee is the hare committee for a layer.
+type committee struct {
+	size    int // expected number of active participants in every round
+	leaders int // expected number of leaders in the proposal round
+}
+
+// adaptCommittee returns the committee for the layer. The oracle limits the configured size
+// by the size of the active set, and the expected number of leaders is not larger than the committee.
+// Thresholds for every round are derived from the committee size.
+func adaptCommittee(ctx context.Context, oracle Rolacle, layer types.LayerID, size, leaders int) (committee, error) {
+	effective, err := oracle.CommitteeSize(ctx, layer, size)
+	if err != nil {
+		return committee{}, fmt.Errorf("committee size for layer %d: %w", layer, err)
+	}
+	if leaders > effective {
+		leaders = effective
+	}
+	return committee{size: effective, leaders: leaders}, nil
+}
+
+// apply returns a copy of the config with the committee size and the expected number of leaders
+// replaced by the values for the committee.
+func (c committee) apply(cfg config.Config) config.Config {
+	cfg.N = c.size
+	cfg.ExpectedLeaders = c.leaders
+	return cfg
+}
@@ -15,7 +15,7 @@ func BuildCommitMsg(signer *signing.EdSigner, s *Set) *Message {
 	builder := newMessageBuilder()
 	builder.SetType(commit).SetLayer(instanceID1).SetRoundCounter(commitRound).SetCommittedRound(ki).SetValues(s)
 	builder.SetEligibilityCount(1)
-	m := builder.Sign(signer).Build()
+	m := signMessage(builder, signer).Build()
 	m.signedHash = types.BytesToHash(m.InnerMessage.HashBytes())
 	return m
 }
@@ -13,10 +13,12 @@ import (
 	mocknet "github.com/libp2p/go-libp2p/p2p/net/mock"
 	"github.com/stretchr/testify/require"
 
+	"github.com/spacemeshos/go-spacemesh/codec"
 	"github.com/spacemeshos/go-spacemesh/common/types"
 	"github.com/spacemeshos/go-spacemesh/datastore"
 	"github.com/spacemeshos/go-spacemesh/eligibility"
 	"github.com/spacemeshos/go-spacemesh/hare/config"
+	"github.com/spacemeshos/go-spacemesh/hare/mocks"
 	"github.com/spacemeshos/go-spacemesh/log/logtest"
 	"github.com/spacemeshos/go-spacemesh/malfeasance"
 	"github.com/spacemeshos/go-spacemesh/p2p"
@@ -189,31 +191,29 @@ func createConsensusProcess(
 	require.NoError(tb, err)
 	c, et, err := broker.Register(ctx, layer)
 	require.NoError(tb, err)
-	mch := make(chan *types.MalfeasanceGossip, cfg.N)
 	comm := communication{
 		inbox:  c,
-		mchOut: mch,
+		mchOut: broker.mch,
 		report: output,
 		wc:     wc,
+		sets:   broker.sets,
 	}
 	proc := newConsensusProcess(
 		ctx,
 		cfg,
 		layer,
 		initialSet,
-		oracle,
+		[]participant{{signer: sig, oracle: oracle}},
 		broker.mockStateQ,
-		sig,
 		edVerifier,
 		et,
-		sig.NodeID(),
 		network,
 		comm,
 		truer{},
 		newRoundClockFromCfg(logtest.New(tb), cfg),
 		logtest.New(tb).WithName(sig.PublicKey().ShortString()),
 	)
-	return &testCP{cp: proc, broker: broker.Broker, mch: mch}
+	return &testCP{cp: proc, broker: broker.Broker, mch: broker.mch}
 }
 
 // Test - runs a single CP for more than one iteration.
@@ -318,6 +318,209 @@ func TestSingleValueForHonestSet(t *testing.T) {
 	test.WaitForTimedTermination(t, 30*time.Second)
 }
 
+func TestLateProcessReachesAgreement(t *testing.T) {
+	test := newConsensusTest()
+
+	cfg := config.Config{N: 10, RoundDuration: 3 * time.Second, ExpectedLeaders: 5, LimitIterations: 1000, Hdist: 20}
+	totalNodes := 10
+	delay := 8 * time.Second
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	mesh, err := mocknet.FullMeshLinked(totalNodes)
+	require.NoError(t, err)
+
+	test.initialSets = make([]*Set, totalNodes)
+	set1 := NewSetFromValues(types.ProposalID{1})
+	test.fill(set1, 0, totalNodes-1)
+	test.honestSets = []*Set{set1}
+	oracle := eligibility.New(logtest.New(t))
+	// all processes share the same layer clock
+	clock := NewSimpleRoundClock(time.Now(), cfg.WakeupDelta, cfg.RoundDuration)
+	nets := make([]pubsub.PublishSubsciber, totalNodes)
+	sigs := make([]*signing.EdSigner, totalNodes)
+	for i := range nets {
+		ps, err := pubsub.New(ctx, logtest.New(t), mesh.Hosts()[i], pubsub.DefaultConfig())
+		require.NoError(t, err)
+		nets[i] = ps
+		sigs[i], err = signing.NewEdSigner()
+		require.NoError(t, err)
+		// eligibility of the late process is computed together with the rest
+		oracle.Register(true, sigs[i].NodeID())
+	}
+	i := 0
+	creationFunc := func() {
+		tcp := createConsensusProcess(t, ctx, sigs[i], true, cfg, oracle, nets[i], test.initialSets[i], instanceID1)
+		tcp.cp.clock = clock
+		test.procs = append(test.procs, tcp.cp)
+		test.brokers = append(test.brokers, tcp.broker)
+		i++
+	}
+	test.Create(totalNodes-1, creationFunc)
+	require.NoError(t, mesh.ConnectAllButSelf())
+	test.Start()
+
+	// the last process starts after status round ended, and doesn't receive any messages before that
+	time.Sleep(time.Until(clock.LayerTime.Add(delay)))
+	lateNet := &latePubSub{PublishSubsciber: nets[i], clock: clock}
+	nets[i] = lateNet
+	creationFunc()
+	late := test.procs[len(test.procs)-1]
+	late.Start()
+	test.WaitForTimedTermination(t, 30*time.Second)
+
+	require.Less(t, late.getRound(), uint32(RoundsPerIteration), "late process must terminate in the first iteration")
+	require.True(t, late.value.Equals(set1))
+	require.Empty(t, lateNet.stale(), "late process must not send messages for ended rounds")
+}
+
+// latePubSub records rounds of the messages that were published after the round ended.
+type latePubSub struct {
+	pubsub.PublishSubsciber
+	clock RoundClock
+
+	mu     sync.Mutex
+	rounds []uint32
+}
+
+func (lps *latePubSub) Publish(ctx context.Context, protocol string, data []byte) error {
+	if protocol == pubsub.HareProtocol {
+		msg, err := MessageFromBuffer(data)
+		if err != nil {
+			return fmt.Errorf("decode published data: %w", err)
+		}
+		if !time.Now().Before(lps.clock.RoundEnd(msg.Round)) {
+			lps.mu.Lock()
+			lps.rounds = append(lps.rounds, msg.Round)
+			lps.mu.Unlock()
+		}
+	}
+	return lps.PublishSubsciber.Publish(ctx, protocol, data)
+}
+
+func (lps *latePubSub) stale() []uint32 {
+	lps.mu.Lock()
+	defer lps.mu.Unlock()
+	return lps.rounds
+}
+
+func TestRestoredProcessReachesAgreement(t *testing.T) {
+	test := newConsensusTest()
+
+	cfg := config.Config{N: 10, RoundDuration: 2 * time.Second, ExpectedLeaders: 5, LimitIterations: 1000, Hdist: 20}
+	totalNodes := 10
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	mesh, err := mocknet.FullMeshLinked(totalNodes)
+	require.NoError(t, err)
+
+	test.initialSets = make([]*Set, totalNodes)
+	set1 := NewSetFromValues(types.ProposalID{1})
+	test.fill(set1, 0, totalNodes-1)
+	test.honestSets = []*Set{set1}
+	oracle := eligibility.New(logtest.New(t))
+	clock := NewSimpleRoundClock(time.Now(), cfg.WakeupDelta, cfg.RoundDuration)
+	nets := make([]pubsub.PublishSubsciber, totalNodes)
+	for i := range nets {
+		ps, err := pubsub.New(ctx, logtest.New(t), mesh.Hosts()[i], pubsub.DefaultConfig())
+		require.NoError(t, err)
+		nets[i] = ps
+	}
+	i := 0
+	creationFunc := func() {
+		sig, err := signing.NewEdSigner()
+		require.NoError(t, err)
+		tcp := createConsensusProcess(t, ctx, sig, true, cfg, oracle, nets[i], test.initialSets[i], instanceID1)
+		tcp.cp.clock = clock
+		test.procs = append(test.procs, tcp.cp)
+		test.brokers = append(test.brokers, tcp.broker)
+		i++
+	}
+	test.Create(totalNodes-1, creationFunc)
+
+	// the last process persists its state, and is killed once it begins the notify round
+	sig, err := signing.NewEdSigner()
+	require.NoError(t, err)
+	network := &restartPubSub{PublishSubsciber: nets[i]}
+	killed := createConsensusProcess(t, ctx, sig, true, cfg, oracle, network, test.initialSets[i], instanceID1)
+	killed.cp.clock = clock
+	snapshots := make(chan []byte, 2*RoundsPerIteration)
+	killed.cp.comm.persist = func(s *Snapshot) {
+		snapshots <- codec.MustEncode(s)
+		if s.Round == notifyRound {
+			<-killed.cp.ctx.Done()
+		}
+	}
+	require.NoError(t, mesh.ConnectAllButSelf())
+	test.Start()
+	killed.cp.Start()
+
+	var (
+		restarted *testCP
+		snapshot  Snapshot
+	)
+	for snapshot.Round != notifyRound {
+		select {
+		case data := <-snapshots:
+			require.NoError(t, codec.Decode(data, &snapshot))
+		case <-time.After(20 * time.Second):
+			require.FailNow(t, "timed out waiting for the notify round")
+		}
+		if snapshot.Round == commitRound {
+			// the broker of the restarted process receives messages before the process is killed,
+			// so that messages sent while the process is restored are not lost
+			restarted = createConsensusProcess(t, ctx, sig, true, cfg, oracle, network, test.initialSets[i], instanceID1)
+			restarted.cp.clock = clock
+		}
+	}
+	killed.cp.terminate()
+	killed.cp.Stop()
+	killed.broker.Close()
+	require.NotNil(t, restarted)
+	require.True(t, NewSet(snapshot.Values).Equals(set1))
+	require.NotNil(t, snapshot.Certificate)
+
+	restarted.cp.Restore(&snapshot)
+	restarted.cp.Start()
+	test.procs = append(test.procs, restarted.cp)
+	test.brokers = append(test.brokers, restarted.broker)
+	test.WaitForTimedTermination(t, 30*time.Second)
+
+	require.Less(t, restarted.cp.getRound(), uint32(RoundsPerIteration), "restored process must terminate in the first iteration")
+	require.True(t, restarted.cp.value.Equals(set1))
+}
+
+// restartPubSub delivers hare messages to all registered handlers, as if a restarted node
+// kept the same network identity.
+type restartPubSub struct {
+	pubsub.PublishSubsciber
+
+	once     sync.Once
+	mu       sync.Mutex
+	handlers []pubsub.GossipHandler
+}
+
+func (rps *restartPubSub) Register(protocol string, handler pubsub.GossipHandler) {
+	rps.mu.Lock()
+	rps.handlers = append(rps.handlers, handler)
+	rps.mu.Unlock()
+	rps.once.Do(func() {
+		rps.PublishSubsciber.Register(protocol, rps.handle)
+	})
+}
+
+func (rps *restartPubSub) handle(ctx context.Context, peer p2p.Peer, data []byte) error {
+	rps.mu.Lock()
+	handlers := rps.handlers
+	rps.mu.Unlock()
+	var err error
+	for _, handler := range handlers {
+		err = handler(ctx, peer, data)
+	}
+	return err
+}
+
 func TestAllDifferentSet(t *testing.T) {
 	test := newConsensusTest()
 
@@ -360,6 +563,63 @@ func TestAllDifferentSet(t *testing.T) {
 	test.WaitForTimedTermination(t, 45*time.Second)
 }
 
+func TestIterationsLimit(t *testing.T) {
+	test := newConsensusTest()
+
+	cfg := config.Config{N: 10, RoundDuration: time.Second, ExpectedLeaders: 5, LimitIterations: 2, Hdist: 20}
+	totalNodes := 10
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	// two disconnected halves with conflicting sets, neither of them can gather the threshold
+	first, err := mocknet.FullMeshLinked(totalNodes / 2)
+	require.NoError(t, err)
+	second, err := mocknet.FullMeshLinked(totalNodes / 2)
+	require.NoError(t, err)
+	hosts := append(first.Hosts(), second.Hosts()...)
+
+	test.initialSets = make([]*Set, totalNodes)
+	set1 := NewSetFromValues(types.ProposalID{1}, types.ProposalID{2})
+	set2 := NewSetFromValues(types.ProposalID{3}, types.ProposalID{4})
+	test.fill(set1, 0, totalNodes/2-1)
+	test.fill(set2, totalNodes/2, totalNodes-1)
+	oracle := eligibility.New(logtest.New(t))
+	i := 0
+	creationFunc := func() {
+		ps, err := pubsub.New(ctx, logtest.New(t), hosts[i], pubsub.DefaultConfig())
+		require.NoError(t, err)
+		sig, err := signing.NewEdSigner()
+		require.NoError(t, err)
+		tcp := createConsensusProcess(t, ctx, sig, true, cfg, oracle, ps, test.initialSets[i], instanceID1)
+		test.procs = append(test.procs, tcp.cp)
+		test.brokers = append(test.brokers, tcp.broker)
+		i++
+	}
+	test.Create(totalNodes, creationFunc)
+	require.NoError(t, first.ConnectAllButSelf())
+	require.NoError(t, second.ConnectAllButSelf())
+	test.Start()
+
+	for _, proc := range test.procs {
+		select {
+		case <-proc.ctx.Done():
+		case <-time.After(30 * time.Second):
+			require.FailNow(t, "timeout")
+		}
+		require.Equal(t, uint32(cfg.LimitIterations)*RoundsPerIteration, proc.getRound())
+		require.Len(t, proc.comm.wc, 1, "weak coin must be reported for the failed layer")
+		select {
+		case rst := <-proc.comm.report:
+			require.False(t, rst.completed)
+			require.Equal(t, instanceID1, rst.id)
+		default:
+			require.FailNow(t, "termination not reported")
+		}
+	}
+	for _, b := range test.brokers {
+		b.Close()
+	}
+}
+
 func TestSndDelayedDishonest(t *testing.T) {
 	if testing.Short() {
 		t.Skip()
@@ -598,6 +858,8 @@ func (eps *equivocatePubSub) Publish(ctx context.Context, protocol string, data
 		return fmt.Errorf("decode published data: %w", err)
 	}
 	msg.Values = []types.ProposalID{types.RandomProposalID()}
+	msg.ValuesHash = NewSet(msg.Values).ID()
+	msg.Delta = nil
 	msg.Signature = eps.sig.Sign(signing.HARE, msg.SignedBytes())
 	msg.SmesherID = eps.sig.NodeID()
 	if err = eps.ps.Publish(ctx, protocol, msg.Bytes()); err != nil {
@@ -610,5 +872,119 @@ func (eps *equivocatePubSub) Publish(ctx context.Context, protocol string, data
 }
 
 func (eps *equivocatePubSub) Register(protocol string, handler pubsub.GossipHandler) {
-	eps.ps.Register(protocol, handler)
+	// relay all messages, including own equivocating messages rejected by the broker
+	eps.ps.Register(protocol, func(ctx context.Context, peer p2p.Peer, msg []byte) error {
+		_ = handler(ctx, peer, msg)
+		return nil
+	})
+}
+
+// recordingPubSub delivers published messages to the handler of the same node and records them.
+type recordingPubSub struct {
+	mu        sync.Mutex
+	handler   pubsub.GossipHandler
+	published []*Message
+}
+
+func (rps *recordingPubSub) Register(_ string, handler pubsub.GossipHandler) {
+	rps.mu.Lock()
+	defer rps.mu.Unlock()
+	rps.handler = handler
+}
+
+func (rps *recordingPubSub) Publish(ctx context.Context, _ string, data []byte) error {
+	msg, err := MessageFromBuffer(data)
+	if err != nil {
+		return fmt.Errorf("decode published data: %w", err)
+	}
+	rps.mu.Lock()
+	rps.published = append(rps.published, msg)
+	handler := rps.handler
+	rps.mu.Unlock()
+	return handler(ctx, "", data)
+}
+
+// senders returns the identities that published a message in the round.
+func (rps *recordingPubSub) senders(round uint32) map[types.NodeID]uint16 {
+	rps.mu.Lock()
+	defer rps.mu.Unlock()
+	senders := map[types.NodeID]uint16{}
+	for _, msg := range rps.published {
+		if msg.Round == round {
+			senders[msg.SmesherID] = msg.Eligibility.Count
+		}
+	}
+	return senders
+}
+
+// TestConsensus_LocalSigners tests that a single process participates on behalf of every local identity, and that
+// the identities are independent committee members that reach agreement without other nodes.
+func TestConsensus_LocalSigners(t *testing.T) {
+	cfg := config.Config{N: 3, RoundDuration: 300 * time.Millisecond, ExpectedLeaders: 1, LimitIterations: 1, Hdist: 20}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var signers []*signing.EdSigner
+	for i := 0; i < 3; i++ {
+		sig, err := signing.NewEdSigner()
+		require.NoError(t, err)
+		signers = append(signers, sig)
+	}
+	// the identities take turns to be ineligible, only two of them are eligible in every round
+	ineligible := func(round uint32) int { return int(round % 3) }
+
+	network := &recordingPubSub{}
+	set := NewSetFromValues(types.ProposalID{1}, types.ProposalID{2})
+	tcp := createConsensusProcess(t, ctx, signers[0], true, cfg, eligibility.New(logtest.New(t)), network, set, instanceID1)
+	t.Cleanup(tcp.broker.Close)
+
+	ctrl := gomock.NewController(t)
+	var participants []*participant
+	for i, sig := range signers {
+		i := i
+		oracle := mocks.NewMockRolacle(ctrl)
+		oracle.EXPECT().IsIdentityActiveOnConsensusView(gomock.Any(), sig.NodeID(), instanceID1).Return(true, nil).AnyTimes()
+		oracle.EXPECT().Proof(gomock.Any(), instanceID1, gomock.Any()).DoAndReturn(
+			func(_ context.Context, _ types.LayerID, round uint32) (types.VrfSignature, error) {
+				return types.VrfSignature{byte(i + 1), byte(round)}, nil
+			}).AnyTimes()
+		oracle.EXPECT().CalcEligibility(gomock.Any(), instanceID1, gomock.Any(), gomock.Any(), sig.NodeID(), gomock.Any()).DoAndReturn(
+			func(_ context.Context, _ types.LayerID, round uint32, _ int, _ types.NodeID, _ types.VrfSignature) (uint16, error) {
+				if ineligible(round) == i {
+					return 0, nil
+				}
+				return 1, nil
+			}).AnyTimes()
+		participants = append(participants, &participant{signer: sig, oracle: oracle})
+	}
+	tcp.cp.participants = participants
+
+	tcp.cp.Start()
+	var rst report
+	select {
+	case rst = <-tcp.cp.comm.report:
+	case <-time.After(5 * time.Second):
+		require.FailNow(t, "timed out waiting for termination")
+	}
+	require.True(t, rst.completed)
+	require.True(t, set.Equals(rst.set))
+
+	for _, round := range []uint32{preRound, statusRound, proposalRound, commitRound, notifyRound} {
+		expected := map[types.NodeID]uint16{}
+		for i, sig := range signers {
+			if ineligible(round) != i {
+				expected[sig.NodeID()] = 1
+			}
+		}
+		require.Equal(t, expected, network.senders(round), "round %d", round)
+	}
+
+	// the certificate is built from the commits of the two identities eligible in the commit round
+	require.NotNil(t, rst.cert)
+	committed := map[types.NodeID]struct{}{}
+	for _, msg := range rst.cert.AggMsgs.Messages {
+		committed[msg.SmesherID] = struct{}{}
+	}
+	require.Len(t, committed, 2)
+	require.NotContains(t, committed, signers[ineligible(commitRound)].NodeID())
 }
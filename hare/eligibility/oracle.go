@@ -64,7 +64,7 @@ type Oracle struct {
 	lock           sync.Mutex
 	beacons        system.BeaconGetter
 	cdb            *datastore.CachedDB
-	vrfSigner      *signing.VRFSigner
+	vrfSigner      signing.VRFSigner
 	vrfVerifier    vrfVerifier
 	layersPerEpoch uint32
 	activesCache   activeSetCache
@@ -78,7 +78,7 @@ func New(
 	beacons system.BeaconGetter,
 	db *datastore.CachedDB,
 	vrfVerifier vrfVerifier,
-	vrfSigner *signing.VRFSigner,
+	vrfSigner signing.VRFSigner,
 	layersPerEpoch uint32,
 	cfg config.Config,
 	logger log.Log,
@@ -321,7 +321,7 @@ func (o *Oracle) Proof(ctx context.Context, layer types.LayerID, round uint32) (
 	if err != nil {
 		return types.EmptyVrfSignature, err
 	}
-	return o.vrfSigner.Sign(msg), nil
+	return o.vrfSigner.Sign(msg)
 }
 
 // Returns a map of all active node IDs in the specified layer id.
@@ -476,6 +476,20 @@ func (o *Oracle) IsIdentityActiveOnConsensusView(ctx context.Context, edID types
 	return exist, nil
 }
 
+// CommitteeSize returns the committee size for the layer. It is the configured size, unless
+// the active set has fewer identities: with a larger committee every identity is eligible
+// in every round, and the thresholds derived from the committee size can't be reached.
+func (o *Oracle) CommitteeSize(ctx context.Context, layer types.LayerID, configured int) (int, error) {
+	actives, err := o.actives(ctx, layer)
+	if err != nil {
+		return 0, err
+	}
+	if len(actives.set) < configured {
+		return len(actives.set), nil
+	}
+	return configured, nil
+}
+
 func (o *Oracle) UpdateActiveSet(epoch types.EpochID, activeSet []types.ATXID) {
 	o.Log.With().Info("received activeset update",
 		epoch,
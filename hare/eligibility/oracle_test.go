@@ -112,6 +112,14 @@ func createLayerData(tb testing.TB, cdb *datastore.CachedDB, lid types.LayerID,
 }
 
 func createActiveSet(tb testing.TB, cdb *datastore.CachedDB, lid types.LayerID, activeSet []types.ATXID) []types.NodeID {
+	units := make([]uint32, len(activeSet))
+	for i := range units {
+		units[i] = uint32(i + 1)
+	}
+	return createActiveSetWithUnits(tb, cdb, lid, activeSet, units)
+}
+
+func createActiveSetWithUnits(tb testing.TB, cdb *datastore.CachedDB, lid types.LayerID, activeSet []types.ATXID, units []uint32) []types.NodeID {
 	var miners []types.NodeID
 	for i, id := range activeSet {
 		nodeID := types.BytesToNodeID([]byte(strconv.Itoa(i)))
@@ -120,7 +128,7 @@ func createActiveSet(tb testing.TB, cdb *datastore.CachedDB, lid types.LayerID,
 			NIPostChallenge: types.NIPostChallenge{
 				PublishEpoch: lid.GetEpoch(),
 			},
-			NumUnits: uint32(i + 1),
+			NumUnits: units[i],
 		}}
 		atx.SetID(id)
 		atx.SetEffectiveNumUnits(atx.NumUnits)
@@ -290,6 +298,41 @@ func TestCalcEligibilityWithSpaceUnit(t *testing.T) {
 	}
 }
 
+func TestCalcEligibility_HeavyIdentity(t *testing.T) {
+	const committeeSize = 100
+	o := defaultOracle(t)
+	o.mVerifier.EXPECT().Verify(gomock.Any(), gomock.Any(), gomock.Any()).Return(true).AnyTimes()
+	o.mBeacon.EXPECT().GetBeacon(gomock.Any()).Return(types.Beacon{1, 0, 0, 0}, nil).AnyTimes()
+
+	lid := types.EpochID(5).FirstLayer()
+	// the first identity holds most of the weight of the epoch
+	activeSet := types.RandomActiveSet(10)
+	units := []uint32{1000, 10, 10, 10, 10, 10, 10, 10, 10, 10}
+	miners := createActiveSetWithUnits(t, o.cdb, lid.Sub(defLayersPerEpoch).GetEpoch().FirstLayer().Sub(1), activeSet, units)
+	createBlock(t, o.cdb, createBallots(t, o.cdb, lid.Sub(defLayersPerEpoch), activeSet, miners))
+
+	for i := 0; i < 10; i++ {
+		sig := types.RandomVrfSignature()
+		heavy, err := o.CalcEligibility(context.Background(), lid, 1, committeeSize, miners[0], sig)
+		require.NoError(t, err)
+		// the heavy identity holds the majority of the slots in the committee alone
+		require.Greater(t, int(heavy), committeeSize/2)
+
+		valid, err := o.Validate(context.Background(), lid, 1, committeeSize, miners[0], sig, heavy)
+		require.NoError(t, err)
+		require.True(t, valid)
+		for _, count := range []uint16{1, heavy - 1, heavy + 1} {
+			valid, err = o.Validate(context.Background(), lid, 1, committeeSize, miners[0], sig, count)
+			require.NoError(t, err)
+			require.False(t, valid, count)
+		}
+
+		light, err := o.CalcEligibility(context.Background(), lid, 1, committeeSize, miners[1], sig)
+		require.NoError(t, err)
+		require.Less(t, light, heavy)
+	}
+}
+
 func BenchmarkOracle_CalcEligibility(b *testing.B) {
 	r := require.New(b)
 
@@ -433,6 +476,30 @@ func TestOracle_IsIdentityActive(t *testing.T) {
 	require.False(t, v)
 }
 
+func TestOracle_CommitteeSize(t *testing.T) {
+	o := defaultOracle(t)
+	layer := types.LayerID(defLayersPerEpoch * 4)
+	numMiners := 30
+	createLayerData(t, o.cdb, layer.Sub(defLayersPerEpoch), numMiners)
+	for _, tc := range []struct {
+		desc       string
+		configured int
+		expected   int
+	}{
+		{desc: "active set is smaller", configured: 800, expected: numMiners},
+		{desc: "same size", configured: numMiners, expected: numMiners},
+		{desc: "active set is larger", configured: 10, expected: 10},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			size, err := o.CommitteeSize(context.Background(), layer, tc.configured)
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, size)
+		})
+	}
+	_, err := o.CommitteeSize(context.Background(), types.GetEffectiveGenesis(), 800)
+	require.ErrorIs(t, err, errEmptyActiveSet)
+}
+
 func TestBuildVRFMessage_BeaconError(t *testing.T) {
 	o := defaultOracle(t)
 	errUnknown := errors.New("unknown")
@@ -13,11 +13,13 @@ import (
 	"github.com/stretchr/testify/require"
 
 	"github.com/spacemeshos/go-spacemesh/common/types"
+	"github.com/spacemeshos/go-spacemesh/datastore"
 	"github.com/spacemeshos/go-spacemesh/hare/config"
 	"github.com/spacemeshos/go-spacemesh/hare/mocks"
 	"github.com/spacemeshos/go-spacemesh/log/logtest"
 	"github.com/spacemeshos/go-spacemesh/p2p/pubsub"
 	"github.com/spacemeshos/go-spacemesh/signing"
+	"github.com/spacemeshos/go-spacemesh/sql"
 	smocks "github.com/spacemeshos/go-spacemesh/system/mocks"
 )
 
@@ -111,9 +113,11 @@ func (m *p2pManipulator) Register(protocol string, handler pubsub.GossipHandler)
 }
 
 func (m *p2pManipulator) Publish(ctx context.Context, protocol string, payload []byte) error {
-	msg, _ := MessageFromBuffer(payload)
-	if msg.Layer == m.stalledLayer && msg.Round < 8 && msg.Round != preRound {
-		return m.err
+	if protocol == pubsub.HareProtocol {
+		msg, _ := MessageFromBuffer(payload)
+		if msg.Layer == m.stalledLayer && msg.Round < 8 && msg.Round != preRound {
+			return m.err
+		}
 	}
 
 	if err := m.nd.Publish(ctx, protocol, payload); err != nil {
@@ -127,6 +131,8 @@ type hareWithMocks struct {
 	*Hare
 	mockRoracle *mocks.MockRolacle
 	mockCoin    *mocks.MockweakCoin
+	mockTrtl    *mocks.Mocktortoise
+	mockPatrol  *mocks.MocklayerPatrol
 }
 
 func createTestHare(tb testing.TB, msh mesh, tcfg config.Config, clock *mockClock, p2p pubsub.PublishSubsciber, name string) *hareWithMocks {
@@ -148,15 +154,23 @@ func createTestHare(tb testing.TB, msh mesh, tcfg config.Config, clock *mockCloc
 	mockSyncS.EXPECT().IsBeaconSynced(gomock.Any()).Return(true).AnyTimes()
 
 	mockRoracle := mocks.NewMockRolacle(ctrl)
+	mockRoracle.EXPECT().CommitteeSize(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+		func(_ context.Context, _ types.LayerID, configured int) (int, error) {
+			return configured, nil
+		}).AnyTimes()
 	mockCoin := mocks.NewMockweakCoin(ctrl)
+	mockTrtl := mocks.NewMocktortoise(ctrl)
+	mockTrtl.EXPECT().LatestComplete().Return(types.GetEffectiveGenesis()).AnyTimes()
+	db := datastore.NewCachedDB(sql.InMemory(), logtest.New(tb))
+	fetcher := newTestSetFetcher(tb, db)
 	hare := New(
-		nil,
+		db,
+		fetcher,
 		tcfg,
 		p2p,
 		signer,
 		edVerifier,
 		signer.NodeID(),
-		make(chan LayerOutput, 100),
 		mockSyncS,
 		mockBeacons,
 		mockRoracle,
@@ -164,15 +178,20 @@ func createTestHare(tb testing.TB, msh mesh, tcfg config.Config, clock *mockCloc
 		mockStateQ,
 		clock,
 		mockCoin,
+		mockTrtl,
 		logtest.New(tb).WithName(name+"_"+signer.PublicKey().ShortString()),
 		withMesh(msh),
 	)
+	fetcher.handler = hare.HandleSyncedSet
 	p2p.Register(pubsub.HareProtocol, hare.GetHareMsgHandler())
+	p2p.Register(pubsub.HareCertificateProtocol, hare.HandleCertificate)
 
 	return &hareWithMocks{
 		Hare:        hare,
 		mockRoracle: mockRoracle,
 		mockCoin:    mockCoin,
+		mockTrtl:    mockTrtl,
+		mockPatrol:  patrol,
 	}
 }
 
@@ -271,6 +290,7 @@ func Test_multipleCPs(t *testing.T) {
 		mockMesh := mocks.NewMockmesh(ctrl)
 		mockMesh.EXPECT().GetEpochAtx(gomock.Any(), gomock.Any()).Return(&types.ActivationTxHeader{BaseTickHeight: 11, TickCount: 1}, nil).AnyTimes()
 		mockMesh.EXPECT().GetMalfeasanceProof(gomock.Any()).AnyTimes()
+		mockMesh.EXPECT().Cache().Return(datastore.NewCachedDB(sql.InMemory(), logtest.New(t))).AnyTimes()
 		for lid := types.GetEffectiveGenesis().Add(1); !lid.After(finalLyr); lid = lid.Add(1) {
 			mockMesh.EXPECT().Proposals(lid).Return(pList[lid], nil)
 			for _, p := range pList[lid] {
@@ -283,8 +303,6 @@ func Test_multipleCPs(t *testing.T) {
 	}
 
 	var pubsubs []*pubsub.PubSub
-	outputs := make([]map[types.LayerID]LayerOutput, totalNodes)
-	var outputsWaitGroup sync.WaitGroup
 	for i := 0; i < totalNodes; i++ {
 		host := mesh.Hosts()[i]
 		ps, err := pubsub.New(ctx, logtest.New(t), host, pubsub.DefaultConfig())
@@ -296,24 +314,6 @@ func Test_multipleCPs(t *testing.T) {
 		h.mockRoracle.EXPECT().CalcEligibility(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(uint16(1), nil).AnyTimes()
 		h.mockRoracle.EXPECT().Validate(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(true, nil).AnyTimes()
 		h.mockCoin.EXPECT().Set(gomock.Any(), gomock.Any()).AnyTimes()
-		outputsWaitGroup.Add(1)
-		go func(idx int) {
-			defer outputsWaitGroup.Done()
-			for {
-				select {
-				case <-ctx.Done():
-					return
-				case out, ok := <-h.blockGenCh:
-					if !ok {
-						return
-					}
-					if outputs[idx] == nil {
-						outputs[idx] = make(map[types.LayerID]LayerOutput)
-					}
-					outputs[idx][out.Layer] = out
-				}
-			}
-		}(i)
 		test.hare = append(test.hare, h.Hare)
 		e := h.Start(ctx)
 		r.NoError(e)
@@ -338,13 +338,10 @@ func Test_multipleCPs(t *testing.T) {
 	// There are 5 rounds per layer and totalCPs layers and we double for good measure.
 	test.WaitForTimedTermination(t, 2*networkDelay*5*time.Duration(totalCp))
 	for _, h := range test.hare {
-		close(h.blockGenCh)
-	}
-	outputsWaitGroup.Wait()
-	for _, out := range outputs {
 		for lid := types.GetEffectiveGenesis().Add(1); !lid.After(finalLyr); lid = lid.Add(1) {
-			require.NotNil(t, out[lid])
-			require.ElementsMatch(t, types.ToProposalIDs(pList[lid]), out[lid].Proposals)
+			out, err := h.GetResult(ctx, lid)
+			require.NoError(t, err)
+			require.ElementsMatch(t, types.ToProposalIDs(pList[lid]), out)
 		}
 	}
 	t.Cleanup(func() {
@@ -392,6 +389,7 @@ func Test_multipleCPsAndIterations(t *testing.T) {
 		mockMesh := mocks.NewMockmesh(ctrl)
 		mockMesh.EXPECT().GetEpochAtx(gomock.Any(), gomock.Any()).Return(&types.ActivationTxHeader{BaseTickHeight: 11, TickCount: 1}, nil).AnyTimes()
 		mockMesh.EXPECT().GetMalfeasanceProof(gomock.Any()).AnyTimes()
+		mockMesh.EXPECT().Cache().Return(datastore.NewCachedDB(sql.InMemory(), logtest.New(t))).AnyTimes()
 		for lid := types.GetEffectiveGenesis().Add(1); !lid.After(finalLyr); lid = lid.Add(1) {
 			mockMesh.EXPECT().Proposals(lid).Return(pList[lid], nil)
 			for _, p := range pList[lid] {
@@ -404,8 +402,6 @@ func Test_multipleCPsAndIterations(t *testing.T) {
 	}
 
 	var pubsubs []*pubsub.PubSub
-	outputs := make([]map[types.LayerID]LayerOutput, totalNodes)
-	var outputsWaitGroup sync.WaitGroup
 	for i := 0; i < totalNodes; i++ {
 		host := mesh.Hosts()[i]
 		ps, err := pubsub.New(ctx, logtest.New(t), host, pubsub.DefaultConfig())
@@ -418,24 +414,6 @@ func Test_multipleCPsAndIterations(t *testing.T) {
 		h.mockRoracle.EXPECT().CalcEligibility(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(uint16(1), nil).AnyTimes()
 		h.mockRoracle.EXPECT().Validate(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(true, nil).AnyTimes()
 		h.mockCoin.EXPECT().Set(gomock.Any(), gomock.Any()).AnyTimes()
-		outputsWaitGroup.Add(1)
-		go func(idx int) {
-			defer outputsWaitGroup.Done()
-			for {
-				select {
-				case <-ctx.Done():
-					return
-				case out, ok := <-h.blockGenCh:
-					if !ok {
-						return
-					}
-					if outputs[idx] == nil {
-						outputs[idx] = make(map[types.LayerID]LayerOutput)
-					}
-					outputs[idx][out.Layer] = out
-				}
-			}
-		}(i)
 		test.hare = append(test.hare, h.Hare)
 		e := h.Start(ctx)
 		r.NoError(e)
@@ -462,13 +440,10 @@ func Test_multipleCPsAndIterations(t *testing.T) {
 	// iterations so we increase the layer count by 1.
 	test.WaitForTimedTermination(t, 2*networkDelay*5*time.Duration(totalCp+1))
 	for _, h := range test.hare {
-		close(h.blockGenCh)
-	}
-	outputsWaitGroup.Wait()
-	for _, out := range outputs {
 		for lid := types.GetEffectiveGenesis().Add(1); !lid.After(finalLyr); lid = lid.Add(1) {
-			require.NotNil(t, out[lid])
-			require.ElementsMatch(t, types.ToProposalIDs(pList[lid]), out[lid].Proposals)
+			out, err := h.GetResult(ctx, lid)
+			require.NoError(t, err)
+			require.ElementsMatch(t, types.ToProposalIDs(pList[lid]), out)
 		}
 	}
 	t.Cleanup(func() {
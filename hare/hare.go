@@ -13,13 +13,17 @@ import (
 	"github.com/spacemeshos/go-spacemesh/codec"
 	"github.com/spacemeshos/go-spacemesh/common/types"
 	"github.com/spacemeshos/go-spacemesh/datastore"
+	"github.com/spacemeshos/go-spacemesh/events"
 	"github.com/spacemeshos/go-spacemesh/hare/config"
 	"github.com/spacemeshos/go-spacemesh/log"
 	"github.com/spacemeshos/go-spacemesh/malfeasance"
+	"github.com/spacemeshos/go-spacemesh/p2p"
 	"github.com/spacemeshos/go-spacemesh/p2p/pubsub"
 	"github.com/spacemeshos/go-spacemesh/signing"
 	"github.com/spacemeshos/go-spacemesh/sql"
-	"github.com/spacemeshos/go-spacemesh/sql/ballots"
+	"github.com/spacemeshos/go-spacemesh/sql/certificates"
+	"github.com/spacemeshos/go-spacemesh/sql/hareresults"
+	"github.com/spacemeshos/go-spacemesh/sql/harestate"
 	"github.com/spacemeshos/go-spacemesh/sql/identities"
 	"github.com/spacemeshos/go-spacemesh/sql/proposals"
 	"github.com/spacemeshos/go-spacemesh/system"
@@ -30,9 +34,8 @@ type consensusFactory func(
 	config.Config,
 	types.LayerID,
 	*Set,
-	Rolacle,
+	[]participant,
 	*EligibilityTracker,
-	*signing.EdSigner,
 	pubsub.Publisher,
 	communication,
 	RoundClock,
@@ -41,6 +44,7 @@ type consensusFactory func(
 // Consensus represents an item that acts like a consensus process.
 type Consensus interface {
 	ID() types.LayerID
+	Restore(*Snapshot)
 	Start()
 	Stop()
 }
@@ -62,13 +66,6 @@ type LayerClock interface {
 	CurrentLayer() types.LayerID
 }
 
-// LayerOutput is the output of each hare consensus process.
-type LayerOutput struct {
-	Ctx       context.Context
-	Layer     types.LayerID
-	Proposals []types.ProposalID
-}
-
 type defaultMesh struct {
 	*datastore.CachedDB
 }
@@ -78,7 +75,7 @@ func (m defaultMesh) Proposals(lid types.LayerID) ([]*types.Proposal, error) {
 }
 
 func (m defaultMesh) Ballot(bid types.BallotID) (*types.Ballot, error) {
-	return ballots.Get(m, bid)
+	return m.GetBallot(bid)
 }
 
 func (m defaultMesh) Cache() *datastore.CachedDB {
@@ -94,23 +91,70 @@ func withMesh(m mesh) Opt {
 	}
 }
 
+// WithSigner adds a local identity that participates in the consensus processes along with the primary
+// identity. The oracle provides the eligibility of the identity and must use its vrf signer.
+func WithSigner(signer signing.Signer, oracle Rolacle) Opt {
+	return func(h *Hare) {
+		h.participants = append(h.participants, participant{signer: signer, oracle: oracle})
+	}
+}
+
+// WithVersionedPublisher sets the publisher that defines the version of the messages in every layer.
+// By default the messages are of SetHashVersion and published as is.
+func WithVersionedPublisher(p versionedPublisher) Opt {
+	return func(h *Hare) {
+		h.versions = p
+	}
+}
+
+// versionedPublisher publishes the messages in the version used on the topic in the layer of the message.
+type versionedPublisher interface {
+	Version(topic string, lid types.LayerID) (byte, bool)
+	PublishLayer(ctx context.Context, topic string, lid types.LayerID, msg []byte) error
+}
+
+// unversioned publishes the messages of SetHashVersion as is.
+type unversioned struct {
+	pubsub.Publisher
+}
+
+func (unversioned) Version(string, types.LayerID) (byte, bool) {
+	return SetHashVersion, false
+}
+
+func (u unversioned) PublishLayer(ctx context.Context, topic string, _ types.LayerID, msg []byte) error {
+	return u.Publish(ctx, topic, msg)
+}
+
+// layerPublisher publishes the messages of the consensus process in the version of its layer.
+type layerPublisher struct {
+	versionedPublisher
+	lid types.LayerID
+}
+
+func (p layerPublisher) Publish(ctx context.Context, topic string, msg []byte) error {
+	return p.PublishLayer(ctx, topic, p.lid, msg)
+}
+
 // Hare is the orchestrator that starts new consensus processes and collects their output.
 type Hare struct {
 	log.Log
 	msh        mesh
 	weakCoin   weakCoin
+	tortoise   tortoise
 	config     config.Config
 	publisher  pubsub.Publisher
+	versions   versionedPublisher
 	layerClock LayerClock
 	broker     *Broker
-	sign       *signing.EdSigner
-	blockGenCh chan LayerOutput
 
 	// channel to receive MalfeasanceGossip generated by the broker and the consensus processes.
 	mchMalfeasance chan *types.MalfeasanceGossip
 
 	beacons       system.BeaconGetter
 	rolacle       Rolacle
+	participants  []participant // the local identities, starting with the primary one
+	ev            *eligibilityValidator
 	patrol        layerPatrol
 	newRoundClock func(LayerID types.LayerID) RoundClock
 
@@ -122,6 +166,7 @@ type Hare struct {
 	lastLayer  types.LayerID
 	outputs    map[types.LayerID][]types.ProposalID
 	cps        map[types.LayerID]Consensus
+	results    map[types.LayerID]chan struct{} // closed when the result for the layer is recorded
 
 	factory consensusFactory
 
@@ -136,12 +181,12 @@ type Hare struct {
 // New returns a new Hare struct.
 func New(
 	cdb *datastore.CachedDB,
+	fetcher setFetcher,
 	conf config.Config,
 	publisher pubsub.PublishSubsciber,
-	sign *signing.EdSigner,
+	sign signing.Signer,
 	edVerifier *signing.EdVerifier,
 	nid types.NodeID,
-	ch chan LayerOutput,
 	syncState system.SyncStateProvider,
 	beacons system.BeaconGetter,
 	rolacle Rolacle,
@@ -149,6 +194,7 @@ func New(
 	stateQ stateQuerier,
 	layerClock LayerClock,
 	weakCoin weakCoin,
+	trtl tortoise,
 	logger log.Log,
 	opts ...Opt,
 ) *Hare {
@@ -172,21 +218,23 @@ func New(
 
 	ev := newEligibilityValidator(rolacle, conf.N, conf.ExpectedLeaders, logger)
 	h.mchMalfeasance = make(chan *types.MalfeasanceGossip, conf.N)
-	h.sign = sign
-	h.blockGenCh = ch
+	h.participants = []participant{{signer: sign, oracle: rolacle}}
 
 	h.beacons = beacons
 	h.rolacle = rolacle
+	h.ev = ev
 	h.patrol = patrol
 	h.weakCoin = weakCoin
+	h.tortoise = trtl
 
 	h.networkDelta = conf.WakeupDelta
 	h.outputChan = make(chan report, h.config.Hdist)
 	h.wcChan = make(chan wcReport, h.config.Hdist)
 	h.outputs = make(map[types.LayerID][]types.ProposalID, h.config.Hdist) // we keep results about LayerBuffer past layers
 	h.cps = make(map[types.LayerID]Consensus, h.config.LimitConcurrent)
-	h.factory = func(ctx context.Context, conf config.Config, instanceId types.LayerID, s *Set, oracle Rolacle, et *EligibilityTracker, signing *signing.EdSigner, p2p pubsub.Publisher, comm communication, clock RoundClock) Consensus {
-		return newConsensusProcess(ctx, conf, instanceId, s, oracle, stateQ, signing, edVerifier, et, nid, p2p, comm, ev, clock, logger)
+	h.results = map[types.LayerID]chan struct{}{}
+	h.factory = func(ctx context.Context, conf config.Config, instanceId types.LayerID, s *Set, participants []participant, et *EligibilityTracker, p2p pubsub.Publisher, comm communication, clock RoundClock) Consensus {
+		return newConsensusProcess(ctx, conf, instanceId, s, participants, stateQ, edVerifier, et, p2p, comm, ev, clock, logger)
 	}
 
 	h.nodeID = nid
@@ -200,7 +248,10 @@ func New(
 	if h.msh == nil {
 		h.msh = defaultMesh{CachedDB: cdb}
 	}
-	h.broker = newBroker(h.config, h.msh, edVerifier, ev, stateQ, syncState, publisher, conf.LimitConcurrent, logger)
+	if h.versions == nil {
+		h.versions = unversioned{Publisher: publisher}
+	}
+	h.broker = newBroker(h.config, h.msh, newSetStore(cdb), h.version, fetcher, edVerifier, ev, stateQ, syncState, publisher, h.mchMalfeasance, conf.LimitConcurrent, logger)
 
 	return h
 }
@@ -210,6 +261,33 @@ func (h *Hare) GetHareMsgHandler() pubsub.GossipHandler {
 	return h.broker.HandleMessage
 }
 
+// MessageHandlers returns the gossip handlers for every version of hare protocol messages.
+func (h *Hare) MessageHandlers() pubsub.VersionedHandlers {
+	return pubsub.VersionedHandlers{
+		LegacyVersion:  h.broker.HandleLegacyMessage,
+		SetHashVersion: h.broker.HandleMessage,
+	}
+}
+
+// CertificateHandlers returns the gossip handlers for every version of hare certificates.
+func (h *Hare) CertificateHandlers() pubsub.VersionedHandlers {
+	return pubsub.VersionedHandlers{
+		LegacyVersion:  h.HandleLegacyCertificate,
+		SetHashVersion: h.HandleCertificate,
+	}
+}
+
+// version returns the version of the hare messages in the layer.
+func (h *Hare) version(lid types.LayerID) byte {
+	version, _ := h.versions.Version(pubsub.HareProtocol, lid)
+	return version
+}
+
+// HandleSyncedSet handles the proposal set referred to by hare messages that was fetched from a peer.
+func (h *Hare) HandleSyncedSet(ctx context.Context, peer p2p.Peer, data []byte) error {
+	return h.broker.handleSyncedSet(ctx, peer, data)
+}
+
 func (h *Hare) HandleEligibility(ctx context.Context, emsg *types.HareEligibilityGossip) {
 	h.broker.HandleEligibility(ctx, emsg)
 }
@@ -278,17 +356,21 @@ func (h *Hare) collectOutput(ctx context.Context, output report) error {
 		set := output.set
 		postNumProposals.Add(float64(set.Size()))
 		pids = set.ToSlice()
-		select {
-		case h.blockGenCh <- LayerOutput{
-			Ctx:       ctx,
-			Layer:     layerID,
-			Proposals: pids,
-		}:
-		case <-ctx.Done():
+		if err := h.persistCertificate(ctx, layerID, output.cert); err != nil {
+			h.WithContext(ctx).With().Error("failed to persist hare certificate", layerID, log.Err(err))
 		}
+		h.recordResult(ctx, layerID, hareresults.Completed, pids)
 	} else {
 		consensusFailCnt.Inc()
-		h.WithContext(ctx).With().Warning("hare terminated with failure", layerID)
+		h.WithContext(ctx).With().Warning("hare terminated with failure",
+			layerID,
+			log.Int("iterations", h.config.LimitIterations),
+		)
+		// the output is not reported to tortoise, so that ballots keep voting abstain on the layer
+		// instead of voting against all blocks. block generation will not happen for this layer.
+		h.patrol.CompleteHare(layerID)
+		events.ReportHareLayerFailed(layerID, h.config.LimitIterations)
+		h.recordResult(ctx, layerID, hareresults.Failed, nil)
 	}
 
 	if h.outOfBufferRange(layerID) {
@@ -328,6 +410,30 @@ func (h *Hare) onTick(ctx context.Context, lid types.LayerID) (bool, error) {
 		return false, nil
 	}
 
+	if h.getCP(lid) != nil {
+		h.With().Debug("not starting hare: instance resumed after restart", log.Context(ctx), lid)
+		return false, nil
+	}
+
+	if decided, err := h.decided(lid); err != nil {
+		return false, err
+	} else if decided {
+		h.With().Debug("not starting hare: layer already decided", log.Context(ctx), lid)
+		consensusSkipCnt.Inc()
+		h.recordResult(ctx, lid, hareresults.NotRun, nil)
+		return false, nil
+	}
+
+	if !h.broker.Synced(ctx, lid) {
+		// don't make tortoise wait for the hare output the node can't produce
+		h.With().Info("not starting hare: node not synced at this layer",
+			log.Context(ctx),
+			lid,
+		)
+		h.notRun(ctx, lid)
+		return false, nil
+	}
+
 	// call to start the calculation of active set size beforehand
 	h.eg.Go(func() error {
 		if !h.broker.Synced(ctx, lid) {
@@ -365,16 +471,34 @@ func (h *Hare) onTick(ctx context.Context, lid types.LayerID) (bool, error) {
 			log.Context(ctx),
 			lid,
 		)
+		h.notRun(ctx, lid)
 		return false, nil
 	}
 
-	var err error
+	return h.startCP(ctx, lid, clock, nil)
+}
+
+// starts the consensus process for the layer. if the snapshot is not nil, the process
+// is restored from it instead of starting with the proposals of the layer.
+func (h *Hare) startCP(ctx context.Context, lid types.LayerID, clock RoundClock, snapshot *Snapshot) (bool, error) {
 	beacon, err := h.beacons.GetBeacon(lid.GetEpoch())
 	if err != nil {
 		h.With().Info("not starting hare: beacon not retrieved",
 			log.Context(ctx),
 			lid,
 		)
+		h.recordResult(ctx, lid, hareresults.NotRun, nil)
+		return false, nil
+	}
+
+	committee, err := h.ev.committee(ctx, lid)
+	if err != nil {
+		h.With().Info("not starting hare: committee is unknown",
+			log.Context(ctx),
+			lid,
+			log.Err(err),
+		)
+		h.recordResult(ctx, lid, hareresults.NotRun, nil)
 		return false, nil
 	}
 
@@ -383,20 +507,37 @@ func (h *Hare) onTick(ctx context.Context, lid types.LayerID) (bool, error) {
 		return false, fmt.Errorf("broker register: %w", err)
 	}
 	comm := communication{
-		inbox:  ch,
-		mchOut: h.mchMalfeasance,
-		report: h.outputChan,
-		wc:     h.wcChan,
+		inbox:   ch,
+		mchOut:  h.mchMalfeasance,
+		report:  h.outputChan,
+		wc:      h.wcChan,
+		persist: h.persistSnapshot,
+	}
+	if h.version(lid) == LegacyVersion {
+		comm.legacy = true
+	} else {
+		comm.sets = h.broker.sets
+	}
+	var set *Set
+	if snapshot == nil {
+		props := goodProposals(ctx, h.Log, h.msh, h.nodeID, lid, beacon)
+		preNumProposals.Add(float64(len(props)))
+		set = NewSet(props)
+	} else {
+		set = NewSet(snapshot.Values)
+	}
+	publisher := layerPublisher{versionedPublisher: h.versions, lid: lid}
+	cp := h.factory(ctx, committee.apply(h.config), lid, set, h.participants, et, publisher, comm, clock)
+	if snapshot != nil {
+		cp.Restore(snapshot)
 	}
-	props := goodProposals(ctx, h.Log, h.msh, h.nodeID, lid, beacon)
-	preNumProposals.Add(float64(len(props)))
-	set := NewSet(props)
-	cp := h.factory(ctx, h.config, lid, set, h.rolacle, et, h.sign, h.publisher, comm, clock)
 
 	h.With().Debug("starting hare",
 		log.Context(ctx),
 		lid,
-		log.Int("num proposals", len(props)),
+		log.Int("num proposals", set.Size()),
+		log.Int("committee_size", committee.size),
+		log.Bool("restored", snapshot != nil),
 	)
 	cp.Start()
 	h.addCP(ctx, cp)
@@ -404,6 +545,29 @@ func (h *Hare) onTick(ctx context.Context, lid types.LayerID) (bool, error) {
 	return true, nil
 }
 
+// decided returns true if the layer is already verified by tortoise or has a block or hare
+// certificate, in which case hare can't change its outcome.
+func (h *Hare) decided(lid types.LayerID) (bool, error) {
+	if !h.tortoise.LatestComplete().Before(lid) {
+		return true, nil
+	}
+	if _, err := certificates.GetHareOutput(h.msh.Cache(), lid); err == nil {
+		return true, nil
+	} else if !errors.Is(err, sql.ErrNotFound) {
+		return false, fmt.Errorf("hare output %v: %w", lid, err)
+	}
+	return certificates.HasHare(h.msh.Cache(), lid)
+}
+
+// notRun reports to tortoise that hare is not run for the layer, so that tortoise doesn't
+// wait for the hare output until the layer falls out of zdist. the certificate synced later
+// for this layer overwrites the report.
+func (h *Hare) notRun(ctx context.Context, lid types.LayerID) {
+	h.tortoise.OnHareOutput(lid, types.EmptyBlockID)
+	consensusSkipCnt.Inc()
+	h.recordResult(ctx, lid, hareresults.NotRun, nil)
+}
+
 func (h *Hare) addCP(ctx context.Context, cp Consensus) {
 	h.mu.Lock()
 	defer h.mu.Unlock()
@@ -584,25 +748,40 @@ func (h *Hare) outputCollectionLoop(ctx context.Context) {
 			}
 			h.broker.Unregister(ctx, out.id)
 			h.removeCP(ctx, out.id)
+			if err := harestate.Delete(h.msh.Cache(), out.id, h.nodeID); err != nil {
+				h.With().Error("failed to delete hare state", log.Context(ctx), layerID, log.Err(err))
+			}
 		case <-h.ctx.Done():
 			return
 		}
 	}
 }
 
+// returns true if the last round within the iterations limit already ended.
+func (h *Hare) missedWindow(clock RoundClock) bool {
+	last := uint32(h.config.LimitIterations)*RoundsPerIteration - 1
+	return !time.Now().Before(clock.RoundEnd(last))
+}
+
 // listens to new layers.
 func (h *Hare) tickLoop(ctx context.Context) {
 	for layer := h.layerClock.CurrentLayer(); ; layer = layer.Add(1) {
 		ctx := log.WithNewSessionID(ctx)
 		select {
 		case <-h.layerClock.AwaitLayer(layer):
-			if time.Since(h.layerClock.LayerToTime(layer)) > h.config.WakeupDelta {
+			// the consensus process skips the rounds that already ended, the layer is skipped
+			// only if the process can't complete within the iterations limit
+			if h.missedWindow(h.newRoundClock(layer)) {
 				h.WithContext(ctx).With().Warning("missed hare window, skipping layer", layer)
+				h.recordResult(ctx, layer, hareresults.NotRun, nil)
 				continue
 			}
 			_, err := h.onTick(ctx, layer)
 			if err != nil && !errors.Is(err, context.Canceled) {
 				h.With().Warning("hare failed", log.Context(ctx), layer, log.Err(err))
+				if !h.isClosed() {
+					h.recordResult(ctx, layer, hareresults.NotRun, nil)
+				}
 			}
 			h.broker.CleanOldLayers(layer)
 		case <-h.ctx.Done():
@@ -614,8 +793,8 @@ func (h *Hare) tickLoop(ctx context.Context) {
 // process two types of messages:
 //   - HareEligibilityGossip received from MalfeasanceProof gossip handler:
 //     relay to the running consensus instance if running.
-//   - MalfeasanceProofGossip generated during the consensus processes:
-//     save it to database and broadcast to network.
+//   - MalfeasanceProofGossip generated by the broker and during the consensus processes:
+//     validate it, save it to database and broadcast to network.
 func (h *Hare) malfeasanceLoop(ctx context.Context) {
 	h.WithContext(ctx).With().Info("starting malfeasance loop")
 	for {
@@ -624,31 +803,40 @@ func (h *Hare) malfeasanceLoop(ctx context.Context) {
 			if gossip.Eligibility == nil {
 				h.WithContext(ctx).Panic("missing hare eligibility")
 			}
+			nodeID, err := malfeasance.Validate(ctx, h.Log, h.msh.Cache(), h.sigVerifier, nil, gossip)
+			if err != nil {
+				h.With().Error("invalid MalfeasanceProof",
+					log.Context(ctx),
+					gossip.Eligibility.NodeID,
+					log.Err(err),
+				)
+				continue
+			}
 			encoded, err := codec.Encode(&gossip.MalfeasanceProof)
 			if err != nil {
 				h.WithContext(ctx).With().Panic("failed to encode MalfeasanceProof", log.Err(err))
 			}
-			if err := identities.SetMalicious(h.msh.Cache(), gossip.Eligibility.NodeID, encoded); err != nil {
+			if err := identities.SetMalicious(h.msh.Cache(), nodeID, encoded); err != nil {
 				h.With().Error("failed to save MalfeasanceProof",
 					log.Context(ctx),
-					gossip.Eligibility.NodeID,
+					nodeID,
 					log.Err(err),
 				)
 				continue
 			}
-			h.msh.Cache().CacheMalfeasanceProof(gossip.Eligibility.NodeID, &gossip.MalfeasanceProof)
+			h.msh.Cache().CacheMalfeasanceProof(nodeID, &gossip.MalfeasanceProof)
 			gossipBytes, err := codec.Encode(gossip)
 			if err != nil {
 				h.With().Fatal("failed to encode MalfeasanceGossip",
 					log.Context(ctx),
-					gossip.Eligibility.NodeID,
+					nodeID,
 					log.Err(err),
 				)
 			}
 			if err = h.publisher.Publish(ctx, pubsub.MalfeasanceProof, gossipBytes); err != nil {
 				h.With().Error("failed to broadcast MalfeasanceProof",
 					log.Context(ctx),
-					gossip.Eligibility.NodeID,
+					nodeID,
 					log.Err(err),
 				)
 			}
@@ -680,6 +868,9 @@ func (h *Hare) Start(ctx context.Context) error {
 	ctxMalfLoop := log.WithNewSessionID(ctx, log.String("protocol", pubsub.HareProtocol+"_malfloop"))
 
 	h.broker.Start(ctxBroker)
+	if err := h.resume(ctx); err != nil {
+		return err
+	}
 
 	h.eg.Go(func() error {
 		h.tickLoop(ctxTickLoop)
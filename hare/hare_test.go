@@ -9,6 +9,7 @@ import (
 	"time"
 
 	"github.com/golang/mock/gomock"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/require"
 	"golang.org/x/sync/errgroup"
 
@@ -16,6 +17,7 @@ import (
 	"github.com/spacemeshos/go-spacemesh/common/types"
 	"github.com/spacemeshos/go-spacemesh/datastore"
 	"github.com/spacemeshos/go-spacemesh/eligibility"
+	"github.com/spacemeshos/go-spacemesh/events"
 	"github.com/spacemeshos/go-spacemesh/hare/config"
 	"github.com/spacemeshos/go-spacemesh/hare/mocks"
 	"github.com/spacemeshos/go-spacemesh/log/logtest"
@@ -23,15 +25,19 @@ import (
 	pubsubmocks "github.com/spacemeshos/go-spacemesh/p2p/pubsub/mocks"
 	"github.com/spacemeshos/go-spacemesh/signing"
 	"github.com/spacemeshos/go-spacemesh/sql"
+	"github.com/spacemeshos/go-spacemesh/sql/certificates"
+	"github.com/spacemeshos/go-spacemesh/sql/harestate"
+	"github.com/spacemeshos/go-spacemesh/sql/identities"
 	smocks "github.com/spacemeshos/go-spacemesh/system/mocks"
 )
 
 type mockConsensusProcess struct {
-	started chan struct{}
-	t       chan report
-	w       chan wcReport
-	id      types.LayerID
-	set     *Set
+	started  chan struct{}
+	t        chan report
+	w        chan wcReport
+	id       types.LayerID
+	set      *Set
+	restored *Snapshot
 }
 
 func (mcp *mockConsensusProcess) Start() {
@@ -42,6 +48,10 @@ func (mcp *mockConsensusProcess) Start() {
 
 func (mcp *mockConsensusProcess) Stop() {}
 
+func (mcp *mockConsensusProcess) Restore(s *Snapshot) {
+	mcp.restored = s
+}
+
 func (mcp *mockConsensusProcess) ID() types.LayerID {
 	return mcp.id
 }
@@ -51,7 +61,7 @@ func (mcp *mockConsensusProcess) SetInbox(_ any) {
 
 var _ Consensus = (*mockConsensusProcess)(nil)
 
-func newMockConsensusProcess(_ config.Config, instanceID types.LayerID, s *Set, _ Rolacle, _ *signing.EdSigner, _ pubsub.Publisher, outputChan chan report, wcChan chan wcReport, started chan struct{}) *mockConsensusProcess {
+func newMockConsensusProcess(_ config.Config, instanceID types.LayerID, s *Set, _ []participant, _ pubsub.Publisher, outputChan chan report, wcChan chan wcReport, started chan struct{}) *mockConsensusProcess {
 	mcp := new(mockConsensusProcess)
 	mcp.started = started
 	mcp.id = instanceID
@@ -122,18 +132,19 @@ func TestHare_New(t *testing.T) {
 	cfg := config.Config{N: 10, RoundDuration: 2 * time.Second, ExpectedLeaders: 5, LimitIterations: 1000, LimitConcurrent: 1000, Hdist: 20}
 	h := New(
 		datastore.NewCachedDB(sql.InMemory(), logtest.New(t)),
+		mocks.NewMocksetFetcher(ctrl),
 		cfg,
 		noopPubSub(t),
 		signer,
 		edVerifier,
 		signer.NodeID(),
-		make(chan LayerOutput, 1),
 		smocks.NewMockSyncStateProvider(ctrl), smocks.NewMockBeaconGetter(ctrl),
 		eligibility.New(logger),
 		mocks.NewMocklayerPatrol(ctrl),
 		mocks.NewMockstateQuerier(ctrl),
 		newMockClock(),
 		mocks.NewMockweakCoin(ctrl),
+		mocks.NewMocktortoise(ctrl),
 		logger,
 		withMesh(mocks.NewMockmesh(ctrl)),
 	)
@@ -141,13 +152,17 @@ func TestHare_New(t *testing.T) {
 }
 
 func TestHare_Start(t *testing.T) {
-	h := createTestHare(t, newMockMesh(t), config.DefaultConfig(), newMockClock(), noopPubSub(t), t.Name())
+	mockMesh := newMockMesh(t)
+	mockMesh.EXPECT().Cache().Return(datastore.NewCachedDB(sql.InMemory(), logtest.New(t))).AnyTimes()
+	h := createTestHare(t, mockMesh, config.DefaultConfig(), newMockClock(), noopPubSub(t), t.Name())
 	require.NoError(t, h.Start(context.Background()))
 	h.Close()
 }
 
 func TestHare_collectOutputAndGetResult(t *testing.T) {
-	h := createTestHare(t, newMockMesh(t), config.DefaultConfig(), newMockClock(), noopPubSub(t), t.Name())
+	mockMesh := newMockMesh(t)
+	mockMesh.EXPECT().Cache().Return(datastore.NewCachedDB(sql.InMemory(), logtest.New(t))).AnyTimes()
+	h := createTestHare(t, mockMesh, config.DefaultConfig(), newMockClock(), noopPubSub(t), t.Name())
 
 	lyrID := types.LayerID(10)
 	res, err := h.getResult(lyrID)
@@ -157,9 +172,9 @@ func TestHare_collectOutputAndGetResult(t *testing.T) {
 	pids := []types.ProposalID{types.RandomProposalID(), types.RandomProposalID(), types.RandomProposalID()}
 	set := NewSetFromValues(pids...)
 	require.NoError(t, h.collectOutput(context.Background(), report{id: lyrID, set: set, completed: true}))
-	lo := <-h.blockGenCh
-	require.Equal(t, lyrID, lo.Layer)
-	require.ElementsMatch(t, pids, lo.Proposals)
+	lo, err := h.GetResult(context.Background(), lyrID)
+	require.NoError(t, err)
+	require.ElementsMatch(t, pids, lo)
 
 	res, err = h.getResult(lyrID)
 	require.NoError(t, err)
@@ -171,7 +186,9 @@ func TestHare_collectOutputAndGetResult(t *testing.T) {
 }
 
 func TestHare_collectOutputGetResult_TerminateTooLate(t *testing.T) {
-	h := createTestHare(t, newMockMesh(t), config.DefaultConfig(), newMockClock(), noopPubSub(t), t.Name())
+	mockMesh := newMockMesh(t)
+	mockMesh.EXPECT().Cache().Return(datastore.NewCachedDB(sql.InMemory(), logtest.New(t))).AnyTimes()
+	h := createTestHare(t, mockMesh, config.DefaultConfig(), newMockClock(), noopPubSub(t), t.Name())
 
 	lyrID := types.LayerID(10)
 	res, err := h.getResult(lyrID)
@@ -184,17 +201,48 @@ func TestHare_collectOutputGetResult_TerminateTooLate(t *testing.T) {
 	set := NewSetFromValues(pids...)
 	err = h.collectOutput(context.Background(), report{id: lyrID, set: set, completed: true})
 	require.Equal(t, ErrTooLate, err)
-	lo := <-h.blockGenCh
-	require.Equal(t, lyrID, lo.Layer)
-	require.ElementsMatch(t, pids, lo.Proposals)
+	lo, err := h.GetResult(context.Background(), lyrID)
+	require.NoError(t, err)
+	require.ElementsMatch(t, pids, lo)
 
 	res, err = h.getResult(lyrID)
 	require.Equal(t, err, errTooOld)
 	require.Empty(t, res)
 }
 
+func TestHare_collectOutput_IterationsLimit(t *testing.T) {
+	events.InitializeReporter()
+	t.Cleanup(events.CloseEventReporter)
+	sub, err := events.Subscribe[events.HareLayerFailed]()
+	require.NoError(t, err)
+
+	mockMesh := newMockMesh(t)
+	mockMesh.EXPECT().Cache().Return(datastore.NewCachedDB(sql.InMemory(), logtest.New(t))).AnyTimes()
+	h := createTestHare(t, mockMesh, config.DefaultConfig(), newMockClock(), noopPubSub(t), t.Name())
+	lyrID := types.LayerID(10)
+	// no output is reported to tortoise, so that ballots abstain on the layer
+	h.mockTrtl.EXPECT().OnHareOutput(gomock.Any(), gomock.Any()).Times(0)
+	h.mockPatrol.EXPECT().CompleteHare(lyrID)
+
+	set := NewSetFromValues(types.RandomProposalID())
+	require.NoError(t, h.collectOutput(context.Background(), report{id: lyrID, set: set, completed: false}))
+	_, err = h.GetResult(context.Background(), lyrID)
+	require.ErrorIs(t, err, ErrHareFailed)
+	res, err := h.getResult(lyrID)
+	require.NoError(t, err)
+	require.Empty(t, res)
+
+	select {
+	case ev := <-sub.Out():
+		require.Equal(t, events.HareLayerFailed{Layer: lyrID, Iterations: h.config.LimitIterations}, ev)
+	case <-time.After(time.Second):
+		require.Fail(t, "timeout")
+	}
+}
+
 func TestHare_OutputCollectionLoop(t *testing.T) {
 	mockMesh := newMockMesh(t)
+	mockMesh.EXPECT().Cache().Return(datastore.NewCachedDB(sql.InMemory(), logtest.New(t))).AnyTimes()
 	h := createTestHare(t, mockMesh, config.DefaultConfig(), newMockClock(), noopPubSub(t), t.Name())
 	require.NoError(t, h.Start(context.Background()))
 
@@ -206,9 +254,9 @@ func TestHare_OutputCollectionLoop(t *testing.T) {
 	h.mockCoin.EXPECT().Set(lyrID, false)
 	h.wcChan <- wcReport{id: lyrID, coinflip: false}
 	h.outputChan <- report{id: lyrID, set: NewEmptySet(0), completed: true}
-	lo := <-h.blockGenCh
-	require.Equal(t, lyrID, lo.Layer)
-	require.Empty(t, lo.Proposals)
+	lo, err := h.GetResult(context.Background(), lyrID)
+	require.NoError(t, err)
+	require.Empty(t, lo)
 	require.Eventually(t, func() bool {
 		return len(h.wcChan) == 0
 	}, time.Second, 100*time.Millisecond)
@@ -217,6 +265,7 @@ func TestHare_OutputCollectionLoop(t *testing.T) {
 func TestHare_malfeasanceLoop(t *testing.T) {
 	mpubsub := pubsubmocks.NewMockPublishSubsciber(gomock.NewController(t))
 	mpubsub.EXPECT().Register(pubsub.HareProtocol, gomock.Any())
+	mpubsub.EXPECT().Register(pubsub.HareCertificateProtocol, gomock.Any())
 	mockMesh := newMockMesh(t)
 	h := createTestHare(t, mockMesh, config.DefaultConfig(), newMockClock(), mpubsub, t.Name())
 
@@ -269,6 +318,22 @@ func TestHare_malfeasanceLoop(t *testing.T) {
 			},
 		},
 	}
+
+	// messages from different rounds are not equivocation, proof is neither stored nor published
+	invalid := &types.HareProof{Messages: proof.Messages}
+	invalid.Messages[1].InnerMsg.Round = round + RoundsPerIteration
+	invalid.Messages[1].Signature = sig.Sign(signing.HARE, invalid.Messages[1].SignedBytes())
+	h.mchMalfeasance <- &types.MalfeasanceGossip{
+		MalfeasanceProof: types.MalfeasanceProof{
+			Layer: lid,
+			Proof: types.Proof{
+				Type: types.HareEquivocation,
+				Data: invalid,
+			},
+		},
+		Eligibility: &types.HareEligibilityGossip{Layer: lid, Round: round, NodeID: sig.NodeID()},
+	}
+
 	h.mchMalfeasance <- &gossip
 	data, err := codec.Encode(&gossip)
 	require.NoError(t, err)
@@ -289,6 +354,9 @@ func TestHare_malfeasanceLoop(t *testing.T) {
 			return false
 		}
 	}, time.Second, 100*time.Millisecond)
+	stored, err := identities.GetMalfeasanceProof(cdb, sig.NodeID())
+	require.NoError(t, err)
+	require.Equal(t, proof, stored.Proof.Data)
 }
 
 func TestHare_onTick(t *testing.T) {
@@ -302,14 +370,15 @@ func TestHare_onTick(t *testing.T) {
 
 	clock := newMockClock()
 	mockMesh := newMockMesh(t)
+	mockMesh.EXPECT().Cache().Return(datastore.NewCachedDB(sql.InMemory(), logtest.New(t))).AnyTimes()
 	h := createTestHare(t, mockMesh, cfg, clock, noopPubSub(t), t.Name())
 
 	h.networkDelta = 0
 	createdChan := make(chan struct{}, 1)
 	startedChan := make(chan struct{}, 1)
 	var nmcp *mockConsensusProcess
-	h.factory = func(ctx context.Context, cfg config.Config, instanceId types.LayerID, s *Set, oracle Rolacle, et *EligibilityTracker, sig *signing.EdSigner, p2p pubsub.Publisher, comm communication, clock RoundClock) Consensus {
-		nmcp = newMockConsensusProcess(cfg, instanceId, s, oracle, sig, p2p, comm.report, comm.wc, startedChan)
+	h.factory = func(ctx context.Context, cfg config.Config, instanceId types.LayerID, s *Set, participants []participant, et *EligibilityTracker, p2p pubsub.Publisher, comm communication, clock RoundClock) Consensus {
+		nmcp = newMockConsensusProcess(cfg, instanceId, s, participants, p2p, comm.report, comm.wc, startedChan)
 		close(createdChan)
 		return nmcp
 	}
@@ -354,9 +423,9 @@ func TestHare_onTick(t *testing.T) {
 	})
 	require.NoError(t, eg.Wait())
 
-	out := <-h.blockGenCh
-	require.Equal(t, lyrID, out.Layer)
-	require.ElementsMatch(t, types.ToProposalIDs(pList), out.Proposals)
+	out, err := h.GetResult(ctx, lyrID)
+	require.NoError(t, err)
+	require.ElementsMatch(t, types.ToProposalIDs(pList), out)
 
 	lyrID = lyrID.Add(1)
 	// consensus process is closed, should not process any tick
@@ -381,6 +450,7 @@ func TestHare_onTick_notMining(t *testing.T) {
 	cfg.Hdist = 1
 	clock := newMockClock()
 	mockMesh := newMockMesh(t)
+	mockMesh.EXPECT().Cache().Return(datastore.NewCachedDB(sql.InMemory(), logtest.New(t))).AnyTimes()
 	h := createTestHare(t, mockMesh, cfg, clock, noopPubSub(t), t.Name())
 
 	h.networkDelta = 0
@@ -388,8 +458,8 @@ func TestHare_onTick_notMining(t *testing.T) {
 	startedChan := make(chan struct{}, 1)
 	wcSaved := make(chan struct{}, 1)
 	var nmcp *mockConsensusProcess
-	h.factory = func(ctx context.Context, cfg config.Config, instanceId types.LayerID, s *Set, oracle Rolacle, et *EligibilityTracker, sig *signing.EdSigner, p2p pubsub.Publisher, comm communication, clock RoundClock) Consensus {
-		nmcp = newMockConsensusProcess(cfg, instanceId, s, oracle, sig, p2p, comm.report, comm.wc, startedChan)
+	h.factory = func(ctx context.Context, cfg config.Config, instanceId types.LayerID, s *Set, participants []participant, et *EligibilityTracker, p2p pubsub.Publisher, comm communication, clock RoundClock) Consensus {
+		nmcp = newMockConsensusProcess(cfg, instanceId, s, participants, p2p, comm.report, comm.wc, startedChan)
 		close(createdChan)
 		return nmcp
 	}
@@ -426,9 +496,9 @@ func TestHare_onTick_notMining(t *testing.T) {
 	}()
 
 	wg.Wait()
-	out := <-h.blockGenCh
-	require.Equal(t, lyrID, out.Layer)
-	require.ElementsMatch(t, types.ToProposalIDs(pList), out.Proposals)
+	out, err := h.GetResult(context.Background(), lyrID)
+	require.NoError(t, err)
+	require.ElementsMatch(t, types.ToProposalIDs(pList), out)
 	select {
 	case <-wcSaved:
 	case <-time.After(10 * time.Second):
@@ -436,10 +506,45 @@ func TestHare_onTick_notMining(t *testing.T) {
 	}
 }
 
+func TestHare_onTick_SmallActiveSet(t *testing.T) {
+	lyr := types.GetEffectiveGenesis().Add(1)
+	cfg := config.DefaultConfig()
+	cfg.N = 800
+	cfg.ExpectedLeaders = 5
+	const actives = 3
+
+	mockMesh := newMockMesh(t)
+	mockMesh.EXPECT().Cache().Return(datastore.NewCachedDB(sql.InMemory(), logtest.New(t))).AnyTimes()
+	h := createTestHare(t, mockMesh, cfg, newMockClock(), noopPubSub(t), t.Name())
+	mo := mocks.NewMockRolacle(gomock.NewController(t))
+	mo.EXPECT().CommitteeSize(gomock.Any(), lyr, cfg.N).Return(actives, nil).AnyTimes()
+	h.ev = newEligibilityValidator(mo, cfg.N, cfg.ExpectedLeaders, logtest.New(t))
+
+	var created config.Config
+	h.factory = func(ctx context.Context, cfg config.Config, instanceId types.LayerID, s *Set, participants []participant, et *EligibilityTracker, p2p pubsub.Publisher, comm communication, clock RoundClock) Consensus {
+		created = cfg
+		return newMockConsensusProcess(cfg, instanceId, s, participants, p2p, comm.report, comm.wc, make(chan struct{}, 1))
+	}
+	h.mockRoracle.EXPECT().IsIdentityActiveOnConsensusView(gomock.Any(), gomock.Any(), lyr).Return(true, nil).MaxTimes(1)
+	mockMesh.EXPECT().GetEpochAtx(lyr.GetEpoch(), h.nodeID).Return(nil, sql.ErrNotFound)
+	mockMesh.EXPECT().Proposals(lyr).Return(nil, nil)
+	h.broker.Start(context.Background())
+	defer h.broker.Close()
+
+	started, err := h.onTick(context.Background(), lyr)
+	require.NoError(t, err)
+	require.True(t, started)
+	require.Equal(t, actives, created.N)
+	require.Equal(t, actives, created.ExpectedLeaders)
+	require.Equal(t, cfg.RoundDuration, created.RoundDuration)
+}
+
 func TestHare_onTick_NoBeacon(t *testing.T) {
 	lyr := types.LayerID(199)
 
-	h := createTestHare(t, newMockMesh(t), config.DefaultConfig(), newMockClock(), noopPubSub(t), t.Name())
+	mockMesh := newMockMesh(t)
+	mockMesh.EXPECT().Cache().Return(datastore.NewCachedDB(sql.InMemory(), logtest.New(t))).AnyTimes()
+	h := createTestHare(t, mockMesh, config.DefaultConfig(), newMockClock(), noopPubSub(t), t.Name())
 	h.mockRoracle.EXPECT().IsIdentityActiveOnConsensusView(gomock.Any(), gomock.Any(), lyr).Return(true, nil).MaxTimes(1)
 	mockBeacons := smocks.NewMockBeaconGetter(gomock.NewController(t))
 	h.beacons = mockBeacons
@@ -455,24 +560,126 @@ func TestHare_onTick_NoBeacon(t *testing.T) {
 func TestHare_onTick_NotSynced(t *testing.T) {
 	lyr := types.LayerID(199)
 
-	h := createTestHare(t, newMockMesh(t), config.DefaultConfig(), newMockClock(), noopPubSub(t), t.Name())
+	mockMesh := newMockMesh(t)
+	mockMesh.EXPECT().Cache().Return(datastore.NewCachedDB(sql.InMemory(), logtest.New(t))).AnyTimes()
+	h := createTestHare(t, mockMesh, config.DefaultConfig(), newMockClock(), noopPubSub(t), t.Name())
 	mockSyncS := smocks.NewMockSyncStateProvider(gomock.NewController(t))
 	h.broker.nodeSyncState = mockSyncS
 	h.broker.Start(context.Background())
 
-	mockSyncS.EXPECT().IsSynced(gomock.Any()).Return(false).Times(2)
+	mockSyncS.EXPECT().IsSynced(gomock.Any()).Return(false).Times(1)
+	h.mockTrtl.EXPECT().OnHareOutput(lyr, types.EmptyBlockID)
 	started, err := h.onTick(context.Background(), lyr)
 	require.NoError(t, err)
 	require.False(t, started)
 
-	mockSyncS.EXPECT().IsSynced(gomock.Any()).Return(true).Times(2)
-	mockSyncS.EXPECT().IsBeaconSynced(gomock.Any()).Return(false).Times(2)
+	mockSyncS.EXPECT().IsSynced(gomock.Any()).Return(true).Times(1)
+	mockSyncS.EXPECT().IsBeaconSynced(gomock.Any()).Return(false).Times(1)
+	h.mockTrtl.EXPECT().OnHareOutput(lyr, types.EmptyBlockID)
 	started, err = h.onTick(context.Background(), lyr)
 	require.NoError(t, err)
 	require.False(t, started)
 	h.Close()
 }
 
+func TestHare_onTick_Backlog(t *testing.T) {
+	// the node restarts in the middle of the epoch with 20 layers of backlog:
+	// tortoise already verified the first layers, certificates for the next ones are synced,
+	// and the node is not synced for the rest.
+	const (
+		backlog   = 20
+		verified  = 5
+		certified = 5
+	)
+	first := types.GetEffectiveGenesis().Add(types.GetLayersPerEpoch()/2 + 1)
+
+	cdb := datastore.NewCachedDB(sql.InMemory(), logtest.New(t))
+	mockMesh := newMockMesh(t)
+	mockMesh.EXPECT().Cache().Return(cdb).AnyTimes()
+	h := createTestHare(t, mockMesh, config.DefaultConfig(), newMockClock(), noopPubSub(t), t.Name())
+	ctrl := gomock.NewController(t)
+	mockTrtl := mocks.NewMocktortoise(ctrl)
+	h.tortoise = mockTrtl
+	mockTrtl.EXPECT().LatestComplete().Return(first.Add(verified - 1)).AnyTimes()
+	// patrol has no expectations: the syncer must stay in charge of all layers in the backlog
+	h.patrol = mocks.NewMocklayerPatrol(ctrl)
+	mockSyncS := smocks.NewMockSyncStateProvider(ctrl)
+	mockSyncS.EXPECT().IsSynced(gomock.Any()).Return(false).Times(backlog - verified - certified)
+	h.broker.nodeSyncState = mockSyncS
+	h.broker.Start(context.Background())
+	defer h.broker.Close()
+
+	for lid := first.Add(verified); lid < first.Add(verified+certified); lid++ {
+		require.NoError(t, certificates.SetHareOutput(cdb, lid, types.RandomBlockID()))
+	}
+	for lid := first.Add(verified + certified); lid < first.Add(backlog); lid++ {
+		mockTrtl.EXPECT().OnHareOutput(lid, types.EmptyBlockID)
+	}
+
+	before := testutil.ToFloat64(consensusSkipCnt)
+	for lid := first; lid < first.Add(backlog); lid++ {
+		started, err := h.onTick(context.Background(), lid)
+		require.NoError(t, err)
+		require.False(t, started)
+	}
+	require.Equal(t, float64(backlog), testutil.ToFloat64(consensusSkipCnt)-before)
+	require.Empty(t, h.cps)
+}
+
+func TestHare_Resume(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.LimitIterations = 2
+	cfg.RoundDuration = time.Second
+
+	expired := types.GetEffectiveGenesis().Add(1)
+	current := expired.Add(1)
+	decided := current.Add(1)
+	clock := newMockClock()
+	clock.layerTime[expired] = time.Now().Add(-time.Hour)
+	clock.layerTime[current] = time.Now()
+	clock.layerTime[decided] = time.Now()
+
+	cdb := datastore.NewCachedDB(sql.InMemory(), logtest.New(t))
+	mockMesh := newMockMesh(t)
+	mockMesh.EXPECT().Cache().Return(cdb).AnyTimes()
+	h := createTestHare(t, mockMesh, cfg, clock, noopPubSub(t), t.Name())
+	var resumed []*mockConsensusProcess
+	h.factory = func(ctx context.Context, cfg config.Config, instanceId types.LayerID, s *Set, participants []participant, et *EligibilityTracker, p2p pubsub.Publisher, comm communication, clock RoundClock) Consensus {
+		mcp := newMockConsensusProcess(cfg, instanceId, s, participants, p2p, comm.report, comm.wc, make(chan struct{}))
+		resumed = append(resumed, mcp)
+		return mcp
+	}
+
+	values := []types.ProposalID{{1}, {2}}
+	for _, lid := range []types.LayerID{expired, current, decided} {
+		snapshot := &Snapshot{Layer: lid, Round: commitRound, CommittedRound: preRound, Values: values}
+		require.NoError(t, harestate.Set(cdb, lid, h.nodeID, codec.MustEncode(snapshot)))
+	}
+	require.NoError(t, certificates.AddHare(cdb, decided, []byte{1}))
+
+	h.broker.Start(context.Background())
+	defer h.broker.Close()
+	require.NoError(t, h.resume(context.Background()))
+
+	require.Len(t, resumed, 1)
+	require.Equal(t, current, resumed[0].id)
+	require.True(t, resumed[0].set.Equals(NewSetFromValues(values...)))
+	require.NotNil(t, resumed[0].restored)
+	require.Equal(t, uint32(commitRound), resumed[0].restored.Round)
+	require.NotNil(t, h.getCP(current))
+
+	_, err := harestate.Get(cdb, current, h.nodeID)
+	require.NoError(t, err)
+	for _, lid := range []types.LayerID{expired, decided} {
+		_, err := harestate.Get(cdb, lid, h.nodeID)
+		require.ErrorIs(t, err, sql.ErrNotFound)
+	}
+
+	started, err := h.onTick(context.Background(), current)
+	require.NoError(t, err)
+	require.False(t, started)
+}
+
 func TestHare_goodProposal(t *testing.T) {
 	beacon := types.RandomBeacon()
 	nodeBeacon := types.RandomBeacon()
@@ -582,7 +789,9 @@ func TestHare_goodProposal(t *testing.T) {
 }
 
 func TestHare_outputBuffer(t *testing.T) {
-	h := createTestHare(t, newMockMesh(t), config.DefaultConfig(), newMockClock(), noopPubSub(t), t.Name())
+	mockMesh := newMockMesh(t)
+	mockMesh.EXPECT().Cache().Return(datastore.NewCachedDB(sql.InMemory(), logtest.New(t))).AnyTimes()
+	h := createTestHare(t, mockMesh, config.DefaultConfig(), newMockClock(), noopPubSub(t), t.Name())
 	var lyr types.LayerID
 	for i := uint32(1); i <= h.config.Hdist; i++ {
 		lyr = types.GetEffectiveGenesis().Add(i)
@@ -609,7 +818,9 @@ func TestHare_outputBuffer(t *testing.T) {
 }
 
 func TestHare_IsTooLate(t *testing.T) {
-	h := createTestHare(t, newMockMesh(t), config.DefaultConfig(), newMockClock(), noopPubSub(t), t.Name())
+	mockMesh := newMockMesh(t)
+	mockMesh.EXPECT().Cache().Return(datastore.NewCachedDB(sql.InMemory(), logtest.New(t))).AnyTimes()
+	h := createTestHare(t, mockMesh, config.DefaultConfig(), newMockClock(), noopPubSub(t), t.Name())
 	var lyr types.LayerID
 	for i := uint32(1); i <= h.config.Hdist*2; i++ {
 		lyr = types.GetEffectiveGenesis().Add(i)
@@ -632,7 +843,9 @@ func TestHare_IsTooLate(t *testing.T) {
 }
 
 func TestHare_oldestInBuffer(t *testing.T) {
-	h := createTestHare(t, newMockMesh(t), config.DefaultConfig(), newMockClock(), noopPubSub(t), t.Name())
+	mockMesh := newMockMesh(t)
+	mockMesh.EXPECT().Cache().Return(datastore.NewCachedDB(sql.InMemory(), logtest.New(t))).AnyTimes()
+	h := createTestHare(t, mockMesh, config.DefaultConfig(), newMockClock(), noopPubSub(t), t.Name())
 	var lyr types.LayerID
 	for i := uint32(1); i <= h.config.Hdist; i++ {
 		lyr = types.GetEffectiveGenesis().Add(i)
@@ -668,45 +881,45 @@ func TestHare_oldestInBuffer(t *testing.T) {
 // regardless of whether it succeeds or fails.
 func TestHare_WeakCoin(t *testing.T) {
 	mockMesh := newMockMesh(t)
+	mockMesh.EXPECT().Cache().Return(datastore.NewCachedDB(sql.InMemory(), logtest.New(t))).AnyTimes()
 	h := createTestHare(t, mockMesh, config.DefaultConfig(), newMockClock(), noopPubSub(t), t.Name())
 	layerID := types.LayerID(10)
 	h.setLastLayer(layerID)
 
 	require.NoError(t, h.Start(context.Background()))
 	defer h.Close()
-	waitForMsg := func() error {
-		tmr := time.NewTimer(time.Second)
-		select {
-		case <-tmr.C:
-			return errors.New("timeout")
-		case <-h.blockGenCh:
-			return nil
-		}
+	waitForResult := func(expected error) {
+		require.Eventually(t, func() bool {
+			_, err := h.loadResult(layerID)
+			return errors.Is(err, expected)
+		}, time.Second, 10*time.Millisecond)
 	}
 
 	set := NewSet([]types.ProposalID{{1}, {2}})
+	// weak coin is recorded for the incomplete instances as well
+	h.mockPatrol.EXPECT().CompleteHare(layerID).Times(2)
 
 	// complete + coin flip true
 	h.mockCoin.EXPECT().Set(layerID, true)
 	h.outputChan <- report{id: layerID, set: set, completed: true}
 	h.wcChan <- wcReport{id: layerID, coinflip: true}
-	require.NoError(t, waitForMsg())
+	waitForResult(nil)
 
 	// incomplete + coin flip true
 	h.mockCoin.EXPECT().Set(layerID, true)
 	h.outputChan <- report{id: layerID, set: set, completed: false}
 	h.wcChan <- wcReport{id: layerID, coinflip: true}
-	require.Error(t, waitForMsg())
+	waitForResult(ErrHareFailed)
 
 	// complete + coin flip false
 	h.mockCoin.EXPECT().Set(layerID, false)
 	h.outputChan <- report{id: layerID, set: set, completed: true}
 	h.wcChan <- wcReport{id: layerID, coinflip: false}
-	require.NoError(t, waitForMsg())
+	waitForResult(nil)
 
 	// incomplete + coin flip false
 	h.mockCoin.EXPECT().Set(layerID, false)
 	h.outputChan <- report{id: layerID, set: set, completed: false}
 	h.wcChan <- wcReport{id: layerID, coinflip: false}
-	require.Error(t, waitForMsg())
+	waitForResult(ErrHareFailed)
 }
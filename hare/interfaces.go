@@ -5,12 +5,14 @@ import (
 
 	"github.com/spacemeshos/go-spacemesh/common/types"
 	"github.com/spacemeshos/go-spacemesh/datastore"
+	"github.com/spacemeshos/go-spacemesh/system"
 )
 
 //go:generate mockgen -package=mocks -destination=./mocks/mocks.go -source=./interfaces.go
 
 type layerPatrol interface {
 	SetHareInCharge(types.LayerID)
+	CompleteHare(types.LayerID)
 }
 
 // Rolacle is the roles oracle provider.
@@ -19,6 +21,7 @@ type Rolacle interface {
 	CalcEligibility(context.Context, types.LayerID, uint32, int, types.NodeID, types.VrfSignature) (uint16, error)
 	Proof(context.Context, types.LayerID, uint32) (types.VrfSignature, error)
 	IsIdentityActiveOnConsensusView(context.Context, types.NodeID, types.LayerID) (bool, error)
+	CommitteeSize(context.Context, types.LayerID, int) (int, error)
 }
 
 // stateQuerier provides a query to check if an Ed public key is active on the current consensus view.
@@ -37,6 +40,16 @@ type mesh interface {
 	Cache() *datastore.CachedDB
 }
 
+type setFetcher interface {
+	system.HareSetFetcher
+	system.PeerTracker
+}
+
 type weakCoin interface {
 	Set(types.LayerID, bool) error
 }
+
+type tortoise interface {
+	LatestComplete() types.LayerID
+	OnHareOutput(types.LayerID, types.BlockID)
+}
@@ -0,0 +1,178 @@
+package hare
+
+import (
+	"fmt"
+
+	"github.com/spacemeshos/go-spacemesh/codec"
+	"github.com/spacemeshos/go-spacemesh/common/types"
+)
+
+//go:generate scalegen -types LegacyMessage,LegacyInnerMessage,LegacyAggregatedMessages,LegacyCertificate,LegacyCertificateMessage
+
+const (
+	// LegacyVersion is the version of the hare messages that carry the proposal sets.
+	// The signature covers the sets.
+	LegacyVersion byte = 0
+	// SetHashVersion is the version of the hare messages that may refer to the proposal sets by hash.
+	// The signature covers the hash of the sets.
+	SetHashVersion byte = 1
+)
+
+// LegacyMessage is the encoding of Message before SetHashVersion.
+type LegacyMessage struct {
+	InnerMessage *LegacyInnerMessage
+
+	SmesherID types.NodeID
+	Signature types.EdSignature
+
+	Eligibility types.HareEligibility
+}
+
+// LegacyInnerMessage is the encoding of InnerMessage before SetHashVersion.
+type LegacyInnerMessage struct {
+	Layer          types.LayerID
+	Round          uint32
+	Type           MessageType
+	CommittedRound uint32
+	Values         []types.ProposalID `scale:"max=500"`
+	Svp            *LegacyAggregatedMessages
+	Cert           *LegacyCertificate
+}
+
+// LegacyAggregatedMessages is the encoding of AggregatedMessages before SetHashVersion.
+type LegacyAggregatedMessages struct {
+	Messages []LegacyMessage `scale:"max=1000"`
+}
+
+// LegacyCertificate is the encoding of Certificate before SetHashVersion.
+type LegacyCertificate struct {
+	Values  []types.ProposalID `scale:"max=500"`
+	AggMsgs *LegacyAggregatedMessages
+}
+
+// LegacyCertificateMessage is the encoding of CertificateMessage before SetHashVersion.
+type LegacyCertificateMessage struct {
+	Layer       types.LayerID
+	Certificate LegacyCertificate
+}
+
+// LegacyMessageFromBuffer builds an Hare message from the bytes encoded before SetHashVersion.
+func LegacyMessageFromBuffer(buf []byte) (*Message, error) {
+	var msg LegacyMessage
+	if err := codec.DecodeStrict(buf, &msg); err != nil {
+		return &Message{}, fmt.Errorf("serialize: %w", err)
+	}
+	return msg.message(), nil
+}
+
+func toLegacyMessage(m *Message) LegacyMessage {
+	return LegacyMessage{
+		InnerMessage: toLegacyInner(m.InnerMessage),
+		SmesherID:    m.SmesherID,
+		Signature:    m.Signature,
+		Eligibility:  m.Eligibility,
+	}
+}
+
+func (m *LegacyMessage) message() *Message {
+	msg := &Message{
+		SmesherID:   m.SmesherID,
+		Signature:   m.Signature,
+		Eligibility: m.Eligibility,
+	}
+	if m.InnerMessage != nil {
+		msg.InnerMessage = m.InnerMessage.inner()
+	}
+	return msg
+}
+
+func toLegacyInner(im *InnerMessage) *LegacyInnerMessage {
+	if im == nil {
+		return nil
+	}
+	return &LegacyInnerMessage{
+		Layer:          im.Layer,
+		Round:          im.Round,
+		Type:           im.Type,
+		CommittedRound: im.CommittedRound,
+		Values:         im.Values,
+		Svp:            toLegacyAggregated(im.Svp),
+		Cert:           toLegacyCertificate(im.Cert),
+	}
+}
+
+// inner returns the inner message committing to the values by hash, as the messages of SetHashVersion.
+func (im *LegacyInnerMessage) inner() *InnerMessage {
+	inner := &InnerMessage{
+		Layer:          im.Layer,
+		Round:          im.Round,
+		Type:           im.Type,
+		CommittedRound: im.CommittedRound,
+		Values:         im.Values,
+		ValuesHash:     NewSet(im.Values).ID(),
+		Svp:            im.Svp.aggregated(),
+		legacy:         true,
+	}
+	if im.Cert != nil {
+		cert := im.Cert.certificate()
+		inner.Cert = &cert
+	}
+	return inner
+}
+
+func toLegacyAggregated(agg *AggregatedMessages) *LegacyAggregatedMessages {
+	if agg == nil {
+		return nil
+	}
+	rst := &LegacyAggregatedMessages{Messages: make([]LegacyMessage, 0, len(agg.Messages))}
+	for i := range agg.Messages {
+		rst.Messages = append(rst.Messages, toLegacyMessage(&agg.Messages[i]))
+	}
+	return rst
+}
+
+func (agg *LegacyAggregatedMessages) aggregated() *AggregatedMessages {
+	if agg == nil {
+		return nil
+	}
+	rst := &AggregatedMessages{Messages: make([]Message, 0, len(agg.Messages))}
+	for i := range agg.Messages {
+		rst.Messages = append(rst.Messages, *agg.Messages[i].message())
+	}
+	return rst
+}
+
+func toLegacyCertificate(cert *Certificate) *LegacyCertificate {
+	if cert == nil {
+		return nil
+	}
+	return &LegacyCertificate{Values: cert.Values, AggMsgs: toLegacyAggregated(cert.AggMsgs)}
+}
+
+func (cert *LegacyCertificate) certificate() Certificate {
+	return Certificate{Values: cert.Values, AggMsgs: cert.AggMsgs.aggregated()}
+}
+
+// markLegacy marks the message and the nested messages as encoded before SetHashVersion.
+// The mark is not encoded, so it is restored for the messages of the persisted state.
+func markLegacy(m *Message) {
+	if m == nil || m.InnerMessage == nil {
+		return
+	}
+	m.legacy = true
+	if m.Svp != nil {
+		markLegacyAggregated(m.Svp)
+	}
+	if m.Cert != nil {
+		markLegacyAggregated(m.Cert.AggMsgs)
+	}
+}
+
+func markLegacyAggregated(agg *AggregatedMessages) {
+	if agg == nil {
+		return
+	}
+	for i := range agg.Messages {
+		markLegacy(&agg.Messages[i])
+	}
+}
@@ -0,0 +1,91 @@
+package hare
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/spacemeshos/go-spacemesh/common/types"
+	"github.com/spacemeshos/go-spacemesh/signing"
+)
+
+func buildLegacyCommitMsg(tb testing.TB, signer *signing.EdSigner, s *Set) *Message {
+	tb.Helper()
+	builder := newMessageBuilder().
+		SetType(commit).
+		SetLayer(instanceID1).
+		SetRoundCounter(commitRound).
+		SetCommittedRound(ki).
+		SetValues(s).
+		SetLegacy(true).
+		SetEligibilityCount(1)
+	return signMessage(builder, signer).Build()
+}
+
+func TestLegacyMessage(t *testing.T) {
+	verifier, err := signing.NewEdVerifier()
+	require.NoError(t, err)
+	values := NewSetFromValues(types.RandomProposalID(), types.RandomProposalID())
+	cert := &Certificate{Values: values.ToSlice(), AggMsgs: &AggregatedMessages{}}
+	for i := 0; i < 3; i++ {
+		signer, err := signing.NewEdSigner()
+		require.NoError(t, err)
+		cert.AggMsgs.Messages = append(cert.AggMsgs.Messages, *buildLegacyCommitMsg(t, signer, values))
+	}
+	compact := compactCertificate(cert)
+
+	signer, err := signing.NewEdSigner()
+	require.NoError(t, err)
+	builder := newMessageBuilder().
+		SetType(notify).
+		SetLayer(instanceID1).
+		SetRoundCounter(notifyRound).
+		SetCommittedRound(ki).
+		SetValues(values).
+		SetCertificate(&compact).
+		SetLegacy(true)
+	msg := signMessage(builder, signer).Build()
+
+	decoded, err := LegacyMessageFromBuffer(msg.Bytes())
+	require.NoError(t, err)
+	require.Equal(t, msg.Bytes(), decoded.Bytes())
+	require.Equal(t, msg.InnerMessage.HashBytes(), decoded.InnerMessage.HashBytes())
+	require.Equal(t, values.ID(), decoded.ValuesHash)
+	require.True(t, verifier.Verify(signing.HARE, decoded.SmesherID, decoded.SignedBytes(), decoded.Signature))
+
+	// the values of the commits are signed, and are refilled from the certificate
+	for i := range decoded.Cert.AggMsgs.Messages {
+		commit := &decoded.Cert.AggMsgs.Messages[i]
+		require.Empty(t, commit.Values)
+		require.False(t, verifier.Verify(signing.HARE, commit.SmesherID, commit.SignedBytes(), commit.Signature))
+		commit.fillValues(decoded.Cert.Values)
+		require.Equal(t, values.ID(), commit.ValuesHash)
+		require.True(t, verifier.Verify(signing.HARE, commit.SmesherID, commit.SignedBytes(), commit.Signature))
+	}
+
+	// the signature doesn't cover the message in the current encoding
+	current := *msg.InnerMessage
+	current.legacy = false
+	require.NotEqual(t, msg.InnerMessage.HashBytes(), current.HashBytes())
+}
+
+func TestMarkLegacy(t *testing.T) {
+	values := NewSetFromValues(types.RandomProposalID())
+	signer, err := signing.NewEdSigner()
+	require.NoError(t, err)
+	legacy := buildLegacyCommitMsg(t, signer, values)
+	msg := newMessageBuilder().
+		SetType(proposal).
+		SetLayer(instanceID1).
+		SetValues(values).
+		SetSVP(&AggregatedMessages{Messages: []Message{*legacy}}).
+		SetLegacy(true).
+		Build()
+
+	// the mark is not encoded
+	restored := marshallUnmarshall(t, msg)
+	require.NotEqual(t, msg.InnerMessage.HashBytes(), restored.InnerMessage.HashBytes())
+	markLegacy(restored)
+	require.Equal(t, msg.InnerMessage.HashBytes(), restored.InnerMessage.HashBytes())
+	require.Equal(t, legacy.InnerMessage.HashBytes(), restored.Svp.Messages[0].InnerMessage.HashBytes())
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"sync"
 	"time"
 
 	"github.com/spacemeshos/go-spacemesh/common/types"
@@ -21,14 +22,45 @@ type eligibilityValidator struct {
 	maxExpActives int // the maximal expected committee size
 	expLeaders    int // the expected number of leaders
 	log.Log
+
+	mu       sync.Mutex
+	reported types.EpochID // the last epoch for which the committee was reported
 }
 
 func newEligibilityValidator(oracle Rolacle, maxExpActives, expLeaders int, logger log.Log) *eligibilityValidator {
-	return &eligibilityValidator{oracle, maxExpActives, expLeaders, logger}
+	return &eligibilityValidator{oracle: oracle, maxExpActives: maxExpActives, expLeaders: expLeaders, Log: logger}
+}
+
+// committee returns the committee for the layer, adapted to the active set of the epoch.
+// The committee is reported once per epoch.
+func (ev *eligibilityValidator) committee(ctx context.Context, layer types.LayerID) (committee, error) {
+	c, err := adaptCommittee(ctx, ev.oracle, layer, ev.maxExpActives, ev.expLeaders)
+	if err != nil {
+		return committee{}, err
+	}
+	ev.mu.Lock()
+	defer ev.mu.Unlock()
+	if epoch := layer.GetEpoch(); epoch > ev.reported {
+		ev.reported = epoch
+		ev.WithContext(ctx).With().Info("hare committee for epoch",
+			epoch,
+			log.Int("committee_size", c.size),
+			log.Int("expected_leaders", c.leaders),
+			log.Int("configured_committee_size", ev.maxExpActives),
+			log.Int("configured_expected_leaders", ev.expLeaders),
+		)
+		committeeSizeGauge.Set(float64(c.size))
+		expectedLeadersGauge.Set(float64(c.leaders))
+	}
+	return c, nil
 }
 
 func (ev *eligibilityValidator) validateRole(ctx context.Context, nodeID types.NodeID, layer types.LayerID, round uint32, proof types.VrfSignature, eligibilityCount uint16) (bool, error) {
-	return ev.oracle.Validate(ctx, layer, round, expectedCommitteeSize(round, ev.maxExpActives, ev.expLeaders), nodeID, proof, eligibilityCount)
+	c, err := ev.committee(ctx, layer)
+	if err != nil {
+		return false, err
+	}
+	return ev.oracle.Validate(ctx, layer, round, expectedCommitteeSize(round, c.size, c.leaders), nodeID, proof, eligibilityCount)
 }
 
 func (ev *eligibilityValidator) ValidateEligibilityGossip(ctx context.Context, em *types.HareEligibilityGossip) bool {
@@ -83,7 +115,7 @@ type pubKeyGetter interface {
 }
 
 type syntaxContextValidator struct {
-	signing          *signing.EdSigner
+	signing          signing.Signer
 	edVerifier       *signing.EdVerifier
 	threshold        int
 	statusValidator  func(m *Message) bool // used to validate status Messages in SVP
@@ -95,7 +127,7 @@ type syntaxContextValidator struct {
 }
 
 func newSyntaxContextValidator(
-	sgr *signing.EdSigner,
+	sgr signing.Signer,
 	edVerifier *signing.EdVerifier,
 	threshold int,
 	validator func(m *Message) bool,
@@ -279,6 +311,7 @@ var (
 	errInnerSyntax       = errors.New("invalid syntax for inner message")
 	errInnerEligibility  = errors.New("inner message is not eligible")
 	errInnerFunc         = errors.New("inner message did not pass validation function")
+	errInnerValues       = errors.New("inner message values don't match the hash")
 )
 
 // validate the provided aggregated messages by the provided validators.
@@ -297,6 +330,11 @@ func (v *syntaxContextValidator) validateAggregatedMessage(ctx context.Context,
 
 	senders := make(map[types.NodeID]struct{})
 	for _, innerMsg := range aggMsg.Messages {
+		// the signature covers the hash of the values, that may be refilled by the receiver
+		if innerMsg.InnerMessage == nil || NewSet(innerMsg.Values).ID() != innerMsg.ValuesHash {
+			return errInnerValues
+		}
+
 		// check if exist in cache of valid messages
 		if nodeID := v.validMsgsTracker.NodeID(&innerMsg); nodeID != types.EmptyNodeID {
 			// validate unique sender
@@ -449,10 +487,9 @@ func (v *syntaxContextValidator) validateCertificate(ctx context.Context, cert *
 		}
 
 		// the values were removed to reduce data volume
-		commit.Values = cert.Values
+		commit.fillValues(cert.Values)
 	}
 
-	// Note: no need to validate notify.Values=commits.Values because we refill the InnerMsg with notify.Values
 	validateSameK := func(m *Message) bool { return m.Round == cert.AggMsgs.Messages[0].Round }
 	validators := []func(m *Message) bool{validateCommitType, validateSameK}
 	if err := v.validateAggregatedMessage(ctx, cert.AggMsgs, validators); err != nil {
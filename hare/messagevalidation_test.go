@@ -97,6 +97,7 @@ func TestEligibilityValidator_validateRole_FailedToValidate(t *testing.T) {
 	m.Layer = types.LayerID(111)
 	myErr := errors.New("my error")
 
+	mo.EXPECT().CommitteeSize(gomock.Any(), m.Layer, 1).Return(1, nil)
 	mo.EXPECT().Validate(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(false, myErr).Times(1)
 	res := ev.Validate(context.Background(), m)
 	require.False(t, res)
@@ -113,6 +114,7 @@ func TestEligibilityValidator_validateRole_NotEligible(t *testing.T) {
 	m := BuildPreRoundMsg(signer, NewDefaultEmptySet(), types.EmptyVrfSignature)
 	m.Layer = types.LayerID(111)
 
+	mo.EXPECT().CommitteeSize(gomock.Any(), m.Layer, 1).Return(1, nil)
 	mo.EXPECT().Validate(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(false, nil).Times(1)
 	res := ev.Validate(context.Background(), m)
 	require.False(t, res)
@@ -129,11 +131,72 @@ func TestEligibilityValidator_validateRole_Success(t *testing.T) {
 	m := BuildPreRoundMsg(signer, NewDefaultEmptySet(), types.EmptyVrfSignature)
 	m.Layer = types.LayerID(111)
 
+	mo.EXPECT().CommitteeSize(gomock.Any(), m.Layer, 1).Return(1, nil)
 	mo.EXPECT().Validate(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(true, nil).Times(1)
 	res := ev.Validate(context.Background(), m)
 	require.True(t, res)
 }
 
+func TestEligibilityValidator_validateRole_SmallActiveSet(t *testing.T) {
+	const (
+		configured = 800
+		leaders    = 5
+	)
+	for _, tc := range []struct {
+		desc      string
+		actives   int
+		committee int
+		leaders   int
+	}{
+		{desc: "smaller than committee", actives: 30, committee: 30, leaders: leaders},
+		{desc: "smaller than leaders", actives: 3, committee: 3, leaders: 3},
+		{desc: "larger than committee", actives: 1000, committee: configured, leaders: leaders},
+	} {
+		tc := tc
+		t.Run(tc.desc, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			mo := mocks.NewMockRolacle(ctrl)
+			ev := newEligibilityValidator(mo, configured, leaders, logtest.New(t))
+			mo.EXPECT().CommitteeSize(gomock.Any(), gomock.Any(), configured).DoAndReturn(
+				func(_ context.Context, _ types.LayerID, size int) (int, error) {
+					if tc.actives < size {
+						return tc.actives, nil
+					}
+					return size, nil
+				}).AnyTimes()
+
+			signer, err := signing.NewEdSigner()
+			require.NoError(t, err)
+			for _, round := range []uint32{preRound, statusRound, proposalRound, commitRound, notifyRound} {
+				expected := tc.committee
+				if round == proposalRound {
+					expected = tc.leaders
+				}
+				m := BuildPreRoundMsg(signer, NewDefaultEmptySet(), types.EmptyVrfSignature)
+				m.Layer = types.LayerID(111)
+				m.Round = round
+				mo.EXPECT().Validate(gomock.Any(), m.Layer, round, expected, signer.NodeID(), gomock.Any(), gomock.Any()).Return(true, nil)
+				require.True(t, ev.Validate(context.Background(), m))
+			}
+		})
+	}
+}
+
+func TestEligibilityValidator_validateRole_CommitteeError(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	mo := mocks.NewMockRolacle(ctrl)
+	ev := newEligibilityValidator(mo, 1, 5, logtest.New(t))
+
+	signer, err := signing.NewEdSigner()
+	require.NoError(t, err)
+
+	m := BuildPreRoundMsg(signer, NewDefaultEmptySet(), types.EmptyVrfSignature)
+	m.Layer = types.LayerID(111)
+
+	mo.EXPECT().CommitteeSize(gomock.Any(), m.Layer, 1).Return(0, errors.New("empty active set"))
+	require.False(t, ev.Validate(context.Background(), m))
+}
+
 func TestMessageValidator_IsStructureValid(t *testing.T) {
 	sv := defaultValidator(t)
 
@@ -298,17 +361,17 @@ func TestMessageValidator_ValidateMessage(t *testing.T) {
 	proc := generateConsensusProcess(t)
 	proc.advanceToNextRound(context.Background())
 	v := proc.validator
-	b, err := proc.initDefaultBuilder(proc.value)
+	b, err := proc.initDefaultBuilder(proc.participants[0], proc.value)
 	require.Nil(t, err)
-	preround := b.SetType(pre).Sign(proc.signer).Build()
-	preround.SmesherID = proc.signer.NodeID()
+	preround := signMessage(b.SetType(pre), proc.participants[0].signer).Build()
+	preround.SmesherID = proc.participants[0].signer.NodeID()
 	require.True(t, v.SyntacticallyValidateMessage(context.Background(), preround))
 	e := v.ContextuallyValidateMessage(context.Background(), preround, 0)
 	require.Nil(t, e)
-	b, err = proc.initDefaultBuilder(proc.value)
+	b, err = proc.initDefaultBuilder(proc.participants[0], proc.value)
 	require.Nil(t, err)
-	status := b.SetType(status).Sign(proc.signer).Build()
-	status.SmesherID = proc.signer.NodeID()
+	status := signMessage(b.SetType(status), proc.participants[0].signer).Build()
+	status.SmesherID = proc.participants[0].signer.NodeID()
 	e = v.ContextuallyValidateMessage(context.Background(), status, 0)
 	require.Nil(t, e)
 	require.True(t, v.SyntacticallyValidateMessage(context.Background(), status))
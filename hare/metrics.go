@@ -1,8 +1,11 @@
 package hare
 
 import (
+	"strconv"
+
 	"github.com/prometheus/client_golang/prometheus"
 
+	"github.com/spacemeshos/go-spacemesh/common/types"
 	"github.com/spacemeshos/go-spacemesh/metrics"
 )
 
@@ -12,6 +15,28 @@ const (
 	// labels for hare consensus output.
 	success = "ok"
 	failure = "fail"
+	skipped = "skipped"
+
+	// labels for dropped messages.
+	duplicate = "duplicate"
+	limit     = "limit"
+	early     = "early"
+
+	// reasons for rejected messages.
+	malformed    = "malformed"
+	signature    = "signature"
+	notEligible  = "not_eligible"
+	notSynced    = "not_synced"
+	timing       = "timing"
+	malicious    = "malicious"
+	equivocation = "equivocation"
+	unknownSet   = "unknown_set"
+	other        = "other"
+
+	unknown = "unknown"
+
+	// layers are labeled modulo layerLabelMod to keep the cardinality of the labels low.
+	layerLabelMod = 4
 )
 
 var (
@@ -37,6 +62,7 @@ var (
 	)
 	consensusOkCnt   = consensusCount.WithLabelValues(success)
 	consensusFailCnt = consensusCount.WithLabelValues(failure)
+	consensusSkipCnt = consensusCount.WithLabelValues(skipped)
 
 	numIterations = metrics.NewHistogramWithBuckets(
 		"num_iterations",
@@ -52,4 +78,89 @@ var (
 		"number of hare processes",
 		[]string{},
 	).WithLabelValues()
+
+	committeeSizeGauge = metrics.NewGauge(
+		"committee_size",
+		namespace,
+		"committee size for the current epoch",
+		[]string{},
+	).WithLabelValues()
+
+	expectedLeadersGauge = metrics.NewGauge(
+		"expected_leaders",
+		namespace,
+		"expected number of leaders for the current epoch",
+		[]string{},
+	).WithLabelValues()
+
+	droppedMsgs = metrics.NewCounter(
+		"dropped_msgs",
+		namespace,
+		"number of hare messages dropped by the broker",
+		[]string{"reason"},
+	)
+	droppedDuplicate = droppedMsgs.WithLabelValues(duplicate)
+	droppedByLimit   = droppedMsgs.WithLabelValues(limit)
+	droppedEarly     = droppedMsgs.WithLabelValues(early)
+
+	receivedMsgs = metrics.NewCounter(
+		"received_msgs",
+		namespace,
+		"number of hare messages received by the broker",
+		[]string{"layer", "round"},
+	)
+	acceptedMsgs = metrics.NewCounter(
+		"accepted_msgs",
+		namespace,
+		"number of hare messages accepted by the broker",
+		[]string{"layer", "round"},
+	)
+	rejectedMsgs = metrics.NewCounter(
+		"rejected_msgs",
+		namespace,
+		"number of hare messages rejected by the broker",
+		[]string{"layer", "round", "reason"},
+	)
+
+	roundThreshold = metrics.NewHistogramWithBuckets(
+		"round_threshold",
+		namespace,
+		"time since the beginning of the round until its threshold was satisfied (seconds)",
+		[]string{"layer", "round"},
+		prometheus.ExponentialBuckets(0.05, 2, 10),
+	)
+
+	fetchedSets = metrics.NewCounter(
+		"fetched_sets",
+		namespace,
+		"number of proposal sets referred to by hare messages that were fetched from peers",
+		[]string{},
+	).WithLabelValues()
+
+	certificatesCnt = metrics.NewCounter(
+		"certificates",
+		namespace,
+		"number of certificates produced by hare",
+		[]string{},
+	).WithLabelValues()
 )
+
+func layerLabel(lid types.LayerID) string {
+	return strconv.Itoa(int(lid.Uint32() % layerLabelMod))
+}
+
+func roundLabel(round uint32) string {
+	if round == preRound {
+		return "preround"
+	}
+	switch round % RoundsPerIteration {
+	case statusRound:
+		return "status"
+	case proposalRound:
+		return "proposal"
+	case commitRound:
+		return "commit"
+	default:
+		return "notify"
+	}
+}
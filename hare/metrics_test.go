@@ -0,0 +1,174 @@
+package hare
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/require"
+
+	"github.com/spacemeshos/go-spacemesh/common/types"
+	"github.com/spacemeshos/go-spacemesh/eligibility"
+	"github.com/spacemeshos/go-spacemesh/hare/config"
+	"github.com/spacemeshos/go-spacemesh/log/logtest"
+	"github.com/spacemeshos/go-spacemesh/p2p/pubsub"
+	"github.com/spacemeshos/go-spacemesh/signing"
+)
+
+// manualRoundClock ends the rounds when the test advances them.
+type manualRoundClock struct {
+	mu   sync.Mutex
+	ends map[uint32]chan struct{}
+	at   map[uint32]time.Time
+}
+
+func newManualRoundClock() *manualRoundClock {
+	return &manualRoundClock{
+		ends: map[uint32]chan struct{}{},
+		at:   map[uint32]time.Time{},
+	}
+}
+
+func (c *manualRoundClock) end(round uint32) chan struct{} {
+	if _, ok := c.ends[round]; !ok {
+		c.ends[round] = make(chan struct{})
+	}
+	return c.ends[round]
+}
+
+func (c *manualRoundClock) AwaitWakeup() <-chan struct{} {
+	ch := make(chan struct{})
+	close(ch)
+	return ch
+}
+
+func (c *manualRoundClock) AwaitEndOfRound(round uint32) <-chan struct{} {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.end(round)
+}
+
+func (c *manualRoundClock) RoundEnd(round uint32) time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if at, ok := c.at[round]; ok {
+		return at
+	}
+	// the round didn't end yet
+	return time.Now().Add(time.Hour)
+}
+
+func (c *manualRoundClock) advance(round uint32) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.at[round] = time.Now()
+	close(c.end(round))
+}
+
+// loopbackPubSub delivers published messages to the handler of the same node.
+type loopbackPubSub struct {
+	mu        sync.Mutex
+	handlers  map[string]pubsub.GossipHandler
+	published chan struct{}
+}
+
+func newLoopbackPubSub() *loopbackPubSub {
+	return &loopbackPubSub{
+		handlers:  map[string]pubsub.GossipHandler{},
+		published: make(chan struct{}, 10),
+	}
+}
+
+func (lps *loopbackPubSub) Register(protocol string, handler pubsub.GossipHandler) {
+	lps.mu.Lock()
+	defer lps.mu.Unlock()
+	lps.handlers[protocol] = handler
+}
+
+func (lps *loopbackPubSub) Publish(ctx context.Context, protocol string, data []byte) error {
+	lps.mu.Lock()
+	handler := lps.handlers[protocol]
+	lps.mu.Unlock()
+	if handler != nil {
+		if err := handler(ctx, "", data); err != nil {
+			return err
+		}
+	}
+	lps.published <- struct{}{}
+	return nil
+}
+
+func sampleCount(tb testing.TB, o prometheus.Observer) uint64 {
+	tb.Helper()
+	var m dto.Metric
+	require.NoError(tb, o.(prometheus.Metric).Write(&m))
+	return m.GetHistogram().GetSampleCount()
+}
+
+func TestMetrics_Instance(t *testing.T) {
+	cfg := config.Config{N: 1, RoundDuration: time.Second, ExpectedLeaders: 1, LimitIterations: 1, Hdist: 20}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	sig, err := signing.NewEdSigner()
+	require.NoError(t, err)
+	network := newLoopbackPubSub()
+	clock := newManualRoundClock()
+	tcp := createConsensusProcess(t, ctx, sig, true, cfg, eligibility.New(logtest.New(t)), network,
+		NewSetFromValues(types.ProposalID{1}), instanceID1)
+	tcp.cp.clock = clock
+	t.Cleanup(tcp.broker.Close)
+
+	layer := layerLabel(instanceID1)
+	rounds := []uint32{preRound, statusRound, proposalRound, commitRound, notifyRound}
+	received := make([]float64, len(rounds))
+	accepted := make([]float64, len(rounds))
+	for i, round := range rounds {
+		received[i] = testutil.ToFloat64(receivedMsgs.WithLabelValues(layer, roundLabel(round)))
+		accepted[i] = testutil.ToFloat64(acceptedMsgs.WithLabelValues(layer, roundLabel(round)))
+	}
+	rejected := testutil.ToFloat64(rejectedMsgs.WithLabelValues(unknown, unknown, malformed))
+	commits := sampleCount(t, roundThreshold.WithLabelValues(layer, roundLabel(commitRound)))
+	notifies := sampleCount(t, roundThreshold.WithLabelValues(layer, roundLabel(notifyRound)))
+	agreements := testutil.ToFloat64(consensusOkCnt)
+	certs := testutil.ToFloat64(certificatesCnt)
+
+	// the node sends a message in every round of the first iteration, the round ends once it is processed
+	tcp.cp.Start()
+	for _, round := range rounds {
+		select {
+		case <-network.published:
+		case <-time.After(5 * time.Second):
+			require.FailNow(t, "timed out waiting for message", "round %d", round)
+		}
+		require.Eventually(t, func() bool { return len(tcp.cp.comm.inbox) == 0 }, time.Second, 10*time.Millisecond)
+		if round != notifyRound {
+			clock.advance(round)
+		}
+	}
+	var rst report
+	select {
+	case rst = <-tcp.cp.comm.report:
+	case <-time.After(5 * time.Second):
+		require.FailNow(t, "timed out waiting for termination")
+	}
+	require.True(t, rst.completed)
+	require.ErrorIs(t, tcp.broker.HandleMessage(ctx, "", []byte{1, 2, 3}), pubsub.ErrValidationReject)
+
+	for i, round := range rounds {
+		require.Equal(t, received[i]+1, testutil.ToFloat64(receivedMsgs.WithLabelValues(layer, roundLabel(round))), roundLabel(round))
+		require.Equal(t, accepted[i]+1, testutil.ToFloat64(acceptedMsgs.WithLabelValues(layer, roundLabel(round))), roundLabel(round))
+	}
+	require.Equal(t, rejected+1, testutil.ToFloat64(rejectedMsgs.WithLabelValues(unknown, unknown, malformed)))
+	require.Equal(t, commits+1, sampleCount(t, roundThreshold.WithLabelValues(layer, roundLabel(commitRound))))
+	require.Equal(t, notifies+1, sampleCount(t, roundThreshold.WithLabelValues(layer, roundLabel(notifyRound))))
+
+	h, _ := createCertTestHare(t, noopPubSub(t))
+	require.NoError(t, h.collectOutput(ctx, rst))
+	require.Equal(t, agreements+1, testutil.ToFloat64(consensusOkCnt))
+	require.Equal(t, certs+1, testutil.ToFloat64(certificatesCnt))
+}
@@ -11,6 +11,7 @@ import (
 	gomock "github.com/golang/mock/gomock"
 	types "github.com/spacemeshos/go-spacemesh/common/types"
 	datastore "github.com/spacemeshos/go-spacemesh/datastore"
+	p2p "github.com/spacemeshos/go-spacemesh/p2p"
 )
 
 // MocklayerPatrol is a mock of layerPatrol interface.
@@ -36,6 +37,18 @@ func (m *MocklayerPatrol) EXPECT() *MocklayerPatrolMockRecorder {
 	return m.recorder
 }
 
+// CompleteHare mocks base method.
+func (m *MocklayerPatrol) CompleteHare(arg0 types.LayerID) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "CompleteHare", arg0)
+}
+
+// CompleteHare indicates an expected call of CompleteHare.
+func (mr *MocklayerPatrolMockRecorder) CompleteHare(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CompleteHare", reflect.TypeOf((*MocklayerPatrol)(nil).CompleteHare), arg0)
+}
+
 // SetHareInCharge mocks base method.
 func (m *MocklayerPatrol) SetHareInCharge(arg0 types.LayerID) {
 	m.ctrl.T.Helper()
@@ -86,6 +99,21 @@ func (mr *MockRolacleMockRecorder) CalcEligibility(arg0, arg1, arg2, arg3, arg4,
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CalcEligibility", reflect.TypeOf((*MockRolacle)(nil).CalcEligibility), arg0, arg1, arg2, arg3, arg4, arg5)
 }
 
+// CommitteeSize mocks base method.
+func (m *MockRolacle) CommitteeSize(arg0 context.Context, arg1 types.LayerID, arg2 int) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CommitteeSize", arg0, arg1, arg2)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CommitteeSize indicates an expected call of CommitteeSize.
+func (mr *MockRolacleMockRecorder) CommitteeSize(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CommitteeSize", reflect.TypeOf((*MockRolacle)(nil).CommitteeSize), arg0, arg1, arg2)
+}
+
 // IsIdentityActiveOnConsensusView mocks base method.
 func (m *MockRolacle) IsIdentityActiveOnConsensusView(arg0 context.Context, arg1 types.NodeID, arg2 types.LayerID) (bool, error) {
 	m.ctrl.T.Helper()
@@ -281,6 +309,55 @@ func (mr *MockmeshMockRecorder) Proposals(arg0 interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Proposals", reflect.TypeOf((*Mockmesh)(nil).Proposals), arg0)
 }
 
+// MocksetFetcher is a mock of setFetcher interface.
+type MocksetFetcher struct {
+	ctrl     *gomock.Controller
+	recorder *MocksetFetcherMockRecorder
+}
+
+// MocksetFetcherMockRecorder is the mock recorder for MocksetFetcher.
+type MocksetFetcherMockRecorder struct {
+	mock *MocksetFetcher
+}
+
+// NewMocksetFetcher creates a new mock instance.
+func NewMocksetFetcher(ctrl *gomock.Controller) *MocksetFetcher {
+	mock := &MocksetFetcher{ctrl: ctrl}
+	mock.recorder = &MocksetFetcherMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MocksetFetcher) EXPECT() *MocksetFetcherMockRecorder {
+	return m.recorder
+}
+
+// GetHareSets mocks base method.
+func (m *MocksetFetcher) GetHareSets(arg0 context.Context, arg1 []types.Hash32) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetHareSets", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// GetHareSets indicates an expected call of GetHareSets.
+func (mr *MocksetFetcherMockRecorder) GetHareSets(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetHareSets", reflect.TypeOf((*MocksetFetcher)(nil).GetHareSets), arg0, arg1)
+}
+
+// RegisterPeerHashes mocks base method.
+func (m *MocksetFetcher) RegisterPeerHashes(peer p2p.Peer, hashes []types.Hash32) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "RegisterPeerHashes", peer, hashes)
+}
+
+// RegisterPeerHashes indicates an expected call of RegisterPeerHashes.
+func (mr *MocksetFetcherMockRecorder) RegisterPeerHashes(peer, hashes interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RegisterPeerHashes", reflect.TypeOf((*MocksetFetcher)(nil).RegisterPeerHashes), peer, hashes)
+}
+
 // MockweakCoin is a mock of weakCoin interface.
 type MockweakCoin struct {
 	ctrl     *gomock.Controller
@@ -317,3 +394,52 @@ func (mr *MockweakCoinMockRecorder) Set(arg0, arg1 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Set", reflect.TypeOf((*MockweakCoin)(nil).Set), arg0, arg1)
 }
+
+// Mocktortoise is a mock of tortoise interface.
+type Mocktortoise struct {
+	ctrl     *gomock.Controller
+	recorder *MocktortoiseMockRecorder
+}
+
+// MocktortoiseMockRecorder is the mock recorder for Mocktortoise.
+type MocktortoiseMockRecorder struct {
+	mock *Mocktortoise
+}
+
+// NewMocktortoise creates a new mock instance.
+func NewMocktortoise(ctrl *gomock.Controller) *Mocktortoise {
+	mock := &Mocktortoise{ctrl: ctrl}
+	mock.recorder = &MocktortoiseMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *Mocktortoise) EXPECT() *MocktortoiseMockRecorder {
+	return m.recorder
+}
+
+// LatestComplete mocks base method.
+func (m *Mocktortoise) LatestComplete() types.LayerID {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LatestComplete")
+	ret0, _ := ret[0].(types.LayerID)
+	return ret0
+}
+
+// LatestComplete indicates an expected call of LatestComplete.
+func (mr *MocktortoiseMockRecorder) LatestComplete() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LatestComplete", reflect.TypeOf((*Mocktortoise)(nil).LatestComplete))
+}
+
+// OnHareOutput mocks base method.
+func (m *Mocktortoise) OnHareOutput(arg0 types.LayerID, arg1 types.BlockID) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "OnHareOutput", arg0, arg1)
+}
+
+// OnHareOutput indicates an expected call of OnHareOutput.
+func (mr *MocktortoiseMockRecorder) OnHareOutput(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "OnHareOutput", reflect.TypeOf((*Mocktortoise)(nil).OnHareOutput), arg0, arg1)
+}
@@ -116,3 +116,12 @@ func (nt *notifyTracker) HasCertificate(k uint32, set *Set) bool {
 	_, exist := nt.certificates[calcID(k, set)]
 	return exist
 }
+
+// Certificates returns the ids of the tracked certificates.
+func (nt *notifyTracker) Certificates() []types.Hash32 {
+	ids := make([]types.Hash32, 0, len(nt.certificates))
+	for id := range nt.certificates {
+		ids = append(ids, id)
+	}
+	return ids
+}
@@ -20,7 +20,7 @@ func BuildNotifyMsg(signing *signing.EdSigner, s *Set) *Message {
 	cert.AggMsgs.Messages = []Message{*BuildCommitMsg(signing, s)}
 	builder.SetCertificate(cert)
 	builder.SetEligibilityCount(1)
-	m := builder.Sign(signing).Build()
+	m := signMessage(builder, signing).Build()
 	m.signedHash = types.BytesToHash(m.InnerMessage.HashBytes())
 	return m
 }
@@ -24,6 +24,7 @@ type preRoundTracker struct {
 	bestVRF   *types.VrfSignature            // the lowest VRF value seen in the round
 	coinflip  bool                           // the value of the weak coin (based on bestVRF)
 	eTracker  *EligibilityTracker
+	provable  map[types.ProposalID]struct{} // values proven before the process was restored
 }
 
 func newPreRoundTracker(logger log.Log, mch chan<- *types.MalfeasanceGossip, et *EligibilityTracker, threshold, expectedSize int) *preRoundTracker {
@@ -113,6 +114,9 @@ func (pre *preRoundTracker) OnPreRound(ctx context.Context, msg *Message) {
 // CanProveValue returns true if the given value is provable, false otherwise.
 // a value is said to be provable if it has at least threshold pre-round votes to support it.
 func (pre *preRoundTracker) CanProveValue(value types.ProposalID) bool {
+	if _, exist := pre.provable[value]; exist {
+		return true
+	}
 	// at least threshold occurrences of a given value
 	countStatus := pre.tracker.CountStatus(value)
 	if countStatus == nil {
@@ -148,3 +152,27 @@ func (pre *preRoundTracker) FilterSet(set *Set) {
 		}
 	}
 }
+
+// ProvableValues returns all values that are provable.
+func (pre *preRoundTracker) ProvableValues() []types.ProposalID {
+	var values []types.ProposalID
+	for id := range pre.tracker.table {
+		if v, ok := id.(types.ProposalID); ok && pre.CanProveValue(v) {
+			values = append(values, v)
+		}
+	}
+	for v := range pre.provable {
+		if _, exist := pre.tracker.table[v]; !exist {
+			values = append(values, v)
+		}
+	}
+	return values
+}
+
+// restore marks the values as provable without the pre-round messages.
+func (pre *preRoundTracker) restore(values []types.ProposalID) {
+	pre.provable = make(map[types.ProposalID]struct{}, len(values))
+	for _, v := range values {
+		pre.provable[v] = struct{}{}
+	}
+}
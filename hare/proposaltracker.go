@@ -13,6 +13,7 @@ type proposalTrackerProvider interface {
 	OnLateProposal(context.Context, *Message)
 	IsConflicting() bool
 	ProposedSet() *Set
+	Proposal() *Message
 }
 
 // proposalTracker tracks proposal messages.
@@ -166,3 +167,8 @@ func (pt *proposalTracker) ProposedSet() *Set {
 
 	return NewSet(pt.proposal.Values)
 }
+
+// Proposal returns the tracked proposal message, nil if there is no proposal.
+func (pt *proposalTracker) Proposal() *Message {
+	return pt.proposal
+}
@@ -0,0 +1,95 @@
+package hare
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/spacemeshos/go-spacemesh/codec"
+	"github.com/spacemeshos/go-spacemesh/common/types"
+	"github.com/spacemeshos/go-spacemesh/log"
+	"github.com/spacemeshos/go-spacemesh/sql"
+	"github.com/spacemeshos/go-spacemesh/sql/hareresults"
+)
+
+var (
+	// ErrHareFailed is returned for the layer where hare didn't reach agreement within the iterations limit.
+	ErrHareFailed = errors.New("hare failed")
+	// ErrHareNotRun is returned for the layer where hare was not started by the node.
+	ErrHareNotRun = errors.New("hare not run")
+)
+
+// GetResult returns the proposals agreed upon by hare for the layer, the list is empty if hare agreed
+// on the empty set, which results in the empty layer. If the result is not known yet,
+// it blocks until hare terminates for the layer or the context is done. ErrHareFailed and ErrHareNotRun
+// are returned if the layer has no result. The results are persisted, so that they are available
+// after a restart.
+func (h *Hare) GetResult(ctx context.Context, lid types.LayerID) ([]types.ProposalID, error) {
+	if lid <= types.GetEffectiveGenesis() {
+		return nil, ErrHareNotRun
+	}
+	if pids, err := h.loadResult(lid); !errors.Is(err, sql.ErrNotFound) {
+		return pids, err
+	}
+	// check again after subscribing, so that the result recorded in between is not missed
+	ready := h.awaitResult(lid)
+	if pids, err := h.loadResult(lid); !errors.Is(err, sql.ErrNotFound) {
+		return pids, err
+	}
+	select {
+	case <-ready:
+		return h.loadResult(lid)
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// awaitResult returns a channel that is closed when the result for the layer is recorded.
+func (h *Hare) awaitResult(lid types.LayerID) <-chan struct{} {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	ch, ok := h.results[lid]
+	if !ok {
+		ch = make(chan struct{})
+		h.results[lid] = ch
+	}
+	return ch
+}
+
+func (h *Hare) loadResult(lid types.LayerID) ([]types.ProposalID, error) {
+	status, data, err := hareresults.Get(h.msh.Cache(), lid)
+	if err != nil {
+		return nil, err
+	}
+	switch status {
+	case hareresults.Completed:
+		return codec.DecodeSlice[types.ProposalID](data)
+	case hareresults.Failed:
+		return nil, ErrHareFailed
+	case hareresults.NotRun:
+		return nil, ErrHareNotRun
+	}
+	return nil, fmt.Errorf("unknown hare result status %d for layer %s", status, lid)
+}
+
+// recordResult persists the outcome of hare for the layer and wakes up the callers waiting for it.
+func (h *Hare) recordResult(ctx context.Context, lid types.LayerID, status hareresults.Status, pids []types.ProposalID) {
+	var data []byte
+	if status == hareresults.Completed {
+		encoded, err := codec.EncodeSlice(pids)
+		if err != nil {
+			h.With().Fatal("failed to encode hare result", log.Context(ctx), lid, log.Err(err))
+		}
+		data = encoded
+	}
+	if err := hareresults.Set(h.msh.Cache(), lid, status, data); err != nil {
+		h.With().Error("failed to persist hare result", log.Context(ctx), lid, log.Err(err))
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if ch, ok := h.results[lid]; ok {
+		close(ch)
+		delete(h.results, lid)
+	}
+}
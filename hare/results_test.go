@@ -0,0 +1,104 @@
+package hare
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/spacemeshos/go-spacemesh/common/types"
+	"github.com/spacemeshos/go-spacemesh/datastore"
+	"github.com/spacemeshos/go-spacemesh/hare/config"
+	"github.com/spacemeshos/go-spacemesh/log/logtest"
+	"github.com/spacemeshos/go-spacemesh/sql"
+)
+
+func TestHare_GetResult(t *testing.T) {
+	cdb := datastore.NewCachedDB(sql.InMemory(), logtest.New(t))
+	mockMesh := newMockMesh(t)
+	mockMesh.EXPECT().Cache().Return(cdb).AnyTimes()
+	h := createTestHare(t, mockMesh, config.DefaultConfig(), newMockClock(), noopPubSub(t), t.Name())
+	lid := types.GetEffectiveGenesis().Add(10)
+
+	t.Run("genesis", func(t *testing.T) {
+		_, err := h.GetResult(context.Background(), types.GetEffectiveGenesis())
+		require.ErrorIs(t, err, ErrHareNotRun)
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		_, err := h.GetResult(ctx, lid)
+		require.ErrorIs(t, err, context.DeadlineExceeded)
+	})
+
+	t.Run("blocks until completed", func(t *testing.T) {
+		pids := []types.ProposalID{types.RandomProposalID(), types.RandomProposalID()}
+		type result struct {
+			pids []types.ProposalID
+			err  error
+		}
+		got := make(chan result, 1)
+		go func() {
+			pids, err := h.GetResult(context.Background(), lid)
+			got <- result{pids: pids, err: err}
+		}()
+		require.Eventually(t, func() bool {
+			h.mu.Lock()
+			defer h.mu.Unlock()
+			_, ok := h.results[lid]
+			return ok
+		}, time.Second, time.Millisecond)
+
+		require.NoError(t, h.collectOutput(context.Background(), report{id: lid, set: NewSetFromValues(pids...), completed: true}))
+		select {
+		case res := <-got:
+			require.NoError(t, res.err)
+			require.ElementsMatch(t, pids, res.pids)
+		case <-time.After(time.Second):
+			require.FailNow(t, "timed out waiting for result")
+		}
+		require.Empty(t, h.results)
+	})
+
+	t.Run("empty set", func(t *testing.T) {
+		empty := lid.Add(3)
+		require.NoError(t, h.collectOutput(context.Background(), report{id: empty, set: NewEmptySet(0), completed: true}))
+		pids, err := h.GetResult(context.Background(), empty)
+		require.NoError(t, err)
+		require.Empty(t, pids)
+	})
+
+	t.Run("failed", func(t *testing.T) {
+		failed := lid.Add(1)
+		h.mockPatrol.EXPECT().CompleteHare(failed)
+		require.NoError(t, h.collectOutput(context.Background(), report{id: failed, set: NewEmptySet(0), completed: false}))
+		_, err := h.GetResult(context.Background(), failed)
+		require.ErrorIs(t, err, ErrHareFailed)
+	})
+
+	t.Run("not run", func(t *testing.T) {
+		skipped := lid.Add(2)
+		h.mockTrtl.EXPECT().OnHareOutput(skipped, types.EmptyBlockID)
+		h.notRun(context.Background(), skipped)
+		_, err := h.GetResult(context.Background(), skipped)
+		require.ErrorIs(t, err, ErrHareNotRun)
+	})
+
+	t.Run("persisted", func(t *testing.T) {
+		mockMesh := newMockMesh(t)
+		mockMesh.EXPECT().Cache().Return(cdb).AnyTimes()
+		restarted := createTestHare(t, mockMesh, config.DefaultConfig(), newMockClock(), noopPubSub(t), t.Name())
+		pids, err := restarted.GetResult(context.Background(), lid)
+		require.NoError(t, err)
+		require.Len(t, pids, 2)
+		_, err = restarted.GetResult(context.Background(), lid.Add(1))
+		require.ErrorIs(t, err, ErrHareFailed)
+		_, err = restarted.GetResult(context.Background(), lid.Add(2))
+		require.ErrorIs(t, err, ErrHareNotRun)
+		pids, err = restarted.GetResult(context.Background(), lid.Add(3))
+		require.NoError(t, err)
+		require.Empty(t, pids)
+	})
+}
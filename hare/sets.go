@@ -0,0 +1,182 @@
+package hare
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/spacemeshos/go-spacemesh/codec"
+	"github.com/spacemeshos/go-spacemesh/common/types"
+	"github.com/spacemeshos/go-spacemesh/p2p"
+	"github.com/spacemeshos/go-spacemesh/p2p/pubsub"
+	"github.com/spacemeshos/go-spacemesh/sql"
+	"github.com/spacemeshos/go-spacemesh/sql/haresets"
+)
+
+// the max number of values in a set, as limited by the encoding of the messages.
+const maxSetSize = 500
+
+var (
+	errUnknownSet   = errors.New("unknown set")
+	errMalformedSet = fmt.Errorf("%w: malformed set", pubsub.ErrValidationReject)
+
+	emptySetID = NewEmptySet(0).ID()
+)
+
+// setStore keeps the proposal sets referred to by hare messages, so that a message may carry the hash
+// of a set known to the receivers instead of the set itself. The sets are served to peers by the fetch service.
+type setStore struct {
+	db sql.Executor
+}
+
+func newSetStore(db sql.Executor) *setStore {
+	return &setStore{db: db}
+}
+
+// add stores the set of values for the layer and returns the hash of the set.
+func (s *setStore) add(lid types.LayerID, values []types.ProposalID) (types.Hash32, error) {
+	set := NewSet(values)
+	data, err := codec.EncodeSlice(set.ToSlice())
+	if err != nil {
+		return types.Hash32{}, fmt.Errorf("encode set: %w", err)
+	}
+	id := set.ID()
+	if err := haresets.Add(s.db, id, lid, data); err != nil {
+		return types.Hash32{}, err
+	}
+	return id, nil
+}
+
+// get returns the sorted values of the set with the given hash.
+func (s *setStore) get(id types.Hash32) ([]types.ProposalID, error) {
+	data, err := haresets.GetBlob(s.db, id.Bytes())
+	if errors.Is(err, sql.ErrNotFound) {
+		return nil, fmt.Errorf("%w: %s", errUnknownSet, id)
+	} else if err != nil {
+		return nil, err
+	}
+	return codec.DecodeSlice[types.ProposalID](data)
+}
+
+// prune deletes the sets of the layers before the given layer.
+func (s *setStore) prune(lid types.LayerID) error {
+	_, err := haresets.DeleteBefore(s.db, lid)
+	return err
+}
+
+// newSetDelta returns the change from the base set to the set.
+func newSetDelta(base, set *Set) *SetDelta {
+	delta := &SetDelta{Base: base.ID()}
+	for _, id := range set.ToSlice() {
+		if !base.Contains(id) {
+			delta.Add = append(delta.Add, id)
+		}
+	}
+	for _, id := range base.ToSlice() {
+		if !set.Contains(id) {
+			delta.Remove = append(delta.Remove, id)
+		}
+	}
+	return delta
+}
+
+// apply returns the set resulting from applying the delta to the base values.
+func (d *SetDelta) apply(base []types.ProposalID) *Set {
+	set := NewSet(base)
+	for _, id := range d.Remove {
+		set.Remove(id)
+	}
+	for _, id := range d.Add {
+		set.Add(id)
+	}
+	return set
+}
+
+// compactValues replaces the values of the message with a reference to a set stored locally, that peers
+// are able to fetch. If the set changed since the previous set sent by the node, the change is attached
+// to the message, as the receivers are expected to know the previous set.
+func compactValues(store *setStore, msg *Message, prev *Set) (*Message, error) {
+	if _, err := store.add(msg.Layer, msg.Values); err != nil {
+		return nil, fmt.Errorf("store own set: %w", err)
+	}
+	inner := *msg.InnerMessage
+	compact := *msg
+	compact.InnerMessage = &inner
+	if prev != nil && prev.ID() != inner.ValuesHash {
+		delta := newSetDelta(prev, NewSet(inner.Values))
+		if len(delta.Add)+len(delta.Remove) < len(inner.Values) {
+			inner.Delta = delta
+		}
+	}
+	inner.Values = nil
+	return &compact, nil
+}
+
+// resolveValues fills the values of the message that refers to a set by its hash. The values are taken from
+// the message itself, applying the attached change to a known set, the known sets, or fetched from the peer
+// that relayed the message.
+func (b *Broker) resolveValues(ctx context.Context, peer p2p.Peer, msg *Message) error {
+	if len(msg.Values) > 0 || msg.Delta == nil && msg.ValuesHash == emptySetID {
+		if NewSet(msg.Values).ID() != msg.ValuesHash {
+			return fmt.Errorf("%w: values don't match hash", errMalformedMsg)
+		}
+		msg.Delta = nil
+		if msg.Type == pre || msg.Type == status {
+			// the set may be referred to by the later messages of the smesher
+			if _, err := b.sets.add(msg.Layer, msg.Values); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if msg.Delta != nil {
+		if len(msg.Delta.Add)+len(msg.Delta.Remove) == 0 {
+			return fmt.Errorf("%w: empty delta", errMalformedMsg)
+		}
+		base, err := b.sets.get(msg.Delta.Base)
+		if err == nil {
+			set := msg.Delta.apply(base)
+			if set.Size() > maxSetSize || set.ID() != msg.ValuesHash {
+				return fmt.Errorf("%w: delta doesn't match hash", errMalformedMsg)
+			}
+			msg.Values = set.ToSlice()
+			msg.Delta = nil
+			_, err = b.sets.add(msg.Layer, msg.Values)
+			return err
+		} else if !errors.Is(err, errUnknownSet) {
+			return err
+		}
+		// the receiver missed the previous message, the set may still be known or fetched
+		msg.Delta = nil
+	}
+	values, err := b.sets.get(msg.ValuesHash)
+	if errors.Is(err, errUnknownSet) {
+		fetchedSets.Inc()
+		b.fetcher.RegisterPeerHashes(peer, []types.Hash32{msg.ValuesHash})
+		if err := b.fetcher.GetHareSets(ctx, []types.Hash32{msg.ValuesHash}); err != nil {
+			return fmt.Errorf("%w: fetch %s: %s", errUnknownSet, msg.ValuesHash, err)
+		}
+		values, err = b.sets.get(msg.ValuesHash)
+	}
+	if err != nil {
+		return err
+	}
+	msg.Values = values
+	return nil
+}
+
+// handleSyncedSet stores the proposal set fetched from a peer.
+func (b *Broker) handleSyncedSet(_ context.Context, _ p2p.Peer, data []byte) error {
+	values, err := codec.DecodeSlice[types.ProposalID](data)
+	if err != nil {
+		return fmt.Errorf("%w: %s", errMalformedSet, err)
+	}
+	if len(values) > maxSetSize {
+		return fmt.Errorf("%w: too many values %d", errMalformedSet, len(values))
+	}
+	// the set is stored with the latest layer, as the layer it belongs to is unknown
+	if _, err := b.sets.add(b.getLatestLayer(), values); err != nil {
+		return err
+	}
+	return nil
+}
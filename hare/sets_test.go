@@ -0,0 +1,251 @@
+package hare
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/spacemeshos/go-spacemesh/codec"
+	"github.com/spacemeshos/go-spacemesh/common/types"
+	"github.com/spacemeshos/go-spacemesh/hare/mocks"
+	"github.com/spacemeshos/go-spacemesh/p2p"
+	"github.com/spacemeshos/go-spacemesh/p2p/pubsub"
+	"github.com/spacemeshos/go-spacemesh/signing"
+	"github.com/spacemeshos/go-spacemesh/sql"
+	"github.com/spacemeshos/go-spacemesh/sql/haresets"
+)
+
+// setsNetwork is the databases of the nodes created by the test.
+type setsNetwork struct {
+	mu  sync.Mutex
+	dbs []sql.Executor
+}
+
+var setsNetworks sync.Map
+
+// testSetFetcher serves the sets stored by the other nodes of the test, as the fetch service would.
+type testSetFetcher struct {
+	network *setsNetwork
+	db      sql.Executor
+	handler func(context.Context, p2p.Peer, []byte) error
+}
+
+func newTestSetFetcher(tb testing.TB, db sql.Executor) *testSetFetcher {
+	v, loaded := setsNetworks.LoadOrStore(tb, &setsNetwork{})
+	if !loaded {
+		tb.Cleanup(func() { setsNetworks.Delete(tb) })
+	}
+	network := v.(*setsNetwork)
+	network.mu.Lock()
+	defer network.mu.Unlock()
+	network.dbs = append(network.dbs, db)
+	return &testSetFetcher{network: network, db: db}
+}
+
+func (f *testSetFetcher) RegisterPeerHashes(p2p.Peer, []types.Hash32) {}
+
+func (f *testSetFetcher) GetHareSets(ctx context.Context, hashes []types.Hash32) error {
+	f.network.mu.Lock()
+	dbs := f.network.dbs
+	f.network.mu.Unlock()
+	for _, id := range hashes {
+		for _, db := range dbs {
+			if db == f.db {
+				continue
+			}
+			if data, err := haresets.GetBlob(db, id.Bytes()); err == nil {
+				if err := f.handler(ctx, "", data); err != nil {
+					return err
+				}
+				break
+			}
+		}
+	}
+	return nil
+}
+
+func TestSetDelta(t *testing.T) {
+	base := NewSetFromValues(types.ProposalID{1}, types.ProposalID{2}, types.ProposalID{3})
+	set := NewSetFromValues(types.ProposalID{2}, types.ProposalID{3}, types.ProposalID{4})
+	delta := newSetDelta(base, set)
+	require.Equal(t, base.ID(), delta.Base)
+	require.Equal(t, []types.ProposalID{{4}}, delta.Add)
+	require.Equal(t, []types.ProposalID{{1}}, delta.Remove)
+	require.True(t, set.Equals(delta.apply(base.ToSlice())))
+}
+
+func TestCompactValues(t *testing.T) {
+	store := newSetStore(sql.InMemory())
+	sig, err := signing.NewEdSigner()
+	require.NoError(t, err)
+	prev := NewSetFromValues(types.ProposalID{1}, types.ProposalID{2}, types.ProposalID{3})
+	set := NewSetFromValues(types.ProposalID{2}, types.ProposalID{3})
+	msg := buildRoundMsg(t, sig, status, 0, set)
+
+	compact, err := compactValues(store, msg, nil)
+	require.NoError(t, err)
+	require.Empty(t, compact.Values)
+	require.Nil(t, compact.Delta)
+	require.Equal(t, set.ID(), compact.ValuesHash)
+	require.Equal(t, msg.SignedBytes(), compact.SignedBytes())
+	require.Equal(t, set.ToSlice(), msg.Values, "the original message is not modified")
+	values, err := store.get(set.ID())
+	require.NoError(t, err)
+	require.Equal(t, set.ToSlice(), values)
+
+	compact, err = compactValues(store, msg, prev)
+	require.NoError(t, err)
+	require.Empty(t, compact.Values)
+	require.Equal(t, &SetDelta{Base: prev.ID(), Remove: []types.ProposalID{{1}}}, compact.Delta)
+
+	// the change is not attached if it is bigger than the set
+	compact, err = compactValues(store, msg, NewSetFromValues(types.ProposalID{5}))
+	require.NoError(t, err)
+	require.Nil(t, compact.Delta)
+}
+
+func TestBroker_ResolveValues(t *testing.T) {
+	known := NewSetFromValues(types.ProposalID{1}, types.ProposalID{2}, types.ProposalID{3})
+	set := NewSetFromValues(types.ProposalID{2}, types.ProposalID{3}, types.ProposalID{4})
+	for _, tc := range []struct {
+		desc  string
+		msg   func(*Message)
+		known bool
+		err   error
+	}{
+		{
+			desc: "full values",
+			msg:  func(*Message) {},
+		},
+		{
+			desc: "values don't match hash",
+			msg:  func(m *Message) { m.Values = known.ToSlice() },
+			err:  pubsub.ErrValidationReject,
+		},
+		{
+			desc:  "known hash",
+			msg:   func(m *Message) { m.Values = nil },
+			known: true,
+		},
+		{
+			desc: "delta",
+			msg: func(m *Message) {
+				m.Values = nil
+				m.Delta = newSetDelta(known, set)
+			},
+		},
+		{
+			desc: "delta doesn't match hash",
+			msg: func(m *Message) {
+				m.Values = nil
+				m.Delta = newSetDelta(known, set)
+				m.Delta.Add = append(m.Delta.Add, types.ProposalID{5})
+			},
+			err: pubsub.ErrValidationReject,
+		},
+		{
+			desc: "unknown hash",
+			msg:  func(m *Message) { m.Values = nil },
+			err:  errUnknownSet,
+		},
+	} {
+		tc := tc
+		t.Run(tc.desc, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			fetcher := mocks.NewMocksetFetcher(ctrl)
+			b := &Broker{sets: newSetStore(sql.InMemory()), fetcher: fetcher}
+			_, err := b.sets.add(instanceID1, known.ToSlice())
+			require.NoError(t, err)
+			if tc.known {
+				_, err := b.sets.add(instanceID1, set.ToSlice())
+				require.NoError(t, err)
+			}
+			if tc.err == errUnknownSet {
+				fetcher.EXPECT().RegisterPeerHashes(p2p.Peer("peer"), []types.Hash32{set.ID()})
+				fetcher.EXPECT().GetHareSets(gomock.Any(), []types.Hash32{set.ID()})
+			}
+
+			sig, err := signing.NewEdSigner()
+			require.NoError(t, err)
+			msg := buildRoundMsg(t, sig, status, 0, set)
+			tc.msg(msg)
+			err = b.resolveValues(context.Background(), "peer", msg)
+			if tc.err != nil {
+				require.ErrorIs(t, err, tc.err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, set.ToSlice(), msg.Values)
+			require.Nil(t, msg.Delta)
+		})
+	}
+}
+
+// TestBroker_FetchUnknownSet tests that the status message referring to a set unknown to the node
+// is processed in the round once the set is fetched from the peer that relayed the message.
+func TestBroker_FetchUnknownSet(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	sender := buildBroker(t, "sender")
+	receiver := buildBroker(t, "receiver")
+	ctrl := gomock.NewController(t)
+	fetcher := mocks.NewMocksetFetcher(ctrl)
+	receiver.fetcher = fetcher
+	receiver.mockSyncS.EXPECT().IsSynced(gomock.Any()).Return(true).AnyTimes()
+	receiver.mockSyncS.EXPECT().IsBeaconSynced(gomock.Any()).Return(true).AnyTimes()
+	receiver.mockStateQ.EXPECT().IsIdentityActiveOnConsensusView(gomock.Any(), gomock.Any(), gomock.Any()).Return(true, nil).AnyTimes()
+	receiver.mockMesh.EXPECT().GetMalfeasanceProof(gomock.Any()).AnyTimes()
+	receiver.Start(ctx)
+	inbox, _, err := receiver.Register(ctx, instanceID1)
+	require.NoError(t, err)
+
+	sig, err := signing.NewEdSigner()
+	require.NoError(t, err)
+	set := NewSetFromValues(types.ProposalID{1}, types.ProposalID{2})
+	msg, err := compactValues(sender.sets, buildRoundMsg(t, sig, status, 0, set), nil)
+	require.NoError(t, err)
+	require.Empty(t, msg.Values)
+
+	peer := p2p.Peer("relay")
+	fetcher.EXPECT().RegisterPeerHashes(peer, []types.Hash32{set.ID()})
+	fetcher.EXPECT().GetHareSets(gomock.Any(), []types.Hash32{set.ID()}).DoAndReturn(
+		func(ctx context.Context, hashes []types.Hash32) error {
+			data, err := haresets.GetBlob(sender.sets.db, hashes[0].Bytes())
+			require.NoError(t, err)
+			return receiver.handleSyncedSet(ctx, peer, data)
+		})
+	require.NoError(t, receiver.HandleMessage(ctx, peer, msg.Bytes()))
+
+	select {
+	case got := <-inbox:
+		require.Equal(t, set.ToSlice(), got.(*Message).Values)
+	default:
+		require.FailNow(t, "message is not forwarded to the instance")
+	}
+
+	// the set is known after it was fetched
+	known, err := compactValues(sender.sets, buildRoundMsg(t, sig, status, RoundsPerIteration, set), nil)
+	require.NoError(t, err)
+	require.NoError(t, receiver.HandleMessage(ctx, peer, known.Bytes()))
+	require.Len(t, inbox, 1)
+}
+
+func TestHandleSyncedSet(t *testing.T) {
+	b := buildBroker(t, t.Name())
+	set := NewSetFromValues(types.ProposalID{1}, types.ProposalID{2})
+	data, err := codec.EncodeSlice(set.ToSlice())
+	require.NoError(t, err)
+	require.NoError(t, b.handleSyncedSet(context.Background(), "", data))
+	values, err := b.sets.get(set.ID())
+	require.NoError(t, err)
+	require.Equal(t, set.ToSlice(), values)
+
+	require.ErrorIs(t, b.handleSyncedSet(context.Background(), "", []byte{1, 2, 3}), pubsub.ErrValidationReject)
+	data, err = codec.EncodeSlice(make([]types.ProposalID, maxSetSize+1))
+	require.NoError(t, err)
+	require.ErrorIs(t, b.handleSyncedSet(context.Background(), "", data), pubsub.ErrValidationReject)
+}
@@ -0,0 +1,169 @@
+package hare
+
+import (
+	"context"
+
+	"github.com/spacemeshos/go-spacemesh/codec"
+	"github.com/spacemeshos/go-spacemesh/common/types"
+	"github.com/spacemeshos/go-spacemesh/log"
+	"github.com/spacemeshos/go-spacemesh/sql/harestate"
+)
+
+//go:generate scalegen -types Snapshot
+
+// Snapshot is the minimal state of the consensus process that is persisted at the beginning of
+// every round, so that the process can rejoin the protocol after a restart.
+type Snapshot struct {
+	Layer types.LayerID
+	// Round is the round counter (K).
+	Round uint32
+	// CommittedRound is the round in which the Values were committed (Ki).
+	CommittedRound uint32
+	// Values is the current set (S).
+	Values []types.ProposalID `scale:"max=500"`
+	// Certificate for the Values, nil if the Values were not committed.
+	Certificate *Certificate
+	// Provable are the values that are proven by the preround messages.
+	Provable []types.ProposalID `scale:"max=1000"`
+	// Proposal of the leader with the safe value proof, nil if there is no proposal in the current iteration.
+	Proposal *Message
+	// Certificates are the ids of the certificates collected in the last notify round.
+	Certificates []types.Hash32 `scale:"max=1000"`
+}
+
+// snapshot returns the current state of the process.
+func (proc *consensusProcess) snapshot() *Snapshot {
+	s := &Snapshot{
+		Layer:          proc.layer,
+		Round:          proc.getRound(),
+		CommittedRound: proc.committedRound,
+		Values:         proc.value.ToSlice(),
+		Certificate:    proc.certificate,
+		Provable:       proc.preRoundTracker.ProvableValues(),
+	}
+	if proc.proposalTracker != nil && !proc.proposalTracker.IsConflicting() {
+		s.Proposal = proc.proposalTracker.Proposal()
+	}
+	if proc.notifyTracker != nil {
+		s.Certificates = proc.notifyTracker.Certificates()
+	}
+	return s
+}
+
+// persist passes the current state of the process to be persisted.
+func (proc *consensusProcess) persist() {
+	if proc.comm.persist == nil {
+		return
+	}
+	proc.comm.persist(proc.snapshot())
+}
+
+// Restore sets the state of the process to the snapshot. The process resumes from the round
+// of the snapshot instead of the preround. Must be called before Start.
+func (proc *consensusProcess) Restore(s *Snapshot) {
+	proc.setRound(s.Round)
+	proc.committedRound = s.CommittedRound
+	proc.value = NewSet(s.Values)
+	proc.certificate = s.Certificate
+	if proc.comm.legacy {
+		// the snapshot is encoded in the current version and the legacy messages are marked again
+		if s.Certificate != nil {
+			markLegacyAggregated(s.Certificate.AggMsgs)
+		}
+		markLegacy(s.Proposal)
+	}
+	proc.preRoundTracker.restore(s.Provable)
+	pt := newProposalTracker(
+		proc.Log.WithContext(proc.ctx).WithFields(proc.layer),
+		proc.comm.mchOut,
+		proc.eTracker)
+	if s.Proposal != nil {
+		s.Proposal.signedHash = types.BytesToHash(s.Proposal.InnerMessage.HashBytes())
+		pt.proposal = s.Proposal
+	}
+	proc.proposalTracker = pt
+	// certificates are used to validate statuses in the next iteration. if the process is restored
+	// in the notify round the tracker is replaced when the round begins.
+	proc.notifyTracker = newNotifyTracker(
+		proc.Log.WithContext(proc.ctx).WithFields(proc.layer),
+		s.Round,
+		proc.comm.mchOut,
+		proc.eTracker,
+		proc.cfg.N,
+	)
+	for _, id := range s.Certificates {
+		proc.notifyTracker.certificates[id] = struct{}{}
+	}
+	proc.resumed = true
+}
+
+// persistSnapshot stores the state of the consensus process.
+func (h *Hare) persistSnapshot(s *Snapshot) {
+	encoded, err := codec.Encode(s)
+	if err != nil {
+		h.With().Fatal("failed to encode hare state", log.Err(err))
+	}
+	if err := harestate.Set(h.msh.Cache(), s.Layer, h.nodeID, encoded); err != nil {
+		h.With().Error("failed to persist hare state", s.Layer, log.Err(err))
+	}
+}
+
+// resume restores the consensus processes that were running before the restart, if they can still
+// complete within the iterations limit. the state of the instances that are not resumed is deleted.
+func (h *Hare) resume(ctx context.Context) error {
+	var stored []types.LayerID
+	snapshots := map[types.LayerID]*Snapshot{}
+	if err := harestate.IterateAll(h.msh.Cache(), h.nodeID, func(lid types.LayerID, data []byte) bool {
+		stored = append(stored, lid)
+		var s Snapshot
+		if err := codec.Decode(data, &s); err != nil {
+			h.With().Warning("failed to decode hare state", log.Context(ctx), lid, log.Err(err))
+			return true
+		}
+		snapshots[lid] = &s
+		return true
+	}); err != nil {
+		return err
+	}
+	for _, lid := range stored {
+		resumed, err := h.resumeCP(ctx, lid, snapshots[lid])
+		if err != nil {
+			h.With().Warning("failed to resume hare", log.Context(ctx), lid, log.Err(err))
+		}
+		if resumed {
+			continue
+		}
+		if err := harestate.Delete(h.msh.Cache(), lid, h.nodeID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (h *Hare) resumeCP(ctx context.Context, lid types.LayerID, s *Snapshot) (bool, error) {
+	if s == nil {
+		return false, nil
+	}
+	clock := h.newRoundClock(lid)
+	if h.missedWindow(clock) {
+		h.With().Debug("not resuming hare: missed hare window", log.Context(ctx), lid)
+		return false, nil
+	}
+	if decided, err := h.decided(lid); err != nil {
+		return false, err
+	} else if decided {
+		h.With().Debug("not resuming hare: layer already decided", log.Context(ctx), lid)
+		return false, nil
+	}
+	if !h.broker.Synced(ctx, lid) {
+		h.With().Info("not resuming hare: node not synced at this layer", log.Context(ctx), lid)
+		return false, nil
+	}
+	h.With().Info("resuming hare after restart",
+		log.Context(ctx),
+		lid,
+		log.Uint32("round", s.Round),
+	)
+	h.setLastLayer(lid)
+	return h.startCP(ctx, lid, clock, s)
+}
@@ -26,7 +26,7 @@ func buildStatusMsg(sig *signing.EdSigner, s *Set, ki uint32) *Message {
 		SetCommittedRound(ki).
 		SetValues(s).
 		SetEligibilityCount(1)
-	m := builder.Sign(sig).Build()
+	m := signMessage(builder, sig).Build()
 	m.signedHash = types.BytesToHash(m.InnerMessage.HashBytes())
 	return m
 }
@@ -59,6 +59,36 @@ func TestStatusTracker_RecordStatus(t *testing.T) {
 	require.Empty(t, mch)
 }
 
+func TestStatusTracker_HeavyIdentity(t *testing.T) {
+	s := NewSetFromValues(types.ProposalID{1})
+	mch := make(chan *types.MalfeasanceGossip, lowThresh10)
+	et := NewEligibilityTracker(lowThresh10)
+	tracker := newStatusTracker(logtest.New(t), statusRound, mch, et, lowThresh10, lowThresh10)
+
+	light, err := signing.NewEdSigner()
+	require.NoError(t, err)
+	m := BuildStatusMsg(light, s)
+	m.Eligibility.Count = lowThresh10 - 1
+	et.Track(m.SmesherID, m.Round, m.Eligibility.Count, true)
+	tracker.RecordStatus(context.Background(), m)
+	tracker.AnalyzeStatusMessages(func(m *Message) bool { return true })
+	require.False(t, tracker.IsSVPReady())
+
+	// a single identity holding enough slots crosses the threshold alone
+	heavy, err := signing.NewEdSigner()
+	require.NoError(t, err)
+	et = NewEligibilityTracker(lowThresh10)
+	tracker = newStatusTracker(logtest.New(t), statusRound, mch, et, lowThresh10, lowThresh10)
+	m = BuildStatusMsg(heavy, s)
+	m.Eligibility.Count = lowThresh10
+	et.Track(m.SmesherID, m.Round, m.Eligibility.Count, true)
+	tracker.RecordStatus(context.Background(), m)
+	tracker.AnalyzeStatusMessages(func(m *Message) bool { return true })
+	require.True(t, tracker.IsSVPReady())
+	require.Len(t, tracker.BuildSVP().Messages, 1)
+	require.Empty(t, mch)
+}
+
 func TestStatusTracker_BuildUnionSet(t *testing.T) {
 	sig1, err := signing.NewEdSigner()
 	require.NoError(t, err)
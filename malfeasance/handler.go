@@ -15,6 +15,7 @@ import (
 	"github.com/spacemeshos/go-spacemesh/sql"
 	"github.com/spacemeshos/go-spacemesh/sql/atxs"
 	"github.com/spacemeshos/go-spacemesh/sql/identities"
+	"github.com/spacemeshos/go-spacemesh/sql/keyrotations"
 )
 
 var (
@@ -200,6 +201,29 @@ func checkIdentityExists(db sql.Executor, nodeID types.NodeID) error {
 	return nil
 }
 
+// verifySignature checks that the message of the identity is signed by one of the keys accepted in the epoch,
+// so that messages signed by the previous and the rotated key are attributed to the same identity.
+func verifySignature(
+	db sql.Executor,
+	edVerifier SigVerifier,
+	d signing.Domain,
+	nodeID types.NodeID,
+	epoch types.EpochID,
+	msg []byte,
+	sig types.EdSignature,
+) error {
+	valid, err := keyrotations.Verify(db, nodeID, epoch, func(key types.NodeID) bool {
+		return edVerifier.Verify(d, key, msg, sig)
+	})
+	if err != nil {
+		return err
+	}
+	if !valid {
+		return errors.New("invalid signature")
+	}
+	return nil
+}
+
 func validateHareEligibility(
 	ctx context.Context,
 	logger log.Log,
@@ -240,8 +264,8 @@ func validateHareEquivocation(
 		return types.EmptyNodeID, errors.New("wrong message type for hare equivocation")
 	}
 	for _, msg := range hp.Messages {
-		if !edVerifier.Verify(signing.HARE, msg.SmesherID, msg.SignedBytes(), msg.Signature) {
-			return types.EmptyNodeID, errors.New("invalid signature")
+		if err := verifySignature(db, edVerifier, signing.HARE, msg.SmesherID, msg.InnerMsg.Layer.GetEpoch(), msg.SignedBytes(), msg.Signature); err != nil {
+			return types.EmptyNodeID, err
 		}
 		if firstNid == types.EmptyNodeID {
 			if err := checkIdentityExists(db, msg.SmesherID); err != nil {
@@ -287,8 +311,8 @@ func validateMultipleATXs(
 		return types.EmptyNodeID, errors.New("wrong message type for multiple ATXs")
 	}
 	for _, msg := range ap.Messages {
-		if !edVerifier.Verify(signing.ATX, msg.SmesherID, msg.SignedBytes(), msg.Signature) {
-			return types.EmptyNodeID, errors.New("invalid signature")
+		if err := verifySignature(db, edVerifier, signing.ATX, msg.SmesherID, msg.InnerMsg.PublishEpoch, msg.SignedBytes(), msg.Signature); err != nil {
+			return types.EmptyNodeID, err
 		}
 		if firstNid == types.EmptyNodeID {
 			if err := checkIdentityExists(db, msg.SmesherID); err != nil {
@@ -334,8 +358,8 @@ func validateMultipleBallots(
 		return types.EmptyNodeID, errors.New("wrong message type for multi ballots")
 	}
 	for _, msg := range bp.Messages {
-		if !edVerifier.Verify(signing.BALLOT, msg.SmesherID, msg.SignedBytes(), msg.Signature) {
-			return types.EmptyNodeID, errors.New("invalid signature")
+		if err := verifySignature(db, edVerifier, signing.BALLOT, msg.SmesherID, msg.InnerMsg.Layer.GetEpoch(), msg.SignedBytes(), msg.Signature); err != nil {
+			return types.EmptyNodeID, err
 		}
 		if firstNid == types.EmptyNodeID {
 			if err = checkIdentityExists(db, msg.SmesherID); err != nil {
@@ -20,6 +20,7 @@ import (
 	"github.com/spacemeshos/go-spacemesh/sql"
 	"github.com/spacemeshos/go-spacemesh/sql/atxs"
 	"github.com/spacemeshos/go-spacemesh/sql/identities"
+	"github.com/spacemeshos/go-spacemesh/sql/keyrotations"
 )
 
 func TestMain(m *testing.M) {
@@ -942,6 +943,66 @@ func TestHandler_HandleSyncedMalfeasanceProof_multipleBallots(t *testing.T) {
 	require.True(t, malicious)
 }
 
+func TestHandler_HandleSyncedMalfeasanceProof_multipleBallotsRotatedKey(t *testing.T) {
+	db := sql.InMemory()
+	lg := logtest.New(t)
+	ctrl := gomock.NewController(t)
+	trt := malfeasance.NewMocktortoise(ctrl)
+	mcp := malfeasance.NewMockconsensusProtocol(ctrl)
+	sigVerifier, err := signing.NewEdVerifier()
+	require.NoError(t, err)
+
+	h := malfeasance.NewHandler(datastore.NewCachedDB(db, lg), lg, "self", mcp, sigVerifier, trt)
+	sig, err := signing.NewEdSigner()
+	require.NoError(t, err)
+	rotated, err := signing.NewEdSigner()
+	require.NoError(t, err)
+	createIdentity(t, db, sig)
+
+	// ballots of the identity in the transition epoch, signed by the previous and the rotated key
+	lid := types.LayerID(11)
+	bp := types.BallotProof{
+		Messages: [2]types.BallotProofMsg{
+			{
+				InnerMsg: types.BallotMetadata{
+					Layer:   lid,
+					MsgHash: types.RandomHash(),
+				},
+			},
+			{
+				InnerMsg: types.BallotMetadata{
+					Layer:   lid,
+					MsgHash: types.RandomHash(),
+				},
+			},
+		},
+	}
+	bp.Messages[0].Signature = sig.Sign(signing.BALLOT, bp.Messages[0].SignedBytes())
+	bp.Messages[0].SmesherID = sig.NodeID()
+	bp.Messages[1].Signature = rotated.Sign(signing.BALLOT, bp.Messages[1].SignedBytes())
+	bp.Messages[1].SmesherID = sig.NodeID()
+	proof := types.MalfeasanceProof{
+		Layer: lid,
+		Proof: types.Proof{
+			Type: types.MultipleBallots,
+			Data: &bp,
+		},
+	}
+	data, err := codec.Encode(&proof)
+	require.NoError(t, err)
+	require.ErrorContains(t, h.HandleSyncedMalfeasanceProof(context.Background(), "peer", data), "invalid signature")
+
+	require.NoError(t, keyrotations.Add(db, &types.KeyRotation{
+		InnerKeyRotation: types.InnerKeyRotation{NodeID: sig.NodeID(), NewKey: rotated.NodeID(), Epoch: lid.GetEpoch()},
+	}))
+	trt.EXPECT().OnMalfeasance(sig.NodeID())
+	require.NoError(t, h.HandleSyncedMalfeasanceProof(context.Background(), "peer", data))
+
+	malicious, err := identities.IsMalicious(db, sig.NodeID())
+	require.NoError(t, err)
+	require.True(t, malicious)
+}
+
 func TestHandler_HandleSyncedMalfeasanceProof_hareEquivocation(t *testing.T) {
 	db := sql.InMemory()
 	lg := logtest.New(t)
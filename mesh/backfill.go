@@ -0,0 +1,85 @@
+package mesh
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/spacemeshos/go-spacemesh/common/types"
+	"github.com/spacemeshos/go-spacemesh/sql"
+	"github.com/spacemeshos/go-spacemesh/sql/ballots"
+	"github.com/spacemeshos/go-spacemesh/sql/blocks"
+	"github.com/spacemeshos/go-spacemesh/sql/certificates"
+)
+
+// LayerBlock is a block together with the validity stored for it.
+type LayerBlock struct {
+	*types.Block
+	Valid bool
+}
+
+// LayerData is the data stored for the layer that is needed to replay consensus on the layer.
+type LayerData struct {
+	Layer   types.LayerID
+	Ballots []*types.Ballot
+	Blocks  []LayerBlock
+	// HareOutput is nil if hare output for the layer is not known.
+	HareOutput *types.BlockID
+}
+
+// IterateLayers loads layers in [from, to] from the database and passes them to fn in layer order.
+// Layers are loaded one at a time, so that the memory used is bounded by the largest layer
+// rather than by the size of the range.
+// Iteration is interrupted if fn returns an error, and the error is returned.
+func IterateLayers(db sql.Executor, from, to types.LayerID, fn func(*LayerData) error) error {
+	for lid := from; !lid.After(to); lid = lid.Add(1) {
+		data, err := loadLayer(db, lid)
+		if err != nil {
+			return err
+		}
+		if err := fn(data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func loadLayer(db sql.Executor, lid types.LayerID) (*LayerData, error) {
+	data := &LayerData{Layer: lid}
+	if err := ballots.IterateLayer(db, lid, func(ballot *types.Ballot) bool {
+		data.Ballots = append(data.Ballots, ballot)
+		return true
+	}); err != nil {
+		return nil, err
+	}
+	if err := blocks.IterateLayer(db, lid, func(block *types.Block) bool {
+		data.Blocks = append(data.Blocks, LayerBlock{Block: block})
+		return true
+	}); err != nil {
+		return nil, err
+	}
+	if len(data.Blocks) > 0 {
+		validity, err := blocks.ContextualValidity(db, lid)
+		if err != nil {
+			return nil, err
+		}
+		valid := make(map[types.BlockID]bool, len(validity))
+		for _, v := range validity {
+			valid[v.ID] = v.Validity
+		}
+		for i := range data.Blocks {
+			data.Blocks[i].Valid = valid[data.Blocks[i].ID()]
+		}
+	}
+	hare, err := certificates.GetHareOutput(db, lid)
+	if err == nil {
+		data.HareOutput = &hare
+	} else if !errors.Is(err, sql.ErrNotFound) {
+		return nil, fmt.Errorf("hare output %s: %w", lid, err)
+	}
+	return data, nil
+}
+
+// IterateLayers passes the ballots, blocks and hare outputs of layers in [from, to] to fn, one layer at a time.
+func (msh *Mesh) IterateLayers(from, to types.LayerID, fn func(*LayerData) error) error {
+	return IterateLayers(msh.cdb, from, to, fn)
+}
@@ -0,0 +1,136 @@
+package mesh
+
+import (
+	"errors"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/spacemeshos/go-spacemesh/common/types"
+	"github.com/spacemeshos/go-spacemesh/datastore"
+	"github.com/spacemeshos/go-spacemesh/log/logtest"
+	"github.com/spacemeshos/go-spacemesh/sql"
+	"github.com/spacemeshos/go-spacemesh/sql/ballots"
+	"github.com/spacemeshos/go-spacemesh/sql/blocks"
+	"github.com/spacemeshos/go-spacemesh/sql/certificates"
+)
+
+func TestMesh_IterateLayers(t *testing.T) {
+	tm := createTestMesh(t)
+	first := types.GetEffectiveGenesis().Add(1)
+	last := first.Add(3)
+	expected := map[types.LayerID]*LayerData{}
+	for lid := first; !lid.After(last); lid = lid.Add(1) {
+		data := &LayerData{Layer: lid, Ballots: createLayerBallots(t, tm.Mesh, lid)}
+		if lid == first.Add(1) {
+			// layer without blocks
+			expected[lid] = data
+			continue
+		}
+		for i, block := range createLayerBlocks(t, tm.db, tm.Mesh, lid) {
+			if i == 0 {
+				require.NoError(t, blocks.SetValid(tm.db, block.ID()))
+			}
+			data.Blocks = append(data.Blocks, LayerBlock{Block: block, Valid: i == 0})
+		}
+		if lid != last {
+			hare := data.Blocks[1].ID()
+			require.NoError(t, certificates.SetHareOutput(tm.db, lid, hare))
+			data.HareOutput = &hare
+		}
+		expected[lid] = data
+	}
+
+	var got []types.LayerID
+	require.NoError(t, tm.IterateLayers(first, last, func(data *LayerData) error {
+		got = append(got, data.Layer)
+		exp := expected[data.Layer]
+		require.ElementsMatch(t, exp.Ballots, data.Ballots)
+		require.Len(t, data.Blocks, len(exp.Blocks))
+		for _, block := range exp.Blocks {
+			require.Contains(t, data.Blocks, block)
+		}
+		require.Equal(t, exp.HareOutput, data.HareOutput)
+		return nil
+	}))
+	require.Equal(t, []types.LayerID{first, first.Add(1), first.Add(2), last}, got)
+
+	errStop := errors.New("stop")
+	got = nil
+	require.ErrorIs(t, tm.IterateLayers(first, last, func(data *LayerData) error {
+		got = append(got, data.Layer)
+		return errStop
+	}), errStop)
+	require.Equal(t, []types.LayerID{first}, got)
+}
+
+// BenchmarkLoadLayers compares the peak heap usage of loading 500 layers with 50 ballots each
+// at once and streaming them one layer at a time.
+func BenchmarkLoadLayers(b *testing.B) {
+	const (
+		count     = 500
+		perLayer  = 50
+		activeSet = 100
+	)
+	db := datastore.NewCachedDB(sql.InMemory(), logtest.New(b))
+	first := types.GetEffectiveGenesis().Add(1)
+	last := first.Add(count - 1)
+	for lid := first; !lid.After(last); lid = lid.Add(1) {
+		for i := 0; i < perLayer; i++ {
+			ballot := genLayerBallot(b, lid)
+			ballot.EpochData = &types.EpochData{ActiveSetHash: types.RandomHash(), Beacon: types.RandomBeacon()}
+			ballot.ActiveSet = types.RandomActiveSet(activeSet)
+			require.NoError(b, ballots.Add(db, ballot))
+		}
+	}
+
+	var ms runtime.MemStats
+	heap := func() uint64 {
+		runtime.ReadMemStats(&ms)
+		return ms.HeapAlloc
+	}
+	load := func(b *testing.B, fn func(sample func()) int) {
+		var peak uint64
+		for i := 0; i < b.N; i++ {
+			runtime.GC()
+			base := heap()
+			sample := func() {
+				if used := heap(); used > base && used-base > peak {
+					peak = used - base
+				}
+			}
+			require.Equal(b, count*perLayer, fn(sample))
+		}
+		b.ReportMetric(float64(peak), "peak-heap-B")
+	}
+	b.Run("All", func(b *testing.B) {
+		load(b, func(sample func()) int {
+			var all [][]*types.Ballot
+			for lid := first; !lid.After(last); lid = lid.Add(1) {
+				blts, err := ballots.Layer(db, lid)
+				require.NoError(b, err)
+				all = append(all, blts)
+			}
+			sample()
+			loaded := 0
+			for _, blts := range all {
+				loaded += len(blts)
+			}
+			return loaded
+		})
+	})
+	b.Run("Stream", func(b *testing.B) {
+		load(b, func(sample func()) int {
+			loaded := 0
+			require.NoError(b, IterateLayers(db, first, last, func(data *LayerData) error {
+				loaded += len(data.Ballots)
+				if data.Layer.Difference(first)%50 == 0 {
+					sample()
+				}
+				return nil
+			}))
+			return loaded
+		})
+	})
+}
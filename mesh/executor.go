@@ -1,19 +1,20 @@
 package mesh
 
 import (
-	"bytes"
 	"context"
 	"errors"
 	"fmt"
-	"sort"
 	"sync"
 	"time"
 
 	"github.com/spacemeshos/go-spacemesh/common/types"
 	"github.com/spacemeshos/go-spacemesh/datastore"
+	"github.com/spacemeshos/go-spacemesh/events"
 	vm "github.com/spacemeshos/go-spacemesh/genvm"
 	"github.com/spacemeshos/go-spacemesh/log"
+	"github.com/spacemeshos/go-spacemesh/sql"
 	"github.com/spacemeshos/go-spacemesh/sql/layers"
+	"github.com/spacemeshos/go-spacemesh/sql/rewards"
 	"github.com/spacemeshos/go-spacemesh/sql/transactions"
 	"github.com/spacemeshos/go-spacemesh/txs"
 )
@@ -30,6 +31,9 @@ type Executor struct {
 	cs     conservativeState
 
 	mu sync.Mutex
+	// reorged is the last layer that was applied before the latest revert.
+	// Layers up to it are applied again and the events for them are reported with the reorg flag.
+	reorged types.LayerID
 }
 
 func NewExecutor(cdb *datastore.CachedDB, vm vmState, cs conservativeState, lg log.Log) *Executor {
@@ -47,6 +51,20 @@ func (e *Executor) Revert(ctx context.Context, revertTo types.LayerID) error {
 	defer e.mu.Unlock()
 
 	logger := e.logger.WithContext(ctx).WithFields(log.Stringer("revert_to", revertTo))
+	applied, err := layers.GetLastApplied(e.cdb)
+	if err != nil {
+		return fmt.Errorf("executor get last applied: %w", err)
+	}
+	// the layers are marked as not applied before the state is reverted, so that if the node
+	// is interrupted the state is reverted again on restart to the last applied layer
+	if err := e.cdb.WithTx(ctx, func(dbtx *sql.Tx) error {
+		return layers.UnsetAppliedFrom(dbtx, revertTo.Add(1))
+	}); err != nil {
+		return fmt.Errorf("unset applied from %v: %w", revertTo.Add(1), err)
+	}
+	if applied.After(revertTo) {
+		e.reorged = types.MaxLayer(e.reorged, applied)
+	}
 	if err := e.vm.Revert(revertTo); err != nil {
 		return fmt.Errorf("revert state: %w", err)
 	}
@@ -67,7 +85,7 @@ func (e *Executor) ExecuteOptimistic(
 	ctx context.Context,
 	lid types.LayerID,
 	tickHeight uint64,
-	rewards []types.AnyReward,
+	blockRewards []types.AnyReward,
 	tids []types.TransactionID,
 ) (*types.Block, error) {
 	e.mu.Lock()
@@ -79,11 +97,11 @@ func (e *Executor) ExecuteOptimistic(
 	if err := e.checkOrder(lid); err != nil {
 		return nil, err
 	}
-	executable, err := e.getExecutableTxs(tids)
+	executable, expired, err := e.getExecutableTxs(lid, tids)
 	if err != nil {
 		return nil, err
 	}
-	crewards, err := e.convertRewards(rewards)
+	crewards, err := e.layerRewards(lid, blockRewards)
 	if err != nil {
 		return nil, err
 	}
@@ -91,11 +109,15 @@ func (e *Executor) ExecuteOptimistic(
 	if err != nil {
 		return nil, fmt.Errorf("apply txs optimistically: %w", err)
 	}
+	ineffective = append(ineffective, expired...)
+	if err := rewards.SetLayer(e.cdb, lid, blockRewards); err != nil {
+		return nil, err
+	}
 	b := &types.Block{
 		InnerBlock: types.InnerBlock{
 			LayerIndex: lid,
 			TickHeight: tickHeight,
-			Rewards:    rewards,
+			Rewards:    blockRewards,
 		},
 	}
 	for _, tx := range executed {
@@ -106,9 +128,9 @@ func (e *Executor) ExecuteOptimistic(
 	if err = e.cs.UpdateCache(ctx, lid, b.ID(), executed, ineffective); err != nil {
 		return nil, fmt.Errorf("update cache: %w", err)
 	}
-	state, err := e.vm.GetStateRoot()
+	state, err := e.persistStateRoot(lid)
 	if err != nil {
-		return nil, fmt.Errorf("get state hash: %w", err)
+		return nil, err
 	}
 	logger.Event().Info("optimistically executed block",
 		log.Stringer("block", b.ID()),
@@ -134,25 +156,35 @@ func (e *Executor) Execute(ctx context.Context, lid types.LayerID, block *types.
 	}
 
 	logger := e.logger.WithContext(ctx).WithFields(lid, block.ID())
-	executable, err := e.getExecutableTxs(block.TxIDs)
+	executable, expired, err := e.getExecutableTxs(block.LayerIndex, block.TxIDs)
 	if err != nil {
 		return err
 	}
-	rewards, err := e.convertRewards(block.Rewards)
+	crewards, err := e.layerRewards(block.LayerIndex, block.Rewards)
 	if err != nil {
 		return err
 	}
-	ineffective, executed, err := e.vm.Apply(vm.ApplyContext{Layer: block.LayerIndex}, executable, rewards)
+	reorg := e.isReorg(lid)
+	ineffective, executed, err := e.vm.Apply(vm.ApplyContext{Layer: block.LayerIndex, Reorg: reorg}, executable, crewards)
 	if err != nil {
 		return fmt.Errorf("apply block: %w", err)
 	}
+	ineffective = append(ineffective, expired...)
+	if err := rewards.SetLayer(e.cdb, block.LayerIndex, block.Rewards); err != nil {
+		return err
+	}
 	updateResults(block.ID(), executed)
 	if err = e.cs.UpdateCache(ctx, block.LayerIndex, block.ID(), executed, ineffective); err != nil {
 		return fmt.Errorf("update cache: %w", err)
 	}
-	state, err := e.vm.GetStateRoot()
+	if reorg {
+		for i := range executed {
+			events.ReportReorgedTx(lid, &executed[i].Transaction)
+		}
+	}
+	state, err := e.persistStateRoot(lid)
 	if err != nil {
-		return fmt.Errorf("get state hash: %w", err)
+		return err
 	}
 	logger.Event().Info("executed block",
 		log.Stringer("block", block.ID()),
@@ -163,36 +195,18 @@ func (e *Executor) Execute(ctx context.Context, lid types.LayerID, block *types.
 	return nil
 }
 
-func (e *Executor) convertRewards(rewards []types.AnyReward) ([]types.CoinbaseReward, error) {
-	res := make([]types.CoinbaseReward, 0, len(rewards))
-	for _, r := range rewards {
-		atx, err := e.cdb.GetAtxHeader(r.AtxID)
-		if err != nil {
-			return nil, fmt.Errorf("exec convert rewards: %w", err)
-		}
-		res = append(res, types.CoinbaseReward{
-			Coinbase: atx.Coinbase,
-			Weight:   r.Weight,
-		})
-	}
-	sort.Slice(res, func(i, j int) bool {
-		return bytes.Compare(res[i].Coinbase.Bytes(), res[j].Coinbase.Bytes()) < 0
-	})
-	return res, nil
-}
-
 func (e *Executor) executeEmpty(ctx context.Context, lid types.LayerID) error {
 	start := time.Now()
 	logger := e.logger.WithContext(ctx).WithFields(lid)
-	if _, _, err := e.vm.Apply(vm.ApplyContext{Layer: lid}, nil, nil); err != nil {
+	if _, _, err := e.vm.Apply(vm.ApplyContext{Layer: lid, Reorg: e.isReorg(lid)}, nil, nil); err != nil {
 		return fmt.Errorf("apply empty layer: %w", err)
 	}
 	if err := e.cs.UpdateCache(ctx, lid, types.EmptyBlockID, nil, nil); err != nil {
 		return fmt.Errorf("update cache: %w", err)
 	}
-	state, err := e.vm.GetStateRoot()
+	state, err := e.persistStateRoot(lid)
 	if err != nil {
-		return fmt.Errorf("get state hash: %w", err)
+		return err
 	}
 	logger.Event().Info("executed empty layer",
 		log.Stringer("state_hash", state),
@@ -201,6 +215,24 @@ func (e *Executor) executeEmpty(ctx context.Context, lid types.LayerID) error {
 	return nil
 }
 
+// persistStateRoot records the state root after the layer was applied. The roots of the reverted layers
+// are removed by Revert and recorded again when the layers are applied after the reorg.
+func (e *Executor) persistStateRoot(lid types.LayerID) (types.Hash32, error) {
+	state, err := e.vm.GetStateRoot()
+	if err != nil {
+		return types.Hash32{}, fmt.Errorf("get state hash: %w", err)
+	}
+	if err := layers.UpdateStateHash(e.cdb, lid, state); err != nil {
+		return types.Hash32{}, fmt.Errorf("persist state hash %v: %w", lid, err)
+	}
+	return state, nil
+}
+
+// isReorg returns true if the layer was applied before the latest revert.
+func (e *Executor) isReorg(lid types.LayerID) bool {
+	return !lid.After(e.reorged)
+}
+
 func (e *Executor) checkOrder(lid types.LayerID) error {
 	inState, err := layers.GetLastApplied(e.cdb)
 	if err != nil {
@@ -222,20 +254,34 @@ func updateResults(bid types.BlockID, executed []types.TransactionWithResult) {
 }
 
 // getExecutableTxs retrieves a list of txs filtering transaction that were previously executed.
-func (e *Executor) getExecutableTxs(ids []types.TransactionID) ([]types.Transaction, error) {
-	etxs := make([]types.Transaction, 0, len(ids))
+// getExecutableTxs returns the txs to execute in the layer, and the txs that expired before the layer.
+// expired txs are skipped as ineffective instead of failing the block, the expiry is checked against
+// the layer of the block rather than the layer when the block is executed.
+func (e *Executor) getExecutableTxs(lid types.LayerID, ids []types.TransactionID) ([]types.Transaction, []types.Transaction, error) {
+	var (
+		etxs    = make([]types.Transaction, 0, len(ids))
+		expired []types.Transaction
+	)
 	for _, tid := range ids {
 		mtx, err := transactions.Get(e.cdb, tid)
 		if err != nil {
-			return nil, fmt.Errorf("executor get tx: %w", err)
+			return nil, nil, fmt.Errorf("executor get tx: %w", err)
 		}
 		if mtx.State == types.APPLIED {
 			continue
 		}
+		if mtx.TxHeader != nil && mtx.Expired(lid) {
+			e.logger.With().Warning("ineffective transaction. expired",
+				tid,
+				lid,
+				log.Stringer("expiry", mtx.Expiry()))
+			expired = append(expired, mtx.Transaction)
+			continue
+		}
 		if mtx.TxHeader == nil {
 			txs.RawTxCount.WithLabelValues(txs.RawFromDB).Inc()
 		}
 		etxs = append(etxs, mtx.Transaction)
 	}
-	return etxs, nil
+	return etxs, expired, nil
 }
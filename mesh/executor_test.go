@@ -13,8 +13,11 @@ import (
 	"github.com/stretchr/testify/require"
 
 	"github.com/spacemeshos/go-spacemesh/activation"
+	"github.com/spacemeshos/go-spacemesh/common/fixture"
 	"github.com/spacemeshos/go-spacemesh/common/types"
+	"github.com/spacemeshos/go-spacemesh/common/types/result"
 	"github.com/spacemeshos/go-spacemesh/datastore"
+	"github.com/spacemeshos/go-spacemesh/events"
 	vm "github.com/spacemeshos/go-spacemesh/genvm"
 	"github.com/spacemeshos/go-spacemesh/log/logtest"
 	"github.com/spacemeshos/go-spacemesh/mesh"
@@ -23,6 +26,8 @@ import (
 	"github.com/spacemeshos/go-spacemesh/sql"
 	"github.com/spacemeshos/go-spacemesh/sql/atxs"
 	"github.com/spacemeshos/go-spacemesh/sql/layers"
+	"github.com/spacemeshos/go-spacemesh/sql/transactions"
+	smocks "github.com/spacemeshos/go-spacemesh/system/mocks"
 )
 
 func TestMain(m *testing.M) {
@@ -220,6 +225,48 @@ func TestExecutor_Execute(t *testing.T) {
 	})
 }
 
+func TestExecutor_ExecuteExpired(t *testing.T) {
+	te := newTestExecutor(t)
+	lid := types.GetEffectiveGenesis()
+	require.NoError(t, layers.SetApplied(te.db, lid, types.EmptyBlockID))
+	lid = lid.Add(1)
+
+	var txs []types.Transaction
+	for _, expiry := range []types.LayerID{lid.Sub(1), lid, 0} {
+		tx := types.Transaction{
+			RawTx: types.NewRawTx(types.RandomBytes(100)),
+			TxHeader: &types.TxHeader{
+				Principal:   types.GenerateAddress(types.RandomBytes(32)),
+				LayerLimits: types.LayerLimits{Max: expiry.Uint32()},
+				GasPrice:    1,
+			},
+		}
+		require.NoError(t, transactions.Add(te.db, &tx, time.Now()))
+		txs = append(txs, tx)
+	}
+	block := types.NewExistingBlock(types.RandomBlockID(), types.InnerBlock{
+		LayerIndex: lid,
+		TxIDs:      []types.TransactionID{txs[0].ID, txs[1].ID, txs[2].ID},
+	})
+	// the tx that expired before the layer of the block is skipped, the block is still applied
+	executed := makeResults(lid, txs[1:]...)
+	te.mvm.EXPECT().Apply(vm.ApplyContext{Layer: lid}, gomock.Any(), []types.CoinbaseReward{}).DoAndReturn(
+		func(_ vm.ApplyContext, got []types.Transaction, _ []types.CoinbaseReward) ([]types.Transaction, []types.TransactionWithResult, error) {
+			require.Len(t, got, 2)
+			require.Equal(t, txs[1].ID, got[0].ID)
+			require.Equal(t, txs[2].ID, got[1].ID)
+			return nil, executed, nil
+		})
+	te.mcs.EXPECT().UpdateCache(gomock.Any(), lid, block.ID(), executed, gomock.Any()).DoAndReturn(
+		func(_ context.Context, _ types.LayerID, _ types.BlockID, _ []types.TransactionWithResult, ineffective []types.Transaction) error {
+			require.Len(t, ineffective, 1)
+			require.Equal(t, txs[0].ID, ineffective[0].ID)
+			return nil
+		})
+	te.mvm.EXPECT().GetStateRoot()
+	require.NoError(t, te.exec.Execute(context.Background(), lid, block))
+}
+
 func TestExecutor_ExecuteOptimistic(t *testing.T) {
 	te := newTestExecutor(t)
 	lid := types.GetEffectiveGenesis()
@@ -384,6 +431,7 @@ func TestExecutor_ExecuteOptimistic(t *testing.T) {
 func TestExecutor_Revert(t *testing.T) {
 	te := newTestExecutor(t)
 	lid := types.GetEffectiveGenesis()
+	require.NoError(t, layers.SetApplied(te.db, lid, types.EmptyBlockID))
 	require.NoError(t, layers.SetApplied(te.db, lid.Add(1), types.RandomBlockID()))
 
 	errInconceivable := errors.New("inconceivable")
@@ -403,5 +451,288 @@ func TestExecutor_Revert(t *testing.T) {
 		te.mcs.EXPECT().RevertCache(lid)
 		te.mvm.EXPECT().GetStateRoot()
 		require.NoError(t, te.exec.Revert(context.Background(), lid))
+		_, err := layers.GetApplied(te.db, lid.Add(1))
+		require.ErrorIs(t, err, sql.ErrNotFound)
 	})
+
+	t.Run("reapplied layer is reported as reorg", func(t *testing.T) {
+		for _, reapplied := range []types.LayerID{lid.Add(1), lid.Add(2)} {
+			te.mvm.EXPECT().Apply(vm.ApplyContext{Layer: reapplied, Reorg: reapplied == lid.Add(1)}, nil, nil)
+			te.mcs.EXPECT().UpdateCache(gomock.Any(), reapplied, types.EmptyBlockID, nil, nil)
+			te.mvm.EXPECT().GetStateRoot()
+			require.NoError(t, te.exec.Execute(context.Background(), reapplied, nil))
+			require.NoError(t, layers.SetApplied(te.db, reapplied, types.EmptyBlockID))
+		}
+	})
+}
+
+// stateMesh is a mesh that applies blocks to the state with the vm.
+type stateMesh struct {
+	*mesh.Mesh
+	exec *mesh.Executor
+	vm   *vm.VM
+	trtl *smocks.MockTortoise
+	// executed are the transactions executed by the vm.
+	executed []types.TransactionWithResult
+}
+
+func newStateMesh(tb testing.TB, db *sql.Database, opts ...mesh.Opt) *stateMesh {
+	tb.Helper()
+	lg := logtest.New(tb)
+	cdb := datastore.NewCachedDB(db, lg)
+	ctrl := gomock.NewController(tb)
+	cs := mocks.NewMockconservativeState(ctrl)
+	n := &stateMesh{vm: vm.New(db, vm.WithLogger(lg)), trtl: smocks.NewMockTortoise(ctrl)}
+	cs.EXPECT().UpdateCache(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+		func(_ context.Context, _ types.LayerID, _ types.BlockID, executed []types.TransactionWithResult, _ []types.Transaction) error {
+			n.executed = append(n.executed, executed...)
+			return nil
+		}).AnyTimes()
+	cs.EXPECT().RevertCache(gomock.Any()).AnyTimes()
+	cs.EXPECT().LinkTXsWithBlock(gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes()
+	n.trtl.EXPECT().TallyVotes(gomock.Any(), gomock.Any()).AnyTimes()
+	n.trtl.EXPECT().OnBlock(gomock.Any()).AnyTimes()
+	n.exec = mesh.NewExecutor(cdb, n.vm, cs, lg)
+	msh, err := mesh.NewMesh(cdb, mocks.NewMocklayerClock(ctrl), n.trtl, n.exec, cs, lg, opts...)
+	require.NoError(tb, err)
+	n.Mesh = msh
+	return n
+}
+
+// process adds the blocks and processes the layer with the blocks as valid in the tortoise results.
+func (n *stateMesh) process(tb testing.TB, lid types.LayerID, blocks ...*types.Block) {
+	tb.Helper()
+	var updates []result.Layer
+	for _, block := range blocks {
+		_, err := n.AddBlockWithTXs(context.Background(), block)
+		require.NoError(tb, err)
+		updates = append(updates, fixture.RLayer(block.LayerIndex, fixture.RBlock(block.ID(), fixture.Good())))
+	}
+	n.trtl.EXPECT().Updates().Return(updates)
+	require.NoError(tb, n.ProcessLayer(context.Background(), lid))
+}
+
+// rewardedBlock returns a block that rewards the coinbase of the atx.
+func rewardedBlock(lid types.LayerID, atx types.ATXID) *types.Block {
+	block := types.NewExistingBlock(types.BlockID{}, types.InnerBlock{
+		LayerIndex: lid,
+		Rewards:    []types.AnyReward{{AtxID: atx, Weight: types.RatNum{Num: 1, Denom: 1}}},
+	})
+	block.Initialize()
+	return block
+}
+
+func TestExecutor_RevertReorg(t *testing.T) {
+	events.CloseEventReporter()
+	events.InitializeReporter()
+	t.Cleanup(events.CloseEventReporter)
+	sub := events.SubscribeRewards()
+
+	genesis := types.GetEffectiveGenesis()
+	last := genesis.Add(8)
+	flipped := last.Sub(4)
+	cbs := []types.Address{{1}, {2}}
+
+	var activations []*types.VerifiedActivationTx
+	newNode := func() *stateMesh {
+		db := sql.InMemory()
+		if activations == nil {
+			cdb := datastore.NewCachedDB(db, logtest.New(t))
+			for _, cb := range cbs {
+				atx, err := cdb.GetFullAtx(createATX(t, db, cb))
+				require.NoError(t, err)
+				activations = append(activations, atx)
+			}
+		} else {
+			for _, atx := range activations {
+				require.NoError(t, atxs.Add(db, atx))
+			}
+		}
+		return newStateMesh(t, db)
+	}
+	rewarded := func(lid types.LayerID, atx int) *types.Block {
+		return rewardedBlock(lid, activations[atx].ID())
+	}
+	drain := func() (rst []events.Reward) {
+		for {
+			select {
+			case ev := <-sub.Out():
+				rst = append(rst, ev.(events.Reward))
+			default:
+				return rst
+			}
+		}
+	}
+
+	n := newNode()
+	var final []*types.Block
+	for lid := genesis.Add(1); !last.Before(lid); lid = lid.Add(1) {
+		block := rewarded(lid, 0)
+		n.process(t, lid, block)
+		final = append(final, block)
+	}
+	for _, reward := range drain() {
+		require.False(t, reward.Reorg)
+	}
+
+	// the block at depth 5 is invalidated by the tortoise, the other block rewarding other coinbase is valid
+	replacement := rewarded(flipped, 1)
+	_, err := n.AddBlockWithTXs(context.Background(), replacement)
+	require.NoError(t, err)
+	updates := []result.Layer{fixture.RLayer(flipped,
+		fixture.RBlock(final[flipped.Difference(genesis)-1].ID(), fixture.Invalid(), fixture.Data()),
+		fixture.RBlock(replacement.ID(), fixture.Good()),
+	)}
+	final[flipped.Difference(genesis)-1] = replacement
+	for lid := flipped.Add(1); !last.Before(lid); lid = lid.Add(1) {
+		updates = append(updates, fixture.RLayer(lid, fixture.RBlock(final[lid.Difference(genesis)-1].ID(), fixture.Good())))
+	}
+	n.trtl.EXPECT().Updates().Return(updates)
+	require.NoError(t, n.ProcessLayer(context.Background(), last))
+	require.Equal(t, last, n.LatestLayerInState())
+
+	reorged := drain()
+	require.Len(t, reorged, int(last.Difference(flipped))+1)
+	for i, reward := range reorged {
+		require.True(t, reward.Reorg)
+		require.Equal(t, flipped.Add(uint32(i)), reward.Layer)
+	}
+	require.Equal(t, cbs[1], reorged[0].Coinbase)
+
+	// the state is the same as if the final blocks were applied from scratch
+	reference := newNode()
+	reference.process(t, last, final...)
+	for _, cb := range cbs {
+		expected, err := reference.vm.GetBalance(cb)
+		require.NoError(t, err)
+		require.NotZero(t, expected)
+		got, err := n.vm.GetBalance(cb)
+		require.NoError(t, err)
+		require.Equal(t, expected, got, cb)
+	}
+	expected, err := reference.vm.GetStateRoot()
+	require.NoError(t, err)
+	got, err := n.vm.GetStateRoot()
+	require.NoError(t, err)
+	require.Equal(t, expected, got)
+	expectedHash, err := reference.MeshHash(last)
+	require.NoError(t, err)
+	gotHash, err := n.MeshHash(last)
+	require.NoError(t, err)
+	require.Equal(t, expectedHash, gotHash)
+}
+
+func TestMesh_StateRoot(t *testing.T) {
+	events.CloseEventReporter()
+	events.InitializeReporter()
+	t.Cleanup(events.CloseEventReporter)
+	sub, err := events.SubscribeMatched(func(update *events.LayerUpdate) bool {
+		return update.Status == events.LayerStatusTypeApplied
+	})
+	require.NoError(t, err)
+
+	genesis := types.GetEffectiveGenesis()
+	last := genesis.Add(20)
+	flipped := last.Sub(6)
+	gate := mesh.WithStateRootMeshHash(genesis.Add(10))
+	cbs := []types.Address{{1}, {2}}
+
+	var activations []*types.VerifiedActivationTx
+	newNode := func(opts ...mesh.Opt) *stateMesh {
+		db := sql.InMemory()
+		if activations == nil {
+			cdb := datastore.NewCachedDB(db, logtest.New(t))
+			for _, cb := range cbs {
+				atx, err := cdb.GetFullAtx(createATX(t, db, cb))
+				require.NoError(t, err)
+				activations = append(activations, atx)
+			}
+		} else {
+			for _, atx := range activations {
+				require.NoError(t, atxs.Add(db, atx))
+			}
+		}
+		return newStateMesh(t, db, opts...)
+	}
+	roots := func(n *stateMesh) map[types.LayerID]types.Hash32 {
+		rst := map[types.LayerID]types.Hash32{}
+		for lid := genesis.Add(1); !last.Before(lid); lid = lid.Add(1) {
+			root, err := n.StateRoot(lid)
+			require.NoError(t, err, lid)
+			rst[lid] = root
+		}
+		return rst
+	}
+
+	n := newNode(gate)
+	var final []*types.Block
+	for lid := genesis.Add(1); !last.Before(lid); lid = lid.Add(1) {
+		block := rewardedBlock(lid, activations[0].ID())
+		n.process(t, lid, block)
+		final = append(final, block)
+	}
+	before := roots(n)
+
+	// the block in the flipped layer is replaced by the block rewarding the other coinbase
+	replacement := rewardedBlock(flipped, activations[1].ID())
+	_, err = n.AddBlockWithTXs(context.Background(), replacement)
+	require.NoError(t, err)
+	updates := []result.Layer{fixture.RLayer(flipped,
+		fixture.RBlock(final[flipped.Difference(genesis)-1].ID(), fixture.Invalid(), fixture.Data()),
+		fixture.RBlock(replacement.ID(), fixture.Good()),
+	)}
+	final[flipped.Difference(genesis)-1] = replacement
+	for lid := flipped.Add(1); !last.Before(lid); lid = lid.Add(1) {
+		updates = append(updates, fixture.RLayer(lid, fixture.RBlock(final[lid.Difference(genesis)-1].ID(), fixture.Good())))
+	}
+	n.trtl.EXPECT().Updates().Return(updates)
+	require.NoError(t, n.ProcessLayer(context.Background(), last))
+
+	// the applied layers are reported with the latest recorded roots
+	after := roots(n)
+	reported := map[types.LayerID]types.Hash32{}
+	for len(reported) < len(after) || reported[last] != after[last] {
+		select {
+		case update := <-sub.Out():
+			reported[update.LayerID] = update.StateRoot
+		case <-time.After(time.Second):
+			require.FailNow(t, "applied layers are not reported")
+		}
+	}
+	require.Equal(t, after, reported)
+	sub.Close()
+
+	for lid := genesis.Add(1); lid.Before(flipped); lid = lid.Add(1) {
+		require.Equal(t, before[lid], after[lid], lid)
+	}
+	require.NotEqual(t, before[flipped], after[flipped])
+
+	// the roots are the same as if the final blocks were applied from scratch
+	reference := newNode(gate)
+	reference.process(t, last, final...)
+	require.Equal(t, roots(reference), after)
+	latest, err := n.LatestStateRoot()
+	require.NoError(t, err)
+	require.Equal(t, after[last], latest)
+	got, err := n.vm.GetLayerStateRoot(last)
+	require.NoError(t, err)
+	require.Equal(t, latest, got)
+
+	// the root is included in the mesh hash starting from the upgrade layer
+	ungated := newNode()
+	ungated.process(t, last, final...)
+	for lid := genesis.Add(1); !last.Before(lid); lid = lid.Add(1) {
+		expected, err := reference.MeshHash(lid)
+		require.NoError(t, err)
+		hash, err := n.MeshHash(lid)
+		require.NoError(t, err)
+		require.Equal(t, expected, hash, lid)
+		ungatedHash, err := ungated.MeshHash(lid)
+		require.NoError(t, err)
+		if lid.Before(genesis.Add(10)) {
+			require.Equal(t, ungatedHash, hash, lid)
+		} else {
+			require.NotEqual(t, ungatedHash, hash, lid)
+		}
+	}
 }
@@ -0,0 +1,197 @@
+package mesh
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/spacemeshos/go-spacemesh/common/types"
+	"github.com/spacemeshos/go-spacemesh/events"
+	"github.com/spacemeshos/go-spacemesh/fetch"
+	"github.com/spacemeshos/go-spacemesh/log"
+	"github.com/spacemeshos/go-spacemesh/mesh/metrics"
+	"github.com/spacemeshos/go-spacemesh/p2p"
+	"github.com/spacemeshos/go-spacemesh/sql"
+	"github.com/spacemeshos/go-spacemesh/sql/layers"
+)
+
+// forkWindow is the number of the latest layers for which the hashes advertised by the peers are kept.
+const forkWindow = 10
+
+// ForkDetector compares the mesh hashes advertised by the peers with the local mesh hashes
+// of the verified layers. When the quorum of the peers disagrees with the local hash, the earliest
+// divergent layer is searched with the queries to one of the disagreeing peers and the fork is reported.
+type ForkDetector struct {
+	logger  log.Log
+	db      sql.Executor
+	cfg     Config
+	fetcher meshHashFetcher
+
+	mu sync.Mutex
+	// hashes advertised by the peers for the recent layers.
+	peers map[types.LayerID]map[p2p.Peer]types.Hash32
+	// local hashes of the layers for which the fork was already reported.
+	reported map[types.LayerID]types.Hash32
+}
+
+// NewForkDetector creates a ForkDetector.
+func NewForkDetector(db sql.Executor, cfg Config, fetcher meshHashFetcher, logger log.Log) *ForkDetector {
+	return &ForkDetector{
+		logger:   logger,
+		db:       db,
+		cfg:      cfg,
+		fetcher:  fetcher,
+		peers:    map[types.LayerID]map[p2p.Peer]types.Hash32{},
+		reported: map[types.LayerID]types.Hash32{},
+	}
+}
+
+// OnPeerMeshHash records the mesh hash of the layer advertised by the peer. The hashes are compared
+// with the local hash by Check, after the hashes of all polled peers are recorded.
+func (fd *ForkDetector) OnPeerMeshHash(peer p2p.Peer, lid types.LayerID, hash types.Hash32) {
+	fd.mu.Lock()
+	defer fd.mu.Unlock()
+	if _, exist := fd.peers[lid]; !exist {
+		fd.peers[lid] = map[p2p.Peer]types.Hash32{}
+	}
+	fd.peers[lid][peer] = hash
+	for layer := range fd.peers {
+		if layer.Add(forkWindow).Before(lid) {
+			delete(fd.peers, layer)
+		}
+	}
+	for layer := range fd.reported {
+		if layer.Add(forkWindow).Before(lid) {
+			delete(fd.reported, layer)
+		}
+	}
+}
+
+// Check reports the fork if the quorum of the peers disagrees with the local hash of the verified layer.
+// The fork is reported once per local hash. The earliest divergent layer is returned if the fork
+// was reported and the tortoise rerun is enabled.
+func (fd *ForkDetector) Check(ctx context.Context, lid types.LayerID) (types.LayerID, error) {
+	fd.mu.Lock()
+	defer fd.mu.Unlock()
+	markers, err := layers.GetMarkers(fd.db)
+	if err != nil {
+		return 0, err
+	}
+	if lid.After(markers.Verified) || !lid.After(types.GetEffectiveGenesis()) {
+		return 0, nil
+	}
+	local, err := layers.GetAggregatedHash(fd.db, lid)
+	if errors.Is(err, sql.ErrNotFound) {
+		return 0, nil
+	} else if err != nil {
+		return 0, err
+	}
+	if reported, exist := fd.reported[lid]; exist && reported == local {
+		return 0, nil
+	}
+
+	advertised := fd.peers[lid]
+	votes := map[types.Hash32][]p2p.Peer{}
+	disagreed := 0
+	for peer, hash := range advertised {
+		if hash != local {
+			votes[hash] = append(votes[hash], peer)
+			disagreed++
+		}
+	}
+	if len(advertised) < fd.cfg.ForkMinPeers || disagreed == 0 ||
+		float64(disagreed) < fd.cfg.ForkQuorum*float64(len(advertised)) {
+		return 0, nil
+	}
+
+	// probe a peer that advertised the most common hash
+	var (
+		peerHash types.Hash32
+		probed   []p2p.Peer
+	)
+	for hash, peers := range votes {
+		if len(peers) > len(probed) || len(peers) == len(probed) && bytes.Compare(hash[:], peerHash[:]) < 0 {
+			peerHash, probed = hash, peers
+		}
+	}
+	sort.Slice(probed, func(i, j int) bool { return probed[i] < probed[j] })
+	divergent, err := fd.findDivergent(ctx, probed[0], lid)
+	if err != nil {
+		return 0, fmt.Errorf("find divergent layer with %s: %w", probed[0], err)
+	}
+	fd.reported[lid] = local
+
+	metrics.ForksDetected.Inc()
+	fd.logger.With().Warning("mesh hash disagrees with the quorum of peers",
+		log.Context(ctx),
+		log.Uint32("layer_id", lid.Uint32()),
+		log.Uint32("divergent", divergent.Uint32()),
+		log.Stringer("hash", local),
+		log.Stringer("peer_hash", peerHash),
+		log.Stringer("probed", probed[0]),
+		log.Int("peers", len(advertised)),
+		log.Int("disagreed", disagreed),
+	)
+	events.ReportForkDetected(events.ForkDetected{
+		Layer:     lid,
+		Divergent: divergent,
+		Hash:      local,
+		PeerHash:  peerHash,
+		Peers:     len(advertised),
+		Disagreed: disagreed,
+	})
+	if !fd.cfg.ForkRerun {
+		return 0, nil
+	}
+	return divergent, nil
+}
+
+// findDivergent returns the earliest layer, up to ForkProbeDepth layers before the diverged layer,
+// where the mesh hash of the peer differs from the local mesh hash. As every hash depends on the hashes
+// of the previous layers, the hashes differ in every layer after the earliest divergent layer.
+func (fd *ForkDetector) findDivergent(ctx context.Context, peer p2p.Peer, diverged types.LayerID) (types.LayerID, error) {
+	lo := types.GetEffectiveGenesis().Add(1)
+	if fd.cfg.ForkProbeDepth > 0 && lo.Add(fd.cfg.ForkProbeDepth).Before(diverged) {
+		lo = diverged.Sub(fd.cfg.ForkProbeDepth)
+	}
+	hi := diverged
+	if lo == hi {
+		return hi, nil
+	}
+	agreed, err := fd.agrees(ctx, peer, lo)
+	if err != nil || !agreed {
+		return lo, err
+	}
+	// the peer agrees with the local mesh in lo and disagrees in hi
+	for hi.Difference(lo) > 1 {
+		mid := lo.Add(hi.Difference(lo) / 2)
+		agreed, err := fd.agrees(ctx, peer, mid)
+		if err != nil {
+			return 0, err
+		}
+		if agreed {
+			lo = mid
+		} else {
+			hi = mid
+		}
+	}
+	return hi, nil
+}
+
+func (fd *ForkDetector) agrees(ctx context.Context, peer p2p.Peer, lid types.LayerID) (bool, error) {
+	local, err := layers.GetAggregatedHash(fd.db, lid)
+	if err != nil {
+		return false, err
+	}
+	resp, err := fd.fetcher.PeerMeshHashes(ctx, peer, &fetch.MeshHashRequest{From: lid, To: lid, Step: 1})
+	if err != nil {
+		return false, err
+	}
+	if len(resp.Hashes) != 1 {
+		return false, fmt.Errorf("unexpected number of hashes for layer %s: %d", lid, len(resp.Hashes))
+	}
+	return resp.Hashes[0] == local, nil
+}
@@ -0,0 +1,158 @@
+package mesh
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+
+	"github.com/spacemeshos/go-spacemesh/common/types"
+	"github.com/spacemeshos/go-spacemesh/events"
+	"github.com/spacemeshos/go-spacemesh/fetch"
+	"github.com/spacemeshos/go-spacemesh/log/logtest"
+	"github.com/spacemeshos/go-spacemesh/mesh/metrics"
+	"github.com/spacemeshos/go-spacemesh/mesh/mocks"
+	"github.com/spacemeshos/go-spacemesh/p2p"
+	"github.com/spacemeshos/go-spacemesh/sql"
+	"github.com/spacemeshos/go-spacemesh/sql/layers"
+)
+
+// forkTest is a node with the verified mesh and the peers that share the mesh up to the fork layer.
+type forkTest struct {
+	*ForkDetector
+	fetcher *mocks.MockmeshHashFetcher
+	last    types.LayerID
+	fork    types.LayerID
+	forked  map[types.LayerID]types.Hash32
+	queries int
+}
+
+func newForkTest(t *testing.T, cfg Config) *forkTest {
+	types.SetLayersPerEpoch(3)
+	db := sql.InMemory()
+	genesis := types.GetEffectiveGenesis()
+	ft := &forkTest{
+		fetcher: mocks.NewMockmeshHashFetcher(gomock.NewController(t)),
+		last:    genesis.Add(20),
+		fork:    genesis.Add(13),
+		forked:  map[types.LayerID]types.Hash32{},
+	}
+	for lid := genesis.Add(1); !lid.After(ft.last); lid = lid.Add(1) {
+		local := types.RandomHash()
+		require.NoError(t, layers.SetAggregatedHash(db, lid, local))
+		if lid.Before(ft.fork) {
+			ft.forked[lid] = local
+		} else {
+			ft.forked[lid] = types.RandomHash()
+		}
+	}
+	require.NoError(t, layers.SetMarkers(db, layers.Markers{
+		Latest: ft.last.Add(1), Processed: ft.last.Add(1), Verified: ft.last, Applied: ft.last,
+	}))
+	ft.fetcher.EXPECT().PeerMeshHashes(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+		func(_ context.Context, _ p2p.Peer, req *fetch.MeshHashRequest) (*fetch.MeshHashes, error) {
+			ft.queries++
+			return &fetch.MeshHashes{Hashes: []types.Hash32{ft.forked[req.From]}}, nil
+		}).AnyTimes()
+	ft.ForkDetector = NewForkDetector(db, cfg, ft.fetcher, logtest.New(t))
+	return ft
+}
+
+func (ft *forkTest) local(t *testing.T, lid types.LayerID) types.Hash32 {
+	hash, err := layers.GetAggregatedHash(ft.db, lid)
+	require.NoError(t, err)
+	return hash
+}
+
+// advertise the hashes of the layer from the peers and check them, the forked peers advertise the hash of the fork.
+func (ft *forkTest) advertise(t *testing.T, lid types.LayerID, peers, forked int) types.LayerID {
+	for i := 0; i < peers; i++ {
+		hash := ft.local(t, lid)
+		if i < forked {
+			hash = ft.forked[lid]
+		}
+		ft.OnPeerMeshHash(p2p.Peer(fmt.Sprint(i)), lid, hash)
+	}
+	rerun, err := ft.Check(context.Background(), lid)
+	require.NoError(t, err)
+	return rerun
+}
+
+func TestForkDetector(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.ForkRerun = true
+
+	t.Run("quorum disagrees", func(t *testing.T) {
+		events.InitializeReporter()
+		t.Cleanup(events.CloseEventReporter)
+		sub, err := events.Subscribe[events.ForkDetected]()
+		require.NoError(t, err)
+		detected := testutil.ToFloat64(metrics.ForksDetected)
+
+		ft := newForkTest(t, cfg)
+		require.Equal(t, ft.fork, ft.advertise(t, ft.last, 10, 7))
+		require.Equal(t, detected+1, testutil.ToFloat64(metrics.ForksDetected))
+		// binary search over the probing range
+		require.LessOrEqual(t, ft.queries, 6)
+		select {
+		case ev := <-sub.Out():
+			require.Equal(t, events.ForkDetected{
+				Layer:     ft.last,
+				Divergent: ft.fork,
+				Hash:      ft.local(t, ft.last),
+				PeerHash:  ft.forked[ft.last],
+				Peers:     10,
+				Disagreed: 7,
+			}, ev)
+		case <-time.After(time.Second):
+			require.FailNow(t, "fork is not reported")
+		}
+
+		// the fork is reported once for the local hash
+		require.Zero(t, ft.advertise(t, ft.last, 10, 7))
+		require.Equal(t, detected+1, testutil.ToFloat64(metrics.ForksDetected))
+	})
+
+	t.Run("minority disagrees", func(t *testing.T) {
+		detected := testutil.ToFloat64(metrics.ForksDetected)
+		ft := newForkTest(t, cfg)
+		require.Zero(t, ft.advertise(t, ft.last, 10, 3))
+		require.Zero(t, ft.queries)
+		require.Equal(t, detected, testutil.ToFloat64(metrics.ForksDetected))
+	})
+
+	t.Run("not enough peers", func(t *testing.T) {
+		ft := newForkTest(t, cfg)
+		require.Zero(t, ft.advertise(t, ft.last, cfg.ForkMinPeers-1, cfg.ForkMinPeers-1))
+		require.Zero(t, ft.queries)
+	})
+
+	t.Run("layer not verified", func(t *testing.T) {
+		ft := newForkTest(t, cfg)
+		require.NoError(t, layers.SetAggregatedHash(ft.db, ft.last.Add(1), types.RandomHash()))
+		ft.forked[ft.last.Add(1)] = types.RandomHash()
+		require.Zero(t, ft.advertise(t, ft.last.Add(1), 10, 7))
+		require.Zero(t, ft.queries)
+	})
+
+	t.Run("probing depth", func(t *testing.T) {
+		cfg := cfg
+		cfg.ForkProbeDepth = 3
+		ft := newForkTest(t, cfg)
+		require.Equal(t, ft.last.Sub(3), ft.advertise(t, ft.last, 10, 7))
+		require.Equal(t, 1, ft.queries)
+	})
+
+	t.Run("rerun disabled", func(t *testing.T) {
+		cfg := cfg
+		cfg.ForkRerun = false
+		detected := testutil.ToFloat64(metrics.ForksDetected)
+		ft := newForkTest(t, cfg)
+		require.Zero(t, ft.advertise(t, ft.last, 10, 7))
+		require.Equal(t, detected+1, testutil.ToFloat64(metrics.ForksDetected))
+	})
+}
@@ -0,0 +1,98 @@
+package mesh
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/spacemeshos/go-spacemesh/common/types"
+	"github.com/spacemeshos/go-spacemesh/hash"
+	"github.com/spacemeshos/go-spacemesh/sql"
+	"github.com/spacemeshos/go-spacemesh/sql/layers"
+)
+
+// ErrGenesisMismatch is returned if the database was initialized with a genesis different from the configured one.
+var ErrGenesisMismatch = errors.New("genesis mismatch")
+
+// GenesisConfig contains the parameters the genesis of the mesh is derived from.
+type GenesisConfig struct {
+	Time      time.Time
+	ExtraData string
+	Accounts  []types.Account
+	// Layer is the last genesis layer, ballots are accepted starting with the next layer.
+	Layer types.LayerID
+}
+
+// AccountsHash commits to the addresses and balances of the genesis accounts.
+// The accounts are hashed in the order of addresses, so the hash doesn't depend on the order in the config.
+func (g *GenesisConfig) AccountsHash() types.Hash32 {
+	accounts := make([]types.Account, len(g.Accounts))
+	copy(accounts, g.Accounts)
+	sort.Slice(accounts, func(i, j int) bool {
+		return bytes.Compare(accounts[i].Address[:], accounts[j].Address[:]) < 0
+	})
+	var (
+		h   = hash.New()
+		buf [8]byte
+		rst types.Hash32
+	)
+	for _, account := range accounts {
+		h.Write(account.Address[:])
+		binary.LittleEndian.PutUint64(buf[:], account.Balance)
+		h.Write(buf[:])
+	}
+	h.Sum(rst[:0])
+	return rst
+}
+
+// Genesis derives the genesis of the mesh from the config.
+// Integers are hashed with a fixed width and byte order, so that every platform derives the same genesis.
+func (g *GenesisConfig) Genesis() layers.Genesis {
+	var (
+		h   = hash.New()
+		buf [8]byte
+	)
+	binary.LittleEndian.PutUint64(buf[:], uint64(g.Time.Unix()))
+	h.Write(buf[:])
+	binary.LittleEndian.PutUint64(buf[:], uint64(len(g.ExtraData)))
+	h.Write(buf[:])
+	h.Write([]byte(g.ExtraData))
+	accounts := g.AccountsHash()
+	h.Write(accounts[:])
+	binary.LittleEndian.PutUint32(buf[:4], g.Layer.Uint32())
+	h.Write(buf[:4])
+
+	genesis := layers.Genesis{Layer: g.Layer}
+	h.Sum(genesis.ID[:0])
+	ballot := hash.Sum([]byte("ballot"), genesis.ID[:])
+	copy(genesis.Ballot[:], ballot[:])
+	block := hash.Sum([]byte("block"), genesis.ID[:])
+	copy(genesis.Block[:], block[:])
+	return genesis
+}
+
+// SetupGenesis stores the genesis derived from the config when the database is initialized,
+// and verifies that the stored genesis matches the config on every later start.
+func SetupGenesis(db sql.Executor, cfg GenesisConfig) (layers.Genesis, error) {
+	genesis := cfg.Genesis()
+	stored, err := layers.GetGenesis(db)
+	if errors.Is(err, sql.ErrNotFound) {
+		if err := layers.SetGenesis(db, genesis); err != nil {
+			return layers.Genesis{}, err
+		}
+		return genesis, nil
+	} else if err != nil {
+		return layers.Genesis{}, err
+	}
+	if stored != genesis {
+		return layers.Genesis{}, fmt.Errorf("%w: database was initialized with genesis %s (layer %s ballot %s block %s), configured genesis is %s (layer %s ballot %s block %s)",
+			ErrGenesisMismatch,
+			stored.ID.Hex(), stored.Layer, stored.Ballot, stored.Block,
+			genesis.ID.Hex(), genesis.Layer, genesis.Ballot, genesis.Block,
+		)
+	}
+	return genesis, nil
+}
@@ -0,0 +1,90 @@
+package mesh_test
+
+import (
+	"encoding/hex"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/spacemeshos/go-spacemesh/common/types"
+	"github.com/spacemeshos/go-spacemesh/mesh"
+	"github.com/spacemeshos/go-spacemesh/sql"
+	"github.com/spacemeshos/go-spacemesh/sql/layers"
+)
+
+func testGenesisConfig() mesh.GenesisConfig {
+	return mesh.GenesisConfig{
+		Time:      time.Date(2023, 7, 14, 8, 0, 0, 0, time.UTC),
+		ExtraData: "testnet",
+		Accounts: []types.Account{
+			{Address: types.Address{1, 2, 3}, Balance: 100_000_000_000},
+			{Address: types.Address{4, 5, 6}, Balance: 1},
+		},
+		Layer: 7,
+	}
+}
+
+func TestGenesis_Deterministic(t *testing.T) {
+	cfg := testGenesisConfig()
+	genesis := cfg.Genesis()
+	// the expected values are fixed, any platform must derive the same genesis from the config
+	require.Equal(t, "0x926c8c1b1b8d80182fb5fd004dfb465e8a5c004eae650b90f24e217dc5884648", genesis.ID.Hex())
+	require.Equal(t, "d657af3ccab83e77275d6f77fae7634ac37e1e38", hex.EncodeToString(genesis.Ballot[:]))
+	require.Equal(t, "b3e80bb445569dcbdb4f328afe23a976c6697510", hex.EncodeToString(genesis.Block[:]))
+	require.Equal(t, cfg.Layer, genesis.Layer)
+
+	reordered := testGenesisConfig()
+	reordered.Accounts[0], reordered.Accounts[1] = reordered.Accounts[1], reordered.Accounts[0]
+	require.Equal(t, genesis, reordered.Genesis())
+	local := testGenesisConfig()
+	local.Time = local.Time.In(time.FixedZone("", 3*60*60))
+	require.Equal(t, genesis, local.Genesis())
+
+	for _, tc := range []struct {
+		desc   string
+		modify func(*mesh.GenesisConfig)
+	}{
+		{"time", func(cfg *mesh.GenesisConfig) { cfg.Time = cfg.Time.Add(time.Second) }},
+		{"extra data", func(cfg *mesh.GenesisConfig) { cfg.ExtraData = "mainnet" }},
+		{"balance", func(cfg *mesh.GenesisConfig) { cfg.Accounts[1].Balance++ }},
+		{"accounts", func(cfg *mesh.GenesisConfig) { cfg.Accounts = cfg.Accounts[:1] }},
+		{"layer", func(cfg *mesh.GenesisConfig) { cfg.Layer++ }},
+	} {
+		tc := tc
+		t.Run(tc.desc, func(t *testing.T) {
+			cfg := testGenesisConfig()
+			tc.modify(&cfg)
+			other := cfg.Genesis()
+			require.NotEqual(t, genesis.ID, other.ID)
+			require.NotEqual(t, genesis.Ballot, other.Ballot)
+			require.NotEqual(t, genesis.Block, other.Block)
+		})
+	}
+}
+
+func TestSetupGenesis(t *testing.T) {
+	db := sql.InMemory()
+	cfg := testGenesisConfig()
+	genesis, err := mesh.SetupGenesis(db, cfg)
+	require.NoError(t, err)
+	require.Equal(t, cfg.Genesis(), genesis)
+	stored, err := layers.GetGenesis(db)
+	require.NoError(t, err)
+	require.Equal(t, genesis, stored)
+
+	// restart with the same config
+	genesis, err = mesh.SetupGenesis(db, testGenesisConfig())
+	require.NoError(t, err)
+	require.Equal(t, stored, genesis)
+
+	// restart with a different config
+	cfg.ExtraData = "mainnet"
+	_, err = mesh.SetupGenesis(db, cfg)
+	require.ErrorIs(t, err, mesh.ErrGenesisMismatch)
+	require.ErrorContains(t, err, stored.ID.Hex())
+	require.ErrorContains(t, err, cfg.Genesis().ID.Hex())
+	got, err := layers.GetGenesis(db)
+	require.NoError(t, err)
+	require.Equal(t, stored, got)
+}
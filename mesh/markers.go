@@ -0,0 +1,82 @@
+package mesh
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/spacemeshos/go-spacemesh/common/types"
+	"github.com/spacemeshos/go-spacemesh/log"
+	"github.com/spacemeshos/go-spacemesh/sql"
+	"github.com/spacemeshos/go-spacemesh/sql/ballots"
+	"github.com/spacemeshos/go-spacemesh/sql/layers"
+)
+
+// LayerMarkers returns a consistent snapshot of the persisted progress of the mesh.
+func (msh *Mesh) LayerMarkers() (layers.Markers, error) {
+	return layers.GetMarkers(msh.cdb)
+}
+
+// repairMarkers validates the persisted markers against the data they describe and repairs them
+// if they disagree, e.g. if the database was written by an older version of the node.
+//
+// The state may still be ahead of the applied marker, as the vm commits the state of a layer
+// before the marker is moved. The state is reverted to the applied marker by the caller.
+func repairMarkers(logger log.Log, dbtx *sql.Tx) (layers.Markers, error) {
+	markers, err := layers.GetMarkers(dbtx)
+	if err != nil {
+		return markers, err
+	}
+	repaired := markers
+	lastBlock, err := layers.LastAppliedBlock(dbtx)
+	if err != nil {
+		return markers, err
+	}
+	if repaired.Applied == 0 || repaired.Applied.After(lastBlock) {
+		repaired.Applied = lastBlock
+	}
+	// drops the blocks recorded as applied without moving the marker and the state hashes
+	// written by the vm for the layers that were not marked as applied
+	if err := layers.UnsetAppliedFrom(dbtx, repaired.Applied.Add(1)); err != nil {
+		return markers, err
+	}
+	if repaired.Verified.After(repaired.Processed) {
+		repaired.Verified = repaired.Processed
+	}
+	latestBallot, err := ballots.LatestLayer(dbtx)
+	if err != nil && !errors.Is(err, sql.ErrNotFound) {
+		return markers, err
+	}
+	for _, lid := range []types.LayerID{repaired.Processed, repaired.Applied, latestBallot} {
+		repaired.Latest = types.MaxLayer(repaired.Latest, lid)
+	}
+	if repaired == markers && !lastBlock.After(markers.Applied) {
+		return markers, nil
+	}
+	logger.With().Warning("repairing inconsistent layer markers",
+		log.Stringer("latest", markers.Latest),
+		log.Stringer("processed", markers.Processed),
+		log.Stringer("verified", markers.Verified),
+		log.Stringer("applied", markers.Applied),
+		log.Stringer("last_applied_block", lastBlock),
+		log.Stringer("repaired_latest", repaired.Latest),
+		log.Stringer("repaired_verified", repaired.Verified),
+		log.Stringer("repaired_applied", repaired.Applied),
+	)
+	if err := layers.SetMarkers(dbtx, repaired); err != nil {
+		return markers, err
+	}
+	return repaired, nil
+}
+
+func (msh *Mesh) recoverMarkers(ctx context.Context) (layers.Markers, error) {
+	var markers layers.Markers
+	if err := msh.cdb.WithTx(ctx, func(dbtx *sql.Tx) error {
+		var err error
+		markers, err = repairMarkers(msh.logger, dbtx)
+		return err
+	}); err != nil {
+		return markers, fmt.Errorf("recover markers: %w", err)
+	}
+	return markers, nil
+}
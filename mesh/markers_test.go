@@ -0,0 +1,165 @@
+package mesh_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/spacemeshos/go-spacemesh/common/types"
+	"github.com/spacemeshos/go-spacemesh/datastore"
+	"github.com/spacemeshos/go-spacemesh/log/logtest"
+	"github.com/spacemeshos/go-spacemesh/sql"
+	"github.com/spacemeshos/go-spacemesh/sql/atxs"
+	"github.com/spacemeshos/go-spacemesh/sql/ballots"
+	"github.com/spacemeshos/go-spacemesh/sql/layers"
+)
+
+type crashTest struct {
+	db       *sql.Database
+	coinbase types.Address
+	atx      *types.VerifiedActivationTx
+	blocks   []*types.Block
+}
+
+func newCrashTest(t *testing.T, applied int) (*crashTest, *stateMesh) {
+	ct := &crashTest{db: sql.InMemory(), coinbase: types.Address{1}}
+	atx, err := datastore.NewCachedDB(ct.db, logtest.New(t)).GetFullAtx(createATX(t, ct.db, ct.coinbase))
+	require.NoError(t, err)
+	ct.atx = atx
+	n := newStateMesh(t, ct.db)
+	for lid := types.GetEffectiveGenesis().Add(1); len(ct.blocks) < applied; lid = lid.Add(1) {
+		ct.blocks = append(ct.blocks, rewardedBlock(lid, atx.ID()))
+		n.process(t, lid, ct.blocks[len(ct.blocks)-1])
+	}
+	return ct, n
+}
+
+func (ct *crashTest) balance(t *testing.T, n *stateMesh) uint64 {
+	t.Helper()
+	balance, err := n.vm.GetBalance(ct.coinbase)
+	require.NoError(t, err)
+	return balance
+}
+
+// reference returns the balance of the coinbase after applying the blocks to the empty state.
+func (ct *crashTest) reference(t *testing.T, blocks []*types.Block) uint64 {
+	t.Helper()
+	db := sql.InMemory()
+	require.NoError(t, atxs.Add(db, ct.atx))
+	n := newStateMesh(t, db)
+	n.process(t, blocks[len(blocks)-1].LayerIndex, blocks...)
+	return ct.balance(t, n)
+}
+
+func TestMesh_RecoverInterruptedApply(t *testing.T) {
+	ct, n := newCrashTest(t, 5)
+	last := ct.blocks[len(ct.blocks)-1].LayerIndex
+	before := ct.balance(t, n)
+
+	// the node is killed after the state of the next layer is written, before the applied marker is moved
+	block := rewardedBlock(last.Add(1), ct.atx.ID())
+	_, err := n.AddBlockWithTXs(context.Background(), block)
+	require.NoError(t, err)
+	require.NoError(t, n.exec.Execute(context.Background(), block.LayerIndex, block))
+	require.Greater(t, ct.balance(t, n), before)
+	markers, err := n.LayerMarkers()
+	require.NoError(t, err)
+	require.Equal(t, last, markers.Applied)
+
+	restarted := newStateMesh(t, ct.db)
+	require.Equal(t, last, restarted.LatestLayerInState())
+	require.Equal(t, before, ct.balance(t, restarted))
+	_, err = layers.GetStateHash(ct.db, block.LayerIndex)
+	require.ErrorIs(t, err, sql.ErrNotFound)
+
+	restarted.process(t, block.LayerIndex, block)
+	require.Equal(t, ct.reference(t, append(ct.blocks, block)), ct.balance(t, restarted))
+	markers, err = restarted.LayerMarkers()
+	require.NoError(t, err)
+	require.Equal(t, block.LayerIndex, markers.Applied)
+}
+
+func TestMesh_RecoverInterruptedRevert(t *testing.T) {
+	ct, n := newCrashTest(t, 6)
+	revertTo := ct.blocks[2].LayerIndex
+	expected := ct.reference(t, ct.blocks[:3])
+
+	// the node is killed after the applied marker is moved back, before the state is reverted
+	require.NoError(t, ct.db.WithTx(context.Background(), func(dbtx *sql.Tx) error {
+		return layers.UnsetAppliedFrom(dbtx, revertTo.Add(1))
+	}))
+	require.Greater(t, ct.balance(t, n), expected)
+
+	restarted := newStateMesh(t, ct.db)
+	require.Equal(t, revertTo, restarted.LatestLayerInState())
+	require.Equal(t, expected, ct.balance(t, restarted))
+
+	restarted.process(t, ct.blocks[len(ct.blocks)-1].LayerIndex, ct.blocks[3:]...)
+	require.Equal(t, ct.reference(t, ct.blocks), ct.balance(t, restarted))
+}
+
+func TestMesh_RepairMarkers(t *testing.T) {
+	ct, n := newCrashTest(t, 4)
+	markers, err := n.LayerMarkers()
+	require.NoError(t, err)
+	last := ct.blocks[len(ct.blocks)-1].LayerIndex
+	require.Equal(t, last, markers.Applied)
+	require.Equal(t, last, markers.Processed)
+	require.Equal(t, last, markers.Verified)
+	require.Equal(t, last, markers.Latest)
+
+	ballot := types.NewExistingBallot(types.BallotID{1}, types.EmptyEdSignature, types.EmptyNodeID, last.Add(10))
+	require.NoError(t, ballots.Add(ct.db, &ballot))
+	for _, tc := range []struct {
+		desc     string
+		markers  layers.Markers
+		expected layers.Markers
+	}{
+		{
+			desc:     "consistent",
+			markers:  layers.Markers{Latest: last.Add(10), Processed: last, Verified: last, Applied: last},
+			expected: layers.Markers{Latest: last.Add(10), Processed: last, Verified: last, Applied: last},
+		},
+		{
+			desc:     "applied marker ahead of blocks",
+			markers:  layers.Markers{Latest: last.Add(10), Processed: last, Verified: last, Applied: last.Add(3)},
+			expected: layers.Markers{Latest: last.Add(10), Processed: last, Verified: last, Applied: last},
+		},
+		{
+			desc:     "applied marker missing",
+			markers:  layers.Markers{Latest: last.Add(10), Processed: last, Verified: last},
+			expected: layers.Markers{Latest: last.Add(10), Processed: last, Verified: last, Applied: last},
+		},
+		{
+			desc:     "verified ahead of processed",
+			markers:  layers.Markers{Latest: last.Add(10), Processed: last, Verified: last.Add(2), Applied: last},
+			expected: layers.Markers{Latest: last.Add(10), Processed: last, Verified: last, Applied: last},
+		},
+		{
+			desc:     "latest behind data",
+			markers:  layers.Markers{Latest: last.Sub(2), Processed: last, Verified: last, Applied: last},
+			expected: layers.Markers{Latest: last.Add(10), Processed: last, Verified: last, Applied: last},
+		},
+		{
+			desc:     "blocks applied without the marker",
+			markers:  layers.Markers{Latest: last.Add(10), Processed: last, Verified: last, Applied: last.Sub(1)},
+			expected: layers.Markers{Latest: last.Add(10), Processed: last, Verified: last, Applied: last.Sub(1)},
+		},
+	} {
+		tc := tc
+		t.Run(tc.desc, func(t *testing.T) {
+			require.NoError(t, layers.SetMarkers(ct.db, tc.markers))
+			restarted := newStateMesh(t, ct.db)
+			got, err := restarted.LayerMarkers()
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, got)
+			require.Equal(t, tc.expected.Applied, restarted.LatestLayerInState())
+			require.Equal(t, tc.expected.Processed, restarted.ProcessedLayer())
+			require.Equal(t, tc.expected.Latest, restarted.LatestLayer())
+			applied, err := layers.LastAppliedBlock(ct.db)
+			require.NoError(t, err)
+			require.Equal(t, tc.expected.Applied, applied)
+		})
+	}
+}
@@ -3,10 +3,10 @@
 package mesh
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"fmt"
-	"sync"
 
 	"go.uber.org/atomic"
 	"go.uber.org/zap/zapcore"
@@ -18,6 +18,7 @@ import (
 	"github.com/spacemeshos/go-spacemesh/events"
 	"github.com/spacemeshos/go-spacemesh/hash"
 	"github.com/spacemeshos/go-spacemesh/log"
+	"github.com/spacemeshos/go-spacemesh/mesh/metrics"
 	"github.com/spacemeshos/go-spacemesh/sql"
 	"github.com/spacemeshos/go-spacemesh/sql/atxs"
 	"github.com/spacemeshos/go-spacemesh/sql/ballots"
@@ -29,6 +30,17 @@ import (
 	"github.com/spacemeshos/go-spacemesh/system"
 )
 
+var (
+	// ErrConflict is returned if a ballot or a block with the same id as the stored one has different content.
+	ErrConflict = errors.New("conflicting content")
+	// ErrConflictingCertificates is returned if valid certificates for more than one block in the layer are stored.
+	ErrConflictingCertificates = errors.New("conflicting certificates")
+)
+
+// largeLayerSize is the number of ballots and blocks in a layer above which loading
+// the whole layer into memory with GetLayer is logged.
+const largeLayerSize = 200
+
 // Mesh is the logic layer above our mesh.DB database.
 type Mesh struct {
 	logger log.Log
@@ -39,7 +51,7 @@ type Mesh struct {
 	conState conservativeState
 	trtl     system.Tortoise
 
-	mu sync.Mutex
+	pipe pipeline
 	// latestLayer is the latest layer this node had seen from blocks
 	latestLayer atomic.Value
 	// latestLayerInState is the latest layer whose contents have been applied to the state
@@ -52,10 +64,24 @@ type Mesh struct {
 	pendingUpdates struct {
 		min, max types.LayerID
 	}
+
+	// stateRootLayer is the first layer where the state root is included in the mesh hash.
+	stateRootLayer types.LayerID
+}
+
+// Opt for configuring Mesh.
+type Opt func(*Mesh)
+
+// WithStateRootMeshHash defines the first layer where the state root is included in the mesh hash.
+// Zero disables the upgrade.
+func WithStateRootMeshHash(lid types.LayerID) Opt {
+	return func(msh *Mesh) {
+		msh.stateRootLayer = lid
+	}
 }
 
 // NewMesh creates a new instant of a mesh.
-func NewMesh(cdb *datastore.CachedDB, c layerClock, trtl system.Tortoise, exec *Executor, state conservativeState, logger log.Log) (*Mesh, error) {
+func NewMesh(cdb *datastore.CachedDB, c layerClock, trtl system.Tortoise, exec *Executor, state conservativeState, logger log.Log, opts ...Opt) (*Mesh, error) {
 	msh := &Mesh{
 		logger:              logger,
 		cdb:                 cdb,
@@ -65,6 +91,9 @@ func NewMesh(cdb *datastore.CachedDB, c layerClock, trtl system.Tortoise, exec *
 		conState:            state,
 		nextProcessedLayers: make(map[types.LayerID]struct{}),
 	}
+	for _, opt := range opts {
+		opt(msh)
+	}
 	msh.latestLayer.Store(types.LayerID(0))
 	msh.latestLayerInState.Store(types.LayerID(0))
 	msh.processedLayer.Store(types.LayerID(0))
@@ -73,9 +102,12 @@ func NewMesh(cdb *datastore.CachedDB, c layerClock, trtl system.Tortoise, exec *
 	if err != nil && !errors.Is(err, sql.ErrNotFound) {
 		return nil, fmt.Errorf("get latest layer %w", err)
 	}
-
-	if err == nil && lid != 0 {
-		msh.recoverFromDB(lid)
+	applied, err := layers.GetLastApplied(cdb)
+	if err != nil {
+		return nil, err
+	}
+	if lid != 0 || applied != 0 {
+		msh.recoverFromDB()
 		return msh, nil
 	}
 
@@ -87,7 +119,16 @@ func NewMesh(cdb *datastore.CachedDB, c layerClock, trtl system.Tortoise, exec *
 		if err = layers.SetApplied(dbtx, genesis, types.EmptyBlockID); err != nil {
 			return fmt.Errorf("mesh init: %w", err)
 		}
-		if err := layers.SetMeshHash(dbtx, genesis, hash.Sum(nil)); err != nil {
+		if err = layers.SetVerified(dbtx, genesis); err != nil {
+			return fmt.Errorf("mesh init: %w", err)
+		}
+		if err = layers.SetLatest(dbtx, genesis); err != nil {
+			return fmt.Errorf("mesh init: %w", err)
+		}
+		if err := layers.SetAggregatedHash(dbtx, genesis, hash.Sum(nil)); err != nil {
+			return err
+		}
+		if err := layers.SetMeshHash(dbtx, genesis, genesisMeshHash()); err != nil {
 			return err
 		}
 		return nil
@@ -101,29 +142,26 @@ func NewMesh(cdb *datastore.CachedDB, c layerClock, trtl system.Tortoise, exec *
 	return msh, nil
 }
 
-func (msh *Mesh) recoverFromDB(latest types.LayerID) {
-	msh.setLatestLayer(msh.logger, latest)
-
-	lyr, err := layers.GetProcessed(msh.cdb)
+func (msh *Mesh) recoverFromDB() {
+	markers, err := msh.recoverMarkers(context.Background())
 	if err != nil {
-		msh.logger.With().Fatal("failed to recover processed layer", log.Err(err))
+		msh.logger.With().Fatal("failed to recover layer markers", log.Err(err))
 	}
-	msh.processedLayer.Store(lyr)
+	msh.setLatestLayer(msh.logger, markers.Latest)
+	msh.processedLayer.Store(markers.Processed)
+	msh.setLatestLayerInState(markers.Applied)
 
-	applied, err := layers.GetLastApplied(msh.cdb)
-	if err != nil {
-		msh.logger.With().Fatal("failed to recover latest applied layer", log.Err(err))
-	}
-	msh.setLatestLayerInState(applied)
-
-	if applied.After(types.GetEffectiveGenesis()) {
-		if err = msh.executor.Revert(context.Background(), applied); err != nil {
+	// the state of the layers after the applied marker may have been written before the node was interrupted
+	if markers.Applied.After(types.GetEffectiveGenesis()) {
+		if err = msh.executor.Revert(context.Background(), markers.Applied); err != nil {
 			msh.logger.With().Fatal("failed to load state for layer", msh.LatestLayerInState(), log.Err(err))
 		}
 	}
 	msh.logger.With().Info("recovered mesh from disk",
 		log.Stringer("latest", msh.LatestLayer()),
-		log.Stringer("processed", msh.ProcessedLayer()))
+		log.Stringer("processed", msh.ProcessedLayer()),
+		log.Stringer("verified", markers.Verified),
+		log.Stringer("applied", markers.Applied))
 }
 
 // LatestLayerInState returns the latest layer we applied to state.
@@ -136,9 +174,30 @@ func (msh *Mesh) LatestLayer() types.LayerID {
 	return msh.latestLayer.Load().(types.LayerID)
 }
 
-// MeshHash returns the aggregated mesh hash at the specified layer.
+// MeshHash returns the mesh hash at the specified layer, see calcMeshHash.
+// The hash is computed and persisted if the layer was applied before the hashes were stored.
 func (msh *Mesh) MeshHash(lid types.LayerID) (types.Hash32, error) {
-	return layers.GetAggregatedHash(msh.cdb, lid)
+	rst, err := layers.GetMeshHash(msh.cdb, lid)
+	if !errors.Is(err, sql.ErrNotFound) {
+		return rst, err
+	}
+	if err := msh.cdb.WithTx(context.Background(), func(dbtx *sql.Tx) error {
+		rst, err = recoverMeshHash(dbtx, lid, msh.stateRootLayer)
+		return err
+	}); err != nil {
+		return types.Hash32{}, err
+	}
+	return rst, nil
+}
+
+// StateRoot returns the state root recorded after the layer was applied.
+func (msh *Mesh) StateRoot(lid types.LayerID) (types.Hash32, error) {
+	return layers.GetStateHash(msh.cdb, lid)
+}
+
+// LatestStateRoot returns the state root of the latest layer applied to the state.
+func (msh *Mesh) LatestStateRoot() (types.Hash32, error) {
+	return msh.StateRoot(msh.LatestLayerInState())
 }
 
 // setLatestLayer sets the latest layer we saw from the network.
@@ -153,6 +212,9 @@ func (msh *Mesh) setLatestLayer(logger log.Log, lid types.LayerID) {
 			return
 		}
 		if msh.latestLayer.CompareAndSwap(current, lid) {
+			if err := layers.SetLatest(msh.cdb, lid); err != nil {
+				logger.With().Error("failed to persist latest layer", lid, log.Err(err))
+			}
 			events.ReportNodeStatusUpdate()
 			logger.With().Debug("set latest known layer", lid)
 		}
@@ -160,6 +222,8 @@ func (msh *Mesh) setLatestLayer(logger log.Log, lid types.LayerID) {
 }
 
 // GetLayer returns GetLayer i from the database.
+// All ballots and blocks of the layer are loaded into memory, prefer
+// IterateLayerBallots and IterateLayerBlocks for serving layers of unbounded size.
 func (msh *Mesh) GetLayer(lid types.LayerID) (*types.Layer, error) {
 	blts, err := ballots.Layer(msh.cdb, lid)
 	if err != nil {
@@ -169,9 +233,56 @@ func (msh *Mesh) GetLayer(lid types.LayerID) (*types.Layer, error) {
 	if err != nil {
 		return nil, fmt.Errorf("layer blks: %w", err)
 	}
+	if size := len(blts) + len(blks); size > largeLayerSize {
+		msh.logger.With().Warning("loaded large layer into memory",
+			lid,
+			log.Int("ballots", len(blts)),
+			log.Int("blocks", len(blks)),
+		)
+	}
 	return types.NewExistingLayer(lid, blts, blks), nil
 }
 
+// GetBallot returns the ballot with the id.
+func (msh *Mesh) GetBallot(id types.BallotID) (*types.Ballot, error) {
+	return msh.cdb.GetBallot(id)
+}
+
+// GetBlock returns the block with the id.
+func (msh *Mesh) GetBlock(id types.BlockID) (*types.Block, error) {
+	return msh.cdb.GetBlock(id)
+}
+
+// IterateLayerBallots passes ballots in the layer to fn one at a time, until fn returns false.
+func (msh *Mesh) IterateLayerBallots(lid types.LayerID, fn func(*types.Ballot) bool) error {
+	return ballots.IterateLayer(msh.cdb, lid, fn)
+}
+
+// IterateLayerBlocks passes blocks in the layer to fn one at a time, until fn returns false.
+func (msh *Mesh) IterateLayerBlocks(lid types.LayerID, fn func(*types.Block) bool) error {
+	return blocks.IterateLayer(msh.cdb, lid, fn)
+}
+
+// LayerBallotIDs returns ids of the ballots in the layer.
+func (msh *Mesh) LayerBallotIDs(lid types.LayerID) ([]types.BallotID, error) {
+	return ballots.IDsInLayer(msh.cdb, lid)
+}
+
+// LayerBlockIDs returns ids of the blocks in the layer.
+func (msh *Mesh) LayerBlockIDs(lid types.LayerID) ([]types.BlockID, error) {
+	return blocks.IDsInLayer(msh.cdb, lid)
+}
+
+// CountLayerBallots returns the number of ballots in the layer.
+func (msh *Mesh) CountLayerBallots(lid types.LayerID) (int, error) {
+	return ballots.CountInLayer(msh.cdb, lid)
+}
+
+// CountLayerBlocks returns the number of blocks in the layer.
+func (msh *Mesh) CountLayerBlocks(lid types.LayerID) (int, error) {
+	return blocks.CountInLayer(msh.cdb, lid)
+}
+
 // ProcessedLayer returns the last processed layer ID.
 func (msh *Mesh) ProcessedLayer() types.LayerID {
 	return msh.processedLayer.Load().(types.LayerID)
@@ -208,7 +319,9 @@ func (msh *Mesh) setProcessedLayer(layerID types.LayerID) error {
 		delete(msh.nextProcessedLayers, i)
 	}
 
-	if err := layers.SetProcessed(msh.cdb, processed); err != nil {
+	if err := msh.cdb.WithTx(context.Background(), func(dbtx *sql.Tx) error {
+		return layers.SetProcessed(dbtx, processed)
+	}); err != nil {
 		return fmt.Errorf("failed to set processed layer %v: %w", processed, err)
 	}
 	msh.processedLayer.Store(processed)
@@ -249,12 +362,10 @@ func (msh *Mesh) ensureStateConsistent(ctx context.Context, results []result.Lay
 		log.Context(ctx),
 		log.Uint32("revert_to", revert.Uint32()),
 	)
+	// applied layers and their mesh hashes are unset together with the state
 	if err := msh.executor.Revert(ctx, revert); err != nil {
 		return fmt.Errorf("revert state to layer %v: %w", revert, err)
 	}
-	if err := layers.UnsetAppliedFrom(msh.cdb, revert.Add(1)); err != nil {
-		return fmt.Errorf("unset applied layer %v: %w", revert.Add(1), err)
-	}
 	msh.setLatestLayerInState(revert)
 	return nil
 }
@@ -262,27 +373,71 @@ func (msh *Mesh) ensureStateConsistent(ctx context.Context, results []result.Lay
 // ProcessLayer reads latest consensus results and ensures that vm state
 // is consistent with results.
 // It is safe to call after optimistically executing the block.
+// Votes for the layer may be tallied while the previous layer is applied to the state, the layers
+// are applied in order, see pipeline.
 func (msh *Mesh) ProcessLayer(ctx context.Context, lid types.LayerID) error {
-	msh.mu.Lock()
-	defer msh.mu.Unlock()
-
+	msh.pipe.tally.Lock()
+	seq := msh.pipe.enter()
 	msh.logger.With().Debug("processing layer",
 		log.Context(ctx),
 		log.Uint32("layer_id", lid.Uint32()),
 	)
+	results, err := msh.tallyLayer(ctx, lid)
+	if err != nil {
+		msh.pipe.tally.Unlock()
+		return &stageError{stage: tallyStage, layer: lid, err: err}
+	}
+	msh.pipe.complete(tallyStage, lid)
+	// the application stage is entered before the tally stage is left, so that the next layer
+	// may be tallied while this layer is applied, but can't be applied before it
+	msh.pipe.apply.Lock()
+	msh.pipe.tally.Unlock()
+	defer msh.pipe.apply.Unlock()
+
+	if err := msh.pipe.halted(seq, lid); err != nil {
+		// updates are applied together with the next layer
+		msh.addPendingUpdates(results)
+		return err
+	}
+	if err := msh.applyLayer(ctx, lid, results); err != nil {
+		return msh.pipe.fail(applyStage, lid, err)
+	}
+	msh.pipe.complete(applyStage, lid)
+	return nil
+}
 
+// tallyLayer counts the votes for the layer and returns the consensus results changed since the previous call.
+func (msh *Mesh) tallyLayer(ctx context.Context, lid types.LayerID) ([]result.Layer, error) {
+	if err := msh.applyCertificate(ctx, lid); err != nil {
+		return nil, err
+	}
 	msh.trtl.TallyVotes(ctx, lid)
 
-	if err := msh.setProcessedLayer(
-		lid); err != nil {
-		return err
+	if err := msh.setProcessedLayer(lid); err != nil {
+		return nil, err
 	}
 	results := msh.trtl.Updates()
+	if err := msh.recordValidityChanges(ctx, lid, results); err != nil {
+		return nil, err
+	}
+	msh.reportValidityChanges(ctx, results)
+	return results, nil
+}
+
+// addPendingUpdates extends the range of layers with results that were not applied yet.
+// It returns true if results from the previous calls were not applied.
+func (msh *Mesh) addPendingUpdates(results []result.Layer) bool {
 	pending := msh.pendingUpdates.min != 0
 	if len(results) > 0 {
 		msh.pendingUpdates.min = types.MinLayer(msh.pendingUpdates.min, results[0].Layer)
 		msh.pendingUpdates.max = types.MaxLayer(msh.pendingUpdates.max, results[len(results)-1].Layer)
 	}
+	return pending
+}
+
+// applyLayer applies the consensus results to the state, including the results that failed to be applied previously.
+func (msh *Mesh) applyLayer(ctx context.Context, lid types.LayerID, results []result.Layer) error {
+	pending := msh.addPendingUpdates(results)
 	if next := msh.LatestLayerInState() + 1; next < msh.pendingUpdates.min {
 		msh.pendingUpdates.min = next
 		pending = true
@@ -321,6 +476,98 @@ func (msh *Mesh) ProcessLayer(ctx context.Context, lid types.LayerID) error {
 	return nil
 }
 
+// reportValidityChanges reports layers confirmed by tortoise and blocks with validity
+// that was changed since the previous updates, including blocks in layers that were healed.
+func (msh *Mesh) reportValidityChanges(ctx context.Context, results []result.Layer) {
+	for _, layer := range results {
+		changed := false
+		for _, block := range layer.Blocks {
+			if !block.Changed {
+				continue
+			}
+			changed = true
+			msh.logger.With().Debug("block validity changed",
+				log.Context(ctx),
+				log.Uint32("layer_id", layer.Layer.Uint32()),
+				log.Stringer("block", block.Header.ID),
+				log.Bool("valid", block.Valid),
+				log.Bool("invalid", block.Invalid),
+			)
+		}
+		if layer.Verified && changed {
+			events.ReportLayerUpdate(events.LayerUpdate{
+				LayerID: layer.Layer,
+				Status:  events.LayerStatusTypeConfirmed,
+			})
+		}
+	}
+}
+
+// recordValidityChanges appends the blocks with validity different from the latest recorded validity
+// to the history of the blocks. The decision of the tortoise is attributed to healing if it replaces
+// a previous decision of the tortoise.
+func (msh *Mesh) recordValidityChanges(ctx context.Context, lid types.LayerID, results []result.Layer) error {
+	if len(results) == 0 {
+		return nil
+	}
+	if err := msh.cdb.WithTx(ctx, func(dbtx *sql.Tx) error {
+		for _, layer := range results {
+			var certs []certificates.CertValidity
+			for _, block := range layer.Blocks {
+				change := blocks.ValidityChange{Layer: lid, Source: blocks.SourceVerifying}
+				switch {
+				case block.Valid:
+					change.New = blocks.Valid
+				case block.Invalid:
+					change.New = blocks.Invalid
+				case block.Hare:
+					change.New = blocks.Valid
+					change.Source = blocks.SourceHare
+					if certs == nil {
+						var err error
+						if certs, err = certificates.Get(dbtx, layer.Layer); err != nil && !errors.Is(err, sql.ErrNotFound) {
+							return err
+						}
+					}
+					for _, cert := range certs {
+						if cert.Block == block.Header.ID && cert.Valid && cert.Cert != nil {
+							change.Source = blocks.SourceCertificate
+						}
+					}
+				default:
+					continue
+				}
+				last, err := blocks.LastValidityChange(dbtx, block.Header.ID)
+				switch {
+				case errors.Is(err, sql.ErrNotFound):
+				case err != nil:
+					return err
+				case last.New == change.New:
+					continue
+				default:
+					change.Old = last.New
+					if change.Source == blocks.SourceVerifying &&
+						(last.Source == blocks.SourceVerifying || last.Source == blocks.SourceHealing) {
+						change.Source = blocks.SourceHealing
+					}
+				}
+				if err := blocks.AddValidityChange(dbtx, block.Header.ID, layer.Layer, change); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}); err != nil {
+		return fmt.Errorf("record validity changes: %w", err)
+	}
+	return nil
+}
+
+// ValidityHistory returns the changes of the contextual validity of the block, from the oldest to the latest.
+func (msh *Mesh) ValidityHistory(id types.BlockID) ([]blocks.ValidityChange, error) {
+	return blocks.ValidityHistory(msh.cdb, id)
+}
+
 func missingBlocks(results []result.Layer) []types.BlockID {
 	var response []types.BlockID
 	for _, layer := range results {
@@ -348,7 +595,7 @@ func (msh *Mesh) applyResults(ctx context.Context, results []result.Layer) error
 			var block *types.Block
 			if !target.IsEmpty() {
 				var err error
-				block, err = blocks.Get(msh.cdb, target)
+				block, err = msh.cdb.GetBlock(target)
 				if err != nil {
 					return fmt.Errorf("get block: %w", err)
 				}
@@ -367,8 +614,13 @@ func (msh *Mesh) applyResults(ctx context.Context, results []result.Layer) error
 			if err := layers.SetApplied(dbtx, layer.Layer, target); err != nil {
 				return fmt.Errorf("set applied for %v/%v: %w", layer.Layer, target, err)
 			}
-			if err := layers.SetMeshHash(dbtx, layer.Layer, layer.Opinion); err != nil {
-				return fmt.Errorf("set mesh hash for %v/%v: %w", layer.Layer, layer.Opinion, err)
+			if err := layers.SetAggregatedHash(dbtx, layer.Layer, layer.Opinion); err != nil {
+				return fmt.Errorf("set aggregated hash for %v/%v: %w", layer.Layer, layer.Opinion, err)
+			}
+			if layer.Verified {
+				if err := layers.SetVerified(dbtx, layer.Layer); err != nil {
+					return fmt.Errorf("set verified %v: %w", layer.Layer, err)
+				}
 			}
 			for _, block := range layer.Blocks {
 				if block.Data && block.Valid {
@@ -381,14 +633,19 @@ func (msh *Mesh) applyResults(ctx context.Context, results []result.Layer) error
 					}
 				}
 			}
-			return nil
+			return updateMeshHashes(dbtx, layer.Layer, msh.stateRootLayer)
 		}); err != nil {
 			return err
 		}
 		if layer.Verified {
+			root, err := msh.StateRoot(layer.Layer)
+			if err != nil {
+				return err
+			}
 			events.ReportLayerUpdate(events.LayerUpdate{
-				LayerID: layer.Layer,
-				Status:  events.LayerStatusTypeApplied,
+				LayerID:   layer.Layer,
+				Status:    events.LayerStatusTypeApplied,
+				StateRoot: root,
 			})
 		}
 
@@ -470,6 +727,53 @@ func (msh *Mesh) saveHareOutput(ctx context.Context, lid types.LayerID, bid type
 	return nil
 }
 
+// Certificate returns the valid certificate for the block output by hare in the layer.
+// ErrConflictingCertificates is returned if more than one block in the layer was certified.
+func (msh *Mesh) Certificate(lid types.LayerID) (*types.Certificate, error) {
+	certs, err := certificates.Get(msh.cdb, lid)
+	if err != nil {
+		return nil, err
+	}
+	var valid *types.Certificate
+	for _, cert := range certs {
+		// hare output of the node is stored without a certificate
+		if !cert.Valid || cert.Cert == nil {
+			continue
+		}
+		if valid != nil {
+			return nil, fmt.Errorf("%w: layer %s", ErrConflictingCertificates, lid)
+		}
+		valid = cert.Cert
+	}
+	if valid == nil {
+		return nil, fmt.Errorf("%w: no valid certificate in layer %s", sql.ErrNotFound, lid)
+	}
+	return valid, nil
+}
+
+// applyCertificate passes the certified block to the tortoise as the hare output for the layer.
+// The certificate is authoritative even if hare failed or didn't run for the layer on this node.
+func (msh *Mesh) applyCertificate(ctx context.Context, lid types.LayerID) error {
+	cert, err := msh.Certificate(lid)
+	if errors.Is(err, sql.ErrNotFound) || errors.Is(err, ErrConflictingCertificates) {
+		// conflicting certificates are reported when they are stored
+		return nil
+	} else if err != nil {
+		return err
+	}
+	msh.logger.With().Debug("using certified hare output",
+		log.Context(ctx),
+		log.Uint32("layer_id", lid.Uint32()),
+		log.Stringer("block_id", cert.BlockID),
+	)
+	msh.trtl.OnHareOutput(lid, cert.BlockID)
+	events.ReportLayerUpdate(events.LayerUpdate{
+		LayerID: lid,
+		Status:  events.LayerStatusTypeApproved,
+	})
+	return nil
+}
+
 // ProcessLayerPerHareOutput receives hare output once it finishes running for a given layer.
 func (msh *Mesh) ProcessLayerPerHareOutput(ctx context.Context, layerID types.LayerID, blockID types.BlockID, executed bool) error {
 	if blockID == types.EmptyBlockID {
@@ -487,7 +791,9 @@ func (msh *Mesh) ProcessLayerPerHareOutput(ctx context.Context, layerID types.La
 		return err
 	}
 	if executed {
-		if err := layers.SetApplied(msh.cdb, layerID, blockID); err != nil {
+		if err := msh.cdb.WithTx(ctx, func(dbtx *sql.Tx) error {
+			return layers.SetApplied(dbtx, layerID, blockID)
+		}); err != nil {
 			return fmt.Errorf("optimistically applied for %v/%v: %w", layerID, blockID, err)
 		}
 	}
@@ -515,19 +821,29 @@ func (msh *Mesh) AddTXsFromProposal(ctx context.Context, layerID types.LayerID,
 	return nil
 }
 
-// AddBallot to the mesh.
-func (msh *Mesh) AddBallot(ctx context.Context, ballot *types.Ballot) (*types.MalfeasanceProof, error) {
+// AddBallot to the mesh. Adding the same ballot again has no effect, the returned flag is true only if
+// the ballot was newly added.
+func (msh *Mesh) AddBallot(ctx context.Context, ballot *types.Ballot) (*types.MalfeasanceProof, bool, error) {
 	malicious, err := msh.cdb.IsMalicious(ballot.SmesherID)
 	if err != nil {
-		return nil, err
+		return nil, false, err
 	}
 	if malicious {
 		ballot.SetMalicious()
 	}
-	var proof *types.MalfeasanceProof
+	var (
+		proof *types.MalfeasanceProof
+		added bool
+	)
 	// ballots.LayerBallotByNodeID and ballots.Add should be atomic
 	// otherwise concurrent ballots.Add from the same smesher may not be noticed
 	if err = msh.cdb.WithTx(ctx, func(dbtx *sql.Tx) error {
+		existing, err := ballots.Get(dbtx, ballot.ID())
+		if err == nil {
+			return msh.checkConflict(ctx, "ballot", ballot.ID(), existing, ballot)
+		} else if !errors.Is(err, sql.ErrNotFound) {
+			return err
+		}
 		if !malicious {
 			prev, err := ballots.LayerBallotByNodeID(dbtx, ballot.Layer, ballot.SmesherID)
 			if err != nil && !errors.Is(err, sql.ErrNotFound) {
@@ -567,36 +883,77 @@ func (msh *Mesh) AddBallot(ctx context.Context, ballot *types.Ballot) (*types.Ma
 				)
 			}
 		}
-		if err = ballots.Add(dbtx, ballot); err != nil && !errors.Is(err, sql.ErrObjectExists) {
+		if err = ballots.Add(dbtx, ballot); err != nil {
 			return err
 		}
+		added = true
 		return nil
 	}); err != nil {
-		return nil, err
+		return nil, false, err
+	}
+	if added {
+		msh.cdb.CacheBallot(ballot)
 	}
 	if proof != nil {
 		msh.cdb.CacheMalfeasanceProof(ballot.SmesherID, proof)
 		msh.trtl.OnMalfeasance(ballot.SmesherID)
 	}
-	return proof, nil
+	return proof, added, nil
 }
 
-// AddBlockWithTXs adds the block and its TXs in into the database.
-func (msh *Mesh) AddBlockWithTXs(ctx context.Context, block *types.Block) error {
+// AddBlockWithTXs adds the block and its TXs in into the database. Adding the same block again has no effect,
+// the returned flag is true only if the block was newly added.
+func (msh *Mesh) AddBlockWithTXs(ctx context.Context, block *types.Block) (bool, error) {
 	logger := msh.logger.WithContext(ctx).WithFields(block.LayerIndex, block.ID(), log.Int("num_txs", len(block.TxIDs)))
+	if existing, err := msh.cdb.GetBlock(block.ID()); err == nil {
+		return false, msh.checkConflict(ctx, "block", block.ID(), &existing.InnerBlock, &block.InnerBlock)
+	} else if !errors.Is(err, sql.ErrNotFound) {
+		return false, err
+	}
 	if err := msh.conState.LinkTXsWithBlock(block.LayerIndex, block.ID(), block.TxIDs); err != nil {
-		return fmt.Errorf("link block txs: %v/%v: %w", block.LayerIndex, block.ID(), err)
+		return false, fmt.Errorf("link block txs: %v/%v: %w", block.LayerIndex, block.ID(), err)
 	}
 	msh.setLatestLayer(logger, block.LayerIndex)
 	logger.Debug("associated txs to block")
 
-	// add block to the tortoise before storing it
-	// otherwise fetcher will not wait until data is stored in the tortoise
-	msh.trtl.OnBlock(block.ToVote())
-	if err := blocks.Add(msh.cdb, block); err != nil && !errors.Is(err, sql.ErrObjectExists) {
-		return err
+	var added bool
+	// the block may be added concurrently after the check above
+	if err := msh.cdb.WithTx(ctx, func(dbtx *sql.Tx) error {
+		existing, err := blocks.Get(dbtx, block.ID())
+		if err == nil {
+			return msh.checkConflict(ctx, "block", block.ID(), &existing.InnerBlock, &block.InnerBlock)
+		} else if !errors.Is(err, sql.ErrNotFound) {
+			return err
+		}
+		// add block to the tortoise before storing it
+		// otherwise fetcher will not wait until data is stored in the tortoise
+		msh.trtl.OnBlock(block.ToVote())
+		if err := blocks.Add(dbtx, block); err != nil {
+			return err
+		}
+		added = true
+		return nil
+	}); err != nil {
+		return false, err
 	}
-	return nil
+	if added {
+		msh.cdb.CacheBlock(block)
+	}
+	return added, nil
+}
+
+// checkConflict returns an error if the record with the same id as the stored one has different content.
+// It should never happen, as ids are derived from the content.
+func (msh *Mesh) checkConflict(ctx context.Context, kind string, id fmt.Stringer, existing, received codec.Encodable) error {
+	if bytes.Equal(codec.MustEncode(existing), codec.MustEncode(received)) {
+		return nil
+	}
+	metrics.Conflicts.WithLabelValues(kind).Inc()
+	msh.logger.WithContext(ctx).With().Error("CRITICAL: conflicting record with the same id",
+		log.String("kind", kind),
+		log.Stringer("id", id),
+	)
+	return fmt.Errorf("%w: %s %s with different content", ErrConflict, kind, id)
 }
 
 // GetATXs uses GetFullAtx to return a list of atxs corresponding to atxIds requested.
@@ -2,21 +2,28 @@ package mesh
 
 import (
 	"context"
+	"errors"
+	"runtime"
 	"testing"
 	"time"
 
 	"github.com/golang/mock/gomock"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/require"
+	"go.uber.org/atomic"
+	"golang.org/x/sync/errgroup"
 
 	"github.com/spacemeshos/go-spacemesh/activation"
 	"github.com/spacemeshos/go-spacemesh/common/fixture"
 	"github.com/spacemeshos/go-spacemesh/common/types"
 	"github.com/spacemeshos/go-spacemesh/common/types/result"
 	"github.com/spacemeshos/go-spacemesh/datastore"
+	"github.com/spacemeshos/go-spacemesh/events"
 	"github.com/spacemeshos/go-spacemesh/genvm/sdk/wallet"
 	"github.com/spacemeshos/go-spacemesh/hash"
 	"github.com/spacemeshos/go-spacemesh/log/logtest"
 	"github.com/spacemeshos/go-spacemesh/malfeasance"
+	"github.com/spacemeshos/go-spacemesh/mesh/metrics"
 	"github.com/spacemeshos/go-spacemesh/mesh/mocks"
 	"github.com/spacemeshos/go-spacemesh/signing"
 	"github.com/spacemeshos/go-spacemesh/sql"
@@ -152,8 +159,9 @@ func createLayerBallots(tb testing.TB, mesh *Mesh, lyrID types.LayerID) []*types
 	for i := 0; i < numBallots; i++ {
 		ballot := genLayerBallot(tb, lyrID)
 		blts = append(blts, ballot)
-		malProof, err := mesh.AddBallot(context.Background(), ballot)
+		malProof, added, err := mesh.AddBallot(context.Background(), ballot)
 		require.NoError(tb, err)
+		require.True(tb, added)
 		require.Nil(tb, malProof)
 	}
 	return blts
@@ -186,6 +194,10 @@ func TestMesh_FromGenesis(t *testing.T) {
 	require.NoError(t, err)
 	buf := hash.Sum(nil)
 	require.Equal(t, buf[:], opinion[:])
+
+	meshHash, err := tm.MeshHash(types.GetEffectiveGenesis())
+	require.NoError(t, err)
+	require.Equal(t, genesisMeshHash(), meshHash)
 }
 
 func TestMesh_WakeUpWhileGenesis(t *testing.T) {
@@ -243,6 +255,112 @@ func TestMesh_GetLayer(t *testing.T) {
 	require.ElementsMatch(t, blks, lyr.Blocks())
 }
 
+func TestMesh_IterateLayer(t *testing.T) {
+	tm := createTestMesh(t)
+	id := types.GetEffectiveGenesis().Add(1)
+	blks := createLayerBlocks(t, tm.db, tm.Mesh, id)
+	blts := createLayerBallots(t, tm.Mesh, id)
+	createLayerBallots(t, tm.Mesh, id.Add(1))
+
+	var gotBallots []*types.Ballot
+	require.NoError(t, tm.IterateLayerBallots(id, func(ballot *types.Ballot) bool {
+		gotBallots = append(gotBallots, ballot)
+		return true
+	}))
+	require.ElementsMatch(t, blts, gotBallots)
+	var gotBlocks []*types.Block
+	require.NoError(t, tm.IterateLayerBlocks(id, func(block *types.Block) bool {
+		gotBlocks = append(gotBlocks, block)
+		return true
+	}))
+	require.ElementsMatch(t, blks, gotBlocks)
+
+	count := 0
+	require.NoError(t, tm.IterateLayerBallots(id, func(*types.Ballot) bool {
+		count++
+		return false
+	}))
+	require.Equal(t, 1, count)
+
+	count, err := tm.CountLayerBallots(id)
+	require.NoError(t, err)
+	require.Equal(t, len(blts), count)
+	count, err = tm.CountLayerBlocks(id)
+	require.NoError(t, err)
+	require.Equal(t, len(blks), count)
+	bids, err := tm.LayerBallotIDs(id)
+	require.NoError(t, err)
+	require.Len(t, bids, len(blts))
+	for _, ballot := range blts {
+		require.Contains(t, bids, ballot.ID())
+	}
+	blockIDs, err := tm.LayerBlockIDs(id)
+	require.NoError(t, err)
+	require.ElementsMatch(t, types.ToBlockIDs(blks), blockIDs)
+}
+
+// BenchmarkServeLayer compares the peak heap usage of serving a layer with 500 proposals
+// by loading the whole layer and by iterating over it.
+func BenchmarkServeLayer(b *testing.B) {
+	const (
+		proposals = 500
+		activeSet = 1000
+	)
+	msh := &Mesh{logger: logtest.New(b), cdb: datastore.NewCachedDB(sql.InMemory(), logtest.New(b))}
+	lid := types.GetEffectiveGenesis().Add(1)
+	for i := 0; i < proposals; i++ {
+		ballot := genLayerBallot(b, lid)
+		ballot.EpochData = &types.EpochData{ActiveSetHash: types.RandomHash(), Beacon: types.RandomBeacon()}
+		ballot.ActiveSet = types.RandomActiveSet(activeSet)
+		require.NoError(b, ballots.Add(msh.cdb, ballot))
+	}
+
+	var ms runtime.MemStats
+	heap := func() uint64 {
+		runtime.ReadMemStats(&ms)
+		return ms.HeapAlloc
+	}
+	serve := func(b *testing.B, fn func(sample func()) int) {
+		var peak uint64
+		for i := 0; i < b.N; i++ {
+			runtime.GC()
+			base := heap()
+			sample := func() {
+				if used := heap(); used > base && used-base > peak {
+					peak = used - base
+				}
+			}
+			require.Equal(b, proposals*activeSet, fn(sample))
+		}
+		b.ReportMetric(float64(peak), "peak-heap-B")
+	}
+	b.Run("GetLayer", func(b *testing.B) {
+		serve(b, func(sample func()) int {
+			layer, err := msh.GetLayer(lid)
+			require.NoError(b, err)
+			sample()
+			served := 0
+			for _, ballot := range layer.Ballots() {
+				served += len(ballot.ActiveSet)
+			}
+			return served
+		})
+	})
+	b.Run("Iterate", func(b *testing.B) {
+		serve(b, func(sample func()) int {
+			served := 0
+			require.NoError(b, msh.IterateLayerBallots(lid, func(ballot *types.Ballot) bool {
+				served += len(ballot.ActiveSet)
+				if served%(100*activeSet) == 0 {
+					sample()
+				}
+				return true
+			}))
+			return served
+		})
+	})
+}
+
 func TestMesh_LatestKnownLayer(t *testing.T) {
 	tm := createTestMesh(t)
 	lg := logtest.New(t)
@@ -284,7 +402,9 @@ func TestMesh_AddBlockWithTXs(t *testing.T) {
 	layerID := types.GetEffectiveGenesis().Add(1)
 	block := genLayerBlock(layerID, txIDs)
 	tm.mockState.EXPECT().LinkTXsWithBlock(layerID, block.ID(), txIDs).Return(nil)
-	r.NoError(tm.AddBlockWithTXs(context.Background(), block))
+	added, err := tm.AddBlockWithTXs(context.Background(), block)
+	r.NoError(err)
+	r.True(added)
 }
 
 func TestMesh_CallOnBlock(t *testing.T) {
@@ -295,7 +415,88 @@ func TestMesh_CallOnBlock(t *testing.T) {
 
 	tm.mockTortoise.EXPECT().OnBlock(block.ToVote())
 	tm.mockState.EXPECT().LinkTXsWithBlock(block.LayerIndex, block.ID(), block.TxIDs)
-	require.NoError(t, tm.AddBlockWithTXs(context.Background(), &block))
+	added, err := tm.AddBlockWithTXs(context.Background(), &block)
+	require.NoError(t, err)
+	require.True(t, added)
+}
+
+func TestMesh_AddBallotIdempotent(t *testing.T) {
+	const concurrency = 8
+	tm := createTestMesh(t)
+	ballot := genLayerBallot(t, types.GetEffectiveGenesis().Add(1))
+
+	var (
+		eg    errgroup.Group
+		added atomic.Int32
+	)
+	for i := 0; i < concurrency; i++ {
+		eg.Go(func() error {
+			copied := *ballot
+			proof, ok, err := tm.AddBallot(context.Background(), &copied)
+			if err != nil {
+				return err
+			}
+			if proof != nil {
+				return errors.New("unexpected malfeasance proof")
+			}
+			if ok {
+				added.Inc()
+			}
+			return nil
+		})
+	}
+	require.NoError(t, eg.Wait())
+	require.EqualValues(t, 1, added.Load())
+	require.False(t, ballot.IsMalicious())
+	mal, err := identities.IsMalicious(tm.cdb, ballot.SmesherID)
+	require.NoError(t, err)
+	require.False(t, mal)
+
+	// the ballot with the same id and different content is never stored
+	conflicts := testutil.ToFloat64(metrics.Conflicts.WithLabelValues("ballot"))
+	conflicting := *ballot
+	conflicting.Signature = types.RandomEdSignature()
+	_, ok, err := tm.AddBallot(context.Background(), &conflicting)
+	require.ErrorIs(t, err, ErrConflict)
+	require.False(t, ok)
+	require.Equal(t, conflicts+1, testutil.ToFloat64(metrics.Conflicts.WithLabelValues("ballot")))
+	stored, err := ballots.Get(tm.cdb, ballot.ID())
+	require.NoError(t, err)
+	require.Equal(t, ballot.Signature, stored.Signature)
+}
+
+func TestMesh_AddBlockIdempotent(t *testing.T) {
+	const concurrency = 8
+	tm := createTestMesh(t)
+	txIDs := types.RandomTXSet(numTXs)
+	layerID := types.GetEffectiveGenesis().Add(1)
+	block := genLayerBlock(layerID, txIDs)
+	// the block is passed to the tortoise once
+	tm.mockTortoise.EXPECT().OnBlock(block.ToVote())
+	tm.mockState.EXPECT().LinkTXsWithBlock(layerID, block.ID(), txIDs).AnyTimes()
+
+	var (
+		eg    errgroup.Group
+		added atomic.Int32
+	)
+	for i := 0; i < concurrency; i++ {
+		eg.Go(func() error {
+			copied := *block
+			ok, err := tm.AddBlockWithTXs(context.Background(), &copied)
+			if err != nil {
+				return err
+			}
+			if ok {
+				added.Inc()
+			}
+			return nil
+		})
+	}
+	require.NoError(t, eg.Wait())
+	require.EqualValues(t, 1, added.Load())
+	ids, err := blocks.IDsInLayer(tm.cdb, layerID)
+	require.NoError(t, err)
+	require.Equal(t, []types.BlockID{block.ID()}, ids)
 }
 
 func TestMesh_MaliciousBallots(t *testing.T) {
@@ -318,7 +519,7 @@ func TestMesh_MaliciousBallots(t *testing.T) {
 		require.NoError(t, b.Initialize())
 		blts = append(blts, b)
 	}
-	malProof, err := tm.AddBallot(context.Background(), blts[0])
+	malProof, _, err := tm.AddBallot(context.Background(), blts[0])
 	require.NoError(t, err)
 	require.Nil(t, malProof)
 	require.False(t, blts[0].IsMalicious())
@@ -332,7 +533,7 @@ func TestMesh_MaliciousBallots(t *testing.T) {
 	// second one will create a MalfeasanceProof
 
 	tm.mockTortoise.EXPECT().OnMalfeasance(sig.NodeID())
-	malProof, err = tm.AddBallot(context.Background(), blts[1])
+	malProof, _, err = tm.AddBallot(context.Background(), blts[1])
 	require.NoError(t, err)
 	require.NotNil(t, malProof)
 	require.True(t, blts[1].IsMalicious())
@@ -350,7 +551,7 @@ func TestMesh_MaliciousBallots(t *testing.T) {
 	expected := malProof
 
 	// third one will NOT generate another MalfeasanceProof
-	malProof, err = tm.AddBallot(context.Background(), blts[2])
+	malProof, _, err = tm.AddBallot(context.Background(), blts[2])
 	require.NoError(t, err)
 	require.Nil(t, malProof)
 	// but identity is still malicious
@@ -751,6 +952,93 @@ func fullcert(id types.BlockID) certificates.CertValidity {
 	}
 }
 
+func TestProcessLayerReportsValidityChanges(t *testing.T) {
+	events.CloseEventReporter()
+	events.InitializeReporter()
+	t.Cleanup(events.CloseEventReporter)
+	sub := events.SubscribeLayers()
+	require.NotNil(t, sub)
+
+	types.SetLayersPerEpoch(3)
+	start := types.GetEffectiveGenesis().Add(1)
+	tm := createTestMesh(t)
+	tm.mockTortoise.EXPECT().TallyVotes(gomock.Any(), gomock.Any())
+	tm.mockVM.EXPECT().GetStateRoot().AnyTimes()
+	tm.mockVM.EXPECT().Revert(gomock.Any()).AnyTimes()
+	tm.mockState.EXPECT().RevertCache(gomock.Any()).AnyTimes()
+	tm.mockVM.EXPECT().Apply(gomock.Any(), gomock.Any(), gomock.Any()).Times(2)
+	tm.mockState.EXPECT().UpdateCache(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Times(2)
+
+	updates := rlayers(
+		rlayer(start, rblock(idg("1"), fixture.Good(), fixture.Changed())),
+		rlayer(start.Add(1), rblock(idg("2"), fixture.Good())),
+	)
+	tm.mockTortoise.EXPECT().Updates().Return(updates)
+	ensuresDatabaseConsistent(t, tm.cdb, updates)
+	require.NoError(t, tm.ProcessLayer(context.Background(), start.Add(1)))
+
+	var confirmed []types.LayerID
+	for {
+		select {
+		case ev := <-sub.Out():
+			if update := ev.(events.LayerUpdate); update.Status == events.LayerStatusTypeConfirmed {
+				confirmed = append(confirmed, update.LayerID)
+			}
+			continue
+		default:
+		}
+		break
+	}
+	require.Equal(t, []types.LayerID{start}, confirmed)
+}
+
+func TestProcessLayer_ValidityHistory(t *testing.T) {
+	tm := createTestMesh(t)
+	start := types.GetEffectiveGenesis().Add(1)
+	tm.mockTortoise.EXPECT().TallyVotes(gomock.Any(), gomock.Any()).AnyTimes()
+	tm.mockTortoise.EXPECT().OnHareOutput(gomock.Any(), gomock.Any()).AnyTimes()
+	tm.mockVM.EXPECT().GetStateRoot().AnyTimes()
+	tm.mockVM.EXPECT().Revert(gomock.Any()).AnyTimes()
+	tm.mockState.EXPECT().RevertCache(gomock.Any()).AnyTimes()
+	tm.mockVM.EXPECT().Apply(gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes()
+	tm.mockState.EXPECT().UpdateCache(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes()
+
+	certified := idg("2")
+	require.NoError(t, certificates.Add(tm.cdb, start.Add(1), &types.Certificate{BlockID: certified}))
+	for i, updates := range [][]result.Layer{
+		rlayers(fixture.RLayerNonFinal(start, rblock(idg("1"), fixture.Hare(), fixture.Data()))),
+		rlayers(
+			rlayer(start, rblock(idg("1"), fixture.Invalid(), fixture.Hare(), fixture.Data())),
+			fixture.RLayerNonFinal(start.Add(1), rblock(certified, fixture.Hare(), fixture.Data())),
+		),
+		// repeated decision is not recorded
+		rlayers(rlayer(start, rblock(idg("1"), fixture.Invalid(), fixture.Hare(), fixture.Data()))),
+		rlayers(rlayer(start, rblock(idg("1"), fixture.Valid(), fixture.Hare(), fixture.Data()))),
+	} {
+		tm.mockTortoise.EXPECT().Updates().Return(updates)
+		ensuresDatabaseConsistent(t, tm.cdb, updates)
+		require.NoError(t, tm.ProcessLayer(context.Background(), start.Add(uint32(i))))
+	}
+
+	history, err := tm.ValidityHistory(idg("1"))
+	require.NoError(t, err)
+	require.Equal(t, []blocks.ValidityChange{
+		{Layer: start, Old: blocks.Undecided, New: blocks.Valid, Source: blocks.SourceHare},
+		{Layer: start.Add(1), Old: blocks.Valid, New: blocks.Invalid, Source: blocks.SourceVerifying},
+		{Layer: start.Add(3), Old: blocks.Invalid, New: blocks.Valid, Source: blocks.SourceHealing},
+	}, history)
+
+	history, err = tm.ValidityHistory(certified)
+	require.NoError(t, err)
+	require.Equal(t, []blocks.ValidityChange{
+		{Layer: start.Add(1), Old: blocks.Undecided, New: blocks.Valid, Source: blocks.SourceCertificate},
+	}, history)
+
+	history, err = tm.ValidityHistory(idg("3"))
+	require.NoError(t, err)
+	require.Empty(t, history)
+}
+
 func TestProcessLayerPerHareOutput(t *testing.T) {
 	t.Parallel()
 	type cert struct {
@@ -899,3 +1187,124 @@ func TestProcessLayerPerHareOutput(t *testing.T) {
 		})
 	}
 }
+
+func TestMesh_Certificate(t *testing.T) {
+	tm := createTestMesh(t)
+	tm.mockTortoise.EXPECT().TallyVotes(gomock.Any(), gomock.Any()).AnyTimes()
+	tm.mockTortoise.EXPECT().Updates().Return(nil).AnyTimes()
+	lid := types.GetEffectiveGenesis().Add(1)
+
+	_, err := tm.Certificate(lid)
+	require.ErrorIs(t, err, sql.ErrNotFound)
+	require.NoError(t, tm.ProcessLayer(context.Background(), lid))
+
+	// the output of the local hare is not a certificate
+	require.NoError(t, certificates.SetHareOutput(tm.cdb, lid, idg("1")))
+	_, err = tm.Certificate(lid)
+	require.ErrorIs(t, err, sql.ErrNotFound)
+
+	// hare failed, but the block was certified by the network
+	lid = lid.Add(1)
+	cert := &types.Certificate{BlockID: idg("2")}
+	require.NoError(t, certificates.Add(tm.cdb, lid, cert))
+	got, err := tm.Certificate(lid)
+	require.NoError(t, err)
+	require.Equal(t, cert, got)
+	tm.mockTortoise.EXPECT().OnHareOutput(lid, cert.BlockID)
+	require.NoError(t, tm.ProcessLayer(context.Background(), lid))
+
+	// both certificates are kept, tortoise isn't told to support either of them
+	lid = lid.Add(1)
+	require.NoError(t, certificates.Add(tm.cdb, lid, &types.Certificate{BlockID: idg("3")}))
+	require.NoError(t, certificates.Add(tm.cdb, lid, &types.Certificate{BlockID: idg("4")}))
+	_, err = tm.Certificate(lid)
+	require.ErrorIs(t, err, ErrConflictingCertificates)
+	require.NoError(t, tm.ProcessLayer(context.Background(), lid))
+	certs, err := certificates.Get(tm.cdb, lid)
+	require.NoError(t, err)
+	require.Len(t, certs, 2)
+}
+
+func TestMesh_MeshHash(t *testing.T) {
+	types.SetLayersPerEpoch(3)
+	tm := createTestMesh(t)
+	tm.mockTortoise.EXPECT().TallyVotes(gomock.Any(), gomock.Any()).AnyTimes()
+	tm.mockVM.EXPECT().GetStateRoot().AnyTimes()
+	tm.mockVM.EXPECT().Revert(gomock.Any()).AnyTimes()
+	tm.mockVM.EXPECT().Apply(gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes()
+	tm.mockState.EXPECT().RevertCache(gomock.Any()).AnyTimes()
+	tm.mockState.EXPECT().UpdateCache(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes()
+
+	genesis := types.GetEffectiveGenesis()
+	last := genesis.Add(6)
+	applied := map[types.LayerID]types.BlockID{}
+	process := func(lid types.LayerID, updates []result.Layer) {
+		t.Helper()
+		for _, layer := range updates {
+			applied[layer.Layer] = layer.FirstValid()
+		}
+		tm.mockTortoise.EXPECT().Updates().Return(updates)
+		ensuresDatabaseConsistent(t, tm.cdb, updates)
+		require.NoError(t, tm.ProcessLayer(context.Background(), lid))
+	}
+	expected := func() map[types.LayerID]types.Hash32 {
+		rst := map[types.LayerID]types.Hash32{genesis: genesisMeshHash()}
+		for lid := genesis.Add(1); !last.Before(lid); lid = lid.Add(1) {
+			var ids []types.BlockID
+			if bid := applied[lid]; !bid.IsEmpty() {
+				ids = []types.BlockID{bid}
+			}
+			rst[lid] = calcMeshHash(rst[lid.Sub(1)], ids, nil)
+		}
+		return rst
+	}
+	requireHashes := func(hashes map[types.LayerID]types.Hash32) {
+		t.Helper()
+		for lid, h := range hashes {
+			got, err := tm.MeshHash(lid)
+			require.NoError(t, err, lid)
+			require.Equal(t, h, got, lid)
+		}
+	}
+
+	for lid := genesis.Add(1); !last.Before(lid); lid = lid.Add(1) {
+		process(lid, rlayers(rlayer(lid, rblock(idg(lid.String()), fixture.Good()))))
+	}
+	before := expected()
+	requireHashes(before)
+	require.Equal(t, calcMeshHash(genesisMeshHash(), nil, nil), calcMeshHash(genesisMeshHash(), []types.BlockID{}, nil))
+	require.NotEqual(t, genesisMeshHash(), calcMeshHash(genesisMeshHash(), nil, nil))
+
+	// validity of the block three layers back flips, all subsequent layers are affected
+	flipped := last.Sub(3)
+	updates := rlayers(rlayer(flipped,
+		rblock(idg(flipped.String()), fixture.Invalid(), fixture.Data()),
+		rblock(idg("flipped"), fixture.Good()),
+	))
+	for lid := flipped.Add(1); !last.Before(lid); lid = lid.Add(1) {
+		updates = append(updates, rlayer(lid, rblock(idg(lid.String()), fixture.Good())))
+	}
+	process(last, updates)
+	after := expected()
+	requireHashes(after)
+	for lid := genesis; !last.Before(lid); lid = lid.Add(1) {
+		if lid.Before(flipped) {
+			require.Equal(t, before[lid], after[lid], lid)
+		} else {
+			require.NotEqual(t, before[lid], after[lid], lid)
+		}
+	}
+
+	// hashes are recomputed for the layers applied before they were stored
+	_, err := tm.cdb.Exec("update layers set mesh_hash = null;", nil, nil)
+	require.NoError(t, err)
+	got, err := tm.MeshHash(last)
+	require.NoError(t, err)
+	require.Equal(t, after[last], got)
+	stored, err := layers.GetMeshHash(tm.cdb, flipped)
+	require.NoError(t, err)
+	require.Equal(t, after[flipped], stored)
+
+	_, err = tm.MeshHash(last.Add(1))
+	require.ErrorIs(t, err, sql.ErrNotFound)
+}
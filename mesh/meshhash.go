@@ -0,0 +1,123 @@
+package mesh
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/spacemeshos/go-spacemesh/common/types"
+	"github.com/spacemeshos/go-spacemesh/hash"
+	"github.com/spacemeshos/go-spacemesh/sql"
+	"github.com/spacemeshos/go-spacemesh/sql/layers"
+)
+
+func genesisMeshHash() types.Hash32 {
+	return types.Hash32(hash.Sum(nil))
+}
+
+// calcMeshHash chains the blocks applied in the layer to the mesh hash of the previous layer:
+// H(prev || sorted ids of the applied blocks || state root). The state root is included only
+// from the upgrade layer, it is nil before. The hash of an empty layer depends only on the previous hash
+// and the state root.
+func calcMeshHash(prev types.Hash32, applied []types.BlockID, root *types.Hash32) types.Hash32 {
+	ids := make([]types.BlockID, len(applied))
+	copy(ids, applied)
+	types.SortBlockIDs(ids)
+	h := hash.New()
+	h.Write(prev[:])
+	for _, id := range ids {
+		h.Write(id[:])
+	}
+	if root != nil {
+		h.Write(root[:])
+	}
+	var rst types.Hash32
+	h.Sum(rst[:0])
+	return rst
+}
+
+func appliedBlocks(db sql.Executor, lid types.LayerID) ([]types.BlockID, error) {
+	applied, err := layers.GetApplied(db, lid)
+	if err != nil {
+		return nil, err
+	}
+	if applied.IsEmpty() {
+		return nil, nil
+	}
+	return []types.BlockID{applied}, nil
+}
+
+// hashedStateRoot returns the state root of the layer if it is included in the mesh hash,
+// i.e. the layer is not before stateRootLayer. Zero stateRootLayer disables the upgrade.
+func hashedStateRoot(db sql.Executor, lid, stateRootLayer types.LayerID) (*types.Hash32, error) {
+	if stateRootLayer == 0 || lid.Before(stateRootLayer) {
+		return nil, nil
+	}
+	root, err := layers.GetStateHash(db, lid)
+	if err != nil {
+		return nil, fmt.Errorf("state root for mesh hash %s: %w", lid, err)
+	}
+	return &root, nil
+}
+
+// updateMeshHashes updates the mesh hash of the layer and of all applied layers after it,
+// as every hash depends on the hashes of the previous layers.
+func updateMeshHashes(dbtx *sql.Tx, from, stateRootLayer types.LayerID) error {
+	prev, err := recoverMeshHash(dbtx, from.Sub(1), stateRootLayer)
+	if err != nil {
+		return err
+	}
+	for lid := from; ; lid = lid.Add(1) {
+		applied, err := appliedBlocks(dbtx, lid)
+		if errors.Is(err, sql.ErrNotFound) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		root, err := hashedStateRoot(dbtx, lid, stateRootLayer)
+		if err != nil {
+			return err
+		}
+		prev = calcMeshHash(prev, applied, root)
+		if err := layers.SetMeshHash(dbtx, lid, prev); err != nil {
+			return err
+		}
+	}
+}
+
+// recoverMeshHash returns the mesh hash of the layer, computing and persisting the hashes
+// of the layers that were applied before the hashes were stored, starting from the closest layer with a known hash.
+func recoverMeshHash(dbtx *sql.Tx, lid, stateRootLayer types.LayerID) (types.Hash32, error) {
+	genesis := types.GetEffectiveGenesis()
+	if !genesis.Before(lid) {
+		return genesisMeshHash(), nil
+	}
+	start := lid
+	prev, err := layers.GetMeshHash(dbtx, start)
+	for errors.Is(err, sql.ErrNotFound) && genesis.Before(start) {
+		start = start.Sub(1)
+		prev, err = layers.GetMeshHash(dbtx, start)
+	}
+	switch {
+	case err == nil:
+	case errors.Is(err, sql.ErrNotFound):
+		prev = genesisMeshHash()
+	default:
+		return types.Hash32{}, err
+	}
+	for start < lid {
+		start = start.Add(1)
+		applied, err := appliedBlocks(dbtx, start)
+		if err != nil {
+			return types.Hash32{}, fmt.Errorf("mesh hash for %s: %w", lid, err)
+		}
+		root, err := hashedStateRoot(dbtx, start, stateRootLayer)
+		if err != nil {
+			return types.Hash32{}, err
+		}
+		prev = calcMeshHash(prev, applied, root)
+		if err := layers.SetMeshHash(dbtx, start, prev); err != nil {
+			return types.Hash32{}, err
+		}
+	}
+	return prev, nil
+}
@@ -19,3 +19,43 @@ var LayerNumBlocks = metrics.NewHistogramWithBuckets(
 	[]string{},
 	prometheus.ExponentialBuckets(1, 2, 16),
 )
+
+// Conflicts is number of records with the same id and different content.
+var Conflicts = metrics.NewCounter(
+	"conflicts",
+	Subsystem,
+	"Number of records with the same id and different content",
+	[]string{"kind"},
+)
+
+// PrunedLayer is the layer before which the ephemeral data was pruned.
+var PrunedLayer = metrics.NewGauge(
+	"pruned_layer",
+	Subsystem,
+	"Layer before which proposals and hare data were pruned",
+	[]string{},
+).WithLabelValues()
+
+// Pruned is number of records deleted by the pruner.
+var Pruned = metrics.NewCounter(
+	"pruned",
+	Subsystem,
+	"Number of records deleted by the pruner",
+	[]string{"kind"},
+)
+
+// StageLayer is the last layer that completed the stage of layer processing.
+var StageLayer = metrics.NewGauge(
+	"stage_layer",
+	Subsystem,
+	"Last layer that completed the stage of layer processing",
+	[]string{"stage"},
+)
+
+// ForksDetected is number of forks detected by comparing the local mesh hashes with the hashes of the peers.
+var ForksDetected = metrics.NewCounter(
+	"forks_detected",
+	Subsystem,
+	"Number of forks detected by comparing local mesh hashes with the hashes of the peers",
+	[]string{},
+).WithLabelValues()
@@ -10,7 +10,9 @@ import (
 
 	gomock "github.com/golang/mock/gomock"
 	types "github.com/spacemeshos/go-spacemesh/common/types"
+	fetch "github.com/spacemeshos/go-spacemesh/fetch"
 	vm "github.com/spacemeshos/go-spacemesh/genvm"
+	p2p "github.com/spacemeshos/go-spacemesh/p2p"
 )
 
 // MockconservativeState is a mock of conservativeState interface.
@@ -196,3 +198,41 @@ func (mr *MocklayerClockMockRecorder) CurrentLayer() *gomock.Call {
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CurrentLayer", reflect.TypeOf((*MocklayerClock)(nil).CurrentLayer))
 }
+
+// MockmeshHashFetcher is a mock of meshHashFetcher interface.
+type MockmeshHashFetcher struct {
+	ctrl     *gomock.Controller
+	recorder *MockmeshHashFetcherMockRecorder
+}
+
+// MockmeshHashFetcherMockRecorder is the mock recorder for MockmeshHashFetcher.
+type MockmeshHashFetcherMockRecorder struct {
+	mock *MockmeshHashFetcher
+}
+
+// NewMockmeshHashFetcher creates a new mock instance.
+func NewMockmeshHashFetcher(ctrl *gomock.Controller) *MockmeshHashFetcher {
+	mock := &MockmeshHashFetcher{ctrl: ctrl}
+	mock.recorder = &MockmeshHashFetcherMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockmeshHashFetcher) EXPECT() *MockmeshHashFetcherMockRecorder {
+	return m.recorder
+}
+
+// PeerMeshHashes mocks base method.
+func (m *MockmeshHashFetcher) PeerMeshHashes(arg0 context.Context, arg1 p2p.Peer, arg2 *fetch.MeshHashRequest) (*fetch.MeshHashes, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PeerMeshHashes", arg0, arg1, arg2)
+	ret0, _ := ret[0].(*fetch.MeshHashes)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// PeerMeshHashes indicates an expected call of PeerMeshHashes.
+func (mr *MockmeshHashFetcherMockRecorder) PeerMeshHashes(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PeerMeshHashes", reflect.TypeOf((*MockmeshHashFetcher)(nil).PeerMeshHashes), arg0, arg1, arg2)
+}
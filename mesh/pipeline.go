@@ -0,0 +1,115 @@
+package mesh
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"go.uber.org/atomic"
+
+	"github.com/spacemeshos/go-spacemesh/common/types"
+	"github.com/spacemeshos/go-spacemesh/mesh/metrics"
+)
+
+// ErrLayerHalted is returned for a layer that is not applied to the state, because applying an earlier layer
+// failed while the votes for the layer were tallied. The results for the layer are applied with the next layer.
+var ErrLayerHalted = errors.New("layer processing halted")
+
+type stage uint8
+
+const (
+	// tallyStage counts the votes for the layer and collects the changed consensus results.
+	tallyStage stage = iota
+	// applyStage applies the consensus results to the state.
+	applyStage
+	numStages
+)
+
+func (s stage) String() string {
+	switch s {
+	case tallyStage:
+		return "tally"
+	case applyStage:
+		return "apply"
+	default:
+		panic(fmt.Sprintf("unknown stage %d", s))
+	}
+}
+
+// stageError is a failure of the stage for the layer.
+type stageError struct {
+	stage stage
+	layer types.LayerID
+	err   error
+}
+
+func (e *stageError) Error() string {
+	return fmt.Sprintf("%s stage for layer %s: %v", e.stage, e.layer, e.err)
+}
+
+func (e *stageError) Unwrap() error {
+	return e.err
+}
+
+// haltError is returned for the layer that was tallied before the apply stage failed for an earlier layer.
+type haltError struct {
+	layer types.LayerID
+	cause *stageError
+}
+
+func (e *haltError) Error() string {
+	return fmt.Sprintf("%v: layer %s after failed %v", ErrLayerHalted, e.layer, e.cause)
+}
+
+func (e *haltError) Is(target error) bool {
+	return target == ErrLayerHalted
+}
+
+func (e *haltError) Unwrap() error {
+	return e.cause
+}
+
+// pipeline orders the stages of layer processing. The votes for a layer may be tallied while the previous
+// layer is applied to the state, but each stage processes one layer at a time, in the order in which the layers
+// were tallied: a layer enters the apply stage before it leaves the tally stage, so it can't be overtaken.
+// The processed layers of the mesh are guarded by the tally stage, and the pending updates by the apply stage.
+type pipeline struct {
+	tally, apply sync.Mutex
+
+	// entered is the number of layers that entered the tally stage, it is the sequence number of the last layer.
+	entered atomic.Uint64
+	// failure is the last failure of the apply stage, guarded by apply. The layers that entered the tally stage
+	// before the failure have sequence numbers up to haltAt, they are not applied.
+	failure *stageError
+	haltAt  uint64
+}
+
+// enter returns the sequence number of the layer that entered the tally stage. Must be called within the stage.
+func (p *pipeline) enter() uint64 {
+	return p.entered.Inc()
+}
+
+// complete records that the layer completed the stage. Must be called within the stage.
+func (p *pipeline) complete(s stage, lid types.LayerID) {
+	metrics.StageLayer.WithLabelValues(s.String()).Set(float64(lid))
+}
+
+// fail records the failure of the apply stage for the layer, the layers that already entered the tally stage
+// are halted.
+// Must be called within the apply stage. The tally stage is not entered, as the next layer may hold it
+// while waiting for the apply stage.
+func (p *pipeline) fail(s stage, lid types.LayerID, err error) error {
+	p.failure = &stageError{stage: s, layer: lid, err: err}
+	p.haltAt = p.entered.Load()
+	return p.failure
+}
+
+// halted returns an error if the layer with the sequence number entered the tally stage before an earlier
+// layer failed.
+// Must be called within the apply stage.
+func (p *pipeline) halted(seq uint64, lid types.LayerID) error {
+	if p.failure == nil || seq > p.haltAt {
+		return nil
+	}
+	return &haltError{layer: lid, cause: p.failure}
+}
@@ -0,0 +1,186 @@
+package mesh
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/atomic"
+	"golang.org/x/sync/errgroup"
+
+	"github.com/spacemeshos/go-spacemesh/common/types"
+	"github.com/spacemeshos/go-spacemesh/common/types/result"
+	vm "github.com/spacemeshos/go-spacemesh/genvm"
+)
+
+// pipelineMesh is a test mesh with the tortoise that outputs an empty verified layer for every tallied layer.
+type pipelineMesh struct {
+	*testMesh
+	delay func()
+
+	mu      sync.Mutex
+	tallied types.LayerID
+	applied []types.LayerID
+	// overlaps counts the layers tallied while another layer was applied
+	applying, overlaps atomic.Int32
+}
+
+func newPipelineMesh(t *testing.T, delay func(), apply func(vm.ApplyContext) error) *pipelineMesh {
+	pm := &pipelineMesh{testMesh: createTestMesh(t), delay: delay}
+	pm.mockTortoise.EXPECT().TallyVotes(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(_ context.Context, lid types.LayerID) {
+			pm.delay()
+			if pm.applying.Load() > 0 {
+				pm.overlaps.Inc()
+			}
+			pm.mu.Lock()
+			pm.tallied = types.MaxLayer(pm.tallied, lid)
+			pm.mu.Unlock()
+		}).AnyTimes()
+	pm.mockTortoise.EXPECT().Updates().DoAndReturn(func() []result.Layer {
+		pm.mu.Lock()
+		defer pm.mu.Unlock()
+		return rlayers(rlayer(pm.tallied))
+	}).AnyTimes()
+	pm.mockTortoise.EXPECT().Results(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(from, to types.LayerID) ([]result.Layer, error) {
+			var rst []result.Layer
+			for lid := from; !lid.After(to); lid = lid.Add(1) {
+				rst = append(rst, rlayer(lid))
+			}
+			return rst, nil
+		}).AnyTimes()
+	pm.mockVM.EXPECT().Apply(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+		func(ctx vm.ApplyContext, _ []types.Transaction, _ []types.CoinbaseReward) ([]types.Transaction, []types.TransactionWithResult, error) {
+			pm.applying.Inc()
+			defer pm.applying.Dec()
+			pm.delay()
+			if err := apply(ctx); err != nil {
+				return nil, nil, err
+			}
+			pm.mu.Lock()
+			pm.applied = append(pm.applied, ctx.Layer)
+			pm.mu.Unlock()
+			return nil, nil, nil
+		}).AnyTimes()
+	pm.mockVM.EXPECT().GetStateRoot().AnyTimes()
+	pm.mockState.EXPECT().UpdateCache(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes()
+	return pm
+}
+
+func TestProcessLayer_PipelineOrder(t *testing.T) {
+	const layers = 100
+	var mu sync.Mutex
+	rng := rand.New(rand.NewSource(time.Now().UnixNano()))
+	delay := func() {
+		mu.Lock()
+		d := time.Duration(rng.Intn(500)) * time.Microsecond
+		mu.Unlock()
+		time.Sleep(d)
+	}
+	layerRange := func() []types.LayerID {
+		var rst []types.LayerID
+		genesis := types.GetEffectiveGenesis()
+		for lid := genesis.Add(1); !lid.After(genesis.Add(layers)); lid = lid.Add(1) {
+			rst = append(rst, lid)
+		}
+		return rst
+	}
+
+	t.Run("sequential", func(t *testing.T) {
+		pm := newPipelineMesh(t, delay, func(vm.ApplyContext) error {
+			time.Sleep(time.Millisecond)
+			return nil
+		})
+		expected := layerRange()
+		var eg errgroup.Group
+		for _, lid := range expected {
+			lid := lid
+			eg.Go(func() error {
+				return pm.ProcessLayer(context.Background(), lid)
+			})
+			// the next layer is passed to the mesh once the votes for this layer are tallied
+			require.Eventually(t, func() bool {
+				return !pm.ProcessedLayer().Before(lid)
+			}, time.Second, 10*time.Microsecond)
+		}
+		require.NoError(t, eg.Wait())
+		require.Equal(t, expected, pm.applied)
+		require.Positive(t, pm.overlaps.Load(), "votes were never tallied while state was applied")
+	})
+	t.Run("concurrent", func(t *testing.T) {
+		pm := newPipelineMesh(t, delay, func(vm.ApplyContext) error { return nil })
+		expected := layerRange()
+		var eg errgroup.Group
+		for _, lid := range expected {
+			lid := lid
+			eg.Go(func() error {
+				delay()
+				return pm.ProcessLayer(context.Background(), lid)
+			})
+		}
+		require.NoError(t, eg.Wait())
+		require.Equal(t, expected, pm.applied)
+		require.Equal(t, expected[len(expected)-1], pm.LatestLayerInState())
+		require.Equal(t, expected[len(expected)-1], pm.ProcessedLayer())
+	})
+}
+
+func TestProcessLayer_PipelineHalted(t *testing.T) {
+	var (
+		genesis  types.LayerID
+		failed   types.LayerID
+		errApply = errors.New("apply failed")
+		tallied  = make(chan struct{})
+		once     sync.Once
+	)
+	pm := newPipelineMesh(t, func() {}, func(ctx vm.ApplyContext) error {
+		if ctx.Layer != failed {
+			return nil
+		}
+		var err error
+		once.Do(func() {
+			// the next layer is tallied while this layer is applied
+			<-tallied
+			err = errApply
+		})
+		return err
+	})
+	genesis = types.GetEffectiveGenesis()
+	failed = genesis.Add(2)
+	require.NoError(t, pm.ProcessLayer(context.Background(), genesis.Add(1)))
+
+	errs := make(chan error, 1)
+	go func() {
+		errs <- pm.ProcessLayer(context.Background(), failed)
+	}()
+	require.Eventually(t, func() bool { return pm.applying.Load() > 0 }, time.Second, time.Millisecond)
+	go func() {
+		defer close(tallied)
+		for {
+			pm.mu.Lock()
+			done := pm.tallied == failed.Add(1)
+			pm.mu.Unlock()
+			if done {
+				return
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}()
+	err := pm.ProcessLayer(context.Background(), failed.Add(1))
+	require.ErrorIs(t, err, ErrLayerHalted)
+	require.ErrorIs(t, err, errApply)
+	require.ErrorContains(t, err, failed.String())
+	require.ErrorIs(t, <-errs, errApply)
+	require.Equal(t, []types.LayerID{genesis.Add(1)}, pm.applied)
+	require.Equal(t, genesis.Add(1), pm.LatestLayerInState())
+
+	// the layers that were not applied are applied in order with the next layer
+	require.NoError(t, pm.ProcessLayer(context.Background(), failed.Add(2)))
+	require.Equal(t, []types.LayerID{genesis.Add(1), failed, failed.Add(1), failed.Add(2)}, pm.applied)
+}
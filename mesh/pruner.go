@@ -0,0 +1,165 @@
+package mesh
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/spacemeshos/go-spacemesh/common/types"
+	"github.com/spacemeshos/go-spacemesh/log"
+	"github.com/spacemeshos/go-spacemesh/mesh/metrics"
+	"github.com/spacemeshos/go-spacemesh/sql"
+	"github.com/spacemeshos/go-spacemesh/sql/blocks"
+	"github.com/spacemeshos/go-spacemesh/sql/hareresults"
+	"github.com/spacemeshos/go-spacemesh/sql/haresets"
+	"github.com/spacemeshos/go-spacemesh/sql/harestate"
+	"github.com/spacemeshos/go-spacemesh/sql/layers"
+	"github.com/spacemeshos/go-spacemesh/sql/proposals"
+)
+
+// Config is the configuration of the mesh housekeeping and fork detection.
+type Config struct {
+	// Retention is the number of applied layers for which proposals and hare data are kept.
+	// Zero disables pruning.
+	Retention uint32 `mapstructure:"retention"`
+	// PruneInterval is the interval between pruning runs.
+	PruneInterval time.Duration `mapstructure:"prune-interval"`
+	// PruneBatch is the max number of layers pruned in a single transaction.
+	PruneBatch uint32 `mapstructure:"prune-batch"`
+
+	// ForkQuorum is the fraction of the peers that must advertise a different mesh hash
+	// for the verified layer to report a fork.
+	ForkQuorum float64 `mapstructure:"fork-quorum"`
+	// ForkMinPeers is the min number of peers that advertised the mesh hash for the layer to report a fork.
+	ForkMinPeers int `mapstructure:"fork-min-peers"`
+	// ForkProbeDepth is the max number of layers before the forked layer searched for the earliest
+	// divergent layer.
+	ForkProbeDepth uint32 `mapstructure:"fork-probe-depth"`
+	// ForkRerun enables the tortoise rerun from the earliest divergent layer when a fork is detected.
+	ForkRerun bool `mapstructure:"fork-rerun"`
+}
+
+// DefaultConfig returns the default mesh configuration.
+func DefaultConfig() Config {
+	return Config{
+		Retention:     1000,
+		PruneInterval: time.Minute,
+		PruneBatch:    100,
+
+		ForkQuorum:     0.66,
+		ForkMinPeers:   3,
+		ForkProbeDepth: 1000,
+	}
+}
+
+// Pruner deletes proposals, hare data and the validity history of the blocks of the layers that were applied
+// more than Retention layers ago. Proposals are discarded by design once the block of the layer is created,
+// and the hare data is not needed after the layer is applied.
+//
+// Active sets are stored as part of the ballots, ballots and blocks are kept, so there is nothing else to prune.
+type Pruner struct {
+	logger log.Log
+	db     *sql.Database
+	cfg    Config
+
+	// next is the first layer that may still have data to prune.
+	next types.LayerID
+}
+
+// NewPruner creates a Pruner.
+func NewPruner(db *sql.Database, cfg Config, logger log.Log) *Pruner {
+	return &Pruner{
+		logger: logger,
+		db:     db,
+		cfg:    cfg,
+		next:   types.GetEffectiveGenesis().Add(1),
+	}
+}
+
+// Run prunes the data periodically until the context is canceled.
+func (p *Pruner) Run(ctx context.Context) error {
+	if p.cfg.Retention == 0 {
+		p.logger.Info("pruning is disabled")
+		return nil
+	}
+	ticker := time.NewTicker(p.cfg.PruneInterval)
+	defer ticker.Stop()
+	for {
+		if err := p.Prune(ctx); err != nil && !errors.Is(err, context.Canceled) {
+			p.logger.With().Error("failed to prune", log.Err(err))
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// Prune deletes the data of the layers before the last applied layer minus Retention.
+// Every batch of layers is deleted in a single transaction, so that the data of a layer served to peers
+// concurrently is either complete or missing.
+func (p *Pruner) Prune(ctx context.Context) error {
+	applied, err := layers.GetLastApplied(p.db)
+	if err != nil {
+		return fmt.Errorf("get last applied: %w", err)
+	}
+	if applied.Uint32() < p.cfg.Retention {
+		return nil
+	}
+	cutoff := applied.Sub(p.cfg.Retention)
+	batch := p.cfg.PruneBatch
+	if batch == 0 {
+		batch = 1
+	}
+	for p.next.Before(cutoff) {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		to := p.next.Add(batch)
+		if cutoff.Before(to) {
+			to = cutoff
+		}
+		if err := p.pruneBefore(ctx, to); err != nil {
+			return err
+		}
+		p.next = to
+		metrics.PrunedLayer.Set(float64(to))
+	}
+	return nil
+}
+
+func (p *Pruner) pruneBefore(ctx context.Context, lid types.LayerID) error {
+	deleted := map[string]int{}
+	if err := p.db.WithTx(ctx, func(tx *sql.Tx) error {
+		for _, prune := range []struct {
+			kind string
+			fn   func(sql.Executor, types.LayerID) (int, error)
+		}{
+			{"proposals", proposals.DeleteBefore},
+			{"hare_results", hareresults.DeleteBefore},
+			{"hare_sets", haresets.DeleteBefore},
+			{"hare_state", harestate.DeleteBefore},
+			{"validity_history", blocks.DeleteValidityHistoryBefore},
+		} {
+			n, err := prune.fn(tx, lid)
+			if err != nil {
+				return err
+			}
+			deleted[prune.kind] = n
+		}
+		return nil
+	}); err != nil {
+		return fmt.Errorf("prune before %s: %w", lid, err)
+	}
+	for kind, n := range deleted {
+		metrics.Pruned.WithLabelValues(kind).Add(float64(n))
+	}
+	p.logger.With().Debug("pruned layers",
+		log.Stringer("before", lid),
+		log.Int("proposals", deleted["proposals"]),
+		log.Int("hare_results", deleted["hare_results"]),
+	)
+	return nil
+}
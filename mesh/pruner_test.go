@@ -0,0 +1,147 @@
+package mesh
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/sync/errgroup"
+
+	"github.com/spacemeshos/go-spacemesh/common/types"
+	"github.com/spacemeshos/go-spacemesh/log/logtest"
+	"github.com/spacemeshos/go-spacemesh/mesh/metrics"
+	"github.com/spacemeshos/go-spacemesh/sql"
+	"github.com/spacemeshos/go-spacemesh/sql/ballots"
+	"github.com/spacemeshos/go-spacemesh/sql/blocks"
+	"github.com/spacemeshos/go-spacemesh/sql/hareresults"
+	"github.com/spacemeshos/go-spacemesh/sql/layers"
+	"github.com/spacemeshos/go-spacemesh/sql/proposals"
+)
+
+const proposalsPerLayer = 3
+
+// layerBlock returns the unique id of the block in the layer.
+func layerBlock(lid types.LayerID) types.BlockID {
+	var id types.BlockID
+	binary.LittleEndian.PutUint32(id[:], lid.Uint32())
+	return id
+}
+
+func addPrunableLayer(tb testing.TB, db sql.Executor, lid types.LayerID) {
+	tb.Helper()
+	for i := 0; i < proposalsPerLayer; i++ {
+		ballot := types.NewExistingBallot(types.RandomBallotID(), types.RandomEdSignature(), types.RandomNodeID(), lid)
+		require.NoError(tb, ballots.Add(db, &ballot))
+		p := &types.Proposal{
+			InnerProposal: types.InnerProposal{Ballot: ballot},
+			Signature:     types.RandomEdSignature(),
+		}
+		p.SetID(types.RandomProposalID())
+		require.NoError(tb, proposals.Add(db, p))
+	}
+	require.NoError(tb, hareresults.Set(db, lid, hareresults.Completed, nil))
+	require.NoError(tb, blocks.AddValidityChange(db, layerBlock(lid), lid,
+		blocks.ValidityChange{Layer: lid, New: blocks.Valid, Source: blocks.SourceHare}))
+	require.NoError(tb, layers.SetApplied(db, lid, types.EmptyBlockID))
+}
+
+func TestPruner(t *testing.T) {
+	types.SetLayersPerEpoch(3)
+	db := sql.InMemory()
+	genesis := types.GetEffectiveGenesis()
+	last := genesis.Add(20)
+	for lid := genesis.Add(1); !last.Before(lid); lid = lid.Add(1) {
+		addPrunableLayer(t, db, lid)
+	}
+	// the layer is not applied yet
+	addPrunableLayer(t, db, last.Add(1))
+	require.NoError(t, layers.UnsetAppliedFrom(db, last.Add(1)))
+
+	pruned := testutil.ToFloat64(metrics.Pruned.WithLabelValues("proposals"))
+	p := NewPruner(db, Config{Retention: 5, PruneBatch: 3}, logtest.New(t))
+	require.NoError(t, p.Prune(context.Background()))
+	cutoff := last.Sub(5)
+	require.Equal(t, cutoff, p.next)
+	require.Equal(t, float64(cutoff), testutil.ToFloat64(metrics.PrunedLayer))
+	require.Equal(t, pruned+float64(proposalsPerLayer*(cutoff.Difference(genesis)-1)),
+		testutil.ToFloat64(metrics.Pruned.WithLabelValues("proposals")))
+
+	for lid := genesis.Add(1); !last.Add(1).Before(lid); lid = lid.Add(1) {
+		got, err := proposals.GetByLayer(db, lid)
+		_, _, rerr := hareresults.Get(db, lid)
+		_, herr := blocks.LastValidityChange(db, layerBlock(lid))
+		if lid.Before(cutoff) {
+			require.ErrorIs(t, err, sql.ErrNotFound, lid)
+			require.ErrorIs(t, rerr, sql.ErrNotFound, lid)
+			require.ErrorIs(t, herr, sql.ErrNotFound, lid)
+		} else {
+			require.NoError(t, err, lid)
+			require.Len(t, got, proposalsPerLayer)
+			require.NoError(t, rerr, lid)
+			require.NoError(t, herr, lid)
+		}
+		// ballots are not pruned
+		ids, err := ballots.IDsInLayer(db, lid)
+		require.NoError(t, err)
+		require.Len(t, ids, proposalsPerLayer)
+	}
+
+	// nothing to do until more layers are applied
+	require.NoError(t, p.Prune(context.Background()))
+	require.Equal(t, cutoff, p.next)
+	require.NoError(t, layers.SetApplied(db, last.Add(1), types.EmptyBlockID))
+	require.NoError(t, p.Prune(context.Background()))
+	require.Equal(t, cutoff.Add(1), p.next)
+	_, err := proposals.GetByLayer(db, cutoff)
+	require.ErrorIs(t, err, sql.ErrNotFound)
+}
+
+func TestPruner_Disabled(t *testing.T) {
+	db := sql.InMemory()
+	addPrunableLayer(t, db, types.GetEffectiveGenesis().Add(1))
+	require.NoError(t, NewPruner(db, Config{}, logtest.New(t)).Run(context.Background()))
+}
+
+func TestPruner_ConcurrentReads(t *testing.T) {
+	types.SetLayersPerEpoch(3)
+	db, err := sql.Open("file:"+filepath.Join(t.TempDir(), "state.sql"), sql.WithConnections(4))
+	require.NoError(t, err)
+	t.Cleanup(func() { require.NoError(t, db.Close()) })
+	genesis := types.GetEffectiveGenesis()
+	last := genesis.Add(100)
+	for lid := genesis.Add(1); !last.Before(lid); lid = lid.Add(1) {
+		addPrunableLayer(t, db, lid)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var eg errgroup.Group
+	for i := 0; i < 4; i++ {
+		eg.Go(func() error {
+			for ctx.Err() == nil {
+				for lid := genesis.Add(1); !last.Before(lid); lid = lid.Add(1) {
+					got, err := proposals.GetByLayer(db, lid)
+					if errors.Is(err, sql.ErrNotFound) {
+						continue
+					} else if err != nil {
+						return err
+					}
+					// the layer is either served in full or not served at all
+					if len(got) != proposalsPerLayer {
+						return errors.New("layer is partially pruned")
+					}
+				}
+			}
+			return nil
+		})
+	}
+	p := NewPruner(db, Config{Retention: 1, PruneBatch: 7}, logtest.New(t))
+	require.NoError(t, p.Prune(ctx))
+	cancel()
+	require.NoError(t, eg.Wait())
+	require.Equal(t, last.Sub(1), p.next)
+}
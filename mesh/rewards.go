@@ -0,0 +1,71 @@
+package mesh
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+
+	"github.com/spacemeshos/go-spacemesh/common/types"
+	"github.com/spacemeshos/go-spacemesh/sql"
+	"github.com/spacemeshos/go-spacemesh/sql/atxs"
+)
+
+// layerRewards converts the rewards included in the block for the layer to the rewards by coinbase.
+// The weights of all eligibilities rewarding the same coinbase are summed, so that the coinbase is
+// credited once per layer.
+func (e *Executor) layerRewards(lid types.LayerID, rewards []types.AnyReward) ([]types.CoinbaseReward, error) {
+	weights := make(map[types.Address]*big.Rat, len(rewards))
+	for _, r := range rewards {
+		coinbase, err := e.coinbase(lid, r.AtxID)
+		if err != nil {
+			return nil, err
+		}
+		weight, exist := weights[coinbase]
+		if !exist {
+			weight = new(big.Rat)
+			weights[coinbase] = weight
+		}
+		weight.Add(weight, r.Weight.ToBigRat())
+	}
+	res := make([]types.CoinbaseReward, 0, len(weights))
+	for coinbase, weight := range weights {
+		if !weight.Num().IsUint64() || !weight.Denom().IsUint64() {
+			return nil, fmt.Errorf("reward weight %v for %v overflows uint64", weight, coinbase)
+		}
+		res = append(res, types.CoinbaseReward{
+			Coinbase: coinbase,
+			Weight:   types.RatNumFromBigRat(weight),
+		})
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return bytes.Compare(res[i].Coinbase.Bytes(), res[j].Coinbase.Bytes()) < 0
+	})
+	return res, nil
+}
+
+// coinbase returns the address rewarded for the eligibility of the atx in the layer.
+// If the smesher published a different atx that is active in the epoch of the layer,
+// the coinbase of that atx is rewarded.
+func (e *Executor) coinbase(lid types.LayerID, id types.ATXID) (types.Address, error) {
+	atx, err := e.cdb.GetAtxHeader(id)
+	if err != nil {
+		return types.Address{}, fmt.Errorf("exec convert rewards: %w", err)
+	}
+	epoch := lid.GetEpoch()
+	if atx.TargetEpoch() == epoch || epoch == 0 {
+		return atx.Coinbase, nil
+	}
+	active, err := atxs.GetIDByEpochAndNodeID(e.cdb, epoch-1, atx.NodeID)
+	if errors.Is(err, sql.ErrNotFound) {
+		return atx.Coinbase, nil
+	} else if err != nil {
+		return types.Address{}, fmt.Errorf("exec get active atx: %w", err)
+	}
+	header, err := e.cdb.GetAtxHeader(active)
+	if err != nil {
+		return types.Address{}, fmt.Errorf("exec convert rewards: %w", err)
+	}
+	return header.Coinbase, nil
+}
@@ -0,0 +1,125 @@
+package mesh_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/spacemeshos/economics/rewards"
+	"github.com/stretchr/testify/require"
+
+	"github.com/spacemeshos/go-spacemesh/activation"
+	"github.com/spacemeshos/go-spacemesh/common/types"
+	"github.com/spacemeshos/go-spacemesh/events"
+	"github.com/spacemeshos/go-spacemesh/genvm/sdk/wallet"
+	"github.com/spacemeshos/go-spacemesh/signing"
+	"github.com/spacemeshos/go-spacemesh/sql"
+	"github.com/spacemeshos/go-spacemesh/sql/atxs"
+	srewards "github.com/spacemeshos/go-spacemesh/sql/rewards"
+	"github.com/spacemeshos/go-spacemesh/sql/transactions"
+)
+
+func createSmesherATX(tb testing.TB, db sql.Executor, sig *signing.EdSigner, prev types.ATXID, publish types.EpochID, cb types.Address) types.ATXID {
+	tb.Helper()
+	nonce := types.VRFPostIndex(1)
+	atx := types.NewActivationTx(types.NIPostChallenge{PublishEpoch: publish, PrevATXID: prev}, cb, nil, 11, &nonce)
+	atx.SetEffectiveNumUnits(atx.NumUnits)
+	atx.SetReceived(time.Now())
+	require.NoError(tb, activation.SignAndFinalizeAtx(sig, atx))
+	vatx, err := atx.Verify(0, 1)
+	require.NoError(tb, err)
+	require.NoError(tb, atxs.Add(db, vatx))
+	return vatx.ID()
+}
+
+func TestMesh_LayerRewards(t *testing.T) {
+	events.CloseEventReporter()
+	events.InitializeReporter()
+	t.Cleanup(events.CloseEventReporter)
+	sub := events.SubscribeRewards()
+
+	db := sql.InMemory()
+	n := newStateMesh(t, db)
+	first := types.GetEffectiveGenesis().Add(1)
+	epoch := first.GetEpoch()
+	require.GreaterOrEqual(t, epoch, types.EpochID(2))
+
+	smeshers := make([]*signing.EdSigner, 2)
+	for i := range smeshers {
+		sig, err := signing.NewEdSigner()
+		require.NoError(t, err)
+		smeshers[i] = sig
+	}
+	coinbase := wallet.Address(smeshers[0].PublicKey().Bytes())
+	active := createSmesherATX(t, db, smeshers[0], types.EmptyATXID, epoch-1, coinbase)
+	// the second smesher refers to the atx from the previous epoch, but switched to the shared coinbase
+	// in the atx active in the epoch of the layers
+	previous := types.Address{2}
+	stale := createSmesherATX(t, db, smeshers[1], types.EmptyATXID, epoch-2, previous)
+	createSmesherATX(t, db, smeshers[1], stale, epoch-1, coinbase)
+
+	spawn := types.Transaction{RawTx: types.NewRawTx(wallet.SelfSpawn(smeshers[0].PrivateKey(), 0))}
+	require.NoError(t, transactions.Add(db, &spawn, time.Now()))
+
+	blocks := []*types.Block{
+		types.NewExistingBlock(types.BlockID{}, types.InnerBlock{
+			LayerIndex: first,
+			Rewards: []types.AnyReward{
+				{AtxID: active, Weight: types.RatNum{Num: 1, Denom: 2}},
+				{AtxID: active, Weight: types.RatNum{Num: 1, Denom: 2}},
+			},
+		}),
+		types.NewExistingBlock(types.BlockID{}, types.InnerBlock{
+			LayerIndex: first.Add(1),
+			TxIDs:      []types.TransactionID{spawn.ID},
+			Rewards: []types.AnyReward{
+				{AtxID: active, Weight: types.RatNum{Num: 1, Denom: 3}},
+				{AtxID: stale, Weight: types.RatNum{Num: 1, Denom: 3}},
+				{AtxID: active, Weight: types.RatNum{Num: 1, Denom: 3}},
+			},
+		}),
+	}
+	var subsidy uint64
+	for _, block := range blocks {
+		block.Initialize()
+		n.executed = nil
+		n.process(t, block.LayerIndex, block)
+		var fees uint64
+		for _, tx := range n.executed {
+			fees += tx.Fee
+		}
+		layerReward := rewards.TotalSubsidyAtLayer(block.LayerIndex.Difference(types.FirstEffectiveGenesis()))
+		subsidy += layerReward
+
+		ev := <-sub.Out()
+		reward := ev.(events.Reward)
+		require.Equal(t, block.LayerIndex, reward.Layer)
+		require.Equal(t, coinbase, reward.Coinbase)
+		require.Equal(t, layerReward+fees, reward.Total)
+		require.Equal(t, layerReward, reward.LayerReward)
+		select {
+		case ev := <-sub.Out():
+			require.FailNow(t, "coinbase credited more than once", "%+v", ev)
+		default:
+		}
+
+		stored, err := srewards.GetLayer(db, block.LayerIndex)
+		require.NoError(t, err)
+		require.Equal(t, block.Rewards, stored)
+	}
+	require.Len(t, n.executed, 1)
+	require.NotZero(t, n.executed[0].Fee)
+
+	minted, err := srewards.List(db, coinbase)
+	require.NoError(t, err)
+	require.Len(t, minted, len(blocks))
+	for i, reward := range minted {
+		require.Equal(t, blocks[i].LayerIndex, reward.Layer)
+	}
+	require.Equal(t, minted[1].LayerReward+n.executed[0].Fee, minted[1].TotalReward)
+	balance, err := n.vm.GetBalance(coinbase)
+	require.NoError(t, err)
+	require.Equal(t, subsidy, balance)
+	balance, err = n.vm.GetBalance(previous)
+	require.NoError(t, err)
+	require.Zero(t, balance)
+}
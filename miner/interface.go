@@ -32,4 +32,5 @@ type layerClock interface {
 	AwaitLayer(layerID types.LayerID) <-chan struct{}
 	CurrentLayer() types.LayerID
 	LayerToTime(types.LayerID) time.Time
+	Now() time.Time
 }
@@ -17,3 +17,11 @@ var ProposalBuildDuration = metrics.NewHistogramWithBuckets(
 	[]string{},
 	[]float64{10, 100, 1000, 5 * 1000, 10 * 1000, 60 * 1000, 10 * 60 * 1000, 60 * 60 * 1000},
 )
+
+// ActiveSetATXs counts the ATXs of the target epoch that are included into or excluded from the active set.
+var ActiveSetATXs = metrics.NewGauge(
+	"active_set_atxs",
+	subsystem,
+	"number of atxs of the epoch graded into the active set",
+	[]string{"grade"},
+)
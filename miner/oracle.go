@@ -2,25 +2,30 @@ package miner
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"sort"
 	"sync"
+	"time"
 
 	"github.com/spacemeshos/go-spacemesh/common/types"
 	"github.com/spacemeshos/go-spacemesh/datastore"
 	"github.com/spacemeshos/go-spacemesh/events"
 	"github.com/spacemeshos/go-spacemesh/log"
+	"github.com/spacemeshos/go-spacemesh/miner/metrics"
 	"github.com/spacemeshos/go-spacemesh/proposals"
 	"github.com/spacemeshos/go-spacemesh/signing"
 	"github.com/spacemeshos/go-spacemesh/sql"
 	"github.com/spacemeshos/go-spacemesh/sql/atxs"
+	"github.com/spacemeshos/go-spacemesh/sql/eligibilities"
 )
 
 var (
 	errMinerHasNoATXInPreviousEpoch = errors.New("miner has no ATX in previous epoch")
 	errZeroEpochWeight              = errors.New("zero total weight for epoch")
 	errEmptyActiveSet               = errors.New("empty active set for epoch")
+	errAtxChanged                   = errors.New("miner atx changed within epoch")
 )
 
 type EpochEligibility struct {
@@ -37,8 +42,11 @@ type Oracle struct {
 	layersPerEpoch     uint32
 	minActiveSetWeight uint64
 	cdb                *datastore.CachedDB
+	clock              layerClock
+	// ATXs received later than the start of the epoch minus the grace period are excluded from the active set.
+	gracePeriod time.Duration
 
-	vrfSigner *signing.VRFSigner
+	vrfSigner signing.VRFSigner
 	nodeID    types.NodeID
 	log       log.Log
 
@@ -46,12 +54,23 @@ type Oracle struct {
 	cache *EpochEligibility
 }
 
-func newMinerOracle(layerSize, layersPerEpoch uint32, minActiveSetWeight uint64, cdb *datastore.CachedDB, vrfSigner *signing.VRFSigner, nodeID types.NodeID, log log.Log) *Oracle {
+func newMinerOracle(
+	layerSize, layersPerEpoch uint32,
+	minActiveSetWeight uint64,
+	cdb *datastore.CachedDB,
+	clock layerClock,
+	gracePeriod time.Duration,
+	vrfSigner signing.VRFSigner,
+	nodeID types.NodeID,
+	log log.Log,
+) *Oracle {
 	return &Oracle{
 		avgLayerSize:       layerSize,
 		layersPerEpoch:     layersPerEpoch,
 		minActiveSetWeight: minActiveSetWeight,
 		cdb:                cdb,
+		clock:              clock,
+		gracePeriod:        gracePeriod,
 		vrfSigner:          vrfSigner,
 		nodeID:             nodeID,
 		log:                log,
@@ -75,47 +94,153 @@ func (o *Oracle) GetProposalEligibility(lid types.LayerID, beacon types.Beacon,
 		log.Stringer("cached epoch", o.cache.Epoch),
 	)
 
-	var layerProofs []types.VotingEligibility
+	atxID, err := atxs.GetIDByEpochAndNodeID(o.cdb, epoch-1, o.nodeID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNotFound) {
+			return nil, errMinerHasNoATXInPreviousEpoch
+		}
+		return nil, fmt.Errorf("failed to get valid atx for node for target epoch %d: %w", epoch, err)
+	}
+
 	if o.cache.Epoch == epoch { // use the cached value
-		layerProofs = o.cache.Proofs[lid]
+		if o.cache.Atx != atxID {
+			return nil, fmt.Errorf("%w: eligibility computed for %s, found %s", errAtxChanged, o.cache.Atx, atxID)
+		}
 		o.log.With().Debug("got cached eligibility",
 			log.Stringer("requested epoch", epoch),
 			log.Stringer("cached epoch", o.cache.Epoch),
-			log.Int("num proposals", len(layerProofs)),
+			log.Int("num proposals", len(o.cache.Proofs[lid])),
 		)
 		return o.cache, nil
 	}
 
-	// calculate the proof
-	atx, err := o.getOwnEpochATX(epoch)
+	ee, err := o.loadEligibility(epoch, atxID, beacon)
 	if err != nil {
-		if errors.Is(err, sql.ErrNotFound) {
-			return nil, errMinerHasNoATXInPreviousEpoch
+		return nil, err
+	}
+	if ee == nil {
+		// calculate the proofs for the whole epoch
+		atx, err := o.cdb.GetAtxHeader(atxID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get valid atx for node for target epoch %d: %w", epoch, err)
 		}
-		return nil, fmt.Errorf("failed to get valid atx for node for target epoch %d: %w", epoch, err)
+		ee, err = o.calcEligibilityProofs(atx, epoch, beacon, nonce)
+		if err != nil {
+			return nil, err
+		}
+		o.persistEligibility(ee, beacon)
+	}
+	events.EmitEligibilities(ee.Epoch, beacon, ee.Atx, uint32(len(ee.ActiveSet)), ee.Proofs)
+	count := 0
+	for _, proofs := range ee.Proofs {
+		count += len(proofs)
 	}
+	events.ReportEligibilityComputed(events.EligibilityComputed{
+		Epoch:  ee.Epoch,
+		NodeID: o.nodeID,
+		Atx:    ee.Atx,
+		Count:  count,
+	})
+	o.cache = ee
+	return ee, nil
+}
 
-	ee, err := o.calcEligibilityProofs(atx, epoch, beacon, nonce)
+// loadEligibility returns the eligibility computed for the epoch before the node was restarted,
+// or nil if it wasn't computed with the same beacon.
+func (o *Oracle) loadEligibility(epoch types.EpochID, atxID types.ATXID, beacon types.Beacon) (*EpochEligibility, error) {
+	stored, err := eligibilities.Get(o.cdb, epoch, o.nodeID)
 	if err != nil {
+		if errors.Is(err, sql.ErrNotFound) {
+			return nil, nil
+		}
 		return nil, err
 	}
-	events.EmitEligibilities(ee.Epoch, beacon, ee.Atx, uint32(len(ee.ActiveSet)), ee.Proofs)
-	o.cache = ee
+	if stored.Atx != atxID {
+		return nil, fmt.Errorf("%w: eligibility computed for %s, found %s", errAtxChanged, stored.Atx, atxID)
+	}
+	if stored.Beacon != beacon {
+		o.log.With().Warning("eligibility was computed with a different beacon",
+			epoch,
+			log.Stringer("stored", stored.Beacon),
+			log.Stringer("beacon", beacon),
+		)
+		return nil, nil
+	}
+	ee := &EpochEligibility{
+		Epoch:     epoch,
+		Atx:       stored.Atx,
+		ActiveSet: stored.ActiveSet,
+		Proofs:    map[types.LayerID][]types.VotingEligibility{},
+		Slots:     stored.Slots,
+	}
+	for _, proof := range stored.Proofs {
+		lid := proposals.CalcEligibleLayer(epoch, o.layersPerEpoch, proof.Sig)
+		ee.Proofs[lid] = append(ee.Proofs[lid], proof)
+	}
+	o.log.With().Info("loaded proposal eligibility", epoch, ee.Atx, log.Uint32("total num slots", ee.Slots))
 	return ee, nil
 }
 
-func (o *Oracle) getOwnEpochATX(targetEpoch types.EpochID) (*types.ActivationTxHeader, error) {
-	publishEpoch := targetEpoch - 1
-	atxID, err := atxs.GetIDByEpochAndNodeID(o.cdb, publishEpoch, o.nodeID)
-	if err != nil {
-		return nil, fmt.Errorf("get ATX ID: %w", err)
+// persistEligibility stores the eligibility, so that it is not recomputed after restart.
+func (o *Oracle) persistEligibility(ee *EpochEligibility, beacon types.Beacon) {
+	stored := &eligibilities.Eligibility{
+		Atx:       ee.Atx,
+		Beacon:    beacon,
+		Slots:     ee.Slots,
+		ActiveSet: ee.ActiveSet,
+	}
+	for _, proofs := range ee.Proofs {
+		stored.Proofs = append(stored.Proofs, proofs...)
+	}
+	sort.Slice(stored.Proofs, func(i, j int) bool {
+		return stored.Proofs[i].J < stored.Proofs[j].J
+	})
+	if err := o.cdb.WithTx(context.Background(), func(tx *sql.Tx) error {
+		if err := eligibilities.Set(tx, ee.Epoch, o.nodeID, stored); err != nil {
+			return err
+		}
+		return eligibilities.DeleteBefore(tx, ee.Epoch-1)
+	}); err != nil {
+		o.log.With().Error("failed to persist proposal eligibility", ee.Epoch, log.Err(err))
 	}
+}
 
-	atx, err := o.cdb.GetAtxHeader(atxID)
+// gradedActiveSet returns the ATXs targeting the epoch that were received before the cutoff and their total weight.
+// The cutoff is the start of the epoch minus the grace period, so that ATXs that arrived too late to be known
+// by the majority of the nodes are not included into the active set.
+func (o *Oracle) gradedActiveSet(epoch types.EpochID) (uint64, []types.ATXID, error) {
+	cutoff := o.clock.LayerToTime(epoch.FirstLayer()).Add(-o.gracePeriod)
+	all, err := atxs.GetIDsByEpoch(o.cdb, epoch-1)
+	if err != nil {
+		return 0, nil, err
+	}
+	graded, err := atxs.GetIDsByEpochReceivedBefore(o.cdb, epoch-1, cutoff)
 	if err != nil {
-		return nil, fmt.Errorf("get ATX header: %w", err)
+		return 0, nil, err
+	}
+	excluded := len(all) - len(graded)
+	metrics.ActiveSetATXs.WithLabelValues("included").Set(float64(len(graded)))
+	metrics.ActiveSetATXs.WithLabelValues("excluded").Set(float64(excluded))
+	o.log.With().Info("graded active set",
+		epoch,
+		log.Time("cutoff", cutoff),
+		log.Int("included", len(graded)),
+		log.Int("excluded", excluded),
+	)
+	if len(graded) == 0 && len(all) > 0 {
+		// the node was not synced before the epoch started and received all ATXs late
+		o.log.With().Warning("no atxs received before the cutoff, using all atxs of the epoch", epoch, log.Time("cutoff", cutoff))
+		graded = all
+	}
+	var weight uint64
+	for _, id := range graded {
+		header, err := o.cdb.GetAtxHeader(id)
+		if err != nil {
+			return 0, nil, err
+		}
+		weight += header.GetWeight()
 	}
-	return atx, nil
+	return weight, graded, nil
 }
 
 // calcEligibilityProofs calculates the eligibility proofs of proposals for the miner in the given epoch
@@ -123,8 +248,7 @@ func (o *Oracle) getOwnEpochATX(targetEpoch types.EpochID) (*types.ActivationTxH
 func (o *Oracle) calcEligibilityProofs(atx *types.ActivationTxHeader, epoch types.EpochID, beacon types.Beacon, nonce types.VRFPostIndex) (*EpochEligibility, error) {
 	weight := atx.GetWeight()
 
-	// get the previous epoch's total weight
-	totalWeight, activeSet, err := o.cdb.GetEpochWeight(epoch)
+	totalWeight, activeSet, err := o.gradedActiveSet(epoch)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get epoch %v weight: %w", epoch, err)
 	}
@@ -153,7 +277,10 @@ func (o *Oracle) calcEligibilityProofs(atx *types.ActivationTxHeader, epoch type
 		if err != nil {
 			o.log.With().Fatal("failed to serialize VRF msg", log.Err(err))
 		}
-		vrfSig := o.vrfSigner.Sign(message)
+		vrfSig, err := o.vrfSigner.Sign(message)
+		if err != nil {
+			return nil, fmt.Errorf("oracle sign vrf message: %w", err)
+		}
 		eligibleLayer := proposals.CalcEligibleLayer(epoch, o.layersPerEpoch, vrfSig)
 		eligibilityProofs[eligibleLayer] = append(eligibilityProofs[eligibleLayer], types.VotingEligibility{
 			J:   counter,
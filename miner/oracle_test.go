@@ -1,7 +1,9 @@
 package miner
 
 import (
+	"bytes"
 	"context"
+	"sort"
 	"testing"
 	"time"
 
@@ -11,12 +13,14 @@ import (
 	"github.com/spacemeshos/go-spacemesh/activation"
 	"github.com/spacemeshos/go-spacemesh/common/types"
 	"github.com/spacemeshos/go-spacemesh/datastore"
+	"github.com/spacemeshos/go-spacemesh/events"
 	"github.com/spacemeshos/go-spacemesh/log/logtest"
 	"github.com/spacemeshos/go-spacemesh/proposals"
 	"github.com/spacemeshos/go-spacemesh/rand"
 	"github.com/spacemeshos/go-spacemesh/signing"
 	"github.com/spacemeshos/go-spacemesh/sql"
 	"github.com/spacemeshos/go-spacemesh/sql/atxs"
+	"github.com/spacemeshos/go-spacemesh/sql/eligibilities"
 	"github.com/spacemeshos/go-spacemesh/system/mocks"
 )
 
@@ -29,10 +33,14 @@ type testOracle struct {
 	*Oracle
 	nodeID    types.NodeID
 	edSigner  *signing.EdSigner
-	vrfSigner *signing.VRFSigner
+	vrfSigner signing.VRFSigner
+	mClock    *MocklayerClock
+
+	// epochStart is returned by the clock as the start of any epoch.
+	epochStart time.Time
 }
 
-func generateNodeIDAndSigner(tb testing.TB) (types.NodeID, *signing.EdSigner, *signing.VRFSigner) {
+func generateNodeIDAndSigner(tb testing.TB) (types.NodeID, *signing.EdSigner, signing.VRFSigner) {
 	tb.Helper()
 
 	edSigner, err := signing.NewEdSigner()
@@ -46,6 +54,17 @@ func generateNodeIDAndSigner(tb testing.TB) (types.NodeID, *signing.EdSigner, *s
 }
 
 func genMinerATX(tb testing.TB, cdb *datastore.CachedDB, id types.ATXID, publishLayer types.LayerID, signer *signing.EdSigner) *types.VerifiedActivationTx {
+	return genMinerATXReceived(tb, cdb, id, publishLayer, signer, time.Now())
+}
+
+func genMinerATXReceived(
+	tb testing.TB,
+	cdb *datastore.CachedDB,
+	id types.ATXID,
+	publishLayer types.LayerID,
+	signer *signing.EdSigner,
+	received time.Time,
+) *types.VerifiedActivationTx {
 	atx := &types.ActivationTx{InnerActivationTx: types.InnerActivationTx{
 		NIPostChallenge: types.NIPostChallenge{
 			PublishEpoch: publishLayer.GetEpoch(),
@@ -54,7 +73,7 @@ func genMinerATX(tb testing.TB, cdb *datastore.CachedDB, id types.ATXID, publish
 	}}
 	atx.SetID(id)
 	atx.SetEffectiveNumUnits(atx.NumUnits)
-	atx.SetReceived(time.Now())
+	atx.SetReceived(received)
 	activation.SignAndFinalizeAtx(signer, atx)
 	vAtx, err := atx.Verify(0, 1)
 	require.NoError(tb, err)
@@ -90,18 +109,29 @@ func genBallotWithEligibility(
 }
 
 func createTestOracle(tb testing.TB, layerSize, layersPerEpoch uint32, minActiveSetWeight uint64) *testOracle {
+	return createGradingTestOracle(tb, layerSize, layersPerEpoch, minActiveSetWeight, 0)
+}
+
+func createGradingTestOracle(tb testing.TB, layerSize, layersPerEpoch uint32, minActiveSetWeight uint64, gracePeriod time.Duration) *testOracle {
 	types.SetLayersPerEpoch(layersPerEpoch)
 
 	lg := logtest.New(tb)
 	cdb := datastore.NewCachedDB(sql.InMemory(), lg)
 	nodeID, edSigner, vrfSigner := generateNodeIDAndSigner(tb)
-
-	return &testOracle{
-		Oracle:    newMinerOracle(layerSize, layersPerEpoch, minActiveSetWeight, cdb, vrfSigner, nodeID, lg),
-		nodeID:    nodeID,
-		edSigner:  edSigner,
-		vrfSigner: vrfSigner,
+	mClock := NewMocklayerClock(gomock.NewController(tb))
+
+	to := &testOracle{
+		Oracle:     newMinerOracle(layerSize, layersPerEpoch, minActiveSetWeight, cdb, mClock, gracePeriod, vrfSigner, nodeID, lg),
+		nodeID:     nodeID,
+		edSigner:   edSigner,
+		vrfSigner:  vrfSigner,
+		mClock:     mClock,
+		epochStart: time.Now().Add(time.Hour),
 	}
+	mClock.EXPECT().LayerToTime(gomock.Any()).DoAndReturn(func(types.LayerID) time.Time {
+		return to.epochStart
+	}).AnyTimes()
+	return to
 }
 
 type epochATXInfo struct {
@@ -202,6 +232,40 @@ func TestOracle_OwnATXNotFound(t *testing.T) {
 	require.Nil(t, ee)
 }
 
+func TestOracle_EligibilityComputedEvent(t *testing.T) {
+	events.InitializeReporter()
+	t.Cleanup(events.CloseEventReporter)
+	sub, err := events.Subscribe[events.BuilderEvent]()
+	require.NoError(t, err)
+
+	avgLayerSize := uint32(10)
+	layersPerEpoch := uint32(20)
+	o := createTestOracle(t, avgLayerSize, layersPerEpoch, 0)
+	lid := types.LayerID(layersPerEpoch * 3)
+	epochInfo := genATXForTargetEpochs(t, o.cdb, lid.GetEpoch(), lid.GetEpoch()+1, o.edSigner, layersPerEpoch)
+	ee, err := o.GetProposalEligibility(lid, epochInfo[lid.GetEpoch()].beacon, types.VRFPostIndex(1))
+	require.NoError(t, err)
+	count := 0
+	for _, proofs := range ee.Proofs {
+		count += len(proofs)
+	}
+	select {
+	case ev := <-sub.Out():
+		require.Equal(t, &events.EligibilityComputed{
+			Epoch:  lid.GetEpoch(),
+			NodeID: o.nodeID,
+			Atx:    ee.Atx,
+			Count:  count,
+		}, ev.EligibilityComputed)
+	case <-time.After(time.Second):
+		require.FailNow(t, "eligibility wasn't reported")
+	}
+	// cached eligibility is not reported again
+	_, err = o.GetProposalEligibility(lid.Add(1), epochInfo[lid.GetEpoch()].beacon, types.VRFPostIndex(1))
+	require.NoError(t, err)
+	require.Empty(t, sub.Out())
+}
+
 func TestOracle_EligibilityCached(t *testing.T) {
 	avgLayerSize := uint32(10)
 	layersPerEpoch := uint32(20)
@@ -237,9 +301,99 @@ func TestOracle_MinimalActiveSetWeight(t *testing.T) {
 
 	o.minActiveSetWeight = 100000
 	o.cache.Epoch = 0
+	// drop the persisted eligibility so that it is recomputed
+	require.NoError(t, eligibilities.DeleteBefore(o.cdb, lid.GetEpoch()+1))
 	ee2, err := o.GetProposalEligibility(lid, info.beacon, types.VRFPostIndex(1))
 	require.NoError(t, err)
 	require.NotNil(t, ee1)
 
 	require.Less(t, ee2.Slots, ee1.Slots)
 }
+
+func TestOracle_GradedActiveSet(t *testing.T) {
+	avgLayerSize := uint32(10)
+	layersPerEpoch := uint32(20)
+	gracePeriod := 10 * time.Second
+	o := createGradingTestOracle(t, avgLayerSize, layersPerEpoch, 0, gracePeriod)
+	lid := types.LayerID(layersPerEpoch * 3)
+	publishLayer := lid.GetEpoch().FirstLayer().Sub(layersPerEpoch)
+	cutoff := o.epochStart.Add(-gracePeriod)
+
+	own := genMinerATXReceived(t, o.cdb, types.RandomATXID(), publishLayer, o.edSigner, cutoff.Add(-time.Minute))
+	signer, err := signing.NewEdSigner()
+	require.NoError(t, err)
+	before := genMinerATXReceived(t, o.cdb, types.RandomATXID(), publishLayer, signer, cutoff.Add(-time.Second))
+	signer, err = signing.NewEdSigner()
+	require.NoError(t, err)
+	late := genMinerATXReceived(t, o.cdb, types.RandomATXID(), publishLayer, signer, cutoff.Add(time.Second))
+
+	ee, err := o.GetProposalEligibility(lid, types.RandomBeacon(), types.VRFPostIndex(1))
+	require.NoError(t, err)
+	expected := types.ATXIDList{own.ID(), before.ID()}
+	sort.Slice(expected, func(i, j int) bool {
+		return bytes.Compare(expected[i].Bytes(), expected[j].Bytes()) < 0
+	})
+	require.Equal(t, expected, ee.ActiveSet)
+	require.NotContains(t, ee.ActiveSet, late.ID())
+}
+
+func TestOracle_GradedActiveSetAllLate(t *testing.T) {
+	avgLayerSize := uint32(10)
+	layersPerEpoch := uint32(20)
+	o := createTestOracle(t, avgLayerSize, layersPerEpoch, 0)
+	lid := types.LayerID(layersPerEpoch * 3)
+	epochInfo := genATXForTargetEpochs(t, o.cdb, lid.GetEpoch(), lid.GetEpoch()+1, o.edSigner, layersPerEpoch)
+	// the node received all atxs after the cutoff, as it wasn't synced
+	o.epochStart = time.Now().Add(-time.Hour)
+
+	ee, err := o.GetProposalEligibility(lid, epochInfo[lid.GetEpoch()].beacon, types.VRFPostIndex(1))
+	require.NoError(t, err)
+	require.ElementsMatch(t, epochInfo[lid.GetEpoch()].activeSet, ee.ActiveSet)
+}
+
+func TestOracle_EligibilityPersisted(t *testing.T) {
+	avgLayerSize := uint32(10)
+	layersPerEpoch := uint32(20)
+	o := createTestOracle(t, avgLayerSize, layersPerEpoch, 0)
+	lid := types.LayerID(layersPerEpoch * 3)
+	epochInfo := genATXForTargetEpochs(t, o.cdb, lid.GetEpoch(), lid.GetEpoch()+1, o.edSigner, layersPerEpoch)
+	beacon := epochInfo[lid.GetEpoch()].beacon
+	ee, err := o.GetProposalEligibility(lid, beacon, types.VRFPostIndex(1))
+	require.NoError(t, err)
+
+	// after restart the eligibility is loaded instead of being computed with a different minimal weight
+	restarted := newMinerOracle(avgLayerSize, layersPerEpoch, 100000, o.cdb, o.mClock, 0, o.vrfSigner, o.nodeID, o.log)
+	loaded, err := restarted.GetProposalEligibility(lid.Add(1), beacon, types.VRFPostIndex(1))
+	require.NoError(t, err)
+	require.Equal(t, ee, loaded)
+
+	// eligibility computed with a different beacon is not used
+	restarted = newMinerOracle(avgLayerSize, layersPerEpoch, 100000, o.cdb, o.mClock, 0, o.vrfSigner, o.nodeID, o.log)
+	other, err := restarted.GetProposalEligibility(lid, types.RandomBeacon(), types.VRFPostIndex(1))
+	require.NoError(t, err)
+	require.Less(t, other.Slots, ee.Slots)
+}
+
+func TestOracle_AtxChanged(t *testing.T) {
+	avgLayerSize := uint32(10)
+	layersPerEpoch := uint32(20)
+	o := createTestOracle(t, avgLayerSize, layersPerEpoch, 0)
+	lid := types.LayerID(layersPerEpoch * 3)
+	epochInfo := genATXForTargetEpochs(t, o.cdb, lid.GetEpoch(), lid.GetEpoch()+1, o.edSigner, layersPerEpoch)
+	beacon := epochInfo[lid.GetEpoch()].beacon
+	_, err := o.GetProposalEligibility(lid, beacon, types.VRFPostIndex(1))
+	require.NoError(t, err)
+
+	// simulate a different atx of the miner for the same epoch
+	stored, err := eligibilities.Get(o.cdb, lid.GetEpoch(), o.nodeID)
+	require.NoError(t, err)
+	o.cache.Atx = types.RandomATXID()
+	_, err = o.GetProposalEligibility(lid.Add(1), beacon, types.VRFPostIndex(1))
+	require.ErrorIs(t, err, errAtxChanged)
+
+	stored.Atx = types.RandomATXID()
+	require.NoError(t, eligibilities.Set(o.cdb, lid.GetEpoch(), o.nodeID, stored))
+	restarted := newMinerOracle(avgLayerSize, layersPerEpoch, 0, o.cdb, o.mClock, 0, o.vrfSigner, o.nodeID, o.log)
+	_, err = restarted.GetProposalEligibility(lid, beacon, types.VRFPostIndex(1))
+	require.ErrorIs(t, err, errAtxChanged)
+}
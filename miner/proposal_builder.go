@@ -2,9 +2,12 @@
 package miner
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"fmt"
+	"io"
+	"sort"
 	"sync"
 	"time"
 
@@ -17,17 +20,26 @@ import (
 	"github.com/spacemeshos/go-spacemesh/log"
 	"github.com/spacemeshos/go-spacemesh/miner/metrics"
 	"github.com/spacemeshos/go-spacemesh/p2p/pubsub"
+	"github.com/spacemeshos/go-spacemesh/proposals"
 	"github.com/spacemeshos/go-spacemesh/signing"
 	"github.com/spacemeshos/go-spacemesh/sql"
 	"github.com/spacemeshos/go-spacemesh/sql/ballots"
 	"github.com/spacemeshos/go-spacemesh/sql/certificates"
+	"github.com/spacemeshos/go-spacemesh/sql/eligibilities"
 	"github.com/spacemeshos/go-spacemesh/sql/layers"
+	"github.com/spacemeshos/go-spacemesh/sql/pendingproposals"
+	"github.com/spacemeshos/go-spacemesh/sql/refballots"
 	"github.com/spacemeshos/go-spacemesh/system"
 	"github.com/spacemeshos/go-spacemesh/tortoise"
 )
 
 const (
 	buildDurationErrorThreshold = 10 * time.Second
+
+	// publishRetryInterval is the initial interval between attempts to publish a proposal.
+	publishRetryInterval = 500 * time.Millisecond
+	// publishMaxRetryInterval caps the backoff between attempts to publish a proposal.
+	publishMaxRetryInterval = 10 * time.Second
 )
 
 var (
@@ -35,6 +47,12 @@ var (
 	errNotSynced      = errors.New("not building proposals: node not synced")
 	errNoBeacon       = errors.New("not building proposals: missing beacon")
 	errDuplicateLayer = errors.New("not building proposals: duplicate layer event")
+	errBallotTooLarge = errors.New("not building proposals: ballot exceeds proposal size budget")
+	errNoOpinion      = errors.New("not building proposals: tortoise has no opinion")
+	errLate           = errors.New("not building proposals: past the build deadline")
+	errNotEligible    = errors.New("not building proposals: not eligible in the layer")
+	errNoVRFNonce     = errors.New("not building proposals: no vrf nonce")
+	errUnknownSigner  = errors.New("not building proposals: identity is not operated by the node")
 )
 
 // ProposalBuilder builds Proposals for a miner.
@@ -50,13 +68,28 @@ type ProposalBuilder struct {
 
 	clock          layerClock
 	publisher      pubsub.Publisher
-	signer         *signing.EdSigner
+	signer         signing.Signer
 	nonceFetcher   nonceFetcher
 	conState       conservativeState
 	tortoise       votesEncoder
 	proposalOracle proposalOracle
 	beaconProvider system.BeaconGetter
 	syncer         system.SyncStateProvider
+
+	// signers are additional identities operated by the node.
+	signers []signing.Signer
+	// sessions build proposals for every identity, starting with the primary one.
+	sessions []*session
+}
+
+// session builds proposals on behalf of a single identity.
+type session struct {
+	signer signing.Signer
+	oracle proposalOracle
+
+	// mu serializes building of the proposals of the identity, so that only the first one
+	// in the epoch carries the reference ballot.
+	mu sync.Mutex
 }
 
 // config defines configuration for the ProposalBuilder.
@@ -66,6 +99,25 @@ type config struct {
 	hdist              uint32
 	minActiveSetWeight uint64
 	nodeID             types.NodeID
+	// proposals are signed in the dedicated domain starting from this layer.
+	signatureDomainLayer types.LayerID
+
+	publishRetryInterval    time.Duration
+	publishMaxRetryInterval time.Duration
+
+	// ATXs received later than the start of the epoch minus the grace period are excluded from the active set.
+	activeSetGracePeriod time.Duration
+
+	// maxProposalBytes is the size budget of the encoded proposal, shared between the ballot
+	// and transactions. Zero means unlimited.
+	maxProposalBytes int
+
+	// buildDeadline is the fraction of the layer duration after which proposals are not built
+	// for the layer. Zero disables the deadline.
+	buildDeadline float64
+	// buildLead is how long before the start of the layer proposals are built.
+	// Proposals built in advance are published at the start of the layer.
+	buildLead time.Duration
 }
 
 type defaultFetcher struct {
@@ -123,12 +175,65 @@ func WithHdist(dist uint32) Opt {
 	}
 }
 
+// WithSignatureDomainLayer defines the layer starting from which proposals are signed in the PROPOSAL domain.
+func WithSignatureDomainLayer(lid types.LayerID) Opt {
+	return func(pb *ProposalBuilder) {
+		pb.cfg.signatureDomainLayer = lid
+	}
+}
+
+// WithActiveSetGracePeriod defines how long before the start of the epoch the ATXs must be received
+// to be included into the active set of the reference ballot.
+func WithActiveSetGracePeriod(period time.Duration) Opt {
+	return func(pb *ProposalBuilder) {
+		pb.cfg.activeSetGracePeriod = period
+	}
+}
+
+// WithMaxProposalBytes defines the size budget of the encoded proposal.
+// Ballot is encoded first and the rest of the budget is filled with transactions.
+func WithMaxProposalBytes(size int) Opt {
+	return func(pb *ProposalBuilder) {
+		pb.cfg.maxProposalBytes = size
+	}
+}
+
+// WithBuildDeadline defines the fraction of the layer duration after which the builder doesn't build proposals
+// for the layer, as they would be rejected by the network as late.
+func WithBuildDeadline(fraction float64) Opt {
+	return func(pb *ProposalBuilder) {
+		pb.cfg.buildDeadline = fraction
+	}
+}
+
+// WithBuildLead defines how long before the start of the layer proposals are built.
+func WithBuildLead(lead time.Duration) Opt {
+	return func(pb *ProposalBuilder) {
+		pb.cfg.buildLead = lead
+	}
+}
+
+// WithSigners registers additional identities that build proposals with the same node.
+// Each identity has its own eligibility and reference ballots.
+func WithSigners(signers ...signing.Signer) Opt {
+	return func(pb *ProposalBuilder) {
+		pb.signers = append(pb.signers, signers...)
+	}
+}
+
 func withOracle(o proposalOracle) Opt {
 	return func(pb *ProposalBuilder) {
 		pb.proposalOracle = o
 	}
 }
 
+func withPublishRetry(interval, max time.Duration) Opt {
+	return func(pb *ProposalBuilder) {
+		pb.cfg.publishRetryInterval = interval
+		pb.cfg.publishMaxRetryInterval = max
+	}
+}
+
 func withNonceFetcher(nf nonceFetcher) Opt {
 	return func(pb *ProposalBuilder) {
 		pb.nonceFetcher = nf
@@ -136,11 +241,12 @@ func withNonceFetcher(nf nonceFetcher) Opt {
 }
 
 // NewProposalBuilder creates a struct of block builder type.
+// The publisher must not retain published messages, as proposals are encoded into pooled buffers.
 func NewProposalBuilder(
 	ctx context.Context,
 	clock layerClock,
-	signer *signing.EdSigner,
-	vrfSigner *signing.VRFSigner,
+	signer signing.Signer,
+	vrfSigner signing.VRFSigner,
 	cdb *datastore.CachedDB,
 	publisher pubsub.Publisher,
 	trtl votesEncoder,
@@ -162,14 +268,34 @@ func NewProposalBuilder(
 		beaconProvider: beaconProvider,
 		syncer:         syncer,
 		conState:       conState,
+		cfg: config{
+			publishRetryInterval:    publishRetryInterval,
+			publishMaxRetryInterval: publishMaxRetryInterval,
+		},
 	}
 
 	for _, opt := range opts {
 		opt(pb)
 	}
 
+	// oracle configured for tests is shared by all identities
+	shared := pb.proposalOracle
 	if pb.proposalOracle == nil {
-		pb.proposalOracle = newMinerOracle(pb.cfg.layerSize, pb.cfg.layersPerEpoch, pb.cfg.minActiveSetWeight, cdb, vrfSigner, pb.cfg.nodeID, pb.logger)
+		pb.proposalOracle = newMinerOracle(pb.cfg.layerSize, pb.cfg.layersPerEpoch, pb.cfg.minActiveSetWeight,
+			cdb, clock, pb.cfg.activeSetGracePeriod, vrfSigner, pb.cfg.nodeID, pb.logger)
+	}
+	pb.sessions = append(pb.sessions, &session{signer: signer, oracle: pb.proposalOracle})
+	for _, s := range pb.signers {
+		oracle := shared
+		if oracle == nil {
+			vrf, err := s.VRFSigner()
+			if err != nil {
+				pb.logger.With().Fatal("failed to get vrf signer", s.NodeID(), log.Err(err))
+			}
+			oracle = newMinerOracle(pb.cfg.layerSize, pb.cfg.layersPerEpoch, pb.cfg.minActiveSetWeight,
+				cdb, clock, pb.cfg.activeSetGracePeriod, vrf, s.NodeID(), pb.logger)
+		}
+		pb.sessions = append(pb.sessions, &session{signer: s, oracle: oracle})
 	}
 
 	if pb.nonceFetcher == nil {
@@ -182,6 +308,7 @@ func NewProposalBuilder(
 // Start starts the loop that listens to layers and build proposals.
 func (pb *ProposalBuilder) Start(ctx context.Context) error {
 	pb.startOnce.Do(func() {
+		pb.republishPending(ctx)
 		pb.eg.Go(func() error {
 			pb.createProposalLoop(log.WithNewSessionID(ctx))
 			return nil
@@ -206,8 +333,155 @@ func (pb *ProposalBuilder) stopped() bool {
 	}
 }
 
+// ScheduledProposal is a layer where an identity of the node is eligible to build proposals.
+type ScheduledProposal struct {
+	NodeID types.NodeID
+	Layer  types.LayerID
+	Time   time.Time
+	// Count is the number of proposals the identity is eligible for in the layer.
+	Count int
+}
+
+// Schedule returns the layers of the current epoch, starting from the current one, where the identities
+// of the node are eligible to build proposals. Eligibilities are computed once the epoch beacon is known,
+// before that the schedule is empty.
+func (pb *ProposalBuilder) Schedule() ([]ScheduledProposal, error) {
+	current := pb.clock.CurrentLayer()
+	epoch := current.GetEpoch()
+	var rst []ScheduledProposal
+	for _, s := range pb.sessions {
+		e, err := eligibilities.Get(pb.cdb, epoch, s.signer.NodeID())
+		if errors.Is(err, sql.ErrNotFound) {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		counts := map[types.LayerID]int{}
+		for _, proof := range e.Proofs {
+			if lid := proposals.CalcEligibleLayer(epoch, pb.cfg.layersPerEpoch, proof.Sig); !lid.Before(current) {
+				counts[lid]++
+			}
+		}
+		for lid, n := range counts {
+			rst = append(rst, ScheduledProposal{
+				NodeID: s.signer.NodeID(),
+				Layer:  lid,
+				Time:   pb.clock.LayerToTime(lid),
+				Count:  n,
+			})
+		}
+	}
+	sort.Slice(rst, func(i, j int) bool {
+		if rst[i].Layer != rst[j].Layer {
+			return rst[i].Layer.Before(rst[j].Layer)
+		}
+		return bytes.Compare(rst[i].NodeID.Bytes(), rst[j].NodeID.Bytes()) < 0
+	})
+	return rst, nil
+}
+
+// Identities returns the signers of the identities the node builds proposals for, starting with the primary one.
+func (pb *ProposalBuilder) Identities() []signing.Signer {
+	rst := make([]signing.Signer, 0, len(pb.sessions))
+	for _, s := range pb.sessions {
+		rst = append(rst, s.signer)
+	}
+	return rst
+}
+
+// BuildDryRun builds the proposal the identity would publish in the layer with the current state of the node,
+// including eligibility check, votes encoding and transactions selection. Transactions are selected as if the
+// identity was the only one operated by the node.
+//
+// The proposal is neither published nor persisted, and doesn't change the reference ballot of the identity.
+// It is not signed, signatures are left empty so that the proposal is rejected by every node if it leaks.
+func (pb *ProposalBuilder) BuildDryRun(ctx context.Context, layerID types.LayerID, signer signing.Signer) (*types.Proposal, error) {
+	if layerID <= types.GetEffectiveGenesis() {
+		return nil, errGenesis
+	}
+	var s *session
+	for _, candidate := range pb.sessions {
+		if candidate.signer.NodeID() == signer.NodeID() {
+			s = candidate
+			break
+		}
+	}
+	if s == nil {
+		return nil, fmt.Errorf("%w: %s", errUnknownSigner, signer.NodeID().ShortString())
+	}
+	if !pb.syncer.IsSynced(ctx) {
+		return nil, errNotSynced
+	}
+	beacon, err := pb.beaconProvider.GetBeacon(layerID.GetEpoch())
+	if err != nil {
+		return nil, errNoBeacon
+	}
+	e, err := pb.checkEligibility(ctx, s, layerID, beacon)
+	if err != nil {
+		return nil, err
+	}
+	pb.tortoise.TallyVotes(ctx, layerID)
+	opinion, err := pb.tortoise.EncodeVotes(ctx, tortoise.EncodeVotesWithCurrent(layerID))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", errNoOpinion, err)
+	}
+	txs := pb.conState.SelectProposalTXs(layerID, len(e.proofs))
+	// the reference ballot must not change while the proposal is assembled
+	s.mu.Lock()
+	p, err := pb.assembleProposal(ctx, s.signer.NodeID(), layerID, e.epoch, beacon, txs, *opinion)
+	s.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	p.SmesherID = s.signer.NodeID()
+	if err := p.Initialize(); err != nil {
+		return nil, fmt.Errorf("initialize proposal: %w", err)
+	}
+	return p, nil
+}
+
 func (pb *ProposalBuilder) createProposal(
 	ctx context.Context,
+	signer signing.Signer,
+	layerID types.LayerID,
+	epochEligibility *EpochEligibility,
+	beacon types.Beacon,
+	txIDs []types.TransactionID,
+	opinion types.Opinion,
+) (*types.Proposal, error) {
+	p, err := pb.assembleProposal(ctx, signer.NodeID(), layerID, epochEligibility, beacon, txIDs, opinion)
+	if err != nil {
+		return nil, err
+	}
+	if p.Ballot.Signature, err = signer.SignContext(ctx, signing.BALLOT, p.Ballot.SignedBytes()); err != nil {
+		return nil, fmt.Errorf("sign ballot: %w", err)
+	}
+	p.SmesherID = signer.NodeID()
+	p.Signature, err = signer.SignContext(ctx, signing.ProposalDomain(pb.cfg.signatureDomainLayer, layerID), p.SignedBytes())
+	if err != nil {
+		return nil, fmt.Errorf("sign proposal: %w", err)
+	}
+	if err := p.Initialize(); err != nil {
+		pb.logger.With().Fatal("proposal failed to initialize",
+			log.Context(ctx),
+			layerID,
+			log.Err(err),
+		)
+	}
+	pb.logger.Event().Info("proposal created",
+		log.Context(ctx),
+		layerID,
+		p.ID(),
+		p.SmesherID,
+		log.Int("num txs", len(p.TxIDs)),
+	)
+	return p, nil
+}
+
+// assembleProposal assembles the unsigned proposal of the identity in the layer.
+func (pb *ProposalBuilder) assembleProposal(
+	ctx context.Context,
+	nodeID types.NodeID,
 	layerID types.LayerID,
 	epochEligibility *EpochEligibility,
 	beacon types.Beacon,
@@ -222,21 +496,19 @@ func (pb *ProposalBuilder) createProposal(
 	}
 
 	ib := &types.InnerBallot{
-		Layer:       layerID,
-		AtxID:       epochEligibility.Atx,
-		OpinionHash: opinion.Hash,
+		Layer: layerID,
+		AtxID: epochEligibility.Atx,
 	}
 
-	epoch := layerID.GetEpoch()
-	refBallot, err := ballots.GetRefBallot(pb.cdb, epoch, pb.signer.NodeID())
+	refBallot, err := pb.refBallot(ctx, nodeID, layerID.GetEpoch())
 	if err != nil {
-		if !errors.Is(err, sql.ErrNotFound) {
-			return nil, fmt.Errorf("get ref ballot: %w", err)
-		}
-
+		return nil, err
+	}
+	if refBallot == types.EmptyBallotID {
 		pb.logger.With().Debug("creating ballot with active set (reference ballot in epoch)",
 			log.Context(ctx),
 			layerID,
+			nodeID,
 			log.Int("active_set_size", len(epochEligibility.ActiveSet)),
 		)
 		ib.RefBallot = types.EmptyBallotID
@@ -249,6 +521,7 @@ func (pb *ProposalBuilder) createProposal(
 		pb.logger.With().Debug("creating ballot with reference ballot (no active set)",
 			log.Context(ctx),
 			layerID,
+			nodeID,
 			log.Named("ref_ballot", refBallot),
 		)
 		ib.RefBallot = refBallot
@@ -258,35 +531,146 @@ func (pb *ProposalBuilder) createProposal(
 		InnerProposal: types.InnerProposal{
 			Ballot: types.Ballot{
 				InnerBallot:       *ib,
-				Votes:             opinion.Votes,
 				EligibilityProofs: epochEligibility.Proofs[layerID],
 			},
-			TxIDs:    txIDs,
 			MeshHash: pb.decideMeshHash(ctx, layerID),
 		},
 	}
+	setOpinion(&p.Ballot, &opinion)
 	if p.EpochData != nil {
 		p.ActiveSet = epochEligibility.ActiveSet
 	}
-	p.Ballot.Signature = pb.signer.Sign(signing.BALLOT, p.Ballot.SignedBytes())
-	p.SmesherID = pb.signer.NodeID()
-	p.Signature = pb.signer.Sign(signing.BALLOT, p.SignedBytes())
-	if err := p.Initialize(); err != nil {
-		pb.logger.With().Fatal("proposal failed to initialize",
-			log.Context(ctx),
-			layerID,
-			log.Err(err),
-		)
+	if err := pb.fitBudget(ctx, nodeID, p, txIDs); err != nil {
+		return nil, err
 	}
-	pb.logger.Event().Info("proposal created",
-		log.Context(ctx),
-		layerID,
-		p.ID(),
-		log.Int("num txs", len(p.TxIDs)),
-	)
 	return p, nil
 }
 
+// fitBudget fills the proposal with transactions, in the order they were selected, within the size budget.
+// If the ballot alone exceeds the budget, exceptions are replaced with abstain votes first,
+// and then eligibility proofs are dropped until the ballot fits.
+func (pb *ProposalBuilder) fitBudget(ctx context.Context, nodeID types.NodeID, p *types.Proposal, txIDs []types.TransactionID) error {
+	budget := pb.cfg.maxProposalBytes
+	if budget == 0 {
+		p.TxIDs = txIDs
+		return nil
+	}
+	logger := pb.logger.WithContext(ctx).WithFields(p.Layer, nodeID, log.Int("budget", budget))
+	// signatures have fixed size, therefore the size of the unsigned proposal is exact
+	size := encodedSize(p)
+	if exceptions := len(p.Votes.Support) + len(p.Votes.Against); size > budget && exceptions > 0 {
+		vote := encodedSize(&types.Vote{})
+		limit := exceptions - (size-budget+vote-1)/vote
+		if limit < 0 {
+			limit = 0
+		}
+		opinion, err := pb.tortoise.EncodeVotes(ctx,
+			tortoise.EncodeVotesWithCurrent(p.Layer),
+			tortoise.EncodeVotesWithMaxExceptions(limit),
+		)
+		if err != nil {
+			return fmt.Errorf("encode votes within budget: %w", err)
+		}
+		logger.With().Warning("ballot exceeds proposal size budget, encoded votes with fewer exceptions",
+			log.Int("size", size),
+			log.Int("exceptions", exceptions),
+			log.Int("max_exceptions", limit),
+		)
+		setOpinion(&p.Ballot, opinion)
+		size = encodedSize(p)
+	}
+	if proofs := len(p.EligibilityProofs); size > budget && proofs > 1 {
+		n, excess := proofs, size-budget
+		for n > 1 && excess > 0 {
+			n--
+			excess -= encodedSize(&p.EligibilityProofs[n])
+		}
+		logger.With().Warning("ballot exceeds proposal size budget, dropped eligibility proofs",
+			log.Int("size", size),
+			log.Int("proofs", proofs),
+			log.Int("kept", n),
+		)
+		p.EligibilityProofs = p.EligibilityProofs[:n]
+		size = encodedSize(p)
+	}
+	if size > budget {
+		return fmt.Errorf("%w: %d bytes with budget %d", errBallotTooLarge, size, budget)
+	}
+	// every transaction adds its id and may grow the compact length prefix of the list
+	sizeWith := func(n int) int {
+		return size - codec.CompactSize(0) + codec.CompactSize(uint32(n)) + n*types.TransactionIDSize
+	}
+	n := len(txIDs)
+	if max := (budget - size) / types.TransactionIDSize; n > max {
+		n = max
+	}
+	for n > 0 && sizeWith(n) > budget {
+		n--
+	}
+	if n < len(txIDs) {
+		logger.With().Info("proposal size budget reached, not all selected transactions are included",
+			log.Int("selected", len(txIDs)),
+			log.Int("included", n),
+		)
+	}
+	p.TxIDs = txIDs[:n]
+	return nil
+}
+
+// setOpinion sets the votes encoded by tortoise, and the hash of the opinion they encode, on the ballot.
+// Votes are never derived from the base ballot by the builder.
+func setOpinion(b *types.Ballot, opinion *types.Opinion) {
+	b.OpinionHash = opinion.Hash
+	b.Votes = opinion.Votes
+}
+
+func encodedSize(value codec.Encodable) int {
+	n, err := codec.EncodeTo(io.Discard, value)
+	if err != nil {
+		log.Fatal("failed to encode %T: %v", value, err)
+	}
+	return n
+}
+
+// refBallot returns the reference ballot of the identity in the epoch,
+// or an empty id if the next ballot of the identity must be the reference ballot.
+func (pb *ProposalBuilder) refBallot(ctx context.Context, nodeID types.NodeID, epoch types.EpochID) (types.BallotID, error) {
+	ref, err := refballots.Get(pb.cdb, epoch, nodeID)
+	switch {
+	case err == nil:
+		published, err := ballots.Has(pb.cdb, ref.Ballot)
+		if err != nil {
+			return types.EmptyBallotID, fmt.Errorf("check ref ballot: %w", err)
+		}
+		pending, err := pendingproposals.Has(pb.cdb, ref.Proposal)
+		if err != nil {
+			return types.EmptyBallotID, fmt.Errorf("check ref ballot: %w", err)
+		}
+		if published || pending {
+			return ref.Ballot, nil
+		}
+		// the node was stopped before the ref ballot was published, and it is expired now
+		pb.logger.WithContext(ctx).With().Warning("ref ballot was never published, rebuilding",
+			epoch,
+			nodeID,
+			log.Named("ref_ballot", ref.Ballot),
+		)
+		return types.EmptyBallotID, nil
+	case errors.Is(err, sql.ErrNotFound):
+	default:
+		return types.EmptyBallotID, fmt.Errorf("get persisted ref ballot: %w", err)
+	}
+	// the ref ballot might have been published before it was persisted by the builder
+	ballot, err := ballots.GetRefBallot(pb.cdb, epoch, nodeID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNotFound) {
+			return types.EmptyBallotID, nil
+		}
+		return types.EmptyBallotID, fmt.Errorf("get ref ballot: %w", err)
+	}
+	return ballot, nil
+}
+
 // only output the mesh hash in the proposal when the following conditions are met:
 // - tortoise has verified every layer i < N-hdist.
 // - the node has hare output for every layer i such that N-hdist <= i <= N.
@@ -358,100 +742,365 @@ func (pb *ProposalBuilder) handleLayer(ctx context.Context, layerID types.LayerI
 	if layerID <= types.GetEffectiveGenesis() {
 		return errGenesis
 	}
+	if err := pb.checkDeadline(layerID); err != nil {
+		pb.logger.WithContext(ctx).With().Warning("skipping layer", layerID, log.Err(err))
+		pb.reportFailedAll(layerID, events.FailureLate, err)
+		return err
+	}
 	if !pb.syncer.IsSynced(ctx) {
+		pb.reportFailedAll(layerID, events.FailureNotSynced, nil)
 		return errNotSynced
 	}
 	if beacon, err = pb.beaconProvider.GetBeacon(epoch); err != nil {
+		pb.reportFailedAll(layerID, events.FailureNoBeacon, err)
 		return errNoBeacon
 	}
 
+	var (
+		eligible []*sessionEligibility
+		total    int
+		rst      error
+	)
+	for _, s := range pb.sessions {
+		e, err := pb.checkEligibility(ctx, s, layerID, beacon)
+		switch {
+		case errors.Is(err, errNoVRFNonce):
+			reportFailed(layerID, s.signer.NodeID(), events.FailureNoNonce, nil)
+			continue
+		case errors.Is(err, errNotEligible):
+			reportFailed(layerID, s.signer.NodeID(), events.FailureNotEligible, nil)
+			continue
+		case err != nil:
+			reason := events.FailureEligibility
+			if errors.Is(err, errDuplicateLayer) {
+				reason = events.FailureDuplicateLayer
+			} else if errors.Is(err, errMinerHasNoATXInPreviousEpoch) {
+				reason = events.FailureNoATX
+			}
+			reportFailed(layerID, s.signer.NodeID(), reason, err)
+			if rst == nil {
+				rst = err
+			}
+			if len(pb.sessions) > 1 {
+				pb.logger.WithContext(ctx).With().Warning("failed to build proposal", layerID, s.signer.NodeID(), log.Err(err))
+			}
+			continue
+		}
+		eligible = append(eligible, e)
+		total += len(e.proofs)
+	}
+	if len(eligible) == 0 {
+		return rst
+	}
+
+	// votes are encoded once and shared by all identities
+	pb.tortoise.TallyVotes(ctx, layerID)
+	// TODO(dshulyak) will get rid from the EncodeVotesWithCurrent option in a followup
+	// there are some dependencies in the tests
+	opinion, err := pb.tortoise.EncodeVotes(ctx, tortoise.EncodeVotesWithCurrent(layerID))
+	if err != nil {
+		// votes can't be encoded while tortoise is catching up, the layer is skipped
+		// instead of publishing votes that are inconsistent with the local opinion
+		pb.logger.WithContext(ctx).With().Warning("skipping layer, failed to encode votes",
+			layerID,
+			log.Err(err),
+		)
+		for _, e := range eligible {
+			reportFailed(layerID, e.session.signer.NodeID(), events.FailureNoOpinion, err)
+		}
+		return fmt.Errorf("%w: %s", errNoOpinion, err)
+	}
+
+	// transactions are selected once for all eligibilities in the layer and split between identities,
+	// so that proposals of the same node don't duplicate transactions.
+	txs := pb.conState.SelectProposalTXs(layerID, total)
+	offset := 0
+	for i, e := range eligible {
+		share := len(txs) - offset
+		if i < len(eligible)-1 {
+			share = len(txs) * len(e.proofs) / total
+		}
+		if err := pb.buildProposal(ctx, e, layerID, beacon, txs[offset:offset+share], *opinion); err != nil && rst == nil {
+			rst = err
+		}
+		offset += share
+	}
+	return rst
+}
+
+// sessionEligibility is an eligibility of a single identity in the layer.
+type sessionEligibility struct {
+	started time.Time
+	session *session
+	epoch   *EpochEligibility
+	proofs  []types.VotingEligibility
+}
+
+// checkEligibility returns eligibility of the identity in the layer. It returns errNotEligible if the identity
+// is not eligible in the layer, and errNoVRFNonce if the identity has no vrf nonce for the epoch.
+func (pb *ProposalBuilder) checkEligibility(
+	ctx context.Context,
+	s *session,
+	layerID types.LayerID,
+	beacon types.Beacon,
+) (*sessionEligibility, error) {
 	started := time.Now()
+	nodeID := s.signer.NodeID()
 
-	count, err := ballots.CountByPubkeyLayer(pb.cdb, layerID, pb.signer.NodeID())
+	count, err := ballots.CountByPubkeyLayer(pb.cdb, layerID, nodeID)
 	if err != nil {
-		return err
+		return nil, err
 	} else if count != 0 {
-		return errDuplicateLayer
+		return nil, errDuplicateLayer
 	}
 
-	nonce, err := pb.nonceFetcher.VRFNonce(pb.signer.NodeID(), layerID.GetEpoch())
+	nonce, err := pb.nonceFetcher.VRFNonce(nodeID, layerID.GetEpoch())
 	if err != nil {
 		if errors.Is(err, sql.ErrNotFound) {
-			pb.logger.WithContext(ctx).With().Info("miner has no valid vrf nonce, not building proposal", layerID)
-			return nil
+			pb.logger.WithContext(ctx).With().Info("miner has no valid vrf nonce, not building proposal", layerID, nodeID)
+			return nil, errNoVRFNonce
 		}
-		return err
+		return nil, err
 	}
-	epochEligibility, err := pb.proposalOracle.GetProposalEligibility(layerID, beacon, nonce)
+	epochEligibility, err := s.oracle.GetProposalEligibility(layerID, beacon, nonce)
 	if err != nil {
 		if errors.Is(err, errMinerHasNoATXInPreviousEpoch) {
-			return fmt.Errorf("miner no ATX: %w", err)
+			return nil, fmt.Errorf("miner no ATX: %w", err)
 		}
-		return fmt.Errorf("proposal eligibility: %w", err)
+		return nil, fmt.Errorf("proposal eligibility: %w", err)
 	}
 	proofs := epochEligibility.Proofs[layerID]
 	if len(proofs) == 0 {
-		pb.logger.WithContext(ctx).With().Debug("not eligible for proposal in layer", layerID)
-		return nil
+		pb.logger.WithContext(ctx).With().Debug("not eligible for proposal in layer", layerID, nodeID)
+		return nil, errNotEligible
 	}
 	pb.logger.WithContext(ctx).With().Debug("eligible for proposals in layer",
 		layerID,
+		nodeID,
 		epochEligibility.Atx,
 		log.Int("num proposals", len(proofs)),
 	)
+	return &sessionEligibility{started: started, session: s, epoch: epochEligibility, proofs: proofs}, nil
+}
 
-	pb.tortoise.TallyVotes(ctx, layerID)
-	// TODO(dshulyak) will get rid from the EncodeVotesWithCurrent option in a followup
-	// there are some dependencies in the tests
-	opinion, err := pb.tortoise.EncodeVotes(ctx, tortoise.EncodeVotesWithCurrent(layerID))
-	if err != nil {
-		pb.logger.WithContext(ctx).With().Error("failed to encode votes",
-			layerID,
-			log.Err(err),
-		)
-		return fmt.Errorf("encode votes: %w", err)
+// buildProposal builds and publishes proposal for a single identity.
+func (pb *ProposalBuilder) buildProposal(
+	ctx context.Context,
+	e *sessionEligibility,
+	layerID types.LayerID,
+	beacon types.Beacon,
+	txList []types.TransactionID,
+	opinion types.Opinion,
+) error {
+	nodeID := e.session.signer.NodeID()
+	events.ReportProposalBuildStarted(events.ProposalBuildStarted{Layer: layerID, NodeID: nodeID, Count: len(e.proofs)})
+	// the lock is held until the proposal is persisted, so that proposals built concurrently
+	// in other layers of the epoch refer to the same ref ballot.
+	e.session.mu.Lock()
+	p, err := pb.createProposal(ctx, e.session.signer, layerID, e.epoch, beacon, txList, opinion)
+	if err == nil {
+		// proposal is persisted before publishing, so that it can be published after restart
+		// if the node is stopped before it was published.
+		if err := pb.persistPending(ctx, p); err != nil {
+			pb.logger.WithContext(ctx).With().Error("failed to persist pending proposal", layerID, p.ID(), log.Err(err))
+		}
 	}
-
-	txList := pb.conState.SelectProposalTXs(layerID, len(proofs))
-	p, err := pb.createProposal(ctx, layerID, epochEligibility, beacon, txList, *opinion)
+	e.session.mu.Unlock()
 	if err != nil {
+		reportFailed(layerID, nodeID, events.FailureBuild, err)
 		return err
 	}
 
-	pb.saveMetrics(ctx, started, layerID)
+	pb.saveMetrics(ctx, e.started, layerID)
 
 	if pb.stopped() {
 		return nil
 	}
 
+	pb.publish(ctx, p)
+	return nil
+}
+
+// persistPending persists the proposal until it is published together with the ref ballot it carries.
+func (pb *ProposalBuilder) persistPending(ctx context.Context, p *types.Proposal) error {
+	return pb.cdb.WithTx(ctx, func(tx *sql.Tx) error {
+		if err := pendingproposals.Add(tx, p); err != nil {
+			return err
+		}
+		if p.EpochData == nil {
+			return nil
+		}
+		return refballots.Set(tx, p.Layer.GetEpoch(), p.SmesherID, refballots.RefBallot{
+			Ballot:   p.Ballot.ID(),
+			Proposal: p.ID(),
+		})
+	})
+}
+
+// publish publishes the proposal in the background. Publication is retried with backoff
+// until it succeeds or the layer of the proposal ends.
+func (pb *ProposalBuilder) publish(ctx context.Context, p *types.Proposal) {
 	pb.eg.Go(func() error {
 		// generate a new requestID for the new proposal message
-		newCtx := log.WithNewRequestID(ctx, layerID, p.ID())
+		newCtx := log.WithNewRequestID(ctx, p.Layer, p.ID())
+		logger := pb.logger.WithContext(newCtx).WithFields(p.Layer, p.ID())
 		// validation handler, where proposal is persisted, is applied synchronously before
 		// proposal is sent over the network
-		data, err := codec.Encode(p)
+		buf, release, err := codec.EncodeToPool(p)
 		if err != nil {
-			pb.logger.WithContext(newCtx).With().Fatal("failed to serialize proposal", log.Err(err))
+			logger.With().Fatal("failed to serialize proposal", log.Err(err))
+		}
+		// the publisher copies the message (see pubsub.VersionedPublisher), the buffer is released
+		// once the proposal is published or dropped
+		defer release()
+		if pb.cfg.buildLead > 0 {
+			// proposal built in advance is not published before its layer starts
+			select {
+			case <-pb.ctx.Done():
+				return nil
+			case <-pb.clock.AwaitLayer(p.Layer):
+			}
+		}
+		var (
+			interval = pb.cfg.publishRetryInterval
+			expired  <-chan struct{}
+		)
+		for attempt := 1; ; attempt++ {
+			err := pb.publisher.Publish(newCtx, pubsub.ProposalProtocol, buf.Bytes())
+			if err == nil {
+				break
+			}
+			logger.With().Warning("failed to send proposal", log.Int("attempt", attempt), log.Err(err))
+			if expired == nil {
+				expired = pb.clock.AwaitLayer(p.Layer.Add(1))
+			}
+			select {
+			case <-pb.ctx.Done():
+				// proposal stays pending and is published after restart if its layer didn't end
+				return nil
+			case <-expired:
+				pb.expire(newCtx, p, fmt.Sprintf("layer ended after %d failed attempts to publish: %v", attempt, err))
+				return nil
+			case <-time.After(interval):
+			}
+			interval *= 2
+			if interval > pb.cfg.publishMaxRetryInterval {
+				interval = pb.cfg.publishMaxRetryInterval
+			}
 		}
-		if err = pb.publisher.Publish(newCtx, pubsub.ProposalProtocol, data); err != nil {
-			pb.logger.WithContext(newCtx).With().Error("failed to send proposal", log.Err(err))
-		} else {
-			events.EmitProposal(layerID, p.ID())
-			events.ReportProposal(events.ProposalCreated, p)
+		if err := pendingproposals.Delete(pb.cdb, p.ID()); err != nil {
+			logger.With().Error("failed to delete published proposal", log.Err(err))
 		}
+		events.EmitProposal(p.Layer, p.ID())
+		events.ReportProposal(events.ProposalCreated, p)
+		events.ReportProposalPublished(events.ProposalPublished{Layer: p.Layer, NodeID: p.SmesherID, Proposal: p.ID()})
 		return nil
 	})
+}
+
+// expire drops the pending proposal that wasn't published before its layer ended.
+func (pb *ProposalBuilder) expire(ctx context.Context, p *types.Proposal, reason string) {
+	pb.logger.WithContext(ctx).With().Warning("proposal expired before it was published",
+		p.Layer,
+		p.ID(),
+		log.String("reason", reason),
+	)
+	if err := pendingproposals.Delete(pb.cdb, p.ID()); err != nil {
+		pb.logger.WithContext(ctx).With().Error("failed to delete expired proposal", p.ID(), log.Err(err))
+	}
+	events.ReportProposalExpired(p, reason)
+	events.ReportProposalFailed(events.ProposalFailed{
+		Layer:  p.Layer,
+		NodeID: p.SmesherID,
+		Reason: events.FailurePublish,
+		Error:  reason,
+	})
+}
+
+// reportFailedAll reports the failure for every identity of the node.
+func (pb *ProposalBuilder) reportFailedAll(layer types.LayerID, reason events.ProposalFailureReason, err error) {
+	for _, s := range pb.sessions {
+		reportFailed(layer, s.signer.NodeID(), reason, err)
+	}
+}
+
+func reportFailed(layer types.LayerID, nodeID types.NodeID, reason events.ProposalFailureReason, err error) {
+	ev := events.ProposalFailed{Layer: layer, NodeID: nodeID, Reason: reason}
+	if err != nil {
+		ev.Error = err.Error()
+	}
+	events.ReportProposalFailed(ev)
+}
+
+// republishPending publishes the proposals that were built but not published before the node was stopped.
+func (pb *ProposalBuilder) republishPending(ctx context.Context) {
+	pending, err := pendingproposals.All(pb.cdb)
+	if err != nil {
+		pb.logger.WithContext(ctx).With().Error("failed to load pending proposals", log.Err(err))
+		return
+	}
+	if len(pending) == 0 {
+		return
+	}
+	current := pb.clock.CurrentLayer()
+	for _, p := range pending {
+		if p.Layer.Before(current) {
+			pb.expire(ctx, p, "layer ended before the node restarted")
+			continue
+		}
+		pb.logger.WithContext(ctx).With().Info("publishing pending proposal", p.Layer, p.ID())
+		pb.publish(ctx, p)
+	}
+}
+
+// checkDeadline returns an error if the build deadline of the layer has passed.
+func (pb *ProposalBuilder) checkDeadline(layerID types.LayerID) error {
+	if pb.cfg.buildDeadline == 0 {
+		return nil
+	}
+	start := pb.clock.LayerToTime(layerID)
+	duration := pb.clock.LayerToTime(layerID.Add(1)).Sub(start)
+	deadline := start.Add(time.Duration(pb.cfg.buildDeadline * float64(duration)))
+	if now := pb.clock.Now(); now.After(deadline) {
+		return fmt.Errorf("%w: %v after the deadline", errLate, now.Sub(deadline))
+	}
 	return nil
 }
 
+// awaitBuild returns a channel that is closed when proposals for the layer should be built.
+func (pb *ProposalBuilder) awaitBuild(layerID types.LayerID) <-chan struct{} {
+	if pb.cfg.buildLead == 0 {
+		return pb.clock.AwaitLayer(layerID)
+	}
+	ch := make(chan struct{})
+	time.AfterFunc(pb.clock.LayerToTime(layerID).Add(-pb.cfg.buildLead).Sub(pb.clock.Now()), func() {
+		close(ch)
+	})
+	return ch
+}
+
+// buildLayer returns the layer to build proposals for. It is the next layer
+// if it starts within the lead time, otherwise the current one.
+func (pb *ProposalBuilder) buildLayer() types.LayerID {
+	current := pb.clock.CurrentLayer()
+	if pb.cfg.buildLead == 0 {
+		return current
+	}
+	next := current.Add(1)
+	if pb.clock.Now().Before(pb.clock.LayerToTime(next).Add(-pb.cfg.buildLead)) {
+		return current
+	}
+	return next
+}
+
 func (pb *ProposalBuilder) createProposalLoop(ctx context.Context) {
 	next := pb.clock.CurrentLayer().Add(1)
 	for {
 		select {
 		case <-pb.ctx.Done():
 			return
-		case <-pb.clock.AwaitLayer(next):
-			current := pb.clock.CurrentLayer()
+		case <-pb.awaitBuild(next):
+			current := pb.buildLayer()
 			if current.Before(next) {
 				pb.logger.Info("time sync detected, realigning ProposalBuilder")
 				continue
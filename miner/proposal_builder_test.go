@@ -6,24 +6,31 @@ import (
 	"errors"
 	"math/rand"
 	"sort"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/require"
+	"golang.org/x/sync/errgroup"
 
 	"github.com/spacemeshos/go-spacemesh/codec"
 	"github.com/spacemeshos/go-spacemesh/common/types"
 	"github.com/spacemeshos/go-spacemesh/datastore"
+	"github.com/spacemeshos/go-spacemesh/events"
 	"github.com/spacemeshos/go-spacemesh/genvm/sdk/wallet"
 	"github.com/spacemeshos/go-spacemesh/log/logtest"
 	"github.com/spacemeshos/go-spacemesh/p2p/pubsub"
 	pubsubmocks "github.com/spacemeshos/go-spacemesh/p2p/pubsub/mocks"
+	"github.com/spacemeshos/go-spacemesh/proposals"
 	"github.com/spacemeshos/go-spacemesh/signing"
 	"github.com/spacemeshos/go-spacemesh/sql"
 	"github.com/spacemeshos/go-spacemesh/sql/ballots"
 	"github.com/spacemeshos/go-spacemesh/sql/certificates"
+	"github.com/spacemeshos/go-spacemesh/sql/eligibilities"
 	"github.com/spacemeshos/go-spacemesh/sql/layers"
+	"github.com/spacemeshos/go-spacemesh/sql/pendingproposals"
+	"github.com/spacemeshos/go-spacemesh/sql/refballots"
 	"github.com/spacemeshos/go-spacemesh/system/mocks"
 )
 
@@ -43,11 +50,25 @@ type testBuilder struct {
 	mBeacon   *mocks.MockBeaconGetter
 	mSync     *mocks.MockSyncStateProvider
 	mNonce    *MocknonceFetcher
+
+	edSigner *signing.EdSigner
+}
+
+func createBuilder(tb testing.TB, opts ...Opt) *testBuilder {
+	_, edSigner, _ := generateNodeIDAndSigner(tb)
+	return newTestBuilder(tb, datastore.NewCachedDB(sql.InMemory(), logtest.New(tb)), edSigner, opts...)
+}
+
+// restartBuilder creates a builder for the same identity and database, as if the node was restarted.
+func restartBuilder(tb testing.TB, b *testBuilder, opts ...Opt) *testBuilder {
+	return newTestBuilder(tb, b.cdb, b.edSigner, opts...)
 }
 
-func createBuilder(tb testing.TB) *testBuilder {
+func newTestBuilder(tb testing.TB, cdb *datastore.CachedDB, edSigner *signing.EdSigner, opts ...Opt) *testBuilder {
 	types.SetLayersPerEpoch(layersPerEpoch)
-	nodeID, edSigner, vrfSigner := generateNodeIDAndSigner(tb)
+	vrfSigner, err := edSigner.VRFSigner()
+	require.NoError(tb, err)
+	nodeID := edSigner.NodeID()
 	ctrl := gomock.NewController(tb)
 	pb := &testBuilder{
 		mOracle:   NewMockproposalOracle(ctrl),
@@ -58,18 +79,20 @@ func createBuilder(tb testing.TB) *testBuilder {
 		mBeacon:   mocks.NewMockBeaconGetter(ctrl),
 		mSync:     mocks.NewMockSyncStateProvider(ctrl),
 		mNonce:    NewMocknonceFetcher(ctrl),
+		edSigner:  edSigner,
 	}
 	lg := logtest.New(tb)
-	cdb := datastore.NewCachedDB(sql.InMemory(), lg)
 	pb.ProposalBuilder = NewProposalBuilder(context.Background(), pb.mClock, edSigner, vrfSigner,
 		cdb, pb.mPubSub, pb.mTortoise, pb.mBeacon, pb.mSync, pb.mCState,
-		WithLogger(lg),
-		WithLayerSize(20),
-		WithLayerPerEpoch(3),
-		WithNodeID(nodeID),
-		WithHdist(3),
-		withOracle(pb.mOracle),
-		withNonceFetcher(pb.mNonce),
+		append([]Opt{
+			WithLogger(lg),
+			WithLayerSize(20),
+			WithLayerPerEpoch(3),
+			WithNodeID(nodeID),
+			WithHdist(3),
+			withOracle(pb.mOracle),
+			withNonceFetcher(pb.mNonce),
+		}, opts...)...,
 	)
 	return pb
 }
@@ -184,7 +207,7 @@ func TestBuilder_HandleLayer_MultipleProposals(t *testing.T) {
 	meshHash := types.RandomHash()
 	edVerifier, err := signing.NewEdVerifier()
 	require.NoError(t, err)
-	require.NoError(t, layers.SetMeshHash(b.cdb, layerID.Sub(1), meshHash))
+	require.NoError(t, layers.SetAggregatedHash(b.cdb, layerID.Sub(1), meshHash))
 	b.mPubSub.EXPECT().Publish(gomock.Any(), pubsub.ProposalProtocol, gomock.Any()).DoAndReturn(
 		func(_ context.Context, _ string, data []byte) error {
 			var p types.Proposal
@@ -211,6 +234,338 @@ func TestBuilder_HandleLayer_MultipleProposals(t *testing.T) {
 	b.Close()
 }
 
+func TestBuilder_HandleLayer_MultipleSigners(t *testing.T) {
+	signers := make([]signing.Signer, 2)
+	for i := range signers {
+		signer, err := signing.NewEdSigner()
+		require.NoError(t, err)
+		signers[i] = signer
+	}
+	b := createBuilder(t, WithSigners(signers...))
+	layerID := types.LayerID(layersPerEpoch * 3)
+	beacon := types.RandomBeacon()
+	activeSet := genActiveSet(t)
+
+	// every identity has its own nonce and atx, oracle returns eligibility for the identity by nonce.
+	type expect struct {
+		atx    types.ATXID
+		proofs int
+	}
+	expected := map[types.NodeID]expect{}
+	eligibilities := map[types.VRFPostIndex]*EpochEligibility{}
+	total := 0
+	for i, signer := range append([]signing.Signer{b.signer}, signers...) {
+		nonce := types.VRFPostIndex(i + 1)
+		ee := &EpochEligibility{
+			Atx:       types.RandomATXID(),
+			ActiveSet: activeSet,
+			Proofs:    map[types.LayerID][]types.VotingEligibility{layerID: genProofs(t, i+1)},
+			Slots:     uint32(i + 1),
+		}
+		eligibilities[nonce] = ee
+		expected[signer.NodeID()] = expect{atx: ee.Atx, proofs: i + 1}
+		total += i + 1
+		b.mNonce.EXPECT().VRFNonce(signer.NodeID(), layerID.GetEpoch()).Return(nonce, nil)
+	}
+	b.mSync.EXPECT().IsSynced(gomock.Any()).Return(true)
+	b.mBeacon.EXPECT().GetBeacon(gomock.Any()).Return(beacon, nil)
+	b.mOracle.EXPECT().GetProposalEligibility(layerID, beacon, gomock.Any()).DoAndReturn(
+		func(_ types.LayerID, _ types.Beacon, nonce types.VRFPostIndex) (*EpochEligibility, error) {
+			return eligibilities[nonce], nil
+		}).Times(len(expected))
+
+	// transactions are selected once for all identities
+	txs := make([]types.TransactionID, 2*total)
+	for i := range txs {
+		txs[i] = types.RandomTransactionID()
+	}
+	b.mCState.EXPECT().SelectProposalTXs(layerID, total).Return(txs)
+	// votes are encoded once and shared by all identities
+	b.mTortoise.EXPECT().TallyVotes(gomock.Any(), layerID)
+	b.mTortoise.EXPECT().EncodeVotes(gomock.Any(), gomock.Any()).Return(&types.Opinion{}, nil)
+	b.mTortoise.EXPECT().LatestComplete().Return(types.LayerID(0)).Times(len(expected))
+
+	var (
+		mu        sync.Mutex
+		published = map[types.NodeID]*types.Proposal{}
+	)
+	b.mPubSub.EXPECT().Publish(gomock.Any(), pubsub.ProposalProtocol, gomock.Any()).DoAndReturn(
+		func(_ context.Context, _ string, data []byte) error {
+			var p types.Proposal
+			require.NoError(t, codec.Decode(data, &p))
+			require.NoError(t, p.Initialize())
+			mu.Lock()
+			published[p.SmesherID] = &p
+			mu.Unlock()
+			return nil
+		}).Times(len(expected))
+
+	require.NoError(t, b.handleLayer(context.Background(), layerID))
+	b.Close()
+
+	require.Len(t, published, len(expected))
+	selected := map[types.TransactionID]types.NodeID{}
+	for id, p := range published {
+		exp, exists := expected[id]
+		require.True(t, exists, id)
+		// every identity publishes its own reference ballot with its own atx
+		require.Equal(t, types.EmptyBallotID, p.RefBallot)
+		require.NotNil(t, p.EpochData)
+		require.Equal(t, exp.atx, p.AtxID)
+		require.Len(t, p.EligibilityProofs, exp.proofs)
+		// transactions are split proportionally to the number of eligibilities
+		require.Len(t, p.TxIDs, 2*exp.proofs)
+		for _, tx := range p.TxIDs {
+			other, exists := selected[tx]
+			require.False(t, exists, "tx %s is in proposals of %s and %s", tx, id, other)
+			selected[tx] = id
+		}
+	}
+	require.Len(t, selected, len(txs))
+}
+
+func TestBuilder_HandleLayer_SignersEligibleInDifferentLayers(t *testing.T) {
+	other, err := signing.NewEdSigner()
+	require.NoError(t, err)
+	b := createBuilder(t, WithSigners(other))
+	first := types.LayerID(layersPerEpoch * 3)
+	second := first.Add(1)
+	beacon := types.RandomBeacon()
+	activeSet := genActiveSet(t)
+
+	// the primary identity is eligible in both layers, the other one only in the second layer
+	nonces := map[types.NodeID]types.VRFPostIndex{b.signer.NodeID(): 1, other.NodeID(): 2}
+	eligibilities := map[types.VRFPostIndex]*EpochEligibility{
+		1: {
+			Atx:       types.RandomATXID(),
+			ActiveSet: activeSet,
+			Proofs:    map[types.LayerID][]types.VotingEligibility{first: genProofs(t, 1), second: genProofs(t, 1)},
+			Slots:     2,
+		},
+		2: {
+			Atx:       types.RandomATXID(),
+			ActiveSet: activeSet,
+			Proofs:    map[types.LayerID][]types.VotingEligibility{second: genProofs(t, 1)},
+			Slots:     1,
+		},
+	}
+	b.mSync.EXPECT().IsSynced(gomock.Any()).Return(true).Times(2)
+	b.mBeacon.EXPECT().GetBeacon(gomock.Any()).Return(beacon, nil).Times(2)
+	b.mNonce.EXPECT().VRFNonce(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(id types.NodeID, _ types.EpochID) (types.VRFPostIndex, error) {
+			return nonces[id], nil
+		}).Times(4)
+	b.mOracle.EXPECT().GetProposalEligibility(gomock.Any(), beacon, gomock.Any()).DoAndReturn(
+		func(_ types.LayerID, _ types.Beacon, nonce types.VRFPostIndex) (*EpochEligibility, error) {
+			return eligibilities[nonce], nil
+		}).Times(4)
+	b.mTortoise.EXPECT().TallyVotes(gomock.Any(), gomock.Any()).Times(2)
+	b.mTortoise.EXPECT().EncodeVotes(gomock.Any(), gomock.Any()).Return(&types.Opinion{}, nil).Times(2)
+	b.mTortoise.EXPECT().LatestComplete().Return(types.LayerID(0)).Times(3)
+	b.mCState.EXPECT().SelectProposalTXs(gomock.Any(), gomock.Any()).Return(nil).Times(2)
+
+	var (
+		mu        sync.Mutex
+		published = map[types.LayerID]map[types.NodeID]*types.Proposal{}
+	)
+	b.mPubSub.EXPECT().Publish(gomock.Any(), pubsub.ProposalProtocol, gomock.Any()).DoAndReturn(
+		func(_ context.Context, _ string, data []byte) error {
+			var p types.Proposal
+			require.NoError(t, codec.Decode(data, &p))
+			require.NoError(t, p.Initialize())
+			// ballot is persisted by the validation handler when the proposal is published
+			require.NoError(t, ballots.Add(b.cdb, &p.Ballot))
+			mu.Lock()
+			defer mu.Unlock()
+			if published[p.Layer] == nil {
+				published[p.Layer] = map[types.NodeID]*types.Proposal{}
+			}
+			published[p.Layer][p.SmesherID] = &p
+			return nil
+		}).Times(3)
+
+	require.NoError(t, b.handleLayer(context.Background(), first))
+	require.NoError(t, b.eg.Wait())
+	require.Len(t, published[first], 1)
+	ref := published[first][b.signer.NodeID()]
+	require.NotNil(t, ref)
+	require.NotNil(t, ref.EpochData)
+
+	require.NoError(t, b.handleLayer(context.Background(), second))
+	b.Close()
+	require.Len(t, published[second], 2)
+	// the primary identity refers to the ballot from the first layer, the other one publishes its own ref ballot
+	primary := published[second][b.signer.NodeID()]
+	require.Equal(t, ref.Ballot.ID(), primary.RefBallot)
+	require.Nil(t, primary.EpochData)
+	require.Equal(t, eligibilities[1].Atx, primary.AtxID)
+	secondary := published[second][other.NodeID()]
+	require.Equal(t, types.EmptyBallotID, secondary.RefBallot)
+	require.NotNil(t, secondary.EpochData)
+	require.Equal(t, eligibilities[2].Slots, secondary.EpochData.EligibilityCount)
+	require.Equal(t, eligibilities[2].Atx, secondary.AtxID)
+}
+
+// expectEpochProposals sets up the mocks to build proposals in every given layer of the epoch
+// and returns the published proposals by layer.
+func expectEpochProposals(tb testing.TB, b *testBuilder, lids ...types.LayerID) (*sync.Mutex, map[types.LayerID]*types.Proposal) {
+	tb.Helper()
+	beacon := types.RandomBeacon()
+	proofs := map[types.LayerID][]types.VotingEligibility{}
+	for _, lid := range lids {
+		proofs[lid] = genProofs(tb, 1)
+	}
+	ee := &EpochEligibility{
+		Atx:       types.RandomATXID(),
+		ActiveSet: genActiveSet(tb),
+		Proofs:    proofs,
+		Slots:     uint32(len(lids)),
+	}
+	n := len(lids)
+	b.mSync.EXPECT().IsSynced(gomock.Any()).Return(true).Times(n)
+	b.mBeacon.EXPECT().GetBeacon(gomock.Any()).Return(beacon, nil).Times(n)
+	b.mNonce.EXPECT().VRFNonce(gomock.Any(), gomock.Any()).Return(types.VRFPostIndex(1), nil).Times(n)
+	b.mOracle.EXPECT().GetProposalEligibility(gomock.Any(), beacon, gomock.Any()).Return(ee, nil).Times(n)
+	b.mTortoise.EXPECT().TallyVotes(gomock.Any(), gomock.Any()).Times(n)
+	b.mTortoise.EXPECT().EncodeVotes(gomock.Any(), gomock.Any()).Return(&types.Opinion{}, nil).Times(n)
+	b.mTortoise.EXPECT().LatestComplete().Return(types.LayerID(0)).AnyTimes()
+	b.mCState.EXPECT().SelectProposalTXs(gomock.Any(), 1).Return(nil).Times(n)
+
+	var (
+		mu        sync.Mutex
+		published = map[types.LayerID]*types.Proposal{}
+	)
+	b.mPubSub.EXPECT().Publish(gomock.Any(), pubsub.ProposalProtocol, gomock.Any()).DoAndReturn(
+		func(_ context.Context, _ string, data []byte) error {
+			var p types.Proposal
+			require.NoError(tb, codec.Decode(data, &p))
+			require.NoError(tb, p.Initialize())
+			// ballot is persisted by the validation handler when the proposal is published
+			require.NoError(tb, ballots.Add(b.cdb, &p.Ballot))
+			mu.Lock()
+			defer mu.Unlock()
+			published[p.Layer] = &p
+			return nil
+		}).AnyTimes()
+	return &mu, published
+}
+
+func TestBuilder_RefBallotConcurrentLayers(t *testing.T) {
+	b := createBuilder(t)
+	first := types.LayerID(layersPerEpoch * 3)
+	second := first.Add(1)
+	mu, published := expectEpochProposals(t, b, first, second)
+
+	var eg errgroup.Group
+	for _, lid := range []types.LayerID{first, second} {
+		lid := lid
+		eg.Go(func() error {
+			return b.handleLayer(context.Background(), lid)
+		})
+	}
+	require.NoError(t, eg.Wait())
+	b.Close()
+
+	mu.Lock()
+	defer mu.Unlock()
+	require.Len(t, published, 2)
+	var refs, refering []*types.Proposal
+	for _, p := range published {
+		if p.EpochData != nil {
+			refs = append(refs, p)
+		} else {
+			refering = append(refering, p)
+		}
+	}
+	require.Len(t, refs, 1, "only one ref ballot is built in the epoch")
+	require.Len(t, refering, 1)
+	require.Equal(t, refs[0].Ballot.ID(), refering[0].RefBallot)
+}
+
+func TestBuilder_RefBallotRestartBeforePublish(t *testing.T) {
+	first := types.LayerID(layersPerEpoch * 3)
+	second := first.Add(1)
+	for _, tc := range []struct {
+		desc string
+		// layer when the node is restarted
+		restart types.LayerID
+		// ref ballot is rebuilt if it was never published
+		rebuilt bool
+	}{
+		{desc: "published after restart", restart: first},
+		{desc: "expired before restart", restart: second, rebuilt: true},
+	} {
+		tc := tc
+		t.Run(tc.desc, func(t *testing.T) {
+			b := createBuilder(t)
+			expectEpochProposals(t, b, first)
+			// builder is stopped after the ref ballot is built but before it is published
+			b.Close()
+			require.NoError(t, b.handleLayer(context.Background(), first))
+			ref := requirePending(t, b, 1)[0]
+			require.NotNil(t, ref.EpochData)
+
+			restarted := restartBuilder(t, b)
+			mu, published := expectEpochProposals(t, restarted, second)
+			restarted.mClock.EXPECT().CurrentLayer().Return(tc.restart).AnyTimes()
+			restarted.mClock.EXPECT().AwaitLayer(gomock.Any()).Return(make(chan struct{})).AnyTimes()
+			require.NoError(t, restarted.Start(context.Background()))
+			require.NoError(t, restarted.handleLayer(context.Background(), second))
+			restarted.Close()
+
+			mu.Lock()
+			defer mu.Unlock()
+			got := published[second]
+			require.NotNil(t, got)
+			if tc.rebuilt {
+				require.NotContains(t, published, first)
+				require.NotNil(t, got.EpochData)
+				require.Equal(t, types.EmptyBallotID, got.RefBallot)
+			} else {
+				require.Contains(t, published, first)
+				require.Nil(t, got.EpochData)
+				require.Equal(t, ref.Ballot.ID(), got.RefBallot)
+			}
+			requirePending(t, restarted, 0)
+		})
+	}
+}
+
+func TestBuilder_HandleLayer_SignerFailureIsolated(t *testing.T) {
+	other, err := signing.NewEdSigner()
+	require.NoError(t, err)
+	b := createBuilder(t, WithSigners(other))
+	layerID := types.LayerID(layersPerEpoch * 3)
+	beacon := types.RandomBeacon()
+
+	errUnknown := errors.New("unknown")
+	b.mSync.EXPECT().IsSynced(gomock.Any()).Return(true)
+	b.mBeacon.EXPECT().GetBeacon(gomock.Any()).Return(beacon, nil)
+	b.mNonce.EXPECT().VRFNonce(b.signer.NodeID(), gomock.Any()).Return(types.VRFPostIndex(0), errUnknown)
+	b.mNonce.EXPECT().VRFNonce(other.NodeID(), gomock.Any()).Return(types.VRFPostIndex(1), nil)
+	b.mOracle.EXPECT().GetProposalEligibility(layerID, beacon, types.VRFPostIndex(1)).Return(&EpochEligibility{
+		Atx:       types.RandomATXID(),
+		ActiveSet: genActiveSet(t),
+		Proofs:    map[types.LayerID][]types.VotingEligibility{layerID: genProofs(t, 1)},
+		Slots:     1,
+	}, nil)
+	b.mTortoise.EXPECT().TallyVotes(gomock.Any(), layerID)
+	b.mTortoise.EXPECT().EncodeVotes(gomock.Any(), gomock.Any()).Return(&types.Opinion{}, nil)
+	b.mTortoise.EXPECT().LatestComplete().Return(types.LayerID(0))
+	b.mCState.EXPECT().SelectProposalTXs(layerID, 1).Return(nil)
+	b.mPubSub.EXPECT().Publish(gomock.Any(), pubsub.ProposalProtocol, gomock.Any()).DoAndReturn(
+		func(_ context.Context, _ string, data []byte) error {
+			var p types.Proposal
+			require.NoError(t, codec.Decode(data, &p))
+			require.Equal(t, other.NodeID(), p.SmesherID)
+			return nil
+		})
+
+	// the error of the primary identity is returned after the proposal of the other identity is built
+	require.ErrorIs(t, b.handleLayer(context.Background(), layerID), errUnknown)
+	b.Close()
+}
+
 func TestBuilder_HandleLayer_OneProposal(t *testing.T) {
 	b := createBuilder(t)
 
@@ -256,7 +611,7 @@ func TestBuilder_HandleLayer_OneProposal(t *testing.T) {
 	meshHash := types.RandomHash()
 	edVerifier, err := signing.NewEdVerifier()
 	require.NoError(t, err)
-	require.NoError(t, layers.SetMeshHash(b.cdb, layerID.Sub(1), meshHash))
+	require.NoError(t, layers.SetAggregatedHash(b.cdb, layerID.Sub(1), meshHash))
 	b.mPubSub.EXPECT().Publish(gomock.Any(), pubsub.ProposalProtocol, gomock.Any()).DoAndReturn(
 		func(_ context.Context, _ string, data []byte) error {
 			var p types.Proposal
@@ -342,7 +697,12 @@ func TestBuilder_HandleLayer_NotEligible(t *testing.T) {
 	require.NoError(t, b.handleLayer(context.Background(), layerID))
 }
 
-func TestBuilder_HandleLayer_BaseBlockError(t *testing.T) {
+func TestBuilder_HandleLayer_NoOpinion(t *testing.T) {
+	events.InitializeReporter()
+	t.Cleanup(events.CloseEventReporter)
+	sub, err := events.Subscribe[events.BuilderEvent]()
+	require.NoError(t, err)
+
 	b := createBuilder(t)
 
 	layerID := types.LayerID(layersPerEpoch * 3)
@@ -362,7 +722,167 @@ func TestBuilder_HandleLayer_BaseBlockError(t *testing.T) {
 	b.mTortoise.EXPECT().TallyVotes(gomock.Any(), gomock.Any())
 	b.mTortoise.EXPECT().EncodeVotes(gomock.Any(), gomock.Any()).Return(nil, errUnknown)
 
-	require.ErrorIs(t, b.handleLayer(context.Background(), layerID), errUnknown)
+	err = b.handleLayer(context.Background(), layerID)
+	require.ErrorIs(t, err, errNoOpinion)
+	require.ErrorContains(t, err, errUnknown.Error())
+	requireBuilderEvents(t, sub, events.BuilderEvent{ProposalFailed: &events.ProposalFailed{
+		Layer:  layerID,
+		NodeID: b.signer.NodeID(),
+		Reason: events.FailureNoOpinion,
+		Error:  errUnknown.Error(),
+	}})
+	requirePending(t, b, 0)
+}
+
+func requireBuilderEvents(tb testing.TB, sub *events.BufferedSubscription[events.BuilderEvent], expected ...events.BuilderEvent) {
+	tb.Helper()
+	for _, ev := range expected {
+		select {
+		case received := <-sub.Out():
+			require.Equal(tb, ev, received)
+		case <-time.After(time.Second):
+			require.FailNow(tb, "timed out waiting for builder event", "%+v", ev)
+		}
+	}
+	require.Empty(tb, sub.Out())
+}
+
+func TestBuilder_BuildEvents(t *testing.T) {
+	events.InitializeReporter()
+	t.Cleanup(events.CloseEventReporter)
+	sub, err := events.Subscribe[events.BuilderEvent]()
+	require.NoError(t, err)
+
+	b := createBuilder(t)
+	nodeID := b.signer.NodeID()
+	layerID := types.LayerID(layersPerEpoch * 3)
+	b.mClock.EXPECT().AwaitLayer(gomock.Any()).Return(make(chan struct{})).AnyTimes()
+
+	t.Run("eligible", func(t *testing.T) {
+		expectProposal(t, b, layerID, types.RandomTransactionID())
+		published := make(chan types.ProposalID, 1)
+		b.mPubSub.EXPECT().Publish(gomock.Any(), pubsub.ProposalProtocol, gomock.Any()).DoAndReturn(
+			func(_ context.Context, _ string, data []byte) error {
+				var p types.Proposal
+				require.NoError(t, codec.Decode(data, &p))
+				require.NoError(t, p.Initialize())
+				published <- p.ID()
+				return nil
+			})
+		require.NoError(t, b.handleLayer(context.Background(), layerID))
+		id := <-published
+		requireBuilderEvents(t, sub,
+			events.BuilderEvent{ProposalBuildStarted: &events.ProposalBuildStarted{Layer: layerID, NodeID: nodeID, Count: 1}},
+			events.BuilderEvent{ProposalPublished: &events.ProposalPublished{Layer: layerID, NodeID: nodeID, Proposal: id}},
+		)
+	})
+	t.Run("not eligible", func(t *testing.T) {
+		lid := layerID.Add(1)
+		beacon := types.RandomBeacon()
+		nonce := types.VRFPostIndex(rand.Uint64())
+		b.mSync.EXPECT().IsSynced(gomock.Any()).Return(true)
+		b.mBeacon.EXPECT().GetBeacon(gomock.Any()).Return(beacon, nil)
+		b.mNonce.EXPECT().VRFNonce(gomock.Any(), gomock.Any()).Return(nonce, nil)
+		b.mOracle.EXPECT().GetProposalEligibility(lid, beacon, nonce).Return(&EpochEligibility{
+			Atx:       types.RandomATXID(),
+			ActiveSet: genActiveSet(t),
+			Proofs:    map[types.LayerID][]types.VotingEligibility{layerID: genProofs(t, 1)},
+		}, nil)
+		require.NoError(t, b.handleLayer(context.Background(), lid))
+		requireBuilderEvents(t, sub, events.BuilderEvent{ProposalFailed: &events.ProposalFailed{
+			Layer:  lid,
+			NodeID: nodeID,
+			Reason: events.FailureNotEligible,
+		}})
+	})
+	t.Run("not synced", func(t *testing.T) {
+		lid := layerID.Add(2)
+		b.mSync.EXPECT().IsSynced(gomock.Any()).Return(false)
+		require.ErrorIs(t, b.handleLayer(context.Background(), lid), errNotSynced)
+		requireBuilderEvents(t, sub, events.BuilderEvent{ProposalFailed: &events.ProposalFailed{
+			Layer:  lid,
+			NodeID: nodeID,
+			Reason: events.FailureNotSynced,
+		}})
+	})
+	t.Run("no beacon", func(t *testing.T) {
+		lid := layerID.Add(2)
+		b.mSync.EXPECT().IsSynced(gomock.Any()).Return(true)
+		b.mBeacon.EXPECT().GetBeacon(gomock.Any()).Return(types.EmptyBeacon, errors.New("no beacon"))
+		require.ErrorIs(t, b.handleLayer(context.Background(), lid), errNoBeacon)
+		requireBuilderEvents(t, sub, events.BuilderEvent{ProposalFailed: &events.ProposalFailed{
+			Layer:  lid,
+			NodeID: nodeID,
+			Reason: events.FailureNoBeacon,
+			Error:  "no beacon",
+		}})
+	})
+	b.Close()
+}
+
+func TestBuilder_HandleLayer_EncodedVotes(t *testing.T) {
+	layerID := types.LayerID(layersPerEpoch * 3)
+	for _, tc := range []struct {
+		desc    string
+		opinion types.Opinion
+	}{
+		{
+			desc:    "no exceptions",
+			opinion: types.Opinion{Hash: types.RandomHash(), Votes: types.Votes{Base: types.RandomBallotID()}},
+		},
+		{
+			desc: "exceptions",
+			opinion: types.Opinion{
+				Hash: types.RandomHash(),
+				Votes: types.Votes{
+					Base: types.RandomBallotID(),
+					Support: []types.Vote{
+						{ID: types.RandomBlockID(), LayerID: layerID.Sub(2), Height: 10},
+						{ID: types.RandomBlockID(), LayerID: layerID.Sub(1), Height: 20},
+					},
+					Against: []types.Vote{{ID: types.RandomBlockID(), LayerID: layerID.Sub(3), Height: 10}},
+					Abstain: []types.LayerID{layerID.Sub(1)},
+				},
+			},
+		},
+	} {
+		tc := tc
+		t.Run(tc.desc, func(t *testing.T) {
+			b := createBuilder(t)
+			beacon := types.RandomBeacon()
+			nonce := types.VRFPostIndex(rand.Uint64())
+			b.mSync.EXPECT().IsSynced(gomock.Any()).Return(true)
+			b.mBeacon.EXPECT().GetBeacon(gomock.Any()).Return(beacon, nil)
+			b.mNonce.EXPECT().VRFNonce(gomock.Any(), gomock.Any()).Return(nonce, nil)
+			b.mOracle.EXPECT().GetProposalEligibility(layerID, beacon, nonce).Return(&EpochEligibility{
+				Atx:       types.RandomATXID(),
+				ActiveSet: genActiveSet(t),
+				Proofs:    map[types.LayerID][]types.VotingEligibility{layerID: genProofs(t, 1)},
+			}, nil)
+			b.mCState.EXPECT().SelectProposalTXs(layerID, 1).Return(nil)
+			b.mTortoise.EXPECT().TallyVotes(gomock.Any(), layerID)
+			opinion := tc.opinion
+			b.mTortoise.EXPECT().EncodeVotes(gomock.Any(), gomock.Any()).Return(&opinion, nil)
+			b.mTortoise.EXPECT().LatestComplete().Return(types.GetEffectiveGenesis())
+
+			var published []byte
+			b.mPubSub.EXPECT().Publish(gomock.Any(), pubsub.ProposalProtocol, gomock.Any()).DoAndReturn(
+				func(_ context.Context, _ string, data []byte) error {
+					// the publisher doesn't retain the message
+					published = append([]byte(nil), data...)
+					return nil
+				})
+			require.NoError(t, b.handleLayer(context.Background(), layerID))
+			b.Close()
+
+			var p types.Proposal
+			require.NoError(t, codec.Decode(published, &p))
+			require.Equal(t, tc.opinion.Hash, p.OpinionHash)
+			require.Equal(t, tc.opinion.Votes, p.Votes)
+			// published data is exactly the encoding of the proposal with the votes above
+			require.Equal(t, published, codec.MustEncode(&p))
+		})
+	}
 }
 
 func TestBuilder_HandleLayer_NoRefBallot(t *testing.T) {
@@ -390,7 +910,7 @@ func TestBuilder_HandleLayer_NoRefBallot(t *testing.T) {
 	b.mTortoise.EXPECT().TallyVotes(gomock.Any(), gomock.Any())
 	b.mTortoise.EXPECT().EncodeVotes(gomock.Any(), gomock.Any()).Return(&types.Opinion{Votes: types.Votes{Base: types.RandomBallotID()}}, nil)
 	b.mTortoise.EXPECT().LatestComplete().Return(types.GetEffectiveGenesis())
-	require.NoError(t, layers.SetMeshHash(b.cdb, layerID.Sub(1), types.RandomHash()))
+	require.NoError(t, layers.SetAggregatedHash(b.cdb, layerID.Sub(1), types.RandomHash()))
 	b.mPubSub.EXPECT().Publish(gomock.Any(), pubsub.ProposalProtocol, gomock.Any()).DoAndReturn(
 		func(_ context.Context, _ string, data []byte) error {
 			var got types.Proposal
@@ -432,7 +952,7 @@ func TestBuilder_HandleLayer_RefBallot(t *testing.T) {
 	b.mTortoise.EXPECT().TallyVotes(gomock.Any(), gomock.Any())
 	b.mTortoise.EXPECT().EncodeVotes(gomock.Any(), gomock.Any()).Return(&types.Opinion{Votes: types.Votes{Base: types.RandomBallotID()}}, nil)
 	b.mTortoise.EXPECT().LatestComplete().Return(types.GetEffectiveGenesis())
-	require.NoError(t, layers.SetMeshHash(b.cdb, layerID.Sub(1), types.RandomHash()))
+	require.NoError(t, layers.SetAggregatedHash(b.cdb, layerID.Sub(1), types.RandomHash()))
 	b.mPubSub.EXPECT().Publish(gomock.Any(), pubsub.ProposalProtocol, gomock.Any()).DoAndReturn(
 		func(_ context.Context, _ string, data []byte) error {
 			var got types.Proposal
@@ -475,47 +995,169 @@ func TestBuilder_HandleLayer_CanceledDuringBuilding(t *testing.T) {
 	b.mTortoise.EXPECT().TallyVotes(gomock.Any(), gomock.Any())
 	b.mTortoise.EXPECT().EncodeVotes(gomock.Any(), gomock.Any()).Return(&types.Opinion{Votes: types.Votes{Base: types.RandomBallotID()}}, nil)
 	b.mTortoise.EXPECT().LatestComplete().Return(types.GetEffectiveGenesis())
-	require.NoError(t, layers.SetMeshHash(b.cdb, layerID.Sub(1), types.RandomHash()))
+	require.NoError(t, layers.SetAggregatedHash(b.cdb, layerID.Sub(1), types.RandomHash()))
 
 	b.Close()
 	require.NoError(t, b.handleLayer(context.Background(), layerID))
 }
 
-func TestBuilder_HandleLayer_PublishError(t *testing.T) {
-	b := createBuilder(t)
-
-	layerID := types.LayerID(layersPerEpoch * 3)
-	b.mClock.EXPECT().CurrentLayer().Return(layerID).AnyTimes()
-	b.mClock.EXPECT().AwaitLayer(layerID.Add(1)).DoAndReturn(func(types.LayerID) <-chan struct{} {
-		return make(chan struct{})
-	}).AnyTimes()
-	require.NoError(t, b.Start(context.Background()))
-
+// expectProposal sets up the mocks to build a single proposal with the given transaction in the layer.
+func expectProposal(tb testing.TB, b *testBuilder, layerID types.LayerID, tx types.TransactionID) {
+	tb.Helper()
 	beacon := types.RandomBeacon()
-	sig, err := signing.NewEdSigner()
-	require.NoError(t, err)
 	nonce := types.VRFPostIndex(rand.Uint64())
-	tx := genTX(t, 1, types.GenerateAddress([]byte{0x01}), sig)
-
 	b.mSync.EXPECT().IsSynced(gomock.Any()).Return(true)
 	b.mBeacon.EXPECT().GetBeacon(gomock.Any()).Return(beacon, nil)
 	b.mNonce.EXPECT().VRFNonce(gomock.Any(), gomock.Any()).Return(nonce, nil)
 	ee := &EpochEligibility{
 		Atx:       types.RandomATXID(),
-		ActiveSet: genActiveSet(t),
-		Proofs:    map[types.LayerID][]types.VotingEligibility{layerID: genProofs(t, 1)},
+		ActiveSet: genActiveSet(tb),
+		Proofs:    map[types.LayerID][]types.VotingEligibility{layerID: genProofs(tb, 1)},
 	}
 	b.mOracle.EXPECT().GetProposalEligibility(layerID, beacon, nonce).Return(ee, nil)
-	b.mCState.EXPECT().SelectProposalTXs(layerID, 1).Return([]types.TransactionID{tx.ID})
+	b.mCState.EXPECT().SelectProposalTXs(layerID, 1).Return([]types.TransactionID{tx})
 	b.mTortoise.EXPECT().TallyVotes(gomock.Any(), gomock.Any())
 	b.mTortoise.EXPECT().EncodeVotes(gomock.Any(), gomock.Any()).Return(&types.Opinion{Votes: types.Votes{Base: types.RandomBallotID()}}, nil)
 	b.mTortoise.EXPECT().LatestComplete().Return(types.GetEffectiveGenesis())
-	require.NoError(t, layers.SetMeshHash(b.cdb, layerID.Sub(1), types.RandomHash()))
-	b.mPubSub.EXPECT().Publish(gomock.Any(), pubsub.ProposalProtocol, gomock.Any()).Return(errors.New("unknown"))
+	require.NoError(tb, layers.SetAggregatedHash(b.cdb, layerID.Sub(1), types.RandomHash()))
+}
+
+func requirePending(tb testing.TB, b *testBuilder, n int) []*types.Proposal {
+	tb.Helper()
+	pending, err := pendingproposals.All(b.cdb)
+	require.NoError(tb, err)
+	require.Len(tb, pending, n)
+	return pending
+}
+
+func TestBuilder_HandleLayer_PublishError(t *testing.T) {
+	events.InitializeReporter()
+	t.Cleanup(events.CloseEventReporter)
+	sub, err := events.Subscribe[events.EventProposal]()
+	require.NoError(t, err)
+
+	b := createBuilder(t, withPublishRetry(time.Millisecond, time.Millisecond))
+	layerID := types.LayerID(layersPerEpoch * 3)
+	expired := make(chan struct{})
+	b.mClock.EXPECT().AwaitLayer(layerID.Add(1)).Return(expired)
+
+	sig, err := signing.NewEdSigner()
+	require.NoError(t, err)
+	tx := genTX(t, 1, types.GenerateAddress([]byte{0x01}), sig)
+	expectProposal(t, b, layerID, tx.ID)
+	published := make(chan struct{}, 1)
+	b.mPubSub.EXPECT().Publish(gomock.Any(), pubsub.ProposalProtocol, gomock.Any()).DoAndReturn(
+		func(context.Context, string, []byte) error {
+			select {
+			case published <- struct{}{}:
+			default:
+			}
+			return errors.New("unknown")
+		}).MinTimes(1)
 
 	// publish error is ignored
 	require.NoError(t, b.handleLayer(context.Background(), layerID))
+	<-published
+	// the proposal is retried until the layer ends
+	close(expired)
+	select {
+	case ev := <-sub.Out():
+		require.Equal(t, events.ProposalExpired, ev.Status)
+		require.Equal(t, layerID, ev.Proposal.Layer)
+		require.Contains(t, ev.Reason, "unknown")
+	case <-time.After(time.Second):
+		require.FailNow(t, "proposal didn't expire")
+	}
+	b.Close()
+	requirePending(t, b, 0)
+}
+
+func TestBuilder_HandleLayer_PublishRetried(t *testing.T) {
+	b := createBuilder(t, withPublishRetry(time.Millisecond, 10*time.Millisecond))
+	layerID := types.LayerID(layersPerEpoch * 3)
+	b.mClock.EXPECT().AwaitLayer(layerID.Add(1)).Return(make(chan struct{}))
+
+	sig, err := signing.NewEdSigner()
+	require.NoError(t, err)
+	tx := genTX(t, 1, types.GenerateAddress([]byte{0x01}), sig)
+	expectProposal(t, b, layerID, tx.ID)
+	var first []byte
+	b.mPubSub.EXPECT().Publish(gomock.Any(), pubsub.ProposalProtocol, gomock.Any()).DoAndReturn(
+		func(_ context.Context, _ string, data []byte) error {
+			first = append([]byte(nil), data...)
+			return errors.New("no peers")
+		})
+	published := make(chan struct{})
+	b.mPubSub.EXPECT().Publish(gomock.Any(), pubsub.ProposalProtocol, gomock.Any()).DoAndReturn(
+		func(_ context.Context, _ string, data []byte) error {
+			require.Equal(t, first, data)
+			close(published)
+			return nil
+		})
+
+	require.NoError(t, b.handleLayer(context.Background(), layerID))
+	select {
+	case <-published:
+	case <-time.After(time.Second):
+		require.FailNow(t, "proposal wasn't retried")
+	}
 	b.Close()
+	requirePending(t, b, 0)
+}
+
+func TestBuilder_RestartBeforePublish(t *testing.T) {
+	b := createBuilder(t)
+	layerID := types.LayerID(layersPerEpoch * 3)
+	sig, err := signing.NewEdSigner()
+	require.NoError(t, err)
+	tx := genTX(t, 1, types.GenerateAddress([]byte{0x01}), sig)
+	expectProposal(t, b, layerID, tx.ID)
+	// builder is stopped after the proposal is built but before it is published
+	b.Close()
+	require.NoError(t, b.handleLayer(context.Background(), layerID))
+	pending := requirePending(t, b, 1)
+	require.Equal(t, []types.TransactionID{tx.ID}, pending[0].TxIDs)
+
+	restarted := restartBuilder(t, b)
+	restarted.mClock.EXPECT().CurrentLayer().Return(layerID).AnyTimes()
+	restarted.mClock.EXPECT().AwaitLayer(layerID.Add(1)).Return(make(chan struct{})).AnyTimes()
+	published := make(chan struct{})
+	restarted.mPubSub.EXPECT().Publish(gomock.Any(), pubsub.ProposalProtocol, gomock.Any()).DoAndReturn(
+		func(_ context.Context, _ string, data []byte) error {
+			var p types.Proposal
+			require.NoError(t, codec.Decode(data, &p))
+			require.NoError(t, p.Initialize())
+			require.Equal(t, pending[0].ID(), p.ID())
+			close(published)
+			return nil
+		})
+	require.NoError(t, restarted.Start(context.Background()))
+	select {
+	case <-published:
+	case <-time.After(time.Second):
+		require.FailNow(t, "pending proposal wasn't published after restart")
+	}
+	restarted.Close()
+	requirePending(t, restarted, 0)
+}
+
+func TestBuilder_RestartAfterLayerEnded(t *testing.T) {
+	b := createBuilder(t)
+	layerID := types.LayerID(layersPerEpoch * 3)
+	sig, err := signing.NewEdSigner()
+	require.NoError(t, err)
+	tx := genTX(t, 1, types.GenerateAddress([]byte{0x01}), sig)
+	expectProposal(t, b, layerID, tx.ID)
+	b.Close()
+	require.NoError(t, b.handleLayer(context.Background(), layerID))
+	requirePending(t, b, 1)
+
+	restarted := restartBuilder(t, b)
+	restarted.mClock.EXPECT().CurrentLayer().Return(layerID.Add(1)).AnyTimes()
+	restarted.mClock.EXPECT().AwaitLayer(layerID.Add(2)).Return(make(chan struct{})).AnyTimes()
+	require.NoError(t, restarted.Start(context.Background()))
+	restarted.Close()
+	requirePending(t, restarted, 0)
 }
 
 func TestBuilder_HandleLayer_NotVerified(t *testing.T) {
@@ -677,14 +1319,343 @@ func TestBuilder_UniqueBlockID(t *testing.T) {
 	meshHash := types.RandomHash()
 
 	builder1.mTortoise.EXPECT().LatestComplete().Return(layerID.Sub(1))
-	require.NoError(t, layers.SetMeshHash(builder1.cdb, layerID.Sub(1), meshHash))
-	b1, err := builder1.createProposal(context.Background(), layerID, &EpochEligibility{Atx: atxID1, ActiveSet: activeSet}, beacon, nil, types.Opinion{})
+	require.NoError(t, layers.SetAggregatedHash(builder1.cdb, layerID.Sub(1), meshHash))
+	b1, err := builder1.createProposal(context.Background(), builder1.signer, layerID, &EpochEligibility{Atx: atxID1, ActiveSet: activeSet}, beacon, nil, types.Opinion{})
 	require.NoError(t, err)
 
 	builder2.mTortoise.EXPECT().LatestComplete().Return(layerID.Sub(1))
-	require.NoError(t, layers.SetMeshHash(builder2.cdb, layerID.Sub(1), meshHash))
-	b2, err := builder2.createProposal(context.Background(), layerID, &EpochEligibility{Atx: atxID2, ActiveSet: activeSet}, beacon, nil, types.Opinion{})
+	require.NoError(t, layers.SetAggregatedHash(builder2.cdb, layerID.Sub(1), meshHash))
+	b2, err := builder2.createProposal(context.Background(), builder2.signer, layerID, &EpochEligibility{Atx: atxID2, ActiveSet: activeSet}, beacon, nil, types.Opinion{})
 	require.NoError(t, err)
 
 	require.NotEqual(t, b1.ID(), b2.ID())
 }
+
+func TestBuilder_Schedule(t *testing.T) {
+	b := createBuilder(t)
+	epoch := types.EpochID(3)
+	current := epoch.FirstLayer().Add(1)
+	genesis := time.Now()
+	b.mClock.EXPECT().CurrentLayer().Return(current).AnyTimes()
+	b.mClock.EXPECT().LayerToTime(gomock.Any()).DoAndReturn(func(lid types.LayerID) time.Time {
+		return genesis.Add(time.Duration(lid) * time.Minute)
+	}).AnyTimes()
+
+	schedule, err := b.Schedule()
+	require.NoError(t, err)
+	require.Empty(t, schedule)
+
+	var proofs []types.VotingEligibility
+	expected := map[types.LayerID]int{}
+	for i := 0; i < 10; i++ {
+		var sig types.VrfSignature
+		rand.Read(sig[:])
+		proofs = append(proofs, types.VotingEligibility{J: uint32(i), Sig: sig})
+		if lid := proposals.CalcEligibleLayer(epoch, layersPerEpoch, sig); !lid.Before(current) {
+			expected[lid]++
+		}
+	}
+	require.NoError(t, eligibilities.Set(b.cdb, epoch, b.signer.NodeID(), &eligibilities.Eligibility{
+		Atx:    types.RandomATXID(),
+		Slots:  uint32(len(proofs)),
+		Proofs: proofs,
+	}))
+
+	schedule, err = b.Schedule()
+	require.NoError(t, err)
+	require.Len(t, schedule, len(expected))
+	for i, sp := range schedule {
+		if i > 0 {
+			require.True(t, schedule[i-1].Layer.Before(sp.Layer))
+		}
+		require.Equal(t, b.signer.NodeID(), sp.NodeID)
+		require.Equal(t, expected[sp.Layer], sp.Count)
+		require.Equal(t, genesis.Add(time.Duration(sp.Layer)*time.Minute), sp.Time)
+	}
+}
+
+func TestBuilder_ProposalSizeBudget(t *testing.T) {
+	layerID := types.LayerID(layersPerEpoch * 3)
+	beacon := types.RandomBeacon()
+	ee := &EpochEligibility{
+		Atx:       types.RandomATXID(),
+		ActiveSet: genActiveSet(t),
+		Proofs:    map[types.LayerID][]types.VotingEligibility{layerID: genProofs(t, 5)},
+		Slots:     5,
+	}
+	var txs []types.TransactionID
+	for i := 0; i < 100; i++ {
+		txs = append(txs, types.RandomTransactionID())
+	}
+	var exceptions []types.Vote
+	for i := 0; i < 10; i++ {
+		exceptions = append(exceptions, types.Vote{ID: types.RandomBlockID(), LayerID: layerID.Sub(1)})
+	}
+	opinion := types.Opinion{Votes: types.Votes{Base: types.RandomBallotID()}}
+	build := func(tb testing.TB, budget int, opinion types.Opinion) (*testBuilder, *types.Proposal, error) {
+		b := createBuilder(tb, WithMaxProposalBytes(budget))
+		b.mTortoise.EXPECT().LatestComplete().Return(types.GetEffectiveGenesis()).AnyTimes()
+		p, err := b.createProposal(context.Background(), b.signer, layerID, ee, beacon, txs, opinion)
+		if err == nil && budget > 0 {
+			require.LessOrEqual(tb, len(codec.MustEncode(p)), budget)
+		}
+		return b, p, err
+	}
+	_, empty, err := build(t, 1<<20, opinion)
+	require.NoError(t, err)
+	require.Equal(t, txs, empty.TxIDs)
+	empty.TxIDs = nil
+	ballotSize := len(codec.MustEncode(empty))
+
+	t.Run("unlimited", func(t *testing.T) {
+		_, p, err := build(t, 0, opinion)
+		require.NoError(t, err)
+		require.Equal(t, txs, p.TxIDs)
+	})
+	t.Run("truncated txs", func(t *testing.T) {
+		for _, tc := range []struct {
+			budget, included int
+		}{
+			{budget: ballotSize, included: 0},
+			{budget: ballotSize + types.TransactionIDSize - 1, included: 0},
+			{budget: ballotSize + 10*types.TransactionIDSize, included: 10},
+			{budget: ballotSize + 10*types.TransactionIDSize - 1, included: 9},
+			// list of 64 ids needs an additional byte for the length prefix
+			{budget: ballotSize + 64*types.TransactionIDSize, included: 63},
+			{budget: ballotSize + 64*types.TransactionIDSize + 1, included: 64},
+		} {
+			_, p, err := build(t, tc.budget, opinion)
+			require.NoError(t, err)
+			require.Equal(t, txs[:tc.included], p.TxIDs, "budget %d", tc.budget)
+			require.Equal(t, ee.Proofs[layerID], p.EligibilityProofs)
+		}
+	})
+	t.Run("fewer exceptions", func(t *testing.T) {
+		withExceptions := opinion
+		withExceptions.Votes.Support = exceptions
+		reduced := types.Opinion{
+			Hash: types.RandomHash(),
+			Votes: types.Votes{
+				Base:    opinion.Votes.Base,
+				Support: exceptions[5:],
+				Abstain: []types.LayerID{layerID.Sub(1)},
+			},
+		}
+		fits := *empty
+		fits.Votes = reduced.Votes
+		budget := len(codec.MustEncode(&fits))
+		b := createBuilder(t, WithMaxProposalBytes(budget))
+		b.mTortoise.EXPECT().LatestComplete().Return(types.GetEffectiveGenesis()).AnyTimes()
+		b.mTortoise.EXPECT().EncodeVotes(gomock.Any(), gomock.Any(), gomock.Any()).Return(&reduced, nil)
+		p, err := b.createProposal(context.Background(), b.signer, layerID, ee, beacon, txs, withExceptions)
+		require.NoError(t, err)
+		require.LessOrEqual(t, len(codec.MustEncode(p)), budget)
+		require.Equal(t, reduced.Votes, p.Votes)
+		require.Equal(t, reduced.Hash, p.OpinionHash)
+		require.Equal(t, ee.Proofs[layerID], p.EligibilityProofs)
+	})
+	t.Run("fewer proofs", func(t *testing.T) {
+		_, p, err := build(t, ballotSize-1, opinion)
+		require.NoError(t, err)
+		require.Equal(t, ee.Proofs[layerID][:4], p.EligibilityProofs)
+		// space left after the dropped proof is filled with transactions
+		require.Equal(t, txs[:len(p.TxIDs)], p.TxIDs)
+	})
+	t.Run("too large", func(t *testing.T) {
+		_, _, err := build(t, ballotSize/2, opinion)
+		require.ErrorIs(t, err, errBallotTooLarge)
+	})
+}
+
+func TestBuilder_BuildDeadline(t *testing.T) {
+	events.InitializeReporter()
+	t.Cleanup(events.CloseEventReporter)
+	sub, err := events.Subscribe[events.BuilderEvent]()
+	require.NoError(t, err)
+
+	b := createBuilder(t, WithBuildDeadline(0.5))
+	layerID := types.LayerID(layersPerEpoch * 3)
+	start := time.Now()
+	b.mClock.EXPECT().LayerToTime(layerID).Return(start).AnyTimes()
+	b.mClock.EXPECT().LayerToTime(layerID.Add(1)).Return(start.Add(10 * time.Second)).AnyTimes()
+	deadline := start.Add(5 * time.Second)
+
+	t.Run("at deadline", func(t *testing.T) {
+		b.mClock.EXPECT().Now().Return(deadline)
+		b.mSync.EXPECT().IsSynced(gomock.Any()).Return(false)
+		require.ErrorIs(t, b.handleLayer(context.Background(), layerID), errNotSynced)
+		requireBuilderEvents(t, sub, events.BuilderEvent{ProposalFailed: &events.ProposalFailed{
+			Layer:  layerID,
+			NodeID: b.signer.NodeID(),
+			Reason: events.FailureNotSynced,
+		}})
+	})
+	t.Run("past deadline", func(t *testing.T) {
+		b.mClock.EXPECT().Now().Return(deadline.Add(time.Nanosecond))
+		err := b.handleLayer(context.Background(), layerID)
+		require.ErrorIs(t, err, errLate)
+		requireBuilderEvents(t, sub, events.BuilderEvent{ProposalFailed: &events.ProposalFailed{
+			Layer:  layerID,
+			NodeID: b.signer.NodeID(),
+			Reason: events.FailureLate,
+			Error:  err.Error(),
+		}})
+	})
+}
+
+func TestBuilder_BuildLead(t *testing.T) {
+	const lead = time.Second
+	b := createBuilder(t, WithBuildLead(lead))
+	current := types.LayerID(layersPerEpoch * 3)
+	next := current.Add(1)
+	start := time.Now().Add(time.Minute)
+	b.mClock.EXPECT().CurrentLayer().Return(current).AnyTimes()
+	b.mClock.EXPECT().LayerToTime(next).Return(start).AnyTimes()
+
+	t.Run("before lead", func(t *testing.T) {
+		b.mClock.EXPECT().Now().Return(start.Add(-lead - time.Nanosecond))
+		require.Equal(t, current, b.buildLayer())
+	})
+	t.Run("within lead", func(t *testing.T) {
+		b.mClock.EXPECT().Now().Return(start.Add(-lead))
+		require.Equal(t, next, b.buildLayer())
+	})
+	t.Run("await", func(t *testing.T) {
+		b.mClock.EXPECT().Now().Return(start.Add(-lead))
+		select {
+		case <-b.awaitBuild(next):
+		case <-time.After(time.Second):
+			require.FailNow(t, "build wasn't started within the lead time")
+		}
+		b.mClock.EXPECT().Now().Return(start.Add(-2 * lead))
+		select {
+		case <-b.awaitBuild(next):
+			require.FailNow(t, "build started before the lead time")
+		case <-time.After(100 * time.Millisecond):
+		}
+	})
+	t.Run("published at layer start", func(t *testing.T) {
+		layerStarted := make(chan struct{})
+		b.mClock.EXPECT().AwaitLayer(next).Return(layerStarted)
+		b.mClock.EXPECT().AwaitLayer(next.Add(1)).Return(make(chan struct{})).AnyTimes()
+		expectProposal(t, b, next, types.RandomTransactionID())
+		published := make(chan struct{})
+		b.mPubSub.EXPECT().Publish(gomock.Any(), pubsub.ProposalProtocol, gomock.Any()).DoAndReturn(
+			func(context.Context, string, []byte) error {
+				close(published)
+				return nil
+			})
+		require.NoError(t, b.handleLayer(context.Background(), next))
+		select {
+		case <-published:
+			require.FailNow(t, "proposal published before the layer started")
+		case <-time.After(100 * time.Millisecond):
+		}
+		close(layerStarted)
+		select {
+		case <-published:
+		case <-time.After(time.Second):
+			require.FailNow(t, "proposal wasn't published")
+		}
+		b.Close()
+	})
+}
+
+func TestBuilder_BuildDryRun(t *testing.T) {
+	events.InitializeReporter()
+	t.Cleanup(events.CloseEventReporter)
+	sub, err := events.Subscribe[events.BuilderEvent]()
+	require.NoError(t, err)
+
+	b := createBuilder(t)
+	layerID := types.LayerID(layersPerEpoch * 3)
+	beacon := types.RandomBeacon()
+	nonce := types.VRFPostIndex(rand.Uint64())
+	ee := &EpochEligibility{
+		Atx:       types.RandomATXID(),
+		ActiveSet: genActiveSet(t),
+		Proofs:    map[types.LayerID][]types.VotingEligibility{layerID: genProofs(t, 2)},
+		Slots:     2,
+	}
+	txs := []types.TransactionID{types.RandomTransactionID(), types.RandomTransactionID()}
+	opinion := &types.Opinion{
+		Hash: types.RandomHash(),
+		Votes: types.Votes{
+			Base:    types.RandomBallotID(),
+			Support: []types.Vote{{ID: types.RandomBlockID(), LayerID: layerID.Sub(1)}},
+		},
+	}
+	// state doesn't change between the dry run and the real build
+	b.mSync.EXPECT().IsSynced(gomock.Any()).Return(true).Times(2)
+	b.mBeacon.EXPECT().GetBeacon(layerID.GetEpoch()).Return(beacon, nil).Times(2)
+	b.mNonce.EXPECT().VRFNonce(b.edSigner.NodeID(), layerID.GetEpoch()).Return(nonce, nil).Times(2)
+	b.mOracle.EXPECT().GetProposalEligibility(layerID, beacon, nonce).Return(ee, nil).Times(2)
+	b.mCState.EXPECT().SelectProposalTXs(layerID, 2).Return(txs).Times(2)
+	b.mTortoise.EXPECT().TallyVotes(gomock.Any(), layerID).Times(2)
+	b.mTortoise.EXPECT().EncodeVotes(gomock.Any(), gomock.Any()).Return(opinion, nil).Times(2)
+	b.mTortoise.EXPECT().LatestComplete().Return(types.GetEffectiveGenesis()).Times(2)
+	require.NoError(t, layers.SetAggregatedHash(b.cdb, layerID.Sub(1), types.RandomHash()))
+
+	dry, err := b.BuildDryRun(context.Background(), layerID, b.edSigner)
+	require.NoError(t, err)
+	require.Equal(t, b.edSigner.NodeID(), dry.SmesherID)
+	require.Equal(t, types.EmptyEdSignature, dry.Signature)
+	require.Equal(t, types.EmptyEdSignature, dry.Ballot.Signature)
+	require.Equal(t, txs, dry.TxIDs)
+	require.Equal(t, []types.ATXID(ee.ActiveSet), dry.ActiveSet)
+	require.Equal(t, opinion.Votes, dry.Votes)
+
+	// dry run is not persisted and doesn't claim the reference ballot
+	requirePending(t, b, 0)
+	_, err = refballots.Get(b.cdb, layerID.GetEpoch(), b.edSigner.NodeID())
+	require.ErrorIs(t, err, sql.ErrNotFound)
+	select {
+	case ev := <-sub.Out():
+		require.FailNow(t, "unexpected builder event", "%+v", ev)
+	default:
+	}
+
+	var published types.Proposal
+	b.mPubSub.EXPECT().Publish(gomock.Any(), pubsub.ProposalProtocol, gomock.Any()).DoAndReturn(
+		func(_ context.Context, _ string, data []byte) error {
+			return codec.Decode(data, &published)
+		})
+	require.NoError(t, b.handleLayer(context.Background(), layerID))
+	b.Close()
+	require.NoError(t, published.Initialize())
+	require.Equal(t, dry.Ballot.ID(), published.Ballot.ID())
+	require.NotEqual(t, types.EmptyEdSignature, published.Signature)
+	require.NotEqual(t, types.EmptyEdSignature, published.Ballot.Signature)
+	published.Signature = types.EmptyEdSignature
+	published.Ballot.Signature = types.EmptyEdSignature
+	require.Equal(t, dry.InnerProposal, published.InnerProposal)
+}
+
+func TestBuilder_BuildDryRun_Errors(t *testing.T) {
+	b := createBuilder(t)
+	layerID := types.LayerID(layersPerEpoch * 3)
+
+	t.Run("genesis", func(t *testing.T) {
+		_, err := b.BuildDryRun(context.Background(), types.GetEffectiveGenesis(), b.edSigner)
+		require.ErrorIs(t, err, errGenesis)
+	})
+	t.Run("unknown signer", func(t *testing.T) {
+		signer, err := signing.NewEdSigner()
+		require.NoError(t, err)
+		_, err = b.BuildDryRun(context.Background(), layerID, signer)
+		require.ErrorIs(t, err, errUnknownSigner)
+	})
+	t.Run("not eligible", func(t *testing.T) {
+		beacon := types.RandomBeacon()
+		nonce := types.VRFPostIndex(rand.Uint64())
+		b.mSync.EXPECT().IsSynced(gomock.Any()).Return(true)
+		b.mBeacon.EXPECT().GetBeacon(layerID.GetEpoch()).Return(beacon, nil)
+		b.mNonce.EXPECT().VRFNonce(b.edSigner.NodeID(), layerID.GetEpoch()).Return(nonce, nil)
+		b.mOracle.EXPECT().GetProposalEligibility(layerID, beacon, nonce).Return(&EpochEligibility{
+			Atx:    types.RandomATXID(),
+			Proofs: map[types.LayerID][]types.VotingEligibility{layerID.Add(1): genProofs(t, 1)},
+		}, nil)
+		_, err := b.BuildDryRun(context.Background(), layerID, b.edSigner)
+		require.ErrorIs(t, err, errNotEligible)
+	})
+}
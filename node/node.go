@@ -2,8 +2,12 @@
 package node
 
 import (
+	"bytes"
 	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"encoding/hex"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"net/http"
@@ -35,6 +39,7 @@ import (
 	"github.com/spacemeshos/go-spacemesh/activation"
 	"github.com/spacemeshos/go-spacemesh/api/grpcserver"
 	"github.com/spacemeshos/go-spacemesh/beacon"
+	"github.com/spacemeshos/go-spacemesh/beacon/weakcoin"
 	"github.com/spacemeshos/go-spacemesh/blocks"
 	"github.com/spacemeshos/go-spacemesh/bootstrap"
 	"github.com/spacemeshos/go-spacemesh/checkpoint"
@@ -74,6 +79,8 @@ import (
 
 const (
 	edKeyFileName   = "key.bin"
+	keyPasswordEnv  = "SPACEMESH_KEY_PASSWORD"
+	signerTokenEnv  = "SPACEMESH_SIGNER_TOKEN"
 	genesisFileName = "genesis.json"
 	dbFile          = "state.sql"
 )
@@ -146,7 +153,7 @@ func GetCommand() *cobra.Command {
 				defer app.Unlock()
 
 				/* Create or load miner identity */
-				if app.edSgn, err = app.LoadOrCreateEdSigner(); err != nil {
+				if app.edSgn, err = app.loadSigner(ctx); err != nil {
 					return fmt.Errorf("could not retrieve identity: %w", err)
 				}
 
@@ -209,8 +216,37 @@ func GetCommand() *cobra.Command {
 var (
 	appLog  log.Log
 	grpclog grpc_logsettable.SettableLoggerV2
+
+	// versionedTopics are the gossip topics with payloads prefixed by the encoding version
+	// starting from the layer configured by gossip-version-layer.
+	// New encoding is published starting from the upgrade layer, handlers for the previous
+	// versions must be kept registered until the rollout is complete.
+	versionedTopics = map[string][]pubsub.Upgrade{
+		pubsub.ProposalProtocol:             {{Version: 0}},
+		pubsub.AtxProtocol:                  {{Version: 0}},
+		pubsub.BeaconWeakCoinProtocol:       {{Version: 0}},
+		pubsub.BeaconProposalProtocol:       {{Version: 0}},
+		pubsub.BeaconFirstVotesProtocol:     {{Version: 0}},
+		pubsub.BeaconFollowingVotesProtocol: {{Version: 0}},
+	}
 )
 
+// gossipUpgrades returns the versioned topics with the hare topics, that switch to hare.SetHashVersion
+// from the set hash layer. Zero layer disables the switch.
+func gossipUpgrades(setHashLayer types.LayerID) map[string][]pubsub.Upgrade {
+	upgrades := make(map[string][]pubsub.Upgrade, len(versionedTopics)+2)
+	for topic, list := range versionedTopics {
+		upgrades[topic] = list
+	}
+	hareUpgrades := []pubsub.Upgrade{{Version: hare.LegacyVersion}}
+	if setHashLayer != 0 {
+		hareUpgrades = append(hareUpgrades, pubsub.Upgrade{Version: hare.SetHashVersion, Layer: setHashLayer})
+	}
+	upgrades[pubsub.HareProtocol] = hareUpgrades
+	upgrades[pubsub.HareCertificateProtocol] = hareUpgrades
+	return upgrades
+}
+
 func init() {
 	appLog = log.NewNop()
 	grpclog = grpc_logsettable.ReplaceGrpcLoggerV2()
@@ -302,7 +338,7 @@ func New(opts ...Option) *App {
 type App struct {
 	*cobra.Command
 	fileLock           *flock.Flock
-	edSgn              *signing.EdSigner
+	edSgn              signing.Signer
 	Config             *config.Config
 	db                 *sql.Database
 	dbMetrics          *dbmetrics.DBMetricsCollector
@@ -313,6 +349,7 @@ type App struct {
 	proposalListener   *proposals.Handler
 	proposalBuilder    *miner.ProposalBuilder
 	mesh               *mesh.Mesh
+	pruner             *mesh.Pruner
 	cachedDB           *datastore.CachedDB
 	clock              *timesync.NodeClock
 	hare               *hare.Hare
@@ -322,6 +359,7 @@ type App struct {
 	postSetupMgr       *activation.PostSetupManager
 	atxBuilder         *activation.Builder
 	atxHandler         *activation.Handler
+	identities         []*identity
 	txHandler          *txs.TxHandler
 	validator          *activation.Validator
 	edVerifier         *signing.EdVerifier
@@ -449,6 +487,12 @@ func (app *App) Initialize() error {
 		return errors.New("incompatible tortoise hare params")
 	}
 
+	if app.Config.HareSetHashLayer != 0 &&
+		(app.Config.GossipVersionLayer == 0 || app.Config.HareSetHashLayer < app.Config.GossipVersionLayer) {
+		return fmt.Errorf("hare set hash layer %d requires versioned gossip, enabled from layer %d",
+			app.Config.HareSetHashLayer, app.Config.GossipVersionLayer)
+	}
+
 	// override default config in timesync since timesync is using TimeConfigValues
 	timeCfg.TimeConfigValues = app.Config.TIME
 
@@ -473,11 +517,79 @@ func (app *App) getAppInfo() string {
 // Cleanup stops all app services.
 func (app *App) Cleanup(ctx context.Context) {
 	log.Info("app cleanup starting...")
+	if err := app.writeOpinionReport(ctx); err != nil {
+		app.log.With().Error("failed to write tortoise opinion report", log.Err(err))
+	}
+	if err := app.writeTortoiseState(); err != nil {
+		app.log.With().Error("failed to write tortoise state", log.Err(err))
+	}
 	app.stopServices(ctx)
+	if remote, ok := app.edSgn.(*signing.RemoteSigner); ok {
+		remote.Close()
+	}
 	// add any other Cleanup tasks here....
 	log.Info("app cleanup completed")
 }
 
+// writeOpinionReport writes signed opinion report for layers in the tortoise window to the configured file.
+func (app *App) writeOpinionReport(ctx context.Context) error {
+	path := app.Config.Tortoise.OpinionReportFile
+	if path == "" || app.tortoise == nil || app.clock == nil {
+		return nil
+	}
+	to := app.clock.CurrentLayer()
+	from := types.GetEffectiveGenesis().Add(1)
+	if window := app.Config.Tortoise.WindowSize; to > from+types.LayerID(window) {
+		from = to.Sub(window)
+	}
+	if to < from {
+		return nil
+	}
+	report, err := app.tortoise.OpinionReport(from, to)
+	if err != nil {
+		return err
+	}
+	if app.edSgn != nil {
+		if err := report.Sign(ctx, app.edSgn); err != nil {
+			return err
+		}
+	}
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encode opinion report: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return fmt.Errorf("write opinion report to %s: %w", path, err)
+	}
+	app.log.With().Info("wrote tortoise opinion report",
+		log.String("path", path),
+		log.Stringer("from", from),
+		log.Stringer("to", to),
+	)
+	return nil
+}
+
+// writeTortoiseState writes in-memory state of the tortoise to the configured file.
+func (app *App) writeTortoiseState() error {
+	path := app.Config.Tortoise.DumpStateFile
+	if path == "" || app.tortoise == nil {
+		return nil
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("create %s: %w", path, err)
+	}
+	defer f.Close()
+	if err := app.tortoise.DumpState(f); err != nil {
+		return fmt.Errorf("dump tortoise state to %s: %w", path, err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("close %s: %w", path, err)
+	}
+	app.log.With().Info("wrote tortoise state", log.String("path", path))
+	return nil
+}
+
 // Wrap the top-level logger to add context info and set the level for a
 // specific module.
 func (app *App) addLogger(name string, logger log.Log) log.Log {
@@ -558,11 +670,26 @@ func (app *App) initServices(ctx context.Context, poetClients []activation.PoetP
 	state := vm.New(app.db,
 		vm.WithConfig(cfg),
 		vm.WithLogger(app.addLogger(VMLogger, lg)))
+	selection := txs.SelectionPolicy(app.Config.TxsSelection)
+	if err := selection.Validate(); err != nil {
+		return err
+	}
+	if app.Config.TxsLocalLane > 100 {
+		return fmt.Errorf("txs local lane %d is not a percent", app.Config.TxsLocalLane)
+	}
 	app.conState = txs.NewConservativeState(state, app.db,
 		txs.WithCSConfig(txs.CSConfig{
-			BlockGasLimit:     app.Config.BlockGasLimit,
-			NumTXsPerProposal: app.Config.TxsPerProposal,
+			BlockGasLimit:      app.Config.BlockGasLimit,
+			NumTXsPerProposal:  app.Config.TxsPerProposal,
+			Selection:          selection,
+			TXsSizePerProposal: app.Config.TxsSizePerProposal,
+			ReplaceFeeBump:     app.Config.TxsReplaceFeeBump,
+			MempoolMaxAge:      app.Config.TxsMempoolMaxAge,
+			MempoolMaxTXs:      app.Config.TxsMempoolMaxTXs,
+			MempoolMaxBytes:    app.Config.TxsMempoolMaxBytes,
+			LocalLane:          app.Config.TxsLocalLane,
 		}),
+		txs.WithPublisher(app.host),
 		txs.WithLogger(app.addLogger(ConStateLogger, lg)))
 
 	genesisAccts := app.Config.Genesis.ToAccounts()
@@ -577,6 +704,9 @@ func (app *App) initServices(ctx context.Context, poetClients []activation.PoetP
 			}
 		}
 	}
+	if err := app.conState.Restore(); err != nil {
+		return fmt.Errorf("restore mempool: %w", err)
+	}
 
 	goldenATXID := types.ATXID(app.Config.Genesis.GoldenATX())
 	if goldenATXID == types.EmptyATXID {
@@ -589,10 +719,30 @@ func (app *App) initServices(ctx context.Context, poetClients []activation.PoetP
 	}
 
 	vrfVerifier := signing.NewVRFVerifier()
-	beaconProtocol := beacon.New(app.edSgn.NodeID(), app.host, app.edSgn, app.edVerifier, vrfSigner, vrfVerifier, app.cachedDB, app.clock,
+	gossipVersionLayer := types.LayerID(app.Config.GossipVersionLayer)
+	upgrades := gossipUpgrades(types.LayerID(app.Config.HareSetHashLayer))
+	versionedPublisher := pubsub.NewVersionedPublisher(app.host, app.clock, gossipVersionLayer, upgrades)
+
+	signers, err := app.loadSigners()
+	if err != nil {
+		return err
+	}
+	signerVRFs := make([]signing.VRFSigner, len(signers))
+	beaconOpts := []beacon.Opt{
 		beacon.WithContext(ctx),
 		beacon.WithConfig(app.Config.Beacon),
 		beacon.WithLogger(app.addLogger(BeaconLogger, lg)),
+	}
+	for i, signer := range signers {
+		signerVRFs[i], err = signer.VRFSigner()
+		if err != nil {
+			return fmt.Errorf("could not create vrf signer for %s: %w", signer.NodeID().ShortString(), err)
+		}
+		// the identity proposes and votes in the beacon protocol with its own atx
+		beaconOpts = append(beaconOpts, beacon.WithSigner(signer, signerVRFs[i]))
+	}
+	beaconProtocol := beacon.New(app.edSgn.NodeID(), versionedPublisher, app.edSgn, app.edVerifier, vrfSigner, vrfVerifier, app.cachedDB, app.clock,
+		beaconOpts...,
 	)
 
 	trtlCfg := app.Config.Tortoise
@@ -623,11 +773,17 @@ func (app *App) initServices(ctx context.Context, poetClients []activation.PoetP
 		return nil
 	})
 
+	if _, err := mesh.SetupGenesis(app.db, app.Config.Genesis.MeshGenesis()); err != nil {
+		return err
+	}
 	executor := mesh.NewExecutor(app.cachedDB, state, app.conState, app.addLogger(ExecutorLogger, lg))
-	msh, err := mesh.NewMesh(app.cachedDB, app.clock, trtl, executor, app.conState, app.addLogger(MeshLogger, lg))
+	msh, err := mesh.NewMesh(app.cachedDB, app.clock, trtl, executor, app.conState, app.addLogger(MeshLogger, lg),
+		mesh.WithStateRootMeshHash(types.LayerID(app.Config.StateRootMeshHashLayer)),
+	)
 	if err != nil {
 		return fmt.Errorf("failed to create mesh: %w", err)
 	}
+	pruner := mesh.NewPruner(app.db, app.Config.Mesh, app.addLogger(MeshLogger, lg))
 
 	poetCfg := activation.PoetConfig{
 		PhaseShift:  app.Config.POET.PhaseShift,
@@ -657,6 +813,9 @@ func (app *App) initServices(ctx context.Context, poetClients []activation.PoetP
 		return fmt.Errorf("confidence param should be smaller than layers per epoch. eligibility-confidence-param: %d. layers-per-epoch: %d",
 			app.Config.HareEligibility.ConfidenceParam, app.Config.BaseConfig.LayersPerEpoch)
 	}
+	if deadline := app.Config.ProposalBuildDeadline; deadline < 0 || deadline > 1 {
+		return fmt.Errorf("proposal build deadline should be a fraction of the layer duration between 0 and 1: %v", deadline)
+	}
 
 	proposalListener := proposals.NewHandler(app.cachedDB, app.edVerifier, app.host, fetcherWrapped, beaconProtocol, msh, trtl, vrfVerifier, app.clock,
 		proposals.WithLogger(app.addLogger(ProposalListenerLogger, lg)),
@@ -667,7 +826,14 @@ func (app *App) initServices(ctx context.Context, poetClients []activation.PoetP
 			MaxExceptions:          trtlCfg.MaxExceptions,
 			Hdist:                  trtlCfg.Hdist,
 			MinimalActiveSetWeight: trtlCfg.MinimalActiveSetWeight,
+			SignatureDomainLayer:   types.LayerID(app.Config.SignatureDomainLayer),
+			MaxPendingBallots:      app.Config.MaxPendingBallots,
+			PendingBallotsTTL:      app.Config.LayerDuration,
+			MaxProposalBytes:       app.Config.MaxProposalBytes,
+			FetchTimeout:           app.Config.LayerDuration,
+			MaxActiveSetSize:       app.Config.MaxActiveSetSize,
 		}),
+		proposals.WithValidatorOpts(proposals.WithNonceVerifier(app.validator, app.Config.POST.LabelsPerUnit)),
 	)
 
 	blockHandler := blocks.NewHandler(fetcherWrapped, app.db, msh,
@@ -677,6 +843,8 @@ func (app *App) initServices(ctx context.Context, poetClients []activation.PoetP
 		app.conState,
 		app.host.ID(),
 		app.addLogger(TxHandlerLogger, lg),
+		txs.WithMaxNonceGap(app.Config.TxsMaxNonceGap),
+		txs.WithParser(txs.NewLegacyParser(app.conState)),
 	)
 
 	app.hOracle = eligibility.New(beaconProtocol, app.cachedDB, vrfVerifier, vrfSigner, app.Config.LayersPerEpoch, app.Config.HareEligibility, app.addLogger(HareOracleLogger, lg))
@@ -694,10 +862,11 @@ func (app *App) initServices(ctx context.Context, poetClients []activation.PoetP
 	app.certifier = blocks.NewCertifier(app.cachedDB, app.hOracle, app.edSgn.NodeID(), app.edSgn, app.edVerifier, app.host, app.clock, beaconProtocol, trtl,
 		blocks.WithCertContext(ctx),
 		blocks.WithCertConfig(blocks.CertConfig{
-			CommitteeSize:    app.Config.HARE.N,
-			CertifyThreshold: app.Config.HARE.N/2 + 1,
-			LayerBuffer:      app.Config.Tortoise.Zdist,
-			NumLayersToKeep:  app.Config.Tortoise.Zdist * 2,
+			CommitteeSize:        app.Config.HARE.N,
+			CertifyThreshold:     app.Config.HARE.N/2 + 1,
+			LayerBuffer:          app.Config.Tortoise.Zdist,
+			NumLayersToKeep:      app.Config.Tortoise.Zdist * 2,
+			SignatureDomainLayer: types.LayerID(app.Config.SignatureDomainLayer),
 		}),
 		blocks.WithCertifierLogger(app.addLogger(BlockCertLogger, lg)),
 	)
@@ -721,33 +890,31 @@ func (app *App) initServices(ctx context.Context, poetClients []activation.PoetP
 	newSyncer := syncer.NewSyncer(app.cachedDB, app.clock, beaconProtocol, msh, trtl, fetcher, patrol, app.certifier,
 		syncer.WithConfig(syncerConf),
 		syncer.WithLogger(app.addLogger(SyncLogger, lg)),
+		syncer.WithTortoiseRerun(trtl),
+		syncer.WithForkDetector(mesh.NewForkDetector(app.db, app.Config.Mesh, fetcher, app.addLogger(MeshLogger, lg))),
 	)
 	// TODO(dshulyak) this needs to be improved, but dependency graph is a bit complicated
 	beaconProtocol.SetSyncState(newSyncer)
 
-	hareOutputCh := make(chan hare.LayerOutput, app.Config.HARE.LimitConcurrent)
-	app.blockGen = blocks.NewGenerator(app.cachedDB, executor, msh, fetcherWrapped, app.certifier, patrol,
-		blocks.WithContext(ctx),
-		blocks.WithConfig(blocks.Config{
-			LayerSize:          layerSize,
-			LayersPerEpoch:     layersPerEpoch,
-			BlockGasLimit:      app.Config.BlockGasLimit,
-			OptFilterThreshold: app.Config.OptFilterThreshold,
-			GenBlockInterval:   500 * time.Millisecond,
-		}),
-		blocks.WithHareOutputChan(hareOutputCh),
-		blocks.WithGeneratorLogger(app.addLogger(BlockGenLogger, lg)))
+	hareOpts := []hare.Opt{hare.WithVersionedPublisher(versionedPublisher)}
+	for i, signer := range signers {
+		id := &identity{signer: signer}
+		id.hOracle = eligibility.New(beaconProtocol, app.cachedDB, vrfVerifier, signerVRFs[i], app.Config.LayersPerEpoch, app.Config.HareEligibility, app.addLogger(HareOracleLogger, lg))
+		// the identity participates in the consensus processes of the node with its own eligibility
+		hareOpts = append(hareOpts, hare.WithSigner(signer, id.hOracle))
+		app.identities = append(app.identities, id)
+	}
 
 	hareCfg := app.Config.HARE
 	hareCfg.Hdist = app.Config.Tortoise.Hdist
 	app.hare = hare.New(
 		app.cachedDB,
+		fetcher,
 		hareCfg,
 		app.host,
 		app.edSgn,
 		app.edVerifier,
 		app.edSgn.NodeID(),
-		hareOutputCh,
 		newSyncer,
 		beaconProtocol,
 		app.hOracle,
@@ -755,16 +922,30 @@ func (app *App) initServices(ctx context.Context, poetClients []activation.PoetP
 		app.hOracle,
 		app.clock,
 		tortoiseWeakCoin{db: app.cachedDB, tortoise: trtl},
+		trtl,
 		app.addLogger(HareLogger, lg),
+		hareOpts...,
 	)
 
+	app.blockGen = blocks.NewGenerator(app.cachedDB, executor, msh, fetcherWrapped, app.certifier, patrol,
+		blocks.WithContext(ctx),
+		blocks.WithConfig(blocks.Config{
+			LayerSize:          layerSize,
+			LayersPerEpoch:     layersPerEpoch,
+			BlockGasLimit:      app.Config.BlockGasLimit,
+			OptFilterThreshold: app.Config.OptFilterThreshold,
+			GenBlockInterval:   500 * time.Millisecond,
+		}),
+		blocks.WithHareResults(app.clock, app.hare),
+		blocks.WithGeneratorLogger(app.addLogger(BlockGenLogger, lg)))
+
 	proposalBuilder := miner.NewProposalBuilder(
 		ctx,
 		app.clock,
 		app.edSgn,
 		vrfSigner,
 		app.cachedDB,
-		app.host,
+		versionedPublisher,
 		trtl,
 		beaconProtocol,
 		newSyncer,
@@ -774,6 +955,12 @@ func (app *App) initServices(ctx context.Context, poetClients []activation.PoetP
 		miner.WithLayerPerEpoch(layersPerEpoch),
 		miner.WithMinimalActiveSetWeight(app.Config.Tortoise.MinimalActiveSetWeight),
 		miner.WithHdist(app.Config.Tortoise.Hdist),
+		miner.WithSignatureDomainLayer(types.LayerID(app.Config.SignatureDomainLayer)),
+		miner.WithActiveSetGracePeriod(app.Config.ActiveSetGracePeriod),
+		miner.WithMaxProposalBytes(app.Config.MaxProposalBytes),
+		miner.WithBuildDeadline(app.Config.ProposalBuildDeadline),
+		miner.WithBuildLead(app.Config.ProposalBuildLead),
+		miner.WithSigners(signers...),
 		miner.WithLogger(app.addLogger(ProposalBuilderLogger, lg)),
 	)
 
@@ -822,7 +1009,7 @@ func (app *App) initServices(ctx context.Context, poetClients []activation.PoetP
 		app.edSgn,
 		app.cachedDB,
 		atxHandler,
-		app.host,
+		versionedPublisher,
 		nipostBuilder,
 		postSetupMgr,
 		app.clock,
@@ -832,6 +1019,45 @@ func (app *App) initServices(ctx context.Context, poetClients []activation.PoetP
 		activation.WithPoetConfig(poetCfg),
 		activation.WithPoetRetryInterval(app.Config.HARE.WakeupDelta),
 	)
+	for _, id := range app.identities {
+		id.postSetupMgr, err = activation.NewPostSetupManager(
+			id.signer.NodeID(),
+			app.Config.POST,
+			app.addLogger(PostLogger, lg).WithFields(id.signer.NodeID()),
+			app.cachedDB, goldenATXID,
+			app.Config.SMESHING.ProvingOpts,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to create post setup manager for %s: %w", id.signer.NodeID().ShortString(), err)
+		}
+		nipost := activation.NewNIPostBuilder(
+			id.signer.NodeID(),
+			id.postSetupMgr,
+			poetClients,
+			poetDb,
+			id.dataDir(app.Config.SMESHING.Opts.DataDir),
+			app.addLogger(NipostBuilderLogger, lg).WithFields(id.signer.NodeID()),
+			id.signer,
+			poetCfg,
+			app.clock,
+		)
+		id.atxBuilder = activation.NewBuilder(
+			builderConfig,
+			id.signer.NodeID(),
+			id.signer,
+			app.cachedDB,
+			atxHandler,
+			versionedPublisher,
+			nipost,
+			id.postSetupMgr,
+			app.clock,
+			newSyncer,
+			app.addLogger("atxBuilder", lg).WithFields(id.signer.NodeID()),
+			activation.WithContext(ctx),
+			activation.WithPoetConfig(poetCfg),
+			activation.WithPoetRetryInterval(app.Config.HARE.WakeupDelta),
+		)
+	}
 
 	malfeasanceHandler := malfeasance.NewHandler(
 		app.cachedDB,
@@ -841,16 +1067,22 @@ func (app *App) initServices(ctx context.Context, poetClients []activation.PoetP
 		app.edVerifier,
 		trtl,
 	)
+	scorer := app.host.Scorer()
 	fetcher.SetValidators(
-		fetch.ValidatorFunc(pubsub.DropPeerOnValidationReject(atxHandler.HandleAtxData, app.host, lg)),
-		fetch.ValidatorFunc(pubsub.DropPeerOnValidationReject(poetDb.ValidateAndStoreMsg, app.host, lg)),
-		fetch.ValidatorFunc(pubsub.DropPeerOnValidationReject(proposalListener.HandleSyncedBallot, app.host, lg)),
-		fetch.ValidatorFunc(pubsub.DropPeerOnValidationReject(blockHandler.HandleSyncedBlock, app.host, lg)),
-		fetch.ValidatorFunc(pubsub.DropPeerOnValidationReject(proposalListener.HandleSyncedProposal, app.host, lg)),
-		fetch.ValidatorFunc(pubsub.DropPeerOnValidationReject(app.txHandler.HandleBlockTransaction, app.host, lg)),
-		fetch.ValidatorFunc(pubsub.DropPeerOnValidationReject(app.txHandler.HandleProposalTransaction, app.host, lg)),
-		fetch.ValidatorFunc(pubsub.DropPeerOnValidationReject(malfeasanceHandler.HandleSyncedMalfeasanceProof, app.host, lg)),
+		fetch.ValidatorFunc(pubsub.ScoreOnValidationReject(atxHandler.HandleAtxData, scorer)),
+		fetch.ValidatorFunc(pubsub.ScoreOnValidationReject(poetDb.ValidateAndStoreMsg, scorer)),
+		fetch.ValidatorFunc(pubsub.ScoreOnValidationReject(proposalListener.HandleSyncedBallot, scorer)),
+		fetch.ValidatorFunc(pubsub.ScoreOnValidationReject(blockHandler.HandleSyncedBlock, scorer)),
+		fetch.ValidatorFunc(pubsub.ScoreOnValidationReject(proposalListener.HandleSyncedProposal, scorer)),
+		fetch.ValidatorFunc(pubsub.ScoreOnValidationReject(app.txHandler.HandleBlockTransaction, scorer)),
+		fetch.ValidatorFunc(pubsub.ScoreOnValidationReject(app.txHandler.HandleProposalTransaction, scorer)),
+		fetch.ValidatorFunc(pubsub.ScoreOnValidationReject(malfeasanceHandler.HandleSyncedMalfeasanceProof, scorer)),
+		fetch.ValidatorFunc(pubsub.ScoreOnValidationReject(atxHandler.HandleSyncedKeyRotations, scorer)),
+		fetch.ValidatorFunc(pubsub.ScoreOnValidationReject(app.hare.HandleSyncedSet, scorer)),
 	)
+	// vrf proofs of the ballots received in a single batch, such as the ballots of the synced layer,
+	// are verified together
+	fetcher.SetBatchValidator(datastore.BallotDB, fetch.BatchValidatorFunc(proposalListener.HandleSyncedBallots))
 
 	syncHandler := func(_ context.Context, _ p2p.Peer, _ []byte) error {
 		if newSyncer.ListenToGossip() {
@@ -865,20 +1097,39 @@ func (app *App) initServices(ctx context.Context, poetClients []activation.PoetP
 		return errors.New("not synced for gossip")
 	}
 
-	app.host.Register(pubsub.BeaconWeakCoinProtocol, pubsub.ChainGossipHandler(syncHandler, beaconProtocol.HandleWeakCoinProposal))
-	app.host.Register(pubsub.BeaconProposalProtocol, pubsub.ChainGossipHandler(syncHandler, beaconProtocol.HandleProposal))
-	app.host.Register(pubsub.BeaconFirstVotesProtocol, pubsub.ChainGossipHandler(syncHandler, beaconProtocol.HandleFirstVotes))
-	app.host.Register(pubsub.BeaconFollowingVotesProtocol, pubsub.ChainGossipHandler(syncHandler, beaconProtocol.HandleFollowingVotes))
-	app.host.Register(pubsub.ProposalProtocol, pubsub.ChainGossipHandler(syncHandler, proposalListener.HandleProposal))
-	app.host.Register(pubsub.AtxProtocol, pubsub.ChainGossipHandler(atxSyncHandler, atxHandler.HandleGossipAtx))
+	app.host.Register(pubsub.BeaconWeakCoinProtocol, pubsub.ChainGossipHandler(syncHandler,
+		pubsub.VersionedHandler(pubsub.BeaconWeakCoinProtocol, app.clock, gossipVersionLayer, pubsub.VersionedHandlers{0: beaconProtocol.HandleWeakCoinProposal},
+			pubsub.WithLegacyFormat(pubsub.Decodes[weakcoin.Message]()))))
+	app.host.Register(pubsub.BeaconProposalProtocol, pubsub.ChainGossipHandler(syncHandler,
+		pubsub.VersionedHandler(pubsub.BeaconProposalProtocol, app.clock, gossipVersionLayer, pubsub.VersionedHandlers{0: beaconProtocol.HandleProposal},
+			pubsub.WithLegacyFormat(pubsub.Decodes[beacon.ProposalMessage]()))))
+	app.host.Register(pubsub.BeaconFirstVotesProtocol, pubsub.ChainGossipHandler(syncHandler,
+		pubsub.VersionedHandler(pubsub.BeaconFirstVotesProtocol, app.clock, gossipVersionLayer, pubsub.VersionedHandlers{0: beaconProtocol.HandleFirstVotes},
+			pubsub.WithLegacyFormat(pubsub.Decodes[beacon.FirstVotingMessage]()))))
+	app.host.Register(pubsub.BeaconFollowingVotesProtocol, pubsub.ChainGossipHandler(syncHandler,
+		pubsub.VersionedHandler(pubsub.BeaconFollowingVotesProtocol, app.clock, gossipVersionLayer, pubsub.VersionedHandlers{0: beaconProtocol.HandleFollowingVotes},
+			pubsub.WithLegacyFormat(pubsub.Decodes[beacon.FollowingVotingMessage]()))))
+	app.host.Register(pubsub.ProposalProtocol, pubsub.ChainGossipHandler(syncHandler,
+		pubsub.VersionedHandler(pubsub.ProposalProtocol, app.clock, gossipVersionLayer, pubsub.VersionedHandlers{0: proposalListener.HandleProposal},
+			pubsub.WithLegacyFormat(pubsub.Decodes[types.Proposal]()))))
+	app.host.Register(pubsub.AtxProtocol, pubsub.ChainGossipHandler(atxSyncHandler,
+		pubsub.VersionedHandler(pubsub.AtxProtocol, app.clock, gossipVersionLayer, pubsub.VersionedHandlers{0: atxHandler.HandleGossipAtx},
+			pubsub.WithLegacyFormat(pubsub.Decodes[types.ActivationTx]()))))
+	app.host.Register(pubsub.KeyRotationProtocol, pubsub.ChainGossipHandler(atxSyncHandler, atxHandler.HandleGossipKeyRotation))
 	app.host.Register(pubsub.TxProtocol, pubsub.ChainGossipHandler(syncHandler, app.txHandler.HandleGossipTransaction))
-	app.host.Register(pubsub.HareProtocol, pubsub.ChainGossipHandler(syncHandler, app.hare.GetHareMsgHandler()))
+	app.host.Register(pubsub.HareProtocol, pubsub.ChainGossipHandler(syncHandler,
+		pubsub.VersionedHandler(pubsub.HareProtocol, app.clock, gossipVersionLayer, app.hare.MessageHandlers(),
+			pubsub.WithLegacyFormat(pubsub.Decodes[hare.LegacyMessage]()))))
+	app.host.Register(pubsub.HareCertificateProtocol, pubsub.ChainGossipHandler(syncHandler,
+		pubsub.VersionedHandler(pubsub.HareCertificateProtocol, app.clock, gossipVersionLayer, app.hare.CertificateHandlers(),
+			pubsub.WithLegacyFormat(pubsub.Decodes[hare.LegacyCertificateMessage]()))))
 	app.host.Register(pubsub.BlockCertify, pubsub.ChainGossipHandler(syncHandler, app.certifier.HandleCertifyMessage))
 	app.host.Register(pubsub.MalfeasanceProof, pubsub.ChainGossipHandler(atxSyncHandler, malfeasanceHandler.HandleMalfeasanceProof))
 
 	app.proposalBuilder = proposalBuilder
 	app.proposalListener = proposalListener
 	app.mesh = msh
+	app.pruner = pruner
 	app.syncer = newSyncer
 	app.svm = state
 	app.atxBuilder = atxBuilder
@@ -972,6 +1223,9 @@ func (app *App) listenToUpdates(ctx context.Context) {
 				}
 				if len(update.Data.ActiveSet) > 0 {
 					app.hOracle.UpdateActiveSet(update.Data.Epoch, update.Data.ActiveSet)
+					for _, id := range app.identities {
+						id.hOracle.UpdateActiveSet(update.Data.Epoch, update.Data.ActiveSet)
+					}
 				}
 			}
 		}
@@ -989,6 +1243,9 @@ func (app *App) startServices(ctx context.Context) error {
 	})
 	app.syncer.Start()
 	app.beaconProtocol.Start(ctx)
+	app.eg.Go(func() error {
+		return app.pruner.Run(ctx)
+	})
 
 	app.blockGen.Start()
 	app.certifier.Start()
@@ -1004,7 +1261,7 @@ func (app *App) startServices(ctx context.Context) error {
 		if err != nil {
 			app.log.Panic("failed to parse CoinbaseAccount address on start `%s`: %v", app.Config.SMESHING.CoinbaseAccount, err)
 		}
-		if err := app.atxBuilder.StartSmeshing(coinbaseAddr, app.Config.SMESHING.Opts); err != nil {
+		if err := app.smeshing().StartSmeshing(coinbaseAddr, app.Config.SMESHING.Opts); err != nil {
 			log.Panic("failed to start smeshing: %v", err)
 		}
 	} else {
@@ -1024,7 +1281,7 @@ func (app *App) startServices(ctx context.Context) error {
 func (app *App) initService(ctx context.Context, svc grpcserver.Service) (grpcserver.ServiceAPI, error) {
 	switch svc {
 	case grpcserver.Debug:
-		return grpcserver.NewDebugService(app.db, app.conState, app.host, app.hOracle), nil
+		return grpcserver.NewDebugService(app.db, app.conState, app.host, app.hOracle, app.tortoise, app.hare, app.edSgn), nil
 	case grpcserver.GlobalState:
 		return grpcserver.NewGlobalStateService(app.mesh, app.conState), nil
 	case grpcserver.Mesh:
@@ -1032,9 +1289,9 @@ func (app *App) initService(ctx context.Context, svc grpcserver.Service) (grpcse
 	case grpcserver.Node:
 		return grpcserver.NewNodeService(ctx, app.host, app.mesh, app.clock, app.syncer, cmd.Version, cmd.Commit), nil
 	case grpcserver.Admin:
-		return grpcserver.NewAdminService(app.db, app.Config.DataDir(), app.log.WithName("admin")), nil
+		return grpcserver.NewAdminService(app.db, app.Config.DataDir(), app.tortoise, app.host.Scorer(), app.log.WithName("admin")), nil
 	case grpcserver.Smesher:
-		return grpcserver.NewSmesherService(app.postSetupMgr, app.atxBuilder, app.Config.API.SmesherStreamInterval, app.Config.SMESHING.Opts), nil
+		return grpcserver.NewSmesherService(app.postSetupMgr, app.smeshing(), app.proposalBuilder, app.Config.API.SmesherStreamInterval, app.Config.SMESHING.Opts), nil
 	case grpcserver.Transaction:
 		return grpcserver.NewTransactionService(app.db, app.host, app.mesh, app.conState, app.syncer, app.txHandler), nil
 	case grpcserver.Activation:
@@ -1147,6 +1404,12 @@ func (app *App) stopServices(ctx context.Context) {
 		app.hare.Close()
 	}
 
+	for _, id := range app.identities {
+		if id.atxBuilder != nil {
+			_ = id.atxBuilder.StopSmeshing(false)
+		}
+	}
+
 	if app.blockGen != nil {
 		app.blockGen.Stop()
 	}
@@ -1189,17 +1452,134 @@ func (app *App) stopServices(ctx context.Context) {
 	events.CloseEventReporter()
 }
 
+// loadSigner connects to the remote signer if it is configured, otherwise the identity is loaded
+// with LoadOrCreateEdSigner.
+func (app *App) loadSigner(ctx context.Context) (signing.Signer, error) {
+	cfg := app.Config.SMESHING.Identity.RemoteSigner
+	url := app.remoteSignerURL()
+	if len(url) == 0 {
+		return app.LoadOrCreateEdSigner()
+	}
+	token, err := app.signerToken()
+	if err != nil {
+		return nil, err
+	}
+	opts := []signing.RemoteSignerOpt{
+		signing.WithRemoteToken(token),
+		signing.WithRemotePrefix(app.Config.Genesis.GenesisID().Bytes()),
+		signing.WithSignTimeout(cfg.Timeout),
+		signing.WithRemoteLogger(app.log),
+	}
+	if len(cfg.CACert) > 0 || len(cfg.Cert) > 0 {
+		tlsConfig, err := remoteSignerTLS(cfg)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, signing.WithRemoteTLS(tlsConfig))
+	}
+	signer, err := signing.NewRemoteSigner(ctx, url, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to remote signer: %w", err)
+	}
+	log.With().Info("using remote signer", log.String("url", url), signer.PublicKey())
+	return signer, nil
+}
+
+// remoteSignerURL returns smeshing.remote-signer-url, or the deprecated smeshing-identity.remote-signer.url
+// if the former is not set.
+func (app *App) remoteSignerURL() string {
+	if url := app.Config.SMESHING.RemoteSignerURL; len(url) > 0 {
+		return url
+	}
+	url := app.Config.SMESHING.Identity.RemoteSigner.URL
+	if len(url) > 0 {
+		app.log.Warning("smeshing-identity.remote-signer.url is deprecated, use smeshing.remote-signer-url")
+	}
+	return url
+}
+
+// signerToken returns the token for the remote signer from the environment or the token file.
+func (app *App) signerToken() (string, error) {
+	if token, exists := os.LookupEnv(signerTokenEnv); exists {
+		if len(token) == 0 {
+			return "", fmt.Errorf("%s is set to an empty token", signerTokenEnv)
+		}
+		return token, nil
+	}
+	path := app.Config.SMESHING.Identity.RemoteSigner.TokenFile
+	if len(path) == 0 {
+		return "", fmt.Errorf("remote signer at smeshing.remote-signer-url requires a token: set %s or smeshing-identity.remote-signer.token-file", signerTokenEnv)
+	}
+	token, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read token file: %w", err)
+	}
+	token = bytes.TrimRight(token, "\r\n")
+	if len(token) == 0 {
+		return "", fmt.Errorf("token file %s is empty", path)
+	}
+	return string(token), nil
+}
+
+func remoteSignerTLS(cfg config.RemoteSignerConfig) (*tls.Config, error) {
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+	if len(cfg.CACert) > 0 {
+		data, err := os.ReadFile(cfg.CACert)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read remote signer ca cert: %w", err)
+		}
+		tlsConfig.RootCAs = x509.NewCertPool()
+		if !tlsConfig.RootCAs.AppendCertsFromPEM(data) {
+			return nil, fmt.Errorf("no certificates in %s", cfg.CACert)
+		}
+	}
+	if len(cfg.Cert) > 0 {
+		cert, err := tls.LoadX509KeyPair(cfg.Cert, cfg.Key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load remote signer client cert: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	return tlsConfig, nil
+}
+
 // LoadOrCreateEdSigner either loads a previously created ed identity for the node or creates a new one if not exists.
 func (app *App) LoadOrCreateEdSigner() (*signing.EdSigner, error) {
+	if identity := app.Config.SMESHING.Identity; len(identity.MnemonicFile) > 0 {
+		mnemonic, err := app.loadOrCreateMnemonic(identity.MnemonicFile)
+		if err != nil {
+			return nil, err
+		}
+		seed, err := signing.SeedFromMnemonic(mnemonic, identity.Passphrase)
+		if err != nil {
+			return nil, fmt.Errorf("failed to derive seed from mnemonic: %w", err)
+		}
+		edSgn, err := signing.NewEdSignerFromSeed(
+			seed,
+			signing.SmesherPath(identity.Index),
+			signing.WithPrefix(app.Config.Genesis.GenesisID().Bytes()),
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to derive identity from mnemonic: %w", err)
+		}
+		log.With().Info("derived identity from mnemonic", log.Uint32("index", identity.Index), edSgn.PublicKey())
+		return edSgn, nil
+	}
+
 	filename := filepath.Join(app.Config.SMESHING.Opts.DataDir, edKeyFileName)
 	log.Info("Looking for identity file at `%v`", filename)
 
+	password, err := app.keyPassword()
+	if err != nil {
+		return nil, err
+	}
+	prefix := signing.WithPrefix(app.Config.Genesis.GenesisID().Bytes())
+
 	var data []byte
 	if len(app.Config.TestConfig.SmesherKey) > 0 {
 		log.With().Error("!!!TESTING!!! using pre-configured smesher key")
 		data = []byte(app.Config.TestConfig.SmesherKey)
 	} else {
-		var err error
 		data, err = os.ReadFile(filename)
 		if err != nil {
 			if !os.IsNotExist(err) {
@@ -1208,9 +1588,7 @@ func (app *App) LoadOrCreateEdSigner() (*signing.EdSigner, error) {
 
 			log.Info("Identity file not found. Creating new identity...")
 
-			edSgn, err := signing.NewEdSigner(
-				signing.WithPrefix(app.Config.Genesis.GenesisID().Bytes()),
-			)
+			edSgn, err := signing.NewEdSigner(prefix)
 			if err != nil {
 				return nil, fmt.Errorf("failed to create identity: %w", err)
 			}
@@ -1218,7 +1596,12 @@ func (app *App) LoadOrCreateEdSigner() (*signing.EdSigner, error) {
 				return nil, fmt.Errorf("failed to create directory for identity file: %w", err)
 			}
 
-			err = os.WriteFile(filename, []byte(hex.EncodeToString(edSgn.PrivateKey())), 0o600)
+			if password != nil {
+				err = signing.SaveKey(filename, edSgn, password)
+			} else {
+				log.Warning("password for identity file is not configured, private key is stored unencrypted")
+				err = os.WriteFile(filename, []byte(hex.EncodeToString(edSgn.PrivateKey())), 0o600)
+			}
 			if err != nil {
 				return nil, fmt.Errorf("failed to write identity file: %w", err)
 			}
@@ -1226,6 +1609,21 @@ func (app *App) LoadOrCreateEdSigner() (*signing.EdSigner, error) {
 			log.With().Info("created new identity", edSgn.PublicKey())
 			return edSgn, nil
 		}
+		if signing.IsEncryptedKey(data) {
+			if password == nil {
+				return nil, fmt.Errorf("identity file is encrypted: set %s or smeshing-identity.password-file", keyPasswordEnv)
+			}
+			priv, err := signing.DecryptKey(data, password)
+			if err != nil {
+				return nil, fmt.Errorf("failed to decrypt identity file: %w", err)
+			}
+			edSgn, err := signing.NewEdSigner(signing.WithPrivateKey(priv), prefix)
+			if err != nil {
+				return nil, fmt.Errorf("failed to construct identity from data file: %w", err)
+			}
+			log.Info("Loaded existing encrypted identity; public key: %v", edSgn.PublicKey())
+			return edSgn, nil
+		}
 	}
 	dst := make([]byte, signing.PrivateKeySize)
 	n, err := hex.Decode(dst, data)
@@ -1235,18 +1633,171 @@ func (app *App) LoadOrCreateEdSigner() (*signing.EdSigner, error) {
 	if n != signing.PrivateKeySize {
 		return nil, fmt.Errorf("invalid key size %d/%d", n, signing.PrivateKeySize)
 	}
-	edSgn, err := signing.NewEdSigner(
-		signing.WithPrivateKey(dst),
-		signing.WithPrefix(app.Config.Genesis.GenesisID().Bytes()),
-	)
+	edSgn, err := signing.NewEdSigner(signing.WithPrivateKey(dst), prefix)
 	if err != nil {
 		return nil, fmt.Errorf("failed to construct identity from data file: %w", err)
 	}
 
 	log.Info("Loaded existing identity; public key: %v", edSgn.PublicKey())
+	if len(app.Config.TestConfig.SmesherKey) > 0 {
+		return edSgn, nil
+	}
+	log.With().Warning("!!!INSECURE!!! identity file stores private key unencrypted", log.String("path", filename))
+	if app.Config.SMESHING.Identity.EncryptKey && password != nil {
+		if err := signing.SaveKey(filename, edSgn, password); err != nil {
+			return nil, fmt.Errorf("failed to re-encrypt identity file: %w", err)
+		}
+		log.With().Info("encrypted identity file", log.String("path", filename))
+	}
 	return edSgn, nil
 }
 
+// loadOrCreateMnemonic decrypts the mnemonic from the keystore at path, or generates new mnemonic
+// and writes it to the path if the file doesn't exist. Mnemonic is never stored unencrypted.
+func (app *App) loadOrCreateMnemonic(path string) (string, error) {
+	password, err := app.keyPassword()
+	if err != nil {
+		return "", err
+	}
+	if password == nil {
+		return "", fmt.Errorf("mnemonic file requires password: set %s or smeshing-identity.password-file", keyPasswordEnv)
+	}
+	mnemonic, err := signing.LoadMnemonic(path, password)
+	switch {
+	case err == nil:
+		return mnemonic, nil
+	case !errors.Is(err, os.ErrNotExist):
+		return "", fmt.Errorf("failed to load mnemonic: %w", err)
+	}
+	mnemonic, err = signing.NewMnemonic(signing.MnemonicEntropy)
+	if err != nil {
+		return "", fmt.Errorf("failed to generate mnemonic: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return "", fmt.Errorf("failed to create directory for mnemonic file: %w", err)
+	}
+	if err := signing.SaveMnemonic(path, mnemonic, password); err != nil {
+		return "", fmt.Errorf("failed to write mnemonic file: %w", err)
+	}
+	log.With().Info("generated new mnemonic, back up the encrypted file and the password", log.String("path", path))
+	return mnemonic, nil
+}
+
+// identity holds services that operate an additional smesher identity. Every identity has its own
+// PoST data, builds its own ATXs and participates in hare and beacon with its own eligibility.
+type identity struct {
+	signer       signing.Signer
+	hOracle      *eligibility.Oracle
+	postSetupMgr *activation.PostSetupManager
+	atxBuilder   *activation.Builder
+}
+
+// dataDir returns the directory with PoST data of the identity, nested in the node's PoST directory.
+func (id *identity) dataDir(root string) string {
+	return filepath.Join(root, id.signer.NodeID().String())
+}
+
+// smeshingIdentities starts and stops smeshing for the primary identity together with all additional
+// identities of the node, so that smeshing started from the api covers every identity.
+type smeshingIdentities struct {
+	*activation.Builder
+	identities []*identity
+}
+
+// StartSmeshing starts smeshing for the additional identities that are not smeshing yet, and then for
+// the primary identity. PoST data of an additional identity is nested in the data directory from opts.
+func (s smeshingIdentities) StartSmeshing(coinbase types.Address, opts activation.PostSetupOpts) error {
+	for _, id := range s.identities {
+		if id.atxBuilder.Smeshing() {
+			continue
+		}
+		idOpts := opts
+		idOpts.DataDir = id.dataDir(opts.DataDir)
+		if err := id.atxBuilder.StartSmeshing(coinbase, idOpts); err != nil {
+			return fmt.Errorf("start smeshing for %s: %w", id.signer.NodeID().ShortString(), err)
+		}
+	}
+	return s.Builder.StartSmeshing(coinbase, opts)
+}
+
+// StopSmeshing stops smeshing for the primary identity and all additional identities.
+func (s smeshingIdentities) StopSmeshing(deleteFiles bool) error {
+	if err := s.Builder.StopSmeshing(deleteFiles); err != nil {
+		return err
+	}
+	for _, id := range s.identities {
+		if !id.atxBuilder.Smeshing() {
+			continue
+		}
+		if err := id.atxBuilder.StopSmeshing(deleteFiles); err != nil {
+			return fmt.Errorf("stop smeshing for %s: %w", id.signer.NodeID().ShortString(), err)
+		}
+	}
+	return nil
+}
+
+// SetCoinbase sets the coinbase for rewards of all identities.
+func (s smeshingIdentities) SetCoinbase(coinbase types.Address) {
+	s.Builder.SetCoinbase(coinbase)
+	for _, id := range s.identities {
+		id.atxBuilder.SetCoinbase(coinbase)
+	}
+}
+
+// smeshing returns the provider that controls smeshing of all identities of the node.
+func (app *App) smeshing() activation.SmeshingProvider {
+	return smeshingIdentities{Builder: app.atxBuilder, identities: app.identities}
+}
+
+// loadSigners loads additional identities from smeshing-identity.dir.
+func (app *App) loadSigners() ([]signing.Signer, error) {
+	dir := app.Config.SMESHING.Identity.Dir
+	if len(dir) == 0 {
+		return nil, nil
+	}
+	password, err := app.keyPassword()
+	if err != nil {
+		return nil, err
+	}
+	store, err := signing.LoadSignerStore(dir, password, signing.WithPrefix(app.Config.Genesis.GenesisID().Bytes()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load identities: %w", err)
+	}
+	var signers []signing.Signer
+	for _, signer := range store.Signers() {
+		if signer.NodeID() == app.edSgn.NodeID() {
+			continue
+		}
+		log.With().Info("loaded additional identity", signer.NodeID())
+		signers = append(signers, signer)
+	}
+	return signers, nil
+}
+
+// keyPassword returns password for the identity file from the environment or the password file.
+// Returns nil if password is not configured.
+func (app *App) keyPassword() ([]byte, error) {
+	if password, exists := os.LookupEnv(keyPasswordEnv); exists {
+		if len(password) == 0 {
+			return nil, fmt.Errorf("%s is set to an empty password", keyPasswordEnv)
+		}
+		return []byte(password), nil
+	}
+	path := app.Config.SMESHING.Identity.PasswordFile
+	if len(path) == 0 {
+		return nil, nil
+	}
+	password, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read password file: %w", err)
+	}
+	password = bytes.TrimRight(password, "\r\n")
+	if len(password) == 0 {
+		return nil, fmt.Errorf("password file %s is empty", path)
+	}
+	return password, nil
+}
+
 func (app *App) setupDBs(ctx context.Context, lg log.Log, dbPath string) error {
 	if err := os.MkdirAll(dbPath, os.ModePerm); err != nil {
 		return fmt.Errorf("failed to create %s: %w", dbPath, err)
@@ -1262,7 +1813,10 @@ func (app *App) setupDBs(ctx context.Context, lg log.Log, dbPath string) error {
 	if app.Config.CollectMetrics {
 		app.dbMetrics = dbmetrics.NewDBMetricsCollector(ctx, sqlDB, app.addLogger(StateDbLogger, lg), 5*time.Minute)
 	}
-	app.cachedDB = datastore.NewCachedDB(sqlDB, app.addLogger(CachedDBLogger, lg))
+	app.cachedDB = datastore.NewCachedDB(sqlDB, app.addLogger(CachedDBLogger, lg),
+		datastore.WithBallotCacheSize(app.Config.DatabaseBallotCacheSize),
+		datastore.WithBlockCacheSize(app.Config.DatabaseBlockCacheSize),
+	)
 	return nil
 }
 
@@ -8,6 +8,7 @@ import (
 	"encoding/hex"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
 	"os"
 	"path/filepath"
@@ -45,9 +46,11 @@ import (
 	"github.com/spacemeshos/go-spacemesh/events"
 	"github.com/spacemeshos/go-spacemesh/genvm/sdk"
 	"github.com/spacemeshos/go-spacemesh/genvm/sdk/wallet"
+	"github.com/spacemeshos/go-spacemesh/hare"
 	"github.com/spacemeshos/go-spacemesh/log"
 	"github.com/spacemeshos/go-spacemesh/log/logtest"
 	"github.com/spacemeshos/go-spacemesh/p2p"
+	"github.com/spacemeshos/go-spacemesh/p2p/pubsub"
 	"github.com/spacemeshos/go-spacemesh/signing"
 	"github.com/spacemeshos/go-spacemesh/timesync"
 )
@@ -121,6 +124,175 @@ func TestSpacemeshApp_getEdIdentity(t *testing.T) {
 	})
 }
 
+func TestSpacemeshApp_EdIdentityFromMnemonic(t *testing.T) {
+	const mnemonic = "abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about"
+	tempdir := t.TempDir()
+	mnemonicFile := filepath.Join(t.TempDir(), "mnemonic.json")
+	require.NoError(t, signing.SaveMnemonic(mnemonicFile, mnemonic, []byte("password")))
+
+	app := New(WithLog(logtest.New(t)))
+	app.Config.SMESHING.Opts.DataDir = tempdir
+	app.Config.SMESHING.Identity.MnemonicFile = mnemonicFile
+	app.Config.SMESHING.Identity.Index = 1
+
+	_, err := app.LoadOrCreateEdSigner()
+	require.ErrorContains(t, err, keyPasswordEnv)
+
+	t.Setenv(keyPasswordEnv, "password")
+	signer, err := app.LoadOrCreateEdSigner()
+	require.NoError(t, err)
+	seed, err := signing.SeedFromMnemonic(mnemonic, "")
+	require.NoError(t, err)
+	expected, err := signing.NewEdSignerFromSeed(seed, signing.SmesherPath(1))
+	require.NoError(t, err)
+	require.Equal(t, expected.NodeID(), signer.NodeID())
+	require.Equal(t, app.Config.Genesis.GenesisID().Bytes(), signer.Prefix())
+
+	// key file is not created for derived identity.
+	infos, err := os.ReadDir(tempdir)
+	require.NoError(t, err)
+	require.Empty(t, infos)
+
+	t.Setenv(keyPasswordEnv, "wrong")
+	_, err = app.LoadOrCreateEdSigner()
+	require.ErrorIs(t, err, signing.ErrWrongPassword)
+}
+
+func TestSpacemeshApp_GeneratedMnemonic(t *testing.T) {
+	t.Setenv(keyPasswordEnv, "password")
+	mnemonicFile := filepath.Join(t.TempDir(), "identity", "mnemonic.json")
+	app := New(WithLog(logtest.New(t)))
+	app.Config.SMESHING.Opts.DataDir = t.TempDir()
+	app.Config.SMESHING.Identity.MnemonicFile = mnemonicFile
+
+	signer, err := app.LoadOrCreateEdSigner()
+	require.NoError(t, err)
+	data, err := os.ReadFile(mnemonicFile)
+	require.NoError(t, err)
+	require.True(t, signing.IsEncryptedKey(data))
+
+	mnemonic, err := signing.LoadMnemonic(mnemonicFile, []byte("password"))
+	require.NoError(t, err)
+	require.NoError(t, signing.ValidateMnemonic(mnemonic))
+	require.NotContains(t, string(data), mnemonic)
+
+	loaded, err := app.LoadOrCreateEdSigner()
+	require.NoError(t, err)
+	require.Equal(t, signer.NodeID(), loaded.NodeID())
+}
+
+func TestSpacemeshApp_EncryptedEdIdentity(t *testing.T) {
+	tempdir := t.TempDir()
+	app := New(WithLog(logtest.New(t)))
+	app.Config.SMESHING.Opts.DataDir = tempdir
+	keyfile := filepath.Join(tempdir, edKeyFileName)
+
+	t.Run("legacy file is re-encrypted", func(t *testing.T) {
+		signer, err := app.LoadOrCreateEdSigner()
+		require.NoError(t, err)
+		data, err := os.ReadFile(keyfile)
+		require.NoError(t, err)
+		require.False(t, signing.IsEncryptedKey(data))
+
+		t.Setenv(keyPasswordEnv, "password")
+		app.Config.SMESHING.Identity.EncryptKey = true
+		loaded, err := app.LoadOrCreateEdSigner()
+		require.NoError(t, err)
+		require.Equal(t, signer.NodeID(), loaded.NodeID())
+		data, err = os.ReadFile(keyfile)
+		require.NoError(t, err)
+		require.True(t, signing.IsEncryptedKey(data))
+
+		loaded, err = app.LoadOrCreateEdSigner()
+		require.NoError(t, err)
+		require.Equal(t, signer.NodeID(), loaded.NodeID())
+	})
+	t.Run("password from file", func(t *testing.T) {
+		passwordFile := filepath.Join(t.TempDir(), "password")
+		require.NoError(t, os.WriteFile(passwordFile, []byte("password\n"), 0o600))
+		app.Config.SMESHING.Identity.PasswordFile = passwordFile
+		_, err := app.LoadOrCreateEdSigner()
+		require.NoError(t, err)
+
+		require.NoError(t, os.WriteFile(passwordFile, []byte("wrong"), 0o600))
+		_, err = app.LoadOrCreateEdSigner()
+		require.ErrorIs(t, err, signing.ErrWrongPassword)
+	})
+	t.Run("missing password", func(t *testing.T) {
+		app.Config.SMESHING.Identity.PasswordFile = ""
+		_, err := app.LoadOrCreateEdSigner()
+		require.ErrorContains(t, err, "identity file is encrypted")
+	})
+	t.Run("empty password", func(t *testing.T) {
+		t.Setenv(keyPasswordEnv, "")
+		_, err := app.LoadOrCreateEdSigner()
+		require.ErrorContains(t, err, "empty password")
+
+		os.Unsetenv(keyPasswordEnv)
+		passwordFile := filepath.Join(t.TempDir(), "password")
+		require.NoError(t, os.WriteFile(passwordFile, []byte("\n"), 0o600))
+		app.Config.SMESHING.Identity.PasswordFile = passwordFile
+		_, err = app.LoadOrCreateEdSigner()
+		require.ErrorContains(t, err, "is empty")
+		app.Config.SMESHING.Identity.PasswordFile = ""
+	})
+	t.Run("new identity is encrypted", func(t *testing.T) {
+		t.Setenv(keyPasswordEnv, "password")
+		app := New(WithLog(logtest.New(t)))
+		app.Config.SMESHING.Opts.DataDir = t.TempDir()
+		_, err := app.LoadOrCreateEdSigner()
+		require.NoError(t, err)
+		data, err := os.ReadFile(filepath.Join(app.Config.SMESHING.Opts.DataDir, edKeyFileName))
+		require.NoError(t, err)
+		require.True(t, signing.IsEncryptedKey(data))
+	})
+}
+
+func TestSpacemeshApp_RemoteSigner(t *testing.T) {
+	app := New(WithLog(logtest.New(t)))
+	app.Config.SMESHING.Opts.DataDir = t.TempDir()
+	app.Config.SMESHING.Identity.RemoteSigner.Timeout = time.Second
+
+	local, err := signing.NewEdSigner()
+	require.NoError(t, err)
+	service, err := signing.NewSignerService(local.PrivateKey(), "token",
+		signing.WithServicePrefix(app.Config.Genesis.GenesisID().Bytes()),
+	)
+	require.NoError(t, err)
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	server := grpc.NewServer()
+	service.Register(server)
+	go server.Serve(lis)
+	t.Cleanup(server.Stop)
+	app.Config.SMESHING.RemoteSignerURL = lis.Addr().String()
+
+	_, err = app.loadSigner(context.Background())
+	require.ErrorContains(t, err, "requires a token")
+
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	require.NoError(t, os.WriteFile(tokenFile, []byte("token\n"), 0o600))
+	app.Config.SMESHING.Identity.RemoteSigner.TokenFile = tokenFile
+	signer, err := app.loadSigner(context.Background())
+	require.NoError(t, err)
+	t.Cleanup(func() { signer.(*signing.RemoteSigner).Close() })
+	require.Equal(t, local.NodeID(), signer.NodeID())
+	// identity file is not created
+	require.NoFileExists(t, filepath.Join(app.Config.SMESHING.Opts.DataDir, edKeyFileName))
+
+	// deprecated key is used if smeshing.remote-signer-url is not set
+	app.Config.SMESHING.RemoteSignerURL = ""
+	app.Config.SMESHING.Identity.RemoteSigner.URL = lis.Addr().String()
+	signer, err = app.loadSigner(context.Background())
+	require.NoError(t, err)
+	t.Cleanup(func() { signer.(*signing.RemoteSigner).Close() })
+	require.Equal(t, local.NodeID(), signer.NodeID())
+
+	t.Setenv(signerTokenEnv, "wrong")
+	_, err = app.loadSigner(context.Background())
+	require.ErrorIs(t, err, signing.ErrRemoteSigner)
+}
+
 func testLoadOrCreateEdSigner(t *testing.T, data []byte, expect string) {
 	tempdir := t.TempDir()
 	app := New(WithLog(logtest.New(t)))
@@ -693,6 +865,68 @@ func TestInitialize_BadTortoiseParams(t *testing.T) {
 	require.EqualError(t, err, "incompatible tortoise hare params")
 }
 
+func TestInitialize_HareSetHashLayer(t *testing.T) {
+	for _, tc := range []struct {
+		desc            string
+		gossip, setHash uint32
+		err             bool
+	}{
+		{desc: "disabled"},
+		{desc: "after versioned gossip", gossip: 10, setHash: 20},
+		{desc: "with versioned gossip", gossip: 10, setHash: 10},
+		{desc: "before versioned gossip", gossip: 10, setHash: 5, err: true},
+		{desc: "gossip not versioned", setHash: 5, err: true},
+	} {
+		tc := tc
+		t.Run(tc.desc, func(t *testing.T) {
+			conf := config.DefaultTestConfig()
+			conf.DataDirParent = t.TempDir()
+			conf.FileLock = filepath.Join(t.TempDir(), "LOCK")
+			conf.GossipVersionLayer = tc.gossip
+			conf.HareSetHashLayer = tc.setHash
+			app := New(WithLog(logtest.New(t)), WithConfig(&conf))
+			if tc.err {
+				require.Error(t, app.Initialize())
+				return
+			}
+			require.NoError(t, app.Initialize())
+			app.Cleanup(context.Background())
+		})
+	}
+}
+
+func TestGossipUpgrades(t *testing.T) {
+	const gossipLayer, setHashLayer = types.LayerID(10), types.LayerID(20)
+	for _, topic := range []string{pubsub.HareProtocol, pubsub.HareCertificateProtocol} {
+		require.Equal(t, []pubsub.Upgrade{{Version: hare.LegacyVersion}}, gossipUpgrades(0)[topic])
+
+		upgrades := gossipUpgrades(setHashLayer)
+		require.Equal(t, []pubsub.Upgrade{
+			{Version: hare.LegacyVersion},
+			{Version: hare.SetHashVersion, Layer: setHashLayer},
+		}, upgrades[topic])
+		for topic, list := range versionedTopics {
+			require.Equal(t, list, upgrades[topic])
+		}
+
+		pub := pubsub.NewVersionedPublisher(nil, nil, gossipLayer, upgrades)
+		for _, tc := range []struct {
+			lid       types.LayerID
+			version   byte
+			versioned bool
+		}{
+			{lid: gossipLayer - 1, version: hare.LegacyVersion},
+			{lid: gossipLayer, version: hare.LegacyVersion, versioned: true},
+			{lid: setHashLayer - 1, version: hare.LegacyVersion, versioned: true},
+			{lid: setHashLayer, version: hare.SetHashVersion, versioned: true},
+		} {
+			version, versioned := pub.Version(topic, tc.lid)
+			require.Equal(t, tc.version, version, tc.lid)
+			require.Equal(t, tc.versioned, versioned, tc.lid)
+		}
+	}
+}
+
 func TestConfig_Preset(t *testing.T) {
 	const name = "testnet"
 
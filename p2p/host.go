@@ -24,6 +24,7 @@ import (
 
 	"github.com/spacemeshos/go-spacemesh/log"
 	p2pmetrics "github.com/spacemeshos/go-spacemesh/p2p/metrics"
+	"github.com/spacemeshos/go-spacemesh/p2p/peerscore"
 )
 
 // DefaultConfig config.
@@ -37,6 +38,7 @@ func DefaultConfig() Config {
 		GracePeersShutdown: 30 * time.Second,
 		MaxMessageSize:     2 << 20,
 		AcceptQueue:        tptu.AcceptQueueLength,
+		Scoring:            peerscore.DefaultConfig(),
 	}
 }
 
@@ -59,6 +61,8 @@ type Config struct {
 	AdvertiseAddress string   `mapstructure:"advertise-address"`
 	AcceptQueue      int      `mapstructure:"p2p-accept-queue"`
 	Metrics          bool     `mapstructure:"p2p-metrics"`
+
+	Scoring peerscore.Config `mapstructure:"scoring"`
 }
 
 // New initializes libp2p host configured for spacemesh.
@@ -79,6 +83,10 @@ func New(_ context.Context, logger log.Log, cfg Config, prologue []byte, opts ..
 	if err != nil {
 		return nil, fmt.Errorf("can't create peer store: %w", err)
 	}
+	scorer, err := peerscore.New(cfg.Scoring, peerscore.WithLog(logger.WithName("peerscore")))
+	if err != nil {
+		return nil, fmt.Errorf("p2p create scorer: %w", err)
+	}
 	lopts := []libp2p.Option{
 		libp2p.Identity(key),
 		libp2p.ListenAddrStrings(cfg.Listen),
@@ -106,6 +114,7 @@ func New(_ context.Context, logger log.Log, cfg Config, prologue []byte, opts ..
 
 		libp2p.ConnectionManager(cm),
 		libp2p.Peerstore(ps),
+		libp2p.ConnectionGater(scorer),
 		libp2p.BandwidthReporter(p2pmetrics.NewBandwidthCollector()),
 	}
 	if cfg.Metrics {
@@ -126,7 +135,7 @@ func New(_ context.Context, logger log.Log, cfg Config, prologue []byte, opts ..
 	logger.Zap().Info("local node identity", zap.Stringer("identity", h.ID()))
 	// TODO(dshulyak) this is small mess. refactor to avoid this patching
 	// both New and Upgrade should use options.
-	opts = append(opts, WithConfig(cfg), WithLog(logger))
+	opts = append(opts, WithConfig(cfg), WithLog(logger), WithScorer(scorer))
 	return Upgrade(h, opts...)
 }
 
@@ -2,12 +2,16 @@ package p2p
 
 import (
 	"context"
+	"fmt"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/libp2p/go-libp2p/core/peer"
 	"github.com/stretchr/testify/require"
 
 	"github.com/spacemeshos/go-spacemesh/log/logtest"
+	"github.com/spacemeshos/go-spacemesh/p2p/pubsub"
 )
 
 func TestPrologue(t *testing.T) {
@@ -28,3 +32,54 @@ func TestPrologue(t *testing.T) {
 	})
 	require.ErrorContains(t, err, "failed to negotiate security protocol")
 }
+
+func TestBanPeerOnInvalidProposals(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	newHost := func() *Host {
+		cfg := DefaultConfig()
+		cfg.DataDir = t.TempDir()
+		cfg.Listen = "/ip4/127.0.0.1/tcp/0"
+		cfg.DisableNatPort = true
+		cfg.Flood = true
+		cfg.Scoring.BanDuration = 2 * time.Second
+		h, err := New(ctx, logtest.New(t), cfg, []byte("prologue"))
+		require.NoError(t, err)
+		t.Cleanup(func() { h.Stop() })
+		return h
+	}
+	honest, bad := newHost(), newHost()
+	var rejected atomic.Int32
+	honest.Register(pubsub.ProposalProtocol, func(context.Context, peer.ID, []byte) error {
+		rejected.Add(1)
+		return pubsub.ErrValidationReject
+	})
+	bad.Register(pubsub.ProposalProtocol, func(context.Context, peer.ID, []byte) error {
+		return nil
+	})
+	require.NoError(t, bad.Connect(ctx, peer.AddrInfo{ID: honest.ID(), Addrs: honest.Addrs()}))
+	require.Eventually(t, func() bool {
+		return len(honest.ProtocolPeers(pubsub.ProposalProtocol)) == 1 &&
+			len(bad.ProtocolPeers(pubsub.ProposalProtocol)) == 1
+	}, 5*time.Second, 10*time.Millisecond)
+
+	for i := 0; i < 50; i++ {
+		require.NoError(t, bad.Publish(ctx, pubsub.ProposalProtocol, []byte(fmt.Sprintf("invalid proposal %d", i))))
+	}
+	require.Eventually(t, func() bool {
+		return honest.Scorer().Banned(bad.ID()) && len(honest.Network().ConnsToPeer(bad.ID())) == 0
+	}, 5*time.Second, 10*time.Millisecond)
+	// the threshold is crossed by the 10th invalid proposal
+	require.GreaterOrEqual(t, rejected.Load(), int32(10))
+
+	// the banned peer can't reconnect
+	require.Error(t, bad.Connect(ctx, peer.AddrInfo{ID: honest.ID(), Addrs: honest.Addrs()}))
+	require.Empty(t, honest.Network().ConnsToPeer(bad.ID()))
+
+	// the ban expires
+	require.Eventually(t, func() bool {
+		return !honest.Scorer().Banned(bad.ID())
+	}, 5*time.Second, 100*time.Millisecond)
+	require.NoError(t, honest.Connect(ctx, peer.AddrInfo{ID: bad.ID(), Addrs: bad.Addrs()}))
+	require.Len(t, honest.Network().ConnsToPeer(bad.ID()), 1)
+}
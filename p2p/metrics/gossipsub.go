@@ -25,6 +25,14 @@ var (
 		[]string{"protocol", "result"},
 		prometheus.ExponentialBuckets(1_000_000, 4, 10),
 	)
+	// UnknownVersionMessages is incremented for every message on a versioned topic
+	// with a payload version that has no registered handler. Labeled by protocol.
+	UnknownVersionMessages = metrics.NewCounter(
+		"unknown_version_messages",
+		subsystem,
+		"Number of messages with unknown payload version",
+		[]string{"protocol"},
+	)
 	deliveredMessagesBytes = metrics.NewCounter(
 		"delivered_messages_bytes",
 		subsystem,
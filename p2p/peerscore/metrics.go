@@ -0,0 +1,36 @@
+package peerscore
+
+import (
+	"github.com/spacemeshos/go-spacemesh/metrics"
+)
+
+const subsystem = "peerscore"
+
+var (
+	reportedOutcomes = metrics.NewCounter(
+		"reported_outcomes",
+		subsystem,
+		"Number of the outcomes reported by the protocols",
+		[]string{"outcome"},
+	)
+	banCount = metrics.NewCounter(
+		"bans",
+		subsystem,
+		"Number of the peers banned",
+		[]string{},
+	).WithLabelValues()
+	banned = metrics.NewGauge(
+		"banned",
+		subsystem,
+		"Number of the current bans, updated when the bans are pruned",
+		[]string{"kind"},
+	)
+	bannedPeers = banned.WithLabelValues("peer")
+	bannedIPs   = banned.WithLabelValues("ip")
+	scoredPeers = metrics.NewGauge(
+		"scored_peers",
+		subsystem,
+		"Number of the peers with a score",
+		[]string{},
+	).WithLabelValues()
+)
@@ -0,0 +1,394 @@
+// Package peerscore scores peers by the outcomes reported by the protocols and temporarily bans
+// the peers whose score crosses the threshold.
+package peerscore
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/control"
+	"github.com/libp2p/go-libp2p/core/network"
+	"github.com/libp2p/go-libp2p/core/peer"
+	ma "github.com/multiformats/go-multiaddr"
+	manet "github.com/multiformats/go-multiaddr/net"
+
+	"github.com/spacemeshos/go-spacemesh/log"
+)
+
+// pruneInterval is how often the peers with negligible scores and without bans are forgotten.
+const pruneInterval = time.Minute
+
+// Outcome is a misbehavior of the peer observed by a protocol.
+type Outcome uint8
+
+const (
+	// InvalidMessage is a gossip or a fetched message rejected by validation.
+	InvalidMessage Outcome = iota
+	// FailedResponse is a response to the request that can't be decoded.
+	FailedResponse
+	// Timeout is a request that the peer didn't respond to in time.
+	Timeout
+)
+
+func (o Outcome) String() string {
+	switch o {
+	case InvalidMessage:
+		return "invalid_message"
+	case FailedResponse:
+		return "failed_response"
+	case Timeout:
+		return "timeout"
+	}
+	return fmt.Sprintf("outcome(%d)", uint8(o))
+}
+
+// Reporter is implemented by the Scorer, protocols report the outcomes with it.
+type Reporter interface {
+	Report(peer.ID, Outcome)
+}
+
+// Config for the Scorer.
+type Config struct {
+	// Threshold is the score that bans the peer once reached.
+	Threshold float64 `mapstructure:"threshold"`
+	// Weights are added to the score of the peer for every reported outcome.
+	InvalidMessageWeight float64 `mapstructure:"invalid-message-weight"`
+	FailedResponseWeight float64 `mapstructure:"failed-response-weight"`
+	TimeoutWeight        float64 `mapstructure:"timeout-weight"`
+	// DecayHalfLife is the time it takes the score to decay by half.
+	DecayHalfLife time.Duration `mapstructure:"decay-half-life"`
+	// BanDuration is the duration of the first ban of the peer. Every next ban is twice as long,
+	// up to MaxBanDuration. The peer that stays unbanned for MaxBanDuration starts over.
+	BanDuration    time.Duration `mapstructure:"ban-duration"`
+	MaxBanDuration time.Duration `mapstructure:"max-ban-duration"`
+	// BanIP bans the ip addresses the peer is connected from together with its id.
+	BanIP bool `mapstructure:"ban-ip"`
+	// Trusted are the ids of the peers that are never banned.
+	Trusted []string `mapstructure:"trusted"`
+}
+
+// DefaultConfig for the Scorer.
+func DefaultConfig() Config {
+	return Config{
+		Threshold:            100,
+		InvalidMessageWeight: 10,
+		FailedResponseWeight: 5,
+		TimeoutWeight:        1,
+		DecayHalfLife:        10 * time.Minute,
+		BanDuration:          10 * time.Minute,
+		MaxBanDuration:       24 * time.Hour,
+	}
+}
+
+func (c Config) weight(o Outcome) float64 {
+	switch o {
+	case InvalidMessage:
+		return c.InvalidMessageWeight
+	case FailedResponse:
+		return c.FailedResponseWeight
+	case Timeout:
+		return c.TimeoutWeight
+	}
+	return 0
+}
+
+// Opt for configuring the Scorer.
+type Opt func(*Scorer)
+
+// WithLog configures logger for the Scorer.
+func WithLog(logger log.Log) Opt {
+	return func(s *Scorer) {
+		s.logger = logger
+	}
+}
+
+// WithClock configures the time source for the Scorer.
+func WithClock(now func() time.Time) Opt {
+	return func(s *Scorer) {
+		s.now = now
+	}
+}
+
+type record struct {
+	score   float64
+	updated time.Time
+	// bans is the number of the bans in a row, banned is the end of the last one.
+	bans   int
+	banned time.Time
+}
+
+// decay brings the score to the time.
+func (r *record) decay(now time.Time, halfLife time.Duration) {
+	if elapsed := now.Sub(r.updated); elapsed > 0 && halfLife > 0 {
+		r.score *= math.Exp2(-float64(elapsed) / float64(halfLife))
+	}
+	r.updated = now
+}
+
+// PeerState is the score of the peer and its ban.
+type PeerState struct {
+	ID          peer.ID   `json:"id"`
+	Score       float64   `json:"score"`
+	Bans        int       `json:"bans"`
+	BannedUntil time.Time `json:"banned_until,omitempty"`
+	Trusted     bool      `json:"trusted,omitempty"`
+}
+
+// IPBan is the ban of the ip address.
+type IPBan struct {
+	IP          string    `json:"ip"`
+	BannedUntil time.Time `json:"banned_until"`
+}
+
+// Snapshot is the state of the Scorer.
+type Snapshot struct {
+	Peers []PeerState `json:"peers"`
+	IPs   []IPBan     `json:"ips"`
+}
+
+// Scorer keeps the scores of the peers. The peer is disconnected and banned when its score
+// reaches the threshold. It implements connmgr.ConnectionGater to reject the connections
+// of the banned peers.
+type Scorer struct {
+	logger  log.Log
+	cfg     Config
+	now     func() time.Time
+	trusted map[peer.ID]struct{}
+
+	mu      sync.Mutex
+	network network.Network
+	peers   map[peer.ID]*record
+	ips     map[string]time.Time
+	pruned  time.Time
+}
+
+// New creates a Scorer.
+func New(cfg Config, opts ...Opt) (*Scorer, error) {
+	s := &Scorer{
+		logger:  log.NewNop(),
+		cfg:     cfg,
+		now:     time.Now,
+		trusted: make(map[peer.ID]struct{}, len(cfg.Trusted)),
+		peers:   make(map[peer.ID]*record),
+		ips:     make(map[string]time.Time),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	for _, raw := range cfg.Trusted {
+		id, err := peer.Decode(raw)
+		if err != nil {
+			return nil, fmt.Errorf("parse trusted peer %s: %w", raw, err)
+		}
+		s.trusted[id] = struct{}{}
+	}
+	return s, nil
+}
+
+// Attach starts disconnecting the banned peers from the network. The connections are also
+// checked after they are established, so that the bans apply to the hosts that don't use
+// the Scorer as the connection gater.
+func (s *Scorer) Attach(n network.Network) {
+	s.mu.Lock()
+	s.network = n
+	s.mu.Unlock()
+	n.Notify(&network.NotifyBundle{
+		ConnectedF: func(n network.Network, conn network.Conn) {
+			if !s.allowed(conn.RemotePeer(), conn.RemoteMultiaddr()) {
+				go n.ClosePeer(conn.RemotePeer())
+			}
+		},
+	})
+}
+
+// Report adds the weight of the outcome to the score of the peer.
+func (s *Scorer) Report(pid peer.ID, outcome Outcome) {
+	reportedOutcomes.WithLabelValues(outcome.String()).Inc()
+	weight := s.cfg.weight(outcome)
+	if weight == 0 || pid == "" {
+		return
+	}
+	s.mu.Lock()
+	now := s.now()
+	s.prune(now)
+	rec, ok := s.peers[pid]
+	if !ok {
+		rec = &record{updated: now}
+		s.peers[pid] = rec
+	}
+	rec.decay(now, s.cfg.DecayHalfLife)
+	rec.score += weight
+	_, trusted := s.trusted[pid]
+	if trusted || rec.score < s.cfg.Threshold || rec.banned.After(now) {
+		s.mu.Unlock()
+		return
+	}
+	if rec.bans > 0 && now.Sub(rec.banned) > s.cfg.MaxBanDuration {
+		rec.bans = 0
+	}
+	duration := s.cfg.BanDuration << rec.bans
+	if duration > s.cfg.MaxBanDuration || duration < s.cfg.BanDuration {
+		duration = s.cfg.MaxBanDuration
+	}
+	rec.bans++
+	rec.banned = now.Add(duration)
+	rec.score = 0
+	bannedPeers.Inc()
+	var ips []string
+	if s.cfg.BanIP && s.network != nil {
+		for _, conn := range s.network.ConnsToPeer(pid) {
+			if ip, err := manet.ToIP(conn.RemoteMultiaddr()); err == nil {
+				if _, ok := s.ips[ip.String()]; !ok {
+					bannedIPs.Inc()
+				}
+				s.ips[ip.String()] = rec.banned
+				ips = append(ips, ip.String())
+			}
+		}
+	}
+	n := s.network
+	bans := rec.bans
+	s.mu.Unlock()
+
+	banCount.Inc()
+	s.logger.With().Info("banned peer",
+		log.Stringer("peer", pid),
+		log.Stringer("outcome", outcome),
+		log.Int("bans", bans),
+		log.Duration("duration", duration),
+		log.String("ips", strings.Join(ips, ",")),
+	)
+	if n != nil {
+		if err := n.ClosePeer(pid); err != nil {
+			s.logger.With().Debug("failed to close banned peer", log.Stringer("peer", pid), log.Err(err))
+		}
+	}
+}
+
+// prune forgets the peers without bans whose scores decayed below 1, and the expired ip bans.
+func (s *Scorer) prune(now time.Time) {
+	if now.Sub(s.pruned) < pruneInterval {
+		return
+	}
+	s.pruned = now
+	for pid, rec := range s.peers {
+		rec.decay(now, s.cfg.DecayHalfLife)
+		if rec.score < 1 && (rec.bans == 0 || now.Sub(rec.banned) > s.cfg.MaxBanDuration) {
+			delete(s.peers, pid)
+		}
+	}
+	for ip, until := range s.ips {
+		if !until.After(now) {
+			delete(s.ips, ip)
+		}
+	}
+	n := 0
+	for _, rec := range s.peers {
+		if rec.banned.After(now) {
+			n++
+		}
+	}
+	bannedPeers.Set(float64(n))
+	bannedIPs.Set(float64(len(s.ips)))
+	scoredPeers.Set(float64(len(s.peers)))
+}
+
+// Banned returns true if the peer is banned.
+func (s *Scorer) Banned(pid peer.ID) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.banned(pid, s.now())
+}
+
+func (s *Scorer) banned(pid peer.ID, now time.Time) bool {
+	rec, ok := s.peers[pid]
+	return ok && rec.banned.After(now)
+}
+
+func (s *Scorer) bannedIP(addr ma.Multiaddr, now time.Time) bool {
+	if addr == nil || len(s.ips) == 0 {
+		return false
+	}
+	ip, err := manet.ToIP(addr)
+	if err != nil {
+		return false
+	}
+	until, ok := s.ips[ip.String()]
+	return ok && until.After(now)
+}
+
+// allowed returns false if the peer, or the address it is connected from, is banned.
+// the addresses of the trusted peers are not checked.
+func (s *Scorer) allowed(pid peer.ID, addr ma.Multiaddr) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	if s.banned(pid, now) {
+		return false
+	}
+	if _, ok := s.trusted[pid]; ok {
+		return true
+	}
+	return !s.bannedIP(addr, now)
+}
+
+// Snapshot returns the scores of the peers and the current bans.
+func (s *Scorer) Snapshot() Snapshot {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	s.pruned = time.Time{}
+	s.prune(now)
+	rst := Snapshot{Peers: []PeerState{}, IPs: []IPBan{}}
+	for pid, rec := range s.peers {
+		rec.decay(now, s.cfg.DecayHalfLife)
+		state := PeerState{ID: pid, Score: rec.score, Bans: rec.bans}
+		if rec.banned.After(now) {
+			state.BannedUntil = rec.banned
+		}
+		_, state.Trusted = s.trusted[pid]
+		rst.Peers = append(rst.Peers, state)
+	}
+	for ip, until := range s.ips {
+		if until.After(now) {
+			rst.IPs = append(rst.IPs, IPBan{IP: ip, BannedUntil: until})
+		}
+	}
+	sort.Slice(rst.Peers, func(i, j int) bool {
+		return rst.Peers[i].Score > rst.Peers[j].Score
+	})
+	sort.Slice(rst.IPs, func(i, j int) bool {
+		return rst.IPs[i].IP < rst.IPs[j].IP
+	})
+	return rst
+}
+
+// InterceptPeerDial rejects dialing the banned peers.
+func (s *Scorer) InterceptPeerDial(pid peer.ID) bool {
+	return !s.Banned(pid)
+}
+
+// InterceptAddrDial rejects dialing the banned peers and addresses.
+func (s *Scorer) InterceptAddrDial(pid peer.ID, addr ma.Multiaddr) bool {
+	return s.allowed(pid, addr)
+}
+
+// InterceptAccept accepts all connections. The address is checked once the peer is known,
+// so that the trusted peers are accepted from the banned addresses.
+func (s *Scorer) InterceptAccept(network.ConnMultiaddrs) bool {
+	return true
+}
+
+// InterceptSecured rejects the connections of the banned peers and from the banned addresses.
+func (s *Scorer) InterceptSecured(_ network.Direction, pid peer.ID, addrs network.ConnMultiaddrs) bool {
+	return s.allowed(pid, addrs.RemoteMultiaddr())
+}
+
+// InterceptUpgraded accepts all upgraded connections.
+func (s *Scorer) InterceptUpgraded(network.Conn) (bool, control.DisconnectReason) {
+	return true, 0
+}
@@ -0,0 +1,192 @@
+package peerscore
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/network"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/libp2p/go-libp2p/core/test"
+	mocknet "github.com/libp2p/go-libp2p/p2p/net/mock"
+	ma "github.com/multiformats/go-multiaddr"
+	"github.com/stretchr/testify/require"
+
+	"github.com/spacemeshos/go-spacemesh/log/logtest"
+)
+
+type clock struct {
+	now time.Time
+}
+
+func (c *clock) Now() time.Time {
+	return c.now
+}
+
+func (c *clock) advance(d time.Duration) {
+	c.now = c.now.Add(d)
+}
+
+func newTestScorer(tb testing.TB, cfg Config) (*Scorer, *clock) {
+	tb.Helper()
+	c := &clock{now: time.Unix(0, 0)}
+	s, err := New(cfg, WithLog(logtest.New(tb)), WithClock(c.Now))
+	require.NoError(tb, err)
+	return s, c
+}
+
+type connAddrs struct {
+	local, remote ma.Multiaddr
+}
+
+func (c connAddrs) LocalMultiaddr() ma.Multiaddr {
+	return c.local
+}
+
+func (c connAddrs) RemoteMultiaddr() ma.Multiaddr {
+	return c.remote
+}
+
+func TestScorer_Threshold(t *testing.T) {
+	s, _ := newTestScorer(t, DefaultConfig())
+	pid := peer.ID("bad")
+	for i := 0; i < 9; i++ {
+		s.Report(pid, InvalidMessage)
+	}
+	require.False(t, s.Banned(pid))
+	for i := 0; i < 9; i++ {
+		s.Report(pid, Timeout)
+	}
+	require.False(t, s.Banned(pid))
+	require.True(t, s.InterceptPeerDial(pid))
+	s.Report(pid, FailedResponse)
+	require.True(t, s.Banned(pid))
+	require.False(t, s.InterceptPeerDial(pid))
+	require.False(t, s.InterceptSecured(network.DirInbound, pid, connAddrs{}))
+	require.False(t, s.Banned("other"))
+}
+
+func TestScorer_Decay(t *testing.T) {
+	cfg := DefaultConfig()
+	s, c := newTestScorer(t, cfg)
+	pid := peer.ID("peer")
+	for i := 0; i < 8; i++ {
+		s.Report(pid, InvalidMessage)
+	}
+	c.advance(cfg.DecayHalfLife)
+	snapshot := s.Snapshot()
+	require.Len(t, snapshot.Peers, 1)
+	require.InDelta(t, 40, snapshot.Peers[0].Score, 0.001)
+
+	// 40 + 50 is below the threshold
+	for i := 0; i < 5; i++ {
+		s.Report(pid, InvalidMessage)
+	}
+	require.False(t, s.Banned(pid))
+	s.Report(pid, InvalidMessage)
+	require.True(t, s.Banned(pid))
+}
+
+func TestScorer_Disabled(t *testing.T) {
+	s, _ := newTestScorer(t, Config{})
+	pid := peer.ID("peer")
+	for i := 0; i < 100; i++ {
+		s.Report(pid, InvalidMessage)
+	}
+	require.False(t, s.Banned(pid))
+	require.Empty(t, s.Snapshot().Peers)
+}
+
+func TestScorer_BanDuration(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.BanDuration = time.Minute
+	cfg.MaxBanDuration = 5 * time.Minute
+	s, c := newTestScorer(t, cfg)
+	pid := peer.ID("bad")
+	ban := func() {
+		for i := 0; i < 10; i++ {
+			s.Report(pid, InvalidMessage)
+		}
+		require.True(t, s.Banned(pid))
+	}
+	for _, expected := range []time.Duration{
+		time.Minute, 2 * time.Minute, 4 * time.Minute, 5 * time.Minute, 5 * time.Minute,
+	} {
+		ban()
+		c.advance(expected - time.Second)
+		require.True(t, s.Banned(pid), expected)
+		c.advance(time.Second)
+		require.False(t, s.Banned(pid), expected)
+	}
+	require.Equal(t, 5, s.Snapshot().Peers[0].Bans)
+
+	// the ban duration is reset after the peer behaves for the max ban duration
+	c.advance(cfg.MaxBanDuration + time.Second)
+	ban()
+	c.advance(time.Minute)
+	require.False(t, s.Banned(pid))
+	require.Equal(t, 1, s.Snapshot().Peers[0].Bans)
+}
+
+func TestScorer_Trusted(t *testing.T) {
+	trusted := test.RandPeerIDFatal(t)
+	cfg := DefaultConfig()
+	cfg.BanIP = true
+	cfg.Trusted = []string{trusted.String()}
+	s, _ := newTestScorer(t, cfg)
+	for i := 0; i < 100; i++ {
+		s.Report(trusted, InvalidMessage)
+	}
+	require.False(t, s.Banned(trusted))
+	require.True(t, s.InterceptPeerDial(trusted))
+	snapshot := s.Snapshot()
+	require.Len(t, snapshot.Peers, 1)
+	require.True(t, snapshot.Peers[0].Trusted)
+
+	cfg.Trusted = []string{"invalid"}
+	_, err := New(cfg)
+	require.Error(t, err)
+}
+
+func TestScorer_BanIP(t *testing.T) {
+	mesh, err := mocknet.FullMeshConnected(2)
+	require.NoError(t, err)
+	t.Cleanup(func() { mesh.Close() })
+	local, remote := mesh.Hosts()[0], mesh.Hosts()[1]
+
+	cfg := DefaultConfig()
+	cfg.BanIP = true
+	trusted := test.RandPeerIDFatal(t)
+	cfg.Trusted = []string{trusted.String()}
+	s, c := newTestScorer(t, cfg)
+	s.Attach(local.Network())
+	conns := local.Network().ConnsToPeer(remote.ID())
+	require.Len(t, conns, 1)
+	addr := conns[0].RemoteMultiaddr()
+
+	for i := 0; i < 10; i++ {
+		s.Report(remote.ID(), InvalidMessage)
+	}
+	require.True(t, s.Banned(remote.ID()))
+	require.Empty(t, local.Network().ConnsToPeer(remote.ID()))
+	snapshot := s.Snapshot()
+	require.Len(t, snapshot.IPs, 1)
+
+	// other peers are rejected from the banned address, except the trusted ones
+	require.False(t, s.InterceptAddrDial("other", addr))
+	require.False(t, s.InterceptSecured(network.DirInbound, "other", connAddrs{remote: addr}))
+	require.True(t, s.InterceptSecured(network.DirInbound, trusted, connAddrs{remote: addr}))
+	require.True(t, s.InterceptAccept(connAddrs{remote: addr}))
+
+	// the peer is disconnected even if the connection wasn't gated
+	_, err = local.Network().DialPeer(context.Background(), remote.ID())
+	require.NoError(t, err)
+	require.Eventually(t, func() bool {
+		return len(local.Network().ConnsToPeer(remote.ID())) == 0
+	}, time.Second, 10*time.Millisecond)
+
+	c.advance(cfg.BanDuration)
+	require.False(t, s.Banned(remote.ID()))
+	require.True(t, s.InterceptSecured(network.DirInbound, "other", connAddrs{remote: addr}))
+	require.Empty(t, s.Snapshot().IPs)
+}
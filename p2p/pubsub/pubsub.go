@@ -14,6 +14,7 @@ import (
 	"github.com/spacemeshos/go-spacemesh/hash"
 	"github.com/spacemeshos/go-spacemesh/log"
 	p2pmetrics "github.com/spacemeshos/go-spacemesh/p2p/metrics"
+	"github.com/spacemeshos/go-spacemesh/p2p/peerscore"
 )
 
 func init() {
@@ -50,6 +51,8 @@ const (
 
 	// AtxProtocol is the protocol id for ATXs.
 	AtxProtocol = "ax1"
+	// KeyRotationProtocol is the protocol id for key rotation records.
+	KeyRotationProtocol = "kr1"
 	// ProposalProtocol is the protocol id for block proposals.
 	ProposalProtocol = "pp1"
 	// TxProtocol iis the protocol id for transactions.
@@ -57,6 +60,8 @@ const (
 
 	// HareProtocol is the protocol id for hare messages.
 	HareProtocol = "hr1"
+	// HareCertificateProtocol is the protocol id for certificates of the hare output.
+	HareCertificateProtocol = "hc1"
 
 	// BlockCertify is the protocol id for block certification.
 	BlockCertify = "bc1"
@@ -90,20 +95,35 @@ type Config struct {
 	MaxMessageSize int
 }
 
+// Opt for configuring PubSub.
+type Opt func(*PubSub)
+
+// WithScorer reports the peers that send messages rejected by validation to the scorer,
+// instead of dropping them on the first rejected message.
+func WithScorer(scorer peerscore.Reporter) Opt {
+	return func(ps *PubSub) {
+		ps.scorer = scorer
+	}
+}
+
 // New creates PubSub instance.
-func New(ctx context.Context, logger log.Log, h host.Host, cfg Config) (*PubSub, error) {
+func New(ctx context.Context, logger log.Log, h host.Host, cfg Config, opts ...Opt) (*PubSub, error) {
 	// TODO(dshulyak) refactor code to accept options
-	opts := getOptions(cfg)
-	ps, err := pubsub.NewGossipSub(ctx, h, opts...)
+	gsopts := getOptions(cfg)
+	ps, err := pubsub.NewGossipSub(ctx, h, gsopts...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to initialize gossipsub instance: %w", err)
 	}
-	return &PubSub{
+	rst := &PubSub{
 		logger: logger,
 		pubsub: ps,
 		topics: map[string]*pubsub.Topic{},
 		host:   h,
-	}, nil
+	}
+	for _, opt := range opts {
+		opt(rst)
+	}
+	return rst, nil
 }
 
 //go:generate mockgen -package=mocks -destination=./mocks/publisher.go -source=./pubsub.go
@@ -164,6 +184,18 @@ func DropPeerOnValidationReject(handler GossipHandler, h host.Host, logger log.L
 	}
 }
 
+// ScoreOnValidationReject wraps a gossip handler to provide a handler that reports the peer
+// to the scorer if the wrapped handler returns ErrValidationReject.
+func ScoreOnValidationReject(handler GossipHandler, scorer peerscore.Reporter) GossipHandler {
+	return func(ctx context.Context, peer peer.ID, data []byte) error {
+		err := handler(ctx, peer, data)
+		if errors.Is(err, ErrValidationReject) {
+			scorer.Report(peer, peerscore.InvalidMessage)
+		}
+		return err
+	}
+}
+
 func msgID(msg *pubsubpb.Message) string {
 	hasher := hash.New()
 	if msg.Topic != nil {
@@ -0,0 +1,195 @@
+package pubsub
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/spacemeshos/go-scale"
+
+	"github.com/spacemeshos/go-spacemesh/codec"
+	"github.com/spacemeshos/go-spacemesh/common/types"
+	"github.com/spacemeshos/go-spacemesh/p2p/metrics"
+)
+
+var (
+	// ErrUnknownVersion is returned when payload on a versioned topic has a version
+	// without registered handler. It results in ValidationIgnore, as the peer
+	// may simply be running a newer version of the protocol.
+	ErrUnknownVersion = errors.New("unknown payload version")
+	// ErrEmptyPayload is returned when payload on a versioned topic is missing a version.
+	ErrEmptyPayload = fmt.Errorf("%w: empty payload on versioned topic", ErrValidationReject)
+)
+
+// Envelope prepends version to the payload.
+func Envelope(version byte, payload []byte) []byte {
+	buf := make([]byte, 0, len(payload)+1)
+	buf = append(buf, version)
+	return append(buf, payload...)
+}
+
+// Open splits versioned payload into version and the original payload.
+func Open(data []byte) (byte, []byte, error) {
+	if len(data) == 0 {
+		return 0, nil, ErrEmptyPayload
+	}
+	return data[0], data[1:], nil
+}
+
+// VersionedHandlers maps payload version to the handler that decodes payloads of this version.
+type VersionedHandlers map[byte]GossipHandler
+
+// GraceLayers is the number of layers before and after the activation layer in which payloads
+// are accepted both with and without version. A message published right before the activation
+// may be relayed after it, and a peer with the clock slightly ahead starts prefixing payloads
+// before the local clock reaches the activation.
+const GraceLayers = 1
+
+// VersionedOpt configures VersionedHandler.
+type VersionedOpt func(*versionedConfig)
+
+type versionedConfig struct {
+	legacy func([]byte) bool
+}
+
+// WithLegacyFormat sets the probe that reports whether the payload is in the format used before
+// versioning. The probe must not have side effects, it is used within GraceLayers from the activation
+// to pick the handler for the payload.
+func WithLegacyFormat(legacy func([]byte) bool) VersionedOpt {
+	return func(cfg *versionedConfig) {
+		cfg.legacy = legacy
+	}
+}
+
+// Decodes returns a probe for WithLegacyFormat that reports whether the payload is a complete encoding of V.
+func Decodes[V any, H scale.DecodablePtr[V]]() func([]byte) bool {
+	return func(data []byte) bool {
+		var value V
+		return codec.Decode(data, H(&value)) == nil
+	}
+}
+
+// VersionedHandler returns handler for the versioned topic that dispatches the payload
+// without version to the handler registered for that version.
+//
+// Payloads are expected to be versioned starting from the activation layer. Before that
+// they are passed as is to the handler for version 0, so that the node stays compatible
+// with peers that don't prefix payloads yet. Within GraceLayers from the activation both
+// formats are accepted: the format is picked by the probe set with WithLegacyFormat, or by the
+// local clock if there is no probe. Every payload is passed to exactly one handler.
+// Zero activation layer disables versioning.
+func VersionedHandler(protocol string, clock layerClock, activation types.LayerID, handlers VersionedHandlers, opts ...VersionedOpt) GossipHandler {
+	var cfg versionedConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	unversioned := func(ctx context.Context, pid peer.ID, data []byte) error {
+		return handlers[0](ctx, pid, data)
+	}
+	versioned := func(ctx context.Context, pid peer.ID, data []byte) error {
+		version, payload, err := Open(data)
+		if err != nil {
+			return err
+		}
+		handler, exists := handlers[version]
+		if !exists {
+			metrics.UnknownVersionMessages.WithLabelValues(protocol).Inc()
+			return fmt.Errorf("%w: %d on topic %s", ErrUnknownVersion, version, protocol)
+		}
+		return handler(ctx, pid, payload)
+	}
+	return func(ctx context.Context, pid peer.ID, data []byte) error {
+		if activation == 0 {
+			return unversioned(ctx, pid, data)
+		}
+		current := clock.CurrentLayer()
+		switch {
+		case current.Add(GraceLayers).Before(activation):
+			return unversioned(ctx, pid, data)
+		case !current.Before(activation.Add(GraceLayers)):
+			return versioned(ctx, pid, data)
+		}
+		legacy := current.Before(activation)
+		if cfg.legacy != nil {
+			legacy = cfg.legacy(data)
+		}
+		if legacy {
+			return unversioned(ctx, pid, data)
+		}
+		return versioned(ctx, pid, data)
+	}
+}
+
+// Upgrade switches the version used for publishing on a topic starting from the layer.
+type Upgrade struct {
+	Version byte
+	Layer   types.LayerID
+}
+
+type layerClock interface {
+	CurrentLayer() types.LayerID
+}
+
+// VersionedPublisher prepends payload version to messages published on versioned topics
+// starting from the activation layer. Messages on other topics are published as is.
+type VersionedPublisher struct {
+	pub        Publisher
+	clock      layerClock
+	activation types.LayerID
+	upgrades   map[string][]Upgrade
+}
+
+// NewVersionedPublisher creates VersionedPublisher. On every versioned topic the latest version
+// whose upgrade layer has passed is used for publishing. Topics must have an upgrade for the first layer.
+// Zero activation layer disables versioning.
+func NewVersionedPublisher(pub Publisher, clock layerClock, activation types.LayerID, upgrades map[string][]Upgrade) *VersionedPublisher {
+	sorted := make(map[string][]Upgrade, len(upgrades))
+	for topic, list := range upgrades {
+		list = append([]Upgrade(nil), list...)
+		sort.Slice(list, func(i, j int) bool {
+			return list[i].Layer.Before(list[j].Layer)
+		})
+		sorted[topic] = list
+	}
+	return &VersionedPublisher{pub: pub, clock: clock, activation: activation, upgrades: sorted}
+}
+
+// Version returns version that will be used for publishing on the topic in the layer.
+// The second value is false if payloads on the topic are not versioned in the layer.
+func (p *VersionedPublisher) Version(topic string, lid types.LayerID) (byte, bool) {
+	list, exists := p.upgrades[topic]
+	if !exists || len(list) == 0 || p.activation == 0 || lid.Before(p.activation) {
+		return 0, false
+	}
+	version := list[0].Version
+	for _, upgrade := range list[1:] {
+		if lid.Before(upgrade.Layer) {
+			break
+		}
+		version = upgrade.Version
+	}
+	return version, true
+}
+
+// Publish message to the topic. The message is copied and is not retained after Publish returns,
+// so it may be encoded into a pooled buffer (see codec.EncodeToPool).
+func (p *VersionedPublisher) Publish(ctx context.Context, topic string, msg []byte) error {
+	return p.PublishLayer(ctx, topic, p.clock.CurrentLayer(), msg)
+}
+
+// PublishLayer publishes the message with the version used on the topic in the layer the message
+// belongs to, for messages whose encoding is defined by their layer rather than the current one.
+// Whether the version is prepended is still decided by the current layer, as receivers do.
+func (p *VersionedPublisher) PublishLayer(ctx context.Context, topic string, lid types.LayerID, msg []byte) error {
+	version, _ := p.Version(topic, lid)
+	if _, versioned := p.Version(topic, p.clock.CurrentLayer()); !versioned {
+		if version != 0 {
+			return fmt.Errorf("%w: %d on topic %s before payloads are versioned", ErrUnknownVersion, version, topic)
+		}
+		// pubsub keeps the message for gossip
+		return p.pub.Publish(ctx, topic, append([]byte(nil), msg...))
+	}
+	return p.pub.Publish(ctx, topic, Envelope(version, msg))
+}
@@ -0,0 +1,347 @@
+package pubsub
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/stretchr/testify/require"
+
+	"github.com/spacemeshos/go-spacemesh/codec"
+	"github.com/spacemeshos/go-spacemesh/common/types"
+)
+
+type testClock struct {
+	layer types.LayerID
+}
+
+func (c *testClock) CurrentLayer() types.LayerID {
+	return c.layer
+}
+
+// testNetwork delivers published messages synchronously to every registered handler.
+type testNetwork struct {
+	handlers []GossipHandler
+	results  []error
+}
+
+func (n *testNetwork) Publish(ctx context.Context, _ string, msg []byte) error {
+	for _, h := range n.handlers {
+		n.results = append(n.results, h(ctx, "", msg))
+	}
+	return nil
+}
+
+func TestEnvelope(t *testing.T) {
+	data := Envelope(7, []byte{1, 2, 3})
+	require.Equal(t, []byte{7, 1, 2, 3}, data)
+	version, payload, err := Open(data)
+	require.NoError(t, err)
+	require.Equal(t, byte(7), version)
+	require.Equal(t, []byte{1, 2, 3}, payload)
+
+	_, _, err = Open(nil)
+	require.ErrorIs(t, err, ErrValidationReject)
+}
+
+func TestVersionedPublisher_Version(t *testing.T) {
+	const (
+		topic      = "test"
+		activation = types.LayerID(5)
+	)
+	pub := NewVersionedPublisher(nil, &testClock{}, activation, map[string][]Upgrade{
+		topic: {{Version: 2, Layer: types.LayerID(20)}, {Version: 0}, {Version: 1, Layer: types.LayerID(10)}},
+	})
+	for _, tc := range []struct {
+		layer     types.LayerID
+		version   byte
+		versioned bool
+	}{
+		{0, 0, false}, {4, 0, false}, {5, 0, true}, {9, 0, true},
+		{10, 1, true}, {19, 1, true}, {20, 2, true}, {100, 2, true},
+	} {
+		version, versioned := pub.Version(topic, tc.layer)
+		require.Equal(t, tc.versioned, versioned, "layer %d", tc.layer)
+		require.Equal(t, tc.version, version, "layer %d", tc.layer)
+	}
+	_, versioned := pub.Version("other", 100)
+	require.False(t, versioned)
+
+	disabled := NewVersionedPublisher(nil, &testClock{}, 0, map[string][]Upgrade{topic: {{Version: 1}}})
+	_, versioned = disabled.Version(topic, 100)
+	require.False(t, versioned)
+}
+
+func TestVersionedRollout(t *testing.T) {
+	const (
+		topic      = "test"
+		activation = types.LayerID(5)
+		upgrade    = types.LayerID(10)
+	)
+	var (
+		receivedUnversioned, receivedOld, receivedNew [][]byte
+		decodeV0                                      = func(received *[][]byte) GossipHandler {
+			return func(_ context.Context, _ peer.ID, msg []byte) error {
+				*received = append(*received, append([]byte("v0:"), msg...))
+				return nil
+			}
+		}
+		decodeV1 = func(_ context.Context, _ peer.ID, msg []byte) error {
+			receivedNew = append(receivedNew, append([]byte("v1:"), msg...))
+			return nil
+		}
+	)
+	clock := &testClock{}
+	network := &testNetwork{handlers: []GossipHandler{
+		// node that is not aware of versioning.
+		func(_ context.Context, _ peer.ID, msg []byte) error {
+			receivedUnversioned = append(receivedUnversioned, msg)
+			return nil
+		},
+		// node that doesn't support v1 yet.
+		VersionedHandler(topic, clock, activation, VersionedHandlers{0: decodeV0(&receivedOld)}),
+		// node that supports both versions during rollout.
+		VersionedHandler(topic, clock, activation, VersionedHandlers{0: decodeV0(&receivedNew), 1: decodeV1}),
+	}}
+	upgrades := map[string][]Upgrade{
+		topic: {{Version: 0}, {Version: 1, Layer: upgrade}},
+	}
+	oldPub := NewVersionedPublisher(network, clock, activation, map[string][]Upgrade{topic: {{Version: 0}}})
+	newPub := NewVersionedPublisher(network, clock, activation, upgrades)
+
+	// payloads are not prefixed before activation, and are compatible with nodes unaware of versioning.
+	clock.layer = activation - 1
+	require.NoError(t, oldPub.Publish(context.Background(), topic, []byte("a")))
+	require.NoError(t, newPub.Publish(context.Background(), topic, []byte("b")))
+	for _, err := range network.results {
+		require.NoError(t, err)
+	}
+	require.Equal(t, [][]byte{[]byte("a"), []byte("b")}, receivedUnversioned)
+	require.Equal(t, [][]byte{[]byte("v0:a"), []byte("v0:b")}, receivedOld)
+	require.Equal(t, [][]byte{[]byte("v0:a"), []byte("v0:b")}, receivedNew)
+
+	network.handlers = network.handlers[1:]
+	network.results = nil
+	clock.layer = upgrade - 1
+	require.NoError(t, oldPub.Publish(context.Background(), topic, []byte("c")))
+	require.NoError(t, newPub.Publish(context.Background(), topic, []byte("d")))
+	for _, err := range network.results {
+		require.NoError(t, err)
+	}
+	require.Equal(t, [][]byte{[]byte("v0:a"), []byte("v0:b"), []byte("v0:c"), []byte("v0:d")}, receivedOld)
+	require.Equal(t, [][]byte{[]byte("v0:a"), []byte("v0:b"), []byte("v0:c"), []byte("v0:d")}, receivedNew)
+
+	network.results = nil
+	clock.layer = upgrade
+	require.NoError(t, oldPub.Publish(context.Background(), topic, []byte("e")))
+	require.NoError(t, newPub.Publish(context.Background(), topic, []byte("f")))
+	require.Len(t, network.results, 4)
+	require.NoError(t, network.results[0])
+	require.NoError(t, network.results[1])
+	// old node ignores messages with the new version, without penalizing the peer.
+	require.ErrorIs(t, network.results[2], ErrUnknownVersion)
+	require.NotErrorIs(t, network.results[2], ErrValidationReject)
+	require.NoError(t, network.results[3])
+	require.Equal(t, [][]byte{
+		[]byte("v0:a"), []byte("v0:b"), []byte("v0:c"), []byte("v0:d"), []byte("v0:e"),
+	}, receivedOld)
+	require.Equal(t, [][]byte{
+		[]byte("v0:a"), []byte("v0:b"), []byte("v0:c"), []byte("v0:d"), []byte("v0:e"), []byte("v1:f"),
+	}, receivedNew)
+}
+
+func TestVersionedHandler_GraceWindow(t *testing.T) {
+	const activation = types.LayerID(10)
+	decoder := func(prefix string) GossipHandler {
+		return func(_ context.Context, _ peer.ID, msg []byte) error {
+			if !bytes.HasPrefix(msg, []byte(prefix)) {
+				return fmt.Errorf("%w: malformed", ErrValidationReject)
+			}
+			return nil
+		}
+	}
+	clock := &testClock{}
+	handlers := VersionedHandlers{
+		0: decoder("\x00v0"),
+		1: decoder("v1"),
+	}
+	var (
+		// the first byte of the legacy payload happens to be the same as version 0
+		unversioned = []byte("\x00v0:a")
+		v0          = Envelope(0, unversioned)
+		v1          = Envelope(1, []byte("v1:b"))
+		unknown     = Envelope(2, []byte("v2:c"))
+	)
+	t.Run("legacy format", func(t *testing.T) {
+		handler := VersionedHandler("test", clock, activation, handlers,
+			WithLegacyFormat(func(msg []byte) bool { return bytes.HasPrefix(msg, []byte("\x00v0")) }),
+		)
+		for _, tc := range []struct {
+			layer                     types.LayerID
+			unversioned, v0, v1, ver2 error
+		}{
+			{layer: activation - 2, v0: ErrValidationReject, v1: ErrValidationReject, ver2: ErrValidationReject},
+			{layer: activation - 1, ver2: ErrUnknownVersion},
+			{layer: activation, ver2: ErrUnknownVersion},
+			{layer: activation + 1, unversioned: ErrValidationReject, ver2: ErrUnknownVersion},
+		} {
+			clock.layer = tc.layer
+			for _, msg := range []struct {
+				data []byte
+				err  error
+			}{{unversioned, tc.unversioned}, {v0, tc.v0}, {v1, tc.v1}, {unknown, tc.ver2}} {
+				err := handler(context.Background(), "", msg.data)
+				if msg.err == nil {
+					require.NoError(t, err, "layer %d payload %q", tc.layer, msg.data)
+				} else {
+					require.ErrorIs(t, err, msg.err, "layer %d payload %q", tc.layer, msg.data)
+				}
+			}
+		}
+	})
+	t.Run("local clock", func(t *testing.T) {
+		handler := VersionedHandler("test", clock, activation, handlers)
+		clock.layer = activation - 1
+		require.NoError(t, handler(context.Background(), "", unversioned))
+		require.ErrorIs(t, handler(context.Background(), "", v1), ErrValidationReject)
+		clock.layer = activation
+		require.NoError(t, handler(context.Background(), "", v1))
+		require.ErrorIs(t, handler(context.Background(), "", unversioned), ErrValidationReject)
+	})
+}
+
+func TestVersionedHandler_ExactlyOnce(t *testing.T) {
+	const activation = types.LayerID(10)
+	var calls int
+	// both handlers accept any payload, and have side effects
+	handler := func(_ context.Context, _ peer.ID, _ []byte) error {
+		calls++
+		return nil
+	}
+	rejecting := func(_ context.Context, _ peer.ID, _ []byte) error {
+		calls++
+		return ErrValidationReject
+	}
+	// legacy payload can be read as a payload with version 0
+	legacy := []byte("\x00legacy")
+	clock := &testClock{}
+	for _, handlers := range []VersionedHandlers{
+		{0: handler, 1: handler},
+		{0: rejecting, 1: rejecting},
+	} {
+		for _, opts := range [][]VersionedOpt{
+			nil,
+			{WithLegacyFormat(func(msg []byte) bool { return bytes.Equal(msg, legacy) })},
+		} {
+			versioned := VersionedHandler("test", clock, activation, handlers, opts...)
+			for lid := activation - 2; lid <= activation+1; lid++ {
+				clock.layer = lid
+				for _, msg := range [][]byte{legacy, Envelope(0, legacy), Envelope(1, []byte("new"))} {
+					calls = 0
+					versioned(context.Background(), "", msg)
+					require.Equal(t, 1, calls, "layer %d payload %q", lid, msg)
+				}
+			}
+		}
+	}
+}
+
+func TestVersionedPublisher_PublishLayer(t *testing.T) {
+	const (
+		topic      = "test"
+		activation = types.LayerID(5)
+		upgrade    = types.LayerID(10)
+	)
+	var received [][]byte
+	network := &testNetwork{handlers: []GossipHandler{
+		func(_ context.Context, _ peer.ID, msg []byte) error {
+			received = append(received, msg)
+			return nil
+		},
+	}}
+	clock := &testClock{}
+	pub := NewVersionedPublisher(network, clock, activation, map[string][]Upgrade{
+		topic: {{Version: 0}, {Version: 1, Layer: upgrade}},
+	})
+
+	// the version is defined by the layer of the message, the prefix by the current layer.
+	clock.layer = activation - 1
+	require.NoError(t, pub.PublishLayer(context.Background(), topic, activation-2, []byte("a")))
+	require.ErrorIs(t, pub.PublishLayer(context.Background(), topic, upgrade, []byte("b")), ErrUnknownVersion)
+	clock.layer = upgrade
+	require.NoError(t, pub.PublishLayer(context.Background(), topic, upgrade-1, []byte("c")))
+	require.NoError(t, pub.PublishLayer(context.Background(), topic, upgrade, []byte("d")))
+	require.NoError(t, pub.Publish(context.Background(), topic, []byte("e")))
+	require.Equal(t, [][]byte{[]byte("a"), []byte("\x00c"), []byte("\x01d"), []byte("\x01e")}, received)
+}
+
+func TestVersionedPublisher_NotVersioned(t *testing.T) {
+	network := &testNetwork{handlers: []GossipHandler{
+		func(_ context.Context, _ peer.ID, msg []byte) error {
+			require.Equal(t, []byte("raw"), msg)
+			return nil
+		},
+	}}
+	pub := NewVersionedPublisher(network, &testClock{}, 1, nil)
+	require.NoError(t, pub.Publish(context.Background(), "test", []byte("raw")))
+	require.Len(t, network.results, 1)
+}
+
+func TestVersionedPublisher_NotRetained(t *testing.T) {
+	const topic = "test"
+	var received []byte
+	network := &testNetwork{handlers: []GossipHandler{
+		func(_ context.Context, _ peer.ID, msg []byte) error {
+			received = msg
+			return nil
+		},
+	}}
+	clock := &testClock{}
+	pub := NewVersionedPublisher(network, clock, 1, map[string][]Upgrade{topic: {{Version: 1}}})
+	msg := []byte("msg")
+	require.NoError(t, pub.Publish(context.Background(), topic, msg))
+	copy(msg, "xxx")
+	require.Equal(t, []byte("msg"), received)
+
+	clock.layer = 1
+	msg = []byte("msg")
+	require.NoError(t, pub.Publish(context.Background(), topic, msg))
+	copy(msg, "xxx")
+	require.Equal(t, []byte("\x01msg"), received)
+}
+
+func BenchmarkVersionedPublisher_Publish(b *testing.B) {
+	const topic = "test"
+	proposal := &types.Proposal{InnerProposal: types.InnerProposal{TxIDs: make([]types.TransactionID, 200)}}
+	for i := range proposal.TxIDs {
+		proposal.TxIDs[i] = types.RandomTransactionID()
+	}
+	pub := NewVersionedPublisher(&testNetwork{}, &testClock{layer: 1}, 1, map[string][]Upgrade{topic: {{Version: 0}}})
+	b.Run("encode", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			data, err := codec.Encode(proposal)
+			if err != nil {
+				b.Fatal(err)
+			}
+			if err := pub.Publish(context.Background(), topic, data); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("pool", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			buf, release, err := codec.EncodeToPool(proposal)
+			if err != nil {
+				b.Fatal(err)
+			}
+			if err := pub.Publish(context.Background(), topic, buf.Bytes()); err != nil {
+				b.Fatal(err)
+			}
+			release()
+		}
+	})
+}
@@ -13,6 +13,7 @@ import (
 
 	"github.com/spacemeshos/go-spacemesh/log"
 	"github.com/spacemeshos/go-spacemesh/p2p/metrics"
+	"github.com/spacemeshos/go-spacemesh/p2p/peerscore"
 )
 
 // PubSub is a spacemesh-specific wrapper around gossip protocol.
@@ -20,6 +21,7 @@ type PubSub struct {
 	logger log.Log
 	pubsub *pubsub.PubSub
 	host   host.Host
+	scorer peerscore.Reporter
 
 	mu     sync.RWMutex
 	topics map[string]*pubsub.Topic
@@ -32,8 +34,12 @@ func (ps *PubSub) Register(topic string, handler GossipHandler) {
 	if _, exist := ps.topics[topic]; exist {
 		ps.logger.Panic("already registered a topic %s", topic)
 	}
-	// Drop peers on ValidationRejectErr
-	handler = DropPeerOnValidationReject(handler, ps.host, ps.logger)
+	// Score or drop peers on ValidationRejectErr
+	if ps.scorer != nil {
+		handler = ScoreOnValidationReject(handler, ps.scorer)
+	} else {
+		handler = DropPeerOnValidationReject(handler, ps.host, ps.logger)
+	}
 	ps.pubsub.RegisterTopicValidator(topic, func(ctx context.Context, pid peer.ID, msg *pubsub.Message) pubsub.ValidationResult {
 		start := time.Now()
 		err := handler(log.WithNewRequestID(ctx), pid, msg.Data)
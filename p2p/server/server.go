@@ -7,15 +7,19 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"net"
+	"os"
 	"time"
 
 	"github.com/libp2p/go-libp2p/core/network"
 	"github.com/libp2p/go-libp2p/core/peer"
 	"github.com/libp2p/go-libp2p/core/protocol"
 	"github.com/multiformats/go-varint"
+	"github.com/spacemeshos/go-scale"
 
 	"github.com/spacemeshos/go-spacemesh/codec"
 	"github.com/spacemeshos/go-spacemesh/log"
+	"github.com/spacemeshos/go-spacemesh/p2p/peerscore"
 )
 
 // ErrNotConnected is returned when peer is not connected.
@@ -45,6 +49,14 @@ func WithContext(ctx context.Context) Opt {
 	}
 }
 
+// WithScorer configures the scorer that is reported the peers that don't respond to the requests
+// in time or respond with garbage.
+func WithScorer(scorer peerscore.Reporter) Opt {
+	return func(s *Server) {
+		s.scorer = scorer
+	}
+}
+
 func WithRequestSizeLimit(limit int) Opt {
 	return func(s *Server) {
 		s.requestLimit = limit
@@ -54,6 +66,32 @@ func WithRequestSizeLimit(limit int) Opt {
 // Handler is the handler to be defined by the application.
 type Handler func(context.Context, []byte) ([]byte, error)
 
+// StreamHandler is the handler that returns a value that is encoded directly into the stream,
+// without materializing the response in memory. Suitable for large responses.
+// Handler must know the size of the encoded value, as it is written before the value.
+type StreamHandler func(context.Context, []byte) (codec.SizedEncodable, error)
+
+// rawData is encoded as is, without length prefix.
+type rawData []byte
+
+func (d rawData) EncodeScale(e *scale.Encoder) (int, error) {
+	return scale.EncodeByteArray(e, d)
+}
+
+func (d rawData) EncodedSize() int {
+	return len(d)
+}
+
+func toStreamHandler(handler Handler) StreamHandler {
+	return func(ctx context.Context, req []byte) (codec.SizedEncodable, error) {
+		buf, err := handler(ctx, req)
+		if err != nil {
+			return nil, err
+		}
+		return rawData(buf), nil
+	}
+}
+
 //go:generate scalegen -types Response
 
 // Response is a server response.
@@ -75,9 +113,10 @@ type Host interface {
 type Server struct {
 	logger       log.Log
 	protocol     string
-	handler      Handler
+	handler      StreamHandler
 	timeout      time.Duration
 	requestLimit int
+	scorer       peerscore.Reporter
 
 	h Host
 
@@ -86,6 +125,12 @@ type Server struct {
 
 // New server for the handler.
 func New(h Host, proto string, handler Handler, opts ...Opt) *Server {
+	return NewStreaming(h, proto, toStreamHandler(handler), opts...)
+}
+
+// NewStreaming creates a server for the handler that encodes responses directly into the stream.
+// Responses are compatible with the ones written by the server created with New.
+func NewStreaming(h Host, proto string, handler StreamHandler, opts ...Opt) *Server {
 	srv := &Server{
 		ctx:          context.Background(),
 		logger:       log.NewNop(),
@@ -125,26 +170,73 @@ func (s *Server) streamHandler(stream network.Stream) {
 		return
 	}
 	start := time.Now()
-	buf, err = s.handler(log.WithNewRequestID(s.ctx), buf)
+	data, err := s.handler(log.WithNewRequestID(s.ctx), buf)
 	s.logger.With().Debug("protocol handler execution time",
 		log.String("protocol", s.protocol),
 		log.Duration("duration", time.Since(start)),
 	)
-	var resp Response
+
+	var resp codec.Encodable
 	if err != nil {
-		resp.Error = err.Error()
+		resp = &Response{Error: err.Error()}
 	} else {
-		resp.Data = buf
+		resp = dataResponse{data}
 	}
-
-	wr := bufio.NewWriter(stream)
-	if _, err := codec.EncodeTo(wr, &resp); err != nil {
+	if err := writeResponse(stream, resp); err != nil {
 		s.logger.With().Warning("failed to write response", log.Err(err))
-		return
 	}
-	if err := wr.Flush(); err != nil {
-		s.logger.With().Warning("failed to flush stream", log.Err(err))
+}
+
+// pooledResponseSize is the largest response that is encoded into a pooled buffer
+// and written to the stream at once. Larger responses are encoded directly into the stream.
+const pooledResponseSize = 1 << 20
+
+func writeResponse(w io.Writer, resp codec.Encodable) error {
+	if sized, ok := resp.(codec.SizedEncodable); ok && sized.EncodedSize() > pooledResponseSize {
+		wr := bufio.NewWriter(w)
+		if _, err := codec.EncodeTo(wr, resp); err != nil {
+			return err
+		}
+		return wr.Flush()
+	}
+	buf, release, err := codec.EncodeToPool(resp)
+	if err != nil {
+		return err
 	}
+	// stream doesn't retain the slice after write returns
+	defer release()
+	_, err = w.Write(buf.Bytes())
+	return err
+}
+
+// dataResponse is encoded to the same bytes as Response with Data set to the encoded value
+// and an empty Error.
+type dataResponse struct {
+	data codec.SizedEncodable
+}
+
+func (r dataResponse) EncodeScale(e *scale.Encoder) (int, error) {
+	size := r.data.EncodedSize()
+	total, err := scale.EncodeCompact32(e, uint32(size))
+	if err != nil {
+		return total, err
+	}
+	n, err := r.data.EncodeScale(e)
+	total += n
+	if err != nil {
+		return total, err
+	}
+	if n != size {
+		return total, fmt.Errorf("response data: declared size %d, written %d", size, n)
+	}
+	n, err = scale.EncodeByteSlice(e, nil)
+	total += n
+	return total, err
+}
+
+func (r dataResponse) EncodedSize() int {
+	size := r.data.EncodedSize()
+	return codec.CompactSize(uint32(size)) + size + codec.CompactSize(0)
 }
 
 // Request sends a binary request to the peer. Request is executed in the background, one of the callbacks
@@ -168,6 +260,9 @@ func (s *Server) Request(ctx context.Context, pid peer.ID, req []byte, resp func
 		defer cancel()
 		stream, err := s.h.NewStream(network.WithNoDial(ctx, "existing connection"), pid, protocol.ID(s.protocol))
 		if err != nil {
+			if errors.Is(err, context.DeadlineExceeded) {
+				s.report(ctx, pid, err)
+			}
 			failure(err)
 			return
 		}
@@ -196,6 +291,7 @@ func (s *Server) Request(ctx context.Context, pid peer.ID, req []byte, resp func
 		rd := bufio.NewReader(stream)
 		var r Response
 		if _, err := codec.DecodeFrom(rd, &r); err != nil {
+			s.report(ctx, pid, err)
 			failure(err)
 			return
 		}
@@ -207,3 +303,17 @@ func (s *Server) Request(ctx context.Context, pid peer.ID, req []byte, resp func
 	}()
 	return nil
 }
+
+// report scores the peer that failed to respond. requests cancelled by the caller are not reported.
+func (s *Server) report(ctx context.Context, pid peer.ID, err error) {
+	if s.scorer == nil || errors.Is(ctx.Err(), context.Canceled) {
+		return
+	}
+	var nerr net.Error
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, os.ErrDeadlineExceeded) ||
+		(errors.As(err, &nerr) && nerr.Timeout()) {
+		s.scorer.Report(pid, peerscore.Timeout)
+		return
+	}
+	s.scorer.Report(pid, peerscore.FailedResponse)
+}
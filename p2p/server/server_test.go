@@ -1,6 +1,7 @@
 package server
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"testing"
@@ -9,6 +10,8 @@ import (
 	mocknet "github.com/libp2p/go-libp2p/p2p/net/mock"
 	"github.com/spacemeshos/go-scale/tester"
 	"github.com/stretchr/testify/require"
+
+	"github.com/spacemeshos/go-spacemesh/codec"
 )
 
 func TestServer(t *testing.T) {
@@ -99,3 +102,36 @@ func FuzzResponseConsistency(f *testing.F) {
 func FuzzResponseSafety(f *testing.F) {
 	tester.FuzzSafety[Response](f)
 }
+
+func TestStreamingResponseCompatible(t *testing.T) {
+	data := &Response{Data: []byte("test data"), Error: "test"}
+	encoded, err := codec.Encode(data)
+	require.NoError(t, err)
+	expected, err := codec.Encode(&Response{Data: encoded})
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	require.NoError(t, writeResponse(&buf, dataResponse{rawData(encoded)}))
+	require.Equal(t, expected, buf.Bytes())
+}
+
+func TestWriteResponse(t *testing.T) {
+	for _, size := range []int{0, 100, pooledResponseSize + 1} {
+		data := make([]byte, size)
+		for i := range data {
+			data[i] = byte(i)
+		}
+		expected, err := codec.Encode(&Response{Data: data})
+		require.NoError(t, err)
+
+		var buf bytes.Buffer
+		require.NoError(t, writeResponse(&buf, dataResponse{rawData(data)}))
+		require.Equal(t, expected, buf.Bytes(), "size %d", size)
+	}
+
+	expected, err := codec.Encode(&Response{Error: "test"})
+	require.NoError(t, err)
+	var buf bytes.Buffer
+	require.NoError(t, writeResponse(&buf, &Response{Error: "test"}))
+	require.Equal(t, expected, buf.Bytes())
+}
@@ -13,6 +13,7 @@ import (
 
 	"github.com/spacemeshos/go-spacemesh/log"
 	"github.com/spacemeshos/go-spacemesh/p2p/peerexchange"
+	"github.com/spacemeshos/go-spacemesh/p2p/peerscore"
 	"github.com/spacemeshos/go-spacemesh/p2p/pubsub"
 )
 
@@ -48,6 +49,14 @@ func WithNodeReporter(reporter func()) Opt {
 	}
 }
 
+// WithScorer sets the scorer for Host. It must be the connection gater of the libp2p host,
+// otherwise the banned peers are disconnected only after the connection is established.
+func WithScorer(scorer *peerscore.Scorer) Opt {
+	return func(fh *Host) {
+		fh.scorer = scorer
+	}
+}
+
 // Host is a conveniency wrapper for all p2p related functionality required to run
 // a full spacemesh node.
 type Host struct {
@@ -65,6 +74,8 @@ type Host struct {
 
 	nodeReporter func()
 
+	scorer *peerscore.Scorer
+
 	discovery *peerexchange.Discovery
 }
 
@@ -98,11 +109,17 @@ func Upgrade(h host.Host, opts ...Opt) (*Host, error) {
 	if err != nil {
 		return nil, fmt.Errorf("check node as bootnode: %w", err)
 	}
+	if fh.scorer == nil {
+		if fh.scorer, err = peerscore.New(cfg.Scoring, peerscore.WithLog(fh.logger.WithName("peerscore"))); err != nil {
+			return nil, fmt.Errorf("failed to initialize scorer: %w", err)
+		}
+	}
+	fh.scorer.Attach(h.Network())
 	if fh.PubSub, err = pubsub.New(fh.ctx, fh.logger, h, pubsub.Config{
 		Flood:          cfg.Flood,
 		IsBootnode:     bootnode,
 		MaxMessageSize: cfg.MaxMessageSize,
-	}); err != nil {
+	}, pubsub.WithScorer(fh.scorer)); err != nil {
 		return nil, fmt.Errorf("failed to initialize pubsub: %w", err)
 	}
 	if fh.discovery, err = peerexchange.New(fh.logger, h, peerexchange.Config{
@@ -133,6 +150,11 @@ func (fh *Host) GetPeers() []Peer {
 	return fh.Host.Network().Peers()
 }
 
+// Scorer returns the scorer of the peers.
+func (fh *Host) Scorer() *peerscore.Scorer {
+	return fh.scorer
+}
+
 // PeerCount returns number of connected peers.
 func (fh *Host) PeerCount() uint64 {
 	return uint64(len(fh.Host.Network().Peers()))
@@ -0,0 +1,144 @@
+package proposals
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/spacemeshos/go-spacemesh/common/types"
+	"github.com/spacemeshos/go-spacemesh/sql/ballots"
+	"github.com/spacemeshos/go-spacemesh/txs"
+)
+
+// fetchCall is the request for a batch of objects that is in flight.
+type fetchCall struct {
+	done chan struct{}
+	err  error
+}
+
+// fetchGroup deduplicates concurrent requests for the same objects.
+// Every object is requested by the first caller that needs it, other callers
+// wait for the result of that request.
+type fetchGroup[T comparable] struct {
+	fetch func(context.Context, []T) error
+
+	mu      sync.Mutex
+	pending map[T]*fetchCall
+}
+
+func newFetchGroup[T comparable](fetch func(context.Context, []T) error) *fetchGroup[T] {
+	return &fetchGroup[T]{
+		fetch:   fetch,
+		pending: map[T]*fetchCall{},
+	}
+}
+
+// Fetch requests objects that are not requested by other callers, and waits
+// until all objects are either fetched or failed to fetch.
+func (g *fetchGroup[T]) Fetch(ctx context.Context, ids []T) error {
+	var (
+		call  = &fetchCall{done: make(chan struct{})}
+		owned []T
+		wait  = map[*fetchCall]struct{}{}
+	)
+	g.mu.Lock()
+	for _, id := range ids {
+		if other, exist := g.pending[id]; exist {
+			if other != call {
+				wait[other] = struct{}{}
+			}
+			continue
+		}
+		g.pending[id] = call
+		owned = append(owned, id)
+	}
+	g.mu.Unlock()
+
+	var err error
+	if len(owned) > 0 {
+		call.err = g.fetch(ctx, owned)
+		g.mu.Lock()
+		for _, id := range owned {
+			delete(g.pending, id)
+		}
+		g.mu.Unlock()
+		close(call.done)
+		err = call.err
+	}
+	for other := range wait {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-other.done:
+			if err == nil {
+				err = other.err
+			}
+		}
+	}
+	return err
+}
+
+// dependencies are the objects referenced by the ballot or proposal that have to be
+// available locally before it is validated.
+type dependencies struct {
+	ballots []types.BallotID
+	atxs    []types.ATXID
+	txs     []types.TransactionID
+}
+
+// collectDependencies returns the objects referenced by the ballot and the transactions of the proposal.
+func collectDependencies(b *types.Ballot, txs []types.TransactionID) dependencies {
+	var deps dependencies
+	if b != nil {
+		if b.Votes.Base != types.EmptyBallotID {
+			deps.ballots = append(deps.ballots, b.Votes.Base)
+		}
+		if b.RefBallot != types.EmptyBallotID {
+			deps.ballots = append(deps.ballots, b.RefBallot)
+		}
+		deps.atxs = []types.ATXID{b.AtxID}
+		if b.EpochData != nil {
+			deps.atxs = append(deps.atxs, b.ActiveSet...)
+		}
+	}
+	deps.txs = txs
+	return deps
+}
+
+// resolveDependencies fetches the dependencies that are not available locally.
+// It fails only if some of them can't be fetched within the configured timeout.
+func (h *Handler) resolveDependencies(ctx context.Context, epoch types.EpochID, deps dependencies) error {
+	if h.cfg.FetchTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, h.cfg.FetchTimeout)
+		defer cancel()
+	}
+	if len(deps.ballots) > 0 {
+		if err := h.ballotsFetch.Fetch(ctx, deps.ballots); err != nil {
+			for _, id := range deps.ballots {
+				if has, herr := ballots.Has(h.cdb, id); herr == nil && !has {
+					return fmt.Errorf("fetch ballots: %w", &missingBallotError{ballot: id, err: err})
+				}
+			}
+			return fmt.Errorf("fetch ballots: %w", err)
+		}
+	}
+	if len(deps.atxs) > 0 {
+		if err := h.atxsFetch.Fetch(ctx, h.decoder.GetMissingActiveSet(epoch, deps.atxs)); err != nil {
+			return fmt.Errorf("fetch referenced ATXs: %w", err)
+		}
+	}
+	if len(deps.txs) > 0 {
+		// only txs that are not available locally are requested from peers
+		_, missing, err := txs.GetBatch(h.cdb, deps.txs)
+		if err != nil {
+			return fmt.Errorf("proposal get local TXs: %w", err)
+		}
+		if len(missing) > 0 {
+			if err := h.txsFetch.Fetch(ctx, missing); err != nil {
+				return fmt.Errorf("proposal get TXs: %w", err)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,196 @@
+package proposals
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/spacemeshos/go-spacemesh/common/types"
+	"github.com/spacemeshos/go-spacemesh/p2p"
+	"github.com/spacemeshos/go-spacemesh/sql/ballots"
+	"github.com/spacemeshos/go-spacemesh/sql/blocks"
+	"github.com/spacemeshos/go-spacemesh/sql/transactions"
+)
+
+func TestFetchGroup(t *testing.T) {
+	t.Run("deduplicated", func(t *testing.T) {
+		var (
+			mu      sync.Mutex
+			fetched = map[int]int{}
+			started = make(chan struct{})
+			release = make(chan struct{})
+		)
+		g := newFetchGroup(func(_ context.Context, ids []int) error {
+			mu.Lock()
+			for _, id := range ids {
+				fetched[id]++
+			}
+			blocking := ids[0] == 1 && fetched[1] == 1
+			mu.Unlock()
+			if blocking {
+				close(started)
+				<-release
+			}
+			return nil
+		})
+
+		first := make(chan error, 1)
+		go func() {
+			first <- g.Fetch(context.Background(), []int{1, 2, 2})
+		}()
+		<-started
+
+		waiting := make(chan error, 1)
+		go func() {
+			waiting <- g.Fetch(context.Background(), []int{2, 3})
+		}()
+		select {
+		case <-waiting:
+			require.FailNow(t, "must wait for the request in flight")
+		case <-time.After(10 * time.Millisecond):
+		}
+		close(release)
+		require.NoError(t, <-first)
+		require.NoError(t, <-waiting)
+		require.Equal(t, map[int]int{1: 1, 2: 1, 3: 1}, fetched)
+
+		// finished requests are not cached
+		require.NoError(t, g.Fetch(context.Background(), []int{1}))
+		require.Equal(t, 2, fetched[1])
+	})
+	t.Run("shared error", func(t *testing.T) {
+		errUnknown := errors.New("unknown")
+		started := make(chan struct{})
+		release := make(chan struct{})
+		var calls atomic.Int32
+		g := newFetchGroup(func(_ context.Context, ids []int) error {
+			if calls.Add(1) > 1 {
+				return nil
+			}
+			close(started)
+			<-release
+			return errUnknown
+		})
+		first := make(chan error, 1)
+		go func() {
+			first <- g.Fetch(context.Background(), []int{1})
+		}()
+		<-started
+		second := make(chan error, 1)
+		go func() {
+			second <- g.Fetch(context.Background(), []int{1})
+		}()
+		time.Sleep(10 * time.Millisecond)
+		close(release)
+		require.ErrorIs(t, <-first, errUnknown)
+		require.ErrorIs(t, <-second, errUnknown)
+		require.EqualValues(t, 1, calls.Load())
+	})
+	t.Run("canceled while waiting", func(t *testing.T) {
+		started := make(chan struct{})
+		release := make(chan struct{})
+		g := newFetchGroup(func(_ context.Context, ids []int) error {
+			close(started)
+			<-release
+			return nil
+		})
+		defer close(release)
+		go g.Fetch(context.Background(), []int{1})
+		<-started
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		require.ErrorIs(t, g.Fetch(ctx, []int{1}), context.Canceled)
+	})
+}
+
+func TestResolveDependencies_Timeout(t *testing.T) {
+	th := createTestHandlerNoopDecoder(t)
+	th.cfg.FetchTimeout = 10 * time.Millisecond
+	b := createBallot(t)
+	th.mf.EXPECT().GetBallots(gomock.Any(), []types.BallotID{b.Votes.Base, b.RefBallot}).DoAndReturn(
+		func(ctx context.Context, _ []types.BallotID) error {
+			<-ctx.Done()
+			return ctx.Err()
+		})
+	err := th.resolveDependencies(context.Background(), b.Layer.GetEpoch(), collectDependencies(b, nil))
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	var missing *missingBallotError
+	require.ErrorAs(t, err, &missing)
+	require.Equal(t, b.Votes.Base, missing.ballot)
+}
+
+func TestResolveDependencies_LocalTXs(t *testing.T) {
+	th := createTestHandlerNoopDecoder(t)
+	local := types.Transaction{RawTx: types.NewRawTx([]byte{1, 2, 3})}
+	require.NoError(t, transactions.Add(th.cdb, &local, time.Now()))
+	missing := types.RandomTransactionID()
+
+	th.mf.EXPECT().GetProposalTxs(gomock.Any(), []types.TransactionID{missing}).Return(nil)
+	deps := collectDependencies(nil, []types.TransactionID{local.ID, missing})
+	require.NoError(t, th.resolveDependencies(context.Background(), 1, deps))
+
+	// nothing is requested if all txs are available
+	deps = collectDependencies(nil, []types.TransactionID{local.ID})
+	require.NoError(t, th.resolveDependencies(context.Background(), 1, deps))
+}
+
+func TestProposal_BaseBallotArrivesLater(t *testing.T) {
+	th := createTestHandlerNoopDecoder(t)
+	lid := types.LayerID(100)
+	supported := []*types.Block{
+		types.NewExistingBlock(types.BlockID{1}, types.InnerBlock{LayerIndex: lid.Sub(1)}),
+	}
+	for _, block := range supported {
+		require.NoError(t, blocks.Add(th.cdb, block))
+	}
+	ref := signAndInit(t, createRefBallot(t))
+	require.NoError(t, ballots.Add(th.cdb, ref))
+	base := createBallot(t, withLayer(lid-1))
+
+	p := createProposal(t,
+		withLayer(lid),
+		withSupportBlocks(supported...),
+		createBallotOpt(func(b *types.Ballot) {
+			b.RefBallot = ref.ID()
+			b.Votes.Base = base.ID()
+		}),
+	)
+	createAtx(t, th.cdb.Database, p.Layer.GetEpoch()-1, p.AtxID, p.SmesherID)
+	data := encodeProposal(t, p)
+	peer := p2p.Peer("buddy")
+	deps := []types.BallotID{base.ID(), ref.ID()}
+
+	// the proposal arrives before its base ballot is available
+	errUnavailable := errors.New("unavailable")
+	th.mf.EXPECT().RegisterPeerHashes(peer, collectHashes(*p)).Times(2)
+	th.mf.EXPECT().GetBallots(gomock.Any(), deps).Return(errUnavailable)
+	err := th.HandleProposal(context.Background(), peer, data)
+	require.ErrorIs(t, err, errUnavailable)
+	var missing *missingBallotError
+	require.ErrorAs(t, err, &missing)
+	require.Equal(t, base.ID(), missing.ballot)
+	checkProposal(t, th.cdb, p, false)
+
+	// the fetcher supplies the base ballot
+	th.mf.EXPECT().GetBallots(gomock.Any(), deps).DoAndReturn(
+		func(_ context.Context, _ []types.BallotID) error {
+			return ballots.Add(th.cdb, base)
+		})
+	th.md.EXPECT().GetMissingActiveSet(gomock.Any(), types.ATXIDList{p.AtxID}).Return(nil)
+	th.mf.EXPECT().GetProposalTxs(gomock.Any(), p.TxIDs).Return(nil)
+	th.mv.EXPECT().CheckEligibility(gomock.Any(), gomock.Any()).Return(true, nil)
+	th.mm.EXPECT().AddBallot(context.Background(), &p.Ballot).DoAndReturn(
+		func(_ context.Context, got *types.Ballot) (*types.MalfeasanceProof, bool, error) {
+			require.NoError(t, ballots.Add(th.cdb, got))
+			return nil, true, nil
+		})
+	th.mm.EXPECT().AddTXsFromProposal(gomock.Any(), p.Layer, p.ID(), p.TxIDs).Return(nil)
+	require.NoError(t, th.HandleProposal(context.Background(), peer, data))
+	checkProposal(t, th.cdb, p, true)
+}
@@ -4,16 +4,29 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"runtime"
+	"sync"
 
+	lru "github.com/hashicorp/golang-lru/v2"
 	"github.com/spacemeshos/fixed"
+	"golang.org/x/sync/errgroup"
 
 	"github.com/spacemeshos/go-spacemesh/common/types"
 	"github.com/spacemeshos/go-spacemesh/datastore"
+	"github.com/spacemeshos/go-spacemesh/hash"
 	"github.com/spacemeshos/go-spacemesh/log"
-	"github.com/spacemeshos/go-spacemesh/sql/ballots"
+	"github.com/spacemeshos/go-spacemesh/sql"
+	"github.com/spacemeshos/go-spacemesh/sql/atxs"
 	"github.com/spacemeshos/go-spacemesh/system"
 )
 
+// verifiedProofsSize is the number of proofs verified in batches that are remembered
+// until the ballots are checked one by one.
+const verifiedProofsSize = 50_000
+
+// verifiedNoncesSize is the number of VRF nonces that passed PoST verification and are not verified again.
+const verifiedNoncesSize = 100_000
+
 var (
 	errTargetEpochMismatch = errors.New("ATX target epoch and ballot publish epoch mismatch")
 	errPublicKeyMismatch   = errors.New("ballot smesher key and ATX node key mismatch")
@@ -22,6 +35,8 @@ var (
 	errIncorrectVRFSig     = errors.New("proof contains incorrect VRF signature")
 	errIncorrectLayerIndex = errors.New("ballot has incorrect layer index")
 	errIncorrectEligCount  = errors.New("ballot has incorrect eligibility count")
+	errMissingVRFNonce     = errors.New("smesher has no valid VRF nonce")
+	errInvalidVRFNonce     = errors.New("smesher VRF nonce fails PoST verification")
 )
 
 // Validator validates the eligibility of a Ballot.
@@ -36,6 +51,21 @@ type Validator struct {
 	logger             log.Log
 	vrfVerifier        vrfVerifier
 	nonceFetcher       nonceFetcher
+	// nonceVerifier checks the VRF nonce against the PoST of the smesher, verification is skipped if it is nil.
+	nonceVerifier nonceVerifier
+	labelsPerUnit uint64
+	// workers is the number of goroutines used by ValidateEligibilities.
+	workers int
+	// verified are the keys of the proofs verified by VerifyProofs.
+	verified *lru.Cache[types.Hash32, struct{}]
+	// verifiedNonces are the nonces that passed PoST verification.
+	verifiedNonces *lru.Cache[nonceKey, struct{}]
+}
+
+type nonceKey struct {
+	nodeID   types.NodeID
+	nonce    types.VRFPostIndex
+	numUnits uint32
 }
 
 type defaultFetcher struct {
@@ -59,6 +89,22 @@ func WithNonceFetcher(nf nonceFetcher) ValidatorOpt {
 	}
 }
 
+// WithNonceVerifier enables verification of the VRF nonce against the PoST of the smesher,
+// labelsPerUnit is the PoST parameter of the network.
+func WithNonceVerifier(nv nonceVerifier, labelsPerUnit uint64) ValidatorOpt {
+	return func(h *Validator) {
+		h.nonceVerifier = nv
+		h.labelsPerUnit = labelsPerUnit
+	}
+}
+
+// WithVerifyWorkers sets the number of goroutines used to validate eligibilities in batch.
+func WithVerifyWorkers(n int) ValidatorOpt {
+	return func(h *Validator) {
+		h.workers = n
+	}
+}
+
 // NewEligibilityValidator returns a new EligibilityValidator.
 func NewEligibilityValidator(
 	avgLayerSize, layersPerEpoch uint32, minActiveSetWeight uint64, cdb *datastore.CachedDB, bc system.BeaconCollector, m meshProvider, lg log.Log, vrfVerifier vrfVerifier, opts ...ValidatorOpt,
@@ -72,6 +118,7 @@ func NewEligibilityValidator(
 		beacons:            bc,
 		logger:             lg,
 		vrfVerifier:        vrfVerifier,
+		workers:            runtime.NumCPU(),
 	}
 	for _, opt := range opts {
 		opt(v)
@@ -80,12 +127,205 @@ func NewEligibilityValidator(
 	if v.nonceFetcher == nil {
 		v.nonceFetcher = defaultFetcher{cdb: cdb}
 	}
+	if v.workers < 1 {
+		v.workers = 1
+	}
+	verified, err := lru.New[types.Hash32, struct{}](verifiedProofsSize)
+	if err != nil {
+		lg.With().Fatal("failed to create cache for verified proofs", log.Err(err))
+	}
+	v.verified = verified
+	verifiedNonces, err := lru.New[nonceKey, struct{}](verifiedNoncesSize)
+	if err != nil {
+		lg.With().Fatal("failed to create cache for verified nonces", log.Err(err))
+	}
+	v.verifiedNonces = verifiedNonces
 
 	return v
 }
 
 // CheckEligibility checks that a ballot is eligible in the layer that it specifies.
 func (v *Validator) CheckEligibility(ctx context.Context, ballot *types.Ballot) (bool, error) {
+	e, err := v.prepare(ballot)
+	if err != nil {
+		return false, err
+	}
+	for i, proof := range ballot.EligibilityProofs {
+		if v.verified.Contains(proofKey(e.nodeID, e.messages[i], proof.Sig)) {
+			continue
+		}
+		if !v.vrfVerifier.Verify(e.nodeID, e.messages[i], proof.Sig) {
+			return false, e.proofError(i)
+		}
+	}
+	if err := v.finish(ctx, e); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// ValidateEligibilities checks eligibility of the ballots, verifying vrf proofs of all ballots in batches.
+// Returned errors correspond to the ballots, nil error means that the ballot is eligible.
+// Ballots that reference a ref ballot require the ref ballot to be stored beforehand.
+func (v *Validator) ValidateEligibilities(ctx context.Context, blts []*types.Ballot) []error {
+	checks, errs := v.verifyProofs(ctx, blts)
+	for i, e := range checks {
+		if errs[i] != nil {
+			continue
+		}
+		errs[i] = v.finish(ctx, e)
+	}
+	return errs
+}
+
+// VerifyProofs verifies vrf proofs of the ballots in batches and remembers the valid ones,
+// so that CheckEligibility doesn't verify them again.
+// The error is returned only for the ballots with an invalid proof. The ballots that can't be
+// checked with the data that is available locally are left for CheckEligibility.
+func (v *Validator) VerifyProofs(ctx context.Context, blts []*types.Ballot) []error {
+	_, errs := v.verifyProofs(ctx, blts)
+	for i, err := range errs {
+		if !errors.Is(err, errIncorrectVRFSig) {
+			errs[i] = nil
+		}
+	}
+	return errs
+}
+
+func (v *Validator) verifyProofs(ctx context.Context, blts []*types.Ballot) ([]*eligibility, []error) {
+	var (
+		errs   = make([]error, len(blts))
+		checks = make([]*eligibility, len(blts))
+		eg     errgroup.Group
+	)
+	eg.SetLimit(v.workers)
+	for i := range blts {
+		i := i
+		eg.Go(func() error {
+			if err := ctx.Err(); err != nil {
+				errs[i] = err
+				return nil
+			}
+			checks[i], errs[i] = v.prepare(blts[i])
+			return nil
+		})
+	}
+	_ = eg.Wait()
+
+	var (
+		owners  []int
+		nodeIDs []types.NodeID
+		msgs    [][]byte
+		sigs    []types.VrfSignature
+	)
+	for i, e := range checks {
+		if errs[i] != nil {
+			continue
+		}
+		for j, proof := range e.ballot.EligibilityProofs {
+			owners = append(owners, i)
+			nodeIDs = append(nodeIDs, e.nodeID)
+			msgs = append(msgs, e.messages[j])
+			sigs = append(sigs, proof.Sig)
+		}
+	}
+	// proofs are split evenly between workers, and every chunk is verified in a single batch.
+	// if batch fails the specific proof is identified and verification is resumed
+	// from the next ballot in the chunk.
+	var (
+		mu     sync.Mutex
+		verify errgroup.Group
+		chunk  = (len(sigs) + v.workers - 1) / v.workers
+	)
+	for from := 0; from < len(sigs); from += chunk {
+		from, to := from, from+chunk
+		if to > len(sigs) {
+			to = len(sigs)
+		}
+		verify.Go(func() error {
+			for start := from; start < to; {
+				idx, ok := v.verifyBatch(nodeIDs[start:to], msgs[start:to], sigs[start:to])
+				if ok {
+					return nil
+				}
+				bad := start + idx
+				owner := owners[bad]
+				mu.Lock()
+				if errs[owner] == nil {
+					errs[owner] = checks[owner].proofError(bad - firstProof(owners, bad))
+				}
+				mu.Unlock()
+				start = bad + 1
+				for start < to && owners[start] == owner {
+					start++
+				}
+			}
+			return nil
+		})
+	}
+	_ = verify.Wait()
+
+	for i, e := range checks {
+		if errs[i] != nil {
+			continue
+		}
+		for j, proof := range e.ballot.EligibilityProofs {
+			v.verified.Add(proofKey(e.nodeID, e.messages[j], proof.Sig), struct{}{})
+		}
+	}
+	return checks, errs
+}
+
+// proofKey identifies the vrf proof of the message by the key.
+func proofKey(nodeID types.NodeID, msg []byte, sig types.VrfSignature) types.Hash32 {
+	return hash.Sum(nodeID.Bytes(), msg, sig[:])
+}
+
+// firstProof returns position of the first proof of the ballot that owns the proof at pos.
+func firstProof(owners []int, pos int) int {
+	for pos > 0 && owners[pos-1] == owners[pos] {
+		pos--
+	}
+	return pos
+}
+
+// batchVerifier is implemented by vrf verifiers that can verify multiple proofs at once,
+// such as signing.VRFVerifier.
+type batchVerifier interface {
+	VerifyBatch([]types.NodeID, [][]byte, []types.VrfSignature) (int, bool)
+}
+
+func (v *Validator) verifyBatch(nodeIDs []types.NodeID, msgs [][]byte, sigs []types.VrfSignature) (int, bool) {
+	if batch, ok := v.vrfVerifier.(batchVerifier); ok {
+		return batch.VerifyBatch(nodeIDs, msgs, sigs)
+	}
+	for i := range sigs {
+		if !v.vrfVerifier.Verify(nodeIDs[i], msgs[i], sigs[i]) {
+			return i, false
+		}
+	}
+	return 0, true
+}
+
+// eligibility of the ballot that is checked except for the vrf proofs.
+type eligibility struct {
+	ballot    *types.Ballot
+	nodeID    types.NodeID
+	beacon    types.Beacon
+	weightPer fixed.Fixed
+	// messages signed by the vrf proofs, in the same order as proofs.
+	messages [][]byte
+}
+
+func (e *eligibility) proofError(i int) error {
+	proof := e.ballot.EligibilityProofs[i]
+	return fmt.Errorf("%w: beacon: %v, epoch: %v, counter: %v, vrfSig: %s",
+		errIncorrectVRFSig, e.beacon.ShortString(), e.ballot.Layer.GetEpoch(), proof.J, proof.Sig,
+	)
+}
+
+// prepare checks everything but vrf proofs, and computes messages that are signed by the proofs.
+func (v *Validator) prepare(ballot *types.Ballot) (*eligibility, error) {
 	var (
 		atxWeight, totalWeight uint64
 		err                    error
@@ -93,29 +333,29 @@ func (v *Validator) CheckEligibility(ctx context.Context, ballot *types.Ballot)
 		epoch                  = ballot.Layer.GetEpoch()
 	)
 	if len(ballot.EligibilityProofs) == 0 {
-		return false, fmt.Errorf("empty eligibility list is invalid (ballot %s)", ballot.ID())
+		return nil, fmt.Errorf("empty eligibility list is invalid (ballot %s)", ballot.ID())
 	}
 
 	if ballot.RefBallot != types.EmptyBallotID {
-		if refBallot, err = ballots.Get(v.cdb, ballot.RefBallot); err != nil {
-			return false, fmt.Errorf("get ref ballot %v: %w", ballot.RefBallot, err)
+		if refBallot, err = v.cdb.GetBallot(ballot.RefBallot); err != nil {
+			return nil, fmt.Errorf("get ref ballot %v: %w", ballot.RefBallot, err)
 		}
 	}
 	if refBallot.EpochData == nil {
-		return false, fmt.Errorf("%w: ref ballot %v", errMissingEpochData, refBallot.ID())
+		return nil, fmt.Errorf("%w: ref ballot %v", errMissingEpochData, refBallot.ID())
 	}
 	if refBallot.AtxID != ballot.AtxID {
-		return false, fmt.Errorf("ballot (%v/%v) should be sharing atx with a reference ballot (%v/%v)", ballot.ID(), ballot.AtxID, refBallot.ID(), refBallot.AtxID)
+		return nil, fmt.Errorf("ballot (%v/%v) should be sharing atx with a reference ballot (%v/%v)", ballot.ID(), ballot.AtxID, refBallot.ID(), refBallot.AtxID)
 	}
 
 	beacon := refBallot.EpochData.Beacon
 	if beacon == types.EmptyBeacon {
-		return false, fmt.Errorf("%w: ref ballot %v", errMissingBeacon, refBallot.ID())
+		return nil, fmt.Errorf("%w: ref ballot %v", errMissingBeacon, refBallot.ID())
 	}
 
 	activeSets := refBallot.ActiveSet
 	if len(activeSets) == 0 {
-		return false, fmt.Errorf("%w: ref ballot %v", errEmptyActiveSet, refBallot.ID())
+		return nil, fmt.Errorf("%w: ref ballot %v", errEmptyActiveSet, refBallot.ID())
 	}
 
 	// todo: optimize by using reference to active set size and cache active set size to not load all atxsIDs from db
@@ -123,7 +363,7 @@ func (v *Validator) CheckEligibility(ctx context.Context, ballot *types.Ballot)
 	for _, atxID := range activeSets {
 		atx, err := v.cdb.GetAtxHeader(atxID)
 		if err != nil {
-			return false, fmt.Errorf("get ATX header %v: %w", atxID, err)
+			return nil, fmt.Errorf("get ATX header %v: %w", atxID, err)
 		}
 		totalWeight += atx.GetWeight()
 		if atxID == ballot.AtxID {
@@ -131,64 +371,101 @@ func (v *Validator) CheckEligibility(ctx context.Context, ballot *types.Ballot)
 		}
 	}
 	if owned == nil {
-		return false, fmt.Errorf("atx %v from ballot %v (refballot %v) is not included into the active set", ballot.AtxID, ballot.ID(), refBallot.ID())
+		return nil, fmt.Errorf("atx %v from ballot %v (refballot %v) is not included into the active set", ballot.AtxID, ballot.ID(), refBallot.ID())
 	}
 	if targetEpoch := owned.TargetEpoch(); targetEpoch != epoch {
-		return false, fmt.Errorf("%w: ATX target epoch (%v), ballot publication epoch (%v)",
+		return nil, fmt.Errorf("%w: ATX target epoch (%v), ballot publication epoch (%v)",
 			errTargetEpochMismatch, targetEpoch, epoch)
 	}
+	// ballot refers to the identity even if it is signed by the rotated key
 	if ballot.SmesherID != owned.NodeID {
-		return false, fmt.Errorf("%w: public key (%v), ATX node key (%v)", errPublicKeyMismatch, ballot.SmesherID.String(), owned.NodeID)
+		return nil, fmt.Errorf("%w: public key (%v), ATX node key (%v)", errPublicKeyMismatch, ballot.SmesherID.String(), owned.NodeID)
 	}
 
 	atxWeight = owned.GetWeight()
 
 	numEligibleSlots, err := GetNumEligibleSlots(atxWeight, v.minActiveSetWeight, totalWeight, v.avgLayerSize, v.layersPerEpoch)
 	if err != nil {
-		return false, err
+		return nil, err
 	}
 	if ballot.EpochData != nil && ballot.EpochData.EligibilityCount != numEligibleSlots {
-		return false, fmt.Errorf("%w: expected %v, got: %v", errIncorrectEligCount, numEligibleSlots, ballot.EpochData.EligibilityCount)
+		return nil, fmt.Errorf("%w: expected %v, got: %v", errIncorrectEligCount, numEligibleSlots, ballot.EpochData.EligibilityCount)
 	}
 
 	var (
-		last    uint32
-		isFirst = true
+		last     uint32
+		isFirst  = true
+		messages = make([][]byte, 0, len(ballot.EligibilityProofs))
 	)
 
-	nonce, err := v.nonceFetcher.VRFNonce(ballot.SmesherID, epoch)
-	if err != nil {
-		return false, err
+	nonce, err := v.nonceFetcher.VRFNonce(owned.NodeID, epoch)
+	if errors.Is(err, sql.ErrNotFound) {
+		return nil, fmt.Errorf("%w: smesher %v, epoch %v", errMissingVRFNonce, owned.NodeID, epoch)
+	} else if err != nil {
+		return nil, err
+	}
+	if err := v.verifyNonce(owned, nonce); err != nil {
+		return nil, err
 	}
 	for _, proof := range ballot.EligibilityProofs {
 		counter := proof.J
 		if counter >= numEligibleSlots {
-			return false, fmt.Errorf("%w: proof counter (%d) numEligibleBallots (%d), totalWeight (%v)",
+			return nil, fmt.Errorf("%w: proof counter (%d) numEligibleBallots (%d), totalWeight (%v)",
 				errIncorrectCounter, counter, numEligibleSlots, totalWeight)
 		}
 		if isFirst {
 			isFirst = false
 		} else if counter <= last {
-			return false, fmt.Errorf("%w: %d <= %d", errInvalidProofsOrder, counter, last)
+			return nil, fmt.Errorf("%w: %d <= %d", errInvalidProofsOrder, counter, last)
 		}
 		last = counter
 
 		message, err := SerializeVRFMessage(beacon, epoch, nonce, counter)
 		if err != nil {
-			return false, err
+			return nil, err
 		}
-		vrfSig := proof.Sig
+		messages = append(messages, message)
+	}
+	return &eligibility{
+		ballot:    ballot,
+		nodeID:    owned.NodeID,
+		beacon:    beacon,
+		weightPer: fixed.DivUint64(atxWeight, uint64(numEligibleSlots)),
+		messages:  messages,
+	}, nil
+}
 
-		beaconStr := beacon.ShortString()
-		if !v.vrfVerifier.Verify(owned.NodeID, message, vrfSig) {
-			return false, fmt.Errorf("%w: beacon: %v, epoch: %v, counter: %v, vrfSig: %s",
-				errIncorrectVRFSig, beaconStr, epoch, counter, vrfSig,
-			)
-		}
+// verifyNonce checks that the VRF nonce of the smesher is valid for the PoST of the ATX.
+func (v *Validator) verifyNonce(atx *types.ActivationTxHeader, nonce types.VRFPostIndex) error {
+	if v.nonceVerifier == nil {
+		return nil
+	}
+	key := nonceKey{nodeID: atx.NodeID, nonce: nonce, numUnits: atx.NumUnits}
+	if v.verifiedNonces.Contains(key) {
+		return nil
+	}
+	commitment, err := atxs.CommitmentATX(v.cdb, atx.NodeID)
+	if err != nil {
+		return fmt.Errorf("get commitment atx for smesher %v: %w", atx.NodeID, err)
+	}
+	meta := &types.PostMetadata{LabelsPerUnit: v.labelsPerUnit}
+	if err := v.nonceVerifier.VRFNonce(atx.NodeID, commitment, &nonce, meta, atx.NumUnits); err != nil {
+		return fmt.Errorf("%w: smesher %v, atx %v: %v", errInvalidVRFNonce, atx.NodeID, atx.ID, err)
+	}
+	v.verifiedNonces.Add(key, struct{}{})
+	return nil
+}
 
-		eligibleLayer := CalcEligibleLayer(epoch, v.layersPerEpoch, vrfSig)
+// finish checks eligible layers of the verified proofs and reports beacon from the ballot.
+func (v *Validator) finish(ctx context.Context, e *eligibility) error {
+	var (
+		ballot = e.ballot
+		epoch  = ballot.Layer.GetEpoch()
+	)
+	for _, proof := range ballot.EligibilityProofs {
+		eligibleLayer := CalcEligibleLayer(epoch, v.layersPerEpoch, proof.Sig)
 		if ballot.Layer != eligibleLayer {
-			return false, fmt.Errorf("%w: ballot layer (%v), eligible layer (%v)",
+			return fmt.Errorf("%w: ballot layer (%v), eligible layer (%v)",
 				errIncorrectLayerIndex, ballot.Layer, eligibleLayer)
 		}
 	}
@@ -197,10 +474,8 @@ func (v *Validator) CheckEligibility(ctx context.Context, ballot *types.Ballot)
 		ballot.ID(),
 		ballot.Layer,
 		epoch,
-		beacon,
+		e.beacon,
 	)
-
-	weightPer := fixed.DivUint64(atxWeight, uint64(numEligibleSlots))
-	v.beacons.ReportBeaconFromBallot(epoch, ballot, beacon, weightPer)
-	return true, nil
+	v.beacons.ReportBeaconFromBallot(epoch, ballot, e.beacon, e.weightPer)
+	return nil
 }
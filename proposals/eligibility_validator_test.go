@@ -2,9 +2,12 @@ package proposals
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"math/rand"
 	"sort"
 	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -20,6 +23,7 @@ import (
 	"github.com/spacemeshos/go-spacemesh/sql"
 	"github.com/spacemeshos/go-spacemesh/sql/atxs"
 	"github.com/spacemeshos/go-spacemesh/sql/ballots"
+	"github.com/spacemeshos/go-spacemesh/sql/keyrotations"
 )
 
 const (
@@ -34,7 +38,7 @@ func genActiveSet() types.ATXIDList {
 	return types.ATXIDList{types.RandomATXID(), types.RandomATXID(), types.RandomATXID(), types.RandomATXID()}
 }
 
-func genActiveSetAndSave(t *testing.T, cdb *datastore.CachedDB, signer *signing.EdSigner) types.ATXIDList {
+func genActiveSetAndSave(t testing.TB, cdb *datastore.CachedDB, signer *signing.EdSigner) types.ATXIDList {
 	t.Helper()
 	activeset := types.ATXIDList{types.RandomATXID(), types.RandomATXID(), types.RandomATXID(), types.RandomATXID()}
 
@@ -104,7 +108,8 @@ func createBallots(tb testing.TB, signer *signing.EdSigner, activeSet types.ATXI
 	for counter := uint32(0); counter < eligibleSlots; counter++ {
 		message, err := SerializeVRFMessage(beacon, epoch, nonce, counter)
 		require.NoError(tb, err)
-		vrfSig := vrfSigner.Sign(message)
+		vrfSig, err := vrfSigner.Sign(message)
+		require.NoError(tb, err)
 		eligibleLayer := CalcEligibleLayer(epoch, layersPerEpoch, vrfSig)
 		if _, exist := eligibilityProofs[eligibleLayer]; !exist {
 			order = append(order, eligibleLayer)
@@ -415,6 +420,132 @@ func TestCheckEligibility_IncorrectLayerIndex(t *testing.T) {
 	require.False(t, eligible)
 }
 
+func TestCheckEligibility_MissingVRFNonce(t *testing.T) {
+	tv := createTestValidator(t)
+	signer, err := signing.NewEdSigner(
+		signing.WithKeyFromRand(rand.New(rand.NewSource(1001))),
+	)
+	require.NoError(t, err)
+
+	activeset := genActiveSetAndSave(t, tv.cdb, signer)
+	blts := createBallots(t, signer, activeset, types.Beacon{1, 1, 1})
+	rb := blts[0]
+	require.NoError(t, ballots.Add(tv.cdb, rb))
+
+	tv.mNonce.EXPECT().VRFNonce(gomock.Any(), gomock.Any()).Return(types.VRFPostIndex(0), fmt.Errorf("get vrf nonce: %w", sql.ErrNotFound)).Times(1)
+	eligible, err := tv.CheckEligibility(context.Background(), blts[1])
+	require.ErrorIs(t, err, errMissingVRFNonce)
+	require.False(t, eligible)
+}
+
+func TestCheckEligibility_VRFNonceFromAtx(t *testing.T) {
+	types.SetLayersPerEpoch(layersPerEpoch)
+	ms := fullMockSet(t)
+	lg := logtest.New(t)
+	cdb := datastore.NewCachedDB(sql.InMemory(), lg)
+	// no nonce fetcher option, nonce is loaded from the stored ATX.
+	v := NewEligibilityValidator(layerAvgSize, layersPerEpoch, 0, cdb, ms.mbc, ms.mm, lg, ms.mvrf)
+
+	signer, err := signing.NewEdSigner(
+		signing.WithKeyFromRand(rand.New(rand.NewSource(1001))),
+	)
+	require.NoError(t, err)
+
+	activeset := genActiveSetAndSave(t, cdb, signer)
+	blts := createBallots(t, signer, activeset, types.Beacon{1, 1, 1})
+	require.NoError(t, ballots.Add(cdb, blts[0]))
+
+	ms.mvrf.EXPECT().Verify(gomock.Any(), gomock.Any(), gomock.Any()).Return(true).AnyTimes()
+	ms.mbc.EXPECT().ReportBeaconFromBallot(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes()
+	eligible, err := v.CheckEligibility(context.Background(), blts[1])
+	require.NoError(t, err)
+	require.True(t, eligible)
+
+	// ballot from a smesher whose ATX does not carry a nonce.
+	other, err := signing.NewEdSigner(
+		signing.WithKeyFromRand(rand.New(rand.NewSource(1002))),
+	)
+	require.NoError(t, err)
+	atx := &types.ActivationTx{InnerActivationTx: types.InnerActivationTx{
+		NIPostChallenge: types.NIPostChallenge{
+			PublishEpoch: epoch - 1,
+		},
+		NumUnits: testedATXUnit,
+	}}
+	atx.SetEffectiveNumUnits(testedATXUnit)
+	atx.SetReceived(time.Now())
+	activation.SignAndFinalizeAtx(other, atx)
+	vAtx, err := atx.Verify(0, 1)
+	require.NoError(t, err)
+	require.NoError(t, atxs.Add(cdb, vAtx))
+
+	activeset = append(types.ATXIDList{atx.ID()}, activeset[1:]...)
+	blts = createBallots(t, other, activeset, types.Beacon{1, 1, 1})
+	eligible, err = v.CheckEligibility(context.Background(), blts[0])
+	require.ErrorIs(t, err, errMissingVRFNonce)
+	require.False(t, eligible)
+}
+
+func TestCheckEligibility_InvalidVRFNonce(t *testing.T) {
+	types.SetLayersPerEpoch(layersPerEpoch)
+	ms := fullMockSet(t)
+	lg := logtest.New(t)
+	cdb := datastore.NewCachedDB(sql.InMemory(), lg)
+	mverifier := NewMocknonceVerifier(gomock.NewController(t))
+	const labelsPerUnit = 1024
+	v := NewEligibilityValidator(layerAvgSize, layersPerEpoch, 0, cdb, ms.mbc, ms.mm, lg, ms.mvrf,
+		WithNonceVerifier(mverifier, labelsPerUnit))
+
+	other, err := signing.NewEdSigner(
+		signing.WithKeyFromRand(rand.New(rand.NewSource(1001))),
+	)
+	require.NoError(t, err)
+	activeset := genActiveSetAndSave(t, cdb, other)
+
+	// smesher ATX with a commitment and a nonce replaces the first ATX in the active set.
+	signer, err := signing.NewEdSigner(
+		signing.WithKeyFromRand(rand.New(rand.NewSource(1002))),
+	)
+	require.NoError(t, err)
+	nonce := types.VRFPostIndex(7)
+	commitment := types.RandomATXID()
+	atx := &types.ActivationTx{InnerActivationTx: types.InnerActivationTx{
+		NIPostChallenge: types.NIPostChallenge{
+			PublishEpoch:  epoch - 1,
+			Sequence:      1,
+			CommitmentATX: &commitment,
+		},
+		NumUnits: testedATXUnit,
+		VRFNonce: &nonce,
+	}}
+	atx.SetEffectiveNumUnits(testedATXUnit)
+	atx.SetReceived(time.Now())
+	activation.SignAndFinalizeAtx(signer, atx)
+	vAtx, err := atx.Verify(0, 1)
+	require.NoError(t, err)
+	require.NoError(t, atxs.Add(cdb, vAtx))
+	activeset[0] = atx.ID()
+	blts := createBallots(t, signer, activeset, types.Beacon{1, 1, 1})
+	require.NoError(t, ballots.Add(cdb, blts[0]))
+
+	mverifier.EXPECT().VRFNonce(signer.NodeID(), commitment, &nonce, &types.PostMetadata{LabelsPerUnit: labelsPerUnit}, atx.NumUnits).
+		Return(errors.New("invalid nonce"))
+	eligible, err := v.CheckEligibility(context.Background(), blts[1])
+	require.ErrorIs(t, err, errInvalidVRFNonce)
+	require.False(t, eligible)
+
+	mverifier.EXPECT().VRFNonce(signer.NodeID(), commitment, &nonce, gomock.Any(), atx.NumUnits).Return(nil)
+	ms.mvrf.EXPECT().Verify(gomock.Any(), gomock.Any(), gomock.Any()).Return(true).AnyTimes()
+	ms.mbc.EXPECT().ReportBeaconFromBallot(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes()
+	eligible, err = v.CheckEligibility(context.Background(), blts[1])
+	require.NoError(t, err)
+	require.True(t, eligible)
+	// valid nonce is not verified again.
+	eligible, err = v.CheckEligibility(context.Background(), blts[1])
+	require.NoError(t, err)
+	require.True(t, eligible)
+}
+
 func TestCheckEligibility_EmptyEligibilityList(t *testing.T) {
 	tv := createTestValidator(t)
 	eligibile, err := tv.CheckEligibility(context.Background(), &types.Ballot{})
@@ -539,3 +670,165 @@ func TestCheckEligibility_MinActiveSetWeight(t *testing.T) {
 	require.ErrorContains(t, err, "ballot has incorrect eligibility count: expected 15, got: 30")
 	require.False(t, eligibile)
 }
+
+func createSignedBallots(tb testing.TB, cdb *datastore.CachedDB, signers int, beacon types.Beacon) []*types.Ballot {
+	tb.Helper()
+	var blts []*types.Ballot
+	for i := 0; i < signers; i++ {
+		signer, err := signing.NewEdSigner(
+			signing.WithKeyFromRand(rand.New(rand.NewSource(int64(i)))),
+		)
+		require.NoError(tb, err)
+		created := createBallots(tb, signer, genActiveSetAndSave(tb, cdb, signer), beacon)
+		require.NoError(tb, ballots.Add(cdb, created[0]))
+		blts = append(blts, created...)
+	}
+	return blts
+}
+
+func TestValidateEligibilities(t *testing.T) {
+	types.SetLayersPerEpoch(layersPerEpoch)
+	ms := fullMockSet(t)
+	lg := logtest.New(t)
+	cdb := datastore.NewCachedDB(sql.InMemory(), lg)
+	v := NewEligibilityValidator(layerAvgSize, layersPerEpoch, 0, cdb, ms.mbc, ms.mm, lg, signing.NewVRFVerifier(),
+		WithVerifyWorkers(3),
+	)
+
+	blts := createSignedBallots(t, cdb, 10, types.Beacon{1, 1, 1})
+	bad := blts[len(blts)/2]
+	bad.EligibilityProofs[len(bad.EligibilityProofs)-1].Sig = types.RandomVrfSignature()
+	ms.mbc.EXPECT().ReportBeaconFromBallot(epoch, gomock.Any(), types.Beacon{1, 1, 1}, gomock.Any()).Times(len(blts) - 1)
+
+	errs := v.ValidateEligibilities(context.Background(), blts)
+	require.Len(t, errs, len(blts))
+	for i, err := range errs {
+		if blts[i] == bad {
+			require.ErrorIs(t, err, errIncorrectVRFSig)
+			require.ErrorContains(t, err, fmt.Sprintf("counter: %d", bad.EligibilityProofs[len(bad.EligibilityProofs)-1].J))
+		} else {
+			require.NoError(t, err, "ballot %d", i)
+		}
+	}
+}
+
+type countingVerifier struct {
+	vrfVerifier
+	calls atomic.Int32
+}
+
+func (c *countingVerifier) Verify(nodeID types.NodeID, msg []byte, sig types.VrfSignature) bool {
+	c.calls.Add(1)
+	return c.vrfVerifier.Verify(nodeID, msg, sig)
+}
+
+func TestVerifyProofs(t *testing.T) {
+	types.SetLayersPerEpoch(layersPerEpoch)
+	ms := fullMockSet(t)
+	lg := logtest.New(t)
+	cdb := datastore.NewCachedDB(sql.InMemory(), lg)
+	verifier := &countingVerifier{vrfVerifier: signing.NewVRFVerifier()}
+	v := NewEligibilityValidator(layerAvgSize, layersPerEpoch, 0, cdb, ms.mbc, ms.mm, lg, verifier,
+		WithVerifyWorkers(2),
+	)
+
+	blts := createSignedBallots(t, cdb, 4, types.Beacon{1, 1, 1})
+	bad := blts[1]
+	bad.EligibilityProofs[0].Sig = types.RandomVrfSignature()
+	// the ref ballot is not available locally
+	unknown := *blts[len(blts)-1]
+	unknown.RefBallot = types.RandomBallotID()
+	blts = append(blts, &unknown)
+
+	errs := v.VerifyProofs(context.Background(), blts)
+	require.Len(t, errs, len(blts))
+	for i, err := range errs {
+		if blts[i] == bad {
+			require.ErrorIs(t, err, errIncorrectVRFSig)
+		} else {
+			require.NoError(t, err, "ballot %d", i)
+		}
+	}
+
+	// verified proofs are not verified again
+	calls := verifier.calls.Load()
+	ms.mbc.EXPECT().ReportBeaconFromBallot(epoch, gomock.Any(), types.Beacon{1, 1, 1}, gomock.Any()).AnyTimes()
+	for _, ballot := range blts[:len(blts)-1] {
+		eligible, err := v.CheckEligibility(context.Background(), ballot)
+		if ballot == bad {
+			require.ErrorIs(t, err, errIncorrectVRFSig)
+			require.False(t, eligible)
+		} else {
+			require.NoError(t, err)
+			require.True(t, eligible)
+		}
+	}
+	require.Equal(t, calls+1, verifier.calls.Load())
+	_, err := v.CheckEligibility(context.Background(), &unknown)
+	require.Error(t, err)
+}
+
+func BenchmarkValidateEligibilities(b *testing.B) {
+	types.SetLayersPerEpoch(layersPerEpoch)
+	ms := fullMockSet(b)
+	lg := logtest.New(b)
+	cdb := datastore.NewCachedDB(sql.InMemory(), lg)
+	v := NewEligibilityValidator(layerAvgSize, layersPerEpoch, 0, cdb, ms.mbc, ms.mm, lg, signing.NewVRFVerifier())
+	ms.mbc.EXPECT().ReportBeaconFromBallot(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes()
+
+	// every signer has eligibleSlots proofs, ~500 proofs in total
+	blts := createSignedBallots(b, cdb, 500/int(eligibleSlots), types.Beacon{1, 1, 1})
+
+	b.Run("sequential", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			for _, ballot := range blts {
+				eligible, err := v.CheckEligibility(context.Background(), ballot)
+				if err != nil || !eligible {
+					b.Fatal(err)
+				}
+			}
+		}
+	})
+	b.Run("batched", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			for _, err := range v.ValidateEligibilities(context.Background(), blts) {
+				if err != nil {
+					b.Fatal(err)
+				}
+			}
+		}
+	})
+}
+
+func TestCheckEligibility_RotatedKey(t *testing.T) {
+	signer, err := signing.NewEdSigner(
+		signing.WithKeyFromRand(rand.New(rand.NewSource(1001))),
+	)
+	require.NoError(t, err)
+	rotated, err := signing.NewEdSigner(
+		signing.WithKeyFromRand(rand.New(rand.NewSource(1002))),
+	)
+	require.NoError(t, err)
+
+	tv := createTestValidator(t)
+	require.NoError(t, keyrotations.Add(tv.cdb, &types.KeyRotation{
+		InnerKeyRotation: types.InnerKeyRotation{NodeID: signer.NodeID(), NewKey: rotated.NodeID(), Epoch: epoch - 1},
+	}))
+	activeset := genActiveSetAndSave(t, tv.cdb, signer)
+
+	// ballot of the identity refers to the identity even if it is signed by the rotated key
+	blts := createBallots(t, rotated, activeset, types.Beacon{1, 1, 1})
+	require.NoError(t, ballots.Add(tv.cdb, blts[0]))
+	_, err = tv.CheckEligibility(context.Background(), blts[1])
+	require.ErrorIs(t, err, errPublicKeyMismatch)
+
+	// vrf nonce and proofs are checked for the identity
+	blts = createBallots(t, signer, activeset, types.Beacon{1, 1, 1})
+	require.NoError(t, ballots.Add(tv.cdb, blts[0]))
+	tv.mNonce.EXPECT().VRFNonce(signer.NodeID(), epoch).Return(types.VRFPostIndex(1), nil)
+	tv.mvrf.EXPECT().Verify(signer.NodeID(), gomock.Any(), gomock.Any()).Return(true).Times(len(blts[1].EligibilityProofs))
+	tv.mbc.EXPECT().ReportBeaconFromBallot(epoch, blts[1], types.Beacon{1, 1, 1}, gomock.Any())
+	eligible, err := tv.CheckEligibility(context.Background(), blts[1])
+	require.NoError(t, err)
+	require.True(t, eligible)
+}
@@ -19,6 +19,7 @@ import (
 	"github.com/spacemeshos/go-spacemesh/signing"
 	"github.com/spacemeshos/go-spacemesh/sql"
 	"github.com/spacemeshos/go-spacemesh/sql/ballots"
+	"github.com/spacemeshos/go-spacemesh/sql/keyrotations"
 	"github.com/spacemeshos/go-spacemesh/sql/proposals"
 	"github.com/spacemeshos/go-spacemesh/system"
 	"github.com/spacemeshos/go-spacemesh/tortoise"
@@ -26,12 +27,14 @@ import (
 
 var (
 	errMalformedData         = fmt.Errorf("%w: malformed data", pubsub.ErrValidationReject)
+	errProposalTooLarge      = errors.New("proposal too large")
 	errInitialize            = errors.New("failed to initialize")
 	errInvalidATXID          = errors.New("ballot has invalid ATXID")
 	errMissingEpochData      = errors.New("epoch data is missing in ref ballot")
 	errUnexpectedEpochData   = errors.New("non-ref ballot declares epoch data")
 	errEmptyActiveSet        = errors.New("ref ballot declares empty active set")
 	errActiveSetNotSorted    = errors.New("active set not sorted")
+	errActiveSetTooLarge     = errors.New("active set too large")
 	errBadActiveSetHash      = errors.New("incorrect active set hash")
 	errMissingBeacon         = errors.New("beacon is missing in ref ballot")
 	errNotEligible           = errors.New("ballot not eligible")
@@ -57,6 +60,17 @@ type Handler struct {
 	validator  eligibilityValidator
 	decoder    ballotDecoder
 	clock      layerClock
+	// validatorOpts are used to create the eligibility Validator if it is not set.
+	validatorOpts []ValidatorOpt
+
+	pending *pendingBallots
+	// ballotsFetch, atxsFetch and txsFetch deduplicate requests for dependencies
+	// shared by the ballots that are processed concurrently.
+	ballotsFetch *fetchGroup[types.BallotID]
+	atxsFetch    *fetchGroup[types.ATXID]
+	txsFetch     *fetchGroup[types.TransactionID]
+	// rules are the syntactic rules applied to every proposal before it is processed.
+	rules []syntacticRule
 }
 
 // Config defines configuration for the handler.
@@ -67,12 +81,28 @@ type Config struct {
 	MaxExceptions          int
 	Hdist                  uint32
 	MinimalActiveSetWeight uint64
+	// SignatureDomainLayer is the first layer where proposals are signed in the PROPOSAL domain.
+	SignatureDomainLayer types.LayerID
+	// MaxPendingBallots is the number of ballots that can wait for a missing base or reference ballot.
+	// Ballots are dropped right away if it is zero.
+	MaxPendingBallots int
+	// PendingBallotsTTL is how long a ballot waits for a missing base or reference ballot.
+	PendingBallotsTTL time.Duration
+	// MaxProposalBytes is the max size of the encoded proposal. Zero is unlimited.
+	MaxProposalBytes int
+	// FetchTimeout bounds the time to fetch the dependencies of the ballot or proposal. Zero is unbounded.
+	FetchTimeout time.Duration
+	// MaxActiveSetSize is the max number of ATXs in the active set of the reference ballot. Zero is unlimited.
+	MaxActiveSetSize int
 }
 
 // defaultConfig for BlockHandler.
 func defaultConfig() Config {
 	return Config{
-		MaxExceptions: 1000,
+		MaxExceptions:     1000,
+		MaxPendingBallots: 1000,
+		PendingBallotsTTL: time.Minute,
+		FetchTimeout:      time.Minute,
 	}
 }
 
@@ -86,6 +116,13 @@ func withValidator(v eligibilityValidator) Opt {
 	}
 }
 
+// WithValidatorOpts configures the eligibility Validator created by the Handler.
+func WithValidatorOpts(opts ...ValidatorOpt) Opt {
+	return func(h *Handler) {
+		h.validatorOpts = append(h.validatorOpts, opts...)
+	}
+}
+
 // WithLogger defines logger for Handler.
 func WithLogger(logger log.Log) Opt {
 	return func(h *Handler) {
@@ -128,8 +165,13 @@ func NewHandler(
 		opt(b)
 	}
 	if b.validator == nil {
-		b.validator = NewEligibilityValidator(b.cfg.LayerSize, b.cfg.LayersPerEpoch, b.cfg.MinimalActiveSetWeight, cdb, bc, m, b.logger, verifier)
+		b.validator = NewEligibilityValidator(b.cfg.LayerSize, b.cfg.LayersPerEpoch, b.cfg.MinimalActiveSetWeight, cdb, bc, m, b.logger, verifier, b.validatorOpts...)
 	}
+	b.pending = newPendingBallots(b.cfg.PendingBallotsTTL, b.cfg.MaxPendingBallots)
+	b.rules = b.syntacticRules()
+	b.ballotsFetch = newFetchGroup(f.GetBallots)
+	b.atxsFetch = newFetchGroup(f.GetAtxs)
+	b.txsFetch = newFetchGroup(f.GetProposalTxs)
 	return b
 }
 
@@ -147,7 +189,9 @@ func (h *Handler) HandleSyncedBallot(ctx context.Context, peer p2p.Peer, data []
 		return fmt.Errorf("ballot before effective genesis: layer %v", b.Layer)
 	}
 
-	if !h.edVerifier.Verify(signing.BALLOT, b.SmesherID, b.SignedBytes(), b.Signature) {
+	if valid, err := h.verifySignature(ctx, signing.BALLOT, b.SmesherID, b.Layer.GetEpoch(), b.SignedBytes(), b.Signature); err != nil {
+		return err
+	} else if !valid {
 		return fmt.Errorf("failed to verify ballot signature")
 	}
 
@@ -167,15 +211,65 @@ func (h *Handler) HandleSyncedBallot(ctx context.Context, peer p2p.Peer, data []
 	ballotDuration.WithLabelValues(peerHashes).Observe(float64(time.Since(t1)))
 
 	logger = logger.WithFields(b.ID(), b.Layer)
-	if _, err := h.processBallot(ctx, logger, &b); err != nil {
+	if _, err := h.processBallot(ctx, logger, &b, nil); err != nil {
 		if errors.Is(err, errKnownBallot) {
 			return nil
 		}
+		h.waitFor(ctx, err, &pendingMessage{ballot: b.ID(), peer: peer, data: data})
 		return err
 	}
 	return nil
 }
 
+// HandleSyncedBallots verifies eligibility proofs of the ballots received from sync in a single
+// batch. Every ballot is then handled by HandleSyncedBallot, that doesn't verify valid proofs again.
+// Returned errors correspond to the ballots, and are not nil only for the ballots with invalid proofs.
+func (h *Handler) HandleSyncedBallots(ctx context.Context, _ p2p.Peer, data [][]byte) []error {
+	var (
+		errs = make([]error, len(data))
+		blts = make([]*types.Ballot, 0, len(data))
+		pos  = make([]int, 0, len(data))
+	)
+	for i := range data {
+		var b types.Ballot
+		// malformed ballots are rejected by HandleSyncedBallot
+		if err := codec.Decode(data[i], &b); err != nil {
+			continue
+		}
+		if err := b.Initialize(); err != nil {
+			continue
+		}
+		blts = append(blts, &b)
+		pos = append(pos, i)
+	}
+	for i, err := range h.validator.VerifyProofs(ctx, blts) {
+		if err != nil {
+			notEligible.Inc()
+			errs[pos[i]] = fmt.Errorf("%w: ballot %s: %v", errNotEligible, blts[i].ID(), err)
+		}
+	}
+	return errs
+}
+
+// verifySignature checks that the message of the identity is signed by one of the keys accepted in the epoch.
+// If none of the known keys match, key rotations of the identity are fetched and the signature is checked again.
+func (h *Handler) verifySignature(ctx context.Context, d signing.Domain, nodeID types.NodeID, epoch types.EpochID, msg []byte, sig types.EdSignature) (bool, error) {
+	verify := func(key types.NodeID) bool {
+		return h.edVerifier.Verify(d, key, msg, sig)
+	}
+	if valid, err := keyrotations.Verify(h.cdb, nodeID, epoch, verify); err != nil || valid {
+		return valid, err
+	}
+	if err := h.fetcher.GetKeyRotations(ctx, []types.NodeID{nodeID}); err != nil {
+		h.logger.WithContext(ctx).With().Debug("failed to fetch key rotations",
+			log.Stringer("smesher", nodeID),
+			log.Err(err),
+		)
+		return false, nil
+	}
+	return keyrotations.Verify(h.cdb, nodeID, epoch, verify)
+}
+
 // collectHashes gathers all hashes in a proposal or ballot.
 func collectHashes(a any) []types.Hash32 {
 	p, ok := a.(types.Proposal)
@@ -198,7 +292,7 @@ func collectHashes(a any) []types.Hash32 {
 
 // HandleSyncedProposal handles Proposal data from sync.
 func (h *Handler) HandleSyncedProposal(ctx context.Context, peer p2p.Peer, data []byte) error {
-	err := h.HandleProposal(ctx, peer, data)
+	err := h.handleProposal(ctx, peer, data, false)
 	if errors.Is(err, errKnownProposal) {
 		return nil
 	}
@@ -207,51 +301,29 @@ func (h *Handler) HandleSyncedProposal(ctx context.Context, peer p2p.Peer, data
 
 // HandleProposal is the gossip receiver for Proposal.
 func (h *Handler) HandleProposal(ctx context.Context, peer p2p.Peer, data []byte) error {
-	err := h.handleProposal(ctx, peer, data)
+	err := h.handleProposal(ctx, peer, data, true)
 	if err != nil {
 		h.logger.WithContext(ctx).With().Debug("failed to process proposal gossip", log.Err(err))
 	}
 	return err
 }
 
-// HandleProposal is the gossip receiver for Proposal.
-func (h *Handler) handleProposal(ctx context.Context, peer p2p.Peer, data []byte) error {
+// handleProposal handles Proposal data from gossip or sync. Only gossip data is required to be
+// canonically encoded, as it is relayed as is.
+func (h *Handler) handleProposal(ctx context.Context, peer p2p.Peer, data []byte, gossip bool) error {
 	receivedTime := time.Now()
 	logger := h.logger.WithContext(ctx)
 
 	t0 := time.Now()
-	var p types.Proposal
-	if err := codec.Decode(data, &p); err != nil {
-		malformed.Inc()
-		return errMalformedData
-	}
-	if p.Layer <= types.GetEffectiveGenesis() {
-		preGenesis.Inc()
-		return fmt.Errorf("proposal before effective genesis: layer %v", p.Layer)
+	msg := &proposalMessage{data: data, gossip: gossip}
+	if err := h.checkSyntax(ctx, msg); err != nil {
+		return err
 	}
+	p := &msg.proposal
 
 	latency := receivedTime.Sub(h.clock.LayerToTime(p.Layer))
 	metrics.ReportMessageLatency(pubsub.ProposalProtocol, pubsub.ProposalProtocol, latency)
 
-	if !h.edVerifier.Verify(signing.BALLOT, p.SmesherID, p.SignedBytes(), p.Signature) {
-		badSigBallot.Inc()
-		return fmt.Errorf("failed to verify proposal signature")
-	}
-	if !h.edVerifier.Verify(signing.BALLOT, p.Ballot.SmesherID, p.Ballot.SignedBytes(), p.Ballot.Signature) {
-		badSigProposal.Inc()
-		return fmt.Errorf("failed to verify ballot signature")
-	}
-
-	// set the proposal ID when received
-	if err := p.Initialize(); err != nil {
-		failedInit.Inc()
-		return errInitialize
-	}
-
-	if p.AtxID == types.EmptyATXID || p.AtxID == h.cfg.GoldenATXID {
-		badData.Inc()
-		return errInvalidATXID
-	}
 	proposalDuration.WithLabelValues(decodeInit).Observe(float64(time.Since(t0)))
 
 	logger = logger.WithFields(p.ID(), p.Ballot.ID(), p.Layer)
@@ -267,27 +339,31 @@ func (h *Handler) handleProposal(ctx context.Context, peer p2p.Peer, data []byte
 
 	logger.With().Info("new proposal", log.Int("num_txs", len(p.TxIDs)))
 	t2 := time.Now()
-	h.fetcher.RegisterPeerHashes(peer, collectHashes(p))
+	h.fetcher.RegisterPeerHashes(peer, collectHashes(*p))
 	proposalDuration.WithLabelValues(peerHashes).Observe(float64(time.Since(t2)))
 
 	t3 := time.Now()
-	proof, err := h.processBallot(ctx, logger, &p.Ballot)
+	proof, err := h.processBallot(ctx, logger, &p.Ballot, p.TxIDs)
 	if err != nil && !errors.Is(err, errKnownBallot) && !errors.Is(err, errMaliciousBallot) {
+		h.waitFor(ctx, err, &pendingMessage{ballot: p.Ballot.ID(), peer: peer, data: data, proposal: true})
 		return err
 	}
 	proposalDuration.WithLabelValues(ballot).Observe(float64(time.Since(t3)))
 
-	// FIXME: how to handle proposals from malicious identity?
-	t4 := time.Now()
-	if err := h.checkTransactions(ctx, &p); err != nil {
-		unavailRef.Inc()
-		return err
+	if errors.Is(err, errKnownBallot) {
+		// the ballot was processed before the proposal, but transactions may still be missing
+		t4 := time.Now()
+		if err := h.resolveDependencies(ctx, p.Layer.GetEpoch(), collectDependencies(nil, p.TxIDs)); err != nil {
+			unavailRef.Inc()
+			return err
+		}
+		proposalDuration.WithLabelValues(fetchTXs).Observe(float64(time.Since(t4)))
 	}
-	proposalDuration.WithLabelValues(fetchTXs).Observe(float64(time.Since(t4)))
 
+	// FIXME: how to handle proposals from malicious identity?
 	logger.With().Debug("proposal is syntactically valid")
 	t5 := time.Now()
-	if err := proposals.Add(h.cdb, &p); err != nil {
+	if err := proposals.Add(h.cdb, p); err != nil {
 		if errors.Is(err, sql.ErrObjectExists) {
 			known.Inc()
 			return fmt.Errorf("%w proposal %s", errKnownProposal, p.ID())
@@ -305,7 +381,7 @@ func (h *Handler) handleProposal(ctx context.Context, peer p2p.Peer, data []byte
 	}
 	proposalDuration.WithLabelValues(linkTxs).Observe(float64(time.Since(t6)))
 
-	reportProposalMetrics(&p)
+	reportProposalMetrics(p)
 
 	// broadcast malfeasance proof last as the verification of the proof will take place
 	// in the same goroutine
@@ -327,7 +403,9 @@ func (h *Handler) handleProposal(ctx context.Context, peer p2p.Peer, data []byte
 	return nil
 }
 
-func (h *Handler) processBallot(ctx context.Context, logger log.Log, b *types.Ballot) (*types.MalfeasanceProof, error) {
+// processBallot validates and saves the ballot. Transactions of the proposal are fetched
+// together with the ballot dependencies, before the ballot is validated.
+func (h *Handler) processBallot(ctx context.Context, logger log.Log, b *types.Ballot, txs []types.TransactionID) (*types.MalfeasanceProof, error) {
 	t0 := time.Now()
 	if has, err := ballots.Has(h.cdb, b.ID()); err != nil {
 		logger.With().Error("failed to look up ballot", log.Err(err))
@@ -340,20 +418,21 @@ func (h *Handler) processBallot(ctx context.Context, logger log.Log, b *types.Ba
 
 	logger.With().Info("new ballot", log.Inline(b))
 
-	decoded, err := h.checkBallotSyntacticValidity(ctx, logger, b)
+	decoded, err := h.checkBallotSyntacticValidity(ctx, logger, b, txs)
 	if err != nil {
 		return nil, err
 	}
 
 	t1 := time.Now()
-	proof, err := h.mesh.AddBallot(ctx, b)
+	proof, added, err := h.mesh.AddBallot(ctx, b)
 	if err != nil {
-		if errors.Is(err, sql.ErrObjectExists) {
-			known.Inc()
-			return nil, fmt.Errorf("%w: ballot %s", errKnownBallot, b.ID())
-		}
 		return nil, fmt.Errorf("save ballot: %w", err)
 	}
+	if !added {
+		// the ballot was added concurrently and is already passed to tortoise
+		known.Inc()
+		return nil, fmt.Errorf("%w: ballot %s", errKnownBallot, b.ID())
+	}
 	ballotDuration.WithLabelValues(dbSave).Observe(float64(time.Since(t1)))
 	if err := h.decoder.StoreBallot(decoded); err != nil {
 		if errors.Is(err, tortoise.ErrBallotExists) {
@@ -362,10 +441,11 @@ func (h *Handler) processBallot(ctx context.Context, logger log.Log, b *types.Ba
 		return nil, fmt.Errorf("store decoded ballot %s: %w", decoded.ID, err)
 	}
 	reportVotesMetrics(b)
+	h.replay(ctx, b.ID())
 	return proof, nil
 }
 
-func (h *Handler) checkBallotSyntacticValidity(ctx context.Context, logger log.Log, b *types.Ballot) (*tortoise.DecodedBallot, error) {
+func (h *Handler) checkBallotSyntacticValidity(ctx context.Context, logger log.Log, b *types.Ballot, txs []types.TransactionID) (*tortoise.DecodedBallot, error) {
 	t0 := time.Now()
 	if err := h.checkBallotDataIntegrity(b); err != nil {
 		badData.Inc()
@@ -374,7 +454,7 @@ func (h *Handler) checkBallotSyntacticValidity(ctx context.Context, logger log.L
 	ballotDuration.WithLabelValues(dataCheck).Observe(float64(time.Since(t0)))
 
 	t1 := time.Now()
-	if err := h.checkBallotDataAvailability(ctx, b); err != nil {
+	if err := h.resolveDependencies(ctx, b.Layer.GetEpoch(), collectDependencies(b, txs)); err != nil {
 		unavailRef.Inc()
 		return nil, err
 	}
@@ -412,7 +492,8 @@ func (h *Handler) checkBallotSyntacticValidity(ctx context.Context, logger log.L
 	return decoded, nil
 }
 
-func (h *Handler) checkBallotDataIntegrity(b *types.Ballot) error {
+// checkEpochDataPresence checks that epoch data with the beacon is declared only in the ref ballot.
+func checkEpochDataPresence(b *types.Ballot) error {
 	if b.RefBallot == types.EmptyBallotID {
 		// this is the smesher's first Ballot in this epoch, should contain EpochData
 		if b.EpochData == nil {
@@ -421,24 +502,43 @@ func (h *Handler) checkBallotDataIntegrity(b *types.Ballot) error {
 		if b.EpochData.Beacon == types.EmptyBeacon {
 			return errMissingBeacon
 		}
-		if len(b.ActiveSet) == 0 {
-			return errEmptyActiveSet
-		}
-		for i := 0; i < len(b.ActiveSet)-1; i++ {
-			if bytes.Compare(b.ActiveSet[i].Bytes(), b.ActiveSet[i+1].Bytes()) >= 0 {
-				return errActiveSetNotSorted
-			}
-		}
-		activeSetHash := types.ATXIDList(b.ActiveSet).Hash()
-		if activeSetHash != b.EpochData.ActiveSetHash {
-			return errBadActiveSetHash
-		}
 	} else if b.EpochData != nil {
 		return errUnexpectedEpochData
 	}
 	return nil
 }
 
+func (h *Handler) checkBallotDataIntegrity(b *types.Ballot) error {
+	if err := checkEpochDataPresence(b); err != nil {
+		return err
+	}
+	if b.RefBallot == types.EmptyBallotID {
+		return checkActiveSet(b, h.cfg.MaxActiveSetSize)
+	}
+	return nil
+}
+
+// checkActiveSet checks that the active set declared in the ref ballot is not empty, not larger
+// than maxSize, sorted without duplicates and matches the hash in the epoch data.
+func checkActiveSet(b *types.Ballot, maxSize int) error {
+	if len(b.ActiveSet) == 0 {
+		return errEmptyActiveSet
+	}
+	if maxSize > 0 && len(b.ActiveSet) > maxSize {
+		return fmt.Errorf("%w: %d atxs with max allowed %d", errActiveSetTooLarge, len(b.ActiveSet), maxSize)
+	}
+	for i := 0; i < len(b.ActiveSet)-1; i++ {
+		// strict order also rules out duplicates
+		if bytes.Compare(b.ActiveSet[i].Bytes(), b.ActiveSet[i+1].Bytes()) >= 0 {
+			return errActiveSetNotSorted
+		}
+	}
+	if types.ATXIDList(b.ActiveSet).Hash() != b.EpochData.ActiveSetHash {
+		return errBadActiveSetHash
+	}
+	return nil
+}
+
 func (h *Handler) checkVotesConsistency(ctx context.Context, b *types.Ballot) error {
 	exceptions := map[types.BlockID]struct{}{}
 	layers := make(map[types.LayerID]types.BlockID)
@@ -472,9 +572,10 @@ func (h *Handler) checkVotesConsistency(ctx context.Context, b *types.Ballot) er
 		}
 		layers[vote.LayerID] = vote.ID
 	}
-	if len(exceptions) > h.cfg.MaxExceptions {
+	// the same limit is used by tortoise when it encodes votes for the local ballot
+	if n := len(b.Votes.Support) + len(b.Votes.Against); n > h.cfg.MaxExceptions {
 		return fmt.Errorf("%w: %d exceptions with max allowed %d in ballot %s",
-			errExceptionsOverflow, len(exceptions), h.cfg.MaxExceptions, b.ID())
+			errExceptionsOverflow, n, h.cfg.MaxExceptions, b.ID())
 	}
 	// a ballot should not abstain on a layer that it voted for/against on block in that layer.
 	for _, lid := range b.Votes.Abstain {
@@ -485,53 +586,6 @@ func (h *Handler) checkVotesConsistency(ctx context.Context, b *types.Ballot) er
 	return nil
 }
 
-func (h *Handler) checkBallotDataAvailability(ctx context.Context, b *types.Ballot) error {
-	var blts []types.BallotID
-	if b.Votes.Base != types.EmptyBallotID {
-		blts = append(blts, b.Votes.Base)
-	}
-	if b.RefBallot != types.EmptyBallotID {
-		blts = append(blts, b.RefBallot)
-	}
-	if err := h.fetcher.GetBallots(ctx, blts); err != nil {
-		return fmt.Errorf("fetch ballots: %w", err)
-	}
-
-	if err := h.fetchReferencedATXs(ctx, b); err != nil {
-		return fmt.Errorf("fetch referenced ATXs: %w", err)
-	}
-	return nil
-}
-
-func (h *Handler) fetchReferencedATXs(ctx context.Context, b *types.Ballot) error {
-	atxs := []types.ATXID{b.AtxID}
-	if b.EpochData != nil {
-		atxs = append(atxs, b.ActiveSet...)
-	}
-	if err := h.fetcher.GetAtxs(ctx, h.decoder.GetMissingActiveSet(b.Layer.GetEpoch(), atxs)); err != nil {
-		return fmt.Errorf("proposal get ATXs: %w", err)
-	}
-	return nil
-}
-
-func (h *Handler) checkTransactions(ctx context.Context, p *types.Proposal) error {
-	if len(p.TxIDs) == 0 {
-		return nil
-	}
-
-	set := make(map[types.TransactionID]struct{}, len(p.TxIDs))
-	for _, tx := range p.TxIDs {
-		if _, exist := set[tx]; exist {
-			return errDuplicateTX
-		}
-		set[tx] = struct{}{}
-	}
-	if err := h.fetcher.GetProposalTxs(ctx, p.TxIDs); err != nil {
-		return fmt.Errorf("proposal get TXs: %w", err)
-	}
-	return nil
-}
-
 func reportProposalMetrics(p *types.Proposal) {
 	proposalSize.WithLabelValues().Observe(float64(len(p.SignedBytes())))
 	numTxsInProposal.WithLabelValues().Observe(float64(len(p.TxIDs)))
@@ -26,6 +26,7 @@ import (
 	"github.com/spacemeshos/go-spacemesh/sql/atxs"
 	"github.com/spacemeshos/go-spacemesh/sql/ballots"
 	"github.com/spacemeshos/go-spacemesh/sql/blocks"
+	"github.com/spacemeshos/go-spacemesh/sql/keyrotations"
 	"github.com/spacemeshos/go-spacemesh/sql/proposals"
 	"github.com/spacemeshos/go-spacemesh/system/mocks"
 	"github.com/spacemeshos/go-spacemesh/tortoise"
@@ -153,8 +154,9 @@ func withAnyRefData() createBallotOpt {
 			return bytes.Compare(activeSet[i].Bytes(), activeSet[j].Bytes()) < 0
 		})
 		b.EpochData = &types.EpochData{
-			ActiveSetHash: activeSet.Hash(),
-			Beacon:        types.RandomBeacon(),
+			ActiveSetHash:    activeSet.Hash(),
+			Beacon:           types.RandomBeacon(),
+			EligibilityCount: 1,
 		}
 		b.ActiveSet = activeSet
 	}
@@ -171,6 +173,7 @@ func withTransactions(ids ...types.TransactionID) createProposalOpt {
 func createProposal(t *testing.T, opts ...any) *types.Proposal {
 	t.Helper()
 	b := types.RandomBallot()
+	b.EligibilityProofs = []types.VotingEligibility{{J: 0, Sig: types.RandomVrfSignature()}}
 	p := &types.Proposal{
 		InnerProposal: types.InnerProposal{
 			Ballot: *b,
@@ -281,10 +284,56 @@ func TestBallot_BadSignature(t *testing.T) {
 	b := createBallot(t)
 	b.Signature[types.EdSignatureSize-1] = 0xff
 	data := encodeBallot(t, b)
+	th.mf.EXPECT().GetKeyRotations(gomock.Any(), []types.NodeID{b.SmesherID}).Return(nil)
 	got := th.HandleSyncedBallot(context.Background(), p2p.NoPeer, data)
 	require.ErrorContains(t, got, "failed to verify ballot signature")
 }
 
+func TestBallot_RotatedKeySignature(t *testing.T) {
+	th := createTestHandlerNoopDecoder(t)
+	signer, err := signing.NewEdSigner()
+	require.NoError(t, err)
+	rotated, err := signing.NewEdSigner()
+	require.NoError(t, err)
+	rotation := &types.KeyRotation{
+		InnerKeyRotation: types.InnerKeyRotation{NodeID: signer.NodeID(), NewKey: rotated.NodeID(), Epoch: 5},
+	}
+	verify := func(key *signing.EdSigner, epoch types.EpochID) (bool, error) {
+		msg := []byte("ballot")
+		return th.verifySignature(context.Background(), signing.BALLOT, signer.NodeID(), epoch, msg, key.Sign(signing.BALLOT, msg))
+	}
+
+	// rotation arrives after the ballot signed by the rotated key, it is fetched
+	th.mf.EXPECT().GetKeyRotations(gomock.Any(), []types.NodeID{signer.NodeID()}).DoAndReturn(
+		func(context.Context, []types.NodeID) error {
+			return keyrotations.Add(th.cdb, rotation)
+		})
+	valid, err := verify(rotated, 5)
+	require.NoError(t, err)
+	require.True(t, valid)
+
+	for _, tc := range []struct {
+		desc  string
+		key   *signing.EdSigner
+		epoch types.EpochID
+		valid bool
+	}{
+		{"identity before transition", signer, 4, true},
+		{"rotated before transition", rotated, 4, false},
+		{"identity in transition", signer, 5, true},
+		{"rotated in transition", rotated, 5, true},
+		{"identity after transition", signer, 6, false},
+		{"rotated after transition", rotated, 6, true},
+	} {
+		if !tc.valid {
+			th.mf.EXPECT().GetKeyRotations(gomock.Any(), []types.NodeID{signer.NodeID()}).Return(nil)
+		}
+		valid, err := verify(tc.key, tc.epoch)
+		require.NoError(t, err, tc.desc)
+		require.Equal(t, tc.valid, valid, tc.desc)
+	}
+}
+
 func TestBallot_KnownBallot(t *testing.T) {
 	th := createTestHandlerNoopDecoder(t)
 	b := createBallot(t)
@@ -489,7 +538,7 @@ func TestBallot_BallotDoubleVotedOutsideHdist(t *testing.T) {
 			require.Equal(t, b.ID(), ballot.ID())
 			return true, nil
 		})
-	th.mm.EXPECT().AddBallot(context.Background(), b).Return(nil, nil)
+	th.mm.EXPECT().AddBallot(context.Background(), b).Return(nil, true, nil)
 	require.NoError(t, th.HandleSyncedBallot(context.Background(), peer, data))
 }
 
@@ -595,6 +644,35 @@ func TestBallot_ExceedMaxExceptions(t *testing.T) {
 	require.ErrorIs(t, th.HandleSyncedBallot(context.Background(), peer, data), errExceptionsOverflow)
 }
 
+func TestBallot_ExceedMaxExceptionsWithAgainst(t *testing.T) {
+	th := createTestHandlerNoopDecoder(t)
+	lid := types.LayerID(100)
+	supported := []*types.Block{
+		types.NewExistingBlock(types.BlockID{1}, types.InnerBlock{LayerIndex: lid.Sub(1)}),
+		types.NewExistingBlock(types.BlockID{2}, types.InnerBlock{LayerIndex: lid.Sub(2)}),
+	}
+	against := []*types.Block{
+		types.NewExistingBlock(types.BlockID{3}, types.InnerBlock{LayerIndex: lid.Sub(3)}),
+		types.NewExistingBlock(types.BlockID{4}, types.InnerBlock{LayerIndex: lid.Sub(4)}),
+	}
+	b := createBallot(t,
+		withLayer(lid),
+		withSupportBlocks(supported...),
+		withAgainstBlocks(against...),
+	)
+	createAtx(t, th.cdb.Database, b.Layer.GetEpoch()-1, b.AtxID, b.SmesherID)
+	for _, blk := range append(supported, against...) {
+		require.NoError(t, blocks.Add(th.cdb, blk))
+	}
+	data := encodeBallot(t, b)
+	peer := p2p.Peer("buddy")
+	th.mf.EXPECT().RegisterPeerHashes(peer, collectHashes(*b))
+	th.mf.EXPECT().GetBallots(gomock.Any(), []types.BallotID{b.Votes.Base, b.RefBallot}).Return(nil).Times(1)
+	th.md.EXPECT().GetMissingActiveSet(gomock.Any(), types.ATXIDList{b.AtxID}).Return(types.ATXIDList{b.AtxID})
+	th.mf.EXPECT().GetAtxs(gomock.Any(), types.ATXIDList{b.AtxID}).Return(nil).Times(1)
+	require.ErrorIs(t, th.HandleSyncedBallot(context.Background(), peer, data), errExceptionsOverflow)
+}
+
 func TestBallot_BallotsNotAvailable(t *testing.T) {
 	th := createTestHandlerNoopDecoder(t)
 	b := createBallot(t)
@@ -651,6 +729,31 @@ func TestBallot_ErrorCheckingEligible(t *testing.T) {
 	require.ErrorIs(t, th.HandleSyncedBallot(context.Background(), peer, data), errNotEligible)
 }
 
+func TestBallot_HandleSyncedBallots(t *testing.T) {
+	th := createTestHandlerNoopDecoder(t)
+	lid := types.LayerID(100)
+	good := createBallot(t, withLayer(lid))
+	bad := createBallot(t, withLayer(lid))
+	th.mv.EXPECT().VerifyProofs(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(_ context.Context, blts []*types.Ballot) []error {
+			require.Len(t, blts, 2)
+			require.Equal(t, good.ID(), blts[0].ID())
+			require.Equal(t, bad.ID(), blts[1].ID())
+			return []error{nil, errIncorrectVRFSig}
+		})
+	errs := th.HandleSyncedBallots(context.Background(), "buddy", [][]byte{
+		encodeBallot(t, good),
+		[]byte("malformed"),
+		encodeBallot(t, bad),
+	})
+	require.Len(t, errs, 3)
+	require.NoError(t, errs[0])
+	// the malformed ballot is rejected when it's handled
+	require.NoError(t, errs[1])
+	require.ErrorIs(t, errs[2], errNotEligible)
+	require.ErrorContains(t, errs[2], bad.ID().String())
+}
+
 func TestBallot_NotEligible(t *testing.T) {
 	th := createTestHandlerNoopDecoder(t)
 	lid := types.LayerID(100)
@@ -707,7 +810,7 @@ func TestBallot_Success(t *testing.T) {
 			require.Equal(t, b.ID(), ballot.ID())
 			return true, nil
 		})
-	th.mm.EXPECT().AddBallot(context.Background(), b).Return(nil, nil)
+	th.mm.EXPECT().AddBallot(context.Background(), b).Return(nil, true, nil)
 	decoded := &tortoise.DecodedBallot{BallotTortoiseData: b.ToTortoiseData()}
 	th.md.EXPECT().DecodeBallot(decoded.BallotTortoiseData).Return(decoded, nil)
 	th.md.EXPECT().StoreBallot(decoded).Return(nil)
@@ -741,7 +844,7 @@ func TestBallot_MaliciousProofIgnoredInSyncFlow(t *testing.T) {
 			require.Equal(t, b.ID(), ballot.ID())
 			return true, nil
 		})
-	th.mm.EXPECT().AddBallot(context.Background(), b).Return(&types.MalfeasanceProof{Layer: lid}, nil)
+	th.mm.EXPECT().AddBallot(context.Background(), b).Return(&types.MalfeasanceProof{Layer: lid}, true, nil)
 	decoded := &tortoise.DecodedBallot{BallotTortoiseData: b.ToTortoiseData()}
 	th.md.EXPECT().DecodeBallot(decoded.BallotTortoiseData).Return(decoded, nil)
 	th.md.EXPECT().StoreBallot(decoded).Return(nil)
@@ -777,7 +880,7 @@ func TestBallot_RefBallot(t *testing.T) {
 			require.Equal(t, b.ID(), ballot.ID())
 			return true, nil
 		})
-	th.mm.EXPECT().AddBallot(context.Background(), b).Return(nil, nil)
+	th.mm.EXPECT().AddBallot(context.Background(), b).Return(nil, true, nil)
 	require.NoError(t, th.HandleSyncedBallot(context.Background(), peer, data))
 }
 
@@ -823,11 +926,76 @@ func TestBallot_DecodedStoreFailure(t *testing.T) {
 
 	decoded := &tortoise.DecodedBallot{BallotTortoiseData: b.ToTortoiseData()}
 	th.md.EXPECT().DecodeBallot(decoded.BallotTortoiseData).Return(decoded, nil)
-	th.mm.EXPECT().AddBallot(context.Background(), b).Return(nil, nil)
+	th.mm.EXPECT().AddBallot(context.Background(), b).Return(nil, true, nil)
 	th.md.EXPECT().StoreBallot(decoded).Return(expected)
 	require.ErrorIs(t, th.HandleSyncedBallot(context.Background(), peer, data), expected)
 }
 
+func TestBallot_TrailingBytes(t *testing.T) {
+	th := createTestHandlerNoopDecoder(t)
+	b := createBallot(t)
+	b.EpochData = nil
+	data := encodeBallot(t, b)
+	padded := append(append([]byte{}, data...), 0)
+	require.ErrorIs(t, th.HandleSyncedBallot(context.Background(), p2p.NoPeer, padded), errMalformedData)
+}
+
+func TestProposal_NonCanonicalData(t *testing.T) {
+	th := createTestHandlerNoopDecoder(t)
+	p := createProposal(t)
+	data := encodeProposal(t, p)
+
+	t.Run("trailing bytes", func(t *testing.T) {
+		padded := append(append([]byte{}, data...), 0)
+		require.ErrorIs(t, th.HandleProposal(context.Background(), "", padded), errMalformedData)
+		require.ErrorIs(t, th.HandleSyncedProposal(context.Background(), p2p.NoPeer, padded), errMalformedData)
+	})
+	t.Run("non canonical option", func(t *testing.T) {
+		p := createProposal(t, createBallotOpt(func(b *types.Ballot) { b.EpochData = nil }))
+		data := encodeProposal(t, p)
+		inner, err := codec.Encode(&p.Ballot.InnerBallot)
+		require.NoError(t, err)
+		altered := append([]byte{}, data...)
+		// EpochData is the last field of the InnerBallot, absent option is encoded as 0.
+		require.Equal(t, byte(0), altered[len(inner)-1])
+		altered[len(inner)-1] = 2
+		var decoded types.Proposal
+		require.NoError(t, codec.Decode(altered, &decoded))
+		// gossip relays the data as received, non canonical encoding is rejected
+		require.ErrorIs(t, th.HandleProposal(context.Background(), "", altered), errMalformedData)
+	})
+}
+
+func BenchmarkDecodeProposal(b *testing.B) {
+	p := types.RandomBallot()
+	p.EligibilityProofs = make([]types.VotingEligibility, 10)
+	p.Votes.Support = make([]types.Vote, 100)
+	proposal := &types.Proposal{InnerProposal: types.InnerProposal{
+		Ballot: *p,
+		TxIDs:  make([]types.TransactionID, 500),
+	}}
+	data, err := codec.Encode(proposal)
+	require.NoError(b, err)
+	for _, bc := range []struct {
+		name   string
+		decode func([]byte, codec.Codec) error
+	}{
+		{"sync", func(buf []byte, value codec.Codec) error { return codec.Decode(buf, value) }},
+		{"gossip", codec.DecodeStrict},
+	} {
+		bc := bc
+		b.Run(bc.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				var decoded types.Proposal
+				if err := bc.decode(data, &decoded); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
 func TestProposal_MalformedData(t *testing.T) {
 	th := createTestHandlerNoopDecoder(t)
 	p := createProposal(t)
@@ -838,6 +1006,16 @@ func TestProposal_MalformedData(t *testing.T) {
 	checkProposal(t, th.cdb, p, false)
 }
 
+func TestProposal_TooLarge(t *testing.T) {
+	th := createTestHandlerNoopDecoder(t)
+	p := createProposal(t)
+	data := encodeProposal(t, p)
+	th.cfg.MaxProposalBytes = len(data) - 1
+	require.ErrorIs(t, th.HandleSyncedProposal(context.Background(), p2p.NoPeer, data), errProposalTooLarge)
+	require.ErrorIs(t, th.HandleProposal(context.Background(), "", data), pubsub.ErrValidationReject)
+	checkProposal(t, th.cdb, p, false)
+}
+
 func TestProposal_BeforeEffectiveGenesis(t *testing.T) {
 	th := createTestHandlerNoopDecoder(t)
 	p := createProposal(t)
@@ -850,11 +1028,43 @@ func TestProposal_BeforeEffectiveGenesis(t *testing.T) {
 	checkProposal(t, th.cdb, p, false)
 }
 
+func TestProposal_DroppedEarly(t *testing.T) {
+	th := createTestHandlerNoopDecoder(t)
+	for _, tc := range []struct {
+		desc string
+		opt  createBallotOpt
+		err  error
+	}{
+		{
+			desc: "empty atx",
+			opt:  func(b *types.Ballot) { b.AtxID = types.EmptyATXID },
+			err:  errInvalidATXID,
+		},
+		{
+			desc: "golden atx",
+			opt:  func(b *types.Ballot) { b.AtxID = th.cfg.GoldenATXID },
+			err:  errInvalidATXID,
+		},
+	} {
+		tc := tc
+		t.Run(tc.desc, func(t *testing.T) {
+			p := createProposal(t, tc.opt)
+			data := encodeProposal(t, p)
+			// active set is not decoded for dropped proposals, therefore truncated data must be dropped too.
+			for _, buf := range [][]byte{data, data[:len(data)/2]} {
+				require.ErrorIs(t, th.HandleProposal(context.Background(), "", buf), tc.err)
+			}
+			checkProposal(t, th.cdb, p, false)
+		})
+	}
+}
+
 func TestProposal_BadSignature(t *testing.T) {
 	th := createTestHandlerNoopDecoder(t)
 	p := createProposal(t)
 	p.Signature = types.EmptyEdSignature
 	data := encodeProposal(t, p)
+	th.mf.EXPECT().GetKeyRotations(gomock.Any(), []types.NodeID{p.SmesherID}).Return(nil).AnyTimes()
 	got := th.HandleSyncedProposal(context.Background(), p2p.NoPeer, data)
 	require.ErrorContains(t, got, "failed to verify proposal signature")
 
@@ -862,6 +1072,24 @@ func TestProposal_BadSignature(t *testing.T) {
 	checkProposal(t, th.cdb, p, false)
 }
 
+func TestProposal_SignatureDomainUpgrade(t *testing.T) {
+	th := createTestHandlerNoopDecoder(t)
+	p := createProposal(t)
+	createAtx(t, th.cdb.Database, p.Layer.GetEpoch()-1, p.AtxID, p.SmesherID)
+	require.NoError(t, ballots.Add(th.cdb, &p.Ballot))
+	require.NoError(t, proposals.Add(th.cdb, p))
+	data := encodeProposal(t, p)
+
+	// signature in the ballot domain is valid before the upgrade.
+	th.cfg.SignatureDomainLayer = p.Layer.Add(1)
+	require.NoError(t, th.HandleSyncedProposal(context.Background(), p2p.NoPeer, data))
+
+	// and can't be replayed after it.
+	th.cfg.SignatureDomainLayer = p.Layer
+	th.mf.EXPECT().GetKeyRotations(gomock.Any(), []types.NodeID{p.SmesherID}).Return(nil)
+	require.ErrorContains(t, th.HandleSyncedProposal(context.Background(), p2p.NoPeer, data), "failed to verify proposal signature")
+}
+
 func TestProposal_InconsistentSmeshers(t *testing.T) {
 	th := createTestHandlerNoopDecoder(t)
 	p := &types.Proposal{
@@ -870,6 +1098,7 @@ func TestProposal_InconsistentSmeshers(t *testing.T) {
 			TxIDs:  []types.TransactionID{types.RandomTransactionID(), types.RandomTransactionID()},
 		},
 	}
+	p.EligibilityProofs = []types.VotingEligibility{{J: 0, Sig: types.RandomVrfSignature()}}
 	signer1, err := signing.NewEdSigner()
 	require.NoError(t, err)
 	signer2, err := signing.NewEdSigner()
@@ -880,6 +1109,7 @@ func TestProposal_InconsistentSmeshers(t *testing.T) {
 	p.SmesherID = signer2.NodeID()
 
 	data := encodeProposal(t, p)
+	th.mf.EXPECT().GetKeyRotations(gomock.Any(), []types.NodeID{p.SmesherID}).Return(nil).AnyTimes()
 	got := th.HandleSyncedProposal(context.Background(), p2p.NoPeer, data)
 	require.ErrorContains(t, got, "failed to verify proposal signature")
 
@@ -901,38 +1131,12 @@ func TestProposal_KnownProposal(t *testing.T) {
 
 func TestProposal_DuplicateTXs(t *testing.T) {
 	th := createTestHandlerNoopDecoder(t)
-	lid := types.LayerID(100)
-	supported := []*types.Block{
-		types.NewExistingBlock(types.BlockID{1}, types.InnerBlock{LayerIndex: lid.Sub(1)}),
-		types.NewExistingBlock(types.BlockID{2}, types.InnerBlock{LayerIndex: lid.Sub(2)}),
-	}
 	tid := types.RandomTransactionID()
-	p := createProposal(t,
-		withLayer(lid),
-		withSupportBlocks(supported...),
-		withTransactions(tid, tid),
-	)
-	createAtx(t, th.cdb.Database, p.Layer.GetEpoch()-1, p.AtxID, p.SmesherID)
-	for _, block := range supported {
-		require.NoError(t, blocks.Add(th.cdb, block))
-	}
+	p := createProposal(t, withLayer(100), withTransactions(tid, tid))
 	data := encodeProposal(t, p)
-	th.mf.EXPECT().GetBallots(gomock.Any(), []types.BallotID{p.Votes.Base, p.RefBallot}).Return(nil).Times(1)
-	th.md.EXPECT().GetMissingActiveSet(gomock.Any(), types.ATXIDList{p.AtxID}).Return(types.ATXIDList{p.AtxID})
-	th.mf.EXPECT().GetAtxs(gomock.Any(), types.ATXIDList{p.AtxID}).Return(nil).Times(1)
-	th.mv.EXPECT().CheckEligibility(gomock.Any(), gomock.Any()).DoAndReturn(
-		func(ctx context.Context, ballot *types.Ballot) (bool, error) {
-			require.Equal(t, p.Ballot.ID(), ballot.ID())
-			return true, nil
-		})
-	th.mm.EXPECT().AddBallot(context.Background(), &p.Ballot).DoAndReturn(
-		func(_ context.Context, got *types.Ballot) (*types.MalfeasanceProof, error) {
-			require.NoError(t, ballots.Add(th.cdb, got))
-			return nil, nil
-		})
-	peer := p2p.Peer("buddy")
-	th.mf.EXPECT().RegisterPeerHashes(peer, collectHashes(*p))
-	require.ErrorIs(t, th.HandleSyncedProposal(context.Background(), peer, data), errDuplicateTX)
+	// duplicate transactions are rejected before any referenced object is fetched
+	require.ErrorIs(t, th.HandleSyncedProposal(context.Background(), p2p.Peer("buddy"), data), errDuplicateTX)
+	require.ErrorIs(t, th.HandleProposal(context.Background(), p2p.Peer("buddy"), data), pubsub.ErrValidationReject)
 	checkProposal(t, th.cdb, p, false)
 }
 
@@ -955,16 +1159,6 @@ func TestProposal_TXsNotAvailable(t *testing.T) {
 	th.mf.EXPECT().GetBallots(gomock.Any(), []types.BallotID{p.Votes.Base, p.RefBallot}).Return(nil).Times(1)
 	th.md.EXPECT().GetMissingActiveSet(gomock.Any(), types.ATXIDList{p.AtxID}).Return(types.ATXIDList{p.AtxID})
 	th.mf.EXPECT().GetAtxs(gomock.Any(), types.ATXIDList{p.AtxID}).Return(nil).Times(1)
-	th.mv.EXPECT().CheckEligibility(gomock.Any(), gomock.Any()).DoAndReturn(
-		func(ctx context.Context, ballot *types.Ballot) (bool, error) {
-			require.Equal(t, p.Ballot.ID(), ballot.ID())
-			return true, nil
-		})
-	th.mm.EXPECT().AddBallot(context.Background(), &p.Ballot).DoAndReturn(
-		func(_ context.Context, got *types.Ballot) (*types.MalfeasanceProof, error) {
-			require.NoError(t, ballots.Add(th.cdb, got))
-			return nil, nil
-		})
 
 	errUnknown := errors.New("unknown")
 	peer := p2p.Peer("buddy")
@@ -972,6 +1166,10 @@ func TestProposal_TXsNotAvailable(t *testing.T) {
 	th.mf.EXPECT().GetProposalTxs(gomock.Any(), p.TxIDs).Return(errUnknown).Times(1)
 	require.ErrorIs(t, th.HandleSyncedProposal(context.Background(), peer, data), errUnknown)
 	checkProposal(t, th.cdb, p, false)
+	// transactions are fetched before the ballot is validated
+	has, err := ballots.Has(th.cdb, p.Ballot.ID())
+	require.NoError(t, err)
+	require.False(t, has)
 }
 
 func TestProposal_FailedToAddProposalTXs(t *testing.T) {
@@ -999,9 +1197,9 @@ func TestProposal_FailedToAddProposalTXs(t *testing.T) {
 			return true, nil
 		})
 	th.mm.EXPECT().AddBallot(context.Background(), &p.Ballot).DoAndReturn(
-		func(_ context.Context, got *types.Ballot) (*types.MalfeasanceProof, error) {
+		func(_ context.Context, got *types.Ballot) (*types.MalfeasanceProof, bool, error) {
 			require.NoError(t, ballots.Add(th.cdb, got))
-			return nil, nil
+			return nil, true, nil
 		})
 	th.mf.EXPECT().GetProposalTxs(gomock.Any(), p.TxIDs).Return(nil).Times(1)
 	peer := p2p.Peer("buddy")
@@ -1040,9 +1238,9 @@ func TestProposal_ProposalGossip_Concurrent(t *testing.T) {
 			return true, nil
 		}).MinTimes(1).MaxTimes(2)
 	th.mm.EXPECT().AddBallot(context.Background(), &p.Ballot).DoAndReturn(
-		func(_ context.Context, got *types.Ballot) (*types.MalfeasanceProof, error) {
+		func(_ context.Context, got *types.Ballot) (*types.MalfeasanceProof, bool, error) {
 			_ = ballots.Add(th.cdb, got)
-			return nil, nil
+			return nil, true, nil
 		}).MinTimes(1).MaxTimes(2)
 	th.mf.EXPECT().GetProposalTxs(gomock.Any(), p.TxIDs).Return(nil).MinTimes(1).MaxTimes(2)
 	th.mm.EXPECT().AddTXsFromProposal(gomock.Any(), p.Layer, p.ID(), p.TxIDs).Return(nil).Times(1)
@@ -1113,9 +1311,9 @@ func TestProposal_BroadcastMaliciousGossip(t *testing.T) {
 		},
 	}
 	th.mm.EXPECT().AddBallot(context.Background(), &pMal.Ballot).DoAndReturn(
-		func(_ context.Context, got *types.Ballot) (*types.MalfeasanceProof, error) {
+		func(_ context.Context, got *types.Ballot) (*types.MalfeasanceProof, bool, error) {
 			_ = ballots.Add(th.cdb, got)
-			return proof, nil
+			return proof, true, nil
 		})
 	th.mf.EXPECT().GetProposalTxs(gomock.Any(), pMal.TxIDs).Return(nil)
 	th.mm.EXPECT().AddTXsFromProposal(gomock.Any(), pMal.Layer, pMal.ID(), pMal.TxIDs)
@@ -1181,7 +1379,7 @@ func TestProposal_ProposalGossip_Fetched(t *testing.T) {
 					}
 					return true, nil
 				})
-			th.mm.EXPECT().AddBallot(context.Background(), &p.Ballot).Return(nil, nil)
+			th.mm.EXPECT().AddBallot(context.Background(), &p.Ballot).Return(nil, true, nil)
 			th.mf.EXPECT().GetProposalTxs(gomock.Any(), p.TxIDs).Return(nil)
 			if tc.propFetched {
 				require.Error(t, th.HandleProposal(context.Background(), peer, data))
@@ -1219,9 +1417,9 @@ func TestProposal_ValidProposal(t *testing.T) {
 			return true, nil
 		})
 	th.mm.EXPECT().AddBallot(context.Background(), &p.Ballot).DoAndReturn(
-		func(_ context.Context, got *types.Ballot) (*types.MalfeasanceProof, error) {
+		func(_ context.Context, got *types.Ballot) (*types.MalfeasanceProof, bool, error) {
 			require.NoError(t, ballots.Add(th.cdb, got))
-			return nil, nil
+			return nil, true, nil
 		})
 	th.mf.EXPECT().GetProposalTxs(gomock.Any(), p.TxIDs).Return(nil).Times(1)
 	peer := p2p.Peer("buddy")
@@ -1256,9 +1454,9 @@ func TestMetrics(t *testing.T) {
 			return true, nil
 		})
 	th.mm.EXPECT().AddBallot(context.Background(), &p.Ballot).DoAndReturn(
-		func(_ context.Context, got *types.Ballot) (*types.MalfeasanceProof, error) {
+		func(_ context.Context, got *types.Ballot) (*types.MalfeasanceProof, bool, error) {
 			require.NoError(t, ballots.Add(th.cdb, got))
-			return nil, nil
+			return nil, true, nil
 		})
 	th.mf.EXPECT().GetProposalTxs(gomock.Any(), p.TxIDs).Return(nil).Times(1)
 	peer := p2p.Peer("buddy")
@@ -11,12 +11,13 @@ import (
 //go:generate mockgen -package=proposals -destination=./mocks.go -source=./interface.go
 
 type meshProvider interface {
-	AddBallot(context.Context, *types.Ballot) (*types.MalfeasanceProof, error)
+	AddBallot(context.Context, *types.Ballot) (*types.MalfeasanceProof, bool, error)
 	AddTXsFromProposal(context.Context, types.LayerID, types.ProposalID, []types.TransactionID) error
 }
 
 type eligibilityValidator interface {
 	CheckEligibility(context.Context, *types.Ballot) (bool, error)
+	VerifyProofs(context.Context, []*types.Ballot) []error
 }
 
 type ballotDecoder interface {
@@ -33,6 +34,10 @@ type nonceFetcher interface {
 	VRFNonce(types.NodeID, types.EpochID) (types.VRFPostIndex, error)
 }
 
+type nonceVerifier interface {
+	VRFNonce(types.NodeID, types.ATXID, *types.VRFPostIndex, *types.PostMetadata, uint32) error
+}
+
 type layerClock interface {
 	CurrentLayer() types.LayerID
 	LayerToTime(types.LayerID) time.Time
@@ -60,7 +60,7 @@ const (
 	linkTxs    = "link"
 
 	dataCheck = "data"   // check data integrity
-	fetchRef  = "fetch"  // check referenced ballots/atxs/blocks are available
+	fetchRef  = "fetch"  // fetch referenced ballots/atxs/txs
 	decode    = "decode" // decoding ballot using tortoise
 	votes     = "votes"  // check votes are consistent
 	eligible  = "elig"   // check ballot eligibility
@@ -90,15 +90,44 @@ var (
 		"number of errors",
 		[]string{"kind"},
 	)
-	malformed      = processErrors.WithLabelValues("mal")
-	failedInit     = processErrors.WithLabelValues("init")
-	known          = processErrors.WithLabelValues("known")
-	preGenesis     = processErrors.WithLabelValues("genesis")
-	badSigProposal = processErrors.WithLabelValues("sigp")
-	badSigBallot   = processErrors.WithLabelValues("sigb")
-	badData        = processErrors.WithLabelValues("data")
-	unavailRef     = processErrors.WithLabelValues("avail")
-	badVote        = processErrors.WithLabelValues("vote")
-	notEligible    = processErrors.WithLabelValues("elig")
-	failedPublish  = processErrors.WithLabelValues("pub")
+	malformed     = processErrors.WithLabelValues("mal")
+	failedInit    = processErrors.WithLabelValues("init")
+	known         = processErrors.WithLabelValues("known")
+	badData       = processErrors.WithLabelValues("data")
+	unavailRef    = processErrors.WithLabelValues("avail")
+	badVote       = processErrors.WithLabelValues("vote")
+	notEligible   = processErrors.WithLabelValues("elig")
+	failedPublish = processErrors.WithLabelValues("pub")
+)
+
+// outcomePass is the outcome label of the syntactic rule that the proposal passed.
+// Failed rules are labeled by their class.
+const outcomePass = "pass"
+
+// syntacticChecks counts proposals checked by the syntactic rules, by rule and outcome.
+var syntacticChecks = metrics.NewCounter(
+	"syntactic_checks",
+	subsystem,
+	"number of proposals checked by the syntactic rules",
+	[]string{"rule", "outcome"},
+)
+
+var (
+	pendingBallotsCounter = metrics.NewCounter(
+		"pending_ballots",
+		subsystem,
+		"number of ballots that waited for a missing base or reference ballot",
+		[]string{"kind"},
+	)
+	pendingQueued   = pendingBallotsCounter.WithLabelValues("queued")
+	pendingReplayed = pendingBallotsCounter.WithLabelValues("replayed")
+	pendingExpired  = pendingBallotsCounter.WithLabelValues("expired")
+	pendingDropped  = pendingBallotsCounter.WithLabelValues("dropped")
+
+	pendingSize = metrics.NewGauge(
+		"pending_ballots_size",
+		subsystem,
+		"number of ballots waiting for a missing base or reference ballot",
+		[]string{},
+	).WithLabelValues()
 )
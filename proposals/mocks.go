@@ -38,12 +38,13 @@ func (m *MockmeshProvider) EXPECT() *MockmeshProviderMockRecorder {
 }
 
 // AddBallot mocks base method.
-func (m *MockmeshProvider) AddBallot(arg0 context.Context, arg1 *types.Ballot) (*types.MalfeasanceProof, error) {
+func (m *MockmeshProvider) AddBallot(arg0 context.Context, arg1 *types.Ballot) (*types.MalfeasanceProof, bool, error) {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "AddBallot", arg0, arg1)
 	ret0, _ := ret[0].(*types.MalfeasanceProof)
-	ret1, _ := ret[1].(error)
-	return ret0, ret1
+	ret1, _ := ret[1].(bool)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
 }
 
 // AddBallot indicates an expected call of AddBallot.
@@ -104,6 +105,20 @@ func (mr *MockeligibilityValidatorMockRecorder) CheckEligibility(arg0, arg1 inte
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CheckEligibility", reflect.TypeOf((*MockeligibilityValidator)(nil).CheckEligibility), arg0, arg1)
 }
 
+// VerifyProofs mocks base method.
+func (m *MockeligibilityValidator) VerifyProofs(arg0 context.Context, arg1 []*types.Ballot) []error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "VerifyProofs", arg0, arg1)
+	ret0, _ := ret[0].([]error)
+	return ret0
+}
+
+// VerifyProofs indicates an expected call of VerifyProofs.
+func (mr *MockeligibilityValidatorMockRecorder) VerifyProofs(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "VerifyProofs", reflect.TypeOf((*MockeligibilityValidator)(nil).VerifyProofs), arg0, arg1)
+}
+
 // MockballotDecoder is a mock of ballotDecoder interface.
 type MockballotDecoder struct {
 	ctrl     *gomock.Controller
@@ -245,6 +260,43 @@ func (mr *MocknonceFetcherMockRecorder) VRFNonce(arg0, arg1 interface{}) *gomock
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "VRFNonce", reflect.TypeOf((*MocknonceFetcher)(nil).VRFNonce), arg0, arg1)
 }
 
+// MocknonceVerifier is a mock of nonceVerifier interface.
+type MocknonceVerifier struct {
+	ctrl     *gomock.Controller
+	recorder *MocknonceVerifierMockRecorder
+}
+
+// MocknonceVerifierMockRecorder is the mock recorder for MocknonceVerifier.
+type MocknonceVerifierMockRecorder struct {
+	mock *MocknonceVerifier
+}
+
+// NewMocknonceVerifier creates a new mock instance.
+func NewMocknonceVerifier(ctrl *gomock.Controller) *MocknonceVerifier {
+	mock := &MocknonceVerifier{ctrl: ctrl}
+	mock.recorder = &MocknonceVerifierMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MocknonceVerifier) EXPECT() *MocknonceVerifierMockRecorder {
+	return m.recorder
+}
+
+// VRFNonce mocks base method.
+func (m *MocknonceVerifier) VRFNonce(arg0 types.NodeID, arg1 types.ATXID, arg2 *types.VRFPostIndex, arg3 *types.PostMetadata, arg4 uint32) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "VRFNonce", arg0, arg1, arg2, arg3, arg4)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// VRFNonce indicates an expected call of VRFNonce.
+func (mr *MocknonceVerifierMockRecorder) VRFNonce(arg0, arg1, arg2, arg3, arg4 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "VRFNonce", reflect.TypeOf((*MocknonceVerifier)(nil).VRFNonce), arg0, arg1, arg2, arg3, arg4)
+}
+
 // MocklayerClock is a mock of layerClock interface.
 type MocklayerClock struct {
 	ctrl     *gomock.Controller
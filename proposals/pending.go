@@ -0,0 +1,165 @@
+package proposals
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/spacemeshos/go-spacemesh/common/types"
+	"github.com/spacemeshos/go-spacemesh/log"
+	"github.com/spacemeshos/go-spacemesh/p2p"
+)
+
+// missingBallotError is returned if ballot can't be processed because its base
+// or reference ballot failed to download.
+type missingBallotError struct {
+	ballot types.BallotID
+	err    error
+}
+
+func (e *missingBallotError) Error() string {
+	return fmt.Sprintf("missing ballot %s: %v", e.ballot, e.err)
+}
+
+func (e *missingBallotError) Unwrap() error {
+	return e.err
+}
+
+// pendingMessage is a ballot or a proposal that waits for a missing ballot.
+type pendingMessage struct {
+	ballot   types.BallotID
+	peer     p2p.Peer
+	data     []byte
+	proposal bool
+	expires  time.Time
+}
+
+// pendingBallots holds messages with ballots that depend on a missing ballot,
+// keyed by the id of the missing ballot.
+type pendingBallots struct {
+	ttl   time.Duration
+	limit int
+
+	mu       sync.Mutex
+	size     int
+	queued   map[types.BallotID]struct{}
+	messages map[types.BallotID][]*pendingMessage
+}
+
+func newPendingBallots(ttl time.Duration, limit int) *pendingBallots {
+	return &pendingBallots{
+		ttl:      ttl,
+		limit:    limit,
+		queued:   map[types.BallotID]struct{}{},
+		messages: map[types.BallotID][]*pendingMessage{},
+	}
+}
+
+// add queues message until the missing ballot is processed. It returns false if
+// the message is already queued or the buffer is full.
+func (p *pendingBallots) add(now time.Time, missing types.BallotID, msg *pendingMessage) bool {
+	if p.limit == 0 {
+		return false
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if _, exist := p.queued[msg.ballot]; exist {
+		return false
+	}
+	if p.size >= p.limit {
+		p.expire(now)
+	}
+	if p.size >= p.limit {
+		pendingDropped.Inc()
+		return false
+	}
+	msg.expires = now.Add(p.ttl)
+	p.messages[missing] = append(p.messages[missing], msg)
+	p.queued[msg.ballot] = struct{}{}
+	p.size++
+	pendingQueued.Inc()
+	pendingSize.Set(float64(p.size))
+	return true
+}
+
+// take removes messages that wait for the ballot and returns the ones that didn't expire.
+func (p *pendingBallots) take(now time.Time, ballot types.BallotID) []*pendingMessage {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	msgs, exist := p.messages[ballot]
+	if !exist {
+		return nil
+	}
+	delete(p.messages, ballot)
+	rst := make([]*pendingMessage, 0, len(msgs))
+	for _, msg := range msgs {
+		delete(p.queued, msg.ballot)
+		p.size--
+		if now.After(msg.expires) {
+			pendingExpired.Inc()
+			continue
+		}
+		rst = append(rst, msg)
+	}
+	pendingSize.Set(float64(p.size))
+	return rst
+}
+
+// expire removes all expired messages. Must be called with the lock held.
+func (p *pendingBallots) expire(now time.Time) {
+	for missing, msgs := range p.messages {
+		alive := msgs[:0]
+		for _, msg := range msgs {
+			if now.After(msg.expires) {
+				delete(p.queued, msg.ballot)
+				p.size--
+				pendingExpired.Inc()
+				continue
+			}
+			alive = append(alive, msg)
+		}
+		if len(alive) == 0 {
+			delete(p.messages, missing)
+		} else {
+			p.messages[missing] = alive
+		}
+	}
+	pendingSize.Set(float64(p.size))
+}
+
+// waitFor queues the message if processing failed because of the missing ballot.
+func (h *Handler) waitFor(ctx context.Context, err error, msg *pendingMessage) {
+	var missing *missingBallotError
+	if !errors.As(err, &missing) {
+		return
+	}
+	if h.pending.add(time.Now(), missing.ballot, msg) {
+		h.logger.WithContext(ctx).With().Debug("ballot waits for missing ballot",
+			msg.ballot,
+			log.Stringer("missing", missing.ballot),
+		)
+	}
+}
+
+// replay processes messages that waited for the ballot.
+func (h *Handler) replay(ctx context.Context, ballot types.BallotID) {
+	for _, msg := range h.pending.take(time.Now(), ballot) {
+		var err error
+		if msg.proposal {
+			// gossip data was checked to be canonical before it was queued
+			err = h.handleProposal(ctx, msg.peer, msg.data, false)
+		} else {
+			err = h.HandleSyncedBallot(ctx, msg.peer, msg.data)
+		}
+		pendingReplayed.Inc()
+		if err != nil {
+			h.logger.WithContext(ctx).With().Debug("failed to replay pending ballot",
+				msg.ballot,
+				log.Stringer("dependency", ballot),
+				log.Err(err),
+			)
+		}
+	}
+}
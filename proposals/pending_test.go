@@ -0,0 +1,149 @@
+package proposals
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/spacemeshos/go-spacemesh/common/types"
+	"github.com/spacemeshos/go-spacemesh/p2p"
+	"github.com/spacemeshos/go-spacemesh/sql/ballots"
+)
+
+func TestPendingBallots(t *testing.T) {
+	now := time.Now()
+	missing := types.RandomBallotID()
+	msg := func() *pendingMessage {
+		return &pendingMessage{ballot: types.RandomBallotID()}
+	}
+
+	t.Run("disabled", func(t *testing.T) {
+		p := newPendingBallots(time.Minute, 0)
+		require.False(t, p.add(now, missing, msg()))
+		require.Empty(t, p.take(now, missing))
+	})
+	t.Run("duplicate", func(t *testing.T) {
+		p := newPendingBallots(time.Minute, 10)
+		m := msg()
+		require.True(t, p.add(now, missing, m))
+		require.False(t, p.add(now, missing, &pendingMessage{ballot: m.ballot}))
+		require.Equal(t, []*pendingMessage{m}, p.take(now, missing))
+		require.Empty(t, p.take(now, missing))
+		require.True(t, p.add(now, missing, m))
+	})
+	t.Run("expired", func(t *testing.T) {
+		p := newPendingBallots(time.Minute, 10)
+		require.True(t, p.add(now, missing, msg()))
+		later := msg()
+		require.True(t, p.add(now.Add(time.Minute), missing, later))
+		require.Equal(t, []*pendingMessage{later}, p.take(now.Add(time.Minute+time.Second), missing))
+		require.Zero(t, p.size)
+	})
+	t.Run("full", func(t *testing.T) {
+		p := newPendingBallots(time.Minute, 2)
+		require.True(t, p.add(now, missing, msg()))
+		require.True(t, p.add(now.Add(time.Second), types.RandomBallotID(), msg()))
+		require.False(t, p.add(now.Add(time.Second), missing, msg()))
+
+		// expired messages are removed to make space for new ones
+		require.True(t, p.add(now.Add(time.Minute+time.Second), missing, msg()))
+		require.Equal(t, 2, p.size)
+		require.Len(t, p.take(now.Add(time.Minute+time.Second), missing), 1)
+	})
+}
+
+func createChainedBallot(tb testing.TB, lid types.LayerID, ref, base types.BallotID) *types.Ballot {
+	tb.Helper()
+	b := types.RandomBallot()
+	b.Layer = lid
+	b.RefBallot = ref
+	b.Votes = types.Votes{Base: base}
+	return signAndInit(tb, b)
+}
+
+func TestBallot_PendingChain(t *testing.T) {
+	th := createTestHandlerNoopDecoder(t)
+	th.pending = newPendingBallots(time.Minute, 10)
+	lid := types.LayerID(100)
+
+	ref := signAndInit(t, createRefBallot(t))
+	require.NoError(t, ballots.Add(th.cdb, ref))
+
+	// a depends on b that depends on c
+	c := createChainedBallot(t, lid, ref.ID(), types.EmptyBallotID)
+	b := createChainedBallot(t, lid+1, ref.ID(), c.ID())
+	a := createChainedBallot(t, lid+2, ref.ID(), b.ID())
+	for _, ballot := range []*types.Ballot{a, b, c} {
+		createAtx(t, th.cdb.Database, ballot.Layer.GetEpoch()-1, ballot.AtxID, ballot.SmesherID)
+	}
+
+	th.mf.EXPECT().RegisterPeerHashes(gomock.Any(), gomock.Any()).AnyTimes()
+	th.mf.EXPECT().GetBallots(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(_ context.Context, ids []types.BallotID) error {
+			for _, id := range ids {
+				if has, err := ballots.Has(th.cdb, id); err != nil || !has {
+					return errors.New("not available")
+				}
+			}
+			return nil
+		}).AnyTimes()
+	th.md.EXPECT().GetMissingActiveSet(gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+	th.mf.EXPECT().GetAtxs(gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+	th.mv.EXPECT().CheckEligibility(gomock.Any(), gomock.Any()).Return(true, nil).AnyTimes()
+	var added []types.BallotID
+	th.mm.EXPECT().AddBallot(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(_ context.Context, ballot *types.Ballot) (*types.MalfeasanceProof, bool, error) {
+			added = append(added, ballot.ID())
+			return nil, true, ballots.Add(th.cdb, ballot)
+		}).Times(3)
+
+	peer := p2p.Peer("buddy")
+	err := th.HandleSyncedBallot(context.Background(), peer, encodeBallot(t, a))
+	var missing *missingBallotError
+	require.ErrorAs(t, err, &missing)
+	require.Equal(t, b.ID(), missing.ballot)
+
+	err = th.HandleSyncedBallot(context.Background(), peer, encodeBallot(t, b))
+	require.ErrorAs(t, err, &missing)
+	require.Equal(t, c.ID(), missing.ballot)
+	require.Equal(t, 2, th.pending.size)
+
+	require.NoError(t, th.HandleSyncedBallot(context.Background(), peer, encodeBallot(t, c)))
+	require.Equal(t, []types.BallotID{c.ID(), b.ID(), a.ID()}, added)
+	require.Zero(t, th.pending.size)
+	require.Empty(t, th.pending.messages)
+}
+
+func TestBallot_PendingExpired(t *testing.T) {
+	th := createTestHandlerNoopDecoder(t)
+	th.pending = newPendingBallots(0, 10)
+	lid := types.LayerID(100)
+
+	ref := signAndInit(t, createRefBallot(t))
+	require.NoError(t, ballots.Add(th.cdb, ref))
+	base := createChainedBallot(t, lid, ref.ID(), types.EmptyBallotID)
+	b := createChainedBallot(t, lid+1, ref.ID(), base.ID())
+	for _, ballot := range []*types.Ballot{base, b} {
+		createAtx(t, th.cdb.Database, ballot.Layer.GetEpoch()-1, ballot.AtxID, ballot.SmesherID)
+	}
+
+	th.mf.EXPECT().RegisterPeerHashes(gomock.Any(), gomock.Any()).AnyTimes()
+	th.mf.EXPECT().GetBallots(gomock.Any(), []types.BallotID{b.Votes.Base, b.RefBallot}).Return(errors.New("not available"))
+	th.mf.EXPECT().GetBallots(gomock.Any(), []types.BallotID{base.RefBallot}).Return(nil)
+	th.md.EXPECT().GetMissingActiveSet(gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+	th.mf.EXPECT().GetAtxs(gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+	th.mv.EXPECT().CheckEligibility(gomock.Any(), gomock.Any()).Return(true, nil).AnyTimes()
+	th.mm.EXPECT().AddBallot(gomock.Any(), base).Return(nil, true, nil)
+
+	peer := p2p.Peer("buddy")
+	require.Error(t, th.HandleSyncedBallot(context.Background(), peer, encodeBallot(t, b)))
+	require.Equal(t, 1, th.pending.size)
+	time.Sleep(time.Millisecond)
+	// b expired and is not replayed
+	require.NoError(t, th.HandleSyncedBallot(context.Background(), peer, encodeBallot(t, base)))
+	require.Zero(t, th.pending.size)
+}
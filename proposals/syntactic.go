@@ -0,0 +1,255 @@
+package proposals
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/spacemeshos/go-spacemesh/codec"
+	"github.com/spacemeshos/go-spacemesh/common/types"
+	"github.com/spacemeshos/go-spacemesh/p2p/pubsub"
+	"github.com/spacemeshos/go-spacemesh/signing"
+)
+
+// maxLayersAhead is how many layers ahead of the local clock proposals are accepted.
+// Proposals can be built before their layer starts, and the clocks of the nodes may drift.
+const maxLayersAhead = 1
+
+var (
+	errFutureLayer    = errors.New("proposal layer is too far in the future")
+	errNoProofs       = errors.New("ballot has no eligibility proofs")
+	errTooManyProofs  = errors.New("ballot has more eligibility proofs than eligibility count")
+	errBadProposalSig = errors.New("failed to verify proposal signature")
+	errBadBallotSig   = errors.New("failed to verify ballot signature")
+)
+
+// ruleClass defines how the proposal that failed the syntactic rule is reported to pubsub.
+type ruleClass uint8
+
+const (
+	// classReject is for violations that can be caused only by the sender. The proposal is rejected,
+	// and the peer that relayed it is penalized.
+	classReject ruleClass = iota + 1
+	// classIgnore is for failures that may depend on the local state of the node, such as the clock
+	// or the known key rotations. The proposal is ignored.
+	classIgnore
+)
+
+// String returns the outcome label of the failed rule.
+func (c ruleClass) String() string {
+	switch c {
+	case classReject:
+		return "reject"
+	case classIgnore:
+		return "ignore"
+	}
+	return "unknown"
+}
+
+// names of the syntactic rules.
+const (
+	ruleSize      = "size"
+	ruleHeader    = "header"
+	ruleGenesis   = "genesis"
+	ruleLayer     = "layer"
+	ruleAtx       = "atx"
+	ruleDecode    = "decode"
+	ruleProofs    = "proofs"
+	ruleEpochData = "epoch_data"
+	ruleActiveSet = "active_set"
+	ruleTxs       = "txs"
+	ruleSignature = "signature"
+	ruleInit      = "init"
+)
+
+// validationError is returned when the proposal violates the syntactic rule.
+// Violations of the rules in classReject are reported as pubsub.ErrValidationReject.
+type validationError struct {
+	rule  string
+	class ruleClass
+	err   error
+}
+
+func (e *validationError) Error() string {
+	return e.err.Error()
+}
+
+func (e *validationError) Unwrap() error {
+	return e.err
+}
+
+func (e *validationError) Is(target error) bool {
+	return e.class == classReject && target == pubsub.ErrValidationReject
+}
+
+// proposalMessage is the proposal received from the network, as it is checked by the syntactic rules.
+type proposalMessage struct {
+	data []byte
+	// gossip is set if the data was received from gossip, and will be relayed to other peers.
+	gossip bool
+	// layer and atx are peeked from the header, before the proposal is decoded.
+	layer    types.LayerID
+	atx      types.ATXID
+	proposal types.Proposal
+}
+
+// syntacticRule checks the proposal without looking up the objects it references.
+type syntacticRule struct {
+	name  string
+	class ruleClass
+	check func(context.Context, *proposalMessage) error
+
+	passed, failed prometheus.Counter
+}
+
+func newSyntacticRule(name string, class ruleClass, check func(context.Context, *proposalMessage) error) syntacticRule {
+	return syntacticRule{
+		name:   name,
+		class:  class,
+		check:  check,
+		passed: syntacticChecks.WithLabelValues(name, outcomePass),
+		failed: syntacticChecks.WithLabelValues(name, class.String()),
+	}
+}
+
+// syntacticRules returns the rules in the order they are applied. Rules that don't need
+// the proposal to be decoded go first, so that obviously invalid proposals are dropped
+// without decoding votes and active set.
+func (h *Handler) syntacticRules() []syntacticRule {
+	return []syntacticRule{
+		newSyntacticRule(ruleSize, classReject, h.checkSize),
+		newSyntacticRule(ruleHeader, classReject, decodeHeader),
+		newSyntacticRule(ruleGenesis, classReject, checkGenesis),
+		newSyntacticRule(ruleLayer, classIgnore, h.checkLayer),
+		newSyntacticRule(ruleAtx, classReject, h.checkAtx),
+		newSyntacticRule(ruleDecode, classReject, decodeProposal),
+		newSyntacticRule(ruleProofs, classReject, checkProofs),
+		newSyntacticRule(ruleEpochData, classReject, checkEpochData),
+		newSyntacticRule(ruleActiveSet, classReject, h.checkActiveSet),
+		newSyntacticRule(ruleTxs, classReject, checkDuplicateTxs),
+		newSyntacticRule(ruleSignature, classIgnore, h.checkSignatures),
+		newSyntacticRule(ruleInit, classIgnore, initialize),
+	}
+}
+
+// checkSyntax applies the syntactic rules to the proposal in order, and stops at the first violation.
+func (h *Handler) checkSyntax(ctx context.Context, msg *proposalMessage) error {
+	for _, rule := range h.rules {
+		if err := rule.check(ctx, msg); err != nil {
+			rule.failed.Inc()
+			return &validationError{rule: rule.name, class: rule.class, err: err}
+		}
+		rule.passed.Inc()
+	}
+	return nil
+}
+
+func (h *Handler) checkSize(_ context.Context, msg *proposalMessage) error {
+	if h.cfg.MaxProposalBytes > 0 && len(msg.data) > h.cfg.MaxProposalBytes {
+		return fmt.Errorf("%w: %d bytes with max allowed %d", errProposalTooLarge, len(msg.data), h.cfg.MaxProposalBytes)
+	}
+	return nil
+}
+
+func decodeHeader(_ context.Context, msg *proposalMessage) error {
+	var err error
+	msg.layer, msg.atx, err = types.DecodeBallotHeader(msg.data)
+	if err != nil {
+		return errMalformedData
+	}
+	return nil
+}
+
+func checkGenesis(_ context.Context, msg *proposalMessage) error {
+	if msg.layer <= types.GetEffectiveGenesis() {
+		return fmt.Errorf("proposal before effective genesis: layer %v", msg.layer)
+	}
+	return nil
+}
+
+func (h *Handler) checkLayer(_ context.Context, msg *proposalMessage) error {
+	if current := h.clock.CurrentLayer(); msg.layer > current.Add(maxLayersAhead) {
+		return fmt.Errorf("%w: layer %v, current layer %v", errFutureLayer, msg.layer, current)
+	}
+	return nil
+}
+
+func (h *Handler) checkAtx(_ context.Context, msg *proposalMessage) error {
+	if msg.atx == types.EmptyATXID || msg.atx == h.cfg.GoldenATXID {
+		return errInvalidATXID
+	}
+	return nil
+}
+
+func decodeProposal(_ context.Context, msg *proposalMessage) error {
+	var err error
+	if msg.gossip {
+		err = codec.DecodeStrict(msg.data, &msg.proposal)
+	} else {
+		err = codec.Decode(msg.data, &msg.proposal)
+	}
+	if err != nil {
+		return errMalformedData
+	}
+	return nil
+}
+
+func checkProofs(_ context.Context, msg *proposalMessage) error {
+	b := &msg.proposal.Ballot
+	if len(b.EligibilityProofs) == 0 {
+		return errNoProofs
+	}
+	if b.EpochData != nil && uint32(len(b.EligibilityProofs)) > b.EpochData.EligibilityCount {
+		return fmt.Errorf("%w: %d proofs with eligibility count %d",
+			errTooManyProofs, len(b.EligibilityProofs), b.EpochData.EligibilityCount)
+	}
+	return nil
+}
+
+func checkEpochData(_ context.Context, msg *proposalMessage) error {
+	return checkEpochDataPresence(&msg.proposal.Ballot)
+}
+
+func (h *Handler) checkActiveSet(_ context.Context, msg *proposalMessage) error {
+	if b := &msg.proposal.Ballot; b.EpochData != nil {
+		return checkActiveSet(b, h.cfg.MaxActiveSetSize)
+	}
+	return nil
+}
+
+func checkDuplicateTxs(_ context.Context, msg *proposalMessage) error {
+	set := make(map[types.TransactionID]struct{}, len(msg.proposal.TxIDs))
+	for _, tx := range msg.proposal.TxIDs {
+		if _, exist := set[tx]; exist {
+			return errDuplicateTX
+		}
+		set[tx] = struct{}{}
+	}
+	return nil
+}
+
+func (h *Handler) checkSignatures(ctx context.Context, msg *proposalMessage) error {
+	p := &msg.proposal
+	epoch := p.Layer.GetEpoch()
+	if valid, err := h.verifySignature(ctx, signing.ProposalDomain(h.cfg.SignatureDomainLayer, p.Layer), p.SmesherID, epoch, p.SignedBytes(), p.Signature); err != nil {
+		return err
+	} else if !valid {
+		return errBadProposalSig
+	}
+	if valid, err := h.verifySignature(ctx, signing.BALLOT, p.Ballot.SmesherID, epoch, p.Ballot.SignedBytes(), p.Ballot.Signature); err != nil {
+		return err
+	} else if !valid {
+		return errBadBallotSig
+	}
+	return nil
+}
+
+func initialize(_ context.Context, msg *proposalMessage) error {
+	// set the proposal ID when received
+	if err := msg.proposal.Initialize(); err != nil {
+		return errInitialize
+	}
+	return nil
+}
@@ -0,0 +1,359 @@
+package proposals
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"sort"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+
+	"github.com/spacemeshos/go-spacemesh/common/types"
+	"github.com/spacemeshos/go-spacemesh/p2p/pubsub"
+	"github.com/spacemeshos/go-spacemesh/signing"
+)
+
+type syntacticCase struct {
+	desc  string
+	data  func(*testing.T, *testHandler) []byte
+	rule  string
+	class ruleClass
+	err   error
+}
+
+func withoutProofs() createBallotOpt {
+	return func(b *types.Ballot) {
+		b.EligibilityProofs = nil
+	}
+}
+
+// withActiveSet makes the ballot a ref ballot with the active set as is.
+func withActiveSet(atxs ...types.ATXID) createBallotOpt {
+	return func(b *types.Ballot) {
+		b.RefBallot = types.EmptyBallotID
+		b.EpochData = &types.EpochData{
+			ActiveSetHash:    types.ATXIDList(atxs).Hash(),
+			Beacon:           types.RandomBeacon(),
+			EligibilityCount: 1,
+		}
+		b.ActiveSet = atxs
+	}
+}
+
+func sortedActiveSet(n int) []types.ATXID {
+	atxs := types.RandomActiveSet(n)
+	sort.Slice(atxs, func(i, j int) bool {
+		return bytes.Compare(atxs[i].Bytes(), atxs[j].Bytes()) < 0
+	})
+	return atxs
+}
+
+func syntacticCases() []syntacticCase {
+	encoded := func(opts ...any) func(*testing.T, *testHandler) []byte {
+		return func(t *testing.T, _ *testHandler) []byte {
+			return encodeProposal(t, createProposal(t, opts...))
+		}
+	}
+	return []syntacticCase{
+		{
+			desc: "too large",
+			data: func(t *testing.T, th *testHandler) []byte {
+				data := encodeProposal(t, createProposal(t))
+				th.cfg.MaxProposalBytes = len(data) - 1
+				return data
+			},
+			rule:  ruleSize,
+			class: classReject,
+			err:   errProposalTooLarge,
+		},
+		{
+			desc: "truncated header",
+			data: func(t *testing.T, _ *testHandler) []byte {
+				return encodeProposal(t, createProposal(t))[:8]
+			},
+			rule:  ruleHeader,
+			class: classReject,
+			err:   errMalformedData,
+		},
+		{
+			desc:  "before genesis",
+			data:  encoded(withLayer(types.GetEffectiveGenesis())),
+			rule:  ruleGenesis,
+			class: classReject,
+		},
+		{
+			desc: "future layer",
+			data: func(t *testing.T, th *testHandler) []byte {
+				lid := th.clock.CurrentLayer().Add(maxLayersAhead + 1)
+				return encodeProposal(t, createProposal(t, withLayer(lid)))
+			},
+			rule:  ruleLayer,
+			class: classIgnore,
+			err:   errFutureLayer,
+		},
+		{
+			desc:  "empty atx",
+			data:  encoded(createBallotOpt(func(b *types.Ballot) { b.AtxID = types.EmptyATXID })),
+			rule:  ruleAtx,
+			class: classReject,
+			err:   errInvalidATXID,
+		},
+		{
+			desc: "truncated body",
+			data: func(t *testing.T, _ *testHandler) []byte {
+				data := encodeProposal(t, createProposal(t))
+				return data[:len(data)-1]
+			},
+			rule:  ruleDecode,
+			class: classReject,
+			err:   errMalformedData,
+		},
+		{
+			desc:  "no eligibility proofs",
+			data:  encoded(withoutProofs()),
+			rule:  ruleProofs,
+			class: classReject,
+			err:   errNoProofs,
+		},
+		{
+			desc: "proofs exceed eligibility count",
+			data: encoded(withAnyRefData(), createBallotOpt(func(b *types.Ballot) {
+				b.EligibilityProofs = append(b.EligibilityProofs, types.VotingEligibility{J: 1})
+			})),
+			rule:  ruleProofs,
+			class: classReject,
+			err:   errTooManyProofs,
+		},
+		{
+			desc: "ref ballot without epoch data",
+			data: encoded(createBallotOpt(func(b *types.Ballot) {
+				b.RefBallot = types.EmptyBallotID
+			})),
+			rule:  ruleEpochData,
+			class: classReject,
+			err:   errMissingEpochData,
+		},
+		{
+			desc: "ref ballot without beacon",
+			data: encoded(withAnyRefData(), createBallotOpt(func(b *types.Ballot) {
+				b.EpochData.Beacon = types.EmptyBeacon
+			})),
+			rule:  ruleEpochData,
+			class: classReject,
+			err:   errMissingBeacon,
+		},
+		{
+			desc: "epoch data in non-ref ballot",
+			data: encoded(withAnyRefData(), createBallotOpt(func(b *types.Ballot) {
+				b.RefBallot = types.RandomBallotID()
+			})),
+			rule:  ruleEpochData,
+			class: classReject,
+			err:   errUnexpectedEpochData,
+		},
+		{
+			desc:  "empty active set",
+			data:  encoded(withActiveSet()),
+			rule:  ruleActiveSet,
+			class: classReject,
+			err:   errEmptyActiveSet,
+		},
+		{
+			desc: "duplicate in active set",
+			data: func(t *testing.T, _ *testHandler) []byte {
+				atxs := sortedActiveSet(3)
+				return encodeProposal(t, createProposal(t, withActiveSet(atxs[0], atxs[1], atxs[1], atxs[2])))
+			},
+			rule:  ruleActiveSet,
+			class: classReject,
+			err:   errActiveSetNotSorted,
+		},
+		{
+			desc: "unsorted active set",
+			data: func(t *testing.T, _ *testHandler) []byte {
+				atxs := sortedActiveSet(3)
+				return encodeProposal(t, createProposal(t, withActiveSet(atxs[2], atxs[1], atxs[0])))
+			},
+			rule:  ruleActiveSet,
+			class: classReject,
+			err:   errActiveSetNotSorted,
+		},
+		{
+			desc: "active set too large",
+			data: func(t *testing.T, th *testHandler) []byte {
+				th.cfg.MaxActiveSetSize = 2
+				return encodeProposal(t, createProposal(t, withActiveSet(sortedActiveSet(3)...)))
+			},
+			rule:  ruleActiveSet,
+			class: classReject,
+			err:   errActiveSetTooLarge,
+		},
+		{
+			desc: "bad active set hash",
+			data: encoded(withAnyRefData(), createBallotOpt(func(b *types.Ballot) {
+				b.EpochData.ActiveSetHash = types.RandomHash()
+			})),
+			rule:  ruleActiveSet,
+			class: classReject,
+			err:   errBadActiveSetHash,
+		},
+		{
+			desc: "duplicate transactions",
+			data: func(t *testing.T, _ *testHandler) []byte {
+				tid := types.RandomTransactionID()
+				return encodeProposal(t, createProposal(t, withTransactions(tid, tid)))
+			},
+			rule:  ruleTxs,
+			class: classReject,
+			err:   errDuplicateTX,
+		},
+		{
+			desc: "bad proposal signature",
+			data: func(t *testing.T, _ *testHandler) []byte {
+				p := createProposal(t)
+				p.Signature = types.RandomEdSignature()
+				return encodeProposal(t, p)
+			},
+			rule:  ruleSignature,
+			class: classIgnore,
+			err:   errBadProposalSig,
+		},
+		{
+			desc: "bad ballot signature",
+			data: func(t *testing.T, _ *testHandler) []byte {
+				p := createProposal(t)
+				signer, err := signing.NewEdSigner()
+				require.NoError(t, err)
+				p.Ballot.Signature = types.RandomEdSignature()
+				p.SmesherID = signer.NodeID()
+				p.Signature = signer.Sign(signing.BALLOT, p.SignedBytes())
+				return encodeProposal(t, p)
+			},
+			rule:  ruleSignature,
+			class: classIgnore,
+			err:   errBadBallotSig,
+		},
+	}
+}
+
+func TestSyntacticRules(t *testing.T) {
+	for _, tc := range syntacticCases() {
+		tc := tc
+		t.Run(tc.desc, func(t *testing.T) {
+			th := createTestHandlerNoopDecoder(t)
+			th.mf.EXPECT().GetKeyRotations(gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+			err := th.checkSyntax(context.Background(), &proposalMessage{data: tc.data(t, th)})
+			var verr *validationError
+			require.True(t, errors.As(err, &verr), "unexpected error %v", err)
+			require.Equal(t, tc.rule, verr.rule)
+			require.Equal(t, tc.class, verr.class)
+			if tc.err != nil {
+				require.ErrorIs(t, err, tc.err)
+			}
+			require.Equal(t, tc.class == classReject, errors.Is(err, pubsub.ErrValidationReject))
+		})
+	}
+	t.Run("valid", func(t *testing.T) {
+		th := createTestHandlerNoopDecoder(t)
+		p := createProposal(t, withAnyRefData())
+		msg := &proposalMessage{data: encodeProposal(t, p)}
+		require.NoError(t, th.checkSyntax(context.Background(), msg))
+		require.Equal(t, p.ID(), msg.proposal.ID())
+		require.Equal(t, p.Ballot.ID(), msg.proposal.Ballot.ID())
+	})
+}
+
+func TestSyntacticRules_Metrics(t *testing.T) {
+	type labels struct {
+		rule, outcome string
+	}
+	rules := createTestHandlerNoopDecoder(t).rules
+	read := func() map[labels]float64 {
+		rst := map[labels]float64{}
+		for _, rule := range rules {
+			for _, outcome := range []string{outcomePass, classReject.String(), classIgnore.String()} {
+				rst[labels{rule.name, outcome}] = testutil.ToFloat64(syntacticChecks.WithLabelValues(rule.name, outcome))
+			}
+		}
+		return rst
+	}
+	before := read()
+
+	cases := syntacticCases()
+	expected := map[labels]float64{}
+	for _, tc := range cases {
+		th := createTestHandlerNoopDecoder(t)
+		th.mf.EXPECT().GetKeyRotations(gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+		err := th.HandleProposal(context.Background(), "", tc.data(t, th))
+		require.Error(t, err, tc.desc)
+		require.Equal(t, tc.class == classReject, errors.Is(err, pubsub.ErrValidationReject), tc.desc)
+		// every rule before the violated one is passed
+		for _, rule := range rules {
+			if rule.name == tc.rule {
+				expected[labels{rule.name, tc.class.String()}]++
+				break
+			}
+			expected[labels{rule.name, outcomePass}]++
+		}
+	}
+	require.Equal(t, 2.0, expected[labels{ruleSignature, classIgnore.String()}])
+	require.Equal(t, float64(len(cases)-1), expected[labels{ruleSize, outcomePass}])
+
+	after := read()
+	for l, before := range before {
+		require.Equal(t, expected[l], after[l]-before, "rule %s outcome %s", l.rule, l.outcome)
+	}
+}
+
+func FuzzHandleProposal(f *testing.F) {
+	const (
+		duplicateTx uint8 = 1 << iota
+		duplicateAtx
+		unsortedAtxs
+		emptyActiveSet
+		largeActiveSet
+		refBallotWithEpochData
+	)
+	f.Add(uint8(0), uint8(3))
+	for _, flag := range []uint8{duplicateTx, duplicateAtx, unsortedAtxs, emptyActiveSet, largeActiveSet, refBallotWithEpochData} {
+		f.Add(flag, uint8(3))
+	}
+	f.Add(duplicateAtx|unsortedAtxs|largeActiveSet, uint8(100))
+	f.Fuzz(func(t *testing.T, flags, size uint8) {
+		th := createTestHandlerNoopDecoder(t)
+		th.cfg.MaxActiveSetSize = 10
+		th.mf.EXPECT().GetKeyRotations(gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+		th.mf.EXPECT().RegisterPeerHashes(gomock.Any(), gomock.Any()).AnyTimes()
+		th.mf.EXPECT().GetBallots(gomock.Any(), gomock.Any()).Return(errors.New("unavailable")).AnyTimes()
+
+		atxs := sortedActiveSet(int(size)%(th.cfg.MaxActiveSetSize-1) + 2)
+		if flags&duplicateAtx != 0 {
+			atxs = append(atxs[:2], atxs[1:]...)
+		}
+		if flags&unsortedAtxs != 0 {
+			atxs[0], atxs[len(atxs)-1] = atxs[len(atxs)-1], atxs[0]
+		}
+		if flags&emptyActiveSet != 0 {
+			atxs = nil
+		}
+		if flags&largeActiveSet != 0 {
+			atxs = sortedActiveSet(th.cfg.MaxActiveSetSize + int(size) + 1)
+		}
+		opts := []any{withActiveSet(atxs...)}
+		if flags&refBallotWithEpochData != 0 {
+			opts = append(opts, createBallotOpt(func(b *types.Ballot) {
+				b.RefBallot = types.RandomBallotID()
+			}))
+		}
+		if flags&duplicateTx != 0 {
+			tid := types.RandomTransactionID()
+			opts = append(opts, withTransactions(tid, types.RandomTransactionID(), tid))
+		}
+		err := th.HandleProposal(context.Background(), "", encodeProposal(t, createProposal(t, opts...)))
+		require.Error(t, err)
+		require.Equal(t, flags&(refBallotWithEpochData<<1-1) != 0, errors.Is(err, pubsub.ErrValidationReject), "flags %b: %v", flags, err)
+	})
+}
@@ -8,7 +8,6 @@ import (
 
 	"github.com/spacemeshos/go-spacemesh/common/types"
 	"github.com/spacemeshos/go-spacemesh/datastore"
-	"github.com/spacemeshos/go-spacemesh/sql/ballots"
 )
 
 var (
@@ -65,7 +64,7 @@ func ComputeWeightPerEligibility(
 		if ballot.RefBallot == types.EmptyBallotID {
 			return nil, fmt.Errorf("%w: empty ref ballot but no epoch data %s", ErrBadBallotData, ballot.ID())
 		}
-		refBallot, err = ballots.Get(cdb, ballot.RefBallot)
+		refBallot, err = cdb.GetBallot(ballot.RefBallot)
 		if err != nil {
 			return nil, fmt.Errorf("%w: missing ref ballot %s (for %s)", err, ballot.RefBallot, ballot.ID())
 		}
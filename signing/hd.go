@@ -0,0 +1,93 @@
+package signing
+
+import (
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/sha512"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"golang.org/x/crypto/pbkdf2"
+)
+
+const (
+	// hardenedOffset is added to the index of a hardened child key.
+	hardenedOffset = 1 << 31
+	// CoinType registered for Spacemesh in SLIP-0044.
+	CoinType = 540
+
+	masterSecret = "ed25519 seed"
+)
+
+var errInvalidPath = errors.New("invalid derivation path")
+
+// SmesherPath returns derivation path of the smesher identity with index.
+func SmesherPath(index uint32) string {
+	return fmt.Sprintf("m/44'/%d'/0'/0'/%d'", CoinType, index)
+}
+
+// SeedFromMnemonic returns seed for the BIP-39 mnemonic with optional passphrase.
+// Mnemonic must use the english wordlist and have a valid checksum.
+func SeedFromMnemonic(mnemonic, passphrase string) ([]byte, error) {
+	if err := ValidateMnemonic(mnemonic); err != nil {
+		return nil, err
+	}
+	mnemonic = strings.Join(strings.Fields(mnemonic), " ")
+	return pbkdf2.Key([]byte(mnemonic), []byte("mnemonic"+passphrase), 2048, 64, sha512.New), nil
+}
+
+// DeriveKey derives ed25519 private key from the seed according to SLIP-0010.
+// Path is in the format m/44'/540'/0'/0'/0'. Only hardened derivation is defined for ed25519,
+// therefore every index in the path must be hardened.
+func DeriveKey(seed []byte, path string) (PrivateKey, error) {
+	indexes, err := parsePath(path)
+	if err != nil {
+		return nil, err
+	}
+	key, chain := hmacSHA512([]byte(masterSecret), seed)
+	for _, index := range indexes {
+		var data [1 + 32 + 4]byte
+		copy(data[1:], key)
+		binary.BigEndian.PutUint32(data[33:], index)
+		key, chain = hmacSHA512(chain, data[:])
+	}
+	return ed25519.NewKeyFromSeed(key), nil
+}
+
+// NewEdSignerFromSeed returns signer for the key derived from the seed with path.
+func NewEdSignerFromSeed(seed []byte, path string, opts ...EdSignerOptionFunc) (*EdSigner, error) {
+	priv, err := DeriveKey(seed, path)
+	if err != nil {
+		return nil, err
+	}
+	return NewEdSigner(append(opts, WithPrivateKey(priv))...)
+}
+
+func parsePath(path string) ([]uint32, error) {
+	parts := strings.Split(path, "/")
+	if parts[0] != "m" {
+		return nil, fmt.Errorf("%w: %s doesn't start with m", errInvalidPath, path)
+	}
+	indexes := make([]uint32, 0, len(parts)-1)
+	for _, part := range parts[1:] {
+		if !strings.HasSuffix(part, "'") {
+			return nil, fmt.Errorf("%w: %s is not hardened", errInvalidPath, part)
+		}
+		index, err := strconv.ParseUint(strings.TrimSuffix(part, "'"), 10, 31)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s: %v", errInvalidPath, part, err)
+		}
+		indexes = append(indexes, uint32(index)+hardenedOffset)
+	}
+	return indexes, nil
+}
+
+func hmacSHA512(key, data []byte) ([]byte, []byte) {
+	mac := hmac.New(sha512.New, key)
+	mac.Write(data)
+	sum := mac.Sum(nil)
+	return sum[:32], sum[32:]
+}
@@ -0,0 +1,110 @@
+package signing
+
+import (
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeriveKey_SLIP10Vectors(t *testing.T) {
+	// test vector 1 for ed25519 from https://github.com/satoshilabs/slips/blob/master/slip-0010.md
+	seed, err := hex.DecodeString("000102030405060708090a0b0c0d0e0f")
+	require.NoError(t, err)
+	for _, tc := range []struct {
+		path, priv, pub string
+	}{
+		{
+			path: "m",
+			priv: "2b4be7f19ee27bbf30c667b642d5f4aa69fd169872f8fc3059c08ebae2eb19e7",
+			pub:  "a4b2856bfec510abab89753fac1ac0e1112364e7d250545963f135f2a33188ed",
+		},
+		{
+			path: "m/0'",
+			priv: "68e0fe46dfb67e368c75379acec591dad19df3cde26e63b93a8e704f1dade7a3",
+			pub:  "8c8a13df77a28f3445213a0f432fde644acaa215fc72dcdf300d5efaa85d350c",
+		},
+		{
+			path: "m/0'/1'",
+			priv: "b1d0bad404bf35da785a64ca1ac54b2617211d2777696fbffaf208f746ae84f2",
+			pub:  "1932a5270f335bed617d5b935c80aedb1a35bd9fc1e31acafd5372c30f5c1187",
+		},
+		{
+			path: "m/0'/1'/2'",
+			priv: "92a5b23c0b8a99e37d07df3fb9966917f5d06e02ddbd909c7e184371463e9fc9",
+			pub:  "ae98736566d30ed0e9d2f4486a64bc95740d89c7db33f52121f8ea8f76ff0fc1",
+		},
+		{
+			path: "m/0'/1'/2'/2'",
+			priv: "30d1dc7e5fc04c31219ab25a27ae00b50f6fd66622f6e9c913253d6511d1e662",
+			pub:  "8abae2d66361c879b900d204ad2cc4984fa2aa344dd7ddc46007329ac76c429c",
+		},
+		{
+			path: "m/0'/1'/2'/2'/1000000000'",
+			priv: "8f94d394a8e8fd6b1bc2f3f49f5c47e385281d5c17e65324b0f62483e37e8793",
+			pub:  "3c24da049451555d51a7014a37337aa4e12d41e485abccfa46b47dfb2af54b7a",
+		},
+	} {
+		tc := tc
+		t.Run(tc.path, func(t *testing.T) {
+			priv, err := DeriveKey(seed, tc.path)
+			require.NoError(t, err)
+			require.Equal(t, tc.priv, hex.EncodeToString(priv.Seed()))
+			require.Equal(t, tc.pub, hex.EncodeToString(Public(priv)))
+		})
+	}
+}
+
+func TestDeriveKey_InvalidPath(t *testing.T) {
+	seed := make([]byte, 32)
+	for _, path := range []string{"", "0'", "m/0", "m/0'/1", "m/a'", "m/2147483648'", "m//"} {
+		_, err := DeriveKey(seed, path)
+		require.ErrorIs(t, err, errInvalidPath, path)
+	}
+}
+
+func TestSeedFromMnemonic(t *testing.T) {
+	// vector from https://github.com/trezor/python-mnemonic/blob/master/vectors.json
+	mnemonic := strings.Repeat("abandon ", 11) + "about"
+	seed, err := SeedFromMnemonic(mnemonic, "TREZOR")
+	require.NoError(t, err)
+	require.Equal(t,
+		"c55257c360c07c72029aebc1b53c05ed0362ada38ead3e3e9efa3708e53495531f09a6987599d18264c1e1c92f2cf141630c7a3c4ab7c81b2f001698e7463b04",
+		hex.EncodeToString(seed),
+	)
+	seed1, err := SeedFromMnemonic(mnemonic, "")
+	require.NoError(t, err)
+	seed2, err := SeedFromMnemonic(" "+mnemonic+"\n", "")
+	require.NoError(t, err)
+	require.Equal(t, seed1, seed2)
+
+	_, err = SeedFromMnemonic(strings.Repeat("abandon ", 12), "")
+	require.ErrorIs(t, err, ErrInvalidMnemonic)
+}
+
+func TestNewEdSignerFromSeed(t *testing.T) {
+	seed, err := SeedFromMnemonic(strings.Repeat("abandon ", 11)+"about", "")
+	require.NoError(t, err)
+	signer1, err := NewEdSignerFromSeed(seed, SmesherPath(0))
+	require.NoError(t, err)
+	signer2, err := NewEdSignerFromSeed(seed, SmesherPath(0))
+	require.NoError(t, err)
+	other, err := NewEdSignerFromSeed(seed, SmesherPath(1))
+	require.NoError(t, err)
+
+	require.Equal(t, signer1.NodeID(), signer2.NodeID())
+	require.NotEqual(t, signer1.NodeID(), other.NodeID())
+
+	// vrf key is the same ed25519 key, therefore it is derived deterministically as well.
+	vrf1, err := signer1.VRFSigner()
+	require.NoError(t, err)
+	vrf2, err := signer2.VRFSigner()
+	require.NoError(t, err)
+	require.Equal(t, vrf1.PublicKey(), vrf2.PublicKey())
+	sig1, err := vrf1.Sign([]byte("msg"))
+	require.NoError(t, err)
+	sig2, err := vrf2.Sign([]byte("msg"))
+	require.NoError(t, err)
+	require.Equal(t, sig1, sig2)
+}
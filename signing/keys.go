@@ -1,6 +1,7 @@
 package signing
 
 import (
+	"bytes"
 	"crypto/ed25519"
 	"encoding/hex"
 
@@ -58,7 +59,14 @@ func (p *PublicKey) ShortString() string {
 	return s[:shortStringSize]
 }
 
+// ArrayKey returns the public key as an array, that can be used as a map key without allocations.
+func (p *PublicKey) ArrayKey() [ed25519.PublicKeySize]byte {
+	var key [ed25519.PublicKeySize]byte
+	copy(key[:], p.Bytes())
+	return key
+}
+
 // Equals returns true iff the public keys are equal.
 func (p *PublicKey) Equals(o *PublicKey) bool {
-	return p.PublicKey.Equal(o.PublicKey)
+	return bytes.Equal(p.Bytes(), o.Bytes())
 }
@@ -0,0 +1,295 @@
+package signing
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"golang.org/x/crypto/argon2"
+)
+
+const (
+	// keystoreVersion 2 authenticates the header of the keystore together with the secret.
+	keystoreVersion = 2
+	kdfArgon2id     = "argon2id"
+
+	// maximums of argon2id parameters. parameters are read from the file before the password is verified,
+	// they are clamped so that a crafted file can't make the node spend unbounded time and memory.
+	maxKDFTime    = 16
+	maxKDFMemory  = 4 << 20 // 4 GiB in KiB
+	maxKDFThreads = 64
+
+	saltSize = 16
+	keySize  = 32
+
+	// kindMnemonic marks keystore that holds BIP-39 mnemonic instead of the private key.
+	kindMnemonic = "mnemonic"
+)
+
+var (
+	// ErrWrongPassword is returned when key can't be decrypted with the password.
+	ErrWrongPassword = errors.New("keystore: wrong password or corrupted file")
+	// ErrUnsupportedVersion is returned for keystore written by a newer version of the node.
+	ErrUnsupportedVersion = errors.New("keystore: unsupported version")
+)
+
+// KDFParams are the parameters of argon2id used to derive the encryption key from the password.
+type KDFParams struct {
+	Name    string `json:"name"`
+	Salt    []byte `json:"salt"`
+	Time    uint32 `json:"time"`
+	Memory  uint32 `json:"memory"`
+	Threads uint8  `json:"threads"`
+}
+
+// DefaultKDFParams are recommended by RFC 9106 for memory constrained environments.
+func DefaultKDFParams() KDFParams {
+	return KDFParams{
+		Name:    kdfArgon2id,
+		Time:    3,
+		Memory:  64 * 1024,
+		Threads: 4,
+	}
+}
+
+// clamp limits the parameters to the maximums.
+func (p KDFParams) clamp() KDFParams {
+	if p.Time > maxKDFTime {
+		p.Time = maxKDFTime
+	}
+	if p.Memory > maxKDFMemory {
+		p.Memory = maxKDFMemory
+	}
+	if p.Threads > maxKDFThreads {
+		p.Threads = maxKDFThreads
+	}
+	return p
+}
+
+// header is the part of the keystore that is authenticated as additional data.
+type header struct {
+	Version int       `json:"version"`
+	Kind    string    `json:"kind,omitempty"`
+	KDF     KDFParams `json:"kdf"`
+	Nonce   []byte    `json:"nonce"`
+}
+
+// aad returns the additional data authenticated with the secret.
+// Keystore of the first version doesn't authenticate the header.
+func (h *header) aad() ([]byte, error) {
+	if h.Version < 2 {
+		return nil, nil
+	}
+	data, err := json.Marshal(h)
+	if err != nil {
+		return nil, fmt.Errorf("keystore: encode header: %w", err)
+	}
+	return data, nil
+}
+
+type keystore struct {
+	header
+	Ciphertext []byte `json:"ciphertext"`
+	MAC        []byte `json:"mac"`
+}
+
+// IsEncryptedKey returns true if data is in the keystore format.
+func IsEncryptedKey(data []byte) bool {
+	var ks keystore
+	return json.Unmarshal(data, &ks) == nil && ks.Version > 0
+}
+
+// SaveKey encrypts private key of the signer with the password and writes it to the path.
+func SaveKey(path string, signer *EdSigner, password []byte) error {
+	return saveKey(path, signer, password, DefaultKDFParams())
+}
+
+func saveKey(path string, signer *EdSigner, password []byte, params KDFParams) error {
+	data, err := EncryptKey(signer.PrivateKey(), password, params)
+	if err != nil {
+		return err
+	}
+	return writeKeystore(path, data)
+}
+
+// SaveMnemonic validates the mnemonic, encrypts it with the password and writes it to the path.
+func SaveMnemonic(path, mnemonic string, password []byte) error {
+	return saveMnemonic(path, mnemonic, password, DefaultKDFParams())
+}
+
+func saveMnemonic(path, mnemonic string, password []byte, params KDFParams) error {
+	if err := ValidateMnemonic(mnemonic); err != nil {
+		return err
+	}
+	data, err := encrypt(kindMnemonic, []byte(mnemonic), password, params)
+	if err != nil {
+		return err
+	}
+	return writeKeystore(path, data)
+}
+
+// LoadMnemonic reads keystore with the mnemonic from the path and decrypts it with the password.
+func LoadMnemonic(path string, password []byte) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("keystore: read %s: %w", path, err)
+	}
+	mnemonic, err := decrypt(kindMnemonic, data, password)
+	if err != nil {
+		return "", err
+	}
+	if err := ValidateMnemonic(string(mnemonic)); err != nil {
+		return "", fmt.Errorf("keystore: %w", err)
+	}
+	return string(mnemonic), nil
+}
+
+// writeKeystore replaces the file at path atomically. The file may hold the only copy of the key,
+// therefore data is written to a temporary file in the same directory, synced and renamed over the path.
+func writeKeystore(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp*")
+	if err != nil {
+		return fmt.Errorf("keystore: create temporary file for %s: %w", path, err)
+	}
+	defer os.Remove(tmp.Name())
+	if err := writeSync(tmp, data); err != nil {
+		return fmt.Errorf("keystore: write %s: %w", tmp.Name(), err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("keystore: rename %s: %w", tmp.Name(), err)
+	}
+	// sync directory so that rename survives a crash. not supported on every platform.
+	if dir, err := os.Open(filepath.Dir(path)); err == nil {
+		dir.Sync()
+		dir.Close()
+	}
+	return nil
+}
+
+func writeSync(f *os.File, data []byte) error {
+	if err := f.Chmod(0o600); err != nil {
+		f.Close()
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// EncryptKey encrypts private key with the password and returns encoded keystore.
+func EncryptKey(priv PrivateKey, password []byte, params KDFParams) ([]byte, error) {
+	return encrypt("", priv.Seed(), password, params)
+}
+
+func encrypt(kind string, secret, password []byte, params KDFParams) ([]byte, error) {
+	ks := keystore{header: header{Version: keystoreVersion, Kind: kind, KDF: params.clamp()}}
+	ks.KDF.Salt = make([]byte, saltSize)
+	if _, err := rand.Read(ks.KDF.Salt); err != nil {
+		return nil, fmt.Errorf("keystore: generate salt: %w", err)
+	}
+	aead, err := newAEAD(password, ks.KDF)
+	if err != nil {
+		return nil, err
+	}
+	ks.Nonce = make([]byte, aead.NonceSize())
+	if _, err := rand.Read(ks.Nonce); err != nil {
+		return nil, fmt.Errorf("keystore: generate nonce: %w", err)
+	}
+	aad, err := ks.aad()
+	if err != nil {
+		return nil, err
+	}
+	sealed := aead.Seal(nil, ks.Nonce, secret, aad)
+	ks.Ciphertext = sealed[:len(sealed)-aead.Overhead()]
+	ks.MAC = sealed[len(sealed)-aead.Overhead():]
+	return json.MarshalIndent(ks, "", "  ")
+}
+
+// LoadKey reads keystore from the path and decrypts it with the password.
+func LoadKey(path string, password []byte, opts ...EdSignerOptionFunc) (*EdSigner, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("keystore: read %s: %w", path, err)
+	}
+	priv, err := DecryptKey(data, password)
+	if err != nil {
+		return nil, err
+	}
+	return NewEdSigner(append(opts, WithPrivateKey(priv))...)
+}
+
+// DecryptKey decrypts private key from the encoded keystore.
+func DecryptKey(data, password []byte) (PrivateKey, error) {
+	seed, err := decrypt("", data, password)
+	if err != nil {
+		return nil, err
+	}
+	if len(seed) != ed25519.SeedSize {
+		return nil, fmt.Errorf("keystore: invalid key size %d", len(seed))
+	}
+	return ed25519.NewKeyFromSeed(seed), nil
+}
+
+func decrypt(kind string, data, password []byte) ([]byte, error) {
+	var ks keystore
+	if err := json.Unmarshal(data, &ks); err != nil {
+		return nil, fmt.Errorf("keystore: decode: %w", err)
+	}
+	if ks.Version > keystoreVersion {
+		return nil, fmt.Errorf("%w: %d", ErrUnsupportedVersion, ks.Version)
+	}
+	if ks.Version < 1 {
+		return nil, fmt.Errorf("keystore: invalid version %d", ks.Version)
+	}
+	if ks.Kind != kind {
+		return nil, fmt.Errorf("keystore: expected %q, got %q", kind, ks.Kind)
+	}
+	aead, err := newAEAD(password, ks.KDF)
+	if err != nil {
+		return nil, err
+	}
+	if len(ks.Nonce) != aead.NonceSize() {
+		return nil, fmt.Errorf("keystore: invalid nonce size %d", len(ks.Nonce))
+	}
+	aad, err := ks.aad()
+	if err != nil {
+		return nil, err
+	}
+	secret, err := aead.Open(nil, ks.Nonce, append(ks.Ciphertext, ks.MAC...), aad)
+	if err != nil {
+		return nil, ErrWrongPassword
+	}
+	return secret, nil
+}
+
+func newAEAD(password []byte, params KDFParams) (cipher.AEAD, error) {
+	if params.Name != kdfArgon2id {
+		return nil, fmt.Errorf("keystore: unknown kdf %q", params.Name)
+	}
+	if params.Time == 0 || params.Memory == 0 || params.Threads == 0 || len(params.Salt) == 0 {
+		return nil, fmt.Errorf("keystore: invalid kdf params %+v", params)
+	}
+	params = params.clamp()
+	key := argon2.IDKey(password, params.Salt, params.Time, params.Memory, params.Threads, keySize)
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("keystore: create cipher: %w", err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("keystore: create gcm: %w", err)
+	}
+	return aead, nil
+}
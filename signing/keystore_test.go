@@ -0,0 +1,176 @@
+package signing
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// testKDFParams are cheap to compute in tests.
+func testKDFParams() KDFParams {
+	return KDFParams{Name: kdfArgon2id, Time: 1, Memory: 64, Threads: 1}
+}
+
+func TestKeystore(t *testing.T) {
+	signer, err := NewEdSigner()
+	require.NoError(t, err)
+	path := filepath.Join(t.TempDir(), "key.json")
+	password := []byte("password")
+	require.NoError(t, saveKey(path, signer, password, testKDFParams()))
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.True(t, IsEncryptedKey(data))
+	require.NotContains(t, string(data), string(signer.PrivateKey().Seed()))
+
+	t.Run("load", func(t *testing.T) {
+		loaded, err := LoadKey(path, password, WithPrefix([]byte{1}))
+		require.NoError(t, err)
+		require.Equal(t, signer.PrivateKey(), loaded.PrivateKey())
+		require.Equal(t, []byte{1}, loaded.Prefix())
+	})
+	t.Run("wrong password", func(t *testing.T) {
+		_, err := LoadKey(path, []byte("wrong"))
+		require.ErrorIs(t, err, ErrWrongPassword)
+	})
+	t.Run("truncated", func(t *testing.T) {
+		_, err := DecryptKey(data[:len(data)/2], password)
+		require.Error(t, err)
+	})
+	t.Run("tampered", func(t *testing.T) {
+		var ks keystore
+		require.NoError(t, json.Unmarshal(data, &ks))
+		ks.Ciphertext[0] ^= 1
+		tampered, err := json.Marshal(ks)
+		require.NoError(t, err)
+		_, err = DecryptKey(tampered, password)
+		require.ErrorIs(t, err, ErrWrongPassword)
+	})
+	t.Run("downgraded version", func(t *testing.T) {
+		var ks keystore
+		require.NoError(t, json.Unmarshal(data, &ks))
+		ks.Version = 1
+		downgraded, err := json.Marshal(ks)
+		require.NoError(t, err)
+		_, err = DecryptKey(downgraded, password)
+		require.ErrorIs(t, err, ErrWrongPassword)
+	})
+	t.Run("newer version", func(t *testing.T) {
+		var ks keystore
+		require.NoError(t, json.Unmarshal(data, &ks))
+		ks.Version = keystoreVersion + 1
+		newer, err := json.Marshal(ks)
+		require.NoError(t, err)
+		require.True(t, IsEncryptedKey(newer))
+		_, err = DecryptKey(newer, password)
+		require.ErrorIs(t, err, ErrUnsupportedVersion)
+	})
+	t.Run("unknown fields", func(t *testing.T) {
+		var fields map[string]any
+		require.NoError(t, json.Unmarshal(data, &fields))
+		fields["comment"] = "added by a newer version"
+		extended, err := json.Marshal(fields)
+		require.NoError(t, err)
+		priv, err := DecryptKey(extended, password)
+		require.NoError(t, err)
+		require.Equal(t, signer.PrivateKey(), priv)
+	})
+}
+
+func TestKeystoreFirstVersion(t *testing.T) {
+	signer, err := NewEdSigner()
+	require.NoError(t, err)
+	password := []byte("password")
+
+	ks := keystore{header: header{Version: 1, KDF: testKDFParams()}}
+	ks.KDF.Salt = []byte("0123456789abcdef")
+	aead, err := newAEAD(password, ks.KDF)
+	require.NoError(t, err)
+	ks.Nonce = make([]byte, aead.NonceSize())
+	sealed := aead.Seal(nil, ks.Nonce, signer.PrivateKey().Seed(), nil)
+	ks.Ciphertext = sealed[:len(sealed)-aead.Overhead()]
+	ks.MAC = sealed[len(sealed)-aead.Overhead():]
+	data, err := json.Marshal(ks)
+	require.NoError(t, err)
+
+	priv, err := DecryptKey(data, password)
+	require.NoError(t, err)
+	require.Equal(t, signer.PrivateKey(), priv)
+}
+
+func TestKDFParamsClamp(t *testing.T) {
+	params := KDFParams{Name: kdfArgon2id, Time: 1 << 30, Memory: 1 << 31, Threads: 255}
+	require.Equal(t, KDFParams{
+		Name:    kdfArgon2id,
+		Time:    maxKDFTime,
+		Memory:  maxKDFMemory,
+		Threads: maxKDFThreads,
+	}, params.clamp())
+	require.Equal(t, DefaultKDFParams(), DefaultKDFParams().clamp())
+
+	signer, err := NewEdSigner()
+	require.NoError(t, err)
+	params = testKDFParams()
+	params.Time = 1 << 30
+	data, err := EncryptKey(signer.PrivateKey(), []byte("password"), params)
+	require.NoError(t, err)
+	var ks keystore
+	require.NoError(t, json.Unmarshal(data, &ks))
+	require.EqualValues(t, maxKDFTime, ks.KDF.Time)
+}
+
+func TestIsEncryptedKey(t *testing.T) {
+	signer, err := NewEdSigner()
+	require.NoError(t, err)
+	require.False(t, IsEncryptedKey([]byte(hex.EncodeToString(signer.PrivateKey()))))
+	require.False(t, IsEncryptedKey(nil))
+}
+
+func TestKeystoreMnemonic(t *testing.T) {
+	mnemonic, err := NewMnemonic(MnemonicEntropy)
+	require.NoError(t, err)
+	path := filepath.Join(t.TempDir(), "mnemonic.json")
+	password := []byte("password")
+	require.NoError(t, saveMnemonic(path, mnemonic, password, testKDFParams()))
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.NotContains(t, string(data), mnemonic)
+
+	loaded, err := LoadMnemonic(path, password)
+	require.NoError(t, err)
+	require.Equal(t, mnemonic, loaded)
+
+	_, err = LoadMnemonic(path, []byte("wrong"))
+	require.ErrorIs(t, err, ErrWrongPassword)
+	_, err = DecryptKey(data, password)
+	require.Error(t, err)
+
+	require.ErrorIs(t, saveMnemonic(path, "abandon", password, testKDFParams()), ErrInvalidMnemonic)
+}
+
+func TestKeystoreOverwrite(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "key.json")
+	require.NoError(t, os.WriteFile(path, []byte("plaintext"), 0o644))
+
+	signer, err := NewEdSigner()
+	require.NoError(t, err)
+	require.NoError(t, saveKey(path, signer, []byte("password"), testKDFParams()))
+	loaded, err := LoadKey(path, []byte("password"))
+	require.NoError(t, err)
+	require.Equal(t, signer.PrivateKey(), loaded.PrivateKey())
+
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	require.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+
+	// temporary file is not left behind.
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+}
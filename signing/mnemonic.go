@@ -0,0 +1,100 @@
+package signing
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"sync"
+)
+
+const (
+	bitsPerWord = 11
+	// MnemonicEntropy is the default size of the entropy for generated mnemonics (24 words).
+	MnemonicEntropy = 256
+)
+
+// ErrInvalidMnemonic is returned for mnemonics with unknown words, wrong length or checksum.
+var ErrInvalidMnemonic = errors.New("invalid mnemonic")
+
+var (
+	wordsOnce sync.Once
+	words     []string
+	wordIndex map[string]int
+)
+
+func wordlist() ([]string, map[string]int) {
+	wordsOnce.Do(func() {
+		words = strings.Fields(englishWordlist)
+		wordIndex = make(map[string]int, len(words))
+		for i, word := range words {
+			wordIndex[word] = i
+		}
+	})
+	return words, wordIndex
+}
+
+// NewMnemonic generates BIP-39 mnemonic from the random entropy of the given size in bits.
+// Size must be a multiple of 32 in the range [128, 256].
+func NewMnemonic(bits int) (string, error) {
+	if bits%32 != 0 || bits < 128 || bits > 256 {
+		return "", fmt.Errorf("invalid entropy size %d", bits)
+	}
+	entropy := make([]byte, bits/8)
+	if _, err := rand.Read(entropy); err != nil {
+		return "", fmt.Errorf("generate entropy: %w", err)
+	}
+	return mnemonicFromEntropy(entropy), nil
+}
+
+func mnemonicFromEntropy(entropy []byte) string {
+	list, _ := wordlist()
+	checksumBits := len(entropy) / 4
+	sum := sha256.Sum256(entropy)
+	data := new(big.Int).SetBytes(entropy)
+	data.Lsh(data, uint(checksumBits))
+	data.Or(data, big.NewInt(int64(sum[0]>>(8-checksumBits))))
+
+	n := (len(entropy)*8 + checksumBits) / bitsPerWord
+	result := make([]string, n)
+	mask := big.NewInt(1<<bitsPerWord - 1)
+	index := new(big.Int)
+	for i := n - 1; i >= 0; i-- {
+		index.And(data, mask)
+		result[i] = list[index.Int64()]
+		data.Rsh(data, bitsPerWord)
+	}
+	return strings.Join(result, " ")
+}
+
+// ValidateMnemonic checks that every word of the mnemonic is in the english wordlist
+// and that the checksum matches the entropy.
+func ValidateMnemonic(mnemonic string) error {
+	_, index := wordlist()
+	fields := strings.Fields(mnemonic)
+	if len(fields)%3 != 0 || len(fields) < 12 || len(fields) > 24 {
+		return fmt.Errorf("%w: %d words", ErrInvalidMnemonic, len(fields))
+	}
+	data := new(big.Int)
+	for _, word := range fields {
+		i, exists := index[word]
+		if !exists {
+			return fmt.Errorf("%w: unknown word %q", ErrInvalidMnemonic, word)
+		}
+		data.Lsh(data, bitsPerWord)
+		data.Or(data, big.NewInt(int64(i)))
+	}
+	checksumBits := len(fields) / 3
+	checksum := new(big.Int).And(data, big.NewInt(1<<checksumBits-1)).Int64()
+	data.Rsh(data, uint(checksumBits))
+
+	entropy := make([]byte, checksumBits*4)
+	data.FillBytes(entropy)
+	sum := sha256.Sum256(entropy)
+	if int64(sum[0]>>(8-checksumBits)) != checksum {
+		return fmt.Errorf("%w: checksum mismatch", ErrInvalidMnemonic)
+	}
+	return nil
+}
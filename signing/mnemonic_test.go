@@ -0,0 +1,91 @@
+package signing
+
+import (
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWordlist(t *testing.T) {
+	list, index := wordlist()
+	require.Len(t, list, 1<<bitsPerWord)
+	require.Len(t, index, 1<<bitsPerWord)
+	require.Equal(t, "abandon", list[0])
+	require.Equal(t, "zoo", list[len(list)-1])
+}
+
+func TestMnemonicVectors(t *testing.T) {
+	// vectors from https://github.com/trezor/python-mnemonic/blob/master/vectors.json
+	for _, tc := range []struct {
+		entropy, mnemonic string
+	}{
+		{
+			entropy:  "00000000000000000000000000000000",
+			mnemonic: strings.Repeat("abandon ", 11) + "about",
+		},
+		{
+			entropy:  "7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f",
+			mnemonic: "legal winner thank year wave sausage worth useful legal winner thank yellow",
+		},
+		{
+			entropy:  "80808080808080808080808080808080",
+			mnemonic: "letter advice cage absurd amount doctor acoustic avoid letter advice cage above",
+		},
+		{
+			entropy:  "ffffffffffffffffffffffffffffffff",
+			mnemonic: strings.Repeat("zoo ", 11) + "wrong",
+		},
+		{
+			entropy:  "9e885d952ad362caeb4efe34a8e91bd2",
+			mnemonic: "ozone drill grab fiber curtain grace pudding thank cruise elder eight picnic",
+		},
+		{
+			entropy:  "000000000000000000000000000000000000000000000000",
+			mnemonic: strings.Repeat("abandon ", 17) + "agent",
+		},
+		{
+			entropy:  "0000000000000000000000000000000000000000000000000000000000000000",
+			mnemonic: strings.Repeat("abandon ", 23) + "art",
+		},
+		{
+			entropy:  "ffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff",
+			mnemonic: strings.Repeat("zoo ", 23) + "vote",
+		},
+	} {
+		tc := tc
+		t.Run(tc.entropy, func(t *testing.T) {
+			entropy, err := hex.DecodeString(tc.entropy)
+			require.NoError(t, err)
+			require.Equal(t, tc.mnemonic, mnemonicFromEntropy(entropy))
+			require.NoError(t, ValidateMnemonic(tc.mnemonic))
+		})
+	}
+}
+
+func TestNewMnemonic(t *testing.T) {
+	for _, bits := range []int{128, 160, 192, 224, 256} {
+		mnemonic, err := NewMnemonic(bits)
+		require.NoError(t, err)
+		require.Len(t, strings.Fields(mnemonic), (bits+bits/32)/bitsPerWord)
+		require.NoError(t, ValidateMnemonic(mnemonic))
+	}
+	for _, bits := range []int{0, 96, 129, 288} {
+		_, err := NewMnemonic(bits)
+		require.Error(t, err)
+	}
+}
+
+func TestValidateMnemonic(t *testing.T) {
+	for _, mnemonic := range []string{
+		"",
+		strings.Repeat("abandon ", 12),
+		strings.Repeat("abandon ", 11) + "abouts",
+		strings.Repeat("abandon ", 10) + "about",
+		strings.Repeat("zoo ", 11) + "zoo",
+		strings.Repeat("abandon ", 27) + "about",
+	} {
+		require.ErrorIs(t, ValidateMnemonic(mnemonic), ErrInvalidMnemonic, mnemonic)
+	}
+}
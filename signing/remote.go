@@ -0,0 +1,424 @@
+package signing
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/subtle"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/spacemeshos/go-spacemesh/api/signing/v1"
+	"github.com/spacemeshos/go-spacemesh/common/types"
+	"github.com/spacemeshos/go-spacemesh/log"
+)
+
+const (
+	authorizationHeader = "authorization"
+	bearerScheme        = "Bearer "
+
+	maxVRFMessageSize = 1024
+)
+
+var (
+	// ErrRemoteSigner is returned when remote signer failed to produce a signature.
+	ErrRemoteSigner = errors.New("remote signer")
+	// ErrSignTimeout is returned when remote signer didn't respond within the configured timeout.
+	ErrSignTimeout = fmt.Errorf("%w: timeout", ErrRemoteSigner)
+)
+
+// DefaultServiceDomains are the domains signed by the SignerService unless configured otherwise.
+// KEY_ROTATION is not included, so that the node can't rotate the key of the identity.
+func DefaultServiceDomains() []Domain {
+	return []Domain{ATX, BALLOT, HARE, POET, PROPOSAL, CERTIFY, BEACON_FIRST_MSG, BEACON_FOLLOWUP_MSG}
+}
+
+type signerServiceOption struct {
+	prefix  []byte
+	domains []Domain
+}
+
+// SignerServiceOpt configures SignerService.
+type SignerServiceOpt func(*signerServiceOption)
+
+// WithServicePrefix sets the prefix prepended to every signed message. This usually is the Network ID.
+func WithServicePrefix(prefix []byte) SignerServiceOpt {
+	return func(opt *signerServiceOption) {
+		opt.prefix = prefix
+	}
+}
+
+// WithServiceDomains limits the domains that the service signs.
+func WithServiceDomains(domains ...Domain) SignerServiceOpt {
+	return func(opt *signerServiceOption) {
+		opt.domains = domains
+	}
+}
+
+// SignerService serves signing protocol with the local key.
+// It is used to run an isolated signing service and in tests.
+type SignerService struct {
+	signer  *EdSigner
+	token   []byte
+	domains map[Domain]struct{}
+}
+
+// NewSignerService creates SignerService for the private key.
+// Every request must be authorized with the token.
+func NewSignerService(priv PrivateKey, token string, opts ...SignerServiceOpt) (*SignerService, error) {
+	if len(token) == 0 {
+		return nil, errors.New("signer service requires a token")
+	}
+	opt := signerServiceOption{domains: DefaultServiceDomains()}
+	for _, o := range opts {
+		o(&opt)
+	}
+	signer, err := NewEdSigner(WithPrivateKey(priv), WithPrefix(opt.prefix))
+	if err != nil {
+		return nil, err
+	}
+	domains := make(map[Domain]struct{}, len(opt.domains))
+	for _, d := range opt.domains {
+		domains[d] = struct{}{}
+	}
+	return &SignerService{signer: signer, token: []byte(token), domains: domains}, nil
+}
+
+// Register SignerService on the server.
+func (s *SignerService) Register(server *grpc.Server) {
+	pb.RegisterSignerServer(server, s)
+}
+
+func (s *SignerService) authorize(ctx context.Context) error {
+	md, _ := metadata.FromIncomingContext(ctx)
+	for _, value := range md.Get(authorizationHeader) {
+		token := strings.TrimPrefix(value, bearerScheme)
+		if len(token) < len(value) && subtle.ConstantTimeCompare([]byte(token), s.token) == 1 {
+			return nil
+		}
+	}
+	return status.Error(codes.Unauthenticated, "invalid token")
+}
+
+// Sign signs the message in the requested domain.
+func (s *SignerService) Sign(ctx context.Context, req *pb.SignRequest) (*pb.SignResponse, error) {
+	if err := s.authorize(ctx); err != nil {
+		return nil, err
+	}
+	if _, exists := s.domains[Domain(req.Domain)]; req.Domain > 255 || !exists {
+		return nil, status.Errorf(codes.PermissionDenied, "domain %d is not allowed", req.Domain)
+	}
+	sig := s.signer.Sign(Domain(req.Domain), req.Message)
+	return &pb.SignResponse{Signature: sig[:]}, nil
+}
+
+// VRFSign produces VRF proof for the message.
+func (s *SignerService) VRFSign(ctx context.Context, req *pb.VRFSignRequest) (*pb.VRFSignResponse, error) {
+	if err := s.authorize(ctx); err != nil {
+		return nil, err
+	}
+	if len(req.Message) > maxVRFMessageSize {
+		return nil, status.Errorf(codes.InvalidArgument, "message is larger than %d bytes", maxVRFMessageSize)
+	}
+	proof, err := s.signer.vrf.Sign(req.Message)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	return &pb.VRFSignResponse{Signature: proof[:]}, nil
+}
+
+// PublicKey returns the public key and the prefix of the service.
+func (s *SignerService) PublicKey(ctx context.Context, _ *pb.PublicKeyRequest) (*pb.PublicKeyResponse, error) {
+	if err := s.authorize(ctx); err != nil {
+		return nil, err
+	}
+	return &pb.PublicKeyResponse{PublicKey: s.signer.PublicKey().Bytes(), Prefix: s.signer.Prefix()}, nil
+}
+
+// tokenCredentials attaches the bearer token to every call.
+type tokenCredentials struct {
+	token  string
+	secure bool
+}
+
+func (c tokenCredentials) GetRequestMetadata(context.Context, ...string) (map[string]string, error) {
+	return map[string]string{authorizationHeader: bearerScheme + c.token}, nil
+}
+
+func (c tokenCredentials) RequireTransportSecurity() bool {
+	return c.secure
+}
+
+type remoteSignerOption struct {
+	prefix   []byte
+	token    string
+	tls      *tls.Config
+	timeout  time.Duration
+	retries  int
+	interval time.Duration
+	logger   log.Log
+}
+
+// RemoteSignerOpt configures RemoteSigner.
+type RemoteSignerOpt func(*remoteSignerOption)
+
+// WithRemotePrefix sets the prefix that the remote signer is expected to prepend to every signed message,
+// same as WithPrefix for EdSigner. Connection fails if the remote signer uses a different prefix.
+func WithRemotePrefix(prefix []byte) RemoteSignerOpt {
+	return func(opt *remoteSignerOption) {
+		opt.prefix = prefix
+	}
+}
+
+// WithRemoteToken sets the token that authorizes calls to the remote signer.
+func WithRemoteToken(token string) RemoteSignerOpt {
+	return func(opt *remoteSignerOption) {
+		opt.token = token
+	}
+}
+
+// WithRemoteTLS enables TLS for the connection to the remote signer. Certificates in the config
+// are presented to the signer for mutual authentication.
+func WithRemoteTLS(config *tls.Config) RemoteSignerOpt {
+	return func(opt *remoteSignerOption) {
+		opt.tls = config
+	}
+}
+
+// WithSignTimeout limits the duration of every signing call.
+func WithSignTimeout(timeout time.Duration) RemoteSignerOpt {
+	return func(opt *remoteSignerOption) {
+		opt.timeout = timeout
+	}
+}
+
+// WithConnectRetries sets how many times the health check is retried when connecting to the remote signer.
+func WithConnectRetries(retries int, interval time.Duration) RemoteSignerOpt {
+	return func(opt *remoteSignerOption) {
+		opt.retries = retries
+		opt.interval = interval
+	}
+}
+
+// WithRemoteLogger sets the logger.
+func WithRemoteLogger(logger log.Log) RemoteSignerOpt {
+	return func(opt *remoteSignerOption) {
+		opt.logger = logger
+	}
+}
+
+// RemoteSigner signs messages with the key stored in the isolated signing service.
+// It implements Signer, but doesn't provide access to the private key.
+type RemoteSigner struct {
+	conn   *grpc.ClientConn
+	client pb.SignerClient
+	opt    remoteSignerOption
+
+	nodeID    types.NodeID
+	publicKey *PublicKey
+	vrf       *remoteVRFSigner
+}
+
+// NewRemoteSigner connects to the signing service at the url and checks that the service is healthy
+// by requesting its public key. Connection fails if the service signs with a different prefix
+// than the one configured with WithRemotePrefix.
+func NewRemoteSigner(ctx context.Context, url string, opts ...RemoteSignerOpt) (*RemoteSigner, error) {
+	opt := remoteSignerOption{
+		timeout:  5 * time.Second,
+		retries:  5,
+		interval: time.Second,
+		logger:   log.NewNop(),
+	}
+	for _, o := range opts {
+		o(&opt)
+	}
+	if len(opt.token) == 0 {
+		return nil, fmt.Errorf("%w: token is required", ErrRemoteSigner)
+	}
+	transport := insecure.NewCredentials()
+	if opt.tls != nil {
+		transport = credentials.NewTLS(opt.tls)
+	}
+	conn, err := grpc.DialContext(ctx, url,
+		grpc.WithTransportCredentials(transport),
+		grpc.WithPerRPCCredentials(tokenCredentials{token: opt.token, secure: opt.tls != nil}),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%w: dial %s: %v", ErrRemoteSigner, url, err)
+	}
+	rs := &RemoteSigner{conn: conn, client: pb.NewSignerClient(conn), opt: opt}
+	if err := rs.connect(ctx, url); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	rs.vrf = &remoteVRFSigner{signer: rs}
+	opt.logger.With().Info("connected to remote signer", log.String("url", url), rs.nodeID)
+	return rs, nil
+}
+
+func (rs *RemoteSigner) connect(ctx context.Context, url string) error {
+	for attempt := 0; ; attempt++ {
+		var resp *pb.PublicKeyResponse
+		err := rs.invoke(ctx, "PublicKey", func(ctx context.Context) (err error) {
+			resp, err = rs.client.PublicKey(ctx, &pb.PublicKeyRequest{})
+			return err
+		})
+		switch {
+		case err == nil && len(resp.PublicKey) != ed25519.PublicKeySize:
+			return fmt.Errorf("%w: invalid public key size %d", ErrRemoteSigner, len(resp.PublicKey))
+		case err == nil && !bytes.Equal(resp.Prefix, rs.opt.prefix):
+			return fmt.Errorf("%w: prefix %x doesn't match %x", ErrRemoteSigner, resp.Prefix, rs.opt.prefix)
+		case err == nil:
+			rs.nodeID = types.BytesToNodeID(resp.PublicKey)
+			rs.publicKey = NewPublicKey(resp.PublicKey)
+			return nil
+		}
+		if code := status.Code(err); code == codes.Unauthenticated || code == codes.PermissionDenied {
+			return fmt.Errorf("health check %s: %w", url, err)
+		}
+		if attempt >= rs.opt.retries {
+			return fmt.Errorf("health check %s: %w", url, err)
+		}
+		rs.opt.logger.With().Warning("remote signer is not available",
+			log.String("url", url),
+			log.Int("attempt", attempt+1),
+			log.Err(err),
+		)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(rs.opt.interval):
+		}
+	}
+}
+
+// remoteError wraps the status error of the call, so that the status code is preserved.
+type remoteError struct {
+	method string
+	err    error
+}
+
+func (e *remoteError) Error() string {
+	return fmt.Sprintf("%v: %s: %v", ErrRemoteSigner, e.method, e.err)
+}
+
+func (e *remoteError) Is(target error) bool {
+	return target == ErrRemoteSigner
+}
+
+func (e *remoteError) Unwrap() error {
+	return e.err
+}
+
+func (rs *RemoteSigner) invoke(ctx context.Context, method string, call func(context.Context) error) error {
+	ctx, cancel := context.WithTimeout(ctx, rs.opt.timeout)
+	defer cancel()
+	if err := call(ctx); err != nil {
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return fmt.Errorf("%w: %s didn't respond in %v", ErrSignTimeout, method, rs.opt.timeout)
+		}
+		return &remoteError{method: method, err: err}
+	}
+	return nil
+}
+
+// SignContext signs the message in the domain. Signature is verified before it is returned,
+// error is returned if the remote signer failed or didn't respond within the configured timeout.
+func (rs *RemoteSigner) SignContext(ctx context.Context, d Domain, m []byte) (types.EdSignature, error) {
+	var resp *pb.SignResponse
+	err := rs.invoke(ctx, "Sign", func(ctx context.Context) (err error) {
+		resp, err = rs.client.Sign(ctx, &pb.SignRequest{Domain: uint32(d), Message: m})
+		return err
+	})
+	if err != nil {
+		return types.EmptyEdSignature, err
+	}
+	if len(resp.Signature) != types.EdSignatureSize {
+		return types.EmptyEdSignature, fmt.Errorf("%w: invalid signature size %d", ErrRemoteSigner, len(resp.Signature))
+	}
+	msg := make([]byte, 0, len(rs.opt.prefix)+1+len(m))
+	msg = append(msg, rs.opt.prefix...)
+	msg = append(msg, byte(d))
+	msg = append(msg, m...)
+	if !ed25519.Verify(rs.publicKey.Bytes(), msg, resp.Signature) {
+		return types.EmptyEdSignature, fmt.Errorf("%w: invalid signature in domain %s", ErrRemoteSigner, d)
+	}
+	var sig types.EdSignature
+	copy(sig[:], resp.Signature)
+	return sig, nil
+}
+
+// NodeID returns the node ID of the remote signer.
+func (rs *RemoteSigner) NodeID() types.NodeID {
+	return rs.nodeID
+}
+
+// PublicKey returns the public key of the remote signer.
+func (rs *RemoteSigner) PublicKey() *PublicKey {
+	return rs.publicKey
+}
+
+// Prefix returns the prefix prepended to every signed message.
+func (rs *RemoteSigner) Prefix() []byte {
+	return rs.opt.prefix
+}
+
+// VRFSigner returns VRFSigner that produces proofs with the remote key.
+func (rs *RemoteSigner) VRFSigner() (VRFSigner, error) {
+	return rs.vrf, nil
+}
+
+// Close the connection to the remote signer.
+func (rs *RemoteSigner) Close() error {
+	return rs.conn.Close()
+}
+
+type remoteVRFSigner struct {
+	signer *RemoteSigner
+}
+
+// Sign produces VRF proof for the message. Proof is verified before it is returned.
+func (s *remoteVRFSigner) Sign(msg []byte) (types.VrfSignature, error) {
+	var resp *pb.VRFSignResponse
+	err := s.signer.invoke(context.Background(), "VRFSign", func(ctx context.Context) (err error) {
+		resp, err = s.signer.client.VRFSign(ctx, &pb.VRFSignRequest{Message: msg})
+		return err
+	})
+	if err != nil {
+		return types.EmptyVrfSignature, err
+	}
+	if len(resp.Signature) != types.VrfSignatureSize {
+		return types.EmptyVrfSignature, fmt.Errorf("%w: invalid vrf proof size %d", ErrRemoteSigner, len(resp.Signature))
+	}
+	var proof types.VrfSignature
+	copy(proof[:], resp.Signature)
+	if !VRFVerify(s.signer.nodeID, msg, proof) {
+		return types.EmptyVrfSignature, fmt.Errorf("%w: invalid vrf proof", ErrRemoteSigner)
+	}
+	return proof, nil
+}
+
+// NodeID of the signer.
+func (s *remoteVRFSigner) NodeID() types.NodeID {
+	return s.signer.nodeID
+}
+
+// PublicKey of the signer.
+func (s *remoteVRFSigner) PublicKey() *PublicKey {
+	return s.signer.publicKey
+}
+
+// LittleEndian indicates whether byte order in a signature is little-endian.
+func (s *remoteVRFSigner) LittleEndian() bool {
+	return true
+}
@@ -0,0 +1,270 @@
+package signing_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/status"
+
+	"github.com/spacemeshos/go-spacemesh/signing"
+)
+
+const testToken = "token"
+
+func startSignerService(tb testing.TB, service *signing.SignerService, opts ...grpc.ServerOption) string {
+	tb.Helper()
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(tb, err)
+	server := grpc.NewServer(opts...)
+	service.Register(server)
+	go server.Serve(lis)
+	tb.Cleanup(server.Stop)
+	return lis.Addr().String()
+}
+
+func newSignerService(tb testing.TB, signer *signing.EdSigner, opts ...signing.SignerServiceOpt) *signing.SignerService {
+	tb.Helper()
+	service, err := signing.NewSignerService(signer.PrivateKey(), testToken, opts...)
+	require.NoError(tb, err)
+	return service
+}
+
+func TestRemoteSigner(t *testing.T) {
+	prefix := []byte("prefix")
+	local, err := signing.NewEdSigner(signing.WithPrefix(prefix))
+	require.NoError(t, err)
+	url := startSignerService(t, newSignerService(t, local, signing.WithServicePrefix(prefix)))
+
+	remote, err := signing.NewRemoteSigner(context.Background(), url,
+		signing.WithRemoteToken(testToken),
+		signing.WithRemotePrefix(prefix),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { remote.Close() })
+	require.Equal(t, local.NodeID(), remote.NodeID())
+	require.Equal(t, local.PublicKey(), remote.PublicKey())
+	require.Equal(t, prefix, remote.Prefix())
+
+	msg := []byte("message")
+	sig, err := remote.SignContext(context.Background(), signing.BALLOT, msg)
+	require.NoError(t, err)
+	// ed25519 signatures are deterministic
+	require.Equal(t, local.Sign(signing.BALLOT, msg), sig)
+
+	verifier, err := signing.NewEdVerifier(signing.WithVerifierPrefix(prefix))
+	require.NoError(t, err)
+	require.True(t, verifier.Verify(signing.BALLOT, remote.NodeID(), msg, sig))
+
+	localVRF, err := local.VRFSigner()
+	require.NoError(t, err)
+	remoteVRF, err := remote.VRFSigner()
+	require.NoError(t, err)
+	proof, err := remoteVRF.Sign(msg)
+	require.NoError(t, err)
+	expected, err := localVRF.Sign(msg)
+	require.NoError(t, err)
+	require.Equal(t, expected, proof)
+	require.True(t, signing.NewVRFVerifier().Verify(remoteVRF.NodeID(), msg, proof))
+
+	_, err = remoteVRF.Sign(make([]byte, 2048))
+	require.ErrorIs(t, err, signing.ErrRemoteSigner)
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestRemoteSigner_Domains(t *testing.T) {
+	local, err := signing.NewEdSigner()
+	require.NoError(t, err)
+	url := startSignerService(t, newSignerService(t, local, signing.WithServiceDomains(signing.BALLOT)))
+
+	remote, err := signing.NewRemoteSigner(context.Background(), url, signing.WithRemoteToken(testToken))
+	require.NoError(t, err)
+	t.Cleanup(func() { remote.Close() })
+
+	_, err = remote.SignContext(context.Background(), signing.BALLOT, []byte("message"))
+	require.NoError(t, err)
+	for _, d := range []signing.Domain{signing.HARE, signing.KEY_ROTATION, signing.Domain(255)} {
+		sig, err := remote.SignContext(context.Background(), d, []byte("message"))
+		require.ErrorIs(t, err, signing.ErrRemoteSigner, d)
+		require.Equal(t, codes.PermissionDenied, status.Code(err), d)
+		require.Empty(t, sig, d)
+	}
+}
+
+func TestRemoteSigner_DefaultDomains(t *testing.T) {
+	local, err := signing.NewEdSigner()
+	require.NoError(t, err)
+	url := startSignerService(t, newSignerService(t, local))
+
+	remote, err := signing.NewRemoteSigner(context.Background(), url, signing.WithRemoteToken(testToken))
+	require.NoError(t, err)
+	t.Cleanup(func() { remote.Close() })
+
+	for _, d := range signing.DefaultServiceDomains() {
+		_, err := remote.SignContext(context.Background(), d, []byte("message"))
+		require.NoError(t, err, d)
+	}
+	_, err = remote.SignContext(context.Background(), signing.KEY_ROTATION, []byte("message"))
+	require.Equal(t, codes.PermissionDenied, status.Code(err))
+}
+
+func TestRemoteSigner_Unauthenticated(t *testing.T) {
+	local, err := signing.NewEdSigner()
+	require.NoError(t, err)
+	url := startSignerService(t, newSignerService(t, local))
+
+	_, err = signing.NewRemoteSigner(context.Background(), url)
+	require.ErrorIs(t, err, signing.ErrRemoteSigner)
+
+	start := time.Now()
+	_, err = signing.NewRemoteSigner(context.Background(), url,
+		signing.WithRemoteToken("wrong"),
+		signing.WithConnectRetries(5, time.Second),
+	)
+	require.ErrorIs(t, err, signing.ErrRemoteSigner)
+	require.Equal(t, codes.Unauthenticated, status.Code(err))
+	// rejected token is not retried
+	require.Less(t, time.Since(start), time.Second)
+
+	_, err = signing.NewSignerService(local.PrivateKey(), "")
+	require.Error(t, err)
+}
+
+func TestRemoteSigner_PrefixMismatch(t *testing.T) {
+	local, err := signing.NewEdSigner()
+	require.NoError(t, err)
+	url := startSignerService(t, newSignerService(t, local, signing.WithServicePrefix([]byte("other"))))
+
+	_, err = signing.NewRemoteSigner(context.Background(), url,
+		signing.WithRemoteToken(testToken),
+		signing.WithRemotePrefix([]byte("prefix")),
+	)
+	require.ErrorIs(t, err, signing.ErrRemoteSigner)
+	require.ErrorContains(t, err, "prefix")
+}
+
+func TestRemoteSigner_Timeout(t *testing.T) {
+	local, err := signing.NewEdSigner()
+	require.NoError(t, err)
+	slow := make(chan struct{})
+	t.Cleanup(func() { close(slow) })
+	url := startSignerService(t, newSignerService(t, local), grpc.UnaryInterceptor(
+		func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+			if info.FullMethod != "/spacemesh.signing.v1.Signer/PublicKey" {
+				select {
+				case <-slow:
+				case <-ctx.Done():
+				}
+			}
+			return handler(ctx, req)
+		},
+	))
+
+	remote, err := signing.NewRemoteSigner(context.Background(), url,
+		signing.WithRemoteToken(testToken),
+		signing.WithSignTimeout(100*time.Millisecond),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { remote.Close() })
+
+	sig, err := remote.SignContext(context.Background(), signing.HARE, []byte("message"))
+	require.ErrorIs(t, err, signing.ErrSignTimeout)
+	require.Empty(t, sig)
+
+	vrf, err := remote.VRFSigner()
+	require.NoError(t, err)
+	_, err = vrf.Sign([]byte("message"))
+	require.ErrorIs(t, err, signing.ErrSignTimeout)
+}
+
+func TestRemoteSigner_Unavailable(t *testing.T) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	url := lis.Addr().String()
+	require.NoError(t, lis.Close())
+
+	_, err = signing.NewRemoteSigner(context.Background(), url,
+		signing.WithRemoteToken(testToken),
+		signing.WithConnectRetries(1, 10*time.Millisecond),
+		signing.WithSignTimeout(100*time.Millisecond),
+	)
+	require.ErrorIs(t, err, signing.ErrRemoteSigner)
+}
+
+func TestRemoteSigner_MutualTLS(t *testing.T) {
+	ca, caKey := newCertificate(t, nil, nil, true)
+	pool := x509.NewCertPool()
+	pool.AddCert(ca)
+	serverCert, serverKey := newCertificate(t, ca, caKey, false)
+	clientCert, clientKey := newCertificate(t, ca, caKey, false)
+
+	local, err := signing.NewEdSigner()
+	require.NoError(t, err)
+	url := startSignerService(t, newSignerService(t, local), grpc.Creds(credentials.NewTLS(&tls.Config{
+		Certificates: []tls.Certificate{{Certificate: [][]byte{serverCert.Raw}, PrivateKey: serverKey}},
+		ClientCAs:    pool,
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		MinVersion:   tls.VersionTLS12,
+	})))
+
+	_, err = signing.NewRemoteSigner(context.Background(), url,
+		signing.WithRemoteToken(testToken),
+		signing.WithRemoteTLS(&tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS12}),
+		signing.WithConnectRetries(0, 0),
+	)
+	require.ErrorIs(t, err, signing.ErrRemoteSigner)
+
+	remote, err := signing.NewRemoteSigner(context.Background(), url,
+		signing.WithRemoteToken(testToken),
+		signing.WithRemoteTLS(&tls.Config{
+			RootCAs:      pool,
+			Certificates: []tls.Certificate{{Certificate: [][]byte{clientCert.Raw}, PrivateKey: clientKey}},
+			MinVersion:   tls.VersionTLS12,
+		}),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { remote.Close() })
+	sig, err := remote.SignContext(context.Background(), signing.ATX, []byte("message"))
+	require.NoError(t, err)
+	require.Equal(t, local.Sign(signing.ATX, []byte("message")), sig)
+}
+
+func newCertificate(tb testing.TB, parent *x509.Certificate, parentKey *ecdsa.PrivateKey, isCA bool) (*x509.Certificate, *ecdsa.PrivateKey) {
+	tb.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(tb, err)
+	serial, err := rand.Int(rand.Reader, big.NewInt(1<<62))
+	require.NoError(tb, err)
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: "signer"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		IsCA:         isCA,
+
+		BasicConstraintsValid: true,
+	}
+	if parent == nil {
+		parent, parentKey = template, key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, parentKey)
+	require.NoError(tb, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(tb, err)
+	return cert, key
+}
@@ -2,13 +2,12 @@ package signing
 
 import (
 	"bytes"
+	"context"
 	"crypto/ed25519"
 	"errors"
 	"fmt"
 	"io"
 
-	oasis "github.com/oasisprotocol/curve25519-voi/primitives/ed25519"
-
 	"github.com/spacemeshos/go-spacemesh/common/types"
 	"github.com/spacemeshos/go-spacemesh/log"
 )
@@ -18,12 +17,17 @@ type Domain byte
 const (
 	ATX Domain = 0
 
-	BALLOT = 2
-	HARE   = 3
-	POET   = 4
+	BALLOT       = 2
+	HARE         = 3
+	POET         = 4
+	PROPOSAL     = 5
+	CERTIFY      = 6
+	KEY_ROTATION = 7
 
 	BEACON_FIRST_MSG    = 10
 	BEACON_FOLLOWUP_MSG = 11
+
+	OPINION_REPORT = 12
 )
 
 // String returns the string representation of a domain.
@@ -37,15 +41,57 @@ func (d Domain) String() string {
 		return "HARE"
 	case POET:
 		return "POET"
+	case PROPOSAL:
+		return "PROPOSAL"
+	case CERTIFY:
+		return "CERTIFY"
+	case KEY_ROTATION:
+		return "KEY_ROTATION"
 	case BEACON_FIRST_MSG:
 		return "BEACON_FIRST_MSG"
 	case BEACON_FOLLOWUP_MSG:
 		return "BEACON_FOLLOWUP_MSG"
+	case OPINION_REPORT:
+		return "OPINION_REPORT"
 	default:
 		return "UNKNOWN"
 	}
 }
 
+// ProposalDomain returns domain for the proposal signature in the layer.
+// Before the upgrade layer proposals are signed in the BALLOT domain, zero upgrade layer disables the upgrade.
+func ProposalDomain(upgrade, lid types.LayerID) Domain {
+	return upgradedDomain(BALLOT, PROPOSAL, upgrade, lid)
+}
+
+// CertifyDomain returns domain for the block certify message signature in the layer.
+// Before the upgrade layer certify messages are signed in the HARE domain, zero upgrade layer disables the upgrade.
+func CertifyDomain(upgrade, lid types.LayerID) Domain {
+	return upgradedDomain(HARE, CERTIFY, upgrade, lid)
+}
+
+func upgradedDomain(legacy, upgraded Domain, upgrade, lid types.LayerID) Domain {
+	if upgrade == 0 || lid.Before(upgrade) {
+		return legacy
+	}
+	return upgraded
+}
+
+// Signer signs messages with the key of the smesher. EdSigner holds the key in memory,
+// RemoteSigner delegates signing to the isolated signing service.
+type Signer interface {
+	// SignContext signs the message in the domain.
+	SignContext(ctx context.Context, d Domain, m []byte) (types.EdSignature, error)
+	// NodeID of the signer.
+	NodeID() types.NodeID
+	// PublicKey of the signer.
+	PublicKey() *PublicKey
+	// Prefix prepended to every signed message.
+	Prefix() []byte
+	// VRFSigner that produces proofs with the same key.
+	VRFSigner() (VRFSigner, error)
+}
+
 type edSignerOption struct {
 	priv   PrivateKey
 	prefix []byte
@@ -96,6 +142,7 @@ func WithKeyFromRand(rand io.Reader) EdSignerOptionFunc {
 // EdSigner represents an ED25519 signer.
 type EdSigner struct {
 	priv PrivateKey
+	vrf  *ecvrfSigner
 
 	prefix []byte
 }
@@ -119,6 +166,7 @@ func NewEdSigner(opts ...EdSignerOptionFunc) (*EdSigner, error) {
 	}
 	sig := &EdSigner{
 		priv:   cfg.priv,
+		vrf:    newECVRFSigner(cfg.priv),
 		prefix: cfg.prefix,
 	}
 	return sig, nil
@@ -134,6 +182,11 @@ func (es *EdSigner) Sign(d Domain, m []byte) types.EdSignature {
 	return *(*[types.EdSignatureSize]byte)(ed25519.Sign(es.priv, msg))
 }
 
+// SignContext signs the message in the domain. It never fails for the local key.
+func (es *EdSigner) SignContext(_ context.Context, d Domain, m []byte) (types.EdSignature, error) {
+	return es.Sign(d, m), nil
+}
+
 // NodeID returns the node ID of the signer.
 func (es *EdSigner) NodeID() types.NodeID {
 	return types.BytesToNodeID(es.PublicKey().Bytes())
@@ -150,13 +203,12 @@ func (es *EdSigner) PrivateKey() PrivateKey {
 }
 
 // VRFSigner wraps same ed25519 key to provide ecvrf.
-func (es *EdSigner) VRFSigner() (*VRFSigner, error) {
-	return &VRFSigner{
-		privateKey: oasis.PrivateKey(es.priv),
-		nodeID:     es.NodeID(),
-	}, nil
+// Every call returns the same instance.
+func (es *EdSigner) VRFSigner() (VRFSigner, error) {
+	return es.vrf, nil
 }
 
+// Prefix returns the prefix prepended to every signed message.
 func (es *EdSigner) Prefix() []byte {
 	return es.prefix
 }
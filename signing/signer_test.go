@@ -60,3 +60,49 @@ func TestPublicKey_ShortString(t *testing.T) {
 	pub = NewPublicKey([]byte{1, 2})
 	require.Equal(t, pub.String(), pub.ShortString())
 }
+
+func TestPublicKey_ArrayKey(t *testing.T) {
+	ed, err := NewEdSigner()
+	require.NoError(t, err)
+	pub := ed.PublicKey()
+	key := pub.ArrayKey()
+	require.Equal(t, pub.Bytes(), key[:])
+	require.Equal(t, key, NewPublicKey(key[:]).ArrayKey())
+	require.True(t, pub.Equals(NewPublicKey(key[:])))
+
+	var empty *PublicKey
+	require.Equal(t, [ed25519.PublicKeySize]byte{}, empty.ArrayKey())
+	require.False(t, pub.Equals(empty))
+	require.Zero(t, testing.AllocsPerRun(10, func() {
+		_ = pub.ArrayKey()
+		_ = pub.Equals(pub)
+	}))
+}
+
+func BenchmarkPublicKey_MapKey(b *testing.B) {
+	const n = 10_000
+	keys := make([]*PublicKey, n)
+	for i := range keys {
+		ed, err := NewEdSigner()
+		require.NoError(b, err)
+		keys[i] = ed.PublicKey()
+	}
+	b.Run("string", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			m := make(map[string]struct{}, n)
+			for _, key := range keys {
+				m[key.String()] = struct{}{}
+			}
+		}
+	})
+	b.Run("array", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			m := make(map[[ed25519.PublicKeySize]byte]struct{}, n)
+			for _, key := range keys {
+				m[key.ArrayKey()] = struct{}{}
+			}
+		}
+	})
+}
@@ -0,0 +1,132 @@
+package signing
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/spacemeshos/go-spacemesh/common/types"
+)
+
+// KeyFileExtension is the extension of the key files loaded by the SignerStore.
+const KeyFileExtension = ".key"
+
+var (
+	// ErrDuplicateSigner is returned when identity is already registered in the SignerStore.
+	ErrDuplicateSigner = errors.New("signer store: duplicate identity")
+	// ErrPasswordRequired is returned when encrypted key is loaded without password.
+	ErrPasswordRequired = errors.New("signer store: key is encrypted but password is not configured")
+)
+
+// SignerStore is a registry of identities operated by a single node.
+type SignerStore struct {
+	mu      sync.RWMutex
+	signers map[types.NodeID]*EdSigner
+}
+
+// NewSignerStore creates SignerStore with the signers.
+func NewSignerStore(signers ...*EdSigner) (*SignerStore, error) {
+	store := &SignerStore{signers: map[types.NodeID]*EdSigner{}}
+	for _, signer := range signers {
+		if err := store.Add(signer); err != nil {
+			return nil, err
+		}
+	}
+	return store, nil
+}
+
+// LoadSignerStore loads every key file with KeyFileExtension from the dir.
+// Keys are either hex encoded or encrypted with the password, see SaveKey.
+func LoadSignerStore(dir string, password []byte, opts ...EdSignerOptionFunc) (*SignerStore, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("signer store: read dir %s: %w", dir, err)
+	}
+	store, _ := NewSignerStore()
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), KeyFileExtension) {
+			continue
+		}
+		path := filepath.Join(dir, entry.Name())
+		signer, err := loadSigner(path, password, opts...)
+		if err != nil {
+			return nil, err
+		}
+		if err := store.Add(signer); err != nil {
+			return nil, fmt.Errorf("%w: %s", err, path)
+		}
+	}
+	return store, nil
+}
+
+func loadSigner(path string, password []byte, opts ...EdSignerOptionFunc) (*EdSigner, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("signer store: read %s: %w", path, err)
+	}
+	if IsEncryptedKey(data) {
+		if password == nil {
+			return nil, fmt.Errorf("%w: %s", ErrPasswordRequired, path)
+		}
+		priv, err := DecryptKey(data, password)
+		if err != nil {
+			return nil, fmt.Errorf("signer store: decrypt %s: %w", path, err)
+		}
+		return NewEdSigner(append(opts, WithPrivateKey(priv))...)
+	}
+	priv := make([]byte, PrivateKeySize)
+	n, err := hex.Decode(priv, bytes.TrimSpace(data))
+	if err != nil {
+		return nil, fmt.Errorf("signer store: decode %s: %w", path, err)
+	}
+	if n != PrivateKeySize {
+		return nil, fmt.Errorf("signer store: invalid key size %d/%d in %s", n, PrivateKeySize, path)
+	}
+	return NewEdSigner(append(opts, WithPrivateKey(priv))...)
+}
+
+// Add registers signer in the store.
+func (s *SignerStore) Add(signer *EdSigner) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, exists := s.signers[signer.NodeID()]; exists {
+		return fmt.Errorf("%w: %s", ErrDuplicateSigner, signer.NodeID().ShortString())
+	}
+	s.signers[signer.NodeID()] = signer
+	return nil
+}
+
+// Get returns signer for the identity.
+func (s *SignerStore) Get(id types.NodeID) (*EdSigner, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	signer, exists := s.signers[id]
+	return signer, exists
+}
+
+// Signers returns registered signers sorted by NodeID.
+func (s *SignerStore) Signers() []*EdSigner {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	rst := make([]*EdSigner, 0, len(s.signers))
+	for _, signer := range s.signers {
+		rst = append(rst, signer)
+	}
+	sort.Slice(rst, func(i, j int) bool {
+		return bytes.Compare(rst[i].NodeID().Bytes(), rst[j].NodeID().Bytes()) < 0
+	})
+	return rst
+}
+
+// Len returns number of registered signers.
+func (s *SignerStore) Len() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.signers)
+}
@@ -0,0 +1,55 @@
+package signing
+
+import (
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSignerStore(t *testing.T) {
+	dir := t.TempDir()
+	password := []byte("password")
+	signers := make([]*EdSigner, 3)
+	for i := range signers {
+		signer, err := NewEdSigner()
+		require.NoError(t, err)
+		signers[i] = signer
+	}
+	require.NoError(t, saveKey(filepath.Join(dir, "0"+KeyFileExtension), signers[0], password, testKDFParams()))
+	require.NoError(t, saveKey(filepath.Join(dir, "1"+KeyFileExtension), signers[1], password, testKDFParams()))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "2"+KeyFileExtension),
+		[]byte(hex.EncodeToString(signers[2].PrivateKey())), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "README"), []byte("ignored"), 0o600))
+
+	t.Run("load", func(t *testing.T) {
+		store, err := LoadSignerStore(dir, password, WithPrefix([]byte{1}))
+		require.NoError(t, err)
+		require.Equal(t, 3, store.Len())
+		for _, signer := range signers {
+			loaded, exists := store.Get(signer.NodeID())
+			require.True(t, exists)
+			require.Equal(t, signer.PrivateKey(), loaded.PrivateKey())
+			require.Equal(t, []byte{1}, loaded.Prefix())
+		}
+		loaded := store.Signers()
+		require.Len(t, loaded, 3)
+		for i := 1; i < len(loaded); i++ {
+			require.Less(t, loaded[i-1].NodeID().String(), loaded[i].NodeID().String())
+		}
+	})
+	t.Run("no password", func(t *testing.T) {
+		_, err := LoadSignerStore(dir, nil)
+		require.ErrorIs(t, err, ErrPasswordRequired)
+	})
+	t.Run("wrong password", func(t *testing.T) {
+		_, err := LoadSignerStore(dir, []byte("wrong"))
+		require.ErrorIs(t, err, ErrWrongPassword)
+	})
+	t.Run("duplicate", func(t *testing.T) {
+		_, err := NewSignerStore(signers[0], signers[0])
+		require.ErrorIs(t, err, ErrDuplicateSigner)
+	})
+}
@@ -72,6 +72,34 @@ func TestVerifier_WithPrefix(t *testing.T) {
 	})
 }
 
+func TestUpgradedDomains(t *testing.T) {
+	const upgrade = types.LayerID(10)
+	require.Equal(t, signing.Domain(signing.BALLOT), signing.ProposalDomain(0, 100))
+	require.Equal(t, signing.Domain(signing.BALLOT), signing.ProposalDomain(upgrade, upgrade-1))
+	require.Equal(t, signing.Domain(signing.PROPOSAL), signing.ProposalDomain(upgrade, upgrade))
+	require.Equal(t, signing.Domain(signing.HARE), signing.CertifyDomain(0, 100))
+	require.Equal(t, signing.Domain(signing.HARE), signing.CertifyDomain(upgrade, upgrade-1))
+	require.Equal(t, signing.Domain(signing.CERTIFY), signing.CertifyDomain(upgrade, upgrade))
+
+	signer, err := signing.NewEdSigner(signing.WithPrefix([]byte("one")))
+	require.NoError(t, err)
+	verifier, err := signing.NewEdVerifier(signing.WithVerifierPrefix([]byte("one")))
+	require.NoError(t, err)
+	msg := []byte("test")
+
+	// signatures after upgrade can't be replayed in the domain they shared before the upgrade.
+	for _, tc := range []struct {
+		upgraded, legacy signing.Domain
+	}{
+		{signing.ProposalDomain(upgrade, upgrade), signing.BALLOT},
+		{signing.CertifyDomain(upgrade, upgrade), signing.HARE},
+	} {
+		sig := signer.Sign(tc.upgraded, msg)
+		require.True(t, verifier.Verify(tc.upgraded, signer.NodeID(), msg, sig))
+		require.False(t, verifier.Verify(tc.legacy, signer.NodeID(), msg, sig), tc.upgraded)
+	}
+}
+
 func Fuzz_EdVerifier(f *testing.F) {
 	f.Fuzz(func(t *testing.T, msg, prefix []byte) {
 		signer, err := signing.NewEdSigner(signing.WithPrefix(prefix))
@@ -86,3 +114,23 @@ func Fuzz_EdVerifier(f *testing.F) {
 		require.True(t, ok)
 	})
 }
+
+func BenchmarkEdVerifier_VerifyBallot(b *testing.B) {
+	signer, err := signing.NewEdSigner()
+	require.NoError(b, err)
+	verifier, err := signing.NewEdVerifier()
+	require.NoError(b, err)
+
+	ballot := types.RandomBallot()
+	ballot.Signature = signer.Sign(signing.BALLOT, ballot.SignedBytes())
+	ballot.SmesherID = signer.NodeID()
+	msg := ballot.SignedBytes()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if !verifier.Verify(signing.BALLOT, ballot.SmesherID, msg, ballot.Signature) {
+			b.Fatal("invalid signature")
+		}
+	}
+}
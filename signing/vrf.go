@@ -7,41 +7,94 @@ import (
 	"github.com/spacemeshos/go-spacemesh/common/types"
 )
 
-// VRFSigner is a signer for VRF purposes.
-type VRFSigner struct {
+// VRFSigner produces VRF proofs with the key of the smesher.
+// It is the single place to swap the VRF primitive used by beacon, hare and ballot eligibility.
+type VRFSigner interface {
+	// Sign produces VRF proof for the message.
+	Sign(msg []byte) (types.VrfSignature, error)
+	// NodeID of the signer.
+	NodeID() types.NodeID
+	// PublicKey of the signer.
+	PublicKey() *PublicKey
+	// LittleEndian indicates whether byte order in a signature is little-endian.
+	LittleEndian() bool
+}
+
+// VRFVerifier verifies VRF proofs produced by VRFSigner.
+type VRFVerifier interface {
+	Verify(nodeID types.NodeID, msg []byte, sig types.VrfSignature) bool
+	// VerifyBatch verifies proofs for the corresponding keys and messages.
+	// If any proof is invalid it returns false and the index of the first invalid proof.
+	// If the lengths don't match, proofs without a key or a message are invalid, and so are keys
+	// and messages without a proof.
+	VerifyBatch(nodeIDs []types.NodeID, msgs [][]byte, sigs []types.VrfSignature) (int, bool)
+}
+
+// ecvrfSigner is a VRFSigner that uses ECVRF-EDWARDS25519-SHA512-ELL2 with the ed25519 key.
+type ecvrfSigner struct {
 	privateKey ed25519.PrivateKey
 	nodeID     types.NodeID
+	publicKey  *PublicKey
+}
+
+func newECVRFSigner(priv PrivateKey) *ecvrfSigner {
+	nodeID := types.BytesToNodeID(Public(priv))
+	return &ecvrfSigner{
+		privateKey: ed25519.PrivateKey(priv),
+		nodeID:     nodeID,
+		publicKey:  NewPublicKey(nodeID.Bytes()),
+	}
 }
 
-// Sign signs a message for VRF purposes.
-func (s VRFSigner) Sign(msg []byte) types.VrfSignature {
-	return *(*[types.VrfSignatureSize]byte)(ecvrf.Prove(s.privateKey, msg))
+// Sign signs a message for VRF purposes. It never fails for the local key.
+func (s *ecvrfSigner) Sign(msg []byte) (types.VrfSignature, error) {
+	return *(*[types.VrfSignatureSize]byte)(ecvrf.Prove(s.privateKey, msg)), nil
 }
 
 // NodeID of the signer.
-func (s VRFSigner) NodeID() types.NodeID {
+func (s *ecvrfSigner) NodeID() types.NodeID {
 	return s.nodeID
 }
 
 // PublicKey of the signer.
-func (s VRFSigner) PublicKey() *PublicKey {
-	return NewPublicKey(s.nodeID.Bytes())
+func (s *ecvrfSigner) PublicKey() *PublicKey {
+	return s.publicKey
 }
 
 // LittleEndian indicates whether byte order in a signature is little-endian.
-func (s VRFSigner) LittleEndian() bool {
+func (s *ecvrfSigner) LittleEndian() bool {
 	return true
 }
 
-type VRFVerifier func(types.NodeID, []byte, types.VrfSignature) bool
+type ecvrfVerifier struct{}
 
+// NewVRFVerifier returns VRFVerifier for proofs produced by the signer from EdSigner.VRFSigner.
 func NewVRFVerifier() VRFVerifier {
-	return VRFVerify
+	return ecvrfVerifier{}
 }
 
 // Verify verifies that a signature matches public key and message.
-func (v VRFVerifier) Verify(nodeID types.NodeID, msg []byte, sig types.VrfSignature) bool {
-	return v(nodeID, msg, sig)
+func (ecvrfVerifier) Verify(nodeID types.NodeID, msg []byte, sig types.VrfSignature) bool {
+	return VRFVerify(nodeID, msg, sig)
+}
+
+// VerifyBatch verifies proofs one by one, as ECVRF primitive doesn't support batch verification.
+func (v ecvrfVerifier) VerifyBatch(nodeIDs []types.NodeID, msgs [][]byte, sigs []types.VrfSignature) (int, bool) {
+	return verifySequential(v, nodeIDs, msgs, sigs)
+}
+
+// verifySequential implements VerifyBatch for primitives without batch mode.
+func verifySequential(v VRFVerifier, nodeIDs []types.NodeID, msgs [][]byte, sigs []types.VrfSignature) (int, bool) {
+	for i := range sigs {
+		// a proof without the key or the message can't be valid
+		if i >= len(nodeIDs) || i >= len(msgs) || !v.Verify(nodeIDs[i], msgs[i], sigs[i]) {
+			return i, false
+		}
+	}
+	if len(nodeIDs) != len(sigs) || len(msgs) != len(sigs) {
+		return len(sigs), false
+	}
+	return 0, true
 }
 
 // VRFVerify verifies that a signature matches public key and message.
@@ -17,7 +17,7 @@ func Fuzz_VRFSignAndVerify(f *testing.F) {
 		vrfSig, err := edSig.VRFSigner()
 		require.NoError(t, err, "failed to create VRF signer")
 
-		signature := vrfSig.Sign(message)
+		signature, err := vrfSig.Sign(message)
 		require.NoError(t, err, "failed to sign message")
 
 		ok := VRFVerify(edSig.NodeID(), message, signature)
@@ -39,7 +39,8 @@ func Test_VRFSignAndVerify(t *testing.T) {
 	require.NoError(t, err, "failed to create VRF signer")
 
 	message := []byte("hello world")
-	signature := vrfSig.Sign(message)
+	signature, err := vrfSig.Sign(message)
+	require.NoError(t, err, "failed to sign message")
 
 	vrfVerify := NewVRFVerifier()
 	ok := vrfVerify.Verify(signer.NodeID(), message, signature)
@@ -69,7 +70,8 @@ func Test_VRFVerifier(t *testing.T) {
 	require.NoError(t, err, "failed to create VRF signer")
 
 	// Act & Assert
-	sig := vrfSig.Sign([]byte("hello world"))
+	sig, err := vrfSig.Sign([]byte("hello world"))
+	require.NoError(t, err, "failed to sign message")
 
 	ok := VRFVerify(signer.NodeID(), []byte("hello world"), sig)
 	require.True(t, ok, "failed to verify VRF signature")
@@ -100,7 +102,8 @@ func Test_VRF_LSB_evenly_distributed(t *testing.T) {
 		_, err := rand.Read(msg)
 		require.NoError(t, err, "failed to read random bytes")
 
-		sig := vrfSig.Sign(msg)
+		sig, err := vrfSig.Sign(msg)
+		require.NoError(t, err, "failed to sign message")
 		lsb[sig.LSB()]++
 	}
 
@@ -110,3 +113,49 @@ func Test_VRF_LSB_evenly_distributed(t *testing.T) {
 		require.InDelta(t, iterations/2, lsb[i], maxDeviation, "LSB %d was not evenly distributed", i)
 	}
 }
+
+func Test_VRFSigner_Cached(t *testing.T) {
+	signer, err := NewEdSigner()
+	require.NoError(t, err)
+	vrf1, err := signer.VRFSigner()
+	require.NoError(t, err)
+	vrf2, err := signer.VRFSigner()
+	require.NoError(t, err)
+	require.Same(t, vrf1, vrf2)
+	require.Same(t, vrf1.PublicKey(), vrf2.PublicKey())
+}
+
+func Test_VRFVerifier_VerifyBatch(t *testing.T) {
+	const n = 5
+	var (
+		verifier = NewVRFVerifier()
+		nodeIDs  = make([]types.NodeID, n)
+		msgs     = make([][]byte, n)
+		sigs     = make([]types.VrfSignature, n)
+	)
+	for i := 0; i < n; i++ {
+		signer, err := NewEdSigner()
+		require.NoError(t, err)
+		vrfSig, err := signer.VRFSigner()
+		require.NoError(t, err)
+		nodeIDs[i] = signer.NodeID()
+		msgs[i] = []byte{byte(i)}
+		sigs[i], err = vrfSig.Sign(msgs[i])
+		require.NoError(t, err)
+	}
+	_, ok := verifier.VerifyBatch(nodeIDs, msgs, sigs)
+	require.True(t, ok)
+
+	msgs[3] = []byte("different message")
+	idx, ok := verifier.VerifyBatch(nodeIDs, msgs, sigs)
+	require.False(t, ok)
+	require.Equal(t, 3, idx)
+
+	msgs[3] = []byte{3}
+	idx, ok = verifier.VerifyBatch(nodeIDs, msgs[:2], sigs)
+	require.False(t, ok)
+	require.Equal(t, 2, idx)
+	idx, ok = verifier.VerifyBatch(nodeIDs, msgs, sigs[:4])
+	require.False(t, ok)
+	require.Equal(t, 4, idx)
+}
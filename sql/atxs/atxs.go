@@ -212,6 +212,29 @@ func GetIDsByEpoch(db sql.Executor, epoch types.EpochID) (ids []types.ATXID, err
 	return ids, nil
 }
 
+// GetIDsByEpochReceivedBefore gets IDs of the ATXs for a given epoch that were received before the cutoff,
+// ordered by id. Checkpointed ATXs are always received before the cutoff.
+func GetIDsByEpochReceivedBefore(db sql.Executor, epoch types.EpochID, cutoff time.Time) (ids []types.ATXID, err error) {
+	enc := func(stmt *sql.Statement) {
+		stmt.BindInt64(1, int64(epoch))
+		stmt.BindInt64(2, cutoff.UnixNano())
+	}
+	dec := func(stmt *sql.Statement) bool {
+		var id types.ATXID
+		stmt.ColumnBytes(0, id[:])
+		ids = append(ids, id)
+		return true
+	}
+
+	if rows, err := db.Exec("select id from atxs where epoch = ?1 and received < ?2 order by id;", enc, dec); err != nil {
+		return nil, fmt.Errorf("exec epoch %v: %w", epoch, err)
+	} else if rows == 0 {
+		return []types.ATXID{}, nil
+	}
+
+	return ids, nil
+}
+
 // VRFNonce gets the VRF nonce of a smesher for a given epoch.
 func VRFNonce(db sql.Executor, id types.NodeID, epoch types.EpochID) (nonce types.VRFPostIndex, err error) {
 	enc := func(stmt *sql.Statement) {
@@ -1,7 +1,9 @@
 package atxs_test
 
 import (
+	"bytes"
 	"os"
+	"sort"
 	"testing"
 	"time"
 
@@ -414,6 +416,40 @@ func TestGetIDsByEpoch(t *testing.T) {
 	require.EqualValues(t, []types.ATXID{atx4.ID()}, ids3)
 }
 
+func TestGetIDsByEpochReceivedBefore(t *testing.T) {
+	db := sql.InMemory()
+	epoch := types.EpochID(2)
+	cutoff := time.Now()
+
+	var expected []types.ATXID
+	for _, received := range []time.Time{cutoff.Add(-time.Minute), cutoff.Add(-time.Second), cutoff, cutoff.Add(time.Second)} {
+		sig, err := signing.NewEdSigner()
+		require.NoError(t, err)
+		atx, err := newAtx(sig, withPublishEpoch(epoch), withReceived(received))
+		require.NoError(t, err)
+		require.NoError(t, atxs.Add(db, atx))
+		if received.Before(cutoff) {
+			expected = append(expected, atx.ID())
+		}
+	}
+	sig, err := signing.NewEdSigner()
+	require.NoError(t, err)
+	other, err := newAtx(sig, withPublishEpoch(epoch+1), withReceived(cutoff.Add(-time.Minute)))
+	require.NoError(t, err)
+	require.NoError(t, atxs.Add(db, other))
+
+	sort.Slice(expected, func(i, j int) bool {
+		return bytes.Compare(expected[i].Bytes(), expected[j].Bytes()) < 0
+	})
+	got, err := atxs.GetIDsByEpochReceivedBefore(db, epoch, cutoff)
+	require.NoError(t, err)
+	require.Equal(t, expected, got)
+
+	got, err = atxs.GetIDsByEpochReceivedBefore(db, epoch+2, cutoff)
+	require.NoError(t, err)
+	require.Empty(t, got)
+}
+
 func TestVRFNonce(t *testing.T) {
 	// Arrange
 	db := sql.InMemory()
@@ -553,6 +589,12 @@ func withPublishEpoch(epoch types.EpochID) createAtxOpt {
 	}
 }
 
+func withReceived(received time.Time) createAtxOpt {
+	return func(atx *types.ActivationTx) {
+		atx.SetReceived(received)
+	}
+}
+
 func withSequence(seq uint64) createAtxOpt {
 	return func(atx *types.ActivationTx) {
 		atx.Sequence = seq
@@ -569,12 +611,12 @@ func newAtx(signer *signing.EdSigner, opts ...createAtxOpt) (*types.VerifiedActi
 			NumUnits: 2,
 		},
 	}
+	atx.SetReceived(time.Now().Local())
 	for _, opt := range opts {
 		opt(atx)
 	}
 	activation.SignAndFinalizeAtx(signer, atx)
 	atx.SetEffectiveNumUnits(atx.NumUnits)
-	atx.SetReceived(time.Now().Local())
 	return atx.Verify(0, 1)
 }
 
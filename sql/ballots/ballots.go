@@ -94,6 +94,18 @@ func Get(db sql.Executor, id types.BallotID) (rst *types.Ballot, err error) {
 
 // Layer returns full body ballot for layer.
 func Layer(db sql.Executor, lid types.LayerID) (rst []*types.Ballot, err error) {
+	if err := IterateLayer(db, lid, func(ballot *types.Ballot) bool {
+		rst = append(rst, ballot)
+		return true
+	}); err != nil {
+		return nil, err
+	}
+	return rst, nil
+}
+
+// IterateLayer decodes ballots in the layer one by one and passes them to fn.
+// Iteration stops early if fn returns false.
+func IterateLayer(db sql.Executor, lid types.LayerID, fn func(*types.Ballot) bool) (err error) {
 	if _, err = db.Exec(`select id, pubkey, ballot, length(identities.proof)
 		from ballots left join identities using(pubkey)
 		where layer = ?1;`, func(stmt *sql.Statement) {
@@ -110,12 +122,25 @@ func Layer(db sql.Executor, lid types.LayerID) (rst []*types.Ballot, err error)
 		if err != nil {
 			return false
 		}
-		rst = append(rst, ballot)
+		return fn(ballot)
+	}); err != nil {
+		return fmt.Errorf("ballots for layer %s: %w", lid, err)
+	}
+	return err
+}
+
+// CountInLayer returns the number of ballots in the layer.
+func CountInLayer(db sql.Executor, lid types.LayerID) (int, error) {
+	var count int
+	if _, err := db.Exec("select count(*) from ballots where layer = ?1;", func(stmt *sql.Statement) {
+		stmt.BindInt64(1, int64(lid))
+	}, func(stmt *sql.Statement) bool {
+		count = int(stmt.ColumnInt64(0))
 		return true
 	}); err != nil {
-		return nil, fmt.Errorf("ballots for layer %s: %w", lid, err)
+		return 0, fmt.Errorf("count ballots in layer %s: %w", lid, err)
 	}
-	return rst, err
+	return count, nil
 }
 
 // IDsInLayer returns ballots ids in the layer.
@@ -55,6 +55,42 @@ func TestLayer(t *testing.T) {
 	}
 }
 
+func TestIterateLayer(t *testing.T) {
+	db := sql.InMemory()
+	start := types.LayerID(1)
+	pub := types.BytesToNodeID([]byte{1, 1, 1})
+	for i := byte(1); i <= 5; i++ {
+		ballot := types.NewExistingBallot(types.BallotID{i}, types.EmptyEdSignature, pub, start)
+		require.NoError(t, Add(db, &ballot))
+	}
+	other := types.NewExistingBallot(types.BallotID{10}, types.EmptyEdSignature, pub, start.Add(1))
+	require.NoError(t, Add(db, &other))
+
+	count, err := CountInLayer(db, start)
+	require.NoError(t, err)
+	require.Equal(t, 5, count)
+	count, err = CountInLayer(db, start.Add(2))
+	require.NoError(t, err)
+	require.Zero(t, count)
+
+	var got []types.BallotID
+	require.NoError(t, IterateLayer(db, start, func(ballot *types.Ballot) bool {
+		require.Equal(t, start, ballot.Layer)
+		got = append(got, ballot.ID())
+		return true
+	}))
+	ids, err := IDsInLayer(db, start)
+	require.NoError(t, err)
+	require.ElementsMatch(t, ids, got)
+
+	got = got[:0]
+	require.NoError(t, IterateLayer(db, start, func(ballot *types.Ballot) bool {
+		got = append(got, ballot.ID())
+		return len(got) < 2
+	}))
+	require.Len(t, got, 2)
+}
+
 func TestAdd(t *testing.T) {
 	db := sql.InMemory()
 	nodeID := types.RandomNodeID()
@@ -137,23 +137,49 @@ func GetLayer(db sql.Executor, id types.BlockID) (types.LayerID, error) {
 
 // Layer returns full body blocks for layer.
 func Layer(db sql.Executor, lid types.LayerID) ([]*types.Block, error) {
-	var (
-		blk *types.Block
-		rst []*types.Block
-		err error
-	)
-	if _, err = db.Exec("select id, block from blocks where layer = ?1;", func(stmt *sql.Statement) {
+	var rst []*types.Block
+	if err := IterateLayer(db, lid, func(blk *types.Block) bool {
+		rst = append(rst, blk)
+		return true
+	}); err != nil {
+		return nil, err
+	}
+	return rst, nil
+}
+
+// IterateLayer decodes blocks in the layer one by one and passes them to fn.
+// Iteration stops early if fn returns false.
+func IterateLayer(db sql.Executor, lid types.LayerID, fn func(*types.Block) bool) error {
+	var derr error
+	if _, err := db.Exec("select id, block from blocks where layer = ?1;", func(stmt *sql.Statement) {
 		stmt.BindInt64(1, int64(lid.Uint32()))
 	}, func(stmt *sql.Statement) bool {
 		id := types.BlockID{}
 		stmt.ColumnBytes(0, id[:])
-		blk, err = decodeBlock(stmt.ColumnReader(1), id)
-		rst = append(rst, blk)
+		var blk *types.Block
+		blk, derr = decodeBlock(stmt.ColumnReader(1), id)
+		if derr != nil {
+			return false
+		}
+		return fn(blk)
+	}); err != nil {
+		return fmt.Errorf("select blocks in layer %s: %w", lid, err)
+	}
+	return derr
+}
+
+// CountInLayer returns the number of blocks in the layer.
+func CountInLayer(db sql.Executor, lid types.LayerID) (int, error) {
+	var count int
+	if _, err := db.Exec("select count(*) from blocks where layer = ?1;", func(stmt *sql.Statement) {
+		stmt.BindInt64(1, int64(lid.Uint32()))
+	}, func(stmt *sql.Statement) bool {
+		count = int(stmt.ColumnInt64(0))
 		return true
 	}); err != nil {
-		return nil, fmt.Errorf("select blocks in layer %s: %w", lid, err)
+		return 0, fmt.Errorf("count blocks in layer %s: %w", lid, err)
 	}
-	return rst, nil
+	return count, nil
 }
 
 // IDsInLayer returns list of block ids in the layer.
@@ -119,6 +119,39 @@ func TestLayerFilter(t *testing.T) {
 	require.ElementsMatch(t, blocks[:2], blks)
 }
 
+func TestIterateLayer(t *testing.T) {
+	db := sql.InMemory()
+	start := types.LayerID(1)
+	for i := byte(1); i <= 5; i++ {
+		require.NoError(t, Add(db, types.NewExistingBlock(types.BlockID{i}, types.InnerBlock{LayerIndex: start})))
+	}
+	require.NoError(t, Add(db, types.NewExistingBlock(types.BlockID{10}, types.InnerBlock{LayerIndex: start.Add(1)})))
+
+	count, err := CountInLayer(db, start)
+	require.NoError(t, err)
+	require.Equal(t, 5, count)
+	count, err = CountInLayer(db, start.Add(2))
+	require.NoError(t, err)
+	require.Zero(t, count)
+
+	var got []types.BlockID
+	require.NoError(t, IterateLayer(db, start, func(block *types.Block) bool {
+		require.Equal(t, start, block.LayerIndex)
+		got = append(got, block.ID())
+		return true
+	}))
+	bids, err := IDsInLayer(db, start)
+	require.NoError(t, err)
+	require.Equal(t, bids, got)
+
+	got = got[:0]
+	require.NoError(t, IterateLayer(db, start, func(block *types.Block) bool {
+		got = append(got, block.ID())
+		return len(got) < 2
+	}))
+	require.Len(t, got, 2)
+}
+
 func TestLayerOrdered(t *testing.T) {
 	db := sql.InMemory()
 	start := types.LayerID(1)
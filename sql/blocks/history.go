@@ -0,0 +1,162 @@
+package blocks
+
+import (
+	"fmt"
+
+	"github.com/spacemeshos/go-spacemesh/common/types"
+	"github.com/spacemeshos/go-spacemesh/sql"
+)
+
+// MaxValidityHistory is the max number of validity changes kept for a block, older changes are dropped.
+const MaxValidityHistory = 32
+
+// Validity is the contextual validity of a block.
+type Validity int8
+
+const (
+	Undecided Validity = 0
+	Valid     Validity = valid
+	Invalid   Validity = invalid
+)
+
+func (v Validity) String() string {
+	switch v {
+	case Undecided:
+		return "undecided"
+	case Valid:
+		return "valid"
+	case Invalid:
+		return "invalid"
+	}
+	return fmt.Sprintf("validity(%d)", int8(v))
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (v Validity) MarshalText() ([]byte, error) {
+	return []byte(v.String()), nil
+}
+
+// ValiditySource is the reason for the change of the block validity.
+type ValiditySource uint8
+
+const (
+	// SourceHare is used when the block was output by hare and not decided by the tortoise yet.
+	SourceHare ValiditySource = iota
+	// SourceCertificate is used when the block has a valid certificate and not decided by the tortoise yet.
+	SourceCertificate
+	// SourceVerifying is used for the first decision of the tortoise.
+	SourceVerifying
+	// SourceHealing is used when the tortoise changed its previous decision.
+	SourceHealing
+)
+
+func (s ValiditySource) String() string {
+	switch s {
+	case SourceHare:
+		return "hare"
+	case SourceCertificate:
+		return "certificate"
+	case SourceVerifying:
+		return "verifying"
+	case SourceHealing:
+		return "healing"
+	}
+	return fmt.Sprintf("source(%d)", uint8(s))
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (s ValiditySource) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// ValidityChange is a single transition of the block validity.
+type ValidityChange struct {
+	// Layer is the layer processed by the node when the change was decided.
+	Layer  types.LayerID  `json:"layer"`
+	Old    Validity       `json:"old"`
+	New    Validity       `json:"new"`
+	Source ValiditySource `json:"source"`
+}
+
+// AddValidityChange appends the change to the history of the block from the layer lid.
+// Only the last MaxValidityHistory changes are kept.
+func AddValidityChange(db sql.Executor, id types.BlockID, lid types.LayerID, change ValidityChange) error {
+	if _, err := db.Exec(`insert into block_validity_history
+		(block, seq, layer, decided, old_validity, new_validity, source)
+		values (?1, coalesce((select max(seq) from block_validity_history where block = ?1), 0) + 1,
+			?2, ?3, ?4, ?5, ?6);`,
+		func(stmt *sql.Statement) {
+			stmt.BindBytes(1, id.Bytes())
+			stmt.BindInt64(2, int64(lid))
+			stmt.BindInt64(3, int64(change.Layer))
+			stmt.BindInt64(4, int64(change.Old))
+			stmt.BindInt64(5, int64(change.New))
+			stmt.BindInt64(6, int64(change.Source))
+		}, nil); err != nil {
+		return fmt.Errorf("add validity change %s: %w", id, err)
+	}
+	if _, err := db.Exec(`delete from block_validity_history where block = ?1
+		and seq <= (select max(seq) from block_validity_history where block = ?1) - ?2;`,
+		func(stmt *sql.Statement) {
+			stmt.BindBytes(1, id.Bytes())
+			stmt.BindInt64(2, MaxValidityHistory)
+		}, nil); err != nil {
+		return fmt.Errorf("trim validity history %s: %w", id, err)
+	}
+	return nil
+}
+
+func decodeValidityChange(stmt *sql.Statement) ValidityChange {
+	return ValidityChange{
+		Layer:  types.LayerID(stmt.ColumnInt64(0)),
+		Old:    Validity(stmt.ColumnInt64(1)),
+		New:    Validity(stmt.ColumnInt64(2)),
+		Source: ValiditySource(stmt.ColumnInt64(3)),
+	}
+}
+
+// ValidityHistory returns the validity changes of the block, from the oldest to the latest.
+func ValidityHistory(db sql.Executor, id types.BlockID) ([]ValidityChange, error) {
+	var rst []ValidityChange
+	if _, err := db.Exec(`select decided, old_validity, new_validity, source from block_validity_history
+		where block = ?1 order by seq asc;`,
+		func(stmt *sql.Statement) {
+			stmt.BindBytes(1, id.Bytes())
+		}, func(stmt *sql.Statement) bool {
+			rst = append(rst, decodeValidityChange(stmt))
+			return true
+		}); err != nil {
+		return nil, fmt.Errorf("validity history %s: %w", id, err)
+	}
+	return rst, nil
+}
+
+// LastValidityChange returns the latest validity change of the block.
+func LastValidityChange(db sql.Executor, id types.BlockID) (rst ValidityChange, err error) {
+	if rows, err := db.Exec(`select decided, old_validity, new_validity, source from block_validity_history
+		where block = ?1 order by seq desc limit 1;`,
+		func(stmt *sql.Statement) {
+			stmt.BindBytes(1, id.Bytes())
+		}, func(stmt *sql.Statement) bool {
+			rst = decodeValidityChange(stmt)
+			return true
+		}); err != nil {
+		return rst, fmt.Errorf("last validity change %s: %w", id, err)
+	} else if rows == 0 {
+		return rst, fmt.Errorf("last validity change %s: %w", id, sql.ErrNotFound)
+	}
+	return rst, nil
+}
+
+// DeleteValidityHistoryBefore deletes the history of the blocks in the layers before the given layer
+// and returns the number of deleted changes.
+func DeleteValidityHistoryBefore(db sql.Executor, lid types.LayerID) (int, error) {
+	rows, err := db.Exec("delete from block_validity_history where layer < ?1 returning block;",
+		func(stmt *sql.Statement) {
+			stmt.BindInt64(1, int64(lid))
+		}, nil)
+	if err != nil {
+		return 0, fmt.Errorf("delete validity history before %s: %w", lid, err)
+	}
+	return rows, nil
+}
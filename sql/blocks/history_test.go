@@ -0,0 +1,83 @@
+package blocks
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/spacemeshos/go-spacemesh/common/types"
+	"github.com/spacemeshos/go-spacemesh/sql"
+)
+
+func TestValidityHistory(t *testing.T) {
+	db := sql.InMemory()
+	id := types.BlockID{1}
+	other := types.BlockID{2}
+
+	history, err := ValidityHistory(db, id)
+	require.NoError(t, err)
+	require.Empty(t, history)
+	_, err = LastValidityChange(db, id)
+	require.ErrorIs(t, err, sql.ErrNotFound)
+
+	changes := []ValidityChange{
+		{Layer: 11, Old: Undecided, New: Valid, Source: SourceHare},
+		{Layer: 12, Old: Valid, New: Invalid, Source: SourceVerifying},
+		{Layer: 20, Old: Invalid, New: Valid, Source: SourceHealing},
+	}
+	for _, change := range changes {
+		require.NoError(t, AddValidityChange(db, id, 10, change))
+	}
+	require.NoError(t, AddValidityChange(db, other, 11, changes[0]))
+
+	history, err = ValidityHistory(db, id)
+	require.NoError(t, err)
+	require.Equal(t, changes, history)
+	last, err := LastValidityChange(db, id)
+	require.NoError(t, err)
+	require.Equal(t, changes[2], last)
+	history, err = ValidityHistory(db, other)
+	require.NoError(t, err)
+	require.Equal(t, changes[:1], history)
+
+	n, err := DeleteValidityHistoryBefore(db, 11)
+	require.NoError(t, err)
+	require.Equal(t, len(changes), n)
+	history, err = ValidityHistory(db, id)
+	require.NoError(t, err)
+	require.Empty(t, history)
+	history, err = ValidityHistory(db, other)
+	require.NoError(t, err)
+	require.Len(t, history, 1)
+}
+
+func TestValidityHistoryCapped(t *testing.T) {
+	db := sql.InMemory()
+	id := types.BlockID{1}
+	validity := Undecided
+	for i := 0; i < 2*MaxValidityHistory; i++ {
+		next := Valid
+		if validity == Valid {
+			next = Invalid
+		}
+		require.NoError(t, AddValidityChange(db, id, 10, ValidityChange{
+			Layer:  types.LayerID(i),
+			Old:    validity,
+			New:    next,
+			Source: SourceHealing,
+		}))
+		validity = next
+	}
+	history, err := ValidityHistory(db, id)
+	require.NoError(t, err)
+	require.Len(t, history, MaxValidityHistory)
+	require.Equal(t, types.LayerID(MaxValidityHistory), history[0].Layer)
+	require.Equal(t, types.LayerID(2*MaxValidityHistory-1), history[len(history)-1].Layer)
+}
+
+func TestValidityChangeJSON(t *testing.T) {
+	data, err := json.Marshal(ValidityChange{Layer: 7, Old: Invalid, New: Valid, Source: SourceCertificate})
+	require.NoError(t, err)
+	require.JSONEq(t, `{"layer":7,"old":"invalid","new":"valid","source":"certificate"}`, string(data))
+}
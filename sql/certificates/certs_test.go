@@ -170,3 +170,32 @@ func TestFirstInEpoch(t *testing.T) {
 	require.ErrorIs(t, err, sql.ErrNotFound)
 	require.Equal(t, types.EmptyBlockID, got)
 }
+
+func TestHareCertificate(t *testing.T) {
+	db := sql.InMemory()
+	lid := types.LayerID(10)
+
+	has, err := HasHare(db, lid)
+	require.NoError(t, err)
+	require.False(t, has)
+	_, err = GetHare(db, lid)
+	require.ErrorIs(t, err, sql.ErrNotFound)
+
+	require.NoError(t, AddHare(db, lid, []byte{1, 2, 3}))
+	has, err = HasHare(db, lid)
+	require.NoError(t, err)
+	require.True(t, has)
+	got, err := GetHare(db, lid)
+	require.NoError(t, err)
+	require.Equal(t, []byte{1, 2, 3}, got)
+
+	// only the first certificate is kept
+	require.NoError(t, AddHare(db, lid, []byte{4, 5}))
+	got, err = GetHare(db, lid)
+	require.NoError(t, err)
+	require.Equal(t, []byte{1, 2, 3}, got)
+
+	has, err = HasHare(db, lid.Add(1))
+	require.NoError(t, err)
+	require.False(t, has)
+}
@@ -0,0 +1,50 @@
+package certificates
+
+import (
+	"fmt"
+
+	"github.com/spacemeshos/go-spacemesh/common/types"
+	"github.com/spacemeshos/go-spacemesh/sql"
+)
+
+// AddHare stores the encoded certificate of the hare output for the layer.
+// Only the first certificate for the layer is stored.
+func AddHare(db sql.Executor, lid types.LayerID, cert []byte) error {
+	if _, err := db.Exec(`insert into hare_certificates (layer, cert) values (?1, ?2)
+		on conflict do nothing;`,
+		func(stmt *sql.Statement) {
+			stmt.BindInt64(1, int64(lid))
+			stmt.BindBytes(2, cert)
+		}, nil); err != nil {
+		return fmt.Errorf("add hare cert %s: %w", lid, err)
+	}
+	return nil
+}
+
+// GetHare returns the encoded certificate of the hare output for the layer.
+func GetHare(db sql.Executor, lid types.LayerID) ([]byte, error) {
+	var cert []byte
+	if rows, err := db.Exec("select cert from hare_certificates where layer = ?1;", func(stmt *sql.Statement) {
+		stmt.BindInt64(1, int64(lid))
+	}, func(stmt *sql.Statement) bool {
+		cert = make([]byte, stmt.ColumnLen(0))
+		stmt.ColumnBytes(0, cert)
+		return true
+	}); err != nil {
+		return nil, fmt.Errorf("get hare cert %s: %w", lid, err)
+	} else if rows == 0 {
+		return nil, fmt.Errorf("get hare cert %s: %w", lid, sql.ErrNotFound)
+	}
+	return cert, nil
+}
+
+// HasHare returns true if the certificate of the hare output for the layer is stored.
+func HasHare(db sql.Executor, lid types.LayerID) (bool, error) {
+	rows, err := db.Exec("select 1 from hare_certificates where layer = ?1;", func(stmt *sql.Statement) {
+		stmt.BindInt64(1, int64(lid))
+	}, nil)
+	if err != nil {
+		return false, fmt.Errorf("has hare cert %s: %w", lid, err)
+	}
+	return rows > 0, nil
+}
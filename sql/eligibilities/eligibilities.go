@@ -0,0 +1,92 @@
+// Package eligibilities persists the proposal eligibilities computed by the identities of the node for an epoch.
+package eligibilities
+
+import (
+	"fmt"
+
+	"github.com/spacemeshos/go-spacemesh/codec"
+	"github.com/spacemeshos/go-spacemesh/common/types"
+	"github.com/spacemeshos/go-spacemesh/sql"
+)
+
+// Eligibility is the set of the proposal eligibilities of the identity in the epoch.
+type Eligibility struct {
+	Atx       types.ATXID
+	Beacon    types.Beacon
+	Slots     uint32
+	ActiveSet []types.ATXID
+	Proofs    []types.VotingEligibility
+}
+
+// Set stores the eligibility of the identity in the epoch, replacing the previous one.
+func Set(db sql.Executor, epoch types.EpochID, nodeID types.NodeID, e *Eligibility) error {
+	activeSet, err := codec.EncodeSlice(e.ActiveSet)
+	if err != nil {
+		return fmt.Errorf("encode active set: %w", err)
+	}
+	proofs, err := codec.EncodeSlice(e.Proofs)
+	if err != nil {
+		return fmt.Errorf("encode proofs: %w", err)
+	}
+	if _, err := db.Exec(`insert into proposal_eligibilities (epoch, node_id, atx, beacon, slots, active_set, proofs)
+		values (?1, ?2, ?3, ?4, ?5, ?6, ?7)
+		on conflict(epoch, node_id) do update set atx = ?3, beacon = ?4, slots = ?5, active_set = ?6, proofs = ?7;`,
+		func(stmt *sql.Statement) {
+			stmt.BindInt64(1, int64(epoch))
+			stmt.BindBytes(2, nodeID.Bytes())
+			stmt.BindBytes(3, e.Atx.Bytes())
+			stmt.BindBytes(4, e.Beacon.Bytes())
+			stmt.BindInt64(5, int64(e.Slots))
+			stmt.BindBytes(6, activeSet)
+			stmt.BindBytes(7, proofs)
+		}, nil); err != nil {
+		return fmt.Errorf("set eligibility %s/%s: %w", epoch, nodeID, err)
+	}
+	return nil
+}
+
+// Get returns the eligibility of the identity in the epoch.
+func Get(db sql.Executor, epoch types.EpochID, nodeID types.NodeID) (*Eligibility, error) {
+	var (
+		e    Eligibility
+		derr error
+	)
+	rows, err := db.Exec("select atx, beacon, slots, active_set, proofs from proposal_eligibilities where epoch = ?1 and node_id = ?2;",
+		func(stmt *sql.Statement) {
+			stmt.BindInt64(1, int64(epoch))
+			stmt.BindBytes(2, nodeID.Bytes())
+		}, func(stmt *sql.Statement) bool {
+			stmt.ColumnBytes(0, e.Atx[:])
+			stmt.ColumnBytes(1, e.Beacon[:])
+			e.Slots = uint32(stmt.ColumnInt64(2))
+			buf := make([]byte, stmt.ColumnLen(3))
+			stmt.ColumnBytes(3, buf)
+			if e.ActiveSet, derr = codec.DecodeSlice[types.ATXID](buf); derr != nil {
+				return false
+			}
+			buf = make([]byte, stmt.ColumnLen(4))
+			stmt.ColumnBytes(4, buf)
+			e.Proofs, derr = codec.DecodeSlice[types.VotingEligibility](buf)
+			return false
+		})
+	if err != nil {
+		return nil, fmt.Errorf("get eligibility %s/%s: %w", epoch, nodeID, err)
+	} else if rows == 0 {
+		return nil, fmt.Errorf("get eligibility %s/%s: %w", epoch, nodeID, sql.ErrNotFound)
+	}
+	if derr != nil {
+		return nil, fmt.Errorf("decode eligibility %s/%s: %w", epoch, nodeID, derr)
+	}
+	return &e, nil
+}
+
+// DeleteBefore deletes the eligibilities of the epochs before the given one.
+func DeleteBefore(db sql.Executor, epoch types.EpochID) error {
+	if _, err := db.Exec("delete from proposal_eligibilities where epoch < ?1;",
+		func(stmt *sql.Statement) {
+			stmt.BindInt64(1, int64(epoch))
+		}, nil); err != nil {
+		return fmt.Errorf("delete eligibilities before %s: %w", epoch, err)
+	}
+	return nil
+}
@@ -0,0 +1,50 @@
+package eligibilities
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/spacemeshos/go-spacemesh/common/types"
+	"github.com/spacemeshos/go-spacemesh/sql"
+)
+
+func TestEligibilities(t *testing.T) {
+	db := sql.InMemory()
+	node := types.RandomNodeID()
+
+	_, err := Get(db, 2, node)
+	require.ErrorIs(t, err, sql.ErrNotFound)
+
+	e := &Eligibility{
+		Atx:       types.RandomATXID(),
+		Beacon:    types.RandomBeacon(),
+		Slots:     3,
+		ActiveSet: []types.ATXID{types.RandomATXID(), types.RandomATXID()},
+		Proofs: []types.VotingEligibility{
+			{J: 0, Sig: types.RandomVrfSignature()},
+			{J: 2, Sig: types.RandomVrfSignature()},
+		},
+	}
+	require.NoError(t, Set(db, 2, node, e))
+	got, err := Get(db, 2, node)
+	require.NoError(t, err)
+	require.Equal(t, e, got)
+	_, err = Get(db, 2, types.RandomNodeID())
+	require.ErrorIs(t, err, sql.ErrNotFound)
+
+	// eligibility is replaced if it is recomputed
+	e.Slots = 1
+	e.Proofs = e.Proofs[:1]
+	require.NoError(t, Set(db, 2, node, e))
+	got, err = Get(db, 2, node)
+	require.NoError(t, err)
+	require.Equal(t, e, got)
+
+	require.NoError(t, Set(db, 3, node, e))
+	require.NoError(t, DeleteBefore(db, 3))
+	_, err = Get(db, 2, node)
+	require.ErrorIs(t, err, sql.ErrNotFound)
+	_, err = Get(db, 3, node)
+	require.NoError(t, err)
+}
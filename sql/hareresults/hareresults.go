@@ -0,0 +1,72 @@
+// Package hareresults persists the outcome of the hare instances run by the node.
+package hareresults
+
+import (
+	"fmt"
+
+	"github.com/spacemeshos/go-spacemesh/common/types"
+	"github.com/spacemeshos/go-spacemesh/sql"
+)
+
+// Status is the outcome of the hare instance for the layer.
+type Status uint8
+
+const (
+	// Completed is the status of the instance that reached agreement on the proposals.
+	Completed Status = iota + 1
+	// Failed is the status of the instance that didn't reach agreement within the iterations limit.
+	Failed
+	// NotRun is the status of the layer for which the instance was not started.
+	NotRun
+)
+
+// Set stores the outcome of the hare instance for the layer, replacing the previous one.
+// The proposals are the encoded output of the completed instance, nil otherwise.
+func Set(db sql.Executor, lid types.LayerID, status Status, proposals []byte) error {
+	if _, err := db.Exec(`insert into hare_results (layer, status, proposals) values (?1, ?2, ?3)
+		on conflict(layer) do update set status = ?2, proposals = ?3;`,
+		func(stmt *sql.Statement) {
+			stmt.BindInt64(1, int64(lid))
+			stmt.BindInt64(2, int64(status))
+			stmt.BindBytes(3, proposals)
+		}, nil); err != nil {
+		return fmt.Errorf("set hare result %s: %w", lid, err)
+	}
+	return nil
+}
+
+// Get returns the status and the encoded proposals of the hare instance for the layer.
+func Get(db sql.Executor, lid types.LayerID) (Status, []byte, error) {
+	var (
+		status    Status
+		proposals []byte
+	)
+	if rows, err := db.Exec("select status, proposals from hare_results where layer = ?1;",
+		func(stmt *sql.Statement) {
+			stmt.BindInt64(1, int64(lid))
+		}, func(stmt *sql.Statement) bool {
+			status = Status(stmt.ColumnInt64(0))
+			if n := stmt.ColumnLen(1); n > 0 {
+				proposals = make([]byte, n)
+				stmt.ColumnBytes(1, proposals)
+			}
+			return true
+		}); err != nil {
+		return 0, nil, fmt.Errorf("get hare result %s: %w", lid, err)
+	} else if rows == 0 {
+		return 0, nil, fmt.Errorf("get hare result %s: %w", lid, sql.ErrNotFound)
+	}
+	return status, proposals, nil
+}
+
+// DeleteBefore deletes the results for the layers before the given layer and returns the number of deleted results.
+func DeleteBefore(db sql.Executor, lid types.LayerID) (int, error) {
+	rows, err := db.Exec("delete from hare_results where layer < ?1 returning layer;",
+		func(stmt *sql.Statement) {
+			stmt.BindInt64(1, int64(lid))
+		}, nil)
+	if err != nil {
+		return 0, fmt.Errorf("delete hare results before %s: %w", lid, err)
+	}
+	return rows, nil
+}
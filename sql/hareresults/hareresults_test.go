@@ -0,0 +1,42 @@
+package hareresults
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/spacemeshos/go-spacemesh/sql"
+)
+
+func TestResults(t *testing.T) {
+	db := sql.InMemory()
+	_, _, err := Get(db, 10)
+	require.ErrorIs(t, err, sql.ErrNotFound)
+
+	require.NoError(t, Set(db, 10, Completed, []byte{1, 2}))
+	require.NoError(t, Set(db, 11, Failed, nil))
+	status, proposals, err := Get(db, 10)
+	require.NoError(t, err)
+	require.Equal(t, Completed, status)
+	require.Equal(t, []byte{1, 2}, proposals)
+
+	status, proposals, err = Get(db, 11)
+	require.NoError(t, err)
+	require.Equal(t, Failed, status)
+	require.Nil(t, proposals)
+
+	// the result is replaced
+	require.NoError(t, Set(db, 10, NotRun, nil))
+	status, proposals, err = Get(db, 10)
+	require.NoError(t, err)
+	require.Equal(t, NotRun, status)
+	require.Nil(t, proposals)
+
+	deleted, err := DeleteBefore(db, 11)
+	require.NoError(t, err)
+	require.Equal(t, 1, deleted)
+	_, _, err = Get(db, 10)
+	require.ErrorIs(t, err, sql.ErrNotFound)
+	_, _, err = Get(db, 11)
+	require.NoError(t, err)
+}
@@ -0,0 +1,54 @@
+// Package haresets persists the proposal sets referred to by hare messages.
+package haresets
+
+import (
+	"fmt"
+
+	"github.com/spacemeshos/go-spacemesh/common/types"
+	"github.com/spacemeshos/go-spacemesh/sql"
+)
+
+// Add stores the encoded set with the given id.
+// The layer is used only to prune old sets, the set is not replaced if it already exists.
+func Add(db sql.Executor, id types.Hash32, lid types.LayerID, set []byte) error {
+	if _, err := db.Exec(`insert into hare_sets (id, layer, proposals) values (?1, ?2, ?3)
+		on conflict(id) do nothing;`,
+		func(stmt *sql.Statement) {
+			stmt.BindBytes(1, id.Bytes())
+			stmt.BindInt64(2, int64(lid))
+			stmt.BindBytes(3, set)
+		}, nil); err != nil {
+		return fmt.Errorf("add hare set %s: %w", id, err)
+	}
+	return nil
+}
+
+// GetBlob returns the encoded set with the given id.
+func GetBlob(db sql.Executor, id []byte) ([]byte, error) {
+	var set []byte
+	if rows, err := db.Exec("select proposals from hare_sets where id = ?1;",
+		func(stmt *sql.Statement) {
+			stmt.BindBytes(1, id)
+		}, func(stmt *sql.Statement) bool {
+			set = make([]byte, stmt.ColumnLen(0))
+			stmt.ColumnBytes(0, set)
+			return true
+		}); err != nil {
+		return nil, fmt.Errorf("get hare set %s: %w", types.BytesToHash(id), err)
+	} else if rows == 0 {
+		return nil, fmt.Errorf("get hare set %s: %w", types.BytesToHash(id), sql.ErrNotFound)
+	}
+	return set, nil
+}
+
+// DeleteBefore deletes the sets stored for the layers before the given layer and returns the number of deleted sets.
+func DeleteBefore(db sql.Executor, lid types.LayerID) (int, error) {
+	rows, err := db.Exec("delete from hare_sets where layer < ?1 returning id;",
+		func(stmt *sql.Statement) {
+			stmt.BindInt64(1, int64(lid))
+		}, nil)
+	if err != nil {
+		return 0, fmt.Errorf("delete hare sets before %s: %w", lid, err)
+	}
+	return rows, nil
+}
@@ -0,0 +1,36 @@
+package haresets
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/spacemeshos/go-spacemesh/common/types"
+	"github.com/spacemeshos/go-spacemesh/sql"
+)
+
+func TestSets(t *testing.T) {
+	db := sql.InMemory()
+	id1 := types.RandomHash()
+	id2 := types.RandomHash()
+
+	_, err := GetBlob(db, id1.Bytes())
+	require.ErrorIs(t, err, sql.ErrNotFound)
+
+	require.NoError(t, Add(db, id1, 10, []byte{1}))
+	require.NoError(t, Add(db, id2, 11, []byte{2}))
+	// the set is not replaced
+	require.NoError(t, Add(db, id1, 12, []byte{3}))
+	got, err := GetBlob(db, id1.Bytes())
+	require.NoError(t, err)
+	require.Equal(t, []byte{1}, got)
+
+	deleted, err := DeleteBefore(db, 11)
+	require.NoError(t, err)
+	require.Equal(t, 1, deleted)
+	_, err = GetBlob(db, id1.Bytes())
+	require.ErrorIs(t, err, sql.ErrNotFound)
+	got, err = GetBlob(db, id2.Bytes())
+	require.NoError(t, err)
+	require.Equal(t, []byte{2}, got)
+}
@@ -0,0 +1,80 @@
+// Package harestate persists the state of the running hare instances.
+package harestate
+
+import (
+	"fmt"
+
+	"github.com/spacemeshos/go-spacemesh/common/types"
+	"github.com/spacemeshos/go-spacemesh/sql"
+)
+
+// Set replaces the encoded state of the hare instance run by the node for the layer.
+func Set(db sql.Executor, lid types.LayerID, node types.NodeID, state []byte) error {
+	if _, err := db.Exec(`insert into hare_state (layer, node, state) values (?1, ?2, ?3)
+		on conflict(layer, node) do update set state = ?3;`,
+		func(stmt *sql.Statement) {
+			stmt.BindInt64(1, int64(lid))
+			stmt.BindBytes(2, node.Bytes())
+			stmt.BindBytes(3, state)
+		}, nil); err != nil {
+		return fmt.Errorf("set hare state %s/%s: %w", lid, node, err)
+	}
+	return nil
+}
+
+// Get returns the encoded state of the hare instance run by the node for the layer.
+func Get(db sql.Executor, lid types.LayerID, node types.NodeID) ([]byte, error) {
+	var state []byte
+	if rows, err := db.Exec("select state from hare_state where layer = ?1 and node = ?2;", func(stmt *sql.Statement) {
+		stmt.BindInt64(1, int64(lid))
+		stmt.BindBytes(2, node.Bytes())
+	}, func(stmt *sql.Statement) bool {
+		state = make([]byte, stmt.ColumnLen(0))
+		stmt.ColumnBytes(0, state)
+		return true
+	}); err != nil {
+		return nil, fmt.Errorf("get hare state %s/%s: %w", lid, node, err)
+	} else if rows == 0 {
+		return nil, fmt.Errorf("get hare state %s/%s: %w", lid, node, sql.ErrNotFound)
+	}
+	return state, nil
+}
+
+// IterateAll calls fn with the encoded state of every instance run by the node in ascending order of layers.
+func IterateAll(db sql.Executor, node types.NodeID, fn func(types.LayerID, []byte) bool) error {
+	if _, err := db.Exec("select layer, state from hare_state where node = ?1 order by layer asc;",
+		func(stmt *sql.Statement) {
+			stmt.BindBytes(1, node.Bytes())
+		}, func(stmt *sql.Statement) bool {
+			state := make([]byte, stmt.ColumnLen(1))
+			stmt.ColumnBytes(1, state)
+			return fn(types.LayerID(uint32(stmt.ColumnInt64(0))), state)
+		}); err != nil {
+		return fmt.Errorf("iterate hare state %s: %w", node, err)
+	}
+	return nil
+}
+
+// Delete deletes the state of the hare instance run by the node for the layer.
+func Delete(db sql.Executor, lid types.LayerID, node types.NodeID) error {
+	if _, err := db.Exec("delete from hare_state where layer = ?1 and node = ?2;", func(stmt *sql.Statement) {
+		stmt.BindInt64(1, int64(lid))
+		stmt.BindBytes(2, node.Bytes())
+	}, nil); err != nil {
+		return fmt.Errorf("delete hare state %s/%s: %w", lid, node, err)
+	}
+	return nil
+}
+
+// DeleteBefore deletes the state of all instances for the layers before the given layer
+// and returns the number of deleted states.
+func DeleteBefore(db sql.Executor, lid types.LayerID) (int, error) {
+	rows, err := db.Exec("delete from hare_state where layer < ?1 returning layer;",
+		func(stmt *sql.Statement) {
+			stmt.BindInt64(1, int64(lid))
+		}, nil)
+	if err != nil {
+		return 0, fmt.Errorf("delete hare state before %s: %w", lid, err)
+	}
+	return rows, nil
+}
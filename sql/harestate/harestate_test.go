@@ -0,0 +1,73 @@
+package harestate
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/spacemeshos/go-spacemesh/common/types"
+	"github.com/spacemeshos/go-spacemesh/sql"
+)
+
+func collect(tb testing.TB, db sql.Executor, node types.NodeID) map[types.LayerID][]byte {
+	tb.Helper()
+	rst := map[types.LayerID][]byte{}
+	require.NoError(tb, IterateAll(db, node, func(lid types.LayerID, state []byte) bool {
+		rst[lid] = state
+		return true
+	}))
+	return rst
+}
+
+func TestState(t *testing.T) {
+	db := sql.InMemory()
+	node := types.RandomNodeID()
+	_, err := Get(db, 10, node)
+	require.ErrorIs(t, err, sql.ErrNotFound)
+
+	require.NoError(t, Set(db, 10, node, []byte{1}))
+	require.NoError(t, Set(db, 11, node, []byte{2}))
+	got, err := Get(db, 10, node)
+	require.NoError(t, err)
+	require.Equal(t, []byte{1}, got)
+
+	require.NoError(t, Set(db, 10, node, []byte{1, 2}))
+	got, err = Get(db, 10, node)
+	require.NoError(t, err)
+	require.Equal(t, []byte{1, 2}, got)
+	require.Equal(t, map[types.LayerID][]byte{10: {1, 2}, 11: {2}}, collect(t, db, node))
+
+	require.NoError(t, Delete(db, 11, node))
+	_, err = Get(db, 11, node)
+	require.ErrorIs(t, err, sql.ErrNotFound)
+	require.Equal(t, map[types.LayerID][]byte{10: {1, 2}}, collect(t, db, node))
+}
+
+func TestState_MultipleNodes(t *testing.T) {
+	db := sql.InMemory()
+	node1 := types.RandomNodeID()
+	node2 := types.RandomNodeID()
+	require.NoError(t, Set(db, 10, node1, []byte{1}))
+	require.NoError(t, Set(db, 10, node2, []byte{2}))
+	require.Equal(t, map[types.LayerID][]byte{10: {1}}, collect(t, db, node1))
+	require.Equal(t, map[types.LayerID][]byte{10: {2}}, collect(t, db, node2))
+
+	require.NoError(t, Delete(db, 10, node1))
+	require.Empty(t, collect(t, db, node1))
+	require.Equal(t, map[types.LayerID][]byte{10: {2}}, collect(t, db, node2))
+}
+
+func TestState_DeleteBefore(t *testing.T) {
+	db := sql.InMemory()
+	node1 := types.RandomNodeID()
+	node2 := types.RandomNodeID()
+	require.NoError(t, Set(db, 10, node1, []byte{1}))
+	require.NoError(t, Set(db, 10, node2, []byte{2}))
+	require.NoError(t, Set(db, 11, node1, []byte{3}))
+
+	deleted, err := DeleteBefore(db, 11)
+	require.NoError(t, err)
+	require.Equal(t, 2, deleted)
+	require.Equal(t, map[types.LayerID][]byte{11: {3}}, collect(t, db, node1))
+	require.Empty(t, collect(t, db, node2))
+}
@@ -0,0 +1,137 @@
+package keyrotations
+
+import (
+	"fmt"
+
+	"github.com/spacemeshos/go-spacemesh/codec"
+	"github.com/spacemeshos/go-spacemesh/common/types"
+	"github.com/spacemeshos/go-spacemesh/sql"
+)
+
+// Add stores the key rotation.
+func Add(db sql.Executor, rotation *types.KeyRotation) error {
+	if _, err := db.Exec(`insert into key_rotations (pubkey, epoch, new_key, atx_id, signature)
+	values (?1, ?2, ?3, ?4, ?5);`,
+		func(stmt *sql.Statement) {
+			stmt.BindBytes(1, rotation.NodeID.Bytes())
+			stmt.BindInt64(2, int64(rotation.Epoch))
+			stmt.BindBytes(3, rotation.NewKey.Bytes())
+			stmt.BindBytes(4, rotation.AtxID.Bytes())
+			stmt.BindBytes(5, rotation.Signature[:])
+		}, nil,
+	); err != nil {
+		return fmt.Errorf("insert key rotation %v/%v: %w", rotation.NodeID, rotation.Epoch, err)
+	}
+	return nil
+}
+
+// Latest returns the key rotation of the identity with the highest epoch.
+func Latest(db sql.Executor, nodeID types.NodeID) (*types.KeyRotation, error) {
+	var rotation *types.KeyRotation
+	if _, err := db.Exec(`select pubkey, epoch, new_key, atx_id, signature from key_rotations
+	where pubkey = ?1 order by epoch desc limit 1;`,
+		func(stmt *sql.Statement) {
+			stmt.BindBytes(1, nodeID.Bytes())
+		}, func(stmt *sql.Statement) bool {
+			rotation = decode(stmt)
+			return true
+		},
+	); err != nil {
+		return nil, fmt.Errorf("latest key rotation %v: %w", nodeID, err)
+	}
+	if rotation == nil {
+		return nil, sql.ErrNotFound
+	}
+	return rotation, nil
+}
+
+// All returns key rotations of the identity in the order of transition epochs.
+func All(db sql.Executor, nodeID types.NodeID) ([]types.KeyRotation, error) {
+	var rst []types.KeyRotation
+	if _, err := db.Exec(`select pubkey, epoch, new_key, atx_id, signature from key_rotations
+	where pubkey = ?1 order by epoch asc;`,
+		func(stmt *sql.Statement) {
+			stmt.BindBytes(1, nodeID.Bytes())
+		}, func(stmt *sql.Statement) bool {
+			rst = append(rst, *decode(stmt))
+			return true
+		},
+	); err != nil {
+		return nil, fmt.Errorf("key rotations %v: %w", nodeID, err)
+	}
+	return rst, nil
+}
+
+// GetBlob returns encoded key rotations of the identity, in the same order as All.
+func GetBlob(db sql.Executor, nodeID []byte) ([]byte, error) {
+	rotations, err := All(db, types.BytesToNodeID(nodeID))
+	if err != nil {
+		return nil, err
+	}
+	if len(rotations) == 0 {
+		return nil, sql.ErrNotFound
+	}
+	data, err := codec.EncodeSlice(rotations)
+	if err != nil {
+		return nil, fmt.Errorf("encode key rotations: %w", err)
+	}
+	return data, nil
+}
+
+// Keys returns keys that are accepted for the identity in the epoch, the newest key first.
+// Before the first rotation only NodeID is accepted. During the transition epoch
+// the previous and the new key are accepted, after it only the new key.
+func Keys(db sql.Executor, nodeID types.NodeID, epoch types.EpochID) ([]types.NodeID, error) {
+	var (
+		epochs []types.EpochID
+		keys   []types.NodeID
+	)
+	if _, err := db.Exec(`select epoch, new_key from key_rotations
+	where pubkey = ?1 and epoch <= ?2 order by epoch desc limit 2;`,
+		func(stmt *sql.Statement) {
+			stmt.BindBytes(1, nodeID.Bytes())
+			stmt.BindInt64(2, int64(epoch))
+		}, func(stmt *sql.Statement) bool {
+			var key types.NodeID
+			stmt.ColumnBytes(1, key[:])
+			epochs = append(epochs, types.EpochID(stmt.ColumnInt64(0)))
+			keys = append(keys, key)
+			return true
+		},
+	); err != nil {
+		return nil, fmt.Errorf("keys %v/%v: %w", nodeID, epoch, err)
+	}
+	switch {
+	case len(keys) == 0:
+		return []types.NodeID{nodeID}, nil
+	case epochs[0] != epoch:
+		return keys[:1], nil
+	case len(keys) == 1:
+		return []types.NodeID{keys[0], nodeID}, nil
+	}
+	return keys, nil
+}
+
+// Verify returns true if verify succeeds for one of the keys accepted for the identity in the epoch.
+func Verify(db sql.Executor, nodeID types.NodeID, epoch types.EpochID, verify func(types.NodeID) bool) (bool, error) {
+	keys, err := Keys(db, nodeID, epoch)
+	if err != nil {
+		return false, err
+	}
+	for _, key := range keys {
+		if verify(key) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func decode(stmt *sql.Statement) *types.KeyRotation {
+	rotation := &types.KeyRotation{}
+	stmt.ColumnBytes(0, rotation.NodeID[:])
+	rotation.Epoch = types.EpochID(stmt.ColumnInt64(1))
+	stmt.ColumnBytes(2, rotation.NewKey[:])
+	stmt.ColumnBytes(3, rotation.AtxID[:])
+	stmt.ColumnBytes(4, rotation.Signature[:])
+	return rotation
+}
@@ -0,0 +1,123 @@
+package keyrotations
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/spacemeshos/go-spacemesh/codec"
+	"github.com/spacemeshos/go-spacemesh/common/types"
+	"github.com/spacemeshos/go-spacemesh/sql"
+)
+
+func TestKeys(t *testing.T) {
+	db := sql.InMemory()
+	nodeID := types.NodeID{1}
+	first, second := types.NodeID{2}, types.NodeID{3}
+
+	_, err := Latest(db, nodeID)
+	require.ErrorIs(t, err, sql.ErrNotFound)
+	keys, err := Keys(db, nodeID, 10)
+	require.NoError(t, err)
+	require.Equal(t, []types.NodeID{nodeID}, keys)
+
+	rotation := &types.KeyRotation{
+		InnerKeyRotation: types.InnerKeyRotation{NodeID: nodeID, NewKey: first, AtxID: types.ATXID{1}, Epoch: 5},
+		Signature:        types.RandomEdSignature(),
+	}
+	require.NoError(t, Add(db, rotation))
+	require.Error(t, Add(db, rotation))
+	require.NoError(t, Add(db, &types.KeyRotation{
+		InnerKeyRotation: types.InnerKeyRotation{NodeID: nodeID, NewKey: second, AtxID: types.ATXID{2}, Epoch: 8},
+	}))
+	latest, err := Latest(db, nodeID)
+	require.NoError(t, err)
+	require.Equal(t, types.EpochID(8), latest.Epoch)
+	require.Equal(t, second, latest.NewKey)
+
+	for _, tc := range []struct {
+		epoch types.EpochID
+		keys  []types.NodeID
+	}{
+		{4, []types.NodeID{nodeID}},
+		{5, []types.NodeID{first, nodeID}},
+		{6, []types.NodeID{first}},
+		{7, []types.NodeID{first}},
+		{8, []types.NodeID{second, first}},
+		{9, []types.NodeID{second}},
+	} {
+		keys, err := Keys(db, nodeID, tc.epoch)
+		require.NoError(t, err)
+		require.Equal(t, tc.keys, keys, "epoch %d", tc.epoch)
+	}
+
+	got, err := Latest(db, nodeID)
+	require.NoError(t, err)
+	require.Equal(t, second, got.NewKey)
+	_, err = Latest(db, types.NodeID{9})
+	require.ErrorIs(t, err, sql.ErrNotFound)
+}
+
+func TestAllAndBlob(t *testing.T) {
+	db := sql.InMemory()
+	nodeID := types.NodeID{1}
+
+	_, err := GetBlob(db, nodeID.Bytes())
+	require.ErrorIs(t, err, sql.ErrNotFound)
+
+	var expected []types.KeyRotation
+	for _, epoch := range []types.EpochID{7, 3, 5} {
+		rotation := types.KeyRotation{
+			InnerKeyRotation: types.InnerKeyRotation{
+				NodeID: nodeID,
+				NewKey: types.RandomNodeID(),
+				AtxID:  types.RandomATXID(),
+				Epoch:  epoch,
+			},
+			Signature: types.RandomEdSignature(),
+		}
+		require.NoError(t, Add(db, &rotation))
+		expected = append(expected, rotation)
+	}
+	require.NoError(t, Add(db, &types.KeyRotation{
+		InnerKeyRotation: types.InnerKeyRotation{NodeID: types.NodeID{2}, NewKey: types.RandomNodeID(), Epoch: 4},
+	}))
+	expected[0], expected[1], expected[2] = expected[1], expected[2], expected[0]
+
+	all, err := All(db, nodeID)
+	require.NoError(t, err)
+	require.Equal(t, expected, all)
+
+	blob, err := GetBlob(db, nodeID.Bytes())
+	require.NoError(t, err)
+	decoded, err := codec.DecodeSlice[types.KeyRotation](blob)
+	require.NoError(t, err)
+	require.Equal(t, expected, decoded)
+}
+
+func TestVerify(t *testing.T) {
+	db := sql.InMemory()
+	nodeID, rotated := types.NodeID{1}, types.NodeID{2}
+	require.NoError(t, Add(db, &types.KeyRotation{
+		InnerKeyRotation: types.InnerKeyRotation{NodeID: nodeID, NewKey: rotated, Epoch: 5},
+	}))
+
+	for _, tc := range []struct {
+		epoch types.EpochID
+		key   types.NodeID
+		valid bool
+	}{
+		{4, nodeID, true},
+		{4, rotated, false},
+		{5, nodeID, true},
+		{5, rotated, true},
+		{6, nodeID, false},
+		{6, rotated, true},
+	} {
+		valid, err := Verify(db, nodeID, tc.epoch, func(key types.NodeID) bool {
+			return key == tc.key
+		})
+		require.NoError(t, err)
+		require.Equal(t, tc.valid, valid, "epoch %d key %s", tc.epoch, tc.key.ShortString())
+	}
+}
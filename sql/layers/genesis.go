@@ -0,0 +1,49 @@
+package layers
+
+import (
+	"fmt"
+
+	"github.com/spacemeshos/go-spacemesh/common/types"
+	"github.com/spacemeshos/go-spacemesh/sql"
+)
+
+// Genesis identifies the genesis of the mesh the database was initialized with.
+type Genesis struct {
+	ID types.Hash32
+	// Layer is the last genesis layer.
+	Layer  types.LayerID
+	Ballot types.BallotID
+	Block  types.BlockID
+}
+
+// SetGenesis stores the genesis of the mesh. The genesis can be stored only once.
+func SetGenesis(db sql.Executor, genesis Genesis) error {
+	if _, err := db.Exec(`insert into genesis (id, genesis_id, layer, ballot, block) values (1, ?1, ?2, ?3, ?4);`,
+		func(stmt *sql.Statement) {
+			stmt.BindBytes(1, genesis.ID[:])
+			stmt.BindInt64(2, int64(genesis.Layer))
+			stmt.BindBytes(3, genesis.Ballot[:])
+			stmt.BindBytes(4, genesis.Block[:])
+		}, nil); err != nil {
+		return fmt.Errorf("set genesis %s: %w", genesis.ID.ShortString(), err)
+	}
+	return nil
+}
+
+// GetGenesis returns the stored genesis of the mesh.
+func GetGenesis(db sql.Executor) (Genesis, error) {
+	var genesis Genesis
+	if rows, err := db.Exec("select genesis_id, layer, ballot, block from genesis where id = 1;", nil,
+		func(stmt *sql.Statement) bool {
+			stmt.ColumnBytes(0, genesis.ID[:])
+			genesis.Layer = types.LayerID(uint32(stmt.ColumnInt64(1)))
+			stmt.ColumnBytes(2, genesis.Ballot[:])
+			stmt.ColumnBytes(3, genesis.Block[:])
+			return true
+		}); err != nil {
+		return Genesis{}, fmt.Errorf("get genesis: %w", err)
+	} else if rows == 0 {
+		return Genesis{}, fmt.Errorf("%w: genesis is not set", sql.ErrNotFound)
+	}
+	return genesis, nil
+}
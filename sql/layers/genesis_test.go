@@ -0,0 +1,32 @@
+package layers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/spacemeshos/go-spacemesh/common/types"
+	"github.com/spacemeshos/go-spacemesh/sql"
+)
+
+func TestGenesis(t *testing.T) {
+	db := sql.InMemory()
+	_, err := GetGenesis(db)
+	require.ErrorIs(t, err, sql.ErrNotFound)
+
+	genesis := Genesis{
+		ID:     types.RandomHash(),
+		Layer:  types.LayerID(7),
+		Ballot: types.RandomBallotID(),
+		Block:  types.RandomBlockID(),
+	}
+	require.NoError(t, SetGenesis(db, genesis))
+	got, err := GetGenesis(db)
+	require.NoError(t, err)
+	require.Equal(t, genesis, got)
+
+	require.Error(t, SetGenesis(db, Genesis{ID: types.RandomHash()}))
+	got, err = GetGenesis(db)
+	require.NoError(t, err)
+	require.Equal(t, genesis, got)
+}
@@ -46,7 +46,8 @@ func GetWeakCoin(db sql.Executor, lid types.LayerID) (bool, error) {
 	return weakcoin, err
 }
 
-// SetApplied for the layer to a block id.
+// SetApplied for the layer to a block id and moves the applied marker forward.
+// Should be called in a transaction so that the marker is updated atomically with the block.
 func SetApplied(db sql.Executor, lid types.LayerID, applied types.BlockID) error {
 	if _, err := db.Exec(`insert into layers (id, applied_block) values (?1, ?2) 
 					on conflict(id) do update set applied_block=?2;`,
@@ -56,17 +57,26 @@ func SetApplied(db sql.Executor, lid types.LayerID, applied types.BlockID) error
 		}, nil); err != nil {
 		return fmt.Errorf("set applied %s: %w", lid, err)
 	}
-	return nil
+	return advanceMarker(db, appliedMarker, lid)
 }
 
-// UnsetAppliedFrom updates the applied block to nil for layer >= `lid`.
+// UnsetAppliedFrom updates the applied block to nil for layer >= `lid` and moves the applied marker before `lid`.
+// Should be called in a transaction so that the marker is updated atomically with the blocks.
 func UnsetAppliedFrom(db sql.Executor, lid types.LayerID) error {
-	if _, err := db.Exec("update layers set applied_block = null, state_hash = null, aggregated_hash = null where id >= ?1;",
+	if _, err := db.Exec(`update layers set applied_block = null, state_hash = null, aggregated_hash = null, mesh_hash = null
+		where id >= ?1;`,
 		func(stmt *sql.Statement) {
 			stmt.BindInt64(1, int64(lid))
 		}, nil); err != nil {
 		return fmt.Errorf("unset applied %s: %w", lid, err)
 	}
+	if _, err := db.Exec(`update layer_markers set layer = max(?1 - 1, 0) where name = ?2 and layer >= ?1;`,
+		func(stmt *sql.Statement) {
+			stmt.BindInt64(1, int64(lid))
+			stmt.BindText(2, appliedMarker)
+		}, nil); err != nil {
+		return fmt.Errorf("unset applied marker %s: %w", lid, err)
+	}
 	return nil
 }
 
@@ -140,20 +150,13 @@ func GetApplied(db sql.Executor, lid types.LayerID) (rst types.BlockID, err erro
 	return rst, err
 }
 
-// GetLastApplied for the applied block for layer.
+// GetLastApplied returns the applied layer marker.
 func GetLastApplied(db sql.Executor) (types.LayerID, error) {
-	var lid types.LayerID
-	if _, err := db.Exec("select max(id) from layers where applied_block is not null", nil,
-		func(stmt *sql.Statement) bool {
-			lid = types.LayerID(uint32(stmt.ColumnInt64(0)))
-			return true
-		}); err != nil {
-		return lid, fmt.Errorf("last applied: %w", err)
-	}
-	return lid, nil
+	return getMarker(db, appliedMarker)
 }
 
-// SetProcessed sets a layer processed.
+// SetProcessed sets a layer processed and moves the processed marker forward.
+// Should be called in a transaction so that the marker is updated atomically with the layer.
 func SetProcessed(db sql.Executor, lid types.LayerID) error {
 	if _, err := db.Exec(
 		`insert into layers (id, processed) values (?1, 1) 
@@ -163,25 +166,16 @@ func SetProcessed(db sql.Executor, lid types.LayerID) error {
 		}, nil); err != nil {
 		return fmt.Errorf("set processed %v: %w", lid, err)
 	}
-	return nil
+	return advanceMarker(db, processedMarker, lid)
 }
 
-// GetProcessed gets the highest layer processed.
+// GetProcessed returns the processed layer marker.
 func GetProcessed(db sql.Executor) (types.LayerID, error) {
-	var lid types.LayerID
-	if _, err := db.Exec("select max(id) from layers where processed = 1;",
-		nil,
-		func(stmt *sql.Statement) bool {
-			lid = types.LayerID(uint32(stmt.ColumnInt64(0)))
-			return true
-		}); err != nil {
-		return lid, fmt.Errorf("processed layer: %w", err)
-	}
-	return lid, nil
+	return getMarker(db, processedMarker)
 }
 
-// SetMeshHash sets the aggregated hash up to the specified layer.
-func SetMeshHash(db sql.Executor, lid types.LayerID, aggHash types.Hash32) error {
+// SetAggregatedHash sets the aggregated hash up to the specified layer.
+func SetAggregatedHash(db sql.Executor, lid types.LayerID, aggHash types.Hash32) error {
 	if _, err := db.Exec(
 		`insert into layers (id, aggregated_hash) values (?1, ?2) 
          on conflict(id) do update set aggregated_hash=?2;`,
@@ -212,6 +206,38 @@ func GetAggregatedHash(db sql.Executor, lid types.LayerID) (types.Hash32, error)
 	return rst, nil
 }
 
+// SetMeshHash sets the hash of the applied layers chained up to the specified layer.
+func SetMeshHash(db sql.Executor, lid types.LayerID, hash types.Hash32) error {
+	if _, err := db.Exec(
+		`insert into layers (id, mesh_hash) values (?1, ?2) 
+         on conflict(id) do update set mesh_hash=?2;`,
+		func(stmt *sql.Statement) {
+			stmt.BindInt64(1, int64(lid.Uint32()))
+			stmt.BindBytes(2, hash[:])
+		}, nil); err != nil {
+		return fmt.Errorf("set mesh hash %v: %w", lid, err)
+	}
+	return nil
+}
+
+// GetMeshHash returns the mesh hash of the layer.
+func GetMeshHash(db sql.Executor, lid types.LayerID) (types.Hash32, error) {
+	var rst types.Hash32
+	if rows, err := db.Exec("select mesh_hash from layers where id = ?1 and mesh_hash is not null;",
+		func(stmt *sql.Statement) {
+			stmt.BindInt64(1, int64(lid.Uint32()))
+		},
+		func(stmt *sql.Statement) bool {
+			stmt.ColumnBytes(0, rst[:])
+			return true
+		}); err != nil {
+		return rst, fmt.Errorf("get mesh hash %s: %w", lid, err)
+	} else if rows == 0 {
+		return rst, fmt.Errorf("%w mesh hash for layer %s", sql.ErrNotFound, lid)
+	}
+	return rst, nil
+}
+
 func GetAggHashes(db sql.Executor, from, to types.LayerID, by uint32) ([]types.Hash32, error) {
 	dist := to.Difference(from)
 	count := int(dist/by + 1)
@@ -104,7 +104,7 @@ func TestSetHashes(t *testing.T) {
 	layers := []uint32{9, 10, 8, 7}
 	aggHashes := []types.Hash32{{5}, {6}, {7}, {8}}
 	for i := range layers {
-		require.NoError(t, SetMeshHash(db, types.LayerID(layers[i]), aggHashes[i]))
+		require.NoError(t, SetAggregatedHash(db, types.LayerID(layers[i]), aggHashes[i]))
 	}
 
 	for i, lid := range layers {
@@ -127,6 +127,33 @@ func TestSetHashes(t *testing.T) {
 	}
 }
 
+func TestMeshHash(t *testing.T) {
+	db := sql.InMemory()
+	_, err := GetMeshHash(db, types.LayerID(11))
+	require.ErrorIs(t, err, sql.ErrNotFound)
+
+	// the layer exists but the hash is not set
+	require.NoError(t, SetAggregatedHash(db, types.LayerID(10), types.Hash32{1}))
+	_, err = GetMeshHash(db, types.LayerID(10))
+	require.ErrorIs(t, err, sql.ErrNotFound)
+
+	require.NoError(t, SetMeshHash(db, types.LayerID(10), types.Hash32{2}))
+	require.NoError(t, SetMeshHash(db, types.LayerID(11), types.Hash32{3}))
+	got, err := GetMeshHash(db, types.LayerID(10))
+	require.NoError(t, err)
+	require.Equal(t, types.Hash32{2}, got)
+	agg, err := GetAggregatedHash(db, types.LayerID(10))
+	require.NoError(t, err)
+	require.Equal(t, types.Hash32{1}, agg)
+
+	require.NoError(t, UnsetAppliedFrom(db, types.LayerID(11)))
+	_, err = GetMeshHash(db, types.LayerID(11))
+	require.ErrorIs(t, err, sql.ErrNotFound)
+	got, err = GetMeshHash(db, types.LayerID(10))
+	require.NoError(t, err)
+	require.Equal(t, types.Hash32{2}, got)
+}
+
 func TestProcessed(t *testing.T) {
 	db := sql.InMemory()
 	lid, err := GetProcessed(db)
@@ -151,7 +178,7 @@ func TestGetAggHashes(t *testing.T) {
 		lid := types.LayerID(i)
 		hash := types.RandomHash()
 		hashes[lid] = hash
-		require.NoError(t, SetMeshHash(db, lid, hash))
+		require.NoError(t, SetAggregatedHash(db, lid, hash))
 	}
 
 	t.Run("missing layers", func(t *testing.T) {
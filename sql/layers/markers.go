@@ -0,0 +1,129 @@
+package layers
+
+import (
+	"fmt"
+
+	"github.com/spacemeshos/go-spacemesh/common/types"
+	"github.com/spacemeshos/go-spacemesh/sql"
+)
+
+const (
+	latestMarker    = "latest"
+	processedMarker = "processed"
+	verifiedMarker  = "verified"
+	appliedMarker   = "applied"
+)
+
+// Markers is the progress of the mesh. The markers are stored in a single table and
+// are updated in the same transaction as the data they describe.
+type Markers struct {
+	// Latest is the latest layer seen from the network.
+	Latest types.LayerID
+	// Processed is the latest layer whose votes were counted by the tortoise.
+	Processed types.LayerID
+	// Verified is the latest layer verified by the tortoise.
+	Verified types.LayerID
+	// Applied is the latest layer applied to the state.
+	Applied types.LayerID
+}
+
+// advanceMarker moves the marker forward to the layer, it never moves the marker back.
+func advanceMarker(db sql.Executor, name string, lid types.LayerID) error {
+	if _, err := db.Exec(`insert into layer_markers (name, layer) values (?1, ?2)
+		on conflict(name) do update set layer = max(layer, ?2);`,
+		func(stmt *sql.Statement) {
+			stmt.BindText(1, name)
+			stmt.BindInt64(2, int64(lid))
+		}, nil); err != nil {
+		return fmt.Errorf("advance %s marker to %s: %w", name, lid, err)
+	}
+	return nil
+}
+
+func setMarker(db sql.Executor, name string, lid types.LayerID) error {
+	if _, err := db.Exec(`insert into layer_markers (name, layer) values (?1, ?2)
+		on conflict(name) do update set layer = ?2;`,
+		func(stmt *sql.Statement) {
+			stmt.BindText(1, name)
+			stmt.BindInt64(2, int64(lid))
+		}, nil); err != nil {
+		return fmt.Errorf("set %s marker to %s: %w", name, lid, err)
+	}
+	return nil
+}
+
+func getMarker(db sql.Executor, name string) (types.LayerID, error) {
+	var lid types.LayerID
+	if _, err := db.Exec("select layer from layer_markers where name = ?1;",
+		func(stmt *sql.Statement) {
+			stmt.BindText(1, name)
+		}, func(stmt *sql.Statement) bool {
+			lid = types.LayerID(uint32(stmt.ColumnInt64(0)))
+			return true
+		}); err != nil {
+		return lid, fmt.Errorf("get %s marker: %w", name, err)
+	}
+	return lid, nil
+}
+
+// SetLatest moves the latest layer marker forward.
+func SetLatest(db sql.Executor, lid types.LayerID) error {
+	return advanceMarker(db, latestMarker, lid)
+}
+
+// SetVerified moves the verified layer marker forward.
+func SetVerified(db sql.Executor, lid types.LayerID) error {
+	return advanceMarker(db, verifiedMarker, lid)
+}
+
+// GetMarkers returns all markers read in a single query. Missing markers are zero.
+func GetMarkers(db sql.Executor) (Markers, error) {
+	var rst Markers
+	if _, err := db.Exec("select name, layer from layer_markers;", nil,
+		func(stmt *sql.Statement) bool {
+			lid := types.LayerID(uint32(stmt.ColumnInt64(1)))
+			switch stmt.ColumnText(0) {
+			case latestMarker:
+				rst.Latest = lid
+			case processedMarker:
+				rst.Processed = lid
+			case verifiedMarker:
+				rst.Verified = lid
+			case appliedMarker:
+				rst.Applied = lid
+			}
+			return true
+		}); err != nil {
+		return Markers{}, fmt.Errorf("get markers: %w", err)
+	}
+	return rst, nil
+}
+
+// SetMarkers overwrites all markers, including moving them back. It is meant for repairing
+// the markers, during normal operation the markers are updated together with the data.
+func SetMarkers(db sql.Executor, markers Markers) error {
+	for name, lid := range map[string]types.LayerID{
+		latestMarker:    markers.Latest,
+		processedMarker: markers.Processed,
+		verifiedMarker:  markers.Verified,
+		appliedMarker:   markers.Applied,
+	} {
+		if err := setMarker(db, name, lid); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// LastAppliedBlock returns the highest layer with the applied block recorded, regardless of the applied marker.
+func LastAppliedBlock(db sql.Executor) (types.LayerID, error) {
+	var lid types.LayerID
+	if _, err := db.Exec("select max(id) from layers where applied_block is not null", nil,
+		func(stmt *sql.Statement) bool {
+			lid = types.LayerID(uint32(stmt.ColumnInt64(0)))
+			return true
+		}); err != nil {
+		return lid, fmt.Errorf("last applied block: %w", err)
+	}
+	return lid, nil
+}
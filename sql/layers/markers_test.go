@@ -0,0 +1,70 @@
+package layers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/spacemeshos/go-spacemesh/common/types"
+	"github.com/spacemeshos/go-spacemesh/sql"
+)
+
+func TestMarkers(t *testing.T) {
+	db := sql.InMemory()
+	markers, err := GetMarkers(db)
+	require.NoError(t, err)
+	require.Equal(t, Markers{}, markers)
+
+	require.NoError(t, SetLatest(db, 12))
+	require.NoError(t, SetProcessed(db, 11))
+	require.NoError(t, SetVerified(db, 10))
+	require.NoError(t, SetApplied(db, 9, types.EmptyBlockID))
+	// markers are never moved back by the updates
+	require.NoError(t, SetLatest(db, 5))
+	require.NoError(t, SetProcessed(db, 5))
+	require.NoError(t, SetVerified(db, 5))
+	require.NoError(t, SetApplied(db, 5, types.EmptyBlockID))
+	markers, err = GetMarkers(db)
+	require.NoError(t, err)
+	require.Equal(t, Markers{Latest: 12, Processed: 11, Verified: 10, Applied: 9}, markers)
+
+	repaired := Markers{Latest: 4, Processed: 3, Verified: 2, Applied: 1}
+	require.NoError(t, SetMarkers(db, repaired))
+	markers, err = GetMarkers(db)
+	require.NoError(t, err)
+	require.Equal(t, repaired, markers)
+}
+
+func TestAppliedMarker(t *testing.T) {
+	db := sql.InMemory()
+	lid := types.LayerID(10)
+	for i := lid; !i.After(lid.Add(5)); i = i.Add(1) {
+		require.NoError(t, SetApplied(db, i, types.RandomBlockID()))
+	}
+	got, err := GetLastApplied(db)
+	require.NoError(t, err)
+	require.Equal(t, lid.Add(5), got)
+
+	// unsetting layers after the marker doesn't move it
+	require.NoError(t, UnsetAppliedFrom(db, lid.Add(6)))
+	got, err = GetLastApplied(db)
+	require.NoError(t, err)
+	require.Equal(t, lid.Add(5), got)
+
+	require.NoError(t, UnsetAppliedFrom(db, lid.Add(2)))
+	got, err = GetLastApplied(db)
+	require.NoError(t, err)
+	require.Equal(t, lid.Add(1), got)
+	got, err = LastAppliedBlock(db)
+	require.NoError(t, err)
+	require.Equal(t, lid.Add(1), got)
+
+	// blocks may be recorded without the marker only if the marker is overwritten
+	require.NoError(t, SetMarkers(db, Markers{Applied: lid}))
+	got, err = GetLastApplied(db)
+	require.NoError(t, err)
+	require.Equal(t, lid, got)
+	got, err = LastAppliedBlock(db)
+	require.NoError(t, err)
+	require.Equal(t, lid.Add(1), got)
+}
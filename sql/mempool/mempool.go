@@ -0,0 +1,75 @@
+// Package mempool persists the membership of transactions in the mempool, so that
+// pending transactions survive restarts. The transactions themselves are stored in
+// the transactions table.
+package mempool
+
+import (
+	"fmt"
+
+	"github.com/spacemeshos/go-spacemesh/common/types"
+	"github.com/spacemeshos/go-spacemesh/sql"
+)
+
+// Add records that the transaction was admitted to the mempool when lid was the last applied layer.
+func Add(db sql.Executor, tx *types.Transaction, lid types.LayerID) error {
+	if _, err := db.Exec(`insert into mempool (id, principal, nonce, layer) values (?1, ?2, ?3, ?4)
+		on conflict(id) do nothing;`,
+		func(stmt *sql.Statement) {
+			stmt.BindBytes(1, tx.ID.Bytes())
+			stmt.BindBytes(2, tx.Principal.Bytes())
+			stmt.BindInt64(3, int64(tx.Nonce))
+			stmt.BindInt64(4, int64(lid))
+		}, nil); err != nil {
+		return fmt.Errorf("add mempool tx %s: %w", tx.ID, err)
+	}
+	return nil
+}
+
+// Delete removes the transaction from the mempool.
+func Delete(db sql.Executor, tid types.TransactionID) error {
+	if _, err := db.Exec("delete from mempool where id = ?1;",
+		func(stmt *sql.Statement) {
+			stmt.BindBytes(1, tid.Bytes())
+		}, nil); err != nil {
+		return fmt.Errorf("delete mempool tx %s: %w", tid, err)
+	}
+	return nil
+}
+
+// Has returns true if the transaction is in the mempool.
+func Has(db sql.Executor, tid types.TransactionID) (bool, error) {
+	rows, err := db.Exec("select 1 from mempool where id = ?1;",
+		func(stmt *sql.Statement) {
+			stmt.BindBytes(1, tid.Bytes())
+		}, nil)
+	if err != nil {
+		return false, fmt.Errorf("has mempool tx %s: %w", tid, err)
+	}
+	return rows > 0, nil
+}
+
+// PruneBefore removes the transactions admitted before the layer.
+func PruneBefore(db sql.Executor, lid types.LayerID) error {
+	if _, err := db.Exec("delete from mempool where layer < ?1;",
+		func(stmt *sql.Statement) {
+			stmt.BindInt64(1, int64(lid))
+		}, nil); err != nil {
+		return fmt.Errorf("prune mempool before %s: %w", lid, err)
+	}
+	return nil
+}
+
+// All returns the transactions in the mempool ordered by principal and nonce.
+func All(db sql.Executor) ([]types.TransactionID, error) {
+	var rst []types.TransactionID
+	if _, err := db.Exec("select id from mempool order by principal, nonce, id;", nil,
+		func(stmt *sql.Statement) bool {
+			var tid types.TransactionID
+			stmt.ColumnBytes(0, tid[:])
+			rst = append(rst, tid)
+			return true
+		}); err != nil {
+		return nil, fmt.Errorf("select mempool: %w", err)
+	}
+	return rst, nil
+}
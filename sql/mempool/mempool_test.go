@@ -0,0 +1,47 @@
+package mempool
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/spacemeshos/go-spacemesh/common/types"
+	"github.com/spacemeshos/go-spacemesh/sql"
+)
+
+func genTx(principal types.Address, nonce uint64) *types.Transaction {
+	tx := &types.Transaction{TxHeader: &types.TxHeader{Principal: principal, Nonce: nonce}}
+	tx.ID = types.RandomTransactionID()
+	return tx
+}
+
+func TestMempool(t *testing.T) {
+	db := sql.InMemory()
+	got, err := All(db)
+	require.NoError(t, err)
+	require.Empty(t, got)
+
+	principal := types.Address{1}
+	tx1 := genTx(principal, 2)
+	tx2 := genTx(principal, 1)
+	tx3 := genTx(types.Address{2}, 1)
+	require.NoError(t, Add(db, tx1, 10))
+	require.NoError(t, Add(db, tx2, 11))
+	require.NoError(t, Add(db, tx3, 12))
+	// admitted again, the original layer is kept
+	require.NoError(t, Add(db, tx1, 13))
+
+	got, err = All(db)
+	require.NoError(t, err)
+	require.Equal(t, []types.TransactionID{tx2.ID, tx1.ID, tx3.ID}, got)
+
+	require.NoError(t, Delete(db, tx2.ID))
+	has, err := Has(db, tx2.ID)
+	require.NoError(t, err)
+	require.False(t, has)
+
+	require.NoError(t, PruneBefore(db, 11))
+	got, err = All(db)
+	require.NoError(t, err)
+	require.Equal(t, []types.TransactionID{tx3.ID}, got)
+}
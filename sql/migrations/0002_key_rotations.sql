@@ -0,0 +1,9 @@
+CREATE TABLE key_rotations
+(
+    pubkey    CHAR(32) NOT NULL,
+    epoch     INT NOT NULL,
+    new_key   CHAR(32) NOT NULL,
+    atx_id    CHAR(32) NOT NULL,
+    signature CHAR(64) NOT NULL,
+    PRIMARY KEY (pubkey, epoch)
+) WITHOUT ROWID;
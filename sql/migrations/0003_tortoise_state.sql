@@ -0,0 +1,49 @@
+CREATE TABLE tortoise_state
+(
+    id          INTEGER PRIMARY KEY CHECK (id = 1),
+    version     INT NOT NULL,
+    config      CHAR(32) NOT NULL,
+    last        INT NOT NULL,
+    processed   INT NOT NULL,
+    verified    INT NOT NULL,
+    evicted     INT NOT NULL,
+    counted     INT NOT NULL,
+    full_mode   SMALL INT NOT NULL,
+    good_weight BLOB NOT NULL
+);
+
+CREATE TABLE tortoise_layers
+(
+    id               INT PRIMARY KEY DESC,
+    opinion          CHAR(32) NOT NULL,
+    hare_terminated  SMALL INT NOT NULL,
+    coinflip         SMALL INT NOT NULL,
+    empty            BLOB NOT NULL,
+    abstained        BLOB NOT NULL,
+    good_uncounted   BLOB NOT NULL,
+    reference_height INT NOT NULL
+) WITHOUT ROWID;
+
+CREATE TABLE tortoise_blocks
+(
+    layer    INT NOT NULL,
+    id       CHAR(20) NOT NULL,
+    height   INT NOT NULL,
+    hare     SMALL INT NOT NULL,
+    validity SMALL INT NOT NULL,
+    margin   BLOB NOT NULL,
+    data     SMALL INT NOT NULL,
+    PRIMARY KEY (layer, id, height)
+) WITHOUT ROWID;
+
+CREATE TABLE tortoise_ballots
+(
+    id         CHAR(20) PRIMARY KEY,
+    layer      INT NOT NULL,
+    bad_beacon SMALL INT NOT NULL,
+    delayed    SMALL INT NOT NULL,
+    counted    SMALL INT NOT NULL,
+    good       SMALL INT NOT NULL,
+    votes      SMALL INT NOT NULL
+) WITHOUT ROWID;
+CREATE INDEX tortoise_ballots_by_layer ON tortoise_ballots (layer);
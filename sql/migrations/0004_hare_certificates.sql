@@ -0,0 +1,5 @@
+CREATE TABLE hare_certificates
+(
+    layer INT PRIMARY KEY DESC,
+    cert  BLOB NOT NULL
+) WITHOUT ROWID;
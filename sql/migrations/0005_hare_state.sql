@@ -0,0 +1,7 @@
+CREATE TABLE hare_state
+(
+    layer INT NOT NULL,
+    node  CHAR(32) NOT NULL,
+    state BLOB NOT NULL,
+    PRIMARY KEY (layer, node)
+) WITHOUT ROWID;
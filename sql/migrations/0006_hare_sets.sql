@@ -0,0 +1,7 @@
+CREATE TABLE hare_sets
+(
+    id        CHAR(32) PRIMARY KEY,
+    layer     INT NOT NULL,
+    proposals BLOB NOT NULL
+) WITHOUT ROWID;
+CREATE INDEX hare_sets_by_layer ON hare_sets (layer);
@@ -0,0 +1,6 @@
+CREATE TABLE hare_results
+(
+    layer     INT PRIMARY KEY,
+    status    INT NOT NULL,
+    proposals BLOB
+) WITHOUT ROWID;
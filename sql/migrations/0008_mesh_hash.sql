@@ -0,0 +1 @@
+ALTER TABLE layers ADD COLUMN mesh_hash CHAR(32);
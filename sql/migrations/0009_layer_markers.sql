@@ -0,0 +1,9 @@
+CREATE TABLE layer_markers
+(
+    name  VARCHAR PRIMARY KEY,
+    layer INT NOT NULL
+) WITHOUT ROWID;
+INSERT INTO layer_markers (name, layer)
+    SELECT 'processed', id FROM layers WHERE processed = 1 ORDER BY id DESC LIMIT 1;
+INSERT INTO layer_markers (name, layer)
+    SELECT 'applied', id FROM layers WHERE applied_block IS NOT NULL ORDER BY id DESC LIMIT 1;
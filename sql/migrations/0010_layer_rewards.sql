@@ -0,0 +1,5 @@
+CREATE TABLE layer_rewards
+(
+    layer   INT PRIMARY KEY,
+    rewards BLOB NOT NULL
+) WITHOUT ROWID;
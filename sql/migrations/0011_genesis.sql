@@ -0,0 +1,8 @@
+CREATE TABLE genesis
+(
+    id         INTEGER PRIMARY KEY CHECK (id = 1),
+    genesis_id CHAR(32) NOT NULL,
+    layer      INT NOT NULL,
+    ballot     CHAR(20) NOT NULL,
+    block      CHAR(20) NOT NULL
+);
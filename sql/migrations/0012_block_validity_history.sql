@@ -0,0 +1,12 @@
+CREATE TABLE block_validity_history
+(
+    block        CHAR(20) NOT NULL,
+    seq          INT NOT NULL,
+    layer        INT NOT NULL,
+    decided      INT NOT NULL,
+    old_validity SMALL INT NOT NULL,
+    new_validity SMALL INT NOT NULL,
+    source       SMALL INT NOT NULL,
+    PRIMARY KEY (block, seq)
+) WITHOUT ROWID;
+CREATE INDEX block_validity_history_by_layer ON block_validity_history (layer);
@@ -0,0 +1,7 @@
+CREATE TABLE pending_proposals
+(
+    id       CHAR(20) PRIMARY KEY,
+    layer    INT NOT NULL,
+    proposal BLOB NOT NULL
+) WITHOUT ROWID;
+CREATE INDEX pending_proposals_by_layer ON pending_proposals (layer);
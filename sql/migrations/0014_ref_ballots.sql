@@ -0,0 +1,8 @@
+CREATE TABLE ref_ballots
+(
+    epoch    INT NOT NULL,
+    node_id  CHAR(32) NOT NULL,
+    ballot   CHAR(20) NOT NULL,
+    proposal CHAR(20) NOT NULL,
+    PRIMARY KEY (epoch, node_id)
+) WITHOUT ROWID;
@@ -0,0 +1,11 @@
+CREATE TABLE proposal_eligibilities
+(
+    epoch      INT NOT NULL,
+    node_id    CHAR(32) NOT NULL,
+    atx        CHAR(32) NOT NULL,
+    beacon     CHAR(4) NOT NULL,
+    slots      INT NOT NULL,
+    active_set BLOB NOT NULL,
+    proofs     BLOB NOT NULL,
+    PRIMARY KEY (epoch, node_id)
+) WITHOUT ROWID;
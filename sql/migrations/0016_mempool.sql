@@ -0,0 +1,8 @@
+CREATE TABLE mempool
+(
+    id        CHAR(32) PRIMARY KEY,
+    principal CHAR(24) NOT NULL,
+    nonce     UNSIGNED LONG INT NOT NULL,
+    layer     INT NOT NULL
+) WITHOUT ROWID;
+CREATE INDEX mempool_by_layer ON mempool (layer);
@@ -0,0 +1,8 @@
+CREATE TABLE transactions_states
+(
+    id    CHAR(32) PRIMARY KEY,
+    state INT NOT NULL,
+    layer INT NOT NULL,
+    block CHAR(20)
+) WITHOUT ROWID;
+CREATE INDEX transactions_states_by_layer ON transactions_states (layer, state);
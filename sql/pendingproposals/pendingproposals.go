@@ -0,0 +1,76 @@
+// Package pendingproposals persists proposals built by the node until they are published.
+package pendingproposals
+
+import (
+	"fmt"
+
+	"github.com/spacemeshos/go-spacemesh/codec"
+	"github.com/spacemeshos/go-spacemesh/common/types"
+	"github.com/spacemeshos/go-spacemesh/sql"
+)
+
+// Add stores the proposal that wasn't published yet.
+func Add(db sql.Executor, p *types.Proposal) error {
+	data, err := codec.Encode(p)
+	if err != nil {
+		return fmt.Errorf("encode pending proposal %s: %w", p.ID(), err)
+	}
+	if _, err := db.Exec(`insert into pending_proposals (id, layer, proposal) values (?1, ?2, ?3)
+		on conflict(id) do nothing;`,
+		func(stmt *sql.Statement) {
+			stmt.BindBytes(1, p.ID().Bytes())
+			stmt.BindInt64(2, int64(p.Layer))
+			stmt.BindBytes(3, data)
+		}, nil); err != nil {
+		return fmt.Errorf("add pending proposal %s: %w", p.ID(), err)
+	}
+	return nil
+}
+
+// Delete removes the proposal once it is published or expired.
+func Delete(db sql.Executor, id types.ProposalID) error {
+	if _, err := db.Exec("delete from pending_proposals where id = ?1;",
+		func(stmt *sql.Statement) {
+			stmt.BindBytes(1, id.Bytes())
+		}, nil); err != nil {
+		return fmt.Errorf("delete pending proposal %s: %w", id, err)
+	}
+	return nil
+}
+
+// Has returns true if the proposal is pending.
+func Has(db sql.Executor, id types.ProposalID) (bool, error) {
+	rows, err := db.Exec("select 1 from pending_proposals where id = ?1;",
+		func(stmt *sql.Statement) {
+			stmt.BindBytes(1, id.Bytes())
+		}, nil)
+	if err != nil {
+		return false, fmt.Errorf("has pending proposal %s: %w", id, err)
+	}
+	return rows > 0, nil
+}
+
+// All returns the pending proposals ordered by layer.
+func All(db sql.Executor) ([]*types.Proposal, error) {
+	var (
+		rst  []*types.Proposal
+		derr error
+	)
+	if _, err := db.Exec("select proposal from pending_proposals order by layer, id;", nil,
+		func(stmt *sql.Statement) bool {
+			var p types.Proposal
+			if _, derr = codec.DecodeFrom(stmt.ColumnReader(0), &p); derr != nil {
+				derr = fmt.Errorf("decode pending proposal: %w", derr)
+				return false
+			}
+			if derr = p.Initialize(); derr != nil {
+				derr = fmt.Errorf("initialize pending proposal: %w", derr)
+				return false
+			}
+			rst = append(rst, &p)
+			return true
+		}); err != nil {
+		return nil, fmt.Errorf("select pending proposals: %w", err)
+	}
+	return rst, derr
+}
@@ -0,0 +1,68 @@
+package pendingproposals
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/spacemeshos/go-spacemesh/common/types"
+	"github.com/spacemeshos/go-spacemesh/signing"
+	"github.com/spacemeshos/go-spacemesh/sql"
+)
+
+func genProposal(tb testing.TB, lid types.LayerID) *types.Proposal {
+	tb.Helper()
+	signer, err := signing.NewEdSigner()
+	require.NoError(tb, err)
+	p := &types.Proposal{
+		InnerProposal: types.InnerProposal{
+			Ballot: types.Ballot{
+				InnerBallot: types.InnerBallot{
+					Layer: lid,
+					AtxID: types.RandomATXID(),
+				},
+			},
+			TxIDs:    []types.TransactionID{types.RandomTransactionID()},
+			MeshHash: types.RandomHash(),
+		},
+	}
+	p.Ballot.Signature = signer.Sign(signing.BALLOT, p.Ballot.SignedBytes())
+	p.Ballot.SmesherID = signer.NodeID()
+	p.Signature = signer.Sign(signing.BALLOT, p.SignedBytes())
+	require.NoError(tb, p.Initialize())
+	return p
+}
+
+func TestPendingProposals(t *testing.T) {
+	db := sql.InMemory()
+	got, err := All(db)
+	require.NoError(t, err)
+	require.Empty(t, got)
+
+	p1 := genProposal(t, 11)
+	p2 := genProposal(t, 10)
+	require.NoError(t, Add(db, p1))
+	require.NoError(t, Add(db, p2))
+	// adding the same proposal twice is a noop
+	require.NoError(t, Add(db, p1))
+
+	got, err = All(db)
+	require.NoError(t, err)
+	require.Len(t, got, 2)
+	require.Equal(t, p2.ID(), got[0].ID())
+	require.Equal(t, p1.ID(), got[1].ID())
+	require.Equal(t, p1.TxIDs, got[1].TxIDs)
+	require.Equal(t, p1.SmesherID, got[1].SmesherID)
+
+	has, err := Has(db, p2.ID())
+	require.NoError(t, err)
+	require.True(t, has)
+	require.NoError(t, Delete(db, p2.ID()))
+	has, err = Has(db, p2.ID())
+	require.NoError(t, err)
+	require.False(t, has)
+	got, err = All(db)
+	require.NoError(t, err)
+	require.Len(t, got, 1)
+	require.Equal(t, p1.ID(), got[0].ID())
+}
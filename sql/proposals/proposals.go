@@ -185,3 +185,15 @@ func decodeProposal(stmt *sql.Statement) (*types.Proposal, error) {
 	proposal.SetID(proposalID)
 	return proposal, nil
 }
+
+// DeleteBefore deletes the proposals in the layers before the given layer and returns the number of deleted proposals.
+func DeleteBefore(db sql.Executor, lid types.LayerID) (int, error) {
+	rows, err := db.Exec("delete from proposals where layer < ?1 returning id;",
+		func(stmt *sql.Statement) {
+			stmt.BindInt64(1, int64(lid.Uint32()))
+		}, nil)
+	if err != nil {
+		return 0, fmt.Errorf("delete proposals before %s: %w", lid, err)
+	}
+	return rows, nil
+}
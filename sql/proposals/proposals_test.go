@@ -83,3 +83,30 @@ func TestGet(t *testing.T) {
 	require.NoError(t, err)
 	require.EqualValues(t, proposal, got)
 }
+
+func TestDeleteBefore(t *testing.T) {
+	db := sql.InMemory()
+	for i, lid := range []types.LayerID{10, 10, 11} {
+		ballot := types.NewExistingBallot(types.BallotID{byte(i)}, types.RandomEdSignature(), types.RandomNodeID(), lid)
+		require.NoError(t, ballots.Add(db, &ballot))
+		proposal := &types.Proposal{
+			InnerProposal: types.InnerProposal{Ballot: ballot},
+			Signature:     types.RandomEdSignature(),
+		}
+		proposal.SetID(types.ProposalID{byte(i)})
+		require.NoError(t, Add(db, proposal))
+	}
+
+	deleted, err := DeleteBefore(db, 11)
+	require.NoError(t, err)
+	require.Equal(t, 2, deleted)
+	_, err = GetByLayer(db, 10)
+	require.ErrorIs(t, err, sql.ErrNotFound)
+	got, err := GetByLayer(db, 11)
+	require.NoError(t, err)
+	require.Len(t, got, 1)
+
+	// ballots are not deleted with proposals
+	_, err = ballots.Get(db, types.BallotID{0})
+	require.NoError(t, err)
+}
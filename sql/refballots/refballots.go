@@ -0,0 +1,50 @@
+// Package refballots persists the reference ballots built by the identities of the node.
+package refballots
+
+import (
+	"fmt"
+
+	"github.com/spacemeshos/go-spacemesh/common/types"
+	"github.com/spacemeshos/go-spacemesh/sql"
+)
+
+// RefBallot is the reference ballot of the identity in the epoch and the proposal that carries it.
+type RefBallot struct {
+	Ballot   types.BallotID
+	Proposal types.ProposalID
+}
+
+// Set stores the reference ballot of the identity in the epoch, replacing the previous one.
+func Set(db sql.Executor, epoch types.EpochID, nodeID types.NodeID, ref RefBallot) error {
+	if _, err := db.Exec(`insert into ref_ballots (epoch, node_id, ballot, proposal) values (?1, ?2, ?3, ?4)
+		on conflict(epoch, node_id) do update set ballot = ?3, proposal = ?4;`,
+		func(stmt *sql.Statement) {
+			stmt.BindInt64(1, int64(epoch))
+			stmt.BindBytes(2, nodeID.Bytes())
+			stmt.BindBytes(3, ref.Ballot.Bytes())
+			stmt.BindBytes(4, ref.Proposal.Bytes())
+		}, nil); err != nil {
+		return fmt.Errorf("set ref ballot %s/%s: %w", epoch, nodeID, err)
+	}
+	return nil
+}
+
+// Get returns the reference ballot of the identity in the epoch.
+func Get(db sql.Executor, epoch types.EpochID, nodeID types.NodeID) (RefBallot, error) {
+	var ref RefBallot
+	rows, err := db.Exec("select ballot, proposal from ref_ballots where epoch = ?1 and node_id = ?2;",
+		func(stmt *sql.Statement) {
+			stmt.BindInt64(1, int64(epoch))
+			stmt.BindBytes(2, nodeID.Bytes())
+		}, func(stmt *sql.Statement) bool {
+			stmt.ColumnBytes(0, ref.Ballot[:])
+			stmt.ColumnBytes(1, ref.Proposal[:])
+			return true
+		})
+	if err != nil {
+		return RefBallot{}, fmt.Errorf("get ref ballot %s/%s: %w", epoch, nodeID, err)
+	} else if rows == 0 {
+		return RefBallot{}, fmt.Errorf("get ref ballot %s/%s: %w", epoch, nodeID, sql.ErrNotFound)
+	}
+	return ref, nil
+}
@@ -0,0 +1,38 @@
+package refballots
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/spacemeshos/go-spacemesh/common/types"
+	"github.com/spacemeshos/go-spacemesh/sql"
+)
+
+func TestRefBallots(t *testing.T) {
+	db := sql.InMemory()
+	node1 := types.RandomNodeID()
+	node2 := types.RandomNodeID()
+
+	_, err := Get(db, 1, node1)
+	require.ErrorIs(t, err, sql.ErrNotFound)
+
+	ref1 := RefBallot{Ballot: types.RandomBallotID(), Proposal: types.ProposalID{1}}
+	ref2 := RefBallot{Ballot: types.RandomBallotID(), Proposal: types.ProposalID{2}}
+	require.NoError(t, Set(db, 1, node1, ref1))
+	require.NoError(t, Set(db, 1, node2, ref2))
+	got, err := Get(db, 1, node1)
+	require.NoError(t, err)
+	require.Equal(t, ref1, got)
+	_, err = Get(db, 2, node1)
+	require.ErrorIs(t, err, sql.ErrNotFound)
+
+	// the ref ballot is replaced if it is rebuilt
+	require.NoError(t, Set(db, 1, node1, ref2))
+	got, err = Get(db, 1, node1)
+	require.NoError(t, err)
+	require.Equal(t, ref2, got)
+	got, err = Get(db, 1, node2)
+	require.NoError(t, err)
+	require.Equal(t, ref2, got)
+}
@@ -3,6 +3,7 @@ package rewards
 import (
 	"fmt"
 
+	"github.com/spacemeshos/go-spacemesh/codec"
 	"github.com/spacemeshos/go-spacemesh/common/types"
 	"github.com/spacemeshos/go-spacemesh/sql"
 )
@@ -28,15 +29,61 @@ func Add(db sql.Executor, reward *types.Reward) error {
 
 // Revert the rewards to the specified layer.
 func Revert(db sql.Executor, revertTo types.LayerID) error {
-	if _, err := db.Exec(`delete from rewards where layer > ?1;`,
+	enc := func(stmt *sql.Statement) {
+		stmt.BindInt64(1, int64(revertTo.Uint32()))
+	}
+	if _, err := db.Exec(`delete from rewards where layer > ?1;`, enc, nil); err != nil {
+		return fmt.Errorf("revert %v: %w", revertTo, err)
+	}
+	if _, err := db.Exec(`delete from layer_rewards where layer > ?1;`, enc, nil); err != nil {
+		return fmt.Errorf("revert layer rewards %v: %w", revertTo, err)
+	}
+	return nil
+}
+
+// SetLayer stores the rewards of the block applied in the layer, as they were included in the block.
+func SetLayer(db sql.Executor, lid types.LayerID, rewards []types.AnyReward) error {
+	buf, err := codec.EncodeSlice(rewards)
+	if err != nil {
+		return fmt.Errorf("encode layer rewards %v: %w", lid, err)
+	}
+	if _, err := db.Exec(`insert into layer_rewards (layer, rewards) values (?1, ?2)
+		on conflict(layer) do update set rewards = ?2;`,
 		func(stmt *sql.Statement) {
-			stmt.BindInt64(1, int64(revertTo.Uint32()))
+			stmt.BindInt64(1, int64(lid.Uint32()))
+			stmt.BindBytes(2, buf)
 		}, nil); err != nil {
-		return fmt.Errorf("revert %v: %w", revertTo, err)
+		return fmt.Errorf("set layer rewards %v: %w", lid, err)
 	}
 	return nil
 }
 
+// GetLayer returns the rewards of the block applied in the layer.
+func GetLayer(db sql.Executor, lid types.LayerID) ([]types.AnyReward, error) {
+	var (
+		rst       []types.AnyReward
+		decodeErr error
+	)
+	rows, err := db.Exec("select rewards from layer_rewards where layer = ?1;",
+		func(stmt *sql.Statement) {
+			stmt.BindInt64(1, int64(lid.Uint32()))
+		}, func(stmt *sql.Statement) bool {
+			buf := make([]byte, stmt.ColumnLen(0))
+			stmt.ColumnBytes(0, buf)
+			rst, decodeErr = codec.DecodeSlice[types.AnyReward](buf)
+			return true
+		})
+	if err == nil {
+		err = decodeErr
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get layer rewards %v: %w", lid, err)
+	} else if rows == 0 {
+		return nil, fmt.Errorf("get layer rewards %v: %w", lid, sql.ErrNotFound)
+	}
+	return rst, nil
+}
+
 // List rewards from all layers for the coinbase address.
 func List(db sql.Executor, coinbase types.Address) (rst []*types.Reward, err error) {
 	_, err = db.Exec("select layer, total_reward, layer_reward from rewards where coinbase = ?1 order by layer;",
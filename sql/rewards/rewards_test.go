@@ -94,3 +94,36 @@ func TestRewards(t *testing.T) {
 	require.Equal(t, part, got[0].TotalReward)
 	require.Equal(t, lyrReward, got[0].LayerReward)
 }
+
+func TestLayerRewards(t *testing.T) {
+	db := sql.InMemory()
+	lid := types.LayerID(10)
+	_, err := GetLayer(db, lid)
+	require.ErrorIs(t, err, sql.ErrNotFound)
+
+	rewards := []types.AnyReward{
+		{AtxID: types.ATXID{1}, Weight: types.RatNum{Num: 1, Denom: 3}},
+		{AtxID: types.ATXID{1}, Weight: types.RatNum{Num: 1, Denom: 3}},
+		{AtxID: types.ATXID{2}, Weight: types.RatNum{Num: 1, Denom: 3}},
+	}
+	for i := uint32(0); i < 3; i++ {
+		require.NoError(t, SetLayer(db, lid.Add(i), rewards[i:]))
+	}
+	for i := uint32(0); i < 3; i++ {
+		got, err := GetLayer(db, lid.Add(i))
+		require.NoError(t, err)
+		require.Equal(t, rewards[i:], got)
+	}
+
+	require.NoError(t, SetLayer(db, lid, nil))
+	got, err := GetLayer(db, lid)
+	require.NoError(t, err)
+	require.Empty(t, got)
+
+	require.NoError(t, Revert(db, lid.Add(1)))
+	_, err = GetLayer(db, lid.Add(2))
+	require.ErrorIs(t, err, sql.ErrNotFound)
+	got, err = GetLayer(db, lid.Add(1))
+	require.NoError(t, err)
+	require.Equal(t, rewards[1:], got)
+}
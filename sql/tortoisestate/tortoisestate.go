@@ -0,0 +1,287 @@
+package tortoisestate
+
+import (
+	"fmt"
+
+	"github.com/spacemeshos/fixed"
+
+	"github.com/spacemeshos/go-spacemesh/common/types"
+	"github.com/spacemeshos/go-spacemesh/sql"
+)
+
+// columnWeight decodes weight stored as raw bytes of the fixed-point value.
+// Weights are not stored as floats, so that the state is recovered exactly as it was computed.
+func columnWeight(stmt *sql.Statement, col int) fixed.Fixed {
+	var buf [16]byte
+	stmt.ColumnBytes(col, buf[:])
+	return fixed.FromBytes(buf[:])
+}
+
+// State is a part of the tortoise state that is not tied to a layer.
+type State struct {
+	Version uint32
+	// Config is a hash of the parameters that were used to compute the state.
+	Config    types.Hash32
+	Last      types.LayerID
+	Processed types.LayerID
+	Verified  types.LayerID
+	Evicted   types.LayerID
+	// Counted is the last layer with ballots counted by full tortoise.
+	Counted    types.LayerID
+	Full       bool
+	GoodWeight fixed.Fixed
+}
+
+// Layer is a tally for the layer.
+type Layer struct {
+	ID              types.LayerID
+	Opinion         types.Hash32
+	HareTerminated  bool
+	Coinflip        int8
+	Empty           fixed.Fixed
+	Abstained       fixed.Fixed
+	GoodUncounted   fixed.Fixed
+	ReferenceHeight uint64
+}
+
+// Block is a tally for the block.
+type Block struct {
+	ID       types.BlockID
+	Layer    types.LayerID
+	Height   uint64
+	Hare     int8
+	Validity int8
+	Margin   fixed.Fixed
+	Data     bool
+}
+
+// Ballot is a result of counting the ballot.
+type Ballot struct {
+	ID        types.BallotID
+	Layer     types.LayerID
+	BadBeacon bool
+	Delayed   bool
+	Counted   bool
+	// Good is set if weight of the ballot is counted as good by verifying tortoise.
+	Good bool
+	// Votes is set if votes of the ballot are counted by full tortoise.
+	Votes bool
+}
+
+// SetState replaces previously stored state.
+func SetState(db sql.Executor, state *State) error {
+	if _, err := db.Exec(`insert into tortoise_state
+	(id, version, config, last, processed, verified, evicted, counted, full_mode, good_weight)
+	values (1, ?1, ?2, ?3, ?4, ?5, ?6, ?7, ?8, ?9)
+	on conflict(id) do update set version=?1, config=?2, last=?3, processed=?4,
+	verified=?5, evicted=?6, counted=?7, full_mode=?8, good_weight=?9;`,
+		func(stmt *sql.Statement) {
+			stmt.BindInt64(1, int64(state.Version))
+			stmt.BindBytes(2, state.Config[:])
+			stmt.BindInt64(3, int64(state.Last))
+			stmt.BindInt64(4, int64(state.Processed))
+			stmt.BindInt64(5, int64(state.Verified))
+			stmt.BindInt64(6, int64(state.Evicted))
+			stmt.BindInt64(7, int64(state.Counted))
+			stmt.BindBool(8, state.Full)
+			stmt.BindBytes(9, state.GoodWeight.Bytes())
+		}, nil); err != nil {
+		return fmt.Errorf("set tortoise state: %w", err)
+	}
+	return nil
+}
+
+// GetState returns stored state or sql.ErrNotFound.
+func GetState(db sql.Executor) (*State, error) {
+	var state *State
+	if _, err := db.Exec(`select version, config, last, processed, verified, evicted,
+	counted, full_mode, good_weight from tortoise_state where id = 1;`, nil,
+		func(stmt *sql.Statement) bool {
+			state = &State{
+				Version:    uint32(stmt.ColumnInt64(0)),
+				Last:       types.LayerID(stmt.ColumnInt64(2)),
+				Processed:  types.LayerID(stmt.ColumnInt64(3)),
+				Verified:   types.LayerID(stmt.ColumnInt64(4)),
+				Evicted:    types.LayerID(stmt.ColumnInt64(5)),
+				Counted:    types.LayerID(stmt.ColumnInt64(6)),
+				Full:       stmt.ColumnInt(7) == 1,
+				GoodWeight: columnWeight(stmt, 8),
+			}
+			stmt.ColumnBytes(1, state.Config[:])
+			return true
+		}); err != nil {
+		return nil, fmt.Errorf("get tortoise state: %w", err)
+	}
+	if state == nil {
+		return nil, sql.ErrNotFound
+	}
+	return state, nil
+}
+
+// SetLayer replaces previously stored tally for the layer.
+func SetLayer(db sql.Executor, layer *Layer) error {
+	if _, err := db.Exec(`insert into tortoise_layers
+	(id, opinion, hare_terminated, coinflip, empty, abstained, good_uncounted, reference_height)
+	values (?1, ?2, ?3, ?4, ?5, ?6, ?7, ?8)
+	on conflict(id) do update set opinion=?2, hare_terminated=?3, coinflip=?4,
+	empty=?5, abstained=?6, good_uncounted=?7, reference_height=?8;`,
+		func(stmt *sql.Statement) {
+			stmt.BindInt64(1, int64(layer.ID))
+			stmt.BindBytes(2, layer.Opinion[:])
+			stmt.BindBool(3, layer.HareTerminated)
+			stmt.BindInt64(4, int64(layer.Coinflip))
+			stmt.BindBytes(5, layer.Empty.Bytes())
+			stmt.BindBytes(6, layer.Abstained.Bytes())
+			stmt.BindBytes(7, layer.GoodUncounted.Bytes())
+			stmt.BindInt64(8, int64(layer.ReferenceHeight))
+		}, nil); err != nil {
+		return fmt.Errorf("set tortoise layer %s: %w", layer.ID, err)
+	}
+	return nil
+}
+
+// IterateLayers calls fn for every layer in [from, to] in ascending order.
+func IterateLayers(db sql.Executor, from, to types.LayerID, fn func(*Layer) bool) error {
+	if _, err := db.Exec(`select id, opinion, hare_terminated, coinflip, empty, abstained,
+	good_uncounted, reference_height from tortoise_layers
+	where id between ?1 and ?2 order by id asc;`,
+		func(stmt *sql.Statement) {
+			stmt.BindInt64(1, int64(from))
+			stmt.BindInt64(2, int64(to))
+		}, func(stmt *sql.Statement) bool {
+			layer := &Layer{
+				ID:              types.LayerID(stmt.ColumnInt64(0)),
+				HareTerminated:  stmt.ColumnInt(2) == 1,
+				Coinflip:        int8(stmt.ColumnInt64(3)),
+				Empty:           columnWeight(stmt, 4),
+				Abstained:       columnWeight(stmt, 5),
+				GoodUncounted:   columnWeight(stmt, 6),
+				ReferenceHeight: uint64(stmt.ColumnInt64(7)),
+			}
+			stmt.ColumnBytes(1, layer.Opinion[:])
+			return fn(layer)
+		}); err != nil {
+		return fmt.Errorf("iterate tortoise layers [%s, %s]: %w", from, to, err)
+	}
+	return nil
+}
+
+// SetBlock replaces previously stored tally for the block.
+func SetBlock(db sql.Executor, block *Block) error {
+	if _, err := db.Exec(`insert into tortoise_blocks
+	(layer, id, height, hare, validity, margin, data)
+	values (?1, ?2, ?3, ?4, ?5, ?6, ?7)
+	on conflict(layer, id, height) do update set hare=?4, validity=?5, margin=?6, data=?7;`,
+		func(stmt *sql.Statement) {
+			stmt.BindInt64(1, int64(block.Layer))
+			stmt.BindBytes(2, block.ID[:])
+			stmt.BindInt64(3, int64(block.Height))
+			stmt.BindInt64(4, int64(block.Hare))
+			stmt.BindInt64(5, int64(block.Validity))
+			stmt.BindBytes(6, block.Margin.Bytes())
+			stmt.BindBool(7, block.Data)
+		}, nil); err != nil {
+		return fmt.Errorf("set tortoise block %s/%s: %w", block.Layer, block.ID, err)
+	}
+	return nil
+}
+
+// IterateBlocks calls fn for every block in layers [from, to] in ascending order of layers.
+func IterateBlocks(db sql.Executor, from, to types.LayerID, fn func(*Block) bool) error {
+	if _, err := db.Exec(`select layer, id, height, hare, validity, margin, data
+	from tortoise_blocks where layer between ?1 and ?2 order by layer asc;`,
+		func(stmt *sql.Statement) {
+			stmt.BindInt64(1, int64(from))
+			stmt.BindInt64(2, int64(to))
+		}, func(stmt *sql.Statement) bool {
+			block := &Block{
+				Layer:    types.LayerID(stmt.ColumnInt64(0)),
+				Height:   uint64(stmt.ColumnInt64(2)),
+				Hare:     int8(stmt.ColumnInt64(3)),
+				Validity: int8(stmt.ColumnInt64(4)),
+				Margin:   columnWeight(stmt, 5),
+				Data:     stmt.ColumnInt(6) == 1,
+			}
+			stmt.ColumnBytes(1, block.ID[:])
+			return fn(block)
+		}); err != nil {
+		return fmt.Errorf("iterate tortoise blocks [%s, %s]: %w", from, to, err)
+	}
+	return nil
+}
+
+// SetBallot replaces previously stored result of counting the ballot.
+func SetBallot(db sql.Executor, ballot *Ballot) error {
+	if _, err := db.Exec(`insert into tortoise_ballots
+	(id, layer, bad_beacon, delayed, counted, good, votes)
+	values (?1, ?2, ?3, ?4, ?5, ?6, ?7)
+	on conflict(id) do update set bad_beacon=?3, delayed=?4, counted=?5, good=?6, votes=?7;`,
+		func(stmt *sql.Statement) {
+			stmt.BindBytes(1, ballot.ID[:])
+			stmt.BindInt64(2, int64(ballot.Layer))
+			stmt.BindBool(3, ballot.BadBeacon)
+			stmt.BindBool(4, ballot.Delayed)
+			stmt.BindBool(5, ballot.Counted)
+			stmt.BindBool(6, ballot.Good)
+			stmt.BindBool(7, ballot.Votes)
+		}, nil); err != nil {
+		return fmt.Errorf("set tortoise ballot %s: %w", ballot.ID, err)
+	}
+	return nil
+}
+
+// IterateBallots calls fn for every ballot in layers [from, to] in ascending order of layers.
+func IterateBallots(db sql.Executor, from, to types.LayerID, fn func(*Ballot) bool) error {
+	if _, err := db.Exec(`select id, layer, bad_beacon, delayed, counted, good, votes
+	from tortoise_ballots where layer between ?1 and ?2 order by layer asc;`,
+		func(stmt *sql.Statement) {
+			stmt.BindInt64(1, int64(from))
+			stmt.BindInt64(2, int64(to))
+		}, func(stmt *sql.Statement) bool {
+			ballot := &Ballot{
+				Layer:     types.LayerID(stmt.ColumnInt64(1)),
+				BadBeacon: stmt.ColumnInt(2) == 1,
+				Delayed:   stmt.ColumnInt(3) == 1,
+				Counted:   stmt.ColumnInt(4) == 1,
+				Good:      stmt.ColumnInt(5) == 1,
+				Votes:     stmt.ColumnInt(6) == 1,
+			}
+			stmt.ColumnBytes(0, ballot.ID[:])
+			return fn(ballot)
+		}); err != nil {
+		return fmt.Errorf("iterate tortoise ballots [%s, %s]: %w", from, to, err)
+	}
+	return nil
+}
+
+// Prune deletes tallies for layers before the layer, and ballots and blocks in the layer.
+// Tally for the layer itself is kept, as its opinion is a prefix for the opinion of the next layer.
+func Prune(db sql.Executor, lid types.LayerID) error {
+	for _, query := range []string{
+		"delete from tortoise_layers where id < ?1;",
+		"delete from tortoise_blocks where layer <= ?1;",
+		"delete from tortoise_ballots where layer <= ?1;",
+	} {
+		if _, err := db.Exec(query, func(stmt *sql.Statement) {
+			stmt.BindInt64(1, int64(lid))
+		}, nil); err != nil {
+			return fmt.Errorf("prune tortoise state %s: %w", lid, err)
+		}
+	}
+	return nil
+}
+
+// Clear deletes all stored state.
+func Clear(db sql.Executor) error {
+	for _, query := range []string{
+		"delete from tortoise_state;",
+		"delete from tortoise_layers;",
+		"delete from tortoise_blocks;",
+		"delete from tortoise_ballots;",
+	} {
+		if _, err := db.Exec(query, nil, nil); err != nil {
+			return fmt.Errorf("clear tortoise state: %w", err)
+		}
+	}
+	return nil
+}
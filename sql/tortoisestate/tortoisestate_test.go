@@ -0,0 +1,143 @@
+package tortoisestate
+
+import (
+	"testing"
+
+	"github.com/spacemeshos/fixed"
+	"github.com/stretchr/testify/require"
+
+	"github.com/spacemeshos/go-spacemesh/common/types"
+	"github.com/spacemeshos/go-spacemesh/sql"
+)
+
+func collectLayers(tb testing.TB, db sql.Executor, from, to types.LayerID) []*Layer {
+	tb.Helper()
+	var rst []*Layer
+	require.NoError(tb, IterateLayers(db, from, to, func(layer *Layer) bool {
+		rst = append(rst, layer)
+		return true
+	}))
+	return rst
+}
+
+func collectBlocks(tb testing.TB, db sql.Executor, from, to types.LayerID) []*Block {
+	tb.Helper()
+	var rst []*Block
+	require.NoError(tb, IterateBlocks(db, from, to, func(block *Block) bool {
+		rst = append(rst, block)
+		return true
+	}))
+	return rst
+}
+
+func collectBallots(tb testing.TB, db sql.Executor, from, to types.LayerID) []*Ballot {
+	tb.Helper()
+	var rst []*Ballot
+	require.NoError(tb, IterateBallots(db, from, to, func(ballot *Ballot) bool {
+		rst = append(rst, ballot)
+		return true
+	}))
+	return rst
+}
+
+func TestState(t *testing.T) {
+	db := sql.InMemory()
+	_, err := GetState(db)
+	require.ErrorIs(t, err, sql.ErrNotFound)
+
+	state := &State{
+		Version:    1,
+		Config:     types.Hash32{1, 2, 3},
+		Last:       20,
+		Processed:  20,
+		Verified:   19,
+		Evicted:    9,
+		Counted:    18,
+		Full:       true,
+		GoodWeight: fixed.DivUint64(3704, 3),
+	}
+	require.NoError(t, SetState(db, state))
+	got, err := GetState(db)
+	require.NoError(t, err)
+	require.Equal(t, state, got)
+
+	state.Processed = 21
+	state.Full = false
+	require.NoError(t, SetState(db, state))
+	got, err = GetState(db)
+	require.NoError(t, err)
+	require.Equal(t, state, got)
+}
+
+func TestTallies(t *testing.T) {
+	db := sql.InMemory()
+	var (
+		lrs []*Layer
+		brs []*Block
+		bls []*Ballot
+	)
+	for lid := types.LayerID(1); lid <= 5; lid++ {
+		layer := &Layer{
+			ID:              lid,
+			Opinion:         types.Hash32{byte(lid)},
+			HareTerminated:  lid%2 == 0,
+			Coinflip:        -1,
+			Empty:           fixed.Div64(int64(lid), 3),
+			Abstained:       fixed.New(10),
+			GoodUncounted:   fixed.DivUint64(1001, 7),
+			ReferenceHeight: uint64(lid) * 10,
+		}
+		require.NoError(t, SetLayer(db, layer))
+		lrs = append(lrs, layer)
+		block := &Block{
+			ID:       types.BlockID{byte(lid)},
+			Layer:    lid,
+			Height:   uint64(lid),
+			Hare:     1,
+			Validity: -1,
+			Margin:   fixed.Div64(-7, 3),
+			Data:     true,
+		}
+		require.NoError(t, SetBlock(db, block))
+		brs = append(brs, block)
+		ballot := &Ballot{
+			ID:        types.BallotID{byte(lid)},
+			Layer:     lid,
+			BadBeacon: lid == 3,
+			Delayed:   lid == 4,
+			Counted:   true,
+			Good:      lid != 3,
+			Votes:     lid == 5,
+		}
+		require.NoError(t, SetBallot(db, ballot))
+		bls = append(bls, ballot)
+	}
+	require.Equal(t, lrs[1:4], collectLayers(t, db, 2, 4))
+	require.Equal(t, brs[1:4], collectBlocks(t, db, 2, 4))
+	require.Equal(t, bls[1:4], collectBallots(t, db, 2, 4))
+
+	lrs[2].Coinflip = 1
+	require.NoError(t, SetLayer(db, lrs[2]))
+	brs[2].Validity = 1
+	brs[2].Margin = fixed.New(7)
+	require.NoError(t, SetBlock(db, brs[2]))
+	bls[2].BadBeacon = false
+	bls[2].Good = true
+	require.NoError(t, SetBallot(db, bls[2]))
+	require.Equal(t, lrs, collectLayers(t, db, 1, 5))
+	require.Equal(t, brs, collectBlocks(t, db, 1, 5))
+	require.Equal(t, bls, collectBallots(t, db, 1, 5))
+
+	require.NoError(t, Prune(db, 2))
+	require.Equal(t, lrs[1:], collectLayers(t, db, 1, 5))
+	require.Equal(t, brs[2:], collectBlocks(t, db, 1, 5))
+	require.Equal(t, bls[2:], collectBallots(t, db, 1, 5))
+
+	require.NoError(t, SetState(db, &State{Version: 1}))
+	require.NoError(t, Clear(db))
+	_, err := GetState(db)
+	require.ErrorIs(t, err, sql.ErrNotFound)
+	require.Empty(t, collectLayers(t, db, 1, 5))
+	require.Empty(t, collectBlocks(t, db, 1, 5))
+	require.Empty(t, collectBallots(t, db, 1, 5))
+}
@@ -0,0 +1,152 @@
+package transactions
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/spacemeshos/go-spacemesh/common/types"
+	"github.com/spacemeshos/go-spacemesh/sql"
+)
+
+// State is the lifecycle state of the transaction, with the layer and the block it refers to.
+// Transactions without a recorded state are in the mempool.
+type State struct {
+	State types.TXState
+	Layer types.LayerID
+	Block types.BlockID
+}
+
+// GetState returns the recorded lifecycle state of the transaction.
+func GetState(db sql.Executor, tid types.TransactionID) (State, error) {
+	var rst State
+	rows, err := db.Exec("select state, layer, block from transactions_states where id = ?1;",
+		func(stmt *sql.Statement) {
+			stmt.BindBytes(1, tid.Bytes())
+		}, func(stmt *sql.Statement) bool {
+			rst.State = types.TXState(stmt.ColumnInt64(0))
+			rst.Layer = types.LayerID(uint32(stmt.ColumnInt64(1)))
+			stmt.ColumnBytes(2, rst.Block[:])
+			return false
+		})
+	if err != nil {
+		return rst, fmt.Errorf("get state %s: %w", tid, err)
+	}
+	if rows == 0 {
+		return rst, fmt.Errorf("%w: no state for tx %s", sql.ErrNotFound, tid)
+	}
+	return rst, nil
+}
+
+// UpdateState moves the transaction forward in its lifecycle. The recorded state is replaced
+// only by a later state, or by the same state in a lower layer.
+// Returns true if the recorded state was replaced.
+func UpdateState(db sql.Executor, tid types.TransactionID, state State) (bool, error) {
+	current, err := GetState(db, tid)
+	switch {
+	case errors.Is(err, sql.ErrNotFound):
+	case err != nil:
+		return false, err
+	case state.State < current.State:
+		return false, nil
+	case state.State == current.State && !state.Layer.Before(current.Layer):
+		return false, nil
+	}
+	if _, err := db.Exec(`insert into transactions_states (id, state, layer, block) values (?1, ?2, ?3, ?4)
+		on conflict(id) do update set state = ?2, layer = ?3, block = ?4;`,
+		func(stmt *sql.Statement) {
+			stmt.BindBytes(1, tid.Bytes())
+			stmt.BindInt64(2, int64(state.State))
+			stmt.BindInt64(3, int64(state.Layer))
+			stmt.BindBytes(4, state.Block.Bytes())
+		}, nil); err != nil {
+		return false, fmt.Errorf("update state %s: %w", tid, err)
+	}
+	return true, nil
+}
+
+func deleteState(db sql.Executor, tid types.TransactionID) error {
+	if _, err := db.Exec("delete from transactions_states where id = ?1;",
+		func(stmt *sql.Statement) {
+			stmt.BindBytes(1, tid.Bytes())
+		}, nil); err != nil {
+		return fmt.Errorf("delete state %s: %w", tid, err)
+	}
+	return nil
+}
+
+func idsWithState(db sql.Executor, query string, lid types.LayerID) ([]types.TransactionID, error) {
+	var rst []types.TransactionID
+	if _, err := db.Exec(query,
+		func(stmt *sql.Statement) {
+			stmt.BindInt64(1, int64(lid))
+			stmt.BindInt64(2, int64(types.APPLIED))
+		}, func(stmt *sql.Statement) bool {
+			var tid types.TransactionID
+			stmt.ColumnBytes(0, tid[:])
+			rst = append(rst, tid)
+			return true
+		}); err != nil {
+		return nil, fmt.Errorf("select states: %w", err)
+	}
+	return rst, nil
+}
+
+// ResetStates moves the transactions that were included in the proposals or blocks of the layer,
+// but were not applied in it, to the next proposal or block that includes them, or back to the mempool.
+// Returns the new states of the moved transactions.
+func ResetStates(db sql.Executor, lid types.LayerID) (map[types.TransactionID]State, error) {
+	tids, err := idsWithState(db, "select id from transactions_states where layer = ?1 and state < ?2;", lid)
+	if err != nil {
+		return nil, err
+	}
+	rst := make(map[types.TransactionID]State, len(tids))
+	for _, tid := range tids {
+		if err := deleteState(db, tid); err != nil {
+			return nil, err
+		}
+		next := State{State: types.MEMPOOL}
+		bid, blid, err := TransactionInBlock(db, tid, lid)
+		if err == nil {
+			next = State{State: types.BLOCK, Layer: blid, Block: bid}
+		} else if !errors.Is(err, sql.ErrNotFound) {
+			return nil, err
+		} else if plid, err := TransactionInProposal(db, tid, lid); err == nil {
+			next = State{State: types.PROPOSAL, Layer: plid}
+		} else if !errors.Is(err, sql.ErrNotFound) {
+			return nil, err
+		}
+		if next.State != types.MEMPOOL {
+			if _, err := UpdateState(db, tid, next); err != nil {
+				return nil, err
+			}
+		}
+		rst[tid] = next
+	}
+	return rst, nil
+}
+
+// UndoStates moves the transactions applied in the layers starting from `from` back to the mempool.
+// Returns the ids of the moved transactions with the layers they were applied in.
+func UndoStates(db sql.Executor, from types.LayerID) (map[types.TransactionID]types.LayerID, error) {
+	rst := make(map[types.TransactionID]types.LayerID)
+	if _, err := db.Exec("select id, layer from transactions_states where layer >= ?1 and state = ?2;",
+		func(stmt *sql.Statement) {
+			stmt.BindInt64(1, int64(from))
+			stmt.BindInt64(2, int64(types.APPLIED))
+		}, func(stmt *sql.Statement) bool {
+			var tid types.TransactionID
+			stmt.ColumnBytes(0, tid[:])
+			rst[tid] = types.LayerID(uint32(stmt.ColumnInt64(1)))
+			return true
+		}); err != nil {
+		return nil, fmt.Errorf("select applied states: %w", err)
+	}
+	if _, err := db.Exec("delete from transactions_states where layer >= ?1 and state = ?2;",
+		func(stmt *sql.Statement) {
+			stmt.BindInt64(1, int64(from))
+			stmt.BindInt64(2, int64(types.APPLIED))
+		}, nil); err != nil {
+		return nil, fmt.Errorf("undo states from %s: %w", from, err)
+	}
+	return rst, nil
+}
@@ -0,0 +1,143 @@
+package transactions_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/spacemeshos/go-spacemesh/common/types"
+	"github.com/spacemeshos/go-spacemesh/sql"
+	"github.com/spacemeshos/go-spacemesh/sql/transactions"
+)
+
+func TestUpdateState(t *testing.T) {
+	db := sql.InMemory()
+	tid := types.RandomTransactionID()
+	_, err := transactions.GetState(db, tid)
+	require.ErrorIs(t, err, sql.ErrNotFound)
+
+	for _, tc := range []struct {
+		desc    string
+		state   transactions.State
+		updated bool
+		expect  transactions.State
+	}{
+		{
+			desc:    "proposed",
+			state:   transactions.State{State: types.PROPOSAL, Layer: 10},
+			updated: true,
+			expect:  transactions.State{State: types.PROPOSAL, Layer: 10},
+		},
+		{
+			desc:   "proposed later",
+			state:  transactions.State{State: types.PROPOSAL, Layer: 11},
+			expect: transactions.State{State: types.PROPOSAL, Layer: 10},
+		},
+		{
+			desc:    "proposed earlier",
+			state:   transactions.State{State: types.PROPOSAL, Layer: 9},
+			updated: true,
+			expect:  transactions.State{State: types.PROPOSAL, Layer: 9},
+		},
+		{
+			desc:    "block",
+			state:   transactions.State{State: types.BLOCK, Layer: 10, Block: types.BlockID{1}},
+			updated: true,
+			expect:  transactions.State{State: types.BLOCK, Layer: 10, Block: types.BlockID{1}},
+		},
+		{
+			desc:   "proposed after block",
+			state:  transactions.State{State: types.PROPOSAL, Layer: 8},
+			expect: transactions.State{State: types.BLOCK, Layer: 10, Block: types.BlockID{1}},
+		},
+		{
+			desc:    "applied",
+			state:   transactions.State{State: types.APPLIED, Layer: 10, Block: types.BlockID{1}},
+			updated: true,
+			expect:  transactions.State{State: types.APPLIED, Layer: 10, Block: types.BlockID{1}},
+		},
+		{
+			desc:   "block after applied",
+			state:  transactions.State{State: types.BLOCK, Layer: 9, Block: types.BlockID{2}},
+			expect: transactions.State{State: types.APPLIED, Layer: 10, Block: types.BlockID{1}},
+		},
+	} {
+		updated, err := transactions.UpdateState(db, tid, tc.state)
+		require.NoError(t, err, tc.desc)
+		require.Equal(t, tc.updated, updated, tc.desc)
+		got, err := transactions.GetState(db, tid)
+		require.NoError(t, err, tc.desc)
+		require.Equal(t, tc.expect, got, tc.desc)
+	}
+}
+
+func TestResetStates(t *testing.T) {
+	db := sql.InMemory()
+	lid := types.LayerID(10)
+	var (
+		applied   = types.RandomTransactionID()
+		reblock   = types.RandomTransactionID()
+		repropose = types.RandomTransactionID()
+		dropped   = types.RandomTransactionID()
+		other     = types.RandomTransactionID()
+	)
+	for _, tid := range []types.TransactionID{reblock, repropose, dropped} {
+		require.NoError(t, transactions.AddToBlock(db, tid, lid, types.BlockID{1}))
+		_, err := transactions.UpdateState(db, tid, transactions.State{State: types.BLOCK, Layer: lid, Block: types.BlockID{1}})
+		require.NoError(t, err)
+	}
+	require.NoError(t, transactions.AddToBlock(db, reblock, lid.Add(2), types.BlockID{2}))
+	require.NoError(t, transactions.AddToProposal(db, repropose, lid.Add(1), types.ProposalID{3}))
+	_, err := transactions.UpdateState(db, applied, transactions.State{State: types.APPLIED, Layer: lid, Block: types.BlockID{4}})
+	require.NoError(t, err)
+	_, err = transactions.UpdateState(db, other, transactions.State{State: types.PROPOSAL, Layer: lid.Add(1)})
+	require.NoError(t, err)
+
+	states, err := transactions.ResetStates(db, lid)
+	require.NoError(t, err)
+	require.Equal(t, map[types.TransactionID]transactions.State{
+		reblock:   {State: types.BLOCK, Layer: lid.Add(2), Block: types.BlockID{2}},
+		repropose: {State: types.PROPOSAL, Layer: lid.Add(1)},
+		dropped:   {State: types.MEMPOOL},
+	}, states)
+
+	for tid, expect := range states {
+		got, err := transactions.GetState(db, tid)
+		if expect.State == types.MEMPOOL {
+			require.ErrorIs(t, err, sql.ErrNotFound)
+			continue
+		}
+		require.NoError(t, err)
+		require.Equal(t, expect, got)
+	}
+	got, err := transactions.GetState(db, applied)
+	require.NoError(t, err)
+	require.Equal(t, types.APPLIED, got.State)
+	got, err = transactions.GetState(db, other)
+	require.NoError(t, err)
+	require.Equal(t, types.PROPOSAL, got.State)
+}
+
+func TestUndoStates(t *testing.T) {
+	db := sql.InMemory()
+	lid := types.LayerID(10)
+	before := types.RandomTransactionID()
+	after := types.RandomTransactionID()
+	proposed := types.RandomTransactionID()
+	_, err := transactions.UpdateState(db, before, transactions.State{State: types.APPLIED, Layer: lid.Sub(1)})
+	require.NoError(t, err)
+	_, err = transactions.UpdateState(db, after, transactions.State{State: types.APPLIED, Layer: lid})
+	require.NoError(t, err)
+	_, err = transactions.UpdateState(db, proposed, transactions.State{State: types.PROPOSAL, Layer: lid.Add(1)})
+	require.NoError(t, err)
+
+	reverted, err := transactions.UndoStates(db, lid)
+	require.NoError(t, err)
+	require.Equal(t, map[types.TransactionID]types.LayerID{after: lid}, reverted)
+	_, err = transactions.GetState(db, after)
+	require.ErrorIs(t, err, sql.ErrNotFound)
+	for _, tid := range []types.TransactionID{before, proposed} {
+		_, err = transactions.GetState(db, tid)
+		require.NoError(t, err)
+	}
+}
@@ -3,6 +3,7 @@ package transactions
 import (
 	"encoding/binary"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/spacemeshos/go-spacemesh/codec"
@@ -192,6 +193,69 @@ func Get(db sql.Executor, id types.TransactionID) (tx *types.MeshTransaction, er
 	return tx, nil
 }
 
+// maxBatchSize is the max number of ids in a single query, sqlite limits the number of
+// parameters in a statement.
+const maxBatchSize = 500
+
+// GetBatch gets transactions from database in as few queries as possible.
+// Found transactions are returned in the order of ids, ids that are not in the database
+// are returned as missing.
+func GetBatch(db sql.Executor, ids []types.TransactionID) ([]*types.MeshTransaction, []types.TransactionID, error) {
+	byID := make(map[types.TransactionID]*types.MeshTransaction, len(ids))
+	for start := 0; start < len(ids); start += maxBatchSize {
+		end := start + maxBatchSize
+		if end > len(ids) {
+			end = len(ids)
+		}
+		chunk := ids[start:end]
+		var (
+			query strings.Builder
+			derr  error
+		)
+		query.WriteString("select tx, header, layer, block, timestamp, id from transactions where id in (")
+		for i := range chunk {
+			if i > 0 {
+				query.WriteString(", ")
+			}
+			fmt.Fprintf(&query, "?%d", i+1)
+		}
+		query.WriteString(");")
+		if _, err := db.Exec(query.String(),
+			func(stmt *sql.Statement) {
+				for i, id := range chunk {
+					stmt.BindBytes(i+1, id.Bytes())
+				}
+			}, func(stmt *sql.Statement) bool {
+				var id types.TransactionID
+				stmt.ColumnBytes(5, id[:])
+				var tx *types.MeshTransaction
+				tx, derr = decodeTransaction(id, stmt)
+				if derr != nil {
+					return false
+				}
+				byID[id] = tx
+				return true
+			}); err != nil {
+			return nil, nil, fmt.Errorf("get batch: %w", err)
+		}
+		if derr != nil {
+			return nil, nil, fmt.Errorf("get batch: %w", derr)
+		}
+	}
+	var (
+		found   = make([]*types.MeshTransaction, 0, len(byID))
+		missing []types.TransactionID
+	)
+	for _, id := range ids {
+		if tx, ok := byID[id]; ok {
+			found = append(found, tx)
+		} else {
+			missing = append(missing, id)
+		}
+	}
+	return found, missing, nil
+}
+
 // GetBlob loads transaction as an encoded blob, ready to be sent over the wire.
 func GetBlob(db sql.Executor, id []byte) (buf []byte, err error) {
 	if rows, err := db.Exec("select tx from transactions where id = ?1",
@@ -17,7 +17,7 @@ import (
 	"github.com/spacemeshos/go-spacemesh/sql/transactions"
 )
 
-func createTX(t *testing.T, principal *signing.EdSigner, dest types.Address, nonce, amount, fee uint64) *types.Transaction {
+func createTX(t testing.TB, principal *signing.EdSigner, dest types.Address, nonce, amount, fee uint64) *types.Transaction {
 	t.Helper()
 
 	var raw []byte
@@ -273,6 +273,74 @@ func TestGetBlob(t *testing.T) {
 	}
 }
 
+func TestGetBatch(t *testing.T) {
+	db := sql.InMemory()
+
+	rng := rand.New(rand.NewSource(1001))
+	signer, err := signing.NewEdSigner(signing.WithKeyFromRand(rng))
+	require.NoError(t, err)
+	// more than fits into a single query
+	numTXs := 1234
+	var (
+		ids     []types.TransactionID
+		missing []types.TransactionID
+		expect  []*types.Transaction
+	)
+	received := time.Now()
+	for i := 0; i < numTXs; i++ {
+		if i%100 == 0 {
+			tid := types.RandomTransactionID()
+			ids = append(ids, tid)
+			missing = append(missing, tid)
+		}
+		tx := createTX(t, signer, types.Address{1}, uint64(i+1), 191, 1)
+		require.NoError(t, transactions.Add(db, tx, received))
+		ids = append(ids, tx.ID)
+		expect = append(expect, tx)
+	}
+
+	got, notFound, err := transactions.GetBatch(db, ids)
+	require.NoError(t, err)
+	require.Equal(t, missing, notFound)
+	require.Len(t, got, len(expect))
+	for i, tx := range expect {
+		checkMeshTXEqual(t, *makeMeshTX(tx, 0, types.EmptyBlockID, received, types.MEMPOOL), *got[i])
+	}
+
+	got, notFound, err = transactions.GetBatch(db, nil)
+	require.NoError(t, err)
+	require.Empty(t, got)
+	require.Empty(t, notFound)
+}
+
+func BenchmarkGetBatch(b *testing.B) {
+	const numTXs = 5000
+	db := sql.InMemory()
+	signer, err := signing.NewEdSigner()
+	require.NoError(b, err)
+	ids := make([]types.TransactionID, 0, numTXs)
+	for i := 0; i < numTXs; i++ {
+		tx := createTX(b, signer, types.Address{1}, uint64(i+1), 191, 1)
+		require.NoError(b, transactions.Add(db, tx, time.Now()))
+		ids = append(ids, tx.ID)
+	}
+	b.Run("batch", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			got, _, err := transactions.GetBatch(db, ids)
+			require.NoError(b, err)
+			require.Len(b, got, numTXs)
+		}
+	})
+	b.Run("per id", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			for _, id := range ids {
+				_, err := transactions.Get(db, id)
+				require.NoError(b, err)
+			}
+		}
+	})
+}
+
 func TestGetByAddress(t *testing.T) {
 	db := sql.InMemory()
 
@@ -32,7 +32,7 @@ type testForkFinder struct {
 func newTestForkFinderWithDuration(t *testing.T, d time.Duration, lg log.Log) *testForkFinder {
 	mf := mocks.NewMockfetcher(gomock.NewController(t))
 	db := sql.InMemory()
-	require.NoError(t, layers.SetMeshHash(db, types.GetEffectiveGenesis(), types.RandomHash()))
+	require.NoError(t, layers.SetAggregatedHash(db, types.GetEffectiveGenesis(), types.RandomHash()))
 	return &testForkFinder{
 		ForkFinder: syncer.NewForkFinder(lg, db, mf, d),
 		db:         db,
@@ -86,9 +86,9 @@ func layerHash(layer int, good bool) types.Hash32 {
 func storeNodeHashes(t *testing.T, db *sql.Database, diverge, max int) {
 	for lid := 0; lid <= max; lid++ {
 		if lid < diverge {
-			require.NoError(t, layers.SetMeshHash(db, types.LayerID(uint32(lid)), layerHash(lid, true)))
+			require.NoError(t, layers.SetAggregatedHash(db, types.LayerID(uint32(lid)), layerHash(lid, true)))
 		} else {
-			require.NoError(t, layers.SetMeshHash(db, types.LayerID(uint32(lid)), layerHash(lid, false)))
+			require.NoError(t, layers.SetAggregatedHash(db, types.LayerID(uint32(lid)), layerHash(lid, false)))
 		}
 	}
 }
@@ -141,7 +141,7 @@ func TestForkFinder_MeshChangedMidSession(t *testing.T) {
 		t.Parallel()
 
 		tf := newTestForkFinderWithDuration(t, time.Hour, logtest.New(t))
-		require.NoError(t, layers.SetMeshHash(tf.db, lastAgreedLid, lastAgreedHash))
+		require.NoError(t, layers.SetAggregatedHash(tf.db, lastAgreedLid, lastAgreedHash))
 		tf.UpdateAgreement(peer, lastAgreedLid, lastAgreedHash, time.Now())
 		tf.UpdateAgreement("shorty", types.LayerID(111), types.RandomHash(), time.Now())
 		require.Equal(t, tf.NumPeersCached(), 2)
@@ -162,7 +162,7 @@ func TestForkFinder_MeshChangedMidSession(t *testing.T) {
 		t.Parallel()
 
 		tf := newTestForkFinderWithDuration(t, time.Hour, logtest.New(t))
-		require.NoError(t, layers.SetMeshHash(tf.db, lastAgreedLid, lastAgreedHash))
+		require.NoError(t, layers.SetAggregatedHash(tf.db, lastAgreedLid, lastAgreedHash))
 		tf.UpdateAgreement(peer, lastAgreedLid, lastAgreedHash, time.Now())
 		tf.UpdateAgreement("shorty", types.LayerID(111), types.RandomHash(), time.Now())
 		require.Equal(t, tf.NumPeersCached(), 2)
@@ -175,7 +175,7 @@ func TestForkFinder_MeshChangedMidSession(t *testing.T) {
 				}
 				// changes the node's own hash for lastAgreedLid
 				for _, lid := range []types.LayerID{types.LayerID(35), types.LayerID(36), types.LayerID(37)} {
-					require.NoError(t, layers.SetMeshHash(tf.db, lid, types.RandomHash()))
+					require.NoError(t, layers.SetAggregatedHash(tf.db, lid, types.RandomHash()))
 				}
 				return mh, nil
 			})
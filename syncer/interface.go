@@ -7,6 +7,7 @@ import (
 	"github.com/spacemeshos/go-spacemesh/common/types"
 	"github.com/spacemeshos/go-spacemesh/fetch"
 	"github.com/spacemeshos/go-spacemesh/p2p"
+	"github.com/spacemeshos/go-spacemesh/tortoise"
 )
 
 //go:generate mockgen -package=mocks -destination=./mocks/mocks.go -source=./interface.go
@@ -70,3 +71,12 @@ type forkFinder interface {
 type idProvider interface {
 	IdentityExists(id types.NodeID) (bool, error)
 }
+
+type rerunner interface {
+	Rerun(context.Context, types.LayerID) (<-chan tortoise.RerunProgress, error)
+}
+
+type forkDetector interface {
+	OnPeerMeshHash(p2p.Peer, types.LayerID, types.Hash32)
+	Check(context.Context, types.LayerID) (types.LayerID, error)
+}
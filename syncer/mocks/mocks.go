@@ -13,6 +13,7 @@ import (
 	types "github.com/spacemeshos/go-spacemesh/common/types"
 	fetch "github.com/spacemeshos/go-spacemesh/fetch"
 	p2p "github.com/spacemeshos/go-spacemesh/p2p"
+	tortoise "github.com/spacemeshos/go-spacemesh/tortoise"
 )
 
 // MocklayerTicker is a mock of layerTicker interface.
@@ -744,3 +745,91 @@ func (mr *MockidProviderMockRecorder) IdentityExists(id interface{}) *gomock.Cal
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IdentityExists", reflect.TypeOf((*MockidProvider)(nil).IdentityExists), id)
 }
+
+// Mockrerunner is a mock of rerunner interface.
+type Mockrerunner struct {
+	ctrl     *gomock.Controller
+	recorder *MockrerunnerMockRecorder
+}
+
+// MockrerunnerMockRecorder is the mock recorder for Mockrerunner.
+type MockrerunnerMockRecorder struct {
+	mock *Mockrerunner
+}
+
+// NewMockrerunner creates a new mock instance.
+func NewMockrerunner(ctrl *gomock.Controller) *Mockrerunner {
+	mock := &Mockrerunner{ctrl: ctrl}
+	mock.recorder = &MockrerunnerMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *Mockrerunner) EXPECT() *MockrerunnerMockRecorder {
+	return m.recorder
+}
+
+// Rerun mocks base method.
+func (m *Mockrerunner) Rerun(arg0 context.Context, arg1 types.LayerID) (<-chan tortoise.RerunProgress, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Rerun", arg0, arg1)
+	ret0, _ := ret[0].(<-chan tortoise.RerunProgress)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Rerun indicates an expected call of Rerun.
+func (mr *MockrerunnerMockRecorder) Rerun(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Rerun", reflect.TypeOf((*Mockrerunner)(nil).Rerun), arg0, arg1)
+}
+
+// MockforkDetector is a mock of forkDetector interface.
+type MockforkDetector struct {
+	ctrl     *gomock.Controller
+	recorder *MockforkDetectorMockRecorder
+}
+
+// MockforkDetectorMockRecorder is the mock recorder for MockforkDetector.
+type MockforkDetectorMockRecorder struct {
+	mock *MockforkDetector
+}
+
+// NewMockforkDetector creates a new mock instance.
+func NewMockforkDetector(ctrl *gomock.Controller) *MockforkDetector {
+	mock := &MockforkDetector{ctrl: ctrl}
+	mock.recorder = &MockforkDetectorMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockforkDetector) EXPECT() *MockforkDetectorMockRecorder {
+	return m.recorder
+}
+
+// Check mocks base method.
+func (m *MockforkDetector) Check(arg0 context.Context, arg1 types.LayerID) (types.LayerID, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Check", arg0, arg1)
+	ret0, _ := ret[0].(types.LayerID)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Check indicates an expected call of Check.
+func (mr *MockforkDetectorMockRecorder) Check(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Check", reflect.TypeOf((*MockforkDetector)(nil).Check), arg0, arg1)
+}
+
+// OnPeerMeshHash mocks base method.
+func (m *MockforkDetector) OnPeerMeshHash(arg0 p2p.Peer, arg1 types.LayerID, arg2 types.Hash32) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "OnPeerMeshHash", arg0, arg1, arg2)
+}
+
+// OnPeerMeshHash indicates an expected call of OnPeerMeshHash.
+func (mr *MockforkDetectorMockRecorder) OnPeerMeshHash(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "OnPeerMeshHash", reflect.TypeOf((*MockforkDetector)(nil).OnPeerMeshHash), arg0, arg1, arg2)
+}
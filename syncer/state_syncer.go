@@ -79,6 +79,7 @@ func (s *Syncer) processLayers(ctx context.Context) error {
 		}
 
 		if opinions, err := s.fetchOpinions(ctx, lid); err == nil {
+			s.detectFork(ctx, lid.Sub(1), opinions)
 			if s.stateSynced() {
 				if err = s.checkMeshAgreement(ctx, lid, opinions); err != nil && errors.Is(err, errMeshHashDiverged) {
 					s.logger.WithContext(ctx).With().Debug("mesh hash diverged, trying to reach agreement",
@@ -148,7 +149,17 @@ func (s *Syncer) needCert(ctx context.Context, lid types.LayerID) (bool, error)
 		s.logger.WithContext(ctx).With().Error("state sync failed to get cert", lid, log.Err(err))
 		return false, err
 	}
-	return errors.Is(err, sql.ErrNotFound), nil
+	if err == nil {
+		return false, nil
+	}
+	// certificate of the hare output is enough to generate the block and certify it locally,
+	// there is no need to ask peers for their certificates
+	hareCert, err := certificates.HasHare(s.cdb, lid)
+	if err != nil {
+		s.logger.WithContext(ctx).With().Error("state sync failed to get hare cert", lid, log.Err(err))
+		return false, err
+	}
+	return !hareCert, nil
 }
 
 func (s *Syncer) fetchOpinions(ctx context.Context, lid types.LayerID) ([]*fetch.LayerOpinion, error) {
@@ -162,6 +173,27 @@ func (s *Syncer) fetchOpinions(ctx context.Context, lid types.LayerID) ([]*fetch
 	return opinions, nil
 }
 
+// detectFork passes the mesh hashes of the layer advertised by the peers to the fork detector,
+// and reruns the tortoise from the earliest divergent layer if a fork is detected.
+func (s *Syncer) detectFork(ctx context.Context, lid types.LayerID, opinions []*fetch.LayerOpinion) {
+	if s.forkDetector == nil {
+		return
+	}
+	for _, opn := range opinions {
+		if opn.PrevAggHash != (types.Hash32{}) {
+			s.forkDetector.OnPeerMeshHash(opn.Peer(), lid, opn.PrevAggHash)
+		}
+	}
+	from, err := s.forkDetector.Check(ctx, lid)
+	if err != nil {
+		s.logger.WithContext(ctx).With().Warning("failed to check mesh hashes of peers", lid, log.Err(err))
+		return
+	}
+	if from != 0 {
+		s.rerunTortoise(ctx, from)
+	}
+}
+
 func (s *Syncer) checkMeshAgreement(ctx context.Context, lid types.LayerID, opinions []*fetch.LayerOpinion) error {
 	prevHash, err := layers.GetAggregatedHash(s.cdb, lid.Sub(1))
 	if err != nil {
@@ -252,8 +284,9 @@ func (s *Syncer) ensureMeshAgreement(
 	}
 
 	var (
-		fork types.LayerID
-		ed   *fetch.EpochData
+		fork      types.LayerID
+		ed        *fetch.EpochData
+		rerunFrom types.LayerID
 	)
 	for _, opn := range opinions {
 		if opn.PrevAggHash == (types.Hash32{}) {
@@ -368,9 +401,47 @@ func (s *Syncer) ensureMeshAgreement(
 			log.Stringer("to", to))
 		resyncPeers[opn.Peer()] = struct{}{}
 		s.forkFinder.AddResynced(prevLid, opn.PrevAggHash)
+		if rerunFrom == 0 || from.Before(rerunFrom) {
+			rerunFrom = from
+		}
 	}
 
 	// clear the agreement cache after syncing new data
 	s.forkFinder.Purge(true)
+	if rerunFrom != 0 {
+		s.rerunTortoise(ctx, rerunFrom)
+	}
 	return nil
 }
+
+// rerunTortoise recomputes tortoise opinions in the background, so that data synced
+// from the diverged peers is counted from the fork. If rerun is already in progress
+// divergence will be detected again and resolved by the next rerun.
+func (s *Syncer) rerunTortoise(ctx context.Context, from types.LayerID) {
+	if s.rerunner == nil {
+		return
+	}
+	progress, err := s.rerunner.Rerun(ctx, from)
+	if err != nil {
+		s.logger.WithContext(ctx).With().Warning("failed to start tortoise rerun",
+			log.Stringer("from", from),
+			log.Err(err),
+		)
+		return
+	}
+	s.eg.Go(func() error {
+		// completion and failures are logged by tortoise
+		for update := range progress {
+			if update.Done {
+				continue
+			}
+			s.logger.WithContext(ctx).With().Debug("tortoise rerun progress",
+				log.Stringer("from", update.From),
+				log.Stringer("to", update.To),
+				log.Uint32("processed", update.Processed),
+				log.Duration("remaining", update.Remaining),
+			)
+		}
+		return nil
+	})
+}
@@ -18,6 +18,8 @@ import (
 	"github.com/spacemeshos/go-spacemesh/sql/blocks"
 	"github.com/spacemeshos/go-spacemesh/sql/certificates"
 	"github.com/spacemeshos/go-spacemesh/sql/layers"
+	"github.com/spacemeshos/go-spacemesh/syncer/mocks"
+	"github.com/spacemeshos/go-spacemesh/tortoise"
 )
 
 func opinions(prevHash types.Hash32) []*fetch.LayerOpinion {
@@ -70,6 +72,9 @@ func TestProcessLayers_MultiLayers(t *testing.T) {
 				require.NoError(t, certificates.Add(ts.cdb, lid, gotC))
 				return nil
 			})
+		ts.mTortoise.EXPECT().OnHareOutput(lid, gomock.Any()).Do(func(_ types.LayerID, got types.BlockID) {
+			require.Equal(t, adopted[lid], got)
+		})
 		ts.mTortoise.EXPECT().TallyVotes(gomock.Any(), lid)
 		ts.mTortoise.EXPECT().Updates().DoAndReturn(func() []result.Layer {
 			return fixture.RLayers(fixture.RLayer(lid, fixture.RBlock(adopted[lid], fixture.Good())))
@@ -95,6 +100,7 @@ func TestProcessLayers_OpinionsNotAdopted(t *testing.T) {
 		name              string
 		opns              []*fetch.LayerOpinion
 		localCert         types.BlockID
+		hareCert          bool
 		certErr, fetchErr error
 	}{
 		{
@@ -102,6 +108,11 @@ func TestProcessLayers_OpinionsNotAdopted(t *testing.T) {
 			opns:      opinions(prevHash),
 			localCert: types.RandomBlockID(),
 		},
+		{
+			name:     "node has hare cert",
+			opns:     opinions(prevHash),
+			hareCert: true,
+		},
 		{
 			name: "no certs available",
 			opns: []*fetch.LayerOpinion{
@@ -132,7 +143,7 @@ func TestProcessLayers_OpinionsNotAdopted(t *testing.T) {
 			t.Parallel()
 
 			ts := newSyncerWithoutSyncTimer(t)
-			require.NoError(t, layers.SetMeshHash(ts.cdb, gLid, prevHash))
+			require.NoError(t, layers.SetAggregatedHash(ts.cdb, gLid, prevHash))
 			ts.syncer.setATXSynced()
 			current := lid.Add(1)
 			ts.syncer.setLastSyncedLayer(current.Sub(1))
@@ -145,11 +156,15 @@ func TestProcessLayers_OpinionsNotAdopted(t *testing.T) {
 				}
 			}
 
+			if tc.hareCert {
+				require.NoError(t, certificates.AddHare(ts.cdb, lid, types.RandomBytes(10)))
+			}
 			// saves opinions
 			if tc.localCert != types.EmptyBlockID {
 				require.NoError(t, blocks.Add(ts.cdb, types.NewExistingBlock(tc.localCert, types.InnerBlock{LayerIndex: lid})))
 				require.NoError(t, certificates.Add(ts.cdb, lid, &types.Certificate{BlockID: tc.localCert}))
 				require.NoError(t, blocks.SetValid(ts.cdb, tc.localCert))
+				ts.mTortoise.EXPECT().OnHareOutput(lid, tc.localCert)
 				ts.mVm.EXPECT().Apply(vm.ApplyContext{Layer: lid}, gomock.Any(), gomock.Any())
 				ts.mConState.EXPECT().UpdateCache(gomock.Any(), lid, tc.localCert, nil, nil)
 				ts.mVm.EXPECT().GetStateRoot()
@@ -160,7 +175,7 @@ func TestProcessLayers_OpinionsNotAdopted(t *testing.T) {
 			}
 			ts.mLyrPatrol.EXPECT().IsHareInCharge(lid).Return(false)
 			ts.mDataFetcher.EXPECT().PollLayerOpinions(gomock.Any(), lid).Return(tc.opns, nil)
-			if tc.localCert == types.EmptyBlockID && hasCert {
+			if tc.localCert == types.EmptyBlockID && !tc.hareCert && hasCert {
 				ts.mDataFetcher.EXPECT().GetBlocks(gomock.Any(), gomock.Any()).DoAndReturn(
 					func(_ context.Context, got []types.BlockID) error {
 						require.Equal(t, []types.BlockID{tc.opns[1].Cert.BlockID}, got)
@@ -376,9 +391,48 @@ func TestProcessLayers_MeshHashDiverged(t *testing.T) {
 	ts.mForkFinder.EXPECT().AddResynced(instate.Sub(1), opns[2].PrevAggHash)
 	ts.mForkFinder.EXPECT().Purge(true)
 
+	// tortoise is rerun from the earliest fork
+	rerun := mocks.NewMockrerunner(gomock.NewController(t))
+	ts.syncer.rerunner = rerun
+	progress := make(chan tortoise.RerunProgress, 1)
+	progress <- tortoise.RerunProgress{From: fork0.Add(1), To: instate, Done: true}
+	close(progress)
+	rerun.EXPECT().Rerun(gomock.Any(), fork0.Add(1)).Return((<-chan tortoise.RerunProgress)(progress), nil)
+
 	ts.mTortoise.EXPECT().TallyVotes(gomock.Any(), instate)
 	ts.mTortoise.EXPECT().Updates().Return(fixture.RLayers(fixture.ROpinion(instate.Sub(1), opns[2].PrevAggHash)))
 	require.NoError(t, ts.syncer.processLayers(context.Background()))
+	require.NoError(t, ts.syncer.eg.Wait())
+}
+
+func TestDetectFork(t *testing.T) {
+	ts := newSyncerWithoutSyncTimer(t)
+	ctrl := gomock.NewController(t)
+	detector := mocks.NewMockforkDetector(ctrl)
+	rerun := mocks.NewMockrerunner(ctrl)
+	ts.syncer.forkDetector = detector
+	ts.syncer.rerunner = rerun
+
+	lid := types.GetEffectiveGenesis().Add(10)
+	opns := []*fetch.LayerOpinion{{PrevAggHash: types.RandomHash()}, {}, {PrevAggHash: types.RandomHash()}}
+	for i, opn := range opns {
+		opn.SetPeer(p2p.Peer(strconv.Itoa(i)))
+	}
+	// peers that didn't advertise the hash are ignored
+	detector.EXPECT().OnPeerMeshHash(opns[0].Peer(), lid, opns[0].PrevAggHash)
+	detector.EXPECT().OnPeerMeshHash(opns[2].Peer(), lid, opns[2].PrevAggHash)
+	detector.EXPECT().Check(gomock.Any(), lid).Return(lid.Sub(3), nil)
+	progress := make(chan tortoise.RerunProgress, 1)
+	progress <- tortoise.RerunProgress{From: lid.Sub(3), To: lid, Done: true}
+	close(progress)
+	rerun.EXPECT().Rerun(gomock.Any(), lid.Sub(3)).Return((<-chan tortoise.RerunProgress)(progress), nil)
+	ts.syncer.detectFork(context.Background(), lid, opns)
+	require.NoError(t, ts.syncer.eg.Wait())
+
+	// no rerun if the fork is not detected
+	detector.EXPECT().OnPeerMeshHash(gomock.Any(), lid, gomock.Any()).Times(2)
+	detector.EXPECT().Check(gomock.Any(), lid).Return(types.LayerID(0), nil)
+	ts.syncer.detectFork(context.Background(), lid, opns)
 }
 
 func TestProcessLayers_NoHashResolutionForNewlySyncedNode(t *testing.T) {
@@ -95,6 +95,20 @@ func WithLogger(l log.Log) Option {
 	}
 }
 
+// WithTortoiseRerun enables tortoise rerun after the data from the peer with diverged mesh hash is synced.
+func WithTortoiseRerun(r rerunner) Option {
+	return func(s *Syncer) {
+		s.rerunner = r
+	}
+}
+
+// WithForkDetector enables the comparison of the mesh hashes advertised by the peers with the local mesh hashes.
+func WithForkDetector(fd forkDetector) Option {
+	return func(s *Syncer) {
+		s.forkDetector = fd
+	}
+}
+
 func withDataFetcher(d fetchLogic) Option {
 	return func(s *Syncer) {
 		s.dataFetcher = d
@@ -120,6 +134,8 @@ type Syncer struct {
 	dataFetcher   fetchLogic
 	patrol        layerPatrol
 	forkFinder    forkFinder
+	rerunner      rerunner
+	forkDetector  forkDetector
 	syncOnce      sync.Once
 	syncState     atomic.Value
 	atxSyncState  atomic.Value
@@ -17,6 +17,8 @@ type Fetcher interface {
 	BallotFetcher
 	ProposalFetcher
 	TxFetcher
+	KeyRotationFetcher
+	HareSetFetcher
 	PeerTracker
 }
 
@@ -51,6 +53,16 @@ type ProposalFetcher interface {
 	GetProposals(context.Context, []types.ProposalID) error
 }
 
+// KeyRotationFetcher defines an interface for fetching key rotations of the identities from remote peers.
+type KeyRotationFetcher interface {
+	GetKeyRotations(context.Context, []types.NodeID) error
+}
+
+// HareSetFetcher defines an interface for fetching the proposal sets referred to by hare messages.
+type HareSetFetcher interface {
+	GetHareSets(context.Context, []types.Hash32) error
+}
+
 // PeerTracker defines an interface to track peer hashes.
 type PeerTracker interface {
 	RegisterPeerHashes(peer p2p.Peer, hashes []types.Hash32)
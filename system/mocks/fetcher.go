@@ -92,6 +92,34 @@ func (mr *MockFetcherMockRecorder) GetBlocks(arg0, arg1 interface{}) *gomock.Cal
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetBlocks", reflect.TypeOf((*MockFetcher)(nil).GetBlocks), arg0, arg1)
 }
 
+// GetHareSets mocks base method.
+func (m *MockFetcher) GetHareSets(arg0 context.Context, arg1 []types.Hash32) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetHareSets", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// GetHareSets indicates an expected call of GetHareSets.
+func (mr *MockFetcherMockRecorder) GetHareSets(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetHareSets", reflect.TypeOf((*MockFetcher)(nil).GetHareSets), arg0, arg1)
+}
+
+// GetKeyRotations mocks base method.
+func (m *MockFetcher) GetKeyRotations(arg0 context.Context, arg1 []types.NodeID) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetKeyRotations", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// GetKeyRotations indicates an expected call of GetKeyRotations.
+func (mr *MockFetcherMockRecorder) GetKeyRotations(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetKeyRotations", reflect.TypeOf((*MockFetcher)(nil).GetKeyRotations), arg0, arg1)
+}
+
 // GetPoetProof mocks base method.
 func (m *MockFetcher) GetPoetProof(arg0 context.Context, arg1 types.Hash32) error {
 	m.ctrl.T.Helper()
@@ -382,6 +410,80 @@ func (mr *MockProposalFetcherMockRecorder) GetProposals(arg0, arg1 interface{})
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetProposals", reflect.TypeOf((*MockProposalFetcher)(nil).GetProposals), arg0, arg1)
 }
 
+// MockKeyRotationFetcher is a mock of KeyRotationFetcher interface.
+type MockKeyRotationFetcher struct {
+	ctrl     *gomock.Controller
+	recorder *MockKeyRotationFetcherMockRecorder
+}
+
+// MockKeyRotationFetcherMockRecorder is the mock recorder for MockKeyRotationFetcher.
+type MockKeyRotationFetcherMockRecorder struct {
+	mock *MockKeyRotationFetcher
+}
+
+// NewMockKeyRotationFetcher creates a new mock instance.
+func NewMockKeyRotationFetcher(ctrl *gomock.Controller) *MockKeyRotationFetcher {
+	mock := &MockKeyRotationFetcher{ctrl: ctrl}
+	mock.recorder = &MockKeyRotationFetcherMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockKeyRotationFetcher) EXPECT() *MockKeyRotationFetcherMockRecorder {
+	return m.recorder
+}
+
+// GetKeyRotations mocks base method.
+func (m *MockKeyRotationFetcher) GetKeyRotations(arg0 context.Context, arg1 []types.NodeID) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetKeyRotations", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// GetKeyRotations indicates an expected call of GetKeyRotations.
+func (mr *MockKeyRotationFetcherMockRecorder) GetKeyRotations(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetKeyRotations", reflect.TypeOf((*MockKeyRotationFetcher)(nil).GetKeyRotations), arg0, arg1)
+}
+
+// MockHareSetFetcher is a mock of HareSetFetcher interface.
+type MockHareSetFetcher struct {
+	ctrl     *gomock.Controller
+	recorder *MockHareSetFetcherMockRecorder
+}
+
+// MockHareSetFetcherMockRecorder is the mock recorder for MockHareSetFetcher.
+type MockHareSetFetcherMockRecorder struct {
+	mock *MockHareSetFetcher
+}
+
+// NewMockHareSetFetcher creates a new mock instance.
+func NewMockHareSetFetcher(ctrl *gomock.Controller) *MockHareSetFetcher {
+	mock := &MockHareSetFetcher{ctrl: ctrl}
+	mock.recorder = &MockHareSetFetcherMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockHareSetFetcher) EXPECT() *MockHareSetFetcherMockRecorder {
+	return m.recorder
+}
+
+// GetHareSets mocks base method.
+func (m *MockHareSetFetcher) GetHareSets(arg0 context.Context, arg1 []types.Hash32) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetHareSets", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// GetHareSets indicates an expected call of GetHareSets.
+func (mr *MockHareSetFetcherMockRecorder) GetHareSets(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetHareSets", reflect.TypeOf((*MockHareSetFetcher)(nil).GetHareSets), arg0, arg1)
+}
+
 // MockPeerTracker is a mock of PeerTracker interface.
 type MockPeerTracker struct {
 	ctrl     *gomock.Controller
@@ -0,0 +1,492 @@
+package testvectors
+
+import (
+	"encoding/hex"
+	"math/rand"
+
+	"github.com/spacemeshos/go-spacemesh/codec"
+	"github.com/spacemeshos/go-spacemesh/common/types"
+	"github.com/spacemeshos/go-spacemesh/hare"
+	"github.com/spacemeshos/go-spacemesh/signing"
+)
+
+// case seeds must never change, new cases must use new seeds.
+// order of cases defines order of vectors in the file.
+var suites = []suite{
+	{
+		typ: "ballot",
+		cases: []testCase{
+			{name: "ref", seed: 1, gen: refBallot},
+			{name: "regular", seed: 2, gen: regularBallot},
+			{name: "large votes", seed: 3, gen: largeVotesBallot},
+		},
+	},
+	{
+		typ: "proposal",
+		cases: []testCase{
+			{name: "ref with txs", seed: 1, gen: refProposal},
+			{name: "regular with txs", seed: 2, gen: regularProposal},
+			{name: "empty", seed: 3, gen: emptyProposal},
+		},
+	},
+	{
+		typ: "atx",
+		cases: []testCase{
+			{name: "initial", seed: 1, gen: initialAtx},
+			{name: "subsequent", seed: 2, gen: subsequentAtx},
+		},
+	},
+	{
+		typ: "hare",
+		cases: []testCase{
+			{name: "preround", seed: 1, gen: preroundMessage},
+			{name: "proposal with svp", seed: 2, gen: proposalMessage},
+			{name: "notify with certificate", seed: 3, gen: notifyMessage},
+		},
+	},
+	{
+		typ: "hare-legacy",
+		cases: []testCase{
+			{name: "preround", seed: 1, gen: legacyPreroundMessage},
+			{name: "proposal with svp", seed: 2, gen: legacyProposalMessage},
+			{name: "notify with certificate", seed: 3, gen: legacyNotifyMessage},
+		},
+	},
+}
+
+type suite struct {
+	typ   string
+	cases []testCase
+}
+
+type testCase struct {
+	name string
+	seed int64
+	gen  func(*generator) (*Vector, error)
+}
+
+// generator produces deterministic values from the seeded source.
+type generator struct {
+	rng    *rand.Rand
+	signer *signing.EdSigner
+}
+
+func (g *generator) bytes(n int) []byte {
+	buf := make([]byte, n)
+	g.rng.Read(buf)
+	return buf
+}
+
+func (g *generator) hash() (h types.Hash32) {
+	g.rng.Read(h[:])
+	return h
+}
+
+func (g *generator) beacon() (beacon types.Beacon) {
+	g.rng.Read(beacon[:])
+	return beacon
+}
+
+func (g *generator) layer() types.LayerID {
+	return types.LayerID(g.rng.Uint32() % 100_000)
+}
+
+func (g *generator) vrf() (sig types.VrfSignature) {
+	g.rng.Read(sig[:])
+	return sig
+}
+
+func (g *generator) atxIDs(n int) []types.ATXID {
+	ids := make([]types.ATXID, n)
+	for i := range ids {
+		ids[i] = types.ATXID(g.hash())
+	}
+	return ids
+}
+
+func (g *generator) votes(support, against, abstain int) types.Votes {
+	votes := types.Votes{Base: types.BallotID(g.hash().ToHash20())}
+	for i := 0; i < support; i++ {
+		votes.Support = append(votes.Support, g.vote())
+	}
+	for i := 0; i < against; i++ {
+		votes.Against = append(votes.Against, g.vote())
+	}
+	for i := 0; i < abstain; i++ {
+		votes.Abstain = append(votes.Abstain, g.layer())
+	}
+	return votes
+}
+
+func (g *generator) vote() types.Vote {
+	return types.Vote{
+		ID:      types.BlockID(g.hash().ToHash20()),
+		LayerID: g.layer(),
+		Height:  g.rng.Uint64(),
+	}
+}
+
+func (g *generator) proofs(n int) []types.VotingEligibility {
+	proofs := make([]types.VotingEligibility, n)
+	for i := range proofs {
+		proofs[i] = types.VotingEligibility{J: uint32(i), Sig: g.vrf()}
+	}
+	return proofs
+}
+
+func (g *generator) ballot(ref bool, votes types.Votes, proofs int) *types.Ballot {
+	ballot := &types.Ballot{
+		InnerBallot: types.InnerBallot{
+			Layer:       g.layer(),
+			AtxID:       types.ATXID(g.hash()),
+			OpinionHash: g.hash(),
+		},
+		Votes:             votes,
+		EligibilityProofs: g.proofs(proofs),
+	}
+	if ref {
+		ballot.ActiveSet = g.atxIDs(10)
+		ballot.EpochData = &types.EpochData{
+			ActiveSetHash:    types.ATXIDList(ballot.ActiveSet).Hash(),
+			Beacon:           g.beacon(),
+			EligibilityCount: uint32(proofs),
+		}
+	} else {
+		ballot.RefBallot = types.BallotID(g.hash().ToHash20())
+	}
+	ballot.Signature = g.signer.Sign(signing.BALLOT, ballot.SignedBytes())
+	ballot.SmesherID = g.signer.NodeID()
+	return ballot
+}
+
+func ballotVector(ballot *types.Ballot) (*Vector, error) {
+	if err := ballot.Initialize(); err != nil {
+		return nil, err
+	}
+	return &Vector{
+		Encoded:     hex.EncodeToString(codec.MustEncode(ballot)),
+		ID:          hex.EncodeToString(ballot.ID().Bytes()),
+		Domain:      signing.BALLOT,
+		SignedBytes: hex.EncodeToString(ballot.SignedBytes()),
+		Signature:   hex.EncodeToString(ballot.Signature.Bytes()),
+	}, nil
+}
+
+func refBallot(g *generator) (*Vector, error) {
+	return ballotVector(g.ballot(true, g.votes(3, 1, 1), 5))
+}
+
+func regularBallot(g *generator) (*Vector, error) {
+	return ballotVector(g.ballot(false, g.votes(2, 2, 0), 1))
+}
+
+// largeVotesBallot has a diff that is large enough to catch mistakes in encoding
+// of compact lengths, without bloating committed vectors.
+func largeVotesBallot(g *generator) (*Vector, error) {
+	return ballotVector(g.ballot(false, g.votes(500, 300, 200), 50))
+}
+
+func (g *generator) proposal(ref bool, txs int) (*Vector, error) {
+	proposal := &types.Proposal{
+		InnerProposal: types.InnerProposal{
+			Ballot:   *g.ballot(ref, g.votes(2, 1, 1), 2),
+			MeshHash: g.hash(),
+		},
+	}
+	for i := 0; i < txs; i++ {
+		proposal.TxIDs = append(proposal.TxIDs, types.TransactionID(g.hash()))
+	}
+	proposal.Signature = g.signer.Sign(signing.PROPOSAL, proposal.SignedBytes())
+	if err := proposal.Initialize(); err != nil {
+		return nil, err
+	}
+	return &Vector{
+		Encoded:     hex.EncodeToString(codec.MustEncode(proposal)),
+		ID:          hex.EncodeToString(proposal.ID().Bytes()),
+		Domain:      signing.PROPOSAL,
+		SignedBytes: hex.EncodeToString(proposal.SignedBytes()),
+		Signature:   hex.EncodeToString(proposal.Signature.Bytes()),
+	}, nil
+}
+
+func refProposal(g *generator) (*Vector, error) {
+	return g.proposal(true, 20)
+}
+
+func regularProposal(g *generator) (*Vector, error) {
+	return g.proposal(false, 5)
+}
+
+func emptyProposal(g *generator) (*Vector, error) {
+	return g.proposal(false, 0)
+}
+
+func (g *generator) nipost() *types.NIPost {
+	nodes := make([]types.Hash32, 5)
+	for i := range nodes {
+		nodes[i] = g.hash()
+	}
+	return &types.NIPost{
+		Membership: types.MerkleProof{Nodes: nodes, LeafIndex: g.rng.Uint64()},
+		Post:       g.post(),
+		PostMetadata: &types.PostMetadata{
+			Challenge:     g.bytes(32),
+			LabelsPerUnit: g.rng.Uint64(),
+		},
+	}
+}
+
+func (g *generator) post() *types.Post {
+	return &types.Post{
+		Nonce:   g.rng.Uint32(),
+		Indices: g.bytes(100),
+		Pow:     g.rng.Uint64(),
+	}
+}
+
+func atxVector(g *generator, atx *types.ActivationTx) (*Vector, error) {
+	atx.Signature = g.signer.Sign(signing.ATX, atx.SignedBytes())
+	atx.SmesherID = g.signer.NodeID()
+	if err := atx.Initialize(); err != nil {
+		return nil, err
+	}
+	return &Vector{
+		Encoded:     hex.EncodeToString(codec.MustEncode(atx)),
+		ID:          hex.EncodeToString(atx.ID().Bytes()),
+		Domain:      signing.ATX,
+		SignedBytes: hex.EncodeToString(atx.SignedBytes()),
+		Signature:   hex.EncodeToString(atx.Signature.Bytes()),
+	}, nil
+}
+
+func initialAtx(g *generator) (*Vector, error) {
+	commitment := types.ATXID(g.hash())
+	nodeID := g.signer.NodeID()
+	nonce := types.VRFPostIndex(g.rng.Uint64())
+	return atxVector(g, &types.ActivationTx{
+		InnerActivationTx: types.InnerActivationTx{
+			NIPostChallenge: types.NIPostChallenge{
+				PublishEpoch:   types.EpochID(g.rng.Uint32() % 1000),
+				PositioningATX: types.ATXID(g.hash()),
+				CommitmentATX:  &commitment,
+				InitialPost:    g.post(),
+			},
+			Coinbase: types.GenerateAddress(g.bytes(32)),
+			NumUnits: g.rng.Uint32(),
+			NIPost:   g.nipost(),
+			NodeID:   &nodeID,
+			VRFNonce: &nonce,
+		},
+	})
+}
+
+func subsequentAtx(g *generator) (*Vector, error) {
+	return atxVector(g, &types.ActivationTx{
+		InnerActivationTx: types.InnerActivationTx{
+			NIPostChallenge: types.NIPostChallenge{
+				PublishEpoch:   types.EpochID(g.rng.Uint32() % 1000),
+				Sequence:       g.rng.Uint64(),
+				PrevATXID:      types.ATXID(g.hash()),
+				PositioningATX: types.ATXID(g.hash()),
+			},
+			Coinbase: types.GenerateAddress(g.bytes(32)),
+			NumUnits: g.rng.Uint32(),
+			NIPost:   g.nipost(),
+		},
+	})
+}
+
+func (g *generator) message(inner *hare.InnerMessage) *hare.Message {
+	inner.ValuesHash = hare.NewSet(inner.Values).ID()
+	msg := &hare.Message{
+		InnerMessage: inner,
+		SmesherID:    g.signer.NodeID(),
+		Eligibility: types.HareEligibility{
+			Proof: g.vrf(),
+			Count: uint16(g.rng.Uint32()),
+		},
+	}
+	msg.Signature = g.signer.Sign(signing.HARE, msg.SignedBytes())
+	return msg
+}
+
+func (g *generator) values(n int) []types.ProposalID {
+	values := make([]types.ProposalID, n)
+	for i := range values {
+		values[i] = types.ProposalID(g.hash().ToHash20())
+	}
+	return values
+}
+
+func (g *generator) aggregated(layer types.LayerID, round uint32, typ hare.MessageType, n int) *hare.AggregatedMessages {
+	agg := &hare.AggregatedMessages{}
+	for i := 0; i < n; i++ {
+		agg.Messages = append(agg.Messages, *g.message(&hare.InnerMessage{
+			Layer:  layer,
+			Round:  round,
+			Type:   typ,
+			Values: g.values(3),
+		}))
+	}
+	return agg
+}
+
+func messageVector(msg *hare.Message) (*Vector, error) {
+	return &Vector{
+		Encoded:     hex.EncodeToString(msg.Bytes()),
+		ID:          hex.EncodeToString(msg.InnerMessage.HashBytes()),
+		Domain:      signing.HARE,
+		SignedBytes: hex.EncodeToString(msg.SignedBytes()),
+		Signature:   hex.EncodeToString(msg.Signature.Bytes()),
+	}, nil
+}
+
+// message types are not exported by hare, values are copied from hare/haretypes.go.
+const (
+	statusType   hare.MessageType = 0
+	proposalType hare.MessageType = 1
+	commitType   hare.MessageType = 2
+	notifyType   hare.MessageType = 3
+	preType      hare.MessageType = 10
+)
+
+func preroundMessage(g *generator) (*Vector, error) {
+	msg := g.message(&hare.InnerMessage{
+		Layer:  g.layer(),
+		Round:  0,
+		Type:   preType,
+		Values: g.values(10),
+	})
+	// preround messages refer to the set by its hash
+	msg.Values = nil
+	return messageVector(msg)
+}
+
+func proposalMessage(g *generator) (*Vector, error) {
+	layer := g.layer()
+	return messageVector(g.message(&hare.InnerMessage{
+		Layer:  layer,
+		Round:  3,
+		Type:   proposalType,
+		Values: g.values(3),
+		Svp:    g.aggregated(layer, 2, statusType, 3),
+	}))
+}
+
+func notifyMessage(g *generator) (*Vector, error) {
+	layer := g.layer()
+	values := g.values(3)
+	return messageVector(g.message(&hare.InnerMessage{
+		Layer:          layer,
+		Round:          5,
+		Type:           notifyType,
+		CommittedRound: 3,
+		Values:         values,
+		Cert: &hare.Certificate{
+			Values:  values,
+			AggMsgs: g.aggregated(layer, 4, commitType, 3),
+		},
+	}))
+}
+
+// legacyMessage returns the message signed as before hare.SetHashVersion.
+func (g *generator) legacyMessage(inner *hare.LegacyInnerMessage) (*hare.LegacyMessage, *hare.Message, error) {
+	msg := &hare.LegacyMessage{
+		InnerMessage: inner,
+		SmesherID:    g.signer.NodeID(),
+		Eligibility: types.HareEligibility{
+			Proof: g.vrf(),
+			Count: uint16(g.rng.Uint32()),
+		},
+	}
+	buf, err := codec.Encode(msg)
+	if err != nil {
+		return nil, nil, err
+	}
+	decoded, err := hare.LegacyMessageFromBuffer(buf)
+	if err != nil {
+		return nil, nil, err
+	}
+	msg.Signature = g.signer.Sign(signing.HARE, decoded.SignedBytes())
+	decoded.Signature = msg.Signature
+	return msg, decoded, nil
+}
+
+func (g *generator) legacyAggregated(layer types.LayerID, round uint32, typ hare.MessageType, n int) (*hare.LegacyAggregatedMessages, error) {
+	agg := &hare.LegacyAggregatedMessages{}
+	for i := 0; i < n; i++ {
+		msg, _, err := g.legacyMessage(&hare.LegacyInnerMessage{
+			Layer:  layer,
+			Round:  round,
+			Type:   typ,
+			Values: g.values(3),
+		})
+		if err != nil {
+			return nil, err
+		}
+		agg.Messages = append(agg.Messages, *msg)
+	}
+	return agg, nil
+}
+
+func legacyMessageVector(msg *hare.LegacyMessage, decoded *hare.Message, err error) (*Vector, error) {
+	if err != nil {
+		return nil, err
+	}
+	buf, err := codec.Encode(msg)
+	if err != nil {
+		return nil, err
+	}
+	return &Vector{
+		Encoded:     hex.EncodeToString(buf),
+		ID:          hex.EncodeToString(decoded.InnerMessage.HashBytes()),
+		Domain:      signing.HARE,
+		SignedBytes: hex.EncodeToString(decoded.SignedBytes()),
+		Signature:   hex.EncodeToString(msg.Signature.Bytes()),
+	}, nil
+}
+
+func legacyPreroundMessage(g *generator) (*Vector, error) {
+	return legacyMessageVector(g.legacyMessage(&hare.LegacyInnerMessage{
+		Layer:  g.layer(),
+		Round:  0,
+		Type:   preType,
+		Values: g.values(10),
+	}))
+}
+
+func legacyProposalMessage(g *generator) (*Vector, error) {
+	layer := g.layer()
+	values := g.values(3)
+	svp, err := g.legacyAggregated(layer, 2, statusType, 3)
+	if err != nil {
+		return nil, err
+	}
+	return legacyMessageVector(g.legacyMessage(&hare.LegacyInnerMessage{
+		Layer:  layer,
+		Round:  3,
+		Type:   proposalType,
+		Values: values,
+		Svp:    svp,
+	}))
+}
+
+func legacyNotifyMessage(g *generator) (*Vector, error) {
+	layer := g.layer()
+	values := g.values(3)
+	commits, err := g.legacyAggregated(layer, 4, commitType, 3)
+	if err != nil {
+		return nil, err
+	}
+	return legacyMessageVector(g.legacyMessage(&hare.LegacyInnerMessage{
+		Layer:          layer,
+		Round:          5,
+		Type:           notifyType,
+		CommittedRound: 3,
+		Values:         values,
+		Cert: &hare.LegacyCertificate{
+			Values:  values,
+			AggMsgs: commits,
+		},
+	}))
+}
@@ -0,0 +1,158 @@
+// Package testvectors contains golden vectors for consensus encodings, ids and signatures.
+// Vectors are generated deterministically by cmd/genvectors and are meant to be used by
+// alternative implementations to check byte-exact compatibility.
+package testvectors
+
+import (
+	"embed"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+
+	"github.com/spacemeshos/go-spacemesh/signing"
+)
+
+//go:generate go run ../cmd/genvectors -out data
+
+// DefaultSeed is used to generate committed vectors.
+const DefaultSeed int64 = 20230701
+
+//go:embed data/*.json
+var data embed.FS
+
+// Vector is a single encoded object.
+type Vector struct {
+	// Name of the case that produced the vector, unique within the File.
+	Name string `json:"name"`
+	// Seed is the seed of the case, mixed with the seed of the File.
+	Seed int64 `json:"seed"`
+	// Encoded object in hex.
+	Encoded string `json:"encoded"`
+	// ID is the id of the object in hex.
+	ID string `json:"id"`
+	// Domain of the signature, prepended to SignedBytes before signing.
+	Domain signing.Domain `json:"domain"`
+	// SignedBytes in hex.
+	SignedBytes string `json:"signed_bytes"`
+	// Signature of the SignedBytes by the key of the File.
+	Signature string `json:"signature"`
+}
+
+// File is a collection of vectors for a single type.
+type File struct {
+	Type       string    `json:"type"`
+	Seed       int64     `json:"seed"`
+	PrivateKey string    `json:"private_key"`
+	PublicKey  string    `json:"public_key"`
+	Vectors    []*Vector `json:"vectors"`
+}
+
+// Signer returns signer for the private key of the File.
+func (f *File) Signer() (*signing.EdSigner, error) {
+	key, err := hex.DecodeString(f.PrivateKey)
+	if err != nil {
+		return nil, fmt.Errorf("decode private key: %w", err)
+	}
+	return signing.NewEdSigner(signing.WithPrivateKey(key))
+}
+
+// Types returns types of the objects that have vectors.
+func Types() []string {
+	types := make([]string, 0, len(suites))
+	for _, s := range suites {
+		types = append(types, s.typ)
+	}
+	return types
+}
+
+// Generate vectors for the type. Key of the File is derived from the seed,
+// every case uses its own seed mixed with the seed of the File, therefore adding
+// a new case doesn't change existing vectors.
+func Generate(typ string, seed int64) (*File, error) {
+	for _, s := range suites {
+		if s.typ != typ {
+			continue
+		}
+		signer, err := signing.NewEdSigner(signing.WithKeyFromRand(rand.New(rand.NewSource(seed))))
+		if err != nil {
+			return nil, err
+		}
+		file := &File{
+			Type:       typ,
+			Seed:       seed,
+			PrivateKey: hex.EncodeToString(signer.PrivateKey()),
+			PublicKey:  signer.NodeID().String(),
+		}
+		for _, c := range s.cases {
+			g := &generator{rng: rand.New(rand.NewSource(seed + c.seed)), signer: signer}
+			vector, err := c.gen(g)
+			if err != nil {
+				return nil, fmt.Errorf("%s/%s: %w", typ, c.name, err)
+			}
+			vector.Name = c.name
+			vector.Seed = c.seed
+			file.Vectors = append(file.Vectors, vector)
+		}
+		return file, nil
+	}
+	return nil, fmt.Errorf("unknown type %s", typ)
+}
+
+// Marshal File in the same format as committed vectors.
+func Marshal(f *File) ([]byte, error) {
+	buf, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(buf, '\n'), nil
+}
+
+// Write vectors for all types to the directory.
+func Write(dir string, seed int64) error {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return fmt.Errorf("create %s: %w", dir, err)
+	}
+	for _, typ := range Types() {
+		file, err := Generate(typ, seed)
+		if err != nil {
+			return err
+		}
+		buf, err := Marshal(file)
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(filepath.Join(dir, typ+".json"), buf, 0o600); err != nil {
+			return fmt.Errorf("write %s vectors: %w", typ, err)
+		}
+	}
+	return nil
+}
+
+// Load committed vectors for the type.
+func Load(typ string) (*File, error) {
+	buf, err := data.ReadFile("data/" + typ + ".json")
+	if err != nil {
+		return nil, fmt.Errorf("read %s vectors: %w", typ, err)
+	}
+	return unmarshal(buf)
+}
+
+// LoadFile loads vectors from the file.
+func LoadFile(path string) (*File, error) {
+	buf, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read %s: %w", path, err)
+	}
+	return unmarshal(buf)
+}
+
+func unmarshal(buf []byte) (*File, error) {
+	var file File
+	if err := json.Unmarshal(buf, &file); err != nil {
+		return nil, fmt.Errorf("decode vectors: %w", err)
+	}
+	return &file, nil
+}
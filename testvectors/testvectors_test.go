@@ -0,0 +1,108 @@
+package testvectors
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/spacemeshos/go-spacemesh/codec"
+	"github.com/spacemeshos/go-spacemesh/common/types"
+	"github.com/spacemeshos/go-spacemesh/hare"
+	"github.com/spacemeshos/go-spacemesh/signing"
+)
+
+// TestCommitted fails if encoding of any type changed. If the change is intentional
+// regenerate vectors with `go generate ./testvectors`.
+func TestCommitted(t *testing.T) {
+	for _, typ := range Types() {
+		typ := typ
+		t.Run(typ, func(t *testing.T) {
+			expected, err := data.ReadFile("data/" + typ + ".json")
+			require.NoError(t, err)
+			file, err := Generate(typ, DefaultSeed)
+			require.NoError(t, err)
+			generated, err := Marshal(file)
+			require.NoError(t, err)
+			require.Equal(t, string(expected), string(generated),
+				"encoding of %s changed, run `go generate ./testvectors` if it is intentional", typ)
+		})
+	}
+}
+
+func TestSeed(t *testing.T) {
+	first, err := Generate("ballot", DefaultSeed)
+	require.NoError(t, err)
+	second, err := Generate("ballot", DefaultSeed+1)
+	require.NoError(t, err)
+	require.NotEqual(t, first.PublicKey, second.PublicKey)
+	for i := range first.Vectors {
+		require.NotEqual(t, first.Vectors[i].Encoded, second.Vectors[i].Encoded)
+	}
+}
+
+func decodeHex(tb testing.TB, s string) []byte {
+	tb.Helper()
+	buf, err := hex.DecodeString(s)
+	require.NoError(tb, err)
+	return buf
+}
+
+func TestDecode(t *testing.T) {
+	verifier, err := signing.NewEdVerifier()
+	require.NoError(t, err)
+	for _, tc := range []struct {
+		typ    string
+		decode func(*testing.T, []byte) (id []byte, signed []byte)
+	}{
+		{"ballot", func(t *testing.T, buf []byte) ([]byte, []byte) {
+			var ballot types.Ballot
+			require.NoError(t, codec.DecodeStrict(buf, &ballot))
+			require.NoError(t, ballot.Initialize())
+			return ballot.ID().Bytes(), ballot.SignedBytes()
+		}},
+		{"proposal", func(t *testing.T, buf []byte) ([]byte, []byte) {
+			var proposal types.Proposal
+			require.NoError(t, codec.DecodeStrict(buf, &proposal))
+			require.NoError(t, proposal.Initialize())
+			return proposal.ID().Bytes(), proposal.SignedBytes()
+		}},
+		{"atx", func(t *testing.T, buf []byte) ([]byte, []byte) {
+			var atx types.ActivationTx
+			require.NoError(t, codec.DecodeStrict(buf, &atx))
+			require.NoError(t, atx.Initialize())
+			return atx.ID().Bytes(), atx.SignedBytes()
+		}},
+		{"hare", func(t *testing.T, buf []byte) ([]byte, []byte) {
+			msg, err := hare.MessageFromBuffer(buf)
+			require.NoError(t, err)
+			return msg.InnerMessage.HashBytes(), msg.SignedBytes()
+		}},
+		{"hare-legacy", func(t *testing.T, buf []byte) ([]byte, []byte) {
+			msg, err := hare.LegacyMessageFromBuffer(buf)
+			require.NoError(t, err)
+			require.Equal(t, buf, msg.Bytes())
+			return msg.InnerMessage.HashBytes(), msg.SignedBytes()
+		}},
+	} {
+		tc := tc
+		t.Run(tc.typ, func(t *testing.T) {
+			file, err := Load(tc.typ)
+			require.NoError(t, err)
+			require.Equal(t, tc.typ, file.Type)
+			signer, err := file.Signer()
+			require.NoError(t, err)
+			require.Equal(t, file.PublicKey, signer.NodeID().String())
+			require.NotEmpty(t, file.Vectors)
+			for _, vector := range file.Vectors {
+				id, signed := tc.decode(t, decodeHex(t, vector.Encoded))
+				require.Equal(t, vector.ID, hex.EncodeToString(id), vector.Name)
+				require.Equal(t, vector.SignedBytes, hex.EncodeToString(signed), vector.Name)
+
+				var sig types.EdSignature
+				copy(sig[:], decodeHex(t, vector.Signature))
+				require.True(t, verifier.Verify(vector.Domain, signer.NodeID(), signed, sig), vector.Name)
+			}
+		})
+	}
+}
@@ -159,6 +159,11 @@ func (t *NodeClock) tick() {
 	t.minLayer = layer.Add(1)
 }
 
+// Now returns the current local time.
+func (t *NodeClock) Now() time.Time {
+	return t.clock.Now()
+}
+
 // CurrentLayer gets the current layer.
 func (t *NodeClock) CurrentLayer() types.LayerID {
 	return t.TimeToLayer(t.clock.Now())
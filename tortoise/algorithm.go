@@ -10,7 +10,10 @@ import (
 
 	"github.com/spacemeshos/go-spacemesh/common/types"
 	"github.com/spacemeshos/go-spacemesh/common/types/result"
+	"github.com/spacemeshos/go-spacemesh/datastore"
 	"github.com/spacemeshos/go-spacemesh/log"
+	"github.com/spacemeshos/go-spacemesh/system"
+	tmetrics "github.com/spacemeshos/go-spacemesh/tortoise/metrics"
 )
 
 // Config for protocol parameters.
@@ -22,8 +25,28 @@ type Config struct {
 	MaxExceptions int    `mapstructure:"tortoise-max-exceptions"` // if candidate for base ballot has more than max exceptions it will be ignored
 	// number of layers to delay votes for blocks with bad beacon values during self-healing. ideally a full epoch.
 	BadBeaconVoteDelayLayers uint32 `mapstructure:"tortoise-delay-layers"`
+	// CoinflipTheta is the band around zero margin, in percents of the global threshold, where
+	// validity of the block is decided by the weak coin during healing. Zero disables the coin.
+	CoinflipTheta uint32 `mapstructure:"tortoise-coinflip-theta"`
+	// HealingDistance is the number of layers after the block layer when the weak coin of that later
+	// layer decides validity of the block that is still within CoinflipTheta band.
+	HealingDistance uint32 `mapstructure:"tortoise-healing-distance"`
+	// QueueSize is the number of ballots, blocks and hare outputs that are queued
+	// before they are applied to the state. Queue is applied by TallyVotes and other
+	// calls that read the state, or by the producer once it is full. Zero applies every input immediately.
+	QueueSize int `mapstructure:"tortoise-queue-size"`
 	// EnableTracer will write tortoise traces to the stderr.
 	EnableTracer bool `mapstructure:"tortoise-enable-tracer"`
+	// OpinionReportFile is the path where node writes the opinion report for layers
+	// in the sliding window on shutdown. Report is not written if empty.
+	OpinionReportFile string `mapstructure:"tortoise-opinion-report"`
+	// DumpStateFile is the path where node writes the in-memory state of the tortoise
+	// on shutdown, see DumpState. State is not written if empty.
+	DumpStateFile string `mapstructure:"tortoise-dump-state"`
+	// LeanVerifying keeps only ids and aggregated weight of the ballots counted by the verifying tortoise,
+	// votes are decoded from the database when the full tortoise is needed.
+	// It is used only by the tortoise recovered from the database.
+	LeanVerifying bool `mapstructure:"tortoise-lean-verifying"`
 	// MinimalActiveSetWeight denotes weight that will replace weight
 	// recorded in the first ballot, if that weight is less than minimal
 	// for purposes of eligibility computation.
@@ -41,6 +64,8 @@ func DefaultConfig() Config {
 		WindowSize:               1000,
 		BadBeaconVoteDelayLayers: 0,
 		MaxExceptions:            50 * 100, // 100 layers of average size
+		HealingDistance:          10,
+		QueueSize:                1000,
 	}
 }
 
@@ -53,6 +78,16 @@ type Tortoise struct {
 	mu     sync.Mutex
 	trtl   *turtle
 	tracer *tracer
+
+	// qmu protects queue, it is never held while waiting for mu.
+	qmu   sync.Mutex
+	queue []input
+
+	// db and beacons are set by Recover, they are required to rerun tortoise
+	// and to persist tortoise state.
+	db        *datastore.CachedDB
+	beacons   system.BeaconGetter
+	rerunning bool
 }
 
 // Opt for configuring tortoise.
@@ -96,6 +131,7 @@ func New(opts ...Opt) (*Tortoise, error) {
 		)
 	}
 	t.trtl = newTurtle(t.logger, t.cfg)
+	windowLayers.Set(float64(t.cfg.WindowSize))
 	if t.tracer != nil {
 		t.tracer.On(&ConfigTrace{
 			Hdist:                    t.cfg.Hdist,
@@ -103,6 +139,8 @@ func New(opts ...Opt) (*Tortoise, error) {
 			WindowSize:               t.cfg.WindowSize,
 			MaxExceptions:            uint32(t.cfg.MaxExceptions),
 			BadBeaconVoteDelayLayers: t.cfg.BadBeaconVoteDelayLayers,
+			CoinflipTheta:            t.cfg.CoinflipTheta,
+			HealingDistance:          t.cfg.HealingDistance,
 			LayerSize:                t.cfg.LayerSize,
 			EpochSize:                types.GetLayersPerEpoch(),
 			EffectiveGenesis:         types.GetEffectiveGenesis().Uint32(),
@@ -115,6 +153,7 @@ func New(opts ...Opt) (*Tortoise, error) {
 func (t *Tortoise) LatestComplete() types.LayerID {
 	t.mu.Lock()
 	defer t.mu.Unlock()
+	t.drain()
 	return t.trtl.verified
 }
 
@@ -135,14 +174,17 @@ func (t *Tortoise) OnWeakCoin(lid types.LayerID, coin bool) {
 	} else {
 		layer.coinflip = against
 	}
+	t.trtl.markDirty(lid)
 	if t.tracer != nil {
 		t.tracer.On(&WeakCoinTrace{Layer: lid, Coin: coin})
 	}
 }
 
 // OnMalfeasance registers node id as malfeasent.
-// - ballots from this id will have zero weight
-// - atxs - will not be counted towards global/local threhsolds
+//   - ballots from this id will have zero weight, weight of ballots that were already
+//     counted is removed from tallies and layers are verified again
+//   - atxs - will not be counted towards global/local threhsolds
+//
 // If node registers equivocating ballot/atx it should
 // call OnMalfeasance before storing ballot/atx.
 func (t *Tortoise) OnMalfeasance(id types.NodeID) {
@@ -151,8 +193,29 @@ func (t *Tortoise) OnMalfeasance(id types.NodeID) {
 	if t.trtl.isMalfeasant(id) {
 		return
 	}
-	t.logger.Debug("on malfeasence", zap.Stringer("id", id))
-	t.trtl.makrMalfeasant(id)
+	var reversed int
+	if err := t.withVotes(func() error {
+		var err error
+		reversed, err = t.trtl.onMalfeasance(id)
+		return err
+	}); err != nil {
+		errorsCounter.Inc()
+		t.logger.Error("failed to reverse ballots of malfeasant identity",
+			zap.Stringer("id", id),
+			zap.Error(err),
+		)
+		return
+	}
+	t.logger.Debug("on malfeasence",
+		zap.Stringer("id", id),
+		zap.Int("reversed ballots", reversed),
+	)
+	if reversed > 0 {
+		if err := t.withVotes(t.trtl.verifyLayers); err != nil {
+			errorsCounter.Inc()
+			t.logger.Error("failed to verify layers", zap.Error(err))
+		}
+	}
 	malfeasantNumber.Inc()
 	if t.tracer != nil {
 		t.tracer.On(&MalfeasanceTrace{ID: id})
@@ -180,6 +243,10 @@ func (t *Tortoise) OnBeacon(eid types.EpochID, beacon types.Beacon) {
 
 type encodeConf struct {
 	current *types.LayerID
+	// maxExceptions lowers the configured limit of exceptions.
+	maxExceptions *int
+	// trace is collected only if requested by EncodeVotesWithTrace or if tracing is enabled.
+	trace *VotesTrace
 }
 
 // EncodeVotesOpts is for configuring EncodeVotes options.
@@ -197,34 +264,75 @@ func EncodeVotesWithCurrent(current types.LayerID) EncodeVotesOpts {
 	}
 }
 
+// EncodeVotesWithMaxExceptions lowers the number of exceptions that can be encoded.
+// Exceptions above the limit are replaced with abstain votes, starting from the oldest layers.
+func EncodeVotesWithMaxExceptions(limit int) EncodeVotesOpts {
+	return func(conf *encodeConf) {
+		conf.maxExceptions = &limit
+	}
+}
+
 // EncodeVotes chooses a base ballot and creates a differences list. needs the hare results for latest layers.
 func (t *Tortoise) EncodeVotes(ctx context.Context, opts ...EncodeVotesOpts) (*types.Opinion, error) {
 	start := time.Now()
 	t.mu.Lock()
 	defer t.mu.Unlock()
 	waitEncodeVotes.Observe(float64(time.Since(start).Nanoseconds()))
+	t.drain()
 	start = time.Now()
 	conf := &encodeConf{}
 	for _, opt := range opts {
 		opt(conf)
 	}
-	opinion, err := t.trtl.EncodeVotes(ctx, conf)
+	return t.encodeVotes(ctx, start, conf)
+}
+
+// EncodeVotesWithTrace encodes votes same as EncodeVotes for the current layer and additionally
+// returns a trace with candidates for the base ballot, local opinion about blocks and reasons for exceptions.
+// It is meant for debugging and is more expensive than EncodeVotes.
+func (t *Tortoise) EncodeVotesWithTrace(ctx context.Context, current types.LayerID) (*types.Opinion, *VotesTrace, error) {
+	start := time.Now()
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	waitEncodeVotes.Observe(float64(time.Since(start).Nanoseconds()))
+	t.drain()
+	start = time.Now()
+	conf := &encodeConf{current: &current, trace: &VotesTrace{Layer: current}}
+	opinion, err := t.encodeVotes(ctx, start, conf)
+	return opinion, conf.trace, err
+}
+
+func (t *Tortoise) encodeVotes(ctx context.Context, start time.Time, conf *encodeConf) (*types.Opinion, error) {
+	layer := t.trtl.last + 1
+	if conf.current != nil {
+		layer = *conf.current
+	}
+	if t.tracer != nil && conf.trace == nil {
+		conf.trace = &VotesTrace{Layer: layer}
+	}
+	var opinion *types.Opinion
+	err := t.withVotes(func() error {
+		if conf.trace != nil {
+			// candidates are traced again after votes are restored
+			*conf.trace = VotesTrace{Layer: conf.trace.Layer}
+		}
+		var err error
+		opinion, err = t.trtl.EncodeVotes(ctx, conf)
+		return err
+	})
 	executeEncodeVotes.Observe(float64(time.Since(start).Nanoseconds()))
 	if err != nil {
 		errorsCounter.Inc()
 	}
 	if t.tracer != nil {
 		event := &EncodeVotesTrace{
+			Layer:   layer,
 			Opinion: opinion,
+			Votes:   conf.trace,
 		}
 		if err != nil {
 			event.Error = err.Error()
 		}
-		if conf.current != nil {
-			event.Layer = *conf.current
-		} else {
-			event.Layer = t.trtl.last + 1
-		}
 		t.tracer.On(event)
 	}
 	return opinion, err
@@ -236,9 +344,22 @@ func (t *Tortoise) TallyVotes(ctx context.Context, lid types.LayerID) {
 	t.mu.Lock()
 	defer t.mu.Unlock()
 	waitTallyVotes.Observe(float64(time.Since(start).Nanoseconds()))
+	t.drain()
 	start = time.Now()
-	t.trtl.onLayer(ctx, lid)
+	if err := t.withVotes(func() error {
+		return t.trtl.onLayer(ctx, lid)
+	}); err != nil {
+		errorsCounter.Inc()
+		t.logger.Error("failed to tally votes",
+			zap.Uint32("lid", lid.Uint32()),
+			zap.Error(err),
+		)
+	}
 	executeTallyVotes.Observe(float64(time.Since(start).Nanoseconds()))
+	tmetrics.TallyVotesDuration.Observe(time.Since(start).Seconds())
+	if t.db != nil {
+		t.persist(ctx)
+	}
 	if t.tracer != nil {
 		t.tracer.On(&TallyTrace{Layer: lid})
 	}
@@ -257,49 +378,25 @@ func (t *Tortoise) OnAtx(header *types.AtxTortoiseData) {
 }
 
 // OnBlock updates tortoise with information that data is available locally.
+// Block is queued and applied before the next call that reads the state.
 func (t *Tortoise) OnBlock(header types.BlockHeader) {
-	start := time.Now()
-	t.mu.Lock()
-	defer t.mu.Unlock()
-	waitBlockDuration.Observe(float64(time.Since(start).Nanoseconds()))
-	t.trtl.onBlock(header, true, false)
-	if t.tracer != nil {
-		t.tracer.On(&BlockTrace{Header: header})
-	}
+	t.enqueue(input{block: &header})
 }
 
 // OnValidBlock inserts block, updates that data is stored locally
 // and that block was previously considered valid by tortoise.
+// Block is queued and applied before the next call that reads the state.
 func (t *Tortoise) OnValidBlock(header types.BlockHeader) {
-	start := time.Now()
-	t.mu.Lock()
-	defer t.mu.Unlock()
-	waitBlockDuration.Observe(float64(time.Since(start).Nanoseconds()))
-	t.trtl.onBlock(header, true, true)
-	if t.tracer != nil {
-		t.tracer.On(&BlockTrace{Header: header, Valid: true})
-	}
+	t.enqueue(input{block: &header, valid: true})
 }
 
 // OnBallot should be called every time new ballot is received.
-// Dependencies (base ballot, ref ballot, active set and its own atx) must
-// be processed before ballot.
+// Dependencies (active set and its own atx) must be processed before ballot.
+//
+// Ballot is queued and applied before the next call that reads the state. Ballot is not applied
+// before its base and reference ballots, therefore they may be received in any order.
 func (t *Tortoise) OnBallot(ballot *types.BallotTortoiseData) {
-	t.mu.Lock()
-	defer t.mu.Unlock()
-	err := t.trtl.onBallot(ballot)
-	if err != nil {
-		errorsCounter.Inc()
-		t.logger.Error("failed to save state from ballot",
-			zap.Stringer("ballot", ballot.ID),
-			zap.Error(err))
-	}
-	if t.tracer != nil {
-		t.tracer.On(&BallotTrace{Ballot: ballot})
-	}
-	if t.tracer != nil {
-		t.tracer.On(&BallotTrace{Ballot: ballot})
-	}
+	t.enqueue(input{ballot: ballot})
 }
 
 // DecodedBallot created after unwrapping exceptions list and computing internal opinion.
@@ -318,6 +415,7 @@ func (t *Tortoise) DecodeBallot(ballot *types.BallotTortoiseData) (*DecodedBallo
 	t.mu.Lock()
 	defer t.mu.Unlock()
 	waitBallotDuration.Observe(float64(time.Since(start).Nanoseconds()))
+	t.drain()
 	decoded, err := t.decodeBallot(ballot)
 	if t.tracer != nil {
 		ev := &DecodeBallotTrace{Ballot: ballot}
@@ -330,7 +428,15 @@ func (t *Tortoise) DecodeBallot(ballot *types.BallotTortoiseData) (*DecodedBallo
 }
 
 func (t *Tortoise) decodeBallot(ballot *types.BallotTortoiseData) (*DecodedBallot, error) {
-	info, min, err := t.trtl.decodeBallot(ballot)
+	var (
+		info *ballotInfo
+		min  types.LayerID
+	)
+	err := t.withVotes(func() error {
+		var err error
+		info, min, err = t.trtl.decodeBallot(ballot)
+		return err
+	})
 	if err != nil {
 		errorsCounter.Inc()
 		return nil, err
@@ -355,6 +461,22 @@ func (t *Tortoise) StoreBallot(decoded *DecodedBallot) error {
 	t.mu.Lock()
 	defer t.mu.Unlock()
 	waitBallotDuration.Observe(float64(time.Since(start).Nanoseconds()))
+	t.drain()
+	if decoded.info.partial && !t.trtl.collapsible(decoded.info.layer) {
+		// state changed since the ballot was decoded relative to the lean base,
+		// it is decoded again as votes before the base layer are needed
+		if err := t.restoreVotes(); err != nil {
+			return err
+		}
+		info, min, err := t.trtl.decodeBallot(decoded.BallotTortoiseData)
+		if err != nil {
+			return err
+		}
+		if info == nil {
+			return fmt.Errorf("can't decode ballot %s", decoded.ID)
+		}
+		decoded.info, decoded.minHint = info, min
+	}
 	if decoded.Malicious {
 		decoded.info.malicious = true
 	}
@@ -375,15 +497,9 @@ func (t *Tortoise) StoreBallot(decoded *DecodedBallot) error {
 //
 // This method should be called with EmptyBlockID if node received proof of malicious behavior,
 // such as signing same block id by members of the same committee.
+// Hare output is queued and applied before the next call that reads the state.
 func (t *Tortoise) OnHareOutput(lid types.LayerID, bid types.BlockID) {
-	start := time.Now()
-	t.mu.Lock()
-	defer t.mu.Unlock()
-	waitHareOutputDuration.Observe(float64(time.Since(start).Nanoseconds()))
-	t.trtl.onHareOutput(lid, bid)
-	if t.tracer != nil {
-		t.tracer.On(&HareTrace{Layer: lid, Vote: bid})
-	}
+	t.enqueue(input{hare: &hareOutput{layer: lid, block: bid}})
 }
 
 // GetMissingActiveSet returns unknown atxs from the original list. It is done for a specific epoch
@@ -406,9 +522,11 @@ func (t *Tortoise) GetMissingActiveSet(epoch types.EpochID, atxs []types.ATXID)
 }
 
 // Updates returns list of layers where opinion was changed since previous call.
+// Blocks with validity that differs from the validity returned by the previous call are marked as changed.
 func (t *Tortoise) Updates() []result.Layer {
 	t.mu.Lock()
 	defer t.mu.Unlock()
+	t.drain()
 	if t.trtl.pending == 0 {
 		return nil
 	}
@@ -420,6 +538,12 @@ func (t *Tortoise) Updates() []result.Layer {
 			zap.Error(err),
 		)
 	}
+	for i := range rst {
+		for j, block := range t.trtl.layer(rst[i].Layer).blocks {
+			rst[i].Blocks[j].Changed = block.validity != block.reported
+			block.reported = block.validity
+		}
+	}
 	t.trtl.pending = 0
 	if t.tracer != nil {
 		t.tracer.On(&UpdatesTrace{ResultsTrace{
@@ -434,6 +558,7 @@ func (t *Tortoise) Updates() []result.Layer {
 func (t *Tortoise) Results(from, to types.LayerID) ([]result.Layer, error) {
 	t.mu.Lock()
 	defer t.mu.Unlock()
+	t.drain()
 	rst, err := t.results(from, to)
 	if t.tracer != nil {
 		ev := &ResultsTrace{
@@ -449,10 +574,24 @@ func (t *Tortoise) Results(from, to types.LayerID) ([]result.Layer, error) {
 }
 
 func (t *Tortoise) results(from, to types.LayerID) ([]result.Layer, error) {
+	var rst []result.Layer
 	if from <= t.trtl.evicted {
-		return nil, fmt.Errorf("requested layer %d is before evicted %d", from, t.trtl.evicted)
+		if t.db == nil {
+			return nil, fmt.Errorf("requested layer %d is before evicted %d", from, t.trtl.evicted)
+		}
+		evicted, err := loadEvicted(t.db, from, types.MinLayer(to, t.trtl.evicted))
+		if err != nil {
+			return nil, err
+		}
+		rst = evicted
+		from = t.trtl.evicted.Add(1)
+	}
+	if to < from {
+		return rst, nil
+	}
+	if rst == nil {
+		rst = make([]result.Layer, 0, to-from+1)
 	}
-	rst := make([]result.Layer, 0, to-from)
 	for lid := from; lid <= to; lid++ {
 		layer := t.trtl.layer(lid)
 		blocks := make([]result.Block, 0, len(layer.blocks))
@@ -494,6 +633,7 @@ const (
 func (t *Tortoise) Mode() Mode {
 	t.mu.Lock()
 	defer t.mu.Unlock()
+	t.drain()
 	if t.trtl.isFull {
 		return Full
 	}
@@ -0,0 +1,133 @@
+package tortoise
+
+import (
+	"bytes"
+	"sort"
+
+	"github.com/spacemeshos/go-spacemesh/common/types"
+)
+
+// BaseVerdict classifies a ballot as a candidate for the base ballot.
+type BaseVerdict string
+
+const (
+	// VerdictGood is a ballot with opinion equal to the local opinion.
+	// The highest good ballot is selected as a base ballot.
+	VerdictGood BaseVerdict = "good"
+	// VerdictAbandoned is a ballot that can be used as a base ballot with exceptions,
+	// it is considered only if there are no good ballots in the window.
+	VerdictAbandoned BaseVerdict = "abandoned"
+	// VerdictBad is a ballot that is never used as a base ballot.
+	VerdictBad BaseVerdict = "bad"
+)
+
+// reasons for abandoned and bad verdicts.
+const (
+	baseReasonBadBeacon    = "bad beacon"
+	baseReasonUnverified   = "exception references unverified layer"
+	baseReasonInconsistent = "inconsistent with local opinion"
+	baseReasonMalicious    = "malicious"
+	baseReasonAbstain      = "abstains on terminated layer"
+	baseReasonGenesis      = "genesis"
+)
+
+type baseCandidate struct {
+	ballot  *ballotInfo
+	verdict BaseVerdict
+	reason  string
+}
+
+func verdictRank(verdict BaseVerdict) int {
+	switch verdict {
+	case VerdictGood:
+		return 0
+	case VerdictAbandoned:
+		return 1
+	}
+	return 2
+}
+
+// lessCandidate defines the order in which candidates are considered as a base ballot:
+// good before abandoned before bad, higher layer first, and lower ballot id first for ballots
+// from the same layer. Order doesn't depend on the order in which ballots were received.
+func lessCandidate(a, b *baseCandidate) bool {
+	if ra, rb := verdictRank(a.verdict), verdictRank(b.verdict); ra != rb {
+		return ra < rb
+	}
+	if a.ballot.layer != b.ballot.layer {
+		return a.ballot.layer > b.ballot.layer
+	}
+	return bytes.Compare(a.ballot.id[:], b.ballot.id[:]) < 0
+}
+
+// orderCandidates sorts candidates in the order they should be considered as a base ballot.
+func orderCandidates(candidates []*baseCandidate) {
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return lessCandidate(candidates[i], candidates[j])
+	})
+}
+
+// classifyBase returns verdict for the ballot as a candidate for the base ballot.
+// It doesn't modify the state.
+func (t *turtle) classifyBase(ballot *ballotInfo) (BaseVerdict, string) {
+	if ballot.layer == types.GetEffectiveGenesis() && ballot.id == types.EmptyBallotID {
+		return VerdictAbandoned, baseReasonGenesis
+	}
+	if ballot.malicious {
+		return VerdictBad, baseReasonMalicious
+	}
+	start := t.evicted.Add(1)
+	for lvote := ballot.votes.tail; lvote != nil && !lvote.lid.Before(start); lvote = lvote.prev {
+		if lvote.vote == abstain && t.layer(lvote.lid).hareTerminated {
+			return VerdictBad, baseReasonAbstain
+		}
+	}
+	if ballot.conditions.badBeacon {
+		return VerdictAbandoned, baseReasonBadBeacon
+	}
+	if ballot.opinion() == t.layer(ballot.layer.Sub(1)).opinion {
+		return VerdictGood, ""
+	}
+	// opinion is cumulative, find the earliest layer where ballot disagrees with local opinion
+	divergent := ballot.layer.Sub(1)
+	for lvote := ballot.votes.tail; lvote != nil && !lvote.lid.Before(start); lvote = lvote.prev {
+		if lvote.opinion == t.layer(lvote.lid).opinion {
+			break
+		}
+		divergent = lvote.lid
+	}
+	if divergent.After(t.verified) {
+		return VerdictAbandoned, baseReasonUnverified
+	}
+	return VerdictAbandoned, baseReasonInconsistent
+}
+
+// baseCandidates returns ballots from the layers before current in the order they should be
+// considered as a base ballot. Ballots are classified one layer at a time, starting from the
+// most recent layer. Unless all is true, classification stops at the first layer with a good ballot,
+// as such ballot is selected if votes can be encoded within MaxExceptions.
+func (t *turtle) baseCandidates(current types.LayerID, all bool) []*baseCandidate {
+	var rst []*baseCandidate
+	for lid := current.Sub(1); lid.After(t.evicted); lid = lid.Sub(1) {
+		var choices []*ballotInfo
+		if lid == types.GetEffectiveGenesis() {
+			choices = []*ballotInfo{{layer: types.GetEffectiveGenesis()}}
+		} else {
+			choices = t.ballots[lid]
+			if layer, exist := t.layers[lid]; exist && len(layer.lean) > 0 {
+				choices = append(layer.leanInfos(), choices...)
+			}
+		}
+		good := false
+		for _, ballot := range choices {
+			verdict, reason := t.classifyBase(ballot)
+			good = good || verdict == VerdictGood
+			rst = append(rst, &baseCandidate{ballot: ballot, verdict: verdict, reason: reason})
+		}
+		if good && !all {
+			break
+		}
+	}
+	orderCandidates(rst)
+	return rst
+}
@@ -0,0 +1,88 @@
+package tortoise
+
+import (
+	"bytes"
+	"context"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/spacemeshos/go-spacemesh/codec"
+	"github.com/spacemeshos/go-spacemesh/common/types"
+	"github.com/spacemeshos/go-spacemesh/log/logtest"
+	"github.com/spacemeshos/go-spacemesh/tortoise/sim"
+)
+
+func TestOrderCandidates(t *testing.T) {
+	candidate := func(lid types.LayerID, id byte, verdict BaseVerdict) *baseCandidate {
+		return &baseCandidate{
+			ballot:  &ballotInfo{layer: lid, id: types.BallotID{id}},
+			verdict: verdict,
+		}
+	}
+	expected := []*baseCandidate{
+		candidate(10, 1, VerdictGood),
+		candidate(10, 2, VerdictGood),
+		candidate(9, 1, VerdictGood),
+		candidate(11, 1, VerdictAbandoned),
+		candidate(10, 3, VerdictAbandoned),
+		candidate(11, 2, VerdictBad),
+	}
+	rng := rand.New(rand.NewSource(101))
+	for i := 0; i < 10; i++ {
+		shuffled := append([]*baseCandidate{}, expected...)
+		rng.Shuffle(len(shuffled), func(i, j int) {
+			shuffled[i], shuffled[j] = shuffled[j], shuffled[i]
+		})
+		orderCandidates(shuffled)
+		require.Equal(t, expected, shuffled)
+	}
+}
+
+func TestBaseBallotDeterministic(t *testing.T) {
+	const (
+		size   = 10
+		layers = 6
+	)
+	ctx := context.Background()
+	s := sim.New(sim.WithLayerSize(size))
+	s.Setup()
+	cfg := defaultTestConfig()
+	cfg.LayerSize = size
+
+	var last types.LayerID
+	for i := 0; i < layers; i++ {
+		last = s.Next()
+	}
+	encode := func(tb testing.TB, seed int64) *types.Opinion {
+		trtl, err := New(WithConfig(cfg), WithLogger(logtest.New(tb)))
+		require.NoError(tb, err)
+		rng := rand.New(rand.NewSource(seed))
+		for lid := types.GetEffectiveGenesis().Add(1); lid <= last; lid++ {
+			ballots := deliverLayer(tb, trtl, s.GetState(0), lid)
+			rng.Shuffle(len(ballots), func(i, j int) {
+				ballots[i], ballots[j] = ballots[j], ballots[i]
+			})
+			for _, ballot := range ballots {
+				trtl.OnBallot(ballot)
+			}
+			trtl.TallyVotes(ctx, lid)
+		}
+		opinion, err := trtl.EncodeVotes(ctx, EncodeVotesWithCurrent(last.Add(1)))
+		require.NoError(tb, err)
+		return opinion
+	}
+	first := encode(t, 1)
+	// all ballots in the last layer are good, ballot with the lowest id is selected
+	var lowest types.BallotID
+	for i, ballot := range deliverLayer(t, defaultAlgorithm(t), s.GetState(0), last) {
+		if i == 0 || bytes.Compare(ballot.ID[:], lowest[:]) < 0 {
+			lowest = ballot.ID
+		}
+	}
+	require.Equal(t, lowest, first.Base)
+	for seed := int64(2); seed < 5; seed++ {
+		require.Equal(t, codec.MustEncode(first), codec.MustEncode(encode(t, seed)))
+	}
+}
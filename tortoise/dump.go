@@ -0,0 +1,468 @@
+package tortoise
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"sort"
+
+	"github.com/spacemeshos/fixed"
+	"go.uber.org/zap"
+	"golang.org/x/exp/maps"
+
+	"github.com/spacemeshos/go-spacemesh/common/types"
+)
+
+// dumpVersion must be changed together with the format of the state dump.
+const dumpVersion = 1
+
+// ErrDumpVersion is returned by LoadState if the dump was written in a different format.
+var ErrDumpVersion = errors.New("tortoise: unsupported state dump version")
+
+// dumpWeight is encoded using the binary representation of the fixed point number,
+// so that weights are restored without rounding.
+type dumpWeight []byte
+
+func toDumpWeight(w weight) dumpWeight {
+	return w.Bytes()
+}
+
+func (w dumpWeight) weight() weight {
+	if len(w) == 0 {
+		return fixed.New(0)
+	}
+	return fixed.FromBytes(w)
+}
+
+type stateDump struct {
+	Version          uint32 `json:"version"`
+	EpochSize        uint32 `json:"epoch-size"`
+	EffectiveGenesis uint32 `json:"effective-genesis"`
+	Config           Config `json:"config"`
+
+	Last           types.LayerID `json:"last"`
+	Processed      types.LayerID `json:"processed"`
+	Verified       types.LayerID `json:"verified"`
+	Evicted        types.LayerID `json:"evicted"`
+	Pending        types.LayerID `json:"pending"`
+	Counted        types.LayerID `json:"counted"`
+	Full           bool          `json:"full"`
+	LocalThreshold dumpWeight    `json:"local-threshold"`
+	GoodWeight     dumpWeight    `json:"good-weight"`
+
+	Malicious []types.NodeID   `json:"malicious,omitempty"`
+	Epochs    []epochDump      `json:"epochs"`
+	Layers    []layerDump      `json:"layers"`
+	Votes     []voteDump       `json:"votes"`
+	Ballots   []ballotDump     `json:"ballots"`
+	Retriable []types.BallotID `json:"retriable,omitempty"`
+	Delayed   []types.BallotID `json:"delayed,omitempty"`
+	Late      []types.Vote     `json:"late,omitempty"`
+	Retally   []types.Vote     `json:"retally,omitempty"`
+}
+
+type epochDump struct {
+	ID     types.EpochID `json:"id"`
+	Weight dumpWeight    `json:"weight"`
+	Height uint64        `json:"height"`
+	Beacon *types.Beacon `json:"beacon,omitempty"`
+	Atxs   []atxDump     `json:"atxs"`
+}
+
+type atxDump struct {
+	ID         types.ATXID `json:"id"`
+	Weight     uint64      `json:"weight"`
+	Height     uint64      `json:"height"`
+	Malfeasant bool        `json:"malfeasant,omitempty"`
+}
+
+type layerDump struct {
+	ID              types.LayerID `json:"id"`
+	Opinion         types.Hash32  `json:"opinion"`
+	PrevOpinion     *types.Hash32 `json:"prev-opinion,omitempty"`
+	HareTerminated  bool          `json:"hare-terminated,omitempty"`
+	Coinflip        sign          `json:"coinflip,omitempty"`
+	Empty           dumpWeight    `json:"empty"`
+	Abstained       dumpWeight    `json:"abstained"`
+	GoodUncounted   dumpWeight    `json:"good-uncounted"`
+	ReferenceHeight uint64        `json:"reference-height"`
+	Blocks          []blockDump   `json:"blocks,omitempty"`
+}
+
+type blockDump struct {
+	ID       types.BlockID  `json:"id"`
+	Height   uint64         `json:"height"`
+	Hare     sign           `json:"hare"`
+	Validity sign           `json:"validity"`
+	Source   DecisionSource `json:"source,omitempty"`
+	Reported sign           `json:"reported"`
+	Margin   dumpWeight     `json:"margin"`
+	Data     bool           `json:"data,omitempty"`
+}
+
+// voteDump is a vote for a single layer. Ballots share votes for the layers before their base ballot,
+// therefore votes are dumped once and referenced by index starting from 1.
+type voteDump struct {
+	Layer     types.LayerID `json:"layer"`
+	Opinion   types.Hash32  `json:"opinion"`
+	Vote      sign          `json:"vote"`
+	Supported []types.Vote  `json:"supported,omitempty"`
+	Prev      uint32        `json:"prev,omitempty"`
+}
+
+type ballotDump struct {
+	ID        types.BallotID `json:"id"`
+	Layer     types.LayerID  `json:"layer"`
+	Smesher   types.NodeID   `json:"smesher"`
+	Base      types.BallotID `json:"base"`
+	BaseLayer types.LayerID  `json:"base-layer"`
+	Malicious bool           `json:"malicious,omitempty"`
+	Weight    dumpWeight     `json:"weight"`
+	RefWeight *big.Rat       `json:"ref-weight"`
+	RefHeight uint64         `json:"ref-height"`
+	RefBeacon types.Beacon   `json:"ref-beacon"`
+	Votes     uint32         `json:"votes,omitempty"`
+	BadBeacon bool           `json:"bad-beacon,omitempty"`
+	Good      bool           `json:"good,omitempty"`
+	Counted   bool           `json:"counted,omitempty"`
+}
+
+// DumpState writes the in-memory state of the tortoise to w. Dump is compressed and contains
+// only the state that is needed to make decisions for the subsequent layers, such as opinions
+// of the ballots in the window and tallies, it doesn't contain ballots and atxs themselves.
+//
+// State can be loaded with LoadState.
+func (t *Tortoise) DumpState(w io.Writer) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.drain()
+	if err := t.restoreVotes(); err != nil {
+		return err
+	}
+	dump := t.trtl.dump()
+	zw := gzip.NewWriter(w)
+	if err := json.NewEncoder(zw).Encode(dump); err != nil {
+		return fmt.Errorf("encode state: %w", err)
+	}
+	return zw.Close()
+}
+
+// LoadState loads state written by DumpState. Config, layers per epoch and effective genesis
+// are set to the values used by the tortoise that wrote the dump, as they have to match for
+// the loaded tortoise to make the same decisions. Therefore it is expected to be used only
+// by tests and tools that analyze the dump.
+func LoadState(r io.Reader, opts ...Opt) (*Tortoise, error) {
+	zr, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("decompress state: %w", err)
+	}
+	defer zr.Close()
+	var dump stateDump
+	if err := json.NewDecoder(zr).Decode(&dump); err != nil {
+		return nil, fmt.Errorf("decode state: %w", err)
+	}
+	if dump.Version != dumpVersion {
+		return nil, fmt.Errorf("%w: %d, expected %d", ErrDumpVersion, dump.Version, dumpVersion)
+	}
+	types.SetLayersPerEpoch(dump.EpochSize)
+	types.SetEffectiveGenesis(dump.EffectiveGenesis)
+	trtl, err := New(append(opts, WithConfig(dump.Config))...)
+	if err != nil {
+		return nil, err
+	}
+	loaded, err := loadTurtle(trtl.logger, &dump)
+	if err != nil {
+		return nil, err
+	}
+	trtl.trtl = loaded
+	return trtl, nil
+}
+
+func (t *turtle) dump() *stateDump {
+	dump := &stateDump{
+		Version:          dumpVersion,
+		EpochSize:        types.GetLayersPerEpoch(),
+		EffectiveGenesis: types.GetEffectiveGenesis().Uint32(),
+		Config:           t.Config,
+		Last:             t.last,
+		Processed:        t.processed,
+		Verified:         t.verified,
+		Evicted:          t.evicted,
+		Pending:          t.pending,
+		Counted:          t.full.counted,
+		Full:             t.isFull,
+		LocalThreshold:   toDumpWeight(t.localThreshold),
+		GoodWeight:       toDumpWeight(t.verifying.totalGoodWeight),
+	}
+	dump.Malicious = maps.Keys(t.malnodes)
+	sort.Slice(dump.Malicious, func(i, j int) bool {
+		return bytes.Compare(dump.Malicious[i][:], dump.Malicious[j][:]) < 0
+	})
+
+	eids := maps.Keys(t.epochs)
+	sort.Slice(eids, func(i, j int) bool { return eids[i] < eids[j] })
+	for _, eid := range eids {
+		epoch := t.epochs[eid]
+		edump := epochDump{
+			ID:     eid,
+			Weight: toDumpWeight(epoch.weight),
+			Height: epoch.height,
+			Beacon: epoch.beacon,
+		}
+		for id, atx := range epoch.atxs {
+			edump.Atxs = append(edump.Atxs, atxDump{
+				ID:         id,
+				Weight:     atx.weight,
+				Height:     atx.height,
+				Malfeasant: atx.malfeasant,
+			})
+		}
+		sort.Slice(edump.Atxs, func(i, j int) bool {
+			return bytes.Compare(edump.Atxs[i].ID[:], edump.Atxs[j].ID[:]) < 0
+		})
+		dump.Epochs = append(dump.Epochs, edump)
+	}
+
+	lids := maps.Keys(t.layers)
+	sort.Slice(lids, func(i, j int) bool { return lids[i] < lids[j] })
+	for _, lid := range lids {
+		layer := t.layers[lid]
+		ldump := layerDump{
+			ID:              lid,
+			Opinion:         layer.opinion,
+			PrevOpinion:     layer.prevOpinion,
+			HareTerminated:  layer.hareTerminated,
+			Coinflip:        layer.coinflip,
+			Empty:           toDumpWeight(layer.empty),
+			Abstained:       toDumpWeight(layer.abstained),
+			GoodUncounted:   toDumpWeight(layer.verifying.goodUncounted),
+			ReferenceHeight: layer.verifying.referenceHeight,
+		}
+		for _, block := range layer.blocks {
+			ldump.Blocks = append(ldump.Blocks, blockDump{
+				ID:       block.id,
+				Height:   block.height,
+				Hare:     block.hare,
+				Validity: block.validity,
+				Source:   block.source,
+				Reported: block.reported,
+				Margin:   toDumpWeight(block.margin),
+				Data:     block.data,
+			})
+		}
+		dump.Layers = append(dump.Layers, ldump)
+	}
+
+	indexes := map[*layerVote]uint32{}
+	var dumpVotes func(lvote *layerVote) uint32
+	dumpVotes = func(lvote *layerVote) uint32 {
+		if lvote == nil {
+			return 0
+		}
+		if index, exist := indexes[lvote]; exist {
+			return index
+		}
+		vdump := voteDump{
+			Layer:   lvote.lid,
+			Opinion: lvote.opinion,
+			Vote:    lvote.vote,
+		}
+		// votes for evicted layers are not used, except for the opinion of the
+		// previous layer that is used when exceptions change the first layer in the window
+		if lvote.lid.After(t.evicted) {
+			vdump.Prev = dumpVotes(lvote.prev)
+			for _, block := range lvote.supported {
+				vdump.Supported = append(vdump.Supported, block.header())
+			}
+		}
+		dump.Votes = append(dump.Votes, vdump)
+		indexes[lvote] = uint32(len(dump.Votes))
+		return indexes[lvote]
+	}
+	blids := maps.Keys(t.ballots)
+	sort.Slice(blids, func(i, j int) bool { return blids[i] < blids[j] })
+	for _, lid := range blids {
+		for _, ballot := range t.ballots[lid] {
+			dump.Ballots = append(dump.Ballots, ballotDump{
+				ID:        ballot.id,
+				Layer:     ballot.layer,
+				Smesher:   ballot.smesher,
+				Base:      ballot.base.id,
+				BaseLayer: ballot.base.layer,
+				Malicious: ballot.malicious,
+				Weight:    toDumpWeight(ballot.weight),
+				RefWeight: ballot.reference.weight,
+				RefHeight: ballot.reference.height,
+				RefBeacon: ballot.reference.beacon,
+				Votes:     dumpVotes(ballot.votes.tail),
+				BadBeacon: ballot.conditions.badBeacon,
+				Good:      ballot.goodCounted,
+				Counted:   ballot.votesCounted,
+			})
+		}
+	}
+
+	for e := t.retriable.Front(); e != nil; e = e.Next() {
+		dump.Retriable = append(dump.Retriable, e.Value.(*ballotInfo).id)
+	}
+	delays := maps.Keys(t.full.delayed)
+	sort.Slice(delays, func(i, j int) bool { return delays[i] < delays[j] })
+	for _, delay := range delays {
+		for _, ballot := range t.full.delayed[delay] {
+			dump.Delayed = append(dump.Delayed, ballot.id)
+		}
+	}
+	for _, block := range t.late {
+		dump.Late = append(dump.Late, block.header())
+	}
+	for _, block := range t.retally {
+		dump.Retally = append(dump.Retally, block.header())
+	}
+	return dump
+}
+
+func loadTurtle(logger *zap.Logger, dump *stateDump) (*turtle, error) {
+	t := newTurtle(logger, dump.Config)
+	// state created by newTurtle is replaced by the dumped state
+	t.epochs = map[types.EpochID]*epochInfo{}
+	t.layers = map[types.LayerID]*layerInfo{}
+
+	t.last = dump.Last
+	t.processed = dump.Processed
+	t.verified = dump.Verified
+	t.evicted = dump.Evicted
+	t.pending = dump.Pending
+	t.full.counted = dump.Counted
+	t.isFull = dump.Full
+	t.localThreshold = dump.LocalThreshold.weight()
+	t.verifying.totalGoodWeight = dump.GoodWeight.weight()
+	for _, id := range dump.Malicious {
+		t.makrMalfeasant(id)
+	}
+
+	for _, edump := range dump.Epochs {
+		epoch := t.epoch(edump.ID)
+		epoch.weight = edump.Weight.weight()
+		epoch.height = edump.Height
+		epoch.beacon = edump.Beacon
+		for _, atx := range edump.Atxs {
+			epoch.atxs[atx.ID] = atxInfo{weight: atx.Weight, height: atx.Height, malfeasant: atx.Malfeasant}
+		}
+	}
+
+	for _, ldump := range dump.Layers {
+		layer := t.layer(ldump.ID)
+		layer.opinion = ldump.Opinion
+		layer.hareTerminated = ldump.HareTerminated
+		layer.coinflip = ldump.Coinflip
+		layer.empty = ldump.Empty.weight()
+		layer.abstained = ldump.Abstained.weight()
+		layer.verifying.goodUncounted = ldump.GoodUncounted.weight()
+		layer.verifying.referenceHeight = ldump.ReferenceHeight
+		if prev, exist := t.layers[ldump.ID.Sub(1)]; exist && ldump.PrevOpinion != nil {
+			// opinion of the layer is updated together with the opinion of the previous layer
+			layer.prevOpinion = &prev.opinion
+		} else {
+			layer.prevOpinion = ldump.PrevOpinion
+		}
+		for _, bdump := range ldump.Blocks {
+			layer.blocks = append(layer.blocks, &blockInfo{
+				id:       bdump.ID,
+				layer:    ldump.ID,
+				height:   bdump.Height,
+				hare:     bdump.Hare,
+				validity: bdump.Validity,
+				source:   bdump.Source,
+				reported: bdump.Reported,
+				margin:   bdump.Margin.weight(),
+				data:     bdump.Data,
+			})
+			blocksNumber.Inc()
+		}
+	}
+
+	lvotes := make([]*layerVote, len(dump.Votes))
+	for i, vdump := range dump.Votes {
+		lvote := &layerVote{
+			lid:     vdump.Layer,
+			opinion: vdump.Opinion,
+			vote:    vdump.Vote,
+		}
+		if vdump.Prev != 0 {
+			if int(vdump.Prev) > i {
+				return nil, fmt.Errorf("vote %d references vote %d that is not loaded", i+1, vdump.Prev)
+			}
+			lvote.prev = lvotes[vdump.Prev-1]
+		}
+		for _, header := range vdump.Supported {
+			block := t.getBlock(header)
+			if block == nil {
+				return nil, fmt.Errorf("block %s/%d is supported by vote %d, but not in the state",
+					header.ID, header.LayerID, i+1)
+			}
+			lvote.supported = append(lvote.supported, block)
+		}
+		lvotes[i] = lvote
+	}
+	for _, bdump := range dump.Ballots {
+		ballot := &ballotInfo{
+			id:        bdump.ID,
+			layer:     bdump.Layer,
+			smesher:   bdump.Smesher,
+			base:      baseInfo{id: bdump.Base, layer: bdump.BaseLayer},
+			malicious: bdump.Malicious,
+			weight:    bdump.Weight.weight(),
+			reference: &referenceInfo{
+				weight: bdump.RefWeight,
+				height: bdump.RefHeight,
+				beacon: bdump.RefBeacon,
+			},
+			conditions:   conditions{badBeacon: bdump.BadBeacon},
+			goodCounted:  bdump.Good,
+			votesCounted: bdump.Counted,
+		}
+		if bdump.Votes != 0 {
+			if int(bdump.Votes) > len(lvotes) {
+				return nil, fmt.Errorf("ballot %s references vote %d that is not loaded", bdump.ID, bdump.Votes)
+			}
+			ballot.votes.tail = lvotes[bdump.Votes-1]
+		}
+		t.addBallot(ballot)
+	}
+
+	for _, id := range dump.Retriable {
+		ballot, exist := t.ballotRefs[id]
+		if !exist {
+			return nil, fmt.Errorf("retriable ballot %s is not in the state", id)
+		}
+		t.retryLater(ballot)
+	}
+	for _, id := range dump.Delayed {
+		ballot, exist := t.ballotRefs[id]
+		if !exist {
+			return nil, fmt.Errorf("delayed ballot %s is not in the state", id)
+		}
+		t.full.delay(ballot)
+	}
+	for _, header := range dump.Late {
+		block := t.getBlock(header)
+		if block == nil {
+			return nil, fmt.Errorf("late block %s/%d is not in the state", header.ID, header.LayerID)
+		}
+		t.late = append(t.late, block)
+	}
+	for _, header := range dump.Retally {
+		block := t.getBlock(header)
+		if block == nil {
+			return nil, fmt.Errorf("retallied block %s/%d is not in the state", header.ID, header.LayerID)
+		}
+		t.retally = append(t.retally, block)
+	}
+	t.dirty = 0
+	t.dirtyBallots = 0
+	return t, nil
+}
@@ -0,0 +1,103 @@
+package tortoise
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/spacemeshos/go-spacemesh/codec"
+	"github.com/spacemeshos/go-spacemesh/common/types"
+	"github.com/spacemeshos/go-spacemesh/log/logtest"
+	"github.com/spacemeshos/go-spacemesh/tortoise/sim"
+)
+
+func TestDumpState(t *testing.T) {
+	const size = 10
+	for _, tc := range []struct {
+		desc string
+		// layers with empty hare output, tortoise switches to full mode to verify them
+		healing [2]int
+		dumpAt  int
+	}{
+		{desc: "verifying", dumpAt: 20},
+		{desc: "full", healing: [2]int{15, 22}, dumpAt: 20},
+		{desc: "after healing", healing: [2]int{5, 12}, dumpAt: 20},
+	} {
+		tc := tc
+		t.Run(tc.desc, func(t *testing.T) {
+			ctx := context.Background()
+			s := sim.New(sim.WithLayerSize(size))
+			s.Setup()
+			cfg := defaultTestConfig()
+			cfg.LayerSize = size
+			cfg.Hdist = 4
+			cfg.Zdist = 2
+			cfg.WindowSize = 10
+
+			next := func(i int) types.LayerID {
+				if i >= tc.healing[0] && i < tc.healing[1] {
+					return s.Next(sim.WithEmptyHareOutput(), sim.WithNumBlocks(1))
+				}
+				return s.Next()
+			}
+			original := tortoiseFromSimState(t, s.GetState(0), WithConfig(cfg), WithLogger(logtest.New(t)))
+			var last types.LayerID
+			for i := 0; i < tc.dumpAt; i++ {
+				last = next(i)
+				original.TallyVotes(ctx, last)
+			}
+
+			var buf bytes.Buffer
+			require.NoError(t, original.DumpState(&buf))
+			trtl, err := LoadState(bytes.NewReader(buf.Bytes()), WithLogger(logtest.New(t)))
+			require.NoError(t, err)
+			loaded := &recoveryAdapter{
+				TB:       t,
+				Tortoise: trtl,
+				db:       s.GetState(0).DB,
+				beacon:   s.GetState(0).Beacons,
+				prev:     last.Add(1),
+			}
+			original.prev = last.Add(1)
+
+			require.Equal(t, original.LatestComplete(), loaded.LatestComplete())
+			require.Equal(t, original.Updates(), loaded.Updates())
+			for i := tc.dumpAt; i < tc.dumpAt+20; i++ {
+				last = next(i)
+				original.TallyVotes(ctx, last)
+				loaded.TallyVotes(ctx, last)
+				require.Equal(t, original.LatestComplete(), loaded.LatestComplete(), "layer=%s", last)
+				require.Equal(t, original.Updates(), loaded.Updates(), "layer=%s", last)
+
+				expected, err := original.EncodeVotes(ctx, EncodeVotesWithCurrent(last.Add(1)))
+				require.NoError(t, err)
+				opinion, err := loaded.EncodeVotes(ctx, EncodeVotesWithCurrent(last.Add(1)))
+				require.NoError(t, err)
+				require.Equal(t, codec.MustEncode(expected), codec.MustEncode(opinion), "layer=%s", last)
+			}
+			require.Equal(t, last.Sub(1), loaded.LatestComplete())
+		})
+	}
+}
+
+func TestLoadStateVersion(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, defaultAlgorithm(t).DumpState(&buf))
+
+	zr, err := gzip.NewReader(&buf)
+	require.NoError(t, err)
+	var dump stateDump
+	require.NoError(t, json.NewDecoder(zr).Decode(&dump))
+	dump.Version++
+
+	buf.Reset()
+	zw := gzip.NewWriter(&buf)
+	require.NoError(t, json.NewEncoder(zw).Encode(&dump))
+	require.NoError(t, zw.Close())
+	_, err = LoadState(&buf)
+	require.ErrorIs(t, err, ErrDumpVersion)
+}
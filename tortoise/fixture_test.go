@@ -491,6 +491,27 @@ func (s *session) hareblock(lid int, id string, height uint64) {
 	s.hare(s.block(lid, id, height))
 }
 
+type coinAction struct {
+	lid  types.LayerID
+	coin bool
+}
+
+func (c *coinAction) String() string {
+	return fmt.Sprintf("coin %d / %v", c.lid, c.coin)
+}
+
+func (*coinAction) deps() []action {
+	return nil
+}
+
+func (c *coinAction) execute(trt *Tortoise) {
+	trt.OnWeakCoin(c.lid, c.coin)
+}
+
+func (s *session) coin(lid int, value bool) {
+	s.register(&coinAction{lid: types.GetEffectiveGenesis() + types.LayerID(lid), coin: value})
+}
+
 type beaconAction struct {
 	epoch  uint32
 	beacon types.Beacon
@@ -543,6 +564,7 @@ const (
 	invalid
 	data
 	local
+	changed
 )
 
 func (r *results) block(id string, height uint64, fields uint) *results {
@@ -553,6 +575,7 @@ func (r *results) block(id string, height uint64, fields uint) *results {
 		Hare:    fields&hare > 0,
 		Data:    fields&data > 0,
 		Local:   fields&local > 0,
+		Changed: fields&changed > 0,
 	}
 	copy(block.Header.ID[:], id)
 	block.Header.LayerID = rst.Layer
@@ -725,6 +748,13 @@ func (s *session) withDelay(val uint32) *session {
 	return s
 }
 
+func (s *session) withCoinflip(theta, distance uint32) *session {
+	s.ensureConfig()
+	s.config.CoinflipTheta = theta
+	s.config.HealingDistance = distance
+	return s
+}
+
 func (s *session) withMinActiveSetWeight(weight uint64) *session {
 	s.ensureConfig()
 	s.config.MinimalActiveSetWeight = weight
@@ -767,7 +797,7 @@ func TestSanity(t *testing.T) {
 	s.tallyWait(2)
 	s.updates(t, new(results).
 		verified(0).
-		verified(1).block("aa", 0, valid|hare|data).
+		verified(1).block("aa", 0, valid|hare|data|changed).
 		next(2).block("bb", 0, hare|data),
 	)
 	t.Run("inorder", func(t *testing.T) { s.runInorder() })
@@ -862,7 +892,7 @@ func TestEpochGap(t *testing.T) {
 	for l := 2; l <= s.epochSize; l++ {
 		id := strconv.Itoa(l - 1)
 		s.hareblock(l-1, id, 0)
-		rst = rst.verified(l-1).block(id, 0, hare|data|valid)
+		rst = rst.verified(l-1).block(id, 0, hare|data|valid|changed)
 		for i := 0; i < 2; i++ {
 			s.smesher(i).atx(1).ballot(l, new(bopt).
 				eligibilities(s.layerSize/2).
@@ -1,8 +1,10 @@
 package tortoise
 
 import (
+	"sort"
 	"time"
 
+	"github.com/spacemeshos/fixed"
 	"go.uber.org/zap"
 
 	"github.com/spacemeshos/go-spacemesh/common/types"
@@ -28,6 +30,8 @@ type full struct {
 	counted types.LayerID
 	// delayed ballots by the layer when they are safe to count
 	delayed map[types.LayerID][]*ballotInfo
+	// total weight of the delayed ballots
+	delayedWeight weight
 }
 
 func (f *full) countBallot(logger *zap.Logger, ballot *ballotInfo) {
@@ -38,14 +42,17 @@ func (f *full) countBallot(logger *zap.Logger, ballot *ballotInfo) {
 	if ballot.malicious {
 		return
 	}
+	ballot.votesCounted = true
+	f.markDirty(f.evicted.Add(1))
 	for lvote := ballot.votes.tail; lvote != nil; lvote = lvote.prev {
 		if !lvote.lid.After(f.evicted) {
 			break
 		}
+		layer := f.layer(lvote.lid)
 		if lvote.vote == abstain {
+			layer.abstained = layer.abstained.Add(ballot.weight)
 			continue
 		}
-		layer := f.layer(lvote.lid)
 		empty := true
 		for _, block := range layer.blocks {
 			if block.height > ballot.reference.height {
@@ -69,17 +76,60 @@ func (f *full) countBallot(logger *zap.Logger, ballot *ballotInfo) {
 	fcountBallotDuration.Observe(float64(time.Since(start).Nanoseconds()))
 }
 
+// uncountBallot reverses countBallot. Blocks that were added after the ballot was counted
+// were counted against by countForLateBlock, therefore every block in the layer is reversed
+// according to the current vote.
+func (f *full) uncountBallot(ballot *ballotInfo) {
+	if !ballot.votesCounted {
+		return
+	}
+	ballot.votesCounted = false
+	f.markDirty(f.evicted.Add(1))
+	for lvote := ballot.votes.tail; lvote != nil; lvote = lvote.prev {
+		if !lvote.lid.After(f.evicted) {
+			break
+		}
+		layer := f.layer(lvote.lid)
+		if lvote.vote == abstain {
+			layer.abstained = layer.abstained.Sub(ballot.weight)
+			continue
+		}
+		empty := true
+		for _, block := range layer.blocks {
+			if block.height > ballot.reference.height {
+				continue
+			}
+			vote := lvote.getVote(block)
+			switch vote {
+			case support:
+				empty = false
+				block.margin = block.margin.Sub(ballot.weight)
+			case against:
+				block.margin = block.margin.Add(ballot.weight)
+			}
+		}
+		if empty {
+			layer.empty = layer.empty.Sub(ballot.weight)
+		} else {
+			layer.empty = layer.empty.Add(ballot.weight)
+		}
+	}
+}
+
 func (f *full) countForLateBlock(block *blockInfo) {
 	start := time.Now()
 	for lid := block.layer.Add(1); !lid.After(f.counted); lid = lid.Add(1) {
 		for _, ballot := range f.ballots[lid] {
-			if block.height > ballot.reference.height {
+			// delayed and retriable ballots will vote against the block when they are counted
+			if !ballot.votesCounted || block.height > ballot.reference.height {
 				continue
 			}
 			for current := ballot.votes.tail; current != nil && !current.lid.Before(block.layer); current = current.prev {
 				if current.lid != block.layer {
 					continue
 				}
+				// late block inherits abstain vote on the layer,
+				// weight is already accounted in layer.abstained
 				if current.vote == abstain {
 					continue
 				}
@@ -90,30 +140,217 @@ func (f *full) countForLateBlock(block *blockInfo) {
 	lateBlockDuration.Observe(float64(time.Since(start).Nanoseconds()))
 }
 
+// countBallots produces the same tallies as countBallot called for every ballot.
+//
+// Ballots share layer votes with their base ballots, and ballots from the same layer usually
+// differ only in votes for the most recent layers. Instead of applying every ballot to every
+// block in the window, weight of the ballots is accumulated on the layer votes, and passed
+// to the previous layer vote once the layer is counted. Therefore every distinct layer vote
+// is applied to the blocks once.
+//
+// Ballots count only for blocks with height that is not above the reference height,
+// so the weight is accumulated separately for every class of reference heights.
+// Class is the number of distinct block heights in the window that are not above
+// the reference height. Only classes of the ballots that share the layer vote are tracked.
+func (f *full) countBallots(logger *zap.Logger, ballots []*ballotInfo) {
+	start := time.Now()
+	var (
+		last    types.LayerID
+		counted = make([]*ballotInfo, 0, len(ballots))
+	)
+	for _, ballot := range ballots {
+		if f.shouldBeDelayed(logger, ballot) || ballot.malicious {
+			continue
+		}
+		ballot.votesCounted = true
+		counted = append(counted, ballot)
+		if ballot.layer.After(last) {
+			last = ballot.layer
+		}
+	}
+	if len(counted) == 0 {
+		return
+	}
+	f.markDirty(f.evicted.Add(1))
+
+	heights := newHeightClasses(f.state, f.evicted.Add(1), last)
+	var (
+		tallies = map[*layerVote]heightTally{}
+		byLayer = map[types.LayerID][]*layerVote{}
+	)
+	accumulate := func(lvote *layerVote, weights ...classWeight) {
+		tally, exist := tallies[lvote]
+		if !exist {
+			byLayer[lvote.lid] = append(byLayer[lvote.lid], lvote)
+		}
+		tallies[lvote] = append(tally, weights...)
+	}
+	for _, ballot := range counted {
+		tail := ballot.votes.tail
+		if tail == nil || !tail.lid.After(f.evicted) {
+			continue
+		}
+		accumulate(tail, classWeight{class: heights.class(ballot.reference.height), weight: ballot.weight})
+	}
+	for lid := last.Sub(1); lid.After(f.evicted); lid = lid.Sub(1) {
+		for _, lvote := range byLayer[lid] {
+			tally := tallies[lvote].compact()
+			f.countLayerVote(heights, lvote, tally)
+			if lvote.prev != nil && lvote.prev.lid.After(f.evicted) {
+				accumulate(lvote.prev, tally...)
+			}
+		}
+	}
+	fcountBallotsDuration.Observe(float64(time.Since(start).Nanoseconds()))
+}
+
+// countLayerVote applies weight of all ballots that share the layer vote.
+// Tally is expected to be sorted by class.
+func (f *full) countLayerVote(heights heightClasses, lvote *layerVote, tally heightTally) {
+	layer := f.layer(lvote.lid)
+	// above[i] is the weight of ballots with class not lower than tally[i].class
+	above := make([]weight, len(tally)+1)
+	for i := len(tally) - 1; i >= 0; i-- {
+		above[i] = above[i+1].Add(tally[i].weight)
+	}
+	if lvote.vote == abstain {
+		layer.abstained = layer.abstained.Add(above[0])
+		return
+	}
+	// ballots vote for the empty layer if they don't count for any supported block
+	supported := len(tally)
+	for _, block := range layer.blocks {
+		class := heights.blockClass(block.height)
+		i := sort.Search(len(tally), func(i int) bool {
+			return tally[i].class >= class
+		})
+		switch lvote.getVote(block) {
+		case support:
+			if i < supported {
+				supported = i
+			}
+			block.margin = block.margin.Add(above[i])
+		case against:
+			block.margin = block.margin.Sub(above[i])
+		}
+	}
+	layer.empty = layer.empty.
+		Add(above[0].Sub(above[supported])).
+		Sub(above[supported])
+}
+
+type classWeight struct {
+	class  int
+	weight weight
+}
+
+// heightTally is the weight of ballots grouped by the class of the reference height.
+type heightTally []classWeight
+
+// compact sorts tally by class and merges weights of the same class.
+func (t heightTally) compact() heightTally {
+	sort.Slice(t, func(i, j int) bool {
+		return t[i].class < t[j].class
+	})
+	compacted := t[:0]
+	for _, cw := range t {
+		if n := len(compacted); n > 0 && compacted[n-1].class == cw.class {
+			compacted[n-1].weight = compacted[n-1].weight.Add(cw.weight)
+			continue
+		}
+		compacted = append(compacted, cw)
+	}
+	return compacted
+}
+
+// heightClasses is a sorted list of distinct block heights.
+type heightClasses []uint64
+
+func newHeightClasses(s *state, from, to types.LayerID) heightClasses {
+	var heights heightClasses
+	for lid := from; lid.Before(to); lid = lid.Add(1) {
+		layer, exist := s.layers[lid]
+		if !exist {
+			continue
+		}
+		for _, block := range layer.blocks {
+			heights = append(heights, block.height)
+		}
+	}
+	sort.Slice(heights, func(i, j int) bool {
+		return heights[i] < heights[j]
+	})
+	unique := heights[:0]
+	for i, height := range heights {
+		if i == 0 || height != heights[i-1] {
+			unique = append(unique, height)
+		}
+	}
+	return unique
+}
+
+// class of the ballot is the number of block heights that are not above the reference height.
+func (h heightClasses) class(height uint64) int {
+	return sort.Search(len(h), func(i int) bool {
+		return h[i] > height
+	})
+}
+
+// blockClass is the lowest class of the ballot that counts for the block with the height.
+func (h heightClasses) blockClass(height uint64) int {
+	return sort.Search(len(h), func(i int) bool {
+		return h[i] >= height
+	}) + 1
+}
+
 func (f *full) countDelayed(logger *zap.Logger, lid types.LayerID) {
+	delayed := f.takeDelayed(lid)
+	for _, ballot := range delayed {
+		f.markDirtyBallot(ballot.layer)
+	}
+	f.countBallots(logger, delayed)
+}
+
+// takeDelayed removes ballots that are safe to count in the layer from delayed.
+func (f *full) takeDelayed(lid types.LayerID) []*ballotInfo {
 	delayed, exist := f.delayed[lid]
 	if !exist {
-		return
+		return nil
 	}
 	delete(f.delayed, lid)
+	delayedBallots.Sub(float64(len(delayed)))
 	for _, ballot := range delayed {
-		delayedBallots.Dec()
-		f.countBallot(logger, ballot)
+		f.delayedWeight = f.delayedWeight.Sub(ballot.weight)
 	}
+	delayedWeight.Set(f.delayedWeight.Float())
+	return delayed
+}
+
+// delay excludes weight of the ballot with bad beacon until the layer when it is safe to count.
+func (f *full) delay(ballot *ballotInfo) types.LayerID {
+	delay := ballot.layer.Add(f.BadBeaconVoteDelayLayers)
+	f.delayed[delay] = append(f.delayed[delay], ballot)
+	f.delayedWeight = f.delayedWeight.Add(ballot.weight)
+	delayedBallots.Inc()
+	delayedWeight.Set(f.delayedWeight.Float())
+	return delay
 }
 
 func (f *full) countVotes(logger *zap.Logger) {
+	var ballots []*ballotInfo
 	for lid := f.counted.Add(1); !lid.After(f.processed); lid = lid.Add(1) {
-		for _, ballot := range f.ballots[lid] {
-			f.countBallot(logger, ballot)
-		}
+		ballots = append(ballots, f.ballots[lid]...)
 	}
+	f.countBallots(logger, ballots)
 	f.counted = f.processed
 }
 
 func (f *full) verify(logger *zap.Logger, lid types.LayerID) (bool, bool) {
-	threshold := f.globalThreshold(f.Config, lid)
 	layer := f.state.layer(lid)
+	// abstained weight doesn't lower the threshold, otherwise adversary with 1/3 of the weight
+	// could verify the layer alone. layer remains undecided until enough weight votes on it
+	// or until it is decided by healing.
+	threshold := f.globalThreshold(f.Config, lid)
 	empty := crossesThreshold(layer.empty, threshold) == support
 
 	if len(layer.blocks) == 0 {
@@ -122,12 +359,14 @@ func (f *full) verify(logger *zap.Logger, lid types.LayerID) (bool, bool) {
 				zap.Uint32("candidate layer", lid.Uint32()),
 				zap.Float64("global threshold", threshold.Float()),
 				zap.Float64("empty weight", layer.empty.Float()),
+				zap.Float64("abstained weight", layer.abstained.Float()),
 			)
 		} else {
 			logger.Debug("margin is too low to terminate layer as empty",
 				zap.Uint32("candidate layer", lid.Uint32()),
 				zap.Float64("global threshold", threshold.Float()),
 				zap.Float64("empty weight", layer.empty.Float()),
+				zap.Float64("abstained weight", layer.abstained.Float()),
 			)
 		}
 		return empty, false
@@ -135,16 +374,22 @@ func (f *full) verify(logger *zap.Logger, lid types.LayerID) (bool, bool) {
 	logger.Debug("global treshold",
 		zap.Uint32("target", lid.Uint32()),
 		zap.Float64("threshold", threshold.Float()),
+		zap.Float64("abstained weight", layer.abstained.Float()),
 	)
 	rst, changes := verifyLayer(
 		logger,
 		layer.blocks,
-		func(block *blockInfo) sign {
+		func(block *blockInfo) (sign, DecisionSource) {
 			decision := crossesThreshold(block.margin, threshold)
 			if decision == neutral && empty {
-				return against
+				return against, DecidedByHealing
+			}
+			if decision == neutral {
+				if coin := f.coinDecision(lid, block.margin, threshold); coin != neutral {
+					return coin, DecidedByWeakCoin
+				}
 			}
-			return decision
+			return decision, DecidedByHealing
 		},
 	)
 	if changes {
@@ -156,6 +401,7 @@ func (f *full) verify(logger *zap.Logger, lid types.LayerID) (bool, bool) {
 			zap.Float64("local threshold", f.localThreshold.Float()),
 			zap.Float64("global threshold", threshold.Float()),
 			zap.Float64("empty weight", layer.empty.Float()),
+			zap.Float64("abstained weight", layer.abstained.Float()),
 			zap.Bool("is empty", empty),
 		)
 	}
@@ -166,16 +412,34 @@ func (f *full) shouldBeDelayed(logger *zap.Logger, ballot *ballotInfo) bool {
 	if !ballot.conditions.badBeacon {
 		return false
 	}
-	delay := ballot.layer.Add(f.BadBeaconVoteDelayLayers)
-	if !delay.After(f.last) {
+	if !ballot.layer.Add(f.BadBeaconVoteDelayLayers).After(f.last) {
 		return false
 	}
+	delay := f.delay(ballot)
 	logger.Debug("ballot is delayed",
 		zap.Stringer("id", ballot.id),
 		zap.Uint32("ballot lid", ballot.layer.Uint32()),
 		zap.Uint32("counted at", delay.Uint32()),
+		zap.Float64("delayed weight", f.delayedWeight.Float()),
 	)
-	delayedBallots.Inc()
-	f.delayed[delay] = append(f.delayed[delay], ballot)
+	f.markDirtyBallot(ballot.layer)
 	return true
 }
+
+// coinDecision returns the weak coin recorded healing distance layers after the target layer,
+// if the margin of the block is within the coinflip band. Without the coin decision is neutral,
+// and the layer waits until the coin is recorded.
+func (f *full) coinDecision(lid types.LayerID, margin, threshold weight) sign {
+	if f.CoinflipTheta == 0 || withinDistance(f.HealingDistance, lid, f.last) {
+		return neutral
+	}
+	band := threshold.Mul(fixed.New64(int64(f.CoinflipTheta))).Div(fixed.New64(100))
+	if margin.Abs().GreaterThan(band) {
+		return neutral
+	}
+	coinLayer, exist := f.layers[lid.Add(f.HealingDistance)]
+	if !exist {
+		return neutral
+	}
+	return coinLayer.coinflip
+}
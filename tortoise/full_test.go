@@ -1,6 +1,8 @@
 package tortoise
 
 import (
+	"context"
+	"fmt"
 	"math/rand"
 	"testing"
 
@@ -431,9 +433,10 @@ func TestFullVerify(t *testing.T) {
 		height, margin int
 	}
 	for _, tc := range []struct {
-		desc   string
-		blocks []testBlock
-		empty  int
+		desc      string
+		blocks    []testBlock
+		empty     int
+		abstained int
 
 		validity []sign
 	}{
@@ -526,6 +529,16 @@ func TestFullVerify(t *testing.T) {
 			desc:  "empty layer not verified",
 			empty: neutral,
 		},
+		{
+			desc:      "abstained weight doesn't lower threshold",
+			blocks:    []testBlock{{margin: neutral}},
+			abstained: 6,
+		},
+		{
+			desc:      "abstained weight doesn't lower threshold for empty layer",
+			empty:     neutral,
+			abstained: 6,
+		},
 	} {
 		t.Run(tc.desc, func(t *testing.T) {
 			full := newFullTortoise(Config{}, newState())
@@ -535,6 +548,7 @@ func TestFullVerify(t *testing.T) {
 
 			layer := full.layer(target)
 			layer.empty = fixed.From(float64(tc.empty))
+			layer.abstained = fixed.From(float64(tc.abstained))
 			for i, block := range tc.blocks {
 				block := &blockInfo{
 					id:     types.BlockID{uint8(i) + 1},
@@ -554,3 +568,370 @@ func TestFullVerify(t *testing.T) {
 		})
 	}
 }
+
+func TestFullCountAbstained(t *testing.T) {
+	target := types.GetEffectiveGenesis().Add(1)
+	full := newFullTortoise(Config{}, newState())
+	full.layer(target).blocks = []*blockInfo{{id: types.BlockID{1}, layer: target}}
+
+	// ballot either abstains on the target layer or supports the first block in it
+	ballot := func(vote sign, weight float64) *ballotInfo {
+		lvote := &layerVote{lid: target, vote: vote}
+		if vote == support {
+			lvote.vote = against
+			lvote.supported = full.layer(target).blocks[:1]
+		}
+		return &ballotInfo{
+			layer:     target.Add(1),
+			weight:    fixed.From(weight),
+			reference: &referenceInfo{},
+			votes:     votes{tail: lvote},
+		}
+	}
+	for i := 0; i < 6; i++ {
+		full.countBallot(logtest.Zap(t), ballot(abstain, 1))
+	}
+	for i := 0; i < 4; i++ {
+		full.countBallot(logtest.Zap(t), ballot(support, 1))
+	}
+	layer := full.layer(target)
+	require.Equal(t, fixed.From(6).String(), layer.abstained.String())
+	require.Equal(t, fixed.From(4).String(), layer.blocks[0].margin.String())
+	require.Equal(t, fixed.From(-4).String(), layer.empty.String())
+
+	// block that arrives late doesn't change abstained weight
+	// and receives votes against only from ballots that voted on the layer
+	late := &blockInfo{id: types.BlockID{2}, layer: target}
+	layer.blocks = append(layer.blocks, late)
+	counted := []*ballotInfo{ballot(abstain, 1), ballot(support, 1)}
+	for _, ballot := range counted {
+		ballot.votesCounted = true
+	}
+	full.ballots[target.Add(1)] = counted
+	full.counted = target.Add(1)
+	full.countForLateBlock(late)
+	require.Equal(t, fixed.From(-1).String(), late.margin.String())
+	require.Equal(t, fixed.From(6).String(), layer.abstained.String())
+}
+
+func TestFullLateBlockDelayedBallot(t *testing.T) {
+	target := types.GetEffectiveGenesis().Add(1)
+	full := newFullTortoise(Config{}, newState())
+	full.layer(target).blocks = []*blockInfo{{id: types.BlockID{1}, layer: target}}
+
+	ballot := &ballotInfo{
+		layer:     target.Add(1),
+		weight:    fixed.From(1),
+		reference: &referenceInfo{},
+		votes: votes{tail: &layerVote{
+			lid:       target,
+			vote:      against,
+			supported: full.layer(target).blocks[:1],
+		}},
+	}
+	// ballot is known to full tortoise, but it is delayed and wasn't counted yet
+	full.ballots[ballot.layer] = []*ballotInfo{ballot}
+	full.counted = ballot.layer
+
+	late := &blockInfo{id: types.BlockID{2}, layer: target}
+	full.layer(target).blocks = append(full.layer(target).blocks, late)
+	full.countForLateBlock(late)
+	require.Equal(t, fixed.From(0).String(), late.margin.String())
+
+	full.countBallot(logtest.Zap(t), ballot)
+	require.Equal(t, fixed.From(-1).String(), late.margin.String())
+	require.Equal(t, fixed.From(1).String(), full.layer(target).blocks[0].margin.String())
+}
+
+// genFullState generates ballots for layers [1, layers] that are linked the same way as decoded ballots.
+// Ballots share votes with the base ballot from the previous layer, and with probability exceptions
+// replace vote for one of the layers before the base.
+// Blocks have one of the heights distinct heights, reference heights of the ballots are spread in the same range.
+func genFullState(rng *rand.Rand, layers, size, blocks, heights int, exceptions float64) (*state, [][]*ballotInfo) {
+	s := newState()
+	genVote := func(lid types.LayerID, prev *layerVote) *layerVote {
+		lvote := &layerVote{lid: lid, vote: against, prev: prev}
+		switch rng.Intn(8) {
+		case 0:
+			lvote.vote = abstain
+		case 1:
+			// against every block, voting for the empty layer
+		default:
+			for _, block := range s.layer(lid).blocks {
+				if rng.Intn(3) == 0 {
+					lvote.supported = append(lvote.supported, block)
+				}
+			}
+		}
+		return lvote
+	}
+	var replace func(tail *layerVote, lid types.LayerID) *layerVote
+	replace = func(tail *layerVote, lid types.LayerID) *layerVote {
+		if tail.lid == lid {
+			return genVote(lid, tail.prev)
+		}
+		copied := *tail
+		copied.prev = replace(tail.prev, lid)
+		return &copied
+	}
+
+	var rst [][]*ballotInfo
+	for i := 1; i <= layers; i++ {
+		lid := types.LayerID(i)
+		layer := s.layer(lid)
+		for j := 0; j < blocks; j++ {
+			layer.blocks = append(layer.blocks, &blockInfo{
+				id:     types.BlockID{byte(i), byte(i >> 8), byte(j)},
+				layer:  lid,
+				height: uint64(rng.Intn(heights) * 10),
+			})
+		}
+		sortBlocks(layer.blocks)
+		var ballots []*ballotInfo
+		for j := 0; j < size; j++ {
+			ballot := &ballotInfo{
+				id:        types.BallotID{byte(i), byte(i >> 8), byte(j), byte(j >> 8)},
+				layer:     lid,
+				weight:    fixed.DivUint64(uint64(rng.Intn(100)+1), uint64(rng.Intn(7)+1)),
+				reference: &referenceInfo{height: uint64(rng.Intn(heights * 10))},
+			}
+			if i > 1 {
+				// most ballots select one of the few best ballots as a base
+				prev := rst[len(rst)-1]
+				base := prev[rng.Intn(3)%len(prev)]
+				tail := base.votes.tail
+				if tail != nil && rng.Float64() < exceptions {
+					tail = replace(tail, types.LayerID(rng.Intn(int(tail.lid))+1))
+				}
+				ballot.votes = votes{tail: genVote(lid.Sub(1), tail)}
+			}
+			s.addBallot(ballot)
+			ballots = append(ballots, ballot)
+		}
+		rst = append(rst, ballots)
+	}
+	last := types.LayerID(layers)
+	s.processed = last
+	s.last = last
+	for eid := types.EpochID(0); eid <= last.GetEpoch(); eid++ {
+		s.epochs[eid] = &epochInfo{
+			weight: fixed.New(size * 8 * int(types.GetLayersPerEpoch())),
+		}
+	}
+	s.localThreshold = fixed.New(size * 8 / localThresholdFraction)
+	return s, rst
+}
+
+func TestFullCountBallotsAggregated(t *testing.T) {
+	const (
+		layers = 20
+		size   = 20
+		blocks = 5
+	)
+	requireSame := func(t *testing.T, expected, actual *full) {
+		t.Helper()
+		logger := logtest.Zap(t)
+		for lid := types.LayerID(1); !lid.After(expected.processed); lid = lid.Add(1) {
+			elayer, alayer := expected.layer(lid), actual.layer(lid)
+			require.Equal(t, elayer.empty.String(), alayer.empty.String(), "layer %d", lid)
+			require.Equal(t, elayer.abstained.String(), alayer.abstained.String(), "layer %d", lid)
+			for i, block := range elayer.blocks {
+				require.Equal(t, block.margin.String(), alayer.blocks[i].margin.String(), "layer %d block %d", lid, i)
+			}
+		}
+		for lid := expected.evicted.Add(1); lid.Before(expected.processed); lid = lid.Add(1) {
+			ev, echanged := expected.verify(logger, lid)
+			av, achanged := actual.verify(logger, lid)
+			require.Equal(t, ev, av, "layer %d", lid)
+			require.Equal(t, echanged, achanged, "layer %d", lid)
+			for i, block := range expected.layer(lid).blocks {
+				require.Equal(t, block.validity, actual.layer(lid).blocks[i].validity, "layer %d block %d", lid, i)
+			}
+		}
+	}
+	for _, tc := range []struct {
+		desc       string
+		exceptions float64
+		evicted    types.LayerID
+		window     bool
+		heights    int
+	}{
+		{desc: "shared votes"},
+		{desc: "exceptions", exceptions: 0.3},
+		{desc: "every ballot with exception", exceptions: 1},
+		{desc: "evicted", exceptions: 0.3, evicted: 5},
+		{desc: "window", exceptions: 0.3, window: true},
+		{desc: "window evicted", exceptions: 0.3, evicted: 5, window: true},
+		{desc: "distinct heights", exceptions: 0.3, window: true, heights: layers * blocks},
+	} {
+		tc := tc
+		heights := tc.heights
+		if heights == 0 {
+			heights = 4
+		}
+		t.Run(tc.desc, func(t *testing.T) {
+			for seed := int64(0); seed < 20; seed++ {
+				gen := func() (*full, [][]*ballotInfo) {
+					state, ballots := genFullState(rand.New(rand.NewSource(seed)), layers, size, blocks, heights, tc.exceptions)
+					state.evicted = tc.evicted
+					return newFullTortoise(Config{}, state), ballots
+				}
+				expected, eballots := gen()
+				actual, aballots := gen()
+				var window []*ballotInfo
+				for i := range eballots {
+					for _, ballot := range eballots[i] {
+						expected.countBallot(logtest.Zap(t), ballot)
+					}
+					if tc.window {
+						window = append(window, aballots[i]...)
+					} else {
+						actual.countBallots(logtest.Zap(t), aballots[i])
+					}
+				}
+				if tc.window {
+					actual.countBallots(logtest.Zap(t), window)
+				}
+				requireSame(t, expected, actual)
+			}
+		})
+	}
+}
+
+func BenchmarkFullCountBallots(b *testing.B) {
+	const (
+		window = 100
+		size   = 200
+		blocks = 50
+	)
+	for _, bc := range []struct {
+		desc    string
+		heights int
+	}{
+		{desc: "FewHeights", heights: 4},
+		// every block and every smesher has a distinct height in a real network
+		{desc: "DistinctHeights", heights: window * blocks},
+	} {
+		state, ballots := genFullState(rand.New(rand.NewSource(1)), window+1, size, blocks, bc.heights, 0.01)
+		full := newFullTortoise(Config{}, state)
+		logger := logtest.Zap(b)
+		layer := ballots[len(ballots)-1]
+		var all []*ballotInfo
+		for _, ballots := range ballots {
+			all = append(all, ballots...)
+		}
+
+		b.Run(bc.desc+"/Layer/Ballot", func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				for _, ballot := range layer {
+					full.countBallot(logger, ballot)
+				}
+			}
+		})
+		b.Run(bc.desc+"/Layer/Aggregated", func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				full.countBallots(logger, layer)
+			}
+		})
+		b.Run(bc.desc+"/Window/Ballot", func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				for _, ballot := range all {
+					full.countBallot(logger, ballot)
+				}
+			}
+		})
+		b.Run(bc.desc+"/Window/Aggregated", func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				full.countBallots(logger, all)
+			}
+		})
+	}
+}
+
+func TestFullWeakCoinHealing(t *testing.T) {
+	const (
+		n        = 2
+		distance = 3
+		last     = 8
+	)
+	// network is split in half on the block in the first layer, so that margin for it stays zero
+	split := func(t *testing.T) *session {
+		s := newSession(t).withHdist(1).withZdist(1).withCoinflip(100, distance)
+		var activeset []*atxAction
+		for i := 0; i < n; i++ {
+			activeset = append(activeset, s.smesher(i).atx(1, new(aopt).height(100).weight(400)))
+		}
+		s.beacon(1, "a")
+		for i := 0; i < n; i++ {
+			s.smesher(i).atx(1).ballot(1, new(bopt).
+				beacon("a").
+				activeset(activeset...).
+				eligibilities(s.layerSize/n))
+		}
+		s.hareblock(1, "aa", 0)
+		for i := 0; i < n; i++ {
+			votes := new(evotes).base(s.smesher(i).atx(1).ballot(1))
+			if i == 0 {
+				votes = votes.support(1, "aa", 0)
+			}
+			s.smesher(i).atx(1).ballot(2, new(bopt).
+				eligibilities(s.layerSize/n).
+				votes(votes))
+		}
+		for l := 3; l <= last; l++ {
+			for i := 0; i < n; i++ {
+				s.smesher(i).atx(1).ballot(l, new(bopt).
+					eligibilities(s.layerSize/n).
+					votes(new(evotes).base(s.smesher(i).atx(1).ballot(l-1))))
+			}
+		}
+		s.tallyWait(last)
+		return s
+	}
+	validity := func(t *testing.T, trt *Tortoise) BlockOpinion {
+		t.Helper()
+		target := types.GetEffectiveGenesis().Add(1)
+		report, err := trt.OpinionReport(target, target)
+		require.NoError(t, err)
+		require.Len(t, report.Layers[0].Blocks, 1)
+		return report.Layers[0].Blocks[0]
+	}
+
+	t.Run("missing coin", func(t *testing.T) {
+		s := split(t)
+		// coin for other layer is not used
+		s.coin(distance, true)
+		trt := s.tortoise()
+		s.runOn(trt)
+		require.Equal(t, types.GetEffectiveGenesis(), trt.LatestComplete())
+		require.Equal(t, validityUndecided, validity(t, trt).Validity)
+	})
+	for _, coin := range []bool{true, false} {
+		coin := coin
+		t.Run(fmt.Sprintf("coin %v", coin), func(t *testing.T) {
+			s := split(t)
+			trt := s.tortoise()
+			s.runOn(trt)
+			require.Equal(t, types.GetEffectiveGenesis(), trt.LatestComplete())
+
+			trt.OnWeakCoin(types.GetEffectiveGenesis().Add(1+distance), coin)
+			trt.TallyVotes(context.Background(), types.GetEffectiveGenesis().Add(last))
+			require.Equal(t, types.GetEffectiveGenesis().Add(last-1), trt.LatestComplete())
+			block := validity(t, trt)
+			require.Zero(t, block.Margin)
+			require.Equal(t, DecidedByWeakCoin, block.Source)
+			if coin {
+				require.Equal(t, validityValid, block.Validity)
+			} else {
+				require.Equal(t, validityInvalid, block.Validity)
+			}
+		})
+	}
+	t.Run("disabled", func(t *testing.T) {
+		s := split(t).withCoinflip(0, distance)
+		s.coin(1+distance, true)
+		trt := s.tortoise()
+		s.runOn(trt)
+		require.Equal(t, types.GetEffectiveGenesis(), trt.LatestComplete())
+	})
+}
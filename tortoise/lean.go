@@ -0,0 +1,445 @@
+package tortoise
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/spacemeshos/go-spacemesh/common/types"
+	"github.com/spacemeshos/go-spacemesh/sql"
+	"github.com/spacemeshos/go-spacemesh/sql/ballots"
+)
+
+// Lean verifying mode.
+//
+// Verifying tortoise needs only the weight of the ballots that agree with local opinion,
+// votes for every layer in the window are needed only to count votes by the full tortoise and
+// to encode votes relative to the ballot that is not consistent with local opinion.
+// In lean mode ballot is dropped from the state once it was counted by the verifying tortoise.
+// Layer keeps sorted ids of such ballots, so that they can be used as base and reference ballots,
+// and their weight aggregated by the opinion, height and beacon, so that they can be counted again
+// if local opinion changes.
+//
+// Votes are needed when the full tortoise is started, when smesher is found malfeasant or when
+// ballot is not consistent with local opinion about layers before its base ballot. In such case
+// errLeanVotes is returned, the caller loads ballots from the database without holding the lock
+// (see Tortoise.restoreVotes) and lean ballots are replaced with decoded ballots.
+//
+// Ballots are lean only in verifying mode and only if they were not counted by the full tortoise.
+
+// errLeanVotes is returned if the state has lean ballots, but their votes are needed.
+var errLeanVotes = errors.New("votes of the lean ballots are not loaded")
+
+// enableLean replaces ballots that were counted by the verifying tortoise with lean ballots.
+// Votes are decoded from the database when needed.
+func (t *turtle) enableLean(db sql.Executor) {
+	t.db = db
+	retriable := map[*ballotInfo]struct{}{}
+	for e := t.retriable.Front(); e != nil; e = e.Next() {
+		retriable[e.Value.(*ballotInfo)] = struct{}{}
+	}
+	for lid := t.evicted.Add(1); !lid.After(t.processed); lid = lid.Add(1) {
+		counted := make([]*ballotInfo, 0, len(t.ballots[lid]))
+		for _, ballot := range t.ballots[lid] {
+			if _, exist := retriable[ballot]; !exist {
+				counted = append(counted, ballot)
+			}
+		}
+		t.compact(lid, counted)
+	}
+}
+
+// collapsible is true if votes of the ballot from the layer are not needed in the current mode.
+func (t *turtle) collapsible(lid types.LayerID) bool {
+	return t.db != nil && !t.isFull && len(t.late) == 0 && lid.After(t.full.counted)
+}
+
+// compact replaces ballots from the layer that were counted by the verifying tortoise with lean ballots.
+func (t *turtle) compact(lid types.LayerID, counted []*ballotInfo) {
+	if len(counted) == 0 || !t.collapsible(lid) {
+		return
+	}
+	layer := t.layer(lid)
+	compacted := map[*ballotInfo]struct{}{}
+	for _, ballot := range counted {
+		if ballot.malicious {
+			continue
+		}
+		layer.addLean(ballot, t.leanReference(lid.GetEpoch(), ballot.reference))
+		delete(t.ballotRefs, ballot.id)
+		compacted[ballot] = struct{}{}
+		if ballot.partial {
+			t.partialBallots--
+		}
+	}
+	if len(compacted) == 0 {
+		return
+	}
+	blts := t.ballots[lid]
+	remaining := blts[:0]
+	for _, ballot := range blts {
+		if _, exist := compacted[ballot]; !exist {
+			remaining = append(remaining, ballot)
+		}
+	}
+	for i := len(remaining); i < len(blts); i++ {
+		blts[i] = nil
+	}
+	if len(remaining) == 0 {
+		delete(t.ballots, lid)
+	} else {
+		t.ballots[lid] = remaining
+	}
+	t.leanBallots += len(compacted)
+	leanBallotsNumber.Add(float64(len(compacted)))
+}
+
+// referenceKey identifies reference info that can be shared by lean ballots.
+type referenceKey struct {
+	height     uint64
+	beacon     types.Beacon
+	num, denom uint64
+}
+
+// leanReference returns reference info with the same values as ref, it is shared by lean ballots
+// from the most recent epoch. Ballots from earlier epochs are rare and keep their own reference info.
+func (t *turtle) leanReference(epoch types.EpochID, ref *referenceInfo) *referenceInfo {
+	if epoch < t.leanEpoch {
+		return ref
+	}
+	if epoch > t.leanEpoch || t.leanReferences == nil {
+		t.leanEpoch = epoch
+		t.leanReferences = map[referenceKey]*referenceInfo{}
+	}
+	key := referenceKey{
+		height: ref.height,
+		beacon: ref.beacon,
+		num:    ref.weight.Num().Uint64(),
+		denom:  ref.weight.Denom().Uint64(),
+	}
+	if shared, exist := t.leanReferences[key]; exist {
+		return shared
+	}
+	t.leanReferences[key] = ref
+	return ref
+}
+
+// addLean adds weight of the ballot to the group with the same conditions and inserts its id.
+func (l *layerInfo) addLean(ballot *ballotInfo, reference *referenceInfo) {
+	var (
+		opinion = ballot.opinion()
+		group   = -1
+	)
+	for i := range l.leanGroups {
+		g := &l.leanGroups[i]
+		if g.opinion == opinion && g.height == ballot.reference.height &&
+			g.badBeacon == ballot.conditions.badBeacon && g.counted == ballot.goodCounted {
+			group = i
+			break
+		}
+	}
+	if group < 0 {
+		group = len(l.leanGroups)
+		l.leanGroups = append(l.leanGroups, leanGroup{
+			opinion:   opinion,
+			height:    ballot.reference.height,
+			badBeacon: ballot.conditions.badBeacon,
+			counted:   ballot.goodCounted,
+		})
+	}
+	l.leanGroups[group].weight = l.leanGroups[group].weight.Add(ballot.weight)
+
+	i := sort.Search(len(l.lean), func(i int) bool {
+		return bytes.Compare(l.lean[i].id[:], ballot.id[:]) >= 0
+	})
+	l.lean = append(l.lean, leanBallot{})
+	copy(l.lean[i+1:], l.lean[i:])
+	l.lean[i] = leanBallot{id: ballot.id, group: uint32(group), reference: reference}
+}
+
+// findLean returns lean ballot with the id or nil if it is not in the layer.
+func (l *layerInfo) findLean(id types.BallotID) *leanBallot {
+	i := sort.Search(len(l.lean), func(i int) bool {
+		return bytes.Compare(l.lean[i].id[:], id[:]) >= 0
+	})
+	if i < len(l.lean) && l.lean[i].id == id {
+		return &l.lean[i]
+	}
+	return nil
+}
+
+// leanInfo returns ballot that knows only the opinion of the lean ballot.
+// It is not added to the state.
+func (l *layerInfo) leanInfo(ballot *leanBallot) *ballotInfo {
+	group := &l.leanGroups[ballot.group]
+	return &ballotInfo{
+		id:          ballot.id,
+		layer:       l.lid,
+		weight:      group.weight,
+		reference:   ballot.reference,
+		conditions:  conditions{badBeacon: group.badBeacon},
+		goodCounted: group.counted,
+		lean:        true,
+		leanOpinion: group.opinion,
+	}
+}
+
+// leanInfos returns ballots for all lean ballots in the layer.
+func (l *layerInfo) leanInfos() []*ballotInfo {
+	rst := make([]*ballotInfo, 0, len(l.lean))
+	for i := range l.lean {
+		rst = append(rst, l.leanInfo(&l.lean[i]))
+	}
+	return rst
+}
+
+// findBallot returns the ballot with the id. Lean ballots are searched starting from the layer from
+// down to the layer after to, a lean ballot is returned without votes.
+func (t *turtle) findBallot(id types.BallotID, from, to types.LayerID) *ballotInfo {
+	if ballot, exist := t.ballotRefs[id]; exist {
+		return ballot
+	}
+	if t.leanBallots == 0 {
+		return nil
+	}
+	for lid := types.MinLayer(from, t.processed); lid.After(to); lid = lid.Sub(1) {
+		layer, exist := t.layers[lid]
+		if !exist {
+			continue
+		}
+		if ballot := layer.findLean(id); ballot != nil {
+			return layer.leanInfo(ballot)
+		}
+	}
+	return nil
+}
+
+// ensureVotes returns errLeanVotes if votes of some ballots are not in the state.
+func (t *turtle) ensureVotes() error {
+	if t.leanBallots > 0 || t.partialBallots > 0 {
+		return errLeanVotes
+	}
+	return nil
+}
+
+// leanLayers returns layers with lean ballots or ballots that were decoded relative to the lean base.
+func (t *turtle) leanLayers() []types.LayerID {
+	layers := map[types.LayerID]struct{}{}
+	for lid, layer := range t.layers {
+		if len(layer.lean) > 0 {
+			layers[lid] = struct{}{}
+		}
+	}
+	if t.partialBallots > 0 {
+		for lid, blts := range t.ballots {
+			for _, ballot := range blts {
+				if ballot.partial {
+					layers[lid] = struct{}{}
+					break
+				}
+			}
+		}
+	}
+	rst := make([]types.LayerID, 0, len(layers))
+	for lid := range layers {
+		rst = append(rst, lid)
+	}
+	sort.Slice(rst, func(i, j int) bool { return rst[i] < rst[j] })
+	return rst
+}
+
+// leanData is loaded from the database to restore votes of the lean ballots.
+type leanData struct {
+	layers []types.LayerID
+	// ballots from layers in the order they are stored.
+	layerBallots map[types.LayerID][]*types.Ballot
+	// ballots by id, including base and reference ballots from other layers.
+	ballots map[types.BallotID]*types.Ballot
+}
+
+func (d *leanData) get(id types.BallotID) (*types.Ballot, error) {
+	ballot, exist := d.ballots[id]
+	if !exist {
+		return nil, fmt.Errorf("ballot %s is not loaded: %w", id, sql.ErrNotFound)
+	}
+	return ballot, nil
+}
+
+// loadLean loads ballots from the layers, and base and reference ballots they depend on.
+// It doesn't access the state and is called without holding the lock.
+func loadLean(db sql.Executor, layers []types.LayerID) (*leanData, error) {
+	data := &leanData{
+		layers:       layers,
+		layerBallots: map[types.LayerID][]*types.Ballot{},
+		ballots:      map[types.BallotID]*types.Ballot{},
+	}
+	for _, lid := range layers {
+		blts, err := ballots.Layer(db, lid)
+		if err != nil {
+			return nil, fmt.Errorf("load ballots in layer %d: %w", lid, err)
+		}
+		data.layerBallots[lid] = blts
+		for _, ballot := range blts {
+			data.ballots[ballot.ID()] = ballot
+		}
+	}
+	for _, lid := range layers {
+		for _, ballot := range data.layerBallots[lid] {
+			deps := []types.BallotID{ballot.Votes.Base}
+			if ballot.EpochData == nil {
+				deps = append(deps, ballot.RefBallot)
+			}
+			for _, id := range deps {
+				if id == types.EmptyBallotID {
+					continue
+				}
+				if _, exist := data.ballots[id]; exist {
+					continue
+				}
+				dep, err := ballots.Get(db, id)
+				if errors.Is(err, sql.ErrNotFound) {
+					// ballot is in the state, otherwise decoding fails
+					continue
+				} else if err != nil {
+					return nil, fmt.Errorf("load ballot %s: %w", id, err)
+				}
+				data.ballots[id] = dep
+			}
+		}
+	}
+	return data, nil
+}
+
+// restoreVotes replaces lean ballots from the loaded layers with ballots decoded from the loaded data,
+// and decodes again ballots that were decoded relative to the lean base.
+// Ballots that became lean after data was loaded are left lean. Returns the number of restored ballots.
+func (t *turtle) restoreVotes(data *leanData) (int, error) {
+	start := time.Now()
+	var (
+		refs     = map[types.BallotID]*referenceInfo{}
+		restored = 0
+	)
+	for _, lid := range data.layers {
+		if !lid.After(t.evicted) {
+			continue
+		}
+		layer := t.layers[lid]
+		order := map[types.BallotID]int{}
+		for i, ballot := range data.layerBallots[lid] {
+			id := ballot.ID()
+			order[id] = i
+			var lean *leanBallot
+			if layer != nil {
+				lean = layer.findLean(id)
+			}
+			existing := t.ballotRefs[id]
+			if lean == nil && (existing == nil || !existing.partial) {
+				continue
+			}
+			if base := ballot.Votes.Base; base != types.EmptyBallotID && t.ballotRefs[base] == nil &&
+				t.findBallot(base, lid.Sub(1), t.evicted) != nil {
+				// base became lean after data was loaded, it will be restored by the next call
+				return restored, nil
+			}
+			decoded, err := t.decodeRecovered(data.get, ballot.ToTortoiseData(), refs)
+			if err != nil {
+				return restored, fmt.Errorf("decode votes of ballot %s: %w", id, err)
+			}
+			for current := decoded.votes.tail; current != nil; current = current.prev {
+				for i, block := range current.supported {
+					if existing := t.getBlock(block.header()); existing != nil {
+						current.supported[i] = existing
+					} else {
+						t.addBlock(block)
+					}
+				}
+			}
+			if lean == nil {
+				existing.votes = decoded.votes
+				existing.partial = false
+				t.partialBallots--
+				restored++
+				continue
+			}
+			group := &layer.leanGroups[lean.group]
+			group.weight = group.weight.Sub(decoded.weight)
+			decoded.conditions.badBeacon = group.badBeacon
+			decoded.goodCounted = group.counted && !decoded.malicious
+			layer.removeLean(id)
+			t.addBallot(decoded)
+			ballotsNumber.Dec() // lean ballot was already counted
+			t.leanBallots--
+			leanBallotsNumber.Dec()
+			restored++
+		}
+		if layer != nil && len(layer.lean) == 0 {
+			layer.lean = nil
+			layer.leanGroups = nil
+		}
+		// restored ballots are in the same order as if they were never lean
+		blts := t.ballots[lid]
+		sort.SliceStable(blts, func(i, j int) bool {
+			oi, iexist := order[blts[i].id]
+			oj, jexist := order[blts[j].id]
+			if iexist != jexist {
+				return iexist
+			}
+			return oi < oj
+		})
+	}
+	t.logger.Debug("restored votes of lean ballots",
+		zap.Int("ballots", restored),
+		zap.Duration("duration", time.Since(start)),
+	)
+	return restored, nil
+}
+
+// removeLean removes lean ballot with the id, weight of the group is updated by the caller.
+func (l *layerInfo) removeLean(id types.BallotID) {
+	i := sort.Search(len(l.lean), func(i int) bool {
+		return bytes.Compare(l.lean[i].id[:], id[:]) >= 0
+	})
+	if i < len(l.lean) && l.lean[i].id == id {
+		l.lean = append(l.lean[:i], l.lean[i+1:]...)
+	}
+}
+
+// restoreVotes loads votes of the lean ballots from the database and applies them to the state.
+// Must be called with t.mu held, the lock is released while ballots are loaded.
+func (t *Tortoise) restoreVotes() error {
+	for t.trtl.ensureVotes() != nil {
+		layers, db := t.trtl.leanLayers(), t.trtl.db
+		t.mu.Unlock()
+		data, err := loadLean(db, layers)
+		t.mu.Lock()
+		if err != nil {
+			return err
+		}
+		restored, err := t.trtl.restoreVotes(data)
+		if err != nil {
+			return err
+		}
+		if restored == 0 && t.trtl.ensureVotes() != nil {
+			return fmt.Errorf("votes of %d lean ballots are not in the database", t.trtl.leanBallots)
+		}
+	}
+	return nil
+}
+
+// withVotes calls fn and, if it needs votes of the lean ballots, restores them and calls it again.
+// Must be called with t.mu held.
+func (t *Tortoise) withVotes(fn func() error) error {
+	for {
+		err := fn()
+		if !errors.Is(err, errLeanVotes) {
+			return err
+		}
+		if err := t.restoreVotes(); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,223 @@
+package tortoise
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"runtime"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/spacemeshos/go-spacemesh/codec"
+	"github.com/spacemeshos/go-spacemesh/common/types"
+	"github.com/spacemeshos/go-spacemesh/log/logtest"
+	"github.com/spacemeshos/go-spacemesh/sql"
+	"github.com/spacemeshos/go-spacemesh/sql/ballots"
+	"github.com/spacemeshos/go-spacemesh/tortoise/sim"
+)
+
+// unlockedExecutor fails the test if the database is accessed while the lock is held.
+type unlockedExecutor struct {
+	sql.Executor
+	tb testing.TB
+	mu *sync.Mutex
+}
+
+func (e *unlockedExecutor) Exec(query string, enc sql.Encoder, dec sql.Decoder) (int, error) {
+	require.True(e.tb, e.mu.TryLock(), "database is accessed while the lock is held")
+	e.mu.Unlock()
+	return e.Executor.Exec(query, enc, dec)
+}
+
+// failingExecutor fails every query.
+type failingExecutor struct {
+	err error
+}
+
+func (e *failingExecutor) Exec(string, sql.Encoder, sql.Decoder) (int, error) {
+	return 0, e.err
+}
+
+func TestLeanVerifying(t *testing.T) {
+	const (
+		size   = 10
+		layers = 40
+	)
+	for _, tc := range []struct {
+		desc string
+		// layers with empty hare output, verifying tortoise stalls and full tortoise is needed
+		stall [2]int
+		// smesher of the first ballot in the layer is found malfeasant after the layer
+		malfeasance int
+	}{
+		{desc: "healthy"},
+		{desc: "stall", stall: [2]int{10, 18}},
+		{desc: "malfeasance", malfeasance: 20},
+	} {
+		tc := tc
+		t.Run(tc.desc, func(t *testing.T) {
+			ctx := context.Background()
+			s := sim.New(sim.WithLayerSize(size))
+			s.Setup()
+			cfg := defaultTestConfig()
+			cfg.LayerSize = size
+			cfg.Hdist = 4
+			cfg.Zdist = 2
+			cfg.WindowSize = 20
+
+			expected := tortoiseFromSimState(t, s.GetState(0), WithConfig(cfg), WithLogger(logtest.New(t)))
+			lean := tortoiseFromSimState(t, s.GetState(0), WithConfig(cfg), WithLogger(logtest.New(t)))
+			lean.trtl.enableLean(&unlockedExecutor{Executor: s.GetState(0).DB, tb: t, mu: &lean.mu})
+
+			for i := 0; i < layers; i++ {
+				var last types.LayerID
+				if i >= tc.stall[0] && i < tc.stall[1] {
+					last = s.Next(sim.WithEmptyHareOutput(), sim.WithNumBlocks(1))
+				} else {
+					last = s.Next()
+				}
+				expected.TallyVotes(ctx, last)
+				lean.TallyVotes(ctx, last)
+				if tc.malfeasance != 0 && i == tc.malfeasance {
+					blts, err := ballots.Layer(s.GetState(0).DB, last)
+					require.NoError(t, err)
+					require.NotEmpty(t, blts)
+					expected.OnMalfeasance(blts[0].SmesherID)
+					lean.OnMalfeasance(blts[0].SmesherID)
+				}
+				require.Equal(t, expected.LatestComplete(), lean.LatestComplete(), "layer=%s", last)
+				require.Equal(t, expected.Updates(), lean.Updates(), "layer=%s", last)
+
+				if lean.trtl.isFull {
+					// votes are needed by the full tortoise
+					require.Zero(t, lean.trtl.leanBallots, "layer=%s", last)
+				}
+				eopinion, err := expected.EncodeVotes(ctx, EncodeVotesWithCurrent(last.Add(1)))
+				require.NoError(t, err)
+				opinion, err := lean.EncodeVotes(ctx, EncodeVotesWithCurrent(last.Add(1)))
+				require.NoError(t, err)
+				require.Equal(t, codec.MustEncode(eopinion), codec.MustEncode(opinion), "layer=%s", last)
+			}
+			require.False(t, lean.trtl.isFull)
+			require.Positive(t, lean.trtl.leanBallots)
+		})
+	}
+}
+
+func TestLeanVerifyingRestoreError(t *testing.T) {
+	const size = 10
+	ctx := context.Background()
+	s := sim.New(sim.WithLayerSize(size))
+	s.Setup()
+	cfg := defaultTestConfig()
+	cfg.LayerSize = size
+
+	errDB := errors.New("test")
+	trtl := tortoiseFromSimState(t, s.GetState(0), WithConfig(cfg), WithLogger(logtest.New(t)))
+	trtl.trtl.enableLean(&failingExecutor{err: errDB})
+	var last types.LayerID
+	for i := 0; i < 10; i++ {
+		last = s.Next()
+		trtl.TallyVotes(ctx, last)
+	}
+	require.Equal(t, last.Sub(1), trtl.LatestComplete())
+	require.Positive(t, trtl.trtl.leanBallots)
+
+	// votes are needed to dump the state and to start the full tortoise
+	require.ErrorIs(t, trtl.DumpState(&bytes.Buffer{}), errDB)
+	for i := 0; i < 10; i++ {
+		last = s.Next(sim.WithEmptyHareOutput(), sim.WithNumBlocks(1))
+		trtl.TallyVotes(ctx, last)
+	}
+	trtl.mu.Lock()
+	defer trtl.mu.Unlock()
+	require.False(t, trtl.trtl.isFull)
+	require.ErrorIs(t, trtl.withVotes(trtl.trtl.verifyLayers), errDB)
+}
+
+func TestRecoverLeanVerifying(t *testing.T) {
+	const size = 10
+	ctx := context.Background()
+	s := sim.New(sim.WithLayerSize(size))
+	s.Setup()
+	var last types.LayerID
+	for i := 0; i < 20; i++ {
+		last = s.Next()
+	}
+	cfg := defaultTestConfig()
+	cfg.LayerSize = size
+	expected, err := Recover(s.GetState(0).DB, s.GetState(0).Beacons, WithLogger(logtest.New(t)), WithConfig(cfg))
+	require.NoError(t, err)
+	require.Zero(t, expected.trtl.leanBallots)
+
+	cfg.LeanVerifying = true
+	lean, err := Recover(s.GetState(0).DB, s.GetState(0).Beacons, WithLogger(logtest.New(t)), WithConfig(cfg))
+	require.NoError(t, err)
+	require.Positive(t, lean.trtl.leanBallots)
+	require.Equal(t, expected.LatestComplete(), lean.LatestComplete())
+
+	eopinion, err := expected.EncodeVotes(ctx, EncodeVotesWithCurrent(last.Add(1)))
+	require.NoError(t, err)
+	opinion, err := lean.EncodeVotes(ctx, EncodeVotesWithCurrent(last.Add(1)))
+	require.NoError(t, err)
+	require.Equal(t, codec.MustEncode(eopinion), codec.MustEncode(opinion))
+
+	// restored state is the same as the state that was never lean, except for the config
+	expected.trtl.LeanVerifying = true
+	var ebuf, buf bytes.Buffer
+	require.NoError(t, expected.DumpState(&ebuf))
+	require.NoError(t, lean.DumpState(&buf))
+	require.Zero(t, lean.trtl.leanBallots, "votes are restored to dump the state")
+	require.Equal(t, ebuf.Bytes(), buf.Bytes())
+}
+
+func BenchmarkLeanVerifying(b *testing.B) {
+	b.Run("Default", func(b *testing.B) {
+		benchmarkLeanVerifying(b, false)
+	})
+	b.Run("Lean", func(b *testing.B) {
+		benchmarkLeanVerifying(b, true)
+	})
+}
+
+// benchmarkLeanVerifying reports heap retained by the tortoise state after a healthy run.
+func benchmarkLeanVerifying(b *testing.B, lean bool) {
+	const (
+		size   = 50
+		layers = 200
+	)
+	s := sim.New(
+		sim.WithLayerSize(size),
+		sim.WithPath(b.TempDir()),
+	)
+	s.Setup()
+	var last types.LayerID
+	for i := 0; i < layers; i++ {
+		last = s.Next()
+	}
+	ctx := context.Background()
+	cfg := defaultTestConfig()
+	cfg.LayerSize = size
+	cfg.WindowSize = layers
+
+	var retained uint64
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		var before, after runtime.MemStats
+		runtime.GC()
+		runtime.ReadMemStats(&before)
+		trtl := tortoiseFromSimState(b, s.GetState(0), WithConfig(cfg), WithLogger(logtest.New(b)))
+		if lean {
+			trtl.trtl.enableLean(s.GetState(0).DB)
+		}
+		trtl.TallyVotes(ctx, last)
+		require.Equal(b, last.Sub(1), trtl.LatestComplete())
+		runtime.GC()
+		runtime.ReadMemStats(&after)
+		retained += after.HeapAlloc - before.HeapAlloc
+		runtime.KeepAlive(trtl)
+	}
+	b.ReportMetric(float64(retained)/float64(b.N), "retained-bytes/op")
+}
@@ -21,6 +21,18 @@ var (
 		"Number of ballots that are delayed due to the wrong beacon",
 		[]string{},
 	).WithLabelValues()
+	delayedWeight = metrics.NewGauge(
+		"delayed_weight",
+		namespace,
+		"Weight of ballots that are delayed due to the wrong beacon",
+		[]string{},
+	).WithLabelValues()
+	leanBallotsNumber = metrics.NewGauge(
+		"lean_ballots",
+		namespace,
+		"Number of lean ballots that are kept only by id and aggregated weight",
+		[]string{},
+	).WithLabelValues()
 	blocksNumber = metrics.NewGauge(
 		"blocks",
 		namespace,
@@ -33,6 +45,18 @@ var (
 		"Number of layers in the state",
 		[]string{},
 	).WithLabelValues()
+	queuedInputs = metrics.NewGauge(
+		"queued_inputs",
+		namespace,
+		"Number of ballots, blocks and hare outputs that are queued but not applied to the state",
+		[]string{},
+	).WithLabelValues()
+	windowLayers = metrics.NewGauge(
+		"window_layers",
+		namespace,
+		"Maximal number of layers that are kept in memory after the verified layer",
+		[]string{},
+	).WithLabelValues()
 	epochsNumber = metrics.NewGauge(
 		"epochs",
 		namespace,
@@ -102,7 +126,6 @@ var (
 		[]string{"step"},
 		prometheus.ExponentialBuckets(100_000, 2, 10),
 	)
-	waitBlockDuration = onBlockHist.WithLabelValues("wait")
 	addBlockDuration  = onBlockHist.WithLabelValues("add")
 	lateBlockDuration = onBlockHist.WithLabelValues("late")
 )
@@ -115,8 +138,7 @@ var (
 		[]string{"step"},
 		prometheus.ExponentialBuckets(100_000, 2, 10),
 	)
-	waitHareOutputDuration = onHareOutputHist.WithLabelValues("wait")
-	addHareOutput          = onHareOutputHist.WithLabelValues("add")
+	addHareOutput = onHareOutputHist.WithLabelValues("add")
 )
 
 var (
@@ -141,6 +163,9 @@ var (
 	)
 	waitTallyVotes    = tallyVotesHist.WithLabelValues("wait")
 	executeTallyVotes = tallyVotesHist.WithLabelValues("execute")
+	persistTallyVotes = tallyVotesHist.WithLabelValues("persist")
+	// full tortoise counts ballots from the layer or from the whole window at once
+	fcountBallotsDuration = tallyVotesHist.WithLabelValues("full_count")
 )
 
 var (
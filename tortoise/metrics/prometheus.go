@@ -0,0 +1,87 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/spacemeshos/go-spacemesh/metrics"
+)
+
+const (
+	// subsystem is a subsystem shared by all metrics exposed by this package.
+	subsystem = "tortoise"
+)
+
+// VerificationLag is the number of layers between the last layer and the verified layer.
+var VerificationLag = metrics.NewGauge(
+	"verification_lag",
+	subsystem,
+	"Number of layers between the last layer and the verified layer",
+	[]string{},
+).WithLabelValues()
+
+var tallyVotesHist = metrics.NewHistogramWithBuckets(
+	"tally_votes_seconds",
+	subsystem,
+	"Time to tally votes for a layer in seconds",
+	[]string{},
+	prometheus.ExponentialBuckets(0.0001, 2, 16),
+)
+
+// TallyVotesDuration is the time to tally votes for a layer in seconds.
+var TallyVotesDuration = tallyVotesHist.WithLabelValues()
+
+// ProcessedBallots is the number of ballots that were added to the tortoise state.
+var ProcessedBallots = metrics.NewCounter(
+	"processed_ballots_total",
+	subsystem,
+	"Number of ballots added to the state",
+	[]string{},
+).WithLabelValues()
+
+var rejectedBallots = metrics.NewCounter(
+	"rejected_ballots_total",
+	subsystem,
+	"Number of ballots that were not counted as good by the verifying tortoise",
+	[]string{"reason"},
+)
+
+var (
+	// RejectedBadBeacon is the number of ballots with beacon that doesn't match local beacon.
+	RejectedBadBeacon = rejectedBallots.WithLabelValues("bad_beacon")
+	// RejectedOpinion is the number of ballots with opinion that doesn't match local opinion.
+	RejectedOpinion = rejectedBallots.WithLabelValues("opinion")
+	// RejectedHeight is the number of ballots with reference height below the height
+	// of the blocks in the previous layer.
+	RejectedHeight = rejectedBallots.WithLabelValues("height")
+	// RejectedMalicious is the number of ballots from malicious identities.
+	RejectedMalicious = rejectedBallots.WithLabelValues("malicious")
+)
+
+// ValidityFlips is the number of times when validity of the decided block was reversed.
+var ValidityFlips = metrics.NewCounter(
+	"validity_flips_total",
+	subsystem,
+	"Number of times validity of the decided block was reversed",
+	[]string{},
+).WithLabelValues()
+
+// HealingActivations is the number of times tortoise switched to the full (healing) mode.
+var HealingActivations = metrics.NewCounter(
+	"healing_activations_total",
+	subsystem,
+	"Number of times tortoise switched to the full mode",
+	[]string{},
+).WithLabelValues()
+
+// Collectors returns all collectors exposed by this package, so that they can be registered
+// with a registry other than the default one.
+func Collectors() []prometheus.Collector {
+	return []prometheus.Collector{
+		VerificationLag,
+		tallyVotesHist,
+		ProcessedBallots,
+		rejectedBallots,
+		ValidityFlips,
+		HealingActivations,
+	}
+}
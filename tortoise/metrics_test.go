@@ -0,0 +1,89 @@
+package tortoise
+
+import (
+	"context"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"github.com/spacemeshos/go-spacemesh/common/types"
+	"github.com/spacemeshos/go-spacemesh/log/logtest"
+	tmetrics "github.com/spacemeshos/go-spacemesh/tortoise/metrics"
+	"github.com/spacemeshos/go-spacemesh/tortoise/sim"
+)
+
+func tallySamples(tb testing.TB, registry *prometheus.Registry) uint64 {
+	tb.Helper()
+	families, err := registry.Gather()
+	require.NoError(tb, err)
+	for _, family := range families {
+		if family.GetName() == "spacemesh_tortoise_tally_votes_seconds" {
+			return family.GetMetric()[0].GetHistogram().GetSampleCount()
+		}
+	}
+	require.FailNow(tb, "tally votes histogram is not registered")
+	return 0
+}
+
+func TestMetrics(t *testing.T) {
+	const (
+		size   = 10
+		layers = 50
+	)
+	registry := prometheus.NewPedanticRegistry()
+	registry.MustRegister(tmetrics.Collectors()...)
+
+	processed := testutil.ToFloat64(tmetrics.ProcessedBallots)
+	healing := testutil.ToFloat64(tmetrics.HealingActivations)
+	samples := tallySamples(t, registry)
+
+	ctx := context.Background()
+	s := sim.New(sim.WithLayerSize(size))
+	s.Setup()
+	cfg := defaultTestConfig()
+	cfg.LayerSize = size
+	cfg.Hdist = 2
+	cfg.Zdist = 2
+	trtl := tortoiseFromSimState(t, s.GetState(0), WithConfig(cfg), WithLogger(logtest.New(t)))
+	var last types.LayerID
+	for i := 0; i < layers; i++ {
+		var opts []sim.NextOpt
+		if i >= 10 && i < 20 {
+			// without hare output layers can be verified only after healing
+			opts = append(opts, sim.WithEmptyHareOutput(), sim.WithNumBlocks(1))
+		}
+		last = s.Next(opts...)
+		trtl.TallyVotes(ctx, last)
+		require.Equal(t, float64(last)-float64(trtl.LatestComplete()), testutil.ToFloat64(tmetrics.VerificationLag))
+	}
+	require.Equal(t, last.Sub(1), trtl.LatestComplete())
+	require.Equal(t, 1.0, testutil.ToFloat64(tmetrics.VerificationLag))
+
+	require.GreaterOrEqual(t, testutil.ToFloat64(tmetrics.ProcessedBallots)-processed, float64(layers*size))
+	require.Greater(t, testutil.ToFloat64(tmetrics.HealingActivations), healing)
+	// metrics are global, simulator may tally votes with its own instance
+	require.GreaterOrEqual(t, tallySamples(t, registry), samples+layers)
+	problems, err := testutil.GatherAndLint(registry)
+	require.NoError(t, err)
+	require.Empty(t, problems)
+}
+
+func TestMetricsValidityFlips(t *testing.T) {
+	flips := testutil.ToFloat64(tmetrics.ValidityFlips)
+	blocks := []*blockInfo{
+		{id: types.BlockID{1}, validity: support},
+		{id: types.BlockID{2}},
+	}
+	decisions := map[types.BlockID]sign{{1}: against, {2}: support}
+	decide := func(block *blockInfo) (sign, DecisionSource) {
+		return decisions[block.id], DecidedByHealing
+	}
+	verified, changed := verifyLayer(zaptest.NewLogger(t), blocks, decide)
+	require.True(t, verified)
+	require.True(t, changed)
+	// decision for the second block was abstain before, it is not a flip
+	require.Equal(t, flips+1, testutil.ToFloat64(tmetrics.ValidityFlips))
+}
@@ -0,0 +1,600 @@
+package tortoise
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/spacemeshos/go-spacemesh/common/types"
+	"github.com/spacemeshos/go-spacemesh/common/types/result"
+	"github.com/spacemeshos/go-spacemesh/datastore"
+	"github.com/spacemeshos/go-spacemesh/sql"
+	"github.com/spacemeshos/go-spacemesh/sql/atxs"
+	"github.com/spacemeshos/go-spacemesh/sql/ballots"
+	"github.com/spacemeshos/go-spacemesh/sql/blocks"
+	"github.com/spacemeshos/go-spacemesh/sql/certificates"
+	"github.com/spacemeshos/go-spacemesh/sql/identities"
+	"github.com/spacemeshos/go-spacemesh/sql/layers"
+	"github.com/spacemeshos/go-spacemesh/sql/tortoisestate"
+	"github.com/spacemeshos/go-spacemesh/system"
+	tmetrics "github.com/spacemeshos/go-spacemesh/tortoise/metrics"
+)
+
+// stateVersion must be changed together with the meaning of the persisted state.
+// State with other version is ignored and recomputed from scratch.
+const stateVersion = 1
+
+var errStateMismatch = errors.New("tortoise: persisted state doesn't match database")
+
+// stateConfig identifies parameters that were used to compute persisted state.
+func stateConfig(cfg Config) types.Hash32 {
+	return types.CalcHash32([]byte(fmt.Sprintf("%d/%d/%d/%d/%d/%d/%d/%d/%d/%d",
+		cfg.Hdist,
+		cfg.Zdist,
+		cfg.WindowSize,
+		cfg.BadBeaconVoteDelayLayers,
+		cfg.CoinflipTheta,
+		cfg.HealingDistance,
+		cfg.MinimalActiveSetWeight,
+		cfg.LayerSize,
+		types.GetLayersPerEpoch(),
+		types.GetEffectiveGenesis(),
+	)))
+}
+
+// persist writes state that changed since the previous call in a single transaction.
+// It is called after every tallied layer if tortoise was recovered from database.
+func (t *Tortoise) persist(ctx context.Context) {
+	start := time.Now()
+	if err := t.db.WithTx(ctx, func(tx *sql.Tx) error {
+		return t.trtl.persist(tx)
+	}); err != nil {
+		// changes are not marked as persisted and will be written after the next layer
+		t.logger.Error("failed to persist tortoise state",
+			zap.Uint32("processed", t.trtl.processed.Uint32()),
+			zap.Error(err),
+		)
+		return
+	}
+	t.trtl.persisted = t.trtl.processed
+	t.trtl.dirty = 0
+	t.trtl.dirtyBallots = 0
+	persistTallyVotes.Observe(float64(time.Since(start).Nanoseconds()))
+}
+
+func (t *turtle) persist(db sql.Executor) error {
+	var (
+		start       = maxLayer(t.evicted.Add(1), types.GetEffectiveGenesis())
+		from        = t.persisted.Add(1)
+		ballotsFrom = t.persisted.Add(1)
+	)
+	if t.persisted == 0 {
+		if err := tortoisestate.Clear(db); err != nil {
+			return err
+		}
+		from, ballotsFrom = start, start
+		// opinion of the evicted layer is a prefix for the opinion of the first layer in the window
+		if first := t.layers[start]; first != nil && first.prevOpinion != nil {
+			if err := tortoisestate.SetLayer(db, &tortoisestate.Layer{
+				ID:      start.Sub(1),
+				Opinion: *first.prevOpinion,
+			}); err != nil {
+				return err
+			}
+		}
+	}
+	if t.dirty != 0 {
+		from = types.MinLayer(from, t.dirty)
+	}
+	if t.dirtyBallots != 0 {
+		ballotsFrom = types.MinLayer(ballotsFrom, t.dirtyBallots)
+	}
+	for lid := maxLayer(from, start); !lid.After(t.processed); lid = lid.Add(1) {
+		layer, exist := t.layers[lid]
+		if !exist {
+			continue
+		}
+		if err := tortoisestate.SetLayer(db, &tortoisestate.Layer{
+			ID:              lid,
+			Opinion:         layer.opinion,
+			HareTerminated:  layer.hareTerminated,
+			Coinflip:        int8(layer.coinflip),
+			Empty:           layer.empty,
+			Abstained:       layer.abstained,
+			GoodUncounted:   layer.verifying.goodUncounted,
+			ReferenceHeight: layer.verifying.referenceHeight,
+		}); err != nil {
+			return err
+		}
+		for _, block := range layer.blocks {
+			if err := tortoisestate.SetBlock(db, &tortoisestate.Block{
+				ID:       block.id,
+				Layer:    block.layer,
+				Height:   block.height,
+				Hare:     int8(block.hare),
+				Validity: int8(block.validity),
+				Margin:   block.margin,
+				Data:     block.data,
+			}); err != nil {
+				return err
+			}
+		}
+	}
+	if !ballotsFrom.After(t.processed) {
+		delayed := map[types.BallotID]struct{}{}
+		for _, blts := range t.full.delayed {
+			for _, ballot := range blts {
+				delayed[ballot.id] = struct{}{}
+			}
+		}
+		retriable := map[types.BallotID]struct{}{}
+		for e := t.retriable.Front(); e != nil; e = e.Next() {
+			retriable[e.Value.(*ballotInfo).id] = struct{}{}
+		}
+		for lid := maxLayer(ballotsFrom, start); !lid.After(t.processed); lid = lid.Add(1) {
+			for _, ballot := range t.ballots[lid] {
+				_, isDelayed := delayed[ballot.id]
+				_, isRetriable := retriable[ballot.id]
+				if err := tortoisestate.SetBallot(db, &tortoisestate.Ballot{
+					ID:        ballot.id,
+					Layer:     ballot.layer,
+					BadBeacon: ballot.conditions.badBeacon,
+					Delayed:   isDelayed,
+					Counted:   !isRetriable,
+					Good:      ballot.goodCounted,
+					Votes:     ballot.votesCounted,
+				}); err != nil {
+					return err
+				}
+			}
+			layer, exist := t.layers[lid]
+			if !exist {
+				continue
+			}
+			for _, ballot := range layer.lean {
+				group := &layer.leanGroups[ballot.group]
+				if err := tortoisestate.SetBallot(db, &tortoisestate.Ballot{
+					ID:        ballot.id,
+					Layer:     lid,
+					BadBeacon: group.badBeacon,
+					Counted:   true,
+					Good:      group.counted,
+				}); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	if err := tortoisestate.SetState(db, &tortoisestate.State{
+		Version:    stateVersion,
+		Config:     stateConfig(t.Config),
+		Last:       t.last,
+		Processed:  t.processed,
+		Verified:   t.verified,
+		Evicted:    t.evicted,
+		Counted:    t.full.counted,
+		Full:       t.isFull,
+		GoodWeight: t.verifying.totalGoodWeight,
+	}); err != nil {
+		return err
+	}
+	return tortoisestate.Prune(db, t.evicted)
+}
+
+// recoverPersisted loads state that was persisted by the tortoise and ballots, blocks and other inputs
+// for layers in the window from the database. Inputs that were stored in the database after
+// the state was persisted are applied on top of it.
+//
+// Returns the last processed layer, the caller is expected to recover layers after it.
+func recoverPersisted(
+	trtl *Tortoise,
+	db *datastore.CachedDB,
+	beacon system.BeaconGetter,
+) (types.LayerID, error) {
+	persisted, err := tortoisestate.GetState(db)
+	if err != nil {
+		return 0, err
+	}
+	if persisted.Version != stateVersion {
+		return 0, fmt.Errorf("%w: version %d, expected %d", errStateMismatch, persisted.Version, stateVersion)
+	}
+	if persisted.Config != stateConfig(trtl.cfg) {
+		return 0, fmt.Errorf("%w: config changed", errStateMismatch)
+	}
+	genesis := types.GetEffectiveGenesis()
+	if persisted.Processed.Before(genesis) || persisted.Evicted.After(persisted.Processed) {
+		return 0, fmt.Errorf("%w: processed %d, evicted %d", errStateMismatch, persisted.Processed, persisted.Evicted)
+	}
+
+	malicious, err := identities.GetMalicious(db)
+	if err != nil {
+		return 0, fmt.Errorf("recover malicious %w", err)
+	}
+	for _, id := range malicious {
+		trtl.OnMalfeasance(id)
+	}
+
+	t := trtl.trtl
+	t.last = persisted.Last
+	t.processed = persisted.Processed
+	t.verified = persisted.Verified
+	t.evicted = persisted.Evicted
+	t.full.counted = persisted.Counted
+	t.isFull = persisted.Full
+	t.verifying.totalGoodWeight = persisted.GoodWeight
+	for lid := range t.layers {
+		if !lid.After(t.evicted) {
+			delete(t.layers, lid)
+		}
+	}
+	first := t.evicted.Add(1).GetEpoch()
+	for eid := range t.epochs {
+		if eid < first {
+			delete(t.epochs, eid)
+		}
+	}
+
+	epoch, err := atxs.LatestEpoch(db)
+	if err != nil {
+		return 0, fmt.Errorf("failed to load latest epoch: %w", err)
+	}
+	for eid := first; eid <= epoch+1; eid++ {
+		if err := recoverEpoch(eid, trtl, db, beacon); err != nil {
+			return 0, err
+		}
+		if lid := eid.FirstLayer(); lid.After(genesis) && !lid.After(t.processed) {
+			t.computeEpochHeight(eid)
+		}
+	}
+
+	if err := t.recoverTallies(db); err != nil {
+		return 0, err
+	}
+	late, err := t.recoverBallots(db)
+	if err != nil {
+		return 0, err
+	}
+
+	// pending is the first layer where opinion differs from the opinion stored by the mesh
+	t.pending = maxLayer(t.evicted.Add(1), genesis)
+	for lid := t.pending; lid.Before(t.processed); lid = lid.Add(1) {
+		opinion, err := layers.GetAggregatedHash(db, lid)
+		if errors.Is(err, sql.ErrNotFound) {
+			break
+		} else if err != nil {
+			return 0, fmt.Errorf("check opinion %w", err)
+		}
+		if t.layer(lid).opinion != opinion {
+			break
+		}
+		t.pending = lid.Add(1)
+	}
+	// verdicts in layers before pending were already consumed by the mesh
+	for lid := t.evicted.Add(1); lid.Before(t.pending); lid = lid.Add(1) {
+		for _, block := range t.layer(lid).blocks {
+			block.reported = block.validity
+		}
+	}
+	t.persisted = t.processed
+	t.dirty = 0
+	t.dirtyBallots = 0
+
+	lastLayer.Set(float64(t.last))
+	processedLayer.Set(float64(t.processed))
+	verifiedLayer.Set(float64(t.verified))
+	tmetrics.VerificationLag.Set(float64(t.last) - float64(t.verified))
+	evictedLayer.Set(float64(t.evicted))
+	if t.isFull {
+		modeGauge.Set(1)
+	} else {
+		modeGauge.Set(0)
+	}
+
+	for lid := t.evicted.Add(1); !lid.After(t.processed); lid = lid.Add(1) {
+		if err := t.recoverLate(db, lid); err != nil {
+			return 0, fmt.Errorf("failed to load tortoise state at layer %d: %w", lid, err)
+		}
+	}
+	for _, ballot := range late {
+		if err := t.onBallot(ballot); err != nil {
+			t.logger.Warn("failed to recover ballot",
+				zap.Stringer("ballot", ballot.ID),
+				zap.Error(err),
+			)
+		}
+	}
+	return t.processed, nil
+}
+
+// recoverTallies loads tallies for layers and blocks in the window.
+func (t *turtle) recoverTallies(db sql.Executor) error {
+	var (
+		prev *types.Hash32
+		next = maxLayer(t.evicted, types.GetEffectiveGenesis())
+		err  error
+	)
+	if ierr := tortoisestate.IterateLayers(db, t.evicted, t.processed, func(stored *tortoisestate.Layer) bool {
+		if stored.ID != next {
+			err = fmt.Errorf("%w: tally for layer %d is missing", errStateMismatch, next)
+			return false
+		}
+		next = next.Add(1)
+		if !stored.ID.After(t.evicted) {
+			opinion := stored.Opinion
+			prev = &opinion
+			return true
+		}
+		layer := t.layer(stored.ID)
+		layer.opinion = stored.Opinion
+		layer.hareTerminated = stored.HareTerminated
+		layer.coinflip = sign(stored.Coinflip)
+		layer.empty = stored.Empty
+		layer.abstained = stored.Abstained
+		layer.verifying.goodUncounted = stored.GoodUncounted
+		layer.verifying.referenceHeight = stored.ReferenceHeight
+		layer.prevOpinion = prev
+		prev = &layer.opinion
+		return true
+	}); ierr != nil {
+		return ierr
+	}
+	if err != nil {
+		return err
+	}
+	if next != t.processed.Add(1) {
+		return fmt.Errorf("%w: tally for layer %d is missing", errStateMismatch, next)
+	}
+	if ierr := tortoisestate.IterateBlocks(db, t.evicted.Add(1), t.processed, func(stored *tortoisestate.Block) bool {
+		layer := t.layer(stored.Layer)
+		block := &blockInfo{
+			id:       stored.ID,
+			layer:    stored.Layer,
+			height:   stored.Height,
+			hare:     sign(stored.Hare),
+			validity: sign(stored.Validity),
+			margin:   stored.Margin,
+			data:     stored.Data,
+		}
+		if block.validity != abstain {
+			// source of the decision is not persisted
+			block.source = DecidedByRecovery
+		}
+		layer.blocks = append(layer.blocks, block)
+		blocksNumber.Inc()
+		return true
+	}); ierr != nil {
+		return ierr
+	}
+	for lid := t.evicted.Add(1); !lid.After(t.processed); lid = lid.Add(1) {
+		sortBlocks(t.layer(lid).blocks)
+	}
+	return nil
+}
+
+// recoverBallots loads ballots that were counted before the state was persisted.
+// Ballots that are not in the persisted state are returned, they need to be counted.
+func (t *turtle) recoverBallots(db sql.Executor) ([]*types.BallotTortoiseData, error) {
+	counted := map[types.BallotID]*tortoisestate.Ballot{}
+	if err := tortoisestate.IterateBallots(db, t.evicted.Add(1), t.processed, func(stored *tortoisestate.Ballot) bool {
+		counted[stored.ID] = stored
+		return true
+	}); err != nil {
+		return nil, err
+	}
+	var (
+		late []*types.BallotTortoiseData
+		refs = map[types.BallotID]*referenceInfo{}
+	)
+	for lid := t.evicted.Add(1); !lid.After(t.processed); lid = lid.Add(1) {
+		blts, err := ballots.Layer(db, lid)
+		if err != nil {
+			return nil, err
+		}
+		for _, ballot := range blts {
+			data := ballot.ToTortoiseData()
+			stored, exist := counted[data.ID]
+			if !exist {
+				late = append(late, data)
+				continue
+			}
+			delete(counted, data.ID)
+			info, err := t.decodeRecovered(func(id types.BallotID) (*types.Ballot, error) {
+				return ballots.Get(db, id)
+			}, data, refs)
+			if err != nil {
+				return nil, err
+			}
+			t.addBallot(info)
+			for current := info.votes.tail; current != nil; current = current.prev {
+				for i, block := range current.supported {
+					existing := t.getBlock(block.header())
+					if existing == nil {
+						return nil, fmt.Errorf("%w: block %s/%d supported by ballot %s is missing",
+							errStateMismatch, block.layer, block.id, info.id)
+					}
+					current.supported[i] = existing
+				}
+			}
+			if info.malicious && (stored.Good || stored.Votes) {
+				// proof was stored after the state was persisted, tallies can't be reversed
+				// as the weight of the ballot is unknown
+				return nil, fmt.Errorf("%w: ballot %s from malfeasant identity is counted", errStateMismatch, info.id)
+			}
+			info.conditions.badBeacon = stored.BadBeacon
+			info.goodCounted = stored.Good
+			info.votesCounted = stored.Votes
+			if stored.Delayed {
+				t.full.delay(info)
+			}
+			if !stored.Counted {
+				t.retryLater(info)
+			}
+		}
+	}
+	if len(counted) != 0 {
+		return nil, fmt.Errorf("%w: %d counted ballots are missing", errStateMismatch, len(counted))
+	}
+	return late, nil
+}
+
+// decodeRecovered decodes ballot that was counted before the state was persisted.
+// Base and reference ballots may be evicted, in such case they are loaded with get.
+// Evicted base ballot contributes only the opinion hash, as it doesn't vote for layers in the window.
+func (t *turtle) decodeRecovered(
+	get func(types.BallotID) (*types.Ballot, error),
+	ballot *types.BallotTortoiseData,
+	refs map[types.BallotID]*referenceInfo,
+) (*ballotInfo, error) {
+	var (
+		base    *ballotInfo
+		evicted = t.evicted
+	)
+	if ballot.Opinion.Votes.Base == types.EmptyBallotID {
+		base = &ballotInfo{layer: types.GetEffectiveGenesis()}
+		evicted = types.GetEffectiveGenesis().Sub(1)
+	} else if base = t.ballotRefs[ballot.Opinion.Votes.Base]; base == nil {
+		stored, err := get(ballot.Opinion.Votes.Base)
+		if err != nil {
+			return nil, fmt.Errorf("load base ballot %s: %w", ballot.Opinion.Votes.Base, err)
+		}
+		if stored.Layer.After(t.evicted) {
+			return nil, fmt.Errorf("%w: base ballot %s is not counted", errStateMismatch, stored.ID())
+		}
+		base = &ballotInfo{
+			id:    stored.ID(),
+			layer: stored.Layer,
+			votes: votes{tail: &layerVote{lid: stored.Layer.Sub(1), opinion: stored.OpinionHash}},
+		}
+		evicted = stored.Layer.Sub(1)
+	}
+
+	var refinfo *referenceInfo
+	if ballot.EpochData != nil {
+		var err error
+		refinfo, err = t.referenceInfo(ballot)
+		if err != nil {
+			return nil, err
+		}
+	} else if ballot.Ref != nil {
+		if ref := t.ballotRefs[*ballot.Ref]; ref != nil {
+			refinfo = ref.reference
+		} else if refinfo = refs[*ballot.Ref]; refinfo == nil {
+			stored, err := get(*ballot.Ref)
+			if err != nil {
+				return nil, fmt.Errorf("load reference ballot %s: %w", *ballot.Ref, err)
+			}
+			data := stored.ToTortoiseData()
+			if data.EpochData == nil {
+				return nil, fmt.Errorf("ballot %s is not a reference ballot", *ballot.Ref)
+			}
+			refinfo, err = t.referenceInfo(data)
+			if err != nil {
+				return nil, err
+			}
+			refs[*ballot.Ref] = refinfo
+		}
+	}
+	if refinfo == nil {
+		return nil, fmt.Errorf("reference is missing for ballot %s", ballot.ID)
+	}
+
+	info, _, err := t.decodeWith(ballot, base, refinfo, evicted)
+	if err != nil {
+		return nil, err
+	}
+	if info.opinion() != ballot.Opinion.Hash {
+		return nil, fmt.Errorf("%w: computed opinion %s doesn't match opinion %s of ballot %s",
+			errStateMismatch, info.opinion().ShortString(), ballot.Opinion.Hash.ShortString(), ballot.ID)
+	}
+	info.votes.cutBefore(t.evicted.Add(1))
+	return info, nil
+}
+
+// recoverLate applies inputs for the layer that were stored after the state was persisted.
+func (t *turtle) recoverLate(db sql.Executor, lid types.LayerID) error {
+	blts, err := blocks.Layer(db, lid)
+	if err != nil {
+		return err
+	}
+	for _, block := range blts {
+		existing := t.getBlock(block.ToVote())
+		if existing != nil && existing.data {
+			continue
+		}
+		valid, err := blocks.IsValid(db, block.ID())
+		if err != nil && !errors.Is(err, sql.ErrNotFound) {
+			return err
+		}
+		t.onBlock(block.ToVote(), true, valid && existing == nil)
+	}
+	hare, err := certificates.GetHareOutput(db, lid)
+	if err != nil && !errors.Is(err, sql.ErrNotFound) {
+		return err
+	}
+	if err == nil {
+		layer := t.layer(lid)
+		current := types.EmptyBlockID
+		for _, block := range layer.blocks {
+			if block.hare == support {
+				current = block.id
+			}
+		}
+		if !layer.hareTerminated || current != hare {
+			t.onHareOutput(lid, hare)
+		}
+	}
+	if t.layer(lid).coinflip == neutral {
+		coin, err := layers.GetWeakCoin(db, lid)
+		if err != nil && !errors.Is(err, sql.ErrNotFound) {
+			return err
+		}
+		if err == nil {
+			layer := t.layer(lid)
+			if coin {
+				layer.coinflip = support
+			} else {
+				layer.coinflip = against
+			}
+			t.markDirty(lid)
+		}
+	}
+	return nil
+}
+
+// loadEvicted reloads results for the layers in [from, to] that were evicted from memory.
+// Evicted layers are verified, therefore validity of the blocks is read from the database
+// where it was recorded after the tortoise reported it. Opinion is not available for such layers.
+func loadEvicted(db sql.Executor, from, to types.LayerID) ([]result.Layer, error) {
+	rst := make([]result.Layer, 0, to-from+1)
+	for lid := from; lid <= to; lid++ {
+		blts, err := blocks.Layer(db, lid)
+		if err != nil {
+			return nil, fmt.Errorf("load evicted layer %d: %w", lid, err)
+		}
+		hare, err := certificates.GetHareOutput(db, lid)
+		if err != nil && !errors.Is(err, sql.ErrNotFound) {
+			return nil, fmt.Errorf("load hare output for evicted layer %d: %w", lid, err)
+		}
+		layer := result.Layer{Layer: lid, Verified: true, Blocks: make([]result.Block, 0, len(blts))}
+		for _, block := range blts {
+			valid, err := blocks.IsValid(db, block.ID())
+			decided := err == nil
+			if err != nil && !errors.Is(err, blocks.ErrValidityNotDecided) {
+				return nil, fmt.Errorf("load validity for evicted block %s: %w", block.ID(), err)
+			}
+			layer.Blocks = append(layer.Blocks, result.Block{
+				Header:  block.ToVote(),
+				Data:    true,
+				Hare:    block.ID() == hare,
+				Valid:   valid,
+				Invalid: decided && !valid,
+				Local:   valid,
+			})
+		}
+		rst = append(rst, layer)
+	}
+	return rst, nil
+}
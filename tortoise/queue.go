@@ -0,0 +1,104 @@
+package tortoise
+
+import (
+	"go.uber.org/zap"
+
+	"github.com/spacemeshos/go-spacemesh/common/types"
+)
+
+// input is a ballot, block or hare output that was received by the tortoise
+// but not yet applied to the state.
+type input struct {
+	ballot *types.BallotTortoiseData
+	block  *types.BlockHeader
+	valid  bool
+	hare   *hareOutput
+}
+
+type hareOutput struct {
+	layer types.LayerID
+	block types.BlockID
+}
+
+// enqueue adds input to the queue without waiting for the lock that protects the state.
+// Queued inputs are applied by the next call that reads the state, such as TallyVotes.
+//
+// If the queue is full caller applies queued inputs itself, this slows down producers
+// until tortoise catches up.
+func (t *Tortoise) enqueue(in input) {
+	t.qmu.Lock()
+	t.queue = append(t.queue, in)
+	full := len(t.queue) >= t.cfg.QueueSize
+	queuedInputs.Set(float64(len(t.queue)))
+	t.qmu.Unlock()
+	if full {
+		t.mu.Lock()
+		t.drain()
+		t.mu.Unlock()
+	}
+}
+
+// drain applies queued inputs. Must be called with t.mu held, the lock is released
+// if votes of the lean ballots are loaded to apply a ballot.
+//
+// Ballot is applied only after its base and reference ballots are in the state. Ballots that
+// depend on other ballots from the queue are retried after the rest of the queue is applied,
+// ballots with dependencies that are still unknown are left in the queue until the next call.
+func (t *Tortoise) drain() {
+	t.qmu.Lock()
+	inputs := t.queue
+	t.queue = nil
+	t.qmu.Unlock()
+	if len(inputs) == 0 {
+		return
+	}
+	for progress := true; progress && len(inputs) > 0; {
+		progress = false
+		var deferred []input
+		for _, in := range inputs {
+			if in.ballot != nil && in.ballot.Layer.After(t.trtl.evicted) && !t.trtl.dependenciesKnown(in.ballot) {
+				deferred = append(deferred, in)
+				continue
+			}
+			t.apply(in)
+			progress = true
+		}
+		inputs = deferred
+	}
+	t.qmu.Lock()
+	if len(inputs) > 0 {
+		t.logger.Debug("ballots with unknown dependencies are left in the queue",
+			zap.Int("count", len(inputs)),
+		)
+		t.queue = append(inputs, t.queue...)
+	}
+	queuedInputs.Set(float64(len(t.queue)))
+	t.qmu.Unlock()
+}
+
+func (t *Tortoise) apply(in input) {
+	switch {
+	case in.ballot != nil:
+		if err := t.withVotes(func() error {
+			return t.trtl.onBallot(in.ballot)
+		}); err != nil {
+			errorsCounter.Inc()
+			t.logger.Error("failed to save state from ballot",
+				zap.Stringer("ballot", in.ballot.ID),
+				zap.Error(err))
+		}
+		if t.tracer != nil {
+			t.tracer.On(&BallotTrace{Ballot: in.ballot})
+		}
+	case in.block != nil:
+		t.trtl.onBlock(*in.block, true, in.valid)
+		if t.tracer != nil {
+			t.tracer.On(&BlockTrace{Header: *in.block, Valid: in.valid})
+		}
+	case in.hare != nil:
+		t.trtl.onHareOutput(in.hare.layer, in.hare.block)
+		if t.tracer != nil {
+			t.tracer.On(&HareTrace{Layer: in.hare.layer, Vote: in.hare.block})
+		}
+	}
+}
@@ -0,0 +1,217 @@
+package tortoise
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/spacemeshos/go-spacemesh/common/types"
+	"github.com/spacemeshos/go-spacemesh/common/types/result"
+	"github.com/spacemeshos/go-spacemesh/log/logtest"
+	"github.com/spacemeshos/go-spacemesh/sql"
+	"github.com/spacemeshos/go-spacemesh/sql/ballots"
+	"github.com/spacemeshos/go-spacemesh/sql/blocks"
+	"github.com/spacemeshos/go-spacemesh/sql/certificates"
+	"github.com/spacemeshos/go-spacemesh/tortoise/sim"
+)
+
+// deliverLayer applies atxs, blocks and hare output from the layer and returns ballots,
+// so that the caller can deliver them in any order.
+func deliverLayer(tb testing.TB, trtl *Tortoise, state sim.State, lid types.LayerID) []*types.BallotTortoiseData {
+	tb.Helper()
+	if lid.FirstInEpoch() {
+		require.NoError(tb, recoverEpoch(lid.GetEpoch(), trtl, state.DB, state.Beacons))
+	}
+	blts, err := blocks.Layer(state.DB, lid)
+	require.NoError(tb, err)
+	for _, block := range blts {
+		trtl.OnBlock(block.ToVote())
+	}
+	hare, err := certificates.GetHareOutput(state.DB, lid)
+	if !errors.Is(err, sql.ErrNotFound) {
+		require.NoError(tb, err)
+		trtl.OnHareOutput(lid, hare)
+	}
+	stored, err := ballots.Layer(state.DB, lid)
+	require.NoError(tb, err)
+	rst := make([]*types.BallotTortoiseData, 0, len(stored))
+	for _, ballot := range stored {
+		rst = append(rst, ballot.ToTortoiseData())
+	}
+	return rst
+}
+
+func TestQueueOutOfOrder(t *testing.T) {
+	ctx := context.Background()
+	const (
+		size   = 10
+		layers = 20
+	)
+	s := sim.New(sim.WithLayerSize(size))
+	s.Setup()
+
+	cfg := defaultTestConfig()
+	cfg.LayerSize = size
+	expected := tortoiseFromSimState(t, s.GetState(0), WithConfig(cfg), WithLogger(logtest.New(t)))
+
+	var last types.LayerID
+	for i := 0; i < layers; i++ {
+		last = s.Next()
+		expected.TallyVotes(ctx, last)
+	}
+	require.Equal(t, last.Sub(1), expected.LatestComplete())
+
+	cfg.QueueSize = 10 * size
+	run := func(tb testing.TB) (*Tortoise, [][]layerSummary) {
+		trtl, err := New(WithConfig(cfg), WithLogger(logtest.New(tb)))
+		require.NoError(tb, err)
+		var (
+			held    []*types.BallotTortoiseData
+			updates [][]layerSummary
+		)
+		for lid := types.GetEffectiveGenesis().Add(1); lid <= last; lid++ {
+			current := deliverLayer(tb, trtl, s.GetState(0), lid)
+			if held == nil {
+				// ballots from every other layer are delivered only after ballots from the next layer
+				held = current
+			} else {
+				// ballots from the current layer are based on the held ballots
+				for i := len(current) - 1; i >= 0; i-- {
+					trtl.OnBallot(current[i])
+				}
+				trtl.mu.Lock()
+				trtl.drain()
+				trtl.mu.Unlock()
+				require.Len(tb, trtl.queue, len(current), "ballots with unknown base must be deferred")
+				for _, ballot := range held {
+					trtl.OnBallot(ballot)
+				}
+				held = nil
+			}
+			trtl.TallyVotes(ctx, lid)
+			updates = append(updates, summarize(trtl.Updates()))
+		}
+		for _, ballot := range held {
+			trtl.OnBallot(ballot)
+		}
+		trtl.TallyVotes(ctx, last)
+		return trtl, updates
+	}
+
+	trtl, updates := run(t)
+	require.Equal(t, expected.LatestComplete(), trtl.LatestComplete())
+	from := types.GetEffectiveGenesis().Add(1)
+	rst, err := trtl.Results(from, last)
+	require.NoError(t, err)
+	erst, err := expected.Results(from, last)
+	require.NoError(t, err)
+	require.Equal(t, erst, rst)
+	require.Empty(t, trtl.queue)
+
+	// same order of inputs produces same updates after every tally
+	_, again := run(t)
+	require.Equal(t, updates, again)
+}
+
+// layerSummary is a part of the result that doesn't depend on the order of blocks.
+type layerSummary struct {
+	layer    types.LayerID
+	verified bool
+	opinion  types.Hash32
+	valid    []types.BlockID
+}
+
+func summarize(layers []result.Layer) []layerSummary {
+	rst := make([]layerSummary, 0, len(layers))
+	for _, layer := range layers {
+		r := layerSummary{layer: layer.Layer, verified: layer.Verified, opinion: layer.Opinion}
+		for _, block := range layer.Blocks {
+			if block.Valid {
+				r.valid = append(r.valid, block.Header.ID)
+			}
+		}
+		rst = append(rst, r)
+	}
+	return rst
+}
+
+func TestQueueConcurrent(t *testing.T) {
+	ctx := context.Background()
+	const (
+		size   = 10
+		layers = 10
+	)
+	s := sim.New(sim.WithLayerSize(size))
+	s.Setup()
+
+	cfg := defaultTestConfig()
+	cfg.LayerSize = size
+	expected := tortoiseFromSimState(t, s.GetState(0), WithConfig(cfg), WithLogger(logtest.New(t)))
+	var last types.LayerID
+	for i := 0; i < layers; i++ {
+		last = s.Next()
+		expected.TallyVotes(ctx, last)
+	}
+
+	cfg.QueueSize = 4
+	trtl, err := New(WithConfig(cfg), WithLogger(logtest.New(t)))
+	require.NoError(t, err)
+	for lid := types.GetEffectiveGenesis().Add(1); lid <= last; lid++ {
+		current := deliverLayer(t, trtl, s.GetState(0), lid)
+		var wg sync.WaitGroup
+		for _, ballot := range current {
+			ballot := ballot
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				trtl.OnBallot(ballot)
+			}()
+		}
+		// tally may run before, after or in between ballots, result is the same once all are applied
+		trtl.TallyVotes(ctx, lid)
+		wg.Wait()
+		trtl.TallyVotes(ctx, lid)
+	}
+	require.Equal(t, expected.LatestComplete(), trtl.LatestComplete())
+	from := types.GetEffectiveGenesis().Add(1)
+	rst, err := trtl.Results(from, last)
+	require.NoError(t, err)
+	erst, err := expected.Results(from, last)
+	require.NoError(t, err)
+	require.Equal(t, summarize(erst), summarize(rst))
+}
+
+func TestQueueBackpressure(t *testing.T) {
+	cfg := defaultTestConfig()
+	cfg.QueueSize = 3
+	trtl, err := New(WithConfig(cfg), WithLogger(logtest.New(t)))
+	require.NoError(t, err)
+
+	lid := types.GetEffectiveGenesis().Add(1)
+	for i := 1; i < cfg.QueueSize; i++ {
+		trtl.OnBlock(types.BlockHeader{ID: types.BlockID{byte(i)}, LayerID: lid})
+		require.Len(t, trtl.queue, i)
+		require.Nil(t, trtl.trtl.getBlock(types.Vote{ID: types.BlockID{byte(i)}, LayerID: lid}))
+	}
+	// producer applies the queue once it is full
+	trtl.OnBlock(types.BlockHeader{ID: types.BlockID{byte(cfg.QueueSize)}, LayerID: lid})
+	require.Empty(t, trtl.queue)
+	for i := 1; i <= cfg.QueueSize; i++ {
+		require.NotNil(t, trtl.trtl.getBlock(types.Vote{ID: types.BlockID{byte(i)}, LayerID: lid}))
+	}
+
+	trtl.OnHareOutput(lid, types.BlockID{1})
+	require.Len(t, trtl.queue, 1)
+	require.False(t, trtl.trtl.layer(lid).hareTerminated)
+	rst, err := trtl.Results(lid, lid)
+	require.NoError(t, err)
+	require.Empty(t, trtl.queue)
+	require.True(t, trtl.trtl.layer(lid).hareTerminated)
+	require.Len(t, rst, 1)
+	for _, block := range rst[0].Blocks {
+		require.Equal(t, block.Header.ID == types.BlockID{1}, block.Hare)
+	}
+}
@@ -5,19 +5,24 @@ import (
 	"errors"
 	"fmt"
 
+	"go.uber.org/zap"
+
 	"github.com/spacemeshos/go-spacemesh/common/types"
 	"github.com/spacemeshos/go-spacemesh/datastore"
+	"github.com/spacemeshos/go-spacemesh/mesh"
 	"github.com/spacemeshos/go-spacemesh/sql"
 	"github.com/spacemeshos/go-spacemesh/sql/atxs"
 	"github.com/spacemeshos/go-spacemesh/sql/ballots"
-	"github.com/spacemeshos/go-spacemesh/sql/blocks"
-	"github.com/spacemeshos/go-spacemesh/sql/certificates"
 	"github.com/spacemeshos/go-spacemesh/sql/identities"
 	"github.com/spacemeshos/go-spacemesh/sql/layers"
 	"github.com/spacemeshos/go-spacemesh/system"
 )
 
 // Recover tortoise state from database.
+//
+// State persisted by the tortoise is loaded for the layers in the window, and only layers
+// after the last persisted layer are tallied. If state wasn't persisted or it can't be used,
+// tortoise recomputes it from all layers. Recovered tortoise persists its state after every layer.
 func Recover(db *datastore.CachedDB, beacon system.BeaconGetter, opts ...Opt) (*Tortoise, error) {
 	trtl, err := New(opts...)
 	if err != nil {
@@ -28,10 +33,58 @@ func Recover(db *datastore.CachedDB, beacon system.BeaconGetter, opts ...Opt) (*
 	if err != nil {
 		return nil, fmt.Errorf("failed to load latest known layer: %w", err)
 	}
+	ctx := context.Background()
+	// persisted state is loaded into a separate instance, so that it can be discarded
+	// and tracer records only calls that are replayed by the full recovery
+	fresh := &Tortoise{
+		logger: trtl.logger,
+		ctx:    trtl.ctx,
+		cfg:    trtl.cfg,
+		trtl:   newTurtle(trtl.logger, trtl.cfg),
+	}
+	processed, err := recoverPersisted(fresh, db, beacon)
+	switch {
+	case err == nil:
+		trtl.logger.Info("recovered persisted tortoise state",
+			zap.Uint32("processed", processed.Uint32()),
+			zap.Uint32("verified", fresh.trtl.verified.Uint32()),
+			zap.Uint32("last", layer.Uint32()),
+		)
+		trtl.trtl = fresh.trtl
+		if err := recoverLayers(ctx, trtl, db, beacon, processed.Add(1), layer, nil); err != nil {
+			return nil, err
+		}
+	case errors.Is(err, sql.ErrNotFound):
+		if err := recoverState(ctx, trtl, db, beacon, layer, nil); err != nil {
+			return nil, err
+		}
+	default:
+		trtl.logger.Warn("persisted tortoise state can't be used, recomputing it", zap.Error(err))
+		if err := recoverState(ctx, trtl, db, beacon, layer, nil); err != nil {
+			return nil, err
+		}
+	}
+	trtl.db = db
+	trtl.beacons = beacon
+	if trtl.cfg.LeanVerifying {
+		trtl.trtl.enableLean(db)
+	}
+	return trtl, nil
+}
 
+// recoverState loads tortoise state from database up to the layer.
+// onLayer is called after every recovered layer, recovery is interrupted if it returns an error.
+func recoverState(
+	ctx context.Context,
+	trtl *Tortoise,
+	db *datastore.CachedDB,
+	beacon system.BeaconGetter,
+	layer types.LayerID,
+	onLayer func(types.LayerID) error,
+) error {
 	malicious, err := identities.GetMalicious(db)
 	if err != nil {
-		return nil, fmt.Errorf("recover malicious %w", err)
+		return fmt.Errorf("recover malicious %w", err)
 	}
 	for _, id := range malicious {
 		trtl.OnMalfeasance(id)
@@ -40,28 +93,43 @@ func Recover(db *datastore.CachedDB, beacon system.BeaconGetter, opts ...Opt) (*
 	if types.GetEffectiveGenesis() != types.FirstEffectiveGenesis() {
 		// need to load the golden atxs after a checkpoint recovery
 		if err := recoverEpoch(types.GetEffectiveGenesis().Add(1).GetEpoch(), trtl, db, beacon); err != nil {
-			return nil, err
+			return err
 		}
 	}
 
 	epoch, err := atxs.LatestEpoch(db)
 	if err != nil {
-		return nil, fmt.Errorf("failed to load latest epoch: %w", err)
+		return fmt.Errorf("failed to load latest epoch: %w", err)
 	}
 	epoch++ // recoverEpoch expects target epoch, rather than publish
 	if layer.GetEpoch() != epoch {
 		for eid := layer.GetEpoch(); eid <= epoch; eid++ {
 			if err := recoverEpoch(eid, trtl, db, beacon); err != nil {
-				return nil, err
+				return err
 			}
 		}
 	}
-	for lid := types.GetEffectiveGenesis().Add(1); !lid.After(layer); lid = lid.Add(1) {
-		if err := RecoverLayer(context.Background(), trtl, db, beacon, lid); err != nil {
-			return nil, fmt.Errorf("failed to load tortoise state at layer %d: %w", lid, err)
+	return recoverLayers(ctx, trtl, db, beacon, types.GetEffectiveGenesis().Add(1), layer, onLayer)
+}
+
+// recoverLayers loads layers in [from, to] from the database one at a time and tallies votes after every layer.
+func recoverLayers(
+	ctx context.Context,
+	trtl *Tortoise,
+	db *datastore.CachedDB,
+	beacon system.BeaconGetter,
+	from, to types.LayerID,
+	onLayer func(types.LayerID) error,
+) error {
+	return mesh.IterateLayers(db, from, to, func(data *mesh.LayerData) error {
+		if err := recoverLayer(ctx, trtl, db, beacon, data); err != nil {
+			return fmt.Errorf("failed to load tortoise state at layer %d: %w", data.Layer, err)
 		}
-	}
-	return trtl, nil
+		if onLayer != nil {
+			return onLayer(data.Layer)
+		}
+		return nil
+	})
 }
 
 func recoverEpoch(epoch types.EpochID, trtl *Tortoise, db *datastore.CachedDB, beacondb system.BeaconGetter) error {
@@ -78,39 +146,37 @@ func recoverEpoch(epoch types.EpochID, trtl *Tortoise, db *datastore.CachedDB, b
 	return nil
 }
 
+// RecoverLayer loads the layer from the database and tallies votes.
 func RecoverLayer(ctx context.Context, trtl *Tortoise, db *datastore.CachedDB, beacon system.BeaconGetter, lid types.LayerID) error {
+	return mesh.IterateLayers(db, lid, lid, func(data *mesh.LayerData) error {
+		return recoverLayer(ctx, trtl, db, beacon, data)
+	})
+}
+
+func recoverLayer(
+	ctx context.Context,
+	trtl *Tortoise,
+	db *datastore.CachedDB,
+	beacon system.BeaconGetter,
+	data *mesh.LayerData,
+) error {
+	lid := data.Layer
 	if lid.FirstInEpoch() {
 		if err := recoverEpoch(lid.GetEpoch(), trtl, db, beacon); err != nil {
 			return err
 		}
 	}
-	blocksrst, err := blocks.Layer(db, lid)
-	if err != nil {
-		return err
-	}
-	for _, block := range blocksrst {
-		valid, err := blocks.IsValid(db, block.ID())
-		if err != nil && errors.Is(err, sql.ErrNotFound) {
-			return err
-		}
-		if valid {
+	for _, block := range data.Blocks {
+		if block.Valid {
 			trtl.OnValidBlock(block.ToVote())
 		} else {
 			trtl.OnBlock(block.ToVote())
 		}
-		hare, err := certificates.GetHareOutput(db, lid)
-		if err != nil && !errors.Is(err, sql.ErrNotFound) {
-			return err
-		}
-		if err == nil {
-			trtl.OnHareOutput(lid, hare)
-		}
 	}
-	ballotsrst, err := ballots.Layer(db, lid)
-	if err != nil {
-		return err
+	if len(data.Blocks) > 0 && data.HareOutput != nil {
+		trtl.OnHareOutput(lid, *data.HareOutput)
 	}
-	for _, ballot := range ballotsrst {
+	for _, ballot := range data.Ballots {
 		trtl.OnBallot(ballot.ToTortoiseData())
 	}
 	coin, err := layers.GetWeakCoin(db, lid)
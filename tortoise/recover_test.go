@@ -11,6 +11,7 @@ import (
 	"github.com/spacemeshos/go-spacemesh/datastore"
 	"github.com/spacemeshos/go-spacemesh/log/logtest"
 	"github.com/spacemeshos/go-spacemesh/sql/layers"
+	"github.com/spacemeshos/go-spacemesh/sql/tortoisestate"
 	"github.com/spacemeshos/go-spacemesh/system"
 	"github.com/spacemeshos/go-spacemesh/tortoise/sim"
 )
@@ -89,7 +90,7 @@ func TestRecoverWithOpinion(t *testing.T) {
 	var last result.Layer
 	for _, rst := range trt.Updates() {
 		if rst.Verified {
-			require.NoError(t, layers.SetMeshHash(s.GetState(0).DB, rst.Layer, rst.Opinion))
+			require.NoError(t, layers.SetAggregatedHash(s.GetState(0).DB, rst.Layer, rst.Opinion))
 		}
 		last = rst
 	}
@@ -100,3 +101,154 @@ func TestRecoverWithOpinion(t *testing.T) {
 	require.Len(t, updates, 1)
 	require.Equal(t, updates[0], last)
 }
+
+func requireSameDecisions(tb testing.TB, expected, actual *Tortoise) {
+	tb.Helper()
+	require.Equal(tb, expected.LatestComplete(), actual.LatestComplete())
+	require.Equal(tb, expected.Mode(), actual.Mode())
+	from := maxLayer(expected.trtl.evicted, actual.trtl.evicted).Add(1)
+	erst, err := expected.Results(from, expected.trtl.processed)
+	require.NoError(tb, err)
+	arst, err := actual.Results(from, actual.trtl.processed)
+	require.NoError(tb, err)
+	require.Equal(tb, erst, arst)
+}
+
+func TestRecoverPersistedState(t *testing.T) {
+	ctx := context.Background()
+	const size = 10
+	s := sim.New(sim.WithLayerSize(size))
+	s.Setup()
+
+	cfg := defaultTestConfig()
+	cfg.LayerSize = size
+	cfg.WindowSize = 10
+	opts := []Opt{WithLogger(logtest.New(t)), WithConfig(cfg)}
+	state := s.GetState(0)
+
+	restart := func(prev types.LayerID) *recoveryAdapter {
+		trtl, err := Recover(state.DB, state.Beacons, opts...)
+		require.NoError(t, err)
+		return &recoveryAdapter{TB: t, Tortoise: trtl, db: state.DB, beacon: state.Beacons, prev: prev}
+	}
+	reference := tortoiseFromSimState(t, state, opts...)
+	persisted := restart(0)
+	next := func(opts ...sim.NextOpt) types.LayerID {
+		lid := s.Next(opts...)
+		reference.TallyVotes(ctx, lid)
+		persisted.TallyVotes(ctx, lid)
+		requireSameDecisions(t, reference.Tortoise, persisted.Tortoise)
+		return lid
+	}
+
+	var last types.LayerID
+	for i := 0; i < 20 || last.OrdinalInEpoch() != 2; i++ {
+		last = next()
+	}
+	require.NotEqual(t, types.GetEffectiveGenesis(), reference.trtl.evicted)
+
+	// restart in the middle of the epoch, state is loaded instead of being recomputed
+	persisted = restart(last)
+	require.Equal(t, last, persisted.trtl.persisted)
+	requireSameDecisions(t, reference.Tortoise, persisted.Tortoise)
+
+	for i := 0; i < 5; i++ {
+		last = next(sim.WithVoteGenerator(splitVoting(size)))
+	}
+	for i := 0; i < 3; i++ {
+		last = next()
+	}
+	persisted = restart(last)
+	require.Equal(t, last, persisted.trtl.persisted)
+	requireSameDecisions(t, reference.Tortoise, persisted.Tortoise)
+
+	for i := 0; i < 20; i++ {
+		last = next()
+	}
+	require.Equal(t, last.Sub(1), persisted.LatestComplete())
+}
+
+func TestRecoverPersistedStateFractionalWeights(t *testing.T) {
+	ctx := context.Background()
+	const size = 10
+	s := sim.New(sim.WithLayerSize(size))
+	// weight of the atx is not a multiple of the number of eligibilities,
+	// therefore ballots and tallies have fractional weights
+	s.Setup(
+		sim.WithSetupMinerRange(3, 3),
+		sim.WithSetupUnitsRange(1, 1),
+		sim.WithSetupTicks(7, 11, 13),
+	)
+
+	cfg := defaultTestConfig()
+	cfg.LayerSize = size
+	opts := []Opt{WithLogger(logtest.New(t)), WithConfig(cfg)}
+	state := s.GetState(0)
+
+	reference := tortoiseFromSimState(t, state, opts...)
+	trtl, err := Recover(state.DB, state.Beacons, opts...)
+	require.NoError(t, err)
+	persisted := &recoveryAdapter{TB: t, Tortoise: trtl, db: state.DB, beacon: state.Beacons}
+	var last types.LayerID
+	for i := 0; i < 20 || last.OrdinalInEpoch() != 2; i++ {
+		last = s.Next()
+		reference.TallyVotes(ctx, last)
+		persisted.TallyVotes(ctx, last)
+	}
+	good := reference.trtl.verifying.totalGoodWeight
+	require.NotEqual(t, good.Float(), float64(good.Floor()))
+
+	trtl, err = Recover(state.DB, state.Beacons, opts...)
+	require.NoError(t, err)
+	require.Equal(t, last, trtl.trtl.persisted)
+	requireSameTallies(t, reference.trtl, trtl.trtl)
+	requireSameGoodWeight(t, reference.trtl, trtl.trtl)
+}
+
+func TestRecoverPersistedStateMismatch(t *testing.T) {
+	ctx := context.Background()
+	const size = 10
+	s := sim.New(sim.WithLayerSize(size))
+	s.Setup()
+
+	cfg := defaultTestConfig()
+	cfg.LayerSize = size
+	opts := []Opt{WithLogger(logtest.New(t)), WithConfig(cfg)}
+	state := s.GetState(0)
+
+	trtl, err := Recover(state.DB, state.Beacons, opts...)
+	require.NoError(t, err)
+	persisted := &recoveryAdapter{TB: t, Tortoise: trtl, db: state.DB, beacon: state.Beacons}
+	var last types.LayerID
+	for i := 0; i < 10; i++ {
+		last = s.Next()
+		persisted.TallyVotes(ctx, last)
+	}
+	require.Equal(t, last.Sub(1), persisted.LatestComplete())
+
+	for _, tc := range []struct {
+		desc   string
+		mutate func(*tortoisestate.State)
+	}{
+		{"version", func(s *tortoisestate.State) { s.Version++ }},
+		{"config", func(s *tortoisestate.State) { s.Config = types.Hash32{1} }},
+		{"missing layer", func(s *tortoisestate.State) { s.Processed = last.Add(1) }},
+	} {
+		tc := tc
+		t.Run(tc.desc, func(t *testing.T) {
+			stored, err := tortoisestate.GetState(state.DB)
+			require.NoError(t, err)
+			original := *stored
+			tc.mutate(stored)
+			require.NoError(t, tortoisestate.SetState(state.DB, stored))
+			t.Cleanup(func() {
+				require.NoError(t, tortoisestate.SetState(state.DB, &original))
+			})
+
+			recovered, err := Recover(state.DB, state.Beacons, opts...)
+			require.NoError(t, err)
+			require.Zero(t, recovered.trtl.persisted)
+			require.Equal(t, last.Sub(1), recovered.LatestComplete())
+		})
+	}
+}
@@ -0,0 +1,203 @@
+package tortoise
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/spacemeshos/go-spacemesh/common/types"
+	"github.com/spacemeshos/go-spacemesh/signing"
+)
+
+// reportBatchSize is the number of layers that are copied from the state while holding the lock.
+// Lock is released between batches so that report for a long range of layers doesn't delay tallying.
+const reportBatchSize = 100
+
+// DecisionSource is the part of the tortoise that decided validity of the block.
+type DecisionSource string
+
+const (
+	// DecidedByHare is used for blocks decided according to the hare output.
+	DecidedByHare DecisionSource = "hare"
+	// DecidedByVerifying is used for blocks decided by the verifying tortoise
+	// after the hare output for the layer was outside hdist.
+	DecidedByVerifying DecisionSource = "verifying"
+	// DecidedByHealing is used for blocks decided by counting all votes (full mode).
+	DecidedByHealing DecisionSource = "healing"
+	// DecidedByWeakCoin is used for blocks with margin close to zero, decided by the weak coin during healing.
+	DecidedByWeakCoin DecisionSource = "coin"
+	// DecidedByRecovery is used for blocks with validity loaded from the database.
+	DecidedByRecovery DecisionSource = "recovered"
+)
+
+const (
+	validityValid     = "valid"
+	validityInvalid   = "invalid"
+	validityUndecided = "undecided"
+)
+
+// BlockOpinion is the local opinion about validity of the block.
+type BlockOpinion struct {
+	ID       types.BlockID  `json:"id"`
+	Height   uint64         `json:"height"`
+	Validity string         `json:"validity"`
+	Source   DecisionSource `json:"source,omitempty"`
+	Hare     bool           `json:"hare"`
+	// Margin is the weight of votes for the block minus the weight of votes against it.
+	// Votes are counted for every block only while tortoise is healing.
+	Margin float64 `json:"margin"`
+	// Relative is margin divided by the global threshold of the layer,
+	// block crosses threshold if absolute value is 1 or more.
+	Relative float64 `json:"relative"`
+}
+
+// LayerOpinion is the local opinion about blocks in the layer.
+type LayerOpinion struct {
+	Layer     types.LayerID  `json:"layer"`
+	Verified  bool           `json:"verified"`
+	Opinion   types.Hash32   `json:"opinion"`
+	Threshold float64        `json:"threshold"`
+	Blocks    []BlockOpinion `json:"blocks"`
+}
+
+// OpinionReport is the machine readable dump of the local opinion for a range of layers.
+// It is used to compare opinions of nodes that diverged.
+type OpinionReport struct {
+	From   types.LayerID  `json:"from"`
+	To     types.LayerID  `json:"to"`
+	Layers []LayerOpinion `json:"layers"`
+
+	// NodeID and Signature are set if the report was signed.
+	NodeID    *types.NodeID      `json:"node,omitempty"`
+	Signature *types.EdSignature `json:"signature,omitempty"`
+}
+
+// SignedBytes returns the part of the report that is covered by the signature.
+func (r *OpinionReport) SignedBytes() []byte {
+	data, err := json.Marshal(&OpinionReport{From: r.From, To: r.To, Layers: r.Layers})
+	if err != nil {
+		panic(fmt.Sprintf("encoding opinion report: %v", err))
+	}
+	return data
+}
+
+// Sign signs the report with the identity of the signer.
+func (r *OpinionReport) Sign(ctx context.Context, signer signing.Signer) error {
+	sig, err := signer.SignContext(ctx, signing.OPINION_REPORT, r.SignedBytes())
+	if err != nil {
+		return fmt.Errorf("sign opinion report: %w", err)
+	}
+	id := signer.NodeID()
+	r.NodeID = &id
+	r.Signature = &sig
+	return nil
+}
+
+// Verify returns true if the report is signed by the identity in the report.
+func (r *OpinionReport) Verify(verifier *signing.EdVerifier) bool {
+	if r.NodeID == nil || r.Signature == nil {
+		return false
+	}
+	return verifier.Verify(signing.OPINION_REPORT, *r.NodeID, r.SignedBytes(), *r.Signature)
+}
+
+// OpinionReport returns validity of blocks in the range [from, to], margins of the blocks
+// and the part of the tortoise that decided validity.
+//
+// Layers are copied in batches, the lock is released between batches. Therefore the report
+// is not a snapshot, layers later in the range may have been tallied after layers earlier in the range.
+// Layers that were evicted are loaded from the database, margin and decision source are not
+// available for them.
+func (t *Tortoise) OpinionReport(from, to types.LayerID) (*OpinionReport, error) {
+	if to < from {
+		return nil, fmt.Errorf("invalid range [%d, %d]", from, to)
+	}
+	report := &OpinionReport{From: from, To: to, Layers: make([]LayerOpinion, 0, to-from+1)}
+	for start := from; start <= to; start = start.Add(reportBatchSize) {
+		end := to
+		if to-start >= reportBatchSize {
+			end = start.Add(reportBatchSize - 1)
+		}
+		layers, err := t.opinionReport(start, end)
+		if err != nil {
+			return nil, err
+		}
+		report.Layers = append(report.Layers, layers...)
+		if end == to {
+			break
+		}
+	}
+	return report, nil
+}
+
+func (t *Tortoise) opinionReport(from, to types.LayerID) ([]LayerOpinion, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.drain()
+
+	var rst []LayerOpinion
+	if from <= t.trtl.evicted {
+		if t.db == nil {
+			return nil, fmt.Errorf("requested layer %d is before evicted %d", from, t.trtl.evicted)
+		}
+		evicted, err := loadEvicted(t.db, from, types.MinLayer(to, t.trtl.evicted))
+		if err != nil {
+			return nil, err
+		}
+		for _, layer := range evicted {
+			opinion := LayerOpinion{Layer: layer.Layer, Verified: layer.Verified}
+			for _, block := range layer.Blocks {
+				validity := validityUndecided
+				if block.Valid {
+					validity = validityValid
+				} else if block.Invalid {
+					validity = validityInvalid
+				}
+				opinion.Blocks = append(opinion.Blocks, BlockOpinion{
+					ID:       block.Header.ID,
+					Height:   block.Header.Height,
+					Validity: validity,
+					Hare:     block.Hare,
+				})
+			}
+			rst = append(rst, opinion)
+		}
+		from = t.trtl.evicted.Add(1)
+	}
+	for lid := from; lid <= to; lid++ {
+		opinion := LayerOpinion{Layer: lid, Verified: t.trtl.verified >= lid}
+		layer, exist := t.trtl.layers[lid]
+		if !exist {
+			rst = append(rst, opinion)
+			continue
+		}
+		opinion.Opinion = layer.opinion
+		if lid < t.trtl.processed {
+			// threshold is computed only for layers that may be verified
+			opinion.Threshold = t.trtl.globalThreshold(t.cfg, lid).Float()
+		}
+		for _, block := range layer.blocks {
+			bopinion := BlockOpinion{
+				ID:       block.id,
+				Height:   block.height,
+				Validity: validityUndecided,
+				Hare:     block.hare == support,
+				Margin:   block.margin.Float(),
+			}
+			if opinion.Threshold != 0 {
+				bopinion.Relative = bopinion.Margin / opinion.Threshold
+			}
+			switch block.validity {
+			case support:
+				bopinion.Validity = validityValid
+				bopinion.Source = block.source
+			case against:
+				bopinion.Validity = validityInvalid
+				bopinion.Source = block.source
+			}
+			opinion.Blocks = append(opinion.Blocks, bopinion)
+		}
+		rst = append(rst, opinion)
+	}
+	return rst, nil
+}
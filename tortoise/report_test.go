@@ -0,0 +1,129 @@
+package tortoise
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/spacemeshos/go-spacemesh/common/types"
+	"github.com/spacemeshos/go-spacemesh/log/logtest"
+	"github.com/spacemeshos/go-spacemesh/signing"
+	"github.com/spacemeshos/go-spacemesh/tortoise/sim"
+)
+
+func TestOpinionReport(t *testing.T) {
+	const size = 4
+	ctx := context.Background()
+	cfg := defaultTestConfig()
+	cfg.LayerSize = size
+
+	t.Run("hare", func(t *testing.T) {
+		const size = 10
+		cfg := cfg
+		cfg.LayerSize = size
+		s := sim.New(sim.WithLayerSize(size))
+		s.Setup()
+		trtl := tortoiseFromSimState(t, s.GetState(0), WithConfig(cfg), WithLogger(logtest.New(t)))
+		var last types.LayerID
+		for i := 0; i < 10; i++ {
+			last = s.Next()
+			trtl.TallyVotes(ctx, last)
+		}
+		verified := trtl.LatestComplete()
+		require.Greater(t, verified, types.GetEffectiveGenesis())
+
+		from := types.GetEffectiveGenesis().Add(1)
+		to := from.Add(2 * reportBatchSize)
+		report, err := trtl.OpinionReport(from, to)
+		require.NoError(t, err)
+		require.Len(t, report.Layers, int(to-from+1))
+		for i, layer := range report.Layers {
+			lid := from.Add(uint32(i))
+			require.Equal(t, lid, layer.Layer)
+			require.Equal(t, lid <= verified, layer.Verified)
+			if lid > last {
+				require.Empty(t, layer.Blocks)
+				continue
+			}
+			require.NotEmpty(t, layer.Blocks)
+			for _, block := range layer.Blocks {
+				if lid > verified {
+					require.Equal(t, validityUndecided, block.Validity)
+					require.Empty(t, block.Source)
+					continue
+				}
+				if block.Hare {
+					require.Equal(t, validityValid, block.Validity, "layer %d", lid)
+				} else {
+					require.Equal(t, validityInvalid, block.Validity, "layer %d", lid)
+				}
+				require.Equal(t, DecidedByHare, block.Source, "layer %d", lid)
+			}
+		}
+	})
+	t.Run("healing", func(t *testing.T) {
+		cfg := cfg
+		cfg.Hdist = 2
+		cfg.Zdist = 2
+		s := sim.New(sim.WithLayerSize(size))
+		s.Setup(sim.WithSetupMinerRange(size, size))
+		trtl := tortoiseFromSimState(t, s.GetState(0), WithConfig(cfg), WithLogger(logtest.New(t)))
+		var last types.LayerID
+		for i := 0; i < 10; i++ {
+			last = s.Next(sim.WithNumBlocks(1), sim.WithEmptyHareOutput())
+			trtl.TallyVotes(ctx, last)
+		}
+		require.Equal(t, Mode(Full), trtl.Mode())
+
+		from := types.GetEffectiveGenesis().Add(1)
+		report, err := trtl.OpinionReport(from, last)
+		require.NoError(t, err)
+		var healed int
+		for _, layer := range report.Layers {
+			if !layer.Verified {
+				continue
+			}
+			require.NotZero(t, layer.Threshold)
+			for _, block := range layer.Blocks {
+				require.NotEqual(t, validityUndecided, block.Validity)
+				require.Equal(t, DecidedByHealing, block.Source)
+				require.False(t, block.Hare)
+				if block.Validity == validityValid {
+					require.GreaterOrEqual(t, block.Relative, 1.0)
+				} else {
+					require.Less(t, block.Relative, 1.0)
+				}
+				healed++
+			}
+		}
+		require.NotZero(t, healed)
+	})
+	t.Run("signed", func(t *testing.T) {
+		s := sim.New(sim.WithLayerSize(size))
+		s.Setup()
+		trtl := tortoiseFromSimState(t, s.GetState(0), WithConfig(cfg), WithLogger(logtest.New(t)))
+		last := s.Next()
+		trtl.TallyVotes(ctx, last)
+
+		report, err := trtl.OpinionReport(last, last)
+		require.NoError(t, err)
+		verifier, err := signing.NewEdVerifier()
+		require.NoError(t, err)
+		require.False(t, report.Verify(verifier))
+
+		signer, err := signing.NewEdSigner()
+		require.NoError(t, err)
+		require.NoError(t, report.Sign(ctx, signer))
+		require.Equal(t, signer.NodeID(), *report.NodeID)
+		require.True(t, report.Verify(verifier))
+
+		report.Layers[0].Verified = !report.Layers[0].Verified
+		require.False(t, report.Verify(verifier))
+	})
+	t.Run("invalid range", func(t *testing.T) {
+		trtl := defaultAlgorithm(t)
+		_, err := trtl.OpinionReport(types.LayerID(10), types.LayerID(9))
+		require.Error(t, err)
+	})
+}
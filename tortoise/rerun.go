@@ -0,0 +1,231 @@
+package tortoise
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/spacemeshos/go-spacemesh/common/types"
+	"github.com/spacemeshos/go-spacemesh/sql/atxs"
+	"github.com/spacemeshos/go-spacemesh/sql/identities"
+)
+
+var (
+	// ErrRerunInProgress is returned if rerun is requested before the previous one completed.
+	ErrRerunInProgress  = errors.New("tortoise: rerun in progress")
+	errRerunUnavailable = errors.New("tortoise: rerun is available only for tortoise recovered from database")
+)
+
+// RerunProgress is reported by Rerun. The last update is sent with Done set to true,
+// after that the channel is closed.
+type RerunProgress struct {
+	From types.LayerID
+	To   types.LayerID
+	// Processed is the number of layers in [From, To] that were tallied.
+	Processed uint32
+	// Changed is the number of layers where opinion differs from the opinion before rerun.
+	// It is known only when rerun is done.
+	Changed uint32
+	// Remaining is the estimated time until rerun completes.
+	Remaining time.Duration
+	Done      bool
+	// Err is set if rerun failed or was cancelled, state of the tortoise is unchanged in such case.
+	Err error
+}
+
+// Rerun recomputes tortoise state from the database in the background.
+//
+// Tortoise state is cumulative, therefore all layers are loaded from the database, but
+// progress is reported and opinions are compared starting from the layer from.
+// Current state is used until rerun completes, after that it is replaced atomically.
+// If ctx is cancelled before rerun completes, state computed by the rerun is discarded.
+//
+// Updates are dropped if the reader is slower than the rerun, but the last update is always delivered.
+func (t *Tortoise) Rerun(ctx context.Context, from types.LayerID) (<-chan RerunProgress, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.db == nil {
+		return nil, errRerunUnavailable
+	}
+	if t.rerunning {
+		return nil, ErrRerunInProgress
+	}
+	to := t.trtl.last
+	if !from.After(types.GetEffectiveGenesis()) || from.After(to) {
+		return nil, fmt.Errorf("tortoise: rerun from %d is outside of (%d, %d]", from, types.GetEffectiveGenesis(), to)
+	}
+	t.rerunning = true
+	progress := make(chan RerunProgress, 1)
+	go t.rerun(ctx, from, to, progress)
+	return progress, nil
+}
+
+func (t *Tortoise) rerun(ctx context.Context, from, to types.LayerID, progress chan RerunProgress) {
+	defer close(progress)
+	var (
+		start   = time.Now()
+		genesis = types.GetEffectiveGenesis()
+		update  = RerunProgress{From: from, To: to}
+		fresh   = &Tortoise{
+			logger: t.logger,
+			ctx:    t.ctx,
+			cfg:    t.cfg,
+			trtl:   newTurtle(t.logger, t.cfg),
+		}
+	)
+	t.logger.Info("tortoise rerun started",
+		zap.Uint32("from", from.Uint32()),
+		zap.Uint32("to", to.Uint32()),
+	)
+	err := recoverState(ctx, fresh, t.db, t.beacons, to, func(lid types.LayerID) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if lid.Before(from) {
+			return nil
+		}
+		update.Processed++
+		done := time.Since(start) / time.Duration(lid.Difference(genesis))
+		update.Remaining = done * time.Duration(to.Difference(lid))
+		report(progress, update)
+		return nil
+	})
+	if err == nil {
+		err = t.replace(ctx, fresh, &update)
+	} else {
+		t.mu.Lock()
+		t.rerunning = false
+		t.mu.Unlock()
+	}
+	update.Remaining = 0
+	update.Done = true
+	update.Err = err
+	if err != nil {
+		t.logger.Warn("tortoise rerun failed",
+			zap.Uint32("from", from.Uint32()),
+			zap.Uint32("to", to.Uint32()),
+			zap.Uint32("processed", update.Processed),
+			zap.Duration("duration", time.Since(start)),
+			zap.Error(err),
+		)
+	} else {
+		t.logger.Info("tortoise rerun completed",
+			zap.Uint32("from", from.Uint32()),
+			zap.Uint32("to", to.Uint32()),
+			zap.Uint32("processed", update.Processed),
+			zap.Uint32("changed", update.Changed),
+			zap.Duration("duration", time.Since(start)),
+		)
+	}
+	report(progress, update)
+}
+
+// replace catches up with layers that were tallied while rerun was running
+// and replaces current state with the state computed by the rerun.
+//
+// Data is loaded from the database without holding the lock, the lock is taken
+// only once fresh state caught up with the last layer known to the current state.
+func (t *Tortoise) replace(ctx context.Context, fresh *Tortoise, update *RerunProgress) error {
+	if err := t.catchUp(ctx, fresh, update); err != nil {
+		t.mu.Lock()
+		t.rerunning = false
+		t.mu.Unlock()
+		return err
+	}
+	defer t.mu.Unlock()
+	t.rerunning = false
+	fresh.drain()
+
+	var changed types.LayerID
+	for lid := update.From; !lid.After(fresh.trtl.processed); lid = lid.Add(1) {
+		if !lid.After(t.trtl.evicted) {
+			continue
+		}
+		prev, exist := t.trtl.layers[lid]
+		if exist && prev.opinion == fresh.trtl.layer(lid).opinion {
+			continue
+		}
+		update.Changed++
+		if changed == 0 {
+			changed = lid
+		}
+	}
+	if changed != 0 && (fresh.trtl.pending == 0 || changed.Before(fresh.trtl.pending)) {
+		fresh.trtl.pending = changed
+	}
+	// validity changes are reported relative to what was already returned by Updates
+	for lid, layer := range fresh.trtl.layers {
+		prev, exist := t.trtl.layers[lid]
+		if !exist {
+			continue
+		}
+		for _, block := range layer.blocks {
+			for _, pblock := range prev.blocks {
+				if block.id == pblock.id && block.height == pblock.height {
+					block.reported = pblock.reported
+				}
+			}
+		}
+	}
+	if t.cfg.LeanVerifying {
+		fresh.trtl.enableLean(t.db)
+	}
+	t.trtl = fresh.trtl
+	return nil
+}
+
+// catchUp loads data that was added to the database after the rerun reached update.To.
+// On success it returns with t.mu locked and fresh state loaded up to t.trtl.last.
+func (t *Tortoise) catchUp(ctx context.Context, fresh *Tortoise, update *RerunProgress) error {
+	malicious, err := identities.GetMalicious(t.db)
+	if err != nil {
+		return fmt.Errorf("recover malicious %w", err)
+	}
+	for _, id := range malicious {
+		fresh.OnMalfeasance(id)
+	}
+	loaded := update.To
+	for first := true; ; first = false {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		t.mu.Lock()
+		last := t.trtl.last
+		if !first && !last.After(loaded) {
+			return nil
+		}
+		t.mu.Unlock()
+
+		epoch, err := atxs.LatestEpoch(t.db)
+		if err != nil {
+			return fmt.Errorf("failed to load latest epoch: %w", err)
+		}
+		for eid := loaded.GetEpoch(); eid <= epoch+1; eid++ {
+			if err := recoverEpoch(eid, fresh, t.db, t.beacons); err != nil {
+				return err
+			}
+		}
+		if err := recoverLayers(ctx, fresh, t.db, t.beacons, loaded.Add(1), last, nil); err != nil {
+			return err
+		}
+		loaded = last
+	}
+}
+
+// report replaces previous update if it wasn't received yet, so that rerun is never blocked by the reader.
+func report(progress chan RerunProgress, update RerunProgress) {
+	for {
+		select {
+		case progress <- update:
+			return
+		default:
+		}
+		select {
+		case <-progress:
+		default:
+		}
+	}
+}
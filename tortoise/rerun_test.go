@@ -2,9 +2,11 @@ package tortoise
 
 import (
 	"context"
+	"os"
 	"testing"
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/require"
 
 	"github.com/spacemeshos/go-spacemesh/common/types"
@@ -148,3 +150,178 @@ func benchmarkTallyVotes(b *testing.B, size int, windowsize uint32, opts ...sim.
 		tortoise.TallyVotes(ctx, last)
 	}
 }
+
+func TestRerunFromDatabase(t *testing.T) {
+	ctx := context.Background()
+	const size = 10
+	s := sim.New(sim.WithLayerSize(size))
+	s.Setup()
+
+	cfg := defaultTestConfig()
+	cfg.LayerSize = size
+	var last types.LayerID
+	for i := 0; i < 30; i++ {
+		last = s.Next()
+	}
+	tortoise, err := Recover(s.GetState(0).DB, s.GetState(0).Beacons, WithLogger(logtest.New(t)), WithConfig(cfg))
+	require.NoError(t, err)
+	require.Equal(t, last.Sub(1), tortoise.LatestComplete())
+
+	t.Run("unavailable", func(t *testing.T) {
+		_, err := defaultAlgorithm(t).Rerun(ctx, last)
+		require.ErrorIs(t, err, errRerunUnavailable)
+	})
+	t.Run("outside of range", func(t *testing.T) {
+		_, err := tortoise.Rerun(ctx, types.GetEffectiveGenesis())
+		require.Error(t, err)
+		_, err = tortoise.Rerun(ctx, last.Add(1))
+		require.Error(t, err)
+	})
+	t.Run("cancelled", func(t *testing.T) {
+		cctx, cancel := context.WithCancel(ctx)
+		cancel()
+		before := tortoise.trtl
+		progress, err := tortoise.Rerun(cctx, last.Sub(10))
+		require.NoError(t, err)
+		var final RerunProgress
+		for update := range progress {
+			final = update
+		}
+		require.True(t, final.Done)
+		require.ErrorIs(t, final.Err, context.Canceled)
+		require.Same(t, before, tortoise.trtl)
+	})
+	t.Run("completed", func(t *testing.T) {
+		from := last.Sub(10)
+		before := tortoise.trtl
+		progress, err := tortoise.Rerun(ctx, from)
+		require.NoError(t, err)
+		var final RerunProgress
+		for update := range progress {
+			require.Equal(t, from, update.From)
+			require.Equal(t, last, update.To)
+			final = update
+		}
+		require.True(t, final.Done)
+		require.NoError(t, final.Err)
+		require.Equal(t, uint32(last-from+1), final.Processed)
+		require.Zero(t, final.Changed)
+		require.Zero(t, final.Remaining)
+		require.NotSame(t, before, tortoise.trtl)
+		require.Equal(t, last.Sub(1), tortoise.LatestComplete())
+
+		// state is consistent after rerun and new layers are tallied as usual
+		last = s.Next()
+		require.NoError(t, RecoverLayer(ctx, tortoise, s.GetState(0).DB, s.GetState(0).Beacons, last))
+		require.Equal(t, last.Sub(1), tortoise.LatestComplete())
+	})
+}
+
+func TestWindowEviction(t *testing.T) {
+	t.Run("1000/100", func(t *testing.T) {
+		testWindowEviction(t, 1_000, 100)
+	})
+	t.Run("10000/100", func(t *testing.T) {
+		// verifying tortoise computes expected weight of every layer in the window on every update,
+		// therefore the window is smaller than in the run that is compared with unbounded tortoise
+		testWindowBounded(t, 10_000, 100)
+	})
+	t.Run("10000/1000", func(t *testing.T) {
+		// unbounded tortoise rechecks all layers on every update, therefore this run takes long
+		if os.Getenv("TORTOISE_SOAK") == "" {
+			t.Skip("set TORTOISE_SOAK to run")
+		}
+		testWindowEviction(t, 10_000, 1_000)
+	})
+}
+
+// testWindowBounded runs only the bounded tortoise and checks that the number of
+// layers in memory, as reported by the metrics, doesn't grow beyond the window.
+func testWindowBounded(t *testing.T, total, window int) {
+	ctx := context.Background()
+	const size = 2
+	s := sim.New(sim.WithLayerSize(size))
+	s.Setup(sim.WithSetupMinerRange(size, size))
+
+	cfg := defaultTestConfig()
+	cfg.LayerSize = size
+	cfg.WindowSize = uint32(window)
+	layers := testutil.ToFloat64(layersNumber)
+	bounded := tortoiseFromSimState(t, s.GetState(0), WithLogger(logtest.New(t)), WithConfig(cfg))
+	require.Equal(t, float64(window), testutil.ToFloat64(windowLayers))
+
+	limit := float64(window) + float64(defaultTestHdist) + 1
+	var last types.LayerID
+	for i := 0; i < total; i++ {
+		last = s.Next(sim.WithNumBlocks(1))
+		bounded.TallyVotes(ctx, last)
+		require.Equal(t, last.Sub(1), bounded.LatestComplete())
+		// pending updates are not evicted, therefore they need to be applied
+		for _, layer := range bounded.Updates() {
+			for _, block := range layer.Blocks {
+				require.NoError(t, blocks.UpdateValid(s.GetState(0).DB, block.Header.ID, block.Valid))
+			}
+		}
+
+		inMemory := testutil.ToFloat64(processedLayer) - testutil.ToFloat64(evictedLayer)
+		require.LessOrEqual(t, inMemory, limit, "layer %d", last)
+		require.LessOrEqual(t, testutil.ToFloat64(layersNumber)-layers, limit, "layer %d", last)
+	}
+	require.Greater(t, bounded.trtl.evicted, last.Sub(uint32(limit)+1))
+}
+
+func testWindowEviction(t *testing.T, total, window int) {
+	ctx := context.Background()
+	const size = 2
+	s := sim.New(sim.WithLayerSize(size))
+	s.Setup(sim.WithSetupMinerRange(size, size))
+
+	cfg := defaultTestConfig()
+	cfg.LayerSize = size
+	cfg.WindowSize = uint32(window)
+	bounded := tortoiseFromSimState(t, s.GetState(0), WithLogger(logtest.New(t)), WithConfig(cfg))
+	cfg.WindowSize = uint32(2 * total)
+	unbounded := tortoiseFromSimState(t, s.GetState(0), WithLogger(logtest.New(t)), WithConfig(cfg))
+
+	var last types.LayerID
+	for i := 0; i < total; i++ {
+		last = s.Next(sim.WithNumBlocks(1))
+		bounded.TallyVotes(ctx, last)
+		unbounded.TallyVotes(ctx, last)
+		require.Equal(t, unbounded.LatestComplete(), bounded.LatestComplete(), "layer %d", last)
+		expected := unbounded.Updates()
+		require.Equal(t, expected, bounded.Updates(), "layer %d", last)
+		for _, layer := range expected {
+			for _, block := range layer.Blocks {
+				require.NoError(t, blocks.UpdateValid(s.GetState(0).DB, block.Header.ID, block.Valid))
+			}
+		}
+
+		inMemory := uint32(last - bounded.trtl.evicted)
+		require.LessOrEqual(t, inMemory, uint32(window)+defaultTestHdist+1, "layer %d", last)
+		require.LessOrEqual(t, len(bounded.trtl.layers), window+int(defaultTestHdist)+1, "layer %d", last)
+	}
+	require.Equal(t, last.Sub(1), bounded.LatestComplete())
+	require.Greater(t, len(unbounded.trtl.layers), total-1)
+
+	// evicted layers are reloaded from the database if tortoise has access to it
+	evicted := bounded.trtl.evicted
+	require.Greater(t, evicted, types.GetEffectiveGenesis())
+	from := types.GetEffectiveGenesis().Add(1)
+	_, err := bounded.Results(from, evicted)
+	require.Error(t, err)
+	bounded.Tortoise.db = s.GetState(0).DB
+	rst, err := bounded.Results(from, evicted.Add(1))
+	require.NoError(t, err)
+	expected, err := unbounded.Results(from, evicted.Add(1))
+	require.NoError(t, err)
+	require.Len(t, rst, len(expected))
+	for i := range expected {
+		require.Equal(t, expected[i].Layer, rst[i].Layer)
+		require.Len(t, rst[i].Blocks, len(expected[i].Blocks))
+		for j, block := range expected[i].Blocks {
+			require.Equal(t, block.Header, rst[i].Blocks[j].Header)
+			require.Equal(t, block.Valid, rst[i].Blocks[j].Valid, "layer %d", expected[i].Layer)
+		}
+	}
+}
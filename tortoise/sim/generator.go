@@ -85,6 +85,7 @@ func New(opts ...GenOpt) *Generator {
 		conf:      defaults(),
 		logger:    log.NewNop(),
 		reordered: map[types.LayerID]types.LayerID{},
+		opinions:  map[types.BallotID]*ballotOpinion{},
 	}
 	for _, opt := range opts {
 		opt(g)
@@ -116,6 +117,9 @@ type Generator struct {
 	prevHeight  []uint64
 
 	keys []*signing.EdSigner
+
+	// opinions of generated ballots, used to compute opinion hash of the ballots that refer to them
+	opinions map[types.BallotID]*ballotOpinion
 }
 
 // SetupOpt configures setup.
@@ -190,11 +190,18 @@ func (g *Generator) genLayer(cfg nextConf) types.LayerID {
 			EligibilityProofs: proofs,
 			ActiveSet:         activeset,
 		}
+		opinion, known := g.opinionHash(g.nextLayer, voting)
+		if known {
+			ballot.OpinionHash = opinion.hash()
+		}
 		ballot.Signature = signer.Sign(signing.BALLOT, ballot.SignedBytes())
 		ballot.SmesherID = signer.NodeID()
 		if err := ballot.Initialize(); err != nil {
 			g.logger.With().Panic("failed to init ballot", log.Err(err))
 		}
+		if known {
+			g.opinions[ballot.ID()] = opinion
+		}
 		for _, state := range g.states {
 			state.OnBallot(ballot)
 		}
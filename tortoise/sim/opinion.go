@@ -0,0 +1,133 @@
+package sim
+
+import (
+	"sort"
+
+	"github.com/spacemeshos/go-spacemesh/common/types"
+	"github.com/spacemeshos/go-spacemesh/tortoise/opinionhash"
+)
+
+// layerOpinion is an opinion of the ballot about the layer.
+// Opinions are linked and shared between ballots, in the same way as tortoise decodes them.
+type layerOpinion struct {
+	lid       types.LayerID
+	abstain   bool
+	supported []types.Vote
+	hash      types.Hash32
+	prev      *layerOpinion
+}
+
+func (l *layerOpinion) computeHash() {
+	hasher := opinionhash.New()
+	if l.prev != nil {
+		hasher.WritePrevious(l.prev.hash)
+	}
+	if len(l.supported) > 0 {
+		sort.Slice(l.supported, func(i, j int) bool {
+			if l.supported[i].Height != l.supported[j].Height {
+				return l.supported[i].Height < l.supported[j].Height
+			}
+			return l.supported[i].ID.Compare(l.supported[j].ID)
+		})
+		for _, vote := range l.supported {
+			hasher.WriteSupport(vote.ID, vote.Height)
+		}
+	} else if l.abstain {
+		hasher.WriteAbstain()
+	}
+	hasher.Sum(l.hash[:0])
+}
+
+type layerDiff struct {
+	abstain bool
+	votes   map[types.BlockID]types.Vote
+	against map[types.BlockID]struct{}
+}
+
+func (l *layerOpinion) update(from types.LayerID, diff map[types.LayerID]*layerDiff) *layerOpinion {
+	if l == nil || l.lid.Before(from) {
+		return l
+	}
+	copied := *l
+	copied.prev = l.prev.update(from, diff)
+	if ldiff, exist := diff[copied.lid]; exist {
+		if ldiff.abstain {
+			copied.abstain = true
+			copied.supported = nil
+		} else {
+			copied.supported = nil
+			for _, vote := range l.supported {
+				_, against := ldiff.against[vote.ID]
+				_, support := ldiff.votes[vote.ID]
+				if !against && !support {
+					copied.supported = append(copied.supported, vote)
+				}
+			}
+			for _, vote := range ldiff.votes {
+				copied.supported = append(copied.supported, vote)
+			}
+		}
+	}
+	copied.computeHash()
+	return &copied
+}
+
+type ballotOpinion struct {
+	layer types.LayerID
+	tail  *layerOpinion
+}
+
+// opinionHash computes opinion hash for the ballot in the layer and remembers it,
+// so that it can be used by ballots that refer to this ballot as a base.
+// Returns false if the base ballot is unknown.
+func (g *Generator) opinionHash(layer types.LayerID, votes Voting) (*ballotOpinion, bool) {
+	base := &ballotOpinion{layer: types.GetEffectiveGenesis()}
+	if votes.Base != types.EmptyBallotID {
+		var exist bool
+		if base, exist = g.opinions[votes.Base]; !exist {
+			return nil, false
+		}
+	}
+	from := base.layer
+	diff := map[types.LayerID]*layerDiff{}
+	get := func(lid types.LayerID) *layerDiff {
+		if from.After(lid) {
+			from = lid
+		}
+		ldiff, exist := diff[lid]
+		if !exist {
+			ldiff = &layerDiff{votes: map[types.BlockID]types.Vote{}, against: map[types.BlockID]struct{}{}}
+			diff[lid] = ldiff
+		}
+		return ldiff
+	}
+	for _, vote := range votes.Support {
+		get(vote.LayerID).votes[vote.ID] = vote
+	}
+	for _, vote := range votes.Against {
+		get(vote.LayerID).against[vote.ID] = struct{}{}
+	}
+	for _, lid := range votes.Abstain {
+		get(lid).abstain = true
+	}
+	tail := base.tail.update(from, diff)
+	for lid := base.layer; lid.Before(layer); lid = lid.Add(1) {
+		lopinion := &layerOpinion{lid: lid, prev: tail}
+		if ldiff, exist := diff[lid]; exist {
+			lopinion.abstain = ldiff.abstain
+			for _, vote := range ldiff.votes {
+				lopinion.supported = append(lopinion.supported, vote)
+			}
+		}
+		lopinion.computeHash()
+		tail = lopinion
+	}
+	return &ballotOpinion{layer: layer, tail: tail}, true
+}
+
+func (b *ballotOpinion) hash() types.Hash32 {
+	if b.tail == nil {
+		return types.Hash32{}
+	}
+	return b.tail.hash
+}
@@ -169,6 +169,9 @@ func (g *Generator) mergeLayers(other *Generator) {
 					state.OnBallot(ballot)
 				}
 				g.layers[i].AddBallot(ballot)
+				if opinion, exist := other.opinions[ballot.ID()]; exist {
+					g.opinions[ballot.ID()] = opinion
+				}
 			}
 		}
 		for _, block := range layer.Blocks() {
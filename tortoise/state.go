@@ -69,6 +69,13 @@ type (
 		// malnodes is a collection with all nodes that equivocated in history.
 		// each node id is 32 bytes. 100 000 of such nodes is only about ~3MB
 		malnodes map[types.NodeID]struct{}
+
+		// persisted is the last processed layer when state was persisted, zero if it was never persisted.
+		persisted types.LayerID
+		// dirty is the lowest layer with tally that changed since state was persisted.
+		// dirtyBallots is the same for ballots, they change only when they are added or counted,
+		// unlike tallies that are changed for many layers by every counted ballot.
+		dirty, dirtyBallots types.LayerID
 	}
 )
 
@@ -114,6 +121,7 @@ func (s *state) addBallot(ballot *ballotInfo) {
 	ballotsNumber.Inc()
 	s.ballots[ballot.layer] = append(s.ballots[ballot.layer], ballot)
 	s.ballotRefs[ballot.id] = ballot
+	s.markDirtyBallot(ballot.layer)
 }
 
 func (s *state) addBlock(block *blockInfo) {
@@ -125,6 +133,7 @@ func (s *state) addBlock(block *blockInfo) {
 	layer.blocks = append(layer.blocks, block)
 	sortBlocks(layer.blocks)
 	s.updateRefHeight(layer, block)
+	s.markDirty(block.layer)
 }
 
 func (s *state) getBlock(header types.Vote) *blockInfo {
@@ -155,10 +164,24 @@ func (s *state) updateRefHeight(layer *layerInfo, block *blockInfo) {
 	}
 	if layer.verifying.referenceHeight == 0 && layer.lid.After(s.evicted) {
 		layer.verifying.referenceHeight = s.findRefHeightBelow(layer.lid)
+		s.markDirty(layer.lid)
 	}
 	if block.height <= epoch.height &&
 		block.height > layer.verifying.referenceHeight {
 		layer.verifying.referenceHeight = block.height
+		s.markDirty(layer.lid)
+	}
+}
+
+func (s *state) markDirty(lid types.LayerID) {
+	if s.dirty == 0 || lid < s.dirty {
+		s.dirty = lid
+	}
+}
+
+func (s *state) markDirtyBallot(lid types.LayerID) {
+	if s.dirtyBallots == 0 || lid < s.dirtyBallots {
+		s.dirtyBallots = lid
 	}
 }
 
@@ -172,8 +195,11 @@ func (s *state) makrMalfeasant(id types.NodeID) {
 }
 
 type layerInfo struct {
-	lid            types.LayerID
-	empty          weight
+	lid   types.LayerID
+	empty weight
+	// abstained is a weight of ballots that abstained on the whole layer.
+	// it applies to every block in the layer, including blocks that were added late.
+	abstained      weight
 	hareTerminated bool
 	blocks         []*blockInfo
 	verifying      verifyingInfo
@@ -184,6 +210,10 @@ type layerInfo struct {
 	// it is stored as a pointer so that when previous layerInfo is evicted
 	// we still have access in case we need to recompute opinion for this layer
 	prevOpinion *types.Hash32
+
+	// lean ballots are sorted by id, leanGroups aggregate their weight. See lean.go.
+	lean       []leanBallot
+	leanGroups []leanGroup
 }
 
 func (l *layerInfo) computeOpinion(hdist uint32, last types.LayerID) {
@@ -229,16 +259,52 @@ type (
 	ballotInfo struct {
 		id         types.BallotID
 		layer      types.LayerID
+		smesher    types.NodeID
 		base       baseInfo
 		malicious  bool
 		weight     weight
 		reference  *referenceInfo
 		votes      votes
 		conditions conditions
+
+		// goodCounted is set if weight of the ballot is counted as good by verifying tortoise.
+		// votesCounted is set if votes of the ballot are counted by full tortoise.
+		// both are needed to reverse counting if smesher is found malfeasant.
+		goodCounted  bool
+		votesCounted bool
+
+		// lean is set if the ballot was created from the lean ballot, only the opinion is known.
+		lean        bool
+		leanOpinion types.Hash32
+		// partial is set if ballot was decoded relative to the lean base ballot,
+		// it has only votes after the base layer.
+		partial bool
+	}
+
+	// leanBallot is what is left of the ballot after it was counted by the verifying
+	// tortoise in lean mode. Reference is kept so that ballot can be used as a reference
+	// ballot, it is shared by lean ballots with the same reference info.
+	leanBallot struct {
+		id        types.BallotID
+		group     uint32
+		reference *referenceInfo
+	}
+
+	// leanGroup is a weight of the lean ballots from the same layer that are counted
+	// the same way by the verifying tortoise.
+	leanGroup struct {
+		opinion   types.Hash32
+		height    uint64
+		badBeacon bool
+		counted   bool
+		weight    weight
 	}
 )
 
 func (b *ballotInfo) opinion() types.Hash32 {
+	if b.lean {
+		return b.leanOpinion
+	}
 	return b.votes.opinion()
 }
 
@@ -408,6 +474,10 @@ type blockInfo struct {
 	margin weight
 
 	validity sign
+	// source is the part of the tortoise that decided validity.
+	source DecisionSource
+	// reported is the validity that was returned by the last call to Updates.
+	reported sign
 
 	data bool // set to true if block is available locally
 }
@@ -470,10 +540,21 @@ func decodeVotes(evicted, blid types.LayerID, base *ballotInfo, exceptions types
 		return votes{}, 0, fmt.Errorf("votes for a block in the layer (%d) outside the window (evicted %d)", from, evicted)
 	}
 
-	// inherit opinion from the base ballot by copying votes
-	decoded, err := base.votes.update(from, diff)
-	if err != nil {
-		return votes{}, 0, err
+	var decoded votes
+	if base.lean {
+		// votes of the lean base are not known, but they are not changed by exceptions.
+		// opinion of the base is a prefix for the opinion of the votes after the base layer
+		if from.Before(base.layer) {
+			return votes{}, 0, fmt.Errorf("votes for layer %d change votes of the lean base ballot %s", from, base.id)
+		}
+		decoded = votes{tail: &layerVote{lid: base.layer.Sub(1), vote: against, opinion: base.leanOpinion}}
+	} else {
+		// inherit opinion from the base ballot by copying votes
+		var err error
+		decoded, err = base.votes.update(from, diff)
+		if err != nil {
+			return votes{}, 0, err
+		}
 	}
 	// add new opinions after the base layer
 	for lid := base.layer; lid.Before(blid); lid = lid.Add(1) {
@@ -497,6 +578,26 @@ func decodeVotes(evicted, blid types.LayerID, base *ballotInfo, exceptions types
 	return decoded, from, nil
 }
 
+// changesBaseVotes is true if exceptions change votes for layers before the base layer.
+func changesBaseVotes(base types.LayerID, exceptions types.Votes) bool {
+	for _, header := range exceptions.Support {
+		if header.LayerID.Before(base) {
+			return true
+		}
+	}
+	for _, header := range exceptions.Against {
+		if header.LayerID.Before(base) {
+			return true
+		}
+	}
+	for _, lid := range exceptions.Abstain {
+		if lid.Before(base) {
+			return true
+		}
+	}
+	return false
+}
+
 func activeSetWeight(epoch *epochInfo, aset []types.ATXID) (uint64, error) {
 	var weight uint64
 	for _, id := range aset {
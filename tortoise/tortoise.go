@@ -7,19 +7,26 @@ import (
 	"fmt"
 	"math"
 	"math/big"
+	"sort"
 	"time"
 
 	"github.com/spacemeshos/fixed"
 	"go.uber.org/zap"
+	"golang.org/x/exp/maps"
 
 	"github.com/spacemeshos/go-spacemesh/common/types"
 	"github.com/spacemeshos/go-spacemesh/log"
 	"github.com/spacemeshos/go-spacemesh/proposals/util"
+	"github.com/spacemeshos/go-spacemesh/sql"
+	tmetrics "github.com/spacemeshos/go-spacemesh/tortoise/metrics"
 )
 
 var (
 	errBeaconUnavailable = errors.New("beacon unavailable")
 	ErrBallotExists      = errors.New("tortoise: ballot exists")
+	// ErrTooManyExceptions is returned if votes can't be encoded within MaxExceptions
+	// using any base ballot in the window.
+	ErrTooManyExceptions = errors.New("tortoise: too many exceptions")
 )
 
 type turtle struct {
@@ -41,6 +48,23 @@ type turtle struct {
 
 	isFull bool
 	full   *full
+
+	// late blocks arrived after their layer was verified, they are waiting
+	// for ballots that support them
+	late []*blockInfo
+	// retally are late blocks with enough support, layers starting from the earliest
+	// of them are not verified until blocks are decided by counting all votes
+	retally []*blockInfo
+
+	// db is set in lean mode to decode votes of the lean ballots.
+	db sql.Executor
+	// leanBallots is the number of lean ballots in the state.
+	leanBallots int
+	// partialBallots is the number of ballots that were decoded relative to the lean base.
+	partialBallots int
+	// leanReferences are shared by lean ballots from the leanEpoch.
+	leanEpoch      types.EpochID
+	leanReferences map[referenceKey]*referenceInfo
 }
 
 // newTurtle creates a new verifying tortoise algorithm instance.
@@ -102,6 +126,14 @@ func (t *turtle) evict(ctx context.Context) {
 		for _, ballot := range t.ballots[lid] {
 			ballotsNumber.Dec()
 			delete(t.ballotRefs, ballot.id)
+			if ballot.partial {
+				t.partialBallots--
+			}
+		}
+		if lean := len(t.layers[lid].lean); lean > 0 {
+			ballotsNumber.Sub(float64(lean))
+			t.leanBallots -= lean
+			leanBallotsNumber.Sub(float64(lean))
 		}
 		for range t.layers[lid].blocks {
 			blocksNumber.Dec()
@@ -110,7 +142,7 @@ func (t *turtle) evict(ctx context.Context) {
 		delete(t.layers, lid)
 		delete(t.ballots, lid)
 		if lid.OrdinalInEpoch() == types.GetLayersPerEpoch()-1 {
-			layersNumber.Dec()
+			epochsNumber.Dec()
 			epoch := t.epoch(lid.GetEpoch())
 			for range epoch.atxs {
 				atxsNumber.Dec()
@@ -132,44 +164,93 @@ func (t *turtle) EncodeVotes(ctx context.Context, conf *encodeConf) (*types.Opin
 	}
 	var (
 		err error
+		// the most recent base ballot that was rejected only because of the number of exceptions
+		overflow *ballotInfo
 
 		current = t.last.Add(1)
 	)
 	if conf.current != nil {
 		current = *conf.current
 	}
-	for lid := current.Sub(1); lid.After(t.evicted); lid = lid.Sub(1) {
-		var choices []*ballotInfo
-		if lid == types.GetEffectiveGenesis() {
-			choices = []*ballotInfo{{layer: types.GetEffectiveGenesis()}}
-		} else {
-			choices = t.ballots[lid]
+	limit := t.MaxExceptions
+	if conf.maxExceptions != nil && *conf.maxExceptions < limit {
+		limit = *conf.maxExceptions
+	}
+	tried := map[*ballotInfo]struct{}{}
+	for _, all := range []bool{false, true} {
+		candidates := t.baseCandidates(current, all)
+		if all || len(candidates) == 0 || candidates[0].verdict != VerdictGood {
+			// votes of the ballots that are not consistent with local opinion are needed
+			// to classify them and to encode exceptions
+			if err := t.ensureVotes(); err != nil {
+				return nil, err
+			}
 		}
-		for _, base := range choices {
-			if base.malicious {
-				// skip them as they are candidates for pruning
+		for _, c := range candidates {
+			base := c.ballot
+			if _, exist := tried[base]; exist {
+				continue
+			}
+			tried[base] = struct{}{}
+			candidate := conf.trace.candidate(base, c.verdict, c.reason)
+			if c.verdict == VerdictBad {
+				if candidate != nil {
+					candidate.Error = c.reason
+				}
 				continue
 			}
 			var opinion *types.Opinion
-			opinion, err = t.encodeVotes(ctx, base, t.evicted.Add(1), current)
+			opinion, err = t.encodeVotes(ctx, base, t.evicted.Add(1), current, conf.trace, limit, false)
+			if errors.Is(err, ErrTooManyExceptions) && overflow == nil {
+				overflow = base
+			}
 			if err == nil {
+				if candidate != nil {
+					candidate.Selected = true
+				}
 				layerDistanceToBaseBallot.Observe(float64(t.last - base.layer))
 				t.logger.Info("encoded votes",
 					log.ZContext(ctx),
 					zap.Stringer("base ballot", base.id),
 					zap.Stringer("base layer", base.layer),
+					zap.String("verdict", string(c.verdict)),
+					zap.String("reason", c.reason),
 					zap.Stringer("voting layer", current),
 					zap.Inline(opinion),
 				)
 				return opinion, nil
 			}
+			if candidate != nil {
+				candidate.Error = err.Error()
+			}
 			t.logger.Debug("failed to encode votes using base ballot id",
 				log.ZContext(ctx),
 				zap.Stringer("ballot", base.id),
+				zap.String("verdict", string(c.verdict)),
 				zap.Error(err),
 				zap.Stringer("current layer", current),
 			)
 		}
+		if len(candidates) == 0 || candidates[0].verdict != VerdictGood {
+			// all ballots in the window were already considered
+			break
+		}
+	}
+	if overflow != nil {
+		// local opinion diverged from every ballot in the window, abstain on the oldest layers
+		// instead of listing exceptions for them
+		opinion, err := t.encodeVotes(ctx, overflow, t.evicted.Add(1), current, conf.trace, limit, true)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode votes: %w", err)
+		}
+		t.logger.Warn("encoded votes with abstain on layers that exceed max exceptions",
+			log.ZContext(ctx),
+			zap.Stringer("base ballot", overflow.id),
+			zap.Stringer("base layer", overflow.layer),
+			zap.Stringer("voting layer", current),
+			zap.Inline(opinion),
+		)
+		return opinion, nil
 	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to encode votes: %w", err)
@@ -178,11 +259,17 @@ func (t *turtle) EncodeVotes(ctx context.Context, conf *encodeConf) (*types.Opin
 }
 
 // encode differences between selected base ballot and local votes.
+// trace is nil unless it was requested with EncodeVotesWithTrace or tracing is enabled.
+// If split is true and there are more than limit exceptions, exceptions for the oldest layers
+// are replaced with abstain votes until the rest fits.
 func (t *turtle) encodeVotes(
 	ctx context.Context,
 	base *ballotInfo,
 	start types.LayerID,
 	current types.LayerID,
+	trace *VotesTrace,
+	limit int,
+	split bool,
 ) (*types.Opinion, error) {
 	votes := types.Votes{
 		Base: base.id,
@@ -203,6 +290,7 @@ func (t *turtle) encodeVotes(
 				zap.Uint32("lid", lvote.lid.Uint32()),
 			)
 			votes.Abstain = append(votes.Abstain, lvote.lid)
+			trace.exception(lvote.lid, nil, abstain, &lvote.vote, reasonHareNotTerminated)
 			continue
 		} else if lvote.vote == abstain && !layer.hareTerminated {
 			// there is nothing to encode if hare didn't terminate
@@ -214,11 +302,15 @@ func (t *turtle) encodeVotes(
 			if err != nil {
 				return nil, err
 			}
+			trace.block(block, vote, reason)
 			// ballot vote is consistent with local opinion, exception is not necessary
 			bvote := lvote.getVote(block)
 			if vote == bvote {
 				continue
 			}
+			if vote != abstain {
+				trace.exception(block.layer, block, vote, &bvote, reason.String())
+			}
 			switch vote {
 			case support:
 				t.logger.Debug("support before base ballot",
@@ -251,6 +343,7 @@ func (t *turtle) encodeVotes(
 		if !layer.hareTerminated {
 			t.logger.Debug("voting abstain on the layer", zap.Uint32("lid", lid.Uint32()))
 			votes.Abstain = append(votes.Abstain, lid)
+			trace.exception(lid, nil, abstain, nil, reasonHareNotTerminated)
 			continue
 		}
 		for _, block := range layer.blocks {
@@ -258,6 +351,7 @@ func (t *turtle) encodeVotes(
 			if err != nil {
 				return nil, err
 			}
+			trace.block(block, vote, reason)
 			switch vote {
 			case support:
 				t.logger.Debug("support after base ballot",
@@ -265,6 +359,7 @@ func (t *turtle) encodeVotes(
 					zap.Inline(block),
 					zap.Stringer("reason", reason))
 				votes.Support = append(votes.Support, block.header())
+				trace.exception(block.layer, block, vote, nil, reason.String())
 			case against:
 				t.logger.Debug("implicit against after base ballot",
 					log.ZContext(ctx), zap.Inline(block), zap.Stringer("reason", reason))
@@ -276,8 +371,11 @@ func (t *turtle) encodeVotes(
 		}
 	}
 
-	if explen := len(votes.Support) + len(votes.Against); explen > t.MaxExceptions {
-		return nil, fmt.Errorf("too many exceptions (%v)", explen)
+	if split {
+		abstainOnOldest(&votes, limit)
+	}
+	if explen := len(votes.Support) + len(votes.Against); explen > limit {
+		return nil, fmt.Errorf("%w: %d with max allowed %d", ErrTooManyExceptions, explen, limit)
 	}
 	decoded, _, err := decodeVotes(t.evicted, current, base, votes)
 	if err != nil {
@@ -289,6 +387,50 @@ func (t *turtle) encodeVotes(
 	}, nil
 }
 
+// abstainOnOldest replaces support and against exceptions with abstain votes,
+// starting from the oldest layer, until the number of exceptions is within limit.
+func abstainOnOldest(votes *types.Votes, limit int) {
+	exceptions := map[types.LayerID]int{}
+	for _, vote := range votes.Support {
+		exceptions[vote.LayerID]++
+	}
+	for _, vote := range votes.Against {
+		exceptions[vote.LayerID]++
+	}
+	layers := maps.Keys(exceptions)
+	sort.Slice(layers, func(i, j int) bool { return layers[i] < layers[j] })
+	total := len(votes.Support) + len(votes.Against)
+	abstained := map[types.LayerID]struct{}{}
+	for _, lid := range layers {
+		if total <= limit {
+			break
+		}
+		total -= exceptions[lid]
+		abstained[lid] = struct{}{}
+		votes.Abstain = append(votes.Abstain, lid)
+	}
+	if len(abstained) == 0 {
+		return
+	}
+	keep := func(vote types.Vote) bool {
+		_, exist := abstained[vote.LayerID]
+		return !exist
+	}
+	votes.Support = filterVotes(votes.Support, keep)
+	votes.Against = filterVotes(votes.Against, keep)
+	sort.Slice(votes.Abstain, func(i, j int) bool { return votes.Abstain[i] < votes.Abstain[j] })
+}
+
+func filterVotes(votes []types.Vote, keep func(types.Vote) bool) []types.Vote {
+	rst := votes[:0]
+	for _, vote := range votes {
+		if keep(vote) {
+			rst = append(rst, vote)
+		}
+	}
+	return rst
+}
+
 // getFullVote unlike getLocalVote will vote according to the counted votes on blocks that are
 // outside of hdist. if opinion is undecided according to the votes it will use coinflip recorded
 // in the current layer.
@@ -312,7 +454,7 @@ func (t *turtle) getFullVote(verified, current types.LayerID, block *blockInfo)
 	return layer.coinflip, reasonCoinflip, nil
 }
 
-func (t *turtle) onLayer(ctx context.Context, last types.LayerID) {
+func (t *turtle) onLayer(ctx context.Context, last types.LayerID) error {
 	t.logger.Debug("on layer", zap.Uint32("last", last.Uint32()))
 	defer t.evict(ctx)
 	if last.After(t.last) {
@@ -327,7 +469,7 @@ func (t *turtle) onLayer(ctx context.Context, last types.LayerID) {
 		}
 	}
 	if err := t.drainRetriable(); err != nil {
-		return
+		return err
 	}
 	for process := t.processed.Add(1); !process.After(t.last); process = process.Add(1) {
 		if process.FirstInEpoch() {
@@ -346,15 +488,7 @@ func (t *turtle) onLayer(ctx context.Context, last types.LayerID) {
 			t.full.countDelayed(t.logger, process)
 			t.full.counted = process
 		}
-		for _, ballot := range t.ballots[process] {
-			if err := t.countBallot(ballot); err != nil {
-				if errors.Is(err, errBeaconUnavailable) {
-					t.retryLater(ballot)
-				} else {
-					panic(err)
-				}
-			}
-		}
+		t.countLayerBallots(t.ballots[process])
 
 		layer.prevOpinion = &prev.opinion
 		opinion := layer.opinion
@@ -381,7 +515,10 @@ func (t *turtle) onLayer(ctx context.Context, last types.LayerID) {
 			}
 		}
 	}
-	t.verifyLayers()
+	if err := t.retallyLateBlocks(); err != nil {
+		return err
+	}
+	return t.verifyLayers()
 }
 
 func (t *turtle) switchModes() {
@@ -401,11 +538,9 @@ func (t *turtle) switchModes() {
 }
 
 func (t *turtle) countBallot(ballot *ballotInfo) error {
-	bad, err := t.compareBeacons(ballot.id, ballot.layer, ballot.reference.beacon)
-	if err != nil {
-		return fmt.Errorf("%w: %s", errBeaconUnavailable, err.Error())
+	if err := t.checkBeacon(ballot); err != nil {
+		return err
 	}
-	ballot.conditions.badBeacon = bad
 	t.verifying.countBallot(t.logger, ballot)
 	if !ballot.layer.After(t.full.counted) {
 		t.full.countBallot(t.logger, ballot)
@@ -413,7 +548,38 @@ func (t *turtle) countBallot(ballot *ballotInfo) error {
 	return nil
 }
 
-func (t *turtle) verifyLayers() {
+// countLayerBallots counts ballots from the same layer, votes are counted by full tortoise
+// for all ballots at once. Ballots with unavailable beacon are retried later.
+func (t *turtle) countLayerBallots(ballots []*ballotInfo) {
+	counted := make([]*ballotInfo, 0, len(ballots))
+	for _, ballot := range ballots {
+		if err := t.checkBeacon(ballot); err != nil {
+			t.retryLater(ballot)
+			continue
+		}
+		t.verifying.countBallot(t.logger, ballot)
+		counted = append(counted, ballot)
+	}
+	if len(counted) > 0 && !counted[0].layer.After(t.full.counted) {
+		t.full.countBallots(t.logger, counted)
+	}
+	if len(counted) > 0 {
+		t.compact(counted[0].layer, counted)
+	}
+}
+
+func (t *turtle) checkBeacon(ballot *ballotInfo) error {
+	bad, err := t.compareBeacons(ballot.id, ballot.layer, ballot.reference.beacon)
+	if err != nil {
+		return fmt.Errorf("%w: %s", errBeaconUnavailable, err.Error())
+	}
+	ballot.conditions.badBeacon = bad
+	t.markDirtyBallot(ballot.layer)
+	return nil
+}
+
+// verifyLayers returns errLeanVotes if the full tortoise needs votes of the lean ballots.
+func (t *turtle) verifyLayers() error {
 	var (
 		verified           = maxLayer(t.evicted, types.GetEffectiveGenesis())
 		nverified, changed types.LayerID
@@ -421,14 +587,24 @@ func (t *turtle) verifyLayers() {
 	if t.isFull {
 		nverified, changed = t.runFull()
 		vverified, vchanged := t.runVerifying()
-		if nverified == t.processed-1 && nverified == vverified {
+		if nverified == t.processed-1 && nverified == vverified && len(t.retally) == 0 {
 			t.switchModes()
 			changed = types.MinLayer(changed, vchanged)
 		}
 	} else {
 		nverified, changed = t.runVerifying()
 		// count all votes if next layer after verified is outside hdist
-		if !withinDistance(t.Hdist, nverified+1, t.last) {
+		// or if late block needs to be decided
+		if len(t.retally) > 0 || !withinDistance(t.Hdist, nverified+1, t.last) {
+			if err := t.ensureVotes(); err != nil {
+				// validity changed by the verifying tortoise is not reported again
+				// when layers are verified after votes are restored
+				if changed != 0 {
+					t.pending = types.MinLayer(t.pending, changed)
+					t.onOpinionChange(changed, false)
+				}
+				return err
+			}
 			fverified, fchanged := t.runFull()
 			nverified = fverified
 			changed = types.MinLayer(changed, fchanged)
@@ -445,6 +621,12 @@ func (t *turtle) verifyLayers() {
 		}
 		verified = target
 	}
+	if held := t.holdRetallied(verified); held != verified {
+		verified = held
+		if verified < t.verified {
+			changed = types.MinLayer(changed, verified.Add(1))
+		}
+	}
 	t.logger.Debug("verified layer",
 		zap.Uint32("last", t.last.Uint32()),
 		zap.Uint32("processed", t.last.Uint32()),
@@ -457,6 +639,8 @@ func (t *turtle) verifyLayers() {
 	}
 	t.verified = verified
 	verifiedLayer.Set(float64(t.verified))
+	tmetrics.VerificationLag.Set(float64(t.last) - float64(t.verified))
+	return nil
 }
 
 func (t *turtle) runVerifying() (verified, changed types.LayerID) {
@@ -468,6 +652,7 @@ func (t *turtle) runVerifying() (verified, changed types.LayerID) {
 		}
 		if c {
 			changed = types.MinLayer(changed, target)
+			t.markDirty(target)
 		}
 		verified = target
 	}
@@ -477,13 +662,17 @@ func (t *turtle) runVerifying() (verified, changed types.LayerID) {
 func (t *turtle) runFull() (verified, changed types.LayerID) {
 	if !t.isFull {
 		t.switchModes()
+		tmetrics.HealingActivations.Inc()
+		var ballots []*ballotInfo
 		for counted := maxLayer(t.full.counted.Add(1), t.evicted.Add(1)); !counted.After(t.processed); counted = counted.Add(1) {
-			for _, ballot := range t.ballots[counted] {
-				t.full.countBallot(t.logger, ballot)
+			ballots = append(ballots, t.ballots[counted]...)
+			for _, ballot := range t.full.takeDelayed(counted) {
+				t.markDirtyBallot(ballot.layer)
+				ballots = append(ballots, ballot)
 			}
-			t.full.countDelayed(t.logger, counted)
-			t.full.counted = counted
 		}
+		t.full.countBallots(t.logger, ballots)
+		t.full.counted = maxLayer(t.full.counted, t.processed)
 	}
 	verified = t.evicted
 	for target := t.evicted.Add(1); target.Before(t.processed); target = target.Add(1) {
@@ -493,6 +682,7 @@ func (t *turtle) runFull() (verified, changed types.LayerID) {
 		}
 		if c {
 			changed = types.MinLayer(changed, target)
+			t.markDirty(target)
 		}
 		verified = target
 	}
@@ -518,7 +708,9 @@ func (t *turtle) onBlock(header types.BlockHeader, data, valid bool) {
 		binfo.data = data
 		if valid {
 			binfo.validity = support
+			binfo.source = DecidedByRecovery
 		}
+		t.markDirty(binfo.layer)
 		return
 	}
 	t.logger.Debug("on data block", zap.Inline(&header))
@@ -527,10 +719,110 @@ func (t *turtle) onBlock(header types.BlockHeader, data, valid bool) {
 	binfo.data = data
 	if valid {
 		binfo.validity = support
+		binfo.source = DecidedByRecovery
+	} else if !header.LayerID.After(t.verified) && !withinDistance(t.Hdist, header.LayerID, t.last) {
+		// blocks outside hdist are received only from sync. verifying tortoise will vote against
+		// such block if the layer has a valid block with lower height, therefore it is tracked
+		// until ballots that support it are counted
+		t.logger.Debug("late block in verified layer",
+			zap.Stringer("block", header.ID),
+			zap.Uint32("lid", header.LayerID.Uint32()),
+			zap.Uint32("verified", t.verified.Uint32()),
+		)
+		t.late = append(t.late, binfo)
 	}
 	t.addBlock(binfo)
 }
 
+// retallyLateBlocks rolls back verified layer to the layer before the earliest late block
+// that is supported by ballots with weight above the global threshold.
+// Layers starting from the rolled back layer are verified again after late block is decided
+// by counting all votes, as the layer is outside hdist.
+func (t *turtle) retallyLateBlocks() error {
+	if len(t.late) == 0 {
+		return nil
+	}
+	if err := t.ensureVotes(); err != nil {
+		return err
+	}
+	var (
+		rollback types.LayerID
+		pending  = t.late[:0]
+	)
+	for _, block := range t.late {
+		if !block.layer.After(t.evicted) || block.validity == support {
+			continue
+		}
+		supported := t.lateSupport(block)
+		threshold := t.globalThreshold(t.Config, block.layer)
+		if crossesThreshold(supported, threshold) != support {
+			pending = append(pending, block)
+			continue
+		}
+		t.logger.Debug("late block is supported by the network",
+			zap.Stringer("block", block.id),
+			zap.Uint32("lid", block.layer.Uint32()),
+			zap.Float64("support", supported.Float()),
+			zap.Float64("threshold", threshold.Float()),
+		)
+		if rollback == 0 || block.layer.Before(rollback) {
+			rollback = block.layer
+		}
+		t.retally = append(t.retally, block)
+	}
+	for i := len(pending); i < len(t.late); i++ {
+		t.late[i] = nil
+	}
+	t.late = pending
+	if rollback == 0 || rollback.After(t.verified) {
+		return nil
+	}
+	t.verified = rollback.Sub(1)
+	t.markDirty(rollback)
+	return nil
+}
+
+// holdRetallied returns the layer before the earliest late block that is not decided
+// by counting all votes, or verified layer if there are no such blocks.
+func (t *turtle) holdRetallied(verified types.LayerID) types.LayerID {
+	pending := t.retally[:0]
+	for _, block := range t.retally {
+		if !block.layer.After(t.evicted) {
+			continue
+		}
+		if crossesThreshold(block.margin, t.globalThreshold(t.Config, block.layer)) != neutral {
+			continue
+		}
+		pending = append(pending, block)
+		if !verified.Before(block.layer) {
+			verified = block.layer.Sub(1)
+		}
+	}
+	for i := len(pending); i < len(t.retally); i++ {
+		t.retally[i] = nil
+	}
+	t.retally = pending
+	return verified
+}
+
+// lateSupport is the weight of ballots that vote for the late block.
+func (t *turtle) lateSupport(block *blockInfo) weight {
+	total := weight{}
+	for lid := block.layer.Add(1); !lid.After(t.processed); lid = lid.Add(1) {
+		for _, ballot := range t.ballots[lid] {
+			if ballot.malicious || ballot.conditions.badBeacon || block.height > ballot.reference.height {
+				continue
+			}
+			for current := ballot.votes.tail; current != nil && !current.lid.Before(block.layer); current = current.prev {
+				if current.lid == block.layer && current.getVote(block) == support {
+					total = total.Add(ballot.weight)
+				}
+			}
+		}
+	}
+	return total
+}
+
 func (t *turtle) addBlock(binfo *blockInfo) {
 	start := time.Now()
 	t.state.addBlock(binfo)
@@ -557,6 +849,7 @@ func (t *turtle) onHareOutput(lid types.LayerID, bid types.BlockID) {
 		zap.Uint32("last", t.last.Uint32()),
 	)
 	layer.hareTerminated = true
+	t.markDirty(lid)
 	for i := range layer.blocks {
 		block := layer.blocks[i]
 		if block.hare == support {
@@ -605,11 +898,39 @@ func (t *turtle) onOpinionChange(lid types.LayerID, early bool) {
 	}
 	if changed != 0 {
 		t.pending = types.MinLayer(t.pending, changed)
+		t.markDirty(changed)
 		t.verifying.resetWeights(lid)
 		for target := lid.Add(1); !target.After(t.processed); target = target.Add(1) {
 			t.verifying.countVotes(t.logger, t.ballots[target])
+			t.verifying.countLean(t.layer(target))
+		}
+	}
+}
+
+// onMalfeasance marks identity as malfeasant and reverses counting of its ballots in the window,
+// so that tallies are the same as if those ballots were counted with zero weight.
+// Returns the number of ballots that were reversed, or errLeanVotes if the state has lean ballots,
+// as they don't keep the smesher.
+func (t *turtle) onMalfeasance(id types.NodeID) (int, error) {
+	if err := t.ensureVotes(); err != nil {
+		return 0, err
+	}
+	t.makrMalfeasant(id)
+	reversed := 0
+	for _, blts := range t.ballots {
+		for _, ballot := range blts {
+			if ballot.smesher != id || ballot.malicious {
+				continue
+			}
+			t.verifying.uncountBallot(ballot)
+			t.full.uncountBallot(ballot)
+			ballot.malicious = true
+			ballot.weight = weight{}
+			t.markDirtyBallot(ballot.layer)
+			reversed++
 		}
 	}
+	return reversed, nil
 }
 
 func (t *turtle) onAtx(atx *types.AtxTortoiseData) {
@@ -652,7 +973,7 @@ func (t *turtle) decodeBallot(ballot *types.BallotTortoiseData) (*ballotInfo, ty
 	if !ballot.Layer.After(t.evicted) {
 		return nil, 0, nil
 	}
-	if info, exist := t.state.ballotRefs[ballot.ID]; exist {
+	if info := t.findBallot(ballot.ID, ballot.Layer, ballot.Layer.Sub(1)); info != nil {
 		return info, 0, nil
 	}
 
@@ -669,7 +990,7 @@ func (t *turtle) decodeBallot(ballot *types.BallotTortoiseData) (*ballotInfo, ty
 	if ballot.Opinion.Votes.Base == types.EmptyBallotID {
 		base = &ballotInfo{layer: types.GetEffectiveGenesis()}
 	} else {
-		base = t.state.ballotRefs[ballot.Opinion.Votes.Base]
+		base = t.findBallot(ballot.Opinion.Votes.Base, ballot.Layer.Sub(1), t.evicted)
 		if base == nil {
 			t.logger.Warn("base ballot not in state",
 				zap.Stringer("base", ballot.Opinion.Votes.Base),
@@ -681,30 +1002,20 @@ func (t *turtle) decodeBallot(ballot *types.BallotTortoiseData) (*ballotInfo, ty
 		return nil, 0, fmt.Errorf("votes for ballot (%s/%s) should be encoded with base ballot (%s/%s) from previous layers",
 			ballot.Layer, ballot.ID, base.layer, base.id)
 	}
+	if base.lean && (!t.collapsible(ballot.Layer) || changesBaseVotes(base.layer, ballot.Opinion.Votes)) {
+		return nil, 0, errLeanVotes
+	}
 
 	if ballot.EpochData != nil {
-		epoch := t.epoch(ballot.Layer.GetEpoch())
-		atx, exists := epoch.atxs[ballot.AtxID]
-		if !exists {
-			return nil, 0, fmt.Errorf("atx %s/%d not in state", ballot.AtxID, ballot.Layer.GetEpoch())
-		}
-		total, err := activeSetWeight(epoch, ballot.EpochData.ActiveSet)
+		var err error
+		refinfo, err = t.referenceInfo(ballot)
 		if err != nil {
 			return nil, 0, err
 		}
-		expected, err := util.GetNumEligibleSlots(atx.weight, t.MinimalActiveSetWeight, total, t.LayerSize, types.GetLayersPerEpoch())
-		if err != nil {
-			return nil, 0, err
-		}
-		refinfo = &referenceInfo{
-			height: atx.height,
-			beacon: ballot.EpochData.Beacon,
-			weight: big.NewRat(int64(atx.weight), int64(expected)),
-		}
 	} else if ballot.Ref != nil {
 		ptr := *ballot.Ref
-		ref, exists := t.state.ballotRefs[ptr]
-		if !exists {
+		ref := t.findBallot(ptr, ballot.Layer, ballot.Layer.GetEpoch().FirstLayer().Sub(1))
+		if ref == nil {
 			t.logger.Warn("ref ballot not in state",
 				zap.Stringer("ref", ptr),
 			)
@@ -717,7 +1028,44 @@ func (t *turtle) decodeBallot(ballot *types.BallotTortoiseData) (*ballotInfo, ty
 	} else {
 		return nil, 0, fmt.Errorf("epoch data and pointer are nil for ballot %s", ballot.ID)
 	}
+	binfo, min, err := t.decodeWith(ballot, base, refinfo, t.evicted)
+	if err != nil {
+		return nil, 0, err
+	}
+	decodeBallotDuration.Observe(float64(time.Since(start).Nanoseconds()))
+	return binfo, min, nil
+}
 
+// referenceInfo computes weight per eligibility for the reference ballot.
+func (t *turtle) referenceInfo(ballot *types.BallotTortoiseData) (*referenceInfo, error) {
+	epoch := t.epoch(ballot.Layer.GetEpoch())
+	atx, exists := epoch.atxs[ballot.AtxID]
+	if !exists {
+		return nil, fmt.Errorf("atx %s/%d not in state", ballot.AtxID, ballot.Layer.GetEpoch())
+	}
+	total, err := activeSetWeight(epoch, ballot.EpochData.ActiveSet)
+	if err != nil {
+		return nil, err
+	}
+	expected, err := util.GetNumEligibleSlots(atx.weight, t.MinimalActiveSetWeight, total, t.LayerSize, types.GetLayersPerEpoch())
+	if err != nil {
+		return nil, err
+	}
+	return &referenceInfo{
+		height: atx.height,
+		beacon: ballot.EpochData.Beacon,
+		weight: big.NewRat(int64(atx.weight), int64(expected)),
+	}, nil
+}
+
+// decodeWith computes weight of the ballot and decodes its votes relative to the base ballot.
+// Votes for layers that are not after evicted are rejected.
+func (t *turtle) decodeWith(
+	ballot *types.BallotTortoiseData,
+	base *ballotInfo,
+	refinfo *referenceInfo,
+	evicted types.LayerID,
+) (*ballotInfo, types.LayerID, error) {
 	binfo := &ballotInfo{
 		id: ballot.ID,
 		base: baseInfo{
@@ -726,6 +1074,7 @@ func (t *turtle) decodeBallot(ballot *types.BallotTortoiseData) (*ballotInfo, ty
 		},
 		reference: refinfo,
 		layer:     ballot.Layer,
+		smesher:   ballot.Smesher,
 		malicious: ballot.Malicious || t.isMalfeasant(ballot.Smesher),
 	}
 
@@ -743,17 +1092,17 @@ func (t *turtle) decodeBallot(ballot *types.BallotTortoiseData) (*ballotInfo, ty
 		zap.Uint32("lid", ballot.Layer.Uint32()),
 	)
 
-	votes, min, err := decodeVotes(t.evicted, binfo.layer, base, ballot.Opinion.Votes)
+	votes, min, err := decodeVotes(evicted, binfo.layer, base, ballot.Opinion.Votes)
 	if err != nil {
 		return nil, 0, err
 	}
 	binfo.votes = votes
+	binfo.partial = base.lean
 	t.logger.Debug("decoded exceptions",
 		zap.Stringer("block", binfo.id),
 		zap.Uint32("lid", binfo.layer.Uint32()),
 		zap.Stringer("opinion", binfo.opinion()),
 	)
-	decodeBallotDuration.Observe(float64(time.Since(start).Nanoseconds()))
 	return binfo, min, nil
 }
 
@@ -761,11 +1110,15 @@ func (t *turtle) storeBallot(ballot *ballotInfo, min types.LayerID) error {
 	if !ballot.layer.After(t.evicted) {
 		return nil
 	}
-	if _, exists := t.ballotRefs[ballot.id]; exists {
+	if t.findBallot(ballot.id, ballot.layer, ballot.layer.Sub(1)) != nil {
 		return fmt.Errorf("%w: %s", ErrBallotExists, ballot.id)
 	}
 
 	t.state.addBallot(ballot)
+	if ballot.partial {
+		t.partialBallots++
+	}
+	tmetrics.ProcessedBallots.Inc()
 	for current := ballot.votes.tail; current != nil && !current.lid.Before(min); current = current.prev {
 		for i, block := range current.supported {
 			existing := t.getBlock(block.header())
@@ -783,11 +1136,28 @@ func (t *turtle) storeBallot(ballot *ballotInfo, min types.LayerID) error {
 			} else {
 				t.logger.Panic("unexpected error in counting ballots", zap.Error(err))
 			}
+		} else {
+			t.compact(ballot.layer, []*ballotInfo{ballot})
 		}
 	}
 	return nil
 }
 
+// dependenciesKnown returns true if base and reference ballots are in the state.
+func (t *turtle) dependenciesKnown(ballot *types.BallotTortoiseData) bool {
+	if base := ballot.Opinion.Votes.Base; base != types.EmptyBallotID {
+		if t.findBallot(base, ballot.Layer.Sub(1), t.evicted) == nil {
+			return false
+		}
+	}
+	if ballot.EpochData == nil && ballot.Ref != nil {
+		if t.findBallot(*ballot.Ref, ballot.Layer, ballot.Layer.GetEpoch().FirstLayer().Sub(1)) == nil {
+			return false
+		}
+	}
+	return true
+}
+
 func (t *turtle) onBallot(ballot *types.BallotTortoiseData) error {
 	decoded, min, err := t.decodeBallot(ballot)
 	if decoded == nil || err != nil {
@@ -819,7 +1189,8 @@ func (t *turtle) retryLater(ballot *ballotInfo) {
 
 func (t *turtle) drainRetriable() error {
 	for front := t.retriable.Front(); front != nil; {
-		if err := t.countBallot(front.Value.(*ballotInfo)); err != nil {
+		ballot := front.Value.(*ballotInfo)
+		if err := t.countBallot(ballot); err != nil {
 			// if beacon is still unavailable - exit and wait for the next call
 			// to drain this queue
 			if errors.Is(err, errBeaconUnavailable) {
@@ -827,6 +1198,7 @@ func (t *turtle) drainRetriable() error {
 			}
 			return err
 		}
+		t.compact(ballot.layer, []*ballotInfo{ballot})
 		next := front.Next()
 		t.retriable.Remove(front)
 		front = next
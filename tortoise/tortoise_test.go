@@ -3,13 +3,16 @@ package tortoise
 import (
 	"context"
 	"encoding/binary"
+	"encoding/json"
 	"fmt"
+	"math"
 	"math/rand"
 	"os"
 	"strconv"
 	"testing"
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/spacemeshos/fixed"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -27,6 +30,7 @@ import (
 	"github.com/spacemeshos/go-spacemesh/sql/certificates"
 	"github.com/spacemeshos/go-spacemesh/sql/identities"
 	"github.com/spacemeshos/go-spacemesh/sql/layers"
+	"github.com/spacemeshos/go-spacemesh/system"
 	"github.com/spacemeshos/go-spacemesh/tortoise/opinionhash"
 	"github.com/spacemeshos/go-spacemesh/tortoise/sim"
 )
@@ -212,6 +216,102 @@ func TestAbstainLateBlock(t *testing.T) {
 	require.Empty(t, events)
 }
 
+func TestAbstainMajorityOnHareFailure(t *testing.T) {
+	const size = 10
+	s := sim.New(
+		sim.WithLayerSize(size),
+	)
+	s.Setup(sim.WithSetupMinerRange(size, size))
+
+	ctx := context.Background()
+	cfg := defaultTestConfig()
+	cfg.LayerSize = size
+	cfg.Hdist = 3
+	cfg.Zdist = 3
+	tortoise := tortoiseFromSimState(t, s.GetState(0), WithConfig(cfg), WithLogger(logtest.New(t)))
+
+	var last types.LayerID
+	for i := 0; i < 2; i++ {
+		last = s.Next(sim.WithNumBlocks(1), sim.WithVoteGenerator(tortoiseVoting(tortoise)))
+		tortoise.TallyVotes(ctx, last)
+	}
+	target := s.Next(sim.WithNumBlocks(1), sim.WithVoteGenerator(tortoiseVoting(tortoise)))
+	tortoise.TallyVotes(ctx, target)
+
+	// hare failed for 60% of the network, they abstain on the whole target layer
+	last = s.Next(sim.WithNumBlocks(1), sim.WithVoteGenerator(
+		sim.VaryingVoting(size*4/10, tortoiseVoting(tortoise), abstainVoting),
+	))
+	tortoise.TallyVotes(ctx, last)
+	// weight of the minority that voted on the layer is not enough to verify it,
+	// abstained weight doesn't lower the threshold
+	require.True(t, tortoise.LatestComplete().Before(target))
+
+	for i := 0; i < int(cfg.Hdist)+2; i++ {
+		last = s.Next(sim.WithNumBlocks(1), sim.WithVoteGenerator(tortoiseVoting(tortoise)))
+		tortoise.TallyVotes(ctx, last)
+	}
+	require.Equal(t, last.Sub(1), tortoise.LatestComplete())
+
+	layer := tortoise.trtl.layer(target)
+	require.Len(t, layer.blocks, 1)
+	require.Equal(t, support, layer.blocks[0].validity)
+}
+
+func TestAbstainLateBlockHealing(t *testing.T) {
+	const size = 4
+	s := sim.New(
+		sim.WithLayerSize(size),
+	)
+	s.Setup(sim.WithSetupMinerRange(size, size))
+
+	ctx := context.Background()
+	cfg := defaultTestConfig()
+	cfg.LayerSize = size
+	cfg.Hdist = 3
+	cfg.Zdist = 2
+	tortoise := tortoiseFromSimState(t, s.GetState(0), WithConfig(cfg), WithLogger(logtest.New(t)))
+
+	var last types.LayerID
+	for i := 0; i < 2; i++ {
+		last = s.Next(sim.WithNumBlocks(1), sim.WithVoteGenerator(tortoiseVoting(tortoise)))
+		tortoise.TallyVotes(ctx, last)
+	}
+	failed := s.Next(
+		sim.WithNumBlocks(1),
+		sim.WithVoteGenerator(tortoiseVoting(tortoise)),
+		sim.WithoutHareOutput(),
+	)
+	tortoise.TallyVotes(ctx, failed)
+	last = s.Next(sim.WithNumBlocks(1), sim.WithVoteGenerator(abstainVoting))
+	tortoise.TallyVotes(ctx, last)
+
+	// block wasn't known to anyone who abstained on the layer,
+	// it inherits the abstain votes instead of receiving votes against
+	late := types.BlockHeader{ID: types.BlockID{1}, LayerID: failed}
+	tortoise.OnBlock(late)
+	tortoise.TallyVotes(ctx, last)
+
+	// abstained weight is not enough to decide the layer, it is decided by healing
+	// according to the weak coin
+	for i := 0; i < int(cfg.Zdist+cfg.Hdist)+2; i++ {
+		last = s.Next(
+			sim.WithNumBlocks(1),
+			sim.WithVoteGenerator(tortoiseVoting(tortoise)),
+			sim.WithCoin(false),
+		)
+		tortoise.TallyVotes(ctx, last)
+	}
+	require.False(t, tortoise.LatestComplete().Before(failed),
+		"layer %s must be verified, latest complete %s", failed, tortoise.LatestComplete())
+
+	layer := tortoise.trtl.layer(failed)
+	require.Len(t, layer.blocks, 2)
+	for _, block := range layer.blocks {
+		require.Equal(t, against, block.validity, block.id)
+	}
+}
+
 func TestEncodeAbstainVotesForZdist(t *testing.T) {
 	const (
 		size  = 4
@@ -1096,21 +1196,25 @@ func TestBaseBallotPrioritization(t *testing.T) {
 			expected: genesis.Add(5),
 		},
 		{
-			desc: "BadBlocksIgnored",
+			// ballots that support every block in the previous layers are inconsistent
+			// with local opinion, the highest ballot with the same opinion is selected
+			desc: "InconsistentBallotsIgnored",
 			seqs: []sim.Sequence{
 				sim.WithSequence(5),
 				sim.WithSequence(5, sim.WithVoteGenerator(olderExceptions)),
 			},
-			expected: genesis.Add(10),
+			expected: genesis.Add(5),
 			window:   5,
 		},
 		{
-			desc: "BadBlocksOverflowAfterEviction",
+			// full tortoise eventually agrees with the votes outside hdist,
+			// ballots that voted on layers within hdist are still inconsistent with hare output
+			desc: "InconsistentBallotsAfterHealing",
 			seqs: []sim.Sequence{
 				sim.WithSequence(5),
 				sim.WithSequence(20, sim.WithVoteGenerator(olderExceptions)),
 			},
-			expected: genesis.Add(25),
+			expected: genesis.Add(16),
 			window:   5,
 		},
 		{
@@ -1119,7 +1223,7 @@ func TestBaseBallotPrioritization(t *testing.T) {
 				sim.WithSequence(5),
 				sim.WithSequence(1, sim.WithVoteGenerator(gapVote)),
 			},
-			expected: genesis.Add(6),
+			expected: genesis.Add(5),
 			window:   10,
 		},
 	} {
@@ -1244,10 +1348,14 @@ func TestWeakCoinVoting(t *testing.T) {
 	votes, err := tortoise.EncodeVotes(ctx, EncodeVotesWithCurrent(last.Add(1)))
 	require.NoError(t, err)
 
-	require.Len(t, votes.Support, 2)
-	block, err := blocks.Get(s.GetState(0).DB, votes.Support[0].ID)
-	require.NoError(t, err)
-	require.Equal(t, block.LayerIndex, genesis.Add(2))
+	// ballots after the first layer split their votes, the base ballot is from the first layer
+	// and both blocks in the layers with split votes are supported according to the weak coin
+	require.Len(t, votes.Support, 4)
+	for _, vote := range votes.Support {
+		block, err := blocks.Get(s.GetState(0).DB, vote.ID)
+		require.NoError(t, err)
+		require.Contains(t, []types.LayerID{genesis.Add(1), genesis.Add(2)}, block.LayerIndex)
+	}
 
 	for i := 0; i < 10; i++ {
 		last = s.Next(sim.WithVoteGenerator(tortoiseVoting(tortoise)))
@@ -1500,6 +1608,21 @@ func TestComputeBallotWeight(t *testing.T) {
 				{ActiveSet: []int{2, 3}, ATX: 2, ExpectedWeight: 20, Eligibilities: 1},
 			},
 		},
+		{
+			// small atxs are rounded up to a single eligibility per epoch,
+			// but their ballots together still weigh less than a single ballot of the large atx
+			desc:           "HighWeightOutweighsManyLowWeight",
+			atxs:           []uint{900, 25, 25, 25, 25},
+			layerSize:      10,
+			layersPerEpoch: 2,
+			ballots: []testBallot{
+				{ActiveSet: []int{0, 1, 2, 3, 4}, ATX: 0, ExpectedWeight: 450, Eligibilities: 9},
+				{ActiveSet: []int{0, 1, 2, 3, 4}, ATX: 1, ExpectedWeight: 25, Eligibilities: 1},
+				{ActiveSet: []int{0, 1, 2, 3, 4}, ATX: 2, ExpectedWeight: 25, Eligibilities: 1},
+				{ActiveSet: []int{0, 1, 2, 3, 4}, ATX: 3, ExpectedWeight: 25, Eligibilities: 1},
+				{ActiveSet: []int{0, 1, 2, 3, 4}, ATX: 4, ExpectedWeight: 25, Eligibilities: 1},
+			},
+		},
 	} {
 		tc := tc
 		t.Run(tc.desc, func(t *testing.T) {
@@ -1516,6 +1639,7 @@ func TestComputeBallotWeight(t *testing.T) {
 
 			cfg := DefaultConfig()
 			cfg.LayerSize = tc.layerSize
+			cfg.QueueSize = 0 // ballot state is checked right after OnBallot
 			trtl, err := New(WithLogger(logtest.New(t)), WithConfig(cfg))
 			require.NoError(t, err)
 			lid := types.LayerID(111)
@@ -1682,6 +1806,78 @@ func TestNetworkRecoversFromFullPartition(t *testing.T) {
 	}
 }
 
+// divergentBeacons returns a different beacon for one epoch.
+type divergentBeacons struct {
+	system.BeaconGetter
+	epoch  types.EpochID
+	beacon types.Beacon
+}
+
+func (d divergentBeacons) GetBeacon(eid types.EpochID) (types.Beacon, error) {
+	if eid == d.epoch {
+		return d.beacon, nil
+	}
+	return d.BeaconGetter.GetBeacon(eid)
+}
+
+func TestBeaconPartitionsReconverge(t *testing.T) {
+	const size = 10
+	ctx := context.Background()
+	s := sim.New(sim.WithLayerSize(size))
+	s.Setup()
+
+	cfg := defaultTestConfig()
+	cfg.LayerSize = size
+	cfg.Hdist = 2
+	cfg.Zdist = 2
+	cfg.BadBeaconVoteDelayLayers = types.GetLayersPerEpoch()
+
+	divergent := types.GetEffectiveGenesis().GetEpoch() + 2
+	trtl1 := tortoiseFromSimState(t, s.GetState(0), WithConfig(cfg), WithLogger(logtest.New(t).Named("first")))
+	trtl2 := tortoiseFromSimState(t, s.GetState(0), WithConfig(cfg), WithLogger(logtest.New(t).Named("second")))
+	// second instance computed a different beacon for one epoch, all ballots from that epoch
+	// reference a beacon that doesn't match its local beacon
+	trtl2.beacon = divergentBeacons{
+		BeaconGetter: s.GetState(0).Beacons,
+		epoch:        divergent,
+		beacon:       types.Beacon{1, 2, 3, 4},
+	}
+
+	var (
+		last     types.LayerID
+		excluded float64
+		lagged   bool
+	)
+	for i := 0; i < 5*int(types.GetLayersPerEpoch()); i++ {
+		last = s.Next()
+		trtl1.TallyVotes(ctx, last)
+		trtl2.TallyVotes(ctx, last)
+		require.Equal(t, last.Sub(1), trtl1.LatestComplete())
+		if last.GetEpoch() == divergent {
+			lagged = lagged || trtl2.LatestComplete().Before(last.Sub(1))
+		}
+		excluded = math.Max(excluded, testutil.ToFloat64(delayedWeight))
+		require.Equal(t, trtl2.trtl.full.delayedWeight.Float(), testutil.ToFloat64(delayedWeight))
+	}
+	require.True(t, lagged, "ballots with bad beacon must not be counted immediately")
+	require.Positive(t, excluded)
+
+	// votes are counted after the delay, and both instances reach the same decisions
+	require.Equal(t, last.Sub(1), trtl2.LatestComplete())
+	require.Zero(t, trtl2.trtl.full.delayedWeight.Float())
+	require.Zero(t, testutil.ToFloat64(delayedWeight))
+	from := types.GetEffectiveGenesis().Add(1)
+	rst1, err := trtl1.Results(from, last.Sub(1))
+	require.NoError(t, err)
+	rst2, err := trtl2.Results(from, last.Sub(1))
+	require.NoError(t, err)
+	require.Len(t, rst2, len(rst1))
+	for i := range rst1 {
+		require.Equal(t, rst1[i].Opinion, rst2[i].Opinion, "layer=%s", rst1[i].Layer)
+		require.Equal(t, rst1[i].FirstValid(), rst2[i].FirstValid(), "layer=%s", rst1[i].Layer)
+	}
+}
+
 func TestVerifyLayerByWeightNotSize(t *testing.T) {
 	const size = 8
 	s := sim.New(
@@ -1875,6 +2071,100 @@ func TestLateBlock(t *testing.T) {
 	require.True(t, valid)
 }
 
+func TestLateBlockAfterVerified(t *testing.T) {
+	ctx := context.Background()
+	const size = 10
+	cfg := defaultTestConfig()
+	cfg.LayerSize = size
+	cfg.Hdist = 2
+	cfg.Zdist = cfg.Hdist
+
+	// late block is added to the first layer after it was verified by verifying tortoise
+	setup := func(t *testing.T) (*sim.Generator, *recoveryAdapter, *types.Block) {
+		s := sim.New(sim.WithLayerSize(size))
+		s.Setup(sim.WithSetupUnitsRange(2, 2))
+		tortoise := tortoiseFromSimState(t, s.GetState(0), WithLogger(logtest.New(t)), WithConfig(cfg))
+		var last types.LayerID
+		for _, last = range sim.GenLayers(s, sim.WithSequence(4)) {
+			tortoise.TallyVotes(ctx, last)
+		}
+		require.Equal(t, last.Sub(1), tortoise.LatestComplete())
+		require.Equal(t, Mode(Verifying), tortoise.Mode())
+		processBlockUpdates(t, tortoise, s.GetState(0).DB)
+
+		target := types.GetEffectiveGenesis().Add(1)
+		blks, err := blocks.Layer(s.GetState(0).DB, target)
+		require.NoError(t, err)
+		require.NotEmpty(t, blks)
+		buf, err := codec.Encode(blks[0])
+		require.NoError(t, err)
+		var block types.Block
+		require.NoError(t, codec.Decode(buf, &block))
+		require.True(t, len(block.TxIDs) > 2)
+		block.TxIDs = block.TxIDs[:2]
+		// verifying tortoise votes against block with height above the height of the valid block
+		block.TickHeight++
+		block.Initialize()
+		tortoise.OnBlock(block.ToVote())
+		require.NoError(t, blocks.Add(s.GetState(0).DB, &block))
+		return s, tortoise, &block
+	}
+	target := func() types.LayerID {
+		return types.GetEffectiveGenesis().Add(1)
+	}
+
+	t.Run("supported", func(t *testing.T) {
+		s, tortoise, block := setup(t)
+		var (
+			last       types.LayerID
+			rolledBack bool
+		)
+		for _, last = range sim.GenLayers(s,
+			sim.WithSequence(3, sim.WithVoteGenerator(voteForBlock(block))),
+			sim.WithSequence(4),
+		) {
+			tortoise.TallyVotes(ctx, last)
+			rolledBack = rolledBack || tortoise.LatestComplete() < target()
+		}
+		require.True(t, rolledBack, "verified layer must be rolled back until late block is decided")
+		require.Equal(t, last.Sub(1), tortoise.LatestComplete())
+
+		updates := tortoise.Updates()
+		require.NotEmpty(t, updates)
+		require.Equal(t, target(), updates[0].Layer)
+		var found bool
+		for _, rst := range updates[0].Blocks {
+			if rst.Header.ID == block.ID() {
+				found = true
+				require.True(t, rst.Valid)
+				require.True(t, rst.Changed)
+			}
+		}
+		require.True(t, found)
+		for _, rst := range updates {
+			for _, b := range rst.Blocks {
+				if b.Valid {
+					require.NoError(t, blocks.SetValid(s.GetState(0).DB, b.Header.ID))
+				}
+			}
+		}
+		valid, err := blocks.IsValid(s.GetState(0).DB, block.ID())
+		require.NoError(t, err)
+		require.True(t, valid)
+	})
+	t.Run("not supported", func(t *testing.T) {
+		s, tortoise, block := setup(t)
+		for _, last := range sim.GenLayers(s, sim.WithSequence(7)) {
+			tortoise.TallyVotes(ctx, last)
+			require.Equal(t, last.Sub(1), tortoise.LatestComplete())
+		}
+		processBlockUpdates(t, tortoise, s.GetState(0).DB)
+		valid, err := blocks.IsValid(s.GetState(0).DB, block.ID())
+		require.NoError(t, err)
+		require.False(t, valid)
+	})
+}
+
 func TestMaliciousBallotsAreIgnored(t *testing.T) {
 	ctx := context.Background()
 	const size = 10
@@ -1903,6 +2193,104 @@ func TestMaliciousBallotsAreIgnored(t *testing.T) {
 	require.Empty(t, votes.Base)
 }
 
+func requireSameTallies(tb testing.TB, expected, actual *turtle) {
+	tb.Helper()
+	require.Equal(tb, expected.evicted, actual.evicted)
+	require.Equal(tb, expected.processed, actual.processed)
+	for lid := expected.evicted.Add(1); !lid.After(expected.processed); lid = lid.Add(1) {
+		elayer, alayer := expected.layer(lid), actual.layer(lid)
+		require.Equal(tb, elayer.empty, alayer.empty, "layer %d", lid)
+		require.Equal(tb, elayer.abstained, alayer.abstained, "layer %d", lid)
+		require.Len(tb, alayer.blocks, len(elayer.blocks), "layer %d", lid)
+		for i, block := range elayer.blocks {
+			require.Equal(tb, block.id, alayer.blocks[i].id, "layer %d", lid)
+			require.Equal(tb, block.margin, alayer.blocks[i].margin, "layer %d block %s", lid, block.id)
+		}
+	}
+}
+
+func requireSameGoodWeight(tb testing.TB, expected, actual *turtle) {
+	tb.Helper()
+	require.Equal(tb, expected.verifying.totalGoodWeight, actual.verifying.totalGoodWeight)
+	for lid := expected.evicted.Add(1); !lid.After(expected.processed); lid = lid.Add(1) {
+		require.Equal(tb,
+			expected.layer(lid).verifying.goodUncounted,
+			actual.layer(lid).verifying.goodUncounted,
+			"layer %d", lid,
+		)
+	}
+}
+
+func TestOnMalfeasanceReversesCountedBallots(t *testing.T) {
+	ctx := context.Background()
+	const size = 8
+	// smeshers with weight 20, 10 and 10 are eligible for 3, 3 and 2 slots in every layer,
+	// therefore two of them control 3/8 of the weight in the layer each
+	s := sim.New(sim.WithLayerSize(size))
+	s.Setup(
+		sim.WithSetupMinerRange(3, 3),
+		sim.WithSetupUnitsRange(1, 1),
+		sim.WithSetupTicks(20, 10, 10),
+	)
+	cfg := defaultTestConfig()
+	cfg.LayerSize = size
+	opts := []Opt{WithLogger(logtest.New(t)), WithConfig(cfg)}
+	state := s.GetState(0)
+
+	tortoise := tortoiseFromSimState(t, state, opts...)
+	var last types.LayerID
+	for i := 0; i < 6; i++ {
+		last = s.Next()
+		tortoise.TallyVotes(ctx, last)
+	}
+	require.Equal(t, last.Sub(1), tortoise.LatestComplete())
+	require.Equal(t, Mode(Verifying), tortoise.Mode())
+
+	blts, err := ballots.Layer(state.DB, last)
+	require.NoError(t, err)
+	var cheaters []types.NodeID
+	for _, ballot := range blts {
+		if len(ballot.EligibilityProofs) == 3 {
+			cheaters = append(cheaters, ballot.SmesherID)
+		}
+	}
+	require.Len(t, cheaters, 2)
+
+	// verifying tortoise can't cross threshold without the weight of the cheater
+	require.NoError(t, identities.SetMalicious(state.DB, cheaters[0], []byte("proof")))
+	tortoise.OnMalfeasance(cheaters[0])
+	require.Equal(t, types.GetEffectiveGenesis(), tortoise.LatestComplete())
+
+	reference := tortoiseFromSimState(t, state, opts...)
+	reference.OnMalfeasance(cheaters[0])
+	reference.TallyVotes(ctx, last)
+	requireSameTallies(t, reference.trtl, tortoise.trtl)
+	requireSameGoodWeight(t, reference.trtl, tortoise.trtl)
+	require.Equal(t, reference.LatestComplete(), tortoise.LatestComplete())
+
+	// layers are verified again by full tortoise once they are out of hdist
+	for i := 0; i < int(cfg.Hdist)+1; i++ {
+		last = s.Next()
+		tortoise.TallyVotes(ctx, last)
+	}
+	require.Equal(t, Mode(Full), tortoise.Mode())
+	require.False(t, tortoise.LatestComplete().Before(last.Sub(2)))
+
+	// ballots that were counted by full tortoise are reversed as well
+	require.NoError(t, identities.SetMalicious(state.DB, cheaters[1], []byte("proof")))
+	tortoise.OnMalfeasance(cheaters[1])
+	require.True(t, tortoise.LatestComplete().Before(last.Sub(2)))
+
+	reference = tortoiseFromSimState(t, state, opts...)
+	for _, id := range cheaters {
+		reference.OnMalfeasance(id)
+	}
+	reference.TallyVotes(ctx, last)
+	require.Equal(t, Mode(Full), reference.Mode())
+	requireSameTallies(t, reference.trtl, tortoise.trtl)
+	require.Equal(t, reference.LatestComplete(), tortoise.LatestComplete())
+}
+
 func TestStateManagement(t *testing.T) {
 	const (
 		size   = 10
@@ -2306,6 +2694,85 @@ func TestSwitchMode(t *testing.T) {
 	})
 }
 
+func TestUpdatesValidityChanges(t *testing.T) {
+	const size = 4
+
+	ctx := context.Background()
+	cfg := defaultTestConfig()
+	cfg.LayerSize = size
+	cfg.Zdist = 2
+	cfg.Hdist = 2
+
+	s := sim.New(
+		sim.WithLayerSize(cfg.LayerSize),
+	)
+	s.Setup(
+		sim.WithSetupMinerRange(size, size),
+	)
+	tortoise := tortoiseFromSimState(t,
+		s.GetState(0), WithConfig(cfg), WithLogger(logtest.New(t)),
+	)
+	// hare terminated empty, therefore honest ballots vote against the block
+	target := s.Next(sim.WithEmptyHareOutput(), sim.WithNumBlocks(1))
+	tortoise.TallyVotes(ctx, target)
+	last := target
+	for i := 0; i < int(cfg.Hdist)+1; i++ {
+		last = s.Next(sim.WithNumBlocks(1), sim.WithVoteGenerator(tortoiseVoting(tortoise)))
+		tortoise.TallyVotes(ctx, last)
+	}
+	require.Equal(t, target.Add(3), last)
+
+	var invalid *result.Block
+	for _, layer := range tortoise.Updates() {
+		if layer.Layer != target {
+			continue
+		}
+		require.True(t, layer.Verified)
+		require.Len(t, layer.Blocks, 1)
+		invalid = &layer.Blocks[0]
+	}
+	require.NotNil(t, invalid)
+	require.True(t, invalid.Invalid)
+	require.True(t, invalid.Changed)
+
+	templates, err := ballots.Layer(s.GetState(0).DB, target.Add(1))
+	require.NoError(t, err)
+	require.NotEmpty(t, templates)
+	template := templates[0]
+	template.Votes.Support = append(template.Votes.Support, invalid.Header)
+	template.Votes.Against = nil
+
+	// add an atx to increase optimistic threshold in verifying tortoise to trigger a switch
+	header := &types.ActivationTxHeader{ID: types.ATXID{1}, EffectiveNumUnits: 1, TickCount: 200}
+	header.PublishEpoch = types.EpochID(1)
+	tortoise.OnAtx(header.ToData())
+	// ballots that support the block outweigh ballots that voted against it
+	for i := 1; i <= 40; i++ {
+		ballot := types.NewExistingBallot(types.BallotID{byte(i)}, types.EmptyEdSignature, types.EmptyNodeID, template.Layer)
+		ballot.InnerBallot = template.InnerBallot
+		ballot.Votes = template.Votes
+		ballot.EligibilityProofs = template.EligibilityProofs
+		ballot.ActiveSet = template.ActiveSet
+		tortoise.OnBallot(ballot.ToTortoiseData())
+	}
+	tortoise.TallyVotes(ctx, last)
+
+	var changed []result.Block
+	for _, layer := range tortoise.Updates() {
+		for _, block := range layer.Blocks {
+			if block.Changed {
+				changed = append(changed, block)
+			}
+		}
+	}
+	require.Len(t, changed, 1)
+	require.Equal(t, invalid.Header, changed[0].Header)
+	require.True(t, changed[0].Valid)
+	require.False(t, changed[0].Invalid)
+
+	require.Empty(t, tortoise.Updates())
+}
+
 func TestOnBallotComputeOpinion(t *testing.T) {
 	const size = 4
 
@@ -2816,6 +3283,131 @@ func TestEncodeVotes(t *testing.T) {
 	})
 }
 
+func TestEncodeVotesMaxExceptions(t *testing.T) {
+	ctx := context.Background()
+	const (
+		size      = 10
+		divergent = 4
+	)
+	// local hare output differs from the hare output of the network in the last layers,
+	// the highest good ballot is from the first divergent layer, votes are encoded with support
+	// for every divergent layer. other ballots need more exceptions, 2 for every divergent layer
+	// before the ballot and 1 for every layer after
+	setup := func(t *testing.T, max int) (*Tortoise, types.LayerID) {
+		s := sim.New(sim.WithLayerSize(size))
+		s.Setup()
+		cfg := defaultTestConfig()
+		cfg.LayerSize = size
+		cfg.MaxExceptions = max
+		trtl := tortoiseFromSimState(t, s.GetState(0), WithConfig(cfg), WithLogger(logtest.New(t)))
+		var last types.LayerID
+		for i := 0; i < 10; i++ {
+			last = s.Next(sim.WithNumBlocks(2))
+			trtl.TallyVotes(ctx, last)
+		}
+		for lid := last.Sub(divergent - 1); lid <= last; lid++ {
+			hare, err := certificates.GetHareOutput(s.GetState(0).DB, lid)
+			require.NoError(t, err)
+			blks, err := blocks.Layer(s.GetState(0).DB, lid)
+			require.NoError(t, err)
+			require.Len(t, blks, 2)
+			for _, block := range blks {
+				if block.ID() != hare {
+					trtl.OnHareOutput(lid, block.ID())
+				}
+			}
+		}
+		return trtl.Tortoise, last
+	}
+	encode := func(t *testing.T, max int) (*types.Opinion, types.LayerID) {
+		trtl, last := setup(t, max)
+		opinion, err := trtl.EncodeVotes(ctx, EncodeVotesWithCurrent(last.Add(1)))
+		require.NoError(t, err)
+		trtl.mu.Lock()
+		defer trtl.mu.Unlock()
+		return opinion, trtl.trtl.ballotRefs[opinion.Base].layer
+	}
+
+	unlimited, base := encode(t, 1000)
+	require.Len(t, unlimited.Support, divergent)
+	require.Empty(t, unlimited.Against)
+	first := types.GetEffectiveGenesis().Add(10 - divergent + 1)
+	require.Equal(t, first, base)
+
+	t.Run("fits", func(t *testing.T) {
+		capped, base := encode(t, divergent)
+		require.Len(t, capped.Support, divergent)
+		require.Empty(t, capped.Abstain)
+		require.Equal(t, first, base)
+		require.Equal(t, unlimited.Hash, capped.Hash)
+	})
+	t.Run("abstain on oldest", func(t *testing.T) {
+		capped, base := encode(t, divergent-2)
+		require.Len(t, capped.Support, divergent-2)
+		require.Empty(t, capped.Against)
+		require.Equal(t, first, base)
+		require.Equal(t, []types.LayerID{first, first.Add(1)}, capped.Abstain)
+		require.NotEqual(t, unlimited.Hash, capped.Hash)
+	})
+	t.Run("lowered by option", func(t *testing.T) {
+		trtl, last := setup(t, 1000)
+		capped, err := trtl.EncodeVotes(ctx, EncodeVotesWithCurrent(last.Add(1)), EncodeVotesWithMaxExceptions(divergent-2))
+		require.NoError(t, err)
+		require.Len(t, capped.Support, divergent-2)
+		require.Empty(t, capped.Against)
+		require.Equal(t, []types.LayerID{first, first.Add(1)}, capped.Abstain)
+
+		// option can't raise the configured limit
+		trtl, last = setup(t, divergent-2)
+		capped, err = trtl.EncodeVotes(ctx, EncodeVotesWithCurrent(last.Add(1)), EncodeVotesWithMaxExceptions(1000))
+		require.NoError(t, err)
+		require.Len(t, capped.Support, divergent-2)
+	})
+	t.Run("abstain", func(t *testing.T) {
+		votes := types.Votes{
+			Support: []types.Vote{{ID: types.BlockID{1}, LayerID: 3}, {ID: types.BlockID{2}, LayerID: 1}},
+			Against: []types.Vote{{ID: types.BlockID{3}, LayerID: 1}, {ID: types.BlockID{4}, LayerID: 2}},
+			Abstain: []types.LayerID{4},
+		}
+		abstainOnOldest(&votes, 2)
+		require.Equal(t, []types.Vote{{ID: types.BlockID{1}, LayerID: 3}}, votes.Support)
+		require.Equal(t, []types.Vote{{ID: types.BlockID{4}, LayerID: 2}}, votes.Against)
+		require.Equal(t, []types.LayerID{1, 4}, votes.Abstain)
+	})
+}
+
+func TestEncodeVotesWithTrace(t *testing.T) {
+	ctx := context.Background()
+	tortoise := defaultAlgorithm(t)
+
+	block := types.Block{}
+	block.LayerIndex = types.GetEffectiveGenesis().Add(1)
+	block.Initialize()
+	tortoise.OnBlock(block.ToVote())
+	tortoise.OnHareOutput(block.LayerIndex, block.ID())
+
+	current := block.LayerIndex.Add(2)
+	tortoise.TallyVotes(ctx, current)
+	opinion, trace, err := tortoise.EncodeVotesWithTrace(ctx, current)
+	require.NoError(t, err)
+	expected, err := tortoise.EncodeVotes(ctx, EncodeVotesWithCurrent(current))
+	require.NoError(t, err)
+	require.Equal(t, expected, opinion)
+
+	require.Equal(t, current, trace.Layer)
+	require.Len(t, trace.Candidates, 1)
+	require.True(t, trace.Candidates[0].Selected)
+	require.Len(t, trace.Blocks, 1)
+	require.Equal(t, block.ID(), trace.Blocks[0].Block)
+	require.Len(t, trace.Exceptions, 2)
+
+	buf, err := json.Marshal(trace)
+	require.NoError(t, err)
+	var decoded VotesTrace
+	require.NoError(t, json.Unmarshal(buf, &decoded))
+	require.Equal(t, trace, &decoded)
+}
+
 func TestBaseBallotBeforeCurrentLayer(t *testing.T) {
 	t.Run("encode", func(t *testing.T) {
 		ctx := context.Background()
@@ -3133,7 +3725,7 @@ func TestSwitch(t *testing.T) {
 	}
 	verified := new(results)
 	for lid := 2; lid <= s.hdist; lid++ {
-		verified = verified.verified(lid-1).block(strconv.Itoa(lid-1), 0, valid|local)
+		verified = verified.verified(lid-1).block(strconv.Itoa(lid-1), 0, valid|local|changed)
 	}
 	verified.next(s.hdist)
 	s.tallyWait(s.hdist - 1)
@@ -3179,7 +3771,9 @@ func TestOnMalfeasance(t *testing.T) {
 		s := newSession(t).
 			withHdist(1).
 			withZdist(1)
-		const smeshers = 3
+		// weight of the malfeasant smesher is removed from counted ballots,
+		// remaining smeshers must have enough weight to cross threshold
+		const smeshers = 4
 		var (
 			elig      = s.layerSize / smeshers
 			activeset []*atxAction
@@ -3211,7 +3805,7 @@ func TestOnMalfeasance(t *testing.T) {
 					new(bopt).eligibilities(elig))
 		}
 		s.tally(2)
-		s.updates(t, new(results).verified(0).verified(1).block("a", 0, valid|local).next(2))
+		s.updates(t, new(results).verified(0).verified(1).block("a", 0, valid|local|changed).next(2))
 		s.runInorder()
 	})
 }
@@ -137,6 +137,8 @@ type ConfigTrace struct {
 	WindowSize               uint32 `json:"window"`
 	MaxExceptions            uint32 `json:"exceptions"`
 	BadBeaconVoteDelayLayers uint32 `json:"delay"`
+	CoinflipTheta            uint32 `json:"coinflip-theta,omitempty"`
+	HealingDistance          uint32 `json:"healing-distance,omitempty"`
 	LayerSize                uint32 `json:"layer-size"`
 	EpochSize                uint32 `json:"epoch-size"` // this field is not set in the original config
 	EffectiveGenesis         uint32 `json:"effective-genesis"`
@@ -159,6 +161,8 @@ func (c *ConfigTrace) Run(r *traceRunner) error {
 		WindowSize:               c.WindowSize,
 		MaxExceptions:            int(c.MaxExceptions),
 		BadBeaconVoteDelayLayers: c.BadBeaconVoteDelayLayers,
+		CoinflipTheta:            c.CoinflipTheta,
+		HealingDistance:          c.HealingDistance,
 		LayerSize:                c.LayerSize,
 	}))...)
 	if err != nil {
@@ -302,6 +306,8 @@ type EncodeVotesTrace struct {
 	Layer   types.LayerID  `json:"lid"`
 	Opinion *types.Opinion `json:"opinion"`
 	Error   string         `json:"e"`
+	// Votes explains how opinion was encoded, it is not used when trace is replayed.
+	Votes *VotesTrace `json:"votes,omitempty"`
 }
 
 func (e *EncodeVotesTrace) Type() eventType {
@@ -2,6 +2,7 @@ package tortoise
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"os"
 	"path/filepath"
@@ -9,6 +10,9 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
 
 	"github.com/spacemeshos/go-spacemesh/common/types"
 	"github.com/spacemeshos/go-spacemesh/log/logtest"
@@ -60,6 +64,61 @@ func TestTracer(t *testing.T) {
 	})
 }
 
+func TestEncodeVotesTrace(t *testing.T) {
+	ctx := context.Background()
+	tortoise := defaultAlgorithm(t)
+	core, logs := observer.New(zapcore.InfoLevel)
+	tortoise.tracer = &tracer{logger: zap.New(core)}
+
+	block := types.Block{}
+	block.LayerIndex = types.GetEffectiveGenesis().Add(1)
+	block.Initialize()
+	tortoise.OnBlock(block.ToVote())
+	tortoise.OnHareOutput(block.LayerIndex, block.ID())
+
+	current := block.LayerIndex.Add(2)
+	tortoise.TallyVotes(ctx, current)
+	opinion, err := tortoise.EncodeVotes(ctx, EncodeVotesWithCurrent(current))
+	require.NoError(t, err)
+
+	var event *EncodeVotesTrace
+	for _, entry := range logs.All() {
+		if entry.ContextMap()["t"] != traceEncode {
+			continue
+		}
+		for _, field := range entry.Context {
+			if field.Key == "o" {
+				event = &EncodeVotesTrace{}
+				require.NoError(t, json.Unmarshal(*field.Interface.(*json.RawMessage), event))
+			}
+		}
+	}
+	require.NotNil(t, event)
+	require.Equal(t, opinion, event.Opinion)
+	trace := event.Votes
+	require.NotNil(t, trace)
+
+	require.Equal(t, current, trace.Layer)
+	require.Len(t, trace.Candidates, 1)
+	require.Equal(t, types.GetEffectiveGenesis(), trace.Candidates[0].Layer)
+	require.True(t, trace.Candidates[0].Selected)
+	require.Empty(t, trace.Candidates[0].Error)
+
+	require.Len(t, trace.Blocks, 1)
+	require.Equal(t, block.ID(), trace.Blocks[0].Block)
+	require.Equal(t, support.String(), trace.Blocks[0].Hare)
+	require.Equal(t, support.String(), trace.Blocks[0].Vote)
+	require.Equal(t, reasonHareOutput.String(), trace.Blocks[0].Reason)
+
+	require.Len(t, trace.Exceptions, 2)
+	require.Equal(t, block.ID(), *trace.Exceptions[0].Block)
+	require.Equal(t, support.String(), trace.Exceptions[0].Vote)
+	require.Nil(t, trace.Exceptions[1].Block)
+	require.Equal(t, current.Sub(1), trace.Exceptions[1].Layer)
+	require.Equal(t, abstain.String(), trace.Exceptions[1].Vote)
+	require.Equal(t, reasonHareNotTerminated, trace.Exceptions[1].Reason)
+}
+
 func TestData(t *testing.T) {
 	t.Parallel()
 	data, err := filepath.Abs("./data")
@@ -7,6 +7,7 @@ import (
 	"go.uber.org/zap/zapcore"
 
 	"github.com/spacemeshos/go-spacemesh/common/types"
+	tmetrics "github.com/spacemeshos/go-spacemesh/tortoise/metrics"
 )
 
 const (
@@ -53,7 +54,7 @@ func maxLayer(i, j types.LayerID) types.LayerID {
 	return j
 }
 
-func verifyLayer(logger *zap.Logger, blocks []*blockInfo, getDecision func(*blockInfo) sign) (bool, bool) {
+func verifyLayer(logger *zap.Logger, blocks []*blockInfo, getDecision func(*blockInfo) (sign, DecisionSource)) (bool, bool) {
 	// order blocks by height in ascending order
 	// if there is a support before any abstain
 	// and a previous height is lower than the current one
@@ -65,10 +66,11 @@ func verifyLayer(logger *zap.Logger, blocks []*blockInfo, getDecision func(*bloc
 	})
 	var (
 		decisions = make([]sign, 0, len(blocks))
+		sources   = make([]DecisionSource, 0, len(blocks))
 		supported *blockInfo
 	)
 	for i, block := range blocks {
-		decision := getDecision(block)
+		decision, source := getDecision(block)
 		logger.Debug("decision for a block",
 			zap.Int("ith", i),
 			zap.Stringer("block", block.id),
@@ -89,11 +91,18 @@ func verifyLayer(logger *zap.Logger, blocks []*blockInfo, getDecision func(*bloc
 			supported = block
 		}
 		decisions = append(decisions, decision)
+		sources = append(sources, source)
 	}
 	changes := false
 	for i, decision := range decisions {
 		if blocks[i].validity != decision {
 			changes = true
+			if blocks[i].validity != abstain {
+				tmetrics.ValidityFlips.Inc()
+			}
+			// source is updated only if decision changed, so that it reports
+			// which part of the tortoise decided validity first
+			blocks[i].source = sources[i]
 		}
 		blocks[i].validity = decision
 	}
@@ -7,6 +7,7 @@ import (
 
 	"github.com/spacemeshos/go-spacemesh/common/types"
 	"github.com/spacemeshos/go-spacemesh/log"
+	tmetrics "github.com/spacemeshos/go-spacemesh/tortoise/metrics"
 )
 
 func newVerifying(config Config, state *state) *verifying {
@@ -27,6 +28,7 @@ type verifying struct {
 // reset all weight that can vote on a voted layer.
 func (v *verifying) resetWeights(voted types.LayerID) {
 	vlayer := v.layer(voted)
+	v.markDirty(voted)
 	v.totalGoodWeight = vlayer.verifying.goodUncounted
 	for lid := voted.Add(1); !lid.After(v.processed); lid = lid.Add(1) {
 		layer := v.layer(lid)
@@ -52,10 +54,23 @@ func (v *verifying) countBallot(logger *zap.Logger, ballot *ballotInfo) {
 		zap.Uint64("ballot height", ballot.reference.height),
 		zap.Bool("counted", counted),
 	)
-	if !counted {
+	// weight of malicious ballot is zero, there is nothing to count
+	ballot.goodCounted = counted && !ballot.malicious
+	if !ballot.goodCounted {
+		switch {
+		case ballot.conditions.badBeacon:
+			tmetrics.RejectedBadBeacon.Inc()
+		case prev.opinion != ballot.opinion():
+			tmetrics.RejectedOpinion.Inc()
+		case prev.verifying.referenceHeight > ballot.reference.height:
+			tmetrics.RejectedHeight.Inc()
+		default:
+			tmetrics.RejectedMalicious.Inc()
+		}
 		return
 	}
 
+	v.markDirty(ballot.layer)
 	for lid := ballot.layer; !lid.After(v.processed); lid = lid.Add(1) {
 		layer := v.layer(lid)
 		layer.verifying.goodUncounted = layer.verifying.goodUncounted.Add(ballot.weight)
@@ -64,12 +79,46 @@ func (v *verifying) countBallot(logger *zap.Logger, ballot *ballotInfo) {
 	vcountBallotDuration.Observe(float64(time.Since(start).Nanoseconds()))
 }
 
+// uncountBallot removes weight of the ballot that was counted as good.
+func (v *verifying) uncountBallot(ballot *ballotInfo) {
+	if !ballot.goodCounted {
+		return
+	}
+	ballot.goodCounted = false
+	v.markDirty(ballot.layer)
+	for lid := ballot.layer; !lid.After(v.processed); lid = lid.Add(1) {
+		layer := v.layer(lid)
+		layer.verifying.goodUncounted = layer.verifying.goodUncounted.Sub(ballot.weight)
+	}
+	v.totalGoodWeight = v.totalGoodWeight.Sub(ballot.weight)
+}
+
 func (v *verifying) countVotes(logger *zap.Logger, ballots []*ballotInfo) {
 	for _, ballot := range ballots {
 		v.countBallot(logger, ballot)
 	}
 }
 
+// countLean counts lean ballots from the layer the same way as countBallot counts every ballot.
+func (v *verifying) countLean(layer *layerInfo) {
+	prev := v.layer(layer.lid.Sub(1))
+	for i := range layer.leanGroups {
+		group := &layer.leanGroups[i]
+		group.counted = !(group.badBeacon ||
+			prev.opinion != group.opinion ||
+			prev.verifying.referenceHeight > group.height)
+		if !group.counted {
+			continue
+		}
+		v.markDirty(layer.lid)
+		for lid := layer.lid; !lid.After(v.processed); lid = lid.Add(1) {
+			layer := v.layer(lid)
+			layer.verifying.goodUncounted = layer.verifying.goodUncounted.Add(group.weight)
+		}
+		v.totalGoodWeight = v.totalGoodWeight.Add(group.weight)
+	}
+}
+
 func (v *verifying) verify(logger *zap.Logger, lid types.LayerID) (bool, bool) {
 	layer := v.layer(lid)
 	if !layer.hareTerminated {
@@ -111,12 +160,15 @@ func (v *verifying) verify(logger *zap.Logger, lid types.LayerID) (bool, bool) {
 	rst, changes := verifyLayer(
 		logger,
 		layer.blocks,
-		func(block *blockInfo) sign {
+		func(block *blockInfo) (sign, DecisionSource) {
 			if block.height > layer.verifying.referenceHeight {
-				return neutral
+				return neutral, DecidedByVerifying
+			}
+			decision, reason := getLocalVote(v.Config, v.state.verified, v.state.last, block)
+			if reason == reasonHareOutput {
+				return decision, DecidedByHare
 			}
-			decision, _ := getLocalVote(v.Config, v.state.verified, v.state.last, block)
-			return decision
+			return decision, DecidedByVerifying
 		},
 	)
 	if changes {
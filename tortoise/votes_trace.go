@@ -0,0 +1,110 @@
+package tortoise
+
+import (
+	"github.com/spacemeshos/go-spacemesh/common/types"
+)
+
+// VotesTrace explains how votes were encoded. It is returned by EncodeVotesWithTrace and recorded
+// with EncodeVotes events when tracing is enabled.
+type VotesTrace struct {
+	Layer types.LayerID `json:"lid"`
+	// Candidates for the base ballot in the order they were considered.
+	// The last candidate is the selected one, unless encoding failed.
+	Candidates []CandidateTrace `json:"candidates"`
+	// Blocks with local opinion that were compared with the opinion of the last candidate.
+	Blocks []BlockOpinionTrace `json:"blocks,omitempty"`
+	// Exceptions that were added on top of the opinion of the last candidate.
+	Exceptions []ExceptionTrace `json:"exceptions,omitempty"`
+}
+
+// CandidateTrace is a ballot considered as a base ballot.
+type CandidateTrace struct {
+	Ballot    types.BallotID `json:"id"`
+	Layer     types.LayerID  `json:"lid"`
+	Malicious bool           `json:"malicious,omitempty"`
+	BadBeacon bool           `json:"bad_beacon,omitempty"`
+	Selected  bool           `json:"selected,omitempty"`
+	// Verdict and Reason classify the ballot as a candidate for the base ballot.
+	Verdict BaseVerdict `json:"verdict,omitempty"`
+	Reason  string      `json:"reason,omitempty"`
+	// Error explains why candidate can't be used as a base ballot.
+	Error string `json:"e,omitempty"`
+}
+
+// BlockOpinionTrace is a local opinion about the block.
+type BlockOpinionTrace struct {
+	Block    types.BlockID `json:"id"`
+	Layer    types.LayerID `json:"lid"`
+	Height   uint64        `json:"height"`
+	Data     bool          `json:"data"`
+	Hare     string        `json:"hare"`
+	Validity string        `json:"validity"`
+	Margin   string        `json:"margin"`
+	Vote     string        `json:"vote"`
+	Reason   string        `json:"reason"`
+}
+
+// ExceptionTrace is an explicit vote that differs from the opinion of the base ballot.
+type ExceptionTrace struct {
+	Layer types.LayerID `json:"lid"`
+	// Block is empty for abstain votes, which apply to the whole layer.
+	Block *types.BlockID `json:"block,omitempty"`
+	Vote  string         `json:"vote"`
+	// BaseVote is a vote of the base ballot, if base ballot voted on the layer.
+	BaseVote string `json:"base_vote,omitempty"`
+	Reason   string `json:"reason"`
+}
+
+// reasonHareNotTerminated is a reason for abstain votes, they are encoded without consulting local opinion.
+const reasonHareNotTerminated = "hare not terminated"
+
+// methods are safe to call on nil trace, which is used when trace is not requested.
+
+func (t *VotesTrace) candidate(base *ballotInfo, verdict BaseVerdict, reason string) *CandidateTrace {
+	if t == nil {
+		return nil
+	}
+	t.Candidates = append(t.Candidates, CandidateTrace{
+		Ballot:    base.id,
+		Layer:     base.layer,
+		Malicious: base.malicious,
+		BadBeacon: base.conditions.badBeacon,
+		Verdict:   verdict,
+		Reason:    reason,
+	})
+	t.Blocks = t.Blocks[:0]
+	t.Exceptions = t.Exceptions[:0]
+	return &t.Candidates[len(t.Candidates)-1]
+}
+
+func (t *VotesTrace) block(block *blockInfo, vote sign, reason voteReason) {
+	if t == nil {
+		return
+	}
+	t.Blocks = append(t.Blocks, BlockOpinionTrace{
+		Block:    block.id,
+		Layer:    block.layer,
+		Height:   block.height,
+		Data:     block.data,
+		Hare:     block.hare.String(),
+		Validity: block.validity.String(),
+		Margin:   block.margin.String(),
+		Vote:     vote.String(),
+		Reason:   reason.String(),
+	})
+}
+
+func (t *VotesTrace) exception(lid types.LayerID, block *blockInfo, vote sign, base *sign, reason string) {
+	if t == nil {
+		return
+	}
+	exception := ExceptionTrace{Layer: lid, Vote: vote.String(), Reason: reason}
+	if block != nil {
+		id := block.id
+		exception.Block = &id
+	}
+	if base != nil {
+		exception.BaseVote = base.String()
+	}
+	t.Exceptions = append(t.Exceptions, exception)
+}
@@ -14,18 +14,31 @@ import (
 	"github.com/spacemeshos/go-spacemesh/log"
 	"github.com/spacemeshos/go-spacemesh/sql"
 	"github.com/spacemeshos/go-spacemesh/sql/layers"
+	dbmempool "github.com/spacemeshos/go-spacemesh/sql/mempool"
 	"github.com/spacemeshos/go-spacemesh/sql/transactions"
 )
 
 const (
 	maxTXsPerAcct  = 100
 	maxTXsPerNonce = 100
+	// maxParkedPerAcct is the max number of txs after a nonce gap that are kept in the cache.
+	// txs with the highest nonces are evicted first, they stay in the db and are reconsidered
+	// after a layer is applied.
+	maxParkedPerAcct = 20
+	// appliedLocalLayers is the number of layers the origin of the applied local txs is kept for,
+	// so that it is known if the layer is reverted by a reorg.
+	appliedLocalLayers = 1000
 )
 
 var (
 	errBadNonce            = errors.New("bad nonce")
 	errInsufficientBalance = errors.New("insufficient balance")
 	errTooManyNonce        = errors.New("account has too many nonce pending")
+	errTooManyParked       = errors.New("account has too many parked txs")
+	errFeeBumpTooLow       = errors.New("fee is too low to replace tx")
+	errReplacePacked       = errors.New("tx to replace is packed in a proposal")
+	errMempoolFull         = errors.New("mempool is full")
+	errExpired             = errors.New("tx expired")
 	errLayerNotInOrder     = errors.New("layers not applied in order")
 )
 
@@ -71,8 +84,36 @@ type accountCache struct {
 	// TODO: evict accounts that only has DB-only txs
 	// https://github.com/spacemeshos/go-spacemesh/issues/3668
 	moreInDB bool
+	// noParking disables parking of txs after a nonce gap.
+	noParking bool
 
-	cachedTXs map[types.TransactionID]*NanoTX // shared with the cache instance
+	cachedTXs map[types.TransactionID]*NanoTX  // shared with the cache instance
+	usage     *poolUsage                       // shared with the cache instance
+	local     map[types.TransactionID]struct{} // shared with the cache instance
+}
+
+// poolUsage is the total size of the txs in the cache.
+type poolUsage struct {
+	bytes uint64
+	// parked is the number of parked txs by account, totalParked is their sum.
+	parked      map[types.Address]int
+	totalParked int
+}
+
+func (ac *accountCache) cacheTX(ntx *NanoTX) {
+	ac.uncacheTX(ntx.ID)
+	if _, ok := ac.local[ntx.ID]; ok {
+		ntx.Local = true
+	}
+	ac.cachedTXs[ntx.ID] = ntx
+	ac.usage.bytes += ntx.Size
+}
+
+func (ac *accountCache) uncacheTX(tid types.TransactionID) {
+	if ntx, ok := ac.cachedTXs[tid]; ok {
+		delete(ac.cachedTXs, tid)
+		ac.usage.bytes -= ntx.Size
+	}
 }
 
 func (ac *accountCache) nextNonce() uint64 {
@@ -82,6 +123,87 @@ func (ac *accountCache) nextNonce() uint64 {
 	return ac.txsByNonce.Back().Value.(*candidate).nonce() + 1
 }
 
+// find returns the candidate with the nonce.
+func (ac *accountCache) find(nonce uint64) *candidate {
+	for e := ac.txsByNonce.Back(); e != nil; e = e.Prev() {
+		cand := e.Value.(*candidate)
+		if cand.nonce() == nonce {
+			return cand
+		}
+		if cand.nonce() < nonce {
+			break
+		}
+	}
+	return nil
+}
+
+// readyNonce returns the first nonce missing in the cache. txs with lower nonces are ready,
+// txs with higher nonces are parked until the missing nonce arrives.
+func (ac *accountCache) readyNonce() uint64 {
+	if ac.noParking {
+		return ac.nextNonce()
+	}
+	expected := ac.startNonce
+	for e := ac.txsByNonce.Front(); e != nil; e = e.Next() {
+		if e.Value.(*candidate).nonce() != expected {
+			break
+		}
+		expected++
+	}
+	return expected
+}
+
+// parked returns the number of txs after the first nonce gap.
+func (ac *accountCache) parked() int {
+	ready := ac.readyNonce()
+	n := 0
+	for e := ac.txsByNonce.Back(); e != nil && e.Value.(*candidate).nonce() > ready; e = e.Prev() {
+		n++
+	}
+	return n
+}
+
+// evictParked removes parked txs with the highest nonces until the account is within maxParkedPerAcct.
+// it returns true if the tx was evicted.
+func (ac *accountCache) evictParked(logger log.Log, tid types.TransactionID) bool {
+	evicted := false
+	for n := ac.parked(); n > maxParkedPerAcct; n-- {
+		removed := ac.txsByNonce.Remove(ac.txsByNonce.Back()).(*candidate)
+		ac.uncacheTX(removed.id())
+		ac.moreInDB = true
+		evicted = evicted || removed.id() == tid
+		logger.With().Debug("evicted parked tx",
+			removed.id(),
+			log.Uint64("nonce", removed.nonce()),
+			log.Int("parked", n-1))
+	}
+	return evicted
+}
+
+// dropExpired removes the txs that can't be applied in the layer and releases the balance they reserved.
+func (ac *accountCache) dropExpired(lid types.LayerID) []*NanoTX {
+	var dropped []*NanoTX
+	for e := ac.txsByNonce.Front(); e != nil; {
+		next := e.Next()
+		if cand := e.Value.(*candidate); cand.best.Expired(lid) {
+			ac.txsByNonce.Remove(e)
+			ac.uncacheTX(cand.id())
+			dropped = append(dropped, cand.best)
+		}
+		e = next
+	}
+	if len(dropped) == 0 {
+		return nil
+	}
+	balance := ac.startBalance
+	for e := ac.txsByNonce.Front(); e != nil; e = e.Next() {
+		cand := e.Value.(*candidate)
+		balance -= cand.maxSpending()
+		cand.postBalance = balance
+	}
+	return dropped
+}
+
 func (ac *accountCache) availBalance() uint64 {
 	if ac.txsByNonce.Len() == 0 {
 		return ac.startBalance
@@ -90,12 +212,11 @@ func (ac *accountCache) availBalance() uint64 {
 }
 
 func (ac *accountCache) precheck(logger log.Log, ntx *NanoTX) (*list.Element, *candidate, error) {
-	if ac.txsByNonce.Len() >= maxTXsPerAcct {
-		ac.moreInDB = true
-		return nil, nil, errTooManyNonce
-	}
 	balance := ac.startBalance
-	var prev *list.Element
+	var (
+		prev     *list.Element
+		existing bool
+	)
 	for e := ac.txsByNonce.Back(); e != nil; e = e.Prev() {
 		cand := e.Value.(*candidate)
 		if cand.nonce() > ntx.Nonce {
@@ -103,12 +224,18 @@ func (ac *accountCache) precheck(logger log.Log, ntx *NanoTX) (*list.Element, *c
 		}
 		if cand.nonce() == ntx.Nonce {
 			balance = cand.postBalance + cand.maxSpending()
+			existing = true
 		} else {
 			balance = cand.postBalance
 		}
 		prev = e
 		break
 	}
+	// replacement of the existing nonce doesn't grow the account, it is not subject to the limit
+	if !existing && ac.txsByNonce.Len() >= maxTXsPerAcct {
+		ac.moreInDB = true
+		return nil, nil, errTooManyNonce
+	}
 	if balance < ntx.MaxSpending() {
 		ac.moreInDB = true
 		logger.With().Debug("insufficient balance",
@@ -133,6 +260,7 @@ func (ac *accountCache) accept(logger log.Log, ntx *NanoTX, blockSeed []byte) er
 	if err != nil {
 		return err
 	}
+	ready := ac.readyNonce()
 
 	if prev == nil { // insert at the first position
 		added = ac.txsByNonce.PushFront(cand)
@@ -144,11 +272,11 @@ func (ac *accountCache) accept(logger log.Log, ntx *NanoTX, blockSeed []byte) er
 		}
 		added = prev
 		replaced = prevCand.best
-		delete(ac.cachedTXs, prevCand.best.ID)
+		ac.uncacheTX(prevCand.best.ID)
 		prevCand.best = ntx
 		prevCand.postBalance = cand.postBalance
 	}
-	ac.cachedTXs[ntx.ID] = ntx
+	ac.cacheTX(ntx)
 
 	if replaced != nil {
 		logger.With().Debug("better transaction replaced for nonce",
@@ -186,12 +314,28 @@ func (ac *accountCache) accept(logger log.Log, ntx *NanoTX, blockSeed []byte) er
 		rm := next
 		next = next.Next()
 		removed := ac.txsByNonce.Remove(rm).(*candidate)
-		delete(ac.cachedTXs, removed.id())
+		ac.uncacheTX(removed.id())
 		logger.With().Debug("tx made infeasible by new/better transaction",
 			removed.id(),
 			log.Uint64("nonce", removed.nonce()),
 			log.Uint64("max_spending", ntx.MaxSpending()))
 	}
+
+	if replaced != nil {
+		return nil
+	}
+	if ntx.Nonce == ready {
+		// the tx filled the gap, parked txs up to the next gap are ready now
+		if promoted := ac.readyNonce() - ready - 1; promoted > 0 {
+			mempoolTxCount.WithLabelValues(promotedParked).Add(float64(promoted))
+			logger.With().Debug("promoted parked txs",
+				ntx.ID,
+				log.Uint64("nonce", ntx.Nonce),
+				log.Uint64("promoted", promoted))
+		}
+	} else if ac.evictParked(logger, ntx.ID) {
+		return errTooManyParked
+	}
 	return nil
 }
 
@@ -250,7 +394,7 @@ func (ac *accountCache) addBatch(logger log.Log, nonce2TXs map[uint64][]*NanoTX,
 			log.Uint64("fee", best.Fee()))
 
 		if err := ac.accept(logger, best, blockSeed); err != nil {
-			if errors.Is(err, errTooManyNonce) {
+			if errors.Is(err, errTooManyNonce) || errors.Is(err, errTooManyParked) {
 				break
 			}
 			continue
@@ -279,19 +423,48 @@ func findBest(ntxs []*NanoTX, balance uint64, blockSeed []byte) *NanoTX {
 	return best
 }
 
+// checkReplace returns an error if the tx can't replace the tx with the same nonce.
+// the fee of the replacement must be higher by at least feeBump percent, and the replaced tx
+// must not be packed in a proposal/block yet, as it would be included twice locally.
+func checkReplace(old *NanoTX, tx *types.Transaction, feeBump uint64) error {
+	if old.Layer != 0 {
+		return fmt.Errorf("%w: %s in layer %d", errReplacePacked, old.ID, old.Layer)
+	}
+	required := old.Fee() + old.Fee()/100*feeBump + old.Fee()%100*feeBump/100
+	if tx.Fee() <= old.Fee() || tx.Fee() < required {
+		return fmt.Errorf("%w: fee %d, required %d to replace %s", errFeeBumpTooLow, tx.Fee(), required, old.ID)
+	}
+	return nil
+}
+
 // adding a tx to the account cache. possible outcomes:
 //   - nonce is smaller than the next nonce in state: reject from cache
 //   - too many txs present: reject from cache
 //   - nonce already exists in the cache:
-//     if it is better than the best candidate in that nonce group, swap
+//     if its fee is higher by at least feeBump percent, replace the tx with the same nonce
 //   - nonce not present: add to cache.
-func (ac *accountCache) add(logger log.Log, tx *types.Transaction, received time.Time) error {
+//
+// the replaced tx is returned if the tx replaced the tx with the same nonce.
+func (ac *accountCache) add(logger log.Log, tx *types.Transaction, received time.Time, feeBump uint64) (*NanoTX, error) {
 	if tx.Nonce < ac.startNonce {
 		logger.With().Debug("nonce too small",
 			tx.ID,
 			log.Uint64("next_nonce", ac.startNonce),
 			log.Uint64("tx_nonce", tx.Nonce))
-		return errBadNonce
+		return nil, errBadNonce
+	}
+	var replaced *NanoTX
+	if cand := ac.find(tx.Nonce); cand != nil {
+		if err := checkReplace(cand.best, tx, feeBump); err != nil {
+			logger.With().Debug("tx can't replace tx with the same nonce", tx.ID, log.Err(err))
+			if errors.Is(err, errReplacePacked) {
+				mempoolTxCount.WithLabelValues(replacePacked).Inc()
+			} else {
+				mempoolTxCount.WithLabelValues(feeBumpTooLow).Inc()
+			}
+			return nil, err
+		}
+		replaced = cand.best
 	}
 
 	ntx := NewNanoTX(&types.MeshTransaction{
@@ -307,11 +480,16 @@ func (ac *accountCache) add(logger log.Log, tx *types.Transaction, received time
 			mempoolTxCount.WithLabelValues(tooManyNonce).Inc()
 		} else if errors.Is(err, errInsufficientBalance) {
 			mempoolTxCount.WithLabelValues(balanceTooSmall).Inc()
+		} else if errors.Is(err, errTooManyParked) {
+			mempoolTxCount.WithLabelValues(tooManyParked).Inc()
 		}
-		return err
+		return nil, err
 	}
 	mempoolTxCount.WithLabelValues(mempool).Inc()
-	return nil
+	if replaced != nil {
+		mempoolTxCount.WithLabelValues(replacedByFee).Inc()
+	}
+	return replaced, nil
 }
 
 func (ac *accountCache) addPendingFromNonce(logger log.Log, db *sql.Database, nonce uint64, applied types.LayerID) error {
@@ -327,7 +505,12 @@ func (ac *accountCache) addPendingFromNonce(logger log.Log, db *sql.Database, no
 	}
 
 	if applied != 0 {
+		unexpired := mtxs[:0]
 		for _, mtx := range mtxs {
+			if mtx.Expired(applied.Add(1)) {
+				continue
+			}
+			unexpired = append(unexpired, mtx)
 			if mtx.State == types.APPLIED {
 				continue
 			}
@@ -341,6 +524,11 @@ func (ac *accountCache) addPendingFromNonce(logger log.Log, db *sql.Database, no
 				logger.With().Debug("next layer found", mtx.ID, nextLayer)
 			}
 		}
+		if len(unexpired) == 0 {
+			ac.moreInDB = false
+			return nil
+		}
+		mtxs = unexpired
 	}
 
 	byPrincipal := groupTXsByPrincipal(logger, mtxs)
@@ -352,12 +540,21 @@ func (ac *accountCache) addPendingFromNonce(logger log.Log, db *sql.Database, no
 
 // find the first nonce without a layer.
 // a nonce with a valid layer indicates that it's already packed in a proposal/block.
-func (ac *accountCache) getMempool(logger log.Log) []*NanoTX {
+// txs from the ready nonce are parked in the cache and returned once the missing nonce arrives,
+// as they would fail execution.
+func (ac *accountCache) getMempool(logger log.Log, ready uint64) []*NanoTX {
 	bests := make([]*NanoTX, 0, maxTXsPerAcct)
 	offset := 0
 	found := false
 	for e := ac.txsByNonce.Front(); e != nil; e = e.Next() {
 		cand := e.Value.(*candidate)
+		if cand.nonce() >= ready {
+			logger.With().Debug("nonce gap, parking txs",
+				log.Uint64("expected", ready),
+				log.Uint64("nonce", cand.nonce()),
+				log.Int("parked", ac.txsByNonce.Len()-offset-len(bests)))
+			break
+		}
 		if !found && cand.layer() == 0 {
 			found = true
 		} else if found && cand.layer() != 0 {
@@ -394,7 +591,7 @@ func (ac *accountCache) resetAfterApply(logger log.Log, db *sql.Database, nextNo
 	logger = logger.WithFields(ac.addr)
 	logger.With().Debug("resetting to nonce", log.Uint64("nonce", nextNonce))
 	for e := ac.txsByNonce.Front(); e != nil; e = e.Next() {
-		delete(ac.cachedTXs, e.Value.(*candidate).id())
+		ac.uncacheTX(e.Value.(*candidate).id())
 	}
 	ac.txsByNonce = list.New()
 	ac.startNonce = nextNonce
@@ -408,22 +605,72 @@ func (ac *accountCache) shouldEvict() bool {
 
 type stateFunc func(types.Address) (uint64, uint64)
 
+// orphan is the tx applied in the layer reverted by a reorg.
+type orphan struct {
+	principal types.Address
+	layer     types.LayerID
+}
+
 type Cache struct {
 	logger log.Log
 	stateF stateFunc
+	// feeBump is the min fee increase in percent for a tx to replace the tx with the same nonce.
+	feeBump uint64
 
 	mu        sync.Mutex
 	pending   map[types.Address]*accountCache
 	cachedTXs map[types.TransactionID]*NanoTX // shared with accountCache instances
+	usage     *poolUsage                      // shared with accountCache instances
+	// local are the txs submitted via the api of this node. the origin is kept in memory only,
+	// it is lost when the node restarts.
+	local map[types.TransactionID]struct{} // shared with accountCache instances
+	// projector keeps the projections of the accounts in pending, they are read without the lock.
+	projector *projector
+	// appliedLocal are the local txs applied in the recent layers, by the layer they were applied in.
+	appliedLocal map[types.TransactionID]types.LayerID
+	// orphans are the txs applied in the layers reverted by a reorg. once the layer is applied again,
+	// the orphans that were not applied in it are re-admitted to the mempool.
+	orphans map[types.TransactionID]orphan
+	// rebroadcast are the local txs re-admitted after a reorg that need to be gossiped again.
+	rebroadcast []types.TransactionID
+	// applied is the last applied layer. txs are persisted in the mempool with this layer
+	// to bound the age of txs reloaded after restart.
+	applied types.LayerID
+	// noParking disables parking of txs after a nonce gap.
+	noParking bool
+	// maxTXs and maxBytes limit the number and the total size of txs in the cache. 0 is unlimited.
+	maxTXs   int
+	maxBytes uint64
+}
+
+// CacheOpt for configuring the Cache.
+type CacheOpt func(*Cache)
+
+// WithoutParking disables parking of txs after a nonce gap. It is meant for the caches
+// built from the txs of proposals, where the nonces in the state are not known.
+func WithoutParking() CacheOpt {
+	return func(c *Cache) {
+		c.noParking = true
+	}
 }
 
-func NewCache(s stateFunc, logger log.Log) *Cache {
-	return &Cache{
+func NewCache(s stateFunc, logger log.Log, opts ...CacheOpt) *Cache {
+	c := &Cache{
 		logger:    logger,
 		stateF:    s,
 		pending:   make(map[types.Address]*accountCache),
 		cachedTXs: make(map[types.TransactionID]*NanoTX),
+		usage:     &poolUsage{},
+		local:     make(map[types.TransactionID]struct{}),
+		projector: newProjector(),
+
+		appliedLocal: make(map[types.TransactionID]types.LayerID),
+		orphans:      make(map[types.TransactionID]orphan),
+	}
+	for _, opt := range opts {
+		opt(c)
 	}
+	return c
 }
 
 func groupTXsByPrincipal(logger log.Log, mtxs []*types.MeshTransaction) map[types.Address]map[uint64][]*NanoTX {
@@ -465,7 +712,11 @@ func (c *Cache) buildFromScratch(db *sql.Database) error {
 		if err != nil {
 			return fmt.Errorf("get pending addr=%s nonce=%d %w", addr.Address, addr.Nonce, err)
 		}
-		rst = append(rst, txs...)
+		for _, mtx := range txs {
+			if !mtx.Expired(applied.Add(1)) {
+				rst = append(rst, mtx)
+			}
+		}
 	}
 	for _, mtx := range rst {
 		if mtx.State == types.APPLIED {
@@ -477,8 +728,87 @@ func (c *Cache) buildFromScratch(db *sql.Database) error {
 		}
 		mtx.LayerID = nextLayer
 		mtx.BlockID = nextBlock
+		// txs from the reverted layers are pending again
+		if err := dbmempool.Add(db, &mtx.Transaction, applied); err != nil {
+			return err
+		}
+	}
+	c.mu.Lock()
+	c.applied = applied
+	c.mu.Unlock()
+	if err := c.BuildFromTXs(rst, nil); err != nil {
+		return err
 	}
-	return c.BuildFromTXs(rst, nil)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.reportSize()
+	return nil
+}
+
+// Restore rebuilds the cache from the txs that were in the mempool before the node restarted.
+// txs that were applied or can no longer be applied are dropped from the mempool, as well as txs
+// admitted more than maxAge layers before the last applied layer. 0 maxAge keeps all txs.
+func (c *Cache) Restore(db *sql.Database, maxAge uint32) error {
+	applied, err := layers.GetLastApplied(db)
+	if err != nil {
+		return fmt.Errorf("cache: get last applied %w", err)
+	}
+	if maxAge > 0 && applied > types.LayerID(maxAge) {
+		if err := dbmempool.PruneBefore(db, applied.Sub(maxAge)); err != nil {
+			return err
+		}
+	}
+	tids, err := dbmempool.All(db)
+	if err != nil {
+		return err
+	}
+	var (
+		rst     []*types.MeshTransaction
+		nonces  = make(map[types.Address]uint64)
+		dropped int
+	)
+	for _, tid := range tids {
+		mtx, err := transactions.Get(db, tid)
+		if err != nil && !errors.Is(err, sql.ErrNotFound) {
+			return err
+		}
+		if mtx != nil && mtx.State == types.MEMPOOL && !mtx.Expired(applied.Add(1)) {
+			nonce, ok := nonces[mtx.Principal]
+			if !ok {
+				nonce, _ = c.stateF(mtx.Principal)
+				nonces[mtx.Principal] = nonce
+			}
+			if mtx.Nonce >= nonce {
+				mtx.LayerID, mtx.BlockID, err = getNextIncluded(db, tid, applied)
+				if err != nil {
+					return err
+				}
+				rst = append(rst, mtx)
+				continue
+			}
+		}
+		if err := dbmempool.Delete(db, tid); err != nil {
+			return err
+		}
+		dropped++
+	}
+	c.logger.With().Info("restored mempool",
+		log.Int("restored", len(rst)),
+		log.Int("dropped", dropped),
+		log.Stringer("applied", applied))
+	c.mu.Lock()
+	c.applied = applied
+	c.mu.Unlock()
+	if err := c.BuildFromTXs(rst, nil); err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, err := c.evict(c.logger, db, types.TransactionID{}); err != nil {
+		return err
+	}
+	c.reportSize()
+	return nil
 }
 
 // BuildFromTXs builds the cache from the provided transactions.
@@ -487,6 +817,9 @@ func (c *Cache) BuildFromTXs(rst []*types.MeshTransaction, blockSeed []byte) err
 	defer c.mu.Unlock()
 
 	c.pending = make(map[types.Address]*accountCache)
+	c.cachedTXs = make(map[types.TransactionID]*NanoTX)
+	c.usage = &poolUsage{}
+	c.projector.reset()
 	toCleanup := make(map[types.Address]struct{})
 	for _, tx := range rst {
 		toCleanup[tx.Principal] = struct{}{}
@@ -524,7 +857,10 @@ func (c *Cache) createAcctIfNotPresent(addr types.Address) {
 			startNonce:   nextNonce,
 			startBalance: balance,
 			txsByNonce:   list.New(),
+			noParking:    c.noParking,
 			cachedTXs:    c.cachedTXs,
+			usage:        c.usage,
+			local:        c.local,
 		}
 	}
 }
@@ -542,7 +878,136 @@ func (c *Cache) cleanupAccounts(accounts map[types.Address]struct{}) {
 		if _, ok := c.pending[addr]; ok && c.pending[addr].shouldEvict() {
 			delete(c.pending, addr)
 		}
+		c.refresh(addr)
+	}
+}
+
+// refresh updates the projection and the number of parked txs after the txs of the account changed.
+func (c *Cache) refresh(addr types.Address) {
+	n := 0
+	if ac, ok := c.pending[addr]; ok {
+		p := ac.project()
+		c.projector.set(addr, p)
+		n = p.Parked
+	} else {
+		c.projector.drop(addr)
+	}
+	if c.usage.parked == nil {
+		c.usage.parked = make(map[types.Address]int)
+	}
+	c.usage.totalParked += n - c.usage.parked[addr]
+	if n == 0 {
+		delete(c.usage.parked, addr)
+	} else {
+		c.usage.parked[addr] = n
+	}
+}
+
+func (c *Cache) full() bool {
+	return (c.maxTXs > 0 && len(c.cachedTXs) > c.maxTXs) ||
+		(c.maxBytes > 0 && c.usage.bytes > c.maxBytes)
+}
+
+// evictable is the tx with the highest nonce of the account.
+type evictable struct {
+	ac     *accountCache
+	ntx    *NanoTX
+	parked bool
+}
+
+// worse returns true if the tx should be evicted before the other one.
+// parked txs are evicted before ready txs, then txs with lower fee density, then older txs.
+// incoming tx is evicted first if its fee density doesn't exceed the fee density of the other tx.
+func (e *evictable) worse(other *evictable, incoming types.TransactionID) bool {
+	if e.parked != other.parked {
+		return e.parked
+	}
+	if e.ntx.GasPrice != other.ntx.GasPrice {
+		return e.ntx.GasPrice < other.ntx.GasPrice
+	}
+	if e.ntx.ID == incoming || other.ntx.ID == incoming {
+		return e.ntx.ID == incoming
+	}
+	if !e.ntx.Received.Equal(other.ntx.Received) {
+		return e.ntx.Received.Before(other.ntx.Received)
+	}
+	return e.ntx.ID.Compare(other.ntx.ID)
+}
+
+// worst returns the tx to evict. only the txs with the highest nonce that are not packed
+// in proposals/blocks are considered, so that evictions don't create nonce gaps.
+func (c *Cache) worst(incoming types.TransactionID) *evictable {
+	var worst *evictable
+	for _, ac := range c.pending {
+		if ac.txsByNonce.Len() == 0 {
+			continue
+		}
+		ntx := ac.txsByNonce.Back().Value.(*candidate).best
+		if ntx.Layer != 0 {
+			continue
+		}
+		e := &evictable{
+			ac:     ac,
+			ntx:    ntx,
+			parked: !ac.noParking && ntx.Nonce-ac.startNonce+1 != uint64(ac.txsByNonce.Len()),
+		}
+		if worst == nil || e.worse(worst, incoming) {
+			worst = e
+		}
+	}
+	return worst
+}
+
+// evict removes the worst txs until the cache is within its limits.
+// it returns true if the incoming tx was evicted.
+func (c *Cache) evict(logger log.Log, db *sql.Database, incoming types.TransactionID) (bool, error) {
+	if c.maxTXs == 0 && c.maxBytes == 0 {
+		return false, nil
+	}
+	var (
+		evicted   bool
+		toCleanup = make(map[types.Address]struct{})
+	)
+	defer c.cleanupAccounts(toCleanup)
+	for c.full() {
+		victim := c.worst(incoming)
+		if victim == nil {
+			break
+		}
+		victim.ac.txsByNonce.Remove(victim.ac.txsByNonce.Back())
+		victim.ac.uncacheTX(victim.ntx.ID)
+		delete(c.local, victim.ntx.ID)
+		toCleanup[victim.ntx.Principal] = struct{}{}
+		if victim.ntx.ID == incoming {
+			evicted = true
+			mempoolTxCount.WithLabelValues(rejectedAtCap).Inc()
+			break
+		}
+		if err := dbmempool.Delete(db, victim.ntx.ID); err != nil {
+			return evicted, err
+		}
+		mempoolTxCount.WithLabelValues(evictedAtCap).Inc()
+		logger.With().Debug("evicted tx from full mempool",
+			victim.ntx.ID,
+			victim.ntx.Principal,
+			log.Uint64("nonce", victim.ntx.Nonce),
+			log.Uint64("gas_price", victim.ntx.GasPrice),
+			log.Bool("parked", victim.parked))
+		events.ReportTxEvicted(events.TxEvicted{
+			ID:        victim.ntx.ID,
+			Principal: victim.ntx.Principal,
+			Nonce:     victim.ntx.Nonce,
+			GasPrice:  victim.ntx.GasPrice,
+		})
 	}
+	return evicted, nil
+}
+
+func (c *Cache) reportSize() {
+	mempoolSize.WithLabelValues(sizeTXs).Set(float64(len(c.cachedTXs)))
+	mempoolSize.WithLabelValues(sizeBytes).Set(float64(c.usage.bytes))
+	mempoolTxsByState.WithLabelValues(stateReady).Set(float64(len(c.cachedTXs) - c.usage.totalParked))
+	mempoolTxsByState.WithLabelValues(stateParked).Set(float64(c.usage.totalParked))
 }
 
 //   - errInsufficientBalance:
@@ -552,27 +1017,92 @@ func (c *Cache) cleanupAccounts(accounts map[types.Address]struct{}) {
 //     re-evaluate it after each layer is applied.
 //   - errTooManyNonce: when a principal has way too many nonces, we don't want to blow up the memory. they should
 //     be stored in db and retrieved after each earlier nonce is applied.
+//   - errTooManyParked: same as errTooManyNonce, but for txs after a nonce gap.
 func acceptable(err error) bool {
-	return err == nil || errors.Is(err, errInsufficientBalance) || errors.Is(err, errTooManyNonce)
+	return err == nil || errors.Is(err, errInsufficientBalance) || errors.Is(err, errTooManyNonce) ||
+		errors.Is(err, errTooManyParked)
 }
 
+// Add adds the tx received from the network to the cache.
 func (c *Cache) Add(ctx context.Context, db *sql.Database, tx *types.Transaction, received time.Time, mustPersist bool) error {
+	return c.add(ctx, db, tx, received, mustPersist, false)
+}
+
+// AddLocal adds the tx submitted via the api of this node to the cache.
+// Local txs are preferred when the txs are selected for the proposals of this node.
+func (c *Cache) AddLocal(ctx context.Context, db *sql.Database, tx *types.Transaction, received time.Time) error {
+	return c.add(ctx, db, tx, received, false, true)
+}
+
+func (c *Cache) add(ctx context.Context, db *sql.Database, tx *types.Transaction, received time.Time, mustPersist, local bool) error {
 	c.mu.Lock()
 	defer c.mu.Unlock()
+	// reported after the accounts are cleaned up
+	defer c.reportSize()
 	principal := tx.Principal
 	c.createAcctIfNotPresent(principal)
 	defer c.cleanupAccounts(map[types.Address]struct{}{principal: {}})
 	logger := c.logger.WithContext(ctx).WithFields(principal)
-	err := c.pending[principal].add(logger, tx, received)
+	if local {
+		c.local[tx.ID] = struct{}{}
+	}
+	var (
+		replaced *NanoTX
+		err      error
+	)
+	if tx.Expired(c.applied.Add(1)) {
+		mempoolTxCount.WithLabelValues(expiredTx).Inc()
+		err = fmt.Errorf("%w: expiry %s, applied %s", errExpired, tx.Expiry(), c.applied)
+	} else {
+		replaced, err = c.pending[principal].add(logger, tx, received, c.feeBump)
+	}
+	if err == nil && replaced == nil {
+		evicted, evictErr := c.evict(logger, db, tx.ID)
+		if evictErr != nil {
+			return evictErr
+		}
+		if evicted {
+			err = fmt.Errorf("%w: gas price %d", errMempoolFull, tx.GasPrice)
+		}
+	}
 	if acceptable(err) {
 		err = nil
 		mempoolTxCount.WithLabelValues(accepted).Inc()
+	} else {
+		delete(c.local, tx.ID)
 	}
 	if err == nil || mustPersist {
 		if dbErr := transactions.Add(db, tx, received); dbErr != nil {
 			return dbErr
 		}
 	}
+	if err == nil {
+		if dbErr := dbmempool.Add(db, tx, c.applied); dbErr != nil {
+			return dbErr
+		}
+		events.ReportTxAdmitted(events.TxAdmitted{
+			ID:        tx.ID,
+			Principal: principal,
+			Nonce:     tx.Nonce,
+			GasPrice:  tx.GasPrice,
+		})
+	}
+	if replaced != nil {
+		delete(c.local, replaced.ID)
+		if dbErr := dbmempool.Delete(db, replaced.ID); dbErr != nil {
+			return dbErr
+		}
+		logger.With().Debug("tx replaced by fee",
+			log.Stringer("replaced", replaced.ID),
+			log.Stringer("replacement", tx.ID),
+			log.Uint64("nonce", tx.Nonce))
+		events.ReportTxReplaced(events.TxReplaced{
+			Principal: principal,
+			Nonce:     tx.Nonce,
+			Replaced:  replaced.ID,
+			New:       tx.ID,
+		})
+	}
 	return err
 }
 
@@ -599,10 +1129,15 @@ func (c *Cache) LinkTXsWithProposal(db *sql.Database, lid types.LayerID, pid typ
 	if len(tids) == 0 {
 		return nil
 	}
-	if err := addToProposal(db, lid, pid, tids); err != nil {
+	states, err := addToProposal(db, lid, pid, tids)
+	if err != nil {
 		c.logger.With().Error("failed to link txs to proposal in db", log.Err(err))
 		return err
 	}
+	reportStates(states)
+	for _, tid := range tids {
+		events.ReportTxIncludedInProposal(events.TxIncludedInProposal{ID: tid, Layer: lid, Proposal: pid})
+	}
 	return c.updateLayer(lid, types.EmptyBlockID, tids)
 }
 
@@ -611,9 +1146,14 @@ func (c *Cache) LinkTXsWithBlock(db *sql.Database, lid types.LayerID, bid types.
 	if len(tids) == 0 {
 		return nil
 	}
-	if err := addToBlock(db, lid, bid, tids); err != nil {
+	states, err := addToBlock(db, lid, bid, tids)
+	if err != nil {
 		return err
 	}
+	reportStates(states)
+	for _, tid := range tids {
+		events.ReportTxIncludedInBlock(events.TxIncludedInBlock{ID: tid, Layer: lid, Block: bid})
+	}
 	return c.updateLayer(lid, bid, tids)
 }
 
@@ -630,7 +1170,13 @@ func (c *Cache) updateLayer(lid types.LayerID, bid types.BlockID, tids []types.T
 			// transaction is not considered best in its nonce group
 			return nil
 		}
-		c.cachedTXs[ID].UpdateLayerMaybe(lid, bid)
+		ntx := c.cachedTXs[ID]
+		if bid != types.EmptyBlockID {
+			inclusionAge.WithLabelValues(inBlock).Observe(time.Since(ntx.Received).Seconds())
+		} else if ntx.Layer == 0 {
+			inclusionAge.WithLabelValues(inProposal).Observe(time.Since(ntx.Received).Seconds())
+		}
+		ntx.UpdateLayerMaybe(lid, bid)
 	}
 	return nil
 }
@@ -638,6 +1184,20 @@ func (c *Cache) updateLayer(lid types.LayerID, bid types.BlockID, tids []types.T
 func (c *Cache) applyEmptyLayer(db *sql.Database, lid types.LayerID) error {
 	c.mu.Lock()
 	defer c.mu.Unlock()
+	defer c.reportSize()
+	c.applied = lid
+
+	var states map[types.TransactionID]transactions.State
+	if err := db.WithTx(context.Background(), func(dbtx *sql.Tx) error {
+		var err error
+		states, err = transactions.ResetStates(dbtx, lid)
+		return err
+	}); err != nil {
+		return fmt.Errorf("reset states %w", err)
+	}
+	reportStates(states)
+	c.pruneAppliedLocal(lid)
+	orphans := c.takeOrphans(lid, nil)
 
 	for tid, ntx := range c.cachedTXs {
 		if ntx.Layer == lid {
@@ -648,6 +1208,11 @@ func (c *Cache) applyEmptyLayer(db *sql.Database, lid types.LayerID) error {
 			ntx.UpdateLayer(nbid, nlid)
 		}
 	}
+	logger := c.logger.WithFields(lid)
+	if err := c.dropExpired(logger, db, lid.Add(1)); err != nil {
+		return err
+	}
+	c.readmitOrphans(logger, orphans)
 	return nil
 }
 
@@ -671,6 +1236,9 @@ func (c *Cache) ApplyLayer(
 
 	c.mu.Lock()
 	defer c.mu.Unlock()
+	// reported after the accounts are cleaned up
+	defer c.reportSize()
+	c.applied = lid
 
 	toCleanup := make(map[types.Address]struct{})
 	toReset := make(map[types.Address]struct{})
@@ -678,21 +1246,48 @@ func (c *Cache) ApplyLayer(
 
 	// commmit results before reporting them
 	// TODO(dshulyak) save results in vm
+	var states map[types.TransactionID]transactions.State
 	if err := db.WithTx(context.Background(), func(dbtx *sql.Tx) error {
+		applied := transactions.State{State: types.APPLIED, Layer: lid, Block: bid}
 		for _, rst := range results {
 			err := transactions.AddResult(dbtx, rst.ID, &rst.TransactionResult)
 			if err != nil {
 				return fmt.Errorf("add result tx=%s nonce=%d %w", rst.ID, rst.Nonce, err)
 			}
+			if err := dbmempool.Delete(dbtx, rst.ID); err != nil {
+				return err
+			}
+			if _, err := transactions.UpdateState(dbtx, rst.ID, applied); err != nil {
+				return err
+			}
+		}
+		for _, tx := range ineffective {
+			if err := dbmempool.Delete(dbtx, tx.ID); err != nil {
+				return err
+			}
+		}
+		// txs in the blocks of the layer that weren't applied
+		var err error
+		if states, err = transactions.ResetStates(dbtx, lid); err != nil {
+			return err
+		}
+		for _, rst := range results {
+			states[rst.ID] = applied
 		}
 		return nil
 	}); err != nil {
 		return fmt.Errorf("add results %w", err)
 	}
+	reportStates(states)
 
+	c.pruneAppliedLocal(lid)
 	for _, rst := range results {
 		byPrincipal[rst.Principal] = struct{}{}
 		toCleanup[rst.Principal] = struct{}{}
+		if _, ok := c.local[rst.ID]; ok {
+			delete(c.local, rst.ID)
+			c.appliedLocal[rst.ID] = lid
+		}
 		if !c.has(rst.ID) {
 			RawTxCount.WithLabelValues(updated).Inc()
 			if err := transactions.Add(db, &rst.Transaction, time.Now()); err != nil {
@@ -703,6 +1298,7 @@ func (c *Cache) ApplyLayer(
 	}
 
 	for _, tx := range ineffective {
+		delete(c.local, tx.ID)
 		if tx.TxHeader == nil {
 			logger.With().Warning("tx header not parsed", tx.ID)
 			continue
@@ -723,6 +1319,17 @@ func (c *Cache) ApplyLayer(
 		}
 		toReset[tx.Principal] = struct{}{}
 	}
+	orphans := c.takeOrphans(lid, results)
+	for _, o := range orphans {
+		toCleanup[o.principal] = struct{}{}
+		if _, ok := byPrincipal[o.principal]; ok {
+			continue
+		}
+		// the orphans are tagged with the reverted blocks, the account is reloaded
+		// with the blocks that include them now.
+		c.createAcctIfNotPresent(o.principal)
+		toReset[o.principal] = struct{}{}
+	}
 	defer c.cleanupAccounts(toCleanup)
 
 	for principal := range byPrincipal {
@@ -756,12 +1363,152 @@ func (c *Cache) ApplyLayer(
 			return err
 		}
 		acctResetDuration.Observe(float64(time.Since(t2)))
+		c.refresh(principal)
+	}
+	if err := c.dropExpired(logger, db, lid.Add(1)); err != nil {
+		return err
+	}
+	// txs reloaded from the db may not fit into the mempool
+	if _, err := c.evict(logger, db, types.TransactionID{}); err != nil {
+		return err
+	}
+	c.readmitOrphans(logger, orphans)
+	return nil
+}
+
+// addOrphans keeps the txs reverted by a reorg until their layers are applied again.
+// the reverted local txs are local again.
+func (c *Cache) addOrphans(db *sql.Database, reverted map[types.TransactionID]types.LayerID) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for tid, applied := range reverted {
+		if _, ok := c.appliedLocal[tid]; ok {
+			delete(c.appliedLocal, tid)
+			c.local[tid] = struct{}{}
+		}
+		mtx, err := transactions.Get(db, tid)
+		if err != nil {
+			return fmt.Errorf("get reverted tx %s: %w", tid, err)
+		}
+		if mtx.TxHeader != nil {
+			c.orphans[tid] = orphan{principal: mtx.Principal, layer: applied}
+		}
+	}
+	return nil
+}
+
+// pruneAppliedLocal forgets the origin of the local txs applied too long before the layer.
+func (c *Cache) pruneAppliedLocal(lid types.LayerID) {
+	for tid, applied := range c.appliedLocal {
+		if applied.Add(appliedLocalLayers).Before(lid) {
+			delete(c.appliedLocal, tid)
+		}
+	}
+}
+
+// takeOrphans removes the orphans of the layers up to the applied layer and returns the ones
+// that were not applied again. an orphan is taken only once, even if several layers were reverted.
+func (c *Cache) takeOrphans(lid types.LayerID, results []types.TransactionWithResult) map[types.TransactionID]orphan {
+	if len(c.orphans) == 0 {
+		return nil
+	}
+	applied := make(map[types.TransactionID]struct{}, len(results))
+	for _, rst := range results {
+		applied[rst.ID] = struct{}{}
+	}
+	var rst map[types.TransactionID]orphan
+	for tid, o := range c.orphans {
+		if o.layer.After(lid) {
+			continue
+		}
+		delete(c.orphans, tid)
+		if _, ok := applied[tid]; ok {
+			continue
+		}
+		if rst == nil {
+			rst = make(map[types.TransactionID]orphan)
+		}
+		rst[tid] = o
+	}
+	return rst
+}
+
+// readmitOrphans counts the orphans that are back in the mempool, subject to the nonce and the balance
+// of their principals. the local ones that are not included in later blocks are queued to be gossiped again.
+func (c *Cache) readmitOrphans(logger log.Log, orphans map[types.TransactionID]orphan) {
+	for tid, o := range orphans {
+		ntx, ok := c.cachedTXs[tid]
+		if !ok {
+			logger.With().Debug("orphaned tx not re-admitted",
+				tid,
+				o.principal,
+				log.Stringer("applied", o.layer))
+			continue
+		}
+		mempoolTxCount.WithLabelValues(readmitted).Inc()
+		logger.With().Debug("re-admitted orphaned tx",
+			tid,
+			o.principal,
+			log.Stringer("applied", o.layer),
+			log.Bool("local", ntx.Local))
+		if ntx.Local && ntx.Layer == 0 {
+			c.rebroadcast = append(c.rebroadcast, tid)
+		}
+	}
+}
+
+// takeRebroadcast returns the local txs re-admitted after a reorg since the last call.
+func (c *Cache) takeRebroadcast() []types.TransactionID {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	rst := c.rebroadcast
+	c.rebroadcast = nil
+	return rst
+}
+
+// dropExpired removes the txs that can't be applied in the layer or later from the mempool.
+func (c *Cache) dropExpired(logger log.Log, db *sql.Database, lid types.LayerID) error {
+	toCleanup := make(map[types.Address]struct{})
+	defer c.cleanupAccounts(toCleanup)
+	for principal, ac := range c.pending {
+		dropped := ac.dropExpired(lid)
+		if len(dropped) == 0 {
+			continue
+		}
+		toCleanup[principal] = struct{}{}
+		for _, ntx := range dropped {
+			delete(c.local, ntx.ID)
+			if err := dbmempool.Delete(db, ntx.ID); err != nil {
+				return err
+			}
+			mempoolTxCount.WithLabelValues(expiredTx).Inc()
+			logger.With().Debug("dropped expired tx",
+				ntx.ID,
+				principal,
+				log.Uint64("nonce", ntx.Nonce),
+				log.Stringer("expiry", ntx.Expiry()))
+			events.ReportTxExpired(events.TxExpired{
+				ID:        ntx.ID,
+				Principal: principal,
+				Nonce:     ntx.Nonce,
+				Expiry:    ntx.Expiry(),
+			})
+		}
 	}
 	return nil
 }
 
 func (c *Cache) RevertToLayer(db *sql.Database, revertTo types.LayerID) error {
-	if err := undoLayers(db, revertTo.Add(1)); err != nil {
+	reverted, err := undoLayers(db, revertTo.Add(1))
+	if err != nil {
+		return err
+	}
+	states := make(map[types.TransactionID]transactions.State, len(reverted))
+	for tid := range reverted {
+		states[tid] = transactions.State{State: types.MEMPOOL}
+	}
+	reportStates(states)
+	if err := c.addOrphans(db, reverted); err != nil {
 		return err
 	}
 
@@ -775,13 +1522,64 @@ func (c *Cache) RevertToLayer(db *sql.Database, revertTo types.LayerID) error {
 // GetProjection returns the projected nonce and balance for an account, including
 // pending transactions that are paced in proposals/blocks but not yet applied to the state.
 func (c *Cache) GetProjection(addr types.Address) (uint64, uint64) {
+	p := c.Projection(addr)
+	return p.NextNonce, p.Balance
+}
+
+// Projection returns the state of the account projected over its transactions in the cache.
+// It doesn't wait for the cache updates in progress, the projection from the last completed
+// update of the account is returned.
+func (c *Cache) Projection(addr types.Address) Projection {
+	if p, ok := c.projector.get(addr); ok {
+		return p
+	}
+	nonce, balance := c.stateF(addr)
+	return Projection{NextNonce: nonce, Balance: balance, ReadyNonce: nonce, ReadyBalance: balance}
+}
+
+// PendingTX is a transaction of the account in the cache.
+type PendingTX struct {
+	*NanoTX
+	// Parked is true if the tx follows a nonce gap. It is not selected for proposals/blocks
+	// until the missing nonce arrives.
+	Parked bool
+}
+
+// GetPending returns the transactions of the account in the cache, ordered by nonce.
+func (c *Cache) GetPending(addr types.Address) []PendingTX {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
-	if _, ok := c.pending[addr]; !ok {
-		return c.stateF(addr)
+	acct, ok := c.pending[addr]
+	if !ok {
+		return nil
+	}
+	ready := c.Projection(addr).ReadyNonce
+	rst := make([]PendingTX, 0, acct.txsByNonce.Len())
+	for e := acct.txsByNonce.Front(); e != nil; e = e.Next() {
+		cand := e.Value.(*candidate)
+		rst = append(rst, PendingTX{NanoTX: cand.best, Parked: cand.nonce() > ready})
 	}
-	return c.pending[addr].nextNonce(), c.pending[addr].availBalance()
+	return rst
+}
+
+// AccountPendingState returns the next nonce and the balance of the account projected over its ready
+// transactions in the cache, and the ids of all its pending transactions ordered by nonce.
+// parked transactions are returned, but they don't change the projection until the missing nonce arrives.
+func (c *Cache) AccountPendingState(addr types.Address) (uint64, uint64, []types.TransactionID) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	p := c.Projection(addr)
+	acct, ok := c.pending[addr]
+	if !ok {
+		return p.ReadyNonce, p.ReadyBalance, nil
+	}
+	pending := make([]types.TransactionID, 0, acct.txsByNonce.Len())
+	for e := acct.txsByNonce.Front(); e != nil; e = e.Next() {
+		pending = append(pending, e.Value.(*candidate).id())
+	}
+	return p.ReadyNonce, p.ReadyBalance, pending
 }
 
 // GetMempool returns all the transactions that eligible for a proposal/block.
@@ -792,7 +1590,7 @@ func (c *Cache) GetMempool(logger log.Log) map[types.Address][]*NanoTX {
 	all := make(map[types.Address][]*NanoTX)
 	logger.With().Debug("cache has pending accounts", log.Int("num_acct", len(c.pending)))
 	for addr, accCache := range c.pending {
-		txs := accCache.getMempool(logger.WithFields(addr))
+		txs := accCache.getMempool(logger.WithFields(addr), c.Projection(addr).ReadyNonce)
 		if len(txs) > 0 {
 			all[addr] = txs
 		}
@@ -816,36 +1614,75 @@ func checkApplyOrder(logger log.Log, db *sql.Database, toApply types.LayerID) er
 	return nil
 }
 
-func addToProposal(db *sql.Database, lid types.LayerID, pid types.ProposalID, tids []types.TransactionID) error {
-	return db.WithTx(context.Background(), func(dbtx *sql.Tx) error {
+func addToProposal(db *sql.Database, lid types.LayerID, pid types.ProposalID, tids []types.TransactionID) (map[types.TransactionID]transactions.State, error) {
+	states := make(map[types.TransactionID]transactions.State)
+	proposed := transactions.State{State: types.PROPOSAL, Layer: lid}
+	if err := db.WithTx(context.Background(), func(dbtx *sql.Tx) error {
 		for _, tid := range tids {
 			if err := transactions.AddToProposal(dbtx, tid, lid, pid); err != nil {
 				return fmt.Errorf("add2prop %w", err)
 			}
+			if updated, err := transactions.UpdateState(dbtx, tid, proposed); err != nil {
+				return err
+			} else if updated {
+				states[tid] = proposed
+			}
 		}
 		return nil
-	})
+	}); err != nil {
+		return nil, err
+	}
+	return states, nil
 }
 
-func addToBlock(db *sql.Database, lid types.LayerID, bid types.BlockID, tids []types.TransactionID) error {
-	return db.WithTx(context.Background(), func(dbtx *sql.Tx) error {
+func addToBlock(db *sql.Database, lid types.LayerID, bid types.BlockID, tids []types.TransactionID) (map[types.TransactionID]transactions.State, error) {
+	states := make(map[types.TransactionID]transactions.State)
+	included := transactions.State{State: types.BLOCK, Layer: lid, Block: bid}
+	if err := db.WithTx(context.Background(), func(dbtx *sql.Tx) error {
 		for _, tid := range tids {
 			if err := transactions.AddToBlock(dbtx, tid, lid, bid); err != nil {
 				return fmt.Errorf("add2block %w", err)
 			}
+			if updated, err := transactions.UpdateState(dbtx, tid, included); err != nil {
+				return err
+			} else if updated {
+				states[tid] = included
+			}
 		}
 		return nil
-	})
+	}); err != nil {
+		return nil, err
+	}
+	return states, nil
 }
 
-func undoLayers(db *sql.Database, from types.LayerID) error {
-	return db.WithTx(context.Background(), func(dbtx *sql.Tx) error {
+// undoLayers reverts the applied txs starting from the layer and returns their ids
+// with the layers they were applied in.
+func undoLayers(db *sql.Database, from types.LayerID) (map[types.TransactionID]types.LayerID, error) {
+	var reverted map[types.TransactionID]types.LayerID
+	if err := db.WithTx(context.Background(), func(dbtx *sql.Tx) error {
 		err := transactions.UndoLayers(dbtx, from)
 		if err != nil {
 			return fmt.Errorf("undo %w", err)
 		}
-		return nil
-	})
+		reverted, err = transactions.UndoStates(dbtx, from)
+		return err
+	}); err != nil {
+		return nil, err
+	}
+	return reverted, nil
+}
+
+// reportStates reports the lifecycle states the txs moved to.
+func reportStates(states map[types.TransactionID]transactions.State) {
+	for tid, state := range states {
+		events.ReportTxState(events.TxState{
+			ID:    tid,
+			State: state.State,
+			Layer: state.Layer,
+			Block: state.Block,
+		})
+	}
 }
 
 func getNextIncluded(db sql.Executor, id types.TransactionID, after types.LayerID) (types.LayerID, types.BlockID, error) {
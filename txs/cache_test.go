@@ -3,16 +3,23 @@ package txs
 import (
 	"context"
 	"math/rand"
+	"runtime"
 	"testing"
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
 	"github.com/stretchr/testify/require"
 
 	"github.com/spacemeshos/go-spacemesh/common/types"
+	"github.com/spacemeshos/go-spacemesh/events"
+	"github.com/spacemeshos/go-spacemesh/log"
 	"github.com/spacemeshos/go-spacemesh/log/logtest"
 	"github.com/spacemeshos/go-spacemesh/signing"
 	"github.com/spacemeshos/go-spacemesh/sql"
 	"github.com/spacemeshos/go-spacemesh/sql/layers"
+	dbmempool "github.com/spacemeshos/go-spacemesh/sql/mempool"
 	"github.com/spacemeshos/go-spacemesh/sql/transactions"
 )
 
@@ -741,8 +748,8 @@ func TestCache_Account_Add_InsufficientBalance_HigherNonceFeasibleFirst(t *testi
 	checkNoTX(t, tc.Cache, mtx0.ID)
 	checkTX(t, tc.Cache, mtx1.ID, 0, types.EmptyBlockID)
 	checkProjection(t, tc.Cache, ta.principal, mtx1.Nonce+1, 0)
-	expectedMempool := map[types.Address][]*types.MeshTransaction{ta.principal: {mtx1}}
-	checkMempool(t, tc.Cache, expectedMempool)
+	// mtx1 is parked until the missing nonces arrive
+	checkMempool(t, tc.Cache, nil)
 	require.True(t, tc.MoreInDB(ta.principal))
 	checkTXStateFromDB(t, tc.db, []*types.MeshTransaction{mtx0, mtx1}, types.MEMPOOL)
 
@@ -755,7 +762,7 @@ func TestCache_Account_Add_InsufficientBalance_HigherNonceFeasibleFirst(t *testi
 	checkTX(t, tc.Cache, mtx0.ID, 0, types.EmptyBlockID)
 	checkTX(t, tc.Cache, mtx1.ID, 0, types.EmptyBlockID)
 	checkProjection(t, tc.Cache, ta.principal, mtx1.Nonce+1, 0)
-	expectedMempool = map[types.Address][]*types.MeshTransaction{ta.principal: {mtx0, mtx1}}
+	expectedMempool := map[types.Address][]*types.MeshTransaction{ta.principal: {mtx0}}
 	checkMempool(t, tc.Cache, expectedMempool)
 	require.False(t, tc.MoreInDB(ta.principal))
 	checkTXStateFromDB(t, tc.db, []*types.MeshTransaction{mtx0, mtx1}, types.MEMPOOL)
@@ -786,8 +793,9 @@ func TestCache_Account_Add_InsufficientBalance_ExistingNonce(t *testing.T) {
 	require.NoError(t, transactions.Add(tc.db, &mtx.Transaction, mtx.Received))
 	buildSingleAccountCache(t, tc, ta, []*types.MeshTransaction{mtx})
 
+	// the fee is high enough to replace mtx, but the balance is not
 	spender := &types.MeshTransaction{
-		Transaction: *newTx(t, ta.nonce, ta.balance, defaultFee, ta.signer),
+		Transaction: *newTx(t, ta.nonce, ta.balance, defaultFee*2, ta.signer),
 		Received:    time.Now(),
 	}
 	require.NoError(t, tc.Add(context.Background(), tc.db, &spender.Transaction, spender.Received, false))
@@ -798,6 +806,125 @@ func TestCache_Account_Add_InsufficientBalance_ExistingNonce(t *testing.T) {
 	checkTXStateFromDB(t, tc.db, []*types.MeshTransaction{mtx, spender}, types.MEMPOOL)
 }
 
+func TestCache_Account_ReplaceByFee_BumpTooLow(t *testing.T) {
+	tc, ta := createSingleAccountTestCache(t)
+	tc.feeBump = 50
+	mtx := newMeshTX(t, ta.nonce, ta.signer, defaultAmount, time.Now())
+	saveTXs(t, tc.db, []*types.MeshTransaction{mtx})
+	buildSingleAccountCache(t, tc, ta, []*types.MeshTransaction{mtx})
+
+	rejected := testutil.ToFloat64(mempoolTxCount.WithLabelValues(feeBumpTooLow))
+	for _, fee := range []uint64{defaultFee - 1, defaultFee, defaultFee + 1} {
+		replacement := newTx(t, ta.nonce, defaultAmount, fee, ta.signer)
+		err := tc.Add(context.Background(), tc.db, replacement, time.Now(), false)
+		require.ErrorIs(t, err, errFeeBumpTooLow)
+		checkNoTX(t, tc.Cache, replacement.ID)
+		checkTXNotInDB(t, tc.db, replacement.ID)
+	}
+	require.Equal(t, rejected+3, testutil.ToFloat64(mempoolTxCount.WithLabelValues(feeBumpTooLow)))
+	checkTX(t, tc.Cache, mtx.ID, 0, types.EmptyBlockID)
+	checkMempool(t, tc.Cache, map[types.Address][]*types.MeshTransaction{ta.principal: {mtx}})
+}
+
+func TestCache_Account_ReplaceByFee(t *testing.T) {
+	events.InitializeReporter()
+	t.Cleanup(events.CloseEventReporter)
+	sub, err := events.Subscribe[events.TxReplaced]()
+	require.NoError(t, err)
+
+	tc, ta := createSingleAccountTestCache(t)
+	tc.feeBump = 50
+	mtxs := genAndSaveTXs(t, tc.db, ta.signer, ta.nonce, ta.nonce+2, time.Now())
+	buildSingleAccountCache(t, tc, ta, mtxs)
+
+	replacement := &types.MeshTransaction{
+		Transaction: *newTx(t, ta.nonce+1, defaultAmount, defaultFee*3/2, ta.signer),
+		Received:    time.Now(),
+	}
+	replaced := testutil.ToFloat64(mempoolTxCount.WithLabelValues(replacedByFee))
+	require.NoError(t, tc.Add(context.Background(), tc.db, &replacement.Transaction, replacement.Received, false))
+	require.Equal(t, replaced+1, testutil.ToFloat64(mempoolTxCount.WithLabelValues(replacedByFee)))
+	checkNoTX(t, tc.Cache, mtxs[1].ID)
+	checkTX(t, tc.Cache, replacement.ID, 0, types.EmptyBlockID)
+	checkTXStateFromDB(t, tc.db, []*types.MeshTransaction{replacement}, types.MEMPOOL)
+	expected := []*types.MeshTransaction{mtxs[0], replacement, mtxs[2]}
+	checkMempool(t, tc.Cache, map[types.Address][]*types.MeshTransaction{ta.principal: expected})
+	newBalance := ta.balance
+	for _, mtx := range expected {
+		newBalance -= mtx.Spending()
+	}
+	checkProjection(t, tc.Cache, ta.principal, ta.nonce+3, newBalance)
+
+	select {
+	case ev := <-sub.Out():
+		require.Equal(t, events.TxReplaced{
+			Principal: ta.principal,
+			Nonce:     ta.nonce + 1,
+			Replaced:  mtxs[1].ID,
+			New:       replacement.ID,
+		}, ev)
+	case <-time.After(time.Second):
+		require.FailNow(t, "replacement is not reported")
+	}
+}
+
+func TestCache_Account_ReplaceByFee_TooManyNonce(t *testing.T) {
+	tc, ta := createSingleAccountTestCache(t)
+	tc.feeBump = 50
+	ta.balance = uint64(1000000)
+	mtxs := genAndSaveTXs(t, tc.db, ta.signer, ta.nonce, ta.nonce+maxTXsPerAcct-1, time.Now())
+	buildSingleAccountCache(t, tc, ta, mtxs)
+
+	// the account is at the limit, new nonce is not cached
+	oneTooMany := newTx(t, ta.nonce+maxTXsPerAcct, defaultAmount, defaultFee, ta.signer)
+	require.NoError(t, tc.Add(context.Background(), tc.db, oneTooMany, time.Now(), false))
+	checkNoTX(t, tc.Cache, oneTooMany.ID)
+
+	// but the existing nonce can still be replaced by fee
+	replacement := &types.MeshTransaction{
+		Transaction: *newTx(t, ta.nonce+1, defaultAmount, defaultFee*3/2, ta.signer),
+		Received:    time.Now(),
+	}
+	require.NoError(t, tc.Add(context.Background(), tc.db, &replacement.Transaction, replacement.Received, false))
+	checkNoTX(t, tc.Cache, mtxs[1].ID)
+	checkTX(t, tc.Cache, replacement.ID, 0, types.EmptyBlockID)
+	expected := append([]*types.MeshTransaction{mtxs[0], replacement}, mtxs[2:]...)
+	checkMempool(t, tc.Cache, map[types.Address][]*types.MeshTransaction{ta.principal: expected})
+}
+
+func TestCache_Account_ReplaceByFee_RacePacked(t *testing.T) {
+	for i := 0; i < 20; i++ {
+		tc, ta := createSingleAccountTestCache(t)
+		mtx := newMeshTX(t, ta.nonce, ta.signer, defaultAmount, time.Now())
+		saveTXs(t, tc.db, []*types.MeshTransaction{mtx})
+		buildSingleAccountCache(t, tc, ta, []*types.MeshTransaction{mtx})
+
+		lid := types.LayerID(10)
+		replacement := newTx(t, ta.nonce, defaultAmount, defaultFee*2, ta.signer)
+		var (
+			start = make(chan struct{})
+			added = make(chan error, 1)
+		)
+		go func() {
+			<-start
+			added <- tc.Add(context.Background(), tc.db, replacement, time.Now(), false)
+		}()
+		close(start)
+		require.NoError(t, tc.LinkTXsWithProposal(tc.db, lid, types.ProposalID{1}, []types.TransactionID{mtx.ID}))
+
+		if err := <-added; err != nil {
+			// the tx was packed first and must not be replaced
+			require.ErrorIs(t, err, errReplacePacked)
+			checkTX(t, tc.Cache, mtx.ID, lid, types.EmptyBlockID)
+			checkNoTX(t, tc.Cache, replacement.ID)
+			checkTXNotInDB(t, tc.db, replacement.ID)
+		} else {
+			checkNoTX(t, tc.Cache, mtx.ID)
+			checkTX(t, tc.Cache, replacement.ID, 0, types.EmptyBlockID)
+		}
+	}
+}
+
 func TestCache_Account_AppliedTXsNotInCache(t *testing.T) {
 	tc, ta := createSingleAccountTestCache(t)
 	mtxs := genTXs(t, ta.signer, ta.nonce, ta.nonce+2, time.Now())
@@ -988,6 +1115,87 @@ func TestCache_Account_NotEvictedAfterApplyDueToNonceGap(t *testing.T) {
 	checkTXStateFromDB(t, tc.db, []*types.MeshTransaction{pendingInsufficient}, types.MEMPOOL)
 }
 
+func checkPending(t *testing.T, c *Cache, addr types.Address, ready, parked []*types.MeshTransaction) {
+	t.Helper()
+	pending := c.GetPending(addr)
+	require.Len(t, pending, len(ready)+len(parked))
+	for i, mtx := range ready {
+		require.Equal(t, mtx.ID, pending[i].ID)
+		require.False(t, pending[i].Parked, "nonce %d", mtx.Nonce)
+	}
+	for i, mtx := range parked {
+		require.Equal(t, mtx.ID, pending[len(ready)+i].ID)
+		require.True(t, pending[len(ready)+i].Parked, "nonce %d", mtx.Nonce)
+	}
+}
+
+func TestCache_Account_ParkedPromotionChain(t *testing.T) {
+	tc, ta := createSingleAccountTestCache(t)
+	mtxs := genTXs(t, ta.signer, ta.nonce, ta.nonce+5, time.Now())
+	for _, mtx := range mtxs[1:] {
+		require.NoError(t, tc.Add(context.Background(), tc.db, &mtx.Transaction, mtx.Received, false))
+	}
+	checkMempool(t, tc.Cache, nil)
+	checkPending(t, tc.Cache, ta.principal, nil, mtxs[1:])
+
+	before := testutil.ToFloat64(mempoolTxCount.WithLabelValues(promotedParked))
+	require.NoError(t, tc.Add(context.Background(), tc.db, &mtxs[0].Transaction, mtxs[0].Received, false))
+	require.Equal(t, 5.0, testutil.ToFloat64(mempoolTxCount.WithLabelValues(promotedParked))-before)
+	checkPending(t, tc.Cache, ta.principal, mtxs, nil)
+	checkMempool(t, tc.Cache, map[types.Address][]*types.MeshTransaction{ta.principal: mtxs})
+	checkTXStateFromDB(t, tc.db, mtxs, types.MEMPOOL)
+}
+
+func TestCache_Account_ParkedPromotionStopsAtGap(t *testing.T) {
+	tc, ta := createSingleAccountTestCache(t)
+	mtxs := genTXs(t, ta.signer, ta.nonce, ta.nonce+4, time.Now())
+	for _, i := range []int{1, 2, 4} {
+		require.NoError(t, tc.Add(context.Background(), tc.db, &mtxs[i].Transaction, mtxs[i].Received, false))
+	}
+	require.NoError(t, tc.Add(context.Background(), tc.db, &mtxs[0].Transaction, mtxs[0].Received, false))
+	checkPending(t, tc.Cache, ta.principal, mtxs[:3], mtxs[4:])
+	checkMempool(t, tc.Cache, map[types.Address][]*types.MeshTransaction{ta.principal: mtxs[:3]})
+}
+
+func TestCache_Account_ParkedEvictedOverCap(t *testing.T) {
+	tc, ta := createSingleAccountTestCache(t)
+	mtxs := genTXs(t, ta.signer, ta.nonce, ta.nonce+maxParkedPerAcct+2, time.Now())
+	// nonces from ta.nonce+2 are parked up to the cap
+	parked := mtxs[2 : 2+maxParkedPerAcct]
+	for _, mtx := range parked {
+		require.NoError(t, tc.Add(context.Background(), tc.db, &mtx.Transaction, mtx.Received, false))
+	}
+	checkPending(t, tc.Cache, ta.principal, nil, parked)
+	require.False(t, tc.MoreInDB(ta.principal))
+
+	// a tx with a higher nonce exceeds the cap and is evicted right away
+	last := mtxs[len(mtxs)-1]
+	require.NoError(t, tc.Add(context.Background(), tc.db, &last.Transaction, last.Received, false))
+	checkNoTX(t, tc.Cache, last.ID)
+	require.True(t, tc.MoreInDB(ta.principal))
+	checkPending(t, tc.Cache, ta.principal, nil, parked)
+
+	// a tx with a lower nonce evicts the parked tx with the highest nonce
+	require.NoError(t, tc.Add(context.Background(), tc.db, &mtxs[1].Transaction, mtxs[1].Received, false))
+	checkNoTX(t, tc.Cache, parked[len(parked)-1].ID)
+	checkPending(t, tc.Cache, ta.principal, nil, mtxs[1:1+maxParkedPerAcct])
+	checkMempool(t, tc.Cache, nil)
+	checkTXStateFromDB(t, tc.db, mtxs[1:], types.MEMPOOL)
+
+	// the block with the missing nonce re-evaluates parked txs, evicted ones are loaded from db
+	saveTXs(t, tc.db, mtxs[:1])
+	ta.nonce++
+	ta.balance -= mtxs[0].Spending()
+	lid := types.LayerID(97)
+	require.NoError(t, layers.SetApplied(tc.db, lid.Sub(1), types.RandomBlockID()))
+	bid := types.BlockID{1, 2, 3}
+	applied := makeResults(lid, bid, mtxs[0].Transaction)
+	require.NoError(t, tc.ApplyLayer(context.Background(), tc.db, lid, bid, applied, []types.Transaction{}))
+	checkPending(t, tc.Cache, ta.principal, mtxs[1:], nil)
+	checkMempool(t, tc.Cache, map[types.Address][]*types.MeshTransaction{ta.principal: mtxs[1:]})
+	require.False(t, tc.MoreInDB(ta.principal))
+}
+
 func TestCache_BuildFromScratch(t *testing.T) {
 	tc, accounts := createCache(t, 1000)
 	mtxs := make(map[types.Address][]*types.MeshTransaction)
@@ -1359,3 +1567,470 @@ func TestCache_GetProjection(t *testing.T) {
 		require.Equal(t, expectedBalance, balance)
 	}
 }
+
+func checkInMempoolDB(t *testing.T, db *sql.Database, tid types.TransactionID, expected bool) {
+	t.Helper()
+	has, err := dbmempool.Has(db, tid)
+	require.NoError(t, err)
+	require.Equal(t, expected, has)
+}
+
+func TestCache_Restore(t *testing.T) {
+	tc, ta := createSingleAccountTestCache(t)
+	mtxs := genTXs(t, ta.signer, ta.nonce, ta.nonce+4, time.Now())
+	for i, mtx := range mtxs {
+		if i == 2 {
+			continue
+		}
+		require.NoError(t, tc.Add(context.Background(), tc.db, &mtx.Transaction, mtx.Received, false))
+		checkInMempoolDB(t, tc.db, mtx.ID, true)
+	}
+	checkPending(t, tc.Cache, ta.principal, mtxs[:2], mtxs[3:])
+
+	// the first tx was applied while the node was down
+	ta.nonce++
+	restarted := NewCache(getStateFunc(map[types.Address]*testAcct{ta.principal: ta}), logtest.New(t))
+	require.NoError(t, restarted.Restore(tc.db, 0))
+	checkPending(t, restarted, ta.principal, mtxs[1:2], mtxs[3:])
+	checkNoTX(t, restarted, mtxs[0].ID)
+	checkInMempoolDB(t, tc.db, mtxs[0].ID, false)
+
+	// the gap is filled after restart
+	require.NoError(t, restarted.Add(context.Background(), tc.db, &mtxs[2].Transaction, mtxs[2].Received, false))
+	checkPending(t, restarted, ta.principal, mtxs[1:], nil)
+}
+
+func TestCache_Restore_MaxAge(t *testing.T) {
+	tc, ta := createSingleAccountTestCache(t)
+	require.NoError(t, layers.SetApplied(tc.db, 5, types.RandomBlockID()))
+	require.NoError(t, tc.Restore(tc.db, 10))
+	stale := newMeshTX(t, ta.nonce, ta.signer, defaultAmount, time.Now())
+	require.NoError(t, tc.Add(context.Background(), tc.db, &stale.Transaction, stale.Received, false))
+
+	require.NoError(t, layers.SetApplied(tc.db, 15, types.RandomBlockID()))
+	require.NoError(t, tc.Restore(tc.db, 10))
+	checkTX(t, tc.Cache, stale.ID, 0, types.EmptyBlockID)
+	fresh := newMeshTX(t, ta.nonce+1, ta.signer, defaultAmount, time.Now())
+	require.NoError(t, tc.Add(context.Background(), tc.db, &fresh.Transaction, fresh.Received, false))
+
+	require.NoError(t, layers.SetApplied(tc.db, 16, types.RandomBlockID()))
+	restarted := NewCache(getStateFunc(map[types.Address]*testAcct{ta.principal: ta}), logtest.New(t))
+	require.NoError(t, restarted.Restore(tc.db, 10))
+	checkNoTX(t, restarted, stale.ID)
+	checkInMempoolDB(t, tc.db, stale.ID, false)
+	checkTXStateFromDB(t, tc.db, []*types.MeshTransaction{stale}, types.MEMPOOL)
+	checkPending(t, restarted, ta.principal, nil, []*types.MeshTransaction{fresh})
+}
+
+func TestCache_ApplyLayer_RemovesFromMempoolDB(t *testing.T) {
+	tc, ta := createSingleAccountTestCache(t)
+	mtxs := genTXs(t, ta.signer, ta.nonce, ta.nonce+1, time.Now())
+	for _, mtx := range mtxs {
+		require.NoError(t, tc.Add(context.Background(), tc.db, &mtx.Transaction, mtx.Received, false))
+	}
+	lid := types.LayerID(1)
+	bid := types.RandomBlockID()
+	ta.nonce++
+	ta.balance -= mtxs[0].Spending()
+	require.NoError(t, tc.ApplyLayer(context.Background(), tc.db, lid, bid,
+		makeResults(lid, bid, mtxs[0].Transaction), nil))
+	checkInMempoolDB(t, tc.db, mtxs[0].ID, false)
+	checkInMempoolDB(t, tc.db, mtxs[1].ID, true)
+}
+
+func acctList(accounts map[types.Address]*testAcct) []*testAcct {
+	rst := make([]*testAcct, 0, len(accounts))
+	for _, ta := range accounts {
+		rst = append(rst, ta)
+	}
+	return rst
+}
+
+func TestCache_Evict_LowestFeeDensity(t *testing.T) {
+	events.InitializeReporter()
+	t.Cleanup(events.CloseEventReporter)
+	sub, err := events.Subscribe[events.TxEvicted]()
+	require.NoError(t, err)
+
+	tc, accounts := createCache(t, 5)
+	tc.maxTXs = 3
+	accts := acctList(accounts)
+	now := time.Now()
+	held := make([]*types.Transaction, 0, 3)
+	for i, price := range []uint64{5, 3, 7} {
+		tx := newTx(t, accts[i].nonce, defaultAmount, price, accts[i].signer)
+		require.NoError(t, tc.Add(context.Background(), tc.db, tx, now, false))
+		held = append(held, tx)
+	}
+	checkMempoolSize(t, tc.Cache, 3)
+
+	rejected := testutil.ToFloat64(mempoolTxCount.WithLabelValues(rejectedAtCap))
+	// the fee density must exceed the lowest in the mempool
+	for _, price := range []uint64{2, 3} {
+		tx := newTx(t, accts[3].nonce, defaultAmount, price, accts[3].signer)
+		require.ErrorIs(t, tc.Add(context.Background(), tc.db, tx, now, false), errMempoolFull)
+		checkNoTX(t, tc.Cache, tx.ID)
+		checkTXNotInDB(t, tc.db, tx.ID)
+	}
+	require.Equal(t, rejected+2, testutil.ToFloat64(mempoolTxCount.WithLabelValues(rejectedAtCap)))
+
+	evicted := testutil.ToFloat64(mempoolTxCount.WithLabelValues(evictedAtCap))
+	tx := newTx(t, accts[3].nonce, defaultAmount, 4, accts[3].signer)
+	require.NoError(t, tc.Add(context.Background(), tc.db, tx, now, false))
+	require.Equal(t, evicted+1, testutil.ToFloat64(mempoolTxCount.WithLabelValues(evictedAtCap)))
+	checkTX(t, tc.Cache, tx.ID, 0, types.EmptyBlockID)
+	checkNoTX(t, tc.Cache, held[1].ID)
+	checkInMempoolDB(t, tc.db, held[1].ID, false)
+	checkMempoolSize(t, tc.Cache, 3)
+	require.Equal(t, 3.0, testutil.ToFloat64(mempoolSize.WithLabelValues(sizeTXs)))
+
+	select {
+	case ev := <-sub.Out():
+		require.Equal(t, events.TxEvicted{
+			ID:        held[1].ID,
+			Principal: held[1].Principal,
+			Nonce:     held[1].Nonce,
+			GasPrice:  3,
+		}, ev)
+	case <-time.After(time.Second):
+		require.FailNow(t, "eviction is not reported")
+	}
+}
+
+func TestCache_Evict_TieOldest(t *testing.T) {
+	tc, accounts := createCache(t, 3)
+	tc.maxTXs = 2
+	accts := acctList(accounts)
+	now := time.Now()
+	older := newTx(t, accts[0].nonce, defaultAmount, defaultFee, accts[0].signer)
+	require.NoError(t, tc.Add(context.Background(), tc.db, older, now, false))
+	newer := newTx(t, accts[1].nonce, defaultAmount, defaultFee, accts[1].signer)
+	require.NoError(t, tc.Add(context.Background(), tc.db, newer, now.Add(time.Second), false))
+
+	tx := newTx(t, accts[2].nonce, defaultAmount, defaultFee+1, accts[2].signer)
+	require.NoError(t, tc.Add(context.Background(), tc.db, tx, now.Add(2*time.Second), false))
+	checkNoTX(t, tc.Cache, older.ID)
+	checkTX(t, tc.Cache, newer.ID, 0, types.EmptyBlockID)
+	checkTX(t, tc.Cache, tx.ID, 0, types.EmptyBlockID)
+}
+
+func TestCache_Evict_ParkedFirst(t *testing.T) {
+	tc, accounts := createCache(t, 3)
+	tc.maxTXs = 2
+	accts := acctList(accounts)
+	now := time.Now()
+	ready := make([]*types.Transaction, 0, 2)
+	for _, ta := range accts[:2] {
+		tx := newTx(t, ta.nonce, defaultAmount, 1, ta.signer)
+		require.NoError(t, tc.Add(context.Background(), tc.db, tx, now, false))
+		ready = append(ready, tx)
+	}
+
+	// a parked tx never evicts a ready tx
+	parked := newTx(t, accts[2].nonce+1, defaultAmount, 100, accts[2].signer)
+	require.ErrorIs(t, tc.Add(context.Background(), tc.db, parked, now, false), errMempoolFull)
+	checkNoTX(t, tc.Cache, parked.ID)
+	for _, tx := range ready {
+		checkTX(t, tc.Cache, tx.ID, 0, types.EmptyBlockID)
+	}
+
+	tc.maxTXs = 3
+	require.NoError(t, tc.Add(context.Background(), tc.db, parked, now, false))
+	checkPending(t, tc.Cache, accts[2].principal, nil, []*types.MeshTransaction{{Transaction: *parked}})
+
+	// a ready tx evicts a parked tx regardless of the fee
+	cheap := newTx(t, accts[0].nonce+1, defaultAmount, 1, accts[0].signer)
+	require.NoError(t, tc.Add(context.Background(), tc.db, cheap, now.Add(time.Second), false))
+	checkNoTX(t, tc.Cache, parked.ID)
+	checkTX(t, tc.Cache, cheap.ID, 0, types.EmptyBlockID)
+	for _, tx := range ready {
+		checkTX(t, tc.Cache, tx.ID, 0, types.EmptyBlockID)
+	}
+}
+
+func TestCache_Evict_MaxBytes(t *testing.T) {
+	tc, accounts := createCache(t, 3)
+	accts := acctList(accounts)
+	now := time.Now()
+	txs := make([]*types.Transaction, 0, len(accts))
+	for i, ta := range accts {
+		txs = append(txs, newTx(t, ta.nonce, defaultAmount, uint64(i+1), ta.signer))
+	}
+	for _, tx := range txs {
+		tc.maxBytes += uint64(len(tx.Raw))
+	}
+	tc.maxBytes--
+	for _, tx := range txs {
+		require.NoError(t, tc.Add(context.Background(), tc.db, tx, now, false))
+	}
+	checkNoTX(t, tc.Cache, txs[0].ID)
+	checkTX(t, tc.Cache, txs[1].ID, 0, types.EmptyBlockID)
+	checkTX(t, tc.Cache, txs[2].ID, 0, types.EmptyBlockID)
+	require.LessOrEqual(t, tc.usage.bytes, tc.maxBytes)
+}
+
+func TestCache_Evict_StableMemory(t *testing.T) {
+	if testing.Short() {
+		t.Skip("long test")
+	}
+	const (
+		numAccounts = 1000
+		maxTXs      = 1000
+	)
+	tc, accounts := createCache(t, numAccounts)
+	tc.Cache.logger = log.NewNop()
+	tc.maxTXs = maxTXs
+	accts := acctList(accounts)
+	heap := func() uint64 {
+		runtime.GC()
+		var stats runtime.MemStats
+		runtime.ReadMemStats(&stats)
+		return stats.HeapAlloc
+	}
+
+	var filled uint64
+	now := time.Now()
+	for i := 0; i < maxTXsPerAcct; i++ {
+		for j, ta := range accts {
+			tx := newTx(t, ta.nonce+uint64(i), defaultAmount, uint64(rand.Intn(50)+1), ta.signer)
+			err := tc.Add(context.Background(), tc.db, tx, now.Add(time.Duration(i*numAccounts+j)), false)
+			if err != nil {
+				require.ErrorIs(t, err, errMempoolFull)
+			}
+			require.LessOrEqual(t, len(tc.cachedTXs), maxTXs)
+		}
+		if i == 9 {
+			filled = heap()
+		}
+	}
+	require.Equal(t, maxTXs, len(tc.cachedTXs))
+	require.LessOrEqual(t, len(tc.pending), numAccounts)
+	// 100k txs were submitted, the memory is bounded by the cap
+	require.Less(t, heap(), 2*filled)
+}
+
+func TestCache_AccountPendingState(t *testing.T) {
+	tc, ta := createSingleAccountTestCache(t)
+	nonce, balance, pending := tc.AccountPendingState(ta.principal)
+	require.Equal(t, ta.nonce, nonce)
+	require.Equal(t, ta.balance, balance)
+	require.Empty(t, pending)
+
+	mtxs := genTXs(t, ta.signer, ta.nonce, ta.nonce+4, time.Now())
+	for i, mtx := range mtxs {
+		if i == 2 {
+			continue
+		}
+		require.NoError(t, tc.Add(context.Background(), tc.db, &mtx.Transaction, mtx.Received, false))
+	}
+	replacement := &types.MeshTransaction{
+		Transaction: *newTx(t, ta.nonce+1, defaultAmount, defaultFee*2, ta.signer),
+		Received:    time.Now(),
+	}
+	require.NoError(t, tc.Add(context.Background(), tc.db, &replacement.Transaction, replacement.Received, false))
+
+	// parked txs are pending, but don't change the projection
+	nonce, balance, pending = tc.AccountPendingState(ta.principal)
+	require.Equal(t, ta.nonce+2, nonce)
+	require.Equal(t, ta.balance-mtxs[0].Spending()-replacement.Spending(), balance)
+	require.Equal(t, []types.TransactionID{mtxs[0].ID, replacement.ID, mtxs[3].ID, mtxs[4].ID}, pending)
+
+	// the missing nonce makes parked txs ready
+	require.NoError(t, tc.Add(context.Background(), tc.db, &mtxs[2].Transaction, mtxs[2].Received, false))
+	expected := []*types.MeshTransaction{mtxs[0], replacement, mtxs[2], mtxs[3], mtxs[4]}
+	projected := ta.balance
+	for _, mtx := range expected {
+		projected -= mtx.Spending()
+	}
+	nonce, balance, pending = tc.AccountPendingState(ta.principal)
+	require.Equal(t, ta.nonce+5, nonce)
+	require.Equal(t, projected, balance)
+	require.Len(t, pending, len(expected))
+	for i, mtx := range expected {
+		require.Equal(t, mtx.ID, pending[i])
+	}
+
+	// the first tx is applied
+	lid := types.LayerID(1)
+	bid := types.RandomBlockID()
+	ta.nonce++
+	ta.balance -= mtxs[0].Spending()
+	require.NoError(t, tc.ApplyLayer(context.Background(), tc.db, lid, bid, makeResults(lid, bid, mtxs[0].Transaction), nil))
+	require.NoError(t, layers.SetApplied(tc.db, lid, bid))
+	nonce, balance, pending = tc.AccountPendingState(ta.principal)
+	require.Equal(t, ta.nonce+4, nonce)
+	require.Equal(t, projected, balance)
+	require.Len(t, pending, len(expected)-1)
+
+	// the layer is reverted, the first tx is pending again
+	ta.nonce--
+	ta.balance += mtxs[0].Spending()
+	require.NoError(t, layers.UnsetAppliedFrom(tc.db, lid))
+	require.NoError(t, tc.RevertToLayer(tc.db, lid.Sub(1)))
+	nonce, balance, pending = tc.AccountPendingState(ta.principal)
+	require.Equal(t, ta.nonce+5, nonce)
+	require.Equal(t, projected, balance)
+	require.Len(t, pending, len(expected))
+	for i, mtx := range expected {
+		require.Equal(t, mtx.ID, pending[i])
+	}
+}
+
+func TestCache_Expiry(t *testing.T) {
+	events.InitializeReporter()
+	t.Cleanup(events.CloseEventReporter)
+	expiredSub, err := events.Subscribe[events.TxExpired](events.WithBuffer(10))
+	require.NoError(t, err)
+
+	tc, ta := createSingleAccountTestCache(t)
+	lid := types.LayerID(1)
+	expiring := newTx(t, ta.nonce, defaultAmount, defaultFee, ta.signer)
+	expiring.LayerLimits.Max = lid.Add(1).Uint32()
+	next := newTx(t, ta.nonce+1, defaultAmount, defaultFee, ta.signer)
+	for _, tx := range []*types.Transaction{expiring, next} {
+		require.NoError(t, tc.Add(context.Background(), tc.db, tx, time.Now(), false))
+	}
+	checkProjection(t, tc.Cache, ta.principal, ta.nonce+2, ta.balance-expiring.Spending()-next.Spending())
+
+	// the tx is applied before its expiry
+	bid := types.RandomBlockID()
+	require.NoError(t, tc.ApplyLayer(context.Background(), tc.db, lid, bid, makeResults(lid, bid, *expiring), nil))
+	require.NoError(t, layers.SetApplied(tc.db, lid, bid))
+	checkNoTX(t, tc.Cache, expiring.ID)
+
+	// the reorg reverts the layer, the tx is pending again until the applied layer moves past its expiry
+	require.NoError(t, layers.UnsetAppliedFrom(tc.db, lid))
+	require.NoError(t, tc.RevertToLayer(tc.db, lid.Sub(1)))
+	checkTX(t, tc.Cache, expiring.ID, 0, types.EmptyBlockID)
+	require.NoError(t, tc.ApplyLayer(context.Background(), tc.db, lid, types.EmptyBlockID, nil, nil))
+	require.NoError(t, layers.SetApplied(tc.db, lid, types.EmptyBlockID))
+	checkTX(t, tc.Cache, expiring.ID, 0, types.EmptyBlockID)
+
+	lid = lid.Add(1)
+	bid = types.RandomBlockID()
+	require.NoError(t, tc.ApplyLayer(context.Background(), tc.db, lid, bid, nil, nil))
+	require.NoError(t, layers.SetApplied(tc.db, lid, bid))
+	checkNoTX(t, tc.Cache, expiring.ID)
+	checkInMempoolDB(t, tc.db, expiring.ID, false)
+	select {
+	case ev := <-expiredSub.Out():
+		require.Equal(t, events.TxExpired{
+			ID:        expiring.ID,
+			Principal: ta.principal,
+			Nonce:     ta.nonce,
+			Expiry:    lid,
+		}, ev)
+	case <-time.After(time.Second):
+		require.FailNow(t, "expiry is not reported")
+	}
+	// the balance reserved by the expired tx is released, the next tx is parked after the nonce gap
+	checkProjection(t, tc.Cache, ta.principal, ta.nonce+2, ta.balance-next.Spending())
+	nonce, balance, pending := tc.AccountPendingState(ta.principal)
+	require.Equal(t, ta.nonce, nonce)
+	require.Equal(t, ta.balance, balance)
+	require.Equal(t, []types.TransactionID{next.ID}, pending)
+
+	// txs that expire before the next layer are not admitted
+	late := newTx(t, ta.nonce, defaultAmount, defaultFee, ta.signer)
+	late.LayerLimits.Max = lid.Uint32()
+	require.ErrorIs(t, tc.Add(context.Background(), tc.db, late, time.Now(), false), errExpired)
+	checkNoTX(t, tc.Cache, late.ID)
+	replacement := newTx(t, ta.nonce, defaultAmount, defaultFee, ta.signer)
+	replacement.LayerLimits.Max = lid.Add(1).Uint32()
+	require.NoError(t, tc.Add(context.Background(), tc.db, replacement, time.Now(), false))
+	checkMempool(t, tc.Cache, map[types.Address][]*types.MeshTransaction{
+		ta.principal: {{Transaction: *replacement}, {Transaction: *next}},
+	})
+}
+
+func sampleCount(tb testing.TB, o prometheus.Observer) uint64 {
+	tb.Helper()
+	var m dto.Metric
+	require.NoError(tb, o.(prometheus.Metric).Write(&m))
+	return m.GetHistogram().GetSampleCount()
+}
+
+func TestCache_MempoolMetricsAndEvents(t *testing.T) {
+	const (
+		numAccounts = 100
+		numTXs      = 1000
+	)
+	events.InitializeReporter()
+	t.Cleanup(events.CloseEventReporter)
+	admittedSub, err := events.Subscribe[events.TxAdmitted](events.WithBuffer(numTXs + 1))
+	require.NoError(t, err)
+	proposalSub, err := events.Subscribe[events.TxIncludedInProposal](events.WithBuffer(numTXs))
+	require.NoError(t, err)
+	blockSub, err := events.Subscribe[events.TxIncludedInBlock](events.WithBuffer(numTXs))
+	require.NoError(t, err)
+
+	tc, accounts := createCache(t, numAccounts)
+	var (
+		admitted      = testutil.ToFloat64(mempoolTxCount.WithLabelValues(accepted))
+		proposalCount = sampleCount(t, inclusionAge.WithLabelValues(inProposal))
+		blockCount    = sampleCount(t, inclusionAge.WithLabelValues(inBlock))
+		received      = time.Now().Add(-time.Minute)
+		txs           = make([]*types.Transaction, 0, numTXs)
+	)
+	for _, ta := range accounts {
+		for i := uint64(0); i < numTXs/numAccounts; i++ {
+			tx := newTx(t, ta.nonce+i, defaultAmount, defaultFee, ta.signer)
+			require.NoError(t, tc.Add(context.Background(), tc.db, tx, received, false))
+			txs = append(txs, tx)
+		}
+	}
+	require.Equal(t, admitted+numTXs, testutil.ToFloat64(mempoolTxCount.WithLabelValues(accepted)))
+	require.Equal(t, float64(numTXs), testutil.ToFloat64(mempoolSize.WithLabelValues(sizeTXs)))
+	require.Equal(t, float64(numTXs), testutil.ToFloat64(mempoolTxsByState.WithLabelValues(stateReady)))
+	require.Zero(t, testutil.ToFloat64(mempoolTxsByState.WithLabelValues(stateParked)))
+
+	// tx after a nonce gap is parked
+	ta := acctList(accounts)[0]
+	parked := newTx(t, ta.nonce+numTXs/numAccounts+1, defaultAmount, defaultFee, ta.signer)
+	require.NoError(t, tc.Add(context.Background(), tc.db, parked, received, false))
+	require.Equal(t, float64(numTXs+1), testutil.ToFloat64(mempoolSize.WithLabelValues(sizeTXs)))
+	require.Equal(t, float64(numTXs), testutil.ToFloat64(mempoolTxsByState.WithLabelValues(stateReady)))
+	require.Equal(t, 1.0, testutil.ToFloat64(mempoolTxsByState.WithLabelValues(stateParked)))
+
+	tids := make([]types.TransactionID, 0, numTXs)
+	for _, tx := range txs {
+		tids = append(tids, tx.ID)
+	}
+	lid := types.LayerID(10)
+	pid := types.ProposalID{1}
+	bid := types.BlockID{1}
+	require.NoError(t, tc.LinkTXsWithProposal(tc.db, lid, pid, tids))
+	require.NoError(t, tc.LinkTXsWithBlock(tc.db, lid, bid, tids))
+	require.Equal(t, proposalCount+numTXs, sampleCount(t, inclusionAge.WithLabelValues(inProposal)))
+	require.Equal(t, blockCount+numTXs, sampleCount(t, inclusionAge.WithLabelValues(inBlock)))
+
+	for _, tx := range append(txs, parked) {
+		select {
+		case ev := <-admittedSub.Out():
+			require.Equal(t, events.TxAdmitted{
+				ID:        tx.ID,
+				Principal: tx.Principal,
+				Nonce:     tx.Nonce,
+				GasPrice:  tx.GasPrice,
+			}, ev)
+		case <-time.After(time.Second):
+			require.FailNow(t, "admission is not reported")
+		}
+	}
+	for _, tid := range tids {
+		select {
+		case ev := <-proposalSub.Out():
+			require.Equal(t, events.TxIncludedInProposal{ID: tid, Layer: lid, Proposal: pid}, ev)
+		case <-time.After(time.Second):
+			require.FailNow(t, "inclusion in proposal is not reported")
+		}
+	}
+	for _, tid := range tids {
+		select {
+		case ev := <-blockSub.Out():
+			require.Equal(t, events.TxIncludedInBlock{ID: tid, Layer: lid, Block: bid}, ev)
+		case <-time.After(time.Second):
+			require.FailNow(t, "inclusion in block is not reported")
+		}
+	}
+}
@@ -2,6 +2,7 @@ package txs
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"math"
 	"math/rand"
@@ -10,6 +11,7 @@ import (
 	"github.com/spacemeshos/go-spacemesh/common/types"
 	"github.com/spacemeshos/go-spacemesh/events"
 	"github.com/spacemeshos/go-spacemesh/log"
+	"github.com/spacemeshos/go-spacemesh/p2p/pubsub"
 	"github.com/spacemeshos/go-spacemesh/sql"
 	"github.com/spacemeshos/go-spacemesh/sql/layers"
 	"github.com/spacemeshos/go-spacemesh/sql/transactions"
@@ -20,12 +22,36 @@ import (
 type CSConfig struct {
 	BlockGasLimit     uint64
 	NumTXsPerProposal int
+
+	// Selection is the policy used to select transactions for proposals.
+	Selection SelectionPolicy
+	// TXsSizePerProposal is the max total size in bytes of the transactions selected per proposal.
+	// Zero means no limit.
+	TXsSizePerProposal uint64
+	// ReplaceFeeBump is the min fee increase in percent for a transaction to replace the transaction
+	// with the same principal and nonce in the mempool.
+	ReplaceFeeBump uint64
+	// MempoolMaxAge is the max number of layers since a transaction was admitted to the mempool
+	// for it to be reloaded after restart. 0 reloads all transactions.
+	MempoolMaxAge uint32
+	// MempoolMaxTXs and MempoolMaxBytes limit the number and the total size of transactions in the mempool.
+	// When the mempool is full, the transactions with the lowest fee density are evicted. 0 is unlimited.
+	MempoolMaxTXs   int
+	MempoolMaxBytes uint64
+	// LocalLane is the percent of the proposal budget filled with the transactions submitted via
+	// the api of this node before the rest of the mempool is considered. 0 disables the local lane.
+	LocalLane uint64
 }
 
 func defaultCSConfig() CSConfig {
 	return CSConfig{
 		BlockGasLimit:     math.MaxUint64,
 		NumTXsPerProposal: 100,
+		Selection:         SelectFIFO,
+		ReplaceFeeBump:    10,
+		MempoolMaxAge:     1000,
+		MempoolMaxTXs:     100_000,
+		MempoolMaxBytes:   64 << 20,
 	}
 }
 
@@ -46,15 +72,24 @@ func WithLogger(logger log.Log) ConservativeStateOpt {
 	}
 }
 
+// WithPublisher defines the publisher used to gossip again the local transactions re-admitted
+// to the mempool after a reorg.
+func WithPublisher(p pubsub.Publisher) ConservativeStateOpt {
+	return func(cs *ConservativeState) {
+		cs.publisher = p
+	}
+}
+
 // ConservativeState provides the conservative version of the VM state by taking into accounts of
 // nonce and balances for pending transactions in un-applied blocks and mempool.
 type ConservativeState struct {
 	vmState
 
-	logger log.Log
-	cfg    CSConfig
-	db     *sql.Database
-	cache  *Cache
+	logger    log.Log
+	cfg       CSConfig
+	db        *sql.Database
+	cache     *Cache
+	publisher pubsub.Publisher
 }
 
 // NewConservativeState returns a ConservativeState.
@@ -69,9 +104,17 @@ func NewConservativeState(state vmState, db *sql.Database, opts ...ConservativeS
 		opt(cs)
 	}
 	cs.cache = NewCache(cs.getState, cs.logger)
+	cs.cache.feeBump = cs.cfg.ReplaceFeeBump
+	cs.cache.maxTXs = cs.cfg.MempoolMaxTXs
+	cs.cache.maxBytes = cs.cfg.MempoolMaxBytes
 	return cs
 }
 
+// Restore reloads the transactions that were in the mempool before the node restarted.
+func (cs *ConservativeState) Restore() error {
+	return cs.cache.Restore(cs.db, cs.cfg.MempoolMaxAge)
+}
+
 func (cs *ConservativeState) getState(addr types.Address) (uint64, uint64) {
 	nonce, err := cs.vmState.GetNonce(addr)
 	if err != nil {
@@ -84,13 +127,39 @@ func (cs *ConservativeState) getState(addr types.Address) (uint64, uint64) {
 	return nonce, balance
 }
 
-// SelectProposalTXs picks a specific number of random txs for miner to pack in a proposal.
+// SelectProposalTXs picks txs for miner to pack in a proposal according to the selection policy.
+// The number and the total size of the txs are limited per eligibility.
 func (cs *ConservativeState) SelectProposalTXs(lid types.LayerID, numEligibility int) []types.TransactionID {
 	logger := cs.logger.WithFields(lid)
-	mi := newMempoolIterator(logger, cs.cache, cs.cfg.BlockGasLimit)
+	budget := newSelectionBudget(
+		numEligibility*cs.cfg.NumTXsPerProposal,
+		uint64(numEligibility)*cs.cfg.TXsSizePerProposal,
+		cs.cfg.BlockGasLimit,
+	)
+	// txs that expire before the layer are never selected
+	cache := unexpiredMempool{cache: cs.cache, lid: lid}
+	var local []types.TransactionID
+	if cs.cfg.LocalLane > 0 {
+		mempool := cache.GetMempool(logger)
+		local = selectLocal(logger, mempool, &budget, cs.cfg.LocalLane)
+		if cs.cfg.Selection == SelectFee {
+			return append(local, selectByFee(logger, mempool, budget)...)
+		}
+	} else if cs.cfg.Selection == SelectFee {
+		return selectByFee(logger, cache.GetMempool(logger), budget)
+	}
+	mi := newMempoolIterator(logger, cache, cs.cfg.BlockGasLimit)
 	predictedBlock, byAddrAndNonce := mi.PopAll()
-	numTXs := numEligibility * cs.cfg.NumTXsPerProposal
-	return getProposalTXs(logger.WithFields(lid), numTXs, predictedBlock, byAddrAndNonce)
+	selected := getProposalTXs(logger.WithFields(lid), budget.count+len(local), predictedBlock, byAddrAndNonce)
+	selected = withoutSelected(selected, local, budget.count)
+	if cs.cfg.TXsSizePerProposal == 0 {
+		return append(local, selected...)
+	}
+	sizes := make(map[types.TransactionID]uint64, len(predictedBlock))
+	for _, ntx := range predictedBlock {
+		sizes[ntx.ID] = ntx.Size
+	}
+	return append(local, truncateToSize(selected, sizes, budget)...)
 }
 
 func getProposalTXs(logger log.Log, numTXs int, predictedBlock []*NanoTX, byAddrAndNonce map[types.Address][]*NanoTX) []types.TransactionID {
@@ -121,6 +190,16 @@ func (cs *ConservativeState) AddToCache(ctx context.Context, tx *types.Transacti
 	return nil
 }
 
+// AddLocalToCache adds the transaction submitted via the api of this node to the conservative cache.
+func (cs *ConservativeState) AddLocalToCache(ctx context.Context, tx *types.Transaction, received time.Time) error {
+	if err := cs.cache.AddLocal(ctx, cs.db, tx, received); err != nil {
+		return err
+	}
+	events.ReportNewTx(0, tx)
+	events.ReportAccountUpdate(tx.Principal)
+	return nil
+}
+
 // RevertCache reverts the conservative cache to the given layer.
 func (cs *ConservativeState) RevertCache(revertTo types.LayerID) error {
 	return cs.cache.RevertToLayer(cs.db, revertTo)
@@ -138,15 +217,52 @@ func (cs *ConservativeState) UpdateCache(
 		return err
 	}
 	cacheApplyDuration.Observe(float64(time.Since(t0)))
+	cs.rebroadcast(ctx)
 	return nil
 }
 
+// rebroadcast gossips again the local transactions that were re-admitted to the mempool after a reorg.
+func (cs *ConservativeState) rebroadcast(ctx context.Context) {
+	tids := cs.cache.takeRebroadcast()
+	if cs.publisher == nil {
+		return
+	}
+	for _, tid := range tids {
+		mtx, err := transactions.Get(cs.db, tid)
+		if err != nil {
+			cs.logger.WithContext(ctx).With().Error("failed to get tx to rebroadcast", tid, log.Err(err))
+			continue
+		}
+		if err := cs.publisher.Publish(ctx, pubsub.TxProtocol, mtx.Raw); err != nil {
+			cs.logger.WithContext(ctx).With().Warning("failed to rebroadcast tx", tid, log.Err(err))
+			continue
+		}
+		cs.logger.WithContext(ctx).With().Debug("rebroadcast orphaned tx", tid)
+	}
+}
+
 // GetProjection returns the projected nonce and balance for an account, including
 // pending transactions that are paced in proposals/blocks but not yet applied to the state.
 func (cs *ConservativeState) GetProjection(addr types.Address) (uint64, uint64) {
 	return cs.cache.GetProjection(addr)
 }
 
+// GetPending returns the transactions of the account in the mempool, ordered by nonce.
+func (cs *ConservativeState) GetPending(addr types.Address) []PendingTX {
+	return cs.cache.GetPending(addr)
+}
+
+// Projection returns the state of the account projected over its transactions in the mempool.
+func (cs *ConservativeState) Projection(addr types.Address) Projection {
+	return cs.cache.Projection(addr)
+}
+
+// AccountPendingState returns the next nonce and the balance of the account projected over
+// its ready transactions in the mempool, and the ids of all its pending transactions.
+func (cs *ConservativeState) AccountPendingState(addr types.Address) (uint64, uint64, []types.TransactionID) {
+	return cs.cache.AccountPendingState(addr)
+}
+
 // LinkTXsWithProposal associates the transactions to a proposal.
 func (cs *ConservativeState) LinkTXsWithProposal(lid types.LayerID, pid types.ProposalID, tids []types.TransactionID) error {
 	return cs.cache.LinkTXsWithProposal(cs.db, lid, pid, tids)
@@ -181,27 +297,85 @@ func (cs *ConservativeState) GetMeshHash(lid types.LayerID) (types.Hash32, error
 }
 
 // GetMeshTransaction retrieves a tx by its id.
+// If the tx is not applied yet, but included in a proposal or a block, the state, layer and block
+// of the tx refer to the earliest of them.
 func (cs *ConservativeState) GetMeshTransaction(tid types.TransactionID) (*types.MeshTransaction, error) {
-	return transactions.Get(cs.db, tid)
+	mtx, err := transactions.Get(cs.db, tid)
+	if err != nil || mtx.State != types.MEMPOOL {
+		return mtx, err
+	}
+	state, err := transactions.GetState(cs.db, tid)
+	if errors.Is(err, sql.ErrNotFound) {
+		return mtx, nil
+	} else if err != nil {
+		return nil, err
+	}
+	if state.State == types.PROPOSAL || state.State == types.BLOCK {
+		mtx.State = state.State
+		mtx.LayerID = state.Layer
+		mtx.BlockID = state.Block
+	}
+	return mtx, nil
 }
 
 // GetMeshTransactions retrieves a list of txs by their id's.
 func (cs *ConservativeState) GetMeshTransactions(ids []types.TransactionID) ([]*types.MeshTransaction, map[types.TransactionID]struct{}) {
 	missing := make(map[types.TransactionID]struct{})
-	mtxs := make([]*types.MeshTransaction, 0, len(ids))
+	mtxs, notFound, err := cs.GetBatch(ids)
+	if err != nil {
+		cs.logger.With().Warning("could not get txs", log.Int("num_txs", len(ids)), log.Err(err))
+		notFound = ids
+	}
+	for _, tid := range notFound {
+		missing[tid] = struct{}{}
+	}
+	return mtxs, missing
+}
+
+// GetBatch retrieves txs by their ids and reports the ids that are not available locally.
+// The txs in the mempool that are included in proposals or blocks are reported with the state,
+// layer and block of the earliest of them.
+func (cs *ConservativeState) GetBatch(ids []types.TransactionID) ([]*types.MeshTransaction, []types.TransactionID, error) {
+	mtxs, missing, err := GetBatch(cs.db, ids)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, mtx := range mtxs {
+		if mtx.State != types.MEMPOOL {
+			continue
+		}
+		ntx := cs.cache.Get(mtx.ID)
+		if ntx == nil || ntx.Layer == 0 {
+			continue
+		}
+		mtx.State = types.PROPOSAL
+		if ntx.Block != types.EmptyBlockID {
+			mtx.State = types.BLOCK
+		}
+		mtx.LayerID = ntx.Layer
+		mtx.BlockID = ntx.Block
+	}
+	return mtxs, missing, nil
+}
+
+// GetBatch retrieves txs from the database in batches instead of one query per tx.
+// Duplicate ids are looked up once, found txs are returned in the order of ids and
+// the ids that are not in the database are returned as missing.
+func GetBatch(db sql.Executor, ids []types.TransactionID) ([]*types.MeshTransaction, []types.TransactionID, error) {
+	unique := make([]types.TransactionID, 0, len(ids))
+	seen := make(map[types.TransactionID]struct{}, len(ids))
 	for _, tid := range ids {
-		var (
-			mtx *types.MeshTransaction
-			err error
-		)
-		if mtx, err = transactions.Get(cs.db, tid); err != nil {
-			cs.logger.With().Warning("could not get tx", tid, log.Err(err))
-			missing[tid] = struct{}{}
-		} else {
-			mtxs = append(mtxs, mtx)
+		if _, ok := seen[tid]; ok {
+			continue
 		}
+		seen[tid] = struct{}{}
+		unique = append(unique, tid)
 	}
-	return mtxs, missing
+	mtxs, missing, err := transactions.GetBatch(db, unique)
+	if err != nil {
+		return nil, nil, fmt.Errorf("get txs: %w", err)
+	}
+	return mtxs, missing, nil
 }
 
 // GetTransactionsByAddress retrieves txs for a single address in between layers [from, to].
@@ -12,15 +12,19 @@ import (
 	"github.com/golang/mock/gomock"
 	"github.com/libp2p/go-libp2p/core/crypto"
 	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/require"
 	"golang.org/x/exp/maps"
 
 	"github.com/spacemeshos/go-spacemesh/common/types"
+	"github.com/spacemeshos/go-spacemesh/events"
 	"github.com/spacemeshos/go-spacemesh/genvm/sdk"
 	"github.com/spacemeshos/go-spacemesh/genvm/sdk/wallet"
 	"github.com/spacemeshos/go-spacemesh/log"
 	"github.com/spacemeshos/go-spacemesh/log/logtest"
 	"github.com/spacemeshos/go-spacemesh/p2p"
+	"github.com/spacemeshos/go-spacemesh/p2p/pubsub"
+	pubsubmocks "github.com/spacemeshos/go-spacemesh/p2p/pubsub/mocks"
 	"github.com/spacemeshos/go-spacemesh/signing"
 	"github.com/spacemeshos/go-spacemesh/sql"
 	"github.com/spacemeshos/go-spacemesh/sql/layers"
@@ -117,7 +121,7 @@ func addBatch(tb testing.TB, tcs *testConState, numTXs int) ([]types.Transaction
 		addr := types.GenerateAddress(signer.PublicKey().Bytes())
 		tcs.mvm.EXPECT().GetBalance(addr).Return(defaultBalance, nil).Times(1)
 		tcs.mvm.EXPECT().GetNonce(addr).Return(nonce, nil).Times(1)
-		tx := newTx(tb, nonce+5, defaultAmount, defaultFee, signer)
+		tx := newTx(tb, nonce, defaultAmount, defaultFee, signer)
 		require.NoError(tb, tcs.AddToCache(context.Background(), tx, time.Now()))
 		ids = append(ids, tx.ID)
 		txs = append(txs, tx)
@@ -135,12 +139,12 @@ func TestSelectProposalTXs(t *testing.T) {
 		require.NoError(t, err)
 		addr := types.GenerateAddress(signer.PublicKey().Bytes())
 		tcs.mvm.EXPECT().GetBalance(addr).Return(defaultBalance, nil).Times(1)
-		tcs.mvm.EXPECT().GetNonce(addr).Return(uint64(1), nil).Times(1)
+		tcs.mvm.EXPECT().GetNonce(addr).Return(uint64(4), nil).Times(1)
 		tx1 := newTx(t, 4, defaultAmount, defaultFee, signer)
 		require.NoError(t, tcs.AddToCache(context.Background(), tx1, time.Now()))
-		// all the TXs with nonce 1 are pending in database
+		// all the TXs with nonce 4 are pending in database
 		require.NoError(t, tcs.LinkTXsWithBlock(lid, bid, []types.TransactionID{tx1.ID}))
-		tx2 := newTx(t, 6, defaultAmount, defaultFee, signer)
+		tx2 := newTx(t, 5, defaultAmount, defaultFee, signer)
 		require.NoError(t, tcs.AddToCache(context.Background(), tx2, time.Now()))
 	}
 
@@ -327,6 +331,133 @@ func TestSelectProposalTXs_TwoPrincipals(t *testing.T) {
 	}
 }
 
+func TestSelectProposalTXs_ByFee(t *testing.T) {
+	tcs := createConservativeState(t)
+	tcs.cfg.Selection = SelectFee
+	lid := types.LayerID(97)
+	signers := make([]*signing.EdSigner, 2)
+	for i := range signers {
+		signer, err := signing.NewEdSigner()
+		require.NoError(t, err)
+		signers[i] = signer
+		addr := types.GenerateAddress(signer.PublicKey().Bytes())
+		tcs.mvm.EXPECT().GetBalance(addr).Return(defaultBalance, nil).Times(1)
+		tcs.mvm.EXPECT().GetNonce(addr).Return(uint64(0), nil).Times(1)
+	}
+	// the middle tx of the first principal has a low fee and is paid for by the following tx
+	var chain []*types.Transaction
+	for i, fee := range []uint64{10, 1, 30} {
+		tx := newTx(t, uint64(i), defaultAmount, fee, signers[0])
+		require.NoError(t, tcs.AddToCache(context.Background(), tx, time.Now()))
+		chain = append(chain, tx)
+	}
+	var other []*types.Transaction
+	for i := 0; i < 3; i++ {
+		tx := newTx(t, uint64(i), defaultAmount, 9, signers[1])
+		require.NoError(t, tcs.AddToCache(context.Background(), tx, time.Now()))
+		other = append(other, tx)
+	}
+
+	tcs.cfg.NumTXsPerProposal = 4
+	require.Equal(t, []types.TransactionID{chain[0].ID, chain[1].ID, chain[2].ID, other[0].ID}, tcs.SelectProposalTXs(lid, 1))
+	tcs.cfg.NumTXsPerProposal = 2
+	require.Equal(t, []types.TransactionID{chain[0].ID, other[0].ID}, tcs.SelectProposalTXs(lid, 1))
+	// the budget is per eligibility
+	require.Len(t, tcs.SelectProposalTXs(lid, 3), 6)
+
+	tcs.cfg.NumTXsPerProposal = 10
+	tcs.cfg.TXsSizePerProposal = uint64(len(chain[0].Raw))
+	require.Equal(t, []types.TransactionID{chain[0].ID}, tcs.SelectProposalTXs(lid, 1))
+}
+
+func TestSelectProposalTXs_SizeLimit(t *testing.T) {
+	tcs := createConservativeState(t)
+	ids, txs := addBatch(t, tcs, numTXsInProposal)
+	tcs.cfg.TXsSizePerProposal = uint64(3 * len(txs[0].Raw))
+	got := tcs.SelectProposalTXs(types.LayerID(97), 1)
+	require.Len(t, got, 3)
+	require.Subset(t, ids, got)
+}
+
+func TestSelectProposalTXs_NonceGap(t *testing.T) {
+	lid := types.LayerID(97)
+	for _, tc := range []struct {
+		desc    string
+		nonces  []uint64
+		missing uint64
+		before  int
+	}{
+		{
+			desc:    "gap at the head",
+			nonces:  []uint64{6, 7},
+			missing: 5,
+		},
+		{
+			desc:    "gap in the middle",
+			nonces:  []uint64{5, 7, 8},
+			missing: 6,
+			before:  1,
+		},
+	} {
+		tc := tc
+		t.Run(tc.desc, func(t *testing.T) {
+			tcs := createConservativeState(t)
+			signer, err := signing.NewEdSigner()
+			require.NoError(t, err)
+			addr := types.GenerateAddress(signer.PublicKey().Bytes())
+			tcs.mvm.EXPECT().GetBalance(addr).Return(defaultBalance, nil).Times(1)
+			tcs.mvm.EXPECT().GetNonce(addr).Return(uint64(5), nil).Times(1)
+			byNonce := map[uint64]*types.Transaction{}
+			for _, n := range tc.nonces {
+				tx := newTx(t, n, defaultAmount, defaultFee, signer)
+				require.NoError(t, tcs.AddToCache(context.Background(), tx, time.Now()))
+				byNonce[n] = tx
+			}
+			expected := []types.TransactionID{}
+			for n := uint64(5); n < tc.missing; n++ {
+				expected = append(expected, byNonce[n].ID)
+			}
+			require.Len(t, expected, tc.before)
+			require.ElementsMatch(t, expected, tcs.SelectProposalTXs(lid, 1))
+
+			// parked txs become selectable once the missing nonce arrives
+			tx := newTx(t, tc.missing, defaultAmount, defaultFee, signer)
+			require.NoError(t, tcs.AddToCache(context.Background(), tx, time.Now()))
+			byNonce[tc.missing] = tx
+			expected = expected[:0]
+			for n := uint64(5); n < uint64(5+len(byNonce)); n++ {
+				expected = append(expected, byNonce[n].ID)
+			}
+			require.Equal(t, expected, tcs.SelectProposalTXs(lid, 1))
+		})
+	}
+}
+
+func TestSelectProposalTXs_SamePrincipalTwoProposals(t *testing.T) {
+	tcs := createConservativeState(t)
+	tcs.cfg.NumTXsPerProposal = 2
+	signer, err := signing.NewEdSigner()
+	require.NoError(t, err)
+	addr := types.GenerateAddress(signer.PublicKey().Bytes())
+	tcs.mvm.EXPECT().GetBalance(addr).Return(defaultBalance, nil).Times(1)
+	tcs.mvm.EXPECT().GetNonce(addr).Return(uint64(0), nil).Times(1)
+	var all []types.TransactionID
+	for _, n := range []uint64{0, 1, 2, 3, 5} {
+		tx := newTx(t, n, defaultAmount, defaultFee, signer)
+		require.NoError(t, tcs.AddToCache(context.Background(), tx, time.Now()))
+		all = append(all, tx.ID)
+	}
+	lid := types.LayerID(97)
+	first := tcs.SelectProposalTXs(lid, 1)
+	require.Equal(t, all[:2], first)
+	require.NoError(t, tcs.LinkTXsWithProposal(lid, types.ProposalID{1}, first))
+
+	// the second proposal in the same layer continues from the next nonce and stops at the gap
+	tcs.cfg.NumTXsPerProposal = 3
+	second := tcs.SelectProposalTXs(lid, 1)
+	require.Equal(t, all[2:4], second)
+}
+
 func TestGetProjection(t *testing.T) {
 	tcs := createConservativeState(t)
 	signer, err := signing.NewEdSigner()
@@ -442,13 +573,221 @@ func TestGetMeshTransaction(t *testing.T) {
 	require.NoError(t, tcs.LinkTXsWithProposal(lid, pid, []types.TransactionID{tx.ID}))
 	mtx, err = tcs.GetMeshTransaction(tx.ID)
 	require.NoError(t, err)
-	require.Equal(t, types.MEMPOOL, mtx.State)
+	require.Equal(t, types.PROPOSAL, mtx.State)
+	require.Equal(t, lid, mtx.LayerID)
 
 	bid := types.BlockID{2, 3, 4}
 	require.NoError(t, tcs.LinkTXsWithBlock(lid, bid, []types.TransactionID{tx.ID}))
 	mtx, err = tcs.GetMeshTransaction(tx.ID)
 	require.NoError(t, err)
+	require.Equal(t, types.BLOCK, mtx.State)
+	require.Equal(t, lid, mtx.LayerID)
+	require.Equal(t, bid, mtx.BlockID)
+
+	// included in a proposal of the later layer
+	require.NoError(t, tcs.LinkTXsWithProposal(lid.Add(1), types.ProposalID{3, 4, 5}, []types.TransactionID{tx.ID}))
+	mtx, err = tcs.GetMeshTransaction(tx.ID)
+	require.NoError(t, err)
+	require.Equal(t, types.BLOCK, mtx.State)
+	require.Equal(t, lid, mtx.LayerID)
+}
+
+func TestGetBatch(t *testing.T) {
+	tcs := createConservativeState(t)
+	ids, txs := addBatch(t, tcs, numTXs)
+	lid := types.LayerID(10)
+	bid := types.BlockID{1, 2, 3}
+	require.NoError(t, tcs.LinkTXsWithProposal(lid, types.ProposalID{1}, ids[:2]))
+	require.NoError(t, tcs.LinkTXsWithBlock(lid, bid, ids[2:3]))
+
+	unknown := types.RandomTransactionID()
+	query := append([]types.TransactionID{unknown}, ids...)
+	query = append(query, ids[0])
+	mtxs, missing, err := tcs.GetBatch(query)
+	require.NoError(t, err)
+	require.Equal(t, []types.TransactionID{unknown}, missing)
+	require.Len(t, mtxs, len(txs))
+	for i, mtx := range mtxs {
+		require.Equal(t, txs[i].ID, mtx.ID)
+		switch {
+		case i < 2:
+			require.Equal(t, types.PROPOSAL, mtx.State)
+			require.Equal(t, lid, mtx.LayerID)
+		case i == 2:
+			require.Equal(t, types.BLOCK, mtx.State)
+			require.Equal(t, bid, mtx.BlockID)
+		default:
+			require.Equal(t, types.MEMPOOL, mtx.State)
+		}
+	}
+}
+
+func TestTXLifecycle_Reorg(t *testing.T) {
+	events.InitializeReporter()
+	t.Cleanup(events.CloseEventReporter)
+	sub, err := events.Subscribe[events.TxState]()
+	require.NoError(t, err)
+	t.Cleanup(sub.Close)
+
+	tcs := createConservativeState(t)
+	signer, err := signing.NewEdSigner()
+	require.NoError(t, err)
+	addr := types.GenerateAddress(signer.PublicKey().Bytes())
+	tx := newTx(t, nonce, defaultAmount, defaultFee, signer)
+	applied := false
+	tcs.mvm.EXPECT().GetNonce(addr).DoAndReturn(func(types.Address) (uint64, error) {
+		if applied {
+			return nonce + 1, nil
+		}
+		return nonce, nil
+	}).AnyTimes()
+	tcs.mvm.EXPECT().GetBalance(addr).DoAndReturn(func(types.Address) (uint64, error) {
+		if applied {
+			return defaultBalance - tx.Spending(), nil
+		}
+		return defaultBalance, nil
+	}).AnyTimes()
+	require.NoError(t, tcs.AddToCache(context.Background(), tx, time.Now()))
+
+	apply := func(lid types.LayerID, bid types.BlockID, executed ...types.TransactionWithResult) {
+		t.Helper()
+		applied = len(executed) > 0
+		require.NoError(t, tcs.UpdateCache(context.Background(), lid, bid, executed, nil))
+		require.NoError(t, layers.SetApplied(tcs.db, lid, bid))
+	}
+	result := func(lid types.LayerID, bid types.BlockID) types.TransactionWithResult {
+		return types.TransactionWithResult{
+			Transaction:       *tx,
+			TransactionResult: types.TransactionResult{Layer: lid, Block: bid},
+		}
+	}
+	checkState := func(state types.TXState, lid types.LayerID, bid types.BlockID) {
+		t.Helper()
+		mtx, err := tcs.GetMeshTransaction(tx.ID)
+		require.NoError(t, err)
+		require.Equal(t, state, mtx.State)
+		require.Equal(t, lid, mtx.LayerID)
+		require.Equal(t, bid, mtx.BlockID)
+		select {
+		case ev := <-sub.Out():
+			require.Equal(t, events.TxState{ID: tx.ID, State: state, Layer: lid, Block: bid}, ev)
+		case <-time.After(time.Second):
+			require.FailNow(t, "timed out waiting for tx state")
+		}
+	}
+
+	lid := types.LayerID(1)
+	require.NoError(t, tcs.LinkTXsWithProposal(lid, types.ProposalID{1}, []types.TransactionID{tx.ID}))
+	checkState(types.PROPOSAL, lid, types.EmptyBlockID)
+	first := types.BlockID{1}
+	require.NoError(t, tcs.LinkTXsWithBlock(lid, first, []types.TransactionID{tx.ID}))
+	checkState(types.BLOCK, lid, first)
+	apply(lid, first, result(lid, first))
+	checkState(types.APPLIED, lid, first)
+
+	// the layer is reverted and applied with the block that doesn't include the tx
+	applied = false
+	require.NoError(t, tcs.RevertCache(lid.Sub(1)))
+	require.NoError(t, layers.UnsetAppliedFrom(tcs.db, lid))
+	checkState(types.MEMPOOL, 0, types.EmptyBlockID)
+	apply(lid, types.BlockID{2})
+	mtx, err := tcs.GetMeshTransaction(tx.ID)
+	require.NoError(t, err)
 	require.Equal(t, types.MEMPOOL, mtx.State)
+
+	next := lid.Add(1)
+	third := types.BlockID{3}
+	require.NoError(t, tcs.LinkTXsWithBlock(next, third, []types.TransactionID{tx.ID}))
+	checkState(types.BLOCK, next, third)
+	apply(next, third, result(next, third))
+	checkState(types.APPLIED, next, third)
+}
+
+func TestUpdateCache_ReorgReadmitsOrphans(t *testing.T) {
+	tcs := createConservativeState(t)
+	publisher := pubsubmocks.NewMockPublisher(gomock.NewController(t))
+	tcs.publisher = publisher
+
+	type account struct {
+		tx      *types.Transaction
+		applied bool
+	}
+	accounts := make([]*account, 2)
+	for i := range accounts {
+		signer, err := signing.NewEdSigner()
+		require.NoError(t, err)
+		acct := &account{tx: newTx(t, nonce, defaultAmount, defaultFee, signer)}
+		tcs.mvm.EXPECT().GetNonce(acct.tx.Principal).DoAndReturn(func(types.Address) (uint64, error) {
+			if acct.applied {
+				return nonce + 1, nil
+			}
+			return nonce, nil
+		}).AnyTimes()
+		tcs.mvm.EXPECT().GetBalance(acct.tx.Principal).DoAndReturn(func(types.Address) (uint64, error) {
+			if acct.applied {
+				return defaultBalance - acct.tx.Spending(), nil
+			}
+			return defaultBalance, nil
+		}).AnyTimes()
+		accounts[i] = acct
+	}
+	shared, orphaned := accounts[0].tx, accounts[1].tx
+	require.NoError(t, tcs.AddToCache(context.Background(), shared, time.Now()))
+	require.NoError(t, tcs.AddLocalToCache(context.Background(), orphaned, time.Now()))
+
+	apply := func(lid types.LayerID, bid types.BlockID, txs ...*types.Transaction) {
+		t.Helper()
+		var executed []types.TransactionWithResult
+		for _, tx := range txs {
+			executed = append(executed, types.TransactionWithResult{
+				Transaction:       *tx,
+				TransactionResult: types.TransactionResult{Layer: lid, Block: bid},
+			})
+		}
+		require.NoError(t, tcs.UpdateCache(context.Background(), lid, bid, executed, nil))
+		require.NoError(t, layers.SetApplied(tcs.db, lid, bid))
+	}
+	checkState := func(tid types.TransactionID, state types.TXState, bid types.BlockID) {
+		t.Helper()
+		mtx, err := tcs.GetMeshTransaction(tid)
+		require.NoError(t, err)
+		require.Equal(t, state, mtx.State)
+		require.Equal(t, bid, mtx.BlockID)
+	}
+
+	// the losing block of the fork is applied first
+	lid := types.LayerID(1)
+	losing, winning := types.BlockID{1}, types.BlockID{2}
+	require.NoError(t, tcs.LinkTXsWithBlock(lid, losing, []types.TransactionID{shared.ID, orphaned.ID}))
+	require.NoError(t, tcs.LinkTXsWithBlock(lid, winning, []types.TransactionID{shared.ID}))
+	accounts[0].applied, accounts[1].applied = true, true
+	apply(lid, losing, shared, orphaned)
+	apply(lid.Add(1), types.EmptyBlockID)
+	checkState(orphaned.ID, types.APPLIED, losing)
+
+	// both layers are reverted at once, and applied again with the winning block
+	accounts[0].applied, accounts[1].applied = false, false
+	require.NoError(t, layers.UnsetAppliedFrom(tcs.db, lid))
+	require.NoError(t, tcs.RevertCache(lid.Sub(1)))
+	readmittedBefore := testutil.ToFloat64(mempoolTxCount.WithLabelValues(readmitted))
+	publisher.EXPECT().Publish(gomock.Any(), pubsub.TxProtocol, orphaned.Raw).Times(1)
+	accounts[0].applied = true
+	apply(lid, winning, shared)
+	apply(lid.Add(1), types.EmptyBlockID)
+
+	// the unique tx of the losing block is back in the mempool, once
+	require.Equal(t, readmittedBefore+1, testutil.ToFloat64(mempoolTxCount.WithLabelValues(readmitted)))
+	checkState(shared.ID, types.APPLIED, winning)
+	checkState(orphaned.ID, types.MEMPOOL, types.EmptyBlockID)
+	ntx := tcs.cache.Get(orphaned.ID)
+	require.NotNil(t, ntx)
+	require.True(t, ntx.Local)
+	require.Zero(t, ntx.Layer)
+	require.Nil(t, tcs.cache.Get(shared.ID))
+	nextNonce, balance := tcs.GetProjection(orphaned.Principal)
+	require.Equal(t, nonce+1, nextNonce)
+	require.Equal(t, defaultBalance-orphaned.Spending(), balance)
+	require.Equal(t, []types.TransactionID{orphaned.ID}, tcs.SelectProposalTXs(lid.Add(2), 1))
 }
 
 func TestUpdateCache_UpdateHeader(t *testing.T) {
@@ -536,6 +875,11 @@ func TestUpdateCache_EmptyLayer(t *testing.T) {
 	require.NoError(t, tcs.UpdateCache(context.Background(), lid, types.EmptyBlockID, nil, nil))
 	mempoolTxs = tcs.SelectProposalTXs(lid, 2)
 	require.Len(t, mempoolTxs, len(ids))
+	for _, id := range ids {
+		mtx, err := tcs.GetMeshTransaction(id)
+		require.NoError(t, err)
+		require.Equal(t, types.MEMPOOL, mtx.State)
+	}
 }
 
 func TestConsistentHandling(t *testing.T) {
@@ -613,3 +957,110 @@ func TestConsistentHandling(t *testing.T) {
 		}
 	}
 }
+
+func TestRestore_PendingIncludedInProposal(t *testing.T) {
+	tcs := createConservativeState(t)
+	signer, err := signing.NewEdSigner()
+	require.NoError(t, err)
+	addr := types.GenerateAddress(signer.PublicKey().Bytes())
+	tcs.mvm.EXPECT().GetBalance(addr).Return(defaultBalance, nil).AnyTimes()
+	tcs.mvm.EXPECT().GetNonce(addr).Return(nonce, nil).AnyTimes()
+	tx := newTx(t, nonce, defaultAmount, defaultFee, signer)
+	require.NoError(t, tcs.AddToCache(context.Background(), tx, time.Now()))
+
+	// the node restarts with the same database
+	restarted := NewConservativeState(tcs.mvm, tcs.db,
+		WithCSConfig(CSConfig{BlockGasLimit: math.MaxUint64, NumTXsPerProposal: numTXsInProposal}),
+		WithLogger(tcs.logger))
+	require.Empty(t, restarted.SelectProposalTXs(types.LayerID(10), 1))
+	require.NoError(t, restarted.Restore())
+	require.Equal(t, []types.TransactionID{tx.ID}, restarted.SelectProposalTXs(types.LayerID(10), 1))
+}
+
+func TestSelectProposalTXs_LocalLane(t *testing.T) {
+	lid := types.LayerID(97)
+	for _, policy := range []SelectionPolicy{SelectFee, SelectFIFO} {
+		policy := policy
+		t.Run(string(policy), func(t *testing.T) {
+			tcs := createConservativeState(t)
+			tcs.cfg.Selection = policy
+			tcs.cfg.NumTXsPerProposal = 4
+			signer, err := signing.NewEdSigner()
+			require.NoError(t, err)
+			addr := types.GenerateAddress(signer.PublicKey().Bytes())
+			tcs.mvm.EXPECT().GetBalance(addr).Return(defaultBalance, nil).Times(1)
+			tcs.mvm.EXPECT().GetNonce(addr).Return(uint64(0), nil).Times(1)
+			var local []*types.Transaction
+			for i := 0; i < 3; i++ {
+				tx := newTx(t, uint64(i), defaultAmount, 1, signer)
+				require.NoError(t, tcs.AddLocalToCache(context.Background(), tx, time.Now()))
+				local = append(local, tx)
+			}
+			var remote []types.TransactionID
+			for i := 0; i < 4; i++ {
+				signer, err := signing.NewEdSigner()
+				require.NoError(t, err)
+				addr := types.GenerateAddress(signer.PublicKey().Bytes())
+				tcs.mvm.EXPECT().GetBalance(addr).Return(defaultBalance, nil).Times(1)
+				tcs.mvm.EXPECT().GetNonce(addr).Return(uint64(0), nil).Times(1)
+				tx := newTx(t, 0, defaultAmount, 50, signer)
+				require.NoError(t, tcs.AddToCache(context.Background(), tx, time.Now()))
+				remote = append(remote, tx.ID)
+			}
+
+			tcs.cfg.LocalLane = 50
+			got := tcs.SelectProposalTXs(lid, 1)
+			require.Len(t, got, 4)
+			require.Equal(t, []types.TransactionID{local[0].ID, local[1].ID}, got[:2])
+			if policy == SelectFee {
+				// low fee local txs beyond the lane lose to the remote txs
+				require.Subset(t, remote, got[2:])
+			}
+			require.NotContains(t, got[2:], local[0].ID)
+			require.NotContains(t, got[2:], local[1].ID)
+
+			if policy == SelectFee {
+				tcs.cfg.LocalLane = 0
+				require.ElementsMatch(t, remote, tcs.SelectProposalTXs(lid, 1))
+			}
+		})
+	}
+}
+
+func TestSelectProposalTXs_Expiry(t *testing.T) {
+	lid := types.LayerID(97)
+	principals := []struct {
+		desc     string
+		expiry   []types.LayerID
+		selected int
+	}{
+		{desc: "expires at the layer", expiry: []types.LayerID{lid}, selected: 1},
+		{desc: "expired before the layer", expiry: []types.LayerID{lid.Sub(1)}},
+		{desc: "after expired tx", expiry: []types.LayerID{lid.Sub(1), 0}},
+		{desc: "before expired tx", expiry: []types.LayerID{0, lid.Sub(1)}, selected: 1},
+	}
+	for _, policy := range []SelectionPolicy{SelectFee, SelectFIFO} {
+		policy := policy
+		t.Run(string(policy), func(t *testing.T) {
+			tcs := createConservativeState(t)
+			tcs.cfg.Selection = policy
+			var expected []types.TransactionID
+			for _, p := range principals {
+				signer, err := signing.NewEdSigner()
+				require.NoError(t, err)
+				addr := types.GenerateAddress(signer.PublicKey().Bytes())
+				tcs.mvm.EXPECT().GetBalance(addr).Return(defaultBalance, nil).Times(1)
+				tcs.mvm.EXPECT().GetNonce(addr).Return(uint64(0), nil).Times(1)
+				for i, expiry := range p.expiry {
+					tx := newTx(t, uint64(i), defaultAmount, defaultFee, signer)
+					tx.LayerLimits.Max = expiry.Uint32()
+					require.NoError(t, tcs.AddToCache(context.Background(), tx, time.Now()), p.desc)
+					if i < p.selected {
+						expected = append(expected, tx.ID)
+					}
+				}
+			}
+			require.ElementsMatch(t, expected, tcs.SelectProposalTXs(lid, 1))
+		})
+	}
+}
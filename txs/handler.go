@@ -19,22 +19,63 @@ var (
 	errDuplicateTX = errors.New("tx already exists")
 	errParse       = errors.New("failed to parse tx")
 	errVerify      = errors.New("failed to verify tx")
+
+	// ErrBalanceTooLow is returned if the balance of the principal, projected over its pending txs,
+	// doesn't cover the max spending of the tx.
+	ErrBalanceTooLow = errors.New("projected balance is too low")
+	// ErrNonceTooFar is returned if the nonce of the tx is too far from the projected next nonce.
+	ErrNonceTooFar = errors.New("nonce is too far from the next nonce")
+	// ErrTooManyPending is returned if the principal has too many pending txs.
+	ErrTooManyPending = errors.New("too many pending txs")
 )
 
+// DefaultMaxNonceGap is the default max distance between the nonce of the tx and the projected
+// next nonce of the principal.
+const DefaultMaxNonceGap = 100
+
+// TxHandlerOpt for configuring TxHandler.
+type TxHandlerOpt func(*TxHandler)
+
+// WithMaxNonceGap sets the max distance between the nonce of the admitted tx and the projected
+// next nonce of the principal.
+func WithMaxNonceGap(gap uint64) TxHandlerOpt {
+	return func(th *TxHandler) {
+		th.maxNonceGap = gap
+	}
+}
+
+// WithParser sets the parser for the wire format of the transactions.
+// By default the transactions are parsed by the vm.
+func WithParser(p Parser) TxHandlerOpt {
+	return func(th *TxHandler) {
+		th.parser = p
+	}
+}
+
 // TxHandler handles the transactions received via gossip or sync.
 type TxHandler struct {
-	self   peer.ID
-	logger log.Log
-	state  conservativeState
+	self        peer.ID
+	logger      log.Log
+	state       conservativeState
+	parser      Parser
+	maxNonceGap uint64
 }
 
 // NewTxHandler returns a new TxHandler.
-func NewTxHandler(s conservativeState, id peer.ID, l log.Log) *TxHandler {
-	return &TxHandler{
-		self:   id,
-		logger: l,
-		state:  s,
+func NewTxHandler(s conservativeState, id peer.ID, l log.Log, opts ...TxHandlerOpt) *TxHandler {
+	th := &TxHandler{
+		self:        id,
+		logger:      l,
+		state:       s,
+		maxNonceGap: DefaultMaxNonceGap,
+	}
+	for _, opt := range opts {
+		opt(th)
+	}
+	if th.parser == nil {
+		th.parser = NewLegacyParser(s)
 	}
+	return th
 }
 
 func updateMetrics(err error, counter *prometheus.CounterVec) {
@@ -49,6 +90,8 @@ func updateMetrics(err error, counter *prometheus.CounterVec) {
 		counter.WithLabelValues(cantParse).Inc()
 	case errors.Is(err, errVerify):
 		counter.WithLabelValues(cantVerify).Inc()
+	case errors.Is(err, ErrBalanceTooLow), errors.Is(err, ErrNonceTooFar), errors.Is(err, ErrTooManyPending):
+		counter.WithLabelValues(notAdmitted).Inc()
 	default:
 		counter.WithLabelValues(rejectedInternalErr).Inc()
 	}
@@ -60,10 +103,11 @@ func (th *TxHandler) HandleGossipTransaction(ctx context.Context, peer p2p.Peer,
 		return nil
 	}
 
-	err := th.VerifyAndCacheTx(ctx, msg)
+	err := th.verifyAndCacheTx(ctx, msg, originGossip)
 	updateMetrics(err, gossipTxCount)
 	if err != nil {
 		th.logger.WithContext(ctx).With().Warning("failed to handle tx", log.Err(err))
+		// the state differs between nodes, the tx is ignored but not rejected
 		return err
 	}
 	return nil
@@ -71,7 +115,8 @@ func (th *TxHandler) HandleGossipTransaction(ctx context.Context, peer p2p.Peer,
 
 // HandleProposalTransaction handles data received on the transactions synced as a part of proposal.
 func (th *TxHandler) HandleProposalTransaction(ctx context.Context, _ p2p.Peer, msg []byte) error {
-	err := th.VerifyAndCacheTx(ctx, msg)
+	// txs referenced by proposals are stored regardless of the local state
+	err := th.verifyAndCacheTx(ctx, msg, originProposal)
 	updateMetrics(err, proposalTxCount)
 	if errors.Is(err, errDuplicateTX) {
 		return nil
@@ -79,7 +124,47 @@ func (th *TxHandler) HandleProposalTransaction(ctx context.Context, _ p2p.Peer,
 	return err
 }
 
+// VerifyAndCacheTx verifies the tx submitted by the api client, checks that it can be admitted
+// given the projected state of the principal and adds it to the mempool as a local tx.
 func (th *TxHandler) VerifyAndCacheTx(ctx context.Context, msg []byte) error {
+	return th.verifyAndCacheTx(ctx, msg, originLocal)
+}
+
+// admit checks the tx against the state of the principal projected over its pending txs.
+func (th *TxHandler) admit(header *types.TxHeader) error {
+	p := th.state.Projection(header.Principal)
+	nonce, balance := p.ReadyNonce, p.ReadyBalance
+	if header.Nonce < nonce {
+		// replaces the pending tx, the cache checks the fee bump and the balance
+		return nil
+	}
+	if header.Nonce-nonce > th.maxNonceGap {
+		admissionTxCount.WithLabelValues(admitNonce).Inc()
+		return fmt.Errorf("%w: nonce %d, next nonce %d", ErrNonceTooFar, header.Nonce, nonce)
+	}
+	if p.Pending >= maxTXsPerAcct {
+		admissionTxCount.WithLabelValues(admitPending).Inc()
+		return fmt.Errorf("%w: %d", ErrTooManyPending, p.Pending)
+	}
+	if balance < header.Spending() {
+		admissionTxCount.WithLabelValues(admitBalance).Inc()
+		return fmt.Errorf("%w: balance %d, max spending %d", ErrBalanceTooLow, balance, header.Spending())
+	}
+	return nil
+}
+
+// txOrigin is where the tx was received from.
+type txOrigin uint8
+
+const (
+	originGossip txOrigin = iota
+	// originProposal txs are referenced by proposals, they are stored regardless of the admission checks.
+	originProposal
+	// originLocal txs are submitted via the api of this node.
+	originLocal
+)
+
+func (th *TxHandler) verifyAndCacheTx(ctx context.Context, msg []byte, origin txOrigin) error {
 	raw := types.NewRawTx(msg)
 	tx, err := th.state.GetMeshTransaction(raw.ID)
 	if err != nil && !errors.Is(err, sql.ErrNotFound) {
@@ -89,18 +174,31 @@ func (th *TxHandler) VerifyAndCacheTx(ctx context.Context, msg []byte) error {
 		return errDuplicateTX
 	}
 
-	req := th.state.Validation(raw)
-	header, err := req.Parse()
+	parsed, err := th.parser.Parse(raw.Raw)
 	if err != nil {
+		var ferr *FormatError
+		if errors.As(err, &ferr) {
+			return fmt.Errorf("%s: %w", raw.ID, ferr)
+		}
 		return fmt.Errorf("%w: %s (err: %s)", errParse, raw.ID, err)
 	}
+	header := headerOf(parsed)
 	if header.GasPrice == 0 {
 		return fmt.Errorf("%w: zero gas price %s", errParse, raw.ID)
 	}
-	if !req.Verify() {
+	if origin != originProposal {
+		if err := th.admit(header); err != nil {
+			return fmt.Errorf("%s: %w", raw.ID, err)
+		}
+	}
+	if !th.parser.Verify(raw.Raw) {
 		return fmt.Errorf("%w: %s", errVerify, raw.ID)
 	}
-	if err := th.state.AddToCache(ctx, &types.Transaction{RawTx: raw, TxHeader: header}, time.Now()); err != nil {
+	add := th.state.AddToCache
+	if origin == originLocal {
+		add = th.state.AddLocalToCache
+	}
+	if err := add(ctx, &types.Transaction{RawTx: raw, TxHeader: header}, time.Now()); err != nil {
 		th.logger.WithContext(ctx).With().Warning("failed to add tx to conservative cache",
 			raw.ID,
 			log.Err(err),
@@ -122,10 +220,11 @@ func (th *TxHandler) HandleBlockTransaction(_ context.Context, _ p2p.Peer, data
 		return nil
 	}
 	tx := &types.Transaction{RawTx: raw}
-	req := th.state.Validation(raw)
-	header, err := req.Parse()
+	var header *types.TxHeader
+	parsed, err := th.parser.Parse(raw.Raw)
 	if err == nil {
-		if req.Verify() {
+		header = headerOf(parsed)
+		if th.parser.Verify(raw.Raw) {
 			tx.TxHeader = header
 		} else {
 			blockTxCount.WithLabelValues(cantVerify).Inc()
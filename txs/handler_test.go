@@ -3,19 +3,23 @@ package txs
 import (
 	"context"
 	"errors"
+	"math"
 	"testing"
 	"time"
 
 	"github.com/golang/mock/gomock"
 	"github.com/libp2p/go-libp2p/core/crypto"
 	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
 	"github.com/spacemeshos/go-spacemesh/common/types"
 	"github.com/spacemeshos/go-spacemesh/log/logtest"
 	"github.com/spacemeshos/go-spacemesh/p2p"
+	"github.com/spacemeshos/go-spacemesh/p2p/pubsub"
 	"github.com/spacemeshos/go-spacemesh/signing"
+	"github.com/spacemeshos/go-spacemesh/system"
 	smocks "github.com/spacemeshos/go-spacemesh/system/mocks"
 )
 
@@ -112,6 +116,7 @@ func gossipExpectations(t *testing.T, fee uint64, hasErr, parseErr, addErr error
 		}
 	}
 	cstate.EXPECT().GetMeshTransaction(tx.ID).Return(rst, hasErr).Times(1)
+	cstate.EXPECT().Projection(tx.Principal).Return(Projection{ReadyNonce: tx.Nonce, ReadyBalance: math.MaxUint64}).AnyTimes()
 	if hasErr == nil && !has {
 		req := smocks.NewMockValidationRequest(ctrl)
 		req.EXPECT().Parse().Times(1).Return(tx.TxHeader, parseErr)
@@ -302,3 +307,130 @@ func Test_HandleProposal(t *testing.T) {
 		})
 	}
 }
+
+func Test_Admission(t *testing.T) {
+	tcs := createConservativeState(t)
+	th := NewTxHandler(tcs, tcs.id, tcs.logger, WithMaxNonceGap(5))
+
+	signer, err := signing.NewEdSigner()
+	require.NoError(t, err)
+	principal := types.GenerateAddress(signer.PublicKey().Bytes())
+	tx1 := newTx(t, nonce, defaultAmount, defaultFee, signer)
+	tx2 := newTx(t, nonce+1, defaultAmount, defaultFee, signer)
+	balance := tx1.Spending() + tx2.Spending()
+	tcs.mvm.EXPECT().GetNonce(principal).Return(nonce, nil).AnyTimes()
+	tcs.mvm.EXPECT().GetBalance(principal).Return(balance, nil).AnyTimes()
+
+	submit := func(tx *types.Transaction) error {
+		req := smocks.NewMockValidationRequest(gomock.NewController(t))
+		req.EXPECT().Parse().Return(tx.TxHeader, nil)
+		req.EXPECT().Verify().Return(true).AnyTimes()
+		tcs.mvm.EXPECT().Validation(tx.RawTx).Return(req)
+		return th.HandleGossipTransaction(context.Background(), p2p.NoPeer, tx.Raw)
+	}
+
+	require.NoError(t, submit(tx1))
+	t.Run("pending tx makes balance insufficient", func(t *testing.T) {
+		rejected := testutil.ToFloat64(admissionTxCount.WithLabelValues(admitBalance))
+		tx := newTx(t, nonce+1, defaultAmount+1, defaultFee, signer)
+		require.Equal(t, balance, tx1.Spending()+tx.Spending()-1)
+		err := submit(tx)
+		require.ErrorIs(t, err, ErrBalanceTooLow)
+		require.NotErrorIs(t, err, pubsub.ErrValidationReject)
+		require.Equal(t, rejected+1, testutil.ToFloat64(admissionTxCount.WithLabelValues(admitBalance)))
+		checkNoTXInDB(t, tcs, tx.ID)
+
+		// the balance covers exactly the pending tx and the next one
+		require.NoError(t, submit(tx2))
+		next, projected, _ := tcs.AccountPendingState(principal)
+		require.Equal(t, nonce+2, next)
+		require.Zero(t, projected)
+	})
+	t.Run("nonce too far", func(t *testing.T) {
+		tx := newTx(t, nonce+2+6, 0, defaultFee, signer)
+		require.ErrorIs(t, submit(tx), ErrNonceTooFar)
+		checkNoTXInDB(t, tcs, tx.ID)
+	})
+	t.Run("replacement is left to the mempool", func(t *testing.T) {
+		tx := newTx(t, nonce, defaultAmount, defaultFee+1, signer)
+		require.NotErrorIs(t, submit(tx), ErrBalanceTooLow)
+	})
+	t.Run("proposal txs are not checked", func(t *testing.T) {
+		tx := newTx(t, nonce+2+6, defaultAmount, defaultFee, signer)
+		req := smocks.NewMockValidationRequest(gomock.NewController(t))
+		req.EXPECT().Parse().Return(tx.TxHeader, nil)
+		req.EXPECT().Verify().Return(true)
+		tcs.mvm.EXPECT().Validation(tx.RawTx).Return(req)
+		require.NoError(t, th.HandleProposalTransaction(context.Background(), p2p.NoPeer, tx.Raw))
+	})
+}
+
+func Test_Admission_TooManyPending(t *testing.T) {
+	tcs := createConservativeState(t)
+	th := NewTxHandler(tcs, tcs.id, tcs.logger)
+	signer, err := signing.NewEdSigner()
+	require.NoError(t, err)
+	principal := types.GenerateAddress(signer.PublicKey().Bytes())
+	tcs.mvm.EXPECT().GetNonce(principal).Return(nonce, nil).AnyTimes()
+	tcs.mvm.EXPECT().GetBalance(principal).Return(uint64(math.MaxUint64), nil).AnyTimes()
+	parsed := map[types.TransactionID]*types.TxHeader{}
+	tcs.mvm.EXPECT().Validation(gomock.Any()).DoAndReturn(func(raw types.RawTx) system.ValidationRequest {
+		req := smocks.NewMockValidationRequest(gomock.NewController(t))
+		req.EXPECT().Parse().Return(parsed[raw.ID], nil)
+		req.EXPECT().Verify().Return(true).AnyTimes()
+		return req
+	}).AnyTimes()
+
+	for i := 0; i <= maxTXsPerAcct; i++ {
+		tx := newTx(t, nonce+uint64(i), defaultAmount, defaultFee, signer)
+		parsed[tx.ID] = tx.TxHeader
+		err := th.VerifyAndCacheTx(context.Background(), tx.Raw)
+		if i < maxTXsPerAcct {
+			require.NoError(t, err)
+		} else {
+			require.ErrorIs(t, err, ErrTooManyPending)
+		}
+	}
+}
+
+func checkNoTXInDB(t *testing.T, tcs *testConState, tid types.TransactionID) {
+	t.Helper()
+	has, err := tcs.HasTx(tid)
+	require.NoError(t, err)
+	require.False(t, has)
+}
+
+func Test_LocalOrigin(t *testing.T) {
+	tcs := createConservativeState(t)
+	th := NewTxHandler(tcs, tcs.id, tcs.logger)
+	signers := make([]*signing.EdSigner, 2)
+	for i := range signers {
+		signer, err := signing.NewEdSigner()
+		require.NoError(t, err)
+		signers[i] = signer
+		principal := types.GenerateAddress(signer.PublicKey().Bytes())
+		tcs.mvm.EXPECT().GetNonce(principal).Return(nonce, nil).AnyTimes()
+		tcs.mvm.EXPECT().GetBalance(principal).Return(defaultBalance, nil).AnyTimes()
+	}
+	parsed := map[types.TransactionID]*types.TxHeader{}
+	tcs.mvm.EXPECT().Validation(gomock.Any()).DoAndReturn(func(raw types.RawTx) system.ValidationRequest {
+		req := smocks.NewMockValidationRequest(gomock.NewController(t))
+		req.EXPECT().Parse().Return(parsed[raw.ID], nil)
+		req.EXPECT().Verify().Return(true).AnyTimes()
+		return req
+	}).AnyTimes()
+
+	local := newTx(t, nonce, defaultAmount, defaultFee, signers[0])
+	parsed[local.ID] = local.TxHeader
+	require.NoError(t, th.VerifyAndCacheTx(context.Background(), local.Raw))
+	gossiped := newTx(t, nonce, defaultAmount, defaultFee, signers[1])
+	parsed[gossiped.ID] = gossiped.TxHeader
+	require.NoError(t, th.HandleGossipTransaction(context.Background(), p2p.NoPeer, gossiped.Raw))
+
+	require.True(t, tcs.cache.Get(local.ID).Local)
+	require.False(t, tcs.cache.Get(gossiped.ID).Local)
+	// the origin is not persisted
+	mtx, err := tcs.GetMeshTransaction(local.ID)
+	require.NoError(t, err)
+	require.Equal(t, local.RawTx, mtx.RawTx)
+}
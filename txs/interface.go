@@ -15,8 +15,10 @@ type conservativeState interface {
 	HasTx(types.TransactionID) (bool, error)
 	Validation(types.RawTx) system.ValidationRequest
 	AddToCache(context.Context, *types.Transaction, time.Time) error
+	AddLocalToCache(context.Context, *types.Transaction, time.Time) error
 	AddToDB(*types.Transaction) error
 	GetMeshTransaction(types.TransactionID) (*types.MeshTransaction, error)
+	Projection(types.Address) Projection
 }
 
 type vmState interface {
@@ -17,6 +17,7 @@ const (
 	cantVerify          = "verify"
 	rejectedBadNonce    = "badNonce"
 	rejectedInternalErr = "err"
+	notAdmitted         = "not_admitted"
 	RawFromDB           = "raw"
 	updated             = "updated"
 
@@ -24,7 +25,33 @@ const (
 	mempool         = "mempool"
 	balanceTooSmall = "balance"
 	tooManyNonce    = "too_many"
+	tooManyParked   = "too_many_parked"
+	promotedParked  = "promoted"
+	replacedByFee   = "replaced"
+	feeBumpTooLow   = "fee_bump"
+	replacePacked   = "replace_packed"
+	rejectedAtCap   = "mempool_full"
+	evictedAtCap    = "evicted"
+	expiredTx       = "expired"
+	readmitted      = "readmitted"
 	accepted        = "ok"
+
+	// labels for the reason the tx is not admitted.
+	admitBalance = "balance"
+	admitNonce   = "nonce"
+	admitPending = "pending"
+
+	// labels for the mempool size.
+	sizeTXs   = "txs"
+	sizeBytes = "bytes"
+
+	// labels for the state of the txs in the mempool.
+	stateReady  = "ready"
+	stateParked = "parked"
+
+	// labels for the inclusion of the mempool txs.
+	inProposal = "proposal"
+	inBlock    = "block"
 )
 
 var (
@@ -58,6 +85,31 @@ var (
 		"number of transactions added to the mempool",
 		[]string{"outcome"},
 	)
+	admissionTxCount = metrics.NewCounter(
+		"admission_rejected_txs",
+		namespace,
+		"number of transactions not admitted to the mempool",
+		[]string{"reason"},
+	)
+	mempoolSize = metrics.NewGauge(
+		"mempool_size",
+		namespace,
+		"number of transactions and their total size in bytes in the mempool",
+		[]string{"unit"},
+	)
+	mempoolTxsByState = metrics.NewGauge(
+		"mempool_txs_state",
+		namespace,
+		"number of transactions in the mempool that are ready for proposals or parked after a nonce gap",
+		[]string{"state"},
+	)
+	inclusionAge = metrics.NewHistogramWithBuckets(
+		"inclusion_age_seconds",
+		namespace,
+		"time in seconds from receiving the transaction until it is included in a proposal or a block",
+		[]string{"included_in"},
+		prometheus.ExponentialBuckets(1, 2, 14),
+	)
 )
 
 var (
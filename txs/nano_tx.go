@@ -15,9 +15,15 @@ type NanoTX struct {
 	ID types.TransactionID
 
 	Received time.Time
+	// Size is the size of the raw transaction in bytes.
+	Size uint64
 
 	Block types.BlockID
 	Layer types.LayerID
+
+	// Local is true if the tx was submitted via the api of this node.
+	// It is not persisted and not gossiped.
+	Local bool
 }
 
 // NewNanoTX converts a NanoTX instance from a MeshTransaction.
@@ -26,6 +32,7 @@ func NewNanoTX(mtx *types.MeshTransaction) *NanoTX {
 		ID:       mtx.ID,
 		TxHeader: *mtx.TxHeader,
 		Received: mtx.Received,
+		Size:     uint64(len(mtx.Raw)),
 		Block:    mtx.BlockID,
 		Layer:    mtx.LayerID,
 	}
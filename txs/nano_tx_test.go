@@ -63,6 +63,7 @@ func TestNewNanoTX(t *testing.T) {
 	require.Equal(t, mtx.Nonce, ntx.Nonce)
 	require.Equal(t, mtx.BlockID, ntx.Block)
 	require.Equal(t, mtx.LayerID, ntx.Layer)
+	require.Equal(t, uint64(len(mtx.Raw)), ntx.Size)
 	require.Equal(t, mtx.MaxSpend+mtx.Fee(), ntx.MaxSpending())
 }
 
@@ -0,0 +1,156 @@
+package txs
+
+import (
+	"bytes"
+	"fmt"
+	"sync"
+
+	lru "github.com/hashicorp/golang-lru/v2"
+
+	"github.com/spacemeshos/go-spacemesh/codec"
+	"github.com/spacemeshos/go-spacemesh/common/types"
+	"github.com/spacemeshos/go-spacemesh/system"
+)
+
+// Parser decodes and verifies raw transactions of a particular wire format.
+type Parser interface {
+	// Parse decodes the header of the transaction and the layer it expires after, if any.
+	Parse(raw []byte) (*types.ParsedTx, error)
+	// Verify checks the signature of the transaction.
+	Verify(raw []byte) bool
+}
+
+// FormatError is returned by the parser if the transaction is not in its wire format.
+type FormatError struct {
+	Format string
+}
+
+func (e *FormatError) Error() string {
+	return fmt.Sprintf("tx is not in %s format", e.Format)
+}
+
+// Is makes format errors count as parsing errors.
+func (e *FormatError) Is(target error) bool {
+	return target == errParse
+}
+
+// headerOf returns the header of the parsed transaction with its expiry.
+// The expiry is kept in the layer limits of the header, so that it is persisted with the transaction.
+func headerOf(parsed *types.ParsedTx) *types.TxHeader {
+	if parsed.Expiry == parsed.Header.Expiry() {
+		return parsed.Header
+	}
+	header := *parsed.Header
+	header.LayerLimits.Max = parsed.Expiry.Uint32()
+	return &header
+}
+
+// parsedCacheSize is the number of transactions that keep the results of parsing.
+const parsedCacheSize = 10_000
+
+type validator interface {
+	Validation(types.RawTx) system.ValidationRequest
+}
+
+// parsedTx keeps the validation request between parsing and verifying the transaction.
+// The request can be verified only once, the outcome is kept instead.
+type parsedTx struct {
+	header *types.TxHeader
+
+	mu       sync.Mutex
+	req      system.ValidationRequest
+	verified bool
+}
+
+func (p *parsedTx) verify() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.req != nil {
+		p.verified = p.req.Verify()
+		p.req = nil
+	}
+	return p.verified
+}
+
+// LegacyParser parses transactions in the format of the account templates of the vm.
+// Results of parsing are cached by the transaction id, so that a transaction received
+// via gossip is not parsed again when it is referenced by a proposal or a block.
+type LegacyParser struct {
+	vm     validator
+	parsed *lru.Cache[types.TransactionID, *parsedTx]
+}
+
+// NewLegacyParser returns a parser that uses the vm to parse and verify transactions.
+func NewLegacyParser(vm validator) *LegacyParser {
+	parsed, err := lru.New[types.TransactionID, *parsedTx](parsedCacheSize)
+	if err != nil {
+		panic(err) // only for a non-positive size
+	}
+	return &LegacyParser{vm: vm, parsed: parsed}
+}
+
+func (p *LegacyParser) parse(raw []byte) (*parsedTx, error) {
+	rtx := types.NewRawTx(raw)
+	if parsed, ok := p.parsed.Get(rtx.ID); ok {
+		return parsed, nil
+	}
+	req := p.vm.Validation(rtx)
+	header, err := req.Parse()
+	if err != nil {
+		return nil, err
+	}
+	parsed := &parsedTx{header: header, req: req}
+	p.parsed.Add(rtx.ID, parsed)
+	return parsed, nil
+}
+
+// Parse decodes the header of the transaction.
+func (p *LegacyParser) Parse(raw []byte) (*types.ParsedTx, error) {
+	parsed, err := p.parse(raw)
+	if err != nil {
+		return nil, err
+	}
+	return &types.ParsedTx{ID: types.NewRawTx(raw).ID, Header: parsed.header, Expiry: parsed.header.Expiry()}, nil
+}
+
+// Verify checks the signature of the transaction, parsing it if necessary.
+func (p *LegacyParser) Verify(raw []byte) bool {
+	parsed, err := p.parse(raw)
+	if err != nil {
+		return false
+	}
+	return parsed.verify()
+}
+
+// TaggedFormat is the name of the format decoded by the TaggedParser.
+const TaggedFormat = "tagged"
+
+// taggedPrefix starts every transaction in the tagged format.
+var taggedPrefix = []byte("smtx\x01")
+
+// TaggedParser parses the self-describing format that carries the header in the transaction itself.
+// It is meant for tests and development networks, transactions are not signed.
+type TaggedParser struct{}
+
+// EncodeTagged encodes the header in the tagged format.
+func EncodeTagged(header *types.TxHeader) []byte {
+	return append(append([]byte{}, taggedPrefix...), codec.MustEncode(header)...)
+}
+
+// Parse decodes the header of the transaction.
+func (TaggedParser) Parse(raw []byte) (*types.ParsedTx, error) {
+	if !bytes.HasPrefix(raw, taggedPrefix) {
+		return nil, &FormatError{Format: TaggedFormat}
+	}
+	var header types.TxHeader
+	if err := codec.Decode(raw[len(taggedPrefix):], &header); err != nil {
+		return nil, fmt.Errorf("decode tagged tx: %w", err)
+	}
+	return &types.ParsedTx{ID: types.NewRawTx(raw).ID, Header: &header, Expiry: header.Expiry()}, nil
+}
+
+// Verify accepts every transaction that can be parsed.
+func (p TaggedParser) Verify(raw []byte) bool {
+	_, err := p.Parse(raw)
+	return err == nil
+}
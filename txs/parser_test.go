@@ -0,0 +1,142 @@
+package txs
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/spacemeshos/go-spacemesh/common/types"
+	"github.com/spacemeshos/go-spacemesh/log/logtest"
+	"github.com/spacemeshos/go-spacemesh/p2p"
+	"github.com/spacemeshos/go-spacemesh/signing"
+	smocks "github.com/spacemeshos/go-spacemesh/system/mocks"
+)
+
+func TestLegacyParser_Cached(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	vm := NewMockvmState(ctrl)
+	parser := NewLegacyParser(vm)
+
+	signer, err := signing.NewEdSigner()
+	require.NoError(t, err)
+	tx := newTx(t, 3, 10, 1, signer)
+	req := smocks.NewMockValidationRequest(ctrl)
+	vm.EXPECT().Validation(tx.RawTx).Return(req).Times(1)
+	req.EXPECT().Parse().Return(tx.TxHeader, nil).Times(1)
+	req.EXPECT().Verify().Return(true).Times(1)
+
+	for i := 0; i < 2; i++ {
+		parsed, err := parser.Parse(tx.Raw)
+		require.NoError(t, err)
+		require.Equal(t, tx.ID, parsed.ID)
+		require.Equal(t, tx.TxHeader, parsed.Header)
+		require.True(t, parser.Verify(tx.Raw))
+	}
+}
+
+func TestLegacyParser_ParseFailedNotCached(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	vm := NewMockvmState(ctrl)
+	parser := NewLegacyParser(vm)
+
+	signer, err := signing.NewEdSigner()
+	require.NoError(t, err)
+	tx := newTx(t, 3, 10, 1, signer)
+	failed := smocks.NewMockValidationRequest(ctrl)
+	failed.EXPECT().Parse().Return(nil, errors.New("test"))
+	vm.EXPECT().Validation(tx.RawTx).Return(failed)
+	_, err = parser.Parse(tx.Raw)
+	require.Error(t, err)
+
+	req := smocks.NewMockValidationRequest(ctrl)
+	req.EXPECT().Parse().Return(tx.TxHeader, nil)
+	req.EXPECT().Verify().Return(false)
+	vm.EXPECT().Validation(tx.RawTx).Return(req)
+	require.False(t, parser.Verify(tx.Raw))
+	require.False(t, parser.Verify(tx.Raw))
+}
+
+func TestTaggedParser(t *testing.T) {
+	signer, err := signing.NewEdSigner()
+	require.NoError(t, err)
+	tx := newTx(t, 3, 10, 1, signer)
+	tx.LayerLimits.Max = 7
+
+	var parser TaggedParser
+	raw := EncodeTagged(tx.TxHeader)
+	parsed, err := parser.Parse(raw)
+	require.NoError(t, err)
+	require.Equal(t, types.NewRawTx(raw).ID, parsed.ID)
+	require.Equal(t, tx.TxHeader, parsed.Header)
+	require.Equal(t, types.LayerID(7), parsed.Expiry)
+	require.True(t, parser.Verify(raw))
+
+	_, err = parser.Parse(tx.Raw)
+	var ferr *FormatError
+	require.ErrorAs(t, err, &ferr)
+	require.Equal(t, TaggedFormat, ferr.Format)
+	require.False(t, parser.Verify(tx.Raw))
+}
+
+func TestTxHandler_RejectsLegacyFormat(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	cstate := NewMockconservativeState(ctrl)
+	th := NewTxHandler(cstate, "self", logtest.New(t), WithParser(TaggedParser{}))
+
+	signer, err := signing.NewEdSigner()
+	require.NoError(t, err)
+	tx := newTx(t, 3, 10, 1, signer)
+
+	cstate.EXPECT().GetMeshTransaction(tx.ID).Return(nil, nil)
+	err = th.VerifyAndCacheTx(context.Background(), tx.Raw)
+	var ferr *FormatError
+	require.ErrorAs(t, err, &ferr)
+	require.ErrorIs(t, err, errParse)
+
+	cstate.EXPECT().HasTx(tx.ID).Return(false, nil)
+	cstate.EXPECT().AddToDB(&types.Transaction{RawTx: tx.RawTx}).Return(nil)
+	require.NoError(t, th.HandleBlockTransaction(context.Background(), p2p.NoPeer, tx.Raw))
+
+	raw := EncodeTagged(tx.TxHeader)
+	tagged := types.NewRawTx(raw)
+	cstate.EXPECT().HasTx(tagged.ID).Return(false, nil)
+	cstate.EXPECT().AddToDB(&types.Transaction{RawTx: tagged, TxHeader: tx.TxHeader}).Return(nil)
+	require.NoError(t, th.HandleBlockTransaction(context.Background(), p2p.NoPeer, raw))
+}
+
+// expiringParser sets the expiry of the transactions regardless of their headers.
+type expiringParser struct {
+	TaggedParser
+	expiry types.LayerID
+}
+
+func (p expiringParser) Parse(raw []byte) (*types.ParsedTx, error) {
+	parsed, err := p.TaggedParser.Parse(raw)
+	if err != nil {
+		return nil, err
+	}
+	parsed.Expiry = p.expiry
+	return parsed, nil
+}
+
+func TestTxHandler_ParsedExpiry(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	cstate := NewMockconservativeState(ctrl)
+	th := NewTxHandler(cstate, "self", logtest.New(t), WithParser(expiringParser{expiry: 9}))
+
+	signer, err := signing.NewEdSigner()
+	require.NoError(t, err)
+	tx := newTx(t, 3, 10, 1, signer)
+	raw := EncodeTagged(tx.TxHeader)
+	tagged := types.NewRawTx(raw)
+
+	// the expiry is persisted in the layer limits of the header
+	expected := *tx.TxHeader
+	expected.LayerLimits.Max = 9
+	cstate.EXPECT().HasTx(tagged.ID).Return(false, nil)
+	cstate.EXPECT().AddToDB(&types.Transaction{RawTx: tagged, TxHeader: &expected}).Return(nil)
+	require.NoError(t, th.HandleBlockTransaction(context.Background(), p2p.NoPeer, raw))
+}
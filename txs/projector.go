@@ -0,0 +1,82 @@
+package txs
+
+import (
+	"sync"
+
+	"github.com/spacemeshos/go-spacemesh/common/types"
+)
+
+// Projection is the state of the account projected over its transactions in the mempool.
+type Projection struct {
+	// NextNonce and Balance are projected over all the pending transactions, including the parked ones.
+	NextNonce uint64
+	Balance   uint64
+	// ReadyNonce is the first nonce missing in the mempool. ReadyBalance is the balance left to spend
+	// by the transactions with this nonce or higher.
+	ReadyNonce   uint64
+	ReadyBalance uint64
+	// Pending is the number of the transactions in the mempool, Parked is the number of the ones
+	// after the first nonce gap.
+	Pending int
+	Parked  int
+}
+
+// project computes the projection of the account from its candidates.
+func (ac *accountCache) project() Projection {
+	p := Projection{
+		NextNonce:    ac.nextNonce(),
+		Balance:      ac.availBalance(),
+		ReadyNonce:   ac.startNonce,
+		ReadyBalance: ac.startBalance,
+		Pending:      ac.txsByNonce.Len(),
+	}
+	ready := 0
+	for e := ac.txsByNonce.Front(); e != nil; e = e.Next() {
+		cand := e.Value.(*candidate)
+		if cand.nonce() != p.ReadyNonce && !ac.noParking {
+			break
+		}
+		p.ReadyNonce = cand.nonce() + 1
+		p.ReadyBalance = cand.postBalance
+		ready++
+	}
+	p.Parked = p.Pending - ready
+	return p
+}
+
+// projector keeps the projections of the accounts with transactions in the mempool.
+// the projection of an account is replaced every time its transactions change, readers get
+// the last replaced projection without waiting for the mempool update to complete.
+type projector struct {
+	mu       sync.RWMutex
+	accounts map[types.Address]Projection
+}
+
+func newProjector() *projector {
+	return &projector{accounts: make(map[types.Address]Projection)}
+}
+
+func (p *projector) get(addr types.Address) (Projection, bool) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	rst, ok := p.accounts[addr]
+	return rst, ok
+}
+
+func (p *projector) set(addr types.Address, projection Projection) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.accounts[addr] = projection
+}
+
+func (p *projector) drop(addr types.Address) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	delete(p.accounts, addr)
+}
+
+func (p *projector) reset() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.accounts = make(map[types.Address]Projection)
+}
@@ -0,0 +1,148 @@
+package txs
+
+import (
+	"context"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/spacemeshos/go-spacemesh/common/types"
+	"github.com/spacemeshos/go-spacemesh/log"
+	"github.com/spacemeshos/go-spacemesh/sql/layers"
+)
+
+// recomputeProjection computes the projection of the account from its txs in the cache.
+func recomputeProjection(c *Cache, addr types.Address) Projection {
+	ac, ok := c.pending[addr]
+	if !ok {
+		nonce, balance := c.stateF(addr)
+		return Projection{NextNonce: nonce, Balance: balance, ReadyNonce: nonce, ReadyBalance: balance}
+	}
+	p := Projection{
+		NextNonce:    ac.startNonce,
+		Balance:      ac.startBalance,
+		ReadyNonce:   ac.startNonce,
+		ReadyBalance: ac.startBalance,
+	}
+	gap := false
+	for e := ac.txsByNonce.Front(); e != nil; e = e.Next() {
+		ntx := e.Value.(*candidate).best
+		p.Pending++
+		p.NextNonce = ntx.Nonce + 1
+		p.Balance -= ntx.MaxSpending()
+		if !gap && ntx.Nonce == p.ReadyNonce {
+			p.ReadyNonce++
+			p.ReadyBalance = p.Balance
+		} else {
+			gap = true
+			p.Parked++
+		}
+	}
+	return p
+}
+
+func TestProjector_Consistency(t *testing.T) {
+	const (
+		numAccounts = 10
+		numOps      = 10_000
+	)
+	tc, accounts := createCache(t, numAccounts)
+	tc.Cache.logger = log.NewNop()
+	tc.maxTXs = 300
+	accts := acctList(accounts)
+	rng := rand.New(rand.NewSource(time.Now().UnixNano()))
+	var (
+		applied types.LayerID
+		byID    = make(map[types.TransactionID]*types.Transaction)
+		now     = time.Now()
+		ctx     = context.Background()
+	)
+	ready := func(addr types.Address) []*types.Transaction {
+		var rst []*types.Transaction
+		for _, ptx := range tc.GetPending(addr) {
+			if !ptx.Parked {
+				rst = append(rst, byID[ptx.ID])
+			}
+		}
+		return rst
+	}
+	for i := 0; i < numOps; i++ {
+		ta := accts[rng.Intn(len(accts))]
+		switch op := rng.Intn(100); {
+		case op < 70: // admit a new tx or replace the pending one
+			tx := newTx(t, ta.nonce+uint64(rng.Intn(30)), uint64(rng.Intn(2_000_000)), uint64(rng.Intn(10)+1), ta.signer)
+			byID[tx.ID] = tx
+			_ = tc.Add(ctx, tc.db, tx, now.Add(time.Duration(i)), false)
+		case op < 80: // include ready txs in a proposal or a block
+			var tids []types.TransactionID
+			for _, tx := range ready(ta.principal) {
+				if rng.Intn(2) == 0 {
+					tids = append(tids, tx.ID)
+				}
+			}
+			if rng.Intn(2) == 0 {
+				require.NoError(t, tc.LinkTXsWithProposal(tc.db, applied.Add(1), types.RandomProposalID(), tids))
+			} else {
+				require.NoError(t, tc.LinkTXsWithBlock(tc.db, applied.Add(1), types.RandomBlockID(), tids))
+			}
+		case op < 95: // apply a prefix of the ready txs of every account
+			applied = applied.Add(1)
+			bid := types.RandomBlockID()
+			var results []types.TransactionWithResult
+			for _, acct := range accts {
+				txs := ready(acct.principal)
+				if len(txs) == 0 {
+					continue
+				}
+				for _, tx := range txs[:rng.Intn(len(txs))+1] {
+					acct.nonce = tx.Nonce + 1
+					acct.balance -= tx.Spending()
+					results = append(results, makeResults(applied, bid, *tx)...)
+				}
+			}
+			if len(results) == 0 {
+				bid = types.EmptyBlockID
+			}
+			require.NoError(t, tc.ApplyLayer(ctx, tc.db, applied, bid, results, nil))
+			require.NoError(t, layers.SetApplied(tc.db, applied, bid))
+		default: // incoming funds, visible to the cache after the account is reset
+			ta.balance += uint64(rng.Intn(10_000_000))
+		}
+
+		totalParked := 0
+		for _, acct := range accts {
+			expected := recomputeProjection(tc.Cache, acct.principal)
+			require.Equal(t, expected, tc.Projection(acct.principal), "op %d", i)
+			totalParked += expected.Parked
+		}
+		require.Equal(t, totalParked, tc.usage.totalParked, "op %d", i)
+	}
+}
+
+func TestProjector_ReadDuringUpdate(t *testing.T) {
+	tc, ta := createSingleAccountTestCache(t)
+	tx := newTx(t, ta.nonce, defaultAmount, defaultFee, ta.signer)
+	require.NoError(t, tc.Add(context.Background(), tc.db, tx, time.Now(), false))
+
+	// the cache is locked for the whole layer update
+	tc.mu.Lock()
+	defer tc.mu.Unlock()
+	got := make(chan Projection, 1)
+	go func() {
+		got <- tc.Projection(ta.principal)
+	}()
+	select {
+	case p := <-got:
+		require.Equal(t, Projection{
+			NextNonce:    ta.nonce + 1,
+			Balance:      ta.balance - tx.Spending(),
+			ReadyNonce:   ta.nonce + 1,
+			ReadyBalance: ta.balance - tx.Spending(),
+			Pending:      1,
+		}, p)
+	case <-time.After(time.Second):
+		require.FailNow(t, "projection blocked by the cache update")
+	}
+}
@@ -0,0 +1,306 @@
+package txs
+
+import (
+	"container/heap"
+	"fmt"
+	"math"
+	"math/bits"
+
+	"github.com/spacemeshos/go-spacemesh/common/types"
+	"github.com/spacemeshos/go-spacemesh/log"
+)
+
+// SelectionPolicy defines how the transactions for a proposal are selected from the mempool.
+type SelectionPolicy string
+
+const (
+	// SelectFIFO selects the transactions that fit into the block gas limit in the mempool order
+	// and picks a random subset of them if they don't fit into the proposal.
+	SelectFIFO SelectionPolicy = "fifo"
+	// SelectFee selects the transactions with the highest fee per gas. A transaction is selected
+	// only together with all pending transactions of the principal with lower nonces.
+	SelectFee SelectionPolicy = "fee"
+)
+
+// Validate returns an error if the policy is unknown.
+func (p SelectionPolicy) Validate() error {
+	switch p {
+	case SelectFIFO, SelectFee:
+		return nil
+	}
+	return fmt.Errorf("unknown transactions selection policy %q, expected %q or %q", p, SelectFIFO, SelectFee)
+}
+
+// selectionBudget is the remaining capacity of the proposal.
+type selectionBudget struct {
+	count int
+	size  uint64
+	gas   uint64
+}
+
+func newSelectionBudget(count int, size, gas uint64) selectionBudget {
+	if size == 0 {
+		size = math.MaxUint64
+	}
+	return selectionBudget{count: count, size: size, gas: gas}
+}
+
+func (b *selectionBudget) fits(p *txPackage) bool {
+	return p.count() <= b.count && p.size <= b.size && p.gas <= b.gas
+}
+
+func (b *selectionBudget) consume(p *txPackage) {
+	b.count -= p.count()
+	b.size -= p.size
+	b.gas -= p.gas
+}
+
+// share returns the percent of the budget.
+func (b selectionBudget) share(percent uint64) selectionBudget {
+	return selectionBudget{
+		count: int(uint64(b.count) * percent / 100),
+		size:  b.size / 100 * percent,
+		gas:   b.gas / 100 * percent,
+	}
+}
+
+// txPackage is a prefix of the pending transactions of a principal, starting from the lowest nonce.
+type txPackage struct {
+	// pending transactions of the principal, ordered by nonce.
+	pending []*NanoTX
+	// n is the number of transactions in the package.
+	n    int
+	fee  uint64
+	gas  uint64
+	size uint64
+}
+
+func (p *txPackage) count() int {
+	return p.n
+}
+
+func (p *txPackage) txs() []*NanoTX {
+	return p.pending[:p.n]
+}
+
+func (p *txPackage) remaining() []*NanoTX {
+	return p.pending[p.n:]
+}
+
+// denser returns true if the package has higher fee per gas than the other package.
+// packages with the same fee density are ordered by the arrival time and id of the first transaction.
+func (p *txPackage) denser(other *txPackage) bool {
+	hi, lo := bits.Mul64(p.fee, other.gas)
+	ohi, olo := bits.Mul64(other.fee, p.gas)
+	if hi != ohi {
+		return hi > ohi
+	}
+	if lo != olo {
+		return lo > olo
+	}
+	first, ofirst := p.pending[0], other.pending[0]
+	if !first.Received.Equal(ofirst.Received) {
+		return first.Received.Before(ofirst.Received)
+	}
+	return first.ID.Compare(ofirst.ID)
+}
+
+// bestPackage returns the prefix of the transactions with the highest fee per gas that fits into the budget,
+// or nil if the lowest nonce transaction doesn't fit.
+func bestPackage(pending []*NanoTX, budget selectionBudget) *txPackage {
+	var (
+		best      *txPackage
+		candidate = &txPackage{pending: pending}
+	)
+	for _, ntx := range pending {
+		candidate = &txPackage{
+			pending: pending,
+			n:       candidate.n + 1,
+			fee:     candidate.fee + ntx.Fee(),
+			gas:     candidate.gas + ntx.MaxGas,
+			size:    candidate.size + ntx.Size,
+		}
+		if !budget.fits(candidate) {
+			break
+		}
+		if best == nil || candidate.denser(best) {
+			best = candidate
+		}
+	}
+	return best
+}
+
+type packageQueue []*txPackage
+
+func (pq packageQueue) Len() int           { return len(pq) }
+func (pq packageQueue) Less(i, j int) bool { return pq[i].denser(pq[j]) }
+func (pq packageQueue) Swap(i, j int)      { pq[i], pq[j] = pq[j], pq[i] }
+
+func (pq *packageQueue) Push(i any) {
+	*pq = append(*pq, i.(*txPackage))
+}
+
+func (pq *packageQueue) Pop() any {
+	old := *pq
+	n := len(old)
+	p := old[n-1]
+	old[n-1] = nil
+	*pq = old[:n-1]
+	return p
+}
+
+// selectByFee selects the transactions with the highest fee per gas from the pending transactions
+// of every principal, ordered by nonce. Packages of transactions are selected greedily, so that a transaction
+// with a low fee is selected if it is followed by the transactions that pay for it.
+// The result is deterministic for the same mempool.
+func selectByFee(logger log.Log, mempool map[types.Address][]*NanoTX, budget selectionBudget) []types.TransactionID {
+	pq := make(packageQueue, 0, len(mempool))
+	for _, txs := range mempool {
+		if p := bestPackage(txs, budget); p != nil {
+			pq = append(pq, p)
+		}
+	}
+	heap.Init(&pq)
+	var result []types.TransactionID
+	for pq.Len() > 0 && budget.count > 0 {
+		p := heap.Pop(&pq).(*txPackage)
+		if !budget.fits(p) {
+			// the budget only shrinks, the best package that still fits has the same or lower density
+			if p = bestPackage(p.pending, budget); p != nil {
+				heap.Push(&pq, p)
+			}
+			continue
+		}
+		budget.consume(p)
+		txs := p.txs()
+		for _, ntx := range txs {
+			result = append(result, ntx.ID)
+		}
+		logger.With().Debug("selected txs package",
+			txs[0].Principal,
+			log.Uint64("from", txs[0].Nonce),
+			log.Uint64("to", txs[len(txs)-1].Nonce),
+			log.Uint64("fee", p.fee),
+			log.Uint64("gas", p.gas),
+		)
+		if next := bestPackage(p.remaining(), budget); next != nil {
+			heap.Push(&pq, next)
+		}
+	}
+	return result
+}
+
+// truncateToSize returns the longest prefix of the transactions that fits into the size budget.
+// As the transactions of a principal are ordered by nonce, the prefix doesn't skip nonces.
+func truncateToSize(selected []types.TransactionID, sizes map[types.TransactionID]uint64, budget selectionBudget) []types.TransactionID {
+	total := uint64(0)
+	for i, id := range selected {
+		total += sizes[id]
+		if total > budget.size {
+			return selected[:i]
+		}
+	}
+	return selected
+}
+
+// selectLocal selects the transactions submitted via the api of this node with the highest fee per gas
+// within the percent of the budget. The local lane of a principal ends at its first transaction received
+// from the network, so that the lane keeps the nonce order. The selected transactions are removed from
+// the mempool and consumed from the budget.
+func selectLocal(logger log.Log, mempool map[types.Address][]*NanoTX, budget *selectionBudget, percent uint64) []types.TransactionID {
+	lane := make(map[types.Address][]*NanoTX)
+	for principal, txs := range mempool {
+		n := 0
+		for n < len(txs) && txs[n].Local {
+			n++
+		}
+		if n > 0 {
+			lane[principal] = txs[:n]
+		}
+	}
+	if len(lane) == 0 {
+		return nil
+	}
+	selected := selectByFee(logger, lane, budget.share(percent))
+	picked := make(map[types.TransactionID]struct{}, len(selected))
+	for _, tid := range selected {
+		picked[tid] = struct{}{}
+	}
+	for principal, txs := range lane {
+		n := 0
+		for ; n < len(txs); n++ {
+			if _, ok := picked[txs[n].ID]; !ok {
+				break
+			}
+			budget.count--
+			budget.size -= txs[n].Size
+			budget.gas -= txs[n].MaxGas
+		}
+		if n == len(mempool[principal]) {
+			delete(mempool, principal)
+		} else {
+			mempool[principal] = mempool[principal][n:]
+		}
+	}
+	if len(selected) > 0 {
+		logger.With().Debug("selected local txs", log.Int("num_txs", len(selected)))
+	}
+	return selected
+}
+
+// withoutSelected removes the transactions that are already selected and returns at most limit
+// of the remaining transactions. The transactions of a principal stay in the nonce order.
+func withoutSelected(txs, selected []types.TransactionID, limit int) []types.TransactionID {
+	if len(selected) == 0 {
+		return txs
+	}
+	skip := make(map[types.TransactionID]struct{}, len(selected))
+	for _, tid := range selected {
+		skip[tid] = struct{}{}
+	}
+	rst := make([]types.TransactionID, 0, len(txs))
+	for _, tid := range txs {
+		if len(rst) == limit {
+			break
+		}
+		if _, ok := skip[tid]; !ok {
+			rst = append(rst, tid)
+		}
+	}
+	return rst
+}
+
+// withoutExpired removes the transactions that can't be applied in the layer. The transactions of
+// a principal after an expired one are removed too, as they would fail with a nonce gap.
+func withoutExpired(logger log.Log, mempool map[types.Address][]*NanoTX, lid types.LayerID) map[types.Address][]*NanoTX {
+	for principal, ntxs := range mempool {
+		for i, ntx := range ntxs {
+			if !ntx.Expired(lid) {
+				continue
+			}
+			logger.With().Debug("skipping expired tx",
+				ntx.ID,
+				principal,
+				log.Uint64("nonce", ntx.Nonce),
+				log.Stringer("expiry", ntx.Expiry()),
+				log.Int("skipped", len(ntxs)-i))
+			if i == 0 {
+				delete(mempool, principal)
+			} else {
+				mempool[principal] = ntxs[:i]
+			}
+			break
+		}
+	}
+	return mempool
+}
+
+// unexpiredMempool is the mempool of the cache without the transactions that can't be applied in the layer.
+type unexpiredMempool struct {
+	cache conStateCache
+	lid   types.LayerID
+}
+
+func (m unexpiredMempool) GetMempool(logger log.Log) map[types.Address][]*NanoTX {
+	return withoutExpired(logger, m.cache.GetMempool(logger), m.lid)
+}
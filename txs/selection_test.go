@@ -0,0 +1,176 @@
+package txs
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/spacemeshos/go-spacemesh/common/types"
+	"github.com/spacemeshos/go-spacemesh/log/logtest"
+)
+
+// pendingChain creates pending transactions of the principal with sequential nonces and the given gas prices.
+func pendingChain(principal types.Address, received time.Time, prices ...uint64) []*NanoTX {
+	rst := make([]*NanoTX, 0, len(prices))
+	for i, price := range prices {
+		ntx := &NanoTX{
+			TxHeader: types.TxHeader{
+				Principal: principal,
+				Nonce:     uint64(i),
+				MaxGas:    defaultGas,
+				GasPrice:  price,
+			},
+			ID:       types.RandomTransactionID(),
+			Received: received.Add(time.Duration(i) * time.Millisecond),
+			Size:     100,
+		}
+		rst = append(rst, ntx)
+	}
+	return rst
+}
+
+func ids(ntxs ...*NanoTX) []types.TransactionID {
+	rst := make([]types.TransactionID, 0, len(ntxs))
+	for _, ntx := range ntxs {
+		rst = append(rst, ntx.ID)
+	}
+	return rst
+}
+
+func TestSelectByFee(t *testing.T) {
+	now := time.Now()
+	// the middle transaction of the first principal has a low fee, the last one pays for it
+	first := pendingChain(types.Address{1}, now, 10, 1, 20)
+	second := pendingChain(types.Address{2}, now.Add(time.Second), 8, 8)
+	third := pendingChain(types.Address{3}, now.Add(2*time.Second), 2)
+	mempool := func() map[types.Address][]*NanoTX {
+		return map[types.Address][]*NanoTX{
+			first[0].Principal:  first,
+			second[0].Principal: second,
+			third[0].Principal:  third,
+		}
+	}
+
+	for _, tc := range []struct {
+		desc     string
+		budget   selectionBudget
+		expected []types.TransactionID
+	}{
+		{
+			desc:     "all selected",
+			budget:   newSelectionBudget(10, 0, math.MaxUint64),
+			expected: ids(first[0], first[1], first[2], second[0], second[1], third[0]),
+		},
+		{
+			desc:     "low fee selected with the tx that pays for it",
+			budget:   newSelectionBudget(4, 0, math.MaxUint64),
+			expected: ids(first[0], first[1], first[2], second[0]),
+		},
+		{
+			desc:     "low fee skipped when the tx that pays for it does not fit",
+			budget:   newSelectionBudget(2, 0, math.MaxUint64),
+			expected: ids(first[0], second[0]),
+		},
+		{
+			desc:     "size budget",
+			budget:   newSelectionBudget(10, 350, math.MaxUint64),
+			expected: ids(first[0], first[1], first[2]),
+		},
+		{
+			desc:     "gas budget",
+			budget:   newSelectionBudget(10, 0, 2*defaultGas),
+			expected: ids(first[0], second[0]),
+		},
+		{
+			desc:   "nothing fits",
+			budget: newSelectionBudget(10, 50, math.MaxUint64),
+		},
+	} {
+		tc := tc
+		t.Run(tc.desc, func(t *testing.T) {
+			got := selectByFee(logtest.New(t), mempool(), tc.budget)
+			require.Equal(t, tc.expected, got)
+		})
+	}
+}
+
+func TestSelectByFee_NonceOrder(t *testing.T) {
+	rng := rand.New(rand.NewSource(1001))
+	now := time.Now()
+	mempool := map[types.Address][]*NanoTX{}
+	for i := 0; i < 20; i++ {
+		prices := make([]uint64, 1+rng.Intn(10))
+		for j := range prices {
+			prices[j] = 1 + uint64(rng.Intn(20))
+		}
+		principal := types.Address{byte(i + 1)}
+		mempool[principal] = pendingChain(principal, now, prices...)
+	}
+	byID := map[types.TransactionID]*NanoTX{}
+	for _, pending := range mempool {
+		for _, ntx := range pending {
+			byID[ntx.ID] = ntx
+		}
+	}
+
+	budget := newSelectionBudget(40, 0, math.MaxUint64)
+	got := selectByFee(logtest.New(t), mempool, budget)
+	require.Len(t, got, 40)
+	next := map[types.Address]uint64{}
+	for _, id := range got {
+		ntx := byID[id]
+		require.Equal(t, next[ntx.Principal], ntx.Nonce, "nonce gap for %s", ntx.Principal)
+		next[ntx.Principal]++
+	}
+	// the selection is the same for the same mempool
+	for i := 0; i < 10; i++ {
+		require.Equal(t, got, selectByFee(logtest.New(t), mempool, budget))
+	}
+}
+
+func TestSelectLocal(t *testing.T) {
+	now := time.Now()
+	local := pendingChain(types.Address{1}, now, 1, 1, 1)
+	for _, ntx := range local[:2] {
+		ntx.Local = true
+	}
+	remote := pendingChain(types.Address{2}, now, 10, 10)
+	mempool := map[types.Address][]*NanoTX{
+		local[0].Principal:  local,
+		remote[0].Principal: remote,
+	}
+	budget := newSelectionBudget(4, 0, math.MaxUint64)
+	// the lane ends at the first tx received from the network
+	require.Equal(t, ids(local[0], local[1]), selectLocal(logtest.New(t), mempool, &budget, 100))
+	require.Equal(t, 2, budget.count)
+	require.Equal(t, math.MaxUint64-2*defaultGas, budget.gas)
+	require.Equal(t, map[types.Address][]*NanoTX{
+		local[0].Principal:  local[2:],
+		remote[0].Principal: remote,
+	}, mempool)
+
+	// the lane is limited by its share of the budget
+	mempool = map[types.Address][]*NanoTX{local[0].Principal: local[:2]}
+	budget = newSelectionBudget(4, 0, math.MaxUint64)
+	require.Equal(t, ids(local[0]), selectLocal(logtest.New(t), mempool, &budget, 25))
+	require.Equal(t, 3, budget.count)
+	require.Equal(t, map[types.Address][]*NanoTX{local[0].Principal: local[1:2]}, mempool)
+}
+
+func TestTruncateToSize(t *testing.T) {
+	selected := ids(pendingChain(types.Address{1}, time.Now(), 1, 1, 1)...)
+	sizes := map[types.TransactionID]uint64{selected[0]: 10, selected[1]: 20, selected[2]: 30}
+	require.Equal(t, selected, truncateToSize(selected, sizes, newSelectionBudget(3, 0, math.MaxUint64)))
+	require.Equal(t, selected[:2], truncateToSize(selected, sizes, newSelectionBudget(3, 59, math.MaxUint64)))
+	require.Empty(t, truncateToSize(selected, sizes, newSelectionBudget(3, 9, math.MaxUint64)))
+}
+
+func TestSelectionPolicy_Validate(t *testing.T) {
+	require.NoError(t, SelectFIFO.Validate())
+	require.NoError(t, SelectFee.Validate())
+	require.Error(t, SelectionPolicy("").Validate())
+	require.Error(t, SelectionPolicy("random").Validate())
+}
@@ -38,6 +38,20 @@ func (m *MockconservativeState) EXPECT() *MockconservativeStateMockRecorder {
 	return m.recorder
 }
 
+// AddLocalToCache mocks base method.
+func (m *MockconservativeState) AddLocalToCache(arg0 context.Context, arg1 *types.Transaction, arg2 time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddLocalToCache", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// AddLocalToCache indicates an expected call of AddLocalToCache.
+func (mr *MockconservativeStateMockRecorder) AddLocalToCache(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddLocalToCache", reflect.TypeOf((*MockconservativeState)(nil).AddLocalToCache), arg0, arg1, arg2)
+}
+
 // AddToCache mocks base method.
 func (m *MockconservativeState) AddToCache(arg0 context.Context, arg1 *types.Transaction, arg2 time.Time) error {
 	m.ctrl.T.Helper()
@@ -96,6 +110,20 @@ func (mr *MockconservativeStateMockRecorder) HasTx(arg0 interface{}) *gomock.Cal
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "HasTx", reflect.TypeOf((*MockconservativeState)(nil).HasTx), arg0)
 }
 
+// Projection mocks base method.
+func (m *MockconservativeState) Projection(arg0 types.Address) Projection {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Projection", arg0)
+	ret0, _ := ret[0].(Projection)
+	return ret0
+}
+
+// Projection indicates an expected call of Projection.
+func (mr *MockconservativeStateMockRecorder) Projection(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Projection", reflect.TypeOf((*MockconservativeState)(nil).Projection), arg0)
+}
+
 // Validation mocks base method.
 func (m *MockconservativeState) Validation(arg0 types.RawTx) system.ValidationRequest {
 	m.ctrl.T.Helper()